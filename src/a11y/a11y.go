@@ -0,0 +1,377 @@
+// Package a11y runs basic accessibility lint over rendered HTML pages and the
+// theme palettes. It backs the operator self-check: it catches the mistakes
+// that are cheap to detect mechanically (missing landmarks, alt text, form
+// labels, low-contrast colors) and is no substitute for testing with real
+// assistive technology.
+package a11y
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Severity grades an issue. Errors break assistive technology outright;
+// warnings make pages harder to use.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule names reported in issues
+const (
+	RuleLang         = "lang"
+	RuleTitle        = "title"
+	RuleMain         = "landmark-main"
+	RuleImageAlt     = "img-alt"
+	RuleLabel        = "label"
+	RuleName         = "accessible-name"
+	RuleHeadingOrder = "heading-order"
+	RuleDuplicateID  = "duplicate-id"
+	RuleContrast     = "contrast"
+)
+
+// Issue is a single lint finding
+type Issue struct {
+	Page     string   `json:"page"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Element is a short rendering of the offending start tag
+	Element string `json:"element,omitempty"`
+}
+
+// Report collects the issues found across pages and palettes
+type Report struct {
+	Pages    []string `json:"pages"`
+	Skipped  []string `json:"skipped,omitempty"`
+	Issues   []Issue  `json:"issues"`
+	Errors   int      `json:"errors"`
+	Warnings int      `json:"warnings"`
+}
+
+// NewReport returns an empty report
+func NewReport() *Report {
+	return &Report{Pages: []string{}, Issues: []Issue{}}
+}
+
+// Add records issues and updates the totals
+func (r *Report) Add(issues ...Issue) {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			r.Errors++
+		} else {
+			r.Warnings++
+		}
+		r.Issues = append(r.Issues, issue)
+	}
+}
+
+// LintPage parses an HTML document and checks it. page names the document
+// in the returned issues.
+func LintPage(page string, doc io.Reader) ([]Issue, error) {
+	root, err := html.Parse(doc)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{page: page, ids: make(map[string]int), labelled: make(map[string]bool)}
+	l.collect(root)
+	l.check(root)
+	return l.issues, nil
+}
+
+type linter struct {
+	page     string
+	issues   []Issue
+	ids      map[string]int
+	labelled map[string]bool
+	mains    int
+	title    bool
+	lastH    int
+}
+
+func (l *linter) report(rule string, severity Severity, n *html.Node, format string, args ...interface{}) {
+	issue := Issue{
+		Page:     l.page,
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if n != nil {
+		issue.Element = startTag(n)
+	}
+	l.issues = append(l.issues, issue)
+}
+
+// collect gathers document-wide facts needed before elements are checked
+func (l *linter) collect(n *html.Node) {
+	if n.Type == html.ElementNode {
+		if id := attr(n, "id"); id != "" {
+			l.ids[id]++
+		}
+		if n.DataAtom == atom.Label {
+			if target := attr(n, "for"); target != "" {
+				l.labelled[target] = true
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l.collect(c)
+	}
+}
+
+func (l *linter) check(root *html.Node) {
+	l.walk(root)
+
+	if htmlNode := findFirst(root, atom.Html); htmlNode != nil && strings.TrimSpace(attr(htmlNode, "lang")) == "" {
+		l.report(RuleLang, SeverityError, htmlNode, "<html> has no lang attribute")
+	}
+	if !l.title {
+		l.report(RuleTitle, SeverityError, nil, "page has no non-empty <title>")
+	}
+	switch {
+	case l.mains == 0:
+		l.report(RuleMain, SeverityError, nil, "page has no <main> landmark")
+	case l.mains > 1:
+		l.report(RuleMain, SeverityWarning, nil, "page has %d <main> landmarks; expected one", l.mains)
+	}
+
+	var dupes []string
+	for id, count := range l.ids {
+		if count > 1 {
+			dupes = append(dupes, id)
+		}
+	}
+	sort.Strings(dupes)
+	for _, id := range dupes {
+		l.report(RuleDuplicateID, SeverityError, nil, "id %q is used %d times", id, l.ids[id])
+	}
+}
+
+func (l *linter) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		if hidden(n) {
+			// Hidden subtrees are not exposed to assistive technology
+			return
+		}
+		l.element(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l.walk(c)
+	}
+}
+
+func (l *linter) element(n *html.Node) {
+	if n.DataAtom == atom.Main || attr(n, "role") == "main" {
+		l.mains++
+	}
+
+	switch n.DataAtom {
+	case atom.Title:
+		if strings.TrimSpace(textContent(n)) != "" {
+			l.title = true
+		}
+	case atom.Img:
+		if _, ok := attrOK(n, "alt"); !ok && attr(n, "role") != "presentation" && attr(n, "role") != "none" {
+			l.report(RuleImageAlt, SeverityError, n, "image has no alt attribute; use alt=\"\" for decorative images")
+		}
+	case atom.Input, atom.Select, atom.Textarea:
+		l.formControl(n)
+	case atom.A:
+		if _, ok := attrOK(n, "href"); ok && !hasName(n) {
+			l.report(RuleName, SeverityError, n, "link has no accessible name")
+		}
+	case atom.Button:
+		if !hasName(n) {
+			l.report(RuleName, SeverityError, n, "button has no accessible name")
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		if l.lastH > 0 && level > l.lastH+1 {
+			l.report(RuleHeadingOrder, SeverityWarning, n, "heading level jumps from h%d to h%d", l.lastH, level)
+		}
+		l.lastH = level
+	}
+
+	l.inlineContrast(n)
+}
+
+func (l *linter) formControl(n *html.Node) {
+	if n.DataAtom == atom.Input {
+		switch strings.ToLower(attr(n, "type")) {
+		case "hidden", "submit", "reset", "button":
+			// Buttons are named by their value
+			return
+		case "image":
+			if strings.TrimSpace(attr(n, "alt")) == "" && !hasARIAName(n) {
+				l.report(RuleName, SeverityError, n, "image button has no alt text")
+			}
+			return
+		}
+	}
+	if hasARIAName(n) || strings.TrimSpace(attr(n, "title")) != "" {
+		return
+	}
+	if id := attr(n, "id"); id != "" && l.labelled[id] {
+		return
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.DataAtom == atom.Label {
+			return
+		}
+	}
+	l.report(RuleLabel, SeverityError, n, "form control has no label")
+}
+
+// inlineContrast checks elements whose inline style sets both a text and a
+// background color; stylesheet colors are covered by CheckPalettes
+func (l *linter) inlineContrast(n *html.Node) {
+	style := attr(n, "style")
+	if style == "" {
+		return
+	}
+	var fg, bg string
+	for _, decl := range strings.Split(style, ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(prop)) {
+		case "color":
+			fg = strings.TrimSpace(value)
+		case "background", "background-color":
+			bg = strings.TrimSpace(value)
+		}
+	}
+	if fg == "" || bg == "" {
+		return
+	}
+	ratio, err := ContrastRatio(fg, bg)
+	if err != nil {
+		return
+	}
+	if ratio < MinContrast {
+		l.report(RuleContrast, SeverityError, n, "inline colors %s on %s have contrast %.2f:1, below %.1f:1", fg, bg, ratio, MinContrast)
+	}
+}
+
+func hidden(n *html.Node) bool {
+	if _, ok := attrOK(n, "hidden"); ok {
+		return true
+	}
+	if attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Template, atom.Script, atom.Style, atom.Noscript:
+		return true
+	}
+	return false
+}
+
+func hasARIAName(n *html.Node) bool {
+	return strings.TrimSpace(attr(n, "aria-label")) != "" || strings.TrimSpace(attr(n, "aria-labelledby")) != ""
+}
+
+// hasName reports whether a link or button exposes a name: ARIA naming, a
+// title, visible text, or an image/SVG descendant that carries a label
+func hasName(n *html.Node) bool {
+	if hasARIAName(n) || strings.TrimSpace(attr(n, "title")) != "" {
+		return true
+	}
+	var named bool
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		if named {
+			return
+		}
+		switch c.Type {
+		case html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				named = true
+			}
+			return
+		case html.ElementNode:
+			if attr(c, "aria-hidden") == "true" {
+				return
+			}
+			if c.DataAtom == atom.Img && strings.TrimSpace(attr(c, "alt")) != "" {
+				named = true
+				return
+			}
+			if c.DataAtom == atom.Svg && hasARIAName(c) {
+				named = true
+				return
+			}
+		}
+		for gc := c.FirstChild; gc != nil; gc = gc.NextSibling {
+			visit(gc)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		visit(c)
+	}
+	return named
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		for gc := c.FirstChild; gc != nil; gc = gc.NextSibling {
+			visit(gc)
+		}
+	}
+	visit(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := attrOK(n, key)
+	return v
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// startTag renders n's start tag, shortened for reports
+func startTag(n *html.Node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		val := a.Val
+		if len(val) > 40 {
+			val = val[:40] + "…"
+		}
+		fmt.Fprintf(&b, " %s=%q", a.Key, val)
+	}
+	b.WriteString(">")
+	return b.String()
+}
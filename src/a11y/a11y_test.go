@@ -0,0 +1,128 @@
+package a11y
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func rules(issues []Issue) map[string]int {
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Rule]++
+	}
+	return counts
+}
+
+func TestLintPageClean(t *testing.T) {
+	doc := `<!DOCTYPE html><html lang="en"><head><title>Search</title></head><body>
+<header><a href="/"><img src="/logo.png" alt="Home"></a></header>
+<main><h1>Results</h1><h2>Web</h2>
+<form><label for="q">Query</label><input id="q" name="q"><input type="hidden" name="t">
+<label>Safe <select name="s"><option>on</option></select></label>
+<button type="submit" aria-label="Search"><svg aria-hidden="true"></svg></button></form>
+<img src="/spacer.gif" alt="">
+<div hidden><img src="/x.png"></div>
+</main></body></html>`
+
+	issues, err := LintPage("/", strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LintPage() error = %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("LintPage() = %+v, want no issues", issues)
+	}
+}
+
+func TestLintPageIssues(t *testing.T) {
+	doc := `<html><head></head><body>
+<h1>Title</h1><h3>Skipped</h3>
+<img src="/a.png">
+<input name="q">
+<a href="/x"><svg></svg></a>
+<button></button>
+<span id="dup"></span><span id="dup"></span>
+<p style="color: #777; background-color: #888">low</p>
+</body></html>`
+
+	issues, err := LintPage("/bad", strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LintPage() error = %v", err)
+	}
+	got := rules(issues)
+	want := map[string]int{
+		RuleLang:         1,
+		RuleTitle:        1,
+		RuleMain:         1,
+		RuleImageAlt:     1,
+		RuleLabel:        1,
+		RuleName:         2,
+		RuleHeadingOrder: 1,
+		RuleDuplicateID:  1,
+		RuleContrast:     1,
+	}
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Errorf("rule %s reported %d times, want %d", rule, got[rule], n)
+		}
+	}
+	for _, issue := range issues {
+		if issue.Page != "/bad" {
+			t.Errorf("issue page = %q, want /bad", issue.Page)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		fg, bg string
+		want   float64
+	}{
+		{"#000000", "#ffffff", 21},
+		{"#fff", "#000", 21},
+		{"#777777", "#777777", 1},
+		{"#767676", "#ffffff", 4.54},
+	}
+	for _, tt := range tests {
+		got, err := ContrastRatio(tt.fg, tt.bg)
+		if err != nil {
+			t.Fatalf("ContrastRatio(%s, %s) error = %v", tt.fg, tt.bg, err)
+		}
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("ContrastRatio(%s, %s) = %.2f, want %.2f", tt.fg, tt.bg, got, tt.want)
+		}
+	}
+	if _, err := ContrastRatio("rgb(0,0,0)", "#fff"); err == nil {
+		t.Error("ContrastRatio() should reject non-hex colors")
+	}
+}
+
+func TestCheckPalettes(t *testing.T) {
+	css := `:root,
+html.theme-dark {
+    --text-primary: #f8f8f2; /* body */
+    --bg-primary: #282a36;
+    --text-muted: #6272a4;
+}
+html.theme-contrast {
+    --text-primary: #ffffff;
+    --bg-primary: #000000;
+    --link-color: #3b82f6;
+}`
+	palettes := ParsePalettes(css)
+	if len(palettes) != 2 || palettes["dark"]["--bg-primary"] != "#282a36" {
+		t.Fatalf("ParsePalettes() = %v", palettes)
+	}
+
+	issues := CheckPalettes("theme", palettes, "contrast")
+	if len(issues) != 2 {
+		t.Fatalf("CheckPalettes() = %+v, want 2 issues", issues)
+	}
+	// #3b82f6 on black passes AA but not the enhanced level
+	if !strings.Contains(issues[0].Message, "theme contrast: --link-color") {
+		t.Errorf("first issue = %q, want the contrast theme link color", issues[0].Message)
+	}
+	if !strings.Contains(issues[1].Message, "theme dark: --text-muted") {
+		t.Errorf("second issue = %q, want the dark theme muted text", issues[1].Message)
+	}
+}
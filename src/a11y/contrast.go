@@ -0,0 +1,138 @@
+package a11y
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Contrast thresholds from WCAG 2.x for normal-size text
+const (
+	// MinContrast is the AA minimum
+	MinContrast = 4.5
+	// EnhancedContrast is the AAA minimum, required of the high-contrast theme
+	EnhancedContrast = 7.0
+)
+
+// ColorPair names a text color variable and the background it is drawn on
+type ColorPair struct {
+	Foreground string
+	Background string
+}
+
+// PalettePairs are the theme variable pairs that carry body text
+var PalettePairs = []ColorPair{
+	{"--text-primary", "--bg-primary"},
+	{"--text-primary", "--bg-secondary"},
+	{"--text-secondary", "--bg-primary"},
+	{"--text-muted", "--bg-primary"},
+	{"--link-color", "--bg-primary"},
+	{"--text-primary", "--input-bg"},
+	{"--accent-error", "--bg-primary"},
+}
+
+var (
+	themeBlockPattern = regexp.MustCompile(`html\.theme-([a-z-]+)\s*\{([^}]*)\}`)
+	cssVarPattern     = regexp.MustCompile(`(--[a-zA-Z0-9-]+)\s*:\s*([^;]+);`)
+)
+
+// ParsePalettes extracts the custom properties of each html.theme-* block in
+// a stylesheet, keyed by theme name
+func ParsePalettes(css string) map[string]map[string]string {
+	palettes := make(map[string]map[string]string)
+	for _, block := range themeBlockPattern.FindAllStringSubmatch(css, -1) {
+		vars := palettes[block[1]]
+		if vars == nil {
+			vars = make(map[string]string)
+			palettes[block[1]] = vars
+		}
+		for _, decl := range cssVarPattern.FindAllStringSubmatch(block[2], -1) {
+			vars[decl[1]] = strings.TrimSpace(decl[2])
+		}
+	}
+	return palettes
+}
+
+// CheckPalettes checks every theme palette against PalettePairs. Themes
+// listed in enhanced must meet EnhancedContrast rather than MinContrast.
+func CheckPalettes(page string, palettes map[string]map[string]string, enhanced ...string) []Issue {
+	themes := make([]string, 0, len(palettes))
+	for theme := range palettes {
+		themes = append(themes, theme)
+	}
+	sort.Strings(themes)
+
+	var issues []Issue
+	for _, theme := range themes {
+		min := MinContrast
+		for _, e := range enhanced {
+			if e == theme {
+				min = EnhancedContrast
+			}
+		}
+		vars := palettes[theme]
+		for _, pair := range PalettePairs {
+			fg, bg := vars[pair.Foreground], vars[pair.Background]
+			if fg == "" || bg == "" {
+				continue
+			}
+			ratio, err := ContrastRatio(fg, bg)
+			if err != nil {
+				continue
+			}
+			if ratio < min {
+				issues = append(issues, Issue{
+					Page:     page,
+					Rule:     RuleContrast,
+					Severity: SeverityError,
+					Message: fmt.Sprintf("theme %s: %s (%s) on %s (%s) has contrast %.2f:1, below %.1f:1",
+						theme, pair.Foreground, fg, pair.Background, bg, ratio, min),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// ContrastRatio returns the WCAG contrast ratio between two hex colors
+func ContrastRatio(fg, bg string) (float64, error) {
+	l1, err := luminance(fg)
+	if err != nil {
+		return 0, err
+	}
+	l2, err := luminance(bg)
+	if err != nil {
+		return 0, err
+	}
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05), nil
+}
+
+// luminance is the WCAG relative luminance of a #rgb or #rrggbb color
+func luminance(color string) (float64, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("unsupported color %q", color)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported color %q", color)
+	}
+
+	channel := func(c uint64) float64 {
+		s := float64(c) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(v>>16&0xff) + 0.7152*channel(v>>8&0xff) + 0.0722*channel(v&0xff), nil
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/apimgr/search/src/model"
 	"github.com/apimgr/search/src/search"
 	"github.com/apimgr/search/src/ssl"
+	"github.com/apimgr/search/src/webhook"
 )
 
 type Frequency string
@@ -111,6 +112,8 @@ type CreateResponse struct {
 	Alert       *Alert
 	ManageToken string
 	RSSToken    string
+	// WebhookSecret signs webhook deliveries; it is shown only at creation
+	WebhookSecret string
 }
 
 type UpdateRequest struct {
@@ -161,7 +164,7 @@ func (m *Manager) Create(ctx context.Context, req CreateRequest) (*CreateRespons
 	req.WebhookURL = strings.TrimSpace(req.WebhookURL)
 	req.BaseURL = strings.TrimRight(strings.TrimSpace(req.BaseURL), "/")
 	if req.BaseURL == "" {
-		req.BaseURL = strings.TrimRight(strings.TrimSpace(m.serverConfig.Server.BaseURL), "/")
+		req.BaseURL = m.serverConfig.Server.PublicURL("")
 	}
 	// Per AI.md PART 11: privacy — raw IPs must never be persisted.
 	// Hash the IP so rate-limit buckets remain functional without storing the address.
@@ -231,13 +234,13 @@ func (m *Manager) Create(ctx context.Context, req CreateRequest) (*CreateRespons
 		return nil, fmt.Errorf("encrypt rss token: %w", err)
 	}
 
-	webhookSecretEncrypted := ""
+	webhookSecret, webhookSecretEncrypted := "", ""
 	if req.DeliverWebhook {
-		secret, err := randomToken(32)
+		webhookSecret, err = randomToken(32)
 		if err != nil {
 			return nil, err
 		}
-		webhookSecretEncrypted, err = ssl.EncryptCredentials(map[string]string{"secret": secret}, m.serverConfig.Server.SecretKey)
+		webhookSecretEncrypted, err = ssl.EncryptCredentials(map[string]string{"secret": webhookSecret}, m.serverConfig.Server.SecretKey)
 		if err != nil {
 			return nil, fmt.Errorf("encrypt webhook secret: %w", err)
 		}
@@ -289,9 +292,10 @@ func (m *Manager) Create(ctx context.Context, req CreateRequest) (*CreateRespons
 	}
 
 	return &CreateResponse{
-		Alert:       alert,
-		ManageToken: manageToken,
-		RSSToken:    rssToken,
+		Alert:         alert,
+		ManageToken:   manageToken,
+		RSSToken:      rssToken,
+		WebhookSecret: webhookSecret,
 	}, nil
 }
 
@@ -646,6 +650,8 @@ func (m *Manager) sendWebhook(ctx context.Context, alert *Alert, results []Alert
 	if err != nil {
 		return err
 	}
+	// X-Search-Alert-Signature signs the body alone and is kept for
+	// receivers written before the timestamped signature
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write(data)
 	signature := hex.EncodeToString(mac.Sum(nil))
@@ -667,6 +673,9 @@ func (m *Manager) sendWebhook(ctx context.Context, alert *Alert, results []Alert
 		}
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("X-Search-Alert-Signature", "sha256="+signature)
+		if err := webhook.Sign(req.Header, secret, data, time.Now()); err != nil {
+			return err
+		}
 		req.Header.Set("User-Agent", "search-alerts/1.0")
 		resp, err := m.client.Do(req)
 		if err == nil && resp != nil && resp.Body != nil {
@@ -1076,6 +1085,78 @@ func decryptAlertToken(encrypted, secretKey string) (string, error) {
 	return strings.TrimSpace(creds["token"]), nil
 }
 
+// CountEncrypted returns how many alerts hold tokens or a webhook secret
+// encrypted with server.secret_key
+func (m *Manager) CountEncrypted(ctx context.Context) (int, error) {
+	if m.db == nil {
+		return 0, nil
+	}
+	var n int
+	err := m.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM search_alerts
+		WHERE manage_token_encrypted != '' OR rss_token_encrypted != '' OR COALESCE(webhook_secret_encrypted, '') != ''`).Scan(&n)
+	return n, err
+}
+
+// Reencrypt re-encrypts every alert's tokens and webhook secret, encrypted
+// with oldKey, under newKey, all or none, returning how many alerts held
+// any. server.secret_key must be set to newKey once it returns.
+func (m *Manager) Reencrypt(ctx context.Context, oldKey, newKey string) (int, error) {
+	if m.db == nil {
+		return 0, nil
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, `SELECT id, manage_token_encrypted, rss_token_encrypted, COALESCE(webhook_secret_encrypted, '') FROM search_alerts`)
+	if err != nil {
+		return 0, err
+	}
+	type encrypted struct{ id, manage, rss, webhook string }
+	var alerts []encrypted
+	for rows.Next() {
+		var a encrypted
+		if err := rows.Scan(&a.id, &a.manage, &a.rss, &a.webhook); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if a.manage != "" || a.rss != "" || a.webhook != "" {
+			alerts = append(alerts, a)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	// Tokens and webhook secrets are both credential maps
+	reencrypt := func(value string) (string, error) {
+		if strings.TrimSpace(value) == "" {
+			return value, nil
+		}
+		creds, err := ssl.DecryptCredentials(value, oldKey)
+		if err != nil {
+			return "", err
+		}
+		return ssl.EncryptCredentials(creds, newKey)
+	}
+	for _, a := range alerts {
+		for _, field := range []*string{&a.manage, &a.rss, &a.webhook} {
+			if *field, err = reencrypt(*field); err != nil {
+				return 0, fmt.Errorf("alert %s: %w", a.id, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE search_alerts SET manage_token_encrypted = ?, rss_token_encrypted = ?, webhook_secret_encrypted = ? WHERE id = ?`,
+			a.manage, a.rss, a.webhook, a.id); err != nil {
+			return 0, fmt.Errorf("alert %s: %w", a.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(alerts), nil
+}
+
 func randomToken(bytesLen int) (string, error) {
 	buf := make([]byte, bytesLen)
 	if _, err := rand.Read(buf); err != nil {
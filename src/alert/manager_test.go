@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/model"
 	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/webhook"
 	_ "modernc.org/sqlite"
 )
 
@@ -224,6 +226,42 @@ func TestCreateStoresDistinctPrivateTokens(t *testing.T) {
 	}
 }
 
+func TestReencryptAlertTokens(t *testing.T) {
+	manager, db := newTestManager(t, newTestEngine("google", "general"))
+	defer db.Close()
+	ctx := context.Background()
+
+	created, err := manager.Create(ctx, CreateRequest{
+		Query:      "privacy search",
+		Category:   "general",
+		Language:   "en",
+		Frequency:  FrequencyDaily,
+		Email:      "alerts@example.com",
+		DeliverRSS: true,
+		BaseURL:    "https://search.test",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if n, err := manager.CountEncrypted(ctx); err != nil || n != 1 {
+		t.Fatalf("CountEncrypted() = %d, %v; want 1", n, err)
+	}
+
+	oldKey := manager.serverConfig.Server.SecretKey
+	if _, err := manager.Reencrypt(ctx, "not-the-key", "new-secret-key"); err == nil {
+		t.Fatal("Reencrypt() with the wrong key should fail")
+	}
+	if n, err := manager.Reencrypt(ctx, oldKey, "new-secret-key"); err != nil || n != 1 {
+		t.Fatalf("Reencrypt() = %d, %v; want 1", n, err)
+	}
+	manager.serverConfig.Server.SecretKey = "new-secret-key"
+
+	rssToken, err := manager.RSSTokenForManageToken(ctx, created.ManageToken)
+	if err != nil || rssToken != created.RSSToken {
+		t.Fatalf("RSSTokenForManageToken() after rotation = %q, %v; want %q", rssToken, err, created.RSSToken)
+	}
+}
+
 func TestUpdateReplacesAlertFilterContext(t *testing.T) {
 	manager, db := newTestManager(t, newTestEngine("google", "general"), newTestEngine("bing", "news"))
 	defer db.Close()
@@ -1114,9 +1152,14 @@ func TestSendWebhookSucceeds(t *testing.T) {
 	allowLoopbackWebhooks = true
 	defer func() { allowLoopbackWebhooks = false }()
 
-	received := make(chan struct{}, 1)
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan delivery, 1)
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		received <- struct{}{}
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{r.Header, body}
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer srv.Close()
@@ -1156,7 +1199,13 @@ func TestSendWebhookSucceeds(t *testing.T) {
 	}
 
 	select {
-	case <-received:
+	case d := <-received:
+		if resp.WebhookSecret == "" {
+			t.Fatal("Create() returned no webhook secret")
+		}
+		if err := webhook.Verify(d.header, resp.WebhookSecret, d.body, time.Now(), 0, nil); err != nil {
+			t.Errorf("delivery signature does not verify: %v", err)
+		}
 	case <-time.After(3 * time.Second):
 		t.Fatal("webhook server never received a request")
 	}
@@ -0,0 +1,147 @@
+package alert
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SubjectExport is every stored record about one data subject: their alert
+// subscriptions and the results found for them. Alert subscriptions are the
+// only personal data the server keeps; searches are never stored.
+type SubjectExport struct {
+	Subject    string         `json:"subject"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Alerts     []SubjectAlert `json:"alerts"`
+}
+
+// SubjectAlert is one alert subscription with its stored results
+type SubjectAlert struct {
+	Alert   *Alert        `json:"alert"`
+	Results []AlertResult `json:"results"`
+}
+
+// SubjectHash identifies an email in audit records without keeping it, so a
+// later request can be matched to an erasure
+func SubjectHash(email string) string {
+	hash := sha256.Sum256([]byte(normalizeSubject(email)))
+	return hex.EncodeToString(hash[:])
+}
+
+func normalizeSubject(email string) string {
+	return strings.TrimSpace(strings.ToLower(email))
+}
+
+// ExportSubject returns every alert subscribed with email, with its
+// results. Intended for operator use only — callers must enforce auth.
+func (m *Manager) ExportSubject(ctx context.Context, email string) (*SubjectExport, error) {
+	email = normalizeSubject(email)
+	if email == "" {
+		return nil, fmt.Errorf("%w: email is required", ErrInvalidInput)
+	}
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT id, email, query, category, language, region, engines_json, safe_search, frequency,
+		       deliver_email, deliver_rss, deliver_webhook, webhook_url,
+		       email_verified, status, base_url, last_checked_at, last_sent_at,
+		       last_error, created_from_ip, created_at, verified_at, paused_at
+		FROM search_alerts
+		WHERE lower(email) = ?
+		ORDER BY created_at ASC
+	`, email)
+	if err != nil {
+		return nil, fmt.Errorf("export alerts: %w", err)
+	}
+	var alerts []*Alert
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return m.subjectExport(ctx, email, alerts)
+}
+
+// ExportAlert returns the alert behind manageToken with its results, for
+// the subscriber's own copy of their data
+func (m *Manager) ExportAlert(ctx context.Context, manageToken string) (*SubjectExport, error) {
+	a, err := m.GetByManageToken(ctx, manageToken)
+	if err != nil {
+		return nil, err
+	}
+	return m.subjectExport(ctx, a.Email, []*Alert{a})
+}
+
+func (m *Manager) subjectExport(ctx context.Context, subject string, alerts []*Alert) (*SubjectExport, error) {
+	export := &SubjectExport{Subject: subject, ExportedAt: time.Now().UTC(), Alerts: []SubjectAlert{}}
+	for _, a := range alerts {
+		results, err := m.alertResults(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Alerts = append(export.Alerts, SubjectAlert{Alert: a, Results: results})
+	}
+	return export, nil
+}
+
+func (m *Manager) alertResults(ctx context.Context, alertID string) ([]AlertResult, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT id, title, url, content, engine, published_at, first_seen_at, notified_email_at, notified_webhook_at
+		FROM search_alert_results
+		WHERE alert_id = ?
+		ORDER BY first_seen_at ASC
+	`, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("export alert results: %w", err)
+	}
+	defer rows.Close()
+	results := []AlertResult{}
+	for rows.Next() {
+		result, err := scanAlertResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *result)
+	}
+	return results, rows.Err()
+}
+
+// EraseSubject irreversibly deletes every alert subscribed with email and
+// its results, returning how many alerts were erased. Intended for
+// operator use only — callers must enforce auth and record the erasure.
+func (m *Manager) EraseSubject(ctx context.Context, email string) (int, error) {
+	email = normalizeSubject(email)
+	if email == "" {
+		return 0, fmt.Errorf("%w: email is required", ErrInvalidInput)
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("erase alerts: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Results first, so nothing is left behind without foreign keys on
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM search_alert_results
+		WHERE alert_id IN (SELECT id FROM search_alerts WHERE lower(email) = ?)
+	`, email); err != nil {
+		return 0, fmt.Errorf("erase alert results: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM search_alerts WHERE lower(email) = ?`, email)
+	if err != nil {
+		return 0, fmt.Errorf("erase alerts: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("erase alerts: %w", err)
+	}
+	erased, _ := result.RowsAffected()
+	return int(erased), nil
+}
@@ -0,0 +1,92 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func createSubjectAlert(t *testing.T, manager *Manager, email, query string) *CreateResponse {
+	t.Helper()
+	resp, err := manager.Create(context.Background(), CreateRequest{
+		Query:      query,
+		Category:   "general",
+		Frequency:  FrequencyDaily,
+		Email:      email,
+		DeliverRSS: true,
+		BaseURL:    "https://search.test",
+	})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	return resp
+}
+
+func TestExportAndEraseSubject(t *testing.T) {
+	google := newTestEngine("google", "general")
+	google.results = []model.Result{{URL: "https://example.com/a", Title: "Alpha", Engine: "google"}}
+	manager, db := newTestManager(t, google)
+	defer db.Close()
+	ctx := context.Background()
+
+	first := createSubjectAlert(t, manager, "Subject@Example.com", "one")
+	createSubjectAlert(t, manager, "subject@example.com", "two")
+	other := createSubjectAlert(t, manager, "other@example.com", "three")
+	if err := manager.ProcessDue(ctx, FrequencyDaily); err != nil {
+		t.Fatalf("ProcessDue() error: %v", err)
+	}
+
+	export, err := manager.ExportSubject(ctx, " SUBJECT@example.com ")
+	if err != nil {
+		t.Fatalf("ExportSubject() error: %v", err)
+	}
+	if len(export.Alerts) != 2 {
+		t.Fatalf("exported %d alerts, want 2", len(export.Alerts))
+	}
+	if len(export.Alerts[0].Results) != 1 || export.Alerts[0].Results[0].Title != "Alpha" {
+		t.Errorf("results = %+v, want the stored result", export.Alerts[0].Results)
+	}
+
+	own, err := manager.ExportAlert(ctx, first.ManageToken)
+	if err != nil {
+		t.Fatalf("ExportAlert() error: %v", err)
+	}
+	if len(own.Alerts) != 1 || own.Alerts[0].Alert.Query != "one" {
+		t.Errorf("ExportAlert() = %+v, want only the token's alert", own.Alerts)
+	}
+
+	erased, err := manager.EraseSubject(ctx, "subject@example.com")
+	if err != nil || erased != 2 {
+		t.Fatalf("EraseSubject() = %d, %v; want 2", erased, err)
+	}
+	var results int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM search_alert_results`).Scan(&results); err != nil {
+		t.Fatal(err)
+	}
+	if results != 1 {
+		t.Errorf("%d results left, want only the other subject's", results)
+	}
+	if _, err := manager.GetByManageToken(ctx, other.ManageToken); err != nil {
+		t.Errorf("other subject's alert was erased: %v", err)
+	}
+	if export, _ := manager.ExportSubject(ctx, "subject@example.com"); len(export.Alerts) != 0 {
+		t.Errorf("exported %d alerts after erasure", len(export.Alerts))
+	}
+}
+
+func TestSubjectRequiresEmail(t *testing.T) {
+	manager, db := newTestManager(t)
+	defer db.Close()
+
+	if _, err := manager.ExportSubject(context.Background(), " "); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("ExportSubject() error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := manager.EraseSubject(context.Background(), ""); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("EraseSubject() error = %v, want ErrInvalidInput", err)
+	}
+	if SubjectHash("A@example.com ") != SubjectHash("a@example.com") {
+		t.Error("SubjectHash() should normalize the email")
+	}
+}
@@ -103,15 +103,19 @@ func (h *Handler) handleCreateAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data := map[string]interface{}{
+		"alert":        created.Alert,
+		"manage_url":   baseURLFromRequest(h, r) + "/alerts/manage/" + created.ManageToken,
+		"rss_url":      baseURLFromRequest(h, r) + "/alerts/" + created.RSSToken + ".rss",
+		"manage_token": created.ManageToken,
+		"rss_token":    created.RSSToken,
+	}
+	if created.WebhookSecret != "" {
+		data["webhook_secret"] = created.WebhookSecret
+	}
 	h.writeJSON(w, http.StatusCreated, APIResponse{
-		OK: true,
-		Data: map[string]interface{}{
-			"alert":        created.Alert,
-			"manage_url":   baseURLFromRequest(h, r) + "/alerts/manage/" + created.ManageToken,
-			"rss_url":      baseURLFromRequest(h, r) + "/alerts/" + created.RSSToken + ".rss",
-			"manage_token": created.ManageToken,
-			"rss_token":    created.RSSToken,
-		},
+		OK:   true,
+		Data: data,
 	})
 }
 
@@ -143,6 +147,15 @@ func (h *Handler) handleAlertByToken(w http.ResponseWriter, r *http.Request) {
 		}
 		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
 		_, _ = w.Write(xmlData)
+	case strings.HasSuffix(path, "/export") && r.Method == http.MethodGet:
+		// The subscriber's own copy of everything stored for this alert
+		export, err := h.alertManager.ExportAlert(r.Context(), strings.TrimSuffix(path, "/export"))
+		if err != nil {
+			h.writeError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="alert-export.json"`)
+		h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: export})
 	default:
 		token := path
 		if idx := strings.IndexRune(token, '/'); idx >= 0 {
@@ -217,12 +230,9 @@ func (h *Handler) writeError(w http.ResponseWriter, code, message string, status
 }
 
 func baseURLFromRequest(h *Handler, r *http.Request) string {
-	if h.config.Server.BaseURL != "" {
-		return strings.TrimRight(h.config.Server.BaseURL, "/")
-	}
 	scheme := httputil.GetProtoFromRequest(r)
 	host := httputil.GetHostFromRequest(r)
-	return scheme + "://" + host
+	return h.config.Server.PublicURL(scheme + "://" + host)
 }
 
 func clientIPForAPI(r *http.Request) string {
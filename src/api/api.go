@@ -2,8 +2,6 @@ package api
 
 import (
 	"context"
-	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -14,19 +12,31 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/apimgr/search/src/alert"
+	"github.com/apimgr/search/src/collection"
 	"github.com/apimgr/search/src/common/httputil"
+	"github.com/apimgr/search/src/common/i18n"
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/direct"
 	"github.com/apimgr/search/src/geoip"
 	"github.com/apimgr/search/src/instant"
 	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/permalink"
+	"github.com/apimgr/search/src/preferences"
+	"github.com/apimgr/search/src/player"
+	"github.com/apimgr/search/src/policy"
+	"github.com/apimgr/search/src/safesearch"
 	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/search/bang"
 	"github.com/apimgr/search/src/search/engine"
+	"github.com/apimgr/search/src/search/macro"
+	"github.com/apimgr/search/src/security"
 	"github.com/apimgr/search/src/service"
 	"github.com/apimgr/search/src/version"
 	"github.com/apimgr/search/src/widget"
@@ -55,11 +65,18 @@ type Handler struct {
 	instantManager  *instant.Manager
 	directManager   *direct.Manager
 	relatedSearches *search.RelatedSearches
+	// Autocomplete providers; replaced on config reload
+	suggester atomic.Pointer[search.Suggester]
 	// Per AI.md PART 32: Tor service for health status
 	torService   *service.TorService
 	geoipLookup  *geoip.Lookup
 	startTime    time.Time
 	alertManager *alert.Manager
+	permalinks   *permalink.Store
+	collections  *collection.Store
+	preferences  *preferences.Store
+	// The server's bang manager, for the custom bangs in /api/v1/bangs
+	bangManager *bang.Manager
 	// validate is the input validator per AI.md PART 3 requirement
 	validate *validator.Validate
 }
@@ -95,6 +112,11 @@ func (h *Handler) SetRelatedSearches(rs *search.RelatedSearches) {
 	h.relatedSearches = rs
 }
 
+// SetSuggester sets the autocomplete suggester for the API handler
+func (h *Handler) SetSuggester(s *search.Suggester) {
+	h.suggester.Store(s)
+}
+
 // SetTorService sets the Tor service for the API handler
 // Per AI.md PART 32: Tor status is checked via service, not hardcoded
 func (h *Handler) SetTorService(ts *service.TorService) {
@@ -105,6 +127,27 @@ func (h *Handler) SetAlertManager(am *alert.Manager) {
 	h.alertManager = am
 }
 
+// SetPermalinkStore sets the store behind /s/<token> search permalinks
+func (h *Handler) SetPermalinkStore(ps *permalink.Store) {
+	h.permalinks = ps
+}
+
+// SetCollectionStore sets the store behind saved-result collections
+func (h *Handler) SetCollectionStore(cs *collection.Store) {
+	h.collections = cs
+}
+
+// SetPreferenceStore sets the store behind server-side search preferences
+func (h *Handler) SetPreferenceStore(ps *preferences.Store) {
+	h.preferences = ps
+}
+
+// SetBangManager sets the bang manager whose custom bangs /api/v1/bangs
+// lists along with the built-ins
+func (h *Handler) SetBangManager(bm *bang.Manager) {
+	h.bangManager = bm
+}
+
 // SetGeoIPLookup sets the GeoIP lookup service for the API handler.
 // Instant answer handlers use it to enrich IP responses with geo data.
 func (h *Handler) SetGeoIPLookup(g *geoip.Lookup) {
@@ -130,11 +173,15 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 
 	// Search
 	r.HandleFunc(APIPrefix+"/search", h.handleSearch)
+	r.HandleFunc(APIPrefix+"/search/stream", h.handleSearchStream)
 	r.HandleFunc(APIPrefix+"/search/related", h.handleRelatedSearches)
+	r.HandleFunc(APIPrefix+"/search/preview", h.handleSearchPreview)
+	r.HandleFunc(APIPrefix+"/images/reverse", h.handleReverseImage)
 	r.HandleFunc(APIPrefix+"/autocomplete", h.handleAutocomplete)
 
 	// Engines
 	r.HandleFunc(APIPrefix+"/engines", h.handleEngines)
+	r.HandleFunc(APIPrefix+"/engines/health", h.handleEngineHealth)
 	r.HandleFunc(APIPrefix+"/engines/*", h.handleEngineByID)
 
 	// Categories
@@ -143,6 +190,12 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	// Bangs
 	r.HandleFunc(APIPrefix+"/bangs", h.handleBangs)
 
+	// Search macros
+	r.Get(APIPrefix+"/macros", h.handleMacros)
+
+	// What the instance does with a request, by its DNT and Sec-GPC signals
+	r.Get(APIPrefix+"/privacy", h.handlePrivacy)
+
 	// Widgets
 	r.HandleFunc(APIPrefix+"/widgets", h.handleWidgets)
 	r.HandleFunc(APIPrefix+"/widgets/*", h.handleWidgetData)
@@ -159,17 +212,40 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.HandleFunc(APIPrefix+"/server/help", h.handleServerHelp)
 	r.HandleFunc(APIPrefix+"/server/terms", h.handleServerTerms)
 	r.HandleFunc(APIPrefix+"/server/contact", h.handleServerContact)
+	r.Get(APIPrefix+"/server/docs", h.handleDocs)
+	r.Get(APIPrefix+"/server/docs/{page}", h.handleDocsPage)
+	r.Get(APIPrefix+"/server/config/reference", h.handleConfigReference)
 	r.HandleFunc(APIPrefix+"/preferences", h.handlePreferences)
+	r.Post(APIPrefix+"/preferences/token", h.withPreferences(h.handleUsePreferenceToken))
 
 	// Favicon proxy - privacy-preserving favicon fetching
 	// Per AI.md PART 16: NO external requests from client, server proxies content
 	r.HandleFunc(APIPrefix+"/favicon", h.handleFavicon)
 	r.HandleFunc(APIPrefix+"/alerts", h.handleAlerts)
 	r.HandleFunc(APIPrefix+"/alerts/*", h.handleAlertByToken)
+	r.Post(APIPrefix+"/permalinks", h.handleCreatePermalink)
+	r.Get(APIPrefix+"/permalinks/{token}", h.handleGetPermalink)
+
+	// Saved-result collections, owned by their manage token
+	r.Post(APIPrefix+"/collections", h.withCollections(h.handleCreateCollection))
+	r.Get(APIPrefix+"/collections/shared/{token}", h.withCollections(h.handleSharedCollection))
+	r.Get(APIPrefix+"/collections/{token}", h.withCollections(h.handleGetCollection))
+	r.Patch(APIPrefix+"/collections/{token}", h.withCollections(h.handleUpdateCollection))
+	r.Delete(APIPrefix+"/collections/{token}", h.withCollections(h.handleDeleteCollection))
+	r.Post(APIPrefix+"/collections/{token}/items", h.withCollections(h.handleAddCollectionItem))
+	r.Patch(APIPrefix+"/collections/{token}/items/{item}", h.withCollections(h.handleUpdateCollectionItem))
+	r.Delete(APIPrefix+"/collections/{token}/items/{item}", h.withCollections(h.handleDeleteCollectionItem))
+	r.Put(APIPrefix+"/collections/{token}/order", h.withCollections(h.handleReorderCollection))
+	r.Post(APIPrefix+"/collections/{token}/share", h.withCollections(h.handleShareCollection))
+	r.Get(APIPrefix+"/collections/{token}/export", h.withCollections(h.handleExportCollection))
 
 	// Operator-gated server status and config — per AI.md PART 14
 	r.Get(APIPrefix+"/server/status", h.requireOperator(h.handleServerStatus))
 	r.Get(APIPrefix+"/server/config", h.requireOperator(h.handleServerConfig))
+	r.Get(APIPrefix+"/server/instant", h.requireOperator(h.handleInstantLadder))
+	r.Get(APIPrefix+"/server/preferences/accounts", h.requireOperator(h.withPreferences(h.handlePreferenceAccounts)))
+	r.Post(APIPrefix+"/server/preferences/accounts", h.requireOperator(h.withPreferences(h.handleCreatePreferenceAccount)))
+	r.Delete(APIPrefix+"/server/preferences/accounts/{id}", h.requireOperator(h.withPreferences(h.handleDeletePreferenceAccount)))
 }
 
 // Response types
@@ -179,21 +255,21 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 // Success: {"ok": true, "data": {...}}
 // Error: {"ok": false, "error": "ERROR_CODE", "message": "Human readable message"}
 type APIResponse struct {
-	OK   bool        `json:"ok"`
-	Data interface{} `json:"data,omitempty"`
+	OK   bool        `json:"ok" xml:"ok"`
+	Data interface{} `json:"data,omitempty" xml:"data,omitempty"`
 	// Error code (e.g., "BAD_REQUEST", "NOT_FOUND")
-	Error string `json:"error,omitempty"`
+	Error string `json:"error,omitempty" xml:"error,omitempty"`
 	// Human-readable error message
-	Message string `json:"message,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty"`
 	// Optional metadata (request_id, process_time_ms)
-	Meta *APIMeta `json:"meta,omitempty"`
+	Meta *APIMeta `json:"meta,omitempty" xml:"meta,omitempty"`
 }
 
 // APIMeta contains response metadata
 type APIMeta struct {
-	RequestID   string  `json:"request_id,omitempty"`
-	ProcessTime float64 `json:"process_time_ms,omitempty"`
-	Version     string  `json:"version"`
+	RequestID   string  `json:"request_id,omitempty" xml:"request_id,omitempty"`
+	ProcessTime float64 `json:"process_time_ms,omitempty" xml:"process_time_ms,omitempty"`
+	Version     string  `json:"version" xml:"version"`
 }
 
 // HealthResponse represents health check response per AI.md PART 13
@@ -289,6 +365,8 @@ type FeaturesInfo struct {
 
 // TorInfo represents Tor status per AI.md PART 13 (line 16290-16296)
 type TorInfo struct {
+	// Tor support is compiled into the binary (false when built with -tags notor)
+	Compiled bool `json:"compiled"`
 	// Tor binary found and config enabled
 	Enabled bool `json:"enabled"`
 	// Hidden service active
@@ -361,68 +439,131 @@ type SearchRequest struct {
 	Category   string   `json:"category"               validate:"omitempty,max=50"`
 	Page       int      `json:"page"                   validate:"omitempty,min=1,max=1000"`
 	Limit      int      `json:"limit"                  validate:"omitempty,min=1,max=100"`
+	// PageToken, from an earlier page, keeps the results already shown in
+	// place on later pages
+	PageToken string `json:"page_token,omitempty" validate:"omitempty,max=64"`
 	Engines    []string `json:"engines,omitempty"`
 	SafeSearch string   `json:"safe_search,omitempty"  validate:"omitempty,oneof=0 1 2"`
-	TimeRange  string   `json:"time_range,omitempty"`
+	TimeRange  string   `json:"time_range,omitempty"   validate:"omitempty,oneof=any day week month year"`
 	Language   string   `json:"language,omitempty"     validate:"omitempty,max=10"`
+	// Sort "date" puts the newest results first
+	Sort string `json:"sort,omitempty" validate:"omitempty,oneof=relevance date"`
+	// Group "publisher" groups a news search by who published each result
+	Group string `json:"group,omitempty" validate:"omitempty,oneof=publisher"`
+	// Type keeps only results with structured data of this type
+	Type string `json:"type,omitempty" validate:"omitempty,oneof=recipe howto event product rating"`
+	// ImageColor and ImageLicense narrow an images search
+	ImageColor   string `json:"image_color,omitempty"   validate:"omitempty,oneof=color monochrome red orange yellow green teal blue purple pink white gray black brown"`
+	ImageLicense string `json:"image_license,omitempty" validate:"omitempty,oneof=public share share_commercial modify modify_commercial"`
+	// VideoLength and VideoQuality narrow a videos search
+	VideoLength  string `json:"video_length,omitempty"  validate:"omitempty,oneof=short medium long"`
+	VideoQuality string `json:"video_quality,omitempty" validate:"omitempty,oneof=hd"`
+	// Localize "0" turns search.geo_boost off for this search
+	Localize string `json:"localize,omitempty" validate:"omitempty,oneof=0 1"`
 }
 
 // Pagination represents standard pagination info per AI.md PART 14
 type Pagination struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
-	Total int `json:"total"`
-	Pages int `json:"pages"`
+	Page  int `json:"page" xml:"page"`
+	Limit int `json:"limit" xml:"limit"`
+	Total int `json:"total" xml:"total"`
+	Pages int `json:"pages" xml:"pages"`
+	// Token, passed back as page_token, continues the same results on
+	// later pages; HasMore is false once the engines have no more
+	Token   string `json:"token,omitempty" xml:"token,omitempty"`
+	HasMore bool   `json:"has_more" xml:"has_more"`
 }
 
 // SearchResponse represents search API response per AI.md PART 14 pagination format
 type SearchResponse struct {
-	Query      string         `json:"query"`
-	Category   string         `json:"category"`
-	Results    []SearchResult `json:"results"`
-	Pagination Pagination     `json:"pagination"`
-	SearchTime float64        `json:"search_time_ms"`
-	Engines    []string       `json:"engines_used"`
+	Query      string         `json:"query" xml:"query"`
+	Category   string         `json:"category" xml:"category"`
+	Results    []SearchResult `json:"results" xml:"results>item"`
+	Pagination Pagination     `json:"pagination" xml:"pagination"`
+	SearchTime float64        `json:"search_time_ms" xml:"search_time_ms"`
+	Engines    []string       `json:"engines_used" xml:"engines_used>item"`
+	// EngineTimings is included with debug=1 or an operator token
+	EngineTimings []model.EngineTiming `json:"engine_timings,omitempty" xml:"engine_timings>item,omitempty"`
+	// Groups is the page's results grouped by publisher, for a news search
+	// with group=publisher
+	Groups []PublisherGroup `json:"groups,omitempty" xml:"groups>group,omitempty"`
+	// Degraded is set when no engine answered; the results, if any, are
+	// stale copies fetched at CachedAt
+	Degraded bool       `json:"degraded,omitempty" xml:"degraded,omitempty"`
+	Stale    bool       `json:"stale,omitempty" xml:"stale,omitempty"`
+	CachedAt *time.Time `json:"cached_at,omitempty" xml:"cached_at,omitempty"`
 }
 
 // SearchResult represents a single search result
 type SearchResult struct {
-	Title       string  `json:"title"`
-	URL         string  `json:"url"`
-	Description string  `json:"description"`
-	Engine      string  `json:"engine"`
-	Score       float64 `json:"score"`
-	Category    string  `json:"category"`
-	Thumbnail   string  `json:"thumbnail,omitempty"`
-	Date        string  `json:"date,omitempty"`
-	Domain      string  `json:"domain,omitempty"`
+	Title       string  `json:"title" xml:"title"`
+	URL         string  `json:"url" xml:"url"`
+	Description string  `json:"description" xml:"description"`
+	Engine      string  `json:"engine" xml:"engine"`
+	Score       float64 `json:"score" xml:"score"`
+	Category    string  `json:"category" xml:"category"`
+	Thumbnail   string  `json:"thumbnail,omitempty" xml:"thumbnail,omitempty"`
+	Date        string  `json:"date,omitempty" xml:"date,omitempty"`
+	Domain      string  `json:"domain,omitempty" xml:"domain,omitempty"`
+	// Publisher is the source of a news result, or its site's domain
+	Publisher string `json:"publisher,omitempty" xml:"publisher,omitempty"`
+	// "malware" or "phishing" when search.screening flags the result
+	Threat string `json:"threat,omitempty" xml:"threat,omitempty"`
+	// "adult" when strict safe search hid the image; Thumbnail is empty
+	ContentFilter string `json:"content_filter,omitempty" xml:"content_filter,omitempty"`
+	// Schema.org data the result page publishes (rating, recipe, event,
+	// product), shown as a rich snippet
+	Structured *model.StructuredData `json:"structured,omitempty" xml:"-"`
+	// Watch is the watch page playing a video result on this instance,
+	// set when search.video_player can play it
+	Watch string `json:"watch,omitempty" xml:"watch,omitempty"`
+	// Video is a video result's length, channel, views and publish date,
+	// and the embeddable player when search.video_player can play it
+	Video *model.VideoResult `json:"video,omitempty" xml:"video,omitempty"`
+	// File is a files result's size, seeders, leechers and magnet or
+	// torrent link
+	File *model.FileResult `json:"file,omitempty" xml:"file,omitempty"`
+	// Map is a maps result's coordinates, bounding box, address and place
+	// type
+	Map *model.MapResult `json:"map,omitempty" xml:"map,omitempty"`
+	// Localized is why search.geo_boost ranked the result higher: "domain"
+	// or "language"
+	Localized string `json:"localized,omitempty" xml:"localized,omitempty"`
+}
+
+// PublisherGroup is the news results of one publisher
+type PublisherGroup struct {
+	Publisher string         `json:"publisher" xml:"publisher"`
+	Results   []SearchResult `json:"results" xml:"results>item"`
 }
 
 // EngineInfo represents engine information
 type EngineInfo struct {
-	ID          string               `json:"id"`
-	Name        string               `json:"name"`
-	Enabled     bool                 `json:"enabled"`
-	Priority    int                  `json:"priority"`
-	Categories  []string             `json:"categories"`
-	Description string               `json:"description,omitempty"`
-	Homepage    string               `json:"homepage,omitempty"`
-	Health      *search.EngineHealth `json:"health,omitempty"`
+	ID          string               `json:"id" xml:"id"`
+	Name        string               `json:"name" xml:"name"`
+	Enabled     bool                 `json:"enabled" xml:"enabled"`
+	Priority    int                  `json:"priority" xml:"priority"`
+	Categories  []string             `json:"categories" xml:"categories>item"`
+	Description string               `json:"description,omitempty" xml:"description,omitempty"`
+	Homepage    string               `json:"homepage,omitempty" xml:"homepage,omitempty"`
+	Health      *search.EngineHealth `json:"health,omitempty" xml:"health,omitempty"`
 }
 
 // CategoryInfo represents category information
 type CategoryInfo struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Icon        string `json:"icon"`
+	ID          string `json:"id" xml:"id"`
+	Name        string `json:"name" xml:"name"`
+	Description string `json:"description" xml:"description"`
+	Icon        string `json:"icon" xml:"icon"`
 }
 
 // Handler methods
 
 func (h *Handler) handleHealthz(w http.ResponseWriter, r *http.Request) {
-	// Tor status per AI.md PART 13 and PART 32
-	torEnabled := h.config.Server.Tor.Enabled
+	// Tor status per AI.md PART 13 and PART 32. A binary built with -tags
+	// notor reports Tor as disabled rather than unavailable.
+	torCompiled := version.HasFeature("tor")
+	torEnabled := h.config.Server.Tor.Enabled && torCompiled
 	torRunning := false
 	torStatus := ""
 	torHostname := ""
@@ -473,6 +614,7 @@ func (h *Handler) handleHealthz(w http.ResponseWriter, r *http.Request) {
 		// 5. Features per AI.md PART 13
 		Features: FeaturesInfo{
 			Tor: TorInfo{
+				Compiled: torCompiled,
 				Enabled:  torEnabled,
 				Running:  torRunning,
 				Status:   torStatus,
@@ -555,7 +697,7 @@ func (h *Handler) handleAutodiscover(w http.ResponseWriter, r *http.Request) {
 	resp.CLIVersions = map[string]CLIBinaryInfo{}
 	resp.CLIMinVersion = ""
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.cacheableResponse(w, r, cacheGroupInfo, &APIResponse{
 		OK:   true,
 		Data: resp,
 	})
@@ -567,7 +709,7 @@ func (h *Handler) handleInfo(w http.ResponseWriter, r *http.Request) {
 
 	enabled := len(h.registry.GetEnabled())
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.cacheableResponse(w, r, cacheGroupInfo, &APIResponse{
 		OK: true,
 		Data: InfoResponse{
 			Name:        h.config.Server.Title,
@@ -599,31 +741,92 @@ func (h *Handler) handleInfo(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
+	req, query, ok := h.parseSearchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	results, pages, err := h.aggregator.SearchPages(ctx, query, req.PageToken)
+	if err != nil && !errors.Is(err, model.ErrNoResults) {
+		h.negotiatedError(w, r, http.StatusInternalServerError, "Search failed", err.Error())
+		return
+	}
+
+	h.negotiatedResponse(w, r, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: h.searchResponse(r, req, query, results, pages, start),
+		Meta: &APIMeta{
+			Version:     APIVersion,
+			ProcessTime: float64(time.Since(start).Microseconds()) / 1000,
+		},
+	})
+}
+
+// parseSearchRequest reads and validates a search from the query string or
+// a JSON body, answering the request itself when it is invalid
+func (h *Handler) parseSearchRequest(w http.ResponseWriter, r *http.Request) (SearchRequest, *model.Query, bool) {
 	// Parse request
 	var req SearchRequest
 
 	if r.Method == http.MethodPost {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			h.errorResponse(w, http.StatusBadRequest, "Invalid JSON body", err.Error())
-			return
+			h.negotiatedError(w, r, http.StatusBadRequest, "Invalid JSON body", err.Error())
+			return req, nil, false
 		}
 		// Trim query from JSON body
 		req.Query = strings.TrimSpace(req.Query)
+		// &safesearch= overrides the body, as it does stored preferences
+		if value := strings.TrimSpace(r.URL.Query().Get("safesearch")); value != "" {
+			req.SafeSearch = value
+		}
 	} else {
 		// Parse from query params (trim all text inputs)
 		req.Query = strings.TrimSpace(r.URL.Query().Get("q"))
 		req.Category = strings.TrimSpace(r.URL.Query().Get("category"))
 		req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
 		req.Limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
-		req.SafeSearch = strings.TrimSpace(r.URL.Query().Get("safe_search"))
+		req.PageToken = strings.TrimSpace(r.URL.Query().Get("page_token"))
+		req.SafeSearch = strings.TrimSpace(r.URL.Query().Get("safesearch"))
+		if req.SafeSearch == "" {
+			req.SafeSearch = strings.TrimSpace(r.URL.Query().Get("safe_search"))
+		}
+		req.TimeRange = strings.TrimSpace(r.URL.Query().Get("time_range"))
+		req.Sort = strings.TrimSpace(r.URL.Query().Get("sort"))
+		req.Group = strings.TrimSpace(r.URL.Query().Get("group"))
 		req.Language = strings.TrimSpace(r.URL.Query().Get("lang"))
+		req.Type = strings.TrimSpace(r.URL.Query().Get("type"))
+		req.ImageColor = strings.TrimSpace(r.URL.Query().Get("image_color"))
+		req.ImageLicense = strings.TrimSpace(r.URL.Query().Get("image_license"))
+		req.VideoLength = strings.TrimSpace(r.URL.Query().Get("video_length"))
+		req.VideoQuality = strings.TrimSpace(r.URL.Query().Get("video_quality"))
+		req.Localize = strings.TrimSpace(r.URL.Query().Get("localize"))
+	}
+
+	// off, moderate and strict are accepted as well as 0, 1 and 2
+	if level, ok := safesearch.Parse(req.SafeSearch); ok {
+		req.SafeSearch = strconv.Itoa(level)
 	}
 
 	// Validate all request fields per AI.md PART 3 using go-playground/validator
 	if err := h.validate.Struct(req); err != nil {
-		h.errorResponse(w, http.StatusBadRequest, "Invalid request parameters", err.Error())
-		return
+		h.negotiatedError(w, r, http.StatusBadRequest, "Invalid request parameters", err.Error())
+		return req, nil, false
+	}
+
+	// Expand a search macro (~name words); the response carries the
+	// expanded query so its links page through what was searched
+	expanded, _, err := macro.Expand(req.Query, macrosFromCookie(r), h.instanceMacros())
+	var missing *macro.MissingArgsError
+	if errors.As(err, &missing) {
+		h.negotiatedError(w, r, http.StatusBadRequest, "Search macro "+missing.Usage+" needs a word for each parameter", err.Error())
+		return req, nil, false
 	}
+	req.Query = expanded
+
+	// Stored preferences fill in what the request leaves out
+	profile := h.requestProfile(r)
+	applyStoredPreferences(&req, profile)
 
 	// Set defaults
 	req.Category = model.ParseCategory(req.Category).String()
@@ -637,57 +840,142 @@ func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
 	// Perform search
 	query := model.NewQuery(req.Query)
 	query.Category = model.ParseCategory(req.Category)
+	if !h.config.Search.CategoryEnabled(req.Category) {
+		h.negotiatedError(w, r, http.StatusNotFound, "Category is disabled on this instance", req.Category)
+		return req, nil, false
+	}
 	query.Page = req.Page
 	query.PerPage = req.Limit
+	query.SafeSearch = h.config.Search.SafeSearch
 	if req.SafeSearch != "" {
 		if safeSearch, err := strconv.Atoi(req.SafeSearch); err == nil {
 			query.SafeSearch = safeSearch
 		}
 	}
+	query.SafeSearch = h.config.Search.ResolveSafeSearch(query.SafeSearch)
+	if req.Language != "" {
+		query.Language = req.Language
+	}
+	if profile != nil {
+		query.EnabledEngines = profile.Preferences.Engines
+	}
+	if timeRange := model.ParseTimeRange(req.TimeRange); timeRange != "" {
+		query.TimeRange = timeRange
+	}
+	query.SortBy = model.ParseSortOrder(req.Sort)
+	if query.Category == model.CategoryImages {
+		query.ImageColor = req.ImageColor
+		query.ImageLicense = req.ImageLicense
+	}
+	if query.Category == model.CategoryVideos {
+		query.VideoLength = req.VideoLength
+		query.VideoQuality = req.VideoQuality
+	}
+	if req.Localize != "0" {
+		query.Country = h.searchCountry(r)
+	}
+	return req, query, true
+}
 
-	ctx := r.Context()
-	results, err := h.aggregator.Search(ctx, query)
-	if err != nil && !errors.Is(err, model.ErrNoResults) {
-		h.errorResponse(w, http.StatusInternalServerError, "Search failed", err.Error())
-		return
+// searchResponse is the data of a search response: the requested page of
+// results with their pagination and timings. pages is the page token of
+// a paginated search; a streamed search has none.
+func (h *Handler) searchResponse(r *http.Request, req SearchRequest, query *model.Query, results *model.SearchResults, pages search.PageInfo, start time.Time) SearchResponse {
+	// Cooking and how-to clients ask for one kind of structured result
+	if req.Type != "" {
+		results = results.WithStructuredType(req.Type)
 	}
 
-	// Convert results
-	apiResults := make([]SearchResult, 0, len(results.Results))
-	for _, result := range results.GetPage(req.Page) {
-		apiResults = append(apiResults, SearchResult{
-			Title:       result.Title,
-			URL:         result.URL,
-			Description: result.Content,
-			Engine:      result.Engine,
-			Score:       result.Score,
-			Category:    string(result.Category),
-			Thumbnail:   result.Thumbnail,
-			Domain:      extractDomain(result.URL),
-		})
+	var timings []model.EngineTiming
+	if h.showEngineTimings(r) {
+		timings = results.EngineTimings
+	}
+
+	// Sized to the page rather than every merged result
+	page := results.GetPage(req.Page)
+	var groups []PublisherGroup
+	if req.Group == "publisher" && query.Category == model.CategoryNews {
+		for _, group := range model.GroupByPublisher(page) {
+			groups = append(groups, PublisherGroup{
+				Publisher: group.Publisher,
+				Results:   h.searchResults(query, group.Results),
+			})
+		}
 	}
 
 	// Calculate total pages per AI.md PART 14 pagination format
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
-		OK: true,
-		Data: SearchResponse{
-			Query:    req.Query,
-			Category: req.Category,
-			Results:  apiResults,
-			Pagination: Pagination{
-				Page:  results.Page,
-				Limit: results.PerPage,
-				Total: results.TotalResults,
-				Pages: results.TotalPages,
-			},
-			SearchTime: float64(time.Since(start).Microseconds()) / 1000,
-			Engines:    results.Engines,
+	return SearchResponse{
+		Query:    req.Query,
+		Category: req.Category,
+		Results:  h.searchResults(query, page),
+		Pagination: Pagination{
+			Page:    results.Page,
+			Limit:   results.PerPage,
+			Total:   results.TotalResults,
+			Pages:   results.TotalPages,
+			Token:   pages.Token,
+			HasMore: pages.More || results.Page < results.TotalPages,
 		},
-		Meta: &APIMeta{
-			Version:     APIVersion,
-			ProcessTime: float64(time.Since(start).Microseconds()) / 1000,
-		},
-	})
+		SearchTime:    float64(time.Since(start).Microseconds()) / 1000,
+		Engines:       results.Engines,
+		EngineTimings: timings,
+		Groups:        groups,
+		Degraded:      results.Degraded,
+		Stale:         results.Stale,
+		CachedAt:      results.CachedAt,
+	}
+}
+
+// showEngineTimings reports whether per-engine timings go in the response:
+// they help integrators diagnose slow instances but name failing engines,
+// so they are opt-in
+func (h *Handler) showEngineTimings(r *http.Request) bool {
+	return r.URL.Query().Get("debug") == "1" || h.hasOperatorToken(r)
+}
+
+// searchResults converts results for the API
+func (h *Handler) searchResults(query *model.Query, results []model.Result) []SearchResult {
+	apiResults := make([]SearchResult, 0, len(results))
+	var videoPlayer *player.Player
+	if cfg := h.config.Search.VideoPlayer; cfg.Enabled && query.Category == model.CategoryVideos {
+		videoPlayer = player.New(cfg.Frontend, cfg.Instance)
+	}
+	for _, result := range results {
+		var watch, date string
+		video := result.Video()
+		if !result.PublishedAt.IsZero() {
+			date = result.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		if result.Threat == "" {
+			watch = videoPlayer.WatchHref(result.URL, result.Title)
+			if video != nil && watch != "" {
+				if embed, ok := videoPlayer.Embed(result.URL); ok {
+					video.Embed = embed.Src
+				}
+			}
+		}
+		apiResults = append(apiResults, SearchResult{
+			Title:         result.Title,
+			URL:           result.URL,
+			Description:   result.Content,
+			Engine:        result.Engine,
+			Score:         result.Score,
+			Category:      string(result.Category),
+			Thumbnail:     result.Thumbnail,
+			Date:          date,
+			Domain:        extractDomain(result.URL),
+			Publisher:     result.Publisher(),
+			Threat:        result.Threat,
+			ContentFilter: result.ContentFilter,
+			Structured:    result.Structured,
+			Watch:         watch,
+			Video:         video,
+			File:          result.File(),
+			Map:           result.Map(),
+			Localized:     result.Localized,
+		})
+	}
+	return apiResults
 }
 
 // HandleAutocomplete is the public method for autocomplete suggestions
@@ -697,6 +985,10 @@ func (h *Handler) HandleAutocomplete(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if wantsOpenSearchSuggestions(r) {
+		h.openSearchSuggestions(w, r, query)
+		return
+	}
 	if query == "" {
 		h.jsonResponse(w, http.StatusOK, &APIResponse{
 			OK:   true,
@@ -706,8 +998,13 @@ func (h *Handler) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch suggestions from DuckDuckGo's autocomplete API
-	suggestions := h.fetchAutocompleteSuggestions(r.Context(), query)
+	// Merged from the configured providers and local history, within the
+	// suggestion budget
+	suggestions := []string{}
+	if suggester := h.suggester.Load(); suggester != nil {
+		// Untrimmed, as a trailing space tells the history a word is complete
+		suggestions = suggester.Suggest(r.Context(), r.URL.Query().Get("q"))
+	}
 
 	h.jsonResponse(w, http.StatusOK, &APIResponse{
 		OK:   true,
@@ -716,60 +1013,6 @@ func (h *Handler) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// fetchAutocompleteSuggestions fetches search suggestions from DuckDuckGo
-func (h *Handler) fetchAutocompleteSuggestions(ctx context.Context, query string) []string {
-	client := &http.Client{Timeout: 2 * time.Second}
-
-	// DuckDuckGo autocomplete API
-	url := fmt.Sprintf("https://duckduckgo.com/ac/?q=%s&type=list", url.QueryEscape(query))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return []string{}
-	}
-
-	req.Header.Set("User-Agent", engine.UserAgent)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return []string{}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return []string{}
-	}
-
-	// DuckDuckGo returns: ["query", ["suggestion1", "suggestion2", ...]]
-	var result []interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return []string{}
-	}
-
-	if len(result) < 2 {
-		return []string{}
-	}
-
-	suggestionsRaw, ok := result[1].([]interface{})
-	if !ok {
-		return []string{}
-	}
-
-	suggestions := make([]string, 0, len(suggestionsRaw))
-	for _, s := range suggestionsRaw {
-		if str, ok := s.(string); ok {
-			suggestions = append(suggestions, str)
-		}
-	}
-
-	// Limit to 10 suggestions
-	if len(suggestions) > 10 {
-		suggestions = suggestions[:10]
-	}
-
-	return suggestions
-}
-
 func (h *Handler) handleEngines(w http.ResponseWriter, r *http.Request) {
 	allEngines := h.registry.GetAll()
 	engineList := make([]EngineInfo, 0, len(allEngines))
@@ -793,7 +1036,7 @@ func (h *Handler) handleEngines(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.negotiatedCacheableResponse(w, r, cacheGroupEngines, &APIResponse{
 		OK:   true,
 		Data: engineList,
 		Meta: &APIMeta{Version: APIVersion},
@@ -813,7 +1056,7 @@ func (h *Handler) handleEngineByID(w http.ResponseWriter, r *http.Request) {
 
 	engine, err := h.registry.Get(id)
 	if err != nil {
-		h.errorResponse(w, http.StatusNotFound, "Engine not found", fmt.Sprintf("No engine with ID: %s", id))
+		h.negotiatedError(w, r, http.StatusNotFound, "Engine not found", fmt.Sprintf("No engine with ID: %s", id))
 		return
 	}
 
@@ -825,7 +1068,7 @@ func (h *Handler) handleEngineByID(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.negotiatedCacheableResponse(w, r, cacheGroupEngines, &APIResponse{
 		OK: true,
 		Data: EngineInfo{
 			ID:         engine.Name(),
@@ -856,14 +1099,24 @@ func (h *Handler) handleCategories(w http.ResponseWriter, r *http.Request) {
 		{ID: "videos", Name: "Videos", Description: "Video search", Icon: "🎥"},
 		{ID: "news", Name: "News", Description: "News search", Icon: "📰"},
 		{ID: "maps", Name: "Maps", Description: "Map and location search", Icon: "🗺️"},
-		{ID: "files", Name: "Files", Description: "Document and downloadable file search", Icon: "📁"},
+		{ID: "files", Name: "Files", Description: "Torrent and file index search with seeders, leechers and magnet links", Icon: "📁"},
 		{ID: "music", Name: "Music", Description: "Music tracks, artists, albums, and videos", Icon: "🎵"},
 		{ID: "science", Name: "Science", Description: "Scientific papers and research search", Icon: "🔬"},
 		{ID: "it", Name: "IT", Description: "Developer, code, and technical search", Icon: "💻"},
 		{ID: "social", Name: "Social", Description: "Social media and community search", Icon: "💬"},
+		{ID: "packages", Name: "Packages", Description: "Package registries: npm, PyPI, crates.io, Go modules, Docker Hub", Icon: "📦"},
 	}
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	// Categories the operator turned off are not offered
+	enabled := categories[:0]
+	for _, category := range categories {
+		if h.config.Search.CategoryEnabled(category.ID) {
+			enabled = append(enabled, category)
+		}
+	}
+	categories = enabled
+
+	h.negotiatedCacheableResponse(w, r, cacheGroupCategories, &APIResponse{
 		OK:   true,
 		Data: categories,
 		Meta: &APIMeta{Version: APIVersion},
@@ -872,14 +1125,14 @@ func (h *Handler) handleCategories(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
-// jsonResponse sends JSON response with 2-space indentation per AI.md PART 14
+// jsonResponse sends JSON response with 2-space indentation per AI.md PART 14.
+// It encodes through a pooled buffer (see writeJSON), as search and
+// autocomplete answer at high rates.
 func (h *Handler) jsonResponse(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.Header().Set("X-API-Version", APIVersion)
-	w.WriteHeader(status)
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	enc.Encode(data)
+	header := w.Header()
+	header.Set("Content-Type", jsonContentType)
+	header.Set("X-API-Version", APIVersion)
+	writeJSON(w, status, data)
 }
 
 // textResponse sends plain text response per AI.md PART 14
@@ -934,6 +1187,12 @@ func stripTxtExtension(path string) string {
 // Error format: {"ok": false, "error": "ERROR_CODE", "message": "Human readable message"}
 // Per AI.md PART 7-9: RequestID must be included in error response meta
 func (h *Handler) errorResponse(w http.ResponseWriter, status int, message, detail string) {
+	h.jsonResponse(w, status, h.errorBody(w, status, message, detail))
+}
+
+// errorBody is the body of an error response, logging detail for
+// operators when the error is the server's
+func (h *Handler) errorBody(w http.ResponseWriter, status int, message, detail string) *APIResponse {
 	// Get request ID from response header (set by middleware)
 	requestID := w.Header().Get("X-Request-ID")
 
@@ -943,7 +1202,7 @@ func (h *Handler) errorResponse(w http.ResponseWriter, status int, message, deta
 		slog.Error("API error", "request_id", requestID, "message", message, "detail", detail)
 	}
 
-	h.jsonResponse(w, status, &APIResponse{
+	return &APIResponse{
 		OK:      false,
 		Error:   model.ErrorCodeFromHTTP(status),
 		Message: message,
@@ -951,7 +1210,7 @@ func (h *Handler) errorResponse(w http.ResponseWriter, status int, message, deta
 			RequestID: requestID,
 			Version:   APIVersion,
 		},
-	})
+	}
 }
 
 func (h *Handler) formatDuration(d time.Duration) string {
@@ -1038,13 +1297,25 @@ type BangInfo struct {
 	Category    string   `json:"category"`
 	Description string   `json:"description,omitempty"`
 	Aliases     []string `json:"aliases,omitempty"`
+	// Custom is set on the server's own bangs, from server.yml or the
+	// bang editor API
+	Custom bool `json:"custom,omitempty"`
 }
 
 func (h *Handler) handleBangs(w http.ResponseWriter, r *http.Request) {
-	// Return built-in bangs list
-	// Custom bangs are stored client-side in localStorage
+	// Return the server's custom bangs and the built-ins they leave
+	// Users' own bangs are stored client-side in localStorage
 
-	bangs := getBuiltinBangs()
+	bangs := h.customBangs()
+	overridden := make(map[string]bool, len(bangs))
+	for _, b := range bangs {
+		overridden[b.Shortcut] = true
+	}
+	for _, b := range getBuiltinBangs() {
+		if !overridden[b.Shortcut] {
+			bangs = append(bangs, b)
+		}
+	}
 
 	// Filter by category if specified
 	category := r.URL.Query().Get("category")
@@ -1072,7 +1343,7 @@ func (h *Handler) handleBangs(w http.ResponseWriter, r *http.Request) {
 		bangs = filtered
 	}
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.cacheableResponse(w, r, cacheGroupBangs, &APIResponse{
 		OK: true,
 		Data: map[string]interface{}{
 			"bangs": bangs,
@@ -1087,6 +1358,28 @@ func (h *Handler) handleBangs(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// customBangs returns the server's custom bangs by shortcut
+func (h *Handler) customBangs() []BangInfo {
+	if h.bangManager == nil {
+		return []BangInfo{}
+	}
+	custom := h.bangManager.GetCustom()
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Shortcut < custom[j].Shortcut })
+	bangs := make([]BangInfo, 0, len(custom))
+	for _, b := range custom {
+		bangs = append(bangs, BangInfo{
+			Shortcut:    b.Shortcut,
+			Name:        b.Name,
+			URL:         b.URL,
+			Category:    b.Category,
+			Description: b.Description,
+			Aliases:     b.Aliases,
+			Custom:      true,
+		})
+	}
+	return bangs
+}
+
 // getBuiltinBangs returns the list of built-in bangs
 func getBuiltinBangs() []BangInfo {
 	return []BangInfo{
@@ -1210,6 +1503,10 @@ func (h *Handler) handleInstantAnswer(w http.ResponseWriter, r *http.Request) {
 	// Inject GeoIP lookup so instant handlers can enrich IP answers with geo data.
 	ctx = instant.WithGeoIPLookup(ctx, h.geoipLookup)
 
+	// Inject the request language so answers match the page that fetched them.
+	lang, _ := i18n.DetectRequestLocale(r)
+	ctx = instant.WithLang(ctx, lang)
+
 	answer, err := h.instantManager.Process(ctx, query)
 	if err != nil {
 		h.errorResponse(w, http.StatusInternalServerError, "Failed to process instant answer", err.Error())
@@ -1364,6 +1661,89 @@ func (h *Handler) handleDirectAnswer(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// previewLimit is how many results a search-as-you-type preview returns
+const previewLimit = 3
+
+// previewMinLength skips previews for queries too short to be meaningful
+const previewMinLength = 3
+
+// SearchPreviewResponse represents search-as-you-type preview API response
+type SearchPreviewResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Cached  bool           `json:"cached"`
+	Engines []string       `json:"engines,omitempty"`
+}
+
+// handleSearchPreview handles GET /api/v1/search/preview. It returns the top
+// few results for a partial query from the result cache or a single fast
+// engine. The client debounces keystrokes; the endpoint is off unless the
+// operator enables search.preview.
+func (h *Handler) handleSearchPreview(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	cfg := h.config.Search.Preview
+	if !cfg.Enabled {
+		h.errorResponse(w, http.StatusNotFound, "Search preview is not enabled", "")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		h.errorResponse(w, http.StatusBadRequest, "Query parameter is required", "")
+		return
+	}
+
+	data := SearchPreviewResponse{
+		Query:   query,
+		Results: []SearchResult{},
+	}
+	meta := func() *APIMeta {
+		return &APIMeta{
+			Version:     APIVersion,
+			ProcessTime: float64(time.Since(start).Microseconds()) / 1000,
+		}
+	}
+
+	// Bangs redirect elsewhere and very short prefixes are mostly noise
+	if len([]rune(query)) < previewMinLength || strings.HasPrefix(query, "!") {
+		h.jsonResponse(w, http.StatusOK, &APIResponse{OK: true, Data: data, Meta: meta()})
+		return
+	}
+
+	q := model.NewQuery(query)
+	q.Category = model.ParseCategory(strings.TrimSpace(r.URL.Query().Get("category")))
+	q.SafeSearch = h.config.Search.SafeSearch
+	if safeSearch, ok := safesearch.FromValues(r.URL.Query()); ok {
+		q.SafeSearch = safeSearch
+	}
+	q.SafeSearch = h.config.Search.ResolveSafeSearch(q.SafeSearch)
+
+	results, err := h.aggregator.Preview(r.Context(), q, previewLimit, cfg.CacheOnly)
+	if err == nil {
+		data.Cached = results.FromCache
+		data.Engines = results.Engines
+		for _, result := range results.Results {
+			data.Results = append(data.Results, SearchResult{
+				Title:         result.Title,
+				URL:           result.URL,
+				Description:   result.Content,
+				Engine:        result.Engine,
+				Score:         result.Score,
+				Category:      string(result.Category),
+				Domain:        extractDomain(result.URL),
+				Threat:        result.Threat,
+				ContentFilter: result.ContentFilter,
+				Structured:    result.Structured,
+			})
+		}
+	}
+
+	// Previews are per-user keystrokes; let the browser reuse them briefly
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	h.jsonResponse(w, http.StatusOK, &APIResponse{OK: true, Data: data, Meta: meta()})
+}
+
 // RelatedSearchResponse represents related searches API response
 type RelatedSearchResponse struct {
 	Query       string   `json:"query"`
@@ -1550,7 +1930,7 @@ func (h *Handler) handleServerPrivacy(w http.ResponseWriter, r *http.Request) {
 		Data: ServerPageResponse{
 			Title:       "Privacy Policy",
 			Description: "Privacy policy for " + appName,
-			Content:     h.config.Server.Pages.Privacy.Content,
+			Content:     string(policy.Render(h.config.Server.Pages.Privacy.Content, policy.Variables(h.config))),
 			Sections:    sections,
 		},
 		Meta: &APIMeta{Version: APIVersion},
@@ -1579,7 +1959,7 @@ func (h *Handler) handleServerHelp(w http.ResponseWriter, r *http.Request) {
 		{
 			ID:      "categories",
 			Title:   "Search Categories",
-			Content: "Available categories are general, images, videos, news, maps, files, it, science, and social. Each category narrows the enabled engines and result types for the query.",
+			Content: "Available categories are general, images, videos, news, maps, files, it, science, social, and packages. Each category narrows the enabled engines and result types for the query.",
 		},
 		{
 			ID:      "bangs",
@@ -1604,7 +1984,7 @@ func (h *Handler) handleServerHelp(w http.ResponseWriter, r *http.Request) {
 		{
 			ID:      "api_documentation",
 			Title:   "API Documentation",
-			Content: "REST, direct-answer, GraphQL, and OpenAPI interfaces are available. Key endpoints include /api/v1/search, /api/v1/search/related, /api/v1/autocomplete, /api/v1/instant, /api/v1/direct/{type}/{term}, /api/v1/engines, /api/v1/categories, /api/v1/bangs, /api/v1/widgets, /api/v1/server/help, /api/v1/server/about, /api/v1/server/privacy, /api/v1/server/contact, /api/v1/server/terms, /api/graphql, /server/docs/graphql, /openapi (Swagger UI), and /openapi.json (OpenAPI spec).",
+			Content: "REST, direct-answer, GraphQL, and OpenAPI interfaces are available. Key endpoints include /api/v1/search, /api/v1/search/related, /api/v1/autocomplete, /api/v1/instant, /api/v1/direct/{type}/{term}, /api/v1/engines, /api/v1/categories, /api/v1/bangs, /api/v1/widgets, /api/v1/server/help, /api/v1/server/about, /api/v1/server/privacy, /api/v1/server/contact, /api/v1/server/terms, /api/graphql, /server/docs/graphql, /openapi (Swagger UI), and /openapi.json (OpenAPI spec). The full documentation is served offline at /server/docs.",
 		},
 	}
 
@@ -1691,7 +2071,7 @@ func (h *Handler) handleServerTerms(w http.ResponseWriter, r *http.Request) {
 		Data: ServerPageResponse{
 			Title:       "Terms of Service",
 			Description: "Terms of service for " + appName,
-			Content:     h.config.Server.Pages.Terms.Content,
+			Content:     string(policy.Render(h.config.Server.Pages.Terms.Content, policy.Variables(h.config))),
 			Sections:    sections,
 		},
 		Meta: &APIMeta{Version: APIVersion},
@@ -1855,35 +2235,6 @@ func (h *Handler) handleServerContact(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handlePreferences handles GET|POST /api/v1/preferences per AI.md PART 1.
-// Preferences are stored client-side (localStorage/cookies). The API endpoint
-// provides the schema and acknowledges client-submitted preference saves.
-func (h *Handler) handlePreferences(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodGet {
-		h.jsonResponse(w, http.StatusOK, &APIResponse{
-			OK: true,
-			Data: map[string]interface{}{
-				"storage": "client-side",
-				"fields": []string{
-					"theme", "language", "safe_search", "per_page",
-					"default_category", "engines",
-				},
-			},
-		})
-		return
-	}
-
-	if r.Method == http.MethodPost {
-		h.jsonResponse(w, http.StatusOK, &APIResponse{
-			OK:   true,
-			Data: map[string]string{"status": "saved"},
-		})
-		return
-	}
-
-	h.errorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", r.Method)
-}
-
 func (h *Handler) serveFaviconFallback(w http.ResponseWriter) {
 	// 1x1 transparent PNG (smallest valid PNG)
 	transparentPNG := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
@@ -1897,27 +2248,46 @@ func (h *Handler) serveFaviconFallback(w http.ResponseWriter) {
 }
 
 
+// searchCountry returns the GeoIP country a search is boosted toward, or ""
+// when search.geo_boost is off, the client sends Do Not Track or Global
+// Privacy Control, or the address has no known country
+func (h *Handler) searchCountry(r *http.Request) string {
+	if !h.config.Search.GeoBoost.Enabled || h.geoipLookup == nil || !h.geoipLookup.IsLoaded() {
+		return ""
+	}
+	if httputil.RequestPrivacySignals(r).OptOut() {
+		return ""
+	}
+	if result := h.geoipLookup.Lookup(clientIPForAPI(r)); result.Found {
+		return result.CountryCode
+	}
+	return ""
+}
+
 // requireOperator wraps a handler and rejects requests without a valid operator
 // bearer token. Per AI.md PART 14: operator-gated endpoints use Bearer auth.
-// Token comparison is constant-time over SHA-256 digests to prevent timing leaks.
+// Token comparison is constant-time, and every rejection answers after
+// security.AuthFailureFloor, so timing leaks neither the token nor why it
+// was rejected.
 func (h *Handler) requireOperator(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		timer := security.StartAuth()
 		expected := h.config.Get().Token
 		if expected == "" {
+			timer.Fail(r.Context())
 			h.errorResponse(w, http.StatusUnauthorized, "Operator token not configured", "")
 			return
 		}
 		hdr := r.Header.Get("Authorization")
 		const prefix = "Bearer "
 		if len(hdr) <= len(prefix) || !strings.EqualFold(hdr[:len(prefix)], prefix) {
+			timer.Fail(r.Context())
 			w.Header().Set("WWW-Authenticate", `Bearer realm="operator"`)
 			h.errorResponse(w, http.StatusUnauthorized, "Operator token required", "")
 			return
 		}
-		presented := strings.TrimSpace(hdr[len(prefix):])
-		expectedSum := sha256.Sum256([]byte(expected))
-		presentedSum := sha256.Sum256([]byte(presented))
-		if subtle.ConstantTimeCompare(expectedSum[:], presentedSum[:]) != 1 {
+		if !security.TokenEqual(strings.TrimSpace(hdr[len(prefix):]), expected) {
+			timer.Fail(r.Context())
 			w.Header().Set("WWW-Authenticate", `Bearer realm="operator"`)
 			h.errorResponse(w, http.StatusUnauthorized, "Invalid operator token", "")
 			return
@@ -1926,6 +2296,18 @@ func (h *Handler) requireOperator(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// hasOperatorToken reports whether the request presents the operator bearer
+// token, for endpoints that are public but show operators more
+func (h *Handler) hasOperatorToken(r *http.Request) bool {
+	expected := h.config.Get().Token
+	hdr := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if expected == "" || len(hdr) <= len(prefix) || !strings.EqualFold(hdr[:len(prefix)], prefix) {
+		return false
+	}
+	return security.TokenEqual(strings.TrimSpace(hdr[len(prefix):]), expected)
+}
+
 // handleServerStatus handles GET /api/v1/server/status (operator token required).
 // Per AI.md PART 14: operator-gated JSON status endpoint mirrors /server/status HTML page.
 func (h *Handler) handleServerStatus(w http.ResponseWriter, r *http.Request) {
@@ -1955,6 +2337,42 @@ func (h *Handler) handleServerStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleInstantLadder handles GET /api/v1/server/instant (operator token required).
+// It lists the instant answer ladder in priority order; with ?q= it also
+// reports which handlers a query triggers, without running any of them.
+func (h *Handler) handleInstantLadder(w http.ResponseWriter, r *http.Request) {
+	if h.instantManager == nil {
+		h.errorResponse(w, http.StatusServiceUnavailable, "Instant answers not available", "")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	entries := h.instantManager.Ladder(query)
+
+	data := map[string]interface{}{
+		"max_answers":   h.instantManager.MaxAnswers(),
+		"max_collapsed": h.instantManager.MaxCollapsed(),
+		"handlers":      entries,
+	}
+	if query != "" {
+		// The first enabled handler that matches is the one tried first
+		winner := ""
+		for _, e := range entries {
+			if e.Enabled && e.Matched {
+				winner = e.Handler
+				break
+			}
+		}
+		data["query"] = query
+		data["first_match"] = winner
+	}
+
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: data,
+	})
+}
+
 // handleServerConfig handles GET /api/v1/server/config (operator token required).
 // Per AI.md PART 14: returns a redacted view of the current server configuration.
 // Sensitive fields (token, secret_key) are never included in the response.
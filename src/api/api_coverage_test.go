@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/apimgr/search/src/instant"
+	"github.com/apimgr/search/src/version"
 )
 
 // TestHandleServerStatus verifies the 0% handler returns 200 with ok=true and status=healthy.
@@ -91,6 +94,35 @@ func TestHandleServerConfig(t *testing.T) {
 	}
 }
 
+// TestHandleInstantLadder verifies the ladder lists handlers and reports the first match for ?q=.
+func TestHandleInstantLadder(t *testing.T) {
+	handler := newTestHandler()
+	handler.SetInstantManager(instant.NewManager())
+	req := httptest.NewRequest(http.MethodGet, "/server/instant?q=uuid", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleInstantLadder(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleInstantLadder() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var resp APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("json decode error: %v", err)
+	}
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("handleInstantLadder() response.Data type = %T, want map[string]interface{}", resp.Data)
+	}
+	if data["first_match"] != "uuid" {
+		t.Errorf("handleInstantLadder() first_match = %v, want uuid", data["first_match"])
+	}
+	if handlers, _ := data["handlers"].([]interface{}); len(handlers) == 0 {
+		t.Error("handleInstantLadder() response should list handlers")
+	}
+}
+
 // TestHandleHealthzMaintenanceMode verifies 503 is returned and status=maintenance when in maintenance.
 func TestHandleHealthzMaintenanceMode(t *testing.T) {
 	handler := newTestHandler()
@@ -120,6 +152,9 @@ func TestHandleHealthzMaintenanceMode(t *testing.T) {
 
 // TestHandleHealthzTorEnabledNilService verifies tor.status=unavailable when tor is enabled but service is nil.
 func TestHandleHealthzTorEnabledNilService(t *testing.T) {
+	if !version.HasFeature("tor") {
+		t.Skip("Tor support is not compiled in (-tags notor)")
+	}
 	handler := newTestHandler()
 	handler.config.Server.Tor.Enabled = true
 
@@ -154,5 +189,8 @@ func TestHandleHealthzTorEnabledNilService(t *testing.T) {
 	if tor["enabled"] != true {
 		t.Errorf("tor.enabled = %v, want true", tor["enabled"])
 	}
+	if tor["compiled"] != true {
+		t.Errorf("tor.compiled = %v, want true", tor["compiled"])
+	}
 }
 
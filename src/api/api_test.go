@@ -3,8 +3,10 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -13,7 +15,9 @@ import (
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/model"
 	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/search/bang"
 	"github.com/apimgr/search/src/search/engine"
+	"github.com/apimgr/search/src/search/macro"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -110,8 +114,8 @@ func TestCategoriesEndpoint(t *testing.T) {
 	}
 
 	// Should expose the full IDEA category set
-	if len(data) != 10 {
-		t.Errorf("Expected 10 categories, got %d", len(data))
+	if len(data) != 11 {
+		t.Errorf("Expected 11 categories, got %d", len(data))
 	}
 }
 
@@ -478,6 +482,45 @@ func TestSearchEndpointWithCategory(t *testing.T) {
 	}
 }
 
+func TestSearchEndpointInvalidType(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=pancakes&type=video", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleSearch(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSearchEndpointInvalidImageColor(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=sunset&category=images&image_color=mauve", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleSearch(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSearchEndpointInvalidVideoLength(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=lecture&category=videos&video_length=epic", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleSearch(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 // Tests for Autocomplete with query
 
 func TestAutocompleteWithQuery(t *testing.T) {
@@ -508,6 +551,28 @@ func TestAutocompleteWithQuery(t *testing.T) {
 	}
 }
 
+func TestAutocompleteUsesSuggester(t *testing.T) {
+	handler := newTestHandler()
+	history := search.NewSuggestionHistory()
+	history.Add([]string{"golang generics", "gopher", "rust"})
+	handler.SetSuggester(search.NewSuggester(nil, history, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/autocomplete?q=go", nil)
+	w := httptest.NewRecorder()
+	handler.handleAutocomplete(w, req)
+
+	var response struct {
+		OK   bool     `json:"ok"`
+		Data []string `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.OK || len(response.Data) != 2 {
+		t.Errorf("data = %q, want the two history completions", response.Data)
+	}
+}
+
 // Tests for Engine by ID endpoint
 
 func TestEngineByIDEmpty(t *testing.T) {
@@ -680,6 +745,80 @@ func TestRelatedSearchesEndpointNoProvider(t *testing.T) {
 	}
 }
 
+// Tests for Search Preview endpoint
+
+func TestSearchPreviewDisabled(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search/preview?q=golang", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleSearchPreview(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSearchPreviewShortQuery(t *testing.T) {
+	handler := newTestHandler()
+	handler.config.Search.Preview.Enabled = true
+
+	for _, q := range []string{"go", "!g golang"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search/preview?q="+url.QueryEscape(q), nil)
+		w := httptest.NewRecorder()
+
+		handler.handleSearchPreview(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status %d, got %d", q, http.StatusOK, w.Code)
+		}
+		var response struct {
+			OK   bool                  `json:"ok"`
+			Data SearchPreviewResponse `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !response.OK || len(response.Data.Results) != 0 {
+			t.Errorf("%q: expected ok with no results, got %+v", q, response)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search/preview", nil)
+	w := httptest.NewRecorder()
+	handler.handleSearchPreview(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for empty query, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// Tests for Reverse Image endpoint
+
+func TestReverseImageEndpoint(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/images/reverse?url=https://example.com/cat.jpg", nil)
+	w := httptest.NewRecorder()
+	handler.handleReverseImage(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("disabled: expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	handler.config.Search.ReverseImage.Enabled = true
+	for target, want := range map[string]int{
+		"/api/v1/images/reverse":                                 http.StatusBadRequest,
+		"/api/v1/images/reverse?url=ftp://example.com/cat.jpg":   http.StatusBadRequest,
+		"/api/v1/images/reverse?url=https://example.com/cat.jpg": http.StatusServiceUnavailable,
+	} {
+		w := httptest.NewRecorder()
+		handler.handleReverseImage(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", target, want, w.Code)
+		}
+	}
+}
+
 // Tests for Set methods
 
 func TestSetWidgetManager(t *testing.T) {
@@ -905,6 +1044,43 @@ func TestBangsEndpointWithCategory(t *testing.T) {
 	}
 }
 
+func TestBangsEndpointWithCustomBangs(t *testing.T) {
+	handler := newTestHandler()
+	bm := bang.NewManager()
+	bm.SetCustomBangs([]*bang.Bang{
+		{Shortcut: "intra", Name: "Intranet", URL: "https://intra.example/?q={query}", Category: "custom"},
+		{Shortcut: "g", Name: "Company Google", URL: "https://google.example/?q={query}", Category: "general"},
+	})
+	handler.SetBangManager(bm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/bangs", nil)
+	w := httptest.NewRecorder()
+	handler.handleBangs(w, req)
+
+	var response struct {
+		Data struct {
+			Bangs []BangInfo `json:"bangs"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	byShortcut := make(map[string][]BangInfo)
+	for _, b := range response.Data.Bangs {
+		byShortcut[b.Shortcut] = append(byShortcut[b.Shortcut], b)
+	}
+	if got := byShortcut["intra"]; len(got) != 1 || !got[0].Custom {
+		t.Errorf("custom bang = %+v", got)
+	}
+	// A custom bang replaces the built-in with its shortcut
+	if got := byShortcut["g"]; len(got) != 1 || got[0].Name != "Company Google" || !got[0].Custom {
+		t.Errorf("overridden bang = %+v", got)
+	}
+	if got := byShortcut["ddg"]; len(got) != 1 || got[0].Custom {
+		t.Errorf("built-in bang = %+v", got)
+	}
+}
+
 func TestBangsEndpointWithSearch(t *testing.T) {
 	handler := newTestHandler()
 
@@ -1886,6 +2062,46 @@ func TestSearchEndpointPageBounds(t *testing.T) {
 	}
 }
 
+// TestSearchEndpointPageToken checks a later page continues the results of
+// the first through its page token, without repeating them
+func TestSearchEndpointPageToken(t *testing.T) {
+	registry := engine.SyntheticRegistry(0)
+	handler := NewHandler(&config.Config{}, registry, search.NewAggregatorSimple(registry.GetEnabled(), 5*time.Second))
+	get := func(url string) SearchResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.handleSearch(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var resp struct {
+			Data SearchResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v\n%s", url, err, w.Body)
+		}
+		return resp.Data
+	}
+
+	first := get("/api/v1/search?q=golang&limit=10")
+	if first.Pagination.Token == "" || !first.Pagination.HasMore || len(first.Results) != 10 {
+		t.Fatalf("first page pagination = %+v with %d results", first.Pagination, len(first.Results))
+	}
+	second := get("/api/v1/search?q=golang&limit=10&page=2&page_token=" + first.Pagination.Token)
+	if second.Pagination.Token != first.Pagination.Token || len(second.Results) != 10 {
+		t.Fatalf("second page pagination = %+v with %d results", second.Pagination, len(second.Results))
+	}
+	shown := make(map[string]bool)
+	for _, r := range first.Results {
+		shown[r.URL] = true
+	}
+	for _, r := range second.Results {
+		if shown[r.URL] {
+			t.Errorf("page 2 repeats %s from page 1", r.URL)
+		}
+	}
+	if again := get("/api/v1/search?q=golang&limit=10&page_token=" + first.Pagination.Token); again.Results[0].URL != first.Results[0].URL {
+		t.Errorf("page 1 reshuffled: %s, was %s", again.Results[0].URL, first.Results[0].URL)
+	}
+}
+
 func TestSearchEndpointQueryTrimming(t *testing.T) {
 	handler := newTestHandler()
 
@@ -2355,6 +2571,268 @@ func TestSearchEndpointEmptyResultsNotFatal(t *testing.T) {
 	}
 }
 
+// TestSearchStream checks /api/v1/search/stream sends an engine event per
+// engine, then done, or error when the search fails
+func TestSearchStream(t *testing.T) {
+	stream := func(handler *Handler) (*httptest.ResponseRecorder, []string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search/stream?q=foo&debug=1", nil)
+		w := httptest.NewRecorder()
+		handler.handleSearchStream(w, req)
+		var events []string
+		for _, line := range strings.Split(w.Body.String(), "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				events = append(events, name)
+			}
+		}
+		return w, events
+	}
+
+	w, events := stream(newHandlerWithEmptyResults())
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if strings.Join(events, ",") != "engine,done" {
+		t.Fatalf("events = %v, want engine then done", events)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"completed":1,"total":1`) || !strings.Contains(body, `"timing":{`) {
+		t.Errorf("engine event missing progress or debug timing: %s", body)
+	}
+
+	if _, events := stream(newTestHandler()); strings.Join(events, ",") != "error" {
+		t.Errorf("events with no engines = %v, want error", events)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/search/stream", strings.NewReader("{"))
+	w = httptest.NewRecorder()
+	newTestHandler().handleSearchStream(w, req)
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") == "text/event-stream" {
+		t.Errorf("bad body: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestWantsXML(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		want   bool
+	}{
+		{"/api/v1/search?q=a", "", false},
+		{"/api/v1/search?q=a&format=xml", "", true},
+		{"/api/v1/search?q=a&format=json", "application/xml", false},
+		{"/api/v1/search?q=a", "application/xml", true},
+		{"/api/v1/search?q=a", "text/xml;q=0.9", true},
+		{"/api/v1/search?q=a", "application/json, application/xml", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := wantsXML(req); got != tt.want {
+			t.Errorf("wantsXML(%q, Accept %q) = %v, want %v", tt.url, tt.accept, got, tt.want)
+		}
+	}
+}
+
+// TestXMLResponses checks search, engines and categories answer in XML with
+// the JSON field names, lists as <item> elements and errors in XML too
+func TestXMLResponses(t *testing.T) {
+	handler := newHandlerWithEmptyResults()
+	get := func(h http.HandlerFunc, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Accept", "application/xml")
+		w := httptest.NewRecorder()
+		h(w, req)
+		if ct := w.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q", url, ct)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("%s: Vary = %q", url, vary)
+		}
+		return w
+	}
+
+	var categories struct {
+		OK   bool `xml:"ok"`
+		Data struct {
+			Items []CategoryInfo `xml:"item"`
+		} `xml:"data"`
+	}
+	w := get(handler.handleCategories, "/api/v1/categories")
+	if err := xml.Unmarshal(w.Body.Bytes(), &categories); err != nil || !categories.OK || len(categories.Data.Items) == 0 || categories.Data.Items[0].ID != "general" {
+		t.Errorf("categories = %+v, %v", categories, err)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("categories XML has no ETag")
+	}
+
+	var search struct {
+		XMLName xml.Name       `xml:"response"`
+		Data    SearchResponse `xml:"data"`
+	}
+	w = get(handler.handleSearch, "/api/v1/search?q=foo")
+	if err := xml.Unmarshal(w.Body.Bytes(), &search); err != nil || search.Data.Query != "foo" || search.Data.Pagination.Limit != 20 {
+		t.Errorf("search = %+v, %v\n%s", search, err, w.Body)
+	}
+
+	w = get(handler.handleEngineByID, "/api/v1/engines/missing")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<error>NOT_FOUND</error>") {
+		t.Errorf("missing engine: %d %s", w.Code, w.Body)
+	}
+
+	// JSON is unchanged without the XML preference
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/categories", nil)
+	rec := httptest.NewRecorder()
+	handler.handleCategories(rec, req)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") || strings.Contains(rec.Body.String(), "XMLName") {
+		t.Errorf("default response: %s %s", rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
+
+func TestResponseProfile(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		want   string
+	}{
+		{"/api/v1/search?q=a", "", ""},
+		{"/api/v1/search?q=a&profile=jsonapi", "", profileJSONAPI},
+		{"/api/v1/search?q=a&profile=hal", "application/vnd.api+json", profileHAL},
+		{"/api/v1/search?q=a&profile=siren", "application/hal+json", ""},
+		{"/api/v1/search?q=a", "application/vnd.api+json", profileJSONAPI},
+		{"/api/v1/search?q=a", "application/hal+json, application/json;q=0.9", profileHAL},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := responseProfile(req); got != tt.want {
+			t.Errorf("responseProfile(%q, Accept %q) = %q, want %q", tt.url, tt.accept, got, tt.want)
+		}
+	}
+}
+
+// TestProfiledResponses checks the JSON:API and HAL documents of search,
+// engines and categories, and their pagination links
+func TestProfiledResponses(t *testing.T) {
+	registry := engine.SyntheticRegistry(0)
+	handler := NewHandler(&config.Config{}, registry, search.NewAggregatorSimple(registry.GetEnabled(), 5*time.Second))
+	get := func(h http.HandlerFunc, url, contentType string, v any) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if ct := w.Header().Get("Content-Type"); ct != contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", url, ct, contentType)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v\n%s", url, err, w.Body)
+		}
+		return w
+	}
+
+	var doc struct {
+		Data []struct {
+			Type          string                    `json:"type"`
+			ID            string                    `json:"id"`
+			Attributes    map[string]any            `json:"attributes"`
+			Relationships map[string]map[string]any `json:"relationships"`
+		} `json:"data"`
+		Meta  map[string]any     `json:"meta"`
+		Links map[string]*string `json:"links"`
+	}
+	get(handler.handleSearch, "/api/v1/search?q=golang&limit=5&page=2&profile=jsonapi", "application/vnd.api+json", &doc)
+	if len(doc.Data) != 5 || doc.Data[0].Type != "results" || doc.Data[0].ID == "" || doc.Data[0].Attributes["url"] != doc.Data[0].ID {
+		t.Fatalf("jsonapi search data = %+v", doc.Data)
+	}
+	if _, ok := doc.Data[0].Attributes["engine"]; ok || doc.Data[0].Relationships["engine"]["data"] == nil {
+		t.Errorf("engine is an attribute, not a relationship: %+v", doc.Data[0])
+	}
+	if doc.Meta["query"] != "golang" || doc.Meta["pagination"] == nil {
+		t.Errorf("jsonapi search meta = %v", doc.Meta)
+	}
+	if doc.Links["prev"] == nil || !strings.Contains(*doc.Links["prev"], "page=1") || !strings.Contains(*doc.Links["self"], "page=2") || !strings.Contains(*doc.Links["self"], "profile=jsonapi") {
+		t.Errorf("jsonapi search links = %v", doc.Links)
+	}
+	get(handler.handleSearch, "/api/v1/search?q=golang&limit=100&profile=jsonapi", "application/vnd.api+json", &doc)
+	if doc.Links["prev"] != nil || doc.Links["next"] != nil {
+		t.Errorf("single page links = %v", doc.Links)
+	}
+
+	var hal struct {
+		Links    map[string]halLink `json:"_links"`
+		Embedded struct {
+			Results []struct {
+				URL   string             `json:"url"`
+				Links map[string]halLink `json:"_links"`
+			} `json:"results"`
+			Engines    []EngineInfo `json:"engines"`
+			Categories []struct {
+				ID    string             `json:"id"`
+				Links map[string]halLink `json:"_links"`
+			} `json:"categories"`
+		} `json:"_embedded"`
+		Query string `json:"query"`
+		Count int    `json:"count"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=golang&limit=5", nil)
+	req.Header.Set("Accept", "application/hal+json")
+	w := httptest.NewRecorder()
+	handler.handleSearch(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &hal); err != nil || w.Header().Get("Content-Type") != "application/hal+json" {
+		t.Fatalf("hal search: %v %s", err, w.Header().Get("Content-Type"))
+	}
+	if hal.Query != "golang" || len(hal.Embedded.Results) != 5 || !strings.HasPrefix(hal.Embedded.Results[0].Links["engine"].Href, "/api/v1/engines/synthetic-") {
+		t.Errorf("hal search = %+v", hal)
+	}
+	if _, ok := hal.Links["prev"]; ok || !strings.Contains(hal.Links["next"].Href, "page=2") {
+		t.Errorf("hal search links = %v", hal.Links)
+	}
+
+	w = get(handler.handleEngines, "/api/v1/engines?profile=hal", "application/hal+json", &hal)
+	if hal.Count != len(registry.GetAll()) || len(hal.Embedded.Engines) != hal.Count || w.Header().Get("ETag") == "" {
+		t.Errorf("hal engines = %d of %d", len(hal.Embedded.Engines), hal.Count)
+	}
+	get(handler.handleCategories, "/api/v1/categories?profile=hal", "application/hal+json", &hal)
+	if c := hal.Embedded.Categories[0]; c.ID != "general" || !c.Links["search"].Templated {
+		t.Errorf("hal category = %+v", c)
+	}
+
+	var one struct {
+		Data struct {
+			Type          string         `json:"type"`
+			ID            string         `json:"id"`
+			Attributes    map[string]any `json:"attributes"`
+			Relationships map[string]struct {
+				Data []jsonAPIIdentifier `json:"data"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+	get(handler.handleEngineByID, "/api/v1/engines/synthetic-a?profile=jsonapi", "application/vnd.api+json", &one)
+	if one.Data.Type != "engines" || one.Data.ID != "synthetic-a" || len(one.Data.Relationships["categories"].Data) == 0 || one.Data.Attributes["id"] != nil {
+		t.Errorf("jsonapi engine = %+v", one.Data)
+	}
+
+	var failed struct {
+		Data   any            `json:"data"`
+		Errors []jsonAPIError `json:"errors"`
+	}
+	w = get(handler.handleEngineByID, "/api/v1/engines/missing?profile=jsonapi", "application/vnd.api+json", &failed)
+	if w.Code != http.StatusNotFound || failed.Data != nil || len(failed.Errors) != 1 || failed.Errors[0].Status != "404" || failed.Errors[0].Code != "NOT_FOUND" {
+		t.Errorf("jsonapi error = %d %+v", w.Code, failed)
+	}
+	// HAL has no error format: errors keep the usual envelope
+	var plain APIResponse
+	get(handler.handleEngineByID, "/api/v1/engines/missing?profile=hal", "application/json; charset=utf-8", &plain)
+	if plain.OK || plain.Error != "NOT_FOUND" {
+		t.Errorf("hal error = %+v", plain)
+	}
+	// XML wins over a profile
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/categories?profile=hal&format=xml", nil)
+	w = httptest.NewRecorder()
+	handler.handleCategories(w, req)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("format=xml&profile=hal Content-Type = %q", ct)
+	}
+}
+
 // ============================================================================
 // Tests for server info page handlers (handleServerAbout, Privacy, Help, Terms)
 // ============================================================================
@@ -2567,6 +3045,66 @@ func TestHandleServerTermsMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestHandleDocs(t *testing.T) {
+	handler := newTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.handleDocs(w, httptest.NewRequest(http.MethodGet, "/api/v1/server/docs?q=bang", nil))
+	var search struct {
+		Data struct {
+			Hits []struct {
+				URL string `json:"url"`
+			} `json:"hits"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&search); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusOK || len(search.Data.Hits) == 0 || !strings.HasPrefix(search.Data.Hits[0].URL, "/server/docs/") {
+		t.Errorf("search: %d, hits %+v", w.Code, search.Data.Hits)
+	}
+
+	page := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/server/docs/"+name, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("page", name)
+		w := httptest.NewRecorder()
+		handler.handleDocsPage(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return w
+	}
+	w = page("search-syntax")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"anchor": "bang-commands"`) {
+		t.Errorf("page: %d %s", w.Code, w.Body.String())
+	}
+	if w := page("missing"); w.Code != http.StatusNotFound {
+		t.Errorf("missing page: %d", w.Code)
+	}
+}
+
+func TestHandleConfigReference(t *testing.T) {
+	handler := newTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.handleConfigReference(w, httptest.NewRequest(http.MethodGet, "/api/v1/server/config/reference", nil))
+	var resp struct {
+		Data struct {
+			Fields []config.ReferenceField `json:"fields"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusOK || len(resp.Data.Fields) == 0 || resp.Data.Fields[0].Key != "server.title" {
+		t.Errorf("reference: %d, %d fields", w.Code, len(resp.Data.Fields))
+	}
+
+	w = httptest.NewRecorder()
+	handler.handleConfigReference(w, httptest.NewRequest(http.MethodGet, "/api/v1/server/config/reference?format=markdown", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/markdown") || !strings.HasPrefix(w.Body.String(), "# Config Reference") {
+		t.Errorf("markdown: %q %.100s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
 // ============================================================================
 // Tests for handleServerContact
 // ============================================================================
@@ -3906,3 +4444,363 @@ func TestErrorResponseWith4xx(t *testing.T) {
 		t.Error("OK = true, want false")
 	}
 }
+
+func TestHasOperatorToken(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=test", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	if handler.hasOperatorToken(req) {
+		t.Error("hasOperatorToken() = true with no token configured")
+	}
+
+	handler.config.Server.Token = "secret"
+	if !handler.hasOperatorToken(req) {
+		t.Error("hasOperatorToken() = false with the operator token")
+	}
+	req.Header.Set("Authorization", "Bearer wrong")
+	if handler.hasOperatorToken(req) {
+		t.Error("hasOperatorToken() = true with a wrong token")
+	}
+}
+
+func TestEngineHealthEndpoint(t *testing.T) {
+	registry := engine.SyntheticRegistry(0)
+	eng, _ := registry.Get("synthetic-b")
+	tracker := eng.(interface {
+		SetCircuitBreaker(search.CircuitBreaker)
+		RecordFailure(error)
+	})
+	tracker.SetCircuitBreaker(search.CircuitBreaker{FailureThreshold: 1, Cooldown: time.Minute})
+	tracker.RecordFailure(model.ErrEngineUnavailable)
+	handler := NewHandler(&config.Config{}, registry, search.NewAggregatorSimple(nil, 30*time.Second))
+
+	w := httptest.NewRecorder()
+	handler.handleEngineHealth(w, httptest.NewRequest(http.MethodGet, "/api/v1/engines/health", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("status = %d, Cache-Control = %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	var resp struct {
+		Data EngineHealthReport `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	report := resp.Data
+	if len(report.Engines) != len(registry.GetEnabled()) || report.Healthy+report.Degraded+report.Unhealthy != len(report.Engines) {
+		t.Fatalf("report = %+v", report)
+	}
+	if first := report.Engines[0]; first.ID != "synthetic-b" || first.Health.Circuit != search.CircuitOpen || first.Health.CircuitOpens != 1 {
+		t.Errorf("first engine = %s %+v, want synthetic-b with its circuit open", first.ID, first.Health)
+	}
+	if report.Engines[1].Health.Circuit != search.CircuitClosed {
+		t.Errorf("second engine circuit = %s", report.Engines[1].Health.Circuit)
+	}
+}
+
+func TestSearchMacros(t *testing.T) {
+	registry := engine.SyntheticRegistry(0)
+	cfg := &config.Config{Search: config.SearchConfig{Macros: []config.MacroConfig{
+		{Name: "issues", Query: "site:github.com/{repo}/issues", Description: "GitHub issues"},
+		{Name: "docs", Query: "site:pkg.go.dev"},
+	}}}
+	handler := NewHandler(cfg, registry, search.NewAggregatorSimple(registry.GetEnabled(), 5*time.Second))
+
+	w := httptest.NewRecorder()
+	handler.handleSearch(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=~issues+apimgr/search+leak", nil))
+	var resp struct {
+		Data SearchResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status = %d, %v", w.Code, err)
+	}
+	if resp.Data.Query != "site:github.com/apimgr/search/issues leak" {
+		t.Errorf("query = %q", resp.Data.Query)
+	}
+
+	// A user's own macro wins over the instance one
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=~issues+apimgr/search", nil)
+	req.AddCookie(&http.Cookie{Name: macro.CookieName, Value: macro.EncodeCookie([]macro.Macro{{Name: "issues", Query: "site:gitlab.com/{repo}"}})})
+	w = httptest.NewRecorder()
+	handler.handleSearch(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Query != "site:gitlab.com/apimgr/search" {
+		t.Errorf("query with the user's macro = %q, %v", resp.Data.Query, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.handleSearch(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=~issues", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "repo") {
+		t.Errorf("missing argument: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handler.handleMacros(w, httptest.NewRequest(http.MethodGet, "/api/v1/macros", nil))
+	var list struct {
+		Data struct {
+			Macros []MacroInfo `json:"macros"`
+			Total  int         `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.Data.Total != 2 {
+		t.Fatalf("macros = %s, %v", w.Body, err)
+	}
+	if m := list.Data.Macros[0]; m.Usage != "~issues {repo}" || len(m.Params) != 1 || m.Description != "GitHub issues" {
+		t.Errorf("first macro = %+v", m)
+	}
+}
+
+// TestPrivacyReport checks /api/v1/privacy follows the config and the
+// request's DNT and Sec-GPC signals
+func TestPrivacyReport(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Logs.Access.Privacy = "anonymized"
+	cfg.Search.GeoBoost.Enabled = true
+	cfg.Search.Permalinks.Enabled = true
+	handler := NewHandler(cfg, nil, nil)
+	get := func(headers map[string]string) PrivacyReport {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/privacy", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.handlePrivacy(w, req)
+		if w.Header().Get("Cache-Control") != "no-store" || !strings.Contains(w.Header().Get("Vary"), "Sec-GPC") {
+			t.Errorf("privacy report headers = %v", w.Header())
+		}
+		var resp struct {
+			Data PrivacyReport `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%v\n%s", err, w.Body)
+		}
+		return resp.Data
+	}
+
+	report := get(nil)
+	if report.Signals.Honored || report.AccessLog.Privacy != "anonymized" || !strings.HasPrefix(report.AccessLog.ClientAddress, "network") {
+		t.Errorf("report without signals = %+v", report)
+	}
+	if report.GeoBoost.Enabled || !strings.Contains(report.GeoBoost.Reason, "GeoIP") {
+		t.Errorf("geo boost without a GeoIP database = %+v", report.GeoBoost)
+	}
+	if !report.ResultCache.Enabled || report.ResultCache.TTL != "5m" || report.ResultCache.Store != "memory" || report.Permalinks.TTL != "7d" {
+		t.Errorf("cache and permalinks = %+v %+v", report.ResultCache, report.Permalinks)
+	}
+
+	report = get(map[string]string{"Sec-GPC": "1", "DNT": "1"})
+	if !report.Signals.GPC || !report.Signals.DNT || !report.Signals.Honored {
+		t.Errorf("signals = %+v", report.Signals)
+	}
+	if report.AccessLog.Privacy != "strict" || report.AccessLog.ClientAddress != "not recorded" || report.AccessLog.UserAgent != "not recorded" {
+		t.Errorf("opted-out access log = %+v", report.AccessLog)
+	}
+	if report.GeoBoost.Enabled || !strings.Contains(report.GeoBoost.Reason, "Global Privacy Control") {
+		t.Errorf("opted-out geo boost = %+v", report.GeoBoost)
+	}
+}
+
+type staticSuggestions []string
+
+func (s staticSuggestions) Name() string { return "static" }
+
+func (s staticSuggestions) Suggest(ctx context.Context, query string) ([]string, error) {
+	return s, nil
+}
+
+func TestOpenSearchSuggestions(t *testing.T) {
+	handler := newTestHandler()
+	handler.SetSuggester(search.NewSuggester([]search.WeightedSuggestionProvider{
+		{Provider: staticSuggestions{"privacy", "private"}, Weight: 1},
+	}, nil, 0))
+
+	for _, tt := range []struct {
+		url    string
+		accept string
+		want   string
+	}{
+		{"/api/v1/autocomplete?q=priv&format=opensearch", "", `["priv",["privacy","private"]]`},
+		{"/api/v1/autocomplete?q=priv", "application/x-suggestions+json", `["priv",["privacy","private"]]`},
+		{"/api/v1/autocomplete?q=&format=opensearch", "", `["",[]]`},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		handler.handleAutocomplete(w, req)
+		if got := w.Body.String(); got != tt.want || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/x-suggestions+json") {
+			t.Errorf("%s: %s %q, want %s", tt.url, w.Header().Get("Content-Type"), got, tt.want)
+		}
+	}
+
+	// The API's own envelope otherwise
+	w := httptest.NewRecorder()
+	handler.handleAutocomplete(w, httptest.NewRequest(http.MethodGet, "/api/v1/autocomplete?q=priv", nil))
+	if !strings.Contains(w.Body.String(), `"ok": true`) {
+		t.Errorf("plain autocomplete = %s", w.Body)
+	}
+}
+
+func TestSearchResultsVideo(t *testing.T) {
+	h := newTestHandler()
+	h.config.Search.VideoPlayer = config.VideoPlayerConfig{Enabled: true, Frontend: "nocookie"}
+	query := &model.Query{Text: "rick astley", Category: model.CategoryVideos}
+	results := h.searchResults(query, []model.Result{
+		{Title: "Never Gonna Give You Up", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Category: model.CategoryVideos, Duration: 213, Author: "Rick Astley"},
+		{Title: "Unplayable", URL: "https://www.twitch.tv/videos/123456789", Category: model.CategoryVideos, Duration: 60},
+		{Title: "Flagged", URL: "https://youtu.be/dQw4w9WgXcQ", Category: model.CategoryVideos, Duration: 60, Threat: "malware"},
+	})
+
+	if video := results[0].Video; video == nil || video.Duration != 213 || video.Channel != "Rick Astley" ||
+		video.Embed != "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ" {
+		t.Errorf("playable video = %+v", video)
+	}
+	if video := results[1].Video; video == nil || video.Embed != "" {
+		t.Errorf("unplayable video = %+v", video)
+	}
+	if video := results[2].Video; video == nil || video.Embed != "" {
+		t.Errorf("flagged video = %+v, want no embed", video)
+	}
+
+	h.config.Search.VideoPlayer.Enabled = false
+	if video := h.searchResults(query, []model.Result{{URL: "https://youtu.be/dQw4w9WgXcQ", Category: model.CategoryVideos, Duration: 1}})[0].Video; video == nil || video.Embed != "" {
+		t.Errorf("video with the player off = %+v", video)
+	}
+}
+
+func TestSearchResponseNewsGroups(t *testing.T) {
+	h := newTestHandler()
+	published := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	results := model.NewSearchResults("election", model.CategoryNews)
+	results.AddResult(model.Result{Title: "Polls close", URL: "https://www.reuters.com/a", Category: model.CategoryNews, Author: "Reuters", PublishedAt: published})
+	results.AddResult(model.Result{Title: "Turnout", URL: "https://apnews.com/b", Category: model.CategoryNews})
+	results.AddResult(model.Result{Title: "Results", URL: "https://www.reuters.com/c", Category: model.CategoryNews, Author: "Reuters"})
+	query := &model.Query{Text: "election", Category: model.CategoryNews}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=election&category=news&group=publisher", nil)
+	resp := h.searchResponse(req, SearchRequest{Query: "election", Category: "news", Page: 1, Group: "publisher"}, query, results, search.PageInfo{}, time.Now())
+	if len(resp.Results) != 3 || resp.Results[0].Publisher != "Reuters" || resp.Results[0].Date != "2026-10-16T20:00:00Z" {
+		t.Errorf("results = %+v", resp.Results)
+	}
+	if len(resp.Groups) != 2 || resp.Groups[0].Publisher != "Reuters" || len(resp.Groups[0].Results) != 2 || resp.Groups[1].Publisher != "apnews.com" {
+		t.Errorf("groups = %+v", resp.Groups)
+	}
+
+	resp = h.searchResponse(req, SearchRequest{Query: "election", Category: "news", Page: 1}, query, results, search.PageInfo{}, time.Now())
+	if resp.Groups != nil {
+		t.Errorf("ungrouped search has groups %+v", resp.Groups)
+	}
+}
+
+func TestParseSearchRequestNewsFilters(t *testing.T) {
+	h := newTestHandler()
+	w := httptest.NewRecorder()
+	_, query, ok := h.parseSearchRequest(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=election&category=news&time_range=month&sort=date", nil))
+	if !ok || query.TimeRange != "month" || query.SortBy != model.SortDate {
+		t.Fatalf("parseSearchRequest() = %+v, %v", query, ok)
+	}
+
+	w = httptest.NewRecorder()
+	if _, _, ok := h.parseSearchRequest(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=election&time_range=decade", nil)); ok || w.Code != http.StatusBadRequest {
+		t.Errorf("time_range=decade: ok %v, status %d", ok, w.Code)
+	}
+}
+
+func TestParseSearchRequestSafeSearch(t *testing.T) {
+	h := newTestHandler()
+	h.config.Search.SafeSearch = 2
+	parse := func(method, target, body string) (*model.Query, int) {
+		w := httptest.NewRecorder()
+		_, query, _ := h.parseSearchRequest(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return query, w.Code
+	}
+
+	if query, _ := parse(http.MethodGet, "/api/v1/search?q=cats", ""); query.SafeSearch != 2 {
+		t.Errorf("default level = %d, want search.safe_search 2", query.SafeSearch)
+	}
+	if query, _ := parse(http.MethodGet, "/api/v1/search?q=cats&safesearch=off&safe_search=1", ""); query.SafeSearch != 0 {
+		t.Errorf("safesearch=off = %d, want 0", query.SafeSearch)
+	}
+	if query, _ := parse(http.MethodGet, "/api/v1/search?q=cats&safe_search=moderate", ""); query.SafeSearch != 1 {
+		t.Errorf("safe_search=moderate = %d, want 1", query.SafeSearch)
+	}
+	if query, _ := parse(http.MethodPost, "/api/v1/search?safesearch=strict", `{"query":"cats","safe_search":"0"}`); query.SafeSearch != 2 {
+		t.Errorf("POST with &safesearch=strict = %d, want 2", query.SafeSearch)
+	}
+	if _, code := parse(http.MethodGet, "/api/v1/search?q=cats&safesearch=high", ""); code != http.StatusBadRequest {
+		t.Errorf("safesearch=high: status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	h.config.Search.SafeSearchLocked = true
+	if query, _ := parse(http.MethodGet, "/api/v1/search?q=cats&safesearch=off", ""); query.SafeSearch != 2 {
+		t.Errorf("locked level = %d, want 2", query.SafeSearch)
+	}
+}
+
+func TestSearchResultsFile(t *testing.T) {
+	h := newTestHandler()
+	results := []model.Result{{
+		Title:    "Ubuntu 24.04 Desktop",
+		URL:      "https://thepiratebay.org/description.php?id=123",
+		Category: model.CategoryFiles,
+		FileSize: 6114656256,
+		Metadata: map[string]interface{}{"seeders": 42, "leechers": 7, "magnet": "magnet:?xt=urn:btih:abc"},
+	}}
+	api := h.searchResults(&model.Query{Category: model.CategoryFiles}, results)
+	if len(api) != 1 || api[0].File == nil || api[0].File.Seeders != 42 || api[0].File.Size != 6114656256 || api[0].File.Magnet == "" {
+		t.Fatalf("searchResults() = %+v", api)
+	}
+	data, _ := json.Marshal(api[0])
+	if !strings.Contains(string(data), `"file":{"size":6114656256,"seeders":42,"leechers":7,"magnet":"magnet:?xt=urn:btih:abc"}`) {
+		t.Errorf("JSON = %s", data)
+	}
+}
+
+func TestSearchResultsMap(t *testing.T) {
+	h := newTestHandler()
+	results := []model.Result{{
+		Title:    "Berlin",
+		URL:      "https://www.openstreetmap.org/relation/62422",
+		Category: model.CategoryMaps,
+		Metadata: map[string]interface{}{
+			"latitude":   52.5173885,
+			"longitude":  13.3951309,
+			"place_type": "city",
+			"bbox":       []float64{52.3382448, 52.6755087, 13.088345, 13.7611609},
+		},
+	}}
+	api := h.searchResults(&model.Query{Category: model.CategoryMaps}, results)
+	if len(api) != 1 || api[0].Map == nil || api[0].Map.BoundingBox == nil || api[0].Map.PlaceType != "city" {
+		t.Fatalf("searchResults() = %+v", api)
+	}
+	data, _ := json.Marshal(api[0])
+	if !strings.Contains(string(data), `"map":{"lat":52.5173885,"lon":13.3951309,"bbox":{"south":52.3382448,"north":52.6755087,"west":13.088345,"east":13.7611609},"place_type":"city"}`) {
+		t.Errorf("JSON = %s", data)
+	}
+}
+
+func TestDisabledCategory(t *testing.T) {
+	h := newTestHandler()
+	h.config.Search.DisabledCategories = []string{"files"}
+
+	w := httptest.NewRecorder()
+	if _, _, ok := h.parseSearchRequest(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=ubuntu&category=files", nil)); ok || w.Code != http.StatusNotFound {
+		t.Errorf("category=files: ok %v, status %d", ok, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.handleCategories(w, httptest.NewRequest(http.MethodGet, "/api/v1/categories", nil))
+	var response struct {
+		Data []CategoryInfo `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 10 {
+		t.Errorf("categories = %d, want 10", len(response.Data))
+	}
+	for _, category := range response.Data {
+		if category.ID == "files" {
+			t.Error("disabled files category is listed")
+		}
+	}
+}
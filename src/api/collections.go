@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/apimgr/search/src/collection"
+	"github.com/go-chi/chi/v5"
+)
+
+type collectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type collectionItemRequest struct {
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Engine  string `json:"engine"`
+	Note    string `json:"note"`
+}
+
+type collectionNoteRequest struct {
+	Note string `json:"note"`
+}
+
+type collectionOrderRequest struct {
+	Items []string `json:"items"`
+}
+
+type collectionShareRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// collectionStore returns the store, or nil when collections are off
+func (h *Handler) collectionStore() *collection.Store {
+	if h.collections == nil || !h.config.Search.Collections.Enabled {
+		return nil
+	}
+	return h.collections
+}
+
+// withCollections rejects requests while collections are unavailable
+func (h *Handler) withCollections(next func(http.ResponseWriter, *http.Request, *collection.Store)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store := h.collectionStore()
+		if store == nil {
+			h.writeError(w, "NOT_AVAILABLE", "Collections are unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r, store)
+	}
+}
+
+// writeCollectionError maps store errors to API errors
+func (h *Handler) writeCollectionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, collection.ErrNotFound):
+		h.writeError(w, "NOT_FOUND", "Collection or item not found", http.StatusNotFound)
+	case errors.Is(err, collection.ErrInvalidInput):
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+	default:
+		h.writeError(w, "INTERNAL_ERROR", "Collection request failed", http.StatusInternalServerError)
+	}
+}
+
+// handleCreateCollection handles POST /api/v1/collections (anonymous — rate limited by middleware).
+func (h *Handler) handleCreateCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	var req collectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	created, token, err := store.Create(r.Context(), req.Name, req.Description)
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, APIResponse{
+		OK: true,
+		Data: map[string]interface{}{
+			"collection":   created,
+			"manage_token": token,
+			"manage_url":   baseURLFromRequest(h, r) + APIPrefix + "/collections/" + token,
+		},
+	})
+}
+
+// handleGetCollection handles GET /api/v1/collections/{token}
+func (h *Handler) handleGetCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	c, err := store.Get(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: c})
+}
+
+// handleUpdateCollection handles PATCH /api/v1/collections/{token}
+func (h *Handler) handleUpdateCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	var req collectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	c, err := store.Update(r.Context(), chi.URLParam(r, "token"), req.Name, req.Description)
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: c})
+}
+
+// handleDeleteCollection handles DELETE /api/v1/collections/{token}
+func (h *Handler) handleDeleteCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	if err := store.Delete(r.Context(), chi.URLParam(r, "token")); err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: map[string]interface{}{"deleted": true}})
+}
+
+// handleAddCollectionItem handles POST /api/v1/collections/{token}/items
+func (h *Handler) handleAddCollectionItem(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	var req collectionItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	item, err := store.AddItem(r.Context(), chi.URLParam(r, "token"), collection.Item{
+		URL:     req.URL,
+		Title:   req.Title,
+		Content: req.Content,
+		Engine:  req.Engine,
+		Note:    req.Note,
+	}, h.config.Search.Collections.MaxItems)
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, APIResponse{OK: true, Data: item})
+}
+
+// handleUpdateCollectionItem handles PATCH /api/v1/collections/{token}/items/{item}
+func (h *Handler) handleUpdateCollectionItem(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	var req collectionNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	item, err := store.SetNote(r.Context(), chi.URLParam(r, "token"), chi.URLParam(r, "item"), req.Note)
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: item})
+}
+
+// handleDeleteCollectionItem handles DELETE /api/v1/collections/{token}/items/{item}
+func (h *Handler) handleDeleteCollectionItem(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	if err := store.RemoveItem(r.Context(), chi.URLParam(r, "token"), chi.URLParam(r, "item")); err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: map[string]interface{}{"deleted": true}})
+}
+
+// handleReorderCollection handles PUT /api/v1/collections/{token}/order
+func (h *Handler) handleReorderCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	var req collectionOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	c, err := store.Reorder(r.Context(), chi.URLParam(r, "token"), req.Items)
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: c})
+}
+
+// handleShareCollection handles POST /api/v1/collections/{token}/share.
+// {"enabled": true} issues a new share link; false turns sharing off.
+func (h *Handler) handleShareCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	var req collectionShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		h.writeError(w, "BAD_REQUEST", "Body must be {\"enabled\": true|false}", http.StatusBadRequest)
+		return
+	}
+	token, err := store.Share(r.Context(), chi.URLParam(r, "token"), *req.Enabled)
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	data := map[string]interface{}{"shared": *req.Enabled}
+	if token != "" {
+		data["share_token"] = token
+		data["share_url"] = baseURLFromRequest(h, r) + APIPrefix + "/collections/shared/" + token
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: data})
+}
+
+// handleExportCollection handles GET /api/v1/collections/{token}/export
+func (h *Handler) handleExportCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	c, err := store.Get(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	h.writeCollectionExport(w, r, c, true)
+}
+
+// handleSharedCollection handles GET /api/v1/collections/shared/{token}: the
+// read-only view behind a share link. JSON by default; ?format= picks
+// markdown or csv.
+func (h *Handler) handleSharedCollection(w http.ResponseWriter, r *http.Request, store *collection.Store) {
+	c, err := store.GetShared(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.writeCollectionError(w, err)
+		return
+	}
+	// Keep the share token out of the Referer sent to saved links
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	h.writeCollectionExport(w, r, c, false)
+}
+
+// writeCollectionExport writes c in the ?format= requested, as a download
+// when attachment is set
+func (h *Handler) writeCollectionExport(w http.ResponseWriter, r *http.Request, c *collection.Collection, attachment bool) {
+	format, err := collection.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", collection.ContentType(format))
+	if attachment {
+		w.Header().Set("Content-Disposition", `attachment; filename="collection-`+c.ID+`.`+collection.FileExtension(format)+`"`)
+	}
+	w.WriteHeader(http.StatusOK)
+	c.Export(w, format)
+}
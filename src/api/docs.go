@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/docs"
+)
+
+// maxDocsHits bounds the sections a documentation search returns
+const maxDocsHits = 50
+
+// handleDocs handles GET /api/v1/server/docs: the list of documentation
+// pages, or with ?q= the sections matching a search
+func (h *Handler) handleDocs(w http.ResponseWriter, r *http.Request) {
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		h.jsonResponse(w, http.StatusOK, &APIResponse{
+			OK:   true,
+			Data: map[string]any{"query": q, "hits": docs.Search(q, maxDocsHits)},
+			Meta: &APIMeta{Version: APIVersion},
+		})
+		return
+	}
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: map[string]any{"pages": docs.Pages()},
+		Meta: &APIMeta{Version: APIVersion},
+	})
+}
+
+// handleDocsPage handles GET /api/v1/server/docs/{page}: a documentation
+// page as markdown and HTML, with its headings
+func (h *Handler) handleDocsPage(w http.ResponseWriter, r *http.Request) {
+	page, ok := docs.Get(chi.URLParam(r, "page"))
+	if !ok {
+		h.errorResponse(w, http.StatusNotFound, "Documentation page not found", "")
+		return
+	}
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: page,
+		Meta: &APIMeta{Version: APIVersion},
+	})
+}
+
+// handleConfigReference handles GET /api/v1/server/config/reference: every
+// server.yml setting with its type, default, environment variable and
+// description, or with ?format=markdown the same as a markdown page
+func (h *Handler) handleConfigReference(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_ = config.WriteReferenceMarkdown(w)
+		return
+	}
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: map[string]any{"fields": config.Reference()},
+		Meta: &APIMeta{Version: APIVersion},
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/apimgr/search/src/search"
+)
+
+// EngineHealthReport is the health of every enabled engine, engines with
+// an open circuit first
+type EngineHealthReport struct {
+	Healthy   int                `json:"healthy" xml:"healthy"`
+	Degraded  int                `json:"degraded" xml:"degraded"`
+	Unhealthy int                `json:"unhealthy" xml:"unhealthy"`
+	Engines   []EngineHealthInfo `json:"engines" xml:"engines>item"`
+}
+
+// EngineHealthInfo is one engine's entry in an EngineHealthReport
+type EngineHealthInfo struct {
+	ID     string               `json:"id" xml:"id"`
+	Name   string               `json:"name" xml:"name"`
+	Health *search.EngineHealth `json:"health" xml:"health"`
+}
+
+// circuitOrder sorts open circuits before half-open ones before closed
+var circuitOrder = map[string]int{search.CircuitOpen: 0, search.CircuitHalfOpen: 1, search.CircuitClosed: 2}
+
+// handleEngineHealth reports each enabled engine's status, circuit, recent
+// error rate and latency. It is not cached: it is read to see what the
+// engines are doing now.
+func (h *Handler) handleEngineHealth(w http.ResponseWriter, r *http.Request) {
+	report := EngineHealthReport{Engines: []EngineHealthInfo{}}
+	for _, eng := range h.registry.GetEnabled() {
+		health := engineHealth(eng)
+		if health == nil {
+			continue
+		}
+		switch health.Status {
+		case "unhealthy":
+			report.Unhealthy++
+		case "degraded":
+			report.Degraded++
+		default:
+			report.Healthy++
+		}
+		report.Engines = append(report.Engines, EngineHealthInfo{ID: eng.Name(), Name: eng.DisplayName(), Health: health})
+	}
+	sort.Slice(report.Engines, func(i, j int) bool {
+		a, b := report.Engines[i], report.Engines[j]
+		if circuitOrder[a.Health.Circuit] != circuitOrder[b.Health.Circuit] {
+			return circuitOrder[a.Health.Circuit] < circuitOrder[b.Health.Circuit]
+		}
+		return a.ID < b.ID
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.negotiatedResponse(w, r, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: report,
+		Meta: &APIMeta{Version: APIVersion},
+	})
+}
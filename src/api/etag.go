@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// Endpoint groups whose responses carry an ETag and the Cache-Control
+// policy set for the group in server.api_cache
+const (
+	cacheGroupEngines    = config.APICacheEngines
+	cacheGroupCategories = config.APICacheCategories
+	cacheGroupBangs      = config.APICacheBangs
+	cacheGroupInfo       = config.APICacheInfo
+	cacheGroupWidgets    = config.APICacheWidgets
+)
+
+// cacheControl returns the Cache-Control policy of an endpoint group
+func (h *Handler) cacheControl(group string) string {
+	var ac config.APICacheConfig
+	if h.config != nil {
+		ac = h.config.Server.APICache
+	}
+	return ac.Policy(group)
+}
+
+// cacheableResponse sends a 200 JSON response like jsonResponse, with an
+// ETag computed from the body and the group's Cache-Control policy. A
+// request whose If-None-Match names the current ETag gets 304 Not
+// Modified without a body.
+func (h *Handler) cacheableResponse(w http.ResponseWriter, r *http.Request, group string, data interface{}) {
+	header := w.Header()
+	header.Set("Content-Type", jsonContentType)
+	header.Set("X-API-Version", APIVersion)
+
+	e, ok := encodeJSON(data)
+	defer releaseJSONEncoder(e)
+	if !ok {
+		writeEncoded(w, http.StatusInternalServerError, e)
+		return
+	}
+
+	h.serveCacheable(w, r, group, e.buf.Bytes())
+}
+
+// serveCacheable sends an encoded 200 response with its ETag and the
+// group's Cache-Control policy, or 304 to a matching If-None-Match
+func (h *Handler) serveCacheable(w http.ResponseWriter, r *http.Request, group string, body []byte) {
+	header := w.Header()
+	header.Set("ETag", entityTag(body))
+	header.Set("Cache-Control", h.cacheControl(group))
+	// ServeContent answers If-None-Match (and If-Match and Range) for us
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// entityTag returns a strong ETag for a response body: the first 128 bits
+// of its SHA-256, so the same body always has the same tag
+func entityTag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheableResponseETag(t *testing.T) {
+	h := newTestHandler()
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/categories", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.handleCategories(w, r)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.Len() == 0 {
+		t.Fatalf("first request: %d, ETag %q, %d bytes", first.Code, etag, first.Body.Len())
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "public, max-age=86400" {
+		t.Errorf("Cache-Control = %q, want the categories default", cc)
+	}
+	if again := get(""); again.Header().Get("ETag") != etag {
+		t.Errorf("ETag changed between identical responses: %q, %q", etag, again.Header().Get("ETag"))
+	}
+
+	for _, match := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := get(match)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: %d with %d bytes, want 304 without a body", match, w.Code, w.Body.Len())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: 304 without the ETag", match)
+		}
+	}
+	if w := get(`"stale"`); w.Code != http.StatusOK || w.Body.String() != first.Body.String() {
+		t.Errorf("stale ETag: %d, want the full response", w.Code)
+	}
+}
+
+func TestCacheControlPerGroup(t *testing.T) {
+	h := newTestHandler()
+	h.config.Server.APICache.Bangs = "no-cache"
+	if got := h.cacheControl(cacheGroupBangs); got != "no-cache" {
+		t.Errorf("configured bangs policy = %q", got)
+	}
+	if got := h.cacheControl(cacheGroupEngines); got != "public, max-age=60" {
+		t.Errorf("engines policy = %q, want the default", got)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/widgets", nil)
+	w := httptest.NewRecorder()
+	h.handleWidgets(w, r)
+	if cc := w.Header().Get("Cache-Control"); cc != "private, max-age=300" || w.Header().Get("ETag") == "" {
+		t.Errorf("widgets: Cache-Control %q, ETag %q", cc, w.Header().Get("ETag"))
+	}
+}
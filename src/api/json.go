@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// maxPooledJSONBuffer bounds the buffers returned to the pool, so one very
+// large response does not keep its memory for the life of the process
+const maxPooledJSONBuffer = 256 << 10
+
+// jsonEncoder is a response buffer and an indenting encoder that writes to
+// it. The encoder keeps its indentation scratch buffer between uses, so a
+// pooled pair encodes a response without growing new buffers each time.
+type jsonEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// jsonContentType is the Content-Type of JSON responses
+const jsonContentType = "application/json; charset=utf-8"
+
+var jsonEncoders = sync.Pool{
+	New: func() any {
+		e := &jsonEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		// 2-space indentation per AI.md PART 14
+		e.enc.SetIndent("", "  ")
+		return e
+	},
+}
+
+// writeJSON encodes data into a pooled buffer and writes it with its
+// Content-Length in one call. The headers other than Content-Length are
+// the caller's. Data that cannot be encoded answers 500 instead of a
+// truncated body.
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	e, ok := encodeJSON(data)
+	defer releaseJSONEncoder(e)
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeEncoded(w, status, e)
+}
+
+// writeEncoded writes an encoded response with its Content-Length
+func writeEncoded(w http.ResponseWriter, status int, e *jsonEncoder) {
+	w.Header().Set("Content-Length", strconv.Itoa(e.buf.Len()))
+	w.WriteHeader(status)
+	w.Write(e.buf.Bytes())
+}
+
+// encodeJSON encodes data into a pooled encoder, which the caller
+// releases. When data cannot be encoded the encoder holds a 500 error
+// response instead and ok is false.
+func encodeJSON(data any) (e *jsonEncoder, ok bool) {
+	e = jsonEncoders.Get().(*jsonEncoder)
+	if err := e.enc.Encode(data); err != nil {
+		slog.Error("API response encoding failed", "err", err)
+		e.buf.Reset()
+		e.enc.Encode(&APIResponse{
+			OK:      false,
+			Error:   model.ErrorCodeFromHTTP(http.StatusInternalServerError),
+			Message: "Internal server error",
+			Meta:    &APIMeta{Version: APIVersion},
+		})
+		return e, false
+	}
+	return e, true
+}
+
+func releaseJSONEncoder(e *jsonEncoder) {
+	if e.buf.Cap() > maxPooledJSONBuffer {
+		return
+	}
+	e.buf.Reset()
+	jsonEncoders.Put(e)
+}
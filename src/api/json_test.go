@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/search/engine"
+)
+
+// discardResponse is a ResponseWriter that keeps only the status and
+// body length, so benchmarks measure encoding rather than recording
+type discardResponse struct {
+	header http.Header
+	status int
+	n      int
+}
+
+func (d *discardResponse) Header() http.Header { return d.header }
+func (d *discardResponse) WriteHeader(status int) {
+	d.status = status
+}
+func (d *discardResponse) Write(p []byte) (int, error) {
+	d.n += len(p)
+	return len(p), nil
+}
+
+func (d *discardResponse) reset() {
+	clear(d.header)
+	d.status, d.n = 0, 0
+}
+
+// searchPayload is a search response as handleSearch builds it
+func searchPayload(results int) *APIResponse {
+	page := make([]SearchResult, results)
+	for i := range page {
+		url := fmt.Sprintf("https://www.example%d.org/articles/golang-generics-%d", i%7, i)
+		page[i] = SearchResult{
+			Title:       fmt.Sprintf("Golang generics explained, part %d", i),
+			URL:         url,
+			Description: "Type parameters let functions and types work with any type that satisfies a constraint, without giving up compile-time checks.",
+			Engine:      "duckduckgo",
+			Score:       float64(100-i) / 3,
+			Category:    "general",
+			Domain:      extractDomain(url),
+		}
+	}
+	return &APIResponse{
+		OK: true,
+		Data: SearchResponse{
+			Query:      "golang generics",
+			Category:   "general",
+			Results:    page,
+			Pagination: Pagination{Page: 1, Limit: results, Total: 120, Pages: 6},
+			SearchTime: 412.5,
+			Engines:    []string{"duckduckgo", "bing", "brave"},
+		},
+		Meta: &APIMeta{Version: APIVersion, ProcessTime: 413.1},
+	}
+}
+
+// autocompletePayload is an autocomplete response
+func autocompletePayload() *APIResponse {
+	return &APIResponse{
+		OK:   true,
+		Data: []string{"golang", "golang generics", "golang tutorial", "golang vs rust", "golang jobs", "golang http server", "golang testing", "golang channels"},
+		Meta: &APIMeta{Version: APIVersion},
+	}
+}
+
+// encodeUnpooled is jsonResponse as it was before pooling, the baseline
+// the benchmarks compare against
+func encodeUnpooled(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-API-Version", APIVersion)
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(data)
+}
+
+func TestJSONResponseMatchesEncoder(t *testing.T) {
+	h := newTestHandler()
+	for name, payload := range map[string]*APIResponse{
+		"search":       searchPayload(20),
+		"autocomplete": autocompletePayload(),
+	} {
+		want := httptest.NewRecorder()
+		encodeUnpooled(want, http.StatusOK, payload)
+
+		// Twice, so the second response comes from a reused buffer
+		for i := 0; i < 2; i++ {
+			got := httptest.NewRecorder()
+			h.jsonResponse(got, http.StatusOK, payload)
+			if !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+				t.Fatalf("%s: pooled body differs from the encoder's:\n%s\nwant:\n%s", name, got.Body, want.Body)
+			}
+			if cl := got.Header().Get("Content-Length"); cl != strconv.Itoa(want.Body.Len()) {
+				t.Errorf("%s: Content-Length = %s, want %d", name, cl, want.Body.Len())
+			}
+			if got.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+				t.Errorf("%s: Content-Type = %q", name, got.Header().Get("Content-Type"))
+			}
+		}
+	}
+}
+
+func TestJSONResponseHeadersNotShared(t *testing.T) {
+	h := newTestHandler()
+	first := httptest.NewRecorder()
+	h.jsonResponse(first, http.StatusOK, autocompletePayload())
+	first.Header()["Content-Type"][0] = "text/html"
+	first.Header()["X-Api-Version"][0] = "changed"
+
+	second := httptest.NewRecorder()
+	h.jsonResponse(second, http.StatusOK, autocompletePayload())
+	if got := second.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q after another response's header changed", got)
+	}
+	if got := second.Header().Get("X-API-Version"); got != APIVersion {
+		t.Errorf("X-API-Version = %q after another response's header changed", got)
+	}
+}
+
+func TestJSONResponseEncodingError(t *testing.T) {
+	h := newTestHandler()
+	w := httptest.NewRecorder()
+	h.jsonResponse(w, http.StatusOK, &APIResponse{OK: true, Data: math.Inf(1)})
+
+	var response APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, w.Body)
+	}
+	if w.Code != http.StatusInternalServerError || response.OK {
+		t.Errorf("status %d, ok %v; want a 500 error instead of a truncated body", w.Code, response.OK)
+	}
+}
+
+func benchmarkEncode(b *testing.B, payload *APIResponse, encode func(http.ResponseWriter, int, interface{})) {
+	w := &discardResponse{header: make(http.Header)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.reset()
+		encode(w, http.StatusOK, payload)
+	}
+	b.SetBytes(int64(w.n))
+}
+
+func BenchmarkSearchResponse(b *testing.B) {
+	h := newTestHandler()
+	benchmarkEncode(b, searchPayload(20), h.jsonResponse)
+}
+
+func BenchmarkSearchResponseUnpooled(b *testing.B) {
+	benchmarkEncode(b, searchPayload(20), encodeUnpooled)
+}
+
+func BenchmarkAutocompleteResponse(b *testing.B) {
+	h := newTestHandler()
+	benchmarkEncode(b, autocompletePayload(), h.jsonResponse)
+}
+
+func BenchmarkAutocompleteResponseUnpooled(b *testing.B) {
+	benchmarkEncode(b, autocompletePayload(), encodeUnpooled)
+}
+
+// BenchmarkHandleSearch runs the whole search handler against synthetic
+// engines, answered from the result cache after the first search
+func BenchmarkHandleSearch(b *testing.B) {
+	registry := engine.SyntheticRegistry(0)
+	aggregator := search.NewAggregator(registry.GetEnabled(), search.AggregatorConfig{
+		Timeout:      5 * time.Second,
+		CacheEnabled: true,
+	})
+	h := NewHandler(&config.Config{}, registry, aggregator)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=golang+generics", nil)
+	w := &discardResponse{header: make(http.Header)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.reset()
+		h.handleSearch(w, r)
+		if w.status != http.StatusOK {
+			b.Fatalf("status %d", w.status)
+		}
+	}
+}
+
+// BenchmarkHandleAutocomplete runs the autocomplete handler with local
+// history suggestions
+func BenchmarkHandleAutocomplete(b *testing.B) {
+	h := newTestHandler()
+	history := search.NewSuggestionHistory()
+	history.Add([]string{"golang generics", "golang tutorial", "gopher", "google"})
+	h.SetSuggester(search.NewSuggester(nil, history, 1))
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/autocomplete?q=go", nil)
+	w := &discardResponse{header: make(http.Header)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.reset()
+		h.handleAutocomplete(w, r)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/apimgr/search/src/search/macro"
+)
+
+// MacroInfo is a search macro in the macros listing
+type MacroInfo struct {
+	Name        string   `json:"name"`
+	Usage       string   `json:"usage"`
+	Query       string   `json:"query"`
+	Params      []string `json:"params"`
+	Description string   `json:"description,omitempty"`
+}
+
+// instanceMacros returns the macros set in server.yml
+func (h *Handler) instanceMacros() []macro.Macro {
+	macros := make([]macro.Macro, 0, len(h.config.Search.Macros))
+	for _, mc := range h.config.Search.Macros {
+		macros = append(macros, macro.Macro{Name: mc.Name, Query: mc.Query, Description: mc.Description})
+	}
+	return macros
+}
+
+// macrosFromCookie returns the macros the preferences page saved in the
+// caller's cookie. Clients without it get the instance macros only.
+func macrosFromCookie(r *http.Request) []macro.Macro {
+	c, err := r.Cookie(macro.CookieName)
+	if err != nil {
+		return nil
+	}
+	return macro.DecodeCookie(c.Value)
+}
+
+// handleMacros lists the instance-wide search macros, which any search
+// may start with as ~name
+func (h *Handler) handleMacros(w http.ResponseWriter, r *http.Request) {
+	macros := make([]MacroInfo, 0, len(h.config.Search.Macros))
+	for _, m := range h.instanceMacros() {
+		params := m.Params()
+		if params == nil {
+			params = []string{}
+		}
+		macros = append(macros, MacroInfo{Name: m.Name, Usage: m.Usage(), Query: m.Query, Params: params, Description: m.Description})
+	}
+
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK: true,
+		Data: map[string]interface{}{
+			"macros": macros,
+			"total":  len(macros),
+		},
+		Meta: &APIMeta{Version: APIVersion},
+	})
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/apimgr/search/src/permalink"
+	"github.com/go-chi/chi/v5"
+)
+
+type permalinkCreateRequest struct {
+	Query      string `json:"query"`
+	Category   string `json:"category"`
+	SafeSearch string `json:"safe_search"`
+	TimeRange  string `json:"time_range"`
+	PerPage    int    `json:"per_page"`
+}
+
+// permalinkStore returns the store, or nil when permalinks are off
+func (h *Handler) permalinkStore() *permalink.Store {
+	if h.permalinks == nil || !h.config.Search.Permalinks.Enabled {
+		return nil
+	}
+	return h.permalinks
+}
+
+// handleCreatePermalink handles POST /api/v1/permalinks (anonymous — rate limited by middleware).
+func (h *Handler) handleCreatePermalink(w http.ResponseWriter, r *http.Request) {
+	store := h.permalinkStore()
+	if store == nil {
+		h.writeError(w, "NOT_AVAILABLE", "Permalinks are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var req permalinkCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	link, err := store.Create(r.Context(), permalink.State{
+		Query:      req.Query,
+		Category:   req.Category,
+		SafeSearch: req.SafeSearch,
+		TimeRange:  req.TimeRange,
+		PerPage:    req.PerPage,
+	}, permalink.ParseTTL(h.config.Search.Permalinks.TTL))
+	if errors.Is(err, permalink.ErrInvalidState) {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.writeError(w, "INTERNAL_ERROR", "Failed to create permalink", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, APIResponse{
+		OK: true,
+		Data: map[string]interface{}{
+			"token":      link.Token,
+			"url":        baseURLFromRequest(h, r) + "/s/" + link.Token,
+			"expires_at": link.ExpiresAt,
+		},
+	})
+}
+
+// handleGetPermalink handles GET /api/v1/permalinks/{token}; the token is
+// the credential, as with alert manage links.
+func (h *Handler) handleGetPermalink(w http.ResponseWriter, r *http.Request) {
+	store := h.permalinkStore()
+	if store == nil {
+		h.writeError(w, "NOT_AVAILABLE", "Permalinks are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	state, expiresAt, err := store.Get(r.Context(), chi.URLParam(r, "token"))
+	if errors.Is(err, permalink.ErrNotFound) {
+		h.writeError(w, "NOT_FOUND", "Permalink not found or expired", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, "INTERNAL_ERROR", "Failed to load permalink", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{
+		OK: true,
+		Data: map[string]interface{}{
+			"search":     state,
+			"expires_at": expiresAt,
+		},
+	})
+}
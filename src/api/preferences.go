@@ -0,0 +1,309 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/apimgr/search/src/preferences"
+	"github.com/go-chi/chi/v5"
+)
+
+// preferenceCookieMaxAge keeps a browser's profile token for a year
+const preferenceCookieMaxAge = 365 * 24 * 60 * 60
+
+// preferenceFields are the preferences a profile stores
+var preferenceFields = []string{"safe_search", "language", "engines", "results_per_page", "theme"}
+
+type preferenceTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type preferenceAccountRequest struct {
+	Name string `json:"name"`
+}
+
+// preferenceStore returns the store, or nil when server-side preferences
+// are off
+func (h *Handler) preferenceStore() *preferences.Store {
+	if h.preferences == nil || !h.config.Search.Preferences.Enabled {
+		return nil
+	}
+	return h.preferences
+}
+
+// withPreferences rejects requests while server-side preferences are
+// unavailable
+func (h *Handler) withPreferences(next func(http.ResponseWriter, *http.Request, *preferences.Store)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store := h.preferenceStore()
+		if store == nil {
+			h.writeError(w, "NOT_AVAILABLE", "Server-side preferences are unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r, store)
+	}
+}
+
+// writePreferenceError maps store errors to API errors
+func (h *Handler) writePreferenceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, preferences.ErrNotFound):
+		h.writeError(w, "NOT_FOUND", "Preferences profile not found", http.StatusNotFound)
+	case errors.Is(err, preferences.ErrInvalidInput):
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+	default:
+		h.writeError(w, "INTERNAL_ERROR", "Preferences request failed", http.StatusInternalServerError)
+	}
+}
+
+// setPreferenceCookie keeps a profile token in the browser; an empty token
+// clears it
+func (h *Handler) setPreferenceCookie(w http.ResponseWriter, r *http.Request, token string) {
+	maxAge := preferenceCookieMaxAge
+	if token == "" {
+		maxAge = -1
+	}
+	cookie := &http.Cookie{
+		Name:     preferences.CookieName,
+		Value:    token,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+	}
+	h.config.Server.Security.Cookies.Session.Apply(cookie, http.SameSiteLaxMode, h.config.Server.RequestTLS(r))
+	http.SetCookie(w, cookie)
+}
+
+// requestProfile returns the stored profile a request's token names, or
+// nil when it carries none or the profile is gone
+func (h *Handler) requestProfile(r *http.Request) *preferences.Profile {
+	store := h.preferenceStore()
+	if store == nil {
+		return nil
+	}
+	token := preferences.TokenFromRequest(r)
+	if token == "" {
+		return nil
+	}
+	profile, err := store.Get(r.Context(), token)
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// handlePreferences handles GET|PUT|DELETE /api/v1/preferences. Without
+// server-side storage it answers as it always has: GET describes the
+// preferences the browser keeps and POST acknowledges a client-side save.
+func (h *Handler) handlePreferences(w http.ResponseWriter, r *http.Request) {
+	store := h.preferenceStore()
+	if store == nil {
+		h.handleClientSidePreferences(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.handleGetPreferences(w, r, store)
+	case http.MethodPut, http.MethodPost:
+		h.handleSavePreferences(w, r, store)
+	case http.MethodDelete:
+		h.handleDeletePreferences(w, r, store)
+	default:
+		h.errorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", r.Method)
+	}
+}
+
+// handleClientSidePreferences handles GET|POST /api/v1/preferences per
+// AI.md PART 1 while preferences are stored client-side (localStorage or
+// cookies): GET provides the schema and POST acknowledges a save
+func (h *Handler) handleClientSidePreferences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.jsonResponse(w, http.StatusOK, &APIResponse{
+			OK: true,
+			Data: map[string]interface{}{
+				"storage": "client-side",
+				"fields": []string{
+					"theme", "language", "safe_search", "per_page",
+					"default_category", "engines",
+				},
+			},
+		})
+	case http.MethodPost:
+		h.jsonResponse(w, http.StatusOK, &APIResponse{
+			OK:   true,
+			Data: map[string]string{"status": "saved"},
+		})
+	default:
+		h.errorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", r.Method)
+	}
+}
+
+// preferencesData is the data of a preferences response
+func preferencesData(profile *preferences.Profile) map[string]interface{} {
+	data := map[string]interface{}{
+		"storage":     "server",
+		"fields":      preferenceFields,
+		"stored":      profile != nil,
+		"preferences": preferences.Preferences{},
+	}
+	if profile != nil {
+		data["preferences"] = profile.Preferences
+		data["updated_at"] = profile.UpdatedAt
+		if profile.IsAccount() {
+			data["account"] = profile.Name
+		}
+	}
+	return data
+}
+
+// handleGetPreferences handles GET /api/v1/preferences: the caller's stored
+// preferences, empty when it has none
+func (h *Handler) handleGetPreferences(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: preferencesData(h.requestProfile(r))})
+}
+
+// handleSavePreferences handles PUT /api/v1/preferences. The first save
+// creates an anonymous profile and sets its cookie; later saves replace
+// the preferences of the profile the caller's token names.
+func (h *Handler) handleSavePreferences(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	var prefs preferences.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	prefs, err := preferences.Normalize(prefs)
+	if err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	for _, name := range prefs.Engines {
+		if _, err := h.registry.Get(name); err != nil {
+			h.writeError(w, "BAD_REQUEST", "Unknown engine: "+name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if h.requestProfile(r) != nil {
+		profile, err := store.Update(r.Context(), preferences.TokenFromRequest(r), prefs)
+		if err != nil {
+			h.writePreferenceError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: preferencesData(profile)})
+		return
+	}
+
+	profile, token, err := store.Create(r.Context(), prefs)
+	if err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	h.setPreferenceCookie(w, r, token)
+	data := preferencesData(profile)
+	data["token"] = token
+	h.writeJSON(w, http.StatusCreated, APIResponse{OK: true, Data: data})
+}
+
+// handleDeletePreferences handles DELETE /api/v1/preferences. An anonymous
+// profile is removed; an account stays, with its preferences cleared.
+func (h *Handler) handleDeletePreferences(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	profile := h.requestProfile(r)
+	if profile == nil {
+		h.writePreferenceError(w, preferences.ErrNotFound)
+		return
+	}
+	token := preferences.TokenFromRequest(r)
+	var err error
+	if profile.IsAccount() {
+		_, err = store.Update(r.Context(), token, preferences.Preferences{})
+	} else {
+		err = store.Delete(r.Context(), token)
+	}
+	if err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	h.setPreferenceCookie(w, r, "")
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: map[string]interface{}{"deleted": true}})
+}
+
+// handleUsePreferenceToken handles POST /api/v1/preferences/token, which
+// keeps an existing token, such as an account's, in the browser's cookie
+func (h *Handler) handleUsePreferenceToken(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	var req preferenceTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	profile, err := store.Get(r.Context(), req.Token)
+	if err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	h.setPreferenceCookie(w, r, req.Token)
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: preferencesData(profile)})
+}
+
+// handlePreferenceAccounts handles GET /api/v1/server/preferences/accounts
+// (operator token required)
+func (h *Handler) handlePreferenceAccounts(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	accounts, err := store.Accounts(r.Context())
+	if err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: map[string]interface{}{
+		"accounts": accounts,
+		"total":    len(accounts),
+	}})
+}
+
+// handleCreatePreferenceAccount handles POST
+// /api/v1/server/preferences/accounts (operator token required). The
+// token in the response is shown only once.
+func (h *Handler) handleCreatePreferenceAccount(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	var req preferenceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "BAD_REQUEST", "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	account, token, err := store.CreateAccount(r.Context(), req.Name)
+	if err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, APIResponse{OK: true, Data: map[string]interface{}{
+		"account": account,
+		"token":   token,
+	}})
+}
+
+// handleDeletePreferenceAccount handles DELETE
+// /api/v1/server/preferences/accounts/{id} (operator token required)
+func (h *Handler) handleDeletePreferenceAccount(w http.ResponseWriter, r *http.Request, store *preferences.Store) {
+	if err := store.DeleteAccount(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.writePreferenceError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, APIResponse{OK: true, Data: map[string]interface{}{"deleted": true}})
+}
+
+// applyStoredPreferences fills what a search request leaves unset from the
+// caller's stored profile: the explicit parameters win
+func applyStoredPreferences(req *SearchRequest, profile *preferences.Profile) {
+	if profile == nil {
+		return
+	}
+	prefs := profile.Preferences
+	if req.SafeSearch == "" && prefs.SafeSearch != nil {
+		req.SafeSearch = strconv.Itoa(*prefs.SafeSearch)
+	}
+	if req.Limit == 0 && prefs.ResultsPerPage > 0 {
+		req.Limit = prefs.ResultsPerPage
+	}
+	if req.Language == "" {
+		req.Language = prefs.Language
+	}
+}
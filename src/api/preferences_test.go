@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/database/dbtest"
+	"github.com/apimgr/search/src/preferences"
+	"github.com/go-chi/chi/v5"
+)
+
+func newPreferencesTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	handler := newTestHandler()
+	handler.config.Search.Preferences.Enabled = true
+	handler.config.Server.Token = "secret"
+	handler.registry.Register(newAlertTestEngine("google", "general"))
+	handler.registry.Register(newAlertTestEngine("bing", "general"))
+	handler.SetPreferenceStore(preferences.NewStore(dbtest.Users(t), ""))
+	return handler
+}
+
+func decodePreferencesResponse(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var resp APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("data = %#v, want an object", resp.Data)
+	}
+	return data
+}
+
+func TestPreferencesAPI(t *testing.T) {
+	handler := newPreferencesTestHandler(t)
+
+	w := httptest.NewRecorder()
+	handler.handlePreferences(w, httptest.NewRequest(http.MethodGet, "/api/v1/preferences", nil))
+	if data := decodePreferencesResponse(t, w); data["storage"] != "server" || data["stored"] != false {
+		t.Fatalf("GET without a profile = %v", data)
+	}
+
+	w = httptest.NewRecorder()
+	handler.handlePreferences(w, httptest.NewRequest(http.MethodPut, "/api/v1/preferences", strings.NewReader(`{"engines":["nope"]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("PUT with an unknown engine: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	body := `{"safe_search":0,"language":"de","engines":["Google"],"results_per_page":50,"theme":"dark"}`
+	w = httptest.NewRecorder()
+	handler.handlePreferences(w, httptest.NewRequest(http.MethodPut, "/api/v1/preferences", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("first PUT: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != preferences.CookieName || !cookies[0].HttpOnly {
+		t.Fatalf("first PUT cookies = %v", cookies)
+	}
+	token := cookies[0].Value
+	if data := decodePreferencesResponse(t, w); data["token"] != token {
+		t.Errorf("token = %v, want the cookie's %q", data["token"], token)
+	}
+
+	// The stored profile fills in what a search leaves out
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=test", nil)
+	req.AddCookie(&http.Cookie{Name: preferences.CookieName, Value: token})
+	searchReq, query, ok := handler.parseSearchRequest(httptest.NewRecorder(), req)
+	if !ok {
+		t.Fatal("parseSearchRequest() failed")
+	}
+	if query.SafeSearch != 0 || query.PerPage != 50 || query.Language != "de" || searchReq.Limit != 50 {
+		t.Errorf("query = safe %d, per page %d, language %q", query.SafeSearch, query.PerPage, query.Language)
+	}
+	if len(query.EnabledEngines) != 1 || query.EnabledEngines[0] != "google" {
+		t.Errorf("EnabledEngines = %v, want [google]", query.EnabledEngines)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/search?q=test&limit=10&safe_search=2&lang=fr", nil)
+	req.Header.Set(preferences.HeaderName, token)
+	if _, query, _ = handler.parseSearchRequest(httptest.NewRecorder(), req); query.SafeSearch != 2 || query.PerPage != 10 || query.Language != "fr" {
+		t.Errorf("explicit parameters lost to the profile: safe %d, per page %d, language %q", query.SafeSearch, query.PerPage, query.Language)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/preferences", strings.NewReader(`{"theme":"light"}`))
+	req.Header.Set(preferences.HeaderName, token)
+	w = httptest.NewRecorder()
+	handler.handlePreferences(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second PUT: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	prefs := decodePreferencesResponse(t, w)["preferences"].(map[string]interface{})
+	if prefs["theme"] != "light" || prefs["language"] != nil {
+		t.Errorf("second PUT preferences = %v, want only the light theme", prefs)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/preferences", nil)
+	req.AddCookie(&http.Cookie{Name: preferences.CookieName, Value: token})
+	w = httptest.NewRecorder()
+	handler.handlePreferences(w, req)
+	if w.Code != http.StatusOK || w.Result().Cookies()[0].MaxAge >= 0 {
+		t.Fatalf("DELETE: status = %d, cookies %v", w.Code, w.Result().Cookies())
+	}
+	if handler.requestProfile(req) != nil {
+		t.Error("profile still stored after DELETE")
+	}
+}
+
+func TestPreferenceAccountsAPI(t *testing.T) {
+	handler := newPreferencesTestHandler(t)
+	mux := chi.NewRouter()
+	handler.RegisterRoutes(mux)
+
+	serve := func(method, path, body string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/api/v1/server/preferences/accounts", `{"name":"kiosk"}`, false); w.Code != http.StatusUnauthorized {
+		t.Fatalf("create without the operator token: status = %d", w.Code)
+	}
+	w := serve(http.MethodPost, "/api/v1/server/preferences/accounts", `{"name":"kiosk"}`, true)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body.String())
+	}
+	data := decodePreferencesResponse(t, w)
+	token, _ := data["token"].(string)
+	id, _ := data["account"].(map[string]interface{})["id"].(string)
+	if token == "" || id == "" {
+		t.Fatalf("create = %v", data)
+	}
+
+	// The account's token is kept in the browser like an anonymous one
+	w = serve(http.MethodPost, "/api/v1/preferences/token", `{"token":"`+token+`"}`, false)
+	if w.Code != http.StatusOK || len(w.Result().Cookies()) != 1 || w.Result().Cookies()[0].Value != token {
+		t.Fatalf("use token: status = %d, cookies %v", w.Code, w.Result().Cookies())
+	}
+	if data := decodePreferencesResponse(t, w); data["account"] != "kiosk" {
+		t.Errorf("use token account = %v, want kiosk", data["account"])
+	}
+	if w := serve(http.MethodPost, "/api/v1/preferences/token", `{"token":"wrong"}`, false); w.Code != http.StatusNotFound {
+		t.Errorf("use an unknown token: status = %d", w.Code)
+	}
+
+	w = serve(http.MethodGet, "/api/v1/server/preferences/accounts", "", true)
+	if data := decodePreferencesResponse(t, w); data["total"] != float64(1) {
+		t.Errorf("list = %v, want one account", data)
+	}
+	if w := serve(http.MethodDelete, "/api/v1/server/preferences/accounts/"+id, "", true); w.Code != http.StatusOK {
+		t.Errorf("delete: status = %d", w.Code)
+	}
+	if w := serve(http.MethodDelete, "/api/v1/server/preferences/accounts/"+id, "", true); w.Code != http.StatusNotFound {
+		t.Errorf("delete twice: status = %d", w.Code)
+	}
+
+	handler.config.Search.Preferences.Enabled = false
+	if w := serve(http.MethodGet, "/api/v1/server/preferences/accounts", "", true); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("list while disabled: status = %d", w.Code)
+	}
+}
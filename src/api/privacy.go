@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/common/httputil"
+)
+
+// PrivacyReport is what the instance does with the request that asked for
+// it, under the current config and the request's privacy signals
+type PrivacyReport struct {
+	Signals     PrivacySignalsReport `json:"signals"`
+	AccessLog   AccessLogReport      `json:"access_log"`
+	GeoBoost    GeoBoostReport       `json:"geo_boost"`
+	ResultCache ResultCacheReport    `json:"result_cache"`
+	Engines     EnginesReport        `json:"engines"`
+	Permalinks  PermalinksReport     `json:"permalinks"`
+}
+
+// PrivacySignalsReport is the request's Do Not Track and Global Privacy
+// Control signals; either one is honored
+type PrivacySignalsReport struct {
+	httputil.PrivacySignals
+	Honored bool `json:"honored"`
+}
+
+// AccessLogReport is what the access log keeps of the request
+type AccessLogReport struct {
+	Enabled bool `json:"enabled"`
+	// Privacy is the preset the request is logged under: strict, or
+	// anonymized unless the request opted out of tracking
+	Privacy       string `json:"privacy,omitempty"`
+	ClientAddress string `json:"client_address"`
+	UserAgent     string `json:"user_agent"`
+	Query         string `json:"query"`
+}
+
+// GeoBoostReport is whether a search from the request ranks results from
+// the client's country higher
+type GeoBoostReport struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+// ResultCacheReport is how long a search's results are kept for the same
+// search by anyone
+type ResultCacheReport struct {
+	Enabled bool   `json:"enabled"`
+	TTL     string `json:"ttl,omitempty"`
+	Store   string `json:"store,omitempty"`
+}
+
+// EnginesReport is what upstream search engines receive
+type EnginesReport struct {
+	ClientAddress string `json:"client_address"`
+	UserAgent     string `json:"user_agent"`
+	Tor           bool   `json:"tor"`
+}
+
+// PermalinksReport is whether a search can be kept as a shareable link
+type PermalinksReport struct {
+	Enabled bool   `json:"enabled"`
+	TTL     string `json:"ttl,omitempty"`
+}
+
+// handlePrivacy handles GET /api/v1/privacy: what the instance does with
+// this very request. The answer depends on the request's DNT and Sec-GPC
+// headers, so it is never cached.
+func (h *Handler) handlePrivacy(w http.ResponseWriter, r *http.Request) {
+	signals := httputil.RequestPrivacySignals(r)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Add("Vary", "DNT, Sec-GPC")
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK:   true,
+		Data: h.privacyReport(signals),
+		Meta: &APIMeta{Version: APIVersion},
+	})
+}
+
+// privacyReport describes the handling of a request with signals
+func (h *Handler) privacyReport(signals httputil.PrivacySignals) PrivacyReport {
+	cfg := h.config
+	report := PrivacyReport{
+		Signals: PrivacySignalsReport{PrivacySignals: signals, Honored: signals.OptOut()},
+		AccessLog: AccessLogReport{
+			Enabled:       !cfg.Server.Logs.Access.Disabled,
+			ClientAddress: "not recorded",
+			UserAgent:     "not recorded",
+			Query:         "never recorded",
+		},
+		Engines: EnginesReport{
+			ClientAddress: "never sent; engines see this instance's address",
+			UserAgent:     "this instance's, never the client's",
+			Tor:           cfg.Server.Tor.UseNetwork,
+		},
+		Permalinks: PermalinksReport{Enabled: cfg.Search.Permalinks.Enabled},
+	}
+
+	if report.AccessLog.Enabled {
+		report.AccessLog.Privacy = "strict"
+		if cfg.Server.Logs.Access.Privacy == "anonymized" && !signals.OptOut() {
+			report.AccessLog.Privacy = "anonymized"
+			report.AccessLog.ClientAddress = "network only (/24 for IPv4, /48 for IPv6)"
+			report.AccessLog.UserAgent = "salted hash; the salt changes on every restart"
+		}
+	}
+
+	geo := cfg.Search.GeoBoost
+	switch {
+	case !geo.Enabled:
+		report.GeoBoost.Reason = "off on this instance"
+	case signals.OptOut():
+		report.GeoBoost.Reason = "off for browsers sending " + signals.String()
+	case h.geoipLookup == nil || !h.geoipLookup.IsLoaded():
+		report.GeoBoost.Reason = "off until the GeoIP database is loaded"
+	default:
+		report.GeoBoost.Enabled = true
+		report.GeoBoost.Reason = "the country of the client address ranks local results higher; it is not stored or sent to engines, and localize=0 turns it off"
+	}
+
+	if !cfg.Search.ResultCache.Disabled {
+		report.ResultCache = ResultCacheReport{Enabled: true, TTL: cfg.Search.ResultCache.TTL, Store: "memory"}
+		if report.ResultCache.TTL == "" {
+			report.ResultCache.TTL = "5m"
+		}
+		if t := strings.ToLower(cfg.Server.Cache.Type); t == "redis" || t == "valkey" {
+			report.ResultCache.Store = t
+		}
+	}
+	if report.Permalinks.Enabled {
+		report.Permalinks.TTL = cfg.Search.Permalinks.TTL
+		if report.Permalinks.TTL == "" {
+			report.Permalinks.TTL = "7d"
+		}
+	}
+	return report
+}
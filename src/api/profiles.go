@@ -0,0 +1,334 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Response profiles for clients built around a hypermedia convention. The
+// search, engines and categories endpoints answer as a JSON:API document to
+// profile=jsonapi or an Accept header naming application/vnd.api+json, and
+// as HAL to profile=hal or application/hal+json. Both carry the same fields
+// as the plain JSON, plus links: search results link to the other pages
+// and to the engine that found them. Profiles are JSON only; format=xml
+// still answers in XML.
+
+const (
+	profileJSONAPI = "jsonapi"
+	profileHAL     = "hal"
+)
+
+const (
+	jsonAPIContentType = "application/vnd.api+json"
+	halContentType     = "application/hal+json"
+)
+
+// responseProfile returns the profile the request asks for, or "" for the
+// plain JSON envelope
+func responseProfile(r *http.Request) string {
+	if profile := r.URL.Query().Get("profile"); profile != "" {
+		if profile == profileJSONAPI || profile == profileHAL {
+			return profile
+		}
+		return ""
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/vnd.api+json"):
+		return profileJSONAPI
+	case strings.Contains(accept, "application/hal+json"):
+		return profileHAL
+	}
+	return ""
+}
+
+// jsonAPIDocument is a JSON:API top-level document
+type jsonAPIDocument struct {
+	JSONAPI map[string]string `json:"jsonapi"`
+	Data    any               `json:"data,omitempty"`
+	Errors  []jsonAPIError    `json:"errors,omitempty"`
+	Meta    map[string]any    `json:"meta,omitempty"`
+	Links   map[string]any    `json:"links,omitempty"`
+}
+
+// jsonAPIResource is a JSON:API resource object
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]any                 `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+type jsonAPIRelationship struct {
+	Data  any               `json:"data"`
+	Links map[string]string `json:"links,omitempty"`
+}
+
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type jsonAPIError struct {
+	Status string `json:"status"`
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+}
+
+// halLink is a HAL link object
+type halLink struct {
+	Href      string `json:"href"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+// profiled returns the response body and content type for the profile
+// the request asks for. It reports false for plain JSON.
+func profiled(r *http.Request, status int, resp *APIResponse) (any, string, bool) {
+	profile := responseProfile(r)
+	if profile == "" {
+		return nil, "", false
+	}
+	body, ok := profiledBody(r, profile, status, resp)
+	if !ok {
+		return nil, "", false
+	}
+	if profile == profileJSONAPI {
+		return body, jsonAPIContentType, true
+	}
+	return body, halContentType, true
+}
+
+// profiledBody returns the response in the profile's format. It reports
+// false for responses the profile does not cover, which are sent as plain
+// JSON.
+func profiledBody(r *http.Request, profile string, status int, resp *APIResponse) (any, bool) {
+	if profile == profileJSONAPI {
+		return jsonAPIBody(r, status, resp)
+	}
+	if !resp.OK {
+		return nil, false
+	}
+	return halBody(r, resp)
+}
+
+func jsonAPIBody(r *http.Request, status int, resp *APIResponse) (any, bool) {
+	doc := jsonAPIDocument{JSONAPI: map[string]string{"version": "1.1"}, Meta: map[string]any{}}
+	if resp.Meta != nil {
+		doc.Meta["version"] = resp.Meta.Version
+		if resp.Meta.RequestID != "" {
+			doc.Meta["request_id"] = resp.Meta.RequestID
+		}
+	}
+	if !resp.OK {
+		doc.Errors = []jsonAPIError{{Status: strconv.Itoa(status), Code: resp.Error, Title: resp.Message}}
+		return doc, true
+	}
+
+	switch data := resp.Data.(type) {
+	case SearchResponse:
+		resources := make([]jsonAPIResource, 0, len(data.Results))
+		for _, result := range data.Results {
+			resources = append(resources, jsonAPIResource{
+				Type: "results",
+				ID:   result.URL,
+				// Attributes and relationships share one namespace
+				Attributes: fields(result, "engine", "category"),
+				Relationships: map[string]jsonAPIRelationship{
+					"engine": {
+						Data:  jsonAPIIdentifier{Type: "engines", ID: result.Engine},
+						Links: map[string]string{"related": engineLink(result.Engine)},
+					},
+					"category": {Data: jsonAPIIdentifier{Type: "categories", ID: result.Category}},
+				},
+			})
+		}
+		doc.Data = resources
+		for k, v := range fields(data, "results") {
+			doc.Meta[k] = v
+		}
+		doc.Links = map[string]any{}
+		for rel, href := range pageLinks(r, data) {
+			doc.Links[rel] = href
+		}
+		for _, rel := range []string{"prev", "next"} {
+			if _, ok := doc.Links[rel]; !ok {
+				doc.Links[rel] = nil
+			}
+		}
+	case []EngineInfo:
+		resources := make([]jsonAPIResource, 0, len(data))
+		for _, engine := range data {
+			resources = append(resources, engineResource(engine))
+		}
+		doc.Data = resources
+		doc.Links = map[string]any{"self": APIPrefix + "/engines"}
+	case EngineInfo:
+		doc.Data = engineResource(data)
+		doc.Links = map[string]any{"self": engineLink(data.ID)}
+	case []CategoryInfo:
+		resources := make([]jsonAPIResource, 0, len(data))
+		for _, category := range data {
+			resources = append(resources, jsonAPIResource{
+				Type:       "categories",
+				ID:         category.ID,
+				Attributes: fields(category, "id"),
+				Links:      map[string]string{"search": categorySearchLink(category.ID)},
+			})
+		}
+		doc.Data = resources
+		doc.Links = map[string]any{"self": APIPrefix + "/categories"}
+	case EngineHealthReport:
+		resources := make([]jsonAPIResource, 0, len(data.Engines))
+		for _, engine := range data.Engines {
+			resources = append(resources, jsonAPIResource{
+				Type:       "engines",
+				ID:         engine.ID,
+				Attributes: fields(engine, "id"),
+				Links:      map[string]string{"self": engineLink(engine.ID)},
+			})
+		}
+		doc.Data = resources
+		for k, v := range fields(data, "engines") {
+			doc.Meta[k] = v
+		}
+		doc.Links = map[string]any{"self": APIPrefix + "/engines/health"}
+	default:
+		return nil, false
+	}
+	return doc, true
+}
+
+func engineResource(engine EngineInfo) jsonAPIResource {
+	categories := make([]jsonAPIIdentifier, 0, len(engine.Categories))
+	for _, category := range engine.Categories {
+		categories = append(categories, jsonAPIIdentifier{Type: "categories", ID: category})
+	}
+	return jsonAPIResource{
+		Type:          "engines",
+		ID:            engine.ID,
+		Attributes:    fields(engine, "id", "categories"),
+		Relationships: map[string]jsonAPIRelationship{"categories": {Data: categories}},
+		Links:         map[string]string{"self": engineLink(engine.ID)},
+	}
+}
+
+func halBody(r *http.Request, resp *APIResponse) (any, bool) {
+	switch data := resp.Data.(type) {
+	case SearchResponse:
+		results := make([]map[string]any, 0, len(data.Results))
+		for _, result := range data.Results {
+			results = append(results, halResource(fields(result), map[string]halLink{
+				"engine": {Href: engineLink(result.Engine)},
+			}))
+		}
+		links := map[string]halLink{}
+		for rel, href := range pageLinks(r, data) {
+			links[rel] = halLink{Href: href}
+		}
+		body := halResource(fields(data, "results"), links)
+		body["_embedded"] = map[string]any{"results": results}
+		return body, true
+	case []EngineInfo:
+		engines := make([]map[string]any, 0, len(data))
+		for _, engine := range data {
+			engines = append(engines, halResource(fields(engine), map[string]halLink{"self": {Href: engineLink(engine.ID)}}))
+		}
+		body := halResource(map[string]any{"count": len(engines)}, map[string]halLink{"self": {Href: APIPrefix + "/engines"}})
+		body["_embedded"] = map[string]any{"engines": engines}
+		return body, true
+	case EngineInfo:
+		return halResource(fields(data), map[string]halLink{
+			"self":       {Href: engineLink(data.ID)},
+			"collection": {Href: APIPrefix + "/engines"},
+		}), true
+	case []CategoryInfo:
+		categories := make([]map[string]any, 0, len(data))
+		for _, category := range data {
+			categories = append(categories, halResource(fields(category), map[string]halLink{
+				"search": {Href: categorySearchLink(category.ID) + "{&q,page,limit}", Templated: true},
+			}))
+		}
+		body := halResource(map[string]any{"count": len(categories)}, map[string]halLink{"self": {Href: APIPrefix + "/categories"}})
+		body["_embedded"] = map[string]any{"categories": categories}
+		return body, true
+	case EngineHealthReport:
+		engines := make([]map[string]any, 0, len(data.Engines))
+		for _, engine := range data.Engines {
+			engines = append(engines, halResource(fields(engine), map[string]halLink{"self": {Href: engineLink(engine.ID)}}))
+		}
+		body := halResource(fields(data, "engines"), map[string]halLink{"self": {Href: APIPrefix + "/engines/health"}})
+		body["_embedded"] = map[string]any{"engines": engines}
+		return body, true
+	}
+	return nil, false
+}
+
+// halResource adds links to a resource's fields
+func halResource(body map[string]any, links map[string]halLink) map[string]any {
+	body["_links"] = links
+	return body
+}
+
+// fields returns the JSON fields of v, without the ones named in omit
+func fields(v any, omit ...string) map[string]any {
+	m := map[string]any{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return m
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return map[string]any{}
+	}
+	for _, name := range omit {
+		delete(m, name)
+	}
+	return m
+}
+
+func engineLink(id string) string {
+	return APIPrefix + "/engines/" + url.PathEscape(id)
+}
+
+func categorySearchLink(id string) string {
+	return APIPrefix + "/search?category=" + url.QueryEscape(id)
+}
+
+// pageLinks returns the self, first, last and, where there is one, prev and
+// next links of a search. They keep the request's query parameters, so a
+// POSTed search pages on as a GET with the same query, and carry its page
+// token, so the pages continue the same results.
+func pageLinks(r *http.Request, resp SearchResponse) map[string]string {
+	params := r.URL.Query()
+	params.Set("q", resp.Query)
+	params.Set("category", resp.Category)
+	params.Set("limit", strconv.Itoa(resp.Pagination.Limit))
+	if resp.Pagination.Token != "" {
+		params.Set("page_token", resp.Pagination.Token)
+	}
+	page := func(n int) string {
+		params.Set("page", strconv.Itoa(n))
+		return APIPrefix + "/search?" + params.Encode()
+	}
+
+	current := max(resp.Pagination.Page, 1)
+	last := max(resp.Pagination.Pages, 1)
+	links := map[string]string{
+		"self":  page(current),
+		"first": page(1),
+		"last":  page(last),
+	}
+	if current > 1 {
+		links["prev"] = page(min(current-1, last))
+	}
+	if current < last || resp.Pagination.HasMore {
+		links["next"] = page(current + 1)
+	}
+	return links
+}
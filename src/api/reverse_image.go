@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/safesearch"
+	"github.com/apimgr/search/src/search"
+)
+
+// handleReverseImage handles GET and POST /api/v1/images/reverse: the url
+// parameter, or a multipart upload in the field "image", is forwarded to
+// the reverse image engines and their results merged
+func (h *Handler) handleReverseImage(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	cfg := h.config.Search.ReverseImage
+	if !cfg.Enabled {
+		h.errorResponse(w, http.StatusNotFound, "Reverse image search is not enabled", "")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		h.errorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	img, err := search.ReadReverseImage(r, cfg.MaxUploadBytes())
+	switch {
+	case errors.Is(err, search.ErrReverseImageTooLarge):
+		h.errorResponse(w, http.StatusRequestEntityTooLarge, "Image too large", err.Error())
+		return
+	case err != nil:
+		h.errorResponse(w, http.StatusBadRequest, "Invalid image", err.Error())
+		return
+	}
+
+	level, ok := safesearch.FromValues(r.URL.Query())
+	if !ok {
+		level = h.config.Search.SafeSearch
+	}
+	results, err := h.aggregator.ReverseImageSearch(r.Context(), img, cfg.Engines, h.config.Search.ResolveSafeSearch(level))
+	if errors.Is(err, model.ErrNoEngines) {
+		h.errorResponse(w, http.StatusServiceUnavailable, "No reverse image engines available", "")
+		return
+	}
+	if err != nil && !errors.Is(err, model.ErrNoResults) {
+		h.errorResponse(w, http.StatusInternalServerError, "Reverse image search failed", err.Error())
+		return
+	}
+
+	apiResults := make([]SearchResult, 0, len(results.Results))
+	for _, result := range results.Results {
+		apiResults = append(apiResults, SearchResult{
+			Title:         result.Title,
+			URL:           result.URL,
+			Description:   result.Content,
+			Engine:        result.Engine,
+			Score:         result.Score,
+			Category:      string(result.Category),
+			Thumbnail:     result.Thumbnail,
+			Domain:        extractDomain(result.URL),
+			Threat:        result.Threat,
+			ContentFilter: result.ContentFilter,
+		})
+	}
+
+	h.jsonResponse(w, http.StatusOK, &APIResponse{
+		OK: true,
+		Data: SearchResponse{
+			Query:    img.URL,
+			Category: string(model.CategoryImages),
+			Results:  apiResults,
+			Pagination: Pagination{
+				Page:  1,
+				Limit: len(apiResults),
+				Total: len(apiResults),
+				Pages: 1,
+			},
+			SearchTime: float64(time.Since(start).Microseconds()) / 1000,
+			Engines:    results.Engines,
+			Degraded:   results.Degraded,
+		},
+		Meta: &APIMeta{
+			Version:     APIVersion,
+			ProcessTime: float64(time.Since(start).Microseconds()) / 1000,
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// SearchProgress is an engine event of /api/v1/search/stream: one engine's
+// answer while the others are still searching
+type SearchProgress struct {
+	Engine string `json:"engine"`
+	// Completed engines out of Total have answered, this one included
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+	// Results are this engine's own, up to the page size; the done event
+	// holds them merged with every other engine's
+	Results []SearchResult `json:"results"`
+	// Timing is included with debug=1 or an operator token
+	Timing *model.EngineTiming `json:"timing,omitempty"`
+}
+
+// handleSearchStream runs a search like /api/v1/search but answers with
+// Server-Sent Events: an engine event as each engine responds, then a done
+// event with the same data /api/v1/search returns, or an error event
+func (h *Handler) handleSearchStream(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	req, query, ok := h.parseSearchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-store")
+	header.Set("X-API-Version", APIVersion)
+	// Ask proxies such as nginx to pass each event on at once
+	header.Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	// The aggregator's timeout bounds the stream, not the server's write
+	// timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	showTimings := h.showEngineTimings(r)
+	for event := range h.aggregator.SearchStream(r.Context(), query) {
+		if !event.Done() {
+			p := event.Engine
+			results := p.Results
+			if req.Type != "" {
+				results = (&model.SearchResults{Results: results}).WithStructuredType(req.Type).Results
+			}
+			if len(results) > query.PerPage {
+				results = results[:query.PerPage]
+			}
+			progress := SearchProgress{Engine: p.Engine, Completed: p.Completed, Total: p.Total, Results: h.searchResults(query, results)}
+			if showTimings {
+				progress.Timing = &p.Timing
+			}
+			if writeEvent(w, "engine", progress) != nil {
+				return
+			}
+			_ = rc.Flush()
+			continue
+		}
+
+		if event.Err != nil && !errors.Is(event.Err, model.ErrNoResults) {
+			requestID := header.Get("X-Request-ID")
+			slog.Error("API error", "request_id", requestID, "message", "Search failed", "detail", event.Err.Error())
+			_ = writeEvent(w, "error", &APIResponse{
+				OK:      false,
+				Error:   model.ErrorCodeFromHTTP(http.StatusInternalServerError),
+				Message: "Search failed",
+				Meta:    &APIMeta{RequestID: requestID, Version: APIVersion},
+			})
+		} else {
+			_ = writeEvent(w, "done", &APIResponse{
+				OK:   true,
+				Data: h.searchResponse(r, req, query, event.Results, search.PageInfo{}, start),
+				Meta: &APIMeta{
+					Version:     APIVersion,
+					ProcessTime: float64(time.Since(start).Microseconds()) / 1000,
+				},
+			})
+		}
+		_ = rc.Flush()
+	}
+}
+
+// writeEvent writes one Server-Sent Event with data as its JSON
+func writeEvent(w http.ResponseWriter, name string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+	return err
+}
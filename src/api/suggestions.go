@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// OpenSearch suggestions, the format browsers ask for once the instance is
+// added as a search engine from /opensearch.xml: a JSON array of the query
+// and the list of suggestions, such as ["priv", ["privacy", "private"]].
+
+// suggestionsContentType is the OpenSearch suggestions media type
+const suggestionsContentType = "application/x-suggestions+json"
+
+// wantsOpenSearchSuggestions reports whether the request asks for the
+// OpenSearch suggestions format, by format=opensearch (which the
+// description's template sets, as browsers send no useful Accept header)
+// or by Accept
+func wantsOpenSearchSuggestions(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "opensearch"
+	}
+	return strings.Contains(r.Header.Get("Accept"), suggestionsContentType)
+}
+
+// openSearchSuggestions answers an autocomplete request in the OpenSearch
+// suggestions format
+func (h *Handler) openSearchSuggestions(w http.ResponseWriter, r *http.Request, query string) {
+	suggestions := []string{}
+	if suggester := h.suggester.Load(); query != "" && suggester != nil {
+		// Untrimmed, as a trailing space tells the history a word is complete
+		if found := suggester.Suggest(r.Context(), r.URL.Query().Get("q")); found != nil {
+			suggestions = found
+		}
+	}
+	data, err := json.Marshal([]any{r.URL.Query().Get("q"), suggestions})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", suggestionsContentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
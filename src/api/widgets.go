@@ -12,7 +12,7 @@ import (
 func (h *Handler) handleWidgets(w http.ResponseWriter, r *http.Request) {
 	if h.widgetManager == nil {
 		// Return basic widgets even without manager
-		h.jsonResponse(w, http.StatusOK, &APIResponse{
+		h.cacheableResponse(w, r, cacheGroupWidgets, &APIResponse{
 			OK: true,
 			Data: map[string]interface{}{
 				"enabled":  true,
@@ -39,7 +39,7 @@ func (h *Handler) handleWidgets(w http.ResponseWriter, r *http.Request) {
 		allWidgets = filtered
 	}
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.cacheableResponse(w, r, cacheGroupWidgets, &APIResponse{
 		OK: true,
 		Data: map[string]interface{}{
 			"enabled":  h.widgetManager.IsEnabled(),
@@ -94,7 +94,7 @@ func (h *Handler) handleWidgetData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.jsonResponse(w, http.StatusOK, &APIResponse{
+	h.cacheableResponse(w, r, cacheGroupWidgets, &APIResponse{
 		OK:   true,
 		Data: data,
 		Meta: &APIMeta{Version: APIVersion},
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// XML output for consumers that cannot take JSON. The search, engines and
+// categories endpoints answer in XML to format=xml or an Accept header that
+// prefers application/xml, encoding the same structs as their JSON through
+// the xml struct tags. The root element is <response> and every list entry
+// is an <item>.
+
+const xmlContentType = "application/xml; charset=utf-8"
+
+// wantsXML reports whether the request asks for XML rather than JSON
+func wantsXML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "xml"
+	}
+	accept := r.Header.Get("Accept")
+	return (strings.Contains(accept, "application/xml") || strings.Contains(accept, "text/xml")) &&
+		!strings.Contains(accept, "application/json")
+}
+
+// xmlList writes a list that is the data of a response as one <item> per
+// entry, rather than repeating <data>
+type xmlList struct {
+	items reflect.Value
+}
+
+func (l xmlList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	item := xml.StartElement{Name: xml.Name{Local: "item"}}
+	for i := range l.items.Len() {
+		if err := e.EncodeElement(l.items.Index(i).Interface(), item); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeXML encodes a response as an XML document indented like the JSON
+func encodeXML(resp *APIResponse) ([]byte, error) {
+	if v := reflect.ValueOf(resp.Data); v.Kind() == reflect.Slice {
+		listed := *resp
+		listed.Data = xmlList{items: v}
+		resp = &listed
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.EncodeElement(resp, xml.StartElement{Name: xml.Name{Local: "response"}}); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// negotiatedResponse sends resp as XML when the request asks for it, and
+// as JSON otherwise, in the response profile the request names
+func (h *Handler) negotiatedResponse(w http.ResponseWriter, r *http.Request, status int, resp *APIResponse) {
+	w.Header().Add("Vary", "Accept")
+	if !wantsXML(r) {
+		if body, contentType, ok := profiled(r, status, resp); ok {
+			header := w.Header()
+			header.Set("Content-Type", contentType)
+			header.Set("X-API-Version", APIVersion)
+			writeJSON(w, status, body)
+			return
+		}
+		h.jsonResponse(w, status, resp)
+		return
+	}
+	body, err := encodeXML(resp)
+	if err != nil {
+		h.errorResponse(w, http.StatusInternalServerError, "Failed to encode XML", err.Error())
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Type", xmlContentType)
+	header.Set("X-API-Version", APIVersion)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// negotiatedError is errorResponse in the format the request asks for
+func (h *Handler) negotiatedError(w http.ResponseWriter, r *http.Request, status int, message, detail string) {
+	h.negotiatedResponse(w, r, status, h.errorBody(w, status, message, detail))
+}
+
+// negotiatedCacheableResponse is cacheableResponse in the format the
+// request asks for
+func (h *Handler) negotiatedCacheableResponse(w http.ResponseWriter, r *http.Request, group string, resp *APIResponse) {
+	w.Header().Add("Vary", "Accept")
+	if !wantsXML(r) {
+		if body, contentType, ok := profiled(r, http.StatusOK, resp); ok {
+			header := w.Header()
+			header.Set("Content-Type", contentType)
+			header.Set("X-API-Version", APIVersion)
+			e, _ := encodeJSON(body)
+			defer releaseJSONEncoder(e)
+			h.serveCacheable(w, r, group, e.buf.Bytes())
+			return
+		}
+		h.cacheableResponse(w, r, group, resp)
+		return
+	}
+	body, err := encodeXML(resp)
+	if err != nil {
+		h.errorResponse(w, http.StatusInternalServerError, "Failed to encode XML", err.Error())
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Type", xmlContentType)
+	header.Set("X-API-Version", APIVersion)
+	h.serveCacheable(w, r, group, body)
+}
@@ -56,6 +56,11 @@ type Manager struct {
 	dataDir   string
 	// Backup encryption password (never stored on disk)
 	password string
+	// Keyfile contents, public key and private key of the other
+	// encryption modes (see Keys)
+	keyfile   []byte
+	recipient string
+	identity  string
 	// Username of who created the backup (per PART 25)
 	createdBy string
 }
@@ -71,6 +76,20 @@ func (m *Manager) SetPassword(password string) {
 	m.password = password
 }
 
+// SetKeys sets everything backups are encrypted and decrypted with,
+// replacing any password set before
+func (m *Manager) SetKeys(keys Keys) {
+	m.password = keys.Password
+	m.keyfile = keys.Keyfile
+	m.recipient = keys.Recipient
+	m.identity = keys.Identity
+}
+
+// keys returns what the manager encrypts and decrypts with
+func (m *Manager) keys() Keys {
+	return Keys{Password: m.password, Keyfile: m.keyfile, Recipient: m.recipient, Identity: m.identity}
+}
+
 // NewManager creates a new backup manager
 func NewManager() *Manager {
 	return &Manager{
@@ -179,6 +198,22 @@ func (m *Manager) Create(filename string) (string, error) {
 		Files: files,
 	}
 
+	// Per-file checksums in sha256sum format, for checking an extracted
+	// backup without this binary
+	sums := formatSums(checksums)
+	sumsHeader := &tar.Header{
+		Name:    SumsFile,
+		Size:    int64(len(sums)),
+		Mode:    0644,
+		ModTime: time.Now(),
+	}
+	if err := tarWriter.WriteHeader(sumsHeader); err != nil {
+		return "", fmt.Errorf("failed to write checksums header: %w", err)
+	}
+	if _, err := tarWriter.Write(sums); err != nil {
+		return "", fmt.Errorf("failed to write checksums: %w", err)
+	}
+
 	// Add metadata to archive as manifest.json
 	metaJSON, _ := json.MarshalIndent(metadata, "", "  ")
 	metaHeader := &tar.Header{
@@ -621,7 +656,7 @@ func (m *Manager) ApplyRetention(policy RetentionPolicy) error {
 // CreateEncrypted creates an encrypted backup with .enc extension
 // Per AI.md PART 21: .enc extension for encrypted backups
 func (m *Manager) CreateEncrypted(filename string) (string, error) {
-	if m.password == "" {
+	if !m.keys().CanEncrypt() {
 		return "", fmt.Errorf("encryption password not set - use SetPassword() or BACKUP_PASSWORD env var")
 	}
 
@@ -631,6 +666,12 @@ func (m *Manager) CreateEncrypted(filename string) (string, error) {
 		return "", err
 	}
 
+	return m.encryptArchive(backupPath)
+}
+
+// encryptArchive encrypts the backup at backupPath to backupPath + ".enc"
+// and removes the unencrypted archive
+func (m *Manager) encryptArchive(backupPath string) (string, error) {
 	// Read backup data
 	data, err := os.ReadFile(backupPath)
 	if err != nil {
@@ -639,7 +680,7 @@ func (m *Manager) CreateEncrypted(filename string) (string, error) {
 	}
 
 	// Encrypt the data
-	encrypted, err := EncryptBackup(data, m.password)
+	encrypted, err := EncryptBackupWithKeys(data, m.keys())
 	if err != nil {
 		os.Remove(backupPath)
 		return "", fmt.Errorf("failed to encrypt backup: %w", err)
@@ -661,7 +702,7 @@ func (m *Manager) CreateEncrypted(filename string) (string, error) {
 // RestoreEncrypted restores from an encrypted backup (.enc extension)
 // Per AI.md PART 21: .enc extension for encrypted backups
 func (m *Manager) RestoreEncrypted(backupPath string) error {
-	if m.password == "" {
+	if !m.keys().canDecrypt() {
 		return fmt.Errorf("decryption password not set - use SetPassword() or BACKUP_PASSWORD env var")
 	}
 
@@ -683,7 +724,7 @@ func (m *Manager) RestoreEncrypted(backupPath string) error {
 	}
 
 	// Decrypt the data
-	decrypted, err := DecryptBackup(encrypted, m.password)
+	decrypted, err := DecryptBackupWithKeys(encrypted, m.keys())
 	if err != nil {
 		return fmt.Errorf("decryption failed: %w", err)
 	}
@@ -714,10 +755,19 @@ type VerificationResult struct {
 	ManifestValid bool `json:"manifest_valid"`
 	ContentValid  bool `json:"content_valid"`
 	DatabaseValid bool `json:"database_valid"`
+	// FilesValid is set when every file's SHA-256 matches the manifest
+	// and no file is missing or unlisted; FilesChecked counts the files
+	// that matched
+	FilesValid   bool `json:"files_valid"`
+	FilesChecked int  `json:"files_checked"`
 	// Only for encrypted backups
-	DecryptValid bool     `json:"decrypt_valid"`
-	AllPassed    bool     `json:"all_passed"`
-	Errors       []string `json:"errors,omitempty"`
+	DecryptValid bool `json:"decrypt_valid"`
+	// DecryptSkipped is set when a backup encrypted to a public key was
+	// verified before encryption, because the server does not hold the
+	// private key
+	DecryptSkipped bool     `json:"decrypt_skipped,omitempty"`
+	AllPassed      bool     `json:"all_passed"`
+	Errors         []string `json:"errors,omitempty"`
 }
 
 // VerifyBackup verifies backup integrity immediately after creation
@@ -726,9 +776,11 @@ type VerificationResult struct {
 // - Size > 0
 // - Checksum valid
 // - Manifest readable
+// - File checksums (each file's SHA-256 matches the manifest)
 // - Content extraction (test extract all files to temp dir)
 // - Database integrity (verify SQLite is valid, if present)
 // - Decrypt test (if encrypted)
+// Nothing is restored; the archive is only read.
 func (m *Manager) VerifyBackup(backupPath string) (*VerificationResult, error) {
 	result := &VerificationResult{
 		// Default true for non-encrypted
@@ -755,7 +807,7 @@ func (m *Manager) VerifyBackup(backupPath string) (*VerificationResult, error) {
 	var dataToVerify []byte
 
 	if isEncrypted {
-		if m.password == "" {
+		if !m.keys().canDecrypt() {
 			result.DecryptValid = false
 			result.Errors = append(result.Errors, "cannot verify encrypted backup: password not set")
 			return result, nil
@@ -767,7 +819,7 @@ func (m *Manager) VerifyBackup(backupPath string) (*VerificationResult, error) {
 			return result, nil
 		}
 
-		decrypted, err := DecryptBackup(encrypted, m.password)
+		decrypted, err := DecryptBackupWithKeys(encrypted, m.keys())
 		if err != nil {
 			result.DecryptValid = false
 			result.Errors = append(result.Errors, fmt.Sprintf("decryption failed: %v", err))
@@ -804,9 +856,20 @@ func (m *Manager) VerifyBackup(backupPath string) (*VerificationResult, error) {
 			// Legacy backups without checksums - pass with warning
 			result.ChecksumValid = true
 		}
+
+		// Check 5: every file's SHA-256 matches the manifest
+		if len(metadata.Checksums) > 0 {
+			checked, fileErrs := verifyFileChecksums(dataToVerify, metadata)
+			result.FilesChecked = checked
+			result.FilesValid = len(fileErrs) == 0
+			result.Errors = append(result.Errors, fileErrs...)
+		} else {
+			// Legacy backups without checksums have nothing to compare
+			result.FilesValid = true
+		}
 	}
 
-	// Check 5 & 6: Content extraction and database integrity
+	// Check 6 & 7: Content extraction and database integrity
 	contentValid, databaseValid, contentErrs := m.verifyContentAndDatabase(dataToVerify)
 	result.ContentValid = contentValid
 	result.DatabaseValid = databaseValid
@@ -814,11 +877,73 @@ func (m *Manager) VerifyBackup(backupPath string) (*VerificationResult, error) {
 
 	// Determine overall result
 	result.AllPassed = result.FileExists && result.SizeValid && result.ChecksumValid && result.ManifestValid &&
-		result.ContentValid && result.DatabaseValid && result.DecryptValid
+		result.FilesValid && result.ContentValid && result.DatabaseValid && result.DecryptValid
 
 	return result, nil
 }
 
+// VerifiedBackup is one backup checked by VerifyAll
+type VerifiedBackup struct {
+	Filename string              `json:"filename"`
+	Result   *VerificationResult `json:"result"`
+}
+
+// VerifyAll verifies every backup in the backup directory without
+// restoring any of them. An encrypted backup the manager holds no key
+// for, such as one encrypted to a public key, only has its header
+// checked and is reported with DecryptSkipped.
+func (m *Manager) VerifyAll() ([]VerifiedBackup, error) {
+	entries, err := os.ReadDir(m.backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var verified []VerifiedBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tar.gz.enc")) {
+			continue
+		}
+		path := filepath.Join(m.backupDir, name)
+
+		var result *VerificationResult
+		if IsEncrypted(path) && !m.keys().canDecrypt() {
+			result = verifyHeaderOnly(path)
+		} else if result, err = m.VerifyBackup(path); err != nil {
+			return verified, err
+		}
+		verified = append(verified, VerifiedBackup{Filename: name, Result: result})
+	}
+	return verified, nil
+}
+
+// verifyHeaderOnly checks what can be checked of an encrypted backup
+// without its key: that it exists, is not empty and has a readable header
+func verifyHeaderOnly(path string) *VerificationResult {
+	result := &VerificationResult{DecryptValid: true, DecryptSkipped: true}
+	info, err := os.Stat(path)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("file not found: %v", err))
+		return result
+	}
+	result.FileExists = true
+	if info.Size() == 0 {
+		result.Errors = append(result.Errors, "backup file is empty (size = 0)")
+		return result
+	}
+	result.SizeValid = true
+	if _, err := ReadHeaderFile(path); err != nil {
+		result.DecryptValid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("encrypted backup header unreadable: %v", err))
+		return result
+	}
+	result.AllPassed = true
+	return result
+}
+
 // verifyContentAndDatabase test-extracts every file in the backup archive to a
 // temp dir and validates any embedded server.db as a well-formed SQLite file.
 // Per AI.md PART 21: Content extraction and Database integrity are Fatal checks.
@@ -976,7 +1101,15 @@ func (m *Manager) CreateAndVerify(filename string) (string, *VerificationResult,
 
 // CreateEncryptedAndVerify creates an encrypted backup and verifies it
 // Per AI.md PART 21: All encrypted backups must pass decrypt test
+// A backup encrypted to a public key cannot be decrypted without the
+// private key, which the server is not meant to hold; it is verified
+// before encryption instead, and the encrypted file's header is checked
+// to name the configured public key.
 func (m *Manager) CreateEncryptedAndVerify(filename string) (string, *VerificationResult, error) {
+	if m.recipient != "" && m.identity == "" {
+		return m.createVerifyThenEncrypt(filename)
+	}
+
 	// Create encrypted backup
 	backupPath, err := m.CreateEncrypted(filename)
 	if err != nil {
@@ -1034,3 +1167,29 @@ func (m *Manager) ScheduledBackupWithVerification(keepCount int) error {
 
 	return nil
 }
+
+// createVerifyThenEncrypt verifies a backup, then encrypts it to the
+// configured public key
+func (m *Manager) createVerifyThenEncrypt(filename string) (string, *VerificationResult, error) {
+	backupPath, result, err := m.CreateAndVerify(filename)
+	if err != nil {
+		return "", result, err
+	}
+
+	encryptedPath, err := m.encryptArchive(backupPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("encrypted backup creation failed: %w", err)
+	}
+
+	header, err := ReadHeaderFile(encryptedPath)
+	if err != nil || header.KeyID != KeyID(m.recipient) {
+		os.Remove(encryptedPath)
+		result.DecryptValid = false
+		result.AllPassed = false
+		result.Errors = append(result.Errors, "encrypted backup does not name the configured public key")
+		return "", result, fmt.Errorf("backup verification failed: %v", result.Errors)
+	}
+	result.DecryptSkipped = true
+
+	return encryptedPath, result, nil
+}
@@ -4,13 +4,20 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/apimgr/search/src/config"
+	"golang.org/x/crypto/argon2"
 )
 
 // Tests for BackupMetadata
@@ -3972,3 +3979,571 @@ func TestIsValidSQLiteFile(t *testing.T) {
 		t.Error("isValidSQLiteFile() should be false for a nonexistent file")
 	}
 }
+
+// Tests for keyfiles and public-key mode
+
+func TestDecryptBackupLegacyFormat(t *testing.T) {
+	// The format written before headers: salt | nonce | ciphertext
+	salt := bytes.Repeat([]byte{1}, argon2SaltLen)
+	nonce := bytes.Repeat([]byte{2}, gcmNonceLen)
+	key := argon2.IDKey([]byte("legacy-pass"), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := append(append(append([]byte{}, salt...), nonce...), gcm.Seal(nil, nonce, []byte("old backup"), nil)...)
+
+	header, err := ReadHeader(encrypted)
+	if err != nil || !header.Legacy || !header.NeedsPassword {
+		t.Fatalf("ReadHeader() = %+v, %v; want legacy passphrase header", header, err)
+	}
+	decrypted, err := DecryptBackup(encrypted, "legacy-pass")
+	if err != nil {
+		t.Fatalf("DecryptBackup() error = %v", err)
+	}
+	if string(decrypted) != "old backup" {
+		t.Errorf("DecryptBackup() = %q", decrypted)
+	}
+}
+
+func TestEncryptBackupWithKeyfile(t *testing.T) {
+	data := []byte("backup data")
+	keyfile := []byte("random keyfile contents")
+
+	encrypted, err := EncryptBackupWithKeys(data, Keys{Password: "pass", Keyfile: keyfile})
+	if err != nil {
+		t.Fatalf("EncryptBackupWithKeys() error = %v", err)
+	}
+	header, err := ReadHeader(encrypted)
+	if err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+	if header.Mode != ModePassphrase || !header.NeedsPassword || !header.NeedsKeyfile {
+		t.Errorf("header = %+v, want passphrase mode needing password and keyfile", header)
+	}
+
+	if _, err := DecryptBackup(encrypted, "pass"); err == nil {
+		t.Error("DecryptBackup() without the keyfile should fail")
+	}
+	if _, err := DecryptBackupWithKeys(encrypted, Keys{Password: "pass", Keyfile: []byte("other")}); err == nil {
+		t.Error("DecryptBackupWithKeys() with the wrong keyfile should fail")
+	}
+	if _, err := DecryptBackupWithKeys(encrypted, Keys{Keyfile: keyfile}); err == nil {
+		t.Error("DecryptBackupWithKeys() without the password should fail")
+	}
+	decrypted, err := DecryptBackupWithKeys(encrypted, Keys{Password: "pass", Keyfile: keyfile})
+	if err != nil {
+		t.Fatalf("DecryptBackupWithKeys() error = %v", err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Errorf("DecryptBackupWithKeys() = %q, want %q", decrypted, data)
+	}
+
+	// A keyfile alone is enough, and a password given anyway is ignored
+	encrypted, err = EncryptBackupWithKeys(data, Keys{Keyfile: keyfile})
+	if err != nil {
+		t.Fatalf("EncryptBackupWithKeys(keyfile only) error = %v", err)
+	}
+	if _, err := DecryptBackupWithKeys(encrypted, Keys{Password: "unused", Keyfile: keyfile}); err != nil {
+		t.Errorf("DecryptBackupWithKeys(keyfile only) error = %v", err)
+	}
+}
+
+func TestEncryptBackupPublicKey(t *testing.T) {
+	publicKey, privateKey, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	if !strings.HasPrefix(publicKey, PublicKeyPrefix) || !strings.HasPrefix(privateKey, PrivateKeyPrefix) {
+		t.Fatalf("GenerateKeyPair() = %q, %q; want prefixed keys", publicKey, privateKey)
+	}
+	data := []byte("backup data")
+
+	encrypted, err := EncryptBackupWithKeys(data, Keys{Recipient: publicKey})
+	if err != nil {
+		t.Fatalf("EncryptBackupWithKeys() error = %v", err)
+	}
+	header, err := ReadHeader(encrypted)
+	if err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+	if header.Mode != ModePublicKey || header.KeyID != KeyID(publicKey) {
+		t.Errorf("header = %+v, want public-key mode for key %s", header, KeyID(publicKey))
+	}
+
+	if _, err := DecryptBackup(encrypted, "password"); err == nil {
+		t.Error("DecryptBackup() with a password should fail")
+	}
+	_, otherKey, _ := GenerateKeyPair()
+	if _, err := DecryptBackupWithKeys(encrypted, Keys{Identity: otherKey}); err == nil || !strings.Contains(err.Error(), header.KeyID) {
+		t.Errorf("DecryptBackupWithKeys(other key) error = %v, want key ID mismatch", err)
+	}
+	decrypted, err := DecryptBackupWithKeys(encrypted, Keys{Identity: privateKey + "\n"})
+	if err != nil {
+		t.Fatalf("DecryptBackupWithKeys() error = %v", err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Errorf("DecryptBackupWithKeys() = %q, want %q", decrypted, data)
+	}
+
+	// The header is authenticated
+	encrypted[len(headerMagic)+1] ^= 1
+	if _, err := DecryptBackupWithKeys(encrypted, Keys{Identity: privateKey}); err == nil {
+		t.Error("DecryptBackupWithKeys() should fail on a modified header")
+	}
+}
+
+func TestEncryptBackupWithKeysErrors(t *testing.T) {
+	if _, err := EncryptBackupWithKeys([]byte("x"), Keys{}); err == nil {
+		t.Error("EncryptBackupWithKeys() without keys should fail")
+	}
+	if _, err := EncryptBackupWithKeys([]byte("x"), Keys{Recipient: "bkpub_short"}); err == nil {
+		t.Error("EncryptBackupWithKeys() with a malformed public key should fail")
+	}
+	if KeyID("not a key") != "" {
+		t.Error("KeyID() of a malformed key should be empty")
+	}
+}
+
+func TestReadHeaderRejectsExcessiveArgon2(t *testing.T) {
+	encrypted, err := EncryptBackup([]byte("x"), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Memory in KiB follows magic, mode, flags and time
+	binary.BigEndian.PutUint32(encrypted[len(headerMagic)+6:], maxArgon2Memory+1)
+	if _, err := ReadHeader(encrypted); err == nil {
+		t.Error("ReadHeader() should refuse Argon2id memory above the cap")
+	}
+	if _, err := ReadHeader(append(append([]byte{}, headerMagic...), 9)); err == nil {
+		t.Error("ReadHeader() should refuse an unknown mode")
+	}
+}
+
+func TestKeysFromConfig(t *testing.T) {
+	publicKey, _, _ := GenerateKeyPair()
+	keyfilePath := filepath.Join(t.TempDir(), "backup.keyfile")
+	os.WriteFile(keyfilePath, []byte("keyfile"), 0600)
+
+	keys, err := KeysFromConfig(config.BackupEncryptionConfig{Keyfile: keyfilePath, Recipient: publicKey}, "pass")
+	if err != nil {
+		t.Fatalf("KeysFromConfig() error = %v", err)
+	}
+	if keys.Password != "pass" || string(keys.Keyfile) != "keyfile" || keys.Recipient != publicKey {
+		t.Errorf("KeysFromConfig() = %+v", keys)
+	}
+
+	t.Setenv("BACKUP_KEYFILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := KeysFromConfig(config.BackupEncryptionConfig{Keyfile: keyfilePath}, ""); err == nil {
+		t.Error("KeysFromConfig() should read BACKUP_KEYFILE in place of the configured keyfile")
+	}
+	t.Setenv("BACKUP_KEYFILE", "")
+	if _, err := KeysFromConfig(config.BackupEncryptionConfig{Recipient: "bkpub_bad"}, ""); err == nil {
+		t.Error("KeysFromConfig() should reject a malformed recipient")
+	}
+}
+
+func TestManagerCreateEncryptedAndVerifyPublicKey(t *testing.T) {
+	publicKey, privateKey, _ := GenerateKeyPair()
+	tempDir := t.TempDir()
+	m := &Manager{
+		backupDir: filepath.Join(tempDir, "backups"),
+		configDir: filepath.Join(tempDir, "config"),
+		dataDir:   filepath.Join(tempDir, "data"),
+	}
+	m.SetKeys(Keys{Recipient: publicKey})
+
+	os.MkdirAll(m.configDir, 0755)
+	os.MkdirAll(m.dataDir, 0755)
+	originalContent := []byte("original content")
+	os.WriteFile(filepath.Join(m.configDir, "test.yml"), originalContent, 0644)
+
+	backupPath, result, err := m.CreateEncryptedAndVerify("")
+	if err != nil {
+		t.Fatalf("CreateEncryptedAndVerify() error = %v", err)
+	}
+	if !strings.HasSuffix(backupPath, ".enc") {
+		t.Errorf("backup path = %s, want .enc", backupPath)
+	}
+	if !result.AllPassed || !result.DecryptSkipped {
+		t.Errorf("result = %+v, want all passed with decrypt skipped", result)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(backupPath, ".enc")); !os.IsNotExist(err) {
+		t.Error("Unencrypted backup should be removed after encryption")
+	}
+
+	// Without the private key the backup cannot be restored
+	if err := m.RestoreEncrypted(backupPath); err == nil {
+		t.Error("RestoreEncrypted() without the private key should fail")
+	}
+
+	os.WriteFile(filepath.Join(m.configDir, "test.yml"), []byte("modified"), 0644)
+	m.SetKeys(Keys{Identity: privateKey})
+	if err := m.RestoreEncrypted(backupPath); err != nil {
+		t.Fatalf("RestoreEncrypted() error = %v", err)
+	}
+	content, _ := os.ReadFile(filepath.Join(m.configDir, "test.yml"))
+	if !bytes.Equal(content, originalContent) {
+		t.Errorf("Content not restored: got %q", content)
+	}
+}
+
+// rewriteArchive copies a tar.gz archive, letting edit replace, drop
+// (nil) or keep each entry's content, then appends extra entries
+func rewriteArchive(t *testing.T, src, dst string, edit func(name string, content []byte) []byte, extra map[string][]byte) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	tarReader := tar.NewReader(gzReader)
+
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	write := func(name string, content []byte) {
+		tarWriter.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0644, Typeflag: tar.TypeReg})
+		tarWriter.Write(content)
+	}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tarReader.Next() error = %v", err)
+		}
+		content, _ := io.ReadAll(tarReader)
+		if content = edit(header.Name, content); content != nil {
+			write(header.Name, content)
+		}
+	}
+	for name, content := range extra {
+		write(name, content)
+	}
+	tarWriter.Close()
+	gzWriter.Close()
+	os.WriteFile(dst, buf.Bytes(), 0644)
+}
+
+// readArchiveEntry returns one entry of a tar.gz archive
+func readArchiveEntry(t *testing.T, path, name string) []byte {
+	t.Helper()
+	data, _ := os.ReadFile(path)
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			t.Fatalf("%s not found in archive: %v", name, err)
+		}
+		if header.Name == name {
+			content, _ := io.ReadAll(tarReader)
+			return content
+		}
+	}
+}
+
+func newChecksumTestManager(t *testing.T) *Manager {
+	t.Helper()
+	tempDir := t.TempDir()
+	m := &Manager{
+		backupDir: filepath.Join(tempDir, "backups"),
+		configDir: filepath.Join(tempDir, "config"),
+		dataDir:   filepath.Join(tempDir, "data"),
+	}
+	os.MkdirAll(m.configDir, 0755)
+	os.MkdirAll(m.dataDir, 0755)
+	os.WriteFile(filepath.Join(m.configDir, "server.yml"), []byte("server:\n  title: Test\n"), 0644)
+	os.WriteFile(filepath.Join(m.dataDir, "notes.txt"), []byte("notes"), 0644)
+	return m
+}
+
+func TestManagerCreateWritesSums(t *testing.T) {
+	m := newChecksumTestManager(t)
+	backupPath, err := m.Create("")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	sums, err := parseSums(readArchiveEntry(t, backupPath, SumsFile))
+	if err != nil {
+		t.Fatalf("parseSums() error = %v", err)
+	}
+	metadata, err := m.GetMetadata(backupPath)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if len(sums) != 2 || len(compareChecksums(metadata.Checksums, sums)) != 0 {
+		t.Errorf("%s = %v, want the manifest checksums %v", SumsFile, sums, metadata.Checksums)
+	}
+
+	result, _ := m.VerifyBackup(backupPath)
+	if !result.AllPassed || !result.FilesValid || result.FilesChecked != 2 {
+		t.Errorf("VerifyBackup() = %+v, want all passed with 2 files checked", result)
+	}
+}
+
+func TestManagerVerifyBackupFileChecksums(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(name string, content []byte) []byte
+		extra   map[string][]byte
+		wantErr string
+	}{
+		{
+			name: "altered file",
+			edit: func(name string, content []byte) []byte {
+				if name == "data/notes.txt" {
+					return []byte("tampered")
+				}
+				return content
+			},
+			wantErr: "checksum mismatch: data/notes.txt",
+		},
+		{
+			name: "missing file",
+			edit: func(name string, content []byte) []byte {
+				if name == "data/notes.txt" {
+					return nil
+				}
+				return content
+			},
+			wantErr: "file missing from archive: data/notes.txt",
+		},
+		{
+			name:    "unlisted file",
+			edit:    func(name string, content []byte) []byte { return content },
+			extra:   map[string][]byte{"data/added.txt": []byte("added")},
+			wantErr: "file not in manifest: data/added.txt",
+		},
+		{
+			name: "sums disagree with manifest",
+			edit: func(name string, content []byte) []byte {
+				if name == SumsFile {
+					return bytes.Replace(content, []byte("data/notes.txt"), []byte("data/other.txt"), 1)
+				}
+				return content
+			},
+			wantErr: SumsFile + " does not match manifest.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newChecksumTestManager(t)
+			backupPath, err := m.Create("")
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			tampered := filepath.Join(t.TempDir(), "tampered.tar.gz")
+			rewriteArchive(t, backupPath, tampered, tt.edit, tt.extra)
+
+			result, err := m.VerifyBackup(tampered)
+			if err != nil {
+				t.Fatalf("VerifyBackup() error = %v", err)
+			}
+			if result.FilesValid || result.AllPassed {
+				t.Errorf("FilesValid = %v, AllPassed = %v, want both false", result.FilesValid, result.AllPassed)
+			}
+			found := false
+			for _, e := range result.Errors {
+				found = found || e == tt.wantErr
+			}
+			if !found {
+				t.Errorf("Errors = %v, want %q", result.Errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSums(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	sums, err := parseSums([]byte(sum + "  config/server.yml\n" + strings.ToUpper(sum) + " *data/server.db\n\n"))
+	if err != nil {
+		t.Fatalf("parseSums() error = %v", err)
+	}
+	if sums["config/server.yml"] != sum || sums["data/server.db"] != sum {
+		t.Errorf("parseSums() = %v", sums)
+	}
+
+	for _, bad := range []string{"abc  file\n", sum + "\n", strings.Repeat("zz", 32) + "  file\n"} {
+		if _, err := parseSums([]byte(bad)); err == nil {
+			t.Errorf("parseSums(%q) should fail", bad)
+		}
+	}
+}
+
+func TestManagerVerifyAll(t *testing.T) {
+	m := newChecksumTestManager(t)
+	if verified, err := m.VerifyAll(); err != nil || len(verified) != 0 {
+		t.Fatalf("VerifyAll() with no backup directory = %v, %v", verified, err)
+	}
+
+	good, err := m.Create("good")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	rewriteArchive(t, good, filepath.Join(m.backupDir, "bad.tar.gz"), func(name string, content []byte) []byte {
+		if name == "config/server.yml" {
+			return []byte("tampered")
+		}
+		return content
+	}, nil)
+
+	publicKey, _, _ := GenerateKeyPair()
+	m.SetKeys(Keys{Recipient: publicKey})
+	if _, err := m.CreateEncrypted("sealed"); err != nil {
+		t.Fatalf("CreateEncrypted() error = %v", err)
+	}
+	os.WriteFile(filepath.Join(m.backupDir, "notes.txt"), []byte("not a backup"), 0644)
+
+	// A manager without the private key can only check the header
+	m.SetKeys(Keys{})
+	verified, err := m.VerifyAll()
+	if err != nil {
+		t.Fatalf("VerifyAll() error = %v", err)
+	}
+	results := make(map[string]*VerificationResult)
+	for _, v := range verified {
+		results[v.Filename] = v.Result
+	}
+	if len(results) != 3 {
+		t.Fatalf("VerifyAll() checked %v, want 3 backups", results)
+	}
+	if r := results["good.tar.gz"]; !r.AllPassed {
+		t.Errorf("good.tar.gz errors = %v", r.Errors)
+	}
+	if r := results["bad.tar.gz"]; r.AllPassed || r.FilesValid {
+		t.Error("bad.tar.gz should fail file checksum verification")
+	}
+	if r := results["sealed.tar.gz.enc"]; !r.AllPassed || !r.DecryptSkipped {
+		t.Errorf("sealed.tar.gz.enc = %+v, want passed with decrypt skipped", r)
+	}
+}
+
+func TestParseRemoteLocation(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want RemoteLocation
+	}{
+		{"s3://bucket/latest", RemoteLocation{Bucket: "bucket", Key: "", Latest: true}},
+		{"s3://bucket", RemoteLocation{Bucket: "bucket", Key: "", Latest: true}},
+		{"s3://bucket/prod/latest", RemoteLocation{Bucket: "bucket", Key: "prod/", Latest: true}},
+		{"s3://bucket/prod/", RemoteLocation{Bucket: "bucket", Key: "prod/", Latest: true}},
+		{"s3://bucket/prod/search_backup_2026-01-02_030405.tar.gz.enc", RemoteLocation{Bucket: "bucket", Key: "prod/search_backup_2026-01-02_030405.tar.gz.enc"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRemoteLocation(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRemoteLocation(%q) = %+v, %v; want %+v", tt.raw, got, err, tt.want)
+		}
+	}
+	for _, raw := range []string{"https://bucket/latest", "s3:///latest", "/var/backups/latest"} {
+		if _, err := ParseRemoteLocation(raw); err == nil {
+			t.Errorf("ParseRemoteLocation(%q) accepted", raw)
+		}
+	}
+}
+
+// newS3TestServer serves a bucket listing and objects the way S3 does
+// with path-style addressing
+func newS3TestServer(t *testing.T, objects map[string][]byte, modified map[string]time.Time) (*httptest.Server, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Path == "/bucket/" {
+			prefix := r.URL.Query().Get("prefix")
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult>`)
+			for key, data := range objects {
+				if strings.HasPrefix(key, prefix) {
+					fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>%d</Size></Contents>",
+						key, modified[key].Format(time.RFC3339), len(data))
+				}
+			}
+			fmt.Fprint(w, `<IsTruncated>false</IsTruncated></ListBucketResult>`)
+			return
+		}
+		data, ok := objects[strings.TrimPrefix(r.URL.Path, "/bucket/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestManagerFetchRemote(t *testing.T) {
+	day := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := map[string][]byte{
+		"search_backup_2026-01-01_000000.tar.gz":     []byte("old"),
+		"search_backup_2026-01-02_000000.tar.gz.enc": []byte("newest"),
+		"notes.txt": []byte("not a backup"),
+		"archive/search_backup_2026-02-01_000000.tar.gz": []byte("in a subdirectory"),
+	}
+	modified := map[string]time.Time{
+		"search_backup_2026-01-01_000000.tar.gz":     day.Add(-24 * time.Hour),
+		"search_backup_2026-01-02_000000.tar.gz.enc": day,
+		"notes.txt": day.Add(time.Hour),
+		"archive/search_backup_2026-02-01_000000.tar.gz": day.Add(30 * 24 * time.Hour),
+	}
+	server, requests := newS3TestServer(t, objects, modified)
+	m := newChecksumTestManager(t)
+	client := &S3Client{Endpoint: server.URL, Region: "eu-west-1", AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret",
+		client: server.Client(), now: func() time.Time { return day }}
+
+	path, err := m.FetchRemote(t.Context(), client, "s3://bucket/latest")
+	if err != nil {
+		t.Fatalf("FetchRemote() error = %v", err)
+	}
+	if path != filepath.Join(m.backupDir, "search_backup_2026-01-02_000000.tar.gz.enc") {
+		t.Errorf("FetchRemote() = %q", path)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "newest" {
+		t.Errorf("downloaded %q", data)
+	}
+
+	auth := (*requests)[0].Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260102/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if (*requests)[0].Header.Get("X-Amz-Date") != "20260102T030405Z" {
+		t.Errorf("X-Amz-Date = %q", (*requests)[0].Header.Get("X-Amz-Date"))
+	}
+
+	if _, err := m.FetchRemote(t.Context(), client, "s3://bucket/search_backup_2026-03-01_000000.tar.gz"); err == nil || !strings.Contains(err.Error(), "NoSuchKey") {
+		t.Errorf("missing object: error = %v", err)
+	}
+	if _, err := m.FetchRemote(t.Context(), client, "s3://bucket/empty/latest"); err == nil || !strings.Contains(err.Error(), "no backups found") {
+		t.Errorf("empty prefix: error = %v", err)
+	}
+	entries, _ := os.ReadDir(m.backupDir)
+	if len(entries) != 1 {
+		t.Errorf("backup directory has %d entries, want only the downloaded backup", len(entries))
+	}
+}
+
+func TestS3ClientAddress(t *testing.T) {
+	aws := &S3Client{Region: "us-west-2"}
+	if host, p := aws.address("backups", "a b/c.tar.gz"); host != "backups.s3.us-west-2.amazonaws.com" || p != "/a%20b/c.tar.gz" {
+		t.Errorf("address() = %q %q", host, p)
+	}
+	if host, p := aws.address("backups.example.com", "c.tar.gz"); host != "s3.us-west-2.amazonaws.com" || p != "/backups.example.com/c.tar.gz" {
+		t.Errorf("address() with dotted bucket = %q %q", host, p)
+	}
+	minio := &S3Client{Endpoint: "http://minio.lan:9000", Region: "us-east-1"}
+	if host, p := minio.address("backups", "c.tar.gz"); host != "minio.lan:9000" || p != "/backups/c.tar.gz" {
+		t.Errorf("address() with endpoint = %q %q", host, p)
+	}
+}
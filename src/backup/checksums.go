@@ -0,0 +1,163 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SumsFile is the archive entry listing the SHA-256 of every backed-up
+// file in sha256sum format, so an extracted backup can also be checked
+// with "sha256sum -c SHA256SUMS"
+const SumsFile = "SHA256SUMS"
+
+// isMetadataEntry reports whether name is one of the archive's own
+// description files rather than a backed-up file
+func isMetadataEntry(name string) bool {
+	return name == "manifest.json" || name == "backup.json" || name == SumsFile
+}
+
+// formatSums renders checksums as sha256sum lines sorted by path
+func formatSums(checksums map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, name := range sortedKeys(checksums) {
+		fmt.Fprintf(&buf, "%s  %s\n", checksums[name], name)
+	}
+	return buf.Bytes()
+}
+
+// parseSums parses sha256sum output, in text ("<hex>  <path>") or
+// binary ("<hex> *<path>") form
+func parseSums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(text, " ")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		if !ok || len(sum) != sha256.Size*2 || name == "" {
+			return nil, fmt.Errorf("line %d is not a sha256sum line", line)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("line %d is not a sha256sum line", line)
+		}
+		sums[name] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// archiveChecksums hashes every regular file in a tar.gz archive and
+// returns the SumsFile entry, if the archive has one
+func archiveChecksums(data []byte) (map[string]string, []byte, error) {
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gzip format: %w", err)
+	}
+	defer gzReader.Close()
+
+	checksums := make(map[string]string)
+	var sums []byte
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading tar: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name == SumsFile {
+			if sums, err = io.ReadAll(tarReader); err != nil {
+				return nil, nil, fmt.Errorf("error reading %s: %w", SumsFile, err)
+			}
+			continue
+		}
+		if isMetadataEntry(header.Name) {
+			continue
+		}
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, tarReader); err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", header.Name, err)
+		}
+		checksums[header.Name] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return checksums, sums, nil
+}
+
+// compareChecksums compares the SHA-256 of each file found in an archive
+// with what the manifest recorded, and returns one error per file that
+// is missing, altered or not listed
+func compareChecksums(want, got map[string]string) []string {
+	var errs []string
+	for _, name := range sortedKeys(want) {
+		sum, ok := got[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Sprintf("file missing from archive: %s", name))
+		case !strings.EqualFold(sum, want[name]):
+			errs = append(errs, fmt.Sprintf("checksum mismatch: %s", name))
+		}
+	}
+	for _, name := range sortedKeys(got) {
+		if _, ok := want[name]; !ok {
+			errs = append(errs, fmt.Sprintf("file not in manifest: %s", name))
+		}
+	}
+	return errs
+}
+
+// verifyFileChecksums hashes each file in the archive data and checks it
+// against the manifest and, when present, the SumsFile entry. It returns
+// how many files matched.
+func verifyFileChecksums(data []byte, metadata *BackupMetadata) (int, []string) {
+	got, sums, err := archiveChecksums(data)
+	if err != nil {
+		return 0, []string{fmt.Sprintf("file checksum verification failed: %v", err)}
+	}
+
+	errs := compareChecksums(metadata.Checksums, got)
+	if sums != nil {
+		listed, err := parseSums(sums)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s is malformed: %v", SumsFile, err))
+		} else if len(compareChecksums(metadata.Checksums, listed)) > 0 {
+			errs = append(errs, fmt.Sprintf("%s does not match manifest.json", SumsFile))
+		}
+	}
+
+	matched := 0
+	for name, sum := range metadata.Checksums {
+		if strings.EqualFold(got[name], sum) {
+			matched++
+		}
+	}
+	return matched, errs
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,12 +1,22 @@
 package backup
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
+	"github.com/apimgr/search/src/config"
 	"golang.org/x/crypto/argon2"
 )
 
@@ -20,6 +30,95 @@ const (
 	argon2SaltLen = 16
 )
 
+// Limits on the Argon2id parameters a backup header may ask for, so a
+// crafted file cannot make a restore allocate unbounded memory
+const (
+	maxArgon2Time   = 16
+	maxArgon2Memory = 1024 * 1024
+)
+
+// Encrypted backups start with a header that says how the key is derived.
+// The header is authenticated along with the data:
+//
+//	"SRCHBAK\x02" | mode | mode fields | nonce (12) | AES-256-GCM ciphertext
+//
+// Passphrase mode: flags (1; bit 0 password, bit 1 keyfile), Argon2id time
+// (4), memory in KiB (4) and threads (1), salt (16).
+// Public-key mode: ephemeral X25519 public key (32), recipient key ID (4).
+//
+// Files without the header are the first format, salt (16) | nonce (12) |
+// ciphertext, keyed by Argon2id of the password with the constants above.
+var headerMagic = []byte("SRCHBAK\x02")
+
+const (
+	modePassphrase byte = 1
+	modePublicKey  byte = 2
+
+	flagPassword byte = 1 << 0
+	flagKeyfile  byte = 1 << 1
+
+	gcmNonceLen   = 12
+	keyIDLen      = 4
+	x25519KeyLen  = 32
+	x25519KDFInfo = "search backup x25519"
+)
+
+// Prefixes of the encoded key pair of public-key mode
+const (
+	PublicKeyPrefix  = "bkpub_"
+	PrivateKeyPrefix = "bksec_"
+)
+
+// Modes of an encrypted backup
+const (
+	ModePassphrase = "passphrase"
+	ModePublicKey  = "public-key"
+)
+
+// Keys are what a backup is encrypted or decrypted with. A backup is
+// encrypted to Recipient when it is set, and otherwise with the password,
+// the keyfile or both.
+type Keys struct {
+	Password string
+	// Keyfile is the contents of a key file, needed along with the password,
+	// or instead of it, to decrypt
+	Keyfile []byte
+	// Recipient is the public key of public-key mode; a backup encrypted to
+	// it needs the matching Identity to decrypt
+	Recipient string
+	// Identity is the private key that decrypts public-key backups
+	Identity string
+}
+
+// CanEncrypt reports whether keys are enough to encrypt a backup
+func (k Keys) CanEncrypt() bool {
+	return k.Recipient != "" || k.Password != "" || len(k.Keyfile) > 0
+}
+
+// canDecrypt reports whether keys hold anything to decrypt with
+func (k Keys) canDecrypt() bool {
+	return k.Identity != "" || k.Password != "" || len(k.Keyfile) > 0
+}
+
+// Header is what an encrypted backup's header says is needed to decrypt it
+type Header struct {
+	// Mode is ModePassphrase or ModePublicKey
+	Mode          string
+	NeedsPassword bool
+	NeedsKeyfile  bool
+	// KeyID identifies the key pair a public-key backup was encrypted to
+	KeyID string
+	// Legacy is set for backups written before the header existed
+	Legacy bool
+
+	size      int
+	time      uint32
+	memory    uint32
+	threads   uint8
+	salt      []byte
+	ephemeral []byte
+}
+
 // EncryptBackup encrypts backup data using AES-256-GCM with password-based key derivation
 // Per AI.md PART 24: Backup Encryption (NON-NEGOTIABLE)
 // Algorithm: AES-256-GCM
@@ -29,52 +128,40 @@ func EncryptBackup(data []byte, password string) ([]byte, error) {
 	if password == "" {
 		return nil, fmt.Errorf("password required for encryption")
 	}
+	return EncryptBackupWithKeys(data, Keys{Password: password})
+}
 
-	// Generate random salt
-	salt := make([]byte, argon2SaltLen)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, fmt.Errorf("failed to generate salt: %w", err)
+// EncryptBackupWithKeys encrypts backup data with AES-256-GCM under a key
+// derived from keys: to the recipient's public key, or with Argon2id from
+// the password and keyfile
+func EncryptBackupWithKeys(data []byte, keys Keys) ([]byte, error) {
+	var header, key []byte
+	var err error
+	switch {
+	case keys.Recipient != "":
+		header, key, err = publicKeyHeader(keys.Recipient)
+	case keys.Password != "" || len(keys.Keyfile) > 0:
+		header, key, err = passphraseHeader(keys)
+	default:
+		err = fmt.Errorf("a password, keyfile or public key is required for encryption")
 	}
-
-	// Derive encryption key from password using Argon2id
-	key := argon2.IDKey(
-		[]byte(password),
-		salt,
-		argon2Time,
-		argon2Memory,
-		argon2Threads,
-		argon2KeyLen,
-	)
-
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
+		return nil, err
 	}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
-
-	// Generate random nonce
-	nonce := make([]byte, gcm.NonceSize())
+	nonce := make([]byte, gcmNonceLen)
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	// Encrypt data
-	ciphertext := gcm.Seal(nil, nonce, data, nil)
-
-	// Prepend salt and nonce to ciphertext for decryption
-	// Format: [salt(16)][nonce(12)][ciphertext]
-	encrypted := make([]byte, 0, len(salt)+len(nonce)+len(ciphertext))
-	encrypted = append(encrypted, salt...)
+	encrypted := make([]byte, 0, len(header)+len(nonce)+len(data)+gcm.Overhead())
+	encrypted = append(encrypted, header...)
 	encrypted = append(encrypted, nonce...)
-	encrypted = append(encrypted, ciphertext...)
-
-	return encrypted, nil
+	return gcm.Seal(encrypted, nonce, data, header), nil
 }
 
 // DecryptBackup decrypts backup data using AES-256-GCM
@@ -83,18 +170,335 @@ func DecryptBackup(encrypted []byte, password string) ([]byte, error) {
 	if password == "" {
 		return nil, fmt.Errorf("password required for decryption")
 	}
+	return DecryptBackupWithKeys(encrypted, Keys{Password: password})
+}
+
+// DecryptBackupWithKeys decrypts backup data encrypted in any mode, asking
+// keys for what its header says is needed
+func DecryptBackupWithKeys(encrypted []byte, keys Keys) ([]byte, error) {
+	header, err := ReadHeader(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if header.Legacy {
+		return decryptLegacy(encrypted, keys.Password)
+	}
+
+	var key []byte
+	switch header.Mode {
+	case ModePublicKey:
+		key, err = header.publicKeyKey(keys.Identity)
+	default:
+		key, err = header.passphraseKey(keys)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// Check minimum size (salt + nonce + at least some data)
-	// salt + GCM nonce
-	minSize := argon2SaltLen + 12
-	if len(encrypted) < minSize {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) < header.size+gcmNonceLen+gcm.Overhead() {
 		return nil, fmt.Errorf("invalid encrypted data: too short")
 	}
+	nonce := encrypted[header.size : header.size+gcmNonceLen]
+	plaintext, err := gcm.Open(nil, nonce, encrypted[header.size+gcmNonceLen:], encrypted[:header.size])
+	if err != nil {
+		if header.Mode == ModePublicKey {
+			return nil, fmt.Errorf("decryption failed (wrong private key or corrupted backup): %w", err)
+		}
+		return nil, fmt.Errorf("decryption failed (wrong password or keyfile?): %w", err)
+	}
+	return plaintext, nil
+}
+
+// ReadHeader reads what is needed to decrypt an encrypted backup
+func ReadHeader(encrypted []byte) (*Header, error) {
+	if !bytes.HasPrefix(encrypted, headerMagic) {
+		// salt + GCM nonce
+		if len(encrypted) < argon2SaltLen+gcmNonceLen {
+			return nil, fmt.Errorf("invalid encrypted data: too short")
+		}
+		return &Header{Mode: ModePassphrase, NeedsPassword: true, Legacy: true}, nil
+	}
+
+	rest := encrypted[len(headerMagic):]
+	if len(rest) < 1 {
+		return nil, fmt.Errorf("invalid encrypted data: truncated header")
+	}
+	switch rest[0] {
+	case modePassphrase:
+		const fields = 1 + 4 + 4 + 1 + argon2SaltLen
+		if len(rest) < 1+fields {
+			return nil, fmt.Errorf("invalid encrypted data: truncated header")
+		}
+		flags := rest[1]
+		h := &Header{
+			Mode:          ModePassphrase,
+			NeedsPassword: flags&flagPassword != 0,
+			NeedsKeyfile:  flags&flagKeyfile != 0,
+			size:          len(headerMagic) + 1 + fields,
+			time:          binary.BigEndian.Uint32(rest[2:6]),
+			memory:        binary.BigEndian.Uint32(rest[6:10]),
+			threads:       rest[10],
+			salt:          rest[11 : 11+argon2SaltLen],
+		}
+		if h.time == 0 || h.time > maxArgon2Time || h.memory == 0 || h.memory > maxArgon2Memory || h.threads == 0 {
+			return nil, fmt.Errorf("invalid encrypted data: unsupported Argon2id parameters")
+		}
+		if !h.NeedsPassword && !h.NeedsKeyfile {
+			return nil, fmt.Errorf("invalid encrypted data: no key source in header")
+		}
+		return h, nil
+	case modePublicKey:
+		if len(rest) < 1+x25519KeyLen+keyIDLen {
+			return nil, fmt.Errorf("invalid encrypted data: truncated header")
+		}
+		return &Header{
+			Mode:      ModePublicKey,
+			KeyID:     hex.EncodeToString(rest[1+x25519KeyLen : 1+x25519KeyLen+keyIDLen]),
+			size:      len(headerMagic) + 1 + x25519KeyLen + keyIDLen,
+			ephemeral: rest[1 : 1+x25519KeyLen],
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid encrypted data: unknown encryption mode %d", rest[0])
+}
+
+// ReadHeaderFile reads the header of the encrypted backup at path
+func ReadHeaderFile(path string) (*Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Enough for any header and the nonce after it
+	buf := make([]byte, 128)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	return ReadHeader(buf[:n])
+}
+
+// passphraseHeader returns a passphrase-mode header and the key derived
+// from the password and keyfile under it
+func passphraseHeader(keys Keys) (header, key []byte, err error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	var flags byte
+	if keys.Password != "" {
+		flags |= flagPassword
+	}
+	if len(keys.Keyfile) > 0 {
+		flags |= flagKeyfile
+	}
+
+	header = append([]byte{}, headerMagic...)
+	header = append(header, modePassphrase, flags)
+	header = binary.BigEndian.AppendUint32(header, argon2Time)
+	header = binary.BigEndian.AppendUint32(header, argon2Memory)
+	header = append(header, argon2Threads)
+	header = append(header, salt...)
+
+	key = argon2.IDKey(passphraseSecret(keys), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return header, key, nil
+}
+
+// passphraseKey derives the key of a passphrase-mode backup
+func (h *Header) passphraseKey(keys Keys) ([]byte, error) {
+	if h.NeedsPassword && keys.Password == "" {
+		return nil, fmt.Errorf("password required for decryption")
+	}
+	if h.NeedsKeyfile && len(keys.Keyfile) == 0 {
+		return nil, fmt.Errorf("this backup was encrypted with a keyfile; the keyfile is required for decryption")
+	}
+	// Only what the backup was encrypted with goes into the key
+	if !h.NeedsPassword {
+		keys.Password = ""
+	}
+	if !h.NeedsKeyfile {
+		keys.Keyfile = nil
+	}
+	return argon2.IDKey(passphraseSecret(keys), h.salt, h.time, h.memory, h.threads, argon2KeyLen), nil
+}
+
+// passphraseSecret is the Argon2id input for a password and keyfile: the
+// password, then a zero byte and the SHA-256 of the keyfile when there is
+// one
+func passphraseSecret(keys Keys) []byte {
+	secret := []byte(keys.Password)
+	if len(keys.Keyfile) > 0 {
+		sum := sha256.Sum256(keys.Keyfile)
+		secret = append(append(secret, 0), sum[:]...)
+	}
+	return secret
+}
+
+// publicKeyHeader returns a public-key-mode header for recipient and the
+// key shared with it through a new ephemeral X25519 key
+func publicKeyHeader(recipient string) (header, key []byte, err error) {
+	pub, err := ParsePublicKey(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+	key, err = x25519Key(shared, ephemeral.PublicKey().Bytes(), pub.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header = append([]byte{}, headerMagic...)
+	header = append(header, modePublicKey)
+	header = append(header, ephemeral.PublicKey().Bytes()...)
+	header = append(header, keyID(pub)...)
+	return header, key, nil
+}
+
+// publicKeyKey derives the key of a public-key-mode backup from the
+// private key it was encrypted to
+func (h *Header) publicKeyKey(identity string) ([]byte, error) {
+	if identity == "" {
+		return nil, fmt.Errorf("this backup was encrypted to a public key; its private key is required for decryption")
+	}
+	priv, err := ParsePrivateKey(identity)
+	if err != nil {
+		return nil, err
+	}
+	if hex.EncodeToString(keyID(priv.PublicKey())) != h.KeyID {
+		return nil, fmt.Errorf("this backup was encrypted to a different public key (key ID %s)", h.KeyID)
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(h.ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted data: bad ephemeral key")
+	}
+	shared, err := priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+	return x25519Key(shared, h.ephemeral, priv.PublicKey().Bytes())
+}
+
+// x25519Key derives the AES key from an X25519 shared secret, bound to
+// both public keys
+func x25519Key(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	return hkdf.Key(sha256.New, shared, salt, x25519KDFInfo, argon2KeyLen)
+}
+
+// keyID is the short fingerprint a public-key backup names its recipient by
+func keyID(pub *ecdh.PublicKey) []byte {
+	sum := sha256.Sum256(pub.Bytes())
+	return sum[:keyIDLen]
+}
+
+// KeyID returns the key ID of an encoded public key, as ReadHeader reports
+// it, or "" if publicKey is not one
+func KeyID(publicKey string) string {
+	pub, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(keyID(pub))
+}
+
+// GenerateKeyPair generates a key pair for public-key mode: backups are
+// encrypted to publicKey and only privateKey decrypts them
+func GenerateKeyPair() (publicKey, privateKey string, err error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key pair: %w", err)
+	}
+	return PublicKeyPrefix + base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		PrivateKeyPrefix + base64.RawURLEncoding.EncodeToString(priv.Bytes()), nil
+}
+
+// ParsePublicKey decodes a public key from GenerateKeyPair
+func ParsePublicKey(s string) (*ecdh.PublicKey, error) {
+	raw, err := decodeKey(s, PublicKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup public key: %w", err)
+	}
+	return ecdh.X25519().NewPublicKey(raw)
+}
+
+// ParsePrivateKey decodes a private key from GenerateKeyPair
+func ParsePrivateKey(s string) (*ecdh.PrivateKey, error) {
+	raw, err := decodeKey(s, PrivateKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup private key: %w", err)
+	}
+	return ecdh.X25519().NewPrivateKey(raw)
+}
+
+func decodeKey(s, prefix string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("want a key starting with %s", prefix)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+	if err != nil || len(raw) != x25519KeyLen {
+		return nil, fmt.Errorf("malformed key")
+	}
+	return raw, nil
+}
+
+// ReadKeyfile reads a keyfile. Any file works; an empty one is refused
+// because it would add nothing to the key.
+func ReadKeyfile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("keyfile %s is empty", path)
+	}
+	return data, nil
+}
+
+// KeysFromConfig returns the keys backups are encrypted with under enc:
+// its public key, or password along with its keyfile. BACKUP_KEYFILE
+// names the keyfile in place of enc.Keyfile.
+func KeysFromConfig(enc config.BackupEncryptionConfig, password string) (Keys, error) {
+	keys := Keys{Password: password, Recipient: strings.TrimSpace(enc.Recipient)}
+	if keys.Recipient != "" {
+		if _, err := ParsePublicKey(keys.Recipient); err != nil {
+			return Keys{}, err
+		}
+	}
+	path := os.Getenv("BACKUP_KEYFILE")
+	if path == "" {
+		path = enc.Keyfile
+	}
+	if path != "" {
+		keyfile, err := ReadKeyfile(path)
+		if err != nil {
+			return Keys{}, err
+		}
+		keys.Keyfile = keyfile
+	}
+	return keys, nil
+}
+
+// decryptLegacy decrypts a backup written before the header existed
+func decryptLegacy(encrypted []byte, password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("password required for decryption")
+	}
 
 	// Extract salt, nonce, and ciphertext
 	salt := encrypted[:argon2SaltLen]
-	nonce := encrypted[argon2SaltLen : argon2SaltLen+12]
-	ciphertext := encrypted[argon2SaltLen+12:]
+	nonce := encrypted[argon2SaltLen : argon2SaltLen+gcmNonceLen]
+	ciphertext := encrypted[argon2SaltLen+gcmNonceLen:]
 
 	// Derive decryption key from password using same parameters
 	key := argon2.IDKey(
@@ -106,6 +510,21 @@ func DecryptBackup(encrypted []byte, password string) ([]byte, error) {
 		argon2KeyLen,
 	)
 
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt data
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed (wrong password?): %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -117,14 +536,7 @@ func DecryptBackup(encrypted []byte, password string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
-
-	// Decrypt data
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return nil, fmt.Errorf("decryption failed (wrong password?): %w", err)
-	}
-
-	return plaintext, nil
+	return gcm, nil
 }
 
 // EncryptFile encrypts a file and writes to output path
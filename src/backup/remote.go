@@ -0,0 +1,376 @@
+package backup
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body, which every
+// signed S3 request here sends
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// RemoteLocation is a backup stored in an S3 bucket: one object, or the
+// newest backup under a prefix
+type RemoteLocation struct {
+	Bucket string
+	// Key is the object to download, or the prefix to search when Latest
+	// is set
+	Key    string
+	Latest bool
+}
+
+// String returns the location as an s3:// URL
+func (l RemoteLocation) String() string {
+	return "s3://" + l.Bucket + "/" + l.Key
+}
+
+// ParseRemoteLocation parses s3://bucket/key. A key ending in .tar.gz or
+// .tar.gz.enc names one backup; anything else is a prefix, and a last
+// segment of "latest" stands for the newest backup beside it, so
+// s3://bucket/latest is the newest backup in the bucket.
+func ParseRemoteLocation(raw string) (RemoteLocation, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return RemoteLocation{}, fmt.Errorf("not an s3://bucket/key URL: %s", raw)
+	}
+	loc := RemoteLocation{Bucket: u.Host, Key: strings.TrimPrefix(u.Path, "/")}
+	if isBackupName(loc.Key) {
+		return loc, nil
+	}
+	loc.Latest = true
+	if loc.Key == "latest" || strings.HasSuffix(loc.Key, "/latest") {
+		loc.Key = strings.TrimSuffix(loc.Key, "latest")
+	}
+	return loc, nil
+}
+
+// isBackupName reports whether name looks like a backup archive
+func isBackupName(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tar.gz.enc")
+}
+
+// S3Client downloads backups from S3 or an S3-compatible store. It signs
+// requests with AWS Signature Version 4 when it has credentials, and
+// sends them unsigned for a public bucket otherwise.
+type S3Client struct {
+	// Endpoint is the store's base URL; empty means AWS in Region
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewS3ClientFromEnv configures a client from the standard AWS variables:
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN,
+// AWS_REGION (or AWS_DEFAULT_REGION, default us-east-1) and
+// AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL) for stores other than AWS
+func NewS3ClientFromEnv() *S3Client {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	return &S3Client{
+		Endpoint:        strings.TrimRight(endpoint, "/"),
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		client:          &http.Client{Timeout: 30 * time.Minute},
+		now:             time.Now,
+	}
+}
+
+// RemoteObject is one object in a bucket listing
+type RemoteObject struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+// listBucketResult is the ListObjectsV2 response
+type listBucketResult struct {
+	Contents              []RemoteObject `xml:"Contents"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken"`
+}
+
+// s3Error is the error body S3 answers a failed request with
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// Resolve returns the object loc names: the object itself, or the newest
+// backup directly under its prefix
+func (c *S3Client) Resolve(ctx context.Context, loc RemoteLocation) (RemoteObject, error) {
+	if !loc.Latest {
+		return RemoteObject{Key: loc.Key}, nil
+	}
+	var newest *RemoteObject
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if loc.Key != "" {
+			query.Set("prefix", loc.Key)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.do(ctx, loc.Bucket, "", query)
+		if err != nil {
+			return RemoteObject{}, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return RemoteObject{}, fmt.Errorf("invalid bucket listing: %w", err)
+		}
+		for i, obj := range page.Contents {
+			// Backups in "directories" below the prefix are not candidates
+			if !isBackupName(obj.Key) || strings.Contains(strings.TrimPrefix(obj.Key, loc.Key), "/") {
+				continue
+			}
+			if newest == nil || obj.LastModified.After(newest.LastModified) ||
+				(obj.LastModified.Equal(newest.LastModified) && obj.Key > newest.Key) {
+				newest = &page.Contents[i]
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	if newest == nil {
+		return RemoteObject{}, fmt.Errorf("no backups found in %s", loc)
+	}
+	return *newest, nil
+}
+
+// Download writes the object key of bucket to dir under its own name and
+// returns the file's path. A partial download is removed.
+func (c *S3Client) Download(ctx context.Context, bucket, key, dir string) (string, error) {
+	name := path.Base(key)
+	if !isBackupName(name) {
+		return "", fmt.Errorf("not a backup archive: %s", key)
+	}
+	resp, err := c.do(ctx, bucket, key, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(dir, name)
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// do sends a GET for key in bucket (the bucket itself when key is empty)
+// and returns the response when it is 200 OK
+func (c *S3Client) do(ctx context.Context, bucket, key string, query url.Values) (*http.Response, error) {
+	host, uriPath := c.address(bucket, key)
+	scheme := "https"
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+	}
+	rawQuery := canonicalQuery(query)
+	target := scheme + "://" + host + uriPath
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.AccessKeyID != "" && c.SecretAccessKey != "" {
+		c.sign(req, host, uriPath, rawQuery)
+	}
+
+	client := c.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var s3err s3Error
+	if xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3err) == nil && s3err.Code != "" {
+		return nil, fmt.Errorf("s3://%s/%s: %s: %s", bucket, key, s3err.Code, s3err.Message)
+	}
+	return nil, fmt.Errorf("s3://%s/%s: %s", bucket, key, resp.Status)
+}
+
+// address returns the host and escaped path of key in bucket. AWS buckets
+// are addressed by virtual host, except those with dots in their name,
+// which would not match the wildcard certificate; other stores use paths.
+func (c *S3Client) address(bucket, key string) (host, uriPath string) {
+	escaped := "/" + escapePath(key)
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err == nil && u.Host != "" {
+			return u.Host, strings.TrimRight(u.Path, "/") + "/" + bucket + escaped
+		}
+	}
+	awsHost := "s3." + c.Region + ".amazonaws.com"
+	if strings.Contains(bucket, ".") {
+		return awsHost, "/" + bucket + escaped
+	}
+	return bucket + "." + awsHost, escaped
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (c *S3Client) sign(req *http.Request, host, uriPath, rawQuery string) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	day := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	headers := map[string]string{
+		"host":                 host,
+		"x-amz-content-sha256": emptyPayloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+		headers["x-amz-security-token"] = c.SessionToken
+	}
+	names := sortedKeys(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet, uriPath, rawQuery, canonicalHeaders.String(), signedHeaders, emptyPayloadHash,
+	}, "\n")
+	scope := day + "/" + c.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), day)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query sorted by name, as Signature Version 4
+// requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes each segment of an object key
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+// FetchRemote downloads the backup raw (an s3:// URL, see
+// ParseRemoteLocation) into the backup directory and returns its path. A
+// nil client is configured from the environment.
+func (m *Manager) FetchRemote(ctx context.Context, client *S3Client, raw string) (string, error) {
+	if client == nil {
+		client = NewS3ClientFromEnv()
+	}
+	loc, err := ParseRemoteLocation(raw)
+	if err != nil {
+		return "", err
+	}
+	obj, err := client.Resolve(ctx, loc)
+	if err != nil {
+		return "", err
+	}
+	return client.Download(ctx, loc.Bucket, obj.Key, m.backupDir)
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -197,6 +198,33 @@ func TestMemoryCacheExistsExpired(t *testing.T) {
 	}
 }
 
+func TestMemoryCacheSetMaxSize(t *testing.T) {
+	c := NewMemoryCache(10, time.Minute)
+	defer c.Close()
+
+	ctx := context.Background()
+	for i, ttl := range []time.Duration{time.Hour, time.Second, 2 * time.Hour, time.Minute} {
+		c.Set(ctx, fmt.Sprintf("key%d", i), []byte("v"), ttl)
+	}
+
+	c.SetMaxSize(2)
+	if c.MaxSize() != 2 {
+		t.Errorf("MaxSize() = %d, want 2", c.MaxSize())
+	}
+	for key, want := range map[string]bool{"key0": true, "key1": false, "key2": true, "key3": false} {
+		if exists, _ := c.Exists(ctx, key); exists != want {
+			t.Errorf("%s exists = %v, want %v (the items closest to expiry go first)", key, exists, want)
+		}
+	}
+
+	// Growing again keeps what is cached
+	c.SetMaxSize(10)
+	c.Set(ctx, "key4", []byte("v"), time.Minute)
+	if stats, _ := c.Stats(ctx); stats.Keys != 3 {
+		t.Errorf("Keys = %d, want 3", stats.Keys)
+	}
+}
+
 func TestMemoryCacheClear(t *testing.T) {
 	c := NewMemoryCache(100, time.Minute)
 	defer c.Close()
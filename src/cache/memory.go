@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -151,6 +152,38 @@ func (c *MemoryCache) evictOldest() {
 	}
 }
 
+// MaxSize returns the most items the cache holds
+func (c *MemoryCache) MaxSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.maxSize
+}
+
+// SetMaxSize changes the most items the cache holds, evicting the items
+// closest to expiry until it fits
+func (c *MemoryCache) SetMaxSize(n int) {
+	if n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = n
+	if over := len(c.items) - n; over > 0 {
+		keys := make([]string, 0, len(c.items))
+		for key := range c.items {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return c.items[keys[i]].expiresAt.Before(c.items[keys[j]].expiresAt)
+		})
+		for _, key := range keys[:over] {
+			delete(c.items, key)
+		}
+		c.stats.Keys = int64(len(c.items))
+	}
+}
+
 // Delete removes a value from the cache
 func (c *MemoryCache) Delete(ctx context.Context, key string) error {
 	c.mu.Lock()
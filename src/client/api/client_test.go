@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/apimgr/search/src/version"
+	"github.com/apimgr/search/src/webhook"
 )
 
 // Tests for NewClient
@@ -824,3 +826,25 @@ func TestDoRequestInvalidMethod(t *testing.T) {
 		t.Errorf("error = %q, want to contain 'failed to create request'", err.Error())
 	}
 }
+
+// Tests for VerifyWebhookRequest
+
+func TestVerifyWebhookRequest(t *testing.T) {
+	body := `{"alert":{"id":"a1"}}`
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	if err := webhook.Sign(req.Header, "secret", []byte(body), time.Now()); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	var guard WebhookReplayGuard
+	got, err := VerifyWebhookRequest(req, "secret", &guard)
+	if err != nil || string(got) != body {
+		t.Fatalf("VerifyWebhookRequest() = %q, %v", got, err)
+	}
+	if err := VerifyWebhook(req.Header, got, "secret", &guard); !errors.Is(err, ErrWebhookReplayed) {
+		t.Errorf("replayed delivery error = %v, want ErrWebhookReplayed", err)
+	}
+	if err := VerifyWebhook(req.Header, got, "wrong", nil); !errors.Is(err, ErrWebhookBadSignature) {
+		t.Errorf("wrong secret error = %v, want ErrWebhookBadSignature", err)
+	}
+}
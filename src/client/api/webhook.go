@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/webhook"
+)
+
+// maxWebhookBody bounds the webhook body VerifyWebhookRequest reads
+const maxWebhookBody = 1 << 20
+
+// Errors returned by the webhook verification helpers, for errors.Is
+var (
+	ErrWebhookMissingHeaders = webhook.ErrMissingHeaders
+	ErrWebhookBadSignature   = webhook.ErrBadSignature
+	ErrWebhookExpired        = webhook.ErrExpired
+	ErrWebhookReplayed       = webhook.ErrReplayed
+)
+
+// WebhookReplayGuard remembers the nonces of verified deliveries so a
+// replayed delivery is rejected. Keep one per receiver; the zero value is
+// ready to use.
+type WebhookReplayGuard = webhook.ReplayGuard
+
+// VerifyWebhook checks a delivery's signature headers against its raw body,
+// allowing webhook.DefaultTolerance of clock skew. guard may be nil to skip
+// the replay check.
+func VerifyWebhook(header http.Header, body []byte, secret string, guard *WebhookReplayGuard) error {
+	return webhook.Verify(header, secret, body, time.Now(), 0, guard)
+}
+
+// VerifyWebhookRequest reads and verifies a delivery in an http.Handler,
+// returning the verified body. r.Body is replaced so it can be read again.
+//
+//	var guard api.WebhookReplayGuard
+//	http.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+//		body, err := api.VerifyWebhookRequest(r, secret, &guard)
+//		if err != nil {
+//			http.Error(w, err.Error(), http.StatusUnauthorized)
+//			return
+//		}
+//		// body is a trusted alert payload
+//	})
+func VerifyWebhookRequest(r *http.Request, secret string, guard *WebhookReplayGuard) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, fmt.Errorf("read webhook body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := VerifyWebhook(r.Header, body, secret, guard); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,421 @@
+// Package collection keeps named lists of saved search results. There are no
+// accounts: a collection is owned by whoever holds its manage token, and can
+// be opened read-only by anyone holding its share token. Both tokens are
+// stored only as SHA-256 hashes.
+package collection
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for an unknown token or item
+	ErrNotFound = errors.New("collection not found")
+	// ErrInvalidInput is returned for a name, item or order that is rejected
+	ErrInvalidInput = errors.New("invalid collection input")
+)
+
+// DefaultMaxItems caps a collection when search.collections.max_items is unset
+const DefaultMaxItems = 500
+
+const (
+	maxNameLength        = 200
+	maxDescriptionLength = 2000
+	maxNoteLength        = 2000
+	maxTitleLength       = 500
+	maxContentLength     = 2000
+	maxURLLength         = 2048
+)
+
+// Collection is a named list of saved results, in order
+type Collection struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Shared      bool      `json:"shared"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Items       []Item    `json:"items"`
+}
+
+// Item is a saved result with the owner's note
+type Item struct {
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content,omitempty"`
+	Engine   string    `json:"engine,omitempty"`
+	Note     string    `json:"note,omitempty"`
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Store keeps collections in the server database
+type Store struct {
+	db          *sql.DB
+	collections string
+	items       string
+}
+
+// NewStore creates a store; tablePrefix is the server table prefix
+func NewStore(db *sql.DB, tablePrefix string) *Store {
+	return &Store{
+		db:          db,
+		collections: tablePrefix + "collections",
+		items:       tablePrefix + "collection_items",
+	}
+}
+
+// Create stores a new, empty collection and returns it with its manage token.
+// The token is shown only here.
+func (s *Store) Create(ctx context.Context, name, description string) (*Collection, string, error) {
+	if s == nil || s.db == nil {
+		return nil, "", errors.New("collection storage is unavailable")
+	}
+	name, description, err := normalizeDetails(name, description)
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.collections+`
+		(id, name, description, manage_token_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, name, description, hashToken(token), now, now); err != nil {
+		return nil, "", fmt.Errorf("create collection: %w", err)
+	}
+	return &Collection{ID: id, Name: name, Description: description, CreatedAt: now, UpdatedAt: now, Items: []Item{}}, token, nil
+}
+
+// Get returns the collection for a manage token, with its items
+func (s *Store) Get(ctx context.Context, manageToken string) (*Collection, error) {
+	return s.load(ctx, "manage_token_hash", manageToken)
+}
+
+// GetShared returns the collection for a share token, with its items
+func (s *Store) GetShared(ctx context.Context, shareToken string) (*Collection, error) {
+	return s.load(ctx, "share_token_hash", shareToken)
+}
+
+// Update renames a collection and replaces its description
+func (s *Store) Update(ctx context.Context, manageToken, name, description string) (*Collection, error) {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return nil, err
+	}
+	name, description, err = normalizeDetails(name, description)
+	if err != nil {
+		return nil, err
+	}
+	c.Name, c.Description, c.UpdatedAt = name, description, time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, `UPDATE `+s.collections+` SET name = ?, description = ?, updated_at = ? WHERE id = ?`,
+		c.Name, c.Description, c.UpdatedAt, c.ID); err != nil {
+		return nil, fmt.Errorf("update collection: %w", err)
+	}
+	return c, nil
+}
+
+// Delete removes a collection and its items
+func (s *Store) Delete(ctx context.Context, manageToken string) error {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+s.items+` WHERE collection_id = ?`, c.ID); err != nil {
+		return fmt.Errorf("delete collection items: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+s.collections+` WHERE id = ?`, c.ID); err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	return tx.Commit()
+}
+
+// AddItem appends a result to the end of the collection, which may hold at
+// most maxItems (DefaultMaxItems when zero)
+func (s *Store) AddItem(ctx context.Context, manageToken string, item Item, maxItems int) (*Item, error) {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := item.normalize(); err != nil {
+		return nil, err
+	}
+	if maxItems <= 0 {
+		maxItems = DefaultMaxItems
+	}
+	if len(c.Items) >= maxItems {
+		return nil, fmt.Errorf("%w: a collection holds at most %d items", ErrInvalidInput, maxItems)
+	}
+	if item.ID, err = randomToken(16); err != nil {
+		return nil, err
+	}
+	item.Position = len(c.Items)
+	item.AddedAt = time.Now().UTC()
+
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.items+`
+		(id, collection_id, url, title, content, engine, note, position, added_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.ID, c.ID, item.URL, item.Title, item.Content, item.Engine, item.Note, item.Position, item.AddedAt); err != nil {
+		return nil, fmt.Errorf("add collection item: %w", err)
+	}
+	s.touch(ctx, c.ID)
+	return &item, nil
+}
+
+// SetNote replaces the note on an item
+func (s *Store) SetNote(ctx context.Context, manageToken, itemID, note string) (*Item, error) {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return nil, err
+	}
+	note = strings.TrimSpace(note)
+	if len(note) > maxNoteLength {
+		return nil, fmt.Errorf("%w: note is too long", ErrInvalidInput)
+	}
+	for i := range c.Items {
+		if c.Items[i].ID != itemID {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE `+s.items+` SET note = ? WHERE id = ? AND collection_id = ?`, note, itemID, c.ID); err != nil {
+			return nil, fmt.Errorf("update collection item: %w", err)
+		}
+		s.touch(ctx, c.ID)
+		c.Items[i].Note = note
+		return &c.Items[i], nil
+	}
+	return nil, ErrNotFound
+}
+
+// RemoveItem deletes an item and closes the gap it leaves in the order
+func (s *Store) RemoveItem(ctx context.Context, manageToken, itemID string) error {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return err
+	}
+	var order []string
+	found := false
+	for _, item := range c.Items {
+		if item.ID == itemID {
+			found = true
+			continue
+		}
+		order = append(order, item.ID)
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("remove collection item: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+s.items+` WHERE id = ? AND collection_id = ?`, itemID, c.ID); err != nil {
+		return fmt.Errorf("remove collection item: %w", err)
+	}
+	if err := s.writeOrder(ctx, tx, c.ID, order); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.touch(ctx, c.ID)
+	return nil
+}
+
+// Reorder puts the items in the given order. itemIDs must list every item
+// in the collection exactly once.
+func (s *Store) Reorder(ctx context.Context, manageToken string, itemIDs []string) (*Collection, error) {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return nil, err
+	}
+	if len(itemIDs) != len(c.Items) {
+		return nil, fmt.Errorf("%w: the order must list all %d items", ErrInvalidInput, len(c.Items))
+	}
+	byID := make(map[string]Item, len(c.Items))
+	for _, item := range c.Items {
+		byID[item.ID] = item
+	}
+	reordered := make([]Item, 0, len(itemIDs))
+	for i, id := range itemIDs {
+		item, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown or repeated item %q", ErrInvalidInput, id)
+		}
+		delete(byID, id)
+		item.Position = i
+		reordered = append(reordered, item)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reorder collection: %w", err)
+	}
+	defer tx.Rollback()
+	if err := s.writeOrder(ctx, tx, c.ID, itemIDs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.touch(ctx, c.ID)
+	c.Items = reordered
+	return c, nil
+}
+
+// Share turns the read-only share link on or off. Turning it on issues a new
+// share token, so a link that was shared before stops working.
+func (s *Store) Share(ctx context.Context, manageToken string, enabled bool) (string, error) {
+	c, err := s.Get(ctx, manageToken)
+	if err != nil {
+		return "", err
+	}
+	var token string
+	var hash any
+	if enabled {
+		if token, err = randomToken(32); err != nil {
+			return "", err
+		}
+		hash = hashToken(token)
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE `+s.collections+` SET share_token_hash = ?, updated_at = ? WHERE id = ?`,
+		hash, time.Now().UTC(), c.ID); err != nil {
+		return "", fmt.Errorf("share collection: %w", err)
+	}
+	return token, nil
+}
+
+func (s *Store) load(ctx context.Context, column, token string) (*Collection, error) {
+	if s == nil || s.db == nil {
+		return nil, ErrNotFound
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrNotFound
+	}
+
+	var c Collection
+	var shareHash sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT id, name, description, share_token_hash, created_at, updated_at
+		FROM `+s.collections+` WHERE `+column+` = ?`, hashToken(token)).
+		Scan(&c.ID, &c.Name, &c.Description, &shareHash, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load collection: %w", err)
+	}
+	c.Shared = shareHash.Valid && shareHash.String != ""
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, url, title, content, engine, note, position, added_at
+		FROM `+s.items+` WHERE collection_id = ? ORDER BY position ASC, added_at ASC`, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load collection items: %w", err)
+	}
+	defer rows.Close()
+	c.Items = []Item{}
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ID, &item.URL, &item.Title, &item.Content, &item.Engine, &item.Note, &item.Position, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("load collection items: %w", err)
+		}
+		c.Items = append(c.Items, item)
+	}
+	return &c, rows.Err()
+}
+
+// writeOrder numbers the items 0..n-1 in the order given
+func (s *Store) writeOrder(ctx context.Context, tx *sql.Tx, collectionID string, itemIDs []string) error {
+	for i, id := range itemIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE `+s.items+` SET position = ? WHERE id = ? AND collection_id = ?`, i, id, collectionID); err != nil {
+			return fmt.Errorf("reorder collection: %w", err)
+		}
+	}
+	return nil
+}
+
+// touch records that a collection changed; a failure only leaves updated_at
+// stale
+func (s *Store) touch(ctx context.Context, collectionID string) {
+	_, _ = s.db.ExecContext(ctx, `UPDATE `+s.collections+` SET updated_at = ? WHERE id = ?`, time.Now().UTC(), collectionID)
+}
+
+func normalizeDetails(name, description string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	if name == "" {
+		return "", "", fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len(name) > maxNameLength {
+		return "", "", fmt.Errorf("%w: name is too long", ErrInvalidInput)
+	}
+	if len(description) > maxDescriptionLength {
+		return "", "", fmt.Errorf("%w: description is too long", ErrInvalidInput)
+	}
+	return name, description, nil
+}
+
+// normalize trims a new item and checks its URL is a web address
+func (item *Item) normalize() error {
+	item.URL = strings.TrimSpace(item.URL)
+	item.Title = strings.TrimSpace(item.Title)
+	item.Content = strings.TrimSpace(item.Content)
+	item.Engine = strings.TrimSpace(item.Engine)
+	item.Note = strings.TrimSpace(item.Note)
+
+	parsed, err := url.Parse(item.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(item.URL) > maxURLLength {
+		return fmt.Errorf("%w: url must be an http or https address", ErrInvalidInput)
+	}
+	if item.Title == "" {
+		item.Title = item.URL
+	}
+	item.Title = truncate(item.Title, maxTitleLength)
+	item.Content = truncate(item.Content, maxContentLength)
+	if len(item.Note) > maxNoteLength {
+		return fmt.Errorf("%w: note is too long", ErrInvalidInput)
+	}
+	return nil
+}
+
+// truncate cuts s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomToken(bytesLen int) (string, error) {
+	buf := make([]byte, bytesLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
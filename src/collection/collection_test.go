@@ -0,0 +1,191 @@
+package collection
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Server(t), "")
+}
+
+// newTestCollection creates a collection holding one item per URL
+func newTestCollection(t *testing.T, store *Store, urls ...string) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	_, token, err := store.Create(ctx, " Reading list ", "Papers to read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, u := range urls {
+		item, err := store.AddItem(ctx, token, Item{URL: u, Title: "Title " + u}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, item.ID)
+	}
+	return token, ids
+}
+
+func TestCreateAndItems(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	token, ids := newTestCollection(t, store, "https://a.example", "https://b.example", "https://c.example")
+
+	if _, err := store.SetNote(ctx, token, ids[1], "  read first "); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveItem(ctx, token, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := store.Get(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "Reading list" || len(c.Items) != 2 {
+		t.Fatalf("collection = %+v", c)
+	}
+	if c.Items[0].ID != ids[1] || c.Items[0].Position != 0 || c.Items[0].Note != "read first" || c.Items[1].Position != 1 {
+		t.Errorf("items = %+v, want b (with its note) then c, renumbered", c.Items)
+	}
+
+	if _, err := store.Get(ctx, "wrong"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(wrong) error = %v, want ErrNotFound", err)
+	}
+	if err := store.RemoveItem(ctx, token, ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveItem(removed) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAddItemValidation(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	token, _ := newTestCollection(t, store, "https://a.example")
+
+	for _, u := range []string{"", "javascript:alert(1)", "ftp://files.example", "https://"} {
+		if _, err := store.AddItem(ctx, token, Item{URL: u}, 0); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("AddItem(%q) error = %v, want ErrInvalidInput", u, err)
+		}
+	}
+	if _, err := store.AddItem(ctx, token, Item{URL: "https://b.example"}, 1); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("AddItem past max_items error = %v, want ErrInvalidInput", err)
+	}
+	if _, _, err := store.Create(ctx, "  ", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Create(blank name) error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestReorder(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	token, ids := newTestCollection(t, store, "https://a.example", "https://b.example", "https://c.example")
+
+	for name, order := range map[string][]string{
+		"missing":  {ids[0], ids[1]},
+		"repeated": {ids[0], ids[0], ids[1]},
+		"unknown":  {ids[0], ids[1], "other"},
+	} {
+		if _, err := store.Reorder(ctx, token, order); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: error = %v, want ErrInvalidInput", name, err)
+		}
+	}
+
+	if _, err := store.Reorder(ctx, token, []string{ids[2], ids[0], ids[1]}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := store.Get(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{ids[2], ids[0], ids[1]} {
+		if c.Items[i].ID != want {
+			t.Fatalf("items = %+v, want c, a, b", c.Items)
+		}
+	}
+}
+
+func TestShareAndDelete(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	token, _ := newTestCollection(t, store, "https://a.example")
+
+	first, err := store.Share(ctx, token, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := store.GetShared(ctx, first)
+	if err != nil || !shared.Shared || len(shared.Items) != 1 {
+		t.Fatalf("GetShared() = %+v, %v", shared, err)
+	}
+	if _, err := store.Get(ctx, first); !errors.Is(err, ErrNotFound) {
+		t.Error("the share token must not manage the collection")
+	}
+
+	// Sharing again replaces the link
+	second, err := store.Share(ctx, token, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetShared(ctx, first); !errors.Is(err, ErrNotFound) {
+		t.Error("the old share link still works")
+	}
+	if _, err := store.Share(ctx, token, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetShared(ctx, second); !errors.Is(err, ErrNotFound) {
+		t.Error("the share link works after sharing was turned off")
+	}
+
+	if err := store.Delete(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	var items int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM collection_items`).Scan(&items); err != nil || items != 0 {
+		t.Errorf("%d items left after Delete(), %v", items, err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	c := &Collection{
+		Name: "Go [notes]",
+		Items: []Item{
+			{URL: "https://go.dev/doc", Title: "Docs", Note: "start here\nthen the spec"},
+			{URL: "https://example.com", Title: "=HYPERLINK(\"x\")"},
+		},
+	}
+
+	var md bytes.Buffer
+	if err := c.Export(&md, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Go \\[notes\\]", "1. [Docs](<https://go.dev/doc>)", "   > then the spec"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := c.Export(&out, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[2][1] != `'=HYPERLINK("x")` {
+		t.Errorf("csv rows = %q, want a header, two items and an escaped formula", rows)
+	}
+
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("ParseFormat(xml) error = %v, want ErrInvalidInput", err)
+	}
+}
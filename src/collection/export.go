@@ -0,0 +1,118 @@
+package collection
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatCSV      = "csv"
+)
+
+// ParseFormat returns the export format for a ?format= value; empty is JSON
+func ParseFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("%w: format must be json, markdown or csv", ErrInvalidInput)
+}
+
+// ContentType returns the Content-Type for an export format
+func ContentType(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// FileExtension returns the download file extension for an export format
+func FileExtension(format string) string {
+	if format == FormatMarkdown {
+		return "md"
+	}
+	return format
+}
+
+// Export writes the collection in the given format
+func (c *Collection) Export(w io.Writer, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return c.ToMarkdown(w)
+	case FormatCSV:
+		return c.ToCSV(w)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// ToMarkdown writes the collection as a numbered Markdown list, with each
+// note as a quote under its link
+func (c *Collection) ToMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownText(c.Name))
+	if c.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", c.Description)
+	}
+	for i, item := range c.Items {
+		fmt.Fprintf(&b, "%d. [%s](<%s>)\n", i+1, markdownText(item.Title), item.URL)
+		if item.Note != "" {
+			for _, line := range strings.Split(item.Note, "\n") {
+				fmt.Fprintf(&b, "   > %s\n", line)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ToCSV writes one row per item, in order
+// Uses idiomatic csv.Writer pattern: write all data, then check for accumulated errors
+func (c *Collection) ToCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Position", "Title", "URL", "Content", "Engine", "Note", "Added"})
+	for i, item := range c.Items {
+		writer.Write([]string{
+			strconv.Itoa(i + 1),
+			csvCell(item.Title),
+			csvCell(item.URL),
+			csvCell(item.Content),
+			csvCell(item.Engine),
+			csvCell(item.Note),
+			item.AddedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// markdownText escapes the characters that would end a heading or link text
+func markdownText(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+// csvCell keeps a spreadsheet from reading a saved title or note as a
+// formula
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PrivacySignals are the tracking opt-outs a browser sends with a request:
+// Do Not Track (DNT: 1) and Global Privacy Control (Sec-GPC: 1)
+type PrivacySignals struct {
+	DNT bool `json:"dnt"`
+	GPC bool `json:"gpc"`
+}
+
+// RequestPrivacySignals reads the privacy signals of a request
+func RequestPrivacySignals(r *http.Request) PrivacySignals {
+	return PrivacySignals{
+		DNT: strings.TrimSpace(r.Header.Get("DNT")) == "1",
+		GPC: strings.TrimSpace(r.Header.Get("Sec-GPC")) == "1",
+	}
+}
+
+// OptOut reports whether either signal asks not to be tracked. Both are
+// honored alike: an opted-out request is not ranked by location and leaves
+// nothing about the client in the access log.
+func (p PrivacySignals) OptOut() bool {
+	return p.DNT || p.GPC
+}
+
+// String names the signals sent, as in "Do Not Track"
+func (p PrivacySignals) String() string {
+	switch {
+	case p.DNT && p.GPC:
+		return "Do Not Track, Global Privacy Control"
+	case p.DNT:
+		return "Do Not Track"
+	case p.GPC:
+		return "Global Privacy Control"
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package httputil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestPrivacySignals(t *testing.T) {
+	tests := []struct {
+		dnt, gpc string
+		want     PrivacySignals
+		name     string
+	}{
+		{"", "", PrivacySignals{}, ""},
+		{"1", "", PrivacySignals{DNT: true}, "Do Not Track"},
+		{"0", " 1 ", PrivacySignals{GPC: true}, "Global Privacy Control"},
+		{"1", "1", PrivacySignals{DNT: true, GPC: true}, "Do Not Track, Global Privacy Control"},
+		{"yes", "true", PrivacySignals{}, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.dnt != "" {
+			req.Header.Set("DNT", tt.dnt)
+		}
+		if tt.gpc != "" {
+			req.Header.Set("Sec-GPC", tt.gpc)
+		}
+		got := RequestPrivacySignals(req)
+		if got != tt.want || got.String() != tt.name || got.OptOut() != (tt.name != "") {
+			t.Errorf("DNT %q, Sec-GPC %q = %+v (%q, opt out %v)", tt.dnt, tt.gpc, got, got.String(), got.OptOut())
+		}
+	}
+}
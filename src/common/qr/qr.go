@@ -0,0 +1,72 @@
+// Package qr renders QR codes for the instance and onion addresses, so mobile
+// users can switch to them by scanning: PNG and SVG for web pages, block
+// characters for terminal output.
+package qr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// DefaultSize is the PNG edge length in pixels
+const DefaultSize = 256
+
+// PNG encodes content as a size×size PNG
+func PNG(content string, size int) ([]byte, error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return q.PNG(size)
+}
+
+// SVG encodes content as an SVG image. Dark modules are drawn as one path so
+// the image scales without blurring.
+func SVG(content string) (string, error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+	bitmap := q.Bitmap()
+	n := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`,
+		n, n, n, n, path.String()), nil
+}
+
+// Terminal renders content for a dark terminal: light modules are drawn and
+// dark ones left blank. unicode packs two rows per line with half blocks;
+// otherwise each module is two '#' characters, for terminals without them.
+func Terminal(content string, unicode bool) (string, error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+	if unicode {
+		return q.ToSmallString(false), nil
+	}
+
+	var b strings.Builder
+	for _, row := range q.Bitmap() {
+		for _, dark := range row {
+			if dark {
+				b.WriteString("  ")
+			} else {
+				b.WriteString("##")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
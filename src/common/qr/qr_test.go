@@ -0,0 +1,55 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	png, err := PNG("http://example.onion", DefaultSize)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("PNG() did not return a PNG image")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg, err := SVG("https://search.example.com")
+	if err != nil {
+		t.Fatalf("SVG() error = %v", err)
+	}
+	if !strings.HasPrefix(svg, "<svg ") || !strings.Contains(svg, `<path d="M`) {
+		t.Errorf("SVG() = %.80q, want an svg with a module path", svg)
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	ascii, err := Terminal("https://search.example.com", false)
+	if err != nil {
+		t.Fatalf("Terminal() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(ascii, "\n"), "\n")
+	if len(lines) == 0 || len(lines[0]) != 2*len(lines) {
+		t.Errorf("Terminal(ascii) is not square: %d lines of %d chars", len(lines), len(lines[0]))
+	}
+	if strings.ContainsAny(ascii, "█▀▄") {
+		t.Error("Terminal(ascii) contains block characters")
+	}
+
+	blocks, err := Terminal("https://search.example.com", true)
+	if err != nil {
+		t.Fatalf("Terminal() error = %v", err)
+	}
+	if got := strings.Count(blocks, "\n"); got != (len(lines)+1)/2 {
+		t.Errorf("Terminal(unicode) has %d lines, want %d", got, (len(lines)+1)/2)
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := PNG(strings.Repeat("x", 4000), DefaultSize); err == nil {
+		t.Error("PNG() accepted content beyond QR capacity")
+	}
+}
@@ -108,6 +108,12 @@ func GetSymbols() Symbols {
 	return ASCIISymbols
 }
 
+// SupportsUnicode reports whether the terminal likely renders Unicode, for
+// output that has no entry in Symbols (such as QR codes)
+func SupportsUnicode() bool {
+	return supportsUnicode()
+}
+
 // supportsUnicode checks if the terminal likely supports Unicode
 func supportsUnicode() bool {
 	// Check LANG/LC_ALL for UTF-8
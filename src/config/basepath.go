@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLPath returns p, a path from the instance root such as "/search",
+// under server.base_path
+func (s *ServerConfig) URLPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return s.BasePath + p
+}
+
+// PublicURL returns the instance's URL without a trailing slash:
+// server.base_url, or origin (such as "https://example.com") when it is
+// unset, with server.base_path appended unless the URL ends with it
+// already. It is "" when both are empty.
+func (s *ServerConfig) PublicURL(origin string) string {
+	base := strings.TrimRight(strings.TrimSpace(s.BaseURL), "/")
+	if base == "" {
+		if origin == "" {
+			return ""
+		}
+		base = strings.TrimRight(origin, "/")
+	}
+	if !strings.HasSuffix(base, s.BasePath) {
+		base += s.BasePath
+	}
+	return base
+}
+
+// NormalizeBasePath returns path as a base path: "" for the root, else
+// starting with a slash and without a trailing one. ok is false when path
+// holds characters or segments a URL prefix cannot have.
+func NormalizeBasePath(path string) (string, bool) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return "", true
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", false
+		}
+		for _, r := range segment {
+			if !isBasePathChar(r) {
+				return "", false
+			}
+		}
+	}
+	return "/" + path, true
+}
+
+// isBasePathChar reports whether r may appear unescaped in a base path
+func isBasePathChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-._~", r)
+}
+
+// validateBasePath normalizes server.base_path. The --baseurl flag or
+// SEARCH_BASE_URL, a path or a URL whose path is used, overrides it.
+// Called with c.mu held.
+func (c *Config) validateBasePath() []ValidationWarning {
+	field := "server.base_path"
+	value := c.Server.BasePath
+	if override := GetBaseURL(); override != "/" {
+		field = "--baseurl"
+		value = override
+		if u, err := url.Parse(override); err == nil && u.Host != "" {
+			value = u.Path
+		}
+	}
+	path, ok := NormalizeBasePath(value)
+	if !ok {
+		c.Server.BasePath = ""
+		return []ValidationWarning{{
+			Field:   field,
+			Message: fmt.Sprintf("'%s' is not a URL path of letters, digits, '-', '.', '_' and '~', serving at /", value),
+		}}
+	}
+	c.Server.BasePath = path
+	return nil
+}
@@ -9,13 +9,18 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/model"
 	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
 )
@@ -259,18 +264,29 @@ type AllowlistEntry struct {
 // ServerConfig represents server configuration
 type ServerConfig struct {
 	// Core settings
-	Title       string `yaml:"title"`
-	Description string `yaml:"description"`
+	Title       string `yaml:"title" env:"APPLICATION_NAME,first-run" desc:"Instance name"`
+	Description string `yaml:"description" desc:"Instance description"`
 	// HTTP port (or single port if HTTPSPort not set)
-	Port int `yaml:"port"`
+	Port int `yaml:"port" env:"PORT,first-run"`
 	// HTTPS port for dual port mode (optional)
 	HTTPSPort int    `yaml:"https_port"`
-	Address   string `yaml:"address"`
-	Mode      string `yaml:"mode"`
-	SecretKey string `yaml:"secret_key"`
-	BaseURL   string `yaml:"base_url"`
+	Address   string `yaml:"address" env:"LISTEN,first-run" desc:"Address to listen on"`
+	Mode      string `yaml:"mode" env:"MODE" desc:"production or development"`
+	SecretKey string `yaml:"secret_key" desc:"Secret for in-memory caches, generated on first run"`
+	BaseURL   string `yaml:"base_url" desc:"Public URL of the instance; empty derives it from requests"`
+	// BasePath serves the instance under a path, such as "/search" for
+	// https://example.com/search/ behind a shared reverse proxy
+	BasePath string `yaml:"base_path" desc:"Path the instance is served under; empty serves it at /"`
 	// Fully qualified domain name — auto-detected from host if empty
-	FQDN string `yaml:"fqdn"`
+	FQDN string `yaml:"fqdn" env:"DOMAIN"`
+	// AllowedHosts are the Host headers the instance answers, such as
+	// "example.com" or "*.example.com"; empty answers any
+	AllowedHosts []string `yaml:"allowed_hosts" desc:"Host names the instance answers; empty answers any"`
+	// UnexpectedHost is what a request for a host not in allowed_hosts
+	// gets: reject (421 Misdirected Request) or redirect (301)
+	UnexpectedHost string `yaml:"unexpected_host" desc:"reject or redirect"`
+	// CanonicalHost sends other hosts and plain HTTP to one URL with a 301
+	CanonicalHost CanonicalHostConfig `yaml:"canonical_host"`
 	// API version prefix used in /api/{api_version}/ routes
 	APIVersion string `yaml:"api_version"`
 	// PID file path; "true" uses the default platform path, "false" disables
@@ -289,6 +305,8 @@ type ServerConfig struct {
 	// Auto-generated on first run if empty. Validated by SHA-256 comparison.
 	// Per AI.md: two-tier auth — server.token + per-resource api_tokens.
 	Token string `yaml:"token"`
+	// Short-lived signed tokens for the operator API
+	JWT JWTConfig `yaml:"jwt"`
 
 	// Branding
 	Branding BrandingConfig `yaml:"branding"`
@@ -338,6 +356,10 @@ type ServerConfig struct {
 	// Tracking — server-wide analytics platform per AI.md PART 12
 	Tracking TrackingConfig `yaml:"tracking"`
 
+	// Features declares dark-launch flags by name; the operator API can
+	// override them at runtime
+	Features map[string]FeatureConfig `yaml:"features"`
+
 	// SEO
 	SEO SEOConfig `yaml:"seo"`
 
@@ -371,6 +393,18 @@ type ServerConfig struct {
 
 	// Maintenance mode self-healing configuration
 	Maintenance MaintenanceSelfHealConfig `yaml:"maintenance"`
+
+	// Retention limits per data class, enforced by the retention task
+	Retention RetentionConfig `yaml:"retention"`
+
+	// Memory watchdog: shrinks caches and tightens GOGC under pressure
+	Memory MemoryConfig `yaml:"memory"`
+
+	// Cache-Control policies of the cacheable API endpoints
+	APICache APICacheConfig `yaml:"api_cache"`
+
+	// CDN/reverse-cache friendly responses and /.well-known/cache-policy
+	CDN CDNConfig `yaml:"cdn"`
 }
 
 // SSLConfig represents SSL/TLS configuration
@@ -406,9 +440,9 @@ type DNS01Config struct {
 // Per AI.md PART 13/16: branding fields for healthz project info
 type BrandingConfig struct {
 	// Per PART 13: project.name source
-	Title string `yaml:"title"`
+	Title string `yaml:"title" env:"APPLICATION_NAME,first-run" desc:"Name shown in the page header and health report"`
 	// Per PART 13: project.tagline source
-	Tagline string `yaml:"tagline"`
+	Tagline string `yaml:"tagline" env:"APPLICATION_TAGLINE,first-run" desc:"Tagline shown with the name"`
 	// Per PART 13: project.description source
 	Description string `yaml:"description"`
 	// Per AI.md: {PLATFORM_REPO_URL} - repository URL
@@ -444,16 +478,10 @@ type RateLimitConfig struct {
 
 // LogsConfig represents logging configuration
 type LogsConfig struct {
-	Level  string `yaml:"level"`
-	File   string `yaml:"file"`
-	Format string `yaml:"format"`
-	Access struct {
-		Filename string `yaml:"filename"`
-		Format   string `yaml:"format"`
-		Custom   string `yaml:"custom"`
-		Rotate   string `yaml:"rotate"`
-		Keep     string `yaml:"keep"`
-	} `yaml:"access"`
+	Level  string          `yaml:"level"`
+	File   string          `yaml:"file"`
+	Format string          `yaml:"format"`
+	Access AccessLogConfig `yaml:"access"`
 	Server struct {
 		Filename string `yaml:"filename"`
 		Format   string `yaml:"format"`
@@ -492,6 +520,85 @@ type LogsConfig struct {
 	} `yaml:"debug"`
 }
 
+// AccessLogConfig is the access log: its format, what it may record about
+// a client and when it rotates
+type AccessLogConfig struct {
+	// Disabled writes no access log at all
+	Disabled bool   `yaml:"disabled"`
+	Filename string `yaml:"filename"`
+	// apache (combined), nginx (common), json or custom
+	Format string `yaml:"format"`
+	// Format string with $variables, for format custom
+	Custom string `yaml:"custom"`
+	// strict records no client address or user agent; anonymized records
+	// the address truncated to its network and a hash of the user agent.
+	// Query strings and referers are never recorded.
+	Privacy string `yaml:"privacy"`
+	// daily, weekly, monthly and/or a size such as 50MB, comma separated;
+	// none never rotates
+	Rotate string `yaml:"rotate"`
+	Keep   string `yaml:"keep"`
+}
+
+// AccessLogFormats are the accepted access log formats
+var AccessLogFormats = []string{"apache", "nginx", "json", "custom"}
+
+// AccessLogPrivacy are the accepted access log privacy presets
+var AccessLogPrivacy = []string{"strict", "anonymized"}
+
+// LogRotation is a parsed log rotation policy
+type LogRotation struct {
+	// daily, weekly, monthly or empty
+	Period string
+	// Rotate once the log reaches this many bytes; 0 for no limit
+	Size int64
+}
+
+// ParseLogRotation parses a rotation policy such as "monthly" or
+// "weekly,50MB". "none" or empty never rotates.
+func ParseLogRotation(policy string) (LogRotation, error) {
+	var rot LogRotation
+	for _, part := range strings.Split(strings.ToLower(policy), ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "", "none":
+		case "daily", "weekly", "monthly":
+			if rot.Period != "" {
+				return rot, fmt.Errorf("'%s' sets two periods", policy)
+			}
+			rot.Period = part
+		default:
+			size, err := parseByteSize(part)
+			if err != nil || size <= 0 || rot.Size != 0 {
+				return rot, fmt.Errorf("'%s' is not daily, weekly, monthly or a size", policy)
+			}
+			rot.Size = size
+		}
+	}
+	return rot, nil
+}
+
+// parseByteSize parses a size such as 50MB or 1GB
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
 // TorConfig represents Tor configuration
 // Per AI.md PART 32: "Auto-enabled if tor binary is installed - no enable flag needed"
 type TorConfig struct {
@@ -503,6 +610,9 @@ type TorConfig struct {
 
 	// Binary path (empty = auto-detect)
 	Binary string `yaml:"binary"`
+	// Disabled keeps the hidden service off even when a tor binary is
+	// installed (default false: auto-enable when found)
+	Disabled bool `yaml:"disabled"`
 
 	// --- Outbound Network Settings ---
 	// Use Tor network for outbound connections (server-wide default)
@@ -540,6 +650,34 @@ type TorConfig struct {
 	// Virtual port for hidden service (1-65535, default 80)
 	// Per AI.md PART 32: yaml key is "virtual_port"
 	VirtualPort int `yaml:"virtual_port"`
+
+	// --- Vanity Address Settings ---
+	// Goroutines searching for a vanity prefix (0 = one per CPU core, default 0)
+	VanityWorkers int `yaml:"vanity_workers"`
+
+	// --- Additional Hidden Services ---
+	// Extra onions published alongside the main one, each with its own keys
+	// (e.g. a separate onion for API clients). Empty by default.
+	Services []TorServiceConfig `yaml:"services"`
+}
+
+// Hidden service scopes: which routes an additional onion serves
+const (
+	// TorScopeAll serves every route, like the main onion
+	TorScopeAll = "all"
+	// TorScopeAPI serves only /api/ and health checks
+	TorScopeAPI = "api"
+)
+
+// TorServiceConfig is an additional hidden service
+type TorServiceConfig struct {
+	// Name identifies the service and its key directory (letters, digits, '-', '_')
+	Name    string `yaml:"name"`
+	Enabled bool   `yaml:"enabled"`
+	// Virtual port on the onion (1-65535, default 80)
+	VirtualPort int `yaml:"virtual_port"`
+	// Scope: "all" (default) or "api"
+	Scope string `yaml:"scope"`
 }
 
 // EmailConfig represents email/SMTP configuration
@@ -557,21 +695,21 @@ type EmailConfig struct {
 type SMTPConfig struct {
 	// If empty: autodetect local SMTP on startup
 	// If set: test connection on startup
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
 	// TLS mode: auto, starttls, tls, none
-	TLS string `yaml:"tls"`
+	TLS string `yaml:"tls" env:"SMTP_TLS"`
 }
 
 // EmailFromConfig represents the from address configuration
 // Per AI.md PART 18: From name and email defaults
 type EmailFromConfig struct {
 	// Default: app title
-	Name string `yaml:"name"`
+	Name string `yaml:"name" env:"SMTP_FROM_NAME"`
 	// Default: no-reply@{fqdn}
-	Email string `yaml:"email"`
+	Email string `yaml:"email" env:"SMTP_FROM_EMAIL"`
 }
 
 // SecurityConfig represents security configuration
@@ -587,6 +725,8 @@ type SecurityConfig struct {
 	// security report bodies (AES fallback when no PGP keypair exists), and any future
 	// at-rest encrypted data. Never logged, never returned in any API response.
 	EncryptionKey string `yaml:"encryption_key"`
+	// Versions and scheduled rotation of the server secrets
+	Keys KeysConfig `yaml:"keys"`
 	// CSRF
 	CSRF struct {
 		Enabled    bool   `yaml:"enabled"`
@@ -594,6 +734,8 @@ type SecurityConfig struct {
 		HeaderName string `yaml:"header_name"`
 		FieldName  string `yaml:"field_name"`
 	} `yaml:"csrf"`
+	// SameSite, Domain, Path and Secure of the session and CSRF cookies
+	Cookies CookiesConfig `yaml:"cookies"`
 	// Headers
 	Headers struct {
 		XFrameOptions         string `yaml:"x_frame_options"`
@@ -830,12 +972,128 @@ type SchedulerTasksConfig struct {
 	BackupDaily TaskConfig `yaml:"backup_daily"`
 	// Hourly incremental backup (skippable, disabled by default)
 	BackupHourly TaskConfig `yaml:"backup_hourly"`
+	// Weekly verification of stored backups (skippable)
+	BackupVerify TaskConfig `yaml:"backup_verify"`
 	// GeoIP database update (skippable)
 	GeoIPUpdate TaskConfig `yaml:"geoip_update"`
 	// Blocklist update (skippable)
 	BlocklistUpdate TaskConfig `yaml:"blocklist_update"`
 	// CVE database update (skippable)
 	CVEUpdate TaskConfig `yaml:"cve_update"`
+	// Precompute search.warm_queries off-peak (skippable)
+	CacheWarm TaskConfig `yaml:"cache_warm"`
+	// Apply server.retention limits (skippable)
+	Retention TaskConfig `yaml:"retention"`
+	// Download search.screening feeds (skippable; only runs when screening is enabled)
+	ThreatFeedUpdate TaskConfig `yaml:"threat_feed_update"`
+	// Rotate the secrets due under server.security.keys (skippable)
+	KeyRotation TaskConfig `yaml:"key_rotation"`
+}
+
+// RetentionConfig sets how long each class of stored data is kept. The
+// retention task always reports what is over a limit; it only deletes when
+// Enforce is set, so the report can be reviewed first.
+type RetentionConfig struct {
+	// Delete data over its limits; false only reports (default: false)
+	Enforce bool `yaml:"enforce"`
+	// Rotated log files in the log directory; the active logs are never removed
+	Logs RetentionRule `yaml:"logs"`
+	// Stored search alert results
+	History RetentionRule `yaml:"history"`
+	// Search and engine statistics and scheduler run history
+	Metrics RetentionRule `yaml:"metrics"`
+	// Files in the cache directory
+	Cache RetentionRule `yaml:"cache"`
+}
+
+// RetentionRule limits one data class; an empty limit is not applied
+type RetentionRule struct {
+	// Remove data older than this (e.g., "30d", "12h")
+	MaxAge string `yaml:"max_age"`
+	// Keep file classes under this total size (e.g., "500MB"), oldest removed first
+	MaxSize string `yaml:"max_size"`
+	// Keep at most this many rows per table in table classes, oldest removed first
+	MaxRows int `yaml:"max_rows"`
+}
+
+// MemoryConfig tunes the memory watchdog. As the process nears its memory
+// limit it halves the in-memory caches and lowers GOGC (soft threshold),
+// then cuts the caches to a fifth and returns freed memory to the operating
+// system (hard threshold), so a small host does not kill the process.
+type MemoryConfig struct {
+	// Turn the watchdog off (default: false, the watchdog runs)
+	Disabled bool `yaml:"disabled"`
+	// Memory available to the process (e.g., "512MB"); "auto" uses the
+	// container's cgroup limit or, without one, the host's memory
+	Limit string `yaml:"limit"`
+	// Percent of the limit at which pressure is elevated (default: 70)
+	SoftPercent int `yaml:"soft_percent"`
+	// Percent of the limit at which pressure is critical (default: 85)
+	HardPercent int `yaml:"hard_percent"`
+	// How often memory is checked (default: "10s")
+	Interval string `yaml:"interval"`
+}
+
+// APICacheConfig sets the Cache-Control header of the cacheable API
+// endpoints, by endpoint group. Their responses also carry an ETag, so a
+// client or CDN holding a copy revalidates it with If-None-Match and gets
+// 304 Not Modified while it is unchanged. An empty value uses the default.
+type APICacheConfig struct {
+	// /api/v1/engines and /api/v1/engines/{id} (default: "public, max-age=60")
+	Engines string `yaml:"engines"`
+	// /api/v1/categories (default: "public, max-age=86400")
+	Categories string `yaml:"categories"`
+	// /api/v1/bangs (default: "public, max-age=86400")
+	Bangs string `yaml:"bangs"`
+	// /api/v1/info and /api/autodiscover (default: "public, max-age=60")
+	Info string `yaml:"info"`
+	// /api/v1/widgets and widget data, which may carry a visitor's
+	// location or tracking numbers (default: "private, max-age=300")
+	Widgets string `yaml:"widgets"`
+}
+
+// CDNConfig prepares responses for a CDN or reverse cache (Varnish,
+// Cloudflare) in front of the server: responses that are the same for
+// every visitor are marked publicly cacheable, all others private, query
+// parameters are put in one canonical order, and the cache keys are
+// described at /.well-known/cache-policy.
+type CDNConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Seconds shared caches keep static assets (default: 86400)
+	StaticMaxAge int `yaml:"static_max_age"`
+}
+
+// API cache groups, as named in server.api_cache
+const (
+	APICacheEngines    = "engines"
+	APICacheCategories = "categories"
+	APICacheBangs      = "bangs"
+	APICacheInfo       = "info"
+	APICacheWidgets    = "widgets"
+)
+
+// Policy returns the Cache-Control policy of an API cache group, or its
+// default when none is set
+func (c APICacheConfig) Policy(group string) string {
+	var policy, fallback string
+	switch group {
+	case APICacheEngines:
+		policy, fallback = c.Engines, "public, max-age=60"
+	case APICacheCategories:
+		policy, fallback = c.Categories, "public, max-age=86400"
+	case APICacheBangs:
+		policy, fallback = c.Bangs, "public, max-age=86400"
+	case APICacheInfo:
+		policy, fallback = c.Info, "public, max-age=60"
+	case APICacheWidgets:
+		// Widget data can carry a visitor's location or tracking numbers,
+		// so shared caches must not keep it
+		policy, fallback = c.Widgets, "private, max-age=300"
+	}
+	if policy == "" {
+		return fallback
+	}
+	return policy
 }
 
 // TaskConfig represents configuration for a scheduled task
@@ -932,6 +1190,18 @@ type BackupEncryptionConfig struct {
 	Enabled bool `yaml:"enabled"`
 	// Optional password hint (stored, NOT the password)
 	Hint string `yaml:"hint"`
+	// Path to a keyfile needed, with the password if one is set, to decrypt
+	// backups; BACKUP_KEYFILE overrides it
+	Keyfile string `yaml:"keyfile,omitempty"`
+	// Public key (bkpub_...) backups are encrypted to; only its private
+	// key, kept off the server, can restore them
+	Recipient string `yaml:"recipient,omitempty"`
+}
+
+// IsEnabled reports whether backups are encrypted: set up with a
+// password, or configured with a keyfile or public key
+func (e BackupEncryptionConfig) IsEnabled() bool {
+	return e.Enabled || e.Keyfile != "" || e.Recipient != ""
 }
 
 // BackupRetentionConfig represents backup retention policy
@@ -1165,24 +1435,746 @@ type I18nConfig struct {
 
 // SearchConfig represents search configuration
 type SearchConfig struct {
-	SafeSearch        int              `yaml:"safe_search"`
-	Autocomplete      string           `yaml:"autocomplete"`
-	DefaultLang       string           `yaml:"default_lang"`
-	DefaultCategories []string         `yaml:"default_categories"`
-	ResultsPerPage    int              `yaml:"results_per_page"`
-	Timeout           int              `yaml:"timeout"`
-	MaxConcurrent     int              `yaml:"max_concurrent"`
-	Bangs             BangsConfig      `yaml:"bangs"`
-	OpenSearch        OpenSearchConfig `yaml:"opensearch"`
-	Widgets           WidgetsConfig    `yaml:"widgets"`
-	Alerts            AlertsConfig     `yaml:"alerts"`
+	SafeSearch int `yaml:"safe_search" desc:"Default safe search level: 0 off, 1 moderate, 2 strict"`
+	// SafeSearchLocked applies safe_search to every search, ignoring the
+	// level a visitor asks for
+	SafeSearchLocked  bool     `yaml:"safe_search_locked"`
+	Autocomplete      string   `yaml:"autocomplete"`
+	DefaultLang       string   `yaml:"default_lang"`
+	DefaultCategories []string `yaml:"default_categories"`
+	// DisabledCategories are not searchable on this instance, for example
+	// files where torrent indexes are not allowed; general cannot be
+	// disabled
+	DisabledCategories []string             `yaml:"disabled_categories"`
+	ResultsPerPage     int                  `yaml:"results_per_page"`
+	Timeout            int                  `yaml:"timeout" desc:"Seconds a search waits for engines"`
+	MaxConcurrent      int                  `yaml:"max_concurrent" desc:"Engines queried at once per search"`
+	Bangs              BangsConfig          `yaml:"bangs"`
+	OpenSearch         OpenSearchConfig     `yaml:"opensearch"`
+	Widgets            WidgetsConfig        `yaml:"widgets"`
+	Market             MarketConfig         `yaml:"market"`
+	InstantAnswers     InstantAnswersConfig `yaml:"instant_answers"`
+	Preview            PreviewConfig        `yaml:"preview"`
+	Alerts             AlertsConfig         `yaml:"alerts"`
+	WarmQueries        WarmQueriesConfig    `yaml:"warm_queries"`
+	Permalinks         PermalinksConfig     `yaml:"permalinks"`
+	Collections        CollectionsConfig    `yaml:"collections"`
+	Preferences        PreferencesConfig    `yaml:"preferences"`
+	Reports            ReportsConfig        `yaml:"reports"`
+	Screening          ScreeningConfig      `yaml:"screening"`
+	Suggestions        SuggestionsConfig    `yaml:"suggestions"`
+	// CategoryEngines lists the engines queried for a category, in order.
+	// A category that is not listed uses every engine that supports it.
+	CategoryEngines map[string][]CategoryEngineConfig `yaml:"category_engines"`
+	// SafeSearchFilter is the blocklist applied to engines that have no
+	// safe search parameter of their own
+	SafeSearchFilter SafeSearchFilterConfig `yaml:"safe_search_filter"`
+	// ImageClassifier hides adult images from strict safe search
+	ImageClassifier ImageClassifierConfig `yaml:"image_classifier"`
+	// EngineLimits bounds every engine response; engines.<name>.limits
+	// overrides it per engine
+	EngineLimits EngineLimitsConfig `yaml:"engine_limits"`
+	// RenderCache keeps rendered result pages for anonymous visitors
+	RenderCache RenderCacheConfig `yaml:"render_cache"`
+	// ResultCache reuses the results of a search for the same query
+	ResultCache ResultCacheConfig `yaml:"result_cache"`
+	// ReverseImage forwards an image to reverse image search engines
+	ReverseImage ReverseImageConfig `yaml:"reverse_image"`
+	// VideoPlayer plays video results on this instance
+	VideoPlayer VideoPlayerConfig `yaml:"video_player"`
+	// Maps sets the Nominatim server, tile server and Leaflet copy of
+	// the maps category
+	Maps MapsConfig `yaml:"maps"`
+	// GeoBoost ranks results from the searcher's GeoIP country higher
+	GeoBoost GeoBoostConfig `yaml:"geo_boost"`
+	// SchemaDrift flags engines whose responses no longer have the
+	// structure their parser expects
+	SchemaDrift SchemaDriftConfig `yaml:"schema_drift"`
+	// Politeness spaces and caps engine requests per upstream host
+	Politeness PolitenessConfig `yaml:"politeness"`
+	// UserAgents picks the user agent engine requests are sent as
+	UserAgents UserAgentsConfig `yaml:"user_agents"`
+	// Ranking adjusts the scores of merged results before they are sorted
+	Ranking RankingConfig `yaml:"ranking"`
+	// CircuitBreaker skips engines that keep failing or answering slowly
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	// Macros are saved searches with parameters every user can invoke
+	// with ~name, next to the ones each user keeps in a cookie
+	Macros []MacroConfig `yaml:"macros"`
+	// Chaos injects latency and failures into engines, in development
+	// mode only
+	Chaos ChaosConfig `yaml:"chaos"`
+}
+
+// ResolveSafeSearch returns the safe search level for a search that asked
+// for requested: requested, or safe_search while it is locked
+func (s SearchConfig) ResolveSafeSearch(requested int) int {
+	if s.SafeSearchLocked {
+		return s.SafeSearch
+	}
+	return requested
+}
+
+// CategoryEnabled reports whether category is searchable: it is not
+// listed in disabled_categories
+func (s SearchConfig) CategoryEnabled(category string) bool {
+	category = strings.ToLower(strings.TrimSpace(category))
+	for _, disabled := range s.DisabledCategories {
+		if strings.ToLower(strings.TrimSpace(disabled)) == category && category != "general" {
+			return false
+		}
+	}
+	return true
+}
+
+// CategoryEngineConfig is one engine in a category's engine list
+type CategoryEngineConfig struct {
+	Engine string `yaml:"engine" json:"engine"`
+	// Weight scales the engine's result scores in this category (default 1.0)
+	Weight float64 `yaml:"weight" json:"weight"`
+}
+
+// MaxCategoryEngineWeight caps per-category engine weights
+const MaxCategoryEngineWeight = 10.0
+
+// NormalizeCategoryEngines checks a category engine list and returns it with
+// names lowercased and unset weights defaulted to 1.0. An empty list is an
+// error: a category must keep at least one engine.
+func NormalizeCategoryEngines(list []CategoryEngineConfig) ([]CategoryEngineConfig, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("category has no engines")
+	}
+	seen := make(map[string]bool, len(list))
+	normalized := make([]CategoryEngineConfig, 0, len(list))
+	for _, e := range list {
+		e.Engine = strings.ToLower(strings.TrimSpace(e.Engine))
+		switch {
+		case e.Engine == "":
+			return nil, fmt.Errorf("engine name is empty")
+		case seen[e.Engine]:
+			return nil, fmt.Errorf("engine '%s' is listed twice", e.Engine)
+		case e.Weight < 0 || e.Weight > MaxCategoryEngineWeight:
+			return nil, fmt.Errorf("engine '%s' weight %g is outside 0-%g", e.Engine, e.Weight, MaxCategoryEngineWeight)
+		}
+		if e.Weight == 0 {
+			e.Weight = 1.0
+		}
+		seen[e.Engine] = true
+		normalized = append(normalized, e)
+	}
+	return normalized, nil
+}
+
+// SuggestionsConfig controls where autocomplete suggestions come from
+type SuggestionsConfig struct {
+	// Providers asked for every suggestion request: duckduckgo, google,
+	// brave or wikipedia. Each weight (0-10, default 1) scales the provider's
+	// say in the merged order.
+	Providers []SuggestionProviderConfig `yaml:"providers"`
+	// History keeps the suggestions providers returned in a local index that
+	// answers too, including when providers are slow. User queries are
+	// never stored.
+	History bool `yaml:"history"`
+	// Weight of the local history (0-10, default 0.5)
+	HistoryWeight float64 `yaml:"history_weight"`
+}
+
+// SuggestionProviderConfig is one autocomplete suggestion provider
+type SuggestionProviderConfig struct {
+	Name   string  `yaml:"name"`
+	Weight float64 `yaml:"weight"`
+}
+
+// FeatureConfig declares a dark-launch flag
+type FeatureConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Share of browsers that see the feature while enabled, 1-100; 0 means
+	// every browser
+	Percent     int    `yaml:"percent" json:"percent"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+// EffectivePercent returns the rollout percentage, treating 0 as 100
+func (f FeatureConfig) EffectivePercent() int {
+	if f.Percent <= 0 || f.Percent > 100 {
+		return 100
+	}
+	return f.Percent
+}
+
+// WarmQueriesConfig lists popular queries the cache_warm task precomputes,
+// so they are answered from the result cache
+type WarmQueriesConfig struct {
+	// One query per line; "category: query" targets a category and # starts
+	// a comment. Relative paths are under the config directory.
+	File string `yaml:"file"`
+	// Most queries refreshed per run (default 100)
+	MaxQueries int `yaml:"max_queries"`
+	// Pause between queries so engines are not flooded (default 2s)
+	Delay string `yaml:"delay"`
+}
+
+// PermalinksConfig controls shareable /s/<token> links to a search. The
+// search is kept server-side, encrypted with the link's token, so the
+// query never appears in URLs, access logs or referrers.
+type PermalinksConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// How long a link works (e.g., "7d"; default 7d)
+	TTL string `yaml:"ttl"`
+}
+
+// RenderCacheConfig keeps the HTML of rendered result pages for a short
+// time, so an identical search from a browser with the same language,
+// theme and preferences is answered without searching or executing
+// templates again. Requests carrying an Authorization header and view-as
+// sessions always bypass it.
+type RenderCacheConfig struct {
+	// Turn the cache off (default: false, pages are cached)
+	Disabled bool `yaml:"disabled"`
+	// How long a rendered page is reused (default: "30s")
+	TTL string `yaml:"ttl"`
+	// Pages kept in memory (default: 200)
+	MaxEntries int `yaml:"max_entries"`
+}
+
+// KeysConfig schedules rotation of the server secrets. Rotating one
+// re-encrypts what it protects before the new secret is saved; the
+// key_rotation task rotates the scheduled secrets once they are
+// rotate_after old.
+type KeysConfig struct {
+	// Rotate a scheduled secret once it is this old, such as "90d"; empty
+	// never rotates on a schedule (default: "")
+	RotateAfter string `yaml:"rotate_after"`
+	// The secrets the key_rotation task rotates: secret_key,
+	// encryption_key, installation_secret and jwt (default: all but
+	// installation_secret, which changes the security_id researchers use)
+	Scheduled []string `yaml:"scheduled"`
+}
+
+// keyNames are the secrets server.security.keys can schedule
+var keyNames = []string{"secret_key", "encryption_key", "installation_secret", "jwt"}
+
+// JWTConfig enables short-lived, stateless operator tokens. A JWT is
+// exchanged for the server token or a named operator token and carries its
+// scopes, so it is verified by its signature alone, without the server
+// database. It cannot be revoked before it expires; removing its key
+// revokes every token the key signed.
+type JWTConfig struct {
+	// Issue and accept JWTs (default: false)
+	Enabled bool `yaml:"enabled"`
+	// How long an issued JWT is valid (default: "15m", at most "24h")
+	TTL string `yaml:"ttl"`
+	// Signing keys. The first signs new tokens and all of them verify, so
+	// a key is rotated by adding the new one first and removing the old
+	// one once its tokens have expired. Empty derives a key from
+	// server.secret_key, so rotating that key revokes every JWT.
+	Keys []JWTKeyConfig `yaml:"keys"`
+}
+
+// JWTKeyConfig is an HMAC key for operator JWTs
+type JWTKeyConfig struct {
+	// Sent in the token's kid header to pick the key that verifies it
+	ID string `yaml:"id"`
+	// At least 32 characters
+	Secret string `yaml:"secret"`
+}
+
+// ResultCacheConfig sets how long the results of a search are reused, in
+// the cache of server.cache, for searches with the same query, category,
+// language and filters; case and spacing in the query do not count. When
+// the engines fail, results up to twelve times as old are shown marked as
+// stale.
+type ResultCacheConfig struct {
+	// Turn result caching off (default: false, results are cached)
+	Disabled bool `yaml:"disabled"`
+	// How long results are reused (default: "5m")
+	TTL string `yaml:"ttl"`
+	// TTL of particular categories, overriding ttl, such as news: "1m"
+	// for fresher news or images: "1h"
+	Categories map[string]string `yaml:"categories"`
+}
+
+// CollectionsConfig controls saved-result collections. A collection belongs
+// to whoever holds its manage token; there are no accounts.
+type CollectionsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Most items one collection holds (default 500)
+	MaxItems int `yaml:"max_items"`
+}
+
+// PreferencesConfig controls search preferences stored on the server. A
+// profile belongs to whoever holds its token; there are no passwords.
+type PreferencesConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// ReportsConfig controls the per-result report form. Reports wait in a
+// moderation queue the operator works through the API.
+type ReportsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Reports accepted per client IP per hour (default 5; 0 = unlimited).
+	// Read at startup.
+	RateLimitPerHour int `yaml:"rate_limit_per_hour"`
+	// Ask a simple arithmetic question before accepting a report
+	Captcha bool `yaml:"captcha"`
+}
+
+// ScreeningConfig checks results against malware and phishing feeds that
+// the threat_feed_update task downloads. Checks run against the local
+// copies only; result URLs are never sent to the feed providers.
+type ScreeningConfig struct {
+	// Off by default: enabling it downloads the feeds every 6 hours
+	Enabled bool `yaml:"enabled"`
+	// warn: mark the result and send clicks through a warning page;
+	// hide: drop it from results (default warn)
+	Action string `yaml:"action"`
+	// low: listed URLs only; medium: also whole domains from domain
+	// feeds; high: also any host that appears in a listed URL, which
+	// flags shared hosting sites (default medium)
+	Sensitivity string `yaml:"sensitivity"`
+	// Feeds to download; empty uses URLhaus and OpenPhish. Read at startup.
+	Feeds []ThreatFeedConfig `yaml:"feeds"`
+}
+
+// ThreatFeedConfig is one malware or phishing list
+type ThreatFeedConfig struct {
+	// File name for the local copy; letters, digits, "-" and "_"
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// urls, domains (plain or hosts-file lines) or sha256 (hex hash of
+	// the canonical URL)
+	Format string `yaml:"format"`
+	// malware or phishing; shown on the warning page
+	Category string `yaml:"category"`
+	Enabled  bool   `yaml:"enabled"`
+}
+
+// ImageClassifierConfig checks image results for adult content when safe
+// search is strict. The model runs in a companion process on the same host
+// (the server is built without cgo); thumbnails are posted to it and its
+// verdicts cached. Read at startup except Enabled.
+type ImageClassifierConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Companion classifier URL; it takes a POST of the image bytes and
+	// answers {"nsfw": 0.0-1.0}
+	Endpoint string `yaml:"endpoint"`
+	// Score at or above which an image is hidden (default 0.8)
+	Threshold float64 `yaml:"threshold"`
+	// Seconds allowed for one thumbnail download plus classification
+	// (default 5)
+	Timeout int `yaml:"timeout"`
+	// Verdicts kept in memory (default 20000)
+	CacheSize int `yaml:"cache_size"`
+}
+
+// SafeSearchFilterConfig is the blocklist that stands in for safe search
+// on engines without a parameter for it. Domains are blocked at moderate
+// and strict, keywords at strict only.
+type SafeSearchFilterConfig struct {
+	// BuiltIn adds the shipped lists of adult domains and keywords
+	// (default true)
+	BuiltIn bool `yaml:"built_in"`
+	// Domains blocked with their subdomains, such as "example.com"
+	Domains []string `yaml:"domains"`
+	// Words or phrases matched as whole words in a result's title,
+	// snippet and URL
+	Keywords []string `yaml:"keywords"`
+}
+
+// EngineLimitsConfig bounds what parsing one engine response may cost. A
+// response over a limit fails that engine's search; zero uses the default.
+type EngineLimitsConfig struct {
+	// Largest response body read from the engine, in KB (default 4096)
+	MaxResponseKB int `yaml:"max_response_kb,omitempty"`
+	// Deepest element nesting accepted in an HTML response (default 256)
+	MaxHTMLDepth int `yaml:"max_html_depth,omitempty"`
+}
+
+// ReverseImageConfig controls search by image: an image URL or upload is
+// forwarded to the engines that can find pages showing it
+type ReverseImageConfig struct {
+	// Off by default: images are sent to third-party engines
+	Enabled bool `yaml:"enabled"`
+	// Engines that receive the image; empty uses every engine that can
+	Engines []string `yaml:"engines"`
+	// Largest accepted upload, in KB (default 5120)
+	MaxUploadKB int `yaml:"max_upload_kb"`
+}
+
+// MaxUploadBytes returns the upload limit in bytes
+func (r ReverseImageConfig) MaxUploadBytes() int64 {
+	if r.MaxUploadKB <= 0 {
+		return 5120 * 1024
+	}
+	return int64(r.MaxUploadKB) * 1024
+}
+
+// VideoPlayerConfig controls the watch page, which plays a video result in
+// an embedded player instead of sending the visitor to the video host
+type VideoPlayerConfig struct {
+	// Off by default: the player frames a third-party site
+	Enabled bool `yaml:"enabled"`
+	// Frontend YouTube videos play through: invidious, piped or nocookie
+	// (youtube-nocookie.com). Vimeo, Dailymotion and PeerTube videos and
+	// direct video files play without one.
+	Frontend string `yaml:"frontend"`
+	// Instance is the https base URL of the Invidious or Piped instance
+	Instance string `yaml:"instance"`
+}
+
+// Default servers of the maps category
+const (
+	DefaultNominatimURL   = "https://nominatim.openstreetmap.org"
+	DefaultMapTileURL     = "https://tile.openstreetmap.org/{z}/{x}/{y}.png"
+	DefaultMapAttribution = "© OpenStreetMap contributors"
+	DefaultLeafletURL     = "https://unpkg.com/leaflet@1.9.4/dist"
+)
+
+// MapsConfig sets where the maps category looks places up and where the
+// results map loads its tiles and Leaflet from, so a self-hosted
+// Nominatim or tile server can replace the OpenStreetMap ones
+type MapsConfig struct {
+	// Nominatim is the base URL of the Nominatim server places are looked
+	// up on
+	Nominatim string `yaml:"nominatim"`
+	// Tiles is the tile URL template the results map loads, with {z},
+	// {x} and {y}
+	Tiles string `yaml:"tiles"`
+	// Attribution is the credit the tile server's license asks for
+	Attribution string `yaml:"attribution"`
+	// Leaflet is the base URL of a Leaflet 1.9 copy holding leaflet.js
+	// and leaflet.css
+	Leaflet string `yaml:"leaflet"`
+}
+
+// NominatimURL returns the Nominatim base URL without a trailing slash
+func (m MapsConfig) NominatimURL() string {
+	return httpURLOr(m.Nominatim, DefaultNominatimURL)
+}
+
+// TileURL returns the tile URL template. A {s} subdomain placeholder is
+// checked as if it were a real subdomain.
+func (m MapsConfig) TileURL() string {
+	tiles := strings.TrimRight(strings.TrimSpace(m.Tiles), "/")
+	if httpURLOr(strings.ReplaceAll(tiles, "{s}.", "a."), "") == "" {
+		return DefaultMapTileURL
+	}
+	return tiles
+}
+
+// TileAttribution returns the map credit, the OpenStreetMap one while
+// the tiles come from OpenStreetMap
+func (m MapsConfig) TileAttribution() string {
+	if attribution := strings.TrimSpace(m.Attribution); attribution != "" {
+		return attribution
+	}
+	return DefaultMapAttribution
+}
+
+// LeafletURL returns the Leaflet base URL without a trailing slash
+func (m MapsConfig) LeafletURL() string {
+	return httpURLOr(m.Leaflet, DefaultLeafletURL)
+}
+
+// httpURLOr returns raw without a trailing slash when it is an http or
+// https URL with a host, or fallback otherwise
+func httpURLOr(raw, fallback string) string {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fallback
+	}
+	return raw
+}
+
+// GeoBoostConfig controls geo boosting: results on the country-code domain
+// of the searcher's GeoIP country, or in a language spoken there, rank
+// higher. It needs server.geoip, and each user can turn it off.
+type GeoBoostConfig struct {
+	// Off by default: results rank differently by where the searcher is
+	Enabled bool `yaml:"enabled"`
+	// Weight is the score boost of a local result: 0.1 (default) ranks it
+	// as if it scored 10% higher
+	Weight float64 `yaml:"weight"`
+}
+
+// BoostWeight returns the boost, or 0 when geo boosting is off
+func (g GeoBoostConfig) BoostWeight() float64 {
+	if !g.Enabled {
+		return 0
+	}
+	if g.Weight <= 0 {
+		return 0.1
+	}
+	return g.Weight
+}
+
+// SchemaDriftConfig controls the engine response schema drift detector
+type SchemaDriftConfig struct {
+	// Turn the detector off (default: false, engines are checked)
+	Disabled bool `yaml:"disabled"`
+	// Threshold is the share of an engine's usual response structure that
+	// must go missing, on average, before its parser is suspected: 0.5
+	// (default) is half
+	Threshold float64 `yaml:"threshold"`
+}
+
+// EffectiveThreshold returns the drift threshold, or 0 when the detector
+// is off
+func (d SchemaDriftConfig) EffectiveThreshold() float64 {
+	if d.Disabled {
+		return 0
+	}
+	if d.Threshold <= 0 || d.Threshold > 1 {
+		return 0.5
+	}
+	return d.Threshold
+}
+
+// PolitenessConfig controls how engine requests treat the upstream hosts
+// they scrape. Limits apply per host, shared by every engine asking it.
+type PolitenessConfig struct {
+	// Turn request spacing and caps off (default: false, hosts are spared)
+	Disabled bool `yaml:"disabled"`
+	// Least time between two requests to a host (default: "250ms")
+	MinInterval string `yaml:"min_interval"`
+	// Most requests in flight to a host; 0 is unlimited (default: 4)
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// Do not read hosts' robots.txt Crawl-delay (default: false, it is
+	// honored)
+	IgnoreRobots bool `yaml:"ignore_robots"`
+	// Longest Crawl-delay honored, so a host asking for minutes does not
+	// stall searches (default: "5s")
+	MaxCrawlDelay string `yaml:"max_crawl_delay"`
+	// Longest a request waits for its turn before the engine is skipped
+	// for that search (default: "2s")
+	MaxWait string `yaml:"max_wait"`
+	// Per-host overrides, keyed by host name such as "www.google.com"
+	Hosts map[string]HostPolitenessConfig `yaml:"hosts"`
+}
+
+// HostPolitenessConfig overrides the politeness for one host. Empty fields
+// use the search.politeness values.
+type HostPolitenessConfig struct {
+	MinInterval   string `yaml:"min_interval" desc:"Least time between requests to the host"`
+	MaxConcurrent int    `yaml:"max_concurrent" desc:"Requests to the host at once"`
+}
+
+// UserAgentsConfig controls the user agent pool engine requests are sent
+// with
+type UserAgentsConfig struct {
+	// Policy for engines without their own: "fixed" (default, each
+	// engine's built-in user agent), "rotate" (the pool in turn) or "best"
+	// (the one that parses best, trying the others now and then)
+	Policy string `yaml:"policy"`
+	// Browser user agents rotated and tested; empty uses the built-in pool
+	Pool []string `yaml:"pool,omitempty"`
+	// Per-engine policies and pins, keyed by engine name
+	Engines map[string]EngineUserAgentConfig `yaml:"engines,omitempty"`
+}
+
+// EngineUserAgentConfig is one engine's user agent policy
+type EngineUserAgentConfig struct {
+	// Policy overrides search.user_agents.policy
+	Policy string `yaml:"policy,omitempty" json:"policy,omitempty"`
+	// Pin sends this user agent whatever the policy
+	Pin string `yaml:"pin,omitempty" json:"pin,omitempty"`
+}
+
+// UserAgentPolicies are the accepted user agent policies
+var UserAgentPolicies = []string{"fixed", "rotate", "best"}
+
+// CircuitBreakerConfig controls when searches skip a failing engine. Its
+// circuit opens after failure_threshold failures in a row, or once
+// error_rate of its last 20 calls failed or were slower than slow_call;
+// after the cooldown the next search tries it again.
+type CircuitBreakerConfig struct {
+	// Never skip engines (default: false, failing engines are skipped)
+	Disabled bool `yaml:"disabled"`
+	// Failures in a row that open the circuit; 0 turns the check off
+	// (default 3)
+	FailureThreshold int `yaml:"failure_threshold"`
+	// Share of failed or slow calls, 0-1, that opens the circuit; 0 turns
+	// the check off (default 0.5)
+	ErrorRate float64 `yaml:"error_rate"`
+	// Calls needed before the error rate counts, 1-20 (default 10)
+	MinRequests int `yaml:"min_requests"`
+	// A successful call slower than this counts against the error rate;
+	// "0s" counts none (default: "10s")
+	SlowCall string `yaml:"slow_call"`
+	// How long the circuit first stays open; it doubles each time the
+	// engine fails its retry (default: "10m")
+	Cooldown string `yaml:"cooldown"`
+	// Longest the circuit stays open (default: "1h")
+	MaxCooldown string `yaml:"max_cooldown"`
+}
+
+// MacroConfig is an instance-wide search macro: "~issues apimgr/search"
+// searches "site:github.com/apimgr/search/issues" for the query
+// "site:github.com/{repo}/issues". Each {parameter} takes one word.
+type MacroConfig struct {
+	// Name is invoked as ~name: lowercase letters, digits, '_' and '-'
+	Name        string `yaml:"name"`
+	Query       string `yaml:"query"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// ChaosConfig injects faults into selected engines so circuit breakers,
+// degraded mode and the result pages can be checked against failing
+// upstreams. It applies only while server.mode is development.
+type ChaosConfig struct {
+	// Engines maps an engine name to the faults injected into its calls
+	Engines map[string]ChaosEngineConfig `yaml:"engines"`
+}
+
+// ChaosEngineConfig is the faults injected into one engine's calls
+type ChaosEngineConfig struct {
+	// Added before the call, e.g. "2s"
+	Latency string `yaml:"latency" json:"latency,omitempty"`
+	// error (the call fails), timeout (it hangs until the search deadline)
+	// or malformed (upstream response bodies are cut in half); empty
+	// injects only latency
+	Fault string `yaml:"fault" json:"fault,omitempty"`
+	// Share of calls affected, 0-1; 0 affects every call
+	Rate float64 `yaml:"rate" json:"rate,omitempty"`
+}
+
+// ChaosFaults are the faults a chaos entry may inject
+var ChaosFaults = []string{"error", "timeout", "malformed"}
+
+// RankingConfig is the ranking pipeline: stages that adjust the scores of
+// merged results, run in list order, for searches sorted by relevance. It
+// runs after the result cache, so a change reorders cached results too.
+type RankingConfig struct {
+	// Stages in the order they run; a stage not listed or not enabled
+	// does not run. None is enabled by default.
+	Stages []RankingStageConfig `yaml:"stages"`
+}
+
+// RankingStageConfig is one ranking stage. Settings other than name and
+// enabled belong to the stages noted.
+type RankingStageConfig struct {
+	// engine_weight, domain_authority, freshness or duplicate_penalty
+	Name    string `yaml:"name" json:"name"`
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	// engine_weight: score multiplier per engine name; domain_authority:
+	// per domain, subdomains included (0-10)
+	Weights map[string]float64 `yaml:"weights,omitempty" json:"weights,omitempty"`
+	// freshness: boost of a result published now, 0.5 (default) ranks it
+	// as if it scored 50% higher
+	Boost float64 `yaml:"boost,omitempty" json:"boost,omitempty"`
+	// freshness: age at which the boost halves (default: "7d")
+	HalfLife string `yaml:"half_life,omitempty" json:"half_life,omitempty"`
+	// duplicate_penalty: score multiplier of each further result from a
+	// domain already ranked, 0-1 (default 0.8)
+	Factor float64 `yaml:"factor,omitempty" json:"factor,omitempty"`
+}
+
+// RankingStages are the ranking stage names, in their default order
+var RankingStages = []string{"engine_weight", "domain_authority", "freshness", "duplicate_penalty"}
+
+// MaxRankingWeight is the largest engine or domain weight
+const MaxRankingWeight = 10.0
+
+// DefaultRankingStages returns every ranking stage, disabled, with its
+// default settings
+func DefaultRankingStages() []RankingStageConfig {
+	return []RankingStageConfig{
+		{Name: "engine_weight"},
+		{Name: "domain_authority"},
+		{Name: "freshness", Boost: 0.5, HalfLife: "7d"},
+		{Name: "duplicate_penalty", Factor: 0.8},
+	}
+}
+
+// NormalizeRankingStages checks a ranking pipeline and returns it with
+// names and weight keys lowercased and unset settings defaulted
+func NormalizeRankingStages(stages []RankingStageConfig) ([]RankingStageConfig, error) {
+	seen := make(map[string]bool, len(stages))
+	normalized := make([]RankingStageConfig, 0, len(stages))
+	for _, stage := range stages {
+		stage.Name = strings.ToLower(strings.TrimSpace(stage.Name))
+		switch {
+		case !slices.Contains(RankingStages, stage.Name):
+			return nil, fmt.Errorf("unknown ranking stage '%s'", stage.Name)
+		case seen[stage.Name]:
+			return nil, fmt.Errorf("ranking stage '%s' is listed twice", stage.Name)
+		case stage.Boost < 0:
+			return nil, fmt.Errorf("ranking stage '%s' boost %g is negative", stage.Name, stage.Boost)
+		case stage.Factor < 0 || stage.Factor > 1:
+			return nil, fmt.Errorf("ranking stage '%s' factor %g is outside 0-1", stage.Name, stage.Factor)
+		}
+		switch stage.Name {
+		case "engine_weight", "domain_authority":
+			weights := make(map[string]float64, len(stage.Weights))
+			for key, weight := range stage.Weights {
+				key = strings.ToLower(strings.TrimSpace(key))
+				if key == "" || weight < 0 || weight > MaxRankingWeight {
+					return nil, fmt.Errorf("ranking stage '%s' weight of '%s' must be 0-%g", stage.Name, key, MaxRankingWeight)
+				}
+				weights[key] = weight
+			}
+			stage.Weights = weights
+		case "freshness":
+			if stage.HalfLife == "" {
+				stage.HalfLife = "7d"
+			}
+			if secs, err := ParseDuration(stage.HalfLife); err != nil || secs <= 0 {
+				return nil, fmt.Errorf("ranking stage '%s' half_life '%s' is not a duration", stage.Name, stage.HalfLife)
+			}
+			if stage.Boost == 0 {
+				stage.Boost = 0.5
+			}
+		case "duplicate_penalty":
+			if stage.Factor == 0 {
+				stage.Factor = 0.8
+			}
+		}
+		seen[stage.Name] = true
+		normalized = append(normalized, stage)
+	}
+	return normalized, nil
+}
+
+// PreviewConfig controls search-as-you-type result previews
+type PreviewConfig struct {
+	// Off by default: partial queries may be forwarded to an upstream engine
+	Enabled bool `yaml:"enabled"`
+	// Serve previews only from the result cache, never querying engines
+	CacheOnly bool `yaml:"cache_only"`
+}
+
+// InstantAnswersConfig controls the instant answer ladder: which handlers
+// run, in what order, and how many answers a results page shows
+type InstantAnswersConfig struct {
+	// Handler names tried first, in this order; the rest keep their built-in order
+	Priority []string `yaml:"priority"`
+	// Per-handler enable flags keyed by handler name; unlisted handlers are enabled
+	Handlers map[string]bool `yaml:"handlers"`
+	// Answers rendered expanded on the results page
+	MaxAnswers int `yaml:"max_answers"`
+	// Further answers rendered collapsed below them; 0 stops after max_answers
+	MaxCollapsed int `yaml:"max_collapsed"`
+}
+
+// MarketConfig holds stock/crypto price instant answer and ticker configuration
+type MarketConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Yahoo Finance-compatible chart API base URL
+	StocksURL string `yaml:"stocks_url"`
+	// CoinGecko-compatible API base URL
+	CoinGeckoURL string `yaml:"coingecko_url"`
+	// Quote currency for crypto prices ("usd", "eur", etc.)
+	Currency string `yaml:"currency"`
+	// Symbols shown in the ticker widget and kept warm by the scheduler
+	Ticker []string `yaml:"ticker"`
 }
 
 type AlertsConfig struct {
 	CreateRateLimitPerHour   int    `yaml:"create_rate_limit_per_hour"`
 	WebhookMaxRetries        int    `yaml:"webhook_max_retries"`
 	WebhookRetryDelayMinutes int    `yaml:"webhook_retry_delay_minutes"`
-	RetentionDays            int    `yaml:"retention_days"`
+	RetentionDays            int    `yaml:"retention_days" desc:"Days alert results are kept"`
 	DefaultFrequency         string `yaml:"default_frequency"`
 	DefaultDeliverRSS        bool   `yaml:"default_deliver_rss"`
 	DefaultDeliverWebhook    bool   `yaml:"default_deliver_webhook"`
@@ -1201,6 +2193,8 @@ type WidgetsConfig struct {
 	Crypto  CryptoWidgetConfig  `yaml:"crypto"`
 	Sports  SportsWidgetConfig  `yaml:"sports"`
 	RSS     RSSWidgetConfig     `yaml:"rss"`
+	Flight  FlightWidgetConfig  `yaml:"flight"`
+	Transit TransitWidgetConfig `yaml:"transit"`
 }
 
 // WeatherWidgetConfig holds weather widget configuration
@@ -1246,10 +2240,49 @@ type RSSWidgetConfig struct {
 	MaxItems int  `yaml:"max_items"`
 }
 
+// FlightWidgetConfig holds flight status widget configuration
+type FlightWidgetConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// readsb-compatible ADS-B API base URL (adsb.lol, airplanes.live, or a local feeder)
+	ADSBURL string `yaml:"adsb_url"`
+	// Optional AviationStack access key for schedule and delay information
+	AviationStackKey string `yaml:"aviationstack_key"`
+}
+
+// TransitWidgetConfig holds public transit departures widget configuration
+type TransitWidgetConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// GTFS-Realtime trip update feeds, configured by the operator
+	Feeds []TransitFeedConfig `yaml:"feeds"`
+	// Maximum departures returned per stop
+	MaxDepartures int `yaml:"max_departures"`
+}
+
+// TransitFeedConfig describes a single GTFS-Realtime trip updates feed
+type TransitFeedConfig struct {
+	// Short identifier used by the widget ("mbta", "bart")
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// TripUpdates protobuf endpoint
+	URL string `yaml:"url"`
+	// Extra request headers, e.g. an agency API key
+	Headers map[string]string `yaml:"headers"`
+	// Stops users may pick; GTFS-RT carries only IDs, so names come from here
+	Stops []TransitStopConfig `yaml:"stops"`
+	// Optional route_id to display name mapping
+	Routes map[string]string `yaml:"routes"`
+}
+
+// TransitStopConfig names a GTFS stop_id for display
+type TransitStopConfig struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+}
+
 // BangsConfig represents bang configuration
 type BangsConfig struct {
 	Enabled       bool         `yaml:"enabled"`
-	ProxyRequests bool         `yaml:"proxy_requests"`
+	ProxyRequests bool         `yaml:"proxy_requests" desc:"Send bang searches through /bang instead of redirecting to the site"`
 	Custom        []BangConfig `yaml:"custom"`
 }
 
@@ -1277,11 +2310,62 @@ type BangConfig struct {
 // EngineConfig represents search engine configuration
 type EngineConfig struct {
 	Enabled    bool     `yaml:"enabled"`
-	Priority   int      `yaml:"priority"`
+	Priority   int      `yaml:"priority" desc:"Higher priority engines are queried and ranked first"`
 	Categories []string `yaml:"categories"`
-	Timeout    int      `yaml:"timeout"`
+	Timeout    int      `yaml:"timeout" desc:"Seconds to wait for the engine; 0 means 10"`
 	Weight     float64  `yaml:"weight"`
 	APIKey     string   `yaml:"api_key,omitempty"`
+	// Request adds headers, cookies and URL parameters to the engine's
+	// requests
+	Request EngineRequestConfig `yaml:"request,omitempty"`
+	// Limits overrides search.engine_limits for this engine
+	Limits EngineLimitsConfig `yaml:"limits,omitempty"`
+	// Quota budgets the engine's requests per day
+	Quota EngineQuotaConfig `yaml:"quota,omitempty"`
+}
+
+// EngineQuotaConfig is a daily request budget, for engines whose API allows
+// only so many requests a day. Days and peak hours are in server local time.
+type EngineQuotaConfig struct {
+	// Requests allowed per day; 0 means no quota
+	Daily int `yaml:"daily,omitempty"`
+	// Peak hours as "17-22"; empty means no peak
+	PeakHours string `yaml:"peak_hours,omitempty"`
+	// Percent of the daily quota held back until the peak hours
+	PeakReserve int `yaml:"peak_reserve,omitempty"`
+	// Engine searched instead once the budget is spent, usually one
+	// scraping the same site; empty leaves the engine out until tomorrow
+	Fallback string `yaml:"fallback,omitempty"`
+}
+
+// PeakRange returns the peak hours as [start, end) in 0-23, or ok false
+// when there is no valid peak
+func (q EngineQuotaConfig) PeakRange() (start, end int, ok bool) {
+	from, to, found := strings.Cut(strings.TrimSpace(q.PeakHours), "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err1 := strconv.Atoi(strings.TrimSpace(from))
+	end, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || start < 0 || start > 23 || end < 0 || end > 24 || start == end%24 {
+		return 0, 0, false
+	}
+	return start, end % 24, true
+}
+
+// EngineRequestConfig customizes an engine's outgoing requests, for example
+// with a consent cookie. Values may use the placeholders {query}, {page},
+// {locale} and {safe_search}, resolved for each search.
+type EngineRequestConfig struct {
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+	// Params are set on the request URL, replacing the engine's own value
+	Params map[string]string `yaml:"params,omitempty"`
+}
+
+// IsZero reports whether the engine's requests are left unchanged
+func (r EngineRequestConfig) IsZero() bool {
+	return len(r.Headers) == 0 && len(r.Cookies) == 0 && len(r.Params) == 0
 }
 
 // DefaultConfig returns a default configuration
@@ -1298,6 +2382,9 @@ func DefaultConfig() *Config {
 			BaseURL: "",
 			// Empty = auto-detected from hostname at runtime
 			FQDN: "",
+			// Empty = answer any Host header
+			AllowedHosts:   []string{},
+			UnexpectedHost: UnexpectedHostReject,
 			// API version prefix used in /api/{api_version}/ routes
 			APIVersion: "v1",
 			// "true" = create PID file at default platform path
@@ -1306,6 +2393,9 @@ func DefaultConfig() *Config {
 			// System service user and group (auto-created by binary on first root run)
 			User:  "search",
 			Group: "search",
+			JWT: JWTConfig{
+				TTL: "15m",
+			},
 			SSL: SSLConfig{
 				Enabled: false,
 				AutoTLS: false,
@@ -1329,15 +2419,10 @@ func DefaultConfig() *Config {
 			},
 			Logs: LogsConfig{
 				Level: "warn",
-				Access: struct {
-					Filename string `yaml:"filename"`
-					Format   string `yaml:"format"`
-					Custom   string `yaml:"custom"`
-					Rotate   string `yaml:"rotate"`
-					Keep     string `yaml:"keep"`
-				}{
+				Access: AccessLogConfig{
 					Filename: "access.log",
 					Format:   "apache",
+					Privacy:  "strict",
 					Rotate:   "monthly",
 					Keep:     "none",
 				},
@@ -1430,6 +2515,9 @@ func DefaultConfig() *Config {
 			},
 			Security: SecurityConfig{
 				InstallationSecret: generateBase64Secret(),
+				Keys: KeysConfig{
+					Scheduled: []string{"secret_key", "encryption_key", "jwt"},
+				},
 				CSRF: struct {
 					Enabled    bool   `yaml:"enabled"`
 					CookieName string `yaml:"cookie_name"`
@@ -1562,11 +2650,16 @@ func DefaultConfig() *Config {
 				Timezone:      "America/New_York",
 				CatchUpWindow: "1h",
 				Tasks: SchedulerTasksConfig{
-					BackupDaily:     TaskConfig{Schedule: "0 2 * * *", Enabled: true},
-					BackupHourly:    TaskConfig{Schedule: "@hourly", Enabled: false},
-					GeoIPUpdate:     TaskConfig{Schedule: "0 3 * * 0", Enabled: true},
-					BlocklistUpdate: TaskConfig{Schedule: "0 4 * * *", Enabled: true},
-					CVEUpdate:       TaskConfig{Schedule: "0 5 * * *", Enabled: true},
+					BackupDaily:      TaskConfig{Schedule: "0 2 * * *", Enabled: true},
+					BackupHourly:     TaskConfig{Schedule: "@hourly", Enabled: false},
+					BackupVerify:     TaskConfig{Schedule: "0 6 * * 0", Enabled: true},
+					GeoIPUpdate:      TaskConfig{Schedule: "0 3 * * 0", Enabled: true},
+					BlocklistUpdate:  TaskConfig{Schedule: "0 4 * * *", Enabled: true},
+					CVEUpdate:        TaskConfig{Schedule: "0 5 * * *", Enabled: true},
+					CacheWarm:        TaskConfig{Schedule: "30 3 * * *", Enabled: true},
+					Retention:        TaskConfig{Schedule: "30 4 * * *", Enabled: true},
+					ThreatFeedUpdate: TaskConfig{Schedule: "@every 6h", Enabled: true},
+					KeyRotation:      TaskConfig{Schedule: "0 5 * * *", Enabled: true},
 				},
 			},
 			Cache: CacheConfig{
@@ -1686,9 +2779,34 @@ func DefaultConfig() *Config {
 					OnExit:  true,
 				},
 			},
+			// Report only until the operator has reviewed the dry run
+			Retention: RetentionConfig{
+				Enforce: false,
+				Logs:    RetentionRule{MaxAge: "30d", MaxSize: "1GB"},
+				History: RetentionRule{MaxAge: "30d"},
+				Metrics: RetentionRule{MaxAge: "90d", MaxRows: 100000},
+				Cache:   RetentionRule{MaxAge: "7d", MaxSize: "512MB"},
+			},
+			Memory: MemoryConfig{
+				Limit:       "auto",
+				SoftPercent: 70,
+				HardPercent: 85,
+				Interval:    "10s",
+			},
+			APICache: APICacheConfig{
+				Engines:    "public, max-age=60",
+				Categories: "public, max-age=86400",
+				Bangs:      "public, max-age=86400",
+				Info:       "public, max-age=60",
+				Widgets:    "private, max-age=300",
+			},
+			CDN: CDNConfig{
+				StaticMaxAge: 86400,
+			},
 		},
 		Search: SearchConfig{
 			SafeSearch:        1,
+			SafeSearchFilter:  SafeSearchFilterConfig{BuiltIn: true},
 			Autocomplete:      "",
 			DefaultLang:       "en",
 			DefaultCategories: []string{"general"},
@@ -1711,10 +2829,115 @@ func DefaultConfig() *Config {
 				LongName: "",
 				Image:    "/static/img/favicon.png",
 			},
-			Alerts: AlertsConfig{
-				CreateRateLimitPerHour:   10,
-				WebhookMaxRetries:        3,
-				WebhookRetryDelayMinutes: 5,
+			WarmQueries: WarmQueriesConfig{
+				File:       "warm_queries.txt",
+				MaxQueries: 100,
+				Delay:      "2s",
+			},
+			Permalinks: PermalinksConfig{
+				Enabled: true,
+				TTL:     "7d",
+			},
+			Collections: CollectionsConfig{
+				Enabled:  true,
+				MaxItems: 500,
+			},
+			Preferences: PreferencesConfig{
+				Enabled: true,
+			},
+			Reports: ReportsConfig{
+				Enabled:          true,
+				RateLimitPerHour: 5,
+			},
+			Screening: ScreeningConfig{
+				Action:      "warn",
+				Sensitivity: "medium",
+			},
+			ImageClassifier: ImageClassifierConfig{
+				Endpoint:  "http://127.0.0.1:8091/classify",
+				Threshold: 0.8,
+				Timeout:   5,
+				CacheSize: 20000,
+			},
+			EngineLimits: EngineLimitsConfig{
+				MaxResponseKB: 4096,
+				MaxHTMLDepth:  256,
+			},
+			RenderCache: RenderCacheConfig{
+				TTL:        "30s",
+				MaxEntries: 200,
+			},
+			ResultCache: ResultCacheConfig{
+				TTL:        "5m",
+				Categories: map[string]string{"news": "1m"},
+			},
+			ReverseImage: ReverseImageConfig{
+				Engines:     []string{"yandex", "bing"},
+				MaxUploadKB: 5120,
+			},
+			VideoPlayer: VideoPlayerConfig{
+				Frontend: "nocookie",
+			},
+			Maps: MapsConfig{
+				Nominatim:   DefaultNominatimURL,
+				Tiles:       DefaultMapTileURL,
+				Attribution: DefaultMapAttribution,
+				Leaflet:     DefaultLeafletURL,
+			},
+			GeoBoost: GeoBoostConfig{
+				Weight: 0.1,
+			},
+			SchemaDrift: SchemaDriftConfig{
+				Threshold: 0.5,
+			},
+			UserAgents: UserAgentsConfig{
+				Policy: "fixed",
+			},
+			Ranking: RankingConfig{
+				Stages: DefaultRankingStages(),
+			},
+			Macros: []MacroConfig{},
+			Chaos: ChaosConfig{
+				Engines: map[string]ChaosEngineConfig{},
+			},
+			CircuitBreaker: CircuitBreakerConfig{
+				FailureThreshold: 3,
+				ErrorRate:        0.5,
+				MinRequests:      10,
+				SlowCall:         "10s",
+				Cooldown:         "10m",
+				MaxCooldown:      "1h",
+			},
+			Politeness: PolitenessConfig{
+				MinInterval:   "250ms",
+				MaxConcurrent: 4,
+				MaxCrawlDelay: "5s",
+				MaxWait:       "2s",
+			},
+			Suggestions: SuggestionsConfig{
+				Providers: []SuggestionProviderConfig{
+					{Name: "duckduckgo", Weight: 1},
+					{Name: "google", Weight: 1},
+				},
+				History:       true,
+				HistoryWeight: 0.5,
+			},
+			Market: MarketConfig{
+				// Off by default: quotes come from third-party APIs
+				Enabled:      false,
+				StocksURL:    "https://query1.finance.yahoo.com",
+				CoinGeckoURL: "https://api.coingecko.com",
+				Currency:     "usd",
+				Ticker:       []string{"AAPL", "MSFT", "BTC", "ETH"},
+			},
+			InstantAnswers: InstantAnswersConfig{
+				MaxAnswers:   1,
+				MaxCollapsed: 2,
+			},
+			Alerts: AlertsConfig{
+				CreateRateLimitPerHour:   10,
+				WebhookMaxRetries:        3,
+				WebhookRetryDelayMinutes: 5,
 				RetentionDays:            30,
 				DefaultFrequency:         "daily",
 				DefaultDeliverRSS:        true,
@@ -1753,6 +2976,16 @@ func DefaultConfig() *Config {
 					MaxFeeds: 5,
 					MaxItems: 10,
 				},
+				Flight: FlightWidgetConfig{
+					Enabled: true,
+					ADSBURL: "https://api.adsb.lol",
+				},
+				Transit: TransitWidgetConfig{
+					// No feeds by default; the operator adds their agency's endpoints
+					Enabled:       false,
+					Feeds:         []TransitFeedConfig{},
+					MaxDepartures: 8,
+				},
 			},
 		},
 		Engines: map[string]EngineConfig{
@@ -1826,6 +3059,13 @@ func DefaultConfig() *Config {
 				Timeout:    10,
 				Weight:     0.8,
 			},
+			"cve": {
+				Enabled:    true,
+				Priority:   65,
+				Categories: []string{"general", "it"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
 			"reddit": {
 				Enabled:    true,
 				Priority:   45,
@@ -1833,6 +3073,90 @@ func DefaultConfig() *Config {
 				Timeout:    10,
 				Weight:     0.7,
 			},
+			"peertube": {
+				Enabled:    true,
+				Priority:   55,
+				Categories: []string{"videos"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"dailymotion": {
+				Enabled:    true,
+				Priority:   50,
+				Categories: []string{"videos"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"bingnews": {
+				Enabled:    true,
+				Priority:   70,
+				Categories: []string{"news"},
+				Timeout:    10,
+				Weight:     0.9,
+			},
+			"gdelt": {
+				Enabled:    true,
+				Priority:   50,
+				Categories: []string{"news"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"archiveorg": {
+				Enabled:    true,
+				Priority:   60,
+				Categories: []string{"files"},
+				Timeout:    10,
+				Weight:     0.9,
+			},
+			"piratebay": {
+				Enabled:    true,
+				Priority:   50,
+				Categories: []string{"files"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"nyaa": {
+				Enabled:    true,
+				Priority:   40,
+				Categories: []string{"files"},
+				Timeout:    10,
+				Weight:     0.7,
+			},
+			"npm": {
+				Enabled:    true,
+				Priority:   60,
+				Categories: []string{"packages"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"pypi": {
+				Enabled:    true,
+				Priority:   58,
+				Categories: []string{"packages"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"crates": {
+				Enabled:    true,
+				Priority:   56,
+				Categories: []string{"packages"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"gomodules": {
+				Enabled:    true,
+				Priority:   54,
+				Categories: []string{"packages"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
+			"dockerhub": {
+				Enabled:    true,
+				Priority:   52,
+				Categories: []string{"packages"},
+				Timeout:    10,
+				Weight:     0.8,
+			},
 		},
 	}
 }
@@ -1934,6 +3258,7 @@ func addConfigComments(node *yaml.Node) {
 		"base_url":         "Public URL for this instance",
 		"ssl":              "SSL/TLS configuration",
 		"token":            "Operator bearer token (server.token). Auto-generated on first run.",
+		"jwt":              "Short-lived signed operator tokens for scaled deployments",
 		"branding":         "Branding and appearance",
 		"rate_limit":       "Rate limiting configuration",
 		"logs":             "Logging configuration",
@@ -1966,6 +3291,9 @@ func addConfigComments(node *yaml.Node) {
 		"group":            "System group the binary runs as after privilege drop",
 		"database":         "Database driver and connection settings",
 		"maintenance":      "Maintenance mode self-healing configuration",
+		"memory":           "Memory watchdog: shrinks caches and tightens GOGC near the memory limit",
+		"api_cache":        "Cache-Control policies of the engines, categories, bangs, info and widgets APIs",
+		"cdn":              "CDN/reverse-cache mode: public caching of safe responses, canonical query order",
 	}
 
 	// Subsection comments under security
@@ -2510,7 +3838,27 @@ func (c *Config) ValidateAndApplyDefaults() []ValidationWarning {
 	}
 
 	// Tor: Per AI.md PART 32, auto-enabled at runtime if binary found
-	// No validation needed - TorService handles everything
+	// TorService handles everything except the additional hidden services
+	warnings = append(warnings, c.validateTorServices()...)
+	warnings = append(warnings, c.validateAccessLog()...)
+
+	// Explicit category engine lists must not leave a category empty
+	warnings = append(warnings, c.validateCategoryEngines()...)
+	warnings = append(warnings, c.validatePoliteness()...)
+	warnings = append(warnings, c.validateResultCache()...)
+	warnings = append(warnings, c.validateJWT()...)
+	warnings = append(warnings, c.validateKeys()...)
+	warnings = append(warnings, c.validateRanking()...)
+	warnings = append(warnings, c.validateCircuitBreaker()...)
+	warnings = append(warnings, c.validateMacros()...)
+	warnings = append(warnings, c.validateChaos()...)
+	warnings = append(warnings, c.validateUserAgents()...)
+	warnings = append(warnings, c.validateSuggestions()...)
+	warnings = append(warnings, c.validateSafeSearch()...)
+	warnings = append(warnings, c.validateFeatures()...)
+	warnings = append(warnings, c.validateCookies()...)
+	warnings = append(warnings, c.validateBasePath()...)
+	warnings = append(warnings, c.validateHosts()...)
 
 	// Metrics configuration
 	if c.Server.Metrics.Enabled && c.Server.Metrics.Endpoint == "" {
@@ -2549,11 +3897,634 @@ func (c *Config) ValidateAndApplyDefaults() []ValidationWarning {
 			engine.Priority = 50
 			c.Engines[name] = engine
 		}
+		warnings = append(warnings, validateEngineRequest(name, engine.Request)...)
+		if engine.Quota != (EngineQuotaConfig{}) {
+			var quotaWarnings []ValidationWarning
+			engine.Quota, quotaWarnings = validateEngineQuota(name, engine.Quota)
+			warnings = append(warnings, quotaWarnings...)
+			c.Engines[name] = engine
+		}
+	}
+
+	return warnings
+}
+
+// httpTokenChar reports whether r may appear in a header or cookie name
+func httpTokenChar(r rune) bool {
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// validHTTPToken reports whether name is a valid header or cookie name
+func validHTTPToken(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !httpTokenChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateEngineRequest drops engine request headers, cookies and params
+// with names that cannot be sent. Host is dropped too: engines pick their
+// own hosts.
+func validateEngineRequest(engine string, req EngineRequestConfig) []ValidationWarning {
+	var warnings []ValidationWarning
+	drop := func(kind string, values map[string]string, valid func(string) bool) {
+		for name := range values {
+			if !valid(name) {
+				warnings = append(warnings, ValidationWarning{
+					Field:   fmt.Sprintf("engines.%s.request.%s", engine, kind),
+					Message: fmt.Sprintf("Invalid name '%s', skipping", name),
+				})
+				delete(values, name)
+			}
+		}
+	}
+	drop("headers", req.Headers, func(name string) bool {
+		return validHTTPToken(name) && !strings.EqualFold(name, "Host")
+	})
+	drop("cookies", req.Cookies, validHTTPToken)
+	drop("params", req.Params, func(name string) bool { return strings.TrimSpace(name) != "" })
+	return warnings
+}
+
+// validateEngineQuota drops invalid peak hours, keeps the peak reserve
+// within 0-100 percent and drops a fallback naming the engine itself
+func validateEngineQuota(engine string, q EngineQuotaConfig) (EngineQuotaConfig, []ValidationWarning) {
+	var warnings []ValidationWarning
+	warn := func(field, message string) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   fmt.Sprintf("engines.%s.quota.%s", engine, field),
+			Message: message,
+		})
+	}
+	if q.Daily < 0 {
+		warn("daily", "Must not be negative, using 0 (no quota)")
+		q.Daily = 0
+	}
+	if _, _, ok := q.PeakRange(); !ok && q.PeakHours != "" {
+		warn("peak_hours", fmt.Sprintf("Invalid peak hours '%s', expected e.g. \"17-22\"; no hours are reserved", q.PeakHours))
+		q.PeakHours = ""
+	}
+	if q.PeakReserve < 0 || q.PeakReserve > 100 {
+		warn("peak_reserve", "Must be a percent from 0 to 100, using 0")
+		q.PeakReserve = 0
+	}
+	if strings.EqualFold(q.Fallback, engine) {
+		warn("fallback", "An engine cannot be its own fallback, skipping")
+		q.Fallback = ""
+	}
+	return q, warnings
+}
+
+// validTorServiceName limits hidden service names to safe directory names:
+// 1-32 lowercase letters, digits, '-' or '_'
+func validTorServiceName(name string) bool {
+	if name == "" || len(name) > 32 {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateTorServices drops additional hidden services that cannot be
+// published and applies defaults to the rest. Called with c.mu held.
+func (c *Config) validateTorServices() []ValidationWarning {
+	var warnings []ValidationWarning
+	seen := map[string]bool{}
+	valid := c.Server.Tor.Services[:0]
+	for i, svc := range c.Server.Tor.Services {
+		field := fmt.Sprintf("server.tor.services[%d]", i)
+		svc.Name = strings.ToLower(strings.TrimSpace(svc.Name))
+		// "site" holds the main onion's keys
+		if !validTorServiceName(svc.Name) || svc.Name == "site" || seen[svc.Name] {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("Invalid or duplicate hidden service name '%s', skipping service", svc.Name),
+			})
+			continue
+		}
+		seen[svc.Name] = true
+
+		if svc.VirtualPort == 0 {
+			svc.VirtualPort = 80
+		} else if svc.VirtualPort < 0 || svc.VirtualPort > 65535 {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field + ".virtual_port",
+				Message: fmt.Sprintf("Invalid virtual port %d, using default", svc.VirtualPort),
+				Default: 80,
+			})
+			svc.VirtualPort = 80
+		}
+
+		svc.Scope = strings.ToLower(strings.TrimSpace(svc.Scope))
+		switch svc.Scope {
+		case TorScopeAll, TorScopeAPI:
+		case "":
+			svc.Scope = TorScopeAll
+		default:
+			warnings = append(warnings, ValidationWarning{
+				Field:   field + ".scope",
+				Message: fmt.Sprintf("Unknown scope '%s', using all", svc.Scope),
+				Default: TorScopeAll,
+			})
+			svc.Scope = TorScopeAll
+		}
+		valid = append(valid, svc)
+	}
+	c.Server.Tor.Services = valid
+	return warnings
+}
+
+// validateAccessLog resets an unknown access log format, privacy preset
+// or rotation policy to its default. Called with c.mu held.
+func (c *Config) validateAccessLog() []ValidationWarning {
+	var warnings []ValidationWarning
+	a := &c.Server.Logs.Access
+	switch a.Format = strings.ToLower(strings.TrimSpace(a.Format)); a.Format {
+	case "":
+		a.Format = "apache"
+	case "combined":
+		a.Format = "apache"
+	case "common":
+		a.Format = "nginx"
+	}
+	if !slices.Contains(AccessLogFormats, a.Format) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.logs.access.format",
+			Message: fmt.Sprintf("'%s' is not one of %s, using apache", a.Format, strings.Join(AccessLogFormats, ", ")),
+			Default: "apache",
+		})
+		a.Format = "apache"
+	}
+	if a.Format == "custom" && strings.TrimSpace(a.Custom) == "" {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.logs.access.custom",
+			Message: "format custom needs a custom format string, using apache",
+			Default: "apache",
+		})
+		a.Format = "apache"
+	}
+	if a.Privacy = strings.ToLower(strings.TrimSpace(a.Privacy)); a.Privacy == "" {
+		a.Privacy = "strict"
+	} else if !slices.Contains(AccessLogPrivacy, a.Privacy) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.logs.access.privacy",
+			Message: fmt.Sprintf("'%s' is not one of %s, using strict", a.Privacy, strings.Join(AccessLogPrivacy, ", ")),
+			Default: "strict",
+		})
+		a.Privacy = "strict"
+	}
+	if _, err := ParseLogRotation(a.Rotate); err != nil {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.logs.access.rotate",
+			Message: err.Error() + ", using monthly",
+			Default: "monthly",
+		})
+		a.Rotate = "monthly"
+	}
+	return warnings
+}
+
+// validateCategoryEngines drops category engine lists that are empty or
+// malformed, so those categories fall back to every engine that supports
+// them. Called with c.mu held.
+func (c *Config) validateCategoryEngines() []ValidationWarning {
+	var warnings []ValidationWarning
+	for category, list := range c.Search.CategoryEngines {
+		normalized, err := NormalizeCategoryEngines(list)
+		if err != nil {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "search.category_engines." + category,
+				Message: err.Error() + ", using every engine that supports the category",
+			})
+			delete(c.Search.CategoryEngines, category)
+			continue
+		}
+		c.Search.CategoryEngines[category] = normalized
+	}
+	return warnings
+}
+
+// validatePoliteness resets malformed search.politeness durations to their
+// defaults. Called with c.mu held.
+func (c *Config) validatePoliteness() []ValidationWarning {
+	var warnings []ValidationWarning
+	p := &c.Search.Politeness
+	check := func(field string, value *string, def string) {
+		if *value == "" {
+			return
+		}
+		if d, err := time.ParseDuration(*value); err != nil || d < 0 {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field,
+				Message: fmt.Sprintf("'%s' is not a duration, using %s", *value, def),
+				Default: def,
+			})
+			*value = def
+		}
+	}
+	check("search.politeness.min_interval", &p.MinInterval, "250ms")
+	check("search.politeness.max_crawl_delay", &p.MaxCrawlDelay, "5s")
+	check("search.politeness.max_wait", &p.MaxWait, "2s")
+	if p.MaxConcurrent < 0 {
+		p.MaxConcurrent = 0
+	}
+	hostDefault := p.MinInterval
+	if hostDefault == "" {
+		hostDefault = "0s"
+	}
+	for host, h := range p.Hosts {
+		check("search.politeness.hosts."+host+".min_interval", &h.MinInterval, hostDefault)
+		p.Hosts[host] = h
+	}
+	return warnings
+}
+
+// validateResultCache resets a malformed search.result_cache.ttl to its
+// default and drops category TTLs that are not durations or not
+// categories. Called with c.mu held.
+func (c *Config) validateResultCache() []ValidationWarning {
+	var warnings []ValidationWarning
+	rc := &c.Search.ResultCache
+	if d, err := time.ParseDuration(rc.TTL); rc.TTL != "" && (err != nil || d <= 0) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "search.result_cache.ttl",
+			Message: fmt.Sprintf("'%s' is not a duration, using 5m", rc.TTL),
+			Default: "5m",
+		})
+		rc.TTL = "5m"
+	}
+	for category, ttl := range rc.Categories {
+		field := "search.result_cache.categories." + category
+		if !slices.Contains(model.AllCategories(), model.Category(category)) {
+			warnings = append(warnings, ValidationWarning{Field: field, Message: "not a category, ignored"})
+			delete(rc.Categories, category)
+			continue
+		}
+		if d, err := time.ParseDuration(ttl); err != nil || d <= 0 {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field,
+				Message: fmt.Sprintf("'%s' is not a duration, using search.result_cache.ttl", ttl),
+			})
+			delete(rc.Categories, category)
+		}
+	}
+	return warnings
+}
+
+// validateJWT resets a malformed or too long server.jwt.ttl and drops
+// signing keys without an ID, with a short secret or whose ID is taken.
+// Called with c.mu held.
+func (c *Config) validateJWT() []ValidationWarning {
+	var warnings []ValidationWarning
+	j := &c.Server.JWT
+	if j.TTL == "" {
+		j.TTL = "15m"
+	}
+	if d, err := time.ParseDuration(j.TTL); err != nil || d <= 0 || d > 24*time.Hour {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.jwt.ttl",
+			Message: fmt.Sprintf("'%s' is not a duration up to 24h, using 15m", j.TTL),
+			Default: "15m",
+		})
+		j.TTL = "15m"
+	}
+	seen := make(map[string]bool, len(j.Keys))
+	keys := j.Keys[:0]
+	for i, key := range j.Keys {
+		field := fmt.Sprintf("server.jwt.keys[%d]", i)
+		switch {
+		case key.ID == "" || seen[key.ID]:
+			warnings = append(warnings, ValidationWarning{Field: field, Message: "needs an id of its own, ignored"})
+		case len(key.Secret) < 32:
+			warnings = append(warnings, ValidationWarning{Field: field, Message: "secret is shorter than 32 characters, ignored"})
+		default:
+			seen[key.ID] = true
+			keys = append(keys, key)
+		}
+	}
+	j.Keys = keys
+	return warnings
+}
+
+// validateKeys clears a malformed server.security.keys.rotate_after and
+// drops scheduled names that are not secrets. Called with c.mu held.
+func (c *Config) validateKeys() []ValidationWarning {
+	var warnings []ValidationWarning
+	k := &c.Server.Security.Keys
+	if seconds, err := ParseDuration(k.RotateAfter); err != nil || seconds < 0 || (k.RotateAfter != "" && seconds < 86400) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.security.keys.rotate_after",
+			Message: fmt.Sprintf("'%s' is not an age of a day or more, secrets are not rotated on a schedule", k.RotateAfter),
+		})
+		k.RotateAfter = ""
+	}
+	scheduled := k.Scheduled[:0]
+	for _, name := range k.Scheduled {
+		if !slices.Contains(keyNames, name) || slices.Contains(scheduled, name) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "server.security.keys.scheduled",
+				Message: fmt.Sprintf("'%s' is not a secret or is listed twice, ignored", name),
+			})
+			continue
+		}
+		scheduled = append(scheduled, name)
+	}
+	k.Scheduled = scheduled
+	return warnings
+}
+
+// validateUserAgents resets unknown user agent policies and drops blank
+// pool entries. Called with c.mu held.
+func (c *Config) validateUserAgents() []ValidationWarning {
+	var warnings []ValidationWarning
+	ua := &c.Search.UserAgents
+	valid := func(policy string) bool {
+		return policy == "" || slices.Contains(UserAgentPolicies, policy)
+	}
+	if ua.Policy = strings.ToLower(strings.TrimSpace(ua.Policy)); !valid(ua.Policy) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "search.user_agents.policy",
+			Message: fmt.Sprintf("Unknown policy '%s', using fixed", ua.Policy),
+			Default: "fixed",
+		})
+		ua.Policy = "fixed"
+	}
+	pool := ua.Pool[:0]
+	for _, agent := range ua.Pool {
+		if agent = strings.TrimSpace(agent); agent != "" {
+			pool = append(pool, agent)
+		}
+	}
+	ua.Pool = pool
+	for name, engine := range ua.Engines {
+		engine.Pin = strings.TrimSpace(engine.Pin)
+		if engine.Policy = strings.ToLower(strings.TrimSpace(engine.Policy)); !valid(engine.Policy) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "search.user_agents.engines." + name + ".policy",
+				Message: fmt.Sprintf("Unknown policy '%s', using search.user_agents.policy", engine.Policy),
+			})
+			engine.Policy = ""
+		}
+		ua.Engines[name] = engine
+	}
+	return warnings
+}
+
+// validateRanking resets a malformed search.ranking pipeline to the
+// default, every stage off. Called with c.mu held.
+func (c *Config) validateRanking() []ValidationWarning {
+	normalized, err := NormalizeRankingStages(c.Search.Ranking.Stages)
+	if err != nil {
+		c.Search.Ranking.Stages = DefaultRankingStages()
+		return []ValidationWarning{{
+			Field:   "search.ranking.stages",
+			Message: err.Error() + ", turning ranking stages off",
+		}}
+	}
+	c.Search.Ranking.Stages = normalized
+	return nil
+}
+
+// validateCircuitBreaker resets malformed search.circuit_breaker settings
+// to their defaults. Called with c.mu held.
+func (c *Config) validateCircuitBreaker() []ValidationWarning {
+	var warnings []ValidationWarning
+	cb := &c.Search.CircuitBreaker
+	check := func(field string, value *string, def string, zeroOK bool) {
+		if *value == "" {
+			*value = def
+			return
+		}
+		if d, err := time.ParseDuration(*value); err != nil || d < 0 || (d == 0 && !zeroOK) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "search.circuit_breaker." + field,
+				Message: fmt.Sprintf("'%s' is not a duration, using %s", *value, def),
+				Default: def,
+			})
+			*value = def
+		}
+	}
+	check("slow_call", &cb.SlowCall, "10s", true)
+	check("cooldown", &cb.Cooldown, "10m", false)
+	check("max_cooldown", &cb.MaxCooldown, "1h", false)
+	if cb.FailureThreshold < 0 {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "search.circuit_breaker.failure_threshold",
+			Message: fmt.Sprintf("%d is negative, using 3", cb.FailureThreshold),
+			Default: "3",
+		})
+		cb.FailureThreshold = 3
+	}
+	if cb.ErrorRate < 0 || cb.ErrorRate > 1 {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "search.circuit_breaker.error_rate",
+			Message: fmt.Sprintf("%g is outside 0-1, using 0.5", cb.ErrorRate),
+			Default: "0.5",
+		})
+		cb.ErrorRate = 0.5
+	}
+	cb.MinRequests = max(1, min(cb.MinRequests, 20))
+	return warnings
+}
+
+// macroNamePattern is what a macro name may look like: "issues", "go-docs"
+var macroNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// validateMacros drops macros with a malformed or repeated name or an
+// empty query. Called with c.mu held.
+func (c *Config) validateMacros() []ValidationWarning {
+	var warnings []ValidationWarning
+	kept := c.Search.Macros[:0]
+	seen := make(map[string]bool)
+	for i, m := range c.Search.Macros {
+		m.Name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(m.Name, "~")))
+		m.Query = strings.TrimSpace(m.Query)
+		field := fmt.Sprintf("search.macros[%d]", i)
+		var problem string
+		switch {
+		case !macroNamePattern.MatchString(m.Name):
+			problem = fmt.Sprintf("name '%s' must be lowercase letters, digits, '_' or '-'", m.Name)
+		case seen[m.Name]:
+			problem = fmt.Sprintf("~%s is defined twice", m.Name)
+		case m.Query == "" || strings.HasPrefix(m.Query, "~"):
+			problem = fmt.Sprintf("~%s needs a query that is not another macro", m.Name)
+		case len(m.Query) > 200:
+			problem = fmt.Sprintf("~%s query is longer than 200 characters", m.Name)
+		}
+		if problem != "" {
+			warnings = append(warnings, ValidationWarning{Field: field, Message: problem + ", ignoring the macro"})
+			continue
+		}
+		seen[m.Name] = true
+		kept = append(kept, m)
+	}
+	c.Search.Macros = kept
+	return warnings
+}
+
+// validateChaos drops chaos entries with a bad latency, fault or rate, and
+// lowercases engine names. Called with c.mu held.
+func (c *Config) validateChaos() []ValidationWarning {
+	var warnings []ValidationWarning
+	engines := make(map[string]ChaosEngineConfig, len(c.Search.Chaos.Engines))
+	for name, chaos := range c.Search.Chaos.Engines {
+		field := "search.chaos.engines." + name
+		if err := chaos.Validate(); err != nil {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field,
+				Message: err.Error() + ", ignoring the entry",
+			})
+			continue
+		}
+		engines[strings.ToLower(strings.TrimSpace(name))] = chaos
+	}
+	c.Search.Chaos.Engines = engines
+	if mode := c.Server.Mode; len(engines) > 0 && mode != "development" && mode != "dev" {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "search.chaos",
+			Message: "applies only in development mode, ignoring it",
+		})
+	}
+	return warnings
+}
+
+// Validate checks a chaos entry's latency, fault and rate
+func (e ChaosEngineConfig) Validate() error {
+	if e.Latency != "" {
+		if d, err := time.ParseDuration(e.Latency); err != nil || d < 0 {
+			return fmt.Errorf("latency '%s' is not a duration", e.Latency)
+		}
+	}
+	if e.Fault != "" && !slices.Contains(ChaosFaults, e.Fault) {
+		return fmt.Errorf("fault '%s' is not one of %s", e.Fault, strings.Join(ChaosFaults, ", "))
+	}
+	if e.Rate < 0 || e.Rate > 1 {
+		return fmt.Errorf("rate %g is outside 0-1", e.Rate)
+	}
+	if e.Latency == "" && e.Fault == "" {
+		return fmt.Errorf("neither latency nor fault is set")
+	}
+	return nil
+}
+
+// featureNamePattern is what a feature flag name may look like:
+// "summarization", "ranking.v2"
+var featureNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// validateFeatures drops feature flags with malformed names and clamps
+// rollout percentages. Called with c.mu held.
+func (c *Config) validateFeatures() []ValidationWarning {
+	var warnings []ValidationWarning
+	for name, flag := range c.Server.Features {
+		if !featureNamePattern.MatchString(name) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "server.features." + name,
+				Message: "feature names must be lowercase letters, digits, '.', '_' or '-', ignoring the flag",
+			})
+			delete(c.Server.Features, name)
+			continue
+		}
+		if flag.Percent < 0 || flag.Percent > 100 {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "server.features." + name + ".percent",
+				Message: "percent must be between 0 and 100, using 100",
+			})
+			flag.Percent = 100
+			c.Server.Features[name] = flag
+		}
+	}
+	return warnings
+}
+
+// validateSuggestions lowercases suggestion provider names, drops blank and
+// repeated ones and defaults or clamps weights. Called with c.mu held.
+func (c *Config) validateSuggestions() []ValidationWarning {
+	var warnings []ValidationWarning
+	sc := &c.Search.Suggestions
+	if sc.Providers == nil {
+		// Not configured; an explicit empty list leaves only the history
+		sc.Providers = []SuggestionProviderConfig{{Name: "duckduckgo", Weight: 1}, {Name: "google", Weight: 1}}
+	}
+	seen := make(map[string]bool, len(sc.Providers))
+	providers := make([]SuggestionProviderConfig, 0, len(sc.Providers))
+	for i, p := range sc.Providers {
+		field := fmt.Sprintf("search.suggestions.providers[%d]", i)
+		p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+		switch {
+		case p.Name == "":
+			warnings = append(warnings, ValidationWarning{Field: field, Message: "provider has no name, ignoring it"})
+			continue
+		case seen[p.Name]:
+			warnings = append(warnings, ValidationWarning{Field: field, Message: fmt.Sprintf("provider '%s' is listed twice, ignoring the repeat", p.Name)})
+			continue
+		}
+		seen[p.Name] = true
+		p.Weight = clampSuggestionWeight(field+".weight", p.Weight, 1, &warnings)
+		providers = append(providers, p)
 	}
+	sc.Providers = providers
+	sc.HistoryWeight = clampSuggestionWeight("search.suggestions.history_weight", sc.HistoryWeight, 0.5, &warnings)
+	return warnings
+}
 
+// validateSafeSearch keeps safe_search a known level and drops empty
+// blocklist entries
+func (c *Config) validateSafeSearch() []ValidationWarning {
+	var warnings []ValidationWarning
+	if c.Search.SafeSearch < 0 || c.Search.SafeSearch > 2 {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "search.safe_search",
+			Message: fmt.Sprintf("%d is not 0 (off), 1 (moderate) or 2 (strict)", c.Search.SafeSearch),
+			Default: 1,
+		})
+		c.Search.SafeSearch = 1
+	}
+	f := &c.Search.SafeSearchFilter
+	f.Domains = nonEmptyLower(f.Domains)
+	f.Keywords = nonEmptyLower(f.Keywords)
 	return warnings
 }
 
+// nonEmptyLower returns values trimmed and lower-cased, without the empty
+// ones
+func nonEmptyLower(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// clampSuggestionWeight returns weight, def when unset, or the nearest
+// bound with a warning when outside 0-MaxCategoryEngineWeight
+func clampSuggestionWeight(field string, weight, def float64, warnings *[]ValidationWarning) float64 {
+	switch {
+	case weight == 0:
+		return def
+	case weight < 0:
+		*warnings = append(*warnings, ValidationWarning{Field: field, Message: "weight must not be negative", Default: def})
+		return def
+	case weight > MaxCategoryEngineWeight:
+		*warnings = append(*warnings, ValidationWarning{Field: field, Message: fmt.Sprintf("weight above %g", MaxCategoryEngineWeight), Default: MaxCategoryEngineWeight})
+		return MaxCategoryEngineWeight
+	}
+	return weight
+}
+
 // LogValidationWarnings prints validation warnings to stdout
 // Per AI.md PART 12: Warn and use defaults, not error
 func LogValidationWarnings(warnings []ValidationWarning) {
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -387,3 +389,803 @@ func TestConfigSaveIncludesAddConfigComments(t *testing.T) {
 		t.Error("Saved config should contain 'server:' section")
 	}
 }
+
+// TestValidateTorServices verifies invalid additional hidden services are
+// dropped and defaults are applied to the rest.
+func TestValidateTorServices(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Tor.Services = []TorServiceConfig{
+		{Name: "API", Enabled: true, Scope: "API"},
+		{Name: "api", Enabled: true},
+		{Name: "site", Enabled: true},
+		{Name: "../keys", Enabled: true},
+		{Name: "mirror", VirtualPort: 70000, Scope: "admin"},
+	}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	want := []TorServiceConfig{
+		{Name: "api", Enabled: true, VirtualPort: 80, Scope: TorScopeAPI},
+		{Name: "mirror", VirtualPort: 80, Scope: TorScopeAll},
+	}
+	if len(cfg.Server.Tor.Services) != len(want) {
+		t.Fatalf("Services = %+v, want %+v", cfg.Server.Tor.Services, want)
+	}
+	for i := range want {
+		if cfg.Server.Tor.Services[i] != want[i] {
+			t.Errorf("Services[%d] = %+v, want %+v", i, cfg.Server.Tor.Services[i], want[i])
+		}
+	}
+
+	torWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "server.tor.services") {
+			torWarnings++
+		}
+	}
+	// duplicate, reserved and unsafe names, then the bad port and scope
+	if torWarnings != 5 {
+		t.Errorf("got %d tor service warnings, want 5", torWarnings)
+	}
+}
+
+func TestValidateCategoryEngines(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.CategoryEngines = map[string][]CategoryEngineConfig{
+		"general": {{Engine: " DuckDuckGo ", Weight: 1.5}, {Engine: "brave"}},
+		"images":  {},
+		"news":    {{Engine: "bing"}, {Engine: "BING"}},
+		"videos":  {{Engine: "youtube", Weight: -1}},
+	}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	want := []CategoryEngineConfig{{Engine: "duckduckgo", Weight: 1.5}, {Engine: "brave", Weight: 1.0}}
+	got := cfg.Search.CategoryEngines["general"]
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("general = %+v, want %+v", got, want)
+	}
+	for _, category := range []string{"images", "news", "videos"} {
+		if _, ok := cfg.Search.CategoryEngines[category]; ok {
+			t.Errorf("invalid %s list was kept", category)
+		}
+	}
+
+	listWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.category_engines.") {
+			listWarnings++
+		}
+	}
+	if listWarnings != 3 {
+		t.Errorf("got %d category engine warnings, want 3", listWarnings)
+	}
+}
+
+func TestValidateEngineRequest(t *testing.T) {
+	cfg := DefaultConfig()
+	google := cfg.Engines["google"]
+	google.Request = EngineRequestConfig{
+		Headers: map[string]string{"Accept-Language": "{locale}", "Bad Header": "x", "host": "evil.example"},
+		Cookies: map[string]string{"SOCS": "CAI", "a;b": "1"},
+		Params:  map[string]string{"hl": "{locale}", " ": "x"},
+	}
+	cfg.Engines["google"] = google
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	req := cfg.Engines["google"].Request
+	if len(req.Headers) != 1 || len(req.Cookies) != 1 || len(req.Params) != 1 {
+		t.Errorf("request = %+v, want only the valid entries", req)
+	}
+	requestWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "engines.google.request.") {
+			requestWarnings++
+		}
+	}
+	if requestWarnings != 4 {
+		t.Errorf("got %d request warnings, want 4", requestWarnings)
+	}
+	if !(EngineRequestConfig{}).IsZero() || req.IsZero() {
+		t.Error("IsZero() misreports whether request settings are present")
+	}
+}
+
+func TestValidateSuggestions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.Suggestions = SuggestionsConfig{
+		Providers: []SuggestionProviderConfig{
+			{Name: " Google ", Weight: 2},
+			{Name: "google"},
+			{Name: ""},
+			{Name: "brave", Weight: 50},
+			{Name: "wikipedia", Weight: -1},
+		},
+		HistoryWeight: 0,
+	}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	want := []SuggestionProviderConfig{{Name: "google", Weight: 2}, {Name: "brave", Weight: MaxCategoryEngineWeight}, {Name: "wikipedia", Weight: 1}}
+	got := cfg.Search.Suggestions.Providers
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("providers = %+v, want %+v", got, want)
+	}
+	if cfg.Search.Suggestions.HistoryWeight != 0.5 {
+		t.Errorf("history_weight = %g, want the 0.5 default", cfg.Search.Suggestions.HistoryWeight)
+	}
+	suggestionWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.suggestions.") {
+			suggestionWarnings++
+		}
+	}
+	if suggestionWarnings != 4 {
+		t.Errorf("got %d suggestion warnings, want 4", suggestionWarnings)
+	}
+
+	// Unset providers get the defaults; an empty list stays empty
+	cfg.Search.Suggestions.Providers = nil
+	cfg.ValidateAndApplyDefaults()
+	if len(cfg.Search.Suggestions.Providers) != 2 {
+		t.Errorf("providers = %+v, want the defaults", cfg.Search.Suggestions.Providers)
+	}
+	cfg.Search.Suggestions.Providers = []SuggestionProviderConfig{}
+	cfg.ValidateAndApplyDefaults()
+	if len(cfg.Search.Suggestions.Providers) != 0 {
+		t.Errorf("providers = %+v, want none", cfg.Search.Suggestions.Providers)
+	}
+}
+
+func TestValidateFeatures(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Features = map[string]FeatureConfig{
+		"summarization": {Enabled: true, Percent: 10},
+		"Bad Name":      {Enabled: true},
+		"ranking.v2":    {Enabled: true, Percent: 150},
+	}
+
+	cfg.ValidateAndApplyDefaults()
+
+	if _, ok := cfg.Server.Features["Bad Name"]; ok {
+		t.Error("flag with an invalid name was kept")
+	}
+	if got := cfg.Server.Features["summarization"].EffectivePercent(); got != 10 {
+		t.Errorf("summarization percent = %d, want 10", got)
+	}
+	if got := cfg.Server.Features["ranking.v2"].Percent; got != 100 {
+		t.Errorf("ranking.v2 percent = %d, want clamped to 100", got)
+	}
+	if got := (FeatureConfig{}).EffectivePercent(); got != 100 {
+		t.Errorf("unset percent = %d, want 100", got)
+	}
+}
+
+func TestValidateEngineQuota(t *testing.T) {
+	cfg := DefaultConfig()
+	google := cfg.Engines["google"]
+	google.Quota = EngineQuotaConfig{Daily: 1000, PeakHours: "17-25", PeakReserve: 120, Fallback: "Google"}
+	cfg.Engines["google"] = google
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	quota := cfg.Engines["google"].Quota
+	if quota != (EngineQuotaConfig{Daily: 1000}) {
+		t.Errorf("quota = %+v, want only the daily quota kept", quota)
+	}
+	quotaWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "engines.google.quota.") {
+			quotaWarnings++
+		}
+	}
+	if quotaWarnings != 3 {
+		t.Errorf("got %d quota warnings, want 3", quotaWarnings)
+	}
+
+	for hours, want := range map[string][2]int{"17-22": {17, 22}, " 22 - 2 ": {22, 2}, "20-24": {20, 0}} {
+		start, end, ok := EngineQuotaConfig{PeakHours: hours}.PeakRange()
+		if !ok || start != want[0] || end != want[1] {
+			t.Errorf("PeakRange(%q) = %d, %d, %v", hours, start, end, ok)
+		}
+	}
+	for _, hours := range []string{"", "17", "5-5", "0-24", "x-3"} {
+		if _, _, ok := (EngineQuotaConfig{PeakHours: hours}).PeakRange(); ok {
+			t.Errorf("PeakRange(%q) accepted", hours)
+		}
+	}
+}
+
+func TestValidatePoliteness(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.Politeness.MinInterval = "fast"
+	cfg.Search.Politeness.MaxWait = "-1s"
+	cfg.Search.Politeness.Hosts = map[string]HostPolitenessConfig{
+		"www.google.com": {MinInterval: "1s"},
+		"www.bing.com":   {MinInterval: "1 second", MaxConcurrent: 2},
+	}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	p := cfg.Search.Politeness
+	if p.MinInterval != "250ms" || p.MaxWait != "2s" || p.MaxCrawlDelay != "5s" {
+		t.Errorf("politeness = %+v, want malformed durations reset", p)
+	}
+	if p.Hosts["www.google.com"].MinInterval != "1s" || p.Hosts["www.bing.com"] != (HostPolitenessConfig{MinInterval: "250ms", MaxConcurrent: 2}) {
+		t.Errorf("hosts = %+v", p.Hosts)
+	}
+	politenessWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.politeness.") {
+			politenessWarnings++
+		}
+	}
+	if politenessWarnings != 3 {
+		t.Errorf("got %d politeness warnings, want 3", politenessWarnings)
+	}
+}
+
+func TestValidateResultCache(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Search.ResultCache.TTL != "5m" || cfg.Search.ResultCache.Categories["news"] != "1m" {
+		t.Fatalf("default result cache = %+v", cfg.Search.ResultCache)
+	}
+	cfg.Search.ResultCache.TTL = "forever"
+	cfg.Search.ResultCache.Categories = map[string]string{"news": "30s", "images": "1 hour", "gossip": "1m"}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	rc := cfg.Search.ResultCache
+	if rc.TTL != "5m" {
+		t.Errorf("ttl = %q, want 5m", rc.TTL)
+	}
+	if len(rc.Categories) != 1 || rc.Categories["news"] != "30s" {
+		t.Errorf("categories = %v, want only news", rc.Categories)
+	}
+	cacheWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.result_cache.") {
+			cacheWarnings++
+		}
+	}
+	if cacheWarnings != 3 {
+		t.Errorf("got %d result cache warnings, want 3", cacheWarnings)
+	}
+}
+
+func TestValidateJWT(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.JWT.TTL = "48h"
+	cfg.Server.JWT.Keys = []JWTKeyConfig{
+		{ID: "a", Secret: strings.Repeat("a", 32)},
+		{ID: "a", Secret: strings.Repeat("b", 32)},
+		{ID: "", Secret: strings.Repeat("c", 32)},
+		{ID: "short", Secret: "secret"},
+		{ID: "b", Secret: strings.Repeat("d", 40)},
+	}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	j := cfg.Server.JWT
+	if j.TTL != "15m" {
+		t.Errorf("ttl = %q, want 15m", j.TTL)
+	}
+	if len(j.Keys) != 2 || j.Keys[0].ID != "a" || j.Keys[0].Secret != strings.Repeat("a", 32) || j.Keys[1].ID != "b" {
+		t.Errorf("keys = %+v, want a and b", j.Keys)
+	}
+	jwtWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "server.jwt.") {
+			jwtWarnings++
+		}
+	}
+	if jwtWarnings != 4 {
+		t.Errorf("got %d jwt warnings, want 4", jwtWarnings)
+	}
+}
+
+func TestValidateKeys(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, w := range cfg.ValidateAndApplyDefaults() {
+		if strings.HasPrefix(w.Field, "server.security.keys.") {
+			t.Errorf("default keys config warned: %+v", w)
+		}
+	}
+
+	for _, tc := range []struct {
+		rotateAfter string
+		want        string
+	}{
+		{"90d", "90d"},
+		{"720h", "720h"},
+		{"12h", ""},
+		{"soon", ""},
+	} {
+		cfg := DefaultConfig()
+		cfg.Server.Security.Keys.RotateAfter = tc.rotateAfter
+		cfg.ValidateAndApplyDefaults()
+		if got := cfg.Server.Security.Keys.RotateAfter; got != tc.want {
+			t.Errorf("rotate_after %q = %q, want %q", tc.rotateAfter, got, tc.want)
+		}
+	}
+
+	cfg = DefaultConfig()
+	cfg.Server.Security.Keys.Scheduled = []string{"jwt", "session", "secret_key", "jwt"}
+	cfg.ValidateAndApplyDefaults()
+	if got := cfg.Server.Security.Keys.Scheduled; len(got) != 2 || got[0] != "jwt" || got[1] != "secret_key" {
+		t.Errorf("scheduled = %v, want [jwt secret_key]", got)
+	}
+}
+
+func TestValidateRanking(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.Ranking.Stages = []RankingStageConfig{
+		{Name: " Freshness ", Enabled: true},
+		{Name: "domain_authority", Enabled: true, Weights: map[string]float64{"Wikipedia.org": 1.5}},
+	}
+	for _, w := range cfg.ValidateAndApplyDefaults() {
+		if strings.HasPrefix(w.Field, "search.ranking.") {
+			t.Errorf("valid ranking warned: %+v", w)
+		}
+	}
+	stages := cfg.Search.Ranking.Stages
+	if len(stages) != 2 || stages[0].Name != "freshness" || stages[0].Boost != 0.5 || stages[0].HalfLife != "7d" {
+		t.Errorf("stages = %+v", stages)
+	}
+	if stages[1].Weights["wikipedia.org"] != 1.5 || stages[1].HalfLife != "" {
+		t.Errorf("domain_authority = %+v", stages[1])
+	}
+
+	for _, stages := range [][]RankingStageConfig{
+		{{Name: "pagerank"}},
+		{{Name: "freshness"}, {Name: "freshness"}},
+		{{Name: "freshness", HalfLife: "soon"}},
+		{{Name: "duplicate_penalty", Factor: 2}},
+		{{Name: "engine_weight", Weights: map[string]float64{"google": 11}}},
+	} {
+		cfg := DefaultConfig()
+		cfg.Search.Ranking.Stages = stages
+		warnings := cfg.ValidateAndApplyDefaults()
+		got := cfg.Search.Ranking.Stages
+		if len(got) != len(RankingStages) || got[2].Enabled {
+			t.Errorf("%+v: stages = %+v, want the defaults", stages, got)
+		}
+		if !slices.ContainsFunc(warnings, func(w ValidationWarning) bool { return w.Field == "search.ranking.stages" }) {
+			t.Errorf("%+v: no warning", stages)
+		}
+	}
+}
+
+func TestValidateCircuitBreaker(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.CircuitBreaker = CircuitBreakerConfig{
+		FailureThreshold: -1,
+		ErrorRate:        2,
+		MinRequests:      50,
+		SlowCall:         "0s",
+		Cooldown:         "0s",
+		MaxCooldown:      "forever",
+	}
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	want := CircuitBreakerConfig{FailureThreshold: 3, ErrorRate: 0.5, MinRequests: 20, SlowCall: "0s", Cooldown: "10m", MaxCooldown: "1h"}
+	if got := cfg.Search.CircuitBreaker; got != want {
+		t.Errorf("circuit breaker = %+v, want %+v", got, want)
+	}
+	n := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.circuit_breaker.") {
+			n++
+		}
+	}
+	if n != 4 {
+		t.Errorf("got %d circuit breaker warnings, want 4", n)
+	}
+}
+
+func TestValidateMacros(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.Macros = []MacroConfig{
+		{Name: "~Issues", Query: " site:github.com/{repo}/issues "},
+		{Name: "issues", Query: "site:gitlab.com/{repo}"},
+		{Name: "bad name", Query: "x"},
+		{Name: "empty"},
+		{Name: "loop", Query: "~issues {repo}"},
+		{Name: "docs", Query: "site:pkg.go.dev"},
+	}
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	want := []MacroConfig{{Name: "issues", Query: "site:github.com/{repo}/issues"}, {Name: "docs", Query: "site:pkg.go.dev"}}
+	if !slices.Equal(cfg.Search.Macros, want) {
+		t.Errorf("macros = %+v", cfg.Search.Macros)
+	}
+	n := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.macros[") {
+			n++
+		}
+	}
+	if n != 4 {
+		t.Errorf("got %d macro warnings, want 4", n)
+	}
+}
+
+func TestValidateChaos(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Mode = "development"
+	cfg.Search.Chaos.Engines = map[string]ChaosEngineConfig{
+		"Google":     {Latency: "2s", Fault: "error", Rate: 0.5},
+		"bing":       {Fault: "explode"},
+		"duckduckgo": {Latency: "soon"},
+		"brave":      {},
+		"qwant":      {Fault: "timeout", Rate: 2},
+	}
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	if len(cfg.Search.Chaos.Engines) != 1 || cfg.Search.Chaos.Engines["google"].Fault != "error" {
+		t.Errorf("chaos engines = %+v", cfg.Search.Chaos.Engines)
+	}
+	n := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.chaos") {
+			n++
+		}
+	}
+	if n != 4 {
+		t.Errorf("got %d chaos warnings, want 4", n)
+	}
+
+	cfg.Server.Mode = "production"
+	for _, w := range cfg.ValidateAndApplyDefaults() {
+		if w.Field == "search.chaos" {
+			return
+		}
+	}
+	t.Error("chaos in production mode was not warned about")
+}
+
+func TestValidateUserAgents(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Search.UserAgents = UserAgentsConfig{
+		Policy: "Random",
+		Pool:   []string{" Mozilla/5.0 A ", "", "Mozilla/5.0 B"},
+		Engines: map[string]EngineUserAgentConfig{
+			"google": {Policy: "BEST"},
+			"bing":   {Policy: "sometimes", Pin: " Mozilla/5.0 B "},
+		},
+	}
+
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	ua := cfg.Search.UserAgents
+	if ua.Policy != "fixed" || len(ua.Pool) != 2 || ua.Pool[0] != "Mozilla/5.0 A" {
+		t.Errorf("user agents = %+v", ua)
+	}
+	if ua.Engines["google"].Policy != "best" || ua.Engines["bing"] != (EngineUserAgentConfig{Pin: "Mozilla/5.0 B"}) {
+		t.Errorf("engines = %+v", ua.Engines)
+	}
+	uaWarnings := 0
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "search.user_agents.") {
+			uaWarnings++
+		}
+	}
+	if uaWarnings != 2 {
+		t.Errorf("got %d user agent warnings, want 2", uaWarnings)
+	}
+}
+
+func TestReference(t *testing.T) {
+	fields := make(map[string]ReferenceField)
+	for _, f := range Reference() {
+		fields[f.Key] = f
+	}
+
+	tests := []ReferenceField{
+		// env tag read only on first run
+		{Key: "server.port", Type: "int", Env: "PORT", EnvFirstRun: true},
+		// desc tag
+		{Key: "search.safe_search", Type: "int", Default: "1", Description: "Default safe search level: 0 off, 1 moderate, 2 strict"},
+		// doc comment, without its specification reference
+		{Key: "server.https_port", Type: "int", Description: "HTTPS port for dual port mode (optional)"},
+		// differs per instance
+		{Key: "server.secret_key", Type: "string", Default: "(generated)"},
+		// map of structs
+		{Key: "engines.<name>.quota.daily", Type: "int", Description: "Requests allowed per day; 0 means no quota"},
+	}
+	for _, want := range tests {
+		got, ok := fields[want.Key]
+		if !ok {
+			t.Errorf("%s missing", want.Key)
+			continue
+		}
+		if want.Description == "" {
+			got.Description = ""
+		}
+		if got != want {
+			t.Errorf("%s = %+v, want %+v", want.Key, got, want)
+		}
+	}
+	if f := fields["server.email.smtp.host"]; f.Env != "SMTP_HOST" || f.EnvFirstRun {
+		t.Errorf("smtp host = %+v", f)
+	}
+	if f := fields["search.politeness.min_interval"]; f.Type != "duration" {
+		t.Errorf("min_interval type = %q", f.Type)
+	}
+}
+
+func TestWriteReferenceMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := WriteReferenceMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{"# Config Reference", "\n## server\n", "\n## engines\n", "| `server.port` | int |  | `PORT` (*first run*) |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q", want)
+		}
+	}
+}
+
+func TestValidateAccessLog(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Logs.Access.Format = "Common"
+	cfg.Server.Logs.Access.Privacy = "paranoid"
+	cfg.Server.Logs.Access.Rotate = "hourly"
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	a := cfg.Server.Logs.Access
+	if a.Format != "nginx" || a.Privacy != "strict" || a.Rotate != "monthly" {
+		t.Errorf("access log = %+v", a)
+	}
+	fields := map[string]bool{}
+	for _, w := range warnings {
+		fields[w.Field] = true
+	}
+	if fields["server.logs.access.format"] || !fields["server.logs.access.privacy"] || !fields["server.logs.access.rotate"] {
+		t.Errorf("warnings = %+v", warnings)
+	}
+
+	cfg.Server.Logs.Access.Format = "custom"
+	cfg.Server.Logs.Access.Custom = " "
+	cfg.ValidateAndApplyDefaults()
+	if cfg.Server.Logs.Access.Format != "apache" {
+		t.Errorf("custom without a format string = %q", cfg.Server.Logs.Access.Format)
+	}
+}
+
+func TestParseLogRotation(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   LogRotation
+		ok     bool
+	}{
+		{"monthly", LogRotation{Period: "monthly"}, true},
+		{"weekly,50MB", LogRotation{Period: "weekly", Size: 50 << 20}, true},
+		{" 1.5gb ", LogRotation{Size: 3 << 29}, true},
+		{"none", LogRotation{}, true},
+		{"", LogRotation{}, true},
+		{"daily,weekly", LogRotation{}, false},
+		{"hourly", LogRotation{}, false},
+		{"0MB", LogRotation{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogRotation(tt.policy)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ParseLogRotation(%q) = %+v, %v", tt.policy, got, err)
+		}
+	}
+}
+
+func TestValidateCookies(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Security.Cookies = CookiesConfig{
+		Session: CookieConfig{SameSite: "None", Domain: ".Example.com", Path: "/search", Secure: true},
+		CSRF:    CookieConfig{SameSite: "none", Domain: "example.com:8080", Path: "search"},
+	}
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	session := cfg.Server.Security.Cookies.Session
+	if session.SameSite != "none" || session.Domain != "example.com" || session.Path != "/search" {
+		t.Errorf("session cookie = %+v, want it kept", session)
+	}
+	if csrf := cfg.Server.Security.Cookies.CSRF; csrf != (CookieConfig{}) {
+		t.Errorf("csrf cookie = %+v, want the defaults", csrf)
+	}
+	var fields []string
+	for _, w := range warnings {
+		if strings.HasPrefix(w.Field, "server.security.cookies.") {
+			fields = append(fields, w.Field)
+		}
+	}
+	want := []string{"server.security.cookies.csrf.same_site", "server.security.cookies.csrf.domain", "server.security.cookies.csrf.path"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("warnings = %v, want %v", fields, want)
+	}
+}
+
+func TestCookieConfigApply(t *testing.T) {
+	cookie := &http.Cookie{Name: "c"}
+	CookieConfig{}.Apply(cookie, http.SameSiteStrictMode, false)
+	if cookie.Path != "/" || cookie.Domain != "" || cookie.SameSite != http.SameSiteStrictMode || cookie.Secure {
+		t.Errorf("default cookie = %+v", cookie)
+	}
+
+	CookieConfig{}.Apply(cookie, http.SameSiteLaxMode, true)
+	if cookie.SameSite != http.SameSiteLaxMode || !cookie.Secure {
+		t.Errorf("cookie over TLS = %+v", cookie)
+	}
+
+	CookieConfig{SameSite: "none", Domain: "example.com", Path: "/search", Secure: true}.Apply(cookie, http.SameSiteStrictMode, false)
+	if cookie.Path != "/search" || cookie.Domain != "example.com" || cookie.SameSite != http.SameSiteNoneMode || !cookie.Secure {
+		t.Errorf("configured cookie = %+v", cookie)
+	}
+}
+
+func TestValidateSafeSearch(t *testing.T) {
+	cfg := DefaultConfig()
+	if !cfg.Search.SafeSearchFilter.BuiltIn {
+		t.Error("the built-in safe search lists are off by default")
+	}
+	cfg.Search.SafeSearch = 5
+	cfg.Search.SafeSearchFilter.Domains = []string{" Example.COM ", ""}
+	cfg.Search.SafeSearchFilter.Keywords = []string{"  "}
+	warnings := cfg.ValidateAndApplyDefaults()
+
+	if cfg.Search.SafeSearch != 1 {
+		t.Errorf("safe_search = %d, want 1", cfg.Search.SafeSearch)
+	}
+	if f := cfg.Search.SafeSearchFilter; !slices.Equal(f.Domains, []string{"example.com"}) || len(f.Keywords) != 0 {
+		t.Errorf("safe_search_filter = %+v", f)
+	}
+	found := false
+	for _, w := range warnings {
+		found = found || w.Field == "search.safe_search"
+	}
+	if !found {
+		t.Error("no warning for safe_search 5")
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"", "", true},
+		{"/", "", true},
+		{"search", "/search", true},
+		{" /apps/search/ ", "/apps/search", true},
+		{"/a//b", "", false},
+		{"/../admin", "", false},
+		{"/search?x=1", "", false},
+		{"/sea rch", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeBasePath(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeBasePath(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateBasePath(t *testing.T) {
+	SetBaseURLOverride("")
+	t.Setenv("SEARCH_BASE_URL", "")
+
+	cfg := DefaultConfig()
+	cfg.Server.BasePath = "search/"
+	cfg.ValidateAndApplyDefaults()
+	if cfg.Server.BasePath != "/search" {
+		t.Errorf("base_path = %q, want /search", cfg.Server.BasePath)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Server.BasePath = "/a/../b"
+	warnings := cfg.ValidateAndApplyDefaults()
+	if cfg.Server.BasePath != "" {
+		t.Errorf("invalid base_path kept as %q", cfg.Server.BasePath)
+	}
+	found := false
+	for _, w := range warnings {
+		found = found || w.Field == "server.base_path"
+	}
+	if !found {
+		t.Error("no warning for an invalid base_path")
+	}
+
+	t.Setenv("SEARCH_BASE_URL", "https://example.com/tools/search/")
+	cfg = DefaultConfig()
+	cfg.Server.BasePath = "/other"
+	cfg.ValidateAndApplyDefaults()
+	if cfg.Server.BasePath != "/tools/search" {
+		t.Errorf("base_path with SEARCH_BASE_URL = %q, want /tools/search", cfg.Server.BasePath)
+	}
+}
+
+func TestServerConfigPublicURL(t *testing.T) {
+	s := ServerConfig{BasePath: "/search"}
+	if got := s.PublicURL(""); got != "" {
+		t.Errorf("PublicURL without base_url or origin = %q", got)
+	}
+	if got := s.PublicURL("http://localhost:8080"); got != "http://localhost:8080/search" {
+		t.Errorf("PublicURL(origin) = %q", got)
+	}
+	s.BaseURL = "https://example.com/"
+	if got := s.PublicURL("http://localhost:8080"); got != "https://example.com/search" {
+		t.Errorf("PublicURL with base_url = %q", got)
+	}
+	s.BaseURL = "https://example.com/search"
+	if got := s.PublicURL(""); got != "https://example.com/search" {
+		t.Errorf("PublicURL with base_url holding the path = %q", got)
+	}
+	if got := s.URLPath("/api/v1/search"); got != "/search/api/v1/search" {
+		t.Errorf("URLPath = %q", got)
+	}
+}
+
+func TestValidateHosts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.AllowedHosts = []string{"Example.COM:443", "*.example.com", "example.com.", "bad host", "10.0.0.5"}
+	cfg.Server.CanonicalHost.Host = "WWW.example.com"
+	cfg.Server.UnexpectedHost = "Redirect"
+	warnings := cfg.ValidateAndApplyDefaults()
+	if want := []string{"example.com", "*.example.com", "10.0.0.5"}; !slices.Equal(cfg.Server.AllowedHosts, want) {
+		t.Errorf("allowed_hosts = %v, want %v", cfg.Server.AllowedHosts, want)
+	}
+	if cfg.Server.CanonicalHost.Host != "www.example.com" || cfg.Server.UnexpectedHost != UnexpectedHostRedirect {
+		t.Errorf("canonical_host = %q, unexpected_host = %q", cfg.Server.CanonicalHost.Host, cfg.Server.UnexpectedHost)
+	}
+	found := false
+	for _, w := range warnings {
+		found = found || w.Field == "server.allowed_hosts"
+	}
+	if !found {
+		t.Error("no warning for an invalid allowed host")
+	}
+
+	// redirect has nowhere to go when every allowed host is a wildcard
+	cfg = DefaultConfig()
+	cfg.Server.AllowedHosts = []string{"*.example.com"}
+	cfg.Server.UnexpectedHost = "redirect"
+	cfg.ValidateAndApplyDefaults()
+	if cfg.Server.UnexpectedHost != UnexpectedHostReject {
+		t.Errorf("unexpected_host without a target = %q, want reject", cfg.Server.UnexpectedHost)
+	}
+}
+
+func TestServerConfigHostAllowed(t *testing.T) {
+	s := ServerConfig{}
+	if !s.HostAllowed("anything.example") {
+		t.Error("an empty allowed_hosts should allow any host")
+	}
+
+	s = ServerConfig{
+		AllowedHosts:  []string{"example.com", "*.example.org"},
+		BaseURL:       "https://search.example.net/",
+		CanonicalHost: CanonicalHostConfig{Host: "www.example.com"},
+	}
+	for host, want := range map[string]bool{
+		"example.com":        true,
+		"www.example.com":    true,
+		"search.example.net": true,
+		"a.example.org":      true,
+		"example.org":        false,
+		"evil.com":           false,
+		"notexample.com":     false,
+		"":                   false,
+	} {
+		if got := s.HostAllowed(host); got != want {
+			t.Errorf("HostAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+	if got := s.HostRedirectTarget(); got != "www.example.com" {
+		t.Errorf("HostRedirectTarget = %q", got)
+	}
+	s.CanonicalHost.Host = ""
+	if got := s.HostRedirectTarget(); got != "search.example.net" {
+		t.Errorf("HostRedirectTarget without canonical_host = %q", got)
+	}
+}
@@ -1114,6 +1114,62 @@ func TestSearchConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestMapsConfig(t *testing.T) {
+	var empty MapsConfig
+	if empty.NominatimURL() != DefaultNominatimURL || empty.TileURL() != DefaultMapTileURL ||
+		empty.TileAttribution() != DefaultMapAttribution || empty.LeafletURL() != DefaultLeafletURL {
+		t.Errorf("empty MapsConfig should use the defaults: %q %q %q %q",
+			empty.NominatimURL(), empty.TileURL(), empty.TileAttribution(), empty.LeafletURL())
+	}
+
+	custom := MapsConfig{
+		Nominatim:   " https://geo.example.com/nominatim/ ",
+		Tiles:       "http://tiles.lan/{z}/{x}/{y}.png",
+		Attribution: "Example tiles",
+		Leaflet:     "https://search.example.com/leaflet/",
+	}
+	if got := custom.NominatimURL(); got != "https://geo.example.com/nominatim" {
+		t.Errorf("NominatimURL() = %q", got)
+	}
+	if got := custom.TileURL(); got != "http://tiles.lan/{z}/{x}/{y}.png" {
+		t.Errorf("TileURL() = %q", got)
+	}
+	if got := custom.TileAttribution(); got != "Example tiles" {
+		t.Errorf("TileAttribution() = %q", got)
+	}
+	if got := custom.LeafletURL(); got != "https://search.example.com/leaflet" {
+		t.Errorf("LeafletURL() = %q", got)
+	}
+
+	subdomains := MapsConfig{Tiles: "https://{s}.tile.example.org/{z}/{x}/{y}.png"}
+	if got := subdomains.TileURL(); got != subdomains.Tiles {
+		t.Errorf("TileURL() with {s} = %q", got)
+	}
+
+	invalid := MapsConfig{Nominatim: "javascript:alert(1)", Leaflet: "/leaflet"}
+	if invalid.NominatimURL() != DefaultNominatimURL || invalid.LeafletURL() != DefaultLeafletURL {
+		t.Errorf("non-http URLs should fall back to the defaults: %q %q", invalid.NominatimURL(), invalid.LeafletURL())
+	}
+}
+
+func TestSearchConfigCategoryEnabled(t *testing.T) {
+	search := DefaultConfig().Search
+	if !search.CategoryEnabled("files") {
+		t.Error("files is disabled by default")
+	}
+
+	search.DisabledCategories = []string{" Files ", "general"}
+	if search.CategoryEnabled("files") {
+		t.Error("files is listed in disabled_categories but enabled")
+	}
+	if !search.CategoryEnabled("general") {
+		t.Error("general cannot be disabled")
+	}
+	if !search.CategoryEnabled("news") {
+		t.Error("news is not listed but disabled")
+	}
+}
+
 func TestSSLConfigDefaults(t *testing.T) {
 	cfg := DefaultConfig()
 
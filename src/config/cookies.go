@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CookiesConfig sets the attributes of the cookies a deployment may need
+// to adjust: behind a proxy that serves the site under a path, on a parent
+// domain shared with other hosts, or embedded in an iframe on another site.
+type CookiesConfig struct {
+	// Session cookies: the stored preferences profile and the view-as
+	// session
+	Session CookieConfig `yaml:"session"`
+	// The CSRF token cookie
+	CSRF CookieConfig `yaml:"csrf"`
+}
+
+// CookieConfig is the attributes of one kind of cookie. Empty fields keep
+// the cookie's own defaults.
+type CookieConfig struct {
+	// SameSite is strict, lax or none. none sends the cookie from pages
+	// that embed this site on another site and needs secure: true
+	// (default: "", lax for the preferences cookie, strict for view-as and
+	// CSRF)
+	SameSite string `yaml:"same_site"`
+	// Domain shares the cookie with the domain's subdomains, such as
+	// "example.com" for search.example.com (default: "", this host only)
+	Domain string `yaml:"domain"`
+	// Path limits the cookie to URLs under it, a path from the instance
+	// root that server.base_path goes in front of (default: "/")
+	Path string `yaml:"path"`
+	// Secure sends the cookie over HTTPS only even when TLS ends at a
+	// proxy; requests that arrive over TLS get secure cookies anyway
+	// (default: false)
+	Secure bool `yaml:"secure"`
+}
+
+// Apply sets the configured attributes on cookie. sameSite is the
+// cookie's own default, used when same_site is empty, and tls whether the
+// request arrived over TLS.
+func (c CookieConfig) Apply(cookie *http.Cookie, sameSite http.SameSite, tls bool) {
+	cookie.Path = "/"
+	if c.Path != "" {
+		cookie.Path = c.Path
+	}
+	cookie.Domain = c.Domain
+	switch c.SameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+	cookie.SameSite = sameSite
+	// Browsers drop SameSite=None cookies that are not secure
+	cookie.Secure = c.Secure || tls || sameSite == http.SameSiteNoneMode
+}
+
+// RequestTLS reports whether cookies set in answer to r count as sent over
+// TLS: server.ssl is on, or r itself arrived over TLS. Every cookie the
+// server sets decides Secure this way.
+func (s *ServerConfig) RequestTLS(r *http.Request) bool {
+	return s.SSL.Enabled || r.TLS != nil
+}
+
+// validateCookies resets cookie attributes a browser would reject, and
+// SameSite=None without secure: true. Called with c.mu held.
+func (c *Config) validateCookies() []ValidationWarning {
+	devMode := c.Server.Mode == "development" || c.Server.Mode == "dev"
+	var warnings []ValidationWarning
+	for _, entry := range []struct {
+		name   string
+		cookie *CookieConfig
+	}{
+		{"session", &c.Server.Security.Cookies.Session},
+		{"csrf", &c.Server.Security.Cookies.CSRF},
+	} {
+		field := "server.security.cookies." + entry.name
+		cookie := entry.cookie
+		cookie.SameSite = strings.ToLower(strings.TrimSpace(cookie.SameSite))
+		switch cookie.SameSite {
+		case "", "strict", "lax":
+		case "none":
+			if !cookie.Secure {
+				warnings = append(warnings, ValidationWarning{
+					Field:   field + ".same_site",
+					Message: "same_site none needs secure: true, using the default",
+				})
+				cookie.SameSite = ""
+			}
+		default:
+			warnings = append(warnings, ValidationWarning{
+				Field:   field + ".same_site",
+				Message: fmt.Sprintf("'%s' is not strict, lax or none, using the default", cookie.SameSite),
+			})
+			cookie.SameSite = ""
+		}
+
+		cookie.Domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(cookie.Domain), "."))
+		if cookie.Domain != "" && (strings.Contains(cookie.Domain, ":") || !IsValidHost(cookie.Domain, devMode, "")) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field + ".domain",
+				Message: fmt.Sprintf("'%s' is not a domain name, using this host only", cookie.Domain),
+			})
+			cookie.Domain = ""
+		}
+
+		cookie.Path = strings.TrimSpace(cookie.Path)
+		if cookie.Path != "" && (!strings.HasPrefix(cookie.Path, "/") || strings.ContainsAny(cookie.Path, ";, \t\r\n")) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   field + ".path",
+				Message: fmt.Sprintf("'%s' is not a path starting with /, using /", cookie.Path),
+				Default: "/",
+			})
+			cookie.Path = ""
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Answers to a request for a host that is not in server.allowed_hosts
+const (
+	// UnexpectedHostReject answers 421 Misdirected Request
+	UnexpectedHostReject = "reject"
+	// UnexpectedHostRedirect sends a 301 to the canonical host
+	UnexpectedHostRedirect = "redirect"
+)
+
+// CanonicalHostConfig sends requests to one host and scheme, so links,
+// bookmarks and search engines settle on a single URL for each page
+type CanonicalHostConfig struct {
+	// Host is the host every other allowed host is sent to with a 301,
+	// such as "example.com" to send www.example.com there, or the
+	// reverse (default: "", each host is served as it is)
+	Host string `yaml:"host"`
+	// HTTPS sends plain-HTTP requests to https:// with a 301; behind a
+	// proxy that ends TLS it needs server.trusted_proxies to see the
+	// proxy's X-Forwarded-Proto (default: false)
+	HTTPS bool `yaml:"https"`
+}
+
+// NormalizeHost returns a Host header or host name lowercased, without its
+// port, brackets or trailing dot
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), ".")
+	return host
+}
+
+// HostAllowed reports whether host, a normalized host name, is one the
+// instance answers. Any host is allowed when server.allowed_hosts is
+// empty; otherwise the canonical host and the host of server.base_url
+// are allowed too. A "*.example.com" entry allows the subdomains of
+// example.com, not example.com itself. The configured hosts are
+// normalized as they are compared, so a reloaded server.yml works as it
+// is.
+func (s *ServerConfig) HostAllowed(host string) bool {
+	if len(s.AllowedHosts) == 0 {
+		return true
+	}
+	if host == "" {
+		return false
+	}
+	if host == NormalizeHost(s.CanonicalHost.Host) || host == s.baseURLHost() {
+		return true
+	}
+	for _, allowed := range s.AllowedHosts {
+		allowed = NormalizeHost(allowed)
+		if domain, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// HostRedirectTarget returns the host a request for an unexpected host is
+// sent to: the canonical host, else the host of server.base_url, else the
+// first allowed host that is not a wildcard. It is "" when there is none.
+func (s *ServerConfig) HostRedirectTarget() string {
+	if host := NormalizeHost(s.CanonicalHost.Host); host != "" {
+		return host
+	}
+	if host := s.baseURLHost(); host != "" {
+		return host
+	}
+	for _, allowed := range s.AllowedHosts {
+		if allowed = NormalizeHost(allowed); allowed != "" && !strings.HasPrefix(allowed, "*") {
+			return allowed
+		}
+	}
+	return ""
+}
+
+// baseURLHost returns the normalized host of server.base_url, or ""
+func (s *ServerConfig) baseURLHost() string {
+	u, err := url.Parse(strings.TrimSpace(s.BaseURL))
+	if err != nil {
+		return ""
+	}
+	return NormalizeHost(u.Host)
+}
+
+// validHostPattern reports whether host, normalized, is a host name or an
+// IP address; wildcard allows a leading "*." as well
+func validHostPattern(host string, wildcard bool) bool {
+	if wildcard {
+		host = strings.TrimPrefix(host, "*.")
+	}
+	if host == "" {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateHosts normalizes server.allowed_hosts, server.unexpected_host
+// and server.canonical_host, dropping entries that are not host names.
+// Called with c.mu held.
+func (c *Config) validateHosts() []ValidationWarning {
+	var warnings []ValidationWarning
+	allowed := make([]string, 0, len(c.Server.AllowedHosts))
+	seen := make(map[string]bool, len(c.Server.AllowedHosts))
+	for _, entry := range c.Server.AllowedHosts {
+		host := NormalizeHost(entry)
+		if !validHostPattern(host, true) {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "server.allowed_hosts",
+				Message: fmt.Sprintf("'%s' is not a host name, IP address or *.domain, ignoring it", entry),
+			})
+			continue
+		}
+		if !seen[host] {
+			seen[host] = true
+			allowed = append(allowed, host)
+		}
+	}
+	c.Server.AllowedHosts = allowed
+
+	canonical := NormalizeHost(c.Server.CanonicalHost.Host)
+	if canonical != "" && !validHostPattern(canonical, false) {
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.canonical_host.host",
+			Message: fmt.Sprintf("'%s' is not a host name or IP address, not redirecting to it", c.Server.CanonicalHost.Host),
+		})
+		canonical = ""
+	}
+	c.Server.CanonicalHost.Host = canonical
+
+	unexpected := strings.ToLower(strings.TrimSpace(c.Server.UnexpectedHost))
+	switch unexpected {
+	case "":
+		unexpected = UnexpectedHostReject
+	case UnexpectedHostReject:
+	case UnexpectedHostRedirect:
+		if len(c.Server.AllowedHosts) > 0 && c.Server.HostRedirectTarget() == "" {
+			warnings = append(warnings, ValidationWarning{
+				Field:   "server.unexpected_host",
+				Message: "redirect needs server.canonical_host.host, server.base_url or an allowed host without a wildcard, rejecting instead",
+				Default: UnexpectedHostReject,
+			})
+			unexpected = UnexpectedHostReject
+		}
+	default:
+		warnings = append(warnings, ValidationWarning{
+			Field:   "server.unexpected_host",
+			Message: fmt.Sprintf("'%s' is not reject or redirect, rejecting", c.Server.UnexpectedHost),
+			Default: UnexpectedHostReject,
+		})
+		unexpected = UnexpectedHostReject
+	}
+	c.Server.UnexpectedHost = unexpected
+	return warnings
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preset is a named bundle of settings that belong together, applied by
+// "search --init --preset" or the operator API. A preset only touches the
+// settings it is about; everything else in server.yml is kept.
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	apply       func(*Config)
+}
+
+// SettingChange is one server.yml setting a preset changes, with the key in
+// dotted form and both values as they appear in YAML
+type SettingChange struct {
+	Key  string `json:"key"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// devEngines serve code, packages and advisories
+var devEngines = []string{"stackoverflow", "github", "cve", "npm", "pypi", "crates", "gomodules", "dockerhub"}
+
+var presets = []Preset{
+	{
+		Name:        "privacy-max",
+		Description: "Only engines that do not build search profiles, proxied bang redirects, no analytics and error-only logs",
+		apply: func(c *Config) {
+			setEnginesEnabled(c, false, "google", "bing", "yahoo", "reddit")
+			c.Search.Bangs.ProxyRequests = true
+			c.Search.Market.Enabled = false
+			c.Search.Suggestions.Providers = []SuggestionProviderConfig{{Name: "duckduckgo", Weight: 1}}
+			disableTracking(c)
+			c.Server.Logs.Level = "error"
+			c.Server.Logs.Access.Privacy = "strict"
+			c.Server.Tor.Disabled = false
+			c.Server.Tor.SafeLogging = true
+		},
+	},
+	{
+		Name:        "family-safe",
+		Description: "Strict safe search that visitors cannot turn off, without social engines",
+		apply: func(c *Config) {
+			c.Search.SafeSearch = 2
+			c.Search.SafeSearchLocked = true
+			setEnginesEnabled(c, false, "reddit")
+		},
+	},
+	{
+		Name:        "developer",
+		Description: "Code, package and advisory engines enabled and weighted like general engines",
+		apply: func(c *Config) {
+			setEnginesEnabled(c, true, devEngines...)
+			for _, name := range devEngines {
+				if engine, ok := c.Engines[name]; ok {
+					engine.Weight = 1.0
+					c.Engines[name] = engine
+				}
+			}
+		},
+	},
+	{
+		Name:        "intranet",
+		Description: "No Tor or analytics, no per-IP rate limits behind a shared NAT, direct bang redirects and metrics on",
+		apply: func(c *Config) {
+			c.Server.Tor.Disabled = true
+			disableTracking(c)
+			c.Server.RateLimit.Enabled = false
+			c.Server.Metrics.Enabled = true
+			c.Server.Logs.Level = "info"
+			c.Search.Bangs.ProxyRequests = false
+		},
+	},
+}
+
+// Presets returns every preset, in the order they are documented
+func Presets() []Preset {
+	return append([]Preset(nil), presets...)
+}
+
+// LookupPreset returns the preset called name
+func LookupPreset(name string) (Preset, error) {
+	names := make([]string, 0, len(presets))
+	for _, p := range presets {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return Preset{}, fmt.Errorf("unknown preset '%s' (choose from %s)", name, strings.Join(names, ", "))
+}
+
+// Apply changes c to the preset's settings. The caller saves c.
+func (p Preset) Apply(c *Config) {
+	p.apply(c)
+}
+
+// Preview returns the settings Apply would change in c, sorted by key,
+// without changing c. An empty list means c already matches the preset.
+func (p Preset) Preview(c *Config) ([]SettingChange, error) {
+	c.mu.RLock()
+	data, err := yaml.Marshal(c)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	before, err := settingValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied Config
+	if err := yaml.Unmarshal(data, &applied); err != nil {
+		return nil, err
+	}
+	p.apply(&applied)
+	data, err = yaml.Marshal(&applied)
+	if err != nil {
+		return nil, err
+	}
+	after, err := settingValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []SettingChange{}
+	for key, to := range after {
+		if from, ok := before[key]; !ok || from != to {
+			changes = append(changes, SettingChange{Key: key, From: before[key], To: to})
+		}
+	}
+	for key, from := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, SettingChange{Key: key, From: from})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// setEnginesEnabled turns the named engines on or off, skipping any that
+// are not configured
+func setEnginesEnabled(c *Config, enabled bool, names ...string) {
+	for _, name := range names {
+		if engine, ok := c.Engines[name]; ok {
+			engine.Enabled = enabled
+			c.Engines[name] = engine
+		}
+	}
+}
+
+// disableTracking turns analytics off, leaving an empty type as it is
+func disableTracking(c *Config) {
+	if c.Server.Tracking.Type != "" {
+		c.Server.Tracking.Type = "none"
+	}
+}
+
+// settingValues flattens a marshalled config to dotted server.yml keys and
+// their values. Lists are kept whole so a changed list is one change.
+func settingValues(data []byte) (map[string]string, error) {
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	flattenSettings("", tree, values)
+	return values, nil
+}
+
+func flattenSettings(prefix string, tree map[string]any, values map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if sub, ok := value.(map[string]any); ok && len(sub) > 0 {
+			flattenSettings(key, sub, values)
+			continue
+		}
+		values[key] = formatSetting(value)
+	}
+}
+
+// formatSetting renders a value the way it reads in server.yml
+func formatSetting(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any, map[string]any:
+		// One line: flow style for lists and maps
+		var node yaml.Node
+		if err := node.Encode(v); err != nil {
+			return fmt.Sprint(v)
+		}
+		setFlowStyle(&node)
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return strings.TrimSpace(string(data))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func setFlowStyle(node *yaml.Node) {
+	node.Style |= yaml.FlowStyle
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
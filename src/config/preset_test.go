@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestPresetPreviewMatchesApply(t *testing.T) {
+	for _, preset := range Presets() {
+		cfg := DefaultConfig()
+		changes, err := preset.Preview(cfg)
+		if err != nil {
+			t.Fatalf("%s: Preview: %v", preset.Name, err)
+		}
+		if len(changes) == 0 {
+			t.Errorf("%s changes nothing in the default config", preset.Name)
+		}
+		for _, change := range changes {
+			if change.From == change.To {
+				t.Errorf("%s: %s listed without a change", preset.Name, change.Key)
+			}
+		}
+
+		// Preview leaves the config alone; applying makes it match
+		again, _ := preset.Preview(cfg)
+		if len(again) != len(changes) {
+			t.Errorf("%s: Preview changed the config", preset.Name)
+		}
+		preset.Apply(cfg)
+		if left, _ := preset.Preview(cfg); len(left) != 0 {
+			t.Errorf("%s: %d changes left after Apply: %+v", preset.Name, len(left), left)
+		}
+	}
+}
+
+func TestPresetFamilySafe(t *testing.T) {
+	preset, err := LookupPreset(" Family-Safe ")
+	if err != nil {
+		t.Fatalf("LookupPreset: %v", err)
+	}
+	cfg := DefaultConfig()
+	changes, _ := preset.Preview(cfg)
+	want := map[string]SettingChange{
+		"engines.reddit.enabled":    {From: "true", To: "false"},
+		"search.safe_search":        {From: "1", To: "2"},
+		"search.safe_search_locked": {From: "false", To: "true"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d", changes, len(want))
+	}
+	for _, change := range changes {
+		if w := want[change.Key]; change.From != w.From || change.To != w.To {
+			t.Errorf("%s: %q -> %q, want %q -> %q", change.Key, change.From, change.To, w.From, w.To)
+		}
+	}
+
+	preset.Apply(cfg)
+	if got := cfg.Search.ResolveSafeSearch(0); got != 2 {
+		t.Errorf("locked safe search resolved 0 to %d, want 2", got)
+	}
+	if got := DefaultConfig().Search.ResolveSafeSearch(0); got != 0 {
+		t.Errorf("unlocked safe search resolved 0 to %d", got)
+	}
+
+	if _, err := LookupPreset("nope"); err == nil {
+		t.Error("LookupPreset accepted an unknown name")
+	}
+}
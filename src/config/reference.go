@@ -0,0 +1,279 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReferenceField is one server.yml setting in the config reference
+type ReferenceField struct {
+	// Key is the dotted YAML path; map keys appear as <name> and list
+	// items as []
+	Key string `json:"key"`
+	// Type is string, bool, int, float, duration, list or map
+	Type string `json:"type"`
+	// Default is the value DefaultConfig sets, empty for none, or
+	// "(generated)" for values such as secrets created per instance
+	Default string `json:"default"`
+	// Env is the environment variable that sets the value, if any
+	Env string `json:"env,omitempty"`
+	// EnvFirstRun is set when Env is only read on first run, when
+	// server.yml is created
+	EnvFirstRun bool `json:"env_first_run,omitempty"`
+	// Description is the field's desc tag, or else its doc comment
+	Description string `json:"description,omitempty"`
+}
+
+// configSource is parsed for the doc comments of the config fields, so the
+// reference describes each setting as the code does
+//
+//go:embed config.go
+var configSource string
+
+// Reference lists every server.yml setting with its type, default,
+// environment variable and description, read from the Config struct's
+// tags and doc comments, so it cannot fall out of step with the code.
+//
+// Struct tags used besides yaml: `desc:"..."` describes a field in place of
+// its doc comment, and `env:"NAME"` names the environment variable that
+// sets it, `env:"NAME,first-run"` one read only when server.yml is created.
+func Reference() []ReferenceField {
+	var fields []ReferenceField
+	// Values that differ between two defaults are generated per instance
+	walkReference(reflect.ValueOf(DefaultConfig()).Elem(), reflect.ValueOf(DefaultConfig()).Elem(), "", "Config", &fields)
+	return fields
+}
+
+// walkReference adds the settings under the struct v, whose path is prefix
+// and whose type is named typeName in config.go. other is the same struct
+// from another DefaultConfig.
+func walkReference(v, other reflect.Value, prefix, typeName string, fields *[]ReferenceField) {
+	docs := fieldDocs()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		// Anonymous structs are named by the field holding them
+		child := func(t reflect.Type) string {
+			if t.Name() != "" {
+				return t.Name()
+			}
+			return typeName + "." + f.Name
+		}
+		fv := v.Field(i)
+		switch {
+		case fv.Kind() == reflect.Struct:
+			walkReference(fv, other.Field(i), key, child(fv.Type()), fields)
+		case fv.Kind() == reflect.Map && fv.Type().Elem().Kind() == reflect.Struct:
+			zero := reflect.New(fv.Type().Elem()).Elem()
+			walkReference(zero, zero, key+".<name>", child(fv.Type().Elem()), fields)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct:
+			zero := reflect.New(fv.Type().Elem()).Elem()
+			walkReference(zero, zero, key+"[]", child(fv.Type().Elem()), fields)
+		default:
+			field := ReferenceField{Key: key, Type: referenceType(fv.Type()), Default: referenceValue(fv)}
+			if field.Default != referenceValue(other.Field(i)) {
+				field.Default = "(generated)"
+			}
+			if field.Type == "string" && isDuration(field.Default) {
+				// Durations are written as strings such as "250ms"
+				field.Type = "duration"
+			}
+			if env, ok := f.Tag.Lookup("env"); ok {
+				field.Env, _, _ = strings.Cut(env, ",")
+				field.EnvFirstRun = strings.HasSuffix(env, ",first-run")
+			}
+			field.Description = f.Tag.Get("desc")
+			if field.Description == "" {
+				field.Description = docs[typeName+"."+f.Name]
+			}
+			*fields = append(*fields, field)
+		}
+	}
+}
+
+// isDuration reports whether s is a duration with a unit
+func isDuration(s string) bool {
+	if _, err := time.ParseDuration(s); err != nil {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err != nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// referenceType names t the way server.yml spells it
+func referenceType(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "map"
+	}
+	return "string"
+}
+
+// referenceValue formats a default as it would be written in server.yml
+func referenceValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return ""
+		}
+	default:
+		if v.IsZero() {
+			return ""
+		}
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		return fmt.Sprintf("%d entries", v.Len())
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+var (
+	docsOnce sync.Once
+	docs     map[string]string
+	// specNote matches the references to the project specification that
+	// many comments start with
+	specNote = regexp.MustCompile(`^(Per|See) ((AI|IDEA)\.md( PART [\d/]+)?|PART [\d/]+)[^:,.]*[:,.]?\s*`)
+)
+
+// fieldDocs returns the doc comments of the struct fields in config.go,
+// keyed by type and field name ("SMTPConfig.Host"); the fields of anonymous
+// structs by the path of type and field names to them
+func fieldDocs() map[string]string {
+	docsOnce.Do(func() {
+		docs = make(map[string]string)
+		file, err := parser.ParseFile(token.NewFileSet(), "config.go", configSource, parser.ParseComments)
+		if err != nil {
+			return
+		}
+		var walk func(name string, st *ast.StructType)
+		walk = func(name string, st *ast.StructType) {
+			for _, field := range st.Fields.List {
+				text := docText(field.Doc, field.Comment)
+				for _, ident := range field.Names {
+					docs[name+"."+ident.Name] = text
+					// Anonymous structs, directly or as map and list items
+					typ := field.Type
+					for {
+						switch t := typ.(type) {
+						case *ast.MapType:
+							typ = t.Value
+							continue
+						case *ast.ArrayType:
+							typ = t.Elt
+							continue
+						case *ast.StructType:
+							walk(name+"."+ident.Name, t)
+						}
+						break
+					}
+				}
+			}
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						walk(ts.Name.Name, st)
+					}
+				}
+			}
+		}
+	})
+	return docs
+}
+
+// docText joins a field's comments into one line, leaving out references
+// to the project specification
+func docText(groups ...*ast.CommentGroup) string {
+	var parts []string
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, line := range strings.Split(g.Text(), "\n") {
+			line = strings.TrimSpace(specNote.ReplaceAllString(strings.TrimSpace(line), ""))
+			if line != "" {
+				parts = append(parts, line)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// WriteReferenceMarkdown writes the config reference as markdown, one table
+// per top level section of server.yml
+func WriteReferenceMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Config Reference\n\n")
+	b.WriteString("Every `server.yml` setting with its type, default and description, generated from the server's own configuration structs. A key part written `<name>` is a map key you choose; `[]` is an item of a list. An environment variable marked *first run* is only read when `server.yml` is created. See [Configuration](configuration.md) for how the settings work together.\n")
+	section := ""
+	for _, f := range Reference() {
+		top, _, _ := strings.Cut(f.Key, ".")
+		if top != section {
+			section = top
+			b.WriteString("\n## " + top + "\n\n| Setting | Type | Default | Environment | Description |\n|---------|------|---------|-------------|-------------|\n")
+		}
+		def := ""
+		if f.Default != "" {
+			def = "`" + strings.ReplaceAll(f.Default, "`", "'") + "`"
+		}
+		env := ""
+		if f.Env != "" {
+			env = "`" + f.Env + "`"
+			if f.EnvFirstRun {
+				env += " (*first run*)"
+			}
+		}
+		desc := strings.ReplaceAll(f.Description, "|", `\|`)
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", f.Key, f.Type, def, env, desc)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrWizardAborted is returned when the input ends before the wizard does
+var ErrWizardAborted = errors.New("setup wizard aborted")
+
+// Wizard asks the first-run questions of "search --init" on a terminal and
+// applies the answers to a config. Every question shows the current value
+// as the default, so pressing Enter throughout keeps the generated config.
+type Wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewWizard returns a wizard reading answers from in and prompting on out
+func NewWizard(in io.Reader, out io.Writer) *Wizard {
+	return &Wizard{in: bufio.NewReader(in), out: out}
+}
+
+// Run asks every question and updates c. The caller saves c.
+func (w *Wizard) Run(c *Config) error {
+	fmt.Fprintln(w.out, "Press Enter to accept the value in [brackets].")
+	fmt.Fprintln(w.out)
+
+	port, err := w.askPort(c.Server.Port)
+	if err != nil {
+		return err
+	}
+	mode, err := w.askChoice("Mode", c.Server.Mode, "production", "development")
+	if err != nil {
+		return err
+	}
+	email, err := w.askEmail("Admin email (server notices, never published)", c.Server.Contact.Admin.Email)
+	if err != nil {
+		return err
+	}
+	tor, err := w.askBool("Publish a Tor hidden service when tor is installed?", !c.Server.Tor.Disabled)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Search categories:")
+	categories := engineCategories(c.Engines)
+	enabled := make(map[string]bool, len(categories))
+	for _, cat := range categories {
+		on, err := w.askBool(fmt.Sprintf("  Enable %s (%s)?", cat.name, strings.Join(cat.engines, ", ")), cat.enabled)
+		if err != nil {
+			return err
+		}
+		enabled[cat.name] = on
+	}
+
+	fmt.Fprintln(w.out)
+	metrics, err := w.askBool("Enable the Prometheus metrics endpoint? It only serves local monitoring; nothing is sent anywhere", c.Server.Metrics.Enabled)
+	if err != nil {
+		return err
+	}
+
+	c.Server.Port = port
+	c.Server.Mode = mode
+	c.Server.Contact.Admin.Email = email
+	c.Server.Tor.Disabled = !tor
+	c.Server.Metrics.Enabled = metrics
+	for name, engine := range c.Engines {
+		var kept []string
+		for _, cat := range engine.Categories {
+			if on, asked := enabled[cat]; !asked || on {
+				kept = append(kept, cat)
+			}
+		}
+		if len(kept) == 0 {
+			engine.Enabled = false
+		} else {
+			engine.Categories = kept
+		}
+		c.Engines[name] = engine
+	}
+	return nil
+}
+
+// wizardCategory is a search category and the engines serving it
+type wizardCategory struct {
+	name    string
+	engines []string
+	enabled bool
+}
+
+// engineCategories groups the engines by category, both sorted by name.
+// A category starts enabled when any enabled engine serves it.
+func engineCategories(engines map[string]EngineConfig) []wizardCategory {
+	byName := make(map[string]*wizardCategory)
+	for name, engine := range engines {
+		for _, cat := range engine.Categories {
+			wc, ok := byName[cat]
+			if !ok {
+				wc = &wizardCategory{name: cat}
+				byName[cat] = wc
+			}
+			wc.engines = append(wc.engines, name)
+			wc.enabled = wc.enabled || engine.Enabled
+		}
+	}
+	categories := make([]wizardCategory, 0, len(byName))
+	for _, wc := range byName {
+		sort.Strings(wc.engines)
+		categories = append(categories, *wc)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].name < categories[j].name })
+	return categories
+}
+
+// ask prompts once and returns the trimmed answer, or def for an empty one
+func (w *Wizard) ask(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(w.out)
+		return "", ErrWizardAborted
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+func (w *Wizard) askPort(def int) (int, error) {
+	for {
+		answer, err := w.ask("Port", strconv.Itoa(def))
+		if err != nil {
+			return 0, err
+		}
+		port, err := strconv.Atoi(answer)
+		if err == nil && port >= 1 && port <= 65535 {
+			return port, nil
+		}
+		fmt.Fprintln(w.out, "  Enter a port between 1 and 65535.")
+	}
+}
+
+func (w *Wizard) askChoice(prompt, def string, choices ...string) (string, error) {
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", prompt, strings.Join(choices, "/")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, choice := range choices {
+			if strings.EqualFold(answer, choice) {
+				return choice, nil
+			}
+		}
+		fmt.Fprintf(w.out, "  Choose one of: %s.\n", strings.Join(choices, ", "))
+	}
+}
+
+func (w *Wizard) askEmail(prompt, def string) (string, error) {
+	for {
+		answer, err := w.ask(prompt+" (- for none)", def)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" || answer == "-" {
+			return "", nil
+		}
+		if IsValidEmail(answer) {
+			return answer, nil
+		}
+		fmt.Fprintln(w.out, "  Enter an email address like admin@example.com.")
+	}
+}
+
+func (w *Wizard) askBool(prompt string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := w.ask(prompt+" ("+hint+")", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(w.out, "  Answer y or n.")
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWizardDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Port = 64123
+	want := DefaultConfig()
+
+	// Enter on every question keeps the generated config
+	categories := len(engineCategories(cfg.Engines))
+	input := strings.Repeat("\n", 5+categories)
+	if err := NewWizard(strings.NewReader(input), &bytes.Buffer{}).Run(cfg); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if cfg.Server.Port != 64123 || cfg.Server.Mode != want.Server.Mode || cfg.Server.Tor.Disabled {
+		t.Errorf("defaults changed: port %d mode %q tor disabled %v", cfg.Server.Port, cfg.Server.Mode, cfg.Server.Tor.Disabled)
+	}
+	for name, engine := range cfg.Engines {
+		if engine.Enabled != want.Engines[name].Enabled || len(engine.Categories) != len(want.Engines[name].Categories) {
+			t.Errorf("engine %s changed: %+v", name, engine)
+		}
+	}
+}
+
+func TestWizardAnswers(t *testing.T) {
+	cfg := DefaultConfig()
+	var answers []string
+	// Port (one invalid try), mode, email (one invalid try), Tor
+	answers = append(answers, "99999", "8080", "dev", "development", "nope", "ops@example.com", "n")
+	// Keep only general search
+	for _, cat := range engineCategories(cfg.Engines) {
+		if cat.name == "general" {
+			answers = append(answers, "y")
+		} else {
+			answers = append(answers, "n")
+		}
+	}
+	// Metrics
+	answers = append(answers, "yes")
+
+	var out bytes.Buffer
+	if err := NewWizard(strings.NewReader(strings.Join(answers, "\n")+"\n"), &out).Run(cfg); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.Mode != "development" || cfg.Server.Contact.Admin.Email != "ops@example.com" {
+		t.Errorf("answers not applied: port %d mode %q email %q", cfg.Server.Port, cfg.Server.Mode, cfg.Server.Contact.Admin.Email)
+	}
+	if !cfg.Server.Tor.Disabled || !cfg.Server.Metrics.Enabled {
+		t.Errorf("tor disabled %v metrics %v, want true true", cfg.Server.Tor.Disabled, cfg.Server.Metrics.Enabled)
+	}
+	if !strings.Contains(out.String(), "Enter a port between 1 and 65535") {
+		t.Error("invalid port was not re-asked")
+	}
+
+	google := cfg.Engines["google"]
+	if !google.Enabled || strings.Join(google.Categories, ",") != "general" {
+		t.Errorf("google = %+v, want enabled for general only", google)
+	}
+	if cfg.Engines["npm"].Enabled {
+		t.Error("npm serves only packages and should be disabled")
+	}
+}
+
+func TestWizardAborted(t *testing.T) {
+	cfg := DefaultConfig()
+	port := cfg.Server.Port
+	err := NewWizard(strings.NewReader("8080\n"), &bytes.Buffer{}).Run(cfg)
+	if !errors.Is(err, ErrWizardAborted) {
+		t.Fatalf("Run on short input = %v, want ErrWizardAborted", err)
+	}
+	if cfg.Server.Port != port {
+		t.Error("an aborted wizard changed the config")
+	}
+}
@@ -405,8 +405,15 @@ func TestInitSchema_CreatesAllTables(t *testing.T) {
 		"engine_stats",
 		"blocked_ips",
 		"custom_bangs",
+		"bang_usage",
 		"search_alerts",
 		"search_alert_results",
+		"search_permalinks",
+		"collections",
+		"collection_items",
+		"result_reports",
+		"result_rules",
+		"trash",
 	}
 	for _, table := range expectedTables {
 		t.Run("table_"+table, func(t *testing.T) {
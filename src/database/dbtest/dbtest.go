@@ -0,0 +1,39 @@
+// Package dbtest opens in-memory SQLite databases with the schema the
+// database package migrates, so tests of the stores run against the same
+// tables as a server instead of copies of their DDL.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/apimgr/search/src/database"
+)
+
+// Server returns an in-memory server database with every server table,
+// unprefixed. It is closed when the test ends.
+func Server(t testing.TB) *sql.DB {
+	t.Helper()
+	return open(t, database.InitServerSchema)
+}
+
+// Users returns an in-memory users database with every users table,
+// unprefixed. It is closed when the test ends.
+func Users(t testing.TB) *sql.DB {
+	t.Helper()
+	return open(t, database.InitUsersSchema)
+}
+
+func open(t testing.TB, initSchema func(context.Context, *database.DB) error) *sql.DB {
+	t.Helper()
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DSN: ":memory:"})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := initSchema(context.Background(), db); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	return db.SQL()
+}
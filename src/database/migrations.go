@@ -10,10 +10,10 @@ import (
 // All statements use CREATE TABLE IF NOT EXISTS and ALTER TABLE ADD COLUMN IF NOT EXISTS.
 // Safe to call on every startup — never drops data.
 func InitSchema(ctx context.Context, dm *DatabaseManager) error {
-	if err := initServerSchema(ctx, dm.ServerDB()); err != nil {
+	if err := InitServerSchema(ctx, dm.ServerDB()); err != nil {
 		return fmt.Errorf("server database schema init failed: %w", err)
 	}
-	if err := initUsersSchema(ctx, dm.UsersDB()); err != nil {
+	if err := InitUsersSchema(ctx, dm.UsersDB()); err != nil {
 		return fmt.Errorf("users database schema init failed: %w", err)
 	}
 	return nil
@@ -28,9 +28,9 @@ func serverTablePrefix(db *DB) string {
 	return ""
 }
 
-// initServerSchema creates all tables for server.db.
+// InitServerSchema creates all tables for server.db.
 // Per AI.md PART 10: Server tables use srv_ prefix when using libSQL/Turso remote database.
-func initServerSchema(ctx context.Context, db *DB) error {
+func InitServerSchema(ctx context.Context, db *DB) error {
 	prefix := serverTablePrefix(db)
 
 	// applyPrefix replaces {prefix} placeholders with the actual prefix
@@ -130,6 +130,24 @@ func initServerSchema(ctx context.Context, db *DB) error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS {prefix}idx_api_tokens_hash ON {prefix}api_tokens(token_hash)`,
 		`CREATE INDEX IF NOT EXISTS {prefix}idx_api_tokens_prefix ON {prefix}api_tokens(token_prefix)`,
+		// Temporary boosts and freezes of named operator tokens
+		`CREATE TABLE IF NOT EXISTS {prefix}api_token_limits (
+			token_id INTEGER PRIMARY KEY,
+			boost INTEGER NOT NULL DEFAULT 0,
+			boost_until DATETIME,
+			frozen INTEGER NOT NULL DEFAULT 0,
+			frozen_until DATETIME
+		)`,
+		// Versions of the server secrets: fingerprints only, the secrets
+		// stay in server.yml
+		`CREATE TABLE IF NOT EXISTS {prefix}server_key_versions (
+			name TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			fingerprint TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (name, version)
+		)`,
 		// Search statistics
 		`CREATE TABLE IF NOT EXISTS {prefix}search_stats (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -166,7 +184,7 @@ func initServerSchema(ctx context.Context, db *DB) error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE INDEX IF NOT EXISTS {prefix}idx_blocked_ips_ip ON {prefix}blocked_ips(ip_address)`,
-		// Custom bangs
+		// The operator's custom bangs; aliases is a JSON array
 		`CREATE TABLE IF NOT EXISTS {prefix}custom_bangs (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			shortcut TEXT UNIQUE NOT NULL,
@@ -175,9 +193,19 @@ func initServerSchema(ctx context.Context, db *DB) error {
 			category TEXT DEFAULT 'custom',
 			description TEXT,
 			active INTEGER DEFAULT 1,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			icon TEXT NOT NULL DEFAULT '',
+			aliases TEXT NOT NULL DEFAULT '[]',
+			updated_at DATETIME
 		)`,
 		`CREATE INDEX IF NOT EXISTS {prefix}idx_custom_bangs_shortcut ON {prefix}custom_bangs(shortcut)`,
+		// Feature flag overrides set through the operator API
+		`CREATE TABLE IF NOT EXISTS {prefix}feature_flags (
+			name TEXT PRIMARY KEY,
+			enabled INTEGER NOT NULL DEFAULT 0,
+			percent INTEGER NOT NULL DEFAULT 100,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
 		// Search alerts
 		`CREATE TABLE IF NOT EXISTS {prefix}search_alerts (
 			id TEXT PRIMARY KEY,
@@ -232,6 +260,95 @@ func initServerSchema(ctx context.Context, db *DB) error {
 			UNIQUE(alert_id, fingerprint)
 		)`,
 		`CREATE INDEX IF NOT EXISTS {prefix}idx_search_alert_results_alert ON {prefix}search_alert_results(alert_id, first_seen_at)`,
+		// Search permalinks: the search state is encrypted with the link token,
+		// which is stored only as a hash
+		`CREATE TABLE IF NOT EXISTS {prefix}search_permalinks (
+			id TEXT PRIMARY KEY,
+			state_encrypted TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}idx_search_permalinks_expires ON {prefix}search_permalinks(expires_at)`,
+		// Saved-result collections, owned by a manage token and optionally
+		// shared read-only; both tokens are stored as hashes
+		`CREATE TABLE IF NOT EXISTS {prefix}collections (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			manage_token_hash TEXT NOT NULL,
+			share_token_hash TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS {prefix}idx_collections_manage_token ON {prefix}collections(manage_token_hash)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS {prefix}idx_collections_share_token ON {prefix}collections(share_token_hash)`,
+		`CREATE TABLE IF NOT EXISTS {prefix}collection_items (
+			id TEXT PRIMARY KEY,
+			collection_id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			title TEXT NOT NULL,
+			content TEXT NOT NULL DEFAULT '',
+			engine TEXT NOT NULL DEFAULT '',
+			note TEXT NOT NULL DEFAULT '',
+			position INTEGER NOT NULL,
+			added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (collection_id) REFERENCES {prefix}collections(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}idx_collection_items_collection ON {prefix}collection_items(collection_id, position)`,
+		// Result reports from the public report form; repeat reports of the
+		// same URL and reason bump the count of the open report
+		`CREATE TABLE IF NOT EXISTS {prefix}result_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			domain TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			comment TEXT NOT NULL DEFAULT '',
+			report_count INTEGER NOT NULL DEFAULT 1,
+			status TEXT NOT NULL DEFAULT 'open',
+			resolution TEXT NOT NULL DEFAULT '',
+			note TEXT NOT NULL DEFAULT '',
+			first_reported_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_reported_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			resolved_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}idx_result_reports_status ON {prefix}result_reports(status, report_count)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}idx_result_reports_url ON {prefix}result_reports(url, reason)`,
+		// Result blocklist rules: a domain (and its subdomains) or an exact URL
+		// hidden from every search
+		`CREATE TABLE IF NOT EXISTS {prefix}result_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(kind, pattern)
+		)`,
+		// Requests counted against engine quotas, per engine, day and hour
+		`CREATE TABLE IF NOT EXISTS {prefix}engine_quota_usage (
+			engine TEXT NOT NULL,
+			day TEXT NOT NULL,
+			hour INTEGER NOT NULL,
+			requests INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (engine, day, hour)
+		)`,
+		// Deleted operator resources (result rules, custom bangs), kept as
+		// JSON for the trash retention so they can be restored
+		`CREATE TABLE IF NOT EXISTS {prefix}trash (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			item_key TEXT NOT NULL,
+			data TEXT NOT NULL,
+			deleted_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}idx_trash_kind ON {prefix}trash(kind, item_key)`,
+		// Uses of each bang, built-in or custom: a count and the last time,
+		// never the query
+		`CREATE TABLE IF NOT EXISTS {prefix}bang_usage (
+			shortcut TEXT PRIMARY KEY,
+			uses INTEGER NOT NULL DEFAULT 0,
+			last_used_at DATETIME
+		)`,
 		// Security reports — coordinated disclosure pipeline per AI.md PART 11.
 		// Plaintext report content is never persisted: sensitive fields (steps to
 		// reproduce, impact, researcher contact, etc.) live only inside encrypted_body.
@@ -274,17 +391,67 @@ func initServerSchema(ctx context.Context, db *DB) error {
 			return fmt.Errorf("schema statement failed: %w\nSQL: %s", err, sql)
 		}
 	}
+
+	// Columns added to tables that shipped without them. SQLite has no ADD
+	// COLUMN IF NOT EXISTS, so the error once a column exists is ignored.
+	columns := []string{
+		`ALTER TABLE {prefix}custom_bangs ADD COLUMN icon TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}custom_bangs ADD COLUMN aliases TEXT NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE {prefix}custom_bangs ADD COLUMN updated_at DATETIME`,
+	}
+	for _, stmt := range columns {
+		db.Exec(ctx, applyPrefix(stmt))
+	}
 	return nil
 }
 
-// initUsersSchema creates all tables for user.db.
-// This project has no user account system; user.db is provisioned empty
-// so the DatabaseManager API remains consistent across projects.
-func initUsersSchema(ctx context.Context, db *DB) error {
-	_ = db
+// usersTablePrefix returns the table prefix for users tables: "usr_" on
+// libsql/Turso, where they may share a database with the server tables,
+// and none on local sqlite.
+func usersTablePrefix(db *DB) string {
+	if db.driver == "libsql" {
+		return "usr_"
+	}
+	return ""
+}
+
+// InitUsersSchema creates all tables for user.db.
+// There are no user logins; user.db holds the search preferences profiles,
+// each owned by whoever holds its token.
+func InitUsersSchema(ctx context.Context, db *DB) error {
+	if db == nil {
+		return nil
+	}
+	prefix := usersTablePrefix(db)
+	statements := []string{
+		// Search preferences stored server-side. name is set for accounts
+		// an operator created and NULL for anonymous profiles; the token is
+		// stored only as a hash.
+		`CREATE TABLE IF NOT EXISTS {prefix}preference_profiles (
+			id TEXT PRIMARY KEY,
+			name TEXT,
+			token_hash TEXT NOT NULL,
+			preferences TEXT NOT NULL DEFAULT '{}',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS {prefix}idx_preference_profiles_token ON {prefix}preference_profiles(token_hash)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS {prefix}idx_preference_profiles_name ON {prefix}preference_profiles(name)`,
+	}
+	for _, stmt := range statements {
+		sql := strings.ReplaceAll(stmt, "{prefix}", prefix)
+		if _, err := db.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("schema statement failed: %w\nSQL: %s", err, sql)
+		}
+	}
 	return nil
 }
 
+// UsersTableName returns the prefixed table name for users database queries.
+func UsersTableName(db *DB, table string) string {
+	return usersTablePrefix(db) + table
+}
+
 // ServerTableName returns the prefixed table name for server database queries.
 // Use this helper when building queries to ensure correct prefix is applied.
 func ServerTableName(db *DB, table string) string {
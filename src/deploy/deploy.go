@@ -0,0 +1,171 @@
+// Package deploy generates container deployment files: the Dockerfile
+// behind "search --build docker" and the compose file written by
+// "search --init docker-compose". Both follow the container layout the
+// binary detects at startup (config under /config, data under /data,
+// listening on port 80), so neither needs hand editing.
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// Image is the published image name
+const Image = "ghcr.io/apimgr/search"
+
+// BaseImage is the distroless runtime base: CA certificates and tzdata, no
+// shell or package manager, running as an unprivileged user
+const BaseImage = "gcr.io/distroless/static-debian12:nonroot"
+
+// ContainerPort is the port the server listens on inside the container
+const ContainerPort = 80
+
+// DefaultHostPort is the host port the compose file publishes
+const DefaultHostPort = 64580
+
+// nonrootUID is the distroless nonroot user
+const nonrootUID = 65532
+
+// Container paths, matching the container defaults in src/config
+const (
+	ConfigVolume = "/config"
+	DataVolume   = "/data"
+	BinaryPath   = "/usr/local/bin/search"
+)
+
+// ImageLabels are the OCI labels of a built image
+type ImageLabels struct {
+	Version  string
+	Revision string
+	// Created is RFC 3339, the build's source date
+	Created string
+}
+
+// Dockerfile returns a Dockerfile that packages a prebuilt static binary,
+// named search in the build context, on the distroless base. The context
+// must also hold empty config and data directories, copied in so named
+// volumes start out owned by the nonroot user.
+func Dockerfile(labels ImageLabels) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by search --build docker\n")
+	fmt.Fprintf(&b, "FROM %s\n\n", BaseImage)
+	fmt.Fprintf(&b, "LABEL org.opencontainers.image.title=\"search\" \\\n")
+	fmt.Fprintf(&b, "      org.opencontainers.image.description=\"Privacy-respecting self-hosted metasearch engine\" \\\n")
+	fmt.Fprintf(&b, "      org.opencontainers.image.source=\"https://github.com/apimgr/search\" \\\n")
+	fmt.Fprintf(&b, "      org.opencontainers.image.licenses=\"MIT\" \\\n")
+	fmt.Fprintf(&b, "      org.opencontainers.image.version=%q \\\n", labels.Version)
+	fmt.Fprintf(&b, "      org.opencontainers.image.revision=%q \\\n", labels.Revision)
+	fmt.Fprintf(&b, "      org.opencontainers.image.created=%q\n\n", labels.Created)
+	fmt.Fprintf(&b, "COPY --chown=%d:%d config %s\n", nonrootUID, nonrootUID, ConfigVolume)
+	fmt.Fprintf(&b, "COPY --chown=%d:%d data %s\n", nonrootUID, nonrootUID, DataVolume)
+	fmt.Fprintf(&b, "COPY --chmod=755 search %s\n\n", BinaryPath)
+	for _, kv := range imageEnv() {
+		fmt.Fprintf(&b, "ENV %s=%s\n", kv[0], kv[1])
+	}
+	fmt.Fprintf(&b, "\nVOLUME [\"%s\", \"%s\"]\n", ConfigVolume, DataVolume)
+	fmt.Fprintf(&b, "EXPOSE %d\n", ContainerPort)
+	fmt.Fprintf(&b, "USER %d:%d\n\n", nonrootUID, nonrootUID)
+	fmt.Fprintf(&b, "HEALTHCHECK --start-period=90s --interval=30s --timeout=5s --retries=3 \\\n")
+	fmt.Fprintf(&b, "    CMD [\"%s\", \"--status\"]\n\n", BinaryPath)
+	fmt.Fprintf(&b, "ENTRYPOINT [\"%s\"]\n", BinaryPath)
+	fmt.Fprintf(&b, "CMD [\"--address\", \"0.0.0.0\", \"--port\", \"%d\"]\n", ContainerPort)
+	return b.String()
+}
+
+// imageEnv is the environment baked into the image, in order
+func imageEnv() [][2]string {
+	return [][2]string{
+		{"MODE", "production"},
+		{"CONFIG_DIR", ConfigVolume + "/" + config.ProjectName},
+		{"DATA_DIR", DataVolume + "/" + config.ProjectName},
+	}
+}
+
+// ComposeOptions tune the generated compose file
+type ComposeOptions struct {
+	// Tag is the image tag; empty means latest
+	Tag string
+	// HostPort is the published host port; 0 means DefaultHostPort
+	HostPort int
+	// BindAddress limits the published port to one host address, e.g.
+	// 127.0.0.1 behind a reverse proxy; empty publishes on every address
+	BindAddress string
+}
+
+// Compose returns a compose file running the image with named volumes, a
+// healthcheck and the environment the binary reads
+func Compose(opts ComposeOptions) ([]byte, error) {
+	tag := opts.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	hostPort := opts.HostPort
+	if hostPort == 0 {
+		hostPort = DefaultHostPort
+	}
+	port := fmt.Sprintf("%d:%d", hostPort, ContainerPort)
+	if opts.BindAddress != "" {
+		port = opts.BindAddress + ":" + port
+	}
+
+	env := []string{
+		"MODE=production",
+		fmt.Sprintf("PORT=%d", ContainerPort),
+		"DEBUG=false",
+		"TZ=${TZ:-America/New_York}",
+	}
+	for _, kv := range imageEnv()[1:] {
+		env = append(env, kv[0]+"="+kv[1])
+	}
+
+	doc := map[string]any{
+		"name": "search",
+		"services": map[string]any{
+			"search": map[string]any{
+				"image":          Image + ":" + tag,
+				"container_name": "search",
+				"restart":        "unless-stopped",
+				"environment":    env,
+				// Quoted: YAML 1.1 reads small "a:b" pairs as base-60 numbers
+				"ports": []*yaml.Node{{Kind: yaml.ScalarNode, Style: yaml.DoubleQuotedStyle, Value: port}},
+				"volumes": []string{
+					"search-config:" + ConfigVolume,
+					"search-data:" + DataVolume,
+				},
+				"healthcheck": map[string]any{
+					"test":         []string{"CMD", BinaryPath, "--status"},
+					"interval":     "30s",
+					"timeout":      "5s",
+					"retries":      3,
+					"start_period": "90s",
+				},
+				"logging": map[string]any{
+					"driver":  "json-file",
+					"options": map[string]string{"max-size": "5m", "max-file": "1"},
+				},
+			},
+		},
+		"volumes": map[string]any{
+			"search-config": map[string]any{},
+			"search-data":   map[string]any{},
+		},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by search --init docker-compose\n")
+	buf.WriteString("# Start with: docker compose up -d\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDockerfile(t *testing.T) {
+	df := Dockerfile(ImageLabels{Version: "1.2.3", Revision: "abc123", Created: "2026-01-02T03:04:05Z"})
+	for _, want := range []string{
+		"FROM " + BaseImage,
+		`org.opencontainers.image.version="1.2.3"`,
+		`org.opencontainers.image.revision="abc123"`,
+		"COPY --chown=65532:65532 config /config",
+		"ENV CONFIG_DIR=/config/search",
+		"ENV DATA_DIR=/data/search",
+		"USER 65532:65532",
+		`CMD ["/usr/local/bin/search", "--status"]`,
+		`ENTRYPOINT ["/usr/local/bin/search"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Errorf("Dockerfile missing %q:\n%s", want, df)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	data, err := Compose(ComposeOptions{BindAddress: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Compose: %v", err)
+	}
+	var doc struct {
+		Services map[string]struct {
+			Image       string   `yaml:"image"`
+			Environment []string `yaml:"environment"`
+			Ports       []string `yaml:"ports"`
+			Volumes     []string `yaml:"volumes"`
+			Healthcheck struct {
+				Test []string `yaml:"test"`
+			} `yaml:"healthcheck"`
+		} `yaml:"services"`
+		Volumes map[string]any `yaml:"volumes"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("compose file is not valid YAML: %v", err)
+	}
+	svc, ok := doc.Services["search"]
+	if !ok {
+		t.Fatal("compose file has no search service")
+	}
+	if svc.Image != Image+":latest" {
+		t.Errorf("image = %q, want %s:latest", svc.Image, Image)
+	}
+	if len(svc.Ports) != 1 || svc.Ports[0] != "127.0.0.1:64580:80" {
+		t.Errorf("ports = %v, want [127.0.0.1:64580:80]", svc.Ports)
+	}
+	if len(svc.Volumes) != 2 || len(doc.Volumes) != 2 {
+		t.Errorf("volumes = %v / %v, want config and data named volumes", svc.Volumes, doc.Volumes)
+	}
+	if strings.Join(svc.Healthcheck.Test, " ") != "CMD /usr/local/bin/search --status" {
+		t.Errorf("healthcheck = %v", svc.Healthcheck.Test)
+	}
+	env := strings.Join(svc.Environment, "\n")
+	for _, want := range []string{"PORT=80", "MODE=production", "DATA_DIR=/data/search"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("environment missing %s: %v", want, svc.Environment)
+		}
+	}
+
+	data, _ = Compose(ComposeOptions{Tag: "1.2.3", HostPort: 8080})
+	if !strings.Contains(string(data), Image+":1.2.3") || !strings.Contains(string(data), "8080:80") {
+		t.Errorf("compose ignores Tag and HostPort:\n%s", data)
+	}
+}
@@ -0,0 +1,207 @@
+// Package docs serves the user and operator documentation from the binary,
+// so air-gapped deployments have the same documentation as the website.
+//
+// pages/ holds copies of the markdown under the repository's docs/, which
+// MkDocs builds the website from; refresh them with go generate after
+// editing docs/. A test fails while they differ. The config reference page
+// is generated from the config structs instead.
+package docs
+
+//go:generate sh -c "rm -f pages/*.md && cp ../../docs/*.md pages/"
+
+import (
+	"embed"
+	"html/template"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// Path is where the documentation is served
+const Path = "/server/docs"
+
+// ConfigReference is the slug of the generated config reference page
+const ConfigReference = "config-reference"
+
+//go:embed pages/*.md
+var files embed.FS
+
+// order is the pages in the order of mkdocs.yml's nav, then the config
+// reference
+var order = []string{
+	"index", "installation", "configuration", "search-syntax", "api",
+	"cli", "integrations", "security", "development", ConfigReference,
+}
+
+// Page is one documentation page
+type Page struct {
+	Slug     string        `json:"slug"`
+	Title    string        `json:"title"`
+	Markdown string        `json:"markdown,omitempty"`
+	HTML     template.HTML `json:"html,omitempty"`
+	Sections []Section     `json:"sections,omitempty"`
+	// intro is the plain text before the first heading, for search
+	intro string
+}
+
+// Section is a heading on a page
+type Section struct {
+	Title  string `json:"title"`
+	Anchor string `json:"anchor"`
+	Level  int    `json:"level"`
+	// text is the section's plain text, for search
+	text string
+}
+
+// Hit is a section matching a search
+type Hit struct {
+	Page      string `json:"page"`
+	PageTitle string `json:"page_title"`
+	Section   string `json:"section"`
+	// URL is the page and section's path on this server
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+	score   int
+}
+
+var (
+	loadOnce sync.Once
+	pages    []Page
+)
+
+// load renders every page once
+func load() {
+	loadOnce.Do(func() {
+		for _, slug := range order {
+			md := configReference()
+			if slug != ConfigReference {
+				b, err := files.ReadFile("pages/" + slug + ".md")
+				if err != nil {
+					continue
+				}
+				md = string(b)
+			}
+			html, sections := render(md)
+			title := slug
+			if len(sections) > 1 && sections[1].Level == 1 {
+				title = sections[1].Title
+			}
+			pages = append(pages, Page{Slug: slug, Title: title, Markdown: md, HTML: html, Sections: sections[1:], intro: sections[0].text})
+		}
+	})
+}
+
+// Pages lists the pages, in reading order, without their content
+func Pages() []Page {
+	load()
+	list := make([]Page, len(pages))
+	for i, p := range pages {
+		list[i] = Page{Slug: p.Slug, Title: p.Title}
+	}
+	return list
+}
+
+// Get returns the page with slug
+func Get(slug string) (Page, bool) {
+	load()
+	for _, p := range pages {
+		if p.Slug == slug {
+			return p, true
+		}
+	}
+	return Page{}, false
+}
+
+// URL returns the path of a page's section on this server
+func URL(slug, anchor string) string {
+	u := Path
+	if slug != "index" {
+		u += "/" + slug
+	}
+	if anchor != "" {
+		u += "#" + anchor
+	}
+	return u
+}
+
+// Search returns up to limit sections containing every word of query, the
+// best first. A word in a section's heading counts more than one in its
+// text.
+func Search(query string, limit int) []Hit {
+	load()
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return []Hit{}
+	}
+	hits := []Hit{}
+	for _, p := range pages {
+		sections := p.Sections
+		if strings.TrimSpace(p.intro) != "" {
+			// The text before the first heading counts as the page's
+			sections = append([]Section{{Title: p.Title, text: p.intro}}, sections...)
+		}
+		for _, sec := range sections {
+			title, text := strings.ToLower(sec.Title), strings.ToLower(sec.text)
+			score := 0
+			for _, w := range words {
+				n := strings.Count(text, w) + 5*strings.Count(title, w)
+				if n == 0 {
+					score = 0
+					break
+				}
+				score += n
+			}
+			if score == 0 {
+				continue
+			}
+			hits = append(hits, Hit{
+				Page:      p.Slug,
+				PageTitle: p.Title,
+				Section:   sec.Title,
+				URL:       URL(p.Slug, sec.Anchor),
+				Snippet:   snippet(sec.text, words[0]),
+				score:     score,
+			})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// snippetLength is about how much text a search hit shows
+const snippetLength = 200
+
+// snippet returns the text around the first occurrence of word
+func snippet(text, word string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	start := strings.Index(strings.ToLower(text), word) - snippetLength/4
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	end := start + snippetLength
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	// Cut on rune boundaries
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	return prefix + strings.TrimSpace(text[start:end]) + suffix
+}
+
+// configReference returns the config reference page
+func configReference() string {
+	var b strings.Builder
+	_ = config.WriteReferenceMarkdown(&b)
+	return b.String()
+}
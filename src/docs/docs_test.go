@@ -0,0 +1,93 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPagesMatchDocs fails while the embedded pages differ from docs/, which
+// go generate fixes
+func TestPagesMatchDocs(t *testing.T) {
+	sources, err := filepath.Glob("../../docs/*.md")
+	if err != nil || len(sources) == 0 {
+		t.Skip("docs/ not found")
+	}
+	embedded, _ := filepath.Glob("pages/*.md")
+	if len(embedded) != len(sources) {
+		t.Errorf("%d pages embedded, docs/ has %d; run go generate ./src/docs", len(embedded), len(sources))
+	}
+	for _, src := range sources {
+		want, _ := os.ReadFile(src)
+		got, err := files.ReadFile("pages/" + filepath.Base(src))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("pages/%s is out of date; run go generate ./src/docs", filepath.Base(src))
+		}
+		slug := strings.TrimSuffix(filepath.Base(src), ".md")
+		if _, ok := Get(slug); !ok {
+			t.Errorf("docs/%s is not served; add it to order", filepath.Base(src))
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	md := "# Title\n\nSee [the API](api.md#caching-and-revalidation) and [home](index.md).\n\n" +
+		"## Query `q` & More\n\n| Name | Example |\n|---|---|\n| `OR` | `a | b` |\n\n" +
+		"- one\n  - nested\n- two\n\n=== \"Docker\"\n\n    ```bash\n    docker run <image>\n    ```\n\n<script>alert(1)</script>\n"
+	html, sections := render(md)
+	for _, want := range []string{
+		`<h1 id="title">Title</h1>`,
+		`<a href="/server/docs/api#caching-and-revalidation">the API</a>`,
+		`<a href="/server/docs">home</a>`,
+		`<h2 id="query-q-more">Query <code>q</code> &amp; More</h2>`,
+		`<td><code>a | b</code></td>`,
+		"<li>one\n<ul>\n<li>nested</li>\n</ul>\n</li>\n<li>two</li>",
+		`<p class="docs-tab"><strong>Docker</strong></p>`,
+		"<pre><code class=\"language-bash\">docker run &lt;image&gt;</code></pre>",
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("rendered HTML lacks %q:\n%s", want, html)
+		}
+	}
+	if len(sections) != 3 || sections[2].Anchor != "query-q-more" || !strings.Contains(sections[2].text, "a | b") {
+		t.Errorf("sections = %+v", sections)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	hits := Search("crawl delay", 5)
+	if len(hits) == 0 {
+		t.Fatal("no hits for crawl delay")
+	}
+	if hits[0].URL != "/server/docs/configuration#politeness" || hits[0].PageTitle != "Configuration" {
+		t.Errorf("first hit = %+v", hits[0])
+	}
+	for _, h := range hits {
+		if !strings.Contains(strings.ToLower(h.Snippet), "crawl") {
+			t.Errorf("snippet %q lacks the word", h.Snippet)
+		}
+	}
+	if hits := Search("", 5); len(hits) != 0 {
+		t.Errorf("empty query found %d hits", len(hits))
+	}
+	if hits := Search("no-such-word-anywhere", 5); len(hits) != 0 {
+		t.Errorf("unknown word found %v", hits)
+	}
+}
+
+func TestConfigReference(t *testing.T) {
+	page, ok := Get(ConfigReference)
+	if !ok {
+		t.Fatal("no config reference page")
+	}
+	for _, want := range []string{"<code>server.port</code>", "<code>search.politeness.max_wait</code>", "(generated)"} {
+		if !strings.Contains(string(page.HTML), want) {
+			t.Errorf("config reference lacks %q", want)
+		}
+	}
+	if Pages()[0].Slug != "index" || Pages()[0].HTML != "" {
+		t.Errorf("Pages() = %+v", Pages()[0])
+	}
+}
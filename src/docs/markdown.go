@@ -0,0 +1,322 @@
+package docs
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	listLine    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	tabLine     = regexp.MustCompile(`^===\s+"(.*)"\s*$`)
+	tableRule   = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// render converts the markdown the documentation is written in to HTML:
+// headings with the anchors MkDocs gives them, paragraphs, nested lists,
+// tables, fenced code, rules and the content tabs of pymdownx.tabbed, which
+// become a bold label over their content. Everything else is escaped, so
+// HTML in the pages shows as text. It also returns the page's sections.
+func render(md string) (template.HTML, []Section) {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	r := &renderer{anchors: map[string]int{}, sections: []Section{{}}}
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "":
+			r.flush()
+			if r.inList() && !continuesList(lines[i+1:]) {
+				r.closeLists(-1)
+			}
+		case strings.HasPrefix(line, "```"):
+			r.flush()
+			indent := len(raw) - len(strings.TrimLeft(raw, " "))
+			var code []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "```"; i++ {
+				code = append(code, dedent(lines[i], indent))
+			}
+			r.text(strings.Join(code, "\n"))
+			lang := strings.TrimSpace(strings.TrimPrefix(line, "```"))
+			class := ""
+			if lang != "" {
+				class = ` class="language-` + html.EscapeString(lang) + `"`
+			}
+			fmt.Fprintf(&r.b, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(strings.Join(code, "\n")))
+		case tabLine.MatchString(line):
+			r.flush()
+			r.closeLists(-1)
+			fmt.Fprintf(&r.b, "<p class=\"docs-tab\"><strong>%s</strong></p>\n", html.EscapeString(tabLine.FindStringSubmatch(line)[1]))
+			// The tab's content is indented four spaces
+			for j := i + 1; j < len(lines) && (strings.TrimSpace(lines[j]) == "" || strings.HasPrefix(lines[j], "    ")); j++ {
+				lines[j] = dedent(lines[j], 4)
+			}
+		case headingLine.MatchString(line):
+			r.flush()
+			r.closeLists(-1)
+			m := headingLine.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+		case line == "---" || line == "***" || line == "___":
+			r.flush()
+			r.closeLists(-1)
+			r.b.WriteString("<hr>\n")
+		case strings.HasPrefix(line, "|") && i+1 < len(lines) && tableRule.MatchString(strings.TrimSpace(lines[i+1])):
+			r.flush()
+			r.closeLists(-1)
+			var rows []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			r.table(rows)
+		case listLine.MatchString(raw):
+			r.flush()
+			m := listLine.FindStringSubmatch(raw)
+			tag := "ol"
+			if strings.ContainsAny(m[2], "-*+") {
+				tag = "ul"
+			}
+			r.item(len(m[1]), tag, m[3])
+		default:
+			r.paragraph = append(r.paragraph, line)
+		}
+	}
+	r.flush()
+	r.closeLists(-1)
+	return template.HTML(r.b.String()), r.sections
+}
+
+// continuesList reports whether the lines after a blank line carry on the
+// list before it
+func continuesList(rest []string) bool {
+	for _, line := range rest {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		return listLine.MatchString(line) || strings.HasPrefix(line, "  ")
+	}
+	return false
+}
+
+// dedent removes up to n leading spaces from line
+func dedent(line string, n int) string {
+	for i := 0; i < n && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
+
+// listLevel is an open list and its indentation
+type listLevel struct {
+	tag    string
+	indent int
+}
+
+type renderer struct {
+	b         strings.Builder
+	paragraph []string
+	lists     []listLevel
+	anchors   map[string]int
+	sections  []Section
+}
+
+func (r *renderer) inList() bool { return len(r.lists) > 0 }
+
+// flush writes the pending paragraph, into the open list item if any
+func (r *renderer) flush() {
+	if len(r.paragraph) == 0 {
+		return
+	}
+	text := strings.Join(r.paragraph, " ")
+	r.paragraph = nil
+	r.text(text)
+	if r.inList() {
+		r.b.WriteString(" " + inline(text))
+		return
+	}
+	r.b.WriteString("<p>" + inline(text) + "</p>\n")
+}
+
+// closeLists closes the lists indented deeper than indent; -1 closes all
+func (r *renderer) closeLists(indent int) {
+	for len(r.lists) > 0 && r.lists[len(r.lists)-1].indent > indent {
+		r.b.WriteString("</li>\n</" + r.lists[len(r.lists)-1].tag + ">\n")
+		r.lists = r.lists[:len(r.lists)-1]
+	}
+}
+
+// item writes a list item, opening, nesting or closing lists for its
+// indentation
+func (r *renderer) item(indent int, tag, text string) {
+	r.closeLists(indent)
+	top := len(r.lists) - 1
+	switch {
+	case top >= 0 && r.lists[top].indent == indent && r.lists[top].tag == tag:
+		r.b.WriteString("</li>\n")
+	case top >= 0 && r.lists[top].indent == indent:
+		r.b.WriteString("</li>\n</" + r.lists[top].tag + ">\n<" + tag + ">\n")
+		r.lists[top].tag = tag
+	default:
+		r.b.WriteString("\n<" + tag + ">\n")
+		r.lists = append(r.lists, listLevel{tag: tag, indent: indent})
+	}
+	r.text(text)
+	r.b.WriteString("<li>" + inline(text))
+}
+
+// heading writes a heading with its anchor and starts a section
+func (r *renderer) heading(level int, title string) {
+	anchor := slug(title)
+	if n := r.anchors[anchor]; n > 0 {
+		r.anchors[anchor] = n + 1
+		anchor = fmt.Sprintf("%s_%d", anchor, n)
+	} else {
+		r.anchors[anchor] = 1
+	}
+	plain := plainText(title)
+	r.sections = append(r.sections, Section{Title: plain, Anchor: anchor, Level: level})
+	fmt.Fprintf(&r.b, "<h%d id=\"%s\">%s</h%d>\n", level, anchor, inline(title), level)
+}
+
+// table writes a table from its rows, the second being the rule
+func (r *renderer) table(rows []string) {
+	r.b.WriteString("<table>\n")
+	for i, row := range rows {
+		if i == 1 {
+			continue
+		}
+		cell := "td"
+		if i == 0 {
+			r.b.WriteString("<thead>\n")
+			cell = "th"
+		}
+		r.b.WriteString("<tr>")
+		for _, c := range tableCells(row) {
+			r.text(c)
+			fmt.Fprintf(&r.b, "<%s>%s</%s>", cell, inline(c), cell)
+		}
+		r.b.WriteString("</tr>\n")
+		if i == 0 {
+			r.b.WriteString("</thead>\n<tbody>\n")
+		}
+	}
+	r.b.WriteString("</tbody>\n</table>\n")
+}
+
+// text adds plain text to the current section, for search
+func (r *renderer) text(s string) {
+	sec := &r.sections[len(r.sections)-1]
+	sec.text += plainText(s) + "\n"
+}
+
+// tableCells splits a table row on the pipes outside code spans
+func tableCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(row); i++ {
+		switch c := row[i]; {
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// slug makes a heading's anchor the way MkDocs' toc extension does: lower
+// case, punctuation dropped and runs of spaces and hyphens made one hyphen
+func slug(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(plainText(title)) {
+		switch {
+		case r == ' ' || r == '-':
+			hyphen = b.Len() > 0
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r > 127:
+			if hyphen {
+				b.WriteByte('-')
+				hyphen = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var (
+	codeSpan = regexp.MustCompile("`([^`]+)`")
+	linkSpan = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldSpan = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	emSpan   = regexp.MustCompile(`(^|[\s(])\*([^*\s][^*]*)\*`)
+	markup   = strings.NewReplacer("`", "", "**", "")
+)
+
+// plainText strips inline markup, keeping link text
+func plainText(s string) string {
+	return markup.Replace(linkSpan.ReplaceAllString(s, "$1"))
+}
+
+// inline escapes text and renders code, links, bold and italics. Code spans
+// are set aside first so their content stays literal.
+func inline(text string) string {
+	var codes []string
+	text = codeSpan.ReplaceAllStringFunc(text, func(m string) string {
+		codes = append(codes, "<code>"+html.EscapeString(m[1:len(m)-1])+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+
+	text = html.EscapeString(text)
+	text = linkSpan.ReplaceAllStringFunc(text, func(m string) string {
+		parts := linkSpan.FindStringSubmatch(m)
+		href, ok := link(html.UnescapeString(parts[2]))
+		if !ok {
+			return parts[1]
+		}
+		return `<a href="` + html.EscapeString(href) + `">` + parts[1] + `</a>`
+	})
+	text = boldSpan.ReplaceAllString(text, "<strong>$1</strong>")
+	text = emSpan.ReplaceAllString(text, "$1<em>$2</em>")
+
+	for i, code := range codes {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), code, 1)
+	}
+	return text
+}
+
+// link returns where a link in the documentation points on this server.
+// Links to other pages (api.md#caching) become /server/docs/api#caching;
+// web, mail, absolute and anchor links are kept and others dropped.
+func link(href string) (string, bool) {
+	lower := strings.ToLower(href)
+	for _, prefix := range []string{"https://", "http://", "mailto:", "/", "#"} {
+		if strings.HasPrefix(lower, prefix) {
+			return href, !strings.HasPrefix(lower, "//")
+		}
+	}
+	page, anchor, _ := strings.Cut(href, "#")
+	page, ok := strings.CutSuffix(page, ".md")
+	if !ok || strings.ContainsAny(page, "/\\:") {
+		return "", false
+	}
+	href = Path
+	if page != "index" {
+		href += "/" + page
+	}
+	if anchor != "" {
+		href += "#" + anchor
+	}
+	return href, true
+}
@@ -0,0 +1,220 @@
+// Package feature holds dark-launch flags. A flag is declared in server.yml
+// under server.features, may be overridden at runtime through the operator
+// API (overrides live in the server database and survive restarts), and
+// can be rolled out to a percentage of browsers.
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database"
+)
+
+// Flag sources
+const (
+	SourceConfig   = "config"
+	SourceOverride = "override"
+)
+
+var (
+	// ErrUnknownFlag is returned for a flag server.yml does not declare
+	ErrUnknownFlag = errors.New("unknown feature flag")
+	// ErrPercent is returned for a rollout percentage outside 0-100
+	ErrPercent = errors.New("percent must be between 0 and 100")
+)
+
+// Flag is a flag's effective state
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Enabled     bool   `json:"enabled"`
+	// Share of browsers that see the feature while Enabled (0-100)
+	Percent int    `json:"percent"`
+	Source  string `json:"source"`
+	// The server.yml state, shown while an override is active
+	Default *config.FeatureConfig `json:"default,omitempty"`
+}
+
+type override struct {
+	enabled bool
+	percent int
+}
+
+// Set evaluates flags. It is safe for concurrent use.
+type Set struct {
+	mu        sync.RWMutex
+	defaults  map[string]config.FeatureConfig
+	overrides map[string]override
+	db        *database.DB
+}
+
+// NewSet returns flags declared by defaults. db stores overrides; it may be
+// nil, in which case overrides last until restart.
+func NewSet(defaults map[string]config.FeatureConfig, db *database.DB) *Set {
+	s := &Set{overrides: make(map[string]override), db: db}
+	s.SetDefaults(defaults)
+	return s
+}
+
+// SetDefaults replaces the declared flags, for a server.yml reload.
+// Overrides of flags no longer declared are kept but ignored.
+func (s *Set) SetDefaults(defaults map[string]config.FeatureConfig) {
+	copied := make(map[string]config.FeatureConfig, len(defaults))
+	for name, flag := range defaults {
+		copied[name] = flag
+	}
+	s.mu.Lock()
+	s.defaults = copied
+	s.mu.Unlock()
+}
+
+// Load reads the stored overrides
+func (s *Set) Load(ctx context.Context) error {
+	if s.db == nil {
+		return nil
+	}
+	table := database.ServerTableName(s.db, "feature_flags")
+	rows, err := s.db.Query(ctx, fmt.Sprintf(`SELECT name, enabled, percent FROM %s`, table))
+	if err != nil {
+		return fmt.Errorf("load feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]override)
+	for rows.Next() {
+		var name string
+		var enabled, percent int
+		if err := rows.Scan(&name, &enabled, &percent); err != nil {
+			return fmt.Errorf("load feature flags: %w", err)
+		}
+		overrides[name] = override{enabled: enabled == 1, percent: percent}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load feature flags: %w", err)
+	}
+	s.mu.Lock()
+	s.overrides = overrides
+	s.mu.Unlock()
+	return nil
+}
+
+// Override turns a declared flag on or off for percent of browsers,
+// without a redeploy
+func (s *Set) Override(ctx context.Context, name string, enabled bool, percent int) error {
+	if percent < 0 || percent > 100 {
+		return ErrPercent
+	}
+	s.mu.RLock()
+	_, ok := s.defaults[name]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrUnknownFlag
+	}
+	if s.db != nil {
+		table := database.ServerTableName(s.db, "feature_flags")
+		if _, err := s.db.Exec(ctx, fmt.Sprintf(
+			`INSERT INTO %s (name, enabled, percent, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET enabled = excluded.enabled, percent = excluded.percent, updated_at = excluded.updated_at`, table),
+			name, boolToInt(enabled), percent, time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+			return fmt.Errorf("save feature flag: %w", err)
+		}
+	}
+	s.mu.Lock()
+	s.overrides[name] = override{enabled: enabled, percent: percent}
+	s.mu.Unlock()
+	return nil
+}
+
+// Reset drops a flag's override, returning it to server.yml
+func (s *Set) Reset(ctx context.Context, name string) error {
+	if s.db != nil {
+		table := database.ServerTableName(s.db, "feature_flags")
+		if _, err := s.db.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE name = ?`, table), name); err != nil {
+			return fmt.Errorf("reset feature flag: %w", err)
+		}
+	}
+	s.mu.Lock()
+	delete(s.overrides, name)
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns a declared flag's effective state
+func (s *Set) Get(name string) (Flag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	def, ok := s.defaults[name]
+	if !ok {
+		return Flag{}, false
+	}
+	flag := Flag{
+		Name:        name,
+		Description: def.Description,
+		Enabled:     def.Enabled,
+		Percent:     def.EffectivePercent(),
+		Source:      SourceConfig,
+	}
+	if o, ok := s.overrides[name]; ok {
+		flag.Enabled = o.enabled
+		flag.Percent = o.percent
+		flag.Source = SourceOverride
+		flag.Default = &def
+	}
+	return flag, true
+}
+
+// List returns every declared flag by name
+func (s *Set) List() []Flag {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.defaults))
+	for name := range s.defaults {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+
+	flags := make([]Flag, 0, len(names))
+	for _, name := range names {
+		if flag, ok := s.Get(name); ok {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// Enabled reports whether the feature is on for the browser in bucket
+// (0-99). Undeclared flags are off, so code can check a flag before it is
+// added to server.yml.
+func (s *Set) Enabled(name string, bucket int) bool {
+	if s == nil {
+		return false
+	}
+	flag, ok := s.Get(name)
+	if !ok || !flag.Enabled {
+		return false
+	}
+	return flag.Percent >= 100 || rolloutBucket(name, bucket) < flag.Percent
+}
+
+// rolloutBucket shifts a browser's bucket by an offset per flag, so a 10%
+// rollout of one flag does not reach the same browsers as a 10% rollout of
+// another, while each flag still reaches exactly its percentage
+func rolloutBucket(name string, bucket int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return (int(h.Sum32()%100) + bucket%100 + 100) % 100
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database"
+)
+
+func newFeatureTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	dm, err := database.NewDatabaseManager(&database.Config{
+		Driver:   "sqlite",
+		DataDir:  t.TempDir(),
+		MaxOpen:  5,
+		MaxIdle:  2,
+		Lifetime: 60,
+	})
+	if err != nil {
+		t.Fatalf("NewDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	if err := database.InitSchema(context.Background(), dm); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+	return dm.ServerDB()
+}
+
+func TestSetEnabled(t *testing.T) {
+	s := NewSet(map[string]config.FeatureConfig{
+		"on":      {Enabled: true},
+		"off":     {Enabled: false},
+		"partial": {Enabled: true, Percent: 25},
+	}, nil)
+
+	if !s.Enabled("on", 0) || s.Enabled("off", 0) || s.Enabled("undeclared", 0) {
+		t.Error("Enabled() does not follow the declared flags")
+	}
+	on := 0
+	for bucket := 0; bucket < 100; bucket++ {
+		if s.Enabled("partial", bucket) {
+			on++
+		}
+	}
+	if on != 25 {
+		t.Errorf("partial flag is on for %d of 100 buckets, want 25", on)
+	}
+
+	var nilSet *Set
+	if nilSet.Enabled("on", 0) {
+		t.Error("nil Set reports a flag as enabled")
+	}
+}
+
+func TestSetOverridePersists(t *testing.T) {
+	ctx := context.Background()
+	db := newFeatureTestDB(t)
+	defaults := map[string]config.FeatureConfig{"ranking.v2": {Enabled: false, Description: "New ranking"}}
+
+	s := NewSet(defaults, db)
+	if err := s.Override(ctx, "ranking.v2", true, 10); err != nil {
+		t.Fatalf("Override() error = %v", err)
+	}
+	if err := s.Override(ctx, "unknown", true, 10); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Override(unknown) error = %v, want ErrUnknownFlag", err)
+	}
+	if err := s.Override(ctx, "ranking.v2", true, 101); !errors.Is(err, ErrPercent) {
+		t.Errorf("Override(101%%) error = %v, want ErrPercent", err)
+	}
+
+	// A restart reads the override back from the database
+	reloaded := NewSet(defaults, db)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	flag, ok := reloaded.Get("ranking.v2")
+	if !ok || !flag.Enabled || flag.Percent != 10 || flag.Source != SourceOverride || flag.Default == nil {
+		t.Errorf("reloaded flag = %+v, want the override", flag)
+	}
+
+	if err := reloaded.Reset(ctx, "ranking.v2"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if flag, _ := reloaded.Get("ranking.v2"); flag.Enabled || flag.Source != SourceConfig {
+		t.Errorf("flag after Reset() = %+v, want the server.yml state", flag)
+	}
+}
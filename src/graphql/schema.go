@@ -19,6 +19,38 @@ func InitSchema() error {
 // initSchemaImpl is the actual schema initialization implementation
 // Per AI.md PART 19: GraphQL must be in sync with REST API
 func initSchemaImpl() error {
+	// Define the VideoResult type
+	videoResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "VideoResult",
+		Description: "Video metadata of a result in the videos category",
+		Fields: graphql.Fields{
+			"duration": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Length in seconds",
+			},
+			"channel": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Channel or account that published the video",
+			},
+			"channelUrl": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Channel page URL",
+			},
+			"publishedAt": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Publication date (RFC 3339)",
+			},
+			"views": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "View count",
+			},
+			"embed": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Embeddable player URL, when the instance's video player can play the video",
+			},
+		},
+	})
+
 	// Define the SearchResult type
 	searchResultType := graphql.NewObject(graphql.ObjectConfig{
 		Name:        "SearchResult",
@@ -56,6 +88,10 @@ func initSchemaImpl() error {
 				Type:        graphql.String,
 				Description: "Publication date",
 			},
+			"video": &graphql.Field{
+				Type:        videoResultType,
+				Description: "Video metadata (for video results)",
+			},
 		},
 	})
 
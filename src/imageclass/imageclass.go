@@ -0,0 +1,299 @@
+// Package imageclass flags adult image results for strict safe search with
+// a local image classifier. The model, a small NSFW classifier in ONNX
+// format run on the CPU, is served by a companion process on the same host:
+// the server is built without cgo and cannot load an ONNX runtime itself.
+// The server fetches each thumbnail, posts its bytes to the classifier and
+// caches the verdict by image URL, so the classifier never learns who
+// searched or what for.
+package imageclass
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// ErrNotImage is returned when a thumbnail is not an image or is too large
+var ErrNotImage = errors.New("not a classifiable image")
+
+const (
+	defaultThreshold = 0.8
+	defaultTimeout   = 5 * time.Second
+	defaultCacheSize = 20000
+	// maxImageBytes bounds a thumbnail download
+	maxImageBytes = 2 << 20
+	// workers is how many thumbnails one search classifies at once
+	workers = 8
+)
+
+// Options configures a Classifier
+type Options struct {
+	// Endpoint receives a POST of the image bytes and answers
+	// {"nsfw": <score from 0 to 1>}
+	Endpoint string
+	// Threshold is the score at or above which an image is adult
+	// (default 0.8)
+	Threshold float64
+	// Timeout bounds one thumbnail download plus its classification
+	// (default 5s)
+	Timeout time.Duration
+	// CacheSize is how many verdicts are kept (default 20000)
+	CacheSize int
+}
+
+// Verdict is the classifier's answer for one image
+type Verdict struct {
+	Score float64 `json:"score"`
+	Adult bool    `json:"adult"`
+}
+
+// Classifier checks images with the companion classifier and caches the
+// verdicts; the least recently used are evicted first
+type Classifier struct {
+	opts     Options
+	client   *http.Client
+	fetcher  *http.Client
+	mu       sync.Mutex
+	verdicts map[string]*list.Element
+	order    *list.List
+}
+
+type cachedVerdict struct {
+	url     string
+	verdict Verdict
+}
+
+// New creates a classifier for the companion process at opts.Endpoint
+func New(opts Options) *Classifier {
+	if opts.Threshold <= 0 || opts.Threshold > 1 {
+		opts.Threshold = defaultThreshold
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = defaultCacheSize
+	}
+	return &Classifier{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		fetcher: &http.Client{
+			Timeout: opts.Timeout,
+			// Thumbnail URLs come from engine results: never let one reach
+			// an internal address, whatever its DNS says at dial time
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{Control: dialControl}).DialContext,
+			},
+		},
+		verdicts: make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Check classifies one image, from the cache when it was seen before
+func (c *Classifier) Check(ctx context.Context, imageURL string) (Verdict, error) {
+	if verdict, ok := c.cached(imageURL); ok {
+		return verdict, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+	image, contentType, err := c.fetch(ctx, imageURL)
+	if err != nil {
+		return Verdict{}, err
+	}
+	score, err := c.classify(ctx, image, contentType)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	verdict := Verdict{Score: score, Adult: score >= c.opts.Threshold}
+	c.store(imageURL, verdict)
+	return verdict, nil
+}
+
+// Flag classifies the thumbnail (or, without one, the URL) of each result
+// and reports which are adult. An image that cannot be checked is not
+// flagged: a classifier outage degrades to the engines' own safe search.
+func (c *Classifier) Flag(ctx context.Context, results []model.Result) []bool {
+	flags := make([]bool, len(results))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var failures sync.Once
+	for i := range results {
+		imageURL := results[i].Thumbnail
+		if imageURL == "" {
+			imageURL = results[i].URL
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, imageURL string) {
+			defer func() { <-sem; wg.Done() }()
+			verdict, err := c.Check(ctx, imageURL)
+			if err != nil {
+				if !errors.Is(err, ErrNotImage) {
+					failures.Do(func() { slog.Warn("image classification failed", "err", err) })
+				}
+				return
+			}
+			flags[i] = verdict.Adult
+		}(i, imageURL)
+	}
+	wg.Wait()
+	return flags
+}
+
+// CacheSize returns how many verdicts are kept
+func (c *Classifier) CacheSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opts.CacheSize
+}
+
+// SetCacheSize changes how many verdicts are kept, evicting the least
+// recently used until the cache fits
+func (c *Classifier) SetCacheSize(n int) {
+	if n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts.CacheSize = n
+	c.evictLocked()
+}
+
+// Count returns the number of cached verdicts
+func (c *Classifier) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// fetch downloads an image
+func (c *Classifier) fetch(ctx context.Context, imageURL string) ([]byte, string, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, "", ErrNotImage
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", ErrNotImage
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := c.fetcher.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetch image: HTTP %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", ErrNotImage
+	}
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch image: %w", err)
+	}
+	if len(image) > maxImageBytes {
+		return nil, "", ErrNotImage
+	}
+	return image, contentType, nil
+}
+
+// classify posts an image to the companion classifier
+func (c *Classifier) classify(ctx context.Context, image []byte, contentType string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.Endpoint, bytes.NewReader(image))
+	if err != nil {
+		return 0, fmt.Errorf("classify image: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("classify image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("classify image: HTTP %d", resp.StatusCode)
+	}
+	var answer struct {
+		NSFW *float64 `json:"nsfw"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&answer); err != nil || answer.NSFW == nil {
+		return 0, fmt.Errorf("classify image: the classifier did not return a score")
+	}
+	return *answer.NSFW, nil
+}
+
+func (c *Classifier) cached(imageURL string) (Verdict, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.verdicts[imageURL]
+	if !ok {
+		return Verdict{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedVerdict).verdict, true
+}
+
+func (c *Classifier) store(imageURL string, verdict Verdict) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.verdicts[imageURL]; ok {
+		el.Value.(*cachedVerdict).verdict = verdict
+		c.order.MoveToFront(el)
+		return
+	}
+	c.verdicts[imageURL] = c.order.PushFront(&cachedVerdict{url: imageURL, verdict: verdict})
+	c.evictLocked()
+}
+
+func (c *Classifier) evictLocked() {
+	for c.order.Len() > c.opts.CacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.verdicts, oldest.Value.(*cachedVerdict).url)
+	}
+}
+
+// allowInternalImages is a test-only seam: httptest servers bind to
+// 127.0.0.1. Production never enables it.
+var allowInternalImages = false
+
+// dialControl refuses thumbnail connections to loopback, private,
+// link-local, unspecified or multicast addresses
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("image dial: invalid address %q", address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("image dial: address %q is not permitted", address)
+	}
+	if allowInternalImages {
+		return nil
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("image dial: address %q is not permitted", address)
+	}
+	return nil
+}
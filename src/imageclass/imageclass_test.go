@@ -0,0 +1,117 @@
+package imageclass
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// newTestClassifier serves images whose body is their score and a
+// classifier that answers with it
+func newTestClassifier(t *testing.T, cacheSize int) (*Classifier, *httptest.Server, *atomic.Int32) {
+	t.Helper()
+	allowInternalImages = true
+	t.Cleanup(func() { allowInternalImages = false })
+
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page.html" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte(r.URL.Query().Get("score")))
+	}))
+	t.Cleanup(images.Close)
+
+	var calls atomic.Int32
+	classifier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "image/jpeg" || len(body) == 0 {
+			http.Error(w, "bad image", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"nsfw": ` + string(body) + `}`))
+	}))
+	t.Cleanup(classifier.Close)
+
+	return New(Options{Endpoint: classifier.URL, CacheSize: cacheSize}), images, &calls
+}
+
+func TestCheckCachesVerdicts(t *testing.T) {
+	c, images, calls := newTestClassifier(t, 2)
+	ctx := context.Background()
+
+	verdict, err := c.Check(ctx, images.URL+"/a.jpg?score=0.93")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verdict.Adult || verdict.Score != 0.93 {
+		t.Errorf("verdict = %+v, want adult at 0.93", verdict)
+	}
+	if verdict, _ := c.Check(ctx, images.URL+"/b.jpg?score=0.2"); verdict.Adult {
+		t.Error("a 0.2 score was flagged at the default 0.8 threshold")
+	}
+
+	if _, err := c.Check(ctx, images.URL+"/a.jpg?score=0.93"); err != nil || calls.Load() != 2 {
+		t.Errorf("classifier calls = %d, %v; want the repeat served from the cache", calls.Load(), err)
+	}
+
+	// A third image evicts the least recently used verdict (b)
+	c.Check(ctx, images.URL+"/c.jpg?score=0.1")
+	if c.Count() != 2 {
+		t.Errorf("Count() = %d, want 2", c.Count())
+	}
+	c.Check(ctx, images.URL+"/b.jpg?score=0.2")
+	if calls.Load() != 4 {
+		t.Errorf("classifier calls = %d, want b classified again after eviction", calls.Load())
+	}
+
+	if _, err := c.Check(ctx, images.URL+"/page.html"); err != ErrNotImage {
+		t.Errorf("Check(html) error = %v, want ErrNotImage", err)
+	}
+}
+
+func TestSetCacheSize(t *testing.T) {
+	c, images, calls := newTestClassifier(t, 3)
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c"} {
+		c.Check(ctx, images.URL+"/"+name+".jpg?score=0.1")
+	}
+
+	c.SetCacheSize(1)
+	if c.Count() != 1 || c.CacheSize() != 1 {
+		t.Fatalf("Count() = %d, CacheSize() = %d after shrinking to 1", c.Count(), c.CacheSize())
+	}
+	c.Check(ctx, images.URL+"/c.jpg?score=0.1")
+	if calls.Load() != 3 {
+		t.Errorf("classifier calls = %d, want the most recent verdict kept", calls.Load())
+	}
+}
+
+func TestFlag(t *testing.T) {
+	c, images, _ := newTestClassifier(t, 0)
+	results := []model.Result{
+		{URL: "https://site.example/1", Thumbnail: images.URL + "/1.jpg?score=0.99"},
+		{URL: images.URL + "/2.jpg?score=0.05"},
+		{URL: "https://site.example/3", Thumbnail: images.URL + "/page.html"},
+	}
+	flags := c.Flag(context.Background(), results)
+	if len(flags) != 3 || !flags[0] || flags[1] || flags[2] {
+		t.Errorf("Flag() = %v, want only the first result flagged", flags)
+	}
+}
+
+func TestFetchRefusesInternalAddresses(t *testing.T) {
+	c, images, _ := newTestClassifier(t, 0)
+	allowInternalImages = false
+	if _, err := c.Check(context.Background(), images.URL+"/a.jpg?score=0.9"); err == nil {
+		t.Error("a thumbnail on a loopback address was fetched")
+	}
+}
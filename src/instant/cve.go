@@ -110,22 +110,38 @@ func (h *CVEHandler) HandleInstantQuery(ctx context.Context, query string) (*Ans
 	}
 
 	// CVSS Score
+	var score float64
+	var severity string
 	if len(cve.Metrics.CVSSMetricV31) > 0 {
 		cvss := cve.Metrics.CVSSMetricV31[0]
-		severity := cvss.CVSSData.BaseSeverity
-		score := cvss.CVSSData.BaseScore
+		severity = cvss.CVSSData.BaseSeverity
+		score = cvss.CVSSData.BaseScore
 		severityClass := getSeverityClass(severity)
 		content.WriteString(fmt.Sprintf("<strong>CVSS 3.1 Score:</strong> <span class=\"%s\">%.1f (%s)</span><br>", severityClass, score, severity))
 		content.WriteString(fmt.Sprintf("<strong>Vector:</strong> <code>%s</code><br><br>", cvss.CVSSData.VectorString))
 	} else if len(cve.Metrics.CVSSMetricV2) > 0 {
 		cvss := cve.Metrics.CVSSMetricV2[0]
-		severity := cvss.BaseSeverity
-		score := cvss.CVSSData.BaseScore
+		severity = cvss.BaseSeverity
+		score = cvss.CVSSData.BaseScore
 		severityClass := getSeverityClass(severity)
 		content.WriteString(fmt.Sprintf("<strong>CVSS 2.0 Score:</strong> <span class=\"%s\">%.1f (%s)</span><br>", severityClass, score, severity))
 		content.WriteString(fmt.Sprintf("<strong>Vector:</strong> <code>%s</code><br><br>", cvss.CVSSData.VectorString))
 	}
 
+	// Affected versions (first 10)
+	affected := affectedVersions(cve)
+	if len(affected) > 0 {
+		content.WriteString("<strong>Affected Versions:</strong><br>")
+		for i, entry := range affected {
+			if i >= 10 {
+				content.WriteString(fmt.Sprintf("&bull; ... and %d more<br>", len(affected)-10))
+				break
+			}
+			content.WriteString(fmt.Sprintf("&bull; <code>%s</code><br>", escapeHTML(entry)))
+		}
+		content.WriteString("<br>")
+	}
+
 	// Dates
 	content.WriteString(fmt.Sprintf("<strong>Published:</strong> %s<br>", formatCVEDate(cve.Published)))
 	content.WriteString(fmt.Sprintf("<strong>Last Modified:</strong> %s<br><br>", formatCVEDate(cve.LastModified)))
@@ -158,6 +174,9 @@ func (h *CVEHandler) HandleInstantQuery(ctx context.Context, query string) (*Ans
 			"published":   cve.Published,
 			"modified":    cve.LastModified,
 			"description": getEnglishDescription(cve.Descriptions),
+			"cvss_score":  score,
+			"severity":    severity,
+			"affected":    affected,
 		},
 	}, nil
 }
@@ -226,6 +245,11 @@ type CVEItem struct {
 			BaseSeverity string `json:"baseSeverity"`
 		} `json:"cvssMetricV2"`
 	} `json:"metrics"`
+	Configurations []struct {
+		Nodes []struct {
+			CPEMatch []CPEMatch `json:"cpeMatch"`
+		} `json:"nodes"`
+	} `json:"configurations"`
 	References []struct {
 		URL    string   `json:"url"`
 		Source string   `json:"source"`
@@ -233,6 +257,61 @@ type CVEItem struct {
 	} `json:"references"`
 }
 
+// CPEMatch is a single affected-product entry in an NVD configuration node
+type CPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+// affectedVersions flattens the vulnerable CPE matches of a CVE into
+// readable ranges such as "apache log4j >= 2.0.1, < 2.15.0"
+func affectedVersions(cve CVEItem) []string {
+	var affected []string
+	seen := make(map[string]bool)
+	for _, cfg := range cve.Configurations {
+		for _, node := range cfg.Nodes {
+			for _, m := range node.CPEMatch {
+				if !m.Vulnerable {
+					continue
+				}
+				// cpe:2.3:part:vendor:product:version:...
+				parts := strings.Split(m.Criteria, ":")
+				if len(parts) < 6 {
+					continue
+				}
+				entry := strings.ReplaceAll(parts[3]+" "+parts[4], "_", " ")
+				var bounds []string
+				if m.VersionStartIncluding != "" {
+					bounds = append(bounds, ">= "+m.VersionStartIncluding)
+				}
+				if m.VersionStartExcluding != "" {
+					bounds = append(bounds, "> "+m.VersionStartExcluding)
+				}
+				if m.VersionEndIncluding != "" {
+					bounds = append(bounds, "<= "+m.VersionEndIncluding)
+				}
+				if m.VersionEndExcluding != "" {
+					bounds = append(bounds, "< "+m.VersionEndExcluding)
+				}
+				if len(bounds) > 0 {
+					entry += " " + strings.Join(bounds, ", ")
+				} else if parts[5] != "*" && parts[5] != "-" {
+					entry += " " + parts[5]
+				}
+				if !seen[entry] {
+					seen[entry] = true
+					affected = append(affected, entry)
+				}
+			}
+		}
+	}
+	return affected
+}
+
 func getSeverityClass(severity string) string {
 	switch strings.ToUpper(severity) {
 	case "CRITICAL":
@@ -1038,9 +1038,14 @@ func (h *TimestampHandler) HandleInstantQuery(ctx context.Context, query string)
 
 	utc := t.UTC()
 	local := t.Local()
+	lang := LangFromContext(ctx)
+	unit := "seconds"
+	if isMillis {
+		unit = "milliseconds"
+	}
 
 	content := fmt.Sprintf(`<div class="timestamp-result">
-<strong>Unix Timestamp:</strong> %d%s<br><br>
+<strong>Unix Timestamp:</strong> %d (%s)<br><br>
 <strong>UTC:</strong> %s<br>
 <strong>Local:</strong> %s<br>
 <strong>ISO 8601:</strong> %s<br>
@@ -1048,16 +1053,11 @@ func (h *TimestampHandler) HandleInstantQuery(ctx context.Context, query string)
 <strong>Relative:</strong> %s
 </div>`,
 		timestamp,
-		func() string {
-			if isMillis {
-				return " (milliseconds)"
-			}
-			return " (seconds)"
-		}(),
+		unit,
 		utc.Format("Monday, January 2, 2006 15:04:05 UTC"),
 		local.Format("Monday, January 2, 2006 15:04:05 MST"),
-		t.Format(time.RFC3339),
-		t.Format(time.RFC1123Z),
+		copyableCode(lang, t.Format(time.RFC3339)),
+		copyableCode(lang, t.Format(time.RFC1123Z)),
 		relativeTime,
 	)
 
@@ -1068,8 +1068,12 @@ func (h *TimestampHandler) HandleInstantQuery(ctx context.Context, query string)
 		Content: content,
 		Data: map[string]interface{}{
 			"timestamp": timestamp,
+			"unit":      unit,
 			"utc":       utc.Format(time.RFC3339),
 			"local":     local.Format(time.RFC3339),
+			"iso8601":   t.Format(time.RFC3339),
+			"rfc2822":   t.Format(time.RFC1123Z),
+			"relative":  relativeTime,
 		},
 	}, nil
 }
@@ -10,6 +10,13 @@ import (
 	"time"
 )
 
+// Pattern fragments shared by the DNS query forms. Named groups let the
+// record type appear before or after the domain.
+const (
+	dnsDomainGroup = `(?P<domain>[a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+)`
+	dnsTypeGroup   = `(?P<type>a|aaaa|cname|mx|ns|txt|all)`
+)
+
 // DNSHandler handles DNS record lookups
 type DNSHandler struct {
 	patterns []*regexp.Regexp
@@ -21,13 +28,19 @@ func NewDNSHandler() *DNSHandler {
 	return &DNSHandler{
 		patterns: []*regexp.Regexp{
 			// "dns:example.com" or "dns: example.com"
-			regexp.MustCompile(`(?i)^dns[:\s]+([a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+)(?:/([a-zA-Z]+))?$`),
+			regexp.MustCompile(`(?i)^dns[:\s]+` + dnsDomainGroup + `(?:/` + dnsTypeGroup + `)?$`),
 			// "dns lookup example.com" or "dns lookup example.com/mx"
-			regexp.MustCompile(`(?i)^dns\s+lookup[:\s]+([a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+)(?:/([a-zA-Z]+))?$`),
+			regexp.MustCompile(`(?i)^dns\s+lookup[:\s]+` + dnsDomainGroup + `(?:/` + dnsTypeGroup + `)?$`),
 			// "nslookup example.com"
-			regexp.MustCompile(`(?i)^nslookup[:\s]+([a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+)(?:/([a-zA-Z]+))?$`),
+			regexp.MustCompile(`(?i)^nslookup[:\s]+` + dnsDomainGroup + `(?:/` + dnsTypeGroup + `)?$`),
 			// "dig example.com"
-			regexp.MustCompile(`(?i)^dig[:\s]+([a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+)(?:/([a-zA-Z]+))?$`),
+			regexp.MustCompile(`(?i)^dig[:\s]+` + dnsDomainGroup + `(?:/` + dnsTypeGroup + `)?$`),
+			// "dns mx example.com", "dig txt example.com"
+			regexp.MustCompile(`(?i)^(?:dns|dig|nslookup)\s+` + dnsTypeGroup + `\s+` + dnsDomainGroup + `$`),
+			// "dig example.com mx", "nslookup example.com ns"
+			regexp.MustCompile(`(?i)^(?:dns|dig|nslookup)\s+` + dnsDomainGroup + `\s+` + dnsTypeGroup + `$`),
+			// "mx records for example.com", "txt record example.com"
+			regexp.MustCompile(`(?i)^` + dnsTypeGroup + `\s+records?\s+(?:for\s+|of\s+)?` + dnsDomainGroup + `$`),
 		},
 		resolver: &net.Resolver{
 			PreferGo: true,
@@ -74,18 +87,7 @@ type mxRecord struct {
 }
 
 func (h *DNSHandler) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
-	// Extract domain and optional record type from query
-	var domain, recordType string
-	for _, p := range h.patterns {
-		if matches := p.FindStringSubmatch(query); len(matches) > 1 {
-			domain = strings.ToLower(matches[1])
-			if len(matches) > 2 && matches[2] != "" {
-				recordType = strings.ToUpper(matches[2])
-			}
-			break
-		}
-	}
-
+	domain, recordType := h.parseQuery(query)
 	if domain == "" {
 		return nil, nil
 	}
@@ -233,6 +235,22 @@ func (h *DNSHandler) HandleInstantQuery(ctx context.Context, query string) (*Ans
 	}, nil
 }
 
+// parseQuery extracts the domain and optional record type from a DNS query
+func (h *DNSHandler) parseQuery(query string) (domain, recordType string) {
+	for _, p := range h.patterns {
+		matches := p.FindStringSubmatch(query)
+		if matches == nil {
+			continue
+		}
+		domain = strings.ToLower(matches[p.SubexpIndex("domain")])
+		if i := p.SubexpIndex("type"); i >= 0 {
+			recordType = strings.ToUpper(matches[i])
+		}
+		return domain, recordType
+	}
+	return "", ""
+}
+
 func (h *DNSHandler) lookupAllRecords(ctx context.Context, domain string, recordType string) *dnsRecords {
 	records := &dnsRecords{
 		A:    make([]string, 0),
@@ -88,31 +88,41 @@ func (h *HashHandler) HandleInstantQuery(ctx context.Context, query string) (*An
 		return nil, nil
 	}
 
+	lang := LangFromContext(ctx)
 	data := []byte(text)
+	result := map[string]interface{}{
+		"input":     text,
+		"algorithm": hashType,
+	}
 	var content strings.Builder
 	content.WriteString(fmt.Sprintf("<strong>Input:</strong> %s<br><br>", escapeHTML(text)))
 
 	if hashType == "md5" || hashType == "all" {
 		hash := md5.Sum(data)
-		content.WriteString(fmt.Sprintf("<strong>MD5:</strong> <code>%s</code><br>", hex.EncodeToString(hash[:])))
+		result["md5"] = hex.EncodeToString(hash[:])
+		content.WriteString(fmt.Sprintf("<strong>MD5:</strong> %s<br>", copyableCode(lang, result["md5"].(string))))
 	}
 	if hashType == "sha1" || hashType == "all" {
 		hash := sha1.Sum(data)
-		content.WriteString(fmt.Sprintf("<strong>SHA1:</strong> <code>%s</code><br>", hex.EncodeToString(hash[:])))
+		result["sha1"] = hex.EncodeToString(hash[:])
+		content.WriteString(fmt.Sprintf("<strong>SHA1:</strong> %s<br>", copyableCode(lang, result["sha1"].(string))))
 	}
 	if hashType == "sha256" || hashType == "all" {
 		hash := sha256.Sum256(data)
-		content.WriteString(fmt.Sprintf("<strong>SHA256:</strong> <code>%s</code><br>", hex.EncodeToString(hash[:])))
+		result["sha256"] = hex.EncodeToString(hash[:])
+		content.WriteString(fmt.Sprintf("<strong>SHA256:</strong> %s<br>", copyableCode(lang, result["sha256"].(string))))
 	}
 	if hashType == "sha512" || hashType == "all" {
 		hash := sha512.Sum512(data)
-		content.WriteString(fmt.Sprintf("<strong>SHA512:</strong> <code>%s</code><br>", hex.EncodeToString(hash[:])))
+		result["sha512"] = hex.EncodeToString(hash[:])
+		content.WriteString(fmt.Sprintf("<strong>SHA512:</strong> %s<br>", copyableCode(lang, result["sha512"].(string))))
 	}
 
 	return &Answer{
 		Type:    AnswerTypeHash,
 		Query:   query,
-		Title:   i18n.T(LangFromContext(ctx), "instant.hash_generator_title"),
+		Title:   i18n.T(lang, "instant.hash_generator_title"),
 		Content: content.String(),
+		Data:    result,
 	}, nil
 }
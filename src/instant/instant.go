@@ -2,9 +2,11 @@ package instant
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"strings"
 
+	"github.com/apimgr/search/src/common/i18n"
 	"github.com/apimgr/search/src/geoip"
 )
 
@@ -152,12 +154,19 @@ type Handler interface {
 // Manager manages all instant answer handlers
 type Manager struct {
 	handlers []Handler
+	// disabled holds handler names switched off by configuration
+	disabled map[string]bool
+	// maxAnswers answers render expanded; up to maxCollapsed more render collapsed
+	maxAnswers   int
+	maxCollapsed int
 }
 
 // NewManager creates a new instant answer manager
 func NewManager() *Manager {
 	m := &Manager{
-		handlers: make([]Handler, 0),
+		handlers:   make([]Handler, 0),
+		disabled:   make(map[string]bool),
+		maxAnswers: 1,
 	}
 
 	// Register all handlers — order matters: more specific handlers before broad ones.
@@ -181,6 +190,7 @@ func NewManager() *Manager {
 	m.Register(NewUUIDHandler())
 	m.Register(NewRandomHandler())
 	m.Register(NewPasswordHandler())
+	m.Register(NewPassphraseHandler())
 	m.Register(NewQRHandler())
 	m.Register(NewASCIIHandler())
 	m.Register(NewCaseHandler())
@@ -249,6 +259,9 @@ func (m *Manager) Process(ctx context.Context, query string) (*Answer, error) {
 	query = strings.TrimSpace(query)
 
 	for _, handler := range m.handlers {
+		if m.disabled[handler.Name()] {
+			continue
+		}
 		if handler.CanHandle(query) {
 			answer, err := handler.HandleInstantQuery(ctx, query)
 			if err != nil || answer != nil {
@@ -265,3 +278,12 @@ func (m *Manager) Process(ctx context.Context, query string) (*Answer, error) {
 func (m *Manager) GetHandlers() []Handler {
 	return m.handlers
 }
+
+// copyableCode renders value as inline code followed by a copy button.
+// The button is wired up by the page-wide .copy-btn click handler in app.js;
+// without JavaScript the value is still selectable text.
+func copyableCode(lang, value string) string {
+	escaped := escapeHTML(value)
+	return fmt.Sprintf(`<span class="instant-copy"><code>%s</code><button type="button" class="copy-btn" data-copy="%s" aria-label="%s"><span class="copy-icon">📋</span></button></span>`,
+		escaped, escaped, escapeHTML(i18n.T(lang, "accessibility.copy_to_clipboard")))
+}
@@ -870,6 +870,14 @@ func TestTimezoneHandlerCanHandle(t *testing.T) {
 	}
 }
 
+func TestTimezoneHandlerIgnoresNonTimezoneConversion(t *testing.T) {
+	h := NewTimezoneHandler()
+	ans, err := h.HandleInstantQuery(context.Background(), "10 reasons to visit new york")
+	if err != nil || ans != nil {
+		t.Errorf("HandleInstantQuery() = %v, %v; want nil answer", ans, err)
+	}
+}
+
 func TestTimezoneHandlerHandleInstantQuery(t *testing.T) {
 	h := NewTimezoneHandler()
 	ctx := context.Background()
@@ -894,6 +902,26 @@ func TestTimezoneHandlerHandleInstantQuery(t *testing.T) {
 			query:  "Tokyo time",
 			checks: []string{"Tokyo"},
 		},
+		{
+			name:   "time in New Delhi",
+			query:  "time in New Delhi",
+			checks: []string{"Asia/Kolkata"},
+		},
+		{
+			name:   "multi-word IANA city",
+			query:  "time in Port Moresby",
+			checks: []string{"Pacific/Port_Moresby"},
+		},
+		{
+			name:   "multi-word city conversion",
+			query:  "9am london to new york",
+			checks: []string{"America/New_York"},
+		},
+		{
+			name:   "abbreviation conversion",
+			query:  "3pm EST to CET",
+			checks: []string{"Europe/Paris"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1334,6 +1362,9 @@ func TestDNSHandlerCanHandle(t *testing.T) {
 		{"nslookup example.com", true},
 		{"dig example.com", true},
 		{"dns: example.com", true},
+		{"dns mx example.com", true},
+		{"dig example.com txt", true},
+		{"mx records for example.com", true},
 		{"unrelated query", false},
 	}
 	for _, tt := range tests {
@@ -1346,6 +1377,32 @@ func TestDNSHandlerCanHandle(t *testing.T) {
 	}
 }
 
+func TestDNSHandlerParseQuery(t *testing.T) {
+	h := NewDNSHandler()
+	tests := []struct {
+		query      string
+		wantDomain string
+		wantType   string
+	}{
+		{"dns example.com", "example.com", ""},
+		{"dns example.com/mx", "example.com", "MX"},
+		{"dns mx example.com", "example.com", "MX"},
+		{"dig Example.COM aaaa", "example.com", "AAAA"},
+		{"nslookup ns example.org", "example.org", "NS"},
+		{"txt record for example.net", "example.net", "TXT"},
+		{"dns a.example.com", "a.example.com", ""},
+		{"unrelated query", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			domain, recordType := h.parseQuery(tt.query)
+			if domain != tt.wantDomain || recordType != tt.wantType {
+				t.Errorf("parseQuery(%q) = (%q, %q), want (%q, %q)", tt.query, domain, recordType, tt.wantDomain, tt.wantType)
+			}
+		})
+	}
+}
+
 // ---- CVEHandler Name/Patterns ----
 
 func TestCVEHandlerNameAndPatterns(t *testing.T) {
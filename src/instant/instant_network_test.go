@@ -10,6 +10,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/market"
 )
 
 // redirectClient returns an *http.Client that redirects all requests to the given test server.
@@ -243,6 +246,28 @@ func TestCVEHandlerHTTPSuccess(t *testing.T) {
 	}
 }
 
+func TestAffectedVersions(t *testing.T) {
+	var cve CVEItem
+	raw := `{"configurations":[{"nodes":[{"cpeMatch":[
+		{"vulnerable":true,"criteria":"cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*","versionStartIncluding":"2.0.1","versionEndExcluding":"2.15.0"},
+		{"vulnerable":true,"criteria":"cpe:2.3:a:apache:log4j:2.0:beta9:*:*:*:*:*:*"},
+		{"vulnerable":false,"criteria":"cpe:2.3:o:linux:linux_kernel:*:*:*:*:*:*:*:*"}]}]}]}`
+	if err := json.Unmarshal([]byte(raw), &cve); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	got := affectedVersions(cve)
+	want := []string{"apache log4j >= 2.0.1, < 2.15.0", "apache log4j 2.0"}
+	if len(got) != len(want) {
+		t.Fatalf("affectedVersions() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("affectedVersions()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
 func TestCVEHandlerHTTPRateLimit(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusTooManyRequests)
@@ -2554,3 +2579,100 @@ func TestMathHandlerHandleInstantQuery(t *testing.T) {
 		})
 	}
 }
+
+// ---- MarketHandler ----
+
+func TestParseMarketQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"AAPL stock", "AAPL"},
+		{"tsla share price", "tsla"},
+		{"stock price of nvda", "nvda"},
+		{"quote msft", "msft"},
+		{"$AAPL", "AAPL"},
+		{"btc price", "btc"},
+		{"bitcoin price", "bitcoin"},
+		{"price of eth", "eth"},
+		{"NVDA price", "NVDA"},
+		// Lower-case "X price" is only a market query for known coins
+		{"milk price", ""},
+		{"price of gold", ""},
+		{"apple", ""},
+		{"$5", ""},
+	}
+	for _, tt := range tests {
+		if got := parseMarketQuery(tt.query); got != tt.want {
+			t.Errorf("parseMarketQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMarketHandlerHandle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v8/finance/chart/AAPL":
+			fmt.Fprint(w, `{"chart":{"result":[{"meta":{"symbol":"AAPL","currency":"USD","exchangeName":"NMS",
+				"shortName":"Apple Inc.","regularMarketPrice":1234.5,"chartPreviousClose":1200},
+				"indicators":{"quote":[{"close":[1200,1210,1234.5]}]}}]}}`)
+		case "/api/v3/coins/markets":
+			fmt.Fprint(w, `[{"id":"ethereum","symbol":"eth","name":"Ethereum","current_price":3000,
+				"price_change_24h":-30,"price_change_percentage_24h":-1,"sparkline_in_7d":{"price":[3030,3000]}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	h := NewMarketHandler(market.NewService(&config.MarketConfig{StocksURL: srv.URL, CoinGeckoURL: srv.URL}))
+
+	answer, err := h.HandleInstantQuery(context.Background(), "AAPL stock")
+	if err != nil || answer == nil {
+		t.Fatalf("HandleInstantQuery(AAPL stock) = %v, %v", answer, err)
+	}
+	if answer.Type != AnswerTypeMarket || answer.Title != "Apple Inc. (AAPL)" {
+		t.Errorf("answer = %q %q", answer.Type, answer.Title)
+	}
+	for _, want := range []string{"1,234.50", "+34.50", "market-sparkline positive", "NMS"} {
+		if !strings.Contains(answer.Content, want) {
+			t.Errorf("Content missing %q: %s", want, answer.Content)
+		}
+	}
+	if answer.Data["price"] != 1234.5 {
+		t.Errorf("Data[price] = %v", answer.Data["price"])
+	}
+
+	answer, err = h.HandleInstantQuery(context.Background(), "eth price")
+	if err != nil || answer == nil {
+		t.Fatalf("HandleInstantQuery(eth price) = %v, %v", answer, err)
+	}
+	if answer.Data["kind"] != "crypto" || !strings.Contains(answer.Content, "market-change negative") {
+		t.Errorf("crypto answer = %+v", answer.Data)
+	}
+
+	// Unknown symbols fall through to web results
+	answer, err = h.HandleInstantQuery(context.Background(), "ZZZZ stock")
+	if answer != nil || err != nil {
+		t.Errorf("HandleInstantQuery(ZZZZ stock) = %v, %v, want nil, nil", answer, err)
+	}
+}
+
+func TestFormatMarketPrice(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{1234567.891, "1,234,567.89"},
+		{100, "100.00"},
+		{-1234.5, "-1,234.50"},
+		{0.54321, "0.5432"},
+		{0.00001234, "0.00001234"},
+		{0, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := formatMarketPrice(tt.in); got != tt.want {
+			t.Errorf("formatMarketPrice(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
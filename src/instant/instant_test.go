@@ -6,9 +6,12 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/apimgr/search/src/config"
 )
 
 func TestAnswerTypeConstants(t *testing.T) {
@@ -172,6 +175,110 @@ func TestManagerProcessTrimWhitespace(t *testing.T) {
 	}
 }
 
+// ladderStub answers every query with a fixed answer type
+type ladderStub struct {
+	name string
+	typ  AnswerType
+}
+
+func (h ladderStub) Name() string                { return h.name }
+func (h ladderStub) Patterns() []*regexp.Regexp  { return []*regexp.Regexp{regexp.MustCompile(`.*`)} }
+func (h ladderStub) CanHandle(query string) bool { return true }
+func (h ladderStub) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
+	return &Answer{Type: h.typ, Query: query, Title: h.name}, nil
+}
+
+func newLadderManager() *Manager {
+	m := &Manager{maxAnswers: 1}
+	m.Register(ladderStub{"first", "a"})
+	m.Register(ladderStub{"second", "b"})
+	m.Register(ladderStub{"duplicate", "a"})
+	m.Register(ladderStub{"third", "c"})
+	return m
+}
+
+func ladderTitles(answers []*Answer) []string {
+	titles := make([]string, len(answers))
+	for i, a := range answers {
+		titles[i] = a.Title
+	}
+	return titles
+}
+
+func TestManagerConfigureLadder(t *testing.T) {
+	m := newLadderManager()
+	unknown := m.Configure(&config.InstantAnswersConfig{
+		Priority:     []string{"third", "missing"},
+		Handlers:     map[string]bool{"second": false},
+		MaxAnswers:   1,
+		MaxCollapsed: 2,
+	})
+	if len(unknown) != 1 || unknown[0] != "missing" {
+		t.Errorf("Configure() unknown = %v, want [missing]", unknown)
+	}
+
+	// third is promoted, second is disabled, duplicate's type is already taken
+	got := ladderTitles(m.ProcessAll(context.Background(), "anything"))
+	want := []string{"third", "first"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ProcessAll() = %v, want %v", got, want)
+	}
+
+	answer, _ := m.Process(context.Background(), "anything")
+	if answer == nil || answer.Title != "third" {
+		t.Errorf("Process() = %v, want the third handler's answer", answer)
+	}
+}
+
+func TestManagerProcessAllLimit(t *testing.T) {
+	m := newLadderManager()
+	if got := m.ProcessAll(context.Background(), "q"); len(got) != 1 {
+		t.Errorf("ProcessAll() with no collapsed answers returned %d answers, want 1", len(got))
+	}
+
+	m.SetLimits(0, 1)
+	if m.MaxAnswers() != 1 {
+		t.Errorf("MaxAnswers() = %d, want 1 (minimum)", m.MaxAnswers())
+	}
+	got := ladderTitles(m.ProcessAll(context.Background(), "q"))
+	if strings.Join(got, ",") != "first,second" {
+		t.Errorf("ProcessAll() = %v, want [first second]", got)
+	}
+}
+
+func TestManagerLadder(t *testing.T) {
+	m := NewManager()
+	m.SetEnabled("uuid", false)
+
+	var uuidEntry *LadderEntry
+	for _, e := range m.Ladder("uuid") {
+		if e.Handler == "uuid" {
+			e := e
+			uuidEntry = &e
+		}
+	}
+	if uuidEntry == nil {
+		t.Fatal("Ladder() is missing the uuid handler")
+	}
+	if uuidEntry.Enabled || !uuidEntry.Matched || len(uuidEntry.Patterns) == 0 {
+		t.Errorf("uuid entry = %+v, want disabled, matched, with patterns", *uuidEntry)
+	}
+
+	if answer, _ := m.Process(context.Background(), "uuid"); answer != nil && answer.Type == AnswerTypeUUID {
+		t.Error("Process() used a disabled handler")
+	}
+}
+
+func TestManagerHandlerNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, h := range NewManager().GetHandlers() {
+		if seen[h.Name()] {
+			t.Errorf("duplicate handler name %q", h.Name())
+		}
+		seen[h.Name()] = true
+	}
+}
+
 func TestMathHandlerName(t *testing.T) {
 	h := NewMathHandler()
 	if h.Name() != "math" {
@@ -1122,8 +1229,13 @@ func TestIPHandlerCanHandle(t *testing.T) {
 		{"ip 1.2.3.4", true},
 		{"8.8.8.8", true},
 		{"1.1.1.1", true},
+		{"ip 2001:4860:4860::8888", true},
+		{"whois 8.8.8.8", true},
+		{"ip lookup 1.1.1.1", true},
+		{"2606:4700::1111", true},
 		{"hello world", false},
 		{"192.168 incomplete", false},
+		{"12:30:45", false},
 	}
 
 	for _, tt := range tests {
@@ -1604,6 +1716,34 @@ func TestHashHandlerHandleAllTypes(t *testing.T) {
 	}
 }
 
+func TestHashHandlerStructuredData(t *testing.T) {
+	h := NewHashHandler()
+
+	answer, err := h.HandleInstantQuery(context.Background(), "md5 hello")
+	if err != nil || answer == nil {
+		t.Fatalf("HandleInstantQuery() = %v, %v", answer, err)
+	}
+	if answer.Data["md5"] != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Data[md5] = %v", answer.Data["md5"])
+	}
+	if answer.Data["algorithm"] != "md5" || answer.Data["sha256"] != nil {
+		t.Errorf("Data = %v, want md5 only", answer.Data)
+	}
+	if !strings.Contains(answer.Content, `data-copy="5d41402abc4b2a76b9719d911017c592"`) {
+		t.Error("Content should include a copy button for the digest")
+	}
+}
+
+func TestCopyableCodeEscapes(t *testing.T) {
+	got := copyableCode("en", `"><script>`)
+	if strings.Contains(got, "<script>") {
+		t.Errorf("copyableCode() did not escape value: %s", got)
+	}
+	if !strings.Contains(got, `class="copy-btn"`) || !strings.Contains(got, `aria-label="Copy to clipboard"`) {
+		t.Errorf("copyableCode() = %s, want copy button with label", got)
+	}
+}
+
 func TestHashHandlerPatterns(t *testing.T) {
 	h := NewHashHandler()
 	patterns := h.Patterns()
@@ -1972,6 +2112,108 @@ func TestPasswordHandlerPatterns(t *testing.T) {
 	}
 }
 
+func TestPasswordHandlerQueryForms(t *testing.T) {
+	h := NewPasswordHandler()
+	ctx := context.Background()
+
+	tests := []struct {
+		query  string
+		length int
+	}{
+		{"password 24", 24},
+		{"password 24 chars", 24},
+		{"strong password 20", 20},
+		{"24 character password", 24},
+		{"password generator", 16},
+	}
+	for _, tt := range tests {
+		if !h.CanHandle(tt.query) {
+			t.Errorf("CanHandle(%q) = false, want true", tt.query)
+			continue
+		}
+		answer, err := h.HandleInstantQuery(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("HandleInstantQuery(%q) error = %v", tt.query, err)
+		}
+		if answer.Data["length"] != tt.length {
+			t.Errorf("%q: length = %v, want %d", tt.query, answer.Data["length"], tt.length)
+		}
+		if !strings.Contains(answer.Content, `data-regenerate="`+tt.query+`"`) {
+			t.Errorf("%q: content should contain a regenerate link", tt.query)
+		}
+	}
+
+	if h.CanHandle("password reset") {
+		t.Error(`CanHandle("password reset") = true, want false`)
+	}
+}
+
+func TestPassphraseHandler(t *testing.T) {
+	h := NewPassphraseHandler()
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		words int
+	}{
+		{"passphrase", 6},
+		{"passphrase 6 words", 6},
+		{"passphrase 8", 8},
+		{"5 word passphrase", 5},
+		{"diceware 7", 7},
+		{"passphrase 1", 3},
+		{"passphrase 99", 16},
+	}
+	for _, tt := range tests {
+		if !h.CanHandle(tt.query) {
+			t.Errorf("CanHandle(%q) = false, want true", tt.query)
+			continue
+		}
+		answer, err := h.HandleInstantQuery(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("HandleInstantQuery(%q) error = %v", tt.query, err)
+		}
+		words, ok := answer.Data["words"].([]string)
+		if !ok || len(words) != tt.words {
+			t.Errorf("%q: words = %v, want %d words", tt.query, answer.Data["words"], tt.words)
+			continue
+		}
+		if answer.Data["passphrase"] != strings.Join(words, "-") {
+			t.Errorf("%q: passphrase = %v, want words joined by -", tt.query, answer.Data["passphrase"])
+		}
+	}
+}
+
+func TestPassphraseWordlist(t *testing.T) {
+	if len(passphraseWords) < 1024 {
+		t.Fatalf("wordlist has %d words, want at least 1024", len(passphraseWords))
+	}
+	seen := make(map[string]bool, len(passphraseWords))
+	for _, w := range passphraseWords {
+		if seen[w] {
+			t.Errorf("duplicate word %q", w)
+		}
+		seen[w] = true
+	}
+}
+
+func TestEntropyStrength(t *testing.T) {
+	tests := []struct {
+		alphabet, length int
+		want             string
+	}{
+		{62, 6, "weak"},
+		{88, 8, "fair"},
+		{1602, 6, "strong"},
+		{88, 16, "very_strong"},
+	}
+	for _, tt := range tests {
+		if got := strengthLevel(entropyBits(tt.alphabet, tt.length)); got != tt.want {
+			t.Errorf("strengthLevel(entropyBits(%d, %d)) = %q, want %q", tt.alphabet, tt.length, got, tt.want)
+		}
+	}
+}
+
 func TestIPHandlerPatterns(t *testing.T) {
 	h := NewIPHandler()
 	patterns := h.Patterns()
@@ -2677,6 +2919,8 @@ func TestIPHandlerSpecificIP(t *testing.T) {
 		{"ip 8.8.8.8", AnswerTypeIP},
 		{"8.8.8.8", AnswerTypeIP},
 		{"ip 192.168.1.1", AnswerTypeIP},
+		{"ip ::1", AnswerTypeIP},
+		{"whois 2001:db8::1", AnswerTypeIP},
 	}
 
 	for _, tt := range tests {
@@ -5124,6 +5368,12 @@ func TestTimestampHandlerHandleSeconds(t *testing.T) {
 	if !contains(answer.Content, "ISO 8601") {
 		t.Error("Content should contain ISO 8601")
 	}
+	if answer.Data["iso8601"] == nil || answer.Data["unit"] != "seconds" {
+		t.Errorf("Data = %v, want iso8601 and unit", answer.Data)
+	}
+	if !contains(answer.Content, "copy-btn") {
+		t.Error("Content should include copy buttons")
+	}
 }
 
 func TestTimestampHandlerHandleMilliseconds(t *testing.T) {
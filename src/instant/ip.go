@@ -11,18 +11,22 @@ import (
 )
 
 // IPHandler handles IP address lookups — both "what is my ip" queries and
-// specific IP lookups ("ip 1.2.3.4" or bare "8.8.8.8").
+// specific IP lookups ("ip 1.2.3.4", "whois 2001:db8::1", or bare "8.8.8.8").
 // When a *geoip.Lookup is present in the context (via WithGeoIPLookup) the
 // response is enriched with country, city, region, timezone, and ASN data.
 type IPHandler struct {
 	// myIPPatterns match queries about the user's own IP
 	myIPPatterns []*regexp.Regexp
-	// specificIPPattern matches queries about a specific IPv4 address
+	// specificIPPattern matches queries about a specific IPv4 or IPv6 address
 	specificIPPattern *regexp.Regexp
-	// bareIPPattern matches a bare IPv4 address as the entire query
+	// bareIPPattern matches a bare IPv4 or IPv6 address as the entire query
 	bareIPPattern *regexp.Regexp
 }
 
+// ipLiteralPattern matches an IPv4 dotted quad or something shaped like an
+// IPv6 literal; net.ParseIP does the real validation.
+const ipLiteralPattern = `[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}|[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}`
+
 func NewIPHandler() *IPHandler {
 	return &IPHandler{
 		myIPPatterns: []*regexp.Regexp{
@@ -34,10 +38,12 @@ func NewIPHandler() *IPHandler {
 			// bare "ip" or "ip:" with no address — shows client's IP
 			regexp.MustCompile(`(?i)^ip[:\s]*$`),
 		},
-		// matches "ip 1.2.3.4" or "ip: 1.2.3.4" — looks up a specific address
-		specificIPPattern: regexp.MustCompile(`(?i)^ip[:\s]+([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})\s*$`),
-		// matches a bare IPv4 address, e.g. "8.8.8.8"
-		bareIPPattern: regexp.MustCompile(`^([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})\s*$`),
+		// matches "ip 1.2.3.4", "ip lookup 1.2.3.4", or "whois 2001:db8::1" —
+		// looks up a specific address; whois for an IP is answered from the
+		// local GeoIP/ASN database rather than a remote WHOIS server
+		specificIPPattern: regexp.MustCompile(`(?i)^(?:ip(?:\s+lookup)?|whois)[:\s]+(` + ipLiteralPattern + `)\s*$`),
+		// matches a bare IPv4 or IPv6 address, e.g. "8.8.8.8" or "2606:4700::1111"
+		bareIPPattern: regexp.MustCompile(`^(` + ipLiteralPattern + `)\s*$`),
 	}
 }
 
@@ -56,7 +62,15 @@ func (h *IPHandler) CanHandle(query string) bool {
 			return true
 		}
 	}
-	return h.specificIPPattern.MatchString(query) || h.bareIPPattern.MatchString(query)
+	if h.specificIPPattern.MatchString(query) {
+		return true
+	}
+	// A bare colon-separated query could be a clock time ("12:30:45"), so
+	// bare IPv6 queries must parse before the handler claims them.
+	if m := h.bareIPPattern.FindStringSubmatch(query); len(m) == 2 {
+		return !strings.Contains(m[1], ":") || net.ParseIP(m[1]) != nil
+	}
+	return false
 }
 
 func (h *IPHandler) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
@@ -128,7 +142,7 @@ func (h *IPHandler) lookupSpecificIP(ctx context.Context, query, rawIP string) (
 			Type:  AnswerTypeIP,
 			Query: query,
 			Title: "IP Address",
-			// rawIP comes from a regex that only matches digit-and-dot sequences — safe to display.
+			// rawIP comes from a regex that only matches hex digits, dots, and colons — safe to display.
 			Content: fmt.Sprintf("<strong>%s</strong> is not a valid IP address", rawIP),
 			Data:    map[string]interface{}{"ip": rawIP, "valid": false},
 		}, nil
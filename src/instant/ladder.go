@@ -0,0 +1,172 @@
+package instant
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// followUpBudget bounds the extra time spent collecting answers after the
+// first one, so stacking answers never delays the results page by much
+const followUpBudget = 1500 * time.Millisecond
+
+// LadderEntry describes one handler's place in the answer ladder and, when
+// a query is given, whether it would trigger
+type LadderEntry struct {
+	Handler string `json:"handler"`
+	Rank    int    `json:"rank"`
+	Enabled bool   `json:"enabled"`
+	// Matched reports CanHandle; some handlers accept less than their patterns
+	Matched  bool     `json:"matched"`
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// Configure applies the operator's ladder settings. It must be called after
+// all handlers are registered and before the manager serves queries. Names
+// that match no registered handler are returned so the caller can warn.
+func (m *Manager) Configure(cfg *config.InstantAnswersConfig) []string {
+	var unknown []string
+	for _, name := range cfg.Priority {
+		if !m.hasHandler(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	m.SetPriority(cfg.Priority)
+
+	for name, enabled := range cfg.Handlers {
+		if !m.hasHandler(name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		m.SetEnabled(name, enabled)
+	}
+
+	m.SetLimits(cfg.MaxAnswers, cfg.MaxCollapsed)
+	sort.Strings(unknown)
+	return unknown
+}
+
+// SetPriority moves the named handlers to the front of the ladder in the
+// given order. Unlisted handlers keep their registration order after them.
+func (m *Manager) SetPriority(names []string) {
+	rank := make(map[string]int, len(names))
+	for i, name := range names {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+	sort.SliceStable(m.handlers, func(i, j int) bool {
+		ri, iok := rank[m.handlers[i].Name()]
+		rj, jok := rank[m.handlers[j].Name()]
+		if iok && jok {
+			return ri < rj
+		}
+		return iok && !jok
+	})
+}
+
+// SetEnabled switches a handler on or off by name
+func (m *Manager) SetEnabled(name string, enabled bool) {
+	if enabled {
+		delete(m.disabled, name)
+		return
+	}
+	if m.disabled == nil {
+		m.disabled = make(map[string]bool)
+	}
+	m.disabled[name] = true
+}
+
+// SetLimits sets how many answers render expanded (at least one) and how
+// many more may render collapsed below them
+func (m *Manager) SetLimits(maxAnswers, maxCollapsed int) {
+	if maxAnswers < 1 {
+		maxAnswers = 1
+	}
+	if maxCollapsed < 0 {
+		maxCollapsed = 0
+	}
+	m.maxAnswers = maxAnswers
+	m.maxCollapsed = maxCollapsed
+}
+
+// MaxAnswers returns how many answers render expanded
+func (m *Manager) MaxAnswers() int {
+	return m.maxAnswers
+}
+
+// MaxCollapsed returns how many further answers may render collapsed
+func (m *Manager) MaxCollapsed() int {
+	return m.maxCollapsed
+}
+
+// ProcessAll walks the ladder and returns up to MaxAnswers plus the collapsed
+// limit answers, highest priority first. Conflicts resolve by rank: the
+// first handler to answer wins, and a lower-ranked answer of the same type
+// is dropped. Unlike Process, a failing handler does not stop the ladder.
+func (m *Manager) ProcessAll(ctx context.Context, query string) []*Answer {
+	query = strings.TrimSpace(query)
+	limit := m.maxAnswers + m.maxCollapsed
+
+	var answers []*Answer
+	seen := make(map[AnswerType]bool)
+	for _, handler := range m.handlers {
+		if len(answers) >= limit {
+			break
+		}
+		if m.disabled[handler.Name()] || !handler.CanHandle(query) {
+			continue
+		}
+		answer, err := handler.HandleInstantQuery(ctx, query)
+		if err != nil || answer == nil || seen[answer.Type] {
+			continue
+		}
+		seen[answer.Type] = true
+		answers = append(answers, answer)
+
+		if len(answers) == 1 && limit > 1 {
+			// Follow-up handlers share one short deadline
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, followUpBudget)
+			defer cancel()
+		}
+	}
+	return answers
+}
+
+// Ladder returns every handler in priority order. When query is not empty,
+// each entry also reports whether the handler would trigger and which of
+// its patterns match, without running any handler.
+func (m *Manager) Ladder(query string) []LadderEntry {
+	query = strings.TrimSpace(query)
+	entries := make([]LadderEntry, 0, len(m.handlers))
+	for i, handler := range m.handlers {
+		entry := LadderEntry{
+			Handler: handler.Name(),
+			Rank:    i + 1,
+			Enabled: !m.disabled[handler.Name()],
+		}
+		if query != "" {
+			entry.Matched = handler.CanHandle(query)
+			for _, p := range handler.Patterns() {
+				if p.MatchString(query) {
+					entry.Patterns = append(entry.Patterns, p.String())
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (m *Manager) hasHandler(name string) bool {
+	for _, handler := range m.handlers {
+		if handler.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package instant
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/market"
+)
+
+// AnswerTypeMarket is the answer type for stock and crypto prices
+const AnswerTypeMarket AnswerType = "market"
+
+var (
+	// "AAPL stock", "$tsla share price", "msft quote"
+	marketSuffixPattern = regexp.MustCompile(`(?i)^\$?([a-z][a-z0-9.\-]{0,9})\s+(?:stock|stocks|shares?|quote|stock price|share price)$`)
+	// "stock AAPL", "quote brk-b", "stock price of nvda"
+	marketPrefixPattern = regexp.MustCompile(`(?i)^(?:stock|quote|(?:stock|share) price(?:\s+of)?)\s+\$?([a-z][a-z0-9.\-]{0,9})$`)
+	// "btc price", "bitcoin price", "NVDA price"
+	marketPricePattern = regexp.MustCompile(`(?i)^\$?([a-z][a-z0-9.\-]{0,19})\s+price$`)
+	// "price of eth", "price btc"
+	marketPriceOfPattern = regexp.MustCompile(`(?i)^price(?:\s+of)?\s+\$?([a-z][a-z0-9.\-]{0,19})$`)
+	// "$AAPL" cashtag
+	marketCashtagPattern = regexp.MustCompile(`^\$([A-Za-z][A-Za-z.]{0,9})$`)
+)
+
+// MarketHandler answers stock and cryptocurrency price queries
+type MarketHandler struct {
+	service  *market.Service
+	patterns []*regexp.Regexp
+}
+
+// NewMarketHandler creates a market price handler backed by service
+func NewMarketHandler(service *market.Service) *MarketHandler {
+	return &MarketHandler{
+		service: service,
+		patterns: []*regexp.Regexp{
+			marketSuffixPattern,
+			marketPrefixPattern,
+			marketPricePattern,
+			marketPriceOfPattern,
+			marketCashtagPattern,
+		},
+	}
+}
+
+func (h *MarketHandler) Name() string               { return "market" }
+func (h *MarketHandler) Patterns() []*regexp.Regexp { return h.patterns }
+
+func (h *MarketHandler) CanHandle(query string) bool {
+	return parseMarketQuery(query) != ""
+}
+
+// parseMarketQuery extracts the symbol from a price query. "X price" is
+// ambiguous ("milk price"), so it only matches known cryptocurrencies or
+// symbols typed in upper case.
+func parseMarketQuery(query string) string {
+	query = strings.TrimSpace(query)
+	for _, p := range []*regexp.Regexp{marketSuffixPattern, marketPrefixPattern, marketCashtagPattern} {
+		if m := p.FindStringSubmatch(query); m != nil {
+			return m[1]
+		}
+	}
+	for _, p := range []*regexp.Regexp{marketPricePattern, marketPriceOfPattern} {
+		if m := p.FindStringSubmatch(query); m != nil {
+			symbol := strings.TrimPrefix(m[1], "$")
+			if market.IsKnownCrypto(symbol) || (symbol == strings.ToUpper(symbol) && len(symbol) <= 10) {
+				return symbol
+			}
+		}
+	}
+	return ""
+}
+
+func (h *MarketHandler) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
+	symbol := parseMarketQuery(query)
+	if symbol == "" {
+		return nil, nil
+	}
+
+	quote, err := h.service.Lookup(ctx, symbol)
+	if err != nil {
+		// Unknown symbols and provider outages fall through to web results
+		return nil, nil
+	}
+
+	lang := LangFromContext(ctx)
+	period := i18n.T(lang, "instant.market_period_today")
+	if quote.Kind == market.KindCrypto {
+		period = i18n.T(lang, "instant.market_period_24h")
+	}
+
+	direction := "positive"
+	sign := "+"
+	if quote.Change < 0 {
+		direction = "negative"
+		sign = ""
+	}
+
+	var content strings.Builder
+	content.WriteString(`<div class="market-result">`)
+	fmt.Fprintf(&content, `<div class="market-price"><strong>%s</strong> %s</div>`,
+		formatMarketPrice(quote.Price), escapeHTML(quote.Currency))
+	fmt.Fprintf(&content, `<div class="market-change %s">%s%s (%s%.2f%%) <small>%s</small></div>`,
+		direction, sign, formatMarketPrice(quote.Change), sign, quote.ChangePercent, escapeHTML(period))
+	if svg := marketSparkline(quote.Sparkline, i18n.T(lang, "instant.market_chart_label")); svg != "" {
+		content.WriteString(svg)
+	}
+	meta := quote.Symbol
+	if quote.Exchange != "" {
+		meta += " · " + quote.Exchange
+	}
+	fmt.Fprintf(&content, `<div class="market-meta"><small>%s</small></div>`, escapeHTML(meta))
+	content.WriteString(`</div>`)
+
+	return &Answer{
+		Type:      AnswerTypeMarket,
+		Query:     query,
+		Title:     fmt.Sprintf("%s (%s)", quote.Name, quote.Symbol),
+		Content:   content.String(),
+		Source:    quote.Source,
+		SourceURL: quote.SourceURL,
+		Data: map[string]interface{}{
+			"symbol":         quote.Symbol,
+			"name":           quote.Name,
+			"kind":           string(quote.Kind),
+			"price":          quote.Price,
+			"change":         quote.Change,
+			"change_percent": quote.ChangePercent,
+			"currency":       quote.Currency,
+			"sparkline":      quote.Sparkline,
+			"updated_at":     quote.UpdatedAt,
+		},
+	}, nil
+}
+
+// formatMarketPrice formats a price with thousands separators; sub-unit
+// prices (most altcoins) keep more precision
+func formatMarketPrice(v float64) string {
+	abs := math.Abs(v)
+	if abs != 0 && abs < 1 {
+		// Four significant digits: 0.5432, 0.00001234
+		decimals := 3 - int(math.Floor(math.Log10(abs)))
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	}
+
+	s := strconv.FormatFloat(abs, 'f', 2, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+	}
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('.')
+	b.WriteString(frac)
+	return b.String()
+}
+
+// marketSparkline renders points as an inline SVG polyline. It is drawn
+// server-side so the chart works without JavaScript.
+func marketSparkline(points []float64, label string) string {
+	if len(points) < 2 {
+		return ""
+	}
+	const width, height = 200.0, 40.0
+
+	lo, hi := points[0], points[0]
+	for _, p := range points {
+		lo = math.Min(lo, p)
+		hi = math.Max(hi, p)
+	}
+	span := hi - lo
+	if span == 0 {
+		span = 1
+	}
+
+	coords := make([]string, len(points))
+	for i, p := range points {
+		x := float64(i) / float64(len(points)-1) * width
+		y := height - (p-lo)/span*height
+		coords[i] = strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+	}
+
+	direction := "positive"
+	if points[len(points)-1] < points[0] {
+		direction = "negative"
+	}
+	return fmt.Sprintf(`<svg class="market-sparkline %s" viewBox="0 0 %.0f %.0f" preserveAspectRatio="none" role="img" aria-label="%s"><polyline fill="none" stroke="currentColor" stroke-width="1.5" points="%s"/></svg>`,
+		direction, width, height, escapeHTML(label), strings.Join(coords, " "))
+}
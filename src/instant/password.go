@@ -3,12 +3,32 @@ package instant
 import (
 	"context"
 	"crypto/rand"
+	_ "embed"
 	"fmt"
+	"math"
 	"math/big"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
+
+	"github.com/apimgr/search/src/common/i18n"
+)
+
+const (
+	passwordCharset     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}|;:,.<>?"
+	alphanumericCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 )
 
+// wordlistData is the passphrase wordlist: short, common English words, one per line
+//
+//go:embed data/wordlist.txt
+var wordlistData string
+
+var passphraseWords = strings.Fields(wordlistData)
+
+var countPattern = regexp.MustCompile(`(\d+)`)
+
 // PasswordHandler generates secure passwords
 type PasswordHandler struct {
 	patterns []*regexp.Regexp
@@ -17,11 +37,10 @@ type PasswordHandler struct {
 func NewPasswordHandler() *PasswordHandler {
 	return &PasswordHandler{
 		patterns: []*regexp.Regexp{
-			regexp.MustCompile(`(?i)^password\s*$`),
-			regexp.MustCompile(`(?i)^generate\s+password\s*$`),
-			regexp.MustCompile(`(?i)^password\s+(\d+)\s*$`),
-			regexp.MustCompile(`(?i)^random\s+password\s*$`),
-			regexp.MustCompile(`(?i)^secure\s+password\s*$`),
+			// "password", "strong password 20", "password 24 chars", "password generator"
+			regexp.MustCompile(`(?i)^(?:(?:generate|random|secure|strong)\s+)?password(?:\s+generator)?(?:\s+(\d+)(?:\s*(?:chars?|characters?))?)?\s*$`),
+			// "24 character password"
+			regexp.MustCompile(`(?i)^(\d+)[\s-]*(?:chars?|characters?)\s+password\s*$`),
 		},
 	}
 }
@@ -39,54 +58,181 @@ func (h *PasswordHandler) CanHandle(query string) bool {
 }
 
 func (h *PasswordHandler) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
-	length := 16
-	lengthPattern := regexp.MustCompile(`(\d+)`)
-	if matches := lengthPattern.FindStringSubmatch(query); len(matches) > 1 {
-		length, _ = strconv.Atoi(matches[1])
-		if length < 8 {
-			length = 8
-		}
-		if length > 128 {
-			length = 128
-		}
-	}
+	lang := LangFromContext(ctx)
+	length := parseCount(query, 16, 8, 128)
 
 	password := generatePassword(length)
 	passwordNoSpecial := generatePasswordNoSpecial(length)
+	bits := entropyBits(len(passwordCharset), length)
+	alphanumericBits := entropyBits(len(alphanumericCharset), length)
+
+	var content strings.Builder
+	content.WriteString(`<div class="password-result">`)
+	fmt.Fprintf(&content, `<div class="password-value"><strong>%s</strong><br>%s</div>`,
+		escapeHTML(i18n.T(lang, "instant.password_length_label", strconv.Itoa(length))), copyableCode(lang, password))
+	content.WriteString(passwordStrength(lang, bits))
+	fmt.Fprintf(&content, `<div class="password-value"><strong>%s</strong><br>%s</div>`,
+		escapeHTML(i18n.T(lang, "instant.password_alphanumeric")), copyableCode(lang, passwordNoSpecial))
+	content.WriteString(passwordStrength(lang, alphanumericBits))
+	content.WriteString(regenerateLink(lang, query))
+	content.WriteString(`</div>`)
 
 	return &Answer{
-		Type:  AnswerTypePassword,
-		Query: query,
-		Title: "Password Generator",
-		Content: fmt.Sprintf(`<div class="password-result">
-<strong>Secure Password (%d chars):</strong><br>
-<code>%s</code><br><br>
-<strong>Alphanumeric Only:</strong><br>
-<code>%s</code>
-</div>`, length, password, passwordNoSpecial),
+		Type:    AnswerTypePassword,
+		Query:   query,
+		Title:   i18n.T(lang, "instant.password_generator_title"),
+		Content: content.String(),
 		Data: map[string]interface{}{
-			"password": password,
-			"length":   length,
+			"password":     password,
+			"length":       length,
+			"entropy_bits": math.Round(bits*10) / 10,
+			"strength":     strengthLevel(bits),
 		},
 	}, nil
 }
 
-func generatePassword(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}|;:,.<>?"
-	result := make([]byte, length)
-	for i := range result {
-		num, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
-		result[i] = charset[num.Int64()]
+// PassphraseHandler generates diceware-style passphrases from the embedded wordlist
+type PassphraseHandler struct {
+	patterns []*regexp.Regexp
+}
+
+func NewPassphraseHandler() *PassphraseHandler {
+	return &PassphraseHandler{
+		patterns: []*regexp.Regexp{
+			// "passphrase", "passphrase 6", "passphrase 6 words", "diceware 8"
+			regexp.MustCompile(`(?i)^(?:(?:generate|random|secure|strong)\s+)?(?:passphrase|pass\s+phrase|diceware)(?:\s+generator)?(?:\s+(\d+)(?:\s*words?)?)?\s*$`),
+			// "6 word passphrase"
+			regexp.MustCompile(`(?i)^(\d+)[\s-]*words?\s+(?:passphrase|pass\s+phrase|diceware)\s*$`),
+		},
 	}
-	return string(result)
+}
+
+func (h *PassphraseHandler) Name() string               { return "passphrase" }
+func (h *PassphraseHandler) Patterns() []*regexp.Regexp { return h.patterns }
+
+func (h *PassphraseHandler) CanHandle(query string) bool {
+	for _, p := range h.patterns {
+		if p.MatchString(query) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *PassphraseHandler) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
+	lang := LangFromContext(ctx)
+	count := parseCount(query, 6, 3, 16)
+
+	words := generatePassphrase(count)
+	passphrase := strings.Join(words, "-")
+	bits := entropyBits(len(passphraseWords), count)
+
+	var content strings.Builder
+	content.WriteString(`<div class="password-result">`)
+	fmt.Fprintf(&content, `<div class="password-value"><strong>%s</strong><br>%s</div>`,
+		escapeHTML(i18n.T(lang, "instant.passphrase_words_label", strconv.Itoa(count))), copyableCode(lang, passphrase))
+	content.WriteString(passwordStrength(lang, bits))
+	content.WriteString(regenerateLink(lang, query))
+	content.WriteString(`</div>`)
+
+	return &Answer{
+		Type:    AnswerTypePassword,
+		Query:   query,
+		Title:   i18n.T(lang, "instant.passphrase_generator_title"),
+		Content: content.String(),
+		Data: map[string]interface{}{
+			"passphrase":   passphrase,
+			"words":        words,
+			"word_count":   count,
+			"wordlist":     len(passphraseWords),
+			"entropy_bits": math.Round(bits*10) / 10,
+			"strength":     strengthLevel(bits),
+		},
+	}, nil
+}
+
+// parseCount returns the first number in query clamped to [lo, hi], or def
+func parseCount(query string, def, lo, hi int) int {
+	m := countPattern.FindStringSubmatch(query)
+	if m == nil {
+		return def
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n > hi {
+		return hi
+	}
+	if n < lo {
+		return lo
+	}
+	return n
+}
+
+// entropyBits is the entropy of length symbols drawn uniformly from an alphabet
+// of the given size. Generated secrets are uniformly random, so this is exact
+// rather than a heuristic guess.
+func entropyBits(alphabet, length int) float64 {
+	return float64(length) * math.Log2(float64(alphabet))
+}
+
+// strengthLevel buckets entropy into the levels shown to users
+func strengthLevel(bits float64) string {
+	switch {
+	case bits < 40:
+		return "weak"
+	case bits < 60:
+		return "fair"
+	case bits < 80:
+		return "strong"
+	default:
+		return "very_strong"
+	}
+}
+
+// passwordStrength renders the strength meter for a generated secret
+func passwordStrength(lang string, bits float64) string {
+	level := strengthLevel(bits)
+	label := i18n.T(lang, "instant.password_strength",
+		i18n.T(lang, "instant.password_strength_"+level), strconv.Itoa(int(bits)))
+	return fmt.Sprintf(`<div class="password-strength strength-%s"><meter min="0" max="128" low="40" high="60" optimum="128" value="%.0f"></meter> <small>%s</small></div>`,
+		level, math.Min(bits, 128), escapeHTML(label))
+}
+
+// regenerateLink re-runs the query. Without JavaScript it reloads the search
+// page; app.js intercepts the click and swaps in a fresh answer from the API.
+func regenerateLink(lang, query string) string {
+	return fmt.Sprintf(`<div class="password-actions"><a class="instant-regenerate" href="/search?q=%s" data-regenerate="%s">🔄 %s</a> <small>%s</small></div>`,
+		url.QueryEscape(query), escapeHTML(query),
+		escapeHTML(i18n.T(lang, "instant.password_regenerate")),
+		escapeHTML(i18n.T(lang, "instant.password_local_note")))
+}
+
+func generatePassword(length int) string {
+	return randomString(passwordCharset, length)
 }
 
 func generatePasswordNoSpecial(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	return randomString(alphanumericCharset, length)
+}
+
+// generatePassphrase picks count words uniformly from the embedded wordlist
+func generatePassphrase(count int) []string {
+	words := make([]string, count)
+	for i := range words {
+		words[i] = passphraseWords[randomIndex(len(passphraseWords))]
+	}
+	return words
+}
+
+func randomString(charset string, length int) string {
 	result := make([]byte, length)
 	for i := range result {
-		num, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
-		result[i] = charset[num.Int64()]
+		result[i] = charset[randomIndex(len(charset))]
 	}
 	return string(result)
 }
+
+// randomIndex returns a uniform index in [0, n) from crypto/rand
+func randomIndex(n int) int {
+	num, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	return int(num.Int64())
+}
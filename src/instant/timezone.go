@@ -7,12 +7,19 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	// Embed the IANA database so zone lookups work in minimal containers
+	// that ship without /usr/share/zoneinfo
+	_ "time/tzdata"
 
 	"github.com/apimgr/search/src/common/i18n"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
+// timezoneConvertPattern matches "[convert] {time} {zone} to|in {zone}" where
+// each zone is an abbreviation, an IANA name, or a city of up to three words
+var timezoneConvertPattern = regexp.MustCompile(`(?i)^(?:convert\s+)?(\d{1,2}(?::\d{2})?(?:\s*[ap]m)?)\s+([a-zA-Z_]+(?:[/\s][a-zA-Z_]+){0,2}?)\s+(?:to|in)\s+([a-zA-Z_]+(?:[/\s][a-zA-Z_]+){0,2})$`)
+
 // TimezoneHandler handles timezone queries and conversions
 type TimezoneHandler struct {
 	patterns  []*regexp.Regexp
@@ -30,10 +37,8 @@ func NewTimezoneHandler() *TimezoneHandler {
 			regexp.MustCompile(`(?i)^what\s+time\s+(?:is\s+it\s+)?in\s+(.+)\??$`),
 			// "current time in {city}"
 			regexp.MustCompile(`(?i)^current\s+time\s+in\s+(.+)$`),
-			// "{time} {tz1} to {tz2}"
-			regexp.MustCompile(`(?i)^(\d{1,2}(?::\d{2})?(?:\s*[ap]m)?)\s+([a-zA-Z]+(?:/[a-zA-Z_]+)?)\s+(?:to|in)\s+([a-zA-Z]+(?:/[a-zA-Z_]+)?)$`),
-			// "convert {time} {tz1} to {tz2}"
-			regexp.MustCompile(`(?i)^convert\s+(\d{1,2}(?::\d{2})?(?:\s*[ap]m)?)\s+([a-zA-Z]+(?:/[a-zA-Z_]+)?)\s+to\s+([a-zA-Z]+(?:/[a-zA-Z_]+)?)$`),
+			// "{time} {tz1} to {tz2}", "9am london to new york"
+			timezoneConvertPattern,
 			// "{city} time"
 			regexp.MustCompile(`(?i)^(.+?)\s+time$`),
 		},
@@ -96,6 +101,7 @@ func NewTimezoneHandler() *TimezoneHandler {
 			"seoul":        "Asia/Seoul",
 			"mumbai":       "Asia/Kolkata",
 			"delhi":        "Asia/Kolkata",
+			"new delhi":    "Asia/Kolkata",
 			"bangalore":    "Asia/Kolkata",
 			"chennai":      "Asia/Kolkata",
 			"kolkata":      "Asia/Kolkata",
@@ -212,8 +218,12 @@ func (h *TimezoneHandler) HandleInstantQuery(ctx context.Context, query string)
 	}
 
 	// Handle "{time} {tz1} to {tz2}" conversions
-	convertPattern := regexp.MustCompile(`(?i)^(?:convert\s+)?(\d{1,2}(?::\d{2})?(?:\s*[ap]m)?)\s+([a-zA-Z_/]+)\s+(?:to|in)\s+([a-zA-Z_/]+)$`)
-	if matches := convertPattern.FindStringSubmatch(query); len(matches) > 3 {
+	if matches := timezoneConvertPattern.FindStringSubmatch(query); len(matches) > 3 {
+		// "10 reasons to visit new york" also fits the pattern; only answer
+		// when at least one side is a zone we recognize
+		if h.resolveTZ(matches[2]) == "" && h.resolveTZ(matches[3]) == "" {
+			return nil, nil
+		}
 		return h.handleConversion(query, matches[1], matches[2], matches[3], lang)
 	}
 
@@ -414,15 +424,18 @@ func (h *TimezoneHandler) resolveTZ(input string) string {
 		}
 	}
 
-	// Try common variations
-	titler := cases.Title(language.Und, cases.NoLower)
+	// Try common variations; IANA city names use underscores ("New_Delhi")
+	city := strings.ReplaceAll(cases.Title(language.Und, cases.NoLower).String(input), " ", "_")
 	variations := []string{
-		"America/" + titler.String(input),
-		"Europe/" + titler.String(input),
-		"Asia/" + titler.String(input),
-		"Australia/" + titler.String(input),
-		"Pacific/" + titler.String(input),
-		"Africa/" + titler.String(input),
+		"America/" + city,
+		"Europe/" + city,
+		"Asia/" + city,
+		"Australia/" + city,
+		"Pacific/" + city,
+		"Africa/" + city,
+		"America/Argentina/" + city,
+		"Atlantic/" + city,
+		"Indian/" + city,
 	}
 
 	for _, tz := range variations {
@@ -39,21 +39,27 @@ func (h *UUIDHandler) CanHandle(query string) bool {
 
 func (h *UUIDHandler) HandleInstantQuery(ctx context.Context, query string) (*Answer, error) {
 	id := uuid.New()
+	lang := LangFromContext(ctx)
+	upper := strings.ToUpper(id.String())
+	compact := strings.ReplaceAll(id.String(), "-", "")
 
 	return &Answer{
 		Type:  AnswerTypeUUID,
 		Query: query,
 		Title: "UUID Generator",
 		Content: fmt.Sprintf(`<div class="uuid-result">
-<strong>UUID v4:</strong> <code>%s</code><br>
-<strong>Uppercase:</strong> <code>%s</code><br>
-<strong>No dashes:</strong> <code>%s</code>
+<strong>UUID v4:</strong> %s<br>
+<strong>Uppercase:</strong> %s<br>
+<strong>No dashes:</strong> %s
 </div>`,
-			id.String(),
-			strings.ToUpper(id.String()),
-			strings.ReplaceAll(id.String(), "-", "")),
+			copyableCode(lang, id.String()),
+			copyableCode(lang, upper),
+			copyableCode(lang, compact)),
 		Data: map[string]interface{}{
-			"uuid": id.String(),
+			"uuid":      id.String(),
+			"version":   4,
+			"uppercase": upper,
+			"compact":   compact,
 		},
 	}, nil
 }
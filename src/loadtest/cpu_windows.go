@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package loadtest
+
+import "time"
+
+// processCPU is not read on Windows; the report leaves CPU time out
+func processCPU() time.Duration {
+	return 0
+}
@@ -0,0 +1,355 @@
+// Package loadtest sends synthetic searches to an instance at a fixed rate
+// and reports latency percentiles and resource usage, to size hardware
+// before an instance goes public. The load is open loop: each search starts
+// on schedule whether or not earlier ones have answered, as with real
+// users, so an overloaded server shows as rising latency and errors rather
+// than as a lower request rate.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultMaxInFlight = 1000
+	sampleInterval     = time.Second
+)
+
+// DefaultQueries are combined in pairs to make the synthetic searches, so a
+// run repeats some searches, as real traffic does, without answering
+// everything from the cache
+var DefaultQueries = []string{
+	"golang", "rust", "python", "linux", "kernel", "privacy", "weather",
+	"news", "recipes", "bread", "coffee", "history", "rome", "music",
+	"guitar", "football", "travel", "japan", "camping", "bicycle", "repair",
+	"garden", "tomatoes", "science", "space", "mars", "climate", "energy",
+	"solar", "movies", "books", "chess", "health", "sleep", "running",
+	"finance", "mortgage", "jobs", "resume", "photography",
+}
+
+// Options configures a run
+type Options struct {
+	// BaseURL is the instance under test, e.g. http://127.0.0.1:64080
+	BaseURL string
+	// QPS is how many searches start each second
+	QPS float64
+	// Duration is how long searches keep starting
+	Duration time.Duration
+	// Queries are sent as they are, picked at random. Empty uses pairs of
+	// DefaultQueries.
+	Queries []string
+	// Timeout bounds one search (default 30s)
+	Timeout time.Duration
+	// MaxInFlight bounds the searches awaiting an answer; searches due
+	// beyond it are counted as dropped instead of sent (default 1000)
+	MaxInFlight int
+	// Client sends the searches (default: a client with Timeout)
+	Client *http.Client
+	// Sample reads the instance's resource usage, once a second during
+	// the run (optional)
+	Sample func(ctx context.Context) (Usage, error)
+	// Progress is called once a second with the searches sent and
+	// answered so far (optional)
+	Progress func(sent, completed int)
+}
+
+// Usage is a reading of the instance's resource usage. Zero fields were
+// not available.
+type Usage struct {
+	Goroutines int
+	// HeapBytes is the Go heap in use
+	HeapBytes uint64
+	// CPU is the process CPU time used so far
+	CPU time.Duration
+}
+
+// Latency summarizes the response times of the answered searches
+type Latency struct {
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// Report is the outcome of a run
+type Report struct {
+	// Elapsed runs from the first search to the last answer
+	Elapsed time.Duration
+	// Sent counts the searches sent; Dropped those not sent because
+	// MaxInFlight searches were awaiting an answer
+	Sent    int
+	Dropped int
+	// Status counts the answers by HTTP status; Errors counts searches
+	// with no answer (timeouts, refused connections)
+	Status map[int]int
+	Errors int
+	// Succeeded counts the 2xx answers
+	Succeeded int
+	// Throughput is the 2xx answers per second
+	Throughput float64
+	// Latency covers every answered search, whatever its status
+	Latency Latency
+	// UsageStart and UsageEnd are read before the first and after the
+	// last search; UsagePeak holds the highest goroutine and heap
+	// readings. Sampled is false without a Sample function.
+	Sampled    bool
+	UsageStart Usage
+	UsagePeak  Usage
+	UsageEnd   Usage
+}
+
+// ErrorRate is the share of sent searches that failed or answered outside
+// 2xx
+func (r *Report) ErrorRate() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Sent-r.Succeeded) / float64(r.Sent)
+}
+
+// Run sends searches until opts.Duration has passed or ctx ends, waits for
+// the searches still in flight and reports
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.QPS <= 0 {
+		return nil, errors.New("qps must be above zero")
+	}
+	if opts.Duration <= 0 {
+		return nil, errors.New("duration must be above zero")
+	}
+	searchURL, err := url.Parse(strings.TrimSuffix(opts.BaseURL, "/") + "/api/v1/search")
+	if err != nil || (searchURL.Scheme != "http" && searchURL.Scheme != "https") || searchURL.Host == "" {
+		return nil, fmt.Errorf("invalid instance URL %q", opts.BaseURL)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = defaultMaxInFlight
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: opts.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: opts.MaxInFlight,
+				IdleConnTimeout:     30 * time.Second,
+			},
+		}
+	}
+
+	r := &run{opts: opts, client: client, searchURL: searchURL, report: &Report{Status: make(map[int]int)}}
+	return r.run(ctx), nil
+}
+
+// run is the state of one Run
+type run struct {
+	opts      Options
+	client    *http.Client
+	searchURL *url.URL
+
+	mu        sync.Mutex
+	report    *Report
+	latencies []time.Duration
+	completed int
+}
+
+func (r *run) run(ctx context.Context) *Report {
+	sampleCtx, stopSampling := context.WithCancel(ctx)
+	samplingDone := make(chan struct{})
+	if r.opts.Sample != nil {
+		r.report.Sampled = true
+		r.report.UsageStart = r.sample(ctx)
+		r.report.UsagePeak = r.report.UsageStart
+	}
+	go r.monitor(sampleCtx, samplingDone)
+
+	start := time.Now()
+	interval := time.Duration(float64(time.Second) / r.opts.QPS)
+	runCtx, cancel := context.WithTimeout(ctx, r.opts.Duration)
+	defer cancel()
+
+	inFlight := make(chan struct{}, r.opts.MaxInFlight)
+	var wg sync.WaitGroup
+	// Searches are scheduled from the start time rather than the previous
+	// tick, so a slow loop iteration does not lower the rate
+	for n := 0; ; n++ {
+		due := start.Add(time.Duration(n) * interval)
+		if wait := time.Until(due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-runCtx.Done():
+				timer.Stop()
+			}
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			r.mu.Lock()
+			r.report.Dropped++
+			r.mu.Unlock()
+			continue
+		}
+		r.mu.Lock()
+		r.report.Sent++
+		r.mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			r.search(ctx)
+		}()
+	}
+	wg.Wait()
+	r.report.Elapsed = time.Since(start)
+
+	stopSampling()
+	<-samplingDone
+	if r.opts.Sample != nil {
+		r.report.UsageEnd = r.sample(ctx)
+		r.report.UsagePeak = peak(r.report.UsagePeak, r.report.UsageEnd)
+	}
+
+	r.report.Latency = summarize(r.latencies)
+	if secs := r.report.Elapsed.Seconds(); secs > 0 {
+		r.report.Throughput = float64(r.report.Succeeded) / secs
+	}
+	return r.report
+}
+
+// monitor samples resource usage and reports progress once a second until
+// ctx ends
+func (r *run) monitor(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if r.opts.Sample != nil {
+			usage := r.sample(ctx)
+			r.mu.Lock()
+			r.report.UsagePeak = peak(r.report.UsagePeak, usage)
+			r.mu.Unlock()
+		}
+		if r.opts.Progress != nil {
+			r.mu.Lock()
+			sent, completed := r.report.Sent, r.completed
+			r.mu.Unlock()
+			r.opts.Progress(sent, completed)
+		}
+	}
+}
+
+func (r *run) sample(ctx context.Context) Usage {
+	usage, err := r.opts.Sample(ctx)
+	if err != nil {
+		return Usage{}
+	}
+	return usage
+}
+
+// search sends one search and records its outcome
+func (r *run) search(ctx context.Context) {
+	u := *r.searchURL
+	u.RawQuery = url.Values{"q": {r.query()}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		r.record(0, 0)
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "search-loadtest")
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.record(0, 0)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	r.record(resp.StatusCode, time.Since(start))
+}
+
+func (r *run) query() string {
+	if len(r.opts.Queries) > 0 {
+		return r.opts.Queries[rand.N(len(r.opts.Queries))]
+	}
+	return DefaultQueries[rand.N(len(DefaultQueries))] + " " + DefaultQueries[rand.N(len(DefaultQueries))]
+}
+
+// record counts an answer; status 0 is a search that got none
+func (r *run) record(status int, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.completed++
+	if status == 0 {
+		r.report.Errors++
+		return
+	}
+	r.report.Status[status]++
+	if status >= 200 && status < 300 {
+		r.report.Succeeded++
+	}
+	r.latencies = append(r.latencies, latency)
+}
+
+// peak keeps the higher goroutine and heap readings, and the later CPU time
+func peak(a, b Usage) Usage {
+	if b.Goroutines > a.Goroutines {
+		a.Goroutines = b.Goroutines
+	}
+	if b.HeapBytes > a.HeapBytes {
+		a.HeapBytes = b.HeapBytes
+	}
+	if b.CPU > a.CPU {
+		a.CPU = b.CPU
+	}
+	return a
+}
+
+// summarize computes the latency percentiles by nearest rank
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return Latency{
+		Mean: total / time.Duration(len(sorted)),
+		P50:  rank(0.50),
+		P90:  rank(0.90),
+		P95:  rank(0.95),
+		P99:  rank(0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,147 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var searches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/search" || r.URL.Query().Get("q") == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if searches.Add(1)%5 == 0 {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	var samples atomic.Int32
+	report, err := Run(context.Background(), Options{
+		BaseURL:  srv.URL,
+		QPS:      100,
+		Duration: 1500 * time.Millisecond,
+		Queries:  []string{"golang"},
+		Sample: func(context.Context) (Usage, error) {
+			n := samples.Add(1)
+			return Usage{Goroutines: int(n), HeapBytes: uint64(100 - n)}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Sent < 140 || report.Sent > 151 {
+		t.Errorf("Sent = %d, want about 150 at 100 qps for 1.5s", report.Sent)
+	}
+	if got := report.Status[200] + report.Status[429]; got != report.Sent || report.Succeeded != report.Status[200] {
+		t.Errorf("Status = %v, Succeeded = %d for %d sent", report.Status, report.Succeeded, report.Sent)
+	}
+	if rate := report.ErrorRate(); rate < 0.15 || rate > 0.25 {
+		t.Errorf("ErrorRate() = %.2f, want the 1 in 5 answered 429", rate)
+	}
+	if l := report.Latency; l.P50 < 10*time.Millisecond || l.P50 > l.P99 || l.P99 > l.Max {
+		t.Errorf("Latency = %+v", l)
+	}
+	// Start, at least one sample during the run, and end
+	if !report.Sampled || report.UsageStart.Goroutines != 1 || report.UsagePeak.Goroutines < 3 ||
+		report.UsagePeak.HeapBytes != 99 || report.UsageEnd.Goroutines != report.UsagePeak.Goroutines {
+		t.Errorf("usage start %+v, peak %+v, end %+v", report.UsageStart, report.UsagePeak, report.UsageEnd)
+	}
+}
+
+func TestRunCountsUnansweredAndDropped(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	report, err := Run(context.Background(), Options{
+		BaseURL:     srv.URL,
+		QPS:         50,
+		Duration:    500 * time.Millisecond,
+		Timeout:     200 * time.Millisecond,
+		MaxInFlight: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Errors != report.Sent || report.Succeeded != 0 || report.Dropped == 0 {
+		t.Errorf("Sent %d, Errors %d, Dropped %d; want every sent search unanswered and some dropped",
+			report.Sent, report.Errors, report.Dropped)
+	}
+}
+
+func TestRunValidatesOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{BaseURL: "http://127.0.0.1:1", Duration: time.Second},
+		{BaseURL: "http://127.0.0.1:1", QPS: 1},
+		{BaseURL: "127.0.0.1:1", QPS: 1, Duration: time.Second},
+	} {
+		if _, err := Run(context.Background(), opts); err == nil {
+			t.Errorf("Run(%+v) error = nil", opts)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	l := summarize(latencies)
+	want := Latency{
+		Mean: 50500 * time.Microsecond,
+		P50:  50 * time.Millisecond,
+		P90:  90 * time.Millisecond,
+		P95:  95 * time.Millisecond,
+		P99:  99 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}
+	if l != want {
+		t.Errorf("summarize() = %+v, want %+v", l, want)
+	}
+	if (summarize(nil) != Latency{}) {
+		t.Error("summarize(nil) is not zero")
+	}
+}
+
+func TestRemoteUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok":true,"data":{"system":{"num_goroutine":42,"mem_alloc":"12.5 MB"}}}`)
+	}))
+	defer srv.Close()
+
+	usage, err := RemoteUsage(srv.Client(), srv.URL+"/")(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Goroutines != 42 || usage.HeapBytes != 12.5*1024*1024 {
+		t.Errorf("RemoteUsage() = %+v", usage)
+	}
+
+	for in, want := range map[string]uint64{"512 B": 512, "1.0 KB": 1024, "2.0 GB": 2 << 30, "": 0, "lots": 0, "3 XB": 0} {
+		if got := parseSize(in); got != want {
+			t.Errorf("parseSize(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestProcessUsage(t *testing.T) {
+	usage, _ := ProcessUsage(context.Background())
+	if usage.Goroutines == 0 || usage.HeapBytes == 0 {
+		t.Errorf("ProcessUsage() = %+v", usage)
+	}
+}
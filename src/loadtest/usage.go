@@ -0,0 +1,86 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessUsage reads this process's resource usage, for an instance run in
+// the same process as the load. Its CPU time includes sending the load.
+func ProcessUsage(context.Context) (Usage, error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return Usage{
+		Goroutines: runtime.NumGoroutine(),
+		HeapBytes:  m.HeapAlloc,
+		CPU:        processCPU(),
+	}, nil
+}
+
+// RemoteUsage reads an instance's goroutines and memory from its
+// /api/v1/info endpoint. The instance reports no CPU time.
+func RemoteUsage(client *http.Client, baseURL string) func(ctx context.Context) (Usage, error) {
+	infoURL := strings.TrimSuffix(baseURL, "/") + "/api/v1/info"
+	return func(ctx context.Context) (Usage, error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, infoURL, nil)
+		if err != nil {
+			return Usage{}, err
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return Usage{}, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return Usage{}, fmt.Errorf("%s: %s", infoURL, resp.Status)
+		}
+		var info struct {
+			Data struct {
+				System struct {
+					NumGoroutine int    `json:"num_goroutine"`
+					MemAlloc     string `json:"mem_alloc"`
+				} `json:"system"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return Usage{}, err
+		}
+		return Usage{
+			Goroutines: info.Data.System.NumGoroutine,
+			HeapBytes:  parseSize(info.Data.System.MemAlloc),
+		}, nil
+	}
+}
+
+// parseSize reads the "12.5 MB" sizes of /api/v1/info (binary units), or
+// returns 0
+func parseSize(s string) uint64 {
+	number, unit, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return 0
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	exp := strings.Index("BKMGTPE", strings.TrimSuffix(unit, "B"))
+	if unit == "B" {
+		exp = 0
+	}
+	if exp < 0 {
+		return 0
+	}
+	for ; exp > 0; exp-- {
+		value *= 1024
+	}
+	return uint64(value)
+}
@@ -2,10 +2,14 @@ package logging
 
 import (
 	"bufio"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -167,7 +171,11 @@ type FormatVariable struct {
 // Privacy: identifying variables ($remote_addr, $query_string,
 // $http_referer, $http_user_agent, $http_host, $http_x_forwarded_for,
 // $http_x_real_ip) are intentionally excluded — privacy is the product.
+// $remote_network and $user_agent_hash are "-" unless the anonymized
+// privacy preset is on.
 var AvailableFormatVariables = []FormatVariable{
+	{"$remote_network", "Client network: the /24 or /48 of its address", "203.0.113.0"},
+	{"$user_agent_hash", "Salted hash of the user agent", "ua-3f2a9c1b7e40"},
 	{"$remote_user", "Client user name (from auth)", "-"},
 	{"$time_local", "Local time in Common Log Format", "02/Jan/2006:15:04:05 -0700"},
 	{"$time_iso8601", "ISO 8601 time format", "2006-01-02T15:04:05-07:00"},
@@ -191,15 +199,33 @@ var AvailableFormatVariables = []FormatVariable{
 	{"$pid", "Process ID", "12345"},
 }
 
+// Access log privacy presets
+const (
+	// PrivacyStrict records no client address or user agent
+	PrivacyStrict = "strict"
+	// PrivacyAnonymized records the client address truncated to its
+	// network (/24 for IPv4, /48 for IPv6) and a salted hash of the user
+	// agent. The salt is new on every start, so hashes only group the
+	// requests of one run.
+	PrivacyAnonymized = "anonymized"
+)
+
 // AccessLogger logs HTTP access in Combined Log Format
 type AccessLogger struct {
 	mu   sync.Mutex
 	file *os.File
 	path string
-	// "combined", "common", "json", "custom"
+	// "combined", "common", "json", "custom"; "apache" and "nginx" are
+	// combined and common
 	format string
 	// Custom format string with variables
 	customFormat string
+	// PrivacyStrict or PrivacyAnonymized
+	privacy string
+	// disabled writes nothing
+	disabled bool
+	// uaSalt keys the user agent hashes of PrivacyAnonymized
+	uaSalt []byte
 }
 
 // AccessEntry represents an access log entry with all fields for custom formatting
@@ -225,14 +251,22 @@ type AccessEntry struct {
 	SSLCipher          string    `json:"ssl_cipher,omitempty"`
 	Connection         int64     `json:"connection,omitempty"`
 	ConnectionRequests int       `json:"connection_requests,omitempty"`
+	// Network and UserAgentHash are the anonymized client, for the
+	// $remote_network and $user_agent_hash variables; IP and UserAgent
+	// carry the same values in the fixed formats
+	Network       string `json:"-"`
+	UserAgentHash string `json:"-"`
 }
 
 // NewAccessLogger creates a new access logger
 func NewAccessLogger(path string) *AccessLogger {
 	l := &AccessLogger{
-		path:   path,
-		format: "combined",
+		path:    path,
+		format:  "combined",
+		privacy: PrivacyStrict,
+		uaSalt:  make([]byte, 16),
 	}
+	rand.Read(l.uaSalt)
 	l.openFile()
 	return l
 }
@@ -257,6 +291,9 @@ func (l *AccessLogger) openFile() error {
 func (l *AccessLogger) Log(entry AccessEntry) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.disabled {
+		return
+	}
 
 	var line string
 
@@ -264,7 +301,7 @@ func (l *AccessLogger) Log(entry AccessEntry) {
 	case "json":
 		data, _ := json.Marshal(entry)
 		line = string(data)
-	case "common":
+	case "common", "nginx":
 		// Common Log Format: host ident authuser date request status bytes
 		line = fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d",
 			entry.IP,
@@ -294,7 +331,7 @@ func (l *AccessLogger) Log(entry AccessEntry) {
 			entry.Status,
 			entry.Size,
 			referer,
-			entry.UserAgent,
+			orDash(entry.UserAgent),
 		)
 	}
 
@@ -331,6 +368,8 @@ func (l *AccessLogger) formatWithVariables(entry AccessEntry) string {
 	// $http_x_forwarded_for, $http_x_real_ip) are omitted so they cannot
 	// appear in any configured format string.
 	replacements := map[string]string{
+		"$remote_network":      orDash(entry.Network),
+		"$user_agent_hash":     orDash(entry.UserAgentHash),
 		"$remote_user":         orDash(entry.RemoteUser),
 		"$time_local":          entry.Timestamp.Format("02/Jan/2006:15:04:05 -0700"),
 		"$time_iso8601":        entry.Timestamp.Format(time.RFC3339),
@@ -395,44 +434,23 @@ func getHostname() string {
 // operational fields needed for monitoring (method, path, status, size,
 // latency) and omit identifying data entirely.
 func (l *AccessLogger) LogRequest(r *http.Request, status int, size int64, latency time.Duration) {
-	protocol := fmt.Sprintf("HTTP/%d.%d", r.ProtoMajor, r.ProtoMinor)
-
-	// Get TLS info if available (transport-level, not identifying)
-	sslProtocol := ""
-	sslCipher := ""
-	if r.TLS != nil {
-		switch r.TLS.Version {
-		case 0x0301:
-			sslProtocol = "TLSv1.0"
-		case 0x0302:
-			sslProtocol = "TLSv1.1"
-		case 0x0303:
-			sslProtocol = "TLSv1.2"
-		case 0x0304:
-			sslProtocol = "TLSv1.3"
-		}
-		sslCipher = tlsCipherSuiteName(r.TLS.CipherSuite)
-	}
-
-	l.Log(AccessEntry{
-		Timestamp:   time.Now(),
-		IP:          "-",
-		Method:      r.Method,
-		Path:        r.URL.Path,
-		Protocol:    protocol,
-		Status:      status,
-		Size:        size,
-		BytesSent:   size + estimateHeaderSize(status),
-		Latency:     latency.Milliseconds(),
-		SSLProtocol: sslProtocol,
-		SSLCipher:   sslCipher,
-	})
+	l.LogRequestFrom(r, "", "", status, size, latency)
 }
 
 // LogRequestWithID logs an HTTP request with a request ID.
 // Privacy: same posture as LogRequest — no IPs, query strings, referers,
 // user-agents, or proxy-supplied identifiers are recorded.
 func (l *AccessLogger) LogRequestWithID(r *http.Request, status int, size int64, latency time.Duration, requestID string) {
+	l.LogRequestFrom(r, "", requestID, status, size, latency)
+}
+
+// LogRequestFrom logs an HTTP request from clientIP, the address resolved
+// through trusted proxies. The address and user agent are recorded only as
+// the privacy preset allows: not at all under PrivacyStrict, truncated and
+// hashed under PrivacyAnonymized. A request without clientIP, such as one
+// that opted out of tracking, is recorded as under PrivacyStrict. The
+// query string is never recorded.
+func (l *AccessLogger) LogRequestFrom(r *http.Request, clientIP, requestID string, status int, size int64, latency time.Duration) {
 	protocol := fmt.Sprintf("HTTP/%d.%d", r.ProtoMajor, r.ProtoMinor)
 
 	// Get TLS info if available (transport-level, not identifying)
@@ -452,22 +470,54 @@ func (l *AccessLogger) LogRequestWithID(r *http.Request, status int, size int64,
 		sslCipher = tlsCipherSuiteName(r.TLS.CipherSuite)
 	}
 
+	network, uaHash := "", ""
+	if l.Privacy() == PrivacyAnonymized && clientIP != "" {
+		network = TruncateIP(clientIP)
+		uaHash = l.hashUserAgent(r.UserAgent())
+	}
+
 	l.Log(AccessEntry{
-		Timestamp:   time.Now(),
-		IP:          "-",
-		Method:      r.Method,
-		Path:        r.URL.Path,
-		Protocol:    protocol,
-		Status:      status,
-		Size:        size,
-		BytesSent:   size + estimateHeaderSize(status),
-		Latency:     latency.Milliseconds(),
-		RequestID:   requestID,
-		SSLProtocol: sslProtocol,
-		SSLCipher:   sslCipher,
+		Timestamp:     time.Now(),
+		IP:            orDash(network),
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Protocol:      protocol,
+		Status:        status,
+		Size:          size,
+		BytesSent:     size + estimateHeaderSize(status),
+		UserAgent:     uaHash,
+		Latency:       latency.Milliseconds(),
+		RequestID:     requestID,
+		SSLProtocol:   sslProtocol,
+		SSLCipher:     sslCipher,
+		Network:       network,
+		UserAgentHash: uaHash,
 	})
 }
 
+// TruncateIP returns the network of ip: the /24 of an IPv4 address and the
+// /48 of an IPv6 one, or "" for anything that is not an address
+func TruncateIP(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// hashUserAgent returns a short salted hash of ua, or "" for none
+func (l *AccessLogger) hashUserAgent(ua string) string {
+	if ua == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, l.uaSalt)
+	mac.Write([]byte(ua))
+	return "ua-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
 // estimateHeaderSize estimates the response header size
 func estimateHeaderSize(status int) int64 {
 	// Rough estimate: status line + common headers
@@ -530,6 +580,45 @@ func (l *AccessLogger) Close() error {
 	return nil
 }
 
+// SetPrivacy sets the privacy preset, PrivacyStrict or PrivacyAnonymized;
+// anything else is PrivacyStrict
+func (l *AccessLogger) SetPrivacy(privacy string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if privacy != PrivacyAnonymized {
+		privacy = PrivacyStrict
+	}
+	l.privacy = privacy
+}
+
+// Privacy returns the privacy preset
+func (l *AccessLogger) Privacy() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.privacy
+}
+
+// SetEnabled turns access logging on or off; off writes nothing at all
+func (l *AccessLogger) SetEnabled(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.disabled = !enabled
+}
+
+// Size returns the size of the current access log file in bytes
+func (l *AccessLogger) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return 0
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
 // SetFormat sets the log format
 func (l *AccessLogger) SetFormat(format string) {
 	l.mu.Lock()
@@ -1115,6 +1204,23 @@ const (
 	AuditActionSchedulerTaskFail  AuditAction = "scheduler.task_failed"
 	AuditActionSchedulerTaskRun   AuditAction = "scheduler.task_manual_run"
 
+	// Data-subject requests: the subject is recorded as a hash, never the email
+	AuditActionSubjectExported AuditAction = "data.subject_exported"
+	AuditActionSubjectErased   AuditAction = "data.subject_erased"
+
+	// Retention events: data deleted for being over a server.retention limit
+	AuditActionRetentionPurged AuditAction = "data.retention_purged"
+
+	// Result moderation: reported results and the result blocklist
+	AuditActionReportResolved AuditAction = "moderation.report_resolved"
+	AuditActionResultRuleAdd  AuditAction = "moderation.rule_added"
+	AuditActionResultRuleDel  AuditAction = "moderation.rule_removed"
+
+	// Custom bangs: stored in the server DB by the operator
+	AuditActionBangSaved     AuditAction = "bangs.saved"
+	AuditActionBangDeleted   AuditAction = "bangs.deleted"
+	AuditActionBangsImported AuditAction = "bangs.imported"
+
 	// PGP keypair events (AI.md PART 11 "GPG Keypair Management")
 	AuditActionPGPKeyGenerated     AuditAction = "security.pgp_key_generated"
 	AuditActionPGPKeyRotated       AuditAction = "security.pgp_key_rotated"
@@ -1771,6 +1877,105 @@ func (l *AuditLogger) LogBackupDelete(actor, ip, filename string) {
 	})
 }
 
+// LogSubjectExported logs an operator export of a data subject's records.
+// subjectHash identifies the subject without keeping their email.
+func (l *AuditLogger) LogSubjectExported(actor, ip, subjectHash string, records int) {
+	l.Log(AuditEntry{
+		Event:    AuditActionSubjectExported,
+		Category: AuditCategoryData,
+		Severity: AuditSeverityInfo,
+		Actor:    AuditActor{Username: actor, IP: ip},
+		Target:   &AuditTarget{Type: "data_subject", ID: subjectHash},
+		Result:   "success",
+		Details:  map[string]interface{}{"alerts": records},
+	})
+}
+
+// LogSubjectErased logs the irreversible erasure of a data subject's records
+func (l *AuditLogger) LogSubjectErased(actor, ip, subjectHash string, records int) {
+	l.Log(AuditEntry{
+		Event:    AuditActionSubjectErased,
+		Category: AuditCategoryData,
+		Severity: AuditSeverityWarning,
+		Actor:    AuditActor{Username: actor, IP: ip},
+		Target:   &AuditTarget{Type: "data_subject", ID: subjectHash},
+		Result:   "success",
+		Details:  map[string]interface{}{"alerts": records},
+	})
+}
+
+// LogRetentionPurged logs data the retention task deleted from one class
+func (l *AuditLogger) LogRetentionPurged(class string, files int, bytes, rows int64) {
+	l.Log(AuditEntry{
+		Event:    AuditActionRetentionPurged,
+		Category: AuditCategoryData,
+		Severity: AuditSeverityInfo,
+		Actor:    AuditActor{Type: "system", Username: "scheduler"},
+		Target:   &AuditTarget{Type: "retention", Name: class},
+		Result:   "success",
+		Details:  map[string]interface{}{"files": files, "bytes": bytes, "rows": rows},
+	})
+}
+
+// LogReportResolved logs an operator closing a reported result
+func (l *AuditLogger) LogReportResolved(actor, ip string, reportID int64, action string) {
+	l.Log(AuditEntry{
+		Event:    AuditActionReportResolved,
+		Category: AuditCategoryConfig,
+		Severity: AuditSeverityInfo,
+		Actor:    AuditActor{Username: actor, IP: ip},
+		Target:   &AuditTarget{Type: "result_report", ID: fmt.Sprint(reportID)},
+		Result:   "success",
+		Details:  map[string]interface{}{"action": action},
+	})
+}
+
+// LogResultRuleChanged logs a result blocklist rule being added or removed
+func (l *AuditLogger) LogResultRuleChanged(actor, ip string, added bool, kind, pattern string) {
+	event := AuditActionResultRuleDel
+	if added {
+		event = AuditActionResultRuleAdd
+	}
+	l.Log(AuditEntry{
+		Event:    event,
+		Category: AuditCategoryConfig,
+		Severity: AuditSeverityInfo,
+		Actor:    AuditActor{Username: actor, IP: ip},
+		Target:   &AuditTarget{Type: "result_rule", Name: pattern},
+		Result:   "success",
+		Details:  map[string]interface{}{"kind": kind},
+	})
+}
+
+// LogBangChanged logs a custom bang being saved or deleted
+func (l *AuditLogger) LogBangChanged(actor, ip string, saved bool, shortcut string) {
+	event := AuditActionBangDeleted
+	if saved {
+		event = AuditActionBangSaved
+	}
+	l.Log(AuditEntry{
+		Event:    event,
+		Category: AuditCategoryConfig,
+		Severity: AuditSeverityInfo,
+		Actor:    AuditActor{Username: actor, IP: ip},
+		Target:   &AuditTarget{Type: "bang", Name: shortcut},
+		Result:   "success",
+	})
+}
+
+// LogBangsImported logs an import of custom bangs
+func (l *AuditLogger) LogBangsImported(actor, ip string, imported, skipped int, replace bool) {
+	l.Log(AuditEntry{
+		Event:    AuditActionBangsImported,
+		Category: AuditCategoryConfig,
+		Severity: AuditSeverityInfo,
+		Actor:    AuditActor{Username: actor, IP: ip},
+		Target:   &AuditTarget{Type: "bang"},
+		Result:   "success",
+		Details:  map[string]interface{}{"imported": imported, "skipped": skipped, "replace": replace},
+	})
+}
+
 // LogBackupFailed logs backup failure
 func (l *AuditLogger) LogBackupFailed(actor, ip, reason string) {
 	l.Log(AuditEntry{
@@ -236,6 +236,72 @@ func TestAccessLoggerLogRequest(t *testing.T) {
 	logger.LogRequest(req, 200, 1024, 50*time.Millisecond)
 }
 
+func TestAccessLoggerPrivacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "privacy.log")
+	logger := NewAccessLogger(path)
+	defer logger.Close()
+	logger.SetFormat("apache")
+
+	req := &http.Request{
+		Method:     "GET",
+		URL:        &url.URL{Path: "/search", RawQuery: "q=secret+terms"},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"User-Agent": []string{"TestAgent/1.0"}, "Referer": []string{"https://ref.example/"}},
+	}
+	logger.LogRequestFrom(req, "203.0.113.77", "", 200, 10, time.Millisecond)
+	logger.SetPrivacy(PrivacyAnonymized)
+	logger.LogRequestFrom(req, "203.0.113.77", "", 200, 10, time.Millisecond)
+	logger.SetCustomFormat("$remote_network $user_agent_hash $status")
+	logger.LogRequestFrom(req, "2001:db8:1234:5678::1", "", 404, 10, time.Millisecond)
+	logger.LogRequestFrom(req, "", "", 200, 10, time.Millisecond)
+	logger.SetEnabled(false)
+	logger.LogRequestFrom(req, "203.0.113.77", "", 500, 10, time.Millisecond)
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("wrote %d lines, want 4 (none while disabled):\n%s", len(lines), data)
+	}
+	for _, leak := range []string{"203.0.113.77", "TestAgent", "secret", "ref.example", "2001:db8:1234:5678::1"} {
+		if strings.Contains(string(data), leak) {
+			t.Errorf("access log leaks %q:\n%s", leak, data)
+		}
+	}
+	if !strings.HasPrefix(lines[0], "- - - [") || !strings.HasSuffix(lines[0], `"-" "-"`) {
+		t.Errorf("strict line = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "203.0.113.0 - - [") || !strings.Contains(lines[1], `"ua-`) {
+		t.Errorf("anonymized line = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 3 || fields[0] != "2001:db8:1234::" || !strings.HasPrefix(fields[1], "ua-") {
+		t.Errorf("custom anonymized line = %q", lines[2])
+	}
+	if lines[3] != "- - 200" {
+		t.Errorf("opted-out anonymized line = %q", lines[3])
+	}
+	if logger.hashUserAgent("TestAgent/1.0") != logger.hashUserAgent("TestAgent/1.0") || logger.hashUserAgent("") != "" {
+		t.Error("user agent hashes are not stable within a run")
+	}
+}
+
+func TestTruncateIP(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.200":       "192.168.1.0",
+		"::ffff:10.1.2.3":     "10.1.2.0",
+		"2001:db8:abcd:12::5": "2001:db8:abcd::",
+		"not an address":      "",
+		"":                    "",
+		"192.168.1.200:8080":  "",
+	}
+	for in, want := range tests {
+		if got := TruncateIP(in); got != want {
+			t.Errorf("TruncateIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 func TestAccessLoggerRotate(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "rotate.log")
@@ -3,19 +3,20 @@ package main
 import (
 	"context"
 	cryptoRand "crypto/rand"
-	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -25,15 +26,20 @@ import (
 	"github.com/apimgr/search/src/backup"
 	"github.com/apimgr/search/src/common/banner"
 	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/common/qr"
+	"github.com/apimgr/search/src/common/terminal"
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/mode"
 	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/release"
 	"github.com/apimgr/search/src/search"
 	"github.com/apimgr/search/src/search/engine"
+	"github.com/apimgr/search/src/security"
 	"github.com/apimgr/search/src/server"
 	"github.com/apimgr/search/src/service"
 	sigsvc "github.com/apimgr/search/src/signal"
 	"github.com/apimgr/search/src/update"
+	"github.com/apimgr/search/src/version"
 
 	_ "modernc.org/sqlite"
 )
@@ -43,7 +49,10 @@ var (
 	flagVersion     bool
 	flagHelp        bool
 	flagInit        bool
+	flagNonInteract bool
+	flagPreset      string
 	flagConfigInfo  bool
+	flagConfigDocs  bool
 	flagStatus      bool
 	flagDaemon      bool
 	flagDebug       bool
@@ -53,6 +62,7 @@ var (
 	flagUpdate      string
 	flagBuild       string
 	flagShell       string
+	flagObserv      string
 
 	// Required flags per AI.md PART 6 (NON-NEGOTIABLE)
 	flagMode    string
@@ -80,7 +90,10 @@ func init() {
 	flag.BoolVar(&flagHelp, "help", false, "Show help message")
 	flag.BoolVar(&flagHelp, "h", false, "Show help message (shorthand)")
 	flag.BoolVar(&flagInit, "init", false, "Initialize configuration")
+	flag.BoolVar(&flagNonInteract, "non-interactive", false, "With --init: write the default configuration without prompting")
+	flag.StringVar(&flagPreset, "preset", "", "With --init: apply a configuration preset (privacy-max|family-safe|developer|intranet)")
 	flag.BoolVar(&flagConfigInfo, "config-info", false, "Show configuration paths and status")
+	flag.BoolVar(&flagConfigDocs, "config-docs", false, "Print the server.yml reference (markdown, or json)")
 	flag.BoolVar(&flagStatus, "status", false, "Show server status")
 	flag.BoolVar(&flagDaemon, "daemon", false, "Daemonize (detach from terminal)")
 	flag.BoolVar(&flagDebug, "debug", false, "Enable debug mode (verbose logging, debug endpoints)")
@@ -92,6 +105,7 @@ func init() {
 	flag.StringVar(&flagUpdate, "update", "", "Update management: check|yes|branch")
 	flag.StringVar(&flagBuild, "build", "", "Build for platforms: all|linux|darwin|windows|freebsd")
 	flag.StringVar(&flagShell, "shell", "", "Shell integration: completions|init|--help")
+	flag.StringVar(&flagObserv, "observability", "", "Monitoring assets: export|rules|dashboard|--help")
 
 	// Configuration override flags (NON-NEGOTIABLE per AI.md PART 6)
 	flag.StringVar(&flagMode, "mode", "", "Set application mode (production|development)")
@@ -116,8 +130,14 @@ func main() {
 		printHelp()
 	}
 
-	// Parse flags
-	flag.Parse()
+	// Parse flags. --test fuzz, --test load, --test quality and --test
+	// security read their own options (runFuzz, runLoad, runQuality,
+	// runSecurityTest), which the global flag set would reject.
+	args := os.Args[1:]
+	if len(args) > 1 && args[0] == "--test" && (args[1] == "fuzz" || args[1] == "load" || args[1] == "quality" || args[1] == "security") {
+		args = args[:2]
+	}
+	flag.CommandLine.Parse(args)
 
 	// Initialize display output (colors and emojis) based on NO_COLOR, TERM, and --color flag
 	// Per AI.md PART 8: Must be called early, before any output
@@ -135,11 +155,22 @@ func main() {
 		printHelp()
 		return
 	case flagInit:
+		if flag.Arg(0) == "docker-compose" {
+			runInitDockerCompose(flag.Arg(1))
+			return
+		}
+		if flag.Arg(0) == "policy" {
+			runInitPolicy()
+			return
+		}
 		runInit()
 		return
 	case flagConfigInfo:
 		showConfigInfo()
 		return
+	case flagConfigDocs:
+		showConfigDocs(flag.Arg(0))
+		return
 	case flagStatus:
 		showStatus()
 		return
@@ -176,6 +207,13 @@ func main() {
 		}
 		runShell(subCmd)
 		return
+	case flagObserv != "" || (len(os.Args) > 1 && os.Args[1] == "--observability"):
+		subCmd := flagObserv
+		if subCmd == "" {
+			subCmd = "--help"
+		}
+		runObservability(subCmd, flag.Arg(0))
+		return
 	}
 
 	// Handle legacy argument style (for backwards compatibility)
@@ -290,6 +328,12 @@ func handleLegacyArgs() {
 		runInit()
 	case "--config-info":
 		showConfigInfo()
+	case "--config-docs":
+		format := ""
+		if len(os.Args) > 2 {
+			format = os.Args[2]
+		}
+		showConfigDocs(format)
 	case "--status":
 		showStatus()
 	case "--service":
@@ -322,6 +366,16 @@ func handleLegacyArgs() {
 			subCmd = os.Args[2]
 		}
 		runShell(subCmd)
+	case "--observability":
+		subCmd := "--help"
+		dir := ""
+		if len(os.Args) > 2 {
+			subCmd = os.Args[2]
+		}
+		if len(os.Args) > 3 {
+			dir = os.Args[3]
+		}
+		runObservability(subCmd, dir)
 	case "--daemon":
 		// Per AI.md PART 8: Only -h and -v may have short flags
 		flagDaemon = true
@@ -503,6 +557,7 @@ func printVersion() {
 	//   Built: {BUILD_DATE}
 	//   Go: {GO_VERSION}
 	//   OS/Arch: {GOOS}/{GOARCH}
+	//   Features: {+feature|-feature ...}
 	// Note: No v prefix in version string
 	binaryName := filepath.Base(os.Args[0])
 
@@ -510,6 +565,8 @@ func printVersion() {
 	fmt.Printf("Built: %s\n", config.BuildDate)
 	fmt.Printf("Go: %s\n", runtime.Version())
 	fmt.Printf("OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	// Optional features left out by build tags (e.g. -tags notor) show as -name
+	fmt.Printf("Features: %s\n", version.FeatureString())
 }
 
 func printHelp() {
@@ -543,15 +600,36 @@ Information:
   --version, -v            Show version information
   --status                 Show server status and health
   --config-info            Show configuration paths and status
+  --config-docs [json]     Print every server.yml setting with its type, default and env var
 
 Shell Integration:
   --shell completions [SHELL]  Print shell completions script
   --shell init [SHELL]         Print shell init command for eval
   --shell --help               Show shell help
 
+Observability:
+  --observability export [dir] Write Prometheus alert rules and a Grafana dashboard
+  --observability rules        Print the Prometheus alert rules
+  --observability dashboard    Print the Grafana dashboard JSON
+
 Setup:
-  --init                   Initialize configuration
+  --init                   Initialize configuration (asks on first run in a terminal)
+  --non-interactive        With --init: write defaults without asking
+  --preset <name>          With --init: preview and apply a preset
+                           (privacy-max, family-safe, developer, intranet)
+  --init docker-compose [file]  Write a docker-compose.yml for the container image
+  --init policy            Add starter privacy policy and terms pages (markdown)
   --test [query]           Test search engines with optional query
+  --test fuzz [target]     Fuzz the engine and query parsers (source checkout,
+                           Docker; --fuzztime 10m per target, --list)
+  --test load              Send synthetic searches and report latency and
+                           resource usage (--qps 10, --duration 1m,
+                           --engines mock|real, --url, --queries FILE)
+  --test quality           Check where expected domains rank for the queries
+                           in quality.yml (--suite FILE, --url, --baseline
+                           FILE to report regressions, --save FILE)
+  --test security          Check that tokens are compared in constant time and
+                           that rejected credentials answer in uniform time
 
 Service Management:
   --service <action>       Service management (requires privileges):
@@ -571,6 +649,9 @@ Maintenance:
                            Use BACKUP_PASSWORD env var for encryption
     restore <file>         Restore from backup
                            Use BACKUP_PASSWORD env var if encrypted
+    verify <file>          Check a backup's checksums without restoring
+    bootstrap <s3-url>     Download, restore, install and start on a new host
+    backup-keygen <file>   Write a key pair for public-key backups
     update                 Alias for --update yes
     mode                   Toggle maintenance mode
     setup                  Reset configuration to defaults
@@ -593,7 +674,10 @@ Build:
     windows                Build for Windows (amd64, arm64)
     freebsd                Build for FreeBSD (amd64, arm64)
     host                   Build for current OS/ARCH only
+    docker                 Build a distroless container image of this version
     linux/amd64            Build for specific OS/ARCH
+                           Writes SPDX/CycloneDX SBOMs and provenance next to
+                           each binary; SOURCE_DATE_EPOCH fixes the build date
 
 Environment Variables:
   SEARCH_SETTINGS_PATH     Path to configuration file
@@ -609,6 +693,8 @@ Environment Variables:
   NO_COLOR                 Disable colors when set (standard)
   DISABLE_TOR              Disable Tor (auto-enabled if tor binary installed)
   BACKUP_PASSWORD          Password for backup encryption (AES-256-GCM)
+  BACKUP_KEYFILE           Keyfile required with the backup password
+  BACKUP_IDENTITY          Private key file for restoring public-key backups
 
 Examples:
   %s                                 Start server with defaults
@@ -617,18 +703,23 @@ Examples:
   %s --config /etc/search --data /var/lib/search  Custom directories
   %s --init                          Create configuration files
   %s --test "golang"                 Test search with "golang" query
+  %s --test fuzz google --fuzztime 1h  Fuzz the Google parser for an hour
+  %s --test load --qps 50 --duration 2m  Load test with synthetic engines
+  %s --test quality --baseline before.json  Compare rankings with an earlier run
   %s --service --install             Install as system service
   %s --service reload                Reload configuration
   %s --update check                  Check for updates
   %s --maintenance rotate-token      Rotate the operator bearer token
   %s --build all                     Build for all platforms
   %s --build host                    Build for current platform
+  %s --observability export ./mon    Export alert rules and dashboard
 
 For more information: https://github.com/apimgr/search
 `, binaryName, binaryName, binaryName,
 		binaryName, binaryName, binaryName, binaryName,
 		binaryName, binaryName, binaryName, binaryName,
-		binaryName, binaryName, binaryName, binaryName)
+		binaryName, binaryName, binaryName, binaryName, binaryName, binaryName,
+		binaryName, binaryName)
 }
 
 func runInit() {
@@ -642,6 +733,24 @@ func runInit() {
 		return
 	}
 
+	// First run on a terminal: walk through the main settings instead of
+	// leaving the operator to edit server.yml
+	if cfg.IsFirstRun() && !flagNonInteract && term.IsTerminal(int(os.Stdin.Fd())) {
+		if err := config.NewWizard(os.Stdin, os.Stdout).Run(cfg); err != nil {
+			fmt.Printf(display.Emoji("⚠️", "[WARN]")+"  %v; keeping the generated defaults\n", err)
+		} else if err := cfg.Save(config.GetConfigPath()); err != nil {
+			slog.Error("Failed to save configuration", "err", err)
+			exitFunc(1)
+			return
+		}
+		fmt.Println()
+	}
+
+	if flagPreset != "" && !applyInitPreset(cfg, flagPreset) {
+		exitFunc(1)
+		return
+	}
+
 	fmt.Println(display.Emoji("✅", "[OK]") + " Configuration initialized successfully!")
 	fmt.Println()
 	fmt.Println(display.Emoji("📁", "[DIR]") + " Configuration Paths:")
@@ -667,6 +776,14 @@ func runInit() {
 		}
 		fmt.Printf("   %s: %s\n", name, status)
 	}
+
+	// The operator token is only shown once; a later first start finds the
+	// config already written and does not print it again
+	if cfg.IsFirstRun() {
+		fmt.Println()
+		fmt.Println(display.Emoji("🔑", "[KEY]") + " Operator token (server.token) - save it now:")
+		fmt.Println("   " + cfg.Server.Token)
+	}
 }
 
 func showConfigInfo() {
@@ -758,6 +875,25 @@ func showConfigInfo() {
 	}
 }
 
+// showConfigDocs prints every server.yml setting with its type, default,
+// environment variable and description, as markdown or, with format
+// "json", as JSON
+func showConfigDocs(format string) {
+	switch format {
+	case "", "markdown", "md":
+		if err := config.WriteReferenceMarkdown(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exitFunc(1)
+		}
+	case "json":
+		jsonData, _ := json.MarshalIndent(config.Reference(), "", "  ")
+		fmt.Println(string(jsonData))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %q (markdown or json)\n", format)
+		exitFunc(1)
+	}
+}
+
 func showStatus() {
 	// Per AI.md PART 31 - --status output format
 	binaryName := filepath.Base(os.Args[0])
@@ -797,6 +933,7 @@ func showStatus() {
 	var mode string
 	var torEnabled bool
 	var torAddress string
+	var webAddress string
 
 	configPath := config.GetConfigPath()
 	if cfg, err := config.Load(configPath); err == nil {
@@ -804,6 +941,16 @@ func showStatus() {
 		mode = cfg.Server.Mode
 		torEnabled = cfg.Server.Tor.Enabled
 		torAddress = cfg.Server.Tor.OnionAddress
+		if torEnabled && torAddress == "" {
+			// The onion address is not persisted; ask the running server
+			torAddress = liveOnionAddress(cfg)
+		}
+		webAddress = strings.TrimRight(cfg.Server.BaseURL, "/")
+		if webAddress == "" {
+			if urls := buildListenURLs(cfg); len(urls) > 0 {
+				webAddress = urls[0]
+			}
+		}
 	} else {
 		// Try to get from env or defaults
 		port = 64580
@@ -842,6 +989,54 @@ func showStatus() {
 	} else {
 		fmt.Println("Tor Hidden Service: Disabled")
 	}
+
+	// Scannable addresses, so a phone can switch to the instance or its onion
+	if webAddress != "" {
+		printStatusQR("Web", webAddress)
+	}
+	if torEnabled && torAddress != "" {
+		printStatusQR("Onion", "http://"+torAddress)
+	}
+}
+
+// printStatusQR prints an address and its QR code for --status
+func printStatusQR(label, address string) {
+	code, err := qr.Terminal(address, terminal.SupportsUnicode())
+	if err != nil {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("%s: %s\n", label, address)
+	fmt.Print(code)
+}
+
+// liveOnionAddress asks the running server for its onion address through the
+// health endpoint. It returns "" if the server does not answer promptly.
+func liveOnionAddress(cfg *config.Config) string {
+	host := cfg.Server.Address
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	endpoint := fmt.Sprintf("http://%s/api/v1/server/healthz", net.JoinHostPort(host, strconv.Itoa(cfg.Server.GetHTTPPort())))
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var health struct {
+		Features struct {
+			Tor struct {
+				Hostname string `json:"hostname"`
+			} `json:"tor"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return ""
+	}
+	return health.Features.Tor.Hostname
 }
 
 // isProcessRunning checks if a process with given PID exists
@@ -1073,9 +1268,7 @@ func authorizeRestore(isFirstRun, isRoot, isServiceUser bool, expectedToken, pre
 		if expectedToken == "" {
 			return errors.New("no operator token is configured")
 		}
-		expectedSum := sha256.Sum256([]byte(expectedToken))
-		presentedSum := sha256.Sum256([]byte(presentedToken))
-		if presentedToken == "" || subtle.ConstantTimeCompare(expectedSum[:], presentedSum[:]) != 1 {
+		if presentedToken == "" || !security.TokenEqual(presentedToken, expectedToken) {
 			return errors.New("invalid operator token")
 		}
 		return nil
@@ -1093,16 +1286,7 @@ func readBackupPassword(prompt string) string {
 	if password := os.Getenv("BACKUP_PASSWORD"); password != "" {
 		return password
 	}
-	if !term.IsTerminal(int(os.Stdin.Fd())) {
-		return ""
-	}
-	fmt.Print(prompt)
-	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Println()
-	if err != nil {
-		return ""
-	}
-	return string(passwordBytes)
+	return readSecret(prompt)
 }
 
 func runMaintenance(action string) {
@@ -1121,24 +1305,47 @@ func runMaintenance(action string) {
 		// otherwise applies only if a backup password was configured during
 		// setup (server.backup.encryption.enabled) — the password itself is
 		// never stored and is resolved via BACKUP_PASSWORD or an interactive
-		// prompt.
+		// prompt. A configured public key (recipient) needs no password, and
+		// a keyfile is added to the password.
 		cfg, cfgErr := config.Initialize()
 		complianceEnabled := cfgErr == nil && cfg.Server.Compliance.Enabled
-		encryptionEnabled := (cfgErr == nil && cfg.Server.Backup.Encryption.Enabled) || complianceEnabled
+		var enc config.BackupEncryptionConfig
+		if cfgErr == nil {
+			enc = cfg.Server.Backup.Encryption
+		}
+		encryptionEnabled := enc.IsEnabled() || complianceEnabled
 
-		var password string
+		var keys backup.Keys
 		if encryptionEnabled {
-			password = readBackupPassword("Enter backup password: ")
-			if password == "" && complianceEnabled {
+			var password string
+			if enc.Recipient == "" {
+				password = readBackupPassword("Enter backup password: ")
+			}
+			var keysErr error
+			keys, keysErr = backup.KeysFromConfig(enc, password)
+			if keysErr != nil {
+				fmt.Printf(display.Emoji("❌", "[ERROR]")+" Backup encryption: %v\n", keysErr)
+				exitFunc(1)
+				return
+			}
+			if !keys.CanEncrypt() && complianceEnabled {
 				fmt.Println(display.Emoji("❌", "[ERROR]") + " Compliance mode requires backup encryption")
 				fmt.Println("   Set BACKUP_PASSWORD or enter a password when prompted, and try again.")
 				exitFunc(1)
 				return
 			}
 		}
-		if password != "" {
-			fmt.Println(display.Emoji("🔐", "[ENCRYPTED]") + " Backup encryption: ENABLED")
-			bm.SetPassword(password)
+		encrypt := keys.CanEncrypt()
+		if encrypt {
+			switch {
+			case keys.Recipient != "":
+				fmt.Printf(display.Emoji("🔐", "[ENCRYPTED]")+" Backup encryption: ENABLED (public key %s)\n", backup.KeyID(keys.Recipient))
+			case len(keys.Keyfile) > 0:
+				fmt.Println(display.Emoji("🔐", "[ENCRYPTED]") + " Backup encryption: ENABLED (with keyfile)")
+			default:
+				fmt.Println(display.Emoji("🔐", "[ENCRYPTED]") + " Backup encryption: ENABLED")
+			}
+			bm.SetKeys(keys)
 		} else {
 			fmt.Println(display.Emoji("🔓", "[PLAIN]") + " Backup encryption: DISABLED (set BACKUP_PASSWORD to enable)")
 		}
@@ -1150,7 +1357,7 @@ func runMaintenance(action string) {
 		var backupPath string
 		var verifyResult *backup.VerificationResult
 		var err error
-		if password != "" {
+		if encrypt {
 			backupPath, verifyResult, err = bm.CreateEncryptedAndVerify(filename)
 		} else {
 			backupPath, verifyResult, err = bm.CreateAndVerify(filename)
@@ -1162,9 +1369,15 @@ func runMaintenance(action string) {
 		}
 		fmt.Printf(display.Emoji("✅", "[OK]")+" Backup created and verified: %s\n", backupPath)
 		if verifyResult != nil {
-			fmt.Printf("   Verified: file=%v size=%v checksum=%v manifest=%v decrypt=%v\n",
+			decrypt := fmt.Sprint(verifyResult.DecryptValid)
+			if verifyResult.DecryptSkipped {
+				// The private key is not on this server; the archive was
+				// verified before it was encrypted
+				decrypt = "skipped (public key)"
+			}
+			fmt.Printf("   Verified: file=%v size=%v checksum=%v manifest=%v decrypt=%s\n",
 				verifyResult.FileExists, verifyResult.SizeValid, verifyResult.ChecksumValid,
-				verifyResult.ManifestValid, verifyResult.DecryptValid)
+				verifyResult.ManifestValid, decrypt)
 		}
 
 		// Show backup info. GetMetadata reads the manifest via a plain gzip
@@ -1231,17 +1444,23 @@ func runMaintenance(action string) {
 
 		// Per AI.md PART 21: the CLI prompts "Enter backup password:" when the
 		// backup is encrypted and no password was provided via BACKUP_PASSWORD.
+		// The backup's header says whether it needs a password, a keyfile or
+		// a private key instead.
 		isEncryptedFile := backup.IsEncrypted(filename)
-		var password string
 		if isEncryptedFile {
-			password = readBackupPassword("Enter backup password: ")
-			if password == "" {
-				fmt.Println(display.Emoji("❌", "[ERROR]") + " This backup is encrypted — a password is required")
-				fmt.Println("   Set BACKUP_PASSWORD or enter a password when prompted, and try again.")
+			header, headerErr := backup.ReadHeaderFile(filename)
+			if headerErr != nil {
+				fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read encrypted backup: %v\n", headerErr)
+				exitFunc(1)
+				return
+			}
+			keys, keysErr := readBackupRestoreKeys(header, authCfg.Server.Backup.Encryption)
+			if keysErr != nil {
+				fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", keysErr)
 				exitFunc(1)
 				return
 			}
-			bm.SetPassword(password)
+			bm.SetKeys(keys)
 		}
 
 		// Per AI.md PART 21: every check in the restore verification checklist
@@ -1331,6 +1550,27 @@ func runMaintenance(action string) {
 			fmt.Println("   The server is now accepting normal requests")
 		}
 
+	case "verify":
+		verifyPath := ""
+		if len(os.Args) > 3 {
+			verifyPath = os.Args[3]
+		}
+		runBackupVerify(verifyPath)
+
+	case "bootstrap":
+		source := ""
+		if len(os.Args) > 3 {
+			source = os.Args[3]
+		}
+		runBootstrap(source)
+
+	case "backup-keygen":
+		keyPath := ""
+		if len(os.Args) > 3 {
+			keyPath = os.Args[3]
+		}
+		runBackupKeygen(keyPath)
+
 	case "rotate-token":
 		// Rotate the operator bearer token (server.token) and persist it.
 		// This is the only credential the application has — there is no admin
@@ -1499,6 +1739,11 @@ func runMaintenance(action string) {
 		fmt.Println("                    Set BACKUP_PASSWORD env var for encryption")
 		fmt.Println("  restore <file>    Restore from backup")
 		fmt.Println("                    Set BACKUP_PASSWORD env var if encrypted")
+		fmt.Println("  verify <file>     Check a backup against its checksums without restoring")
+		fmt.Println("  bootstrap <s3-url>")
+		fmt.Println("                    Restore the newest backup on a new host, install and start")
+		fmt.Println("  backup-keygen <file>")
+		fmt.Println("                    Write a private key to file, print its public key")
 		fmt.Println("  list              List available backups")
 		fmt.Println("  update            Check and install updates")
 		fmt.Println("  mode              Toggle maintenance mode")
@@ -1510,10 +1755,15 @@ func runMaintenance(action string) {
 		fmt.Println("Backup Encryption:")
 		fmt.Println("  BACKUP_PASSWORD=secret search --maintenance backup")
 		fmt.Println("  BACKUP_PASSWORD=secret search --maintenance restore backup.tar.gz")
+		fmt.Println("  BACKUP_PASSWORD=secret BACKUP_KEYFILE=/path/key search --maintenance backup")
+		fmt.Println("  BACKUP_IDENTITY=backup.key search --maintenance restore backup.tar.gz.enc")
+		fmt.Println()
+		fmt.Println("Disaster Recovery:")
+		fmt.Println("  AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=... search --maintenance bootstrap s3://bucket/latest")
 
 	default:
 		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Unknown action: %s\n", action)
-		fmt.Println("Valid actions: backup, restore, list, update, mode, setup, pgp, rotate-token, help")
+		fmt.Println("Valid actions: backup, restore, verify, bootstrap, backup-keygen, list, update, mode, setup, pgp, rotate-token, help")
 	}
 }
 
@@ -1662,6 +1912,23 @@ func runUpdate(subCmd string) {
 }
 
 func runTest() {
+	if len(os.Args) > 2 && os.Args[2] == "fuzz" {
+		runFuzz(os.Args[3:])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "load" {
+		runLoad(os.Args[3:])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "quality" {
+		runQuality(os.Args[3:])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "security" {
+		runSecurityTest(os.Args[3:])
+		return
+	}
+
 	fmt.Println(display.Emoji("🧪", "[TEST]") + " Testing Search Engines...")
 	fmt.Println()
 
@@ -1855,6 +2122,9 @@ func runBuild(platform string) {
 		targets = []BuildTarget{{"freebsd", "amd64"}, {"freebsd", "arm64"}}
 	case "host":
 		targets = []BuildTarget{{runtime.GOOS, runtime.GOARCH}}
+	case "docker":
+		runBuildDocker(srcDir)
+		return
 	default:
 		// Check for OS/ARCH format
 		parts := strings.Split(platform, "/")
@@ -1874,9 +2144,20 @@ func runBuild(platform string) {
 		exitFunc(1)
 	}
 
+	// Stamp every binary with the source date so rebuilding a commit is
+	// byte-for-byte reproducible
+	sourceDate, reproducible := release.SourceDateEpoch(srcDir)
+	commit := gitCommit(srcDir)
+
 	fmt.Printf(display.Emoji("📁", "[DIR]")+" Source: %s\n", srcDir)
 	fmt.Printf(display.Emoji("📁", "[DIR]")+" Output: %s\n", outputDir)
-	fmt.Printf(display.Emoji("🎯", "[>]")+" Targets: %d platforms\n\n", len(targets))
+	fmt.Printf(display.Emoji("🎯", "[>]")+" Targets: %d platforms\n", len(targets))
+	if reproducible {
+		fmt.Printf(display.Emoji("🕒", "[TIME]")+" Source date: %s\n\n", sourceDate.Format(time.RFC3339))
+	} else {
+		fmt.Println(display.Emoji("⚠️", "[WARN]") + "  No SOURCE_DATE_EPOCH or git commit; binaries are stamped with the current time and are not reproducible")
+		fmt.Println()
+	}
 
 	// Build for each target
 	failed := 0
@@ -1890,16 +2171,30 @@ func runBuild(platform string) {
 
 		fmt.Printf("   Building %s/%s... ", target.OS, target.Arch)
 
-		if err := buildWithDocker(srcDir, outputPath, target.OS, target.Arch); err != nil {
+		started := time.Now()
+		if err := buildWithDocker(srcDir, outputPath, target.OS, target.Arch, sourceDate, commit); err != nil {
 			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
 			failed++
+			continue
+		}
+		// Get file size
+		if info, err := os.Stat(outputPath); err == nil {
+			fmt.Printf(display.Emoji("✅", "[OK]")+" (%s)\n", formatBytes(info.Size()))
 		} else {
-			// Get file size
-			if info, err := os.Stat(outputPath); err == nil {
-				fmt.Printf(display.Emoji("✅", "[OK]")+" (%s)\n", formatBytes(info.Size()))
-			} else {
-				fmt.Println(display.Emoji("✅", "[OK]") + "")
-			}
+			fmt.Println(display.Emoji("✅", "[OK]") + "")
+		}
+
+		// SBOMs and provenance for supply-chain verification
+		if _, err := release.WriteAttestations(outputPath, release.BuildParams{
+			Version:    config.Version,
+			Commit:     commit,
+			Image:      buildImage,
+			SourceDate: sourceDate,
+			Started:    started,
+			Finished:   time.Now(),
+		}); err != nil {
+			fmt.Printf("     "+display.Emoji("❌", "[ERROR]")+" SBOM/provenance: %v\n", err)
+			failed++
 		}
 	}
 
@@ -1911,6 +2206,15 @@ func runBuild(platform string) {
 	fmt.Printf(display.Emoji("✅", "[OK]")+" Build complete: %d binaries in %s/\n", len(targets), outputDir)
 }
 
+// gitCommit returns the checked out commit of srcDir, or "" outside git
+func gitCommit(srcDir string) string {
+	out, err := exec.Command("git", "-C", srcDir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
 // findSourceDir locates the source directory
 func findSourceDir() (string, error) {
 	// Try current directory first
@@ -1948,10 +2252,25 @@ func findSourceDir() (string, error) {
 	return "", fmt.Errorf("could not find go.mod in any expected location")
 }
 
-// buildWithDocker builds a binary using Docker
-func buildWithDocker(srcDir, outputPath, goos, goarch string) error {
+// buildImage is the required build image per AI.md PART 7
+const buildImage = "casjaysdev/go:latest"
+
+// buildWithDocker builds a binary using Docker. The build is reproducible:
+// paths are trimmed, the toolchain stamps the VCS revision, and the build
+// date is sourceDate rather than the wall clock.
+func buildWithDocker(srcDir, outputPath, goos, goarch string, sourceDate time.Time, commit string) error {
 	outputName := filepath.Base(outputPath)
 
+	shortCommit := commit
+	if len(shortCommit) > 7 {
+		shortCommit = shortCommit[:7]
+	}
+	if shortCommit == "" {
+		shortCommit = "unknown"
+	}
+	ldflags := fmt.Sprintf("-s -w -buildid= -X 'github.com/apimgr/search/src/config.Version=%s' -X 'github.com/apimgr/search/src/config.CommitID=%s' -X 'github.com/apimgr/search/src/config.BuildDate=%s'",
+		config.Version, shortCommit, sourceDate.UTC().Format("Mon Jan 02, 2006 at 15:04:05 MST"))
+
 	// Docker command to build using the required build image per AI.md PART 7
 	cmd := exec.Command("docker", "run", "--rm",
 		"-v", srcDir+":/app",
@@ -1959,10 +2278,17 @@ func buildWithDocker(srcDir, outputPath, goos, goarch string) error {
 		"-e", "CGO_ENABLED=0",
 		"-e", "GOOS="+goos,
 		"-e", "GOARCH="+goarch,
-		"casjaysdev/go:latest",
+		"-e", fmt.Sprintf("SOURCE_DATE_EPOCH=%d", sourceDate.Unix()),
+		// The mounted checkout is owned by another user; let git read it so
+		// the toolchain can stamp vcs.revision
+		"-e", "GIT_CONFIG_COUNT=1",
+		"-e", "GIT_CONFIG_KEY_0=safe.directory",
+		"-e", "GIT_CONFIG_VALUE_0=/app",
+		buildImage,
 		"go", "build",
-		"-ldflags", fmt.Sprintf("-s -w -X github.com/apimgr/search/src/config.Version=%s -X github.com/apimgr/search/src/config.BuildDate=%s",
-			config.Version, time.Now().Format("Mon Jan 02, 2006 at 15:04:05 MST")),
+		"-trimpath",
+		"-buildvcs=auto",
+		"-ldflags", ldflags,
 		"-o", "/app/binaries/"+outputName,
 		"./src",
 	)
@@ -2114,9 +2440,9 @@ _%s_completions() {
     cur="${COMP_WORDS[COMP_CWORD]}"
     prev="${COMP_WORDS[COMP_CWORD-1]}"
 
-    opts="--help --version --status --init --config-info --test --daemon --debug"
+    opts="--help --version --status --init --non-interactive --preset --config-info --config-docs --test --daemon --debug"
     opts="$opts --mode --config --data --cache --log --backup --pid --address --port"
-    opts="$opts --service --maintenance --update --build --shell"
+    opts="$opts --service --maintenance --update --build --shell --observability"
 
     case "${prev}" in
         --service)
@@ -2124,7 +2450,7 @@ _%s_completions() {
             return 0
             ;;
         --maintenance)
-            COMPREPLY=( $(compgen -W "backup restore list update mode setup help" -- ${cur}) )
+            COMPREPLY=( $(compgen -W "backup restore verify bootstrap backup-keygen list update mode setup help" -- ${cur}) )
             return 0
             ;;
         --update)
@@ -2132,13 +2458,21 @@ _%s_completions() {
             return 0
             ;;
         --build)
-            COMPREPLY=( $(compgen -W "all linux darwin windows freebsd host" -- ${cur}) )
+            COMPREPLY=( $(compgen -W "all linux darwin windows freebsd host docker" -- ${cur}) )
             return 0
             ;;
         --shell)
             COMPREPLY=( $(compgen -W "completions init --help" -- ${cur}) )
             return 0
             ;;
+        --observability)
+            COMPREPLY=( $(compgen -W "export rules dashboard --help" -- ${cur}) )
+            return 0
+            ;;
+        --preset)
+            COMPREPLY=( $(compgen -W "privacy-max family-safe developer intranet" -- ${cur}) )
+            return 0
+            ;;
         --mode)
             COMPREPLY=( $(compgen -W "production development" -- ${cur}) )
             return 0
@@ -2174,7 +2508,10 @@ _%s() {
         '-v[Show version]'
         '--status[Show server status]'
         '--init[Initialize configuration]'
+        '--non-interactive[Initialize without prompting]'
+        '--preset[Configuration preset]:preset:(privacy-max family-safe developer intranet)'
         '--config-info[Show configuration paths]'
+        '--config-docs[Print the config reference]:format:(markdown json)'
         '--test[Test search engines]:query:'
         '--daemon[Run as daemon]'
         '--debug[Enable debug mode]'
@@ -2188,10 +2525,11 @@ _%s() {
         '--address[Listen address]:address:'
         '--port[Listen port]:port:'
         '--service[Service management]:action:(install uninstall start stop restart reload enable disable status help)'
-        '--maintenance[Maintenance]:action:(backup restore list update mode setup help)'
+        '--maintenance[Maintenance]:action:(backup restore verify bootstrap backup-keygen list update mode setup help)'
         '--update[Update management]:action:(check yes rollback list branch)'
-        '--build[Build binaries]:platform:(all linux darwin windows freebsd host)'
+        '--build[Build binaries]:platform:(all linux darwin windows freebsd host docker)'
         '--shell[Shell integration]:subcommand:(completions init --help)'
+        '--observability[Monitoring assets]:subcommand:(export rules dashboard --help)'
     )
 
     _arguments -s $opts
@@ -2209,7 +2547,10 @@ complete -c %s -s h -l help -d 'Show help message'
 complete -c %s -s v -l version -d 'Show version'
 complete -c %s -l status -d 'Show server status'
 complete -c %s -l init -d 'Initialize configuration'
+complete -c %s -l non-interactive -d 'Initialize without prompting'
+complete -c %s -l preset -d 'Configuration preset' -xa 'privacy-max family-safe developer intranet'
 complete -c %s -l config-info -d 'Show configuration paths'
+complete -c %s -l config-docs -d 'Print the config reference' -xa 'markdown json'
 complete -c %s -l test -d 'Test search engines'
 complete -c %s -l daemon -d 'Run as daemon'
 complete -c %s -l debug -d 'Enable debug mode'
@@ -2223,16 +2564,18 @@ complete -c %s -l pid -d 'PID file'
 complete -c %s -l address -d 'Listen address'
 complete -c %s -l port -d 'Listen port'
 complete -c %s -l service -d 'Service management' -xa 'install uninstall start stop restart reload enable disable status help'
-complete -c %s -l maintenance -d 'Maintenance' -xa 'backup restore list update mode setup help'
+complete -c %s -l maintenance -d 'Maintenance' -xa 'backup restore verify bootstrap backup-keygen list update mode setup help'
 complete -c %s -l update -d 'Update management' -xa 'check yes rollback list branch'
-complete -c %s -l build -d 'Build binaries' -xa 'all linux darwin windows freebsd host'
+complete -c %s -l build -d 'Build binaries' -xa 'all linux darwin windows freebsd host docker'
 complete -c %s -l shell -d 'Shell integration' -xa 'completions init --help'
+complete -c %s -l observability -d 'Monitoring assets' -xa 'export rules dashboard --help'
 `, binaryName, binaryName,
 			binaryName, binaryName, binaryName, binaryName, binaryName,
 			binaryName, binaryName, binaryName, binaryName, binaryName,
 			binaryName, binaryName, binaryName, binaryName, binaryName,
 			binaryName, binaryName, binaryName, binaryName, binaryName,
-			binaryName, binaryName, binaryName)
+			binaryName, binaryName, binaryName, binaryName, binaryName,
+			binaryName, binaryName)
 
 	case "powershell", "pwsh":
 		fmt.Printf(`# PowerShell completions for %s
@@ -2248,7 +2591,10 @@ Register-ArgumentCompleter -CommandName %s -ScriptBlock {
         @{Name='-v'; Description='Show version'}
         @{Name='--status'; Description='Show server status'}
         @{Name='--init'; Description='Initialize configuration'}
+        @{Name='--non-interactive'; Description='Initialize without prompting'}
+        @{Name='--preset'; Description='Configuration preset'}
         @{Name='--config-info'; Description='Show configuration paths'}
+        @{Name='--config-docs'; Description='Print the config reference'}
         @{Name='--test'; Description='Test search engines'}
         @{Name='--daemon'; Description='Run as daemon'}
         @{Name='--debug'; Description='Enable debug mode'}
@@ -2266,6 +2612,7 @@ Register-ArgumentCompleter -CommandName %s -ScriptBlock {
         @{Name='--update'; Description='Update management'}
         @{Name='--build'; Description='Build binaries'}
         @{Name='--shell'; Description='Shell integration'}
+        @{Name='--observability'; Description='Monitoring assets'}
     )
 
     $commands | Where-Object { $_.Name -like "$wordToComplete*" } | ForEach-Object {
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apimgr/search/src/backup"
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/service"
+	"golang.org/x/term"
+)
+
+// runBackupKeygen is search --maintenance backup-keygen <file>: it writes
+// a new private key for public-key backups to file and prints the public
+// key to put in server.backup.encryption.recipient. The private key is
+// meant to be moved off the server; backups encrypted to the public key
+// cannot be restored without it.
+func runBackupKeygen(path string) {
+	fmt.Println(display.Emoji("🔑", "[KEY]") + " Backup Key Pair")
+	fmt.Println()
+	if path == "" {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " A path for the private key is required.")
+		fmt.Println("Usage: search --maintenance backup-keygen <file>")
+		exitFunc(1)
+		return
+	}
+
+	publicKey, privateKey, err := backup.GenerateKeyPair()
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	// O_EXCL: never replace a key that may still be the only way to
+	// restore existing backups
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %s already exists; not overwriting it\n", path)
+		} else {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to create %s: %v\n", path, err)
+		}
+		exitFunc(1)
+		return
+	}
+	_, err = fmt.Fprintln(f, privateKey)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to write %s: %v\n", path, err)
+		exitFunc(1)
+		return
+	}
+
+	fmt.Println(display.Emoji("✅", "[OK]") + " Private key written to " + path + " (mode 0600)")
+	fmt.Println("   Keep it off this server. Backups encrypted to this key cannot be")
+	fmt.Println("   restored without it.")
+	fmt.Println()
+	fmt.Println("Public key (key ID " + backup.KeyID(publicKey) + "):")
+	fmt.Println("  " + publicKey)
+	fmt.Println()
+	fmt.Println("Add it to server.yml to encrypt backups to it:")
+	fmt.Println("  server:")
+	fmt.Println("    backup:")
+	fmt.Println("      encryption:")
+	fmt.Println("        recipient: " + publicKey)
+	fmt.Println()
+	fmt.Println("Restore with BACKUP_IDENTITY=" + path + " search --maintenance restore <file>")
+}
+
+// runBackupVerify is search --maintenance verify <file>: it checks a
+// backup against the SHA-256 manifest inside it without restoring
+// anything. Encrypted backups are decrypted in memory, so they need the
+// same keys a restore does.
+func runBackupVerify(path string) {
+	if path == "" {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " Please specify backup file to verify")
+		fmt.Println("Usage: search --maintenance verify <backup-file>")
+		exitFunc(1)
+		return
+	}
+	// A bare filename names a backup in the backup directory
+	if _, err := os.Stat(path); os.IsNotExist(err) && filepath.Base(path) == path {
+		path = filepath.Join(config.GetBackupDir(), path)
+	}
+	fmt.Printf("Verifying: %s\n", path)
+
+	bm := backup.NewManager()
+	if backup.IsEncrypted(path) {
+		header, err := backup.ReadHeaderFile(path)
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read encrypted backup: %v\n", err)
+			exitFunc(1)
+			return
+		}
+		var enc config.BackupEncryptionConfig
+		if cfg, err := config.Initialize(); err == nil {
+			enc = cfg.Server.Backup.Encryption
+		}
+		keys, err := readBackupRestoreKeys(header, enc)
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+			exitFunc(1)
+			return
+		}
+		bm.SetKeys(keys)
+	}
+
+	result, err := bm.VerifyBackup(path)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Verification failed: %v\n", err)
+		exitFunc(1)
+		return
+	}
+
+	fmt.Println()
+	checks := []struct {
+		name string
+		ok   bool
+	}{
+		{"File exists", result.FileExists},
+		{"Size", result.SizeValid},
+		{"Decrypt", result.DecryptValid},
+		{"Manifest", result.ManifestValid},
+		{"Checksum", result.ChecksumValid},
+		{fmt.Sprintf("File checksums (%d files)", result.FilesChecked), result.FilesValid},
+		{"Content", result.ContentValid},
+		{"Database", result.DatabaseValid},
+	}
+	for _, check := range checks {
+		status := display.Emoji("✅", "[OK]")
+		if !check.ok {
+			status = display.Emoji("❌", "[FAIL]")
+		}
+		fmt.Printf("  %s %s\n", status, check.name)
+	}
+	fmt.Println()
+
+	if !result.AllPassed {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " Backup verification failed")
+		for _, verifyErr := range result.Errors {
+			fmt.Printf("   - %s\n", verifyErr)
+		}
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]") + " Backup verified; nothing was restored")
+}
+
+// bootstrapService installs and starts the system service once a
+// bootstrap has restored the backup
+var bootstrapService = func(cfg *config.Config) error {
+	sm := service.NewServiceManager(cfg)
+	if err := sm.Install(); err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+	if err := sm.StartAllServices(); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	return nil
+}
+
+// runBootstrap is search --maintenance bootstrap <s3-url>: disaster
+// recovery on a new host in one command. It downloads the backup (the
+// newest one for s3://bucket/latest), verifies and restores it, then
+// installs and starts the service. S3 credentials come from the usual
+// AWS_* variables, and an encrypted backup's key from BACKUP_PASSWORD,
+// BACKUP_KEYFILE or BACKUP_IDENTITY.
+func runBootstrap(source string) {
+	if source == "" {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " Please specify where the backup is")
+		fmt.Println("Usage: search --maintenance bootstrap s3://bucket/latest")
+		exitFunc(1)
+		return
+	}
+	if _, err := backup.ParseRemoteLocation(source); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	// Installing the service needs root, and so does restoring over
+	// another user's files
+	if !config.IsPrivileged() {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " This command requires elevated privileges")
+		fmt.Println("   Run with sudo/admin rights")
+		exitFunc(1)
+		return
+	}
+
+	cfg, err := config.Initialize()
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to load config: %v\n", err)
+		exitFunc(1)
+		return
+	}
+	// Bootstrap is meant for a new host; anywhere else it overwrites a
+	// working configuration
+	if !cfg.IsFirstRun() {
+		fmt.Print("This host is already configured; the backup will overwrite it. Continue? (yes/no): ")
+		var confirm string
+		fmt.Scanln(&confirm)
+		if confirm != "yes" {
+			fmt.Println("Bootstrap cancelled.")
+			return
+		}
+	}
+
+	fmt.Printf("Downloading backup from %s...\n", source)
+	bm := backup.NewManager()
+	path, err := bm.FetchRemote(context.Background(), nil, source)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Download failed: %v\n", err)
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]") + " Downloaded " + filepath.Base(path))
+
+	encrypted := backup.IsEncrypted(path)
+	if encrypted {
+		header, err := backup.ReadHeaderFile(path)
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read encrypted backup: %v\n", err)
+			exitFunc(1)
+			return
+		}
+		keys, err := readBackupRestoreKeys(header, cfg.Server.Backup.Encryption)
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+			exitFunc(1)
+			return
+		}
+		bm.SetKeys(keys)
+	}
+
+	fmt.Println("Verifying backup integrity...")
+	result, err := bm.VerifyBackup(path)
+	if err != nil || result == nil || !result.AllPassed {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " Backup verification failed")
+		if err != nil {
+			fmt.Printf("   %v\n", err)
+		}
+		if result != nil {
+			for _, verifyErr := range result.Errors {
+				fmt.Printf("   - %s\n", verifyErr)
+			}
+		}
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]") + " Verifying backup integrity... OK")
+
+	fmt.Println("Restoring...")
+	if encrypted {
+		err = bm.RestoreEncrypted(path)
+	} else {
+		err = bm.Restore(path)
+	}
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Restore failed: %v\n", err)
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]") + " Restore completed")
+
+	// The service is installed from the restored configuration
+	cfg, err = config.Initialize()
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to load restored config: %v\n", err)
+		exitFunc(1)
+		return
+	}
+	fmt.Println("Installing and starting the service...")
+	if err := bootstrapService(cfg); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]") + " Bootstrap complete; the server is running")
+}
+
+// readSecret reads a secret at a masked prompt, or returns "" when stdin
+// is not a terminal
+func readSecret(prompt string) string {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return ""
+	}
+	fmt.Print(prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return ""
+	}
+	return string(secret)
+}
+
+// readBackupRestoreKeys collects what header says the backup needs: the
+// private key from the file BACKUP_IDENTITY names or a masked prompt, or
+// the password and the keyfile from BACKUP_KEYFILE or enc.Keyfile
+func readBackupRestoreKeys(header *backup.Header, enc config.BackupEncryptionConfig) (backup.Keys, error) {
+	var keys backup.Keys
+	if header.Mode == backup.ModePublicKey {
+		if path := os.Getenv("BACKUP_IDENTITY"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return keys, fmt.Errorf("failed to read BACKUP_IDENTITY: %w", err)
+			}
+			keys.Identity = strings.TrimSpace(string(data))
+		} else {
+			keys.Identity = strings.TrimSpace(readSecret("Enter backup private key: "))
+		}
+		if keys.Identity == "" {
+			return keys, fmt.Errorf("this backup was encrypted to public key %s; set BACKUP_IDENTITY to its private key file", header.KeyID)
+		}
+		return keys, nil
+	}
+
+	if header.NeedsPassword {
+		keys.Password = readBackupPassword("Enter backup password: ")
+		if keys.Password == "" {
+			return keys, fmt.Errorf("this backup is encrypted — a password is required; set BACKUP_PASSWORD or enter a password when prompted")
+		}
+	}
+	if header.NeedsKeyfile {
+		path := os.Getenv("BACKUP_KEYFILE")
+		if path == "" {
+			path = enc.Keyfile
+		}
+		if path == "" {
+			return keys, fmt.Errorf("this backup was encrypted with a keyfile; set BACKUP_KEYFILE to its path")
+		}
+		keyfile, err := backup.ReadKeyfile(path)
+		if err != nil {
+			return keys, err
+		}
+		keys.Keyfile = keyfile
+	}
+	return keys, nil
+}
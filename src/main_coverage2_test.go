@@ -1,9 +1,23 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/apimgr/search/src/backup"
+	"github.com/apimgr/search/src/config"
 )
 
 // withExitFunc overrides exitFunc to a no-op during the test.
@@ -33,6 +47,17 @@ func TestShowConfigInfo(t *testing.T) {
 	}
 }
 
+// TestShowConfigDocs verifies the config reference prints as markdown and JSON.
+func TestShowConfigDocs(t *testing.T) {
+	withExitFunc(t)
+	if out := captureStdout(t, func() { showConfigDocs("") }); !strings.Contains(out, "| `server.port` | int |") {
+		t.Errorf("markdown reference missing server.port:\n%.300s", out)
+	}
+	if out := captureStdout(t, func() { showConfigDocs("json") }); !strings.Contains(out, `"key": "search.safe_search"`) {
+		t.Errorf("json reference missing search.safe_search:\n%.300s", out)
+	}
+}
+
 // TestShowStatusNotRunning verifies showStatus produces output without panicking.
 func TestShowStatusNotRunning(t *testing.T) {
 	withArgs(t, []string{"search"})
@@ -308,3 +333,186 @@ func TestRunMaintenanceRotateTokenCancelled(t *testing.T) {
 
 	captureStdout(t, func() { runMaintenance("rotate-token") })
 }
+
+// TestRunBackupKeygen verifies backup-keygen writes a private key that
+// matches the printed public key and never overwrites an existing file.
+func TestRunBackupKeygen(t *testing.T) {
+	withExitFunc(t)
+	keyPath := filepath.Join(t.TempDir(), "backup.key")
+
+	out := captureStdout(t, func() { runBackupKeygen(keyPath) })
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatalf("private key not written: %v", err)
+	}
+	if info, _ := os.Stat(keyPath); info.Mode().Perm() != 0o600 {
+		t.Errorf("private key mode = %v, want 0600", info.Mode().Perm())
+	}
+	priv, err := backup.ParsePrivateKey(string(data))
+	if err != nil {
+		t.Fatalf("written key does not parse: %v", err)
+	}
+	if !strings.Contains(out, "recipient: "+backup.PublicKeyPrefix) {
+		t.Errorf("output lacks the recipient snippet: %q", out)
+	}
+	if !strings.Contains(out, base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes())) {
+		t.Error("printed public key does not match the written private key")
+	}
+
+	out = captureStdout(t, func() { runBackupKeygen(keyPath) })
+	if !strings.Contains(out, "already exists") {
+		t.Errorf("second keygen should refuse to overwrite, got %q", out)
+	}
+	if again, _ := os.ReadFile(keyPath); string(again) != string(data) {
+		t.Error("existing private key was overwritten")
+	}
+}
+
+// TestReadBackupRestoreKeys verifies restore asks for what the backup's
+// header says it was encrypted with.
+func TestReadBackupRestoreKeys(t *testing.T) {
+	dir := t.TempDir()
+	publicKey, privateKey, _ := backup.GenerateKeyPair()
+	identityPath := filepath.Join(dir, "backup.key")
+	os.WriteFile(identityPath, []byte(privateKey+"\n"), 0o600)
+	keyfilePath := filepath.Join(dir, "keyfile")
+	os.WriteFile(keyfilePath, []byte("keyfile"), 0o600)
+
+	encrypted, _ := backup.EncryptBackupWithKeys([]byte("x"), backup.Keys{Recipient: publicKey})
+	header, _ := backup.ReadHeader(encrypted)
+	t.Setenv("BACKUP_IDENTITY", identityPath)
+	keys, err := readBackupRestoreKeys(header, config.BackupEncryptionConfig{})
+	if err != nil || keys.Identity != privateKey {
+		t.Errorf("public-key backup: keys = %+v, err = %v", keys, err)
+	}
+
+	encrypted, _ = backup.EncryptBackupWithKeys([]byte("x"), backup.Keys{Password: "pass", Keyfile: []byte("keyfile")})
+	header, _ = backup.ReadHeader(encrypted)
+	t.Setenv("BACKUP_PASSWORD", "pass")
+	t.Setenv("BACKUP_KEYFILE", "")
+	if _, err := readBackupRestoreKeys(header, config.BackupEncryptionConfig{}); err == nil {
+		t.Error("keyfile backup without a keyfile should fail")
+	}
+	keys, err = readBackupRestoreKeys(header, config.BackupEncryptionConfig{Keyfile: keyfilePath})
+	if err != nil || keys.Password != "pass" || string(keys.Keyfile) != "keyfile" {
+		t.Errorf("keyfile backup: keys = %+v, err = %v", keys, err)
+	}
+	if _, err := backup.DecryptBackupWithKeys(encrypted, keys); err != nil {
+		t.Errorf("collected keys do not decrypt: %v", err)
+	}
+}
+
+// TestRunBackupVerify verifies the verify command passes an intact backup
+// and reports a file whose contents no longer match the manifest.
+func TestRunBackupVerify(t *testing.T) {
+	withExitFunc(t)
+	dir := t.TempDir()
+	content := []byte("server:\n  title: Test\n")
+	sum := sha256.Sum256(content)
+	checksums := map[string]string{"config/server.yml": hex.EncodeToString(sum[:])}
+
+	writeBackup := func(name string, fileContent []byte) string {
+		manifest, _ := json.Marshal(backup.BackupMetadata{Version: "1.0.0", Checksums: checksums})
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, entry := range []struct {
+			name string
+			data []byte
+		}{{"config/server.yml", fileContent}, {"manifest.json", manifest}} {
+			tw.WriteHeader(&tar.Header{Name: entry.name, Size: int64(len(entry.data)), Mode: 0644, Typeflag: tar.TypeReg})
+			tw.Write(entry.data)
+		}
+		tw.Close()
+		gz.Close()
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, buf.Bytes(), 0644)
+		return path
+	}
+
+	exitCode := 0
+	exitFunc = func(code int) { exitCode = code }
+
+	out := captureStdout(t, func() { runBackupVerify(writeBackup("good.tar.gz", content)) })
+	if exitCode != 0 || !strings.Contains(out, "Backup verified") || !strings.Contains(out, "File checksums (1 files)") {
+		t.Errorf("intact backup: exit %d, output %q", exitCode, out)
+	}
+
+	out = captureStdout(t, func() { runBackupVerify(writeBackup("bad.tar.gz", []byte("tampered"))) })
+	if exitCode != 1 || !strings.Contains(out, "checksum mismatch: config/server.yml") {
+		t.Errorf("tampered backup: exit %d, output %q", exitCode, out)
+	}
+
+	exitCode = 0
+	out = captureStdout(t, func() { runBackupVerify("") })
+	if exitCode != 1 || !strings.Contains(out, "Usage: search --maintenance verify") {
+		t.Errorf("no file: exit %d, output %q", exitCode, out)
+	}
+}
+
+func TestRunBootstrap(t *testing.T) {
+	withExitFunc(t)
+	exitCode := 0
+	exitFunc = func(code int) { exitCode = code }
+
+	useDirs := func(root string) {
+		for _, name := range []string{"CONFIG", "DATA", "LOG", "CACHE", "BACKUP"} {
+			t.Setenv("SEARCH_"+name+"_DIR", filepath.Join(root, strings.ToLower(name)))
+		}
+	}
+
+	// The host that made the backup
+	source := t.TempDir()
+	useDirs(source)
+	os.MkdirAll(filepath.Join(source, "config"), 0755)
+	os.MkdirAll(filepath.Join(source, "data"), 0755)
+	os.WriteFile(filepath.Join(source, "config", "server.yml"), []byte("server:\n  title: Recovered Search\n"), 0644)
+	backupPath, err := backup.NewManager().Create("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, _ := os.ReadFile(backupPath)
+	name := filepath.Base(backupPath)
+
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/backups/":
+			fmt.Fprintf(w, "<ListBucketResult><Contents><Key>%s</Key><LastModified>2026-01-02T03:04:05Z</LastModified></Contents></ListBucketResult>", name)
+		case "/backups/" + name:
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer bucket.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", bucket.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+
+	var installed *config.Config
+	orig := bootstrapService
+	bootstrapService = func(cfg *config.Config) error {
+		installed = cfg
+		return nil
+	}
+	t.Cleanup(func() { bootstrapService = orig })
+
+	// A new host
+	useDirs(t.TempDir())
+	out := captureStdout(t, func() { runBootstrap("s3://backups/latest") })
+	if exitCode != 0 || !strings.Contains(out, "Bootstrap complete") {
+		t.Fatalf("bootstrap: exit %d, output %q", exitCode, out)
+	}
+	if installed == nil || installed.Server.Title != "Recovered Search" {
+		t.Errorf("service installed from the wrong config: %+v", installed)
+	}
+
+	out = captureStdout(t, func() { runBootstrap("/var/backups/latest") })
+	if exitCode != 1 || !strings.Contains(out, "not an s3://bucket/key URL") {
+		t.Errorf("local path: exit %d, output %q", exitCode, out)
+	}
+	exitCode = 0
+	out = captureStdout(t, func() { runBootstrap("") })
+	if exitCode != 1 || !strings.Contains(out, "Usage: search --maintenance bootstrap") {
+		t.Errorf("no source: exit %d, output %q", exitCode, out)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/deploy"
+	"github.com/apimgr/search/src/release"
+)
+
+// runBuildDocker builds the linux binary for the Docker host's architecture
+// reproducibly, then packages it on the distroless base and tags the image
+// with this version and latest
+func runBuildDocker(srcDir string) {
+	sourceDate, _ := release.SourceDateEpoch(srcDir)
+	commit := gitCommit(srcDir)
+	arch := runtime.GOARCH
+
+	outputDir := filepath.Join(srcDir, "binaries")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to create output directory: %v\n", err)
+		exitFunc(1)
+		return
+	}
+	outputPath := filepath.Join(outputDir, "search-linux-"+arch)
+
+	fmt.Printf("   Building linux/%s... ", arch)
+	started := time.Now()
+	if err := buildWithDocker(srcDir, outputPath, "linux", arch, sourceDate, commit); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]"))
+	if _, err := release.WriteAttestations(outputPath, release.BuildParams{
+		Version:    config.Version,
+		Commit:     commit,
+		Image:      buildImage,
+		SourceDate: sourceDate,
+		Started:    started,
+		Finished:   time.Now(),
+	}); err != nil {
+		fmt.Printf(display.Emoji("⚠️", "[WARN]")+"  SBOM/provenance: %v\n", err)
+	}
+
+	// Build context: the binary, the Dockerfile and empty volume roots
+	ctxDir, err := os.MkdirTemp("", "search-docker-")
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	defer os.RemoveAll(ctxDir)
+
+	binary, err := os.ReadFile(outputPath)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(ctxDir, "search"), binary, 0755)
+	}
+	for _, dir := range []string{"config", "data"} {
+		if err == nil {
+			err = os.Mkdir(filepath.Join(ctxDir, dir), 0755)
+		}
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(ctxDir, "Dockerfile"), []byte(deploy.Dockerfile(deploy.ImageLabels{
+			Version:  config.Version,
+			Revision: commit,
+			Created:  sourceDate.Format(time.RFC3339),
+		})), 0644)
+	}
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to prepare build context: %v\n", err)
+		exitFunc(1)
+		return
+	}
+
+	tag := deploy.Image + ":" + config.Version
+	fmt.Printf("   Building image %s... ", tag)
+	cmd := exec.Command("docker", "build",
+		"--platform", "linux/"+arch,
+		"-t", tag,
+		"-t", deploy.Image+":latest",
+		ctxDir,
+	)
+	// Docker stamps image layers with SOURCE_DATE_EPOCH when BuildKit is used
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOURCE_DATE_EPOCH=%d", sourceDate.Unix()))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v: %s\n", err, output)
+		exitFunc(1)
+		return
+	}
+	fmt.Println(display.Emoji("✅", "[OK]"))
+	fmt.Println()
+	fmt.Printf(display.Emoji("✅", "[OK]")+" Image: %s (also tagged latest)\n", tag)
+	fmt.Printf("   Run it with: %s --init docker-compose && docker compose up -d\n", filepath.Base(os.Args[0]))
+}
+
+// runInitDockerCompose writes a compose file for the container image to
+// path (default docker-compose.yml), refusing to overwrite one
+func runInitDockerCompose(path string) {
+	if path == "" {
+		path = "docker-compose.yml"
+	}
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %s already exists; remove it or pass another file name\n", path)
+		exitFunc(1)
+		return
+	}
+
+	data, err := deploy.Compose(deploy.ComposeOptions{})
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+
+	fmt.Printf(display.Emoji("✅", "[OK]")+" Wrote %s\n", path)
+	fmt.Printf("   Image:   %s:latest\n", deploy.Image)
+	fmt.Printf("   Port:    %d (host) -> %d (container)\n", deploy.DefaultHostPort, deploy.ContainerPort)
+	fmt.Printf("   Volumes: search-config -> %s, search-data -> %s\n", deploy.ConfigVolume, deploy.DataVolume)
+	fmt.Println()
+	fmt.Println("Start it with: docker compose up -d")
+	fmt.Println("Find the operator token with: docker compose logs search")
+}
@@ -222,11 +222,18 @@ func captureStdout(t *testing.T, fn func()) string {
 	}
 	old := os.Stdout
 	os.Stdout = w
+	// Read while fn writes, so output larger than the pipe buffer does not
+	// block it
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
 	fn()
 	w.Close()
 	os.Stdout = old
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
+	<-done
 	r.Close()
 	return buf.String()
 }
@@ -252,6 +259,12 @@ func TestPrintVersion(t *testing.T) {
 		}
 	})
 
+	t.Run("contains Features: line", func(t *testing.T) {
+		if !strings.Contains(out, version.LabelFeatures+" "+version.FeatureString()) {
+			t.Errorf("printVersion() output missing %q\n%s", version.LabelFeatures, out)
+		}
+	})
+
 	t.Run("output is non-empty", func(t *testing.T) {
 		if strings.TrimSpace(out) == "" {
 			t.Error("printVersion() produced no output")
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apimgr/search/src/backup"
 	"github.com/apimgr/search/src/config"
@@ -855,3 +856,84 @@ func TestFindSourceDirAppPath(t *testing.T) {
 		t.Error("findSourceDir() returned empty dir when /app/go.mod exists")
 	}
 }
+
+func TestParseFuzzArgs(t *testing.T) {
+	names, fuzzTime, list, err := parseFuzzArgs([]string{"google", "--fuzztime", "30s", "FuzzBing"})
+	if err != nil || list || fuzzTime != 30*time.Second || strings.Join(names, ",") != "google,FuzzBing" {
+		t.Errorf("parseFuzzArgs() = %v, %v, %v, %v", names, fuzzTime, list, err)
+	}
+	if _, fuzzTime, list, err := parseFuzzArgs([]string{"--list", "--fuzztime=2m"}); err != nil || !list || fuzzTime != 2*time.Minute {
+		t.Errorf("parseFuzzArgs(--list --fuzztime=2m) = %v, %v, %v", fuzzTime, list, err)
+	}
+	for _, args := range [][]string{{"--fuzztime"}, {"--fuzztime", "soon"}, {"--fuzztime=-1m"}, {"--race"}} {
+		if _, _, _, err := parseFuzzArgs(args); err == nil {
+			t.Errorf("parseFuzzArgs(%q) error = nil", args)
+		}
+	}
+}
+
+func TestFindFuzzTargets(t *testing.T) {
+	all, err := findFuzzTargets("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]string{}
+	for _, target := range all {
+		found[target.Name] = target.Package
+	}
+	if found["FuzzParseOperators"] != "./src/search" || found["FuzzGoogle"] != "./src/search/engine" {
+		t.Errorf("findFuzzTargets() = %v, want the query and engine parsers", all)
+	}
+
+	selected, err := selectFuzzTargets(all, []string{"google", "FuzzParseOperators"})
+	if err != nil || len(selected) != 2 || selected[0].Name != "FuzzGoogle" {
+		t.Errorf("selectFuzzTargets() = %v, %v", selected, err)
+	}
+	if _, err := selectFuzzTargets(all, []string{"altavista"}); err == nil {
+		t.Error("selectFuzzTargets() accepted an unknown target")
+	}
+}
+
+func TestParseLoadArgs(t *testing.T) {
+	opts, err := parseLoadArgs(nil)
+	if err != nil || opts.QPS != 10 || opts.Duration != time.Minute || opts.Engines != "mock" {
+		t.Errorf("parseLoadArgs(nil) = %+v, %v", opts, err)
+	}
+	opts, err = parseLoadArgs([]string{"--qps", "50", "--duration=2m", "--url", "http://10.0.0.2:8080", "--queries", "q.txt"})
+	if err != nil || opts.QPS != 50 || opts.Duration != 2*time.Minute || opts.Engines != "real" || opts.QueriesFile != "q.txt" {
+		t.Errorf("parseLoadArgs() = %+v, %v; want real engines for a --url", opts, err)
+	}
+	for _, args := range [][]string{
+		{"--qps", "0"}, {"--qps"}, {"--duration", "soon"}, {"--engines", "fake"},
+		{"--engines", "mock", "--url", "http://10.0.0.2"}, {"--latency", "-1s"}, {"--race"},
+	} {
+		if _, err := parseLoadArgs(args); err == nil {
+			t.Errorf("parseLoadArgs(%q) error = nil", args)
+		}
+	}
+}
+
+func TestParseQualityArgs(t *testing.T) {
+	opts, err := parseQualityArgs([]string{"--suite", "q.yml", "--url=http://10.0.0.2:8080", "--baseline", "before.json", "--save", "after.json"})
+	if err != nil || opts.SuiteFile != "q.yml" || opts.URL != "http://10.0.0.2:8080" || opts.BaselineFile != "before.json" || opts.SaveFile != "after.json" {
+		t.Errorf("parseQualityArgs() = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{{"--suite"}, {"--save="}, {"--qps", "1"}} {
+		if _, err := parseQualityArgs(args); err == nil {
+			t.Errorf("parseQualityArgs(%q) error = nil", args)
+		}
+	}
+}
+
+func TestReadLoadQueries(t *testing.T) {
+	path := t.TempDir() + "/queries.txt"
+	os.WriteFile(path, []byte("# popular\ngolang\n\n  rust book  \n"), 0644)
+	queries, err := readLoadQueries(path)
+	if err != nil || strings.Join(queries, "|") != "golang|rust book" {
+		t.Errorf("readLoadQueries() = %q, %v", queries, err)
+	}
+	os.WriteFile(path, []byte("# nothing\n"), 0644)
+	if _, err := readLoadQueries(path); err == nil {
+		t.Error("readLoadQueries() accepted a file without queries")
+	}
+}
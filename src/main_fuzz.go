@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/common/display"
+)
+
+// fuzzPackages hold the fuzz targets: the query parser and the engine
+// parsers
+var fuzzPackages = []string{"./src/search", "./src/search/engine"}
+
+// defaultFuzzTime is how long each target is fuzzed
+const defaultFuzzTime = 10 * time.Minute
+
+// fuzzCacheVolume keeps the fuzzing corpus between sessions
+const fuzzCacheVolume = "search-fuzz-cache"
+
+var fuzzFuncPattern = regexp.MustCompile(`(?m)^func (Fuzz\w+)\(f \*testing\.F\)`)
+
+// fuzzTarget is one fuzz function and the package it lives in
+type fuzzTarget struct {
+	Name    string
+	Package string
+}
+
+// findFuzzTargets lists the fuzz functions in the test files of
+// fuzzPackages under srcDir, sorted by name
+func findFuzzTargets(srcDir string) ([]fuzzTarget, error) {
+	var targets []fuzzTarget
+	for _, pkg := range fuzzPackages {
+		files, err := filepath.Glob(filepath.Join(srcDir, pkg, "*_test.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range fuzzFuncPattern.FindAllSubmatch(data, -1) {
+				targets = append(targets, fuzzTarget{Name: string(m[1]), Package: pkg})
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+	return targets, nil
+}
+
+// selectFuzzTargets picks the targets named in names, matched without case
+// and with or without the Fuzz prefix ("google" selects FuzzGoogle). No
+// names selects every target.
+func selectFuzzTargets(all []fuzzTarget, names []string) ([]fuzzTarget, error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+	var selected []fuzzTarget
+	for _, name := range names {
+		want := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(name, "Fuzz"), "fuzz"))
+		found := false
+		for _, t := range all {
+			if strings.ToLower(strings.TrimPrefix(t.Name, "Fuzz")) == want {
+				selected = append(selected, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown fuzz target %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// parseFuzzArgs reads the arguments of --test fuzz: target names, --list
+// and --fuzztime <duration>
+func parseFuzzArgs(args []string) (names []string, fuzzTime time.Duration, list bool, err error) {
+	fuzzTime = defaultFuzzTime
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--list":
+			list = true
+		case arg == "--fuzztime" || strings.HasPrefix(arg, "--fuzztime="):
+			value, ok := strings.CutPrefix(arg, "--fuzztime=")
+			if !ok {
+				if i+1 >= len(args) {
+					return nil, 0, false, fmt.Errorf("--fuzztime needs a duration, e.g. 30m")
+				}
+				i++
+				value = args[i]
+			}
+			fuzzTime, err = time.ParseDuration(value)
+			if err != nil || fuzzTime <= 0 {
+				return nil, 0, false, fmt.Errorf("invalid --fuzztime %q", value)
+			}
+		case strings.HasPrefix(arg, "-"):
+			return nil, 0, false, fmt.Errorf("unknown option %s", arg)
+		default:
+			names = append(names, arg)
+		}
+	}
+	return names, fuzzTime, list, nil
+}
+
+// fuzzCommand runs one target in the build image per AI.md PART 7. The
+// corpus the fuzzer grows is kept in a Docker volume; inputs that fail are
+// written to testdata/fuzz/<target>/ in the checkout, where go test replays
+// them as regression seeds.
+func fuzzCommand(srcDir string, target fuzzTarget, fuzzTime time.Duration) *exec.Cmd {
+	return exec.Command("docker", "run", "--rm", "-t",
+		"-v", srcDir+":/app",
+		"-v", fuzzCacheVolume+":/root/.cache/go-build",
+		"-w", "/app",
+		"-e", "CGO_ENABLED=0",
+		buildImage,
+		"go", "test",
+		"-run", "^$",
+		"-fuzz", "^"+target.Name+"$",
+		"-fuzztime", fuzzTime.String(),
+		target.Package,
+	)
+}
+
+// runFuzz is the developer command search --test fuzz [target...]: it
+// fuzzes each selected target in turn for --fuzztime (default 10m). It
+// needs the source checkout and Docker.
+func runFuzz(args []string) {
+	names, fuzzTime, list, err := parseFuzzArgs(args)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		fmt.Println("   Usage: search --test fuzz [target...] [--fuzztime 10m] [--list]")
+		exitFunc(1)
+		return
+	}
+
+	srcDir, err := findSourceDir()
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot find source directory: %v\n", err)
+		fmt.Println("   Fuzzing runs the tests in the source checkout")
+		exitFunc(1)
+		return
+	}
+	if srcDir, err = filepath.Abs(srcDir); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+
+	all, err := findFuzzTargets(srcDir)
+	if err == nil && len(all) == 0 {
+		err = fmt.Errorf("no fuzz targets in %s", strings.Join(fuzzPackages, ", "))
+	}
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	if list {
+		for _, t := range all {
+			fmt.Printf("  %-24s %s\n", t.Name, t.Package)
+		}
+		return
+	}
+	targets, err := selectFuzzTargets(all, names)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v (see --test fuzz --list)\n", err)
+		exitFunc(1)
+		return
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		fmt.Println(display.Emoji("❌", "[ERROR]") + " Docker is required for fuzzing")
+		exitFunc(1)
+		return
+	}
+
+	fmt.Printf(display.Emoji("🧪", "[TEST]")+" Fuzzing %d targets for %s each (about %s)\n\n",
+		len(targets), fuzzTime, fuzzTime*time.Duration(len(targets)))
+	var failed []string
+	for i, t := range targets {
+		fmt.Printf(display.Emoji("🔎", "[FUZZ]")+" [%d/%d] %s (%s)\n", i+1, len(targets), t.Name, t.Package)
+		cmd := fuzzCommand(srcDir, t, fuzzTime)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			failed = append(failed, t.Name)
+			fmt.Printf(display.Emoji("❌", "[FAIL]")+" %s: %v\n", t.Name, err)
+		}
+		fmt.Println()
+	}
+
+	if len(failed) > 0 {
+		fmt.Printf(display.Emoji("⚠️", "[WARN]")+"  %d/%d targets failed: %s\n", len(failed), len(targets), strings.Join(failed, ", "))
+		fmt.Println("   Failing inputs are in testdata/fuzz/<target>/; commit them with the fix")
+		exitFunc(1)
+		return
+	}
+	fmt.Printf(display.Emoji("✅", "[OK]")+" No failures in %d targets\n", len(targets))
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/loadtest"
+	"github.com/apimgr/search/src/search/engine"
+	"github.com/apimgr/search/src/server"
+	"golang.org/x/term"
+)
+
+// loadOptions are the options of search --test load
+type loadOptions struct {
+	QPS      float64
+	Duration time.Duration
+	// Engines is "mock" (synthetic engines in a throwaway instance) or
+	// "real" (the running instance and its engines)
+	Engines string
+	URL     string
+	// Latency is the response time of the synthetic engines
+	Latency     time.Duration
+	QueriesFile string
+}
+
+// parseLoadArgs reads the arguments of --test load: --qps, --duration,
+// --engines mock|real, --url, --latency and --queries
+func parseLoadArgs(args []string) (loadOptions, error) {
+	opts := loadOptions{QPS: 10, Duration: time.Minute, Engines: "mock", Latency: 300 * time.Millisecond}
+	engines := ""
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--qps", "--duration", "--engines", "--url", "--latency", "--queries":
+		default:
+			return opts, fmt.Errorf("unknown option %s", args[i])
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		var err error
+		switch name {
+		case "--qps":
+			opts.QPS, err = strconv.ParseFloat(value, 64)
+			if err == nil && opts.QPS <= 0 {
+				err = fmt.Errorf("must be above zero")
+			}
+		case "--duration":
+			opts.Duration, err = time.ParseDuration(value)
+			if err == nil && opts.Duration <= 0 {
+				err = fmt.Errorf("must be above zero")
+			}
+		case "--latency":
+			opts.Latency, err = time.ParseDuration(value)
+			if err == nil && opts.Latency < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "--engines":
+			if value != "mock" && value != "real" {
+				err = fmt.Errorf("use mock or real")
+			}
+			engines = value
+		case "--url":
+			opts.URL = value
+		case "--queries":
+			opts.QueriesFile = value
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s %q: %v", name, value, err)
+		}
+	}
+
+	// A URL names a running instance, which searches its real engines
+	switch {
+	case engines == "mock" && opts.URL != "":
+		return opts, fmt.Errorf("--url tests a running instance; it cannot be used with --engines mock")
+	case engines != "":
+		opts.Engines = engines
+	case opts.URL != "":
+		opts.Engines = "real"
+	}
+	return opts, nil
+}
+
+// readLoadQueries reads one search per line, skipping blank lines and
+// # comments
+func readLoadQueries(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var queries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			queries = append(queries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("%s has no queries", path)
+	}
+	return queries, nil
+}
+
+// startLoadInstance starts an instance in this process that searches
+// synthetic engines, with a default configuration, data and logs in a
+// temporary directory, so the test neither touches the real instance nor
+// sends traffic to real engines. Rate limiting and Tor are off.
+func startLoadInstance(latency time.Duration) (baseURL string, stop func(), err error) {
+	dir, err := os.MkdirTemp("", "search-load-")
+	if err != nil {
+		return "", nil, err
+	}
+	config.SetConfigDirOverride(filepath.Join(dir, "config"))
+	config.SetDataDirOverride(filepath.Join(dir, "data"))
+	config.SetDatabaseDirOverride(filepath.Join(dir, "data", "db"))
+	config.SetLogDirOverride(filepath.Join(dir, "logs"))
+	config.SetCacheDirOverride(filepath.Join(dir, "cache"))
+	config.SetBackupDirOverride(filepath.Join(dir, "backups"))
+
+	cfg, err := config.Initialize()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	cfg.Server.Address = "127.0.0.1"
+	cfg.Server.RateLimit.Enabled = false
+	cfg.Server.Tor.Disabled = true
+	cfg.Server.SSL.Enabled = false
+	cfg.Server.Database = config.DatabaseDriverConfig{Driver: "sqlite"}
+	cfg.Server.Cache.Type = "memory"
+
+	srv := server.NewServerWithRegistry(cfg, engine.SyntheticRegistry(latency))
+	readyCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.StartHTTPServer(readyCh)
+	}()
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		os.RemoveAll(dir)
+		if err == nil {
+			err = fmt.Errorf("server stopped before it was ready")
+		}
+		return "", nil, err
+	case <-time.After(30 * time.Second):
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("server was not ready within 30s")
+	}
+
+	stop = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", cfg.Server.Port), stop, nil
+}
+
+// localInstanceURL is the address of the instance configured on this host
+func localInstanceURL() (string, error) {
+	cfg, err := config.Initialize()
+	if err != nil {
+		return "", err
+	}
+	host := cfg.Server.Address
+	if host == "" || host == "0.0.0.0" || host == "::" || host == "[::]" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(cfg.Server.Port)), nil
+}
+
+// runLoad is the command search --test load: it sends synthetic searches
+// at --qps for --duration and prints latency percentiles and the
+// instance's resource usage, to size hardware before going public.
+func runLoad(args []string) {
+	opts, err := parseLoadArgs(args)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		fmt.Println("   Usage: search --test load [--qps 10] [--duration 1m] [--engines mock|real]")
+		fmt.Println("                             [--url URL] [--latency 300ms] [--queries FILE]")
+		exitFunc(1)
+		return
+	}
+
+	var queries []string
+	if opts.QueriesFile != "" {
+		if queries, err = readLoadQueries(opts.QueriesFile); err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+			exitFunc(1)
+			return
+		}
+	}
+
+	run := loadtest.Options{QPS: opts.QPS, Duration: opts.Duration, Queries: queries}
+	var target string
+	if opts.Engines == "mock" {
+		fmt.Println(display.Emoji("🧪", "[TEST]") + " Starting a throwaway instance with synthetic engines...")
+		baseURL, stop, err := startLoadInstance(opts.Latency)
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot start the test instance: %v\n", err)
+			exitFunc(1)
+			return
+		}
+		defer stop()
+		run.BaseURL = baseURL
+		run.Sample = loadtest.ProcessUsage
+		target = fmt.Sprintf("synthetic engines answering in about %s", opts.Latency)
+	} else {
+		run.BaseURL = opts.URL
+		if run.BaseURL == "" {
+			if run.BaseURL, err = localInstanceURL(); err != nil {
+				fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read the local configuration: %v\n", err)
+				exitFunc(1)
+				return
+			}
+		}
+		run.Sample = loadtest.RemoteUsage(&http.Client{Timeout: 5 * time.Second}, run.BaseURL)
+		target = run.BaseURL + " with its real engines"
+		fmt.Println(display.Emoji("⚠️", "[WARN]") + "  Real engines receive every search; a high rate can get this host blocked by them")
+	}
+
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		run.Progress = func(sent, completed int) {
+			fmt.Printf("\r   %d sent, %d answered", sent, completed)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	fmt.Printf(display.Emoji("🔎", "[LOAD]")+" %s searches/s for %s against %s\n",
+		strconv.FormatFloat(opts.QPS, 'f', -1, 64), opts.Duration, target)
+	report, err := loadtest.Run(ctx, run)
+	if run.Progress != nil {
+		fmt.Print("\r\033[K")
+	}
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+	printLoadReport(report)
+}
+
+// printLoadReport prints the outcome of a load test
+func printLoadReport(r *loadtest.Report) {
+	fmt.Println()
+	fmt.Println(display.Emoji("📊", "[STATS]") + " Load test results")
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Printf("  Searches:    %d sent, %d succeeded, %d failed (%.2f%%)",
+		r.Sent, r.Succeeded, r.Sent-r.Succeeded, r.ErrorRate()*100)
+	if r.Dropped > 0 {
+		fmt.Printf(", %d not sent (too many in flight)", r.Dropped)
+	}
+	fmt.Println()
+	fmt.Printf("  Throughput:  %.1f successful searches/s over %s\n", r.Throughput, r.Elapsed.Round(100*time.Millisecond))
+
+	statuses := make([]int, 0, len(r.Status))
+	for status := range r.Status {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	parts := make([]string, 0, len(statuses)+1)
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%d × %d", status, r.Status[status]))
+	}
+	if r.Errors > 0 {
+		parts = append(parts, fmt.Sprintf("no answer × %d", r.Errors))
+	}
+	fmt.Printf("  Responses:   %s\n", strings.Join(parts, ", "))
+
+	l := r.Latency
+	ms := func(d time.Duration) string { return strconv.FormatInt(d.Milliseconds(), 10) + "ms" }
+	fmt.Printf("  Latency:     mean %s  p50 %s  p90 %s  p95 %s  p99 %s  max %s\n",
+		ms(l.Mean), ms(l.P50), ms(l.P90), ms(l.P95), ms(l.P99), ms(l.Max))
+
+	if r.Sampled {
+		fmt.Printf("  Goroutines:  %d at start, %d peak, %d at end\n",
+			r.UsageStart.Goroutines, r.UsagePeak.Goroutines, r.UsageEnd.Goroutines)
+		if r.UsagePeak.HeapBytes > 0 {
+			fmt.Printf("  Heap:        %s at start, %s peak, %s at end\n",
+				formatBytes(int64(r.UsageStart.HeapBytes)), formatBytes(int64(r.UsagePeak.HeapBytes)), formatBytes(int64(r.UsageEnd.HeapBytes)))
+		}
+		if cpu := r.UsageEnd.CPU - r.UsageStart.CPU; cpu > 0 && r.Elapsed > 0 {
+			fmt.Printf("  CPU:         %s, %.0f%% of one core (includes the load generator)\n",
+				cpu.Round(10*time.Millisecond), cpu.Seconds()/r.Elapsed.Seconds()*100)
+		}
+	}
+	fmt.Println(strings.Repeat("─", 60))
+
+	if r.Status[http.StatusTooManyRequests] > 0 {
+		fmt.Println(display.Emoji("💡", "[TIP]") + " 429 answers come from server.rate_limit; add this host to")
+		fmt.Println("   server.security.allowlist for the test to measure the server itself")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/observability"
+)
+
+// runObservability handles --observability <subcommand>. dir is where
+// export writes; empty means the current directory.
+func runObservability(subCmd, dir string) {
+	binaryName := filepath.Base(os.Args[0])
+
+	switch subCmd {
+	case "export":
+		if dir == "" {
+			dir = "."
+		}
+		written, err := observability.Export(dir)
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+			exitFunc(1)
+			return
+		}
+		for _, path := range written {
+			fmt.Printf(display.Emoji("✅", "[OK]")+" Wrote %s\n", path)
+		}
+		fmt.Printf("\nAdd %s to rule_files in prometheus.yml and import %s in Grafana.\n",
+			observability.RulesFile, observability.DashboardFile)
+	case "rules":
+		data, err := observability.RulesYAML()
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+			exitFunc(1)
+			return
+		}
+		os.Stdout.Write(data)
+	case "dashboard":
+		data, err := observability.DashboardJSON()
+		if err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+			exitFunc(1)
+			return
+		}
+		fmt.Println(string(data))
+	case "help", "--help":
+		printObservabilityHelp(binaryName)
+	default:
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Unknown observability subcommand: %s\n", subCmd)
+		fmt.Println("Valid subcommands: export, rules, dashboard, --help")
+		exitFunc(1)
+	}
+}
+
+func printObservabilityHelp(binaryName string) {
+	fmt.Printf(`Observability for %s
+
+Usage:
+  %s --observability export [dir]   Write %s and %s to dir (default: .)
+  %s --observability rules          Print the Prometheus alerting rules
+  %s --observability dashboard      Print the Grafana dashboard JSON
+  %s --observability --help         Show this help
+
+The rules alert on engines down, high error rates and TLS certificate
+expiry. The dashboard charts the same metrics; it asks for the Prometheus
+data source on import. Both read the metrics served on the metrics
+endpoint (server.metrics in server.yml), so enable it and point a
+Prometheus scrape job at it.
+`, binaryName, binaryName, observability.RulesFile, observability.DashboardFile,
+		binaryName, binaryName, binaryName)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/policy"
+)
+
+// runInitPolicy writes the starter privacy policy and terms of service to
+// server.yml, leaving a page that already has content alone
+func runInitPolicy() {
+	cfg, err := config.Initialize()
+	if err != nil {
+		slog.Error("Initialization failed", "err", err)
+		exitFunc(1)
+		return
+	}
+
+	pages := []struct {
+		name    string
+		path    string
+		content *string
+		enabled *bool
+		starter string
+	}{
+		{"privacy policy", "/server/privacy", &cfg.Server.Pages.Privacy.Content, &cfg.Server.Pages.Privacy.Enabled, policy.Privacy},
+		{"terms of service", "/server/terms", &cfg.Server.Pages.Terms.Content, &cfg.Server.Pages.Terms.Enabled, policy.Terms},
+	}
+	written := 0
+	for _, page := range pages {
+		if *page.content != "" {
+			fmt.Printf(display.Emoji("⚠️", "[WARN]")+"  The %s already has content; not replaced\n", page.name)
+			continue
+		}
+		*page.content = page.starter
+		*page.enabled = true
+		written++
+		fmt.Printf(display.Emoji("✅", "[OK]")+" Added the starter %s (%s)\n", page.name, page.path)
+	}
+	if written == 0 {
+		return
+	}
+
+	if err := cfg.Save(config.GetConfigPath()); err != nil {
+		slog.Error("Failed to save configuration", "err", err)
+		exitFunc(1)
+		return
+	}
+	fmt.Println()
+	fmt.Println("Edit the markdown under server.pages in", config.GetConfigPath())
+	fmt.Println("Placeholders such as {instance_name}, {contact} and {access_log} are filled from the config.")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"golang.org/x/term"
+)
+
+// applyInitPreset shows what the named preset changes in cfg and, once the
+// operator confirms on a terminal (or with --non-interactive), applies and
+// saves it. It reports whether --init should go on.
+func applyInitPreset(cfg *config.Config, name string) bool {
+	preset, err := config.LookupPreset(name)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		return false
+	}
+	changes, err := preset.Preview(cfg)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		return false
+	}
+
+	fmt.Printf(display.Emoji("🧩", "[PRESET]")+" %s: %s\n", preset.Name, preset.Description)
+	if len(changes) == 0 {
+		fmt.Println("   The configuration already matches this preset.")
+		fmt.Println()
+		return true
+	}
+	for _, change := range changes {
+		fmt.Printf("   %s: %s -> %s\n", change.Key, quoteSetting(change.From), quoteSetting(change.To))
+	}
+	fmt.Println()
+
+	if !flagNonInteract && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Printf("Apply %d changes? (y/N): ", len(changes))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		default:
+			fmt.Println("   Preset not applied.")
+			fmt.Println()
+			return true
+		}
+	}
+
+	preset.Apply(cfg)
+	if err := cfg.Save(config.GetConfigPath()); err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Failed to save configuration: %v\n", err)
+		return false
+	}
+	fmt.Printf(display.Emoji("✅", "[OK]")+" Applied preset %s\n", preset.Name)
+	fmt.Println()
+	return true
+}
+
+// quoteSetting shows an empty setting as "" so it is not mistaken for a
+// missing value
+func quoteSetting(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
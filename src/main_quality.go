@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/quality"
+	"golang.org/x/term"
+)
+
+// defaultQualitySuite is the suite file read from the config directory
+// when --suite is not given
+const defaultQualitySuite = "quality.yml"
+
+// qualityOptions are the options of search --test quality
+type qualityOptions struct {
+	SuiteFile string
+	URL       string
+	// BaselineFile is a report saved by an earlier run to compare against
+	BaselineFile string
+	// SaveFile is where this run's report is written
+	SaveFile string
+}
+
+// parseQualityArgs reads the arguments of --test quality: --suite, --url,
+// --baseline and --save
+func parseQualityArgs(args []string) (qualityOptions, error) {
+	var opts qualityOptions
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--suite", "--url", "--baseline", "--save":
+		default:
+			return opts, fmt.Errorf("unknown option %s", args[i])
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if value == "" {
+			return opts, fmt.Errorf("%s needs a value", name)
+		}
+		switch name {
+		case "--suite":
+			opts.SuiteFile = value
+		case "--url":
+			opts.URL = value
+		case "--baseline":
+			opts.BaselineFile = value
+		case "--save":
+			opts.SaveFile = value
+		}
+	}
+	return opts, nil
+}
+
+// runQuality is the command search --test quality: it runs the golden
+// ranking suite against an instance and reports the checks that fail, or
+// with --baseline the checks that passed in an earlier run and fail now.
+// It exits 1 on failures (regressions with --baseline), so it can gate a
+// ranking change before it is merged.
+func runQuality(args []string) {
+	opts, err := parseQualityArgs(args)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		fmt.Println("   Usage: search --test quality [--suite FILE] [--url URL]")
+		fmt.Println("                                [--baseline FILE] [--save FILE]")
+		exitFunc(1)
+		return
+	}
+
+	if opts.SuiteFile == "" {
+		opts.SuiteFile = filepath.Join(config.GetConfigDir(), defaultQualitySuite)
+	}
+	suite, err := quality.LoadSuite(opts.SuiteFile)
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read the suite: %v\n", err)
+		exitFunc(1)
+		return
+	}
+	var baseline *quality.Report
+	if opts.BaselineFile != "" {
+		if baseline, err = quality.LoadReport(opts.BaselineFile); err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read the baseline: %v\n", err)
+			exitFunc(1)
+			return
+		}
+	}
+	if opts.URL == "" {
+		if opts.URL, err = localInstanceURL(); err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot read the local configuration: %v\n", err)
+			exitFunc(1)
+			return
+		}
+	}
+
+	run := quality.Options{BaseURL: opts.URL, Suite: suite}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		run.Progress = func(done, total int, c quality.Case) {
+			fmt.Printf("\r\033[K   %d/%d %s", done+1, total, c.Label())
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	fmt.Printf(display.Emoji("🔎", "[QUALITY]")+" %d queries from %s against %s\n", len(suite.Cases), opts.SuiteFile, opts.URL)
+	report, err := quality.Run(ctx, run)
+	if run.Progress != nil {
+		fmt.Print("\r\033[K")
+	}
+	if err != nil {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" %v\n", err)
+		exitFunc(1)
+		return
+	}
+
+	if opts.SaveFile != "" {
+		if err := report.Save(opts.SaveFile); err != nil {
+			fmt.Printf(display.Emoji("❌", "[ERROR]")+" Cannot save the report: %v\n", err)
+			exitFunc(1)
+			return
+		}
+	}
+	ok := printQualityReport(report, baseline)
+	if opts.SaveFile != "" {
+		fmt.Printf(display.Emoji("💾", "[SAVED]")+" Report saved to %s; pass it as --baseline to compare a later run\n", opts.SaveFile)
+	}
+	if !ok {
+		exitFunc(1)
+	}
+}
+
+// printQualityReport prints each case's failing checks, the totals and,
+// with a baseline, the regressions and fixes. It returns false when the
+// run should fail: any failed check without a baseline, any regression
+// with one.
+func printQualityReport(r *quality.Report, baseline *quality.Report) bool {
+	fmt.Println()
+	fmt.Println(display.Emoji("📊", "[STATS]") + " Search quality results")
+	fmt.Println(strings.Repeat("─", 60))
+	for _, c := range r.Cases {
+		if c.Passed() {
+			fmt.Printf("  "+display.Emoji("✅", "[OK]")+" %s\n", c.Case.Label())
+			continue
+		}
+		fmt.Printf("  "+display.Emoji("❌", "[FAIL]")+" %s\n", c.Case.Label())
+		if c.Error != "" {
+			fmt.Printf("       search failed: %s\n", c.Error)
+			continue
+		}
+		for _, res := range c.Results {
+			if !res.Passed {
+				fmt.Printf("       %s: %s\n", res.Check, qualityRank(res.Rank))
+			}
+		}
+	}
+	passed, failed, errored := r.Counts()
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Printf("  Checks:      %d passed, %d failed", passed, failed)
+	if errored > 0 {
+		fmt.Printf(", %d queries not searched", errored)
+	}
+	fmt.Println()
+
+	if baseline == nil {
+		return failed == 0 && errored == 0
+	}
+	regressions, fixes := quality.Compare(baseline, r)
+	fmt.Printf("  Baseline:    %d regressions, %d fixed since %s\n",
+		len(regressions), len(fixes), baseline.RanAt.Local().Format("Jan 2 15:04"))
+	for _, change := range regressions {
+		fmt.Printf("       "+display.Emoji("📉", "-")+" %s: %s, %s → %s\n",
+			change.Case, change.Result.Check, qualityRank(change.BaselineRank), qualityRank(change.Result.Rank))
+	}
+	for _, change := range fixes {
+		fmt.Printf("       "+display.Emoji("📈", "+")+" %s: %s, %s → %s\n",
+			change.Case, change.Result.Check, qualityRank(change.BaselineRank), qualityRank(change.Result.Rank))
+	}
+	return len(regressions) == 0
+}
+
+// qualityRank describes a rank from a quality report
+func qualityRank(rank int) string {
+	if rank == 0 {
+		return "not found"
+	}
+	return fmt.Sprintf("#%d", rank)
+}
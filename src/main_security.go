@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/apimgr/search/src/common/display"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/security"
+	"github.com/apimgr/search/src/server"
+)
+
+// runSecurityTest is search --test security: it checks on this machine,
+// with this build, that secrets are compared in constant time and that
+// failed credential checks answer in uniform time, and exits 1 if not
+func runSecurityTest(args []string) {
+	if len(args) > 0 {
+		fmt.Printf(display.Emoji("❌", "[ERROR]")+" unknown option %s\n", args[0])
+		fmt.Println("   Usage: search --test security")
+		exitFunc(1)
+		return
+	}
+
+	fmt.Println(display.Emoji("🔐", "[SECURITY]") + " Checking token comparisons and failure timing...")
+	fmt.Println()
+	checks := append(security.SelfTest(), operatorTokenChecks()...)
+	failed := 0
+	for _, check := range checks {
+		mark := display.Emoji("✅", "[OK]")
+		if !check.OK {
+			mark = display.Emoji("❌", "[FAIL]")
+			failed++
+		}
+		fmt.Printf("%s %s: %s\n", mark, check.Name, check.Detail)
+	}
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf(display.Emoji("❌", "[FAIL]")+" %d of %d checks failed\n", failed, len(checks))
+		exitFunc(1)
+		return
+	}
+	fmt.Printf(display.Emoji("✅", "[OK]")+" All %d checks passed\n", len(checks))
+}
+
+// operatorTokenChecks runs the server's own operator token check against
+// the right token and wrong ones
+func operatorTokenChecks() []security.SelfTestCheck {
+	cfg := &config.Config{}
+	cfg.Server.Token = strings.Repeat("t", 64)
+	request := func(token string) func() bool {
+		req := httptest.NewRequest("GET", "/api/v1/server/tokens/self", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return func() bool { return server.ValidateOperatorToken(req, cfg) }
+	}
+	right := request(cfg.Server.Token)
+	first := request("x" + cfg.Server.Token[1:])
+	last := request(cfg.Server.Token[:63] + "x")
+	short := request(cfg.Server.Token[:8])
+	missing := request("")
+
+	correct := right() && !first() && !last() && !short() && !missing()
+	return []security.SelfTestCheck{
+		{Name: "Operator token check", OK: correct, Detail: "accepts server.token and rejects wrong, truncated and missing tokens"},
+		security.TimingCheck("Operator token timing", map[string]func(){
+			"right token":        func() { right() },
+			"first byte differs": func() { first() },
+			"last byte differs":  func() { last() },
+			"shorter":            func() { short() },
+		}),
+	}
+}
@@ -0,0 +1,105 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/version"
+)
+
+// CoinGeckoProvider fetches cryptocurrency quotes from the CoinGecko API
+type CoinGeckoProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewCoinGeckoProvider creates a provider for the CoinGecko API at baseURL
+func NewCoinGeckoProvider(baseURL string) *CoinGeckoProvider {
+	if baseURL == "" {
+		baseURL = "https://api.coingecko.com"
+	}
+	return &CoinGeckoProvider{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Name returns the provider name
+func (p *CoinGeckoProvider) Name() string {
+	return "CoinGecko"
+}
+
+// coinGeckoMarket is one entry of the /coins/markets response
+type coinGeckoMarket struct {
+	ID                       string  `json:"id"`
+	Symbol                   string  `json:"symbol"`
+	Name                     string  `json:"name"`
+	CurrentPrice             float64 `json:"current_price"`
+	PriceChange24h           float64 `json:"price_change_24h"`
+	PriceChangePercentage24h float64 `json:"price_change_percentage_24h"`
+	LastUpdated              string  `json:"last_updated"`
+	SparklineIn7d            struct {
+		Price []float64 `json:"price"`
+	} `json:"sparkline_in_7d"`
+}
+
+// Quote fetches the price, 24h change, and 7-day sparkline for a CoinGecko coin ID
+func (p *CoinGeckoProvider) Quote(ctx context.Context, id, currency string) (*Quote, error) {
+	params := url.Values{}
+	params.Set("vs_currency", currency)
+	params.Set("ids", id)
+	params.Set("sparkline", "true")
+	apiURL := p.baseURL + "/api/v3/coins/markets?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.BrowserUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CoinGecko returned status %d", resp.StatusCode)
+	}
+
+	var markets []coinGeckoMarket
+	if err := json.NewDecoder(resp.Body).Decode(&markets); err != nil {
+		return nil, err
+	}
+	if len(markets) == 0 {
+		return nil, ErrNotFound
+	}
+
+	m := markets[0]
+	updated, err := time.Parse(time.RFC3339, m.LastUpdated)
+	if err != nil {
+		updated = time.Now()
+	}
+
+	return &Quote{
+		Symbol:        strings.ToUpper(m.Symbol),
+		Name:          m.Name,
+		Kind:          KindCrypto,
+		Price:         m.CurrentPrice,
+		Change:        m.PriceChange24h,
+		ChangePercent: m.PriceChangePercentage24h,
+		Currency:      strings.ToUpper(currency),
+		Sparkline:     downsample(m.SparklineIn7d.Price, sparklinePoints),
+		Source:        p.Name(),
+		SourceURL:     "https://www.coingecko.com/en/coins/" + url.PathEscape(m.ID),
+		UpdatedAt:     updated,
+	}, nil
+}
@@ -0,0 +1,249 @@
+// Package market provides stock and cryptocurrency quotes for instant answers
+// and the homepage ticker widget. Quotes come from pluggable providers (a
+// Yahoo Finance-compatible chart API for equities, CoinGecko for crypto) and
+// are held in an in-memory cache that the scheduler refreshes periodically.
+package market
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// Kind identifies the asset class of a symbol
+type Kind string
+
+const (
+	KindStock  Kind = "stock"
+	KindCrypto Kind = "crypto"
+)
+
+// RefreshInterval is how often the scheduler refreshes cached quotes
+const RefreshInterval = 5 * time.Minute
+
+// idleEviction drops quotes nobody has asked for recently so the refresh
+// task does not keep polling symbols from one-off searches forever
+const idleEviction = time.Hour
+
+// sparklinePoints is the maximum number of points kept for a sparkline
+const sparklinePoints = 48
+
+// ErrNotFound is returned when a provider has no data for a symbol
+var ErrNotFound = errors.New("symbol not found")
+
+// Quote is a point-in-time price for a stock or cryptocurrency
+type Quote struct {
+	Symbol        string    `json:"symbol"`
+	Name          string    `json:"name"`
+	Kind          Kind      `json:"kind"`
+	Price         float64   `json:"price"`
+	Change        float64   `json:"change"`
+	ChangePercent float64   `json:"change_percent"`
+	Currency      string    `json:"currency"`
+	Exchange      string    `json:"exchange,omitempty"`
+	Sparkline     []float64 `json:"sparkline,omitempty"`
+	Source        string    `json:"source"`
+	SourceURL     string    `json:"source_url,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Provider fetches a quote for a provider-specific identifier
+type Provider interface {
+	Name() string
+	Quote(ctx context.Context, id, currency string) (*Quote, error)
+}
+
+// Service resolves symbols to providers and caches the results
+type Service struct {
+	stocks   Provider
+	crypto   Provider
+	currency string
+	ticker   []string
+
+	mu     sync.Mutex
+	quotes map[string]*cachedQuote
+}
+
+type cachedQuote struct {
+	quote     *Quote
+	fetchedAt time.Time
+	lastUsed  time.Time
+}
+
+// NewService creates a market data service from configuration
+func NewService(cfg *config.MarketConfig) *Service {
+	currency := strings.ToLower(cfg.Currency)
+	if currency == "" {
+		currency = "usd"
+	}
+	return &Service{
+		stocks:   NewYahooProvider(cfg.StocksURL),
+		crypto:   NewCoinGeckoProvider(cfg.CoinGeckoURL),
+		currency: currency,
+		ticker:   cfg.Ticker,
+		quotes:   make(map[string]*cachedQuote),
+	}
+}
+
+// Resolve classifies a user-supplied symbol. Known cryptocurrencies (by
+// ticker or CoinGecko ID) map to KindCrypto; everything else is treated as
+// an equity ticker.
+func Resolve(symbol string) (Kind, string) {
+	s := strings.TrimPrefix(strings.TrimSpace(symbol), "$")
+	if id, ok := coinIDs[strings.ToUpper(s)]; ok {
+		return KindCrypto, id
+	}
+	lower := strings.ToLower(s)
+	for _, id := range coinIDs {
+		if id == lower {
+			return KindCrypto, id
+		}
+	}
+	return KindStock, strings.ToUpper(s)
+}
+
+// IsKnownCrypto reports whether symbol is a cryptocurrency ticker or name
+func IsKnownCrypto(symbol string) bool {
+	kind, _ := Resolve(symbol)
+	return kind == KindCrypto
+}
+
+// Lookup returns the quote for symbol, from cache when it is fresh enough
+func (s *Service) Lookup(ctx context.Context, symbol string) (*Quote, error) {
+	kind, id := Resolve(symbol)
+	if id == "" {
+		return nil, ErrNotFound
+	}
+	key := string(kind) + ":" + id
+
+	now := time.Now()
+	s.mu.Lock()
+	if cached, ok := s.quotes[key]; ok {
+		cached.lastUsed = now
+		// The refresh task keeps entries current; allow one missed run
+		if now.Sub(cached.fetchedAt) < 2*RefreshInterval {
+			s.mu.Unlock()
+			return cached.quote, nil
+		}
+	}
+	s.mu.Unlock()
+
+	quote, err := s.fetch(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.quotes[key] = &cachedQuote{quote: quote, fetchedAt: now, lastUsed: now}
+	s.mu.Unlock()
+	return quote, nil
+}
+
+// Quotes returns quotes for each symbol, skipping ones that fail
+func (s *Service) Quotes(ctx context.Context, symbols []string) []*Quote {
+	quotes := make([]*Quote, 0, len(symbols))
+	for _, symbol := range symbols {
+		if q, err := s.Lookup(ctx, symbol); err == nil {
+			quotes = append(quotes, q)
+		}
+	}
+	return quotes
+}
+
+// Ticker returns the operator-configured ticker symbols
+func (s *Service) Ticker() []string {
+	return s.ticker
+}
+
+// Refresh re-fetches the configured ticker and every recently used quote.
+// It is run by the scheduler's market_refresh task.
+func (s *Service) Refresh(ctx context.Context) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	keys := make(map[string]bool)
+	for key, cached := range s.quotes {
+		if now.Sub(cached.lastUsed) > idleEviction {
+			delete(s.quotes, key)
+			continue
+		}
+		keys[key] = true
+	}
+	s.mu.Unlock()
+
+	for _, symbol := range s.ticker {
+		kind, id := Resolve(symbol)
+		keys[string(kind)+":"+id] = true
+	}
+
+	var errs []error
+	for key := range keys {
+		kind, id, _ := strings.Cut(key, ":")
+		quote, err := s.fetch(ctx, Kind(kind), id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.mu.Lock()
+		lastUsed := now
+		if cached, ok := s.quotes[key]; ok {
+			lastUsed = cached.lastUsed
+		}
+		s.quotes[key] = &cachedQuote{quote: quote, fetchedAt: now, lastUsed: lastUsed}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) fetch(ctx context.Context, kind Kind, id string) (*Quote, error) {
+	if kind == KindCrypto {
+		return s.crypto.Quote(ctx, id, s.currency)
+	}
+	return s.stocks.Quote(ctx, id, s.currency)
+}
+
+// downsample reduces points to at most n values, keeping the last point
+func downsample(points []float64, n int) []float64 {
+	if len(points) <= n {
+		return points
+	}
+	out := make([]float64, 0, n)
+	step := float64(len(points)-1) / float64(n-1)
+	for i := 0; i < n; i++ {
+		out = append(out, points[int(float64(i)*step+0.5)])
+	}
+	return out
+}
+
+// coinIDs maps common cryptocurrency tickers to CoinGecko IDs
+var coinIDs = map[string]string{
+	"BTC":   "bitcoin",
+	"ETH":   "ethereum",
+	"USDT":  "tether",
+	"BNB":   "binancecoin",
+	"XRP":   "ripple",
+	"USDC":  "usd-coin",
+	"SOL":   "solana",
+	"ADA":   "cardano",
+	"DOGE":  "dogecoin",
+	"TRX":   "tron",
+	"DOT":   "polkadot",
+	"MATIC": "matic-network",
+	"SHIB":  "shiba-inu",
+	"LTC":   "litecoin",
+	"AVAX":  "avalanche-2",
+	"LINK":  "chainlink",
+	"XLM":   "stellar",
+	"XMR":   "monero",
+	"ALGO":  "algorand",
+	"ATOM":  "cosmos",
+	"BCH":   "bitcoin-cash",
+	"ETC":   "ethereum-classic",
+	"TON":   "the-open-network",
+	"UNI":   "uniswap",
+	"ZEC":   "zcash",
+}
@@ -0,0 +1,137 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apimgr/search/src/config"
+)
+
+func newTestServer(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		switch {
+		case r.URL.Path == "/v8/finance/chart/AAPL":
+			w.Write([]byte(`{"chart":{"result":[{"meta":{"symbol":"AAPL","currency":"USD","exchangeName":"NMS",
+				"shortName":"Apple Inc.","regularMarketPrice":110,"regularMarketTime":1700000000,"chartPreviousClose":100},
+				"indicators":{"quote":[{"close":[100,null,105,110]}]}}],"error":null}}`))
+		case strings.HasPrefix(r.URL.Path, "/v8/finance/chart/"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"chart":{"result":null,"error":{"code":"Not Found","description":"No data found"}}}`))
+		case r.URL.Path == "/api/v3/coins/markets":
+			if r.URL.Query().Get("ids") != "bitcoin" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"id":"bitcoin","symbol":"btc","name":"Bitcoin","current_price":50000,
+				"price_change_24h":-500,"price_change_percentage_24h":-0.99,"last_updated":"2026-01-01T00:00:00Z",
+				"sparkline_in_7d":{"price":[51000,50500,50000]}}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantKind Kind
+		wantID   string
+	}{
+		{"BTC", KindCrypto, "bitcoin"},
+		{"btc", KindCrypto, "bitcoin"},
+		{"ethereum", KindCrypto, "ethereum"},
+		{"$eth", KindCrypto, "ethereum"},
+		{"aapl", KindStock, "AAPL"},
+		{"BRK-B", KindStock, "BRK-B"},
+		{" $TSLA ", KindStock, "TSLA"},
+	}
+	for _, tt := range tests {
+		kind, id := Resolve(tt.input)
+		if kind != tt.wantKind || id != tt.wantID {
+			t.Errorf("Resolve(%q) = (%q, %q), want (%q, %q)", tt.input, kind, id, tt.wantKind, tt.wantID)
+		}
+	}
+}
+
+func TestServiceLookup(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	svc := NewService(&config.MarketConfig{StocksURL: server.URL, CoinGeckoURL: server.URL})
+
+	stock, err := svc.Lookup(context.Background(), "aapl")
+	if err != nil {
+		t.Fatalf("Lookup(aapl) error = %v", err)
+	}
+	if stock.Kind != KindStock || stock.Price != 110 || stock.Change != 10 || stock.ChangePercent != 10 {
+		t.Errorf("stock quote = %+v", stock)
+	}
+	if len(stock.Sparkline) != 3 {
+		t.Errorf("Sparkline = %v, want null closes skipped", stock.Sparkline)
+	}
+
+	coin, err := svc.Lookup(context.Background(), "BTC")
+	if err != nil {
+		t.Fatalf("Lookup(BTC) error = %v", err)
+	}
+	if coin.Kind != KindCrypto || coin.Symbol != "BTC" || coin.Currency != "USD" || coin.Change != -500 {
+		t.Errorf("crypto quote = %+v", coin)
+	}
+
+	before := atomic.LoadInt32(&hits)
+	if _, err := svc.Lookup(context.Background(), "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&hits) != before {
+		t.Error("cached quote was fetched again")
+	}
+
+	if _, err := svc.Lookup(context.Background(), "NOPE"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup(NOPE) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServiceRefresh(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	svc := NewService(&config.MarketConfig{
+		StocksURL:    server.URL,
+		CoinGeckoURL: server.URL,
+		Ticker:       []string{"AAPL", "BTC"},
+	})
+
+	if err := svc.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("Refresh() made %d requests, want 2", hits)
+	}
+
+	// Ticker symbols are served from the warm cache
+	quotes := svc.Quotes(context.Background(), svc.Ticker())
+	if len(quotes) != 2 || hits != 2 {
+		t.Errorf("Quotes() = %d quotes after %d requests, want 2 from cache", len(quotes), hits)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	points := make([]float64, 100)
+	for i := range points {
+		points[i] = float64(i)
+	}
+	got := downsample(points, 10)
+	if len(got) != 10 || got[0] != 0 || got[9] != 99 {
+		t.Errorf("downsample() = %v", got)
+	}
+	if short := downsample(points[:5], 10); len(short) != 5 {
+		t.Errorf("downsample() of short series changed length to %d", len(short))
+	}
+}
@@ -0,0 +1,156 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/version"
+)
+
+// YahooProvider fetches equity quotes from a Yahoo Finance-compatible
+// v8 chart API. Intraday closes from the same response form the sparkline.
+type YahooProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewYahooProvider creates a provider for the chart API at baseURL
+func NewYahooProvider(baseURL string) *YahooProvider {
+	if baseURL == "" {
+		baseURL = "https://query1.finance.yahoo.com"
+	}
+	return &YahooProvider{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Name returns the provider name
+func (p *YahooProvider) Name() string {
+	return "Yahoo Finance"
+}
+
+// yahooChartResponse is the subset of the v8 chart response we use
+type yahooChartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				Symbol             string  `json:"symbol"`
+				Currency           string  `json:"currency"`
+				ExchangeName       string  `json:"exchangeName"`
+				ShortName          string  `json:"shortName"`
+				LongName           string  `json:"longName"`
+				RegularMarketPrice float64 `json:"regularMarketPrice"`
+				RegularMarketTime  int64   `json:"regularMarketTime"`
+				ChartPreviousClose float64 `json:"chartPreviousClose"`
+				PreviousClose      float64 `json:"previousClose"`
+			} `json:"meta"`
+			Indicators struct {
+				Quote []struct {
+					// Gaps in trading are reported as null
+					Close []*float64 `json:"close"`
+				} `json:"quote"`
+			} `json:"indicators"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+// Quote fetches the latest price and intraday sparkline for symbol.
+// Yahoo reports prices in the listing currency, so currency is ignored.
+func (p *YahooProvider) Quote(ctx context.Context, symbol, currency string) (*Quote, error) {
+	apiURL := p.baseURL + "/v8/finance/chart/" + url.PathEscape(symbol) + "?range=1d&interval=5m"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.BrowserUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chart API returned status %d", resp.StatusCode)
+	}
+
+	var chart yahooChartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chart); err != nil {
+		return nil, err
+	}
+	if chart.Chart.Error != nil {
+		if chart.Chart.Error.Code == "Not Found" {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("chart API error: %s", chart.Chart.Error.Description)
+	}
+	if len(chart.Chart.Result) == 0 {
+		return nil, ErrNotFound
+	}
+
+	result := chart.Chart.Result[0]
+	meta := result.Meta
+	if meta.RegularMarketPrice == 0 {
+		return nil, ErrNotFound
+	}
+
+	previous := meta.ChartPreviousClose
+	if previous == 0 {
+		previous = meta.PreviousClose
+	}
+
+	var closes []float64
+	if len(result.Indicators.Quote) > 0 {
+		for _, c := range result.Indicators.Quote[0].Close {
+			if c != nil {
+				closes = append(closes, *c)
+			}
+		}
+	}
+
+	name := meta.ShortName
+	if name == "" {
+		name = meta.LongName
+	}
+	if name == "" {
+		name = meta.Symbol
+	}
+
+	q := &Quote{
+		Symbol:    meta.Symbol,
+		Name:      name,
+		Kind:      KindStock,
+		Price:     meta.RegularMarketPrice,
+		Currency:  strings.ToUpper(meta.Currency),
+		Exchange:  meta.ExchangeName,
+		Sparkline: downsample(closes, sparklinePoints),
+		Source:    p.Name(),
+		SourceURL: "https://finance.yahoo.com/quote/" + url.PathEscape(meta.Symbol),
+		UpdatedAt: time.Unix(meta.RegularMarketTime, 0),
+	}
+	if previous != 0 {
+		q.Change = q.Price - previous
+		q.ChangePercent = q.Change / previous * 100
+	}
+	if meta.RegularMarketTime == 0 {
+		q.UpdatedAt = time.Now()
+	}
+	return q, nil
+}
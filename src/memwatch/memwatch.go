@@ -0,0 +1,355 @@
+// Package memwatch keeps the process inside its memory limit on small
+// hosts. A watchdog reads the resident set size and the Go heap at an
+// interval and, as usage nears the limit, shrinks the registered in-memory
+// caches and lowers GOGC so the garbage collector runs more often. Both
+// return to their configured values once the pressure eases.
+package memwatch
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSoftPercent = 70
+	defaultHardPercent = 85
+	defaultInterval    = 10 * time.Second
+
+	// hysteresis is how many percentage points usage must fall below a
+	// threshold before the level drops, so usage hovering at a threshold
+	// does not resize the caches on every check
+	hysteresis = 5
+
+	// GOGC at each level; a lower configured GOGC is kept
+	elevatedGCPercent = 50
+	criticalGCPercent = 20
+)
+
+// Level is the memory pressure the watchdog acts on
+type Level int
+
+const (
+	// Normal: caches at their configured sizes, GOGC as configured
+	Normal Level = iota
+	// Elevated: usage over the soft threshold; caches at half size
+	Elevated
+	// Critical: usage over the hard threshold; caches at a fifth, and
+	// freed memory is returned to the operating system
+	Critical
+)
+
+// String returns the level name used in logs and the API
+func (l Level) String() string {
+	switch l {
+	case Elevated:
+		return "elevated"
+	case Critical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// MarshalText encodes the level by name
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// Options configures a watchdog
+type Options struct {
+	// Limit is the memory available to the process in bytes; 0 detects
+	// the cgroup limit or, without one, the host's memory
+	Limit uint64
+	// SoftPercent and HardPercent of the limit start the elevated and
+	// critical levels (default 70 and 85)
+	SoftPercent int
+	HardPercent int
+	// Interval between checks (default 10s)
+	Interval time.Duration
+}
+
+// Status is the watchdog's latest reading and the limits in force
+type Status struct {
+	Level Level `json:"level"`
+	// LimitBytes is the memory limit; LimitSource is "config", "cgroup",
+	// "host" or empty when no limit is known, which leaves the level at
+	// normal
+	LimitBytes  uint64 `json:"limit_bytes"`
+	LimitSource string `json:"limit_source"`
+	SoftPercent int    `json:"soft_percent"`
+	HardPercent int    `json:"hard_percent"`
+	// RSSBytes is the process's resident memory, HeapBytes the live Go
+	// heap; UsedPercent is RSSBytes as a share of LimitBytes
+	RSSBytes    uint64  `json:"rss_bytes"`
+	HeapBytes   uint64  `json:"heap_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+	// GCPercent is the GOGC in force; GoMemoryLimit the runtime's soft
+	// memory limit (math.MaxInt64 when unset)
+	GCPercent     int           `json:"gc_percent"`
+	GoMemoryLimit int64         `json:"go_memory_limit_bytes"`
+	Caches        []CacheStatus `json:"caches"`
+	CheckedAt     time.Time     `json:"checked_at"`
+}
+
+// CacheStatus is one managed cache's configured and current capacity
+type CacheStatus struct {
+	Name     string `json:"name"`
+	BaseSize int    `json:"base_size"`
+	Size     int    `json:"size"`
+}
+
+// Watchdog watches memory usage and adjusts the caches and GOGC
+type Watchdog struct {
+	mu          sync.Mutex
+	opts        Options
+	limit       uint64
+	limitSource string
+	baseGC      int
+	gcPercent   int
+	level       Level
+	caches      []*managedCache
+	last        Status
+
+	// Seams for tests
+	readUsage    func() (rss, heap uint64)
+	setGCPercent func(int) int
+	freeMemory   func()
+}
+
+// setMemoryLimit sets the runtime's soft memory limit; a variable so tests
+// do not limit the test binary
+var setMemoryLimit = debug.SetMemoryLimit
+
+// managedCache is a registered cache and its configured capacity
+type managedCache struct {
+	name   string
+	base   int
+	size   int
+	resize func(int)
+}
+
+// New creates a watchdog. GOGC starts from the GOGC environment variable
+// (default 100). Unless GOMEMLIMIT is set, the runtime's soft memory limit
+// is set to the hard threshold, so the collector also works harder before
+// the process reaches it.
+func New(opts Options) *Watchdog {
+	w := &Watchdog{
+		baseGC:       envGCPercent(),
+		readUsage:    readUsage,
+		setGCPercent: debug.SetGCPercent,
+		freeMemory:   debug.FreeOSMemory,
+	}
+	w.gcPercent = w.baseGC
+	w.SetOptions(opts)
+	return w
+}
+
+// SetOptions applies new options, e.g. after a config reload. The level is
+// re-evaluated at the next check.
+func (w *Watchdog) SetOptions(opts Options) {
+	if opts.SoftPercent <= 0 || opts.SoftPercent >= 100 {
+		opts.SoftPercent = defaultSoftPercent
+	}
+	if opts.HardPercent <= 0 || opts.HardPercent > 100 {
+		opts.HardPercent = defaultHardPercent
+	}
+	if opts.SoftPercent >= opts.HardPercent {
+		opts.SoftPercent, opts.HardPercent = defaultSoftPercent, defaultHardPercent
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+
+	limit, source := opts.Limit, "config"
+	if limit == 0 {
+		limit, source = DetectLimit()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.opts = opts
+	w.limit, w.limitSource = limit, source
+	if limit > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		setMemoryLimit(int64(float64(limit) * float64(opts.HardPercent) / 100))
+	}
+}
+
+// Register puts a cache under the watchdog. size is its configured
+// capacity and resize sets a new one, evicting what no longer fits; it is
+// called at once when memory is already under pressure.
+func (w *Watchdog) Register(name string, size int, resize func(int)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c := &managedCache{name: name, base: size, size: size, resize: resize}
+	w.caches = append(w.caches, c)
+	if w.level != Normal {
+		c.size = cacheSize(size, w.level)
+		resize(c.size)
+	}
+}
+
+// Run checks memory at the configured interval until ctx ends
+func (w *Watchdog) Run(ctx context.Context) {
+	w.Check()
+	timer := time.NewTimer(w.interval())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		w.Check()
+		timer.Reset(w.interval())
+	}
+}
+
+func (w *Watchdog) interval() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.opts.Interval
+}
+
+// Check reads memory usage once, moves to the matching level and returns
+// the new status
+func (w *Watchdog) Check() Status {
+	rss, heap := w.readUsage()
+
+	w.mu.Lock()
+	var used float64
+	if w.limit > 0 {
+		used = float64(rss) * 100 / float64(w.limit)
+	}
+	level := w.levelFor(used)
+	changed := level != w.level
+	if changed {
+		w.apply(level)
+	}
+	w.last = Status{
+		Level:         w.level,
+		LimitBytes:    w.limit,
+		LimitSource:   w.limitSource,
+		SoftPercent:   w.opts.SoftPercent,
+		HardPercent:   w.opts.HardPercent,
+		RSSBytes:      rss,
+		HeapBytes:     heap,
+		UsedPercent:   math.Round(used*10) / 10,
+		GCPercent:     w.gcPercent,
+		GoMemoryLimit: debug.SetMemoryLimit(-1),
+		Caches:        w.cacheStatus(),
+		CheckedAt:     time.Now(),
+	}
+	status := w.last
+	w.mu.Unlock()
+
+	if changed {
+		log := slog.Info
+		if level > Normal {
+			log = slog.Warn
+		}
+		log("memory pressure changed",
+			"level", level.String(),
+			"used_percent", status.UsedPercent,
+			"rss_bytes", rss,
+			"limit_bytes", status.LimitBytes,
+			"gc_percent", status.GCPercent)
+		if level == Critical {
+			w.freeMemory()
+		}
+	}
+	return status
+}
+
+// Status returns the latest reading without checking again
+func (w *Watchdog) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.last
+	status.Caches = w.cacheStatus()
+	return status
+}
+
+// levelFor returns the level for usage, in percent of the limit. Levels
+// rise at their threshold and fall only hysteresis points below it.
+func (w *Watchdog) levelFor(used float64) Level {
+	soft, hard := float64(w.opts.SoftPercent), float64(w.opts.HardPercent)
+	switch {
+	case used >= hard, w.level == Critical && used >= hard-hysteresis:
+		return Critical
+	case used >= soft, w.level >= Elevated && used >= soft-hysteresis:
+		return Elevated
+	}
+	return Normal
+}
+
+// apply resizes the caches and sets GOGC for level; w.mu is held
+func (w *Watchdog) apply(level Level) {
+	w.level = level
+	gc := gcPercent(w.baseGC, level)
+	if gc != w.gcPercent {
+		w.setGCPercent(gc)
+		w.gcPercent = gc
+	}
+	for _, c := range w.caches {
+		if size := cacheSize(c.base, level); size != c.size {
+			c.size = size
+			c.resize(size)
+		}
+	}
+}
+
+func (w *Watchdog) cacheStatus() []CacheStatus {
+	caches := make([]CacheStatus, 0, len(w.caches))
+	for _, c := range w.caches {
+		caches = append(caches, CacheStatus{Name: c.name, BaseSize: c.base, Size: c.size})
+	}
+	return caches
+}
+
+// cacheSize is a cache's capacity at level: all of base when normal, half
+// when elevated and a fifth when critical, never below one entry
+func cacheSize(base int, level Level) int {
+	switch level {
+	case Elevated:
+		return max(base/2, 1)
+	case Critical:
+		return max(base/5, 1)
+	}
+	return base
+}
+
+// gcPercent is GOGC at level; base is the configured GOGC, -1 when the
+// collector is off
+func gcPercent(base int, level Level) int {
+	target := base
+	switch level {
+	case Elevated:
+		target = elevatedGCPercent
+	case Critical:
+		target = criticalGCPercent
+	}
+	if base >= 0 && base < target {
+		return base
+	}
+	return target
+}
+
+// envGCPercent reads GOGC as the runtime does: "off" is -1, and anything
+// unset or invalid is 100
+func envGCPercent() int {
+	value := strings.TrimSpace(os.Getenv("GOGC"))
+	if strings.EqualFold(value, "off") {
+		return -1
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return n
+	}
+	return 100
+}
@@ -0,0 +1,154 @@
+package memwatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestWatchdog returns a watchdog with a 1000-byte limit whose usage
+// reading is *rss, and records the GOGC it sets and memory it frees
+func newTestWatchdog(t *testing.T, rss *uint64) (*Watchdog, *int, *int) {
+	t.Helper()
+	saved := setMemoryLimit
+	setMemoryLimit = func(int64) int64 { return 0 }
+	t.Cleanup(func() { setMemoryLimit = saved })
+
+	w := New(Options{Limit: 1000})
+	w.baseGC = 100
+	w.gcPercent = 100
+	gc, frees := new(int), new(int)
+	*gc = 100
+	w.readUsage = func() (uint64, uint64) { return *rss, *rss / 2 }
+	w.setGCPercent = func(n int) int { prev := *gc; *gc = n; return prev }
+	w.freeMemory = func() { *frees++ }
+	return w, gc, frees
+}
+
+func TestLevelsResizeCachesAndGC(t *testing.T) {
+	var rss uint64 = 100
+	w, gc, frees := newTestWatchdog(t, &rss)
+	var results, verdicts int
+	w.Register("results", 1000, func(n int) { results = n })
+	w.Register("verdicts", 3, func(n int) { verdicts = n })
+
+	for _, step := range []struct {
+		rss      uint64
+		level    Level
+		results  int
+		verdicts int
+		gc       int
+	}{
+		{100, Normal, 0, 0, 100},
+		{720, Elevated, 500, 1, 50},
+		// Below the soft threshold but within the hysteresis band
+		{680, Elevated, 500, 1, 50},
+		{900, Critical, 200, 1, 20},
+		{820, Critical, 200, 1, 20},
+		{790, Elevated, 500, 1, 50},
+		{600, Normal, 1000, 3, 100},
+	} {
+		rss = step.rss
+		status := w.Check()
+		if status.Level != step.level || results != step.results || verdicts != step.verdicts || *gc != step.gc {
+			t.Fatalf("at %d bytes: level %s, results %d, verdicts %d, GOGC %d; want %s, %d, %d, %d",
+				step.rss, status.Level, results, verdicts, *gc, step.level, step.results, step.verdicts, step.gc)
+		}
+	}
+	if *frees != 1 {
+		t.Errorf("memory freed %d times, want once on entering critical", *frees)
+	}
+}
+
+func TestRegisterUnderPressure(t *testing.T) {
+	var rss uint64 = 950
+	w, _, _ := newTestWatchdog(t, &rss)
+	w.Check()
+
+	size := 0
+	w.Register("index", 10000, func(n int) { size = n })
+	if size != 2000 {
+		t.Fatalf("cache registered at critical has size %d, want 2000", size)
+	}
+	status := w.Status()
+	if len(status.Caches) != 1 || status.Caches[0].BaseSize != 10000 || status.Caches[0].Size != 2000 {
+		t.Fatalf("caches = %+v", status.Caches)
+	}
+}
+
+func TestGCPercentKeepsLowerSetting(t *testing.T) {
+	for _, tt := range []struct {
+		base  int
+		level Level
+		want  int
+	}{
+		{100, Normal, 100},
+		{100, Elevated, 50},
+		{30, Elevated, 30},
+		{30, Critical, 20},
+		{10, Critical, 10},
+		{-1, Normal, -1},
+		{-1, Elevated, 50},
+	} {
+		if got := gcPercent(tt.base, tt.level); got != tt.want {
+			t.Errorf("gcPercent(%d, %s) = %d, want %d", tt.base, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSetOptionsDefaults(t *testing.T) {
+	var rss uint64
+	w, _, _ := newTestWatchdog(t, &rss)
+	w.SetOptions(Options{Limit: 1000, SoftPercent: 90, HardPercent: 80})
+	if w.opts.SoftPercent != defaultSoftPercent || w.opts.HardPercent != defaultHardPercent {
+		t.Errorf("soft %d, hard %d; a soft threshold above the hard one should fall back to the defaults",
+			w.opts.SoftPercent, w.opts.HardPercent)
+	}
+	if w.opts.Interval != defaultInterval || w.limitSource != "config" {
+		t.Errorf("interval %s, limit source %q", w.opts.Interval, w.limitSource)
+	}
+}
+
+func TestDetectLimit(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	saved := []string{meminfoPath, cgroupV2Path, cgroupV1Path}
+	t.Cleanup(func() { meminfoPath, cgroupV2Path, cgroupV1Path = saved[0], saved[1], saved[2] })
+
+	meminfoPath = write("meminfo", "MemTotal:        2048000 kB\nMemFree:          100000 kB\n")
+	cgroupV1Path = filepath.Join(dir, "missing")
+
+	cgroupV2Path = write("memory.max", "536870912\n")
+	if limit, source := DetectLimit(); limit != 536870912 || source != "cgroup" {
+		t.Errorf("cgroup v2 limit: %d from %q", limit, source)
+	}
+
+	cgroupV2Path = write("memory.max", "max\n")
+	if limit, source := DetectLimit(); limit != 2048000*1024 || source != "host" {
+		t.Errorf("unlimited cgroup: %d from %q, want the host's memory", limit, source)
+	}
+
+	cgroupV2Path = filepath.Join(dir, "missing")
+	cgroupV1Path = write("memory.limit_in_bytes", "9223372036854771712\n")
+	if limit, source := DetectLimit(); source != "host" || limit != 2048000*1024 {
+		t.Errorf("unlimited cgroup v1: %d from %q, want the host's memory", limit, source)
+	}
+}
+
+func TestReadRSS(t *testing.T) {
+	saved := statmPath
+	t.Cleanup(func() { statmPath = saved })
+	statmPath = filepath.Join(t.TempDir(), "statm")
+	if err := os.WriteFile(statmPath, []byte("5000 300 100 10 0 200 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readRSS(), uint64(300*os.Getpagesize()); got != want {
+		t.Errorf("readRSS() = %d, want %d", got, want)
+	}
+}
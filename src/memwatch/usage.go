@@ -0,0 +1,118 @@
+package memwatch
+
+import (
+	"bufio"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+)
+
+// Files read for usage and limits; variables so tests can point them at
+// fixtures. They exist on Linux only: elsewhere the Go runtime's figures
+// are used and the limit must be configured.
+var (
+	statmPath    = "/proc/self/statm"
+	meminfoPath  = "/proc/meminfo"
+	cgroupV2Path = "/sys/fs/cgroup/memory.max"
+	cgroupV1Path = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+)
+
+// readUsage returns the resident set size and the live Go heap. Without
+// /proc the resident size is estimated as the memory the runtime holds
+// from the operating system.
+func readUsage() (rss, heap uint64) {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/heap/objects:bytes"},
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	heap = sampleValue(samples[0])
+	if rss = readRSS(); rss == 0 {
+		rss = sampleValue(samples[1]) - min(sampleValue(samples[2]), sampleValue(samples[1]))
+	}
+	return rss, heap
+}
+
+func sampleValue(s metrics.Sample) uint64 {
+	if s.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s.Value.Uint64()
+}
+
+// readRSS reads the resident set size from /proc/self/statm, whose second
+// field counts resident pages
+func readRSS() uint64 {
+	data, err := os.ReadFile(statmPath)
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// DetectLimit returns the memory available to the process and where it
+// came from: the container's cgroup limit ("cgroup") when it is below the
+// host's memory, else the host's memory ("host"). It returns 0 and an
+// empty source when neither can be read.
+func DetectLimit() (uint64, string) {
+	host := hostMemory()
+	cgroup := cgroupLimit()
+	if cgroup > 0 && (host == 0 || cgroup < host) {
+		return cgroup, "cgroup"
+	}
+	if host > 0 {
+		return host, "host"
+	}
+	return 0, ""
+}
+
+// cgroupLimit reads the cgroup v2 memory.max, falling back to the v1
+// memory.limit_in_bytes; 0 means no limit. An unlimited v1 group reports
+// a huge value, which the comparison with the host's memory discards.
+func cgroupLimit() uint64 {
+	for _, path := range []string{cgroupV2Path, cgroupV1Path} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0
+		}
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// hostMemory reads MemTotal from /proc/meminfo
+func hostMemory() uint64 {
+	f, err := os.Open(meminfoPath)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
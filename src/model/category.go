@@ -16,6 +16,8 @@ const (
 	CategoryIT      Category = "it"
 	CategoryScience Category = "science"
 	CategorySocial  Category = "social"
+	// Package registries (npm, PyPI, crates.io, Go modules, Docker Hub)
+	CategoryPackages Category = "packages"
 )
 
 // AllCategories returns all available categories
@@ -31,6 +33,7 @@ func AllCategories() []Category {
 		CategoryIT,
 		CategoryScience,
 		CategorySocial,
+		CategoryPackages,
 	}
 }
 
@@ -58,6 +61,8 @@ func ParseCategory(value string) Category {
 		return CategoryScience
 	case "social":
 		return CategorySocial
+	case "packages", "package", "pkg":
+		return CategoryPackages
 	default:
 		return CategoryGeneral
 	}
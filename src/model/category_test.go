@@ -17,6 +17,7 @@ func TestCategoryString(t *testing.T) {
 		{CategoryIT, "it"},
 		{CategoryScience, "science"},
 		{CategorySocial, "social"},
+		{CategoryPackages, "packages"},
 	}
 
 	for _, tt := range tests {
@@ -43,6 +44,7 @@ func TestCategoryIsValid(t *testing.T) {
 		{CategoryIT, true},
 		{CategoryScience, true},
 		{CategorySocial, true},
+		{CategoryPackages, true},
 		{Category("invalid"), false},
 		{Category(""), false},
 		// case sensitive
@@ -62,9 +64,9 @@ func TestCategoryIsValid(t *testing.T) {
 func TestAllCategories(t *testing.T) {
 	categories := AllCategories()
 
-	// Should have 10 categories
-	if len(categories) != 10 {
-		t.Errorf("AllCategories() returned %d categories, want 10", len(categories))
+	// Should have 11 categories
+	if len(categories) != 11 {
+		t.Errorf("AllCategories() returned %d categories, want 11", len(categories))
 	}
 
 	// Check that all categories are valid
@@ -96,3 +98,23 @@ func TestAllCategories(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Category
+	}{
+		{"", CategoryGeneral},
+		{"web", CategoryGeneral},
+		{"code", CategoryIT},
+		{"packages", CategoryPackages},
+		{" PKG ", CategoryPackages},
+		{"unknown", CategoryGeneral},
+	}
+
+	for _, tt := range tests {
+		if got := ParseCategory(tt.input); got != tt.want {
+			t.Errorf("ParseCategory(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -16,6 +16,17 @@ type EngineConfig struct {
 	SupportsTor bool `yaml:"supports_tor" json:"supports_tor"`
 	UseTor      bool `yaml:"use_tor" json:"use_tor"`
 
+	// SupportsImageFilters is set when the engine applies the image color
+	// and license filters itself
+	SupportsImageFilters bool `yaml:"supports_image_filters" json:"supports_image_filters"`
+	// SupportsVideoFilters is set when the engine applies the video length
+	// and quality filters itself
+	SupportsVideoFilters bool `yaml:"supports_video_filters" json:"supports_video_filters"`
+	// SupportsSafeSearch is set when the engine sends the query's safe
+	// search level in its own parameter; the results of the other engines
+	// go through the safe search blocklist instead
+	SupportsSafeSearch bool `yaml:"supports_safe_search" json:"supports_safe_search"`
+
 	// Rate limiting
 	RateLimit struct {
 		Requests int `yaml:"requests" json:"requests"`
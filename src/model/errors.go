@@ -120,6 +120,8 @@ var (
 	// Query errors
 	ErrEmptyQuery      = errors.New("query text cannot be empty")
 	ErrInvalidCategory = errors.New("invalid category")
+	// ErrCategoryDisabled is a search in a category the instance turned off
+	ErrCategoryDisabled = errors.New("category is disabled")
 
 	// Engine errors
 	ErrEngineNotFound    = errors.New("engine not found")
@@ -127,6 +129,7 @@ var (
 	ErrEngineUnavailable = errors.New("engine is unavailable")
 	ErrEngineTimeout     = errors.New("engine request timed out")
 	ErrEngineRateLimit   = errors.New("engine rate limit exceeded")
+	ErrEngineBlocked     = errors.New("engine blocked the request")
 
 	// Search errors
 	ErrNoResults     = errors.New("no results found")
@@ -146,6 +146,7 @@ func TestDomainErrors(t *testing.T) {
 	errors := []error{
 		ErrEmptyQuery,
 		ErrInvalidCategory,
+		ErrCategoryDisabled,
 		ErrEngineNotFound,
 		ErrEngineDisabled,
 		ErrEngineUnavailable,
@@ -175,6 +176,7 @@ func TestDomainErrorMessages(t *testing.T) {
 	}{
 		{ErrEmptyQuery, "query text cannot be empty"},
 		{ErrInvalidCategory, "invalid category"},
+		{ErrCategoryDisabled, "category is disabled"},
 		{ErrEngineNotFound, "engine not found"},
 		{ErrEngineDisabled, "engine is disabled"},
 		{ErrEngineUnavailable, "engine is unavailable"},
@@ -0,0 +1,47 @@
+package model
+
+// FileResult is the file metadata of a result from a torrent or file
+// index: how big the download is, how many peers share it and how to get
+// it. Engines put the size in FileSize and the rest in Metadata.
+type FileResult struct {
+	// Size in bytes; 0 when the index does not say
+	Size int64 `json:"size,omitempty" xml:"size,omitempty"`
+	// Seeders and Leechers are the peers with the whole file and the
+	// peers still downloading it, as the index last saw them
+	Seeders  int `json:"seeders" xml:"seeders"`
+	Leechers int `json:"leechers" xml:"leechers"`
+	// Magnet is a magnet link to the torrent, Torrent a URL of the
+	// .torrent file; either may be empty
+	Magnet   string `json:"magnet,omitempty" xml:"magnet,omitempty"`
+	Torrent  string `json:"torrent,omitempty" xml:"torrent,omitempty"`
+	InfoHash string `json:"info_hash,omitempty" xml:"info_hash,omitempty"`
+}
+
+// File returns r's file metadata, or nil when r is not a files result
+func (r *Result) File() *FileResult {
+	if r.Category != CategoryFiles {
+		return nil
+	}
+	file := &FileResult{Size: r.FileSize}
+	file.Seeders = metadataInt(r.Metadata["seeders"])
+	file.Leechers = metadataInt(r.Metadata["leechers"])
+	file.Magnet, _ = r.Metadata["magnet"].(string)
+	file.Torrent, _ = r.Metadata["torrent"].(string)
+	file.InfoHash, _ = r.Metadata["info_hash"].(string)
+	if *file == (FileResult{}) {
+		return nil
+	}
+	return file
+}
+
+// metadataInt reads a count from Metadata, where it is an int as the
+// engine set it or a float64 once the result went through the JSON cache
+func metadataInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+package model
+
+// MapResult is the place a maps result points at: where it is and how
+// much of the map it covers. Engines put the coordinates and the rest in
+// Metadata.
+type MapResult struct {
+	Latitude  float64 `json:"lat" xml:"lat"`
+	Longitude float64 `json:"lon" xml:"lon"`
+	// BoundingBox is the area the place covers, when the engine gives one
+	BoundingBox *BoundingBox `json:"bbox,omitempty" xml:"bbox,omitempty"`
+	// Address is the place's postal address as one line
+	Address string `json:"address,omitempty" xml:"address,omitempty"`
+	// PlaceType is what the place is, such as "city" or "restaurant"
+	PlaceType string `json:"place_type,omitempty" xml:"place_type,omitempty"`
+}
+
+// BoundingBox is an area between two latitudes and two longitudes
+type BoundingBox struct {
+	South float64 `json:"south" xml:"south"`
+	North float64 `json:"north" xml:"north"`
+	West  float64 `json:"west" xml:"west"`
+	East  float64 `json:"east" xml:"east"`
+}
+
+// Map returns r's location, or nil when r is not a maps result or has no
+// coordinates
+func (r *Result) Map() *MapResult {
+	if r.Category != CategoryMaps {
+		return nil
+	}
+	lat, latOK := r.Metadata["latitude"].(float64)
+	lon, lonOK := r.Metadata["longitude"].(float64)
+	if !latOK || !lonOK {
+		return nil
+	}
+	place := &MapResult{Latitude: lat, Longitude: lon}
+	place.Address, _ = r.Metadata["address"].(string)
+	place.PlaceType, _ = r.Metadata["place_type"].(string)
+	if box := metadataFloats(r.Metadata["bbox"]); len(box) == 4 {
+		place.BoundingBox = &BoundingBox{South: box[0], North: box[1], West: box[2], East: box[3]}
+	}
+	return place
+}
+
+// metadataFloats reads a list of numbers from Metadata, where it is a
+// []float64 as the engine set it or a []interface{} once the result went
+// through the JSON cache
+func metadataFloats(v interface{}) []float64 {
+	switch list := v.(type) {
+	case []float64:
+		return list
+	case []interface{}:
+		floats := make([]float64, 0, len(list))
+		for _, item := range list {
+			f, ok := item.(float64)
+			if !ok {
+				return nil
+			}
+			floats = append(floats, f)
+		}
+		return floats
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Publisher returns who published a news result: the source the engine
+// names, or else the site's domain. It is empty for other categories.
+func (r *Result) Publisher() string {
+	if r.Category != CategoryNews {
+		return ""
+	}
+	if source := strings.TrimSpace(r.Author); source != "" {
+		return source
+	}
+	host := r.Domain
+	if host == "" {
+		if u, err := url.Parse(r.URL); err == nil {
+			host = u.Hostname()
+		}
+	}
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// PublisherGroup is the results of one publisher, in their ranked order
+type PublisherGroup struct {
+	Publisher string
+	Results   []Result
+}
+
+// GroupByPublisher groups news results by Publisher. Groups come in the
+// order their publisher first appears, so they keep the order results
+// were sorted in, by relevance or by date. Publishers differing only in
+// case share a group.
+func GroupByPublisher(results []Result) []PublisherGroup {
+	groups := make([]PublisherGroup, 0)
+	index := make(map[string]int)
+	for _, r := range results {
+		publisher := r.Publisher()
+		key := strings.ToLower(publisher)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, PublisherGroup{Publisher: publisher})
+		}
+		groups[i].Results = append(groups[i].Results, r)
+	}
+	return groups
+}
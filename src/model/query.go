@@ -1,6 +1,9 @@
 package model
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 // SortOrder defines how results are sorted
 type SortOrder string
@@ -28,6 +31,9 @@ type Query struct {
 	Language string   `json:"language"`
 	// Region code (us, uk, de, etc.)
 	Region string `json:"region,omitempty"`
+	// Country is the searcher's ISO 3166 country from GeoIP, set when
+	// results are boosted toward it. It is never sent to engines.
+	Country string `json:"country,omitempty"`
 	// 0: off, 1: moderate, 2: strict
 	SafeSearch int `json:"safe_search"`
 
@@ -65,13 +71,15 @@ type Query struct {
 	ImageSize string `json:"image_size,omitempty"`
 	// photo, clipart, lineart, animated
 	ImageType string `json:"image_type,omitempty"`
-	// color, gray, trans, red, etc.
+	// One of ImageColors: color, monochrome, or a dominant color
 	ImageColor string `json:"image_color,omitempty"`
+	// One of ImageLicenses
+	ImageLicense string `json:"image_license,omitempty"`
 	// square, wide, tall
 	ImageAspect string `json:"image_aspect,omitempty"`
-	// short, medium, long
+	// One of VideoLengths
 	VideoLength string `json:"video_length,omitempty"`
-	// hd, 4k
+	// One of VideoQualities
 	VideoQuality string `json:"video_quality,omitempty"`
 
 	// News-specific
@@ -81,6 +89,9 @@ type Query struct {
 	// Engine selection
 	Engines        []string `json:"engines,omitempty"`
 	ExcludeEngines []string `json:"exclude_engines,omitempty"`
+	// EnabledEngines are the engines the searcher's preferences allow;
+	// the others are skipped unless none of these can answer the query
+	EnabledEngines []string `json:"enabled_engines,omitempty"`
 
 	// Parsed operators (internal use)
 	ParsedOperators interface{} `json:"-"`
@@ -124,6 +135,7 @@ func (q *Query) Sanitize() {
 	q.ImageSize = strings.TrimSpace(q.ImageSize)
 	q.ImageType = strings.TrimSpace(q.ImageType)
 	q.ImageColor = strings.TrimSpace(q.ImageColor)
+	q.ImageLicense = strings.TrimSpace(q.ImageLicense)
 	q.ImageAspect = strings.TrimSpace(q.ImageAspect)
 	q.VideoLength = strings.TrimSpace(q.VideoLength)
 	q.VideoQuality = strings.TrimSpace(q.VideoQuality)
@@ -208,11 +220,138 @@ func (q *Query) HasMediaFilters() bool {
 	return q.ImageSize != "" ||
 		q.ImageType != "" ||
 		q.ImageColor != "" ||
+		q.ImageLicense != "" ||
 		q.ImageAspect != "" ||
 		q.VideoLength != "" ||
 		q.VideoQuality != ""
 }
 
+// HasImageFilters reports whether an image search is narrowed by color or
+// license, which only some engines can do
+func (q *Query) HasImageFilters() bool {
+	return q.Category == CategoryImages && (q.ImageColor != "" || q.ImageLicense != "")
+}
+
+// ImageColors are the image_color filter values: full color, black and
+// white, or a dominant color
+var ImageColors = []string{
+	"color", "monochrome",
+	"red", "orange", "yellow", "green", "teal", "blue", "purple", "pink",
+	"white", "gray", "black", "brown",
+}
+
+// ImageLicenses are the image_license filter values, the usage rights an
+// image's license grants: public domain, free to share and use (also
+// commercially), free to modify, share and use (also commercially)
+var ImageLicenses = []string{"public", "share", "share_commercial", "modify", "modify_commercial"}
+
+// ParseImageColor returns the image color filter s names, or "" for none
+func ParseImageColor(s string) string {
+	return parseFilterValue(s, ImageColors)
+}
+
+// ParseImageLicense returns the image license filter s names, or "" for none
+func ParseImageLicense(s string) string {
+	return parseFilterValue(s, ImageLicenses)
+}
+
+// HasVideoFilters reports whether a video search is narrowed by length or
+// quality, which only some engines can do
+func (q *Query) HasVideoFilters() bool {
+	return q.Category == CategoryVideos && (q.VideoLength != "" || q.VideoQuality != "")
+}
+
+// VideoLengths are the video_length filter values: under 4 minutes, 4 to 20
+// minutes, and over 20 minutes
+var VideoLengths = []string{"short", "medium", "long"}
+
+// VideoQualities are the video_quality filter values
+var VideoQualities = []string{"hd"}
+
+// ParseVideoLength returns the video length filter s names, or "" for none
+func ParseVideoLength(s string) string {
+	return parseFilterValue(s, VideoLengths)
+}
+
+// ParseVideoQuality returns the video quality filter s names, or "" for none
+func ParseVideoQuality(s string) string {
+	return parseFilterValue(s, VideoQualities)
+}
+
+// MatchesVideoLength reports whether a video of the given duration in
+// seconds passes the video length filter. Videos of unknown duration (0)
+// always pass.
+func (q *Query) MatchesVideoLength(seconds int) bool {
+	if seconds <= 0 {
+		return true
+	}
+	switch q.VideoLength {
+	case "short":
+		return seconds < 4*60
+	case "medium":
+		return seconds >= 4*60 && seconds <= 20*60
+	case "long":
+		return seconds > 20*60
+	}
+	return true
+}
+
+// TimeRanges are the time_range filter values: published in the past
+// day, week, month or year. "any", the default, does not filter.
+var TimeRanges = []string{"day", "week", "month", "year"}
+
+// ParseTimeRange returns the time range s names, or "" for any
+func ParseTimeRange(s string) string {
+	return parseFilterValue(s, TimeRanges)
+}
+
+// TimeRangeStart returns the earliest publish date the time range lets
+// through, or the zero time when it lets everything through
+func (q *Query) TimeRangeStart(now time.Time) time.Time {
+	switch q.TimeRange {
+	case "day":
+		return now.AddDate(0, 0, -1)
+	case "week":
+		return now.AddDate(0, 0, -7)
+	case "month":
+		return now.AddDate(0, -1, 0)
+	case "year":
+		return now.AddDate(-1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// MatchesTimeRange reports whether a result published at published passes
+// the time range filter. Results of unknown date (zero) always pass, as
+// the engines that cannot report dates apply the range themselves or not
+// at all.
+func (q *Query) MatchesTimeRange(published, now time.Time) bool {
+	start := q.TimeRangeStart(now)
+	return published.IsZero() || start.IsZero() || !published.Before(start)
+}
+
+// SortOrders are the sort values searchers choose between: by relevance,
+// the default, or newest first
+var SortOrders = []string{string(SortRelevance), string(SortDate)}
+
+// ParseSortOrder returns the sort order s names, or SortRelevance
+func ParseSortOrder(s string) SortOrder {
+	if sortBy := parseFilterValue(s, SortOrders); sortBy != "" {
+		return SortOrder(sortBy)
+	}
+	return SortRelevance
+}
+
+func parseFilterValue(s string, values []string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, v := range values {
+		if s == v {
+			return v
+		}
+	}
+	return ""
+}
+
 // GetEffectiveText returns the text to use for searching (cleaned or original)
 func (q *Query) GetEffectiveText() string {
 	if q.CleanedText != "" {
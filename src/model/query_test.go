@@ -1,6 +1,9 @@
 package model
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestNewQuery(t *testing.T) {
 	query := NewQuery("test search")
@@ -515,3 +518,87 @@ func TestQueryStruct(t *testing.T) {
 		t.Errorf("ExcludeEngines length = %d, want %d", len(query.ExcludeEngines), 1)
 	}
 }
+
+func TestImageFilters(t *testing.T) {
+	if got := ParseImageColor(" Red "); got != "red" {
+		t.Errorf("ParseImageColor(Red) = %q", got)
+	}
+	if got := ParseImageColor("mauve"); got != "" {
+		t.Errorf("ParseImageColor(mauve) = %q, want none", got)
+	}
+	if got := ParseImageLicense("share_commercial"); got != "share_commercial" {
+		t.Errorf("ParseImageLicense(share_commercial) = %q", got)
+	}
+
+	q := &Query{Category: CategoryImages, ImageLicense: "public"}
+	if !q.HasImageFilters() || !q.HasMediaFilters() {
+		t.Error("license filter not reported")
+	}
+	q.Category = CategoryGeneral
+	if q.HasImageFilters() {
+		t.Error("image filters reported outside the images category")
+	}
+}
+
+func TestVideoFilters(t *testing.T) {
+	if got := ParseVideoLength("Long"); got != "long" {
+		t.Errorf("ParseVideoLength(Long) = %q", got)
+	}
+	if got := ParseVideoQuality("4k"); got != "" {
+		t.Errorf("ParseVideoQuality(4k) = %q, want none", got)
+	}
+
+	q := &Query{Category: CategoryVideos, VideoLength: "short"}
+	if !q.HasVideoFilters() {
+		t.Error("length filter not reported")
+	}
+	for seconds, want := range map[int]bool{0: true, 90: true, 240: false, 3600: false} {
+		if got := q.MatchesVideoLength(seconds); got != want {
+			t.Errorf("short MatchesVideoLength(%d) = %v, want %v", seconds, got, want)
+		}
+	}
+	q.VideoLength = "medium"
+	if !q.MatchesVideoLength(600) || q.MatchesVideoLength(1500) {
+		t.Error("medium length range wrong")
+	}
+	q.Category = CategoryImages
+	if q.HasVideoFilters() {
+		t.Error("video filters reported outside the videos category")
+	}
+}
+
+func TestTimeRangeFilter(t *testing.T) {
+	if got := ParseTimeRange(" Week "); got != "week" {
+		t.Errorf("ParseTimeRange(Week) = %q", got)
+	}
+	if got := ParseTimeRange("any"); got != "" {
+		t.Errorf("ParseTimeRange(any) = %q, want none", got)
+	}
+
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	q := NewQuery("election")
+	if !q.TimeRangeStart(now).IsZero() || !q.MatchesTimeRange(now.AddDate(-5, 0, 0), now) {
+		t.Error("any time range filters results")
+	}
+	q.TimeRange = "week"
+	if got := q.TimeRangeStart(now); !got.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("week TimeRangeStart = %v", got)
+	}
+	for published, want := range map[time.Time]bool{
+		{}:                    true,
+		now.Add(-time.Hour):   true,
+		now.AddDate(0, 0, -8): false,
+	} {
+		if got := q.MatchesTimeRange(published, now); got != want {
+			t.Errorf("week MatchesTimeRange(%v) = %v, want %v", published, got, want)
+		}
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	for s, want := range map[string]SortOrder{"": SortRelevance, "Date": SortDate, "relevance": SortRelevance, "random": SortRelevance} {
+		if got := ParseSortOrder(s); got != want {
+			t.Errorf("ParseSortOrder(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -120,6 +120,19 @@ type Result struct {
 	// Language detection
 	Language string `json:"language,omitempty" xml:"language,omitempty"`
 
+	// Localized is why the result was boosted toward the searcher's country:
+	// "domain" for a matching country-code domain, "language" for a
+	// language spoken there
+	Localized string `json:"localized,omitempty" xml:"-"`
+
+	// Screening: "malware" or "phishing" when a threat feed lists the URL
+	Threat string `json:"threat,omitempty" xml:"-"`
+	// "adult" when strict safe search hid the image; Thumbnail is cleared
+	ContentFilter string `json:"content_filter,omitempty" xml:"-"`
+
+	// Schema.org fields for a rich snippet (recipe, event, product, rating)
+	Structured *StructuredData `json:"structured,omitempty" xml:"-"`
+
 	// Metadata
 	Metadata map[string]interface{} `json:"metadata,omitempty" xml:"-"`
 }
@@ -198,10 +211,39 @@ type SearchResults struct {
 	FromCache    bool      `json:"from_cache,omitempty" xml:"fromCache,omitempty"`
 	Stale        bool      `json:"stale,omitempty" xml:"stale,omitempty"`
 	CacheAgeSec  int64     `json:"cache_age_sec,omitempty" xml:"cacheAgeSec,omitempty"`
+	// Degraded is set when no engine answered and the results come from the
+	// stale cache or the local index instead; CachedAt is when they were
+	// fetched (the oldest result's time for the local index)
+	Degraded bool       `json:"degraded,omitempty" xml:"degraded,omitempty"`
+	CachedAt *time.Time `json:"cached_at,omitempty" xml:"cachedAt,omitempty"`
 
 	// Facets for filtering - populated by aggregator when results contain domain/language metadata
 	Domains   map[string]int `json:"domains,omitempty" xml:"-"`
 	Languages map[string]int `json:"languages,omitempty" xml:"-"`
+
+	// EngineTimings breaks the search down per engine. Set on fresh results
+	// only; cached copies do not carry it.
+	EngineTimings []EngineTiming `json:"engine_timings,omitempty" xml:"-"`
+}
+
+// EngineTiming is one engine's part in a search. Times are milliseconds from
+// the start of the search.
+type EngineTiming struct {
+	Engine string `json:"engine" xml:"engine"`
+	// RequestedMs is when the engine's first HTTP request was sent
+	RequestedMs float64 `json:"requested_ms" xml:"requested_ms"`
+	// RespondedMs is when the first byte of its last response arrived
+	RespondedMs float64 `json:"responded_ms" xml:"responded_ms"`
+	// ParseMs is the time from RespondedMs to the engine returning: reading
+	// the body and parsing it
+	ParseMs float64 `json:"parse_ms" xml:"parse_ms"`
+	TotalMs float64 `json:"total_ms" xml:"total_ms"`
+	// Results is what the engine returned; Contributed is how many of those
+	// survived deduplication and filtering
+	Results     int    `json:"results" xml:"results"`
+	Contributed int    `json:"contributed" xml:"contributed"`
+	Error       string `json:"error,omitempty" xml:"error,omitempty"`
+	TimedOut    bool   `json:"timed_out,omitempty" xml:"timed_out,omitempty"`
 }
 
 // NewSearchResults creates a new SearchResults instance
@@ -257,6 +299,21 @@ func (sr *SearchResults) CalculateTotalPages() {
 	}
 }
 
+// WithStructuredType returns a copy of the results holding only those
+// with structured data of type t, e.g. recipes, paginated again
+func (sr *SearchResults) WithStructuredType(t string) *SearchResults {
+	filtered := *sr
+	filtered.Results = make([]Result, 0, len(sr.Results))
+	for _, r := range sr.Results {
+		if r.Structured != nil && r.Structured.Type == t {
+			filtered.Results = append(filtered.Results, r)
+		}
+	}
+	filtered.TotalResults = len(filtered.Results)
+	filtered.CalculateTotalPages()
+	return &filtered
+}
+
 // GetPage returns results for a specific page
 func (sr *SearchResults) GetPage(page int) []Result {
 	start := (page - 1) * sr.PerPage
@@ -425,6 +425,30 @@ func TestSearchResultsGetPage(t *testing.T) {
 	}
 }
 
+func TestSearchResultsWithStructuredType(t *testing.T) {
+	sr := NewSearchResults("pancakes", CategoryGeneral)
+	sr.PerPage = 1
+	recipe := &StructuredData{Type: StructuredRecipe, Recipe: &RecipeInfo{TotalTime: 20}}
+	sr.Results = []Result{
+		{Title: "A", Structured: recipe},
+		{Title: "B"},
+		{Title: "C", Structured: &StructuredData{Type: StructuredProduct, Product: &ProductInfo{Price: "5"}}},
+		{Title: "D", Structured: recipe},
+	}
+	sr.TotalResults = len(sr.Results)
+
+	got := sr.WithStructuredType(StructuredRecipe)
+	if got.TotalResults != 2 || got.TotalPages != 2 || got.Results[0].Title != "A" || got.Results[1].Title != "D" {
+		t.Errorf("WithStructuredType(recipe) = %d results, %d pages", got.TotalResults, got.TotalPages)
+	}
+	if sr.TotalResults != 4 || len(sr.Results) != 4 {
+		t.Error("WithStructuredType() changed the original results")
+	}
+	if got := sr.WithStructuredType(StructuredHowTo); got.TotalResults != 0 || len(got.GetPage(1)) != 0 {
+		t.Errorf("WithStructuredType(howto) = %d results", got.TotalResults)
+	}
+}
+
 func TestSearchResultsToJSON(t *testing.T) {
 	sr := NewSearchResults("test", CategoryGeneral)
 	sr.AddResult(Result{Title: "Test", URL: "https://example.com", Engine: "google"})
@@ -1114,3 +1138,129 @@ func TestSortOrderConstants(t *testing.T) {
 		}
 	}
 }
+
+func TestResultVideo(t *testing.T) {
+	if video := (&Result{Category: CategoryGeneral}).Video(); video != nil {
+		t.Errorf("Video() of a web result = %+v, want nil", video)
+	}
+	if video := (&Result{Category: CategoryVideos}).Video(); video != nil {
+		t.Errorf("Video() without metadata = %+v, want nil", video)
+	}
+
+	published := time.Date(2009, 10, 25, 6, 57, 33, 0, time.UTC)
+	r := &Result{
+		Category:    CategoryVideos,
+		Duration:    213,
+		Author:      "Rick Astley",
+		ViewCount:   1500000000,
+		PublishedAt: published,
+		Metadata:    map[string]interface{}{"channel_url": "https://www.youtube.com/@RickAstleyYT"},
+	}
+	video := r.Video()
+	if video == nil || video.Duration != 213 || video.Channel != "Rick Astley" || video.Views != 1500000000 ||
+		video.ChannelURL != "https://www.youtube.com/@RickAstleyYT" || video.PublishedAt == nil || !video.PublishedAt.Equal(published) {
+		t.Fatalf("Video() = %+v", video)
+	}
+	data, _ := json.Marshal(video)
+	if !strings.Contains(string(data), `"published_at":"2009-10-25T06:57:33Z"`) || strings.Contains(string(data), "embed") {
+		t.Errorf("Video() JSON = %s", data)
+	}
+
+	// A video found by a general search still has a length
+	if video := (&Result{Category: CategoryGeneral, Duration: 90}).Video(); video == nil || video.Duration != 90 {
+		t.Errorf("Video() of a web result with a duration = %+v", video)
+	}
+}
+
+func TestResultFile(t *testing.T) {
+	if file := (&Result{Category: CategoryGeneral, FileSize: 1024}).File(); file != nil {
+		t.Errorf("File() of a web result = %+v, want nil", file)
+	}
+	if file := (&Result{Category: CategoryFiles}).File(); file != nil {
+		t.Errorf("File() without metadata = %+v, want nil", file)
+	}
+
+	r := &Result{
+		Category: CategoryFiles,
+		FileSize: 4700000000,
+		Metadata: map[string]interface{}{
+			"seeders":   42,
+			"leechers":  7,
+			"magnet":    "magnet:?xt=urn:btih:abc",
+			"info_hash": "abc",
+		},
+	}
+	file := r.File()
+	if file == nil || file.Size != 4700000000 || file.Seeders != 42 || file.Leechers != 7 || file.Magnet != "magnet:?xt=urn:btih:abc" {
+		t.Fatalf("File() = %+v", file)
+	}
+
+	// Counts read back from the JSON cache are float64
+	data, _ := json.Marshal(r)
+	var cached Result
+	if err := json.Unmarshal(data, &cached); err != nil {
+		t.Fatal(err)
+	}
+	if file := cached.File(); file == nil || file.Seeders != 42 || file.Leechers != 7 {
+		t.Errorf("File() of a cached result = %+v", file)
+	}
+}
+
+func TestResultMap(t *testing.T) {
+	if place := (&Result{Category: CategoryGeneral, Metadata: map[string]interface{}{"latitude": 1.0, "longitude": 2.0}}).Map(); place != nil {
+		t.Errorf("Map() of a web result = %+v, want nil", place)
+	}
+	if place := (&Result{Category: CategoryMaps}).Map(); place != nil {
+		t.Errorf("Map() without coordinates = %+v, want nil", place)
+	}
+
+	r := &Result{
+		Category: CategoryMaps,
+		Metadata: map[string]interface{}{
+			"latitude":   52.517,
+			"longitude":  13.389,
+			"bbox":       []float64{52.3, 52.7, 13.0, 13.8},
+			"address":    "Berlin, Germany",
+			"place_type": "city",
+		},
+	}
+	want := MapResult{Latitude: 52.517, Longitude: 13.389, Address: "Berlin, Germany", PlaceType: "city"}
+	place := r.Map()
+	if place == nil || place.BoundingBox == nil || *place.BoundingBox != (BoundingBox{South: 52.3, North: 52.7, West: 13.0, East: 13.8}) {
+		t.Fatalf("Map() = %+v", place)
+	}
+	place.BoundingBox = nil
+	if *place != want {
+		t.Errorf("Map() = %+v, want %+v", place, want)
+	}
+
+	// The bounding box read back from the JSON cache is a []interface{}
+	data, _ := json.Marshal(r)
+	var cached Result
+	if err := json.Unmarshal(data, &cached); err != nil {
+		t.Fatal(err)
+	}
+	if place := cached.Map(); place == nil || place.BoundingBox == nil || place.BoundingBox.East != 13.8 {
+		t.Errorf("Map() of a cached result = %+v", place)
+	}
+}
+
+func TestGroupByPublisher(t *testing.T) {
+	results := []Result{
+		{Title: "a", URL: "https://www.reuters.com/a", Category: CategoryNews},
+		{Title: "b", URL: "https://apnews.com/b", Category: CategoryNews, Author: "AP News"},
+		{Title: "c", URL: "https://reuters.com/c", Category: CategoryNews, Domain: "Reuters.com"},
+		{Title: "d", URL: "https://apnews.com/d", Category: CategoryNews, Author: "ap news"},
+	}
+	groups := GroupByPublisher(results)
+	if len(groups) != 2 || groups[0].Publisher != "reuters.com" || groups[1].Publisher != "AP News" {
+		t.Fatalf("GroupByPublisher() = %+v", groups)
+	}
+	if len(groups[0].Results) != 2 || groups[0].Results[1].Title != "c" || len(groups[1].Results) != 2 {
+		t.Errorf("GroupByPublisher() results = %+v", groups)
+	}
+
+	if publisher := (&Result{URL: "https://www.reuters.com/a", Category: CategoryGeneral}).Publisher(); publisher != "" {
+		t.Errorf("Publisher() of a web result = %q, want none", publisher)
+	}
+}
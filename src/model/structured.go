@@ -0,0 +1,151 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Structured data types, the schema.org types a result's rich snippet is
+// built from. StructuredRating is a result that only has a rating.
+const (
+	StructuredRecipe  = "recipe"
+	StructuredHowTo   = "howto"
+	StructuredEvent   = "event"
+	StructuredProduct = "product"
+	StructuredRating  = "rating"
+)
+
+// previewLength is how many ingredients or steps a result card lists
+const previewLength = 4
+
+// IsStructuredType reports whether t is one of the structured data types
+func IsStructuredType(t string) bool {
+	switch t {
+	case StructuredRecipe, StructuredHowTo, StructuredEvent, StructuredProduct, StructuredRating:
+		return true
+	}
+	return false
+}
+
+// StructuredData holds the schema.org fields of a result that a rich
+// snippet shows. Only the block matching Type is set; Rating may be set
+// for any type.
+type StructuredData struct {
+	Type    string       `json:"type"`
+	Rating  *Rating      `json:"rating,omitempty"`
+	Recipe  *RecipeInfo  `json:"recipe,omitempty"`
+	HowTo   *HowToInfo   `json:"howto,omitempty"`
+	Event   *EventInfo   `json:"event,omitempty"`
+	Product *ProductInfo `json:"product,omitempty"`
+}
+
+// Rating is a schema.org AggregateRating
+type Rating struct {
+	Value float64 `json:"value"`
+	// Best is the top of the scale (default 5)
+	Best float64 `json:"best"`
+	// Count is how many ratings or reviews the value is from
+	Count int64 `json:"count,omitempty"`
+}
+
+// RecipeInfo is the part of a schema.org Recipe a snippet shows
+type RecipeInfo struct {
+	// TotalTime is in minutes
+	TotalTime int    `json:"total_time,omitempty"`
+	Calories  int    `json:"calories,omitempty"`
+	Yield     string `json:"yield,omitempty"`
+	// Ingredients are the first ingredients as the page lists them
+	Ingredients []string `json:"ingredients,omitempty"`
+	// IngredientCount is how many ingredients the recipe has in all
+	IngredientCount int `json:"ingredient_count,omitempty"`
+}
+
+// HowToInfo is the part of a schema.org HowTo a snippet shows
+type HowToInfo struct {
+	// TotalTime is in minutes
+	TotalTime int `json:"total_time,omitempty"`
+	// Steps are the names of the first steps
+	Steps []string `json:"steps,omitempty"`
+	// StepCount is how many steps the how-to has in all
+	StepCount int `json:"step_count,omitempty"`
+}
+
+// EventInfo is the part of a schema.org Event a snippet shows
+type EventInfo struct {
+	StartDate time.Time `json:"start_date"`
+	Location  string    `json:"location,omitempty"`
+}
+
+// ProductInfo is the part of a schema.org Product offer a snippet shows
+type ProductInfo struct {
+	// Price is as the page gives it, e.g. "12.99"
+	Price    string `json:"price,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// Availability is "in_stock" or "out_of_stock"
+	Availability string `json:"availability,omitempty"`
+}
+
+// Stars renders the rating on a five-star scale, e.g. "★★★★☆"
+func (r *Rating) Stars() string {
+	if r == nil || r.Best <= 0 {
+		return ""
+	}
+	full := int(r.Value/r.Best*5 + 0.5)
+	full = max(0, min(full, 5))
+	return strings.Repeat("★", full) + strings.Repeat("☆", 5-full)
+}
+
+// String renders the rating value on its scale, e.g. "4.5/5"
+func (r *Rating) String() string {
+	if r == nil {
+		return ""
+	}
+	return strconv.FormatFloat(r.Value, 'f', -1, 64) + "/" + strconv.FormatFloat(r.Best, 'f', -1, 64)
+}
+
+// IngredientPreview is the ingredients a result card lists
+func (r *RecipeInfo) IngredientPreview() []string {
+	return r.Ingredients[:min(len(r.Ingredients), previewLength)]
+}
+
+// MoreIngredients is how many ingredients the card leaves out
+func (r *RecipeInfo) MoreIngredients() int {
+	return max(0, r.IngredientCount-len(r.IngredientPreview()))
+}
+
+// StepPreview is the steps a result card lists
+func (h *HowToInfo) StepPreview() []string {
+	return h.Steps[:min(len(h.Steps), previewLength)]
+}
+
+// MoreSteps is how many steps the card leaves out
+func (h *HowToInfo) MoreSteps() int {
+	return max(0, h.StepCount-len(h.StepPreview()))
+}
+
+// Card reports whether the data is shown as a result card (recipes and
+// how-tos) rather than a snippet line
+func (d *StructuredData) Card() bool {
+	return d.Type == StructuredRecipe || d.Type == StructuredHowTo
+}
+
+// Valid reports whether the data has anything a snippet can show
+func (d *StructuredData) Valid() bool {
+	if d == nil {
+		return false
+	}
+	switch {
+	case d.Rating != nil:
+		return true
+	case d.Recipe != nil:
+		return d.Recipe.TotalTime > 0 || d.Recipe.Calories > 0 || d.Recipe.Yield != "" || len(d.Recipe.Ingredients) > 0
+	case d.HowTo != nil:
+		return d.HowTo.TotalTime > 0 || len(d.HowTo.Steps) > 0
+	case d.Event != nil:
+		return !d.Event.StartDate.IsZero()
+	case d.Product != nil:
+		return d.Product.Price != "" || d.Product.Availability != ""
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// VideoResult is the video metadata of a result: how long the video runs,
+// the channel that published it and when. It is read from the result's
+// own fields, so engines fill in Duration, Author, ViewCount and
+// PublishedAt as for any other result.
+type VideoResult struct {
+	// Duration in seconds; 0 when the engine does not say
+	Duration int `json:"duration,omitempty" xml:"duration,omitempty"`
+	// Channel is the channel or account that published the video
+	Channel    string `json:"channel,omitempty" xml:"channel,omitempty"`
+	ChannelURL string `json:"channel_url,omitempty" xml:"channel_url,omitempty"`
+	// PublishedAt is nil when the engine does not say
+	PublishedAt *time.Time `json:"published_at,omitempty" xml:"published_at,omitempty"`
+	Views       int64      `json:"views,omitempty" xml:"views,omitempty"`
+	// Embed is the URL of a player frame, or of the video file itself,
+	// that plays the video without loading the video host's page. It is
+	// set by the API when search.video_player can play the video.
+	Embed string `json:"embed,omitempty" xml:"embed,omitempty"`
+}
+
+// Video returns r's video metadata, or nil when r is not a video result
+// or carries none
+func (r *Result) Video() *VideoResult {
+	if r.Category != CategoryVideos && r.Duration == 0 {
+		return nil
+	}
+	video := &VideoResult{
+		Duration: r.Duration,
+		Channel:  r.Author,
+		Views:    r.ViewCount,
+	}
+	if channelURL, ok := r.Metadata["channel_url"].(string); ok {
+		video.ChannelURL = channelURL
+	}
+	if !r.PublishedAt.IsZero() {
+		published := r.PublishedAt
+		video.PublishedAt = &published
+	}
+	if *video == (VideoResult{}) {
+		return nil
+	}
+	return video
+}
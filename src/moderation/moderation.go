@@ -0,0 +1,532 @@
+// Package moderation keeps the queue of search results that visitors
+// reported and the operator's result blocklist. Rules are also held in
+// memory so Blocked and ImageAllowed can run on every result of every
+// search.
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for an unknown report or rule
+	ErrNotFound = errors.New("report not found")
+	// ErrInvalidInput is returned for a report, action or rule that is rejected
+	ErrInvalidInput = errors.New("invalid moderation input")
+	// ErrQueueFull is returned when too many reports are waiting for review
+	ErrQueueFull = errors.New("report queue is full")
+)
+
+// Report reasons
+const (
+	ReasonSpam       = "spam"
+	ReasonMalware    = "malware"
+	ReasonIllegal    = "illegal"
+	ReasonBrokenLink = "broken_link"
+	// ReasonMisclassified reports an image the adult-content classifier
+	// hid by mistake. It is offered only from a hidden image, not in
+	// Reasons.
+	ReasonMisclassified = "misclassified"
+)
+
+// Reasons lists the report reasons in the order the form shows them
+var Reasons = []string{ReasonSpam, ReasonMalware, ReasonIllegal, ReasonBrokenLink}
+
+// Report statuses
+const (
+	StatusOpen      = "open"
+	StatusDismissed = "dismissed"
+	StatusActioned  = "actioned"
+)
+
+// Resolve actions
+const (
+	ActionDismiss     = "dismiss"
+	ActionBlockDomain = "block_domain"
+	ActionBlockURL    = "block_url"
+	ActionAllowImage  = "allow_image"
+)
+
+// Rule kinds
+const (
+	RuleDomain = "domain"
+	RuleURL    = "url"
+	// RuleImageAllow exempts one image URL from the adult-content
+	// classifier; it hides nothing
+	RuleImageAllow = "image_allow"
+)
+
+const (
+	maxCommentLength = 500
+	maxNoteLength    = 1000
+	maxReasonLength  = 200
+	maxURLLength     = 2048
+	// maxOpenReports bounds the queue so a flood of distinct URLs cannot
+	// grow it without limit; repeat reports still count
+	maxOpenReports  = 10000
+	defaultListSize = 100
+	maxListSize     = 500
+)
+
+// Report is a reported result. Repeat reports of the same URL and reason
+// while it is open add to Count.
+type Report struct {
+	ID              int64      `json:"id"`
+	URL             string     `json:"url"`
+	Domain          string     `json:"domain"`
+	Reason          string     `json:"reason"`
+	Comment         string     `json:"comment,omitempty"`
+	Count           int        `json:"count"`
+	Status          string     `json:"status"`
+	Resolution      string     `json:"resolution,omitempty"`
+	Note            string     `json:"note,omitempty"`
+	FirstReportedAt time.Time  `json:"first_reported_at"`
+	LastReportedAt  time.Time  `json:"last_reported_at"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Rule hides a domain (with its subdomains) or one exact URL from results,
+// or exempts an image URL from the adult-content classifier
+type Rule struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Reason    string    `json:"reason,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store keeps reports and rules in the server database
+type Store struct {
+	db      *sql.DB
+	reports string
+	rules   string
+
+	mu      sync.RWMutex
+	domains map[string]bool
+	urls    map[string]bool
+	images  map[string]bool
+}
+
+// NewStore creates a store; tablePrefix is the server table prefix. Call
+// Load before relying on Blocked.
+func NewStore(db *sql.DB, tablePrefix string) *Store {
+	return &Store{
+		db:      db,
+		reports: tablePrefix + "result_reports",
+		rules:   tablePrefix + "result_rules",
+		domains: map[string]bool{},
+		urls:    map[string]bool{},
+		images:  map[string]bool{},
+	}
+}
+
+// Load reads the rules into memory
+func (s *Store) Load(ctx context.Context) error {
+	rules, err := s.Rules(ctx)
+	if err != nil {
+		return err
+	}
+	domains := make(map[string]bool, len(rules))
+	urls := make(map[string]bool, len(rules))
+	images := make(map[string]bool)
+	for _, rule := range rules {
+		switch rule.Kind {
+		case RuleDomain:
+			domains[rule.Pattern] = true
+		case RuleImageAllow:
+			images[rule.Pattern] = true
+		default:
+			urls[rule.Pattern] = true
+		}
+	}
+	s.mu.Lock()
+	s.domains, s.urls, s.images = domains, urls, images
+	s.mu.Unlock()
+	return nil
+}
+
+// Blocked reports whether a result URL matches a rule: the exact URL, or
+// its host or any parent domain of it
+func (s *Store) Blocked(rawURL string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.domains) == 0 && len(s.urls) == 0 {
+		return false
+	}
+	normalized, domain, err := normalizeURL(rawURL)
+	if err != nil {
+		return false
+	}
+	if s.urls[normalized] {
+		return true
+	}
+	for {
+		if s.domains[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// ImageAllowed reports whether the operator exempted an image URL from the
+// adult-content classifier
+func (s *Store) ImageAllowed(rawURL string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.images) == 0 {
+		return false
+	}
+	normalized, _, err := normalizeURL(rawURL)
+	return err == nil && s.images[normalized]
+}
+
+// Submit files a report, or counts it against the open report for the same
+// URL and reason
+func (s *Store) Submit(ctx context.Context, rawURL, reason, comment string) (*Report, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("report storage is unavailable")
+	}
+	if !validReason(reason) {
+		return nil, fmt.Errorf("%w: reason must be one of %s or %s", ErrInvalidInput, strings.Join(Reasons, ", "), ReasonMisclassified)
+	}
+	normalized, domain, err := normalizeURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	comment = truncate(strings.TrimSpace(comment), maxCommentLength)
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("submit report: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM `+s.reports+` WHERE url = ? AND reason = ? AND status = ?`,
+		normalized, reason, StatusOpen).Scan(&id)
+	switch {
+	case err == nil:
+		// Keep the earlier comment unless this report says something
+		if _, err := tx.ExecContext(ctx, `UPDATE `+s.reports+` SET report_count = report_count + 1, last_reported_at = ?,
+			comment = CASE WHEN ? = '' THEN comment ELSE ? END WHERE id = ?`, now, comment, comment, id); err != nil {
+			return nil, fmt.Errorf("submit report: %w", err)
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		var open int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+s.reports+` WHERE status = ?`, StatusOpen).Scan(&open); err != nil {
+			return nil, fmt.Errorf("submit report: %w", err)
+		}
+		if open >= maxOpenReports {
+			return nil, ErrQueueFull
+		}
+		result, err := tx.ExecContext(ctx, `INSERT INTO `+s.reports+`
+			(url, domain, reason, comment, report_count, status, first_reported_at, last_reported_at) VALUES (?, ?, ?, ?, 1, ?, ?, ?)`,
+			normalized, domain, reason, comment, StatusOpen, now, now)
+		if err != nil {
+			return nil, fmt.Errorf("submit report: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("submit report: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("submit report: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("submit report: %w", err)
+	}
+	return s.Get(ctx, id)
+}
+
+// Get returns one report
+func (s *Store) Get(ctx context.Context, id int64) (*Report, error) {
+	if s == nil || s.db == nil {
+		return nil, ErrNotFound
+	}
+	reports, err := s.query(ctx, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, ErrNotFound
+	}
+	return &reports[0], nil
+}
+
+// List returns reports with the given status ("" for open, "all" for every
+// status), most reported first
+func (s *Store) List(ctx context.Context, status string, limit int) ([]Report, error) {
+	if s == nil || s.db == nil {
+		return []Report{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultListSize
+	}
+	if limit > maxListSize {
+		limit = maxListSize
+	}
+	order := ` ORDER BY report_count DESC, last_reported_at DESC LIMIT ` + strconv.Itoa(limit)
+	switch status {
+	case "":
+		return s.query(ctx, `WHERE status = ?`+order, StatusOpen)
+	case "all":
+		return s.query(ctx, order)
+	case StatusOpen, StatusDismissed, StatusActioned:
+		return s.query(ctx, `WHERE status = ?`+order, status)
+	}
+	return nil, fmt.Errorf("%w: status must be open, dismissed, actioned or all", ErrInvalidInput)
+}
+
+// Resolve closes an open report. dismiss only closes it; block_domain and
+// block_url add a rule for its domain or URL and close every open report
+// the rule covers; allow_image exempts its URL from the adult-content
+// classifier and closes the open misclassified reports for it. The rule is
+// nil for dismiss.
+func (s *Store) Resolve(ctx context.Context, id int64, action, note string) (*Report, *Rule, error) {
+	report, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if report.Status != StatusOpen {
+		return nil, nil, fmt.Errorf("%w: report is already %s", ErrInvalidInput, report.Status)
+	}
+	note = truncate(strings.TrimSpace(note), maxNoteLength)
+	now := time.Now().UTC()
+	source := "report:" + strconv.FormatInt(report.ID, 10)
+
+	var rule *Rule
+	switch action {
+	case ActionDismiss:
+		_, err = s.db.ExecContext(ctx, `UPDATE `+s.reports+` SET status = ?, resolution = ?, note = ?, resolved_at = ? WHERE id = ?`,
+			StatusDismissed, action, note, now, id)
+	case ActionBlockDomain:
+		if rule, err = s.AddRule(ctx, RuleDomain, report.Domain, report.Reason, source); err != nil {
+			return nil, nil, err
+		}
+		_, err = s.db.ExecContext(ctx, `UPDATE `+s.reports+` SET status = ?, resolution = ?, note = ?, resolved_at = ?
+			WHERE status = ? AND (domain = ? OR domain LIKE ?)`,
+			StatusActioned, action, note, now, StatusOpen, rule.Pattern, "%."+rule.Pattern)
+	case ActionBlockURL:
+		if rule, err = s.AddRule(ctx, RuleURL, report.URL, report.Reason, source); err != nil {
+			return nil, nil, err
+		}
+		_, err = s.db.ExecContext(ctx, `UPDATE `+s.reports+` SET status = ?, resolution = ?, note = ?, resolved_at = ?
+			WHERE status = ? AND url = ?`,
+			StatusActioned, action, note, now, StatusOpen, rule.Pattern)
+	case ActionAllowImage:
+		if rule, err = s.AddRule(ctx, RuleImageAllow, report.URL, report.Reason, source); err != nil {
+			return nil, nil, err
+		}
+		_, err = s.db.ExecContext(ctx, `UPDATE `+s.reports+` SET status = ?, resolution = ?, note = ?, resolved_at = ?
+			WHERE status = ? AND url = ? AND reason = ?`,
+			StatusActioned, action, note, now, StatusOpen, rule.Pattern, ReasonMisclassified)
+	default:
+		return nil, nil, fmt.Errorf("%w: action must be dismiss, block_domain, block_url or allow_image", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve report: %w", err)
+	}
+	report, err = s.Get(ctx, id)
+	return report, rule, err
+}
+
+// AddRule adds a blocklist rule and applies it at once. Adding a rule that
+// already exists returns the existing one.
+func (s *Store) AddRule(ctx context.Context, kind, pattern, reason, source string) (*Rule, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("report storage is unavailable")
+	}
+	var err error
+	switch kind {
+	case RuleDomain:
+		pattern, err = normalizeDomain(pattern)
+	case RuleURL, RuleImageAllow:
+		pattern, _, err = normalizeURL(pattern)
+	default:
+		err = fmt.Errorf("%w: kind must be domain, url or image_allow", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule, err := s.findRule(ctx, `kind = ? AND pattern = ?`, kind, pattern); !errors.Is(err, ErrNotFound) {
+		return rule, err
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.rules+` (kind, pattern, reason, source, created_at) VALUES (?, ?, ?, ?, ?)`,
+		kind, pattern, truncate(strings.TrimSpace(reason), maxReasonLength), source, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("add rule: %w", err)
+	}
+	rule, err := s.findRule(ctx, `kind = ? AND pattern = ?`, kind, pattern)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.ruleSet(kind)[pattern] = true
+	s.mu.Unlock()
+	return rule, nil
+}
+
+// RemoveRule deletes a rule and returns it; its results show again at once
+func (s *Store) RemoveRule(ctx context.Context, id int64) (*Rule, error) {
+	if s == nil || s.db == nil {
+		return nil, ErrNotFound
+	}
+	rule, err := s.findRule(ctx, `id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM `+s.rules+` WHERE id = ?`, id); err != nil {
+		return nil, fmt.Errorf("remove rule: %w", err)
+	}
+	s.mu.Lock()
+	delete(s.ruleSet(rule.Kind), rule.Pattern)
+	s.mu.Unlock()
+	return rule, nil
+}
+
+// Rules returns every rule, newest first
+func (s *Store) Rules(ctx context.Context) ([]Rule, error) {
+	if s == nil || s.db == nil {
+		return []Rule{}, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, kind, pattern, reason, source, created_at FROM `+s.rules+` ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %w", err)
+	}
+	defer rows.Close()
+	rules := []Rule{}
+	for rows.Next() {
+		var rule Rule
+		if err := rows.Scan(&rule.ID, &rule.Kind, &rule.Pattern, &rule.Reason, &rule.Source, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list rules: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *Store) findRule(ctx context.Context, where string, args ...any) (*Rule, error) {
+	var rule Rule
+	err := s.db.QueryRowContext(ctx, `SELECT id, kind, pattern, reason, source, created_at FROM `+s.rules+` WHERE `+where, args...).
+		Scan(&rule.ID, &rule.Kind, &rule.Pattern, &rule.Reason, &rule.Source, &rule.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// ruleSet returns the in-memory set for a rule kind; s.mu must be held
+func (s *Store) ruleSet(kind string) map[string]bool {
+	switch kind {
+	case RuleDomain:
+		return s.domains
+	case RuleImageAllow:
+		return s.images
+	}
+	return s.urls
+}
+
+func (s *Store) query(ctx context.Context, clause string, args ...any) ([]Report, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, url, domain, reason, comment, report_count, status, resolution, note,
+		first_reported_at, last_reported_at, resolved_at FROM `+s.reports+` `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list reports: %w", err)
+	}
+	defer rows.Close()
+	reports := []Report{}
+	for rows.Next() {
+		var r Report
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.URL, &r.Domain, &r.Reason, &r.Comment, &r.Count, &r.Status, &r.Resolution, &r.Note,
+			&r.FirstReportedAt, &r.LastReportedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("list reports: %w", err)
+		}
+		if resolvedAt.Valid {
+			r.ResolvedAt = &resolvedAt.Time
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+func validReason(reason string) bool {
+	if reason == ReasonMisclassified {
+		return true
+	}
+	for _, r := range Reasons {
+		if reason == r {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeURL checks rawURL is a web address and returns it with the
+// scheme and host lowercased and the fragment dropped, and its domain
+// without a leading "www."
+func normalizeURL(rawURL string) (string, string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || len(rawURL) > maxURLLength {
+		return "", "", fmt.Errorf("%w: url must be an http or https address", ErrInvalidInput)
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", "", fmt.Errorf("%w: url must be an http or https address", ErrInvalidInput)
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String(), trimDomain(parsed.Hostname()), nil
+}
+
+// normalizeDomain accepts "example.com", "*.example.com" or a URL and
+// returns the bare domain
+func normalizeDomain(pattern string) (string, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if strings.Contains(pattern, "://") {
+		_, domain, err := normalizeURL(pattern)
+		if err != nil {
+			return "", err
+		}
+		pattern = domain
+	}
+	pattern = trimDomain(strings.TrimPrefix(pattern, "*."))
+	if !strings.Contains(pattern, ".") || strings.HasPrefix(pattern, ".") || strings.ContainsAny(pattern, "/:?# ") {
+		return "", fmt.Errorf("%w: domain must look like example.com", ErrInvalidInput)
+	}
+	return pattern, nil
+}
+
+func trimDomain(host string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(host), "."), "www.")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,185 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Server(t), "")
+}
+
+func TestSubmitCountsRepeats(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	first, err := store.Submit(ctx, "HTTPS://WWW.Spam.example/win#top", ReasonSpam, "  casino  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.URL != "https://www.spam.example/win" || first.Domain != "spam.example" || first.Comment != "casino" || first.Count != 1 {
+		t.Fatalf("report = %+v", first)
+	}
+	again, err := store.Submit(ctx, "https://www.spam.example/win", ReasonSpam, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Count != 2 || again.Comment != "casino" {
+		t.Errorf("repeat report = %+v, want the first report counted twice", again)
+	}
+	other, err := store.Submit(ctx, "https://www.spam.example/win", ReasonMalware, "")
+	if err != nil || other.ID == first.ID {
+		t.Errorf("Submit(other reason) = %+v, %v; want a separate report", other, err)
+	}
+
+	for name, args := range map[string][2]string{
+		"reason": {"https://a.example", "boring"},
+		"scheme": {"javascript:alert(1)", ReasonSpam},
+		"host":   {"https://", ReasonSpam},
+	} {
+		if _, err := store.Submit(ctx, args[0], args[1], ""); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: error = %v, want ErrInvalidInput", name, err)
+		}
+	}
+
+	open, err := store.List(ctx, "", 0)
+	if err != nil || len(open) != 2 || open[0].ID != first.ID {
+		t.Errorf("List() = %+v, %v; want both reports, most reported first", open, err)
+	}
+}
+
+func TestResolveBlockDomain(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	report, err := store.Submit(ctx, "https://www.bad.example/a", ReasonMalware, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sibling, _ := store.Submit(ctx, "https://cdn.bad.example/b", ReasonSpam, "")
+	unrelated, _ := store.Submit(ctx, "https://notbad.example/c", ReasonSpam, "")
+
+	resolved, rule, err := store.Resolve(ctx, report.ID, ActionBlockDomain, "drive-by download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule == nil || rule.Kind != RuleDomain || rule.Pattern != "bad.example" || rule.Source != "report:1" {
+		t.Fatalf("rule = %+v", rule)
+	}
+	if resolved.Status != StatusActioned || resolved.Note != "drive-by download" || resolved.ResolvedAt == nil {
+		t.Errorf("report = %+v", resolved)
+	}
+	if got, _ := store.Get(ctx, sibling.ID); got.Status != StatusActioned {
+		t.Errorf("subdomain report status = %s, want actioned", got.Status)
+	}
+	if got, _ := store.Get(ctx, unrelated.ID); got.Status != StatusOpen {
+		t.Errorf("unrelated report status = %s, want open", got.Status)
+	}
+
+	for u, want := range map[string]bool{
+		"https://bad.example/":         true,
+		"http://deep.cdn.bad.example/": true,
+		"https://notbad.example/":      false,
+		"not a url":                    false,
+	} {
+		if got := store.Blocked(u); got != want {
+			t.Errorf("Blocked(%q) = %v, want %v", u, got, want)
+		}
+	}
+
+	if _, _, err := store.Resolve(ctx, report.ID, ActionDismiss, ""); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Resolve(resolved) error = %v, want ErrInvalidInput", err)
+	}
+	if _, _, err := store.Resolve(ctx, 99, ActionDismiss, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	rule, err := store.AddRule(ctx, RuleURL, "https://Example.com/page#x", "phishing", "operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := store.AddRule(ctx, RuleURL, "https://example.com/page", "", ""); err != nil || again.ID != rule.ID {
+		t.Errorf("AddRule(duplicate) = %+v, %v; want the existing rule", again, err)
+	}
+	if !store.Blocked("https://example.com/page") || store.Blocked("https://example.com/other") {
+		t.Error("a url rule must block that URL only")
+	}
+	if _, err := store.AddRule(ctx, RuleDomain, "*.Example.com", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"com", "", "example.com/path"} {
+		if _, err := store.AddRule(ctx, RuleDomain, bad, "", ""); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("AddRule(domain %q) error = %v, want ErrInvalidInput", bad, err)
+		}
+	}
+
+	// A fresh store sees the saved rules after Load
+	reloaded := NewStore(store.db, "")
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Blocked("https://www.example.com/") {
+		t.Error("Load() did not restore the domain rule")
+	}
+
+	rules, err := store.Rules(ctx)
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("Rules() = %+v, %v", rules, err)
+	}
+	for _, r := range rules {
+		if _, err := store.RemoveRule(ctx, r.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.Blocked("https://example.com/page") {
+		t.Error("results are still blocked after their rules were removed")
+	}
+	if _, err := store.RemoveRule(ctx, rule.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveRule(removed) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestResolveAllowImage(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	report, err := store.Submit(ctx, "https://img.example/cat.jpg", ReasonMisclassified, "a cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spam, _ := store.Submit(ctx, "https://img.example/cat.jpg", ReasonSpam, "")
+
+	_, rule, err := store.Resolve(ctx, report.ID, ActionAllowImage, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Kind != RuleImageAllow || rule.Pattern != "https://img.example/cat.jpg" {
+		t.Fatalf("rule = %+v", rule)
+	}
+	if !store.ImageAllowed("HTTPS://IMG.example/cat.jpg") || store.ImageAllowed("https://img.example/dog.jpg") {
+		t.Error("an image_allow rule must exempt that image only")
+	}
+	if store.Blocked("https://img.example/cat.jpg") {
+		t.Error("an image_allow rule must not hide the result")
+	}
+	if got, _ := store.Get(ctx, spam.ID); got.Status != StatusOpen {
+		t.Errorf("spam report status = %s, want open", got.Status)
+	}
+
+	reloaded := NewStore(store.db, "")
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.ImageAllowed("https://img.example/cat.jpg") || reloaded.Blocked("https://img.example/cat.jpg") {
+		t.Error("Load() did not restore the image_allow rule as an exemption")
+	}
+}
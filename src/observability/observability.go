@@ -0,0 +1,375 @@
+// Package observability generates monitoring assets matched to the metrics
+// the server exposes: Prometheus alerting rules and a Grafana dashboard.
+// Operators get them with:
+//
+//	search --observability export [dir]
+//
+// The metric names here must stay in step with src/server/metrics.go.
+package observability
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Exported file names
+const (
+	RulesFile     = "search-alerts.yml"
+	DashboardFile = "search-dashboard.json"
+)
+
+// DashboardUID is the Grafana dashboard UID, fixed so a re-import replaces
+// the previous dashboard instead of adding a copy
+const DashboardUID = "apimgr-search"
+
+// Metrics lists the metric names the rules and dashboard query
+var Metrics = []string{
+	"search_http_requests_total",
+	"search_http_request_duration_seconds",
+	"search_http_active_requests",
+	"search_searches_total",
+	"search_search_duration_seconds",
+	"search_engine_up",
+	"search_engine_requests_total",
+	"search_engine_errors_total",
+	"search_engine_quota_used",
+	"search_engine_quota_remaining",
+	"search_engine_schema_drift",
+	"search_engine_parser_suspect",
+	"search_engine_circuit_open",
+	"search_engine_latency_p95_seconds",
+	"search_ssl_certificate_expiry_timestamp_seconds",
+	"search_cache_hits_total",
+	"search_cache_misses_total",
+	"search_uptime_seconds",
+	"search_memory_alloc_bytes",
+	"search_goroutines",
+	"search_memory_limit_bytes",
+	"search_memory_rss_bytes",
+	"search_memory_pressure_level",
+	"search_gc_percent",
+	"search_cache_capacity",
+}
+
+// Rule is a Prometheus alerting rule
+type Rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// RuleGroup is a Prometheus rule group
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// RuleFile is a Prometheus rule file, loaded with rule_files in
+// prometheus.yml
+type RuleFile struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+// Rules returns the alerting rules: engines down, out of quota or with a
+// likely broken parser, error rates, certificate expiry and memory pressure
+func Rules() RuleFile {
+	return RuleFile{Groups: []RuleGroup{
+		{
+			Name: "search-engines",
+			Rules: []Rule{
+				{
+					Alert:  "SearchEngineDown",
+					Expr:   `search_engine_up == 0`,
+					For:    "10m",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "Search engine {{ $labels.engine }} is down on {{ $labels.instance }}",
+						"description": "{{ $labels.engine }} has been cooling down after repeated failures for 10 minutes; results come from the remaining engines.",
+					},
+				},
+				{
+					Alert:  "SearchAllEnginesDown",
+					Expr:   `max by (instance) (search_engine_up) == 0`,
+					For:    "5m",
+					Labels: map[string]string{"severity": "critical"},
+					Annotations: map[string]string{
+						"summary":     "Every search engine is down on {{ $labels.instance }}",
+						"description": "No enabled engine is accepting searches, so searches return no results.",
+					},
+				},
+				{
+					Alert: "SearchEngineErrorRate",
+					Expr: `sum by (instance, engine) (rate(search_engine_errors_total[15m]))
+  / sum by (instance, engine) (rate(search_engine_requests_total[15m])) > 0.5`,
+					For:    "15m",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "Search engine {{ $labels.engine }} fails {{ $value | humanizePercentage }} of requests on {{ $labels.instance }}",
+						"description": "The upstream may be blocking this instance or have changed its responses.",
+					},
+				},
+				{
+					Alert:  "SearchEngineCircuitOpen",
+					Expr:   `search_engine_circuit_open == 1`,
+					For:    "30m",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "Search engine {{ $labels.engine }} has been skipped for 30 minutes on {{ $labels.instance }}",
+						"description": "Its circuit breaker keeps opening: the engine fails or answers too slowly, and its retries after each cooldown fail too.",
+					},
+				},
+				{
+					Alert:  "SearchEngineParserBroken",
+					Expr:   `search_engine_parser_suspect == 1`,
+					For:    "15m",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "Search engine {{ $labels.engine }} likely has a broken parser on {{ $labels.instance }}",
+						"description": "Its responses still succeed but no longer have the structure the parser learned; the site has probably changed its markup or API.",
+					},
+				},
+				{
+					Alert:  "SearchEngineQuotaExhausted",
+					Expr:   `search_engine_quota_remaining == 0`,
+					Labels: map[string]string{"severity": "info"},
+					Annotations: map[string]string{
+						"summary":     "Search engine {{ $labels.engine }} has used its daily quota on {{ $labels.instance }}",
+						"description": "Its fallback engine, if any, answers in its place until the day's quota resets.",
+					},
+				},
+			},
+		},
+		{
+			Name: "search-http",
+			Rules: []Rule{
+				{
+					Alert: "SearchHighErrorRate",
+					Expr: `sum by (instance) (rate(search_http_requests_total{status=~"5.."}[5m]))
+  / sum by (instance) (rate(search_http_requests_total[5m])) > 0.05`,
+					For:    "10m",
+					Labels: map[string]string{"severity": "critical"},
+					Annotations: map[string]string{
+						"summary":     "{{ $value | humanizePercentage }} of requests to {{ $labels.instance }} fail with a 5xx",
+						"description": "More than 5% of HTTP requests have returned a server error for 10 minutes.",
+					},
+				},
+				{
+					Alert:  "SearchSlowSearches",
+					Expr:   `histogram_quantile(0.95, sum by (instance, le) (rate(search_search_duration_seconds_bucket[10m]))) > 5`,
+					For:    "15m",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "95th percentile search time on {{ $labels.instance }} is {{ $value | humanizeDuration }}",
+						"description": "Searches are slower than 5 seconds; check engine health and timeouts.",
+					},
+				},
+			},
+		},
+		{
+			Name: "search-tls",
+			Rules: []Rule{
+				{
+					Alert:  "SearchCertificateExpiringSoon",
+					Expr:   `search_ssl_certificate_expiry_timestamp_seconds > 0 and search_ssl_certificate_expiry_timestamp_seconds - time() < 14 * 86400`,
+					For:    "1h",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "TLS certificate on {{ $labels.instance }} expires in {{ $value | humanizeDuration }}",
+						"description": "Let's Encrypt renews 7 days before expiry; a certificate this close to expiry is not being renewed.",
+					},
+				},
+				{
+					Alert:  "SearchCertificateExpiring",
+					Expr:   `search_ssl_certificate_expiry_timestamp_seconds > 0 and search_ssl_certificate_expiry_timestamp_seconds - time() < 3 * 86400`,
+					Labels: map[string]string{"severity": "critical"},
+					Annotations: map[string]string{
+						"summary":     "TLS certificate on {{ $labels.instance }} expires in {{ $value | humanizeDuration }}",
+						"description": "Renew the certificate now; browsers will refuse the site once it expires.",
+					},
+				},
+			},
+		},
+		{
+			Name: "search-memory",
+			Rules: []Rule{
+				{
+					Alert:  "SearchMemoryPressure",
+					Expr:   `search_memory_pressure_level >= 2`,
+					For:    "10m",
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary":     "{{ $labels.instance }} has been near its memory limit for 10 minutes",
+						"description": "The memory watchdog has cut the caches to a fifth and GOGC to 20; searches hit the engines more often. Add memory or lower the load.",
+					},
+				},
+			},
+		},
+	}}
+}
+
+// RulesYAML returns the alerting rules as a Prometheus rule file
+func RulesYAML() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(Rules()); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// panel builds a Grafana panel at the given grid position
+func panel(id int, kind, title, unit string, x, y, w, h int, targets ...map[string]any) map[string]any {
+	for i, target := range targets {
+		target["refId"] = string(rune('A' + i))
+		target["datasource"] = map[string]any{"type": "prometheus", "uid": "${datasource}"}
+	}
+	return map[string]any{
+		"id":         id,
+		"type":       kind,
+		"title":      title,
+		"datasource": map[string]any{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":    map[string]any{"x": x, "y": y, "w": w, "h": h},
+		"fieldConfig": map[string]any{
+			"defaults":  map[string]any{"unit": unit},
+			"overrides": []any{},
+		},
+		"targets": targets,
+	}
+}
+
+// target builds a Prometheus query
+func target(expr, legend string) map[string]any {
+	return map[string]any{"expr": expr, "legendFormat": legend}
+}
+
+// Dashboard returns the Grafana dashboard model. It takes the Prometheus
+// data source and instance as variables, so it imports as-is.
+func Dashboard() map[string]any {
+	sel := `instance=~"$instance"`
+	panels := []map[string]any{
+		panel(1, "stat", "Engines up", "none", 0, 0, 6, 4,
+			target(`sum(search_engine_up{`+sel+`})`, "up")),
+		panel(2, "stat", "Certificate expires in", "s", 6, 0, 6, 4,
+			target(`search_ssl_certificate_expiry_timestamp_seconds{`+sel+`} - time() > 0`, "{{instance}}")),
+		panel(3, "stat", "Uptime", "s", 12, 0, 6, 4,
+			target(`search_uptime_seconds{`+sel+`}`, "{{instance}}")),
+		panel(4, "stat", "Active requests", "none", 18, 0, 6, 4,
+			target(`sum(search_http_active_requests{`+sel+`})`, "active")),
+		panel(5, "timeseries", "HTTP requests by status", "reqps", 0, 4, 12, 8,
+			target(`sum by (status) (rate(search_http_requests_total{`+sel+`}[$__rate_interval]))`, "{{status}}")),
+		panel(6, "timeseries", "HTTP 5xx error ratio", "percentunit", 12, 4, 12, 8,
+			target(`sum(rate(search_http_requests_total{`+sel+`,status=~"5.."}[$__rate_interval])) / sum(rate(search_http_requests_total{`+sel+`}[$__rate_interval]))`, "5xx")),
+		panel(7, "timeseries", "Searches", "reqps", 0, 12, 12, 8,
+			target(`sum(rate(search_searches_total{`+sel+`}[$__rate_interval]))`, "searches")),
+		panel(8, "timeseries", "Search time p95 by category", "s", 12, 12, 12, 8,
+			target(`histogram_quantile(0.95, sum by (category, le) (rate(search_search_duration_seconds_bucket{`+sel+`}[$__rate_interval])))`, "{{category}}")),
+		panel(9, "state-timeline", "Engine up", "none", 0, 20, 12, 8,
+			target(`max by (engine) (search_engine_up{`+sel+`})`, "{{engine}}")),
+		panel(10, "timeseries", "Engine error ratio", "percentunit", 12, 20, 12, 8,
+			target(`sum by (engine) (rate(search_engine_errors_total{`+sel+`}[$__rate_interval])) / sum by (engine) (rate(search_engine_requests_total{`+sel+`}[$__rate_interval]))`, "{{engine}}")),
+		panel(11, "timeseries", "HTTP latency p95", "s", 0, 28, 12, 8,
+			target(`histogram_quantile(0.95, sum by (le) (rate(search_http_request_duration_seconds_bucket{`+sel+`}[$__rate_interval])))`, "p95")),
+		panel(12, "timeseries", "Cache hit ratio", "percentunit", 12, 28, 12, 8,
+			target(`sum by (cache) (rate(search_cache_hits_total{`+sel+`}[$__rate_interval])) / (sum by (cache) (rate(search_cache_hits_total{`+sel+`}[$__rate_interval])) + sum by (cache) (rate(search_cache_misses_total{`+sel+`}[$__rate_interval])))`, "{{cache}}")),
+		panel(13, "timeseries", "Memory allocated", "bytes", 0, 36, 12, 8,
+			target(`search_memory_alloc_bytes{`+sel+`}`, "{{instance}}")),
+		panel(14, "timeseries", "Goroutines", "none", 12, 36, 12, 8,
+			target(`search_goroutines{`+sel+`}`, "{{instance}}")),
+		panel(15, "timeseries", "Memory used and limit", "bytes", 0, 44, 12, 8,
+			target(`search_memory_rss_bytes{`+sel+`}`, "resident {{instance}}"),
+			target(`search_memory_limit_bytes{`+sel+`} > 0`, "limit {{instance}}")),
+		panel(16, "timeseries", "Cache capacity", "none", 12, 44, 12, 8,
+			target(`sum by (cache) (search_cache_capacity{`+sel+`})`, "{{cache}}")),
+		panel(17, "state-timeline", "Memory pressure", "none", 0, 52, 12, 8,
+			target(`max by (instance) (search_memory_pressure_level{`+sel+`})`, "{{instance}}")),
+		panel(18, "timeseries", "GOGC", "none", 12, 52, 12, 8,
+			target(`search_gc_percent{`+sel+`}`, "{{instance}}")),
+		panel(19, "timeseries", "Engine quota used today", "none", 0, 60, 12, 8,
+			target(`max by (engine) (search_engine_quota_used{`+sel+`})`, "{{engine}}")),
+		panel(20, "bargauge", "Engine quota remaining", "none", 12, 60, 12, 8,
+			target(`min by (engine) (search_engine_quota_remaining{`+sel+`})`, "{{engine}}")),
+		panel(21, "state-timeline", "Engine parser likely broken", "none", 0, 68, 12, 8,
+			target(`max by (engine) (search_engine_parser_suspect{`+sel+`})`, "{{engine}}")),
+		panel(22, "timeseries", "Engine response schema drift", "percentunit", 12, 68, 12, 8,
+			target(`max by (engine) (search_engine_schema_drift{`+sel+`})`, "{{engine}}")),
+		panel(23, "state-timeline", "Engine circuit open", "none", 0, 76, 12, 8,
+			target(`max by (engine) (search_engine_circuit_open{`+sel+`})`, "{{engine}}")),
+		panel(24, "timeseries", "Engine latency p95", "s", 12, 76, 12, 8,
+			target(`max by (engine) (search_engine_latency_p95_seconds{`+sel+`})`, "{{engine}}")),
+	}
+
+	return map[string]any{
+		"uid":           DashboardUID,
+		"title":         "Search",
+		"tags":          []string{"search", "metasearch"},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]any{"from": "now-6h", "to": "now"},
+		"templating": map[string]any{"list": []any{
+			map[string]any{
+				"name":  "datasource",
+				"label": "Data source",
+				"type":  "datasource",
+				"query": "prometheus",
+			},
+			map[string]any{
+				"name":       "instance",
+				"label":      "Instance",
+				"type":       "query",
+				"datasource": map[string]any{"type": "prometheus", "uid": "${datasource}"},
+				"query":      "label_values(search_uptime_seconds, instance)",
+				"refresh":    2,
+				"includeAll": true,
+				"multi":      true,
+				"current":    map[string]any{"text": "All", "value": "$__all"},
+			},
+		}},
+		"panels": panels,
+	}
+}
+
+// DashboardJSON returns the Grafana dashboard as importable JSON
+func DashboardJSON() ([]byte, error) {
+	return json.MarshalIndent(Dashboard(), "", "  ")
+}
+
+// Export writes the rule file and dashboard into dir, creating it, and
+// returns the written paths
+func Export(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create %s: %w", dir, err)
+	}
+	rules, err := RulesYAML()
+	if err != nil {
+		return nil, fmt.Errorf("encode alert rules: %w", err)
+	}
+	dashboard, err := DashboardJSON()
+	if err != nil {
+		return nil, fmt.Errorf("encode dashboard: %w", err)
+	}
+
+	var written []string
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{RulesFile, rules}, {DashboardFile, dashboard}} {
+		path := filepath.Join(dir, file.name)
+		if err := os.WriteFile(path, file.data, 0644); err != nil {
+			return written, fmt.Errorf("write %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
@@ -0,0 +1,133 @@
+package observability
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// metricRef matches a metric name in a PromQL expression
+var metricRef = regexp.MustCompile(`\bsearch_[a-z0-9_]+`)
+
+// queriedMetric strips the series suffix Prometheus adds to histograms
+func queriedMetric(name string) string {
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if strings.HasSuffix(name, "_seconds"+suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+func TestMetricsExposedByServer(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("..", "server", "metrics.go"))
+	if err != nil {
+		t.Fatalf("read server metrics: %v", err)
+	}
+	for _, name := range Metrics {
+		if !regexp.MustCompile(`Name:\s+"` + name + `"`).Match(src) {
+			t.Errorf("metric %s is not registered in src/server/metrics.go", name)
+		}
+	}
+}
+
+func TestRulesYAML(t *testing.T) {
+	data, err := RulesYAML()
+	if err != nil {
+		t.Fatalf("RulesYAML: %v", err)
+	}
+	var file RuleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatalf("rules are not valid YAML: %v", err)
+	}
+
+	known := make(map[string]bool)
+	for _, name := range Metrics {
+		known[name] = true
+	}
+	alerts := make(map[string]bool)
+	for _, group := range file.Groups {
+		for _, rule := range group.Rules {
+			alerts[rule.Alert] = true
+			if rule.Labels["severity"] == "" || rule.Annotations["summary"] == "" {
+				t.Errorf("rule %s needs a severity and a summary", rule.Alert)
+			}
+			for _, name := range metricRef.FindAllString(rule.Expr, -1) {
+				if !known[queriedMetric(name)] {
+					t.Errorf("rule %s queries unknown metric %s", rule.Alert, name)
+				}
+			}
+		}
+	}
+	for _, want := range []string{"SearchEngineDown", "SearchHighErrorRate", "SearchCertificateExpiring"} {
+		if !alerts[want] {
+			t.Errorf("rules have no %s alert", want)
+		}
+	}
+}
+
+func TestDashboardJSON(t *testing.T) {
+	data, err := DashboardJSON()
+	if err != nil {
+		t.Fatalf("DashboardJSON: %v", err)
+	}
+	var dashboard struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			ID      int `json:"id"`
+			Targets []struct {
+				Expr  string `json:"expr"`
+				RefID string `json:"refId"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		t.Fatalf("dashboard is not valid JSON: %v", err)
+	}
+	if dashboard.UID != DashboardUID || len(dashboard.Panels) == 0 {
+		t.Fatalf("dashboard uid = %q with %d panels", dashboard.UID, len(dashboard.Panels))
+	}
+
+	known := make(map[string]bool)
+	for _, name := range Metrics {
+		known[name] = true
+	}
+	ids := make(map[int]bool)
+	for _, p := range dashboard.Panels {
+		if ids[p.ID] {
+			t.Errorf("panel id %d is used twice", p.ID)
+		}
+		ids[p.ID] = true
+		for _, target := range p.Targets {
+			if target.RefID == "" {
+				t.Errorf("panel %d has a target without a refId", p.ID)
+			}
+			for _, name := range metricRef.FindAllString(target.Expr, -1) {
+				if !known[queriedMetric(name)] {
+					t.Errorf("panel %d queries unknown metric %s", p.ID, name)
+				}
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "monitoring")
+	written, err := Export(dir)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(written) != 2 {
+		t.Fatalf("Export wrote %v, want 2 files", written)
+	}
+	for _, name := range []string{RulesFile, DashboardFile} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+}
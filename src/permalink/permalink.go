@@ -0,0 +1,207 @@
+// Package permalink stores searches behind shareable /s/<token> links. The
+// search is kept server-side so the query never appears in a URL, an access
+// log or a Referer header. Each row is encrypted with its link's token and
+// keyed by the token's hash, so the stored searches cannot be read without
+// the links.
+package permalink
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/ssl"
+)
+
+var (
+	// ErrNotFound is returned for an unknown or expired link
+	ErrNotFound = errors.New("permalink not found or expired")
+	// ErrInvalidState is returned for a search that cannot be shared
+	ErrInvalidState = errors.New("invalid permalink search")
+)
+
+// DefaultTTL is how long a link works when search.permalinks.ttl is unset
+const DefaultTTL = 7 * 24 * time.Hour
+
+// maxQueryLength bounds the stored query, matching the search box
+const maxQueryLength = 1000
+
+// timeRanges are the accepted time_range values
+var timeRanges = map[string]bool{"any": true, "day": true, "week": true, "month": true, "year": true}
+
+// State is a shared search: the query and the filters it ran with
+type State struct {
+	Query    string `json:"query"`
+	Category string `json:"category,omitempty"`
+	// SafeSearch is 0-2, or empty for the viewer's own preference
+	SafeSearch string `json:"safe_search,omitempty"`
+	// TimeRange is any, day, week, month or year
+	TimeRange string `json:"time_range,omitempty"`
+	PerPage   int    `json:"per_page,omitempty"`
+}
+
+// Normalize trims the state and checks it can be shared
+func (st *State) Normalize() error {
+	st.Query = strings.TrimSpace(st.Query)
+	st.Category = strings.ToLower(strings.TrimSpace(st.Category))
+	st.SafeSearch = strings.TrimSpace(st.SafeSearch)
+	st.TimeRange = strings.ToLower(strings.TrimSpace(st.TimeRange))
+	if st.Query == "" {
+		return fmt.Errorf("%w: query is required", ErrInvalidState)
+	}
+	if len(st.Query) > maxQueryLength {
+		return fmt.Errorf("%w: query is too long", ErrInvalidState)
+	}
+	if st.SafeSearch != "" {
+		if level, err := strconv.Atoi(st.SafeSearch); err != nil || level < 0 || level > 2 {
+			return fmt.Errorf("%w: safe_search must be 0, 1 or 2", ErrInvalidState)
+		}
+	}
+	if st.TimeRange == "any" {
+		st.TimeRange = ""
+	}
+	if st.TimeRange != "" && !timeRanges[st.TimeRange] {
+		return fmt.Errorf("%w: time_range must be any, day, week, month or year", ErrInvalidState)
+	}
+	if st.PerPage < 0 || st.PerPage > 100 {
+		st.PerPage = 0
+	}
+	return nil
+}
+
+func (st State) fields() map[string]string {
+	return map[string]string{
+		"query":       st.Query,
+		"category":    st.Category,
+		"safe_search": st.SafeSearch,
+		"time_range":  st.TimeRange,
+		"per_page":    strconv.Itoa(st.PerPage),
+	}
+}
+
+func stateFromFields(fields map[string]string) *State {
+	perPage, _ := strconv.Atoi(fields["per_page"])
+	return &State{
+		Query:      fields["query"],
+		Category:   fields["category"],
+		SafeSearch: fields["safe_search"],
+		TimeRange:  fields["time_range"],
+		PerPage:    perPage,
+	}
+}
+
+// ParseTTL parses search.permalinks.ttl ("7d", "12h"); an empty or invalid
+// value is DefaultTTL
+func ParseTTL(value string) time.Duration {
+	seconds, err := config.ParseDuration(value)
+	if err != nil || seconds <= 0 {
+		return DefaultTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Link is a created permalink
+type Link struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store keeps permalinks in the server database
+type Store struct {
+	db    *sql.DB
+	table string
+}
+
+// NewStore creates a store; tablePrefix is the server table prefix
+func NewStore(db *sql.DB, tablePrefix string) *Store {
+	return &Store{db: db, table: tablePrefix + "search_permalinks"}
+}
+
+// Create stores st and returns a link that works for ttl (DefaultTTL when
+// zero)
+func (s *Store) Create(ctx context.Context, st State, ttl time.Duration) (*Link, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("permalink storage is unavailable")
+	}
+	if err := st.Normalize(); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	encrypted, err := ssl.EncryptCredentials(st.fields(), token)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt permalink: %w", err)
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	link := &Link{Token: token, ExpiresAt: time.Now().UTC().Add(ttl)}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.table+` (id, state_encrypted, expires_at) VALUES (?, ?, ?)`,
+		tokenID(token), encrypted, link.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("store permalink: %w", err)
+	}
+	return link, nil
+}
+
+// Get returns the search behind token and when the link expires
+func (s *Store) Get(ctx context.Context, token string) (*State, time.Time, error) {
+	if s == nil || s.db == nil {
+		return nil, time.Time{}, ErrNotFound
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, time.Time{}, ErrNotFound
+	}
+
+	var encrypted string
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT state_encrypted, expires_at FROM `+s.table+` WHERE id = ?`, tokenID(token)).
+		Scan(&encrypted, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("load permalink: %w", err)
+	}
+	if time.Now().After(expiresAt) {
+		return nil, time.Time{}, ErrNotFound
+	}
+
+	fields, err := ssl.DecryptCredentials(encrypted, token)
+	if err != nil {
+		return nil, time.Time{}, ErrNotFound
+	}
+	return stateFromFields(fields), expiresAt, nil
+}
+
+// PurgeExpired deletes expired links and returns how many were removed
+func (s *Store) PurgeExpired(ctx context.Context) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE expires_at < ?`, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge permalinks: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// tokenID is the stored key for a token; the token itself is never stored
+func tokenID(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,97 @@
+package permalink
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Server(t), "")
+}
+
+func TestCreateAndGet(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	link, err := store.Create(ctx, State{Query: "  golang generics ", Category: "News", SafeSearch: "2", TimeRange: "Week", PerPage: 30}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Token == "" || time.Until(link.ExpiresAt) > time.Hour {
+		t.Fatalf("link = %+v", link)
+	}
+
+	state, expiresAt, err := store.Get(ctx, link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := State{Query: "golang generics", Category: "news", SafeSearch: "2", TimeRange: "week", PerPage: 30}
+	if *state != want {
+		t.Errorf("state = %+v, want %+v", *state, want)
+	}
+	if !expiresAt.Equal(link.ExpiresAt) {
+		t.Errorf("expires_at = %v, want %v", expiresAt, link.ExpiresAt)
+	}
+
+	// Neither the token nor the query is stored in the clear
+	var id, stored string
+	if err := store.db.QueryRow(`SELECT id, state_encrypted FROM search_permalinks`).Scan(&id, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if id == link.Token || strings.Contains(stored, "golang") {
+		t.Error("permalink row holds the token or the query in plain text")
+	}
+}
+
+func TestGetUnknownAndExpired(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	if _, _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	link, err := store.Create(ctx, State{Query: "expired"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(`UPDATE search_permalinks SET expires_at = ?`, time.Now().UTC().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get(ctx, link.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(expired) error = %v, want ErrNotFound", err)
+	}
+
+	purged, err := store.PurgeExpired(ctx)
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeExpired() = %d, %v; want 1", purged, err)
+	}
+}
+
+func TestCreateInvalidState(t *testing.T) {
+	store := newTestStore(t)
+	for name, st := range map[string]State{
+		"empty query": {Query: "   "},
+		"long query":  {Query: strings.Repeat("a", maxQueryLength+1)},
+		"safe search": {Query: "q", SafeSearch: "5"},
+		"time range":  {Query: "q", TimeRange: "decade"},
+	} {
+		if _, err := store.Create(context.Background(), st, 0); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%s: error = %v, want ErrInvalidState", name, err)
+		}
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": DefaultTTL, "soon": DefaultTTL, "12h": 12 * time.Hour, "30d": 30 * 24 * time.Hour} {
+		if got := ParseTTL(in); got != want {
+			t.Errorf("ParseTTL(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
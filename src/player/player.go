@@ -0,0 +1,169 @@
+// Package player works out how a video result can be played on this
+// instance without loading the video host's own page: through a privacy
+// frontend, a cookie-free embed, the host's embed player (Vimeo,
+// Dailymotion, PeerTube), or the video file itself.
+package player
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Frontends YouTube videos can be played through
+const (
+	// FrontendInvidious and FrontendPiped embed from the operator's
+	// instance of that frontend
+	FrontendInvidious = "invidious"
+	FrontendPiped     = "piped"
+	// FrontendNoCookie embeds from youtube-nocookie.com
+	FrontendNoCookie = "nocookie"
+)
+
+// Frontends are the values of search.video_player.frontend
+var Frontends = []string{FrontendInvidious, FrontendPiped, FrontendNoCookie}
+
+// videoExtensions are the files a <video> element plays directly
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".m4v":  true,
+	".webm": true,
+	".ogv":  true,
+}
+
+var (
+	youtubeIDRe     = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDRe       = regexp.MustCompile(`^[0-9]{3,12}$`)
+	dailymotionIDRe = regexp.MustCompile(`^x[0-9a-z]{4,12}$`)
+	// PeerTube watch pages are /videos/watch/<uuid> or, since PeerTube 3.3,
+	// /w/<short uuid> on whichever instance hosts the video
+	peertubeWatchRe = regexp.MustCompile(`^/(?:videos/watch|w)/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[1-9A-HJ-NP-Za-km-z]{22})$`)
+)
+
+// Embed is how one video is played
+type Embed struct {
+	// Src is the frame or, for a Video, the file
+	Src string
+	// Video is set for a file played in a <video> element rather than a
+	// frame
+	Video bool
+	// Origin is Src's scheme and host, which the page's content security
+	// policy must allow
+	Origin string
+}
+
+// Player resolves video URLs to embeds for one configuration
+type Player struct {
+	frontend string
+	instance string
+}
+
+// New returns a player using frontend for YouTube videos. Invidious and
+// Piped need the base URL of an instance; without one, and with no
+// frontend, YouTube videos are not played.
+func New(frontend, instance string) *Player {
+	p := &Player{frontend: strings.ToLower(strings.TrimSpace(frontend))}
+	if u, err := url.Parse(strings.TrimSpace(instance)); err == nil && u.Scheme == "https" && u.Host != "" {
+		p.instance = "https://" + u.Host + strings.TrimSuffix(u.Path, "/")
+	}
+	return p
+}
+
+// Embed returns how the video at rawURL is played, or false if it cannot
+// be played here
+func (p *Player) Embed(rawURL string) (*Embed, bool) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	switch host {
+	case "youtube.com", "youtu.be", "youtube-nocookie.com":
+		if id := youtubeID(host, u); id != "" {
+			return p.youtube(id)
+		}
+		return nil, false
+	case "vimeo.com", "player.vimeo.com":
+		id := path.Base(u.Path)
+		if !vimeoIDRe.MatchString(id) {
+			return nil, false
+		}
+		// dnt stops Vimeo setting tracking cookies
+		return newEmbed("https://player.vimeo.com/video/"+id+"?dnt=1", false), true
+	case "dailymotion.com", "dai.ly":
+		id := path.Base(u.Path)
+		if host == "dailymotion.com" && !strings.HasPrefix(u.Path, "/video/") {
+			return nil, false
+		}
+		if !dailymotionIDRe.MatchString(id) {
+			return nil, false
+		}
+		return newEmbed("https://www.dailymotion.com/embed/video/"+id, false), true
+	}
+
+	if m := peertubeWatchRe.FindStringSubmatch(u.Path); m != nil && u.Scheme == "https" {
+		return newEmbed("https://"+u.Host+"/videos/embed/"+m[1], false), true
+	}
+
+	// A page served over https cannot play an http file
+	if u.Scheme == "https" && videoExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return newEmbed(u.String(), true), true
+	}
+	return nil, false
+}
+
+// WatchHref returns the path of the watch page playing rawURL, or "" when
+// p is nil or the video cannot be played here
+func (p *Player) WatchHref(rawURL, title string) string {
+	if p == nil {
+		return ""
+	}
+	if _, ok := p.Embed(rawURL); !ok {
+		return ""
+	}
+	params := url.Values{}
+	params.Set("url", rawURL)
+	if title != "" {
+		params.Set("title", title)
+	}
+	return "/watch?" + params.Encode()
+}
+
+func (p *Player) youtube(id string) (*Embed, bool) {
+	switch p.frontend {
+	case FrontendInvidious, FrontendPiped:
+		if p.instance == "" {
+			return nil, false
+		}
+		return newEmbed(p.instance+"/embed/"+id, false), true
+	case FrontendNoCookie:
+		return newEmbed("https://www.youtube-nocookie.com/embed/"+id, false), true
+	}
+	return nil, false
+}
+
+// youtubeID returns the video ID of a YouTube watch, short, embed or
+// youtu.be URL
+func youtubeID(host string, u *url.URL) string {
+	var id string
+	switch {
+	case host == "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case u.Path == "/watch":
+		id = u.Query().Get("v")
+	case strings.HasPrefix(u.Path, "/shorts/"), strings.HasPrefix(u.Path, "/embed/"), strings.HasPrefix(u.Path, "/live/"):
+		id = path.Base(u.Path)
+	}
+	if !youtubeIDRe.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+func newEmbed(src string, video bool) *Embed {
+	u, _ := url.Parse(src)
+	return &Embed{Src: src, Video: video, Origin: u.Scheme + "://" + u.Host}
+}
@@ -0,0 +1,69 @@
+package player
+
+import "testing"
+
+func TestEmbed(t *testing.T) {
+	invidious := New("invidious", "https://yewtu.be/")
+	tests := []struct {
+		player *Player
+		url    string
+		src    string
+		video  bool
+	}{
+		{invidious, "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42", "https://yewtu.be/embed/dQw4w9WgXcQ", false},
+		{invidious, "https://youtu.be/dQw4w9WgXcQ", "https://yewtu.be/embed/dQw4w9WgXcQ", false},
+		{New("piped", "https://piped.example/app"), "https://m.youtube.com/shorts/dQw4w9WgXcQ", "https://piped.example/app/embed/dQw4w9WgXcQ", false},
+		{New("nocookie", ""), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", false},
+		{New("", ""), "https://vimeo.com/76979871", "https://player.vimeo.com/video/76979871?dnt=1", false},
+		{New("", ""), "https://media.example/talks/keynote.WebM", "https://media.example/talks/keynote.WebM", true},
+		{New("", ""), "https://www.dailymotion.com/video/x8abc12", "https://www.dailymotion.com/embed/video/x8abc12", false},
+		{New("", ""), "https://dai.ly/x8abc12", "https://www.dailymotion.com/embed/video/x8abc12", false},
+		{New("", ""), "https://framatube.org/videos/watch/9c9de5e8-0a1e-484a-b099-e80766180a6d", "https://framatube.org/videos/embed/9c9de5e8-0a1e-484a-b099-e80766180a6d", false},
+		{New("", ""), "https://tilvids.com/w/kkGMgK9ZtnKfYAgnEtQxbv", "https://tilvids.com/videos/embed/kkGMgK9ZtnKfYAgnEtQxbv", false},
+	}
+	for _, tt := range tests {
+		embed, ok := tt.player.Embed(tt.url)
+		if !ok || embed.Src != tt.src || embed.Video != tt.video {
+			t.Errorf("Embed(%q) = %+v, %v; want %q", tt.url, embed, ok, tt.src)
+		}
+	}
+
+	if embed, _ := invidious.Embed("https://youtu.be/dQw4w9WgXcQ"); embed.Origin != "https://yewtu.be" {
+		t.Errorf("Origin = %q", embed.Origin)
+	}
+
+	for _, tc := range []struct {
+		player *Player
+		url    string
+	}{
+		{New("", ""), "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{New("invidious", ""), "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{New("invidious", "http://insecure.example"), "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{invidious, "https://www.youtube.com/watch?v=short"},
+		{invidious, "https://www.youtube.com/channel/UC123"},
+		{invidious, "http://media.example/clip.mp4"},
+		{invidious, "https://www.dailymotion.com/user/x8abc"},
+		{invidious, "https://www.twitch.tv/videos/123456789"},
+		{invidious, "http://framatube.org/videos/watch/9c9de5e8-0a1e-484a-b099-e80766180a6d"},
+		{invidious, "https://example.com/w/short"},
+		{invidious, "javascript:alert(1)"},
+	} {
+		if embed, ok := tc.player.Embed(tc.url); ok {
+			t.Errorf("Embed(%q) = %+v, want not playable", tc.url, embed)
+		}
+	}
+}
+
+func TestWatchHref(t *testing.T) {
+	p := New("nocookie", "")
+	if got, want := p.WatchHref("https://youtu.be/dQw4w9WgXcQ", "Song & dance"), "/watch?title=Song+%26+dance&url=https%3A%2F%2Fyoutu.be%2FdQw4w9WgXcQ"; got != want {
+		t.Errorf("WatchHref() = %q, want %q", got, want)
+	}
+	if got := p.WatchHref("https://www.twitch.tv/videos/123456789", ""); got != "" {
+		t.Errorf("WatchHref(unplayable) = %q", got)
+	}
+	var disabled *Player
+	if got := disabled.WatchHref("https://youtu.be/dQw4w9WgXcQ", ""); got != "" {
+		t.Errorf("nil player WatchHref() = %q", got)
+	}
+}
@@ -0,0 +1,229 @@
+// Package policy renders the privacy policy and terms of service pages
+// from markdown set in server.pages.privacy.content and
+// server.pages.terms.content. Placeholders like {instance_name} are filled
+// from the running config, so the pages keep describing what the instance
+// actually does when logging, engines or contacts change.
+package policy
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/safesearch"
+)
+
+// Variables returns the placeholder values for cfg, keyed by name without
+// braces. contact is markdown: a mailto link, or the contact form.
+func Variables(cfg *config.Config) map[string]string {
+	s := cfg.Server
+
+	name := s.Branding.Title
+	if name == "" {
+		name = s.Title
+	}
+	baseURL := s.BaseURL
+	if baseURL == "" && s.FQDN != "" {
+		baseURL = "https://" + s.FQDN
+	}
+
+	contactEmail := s.Contact.General.Email
+	if contactEmail == "" {
+		contactEmail = s.Pages.Contact.Email
+	}
+	contact := "[the contact form](/server/contact)"
+	if contactEmail != "" {
+		contact = fmt.Sprintf("[%s](mailto:%s)", contactEmail, contactEmail)
+	}
+	abuse := contact
+	if s.Contact.Abuse.Email != "" {
+		abuse = fmt.Sprintf("[%s](mailto:%s)", s.Contact.Abuse.Email, s.Contact.Abuse.Email)
+	}
+
+	accessLog := "disabled"
+	if s.Logs.Access.Filename != "" && !s.Logs.Access.Disabled {
+		accessLog = "enabled"
+	}
+	accessLogClient := "never IP addresses, search terms, referrers or browser details"
+	if s.Logs.Access.Privacy == "anonymized" {
+		accessLogClient = "your network rather than your IP address (the address with its last part zeroed) and, in place of your browser details, a code that changes whenever the server restarts; never search terms or referrers"
+	}
+	analytics := s.Tracking.Type
+	if analytics == "" {
+		analytics = "none"
+	}
+	tor := "available when the tor binary is installed"
+	if s.Tor.Disabled {
+		tor = "disabled"
+	}
+
+	var engines []string
+	for engine, ec := range cfg.Engines {
+		if ec.Enabled {
+			engines = append(engines, engine)
+		}
+	}
+	sort.Strings(engines)
+
+	return map[string]string{
+		"instance_name":     name,
+		"base_url":          baseURL,
+		"contact":           contact,
+		"contact_email":     contactEmail,
+		"abuse_contact":     abuse,
+		"access_log":        accessLog,
+		"access_log_client": accessLogClient,
+		"log_level":         s.Logs.Level,
+		"log_rotation":      strings.ReplaceAll(s.Logs.Access.Rotate, ",", " or at "),
+		"analytics":         analytics,
+		"tor":               tor,
+		"engines":           strings.Join(engines, ", "),
+		"safe_search":       safesearch.Name(cfg.Search.SafeSearch),
+	}
+}
+
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Expand replaces every {name} in text with its value; unknown names are
+// left as written so a typo shows on the page
+func Expand(text string, vars map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		if value, ok := vars[m[1:len(m)-1]]; ok {
+			return value
+		}
+		return m
+	})
+}
+
+// Render expands the placeholders in md and converts it to HTML
+func Render(md string, vars map[string]string) template.HTML {
+	return Markdown(Expand(md, vars))
+}
+
+var (
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletLine  = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedLine = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	ruleLine    = regexp.MustCompile(`^(-{3,}|\*{3,}|_{3,})$`)
+)
+
+// Markdown converts the markdown subset policy pages need to HTML:
+// headings, paragraphs, bulleted and numbered lists, rules, bold, italics,
+// code and links. Other text is escaped. A block starting with "<" is
+// passed through as HTML, as in markdown, so pages written as HTML before
+// markdown support keep rendering.
+func Markdown(md string) template.HTML {
+	var b strings.Builder
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+
+	var paragraph []string
+	var list string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + inline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			flush()
+			closeList()
+		case strings.HasPrefix(line, "<") && len(paragraph) == 0 && list == "":
+			// Raw HTML runs to the next blank line
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				b.WriteString(lines[i] + "\n")
+			}
+		case ruleLine.MatchString(line):
+			flush()
+			closeList()
+			b.WriteString("<hr>\n")
+		case headingLine.MatchString(line):
+			flush()
+			closeList()
+			m := headingLine.FindStringSubmatch(line)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", len(m[1]), inline(m[2]), len(m[1]))
+		case bulletLine.MatchString(line):
+			flush()
+			openList("ul")
+			b.WriteString("<li>" + inline(bulletLine.FindStringSubmatch(line)[1]) + "</li>\n")
+		case orderedLine.MatchString(line):
+			flush()
+			openList("ol")
+			b.WriteString("<li>" + inline(orderedLine.FindStringSubmatch(line)[1]) + "</li>\n")
+		default:
+			if list != "" {
+				// A continuation line of the last list item
+				closeList()
+			}
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+	closeList()
+	return template.HTML(b.String())
+}
+
+var (
+	codeSpan = regexp.MustCompile("`([^`]+)`")
+	linkSpan = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldSpan = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	emSpan   = regexp.MustCompile(`\*([^*]+)\*`)
+)
+
+// inline escapes text and renders code, links, bold and italics. Code spans
+// are set aside first so their content stays literal.
+func inline(text string) string {
+	var codes []string
+	text = codeSpan.ReplaceAllStringFunc(text, func(m string) string {
+		codes = append(codes, "<code>"+html.EscapeString(m[1:len(m)-1])+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+
+	text = html.EscapeString(text)
+	text = linkSpan.ReplaceAllStringFunc(text, func(m string) string {
+		parts := linkSpan.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		if !safeLink(href) {
+			return parts[1]
+		}
+		return `<a href="` + html.EscapeString(href) + `">` + parts[1] + `</a>`
+	})
+	text = boldSpan.ReplaceAllString(text, "<strong>$1</strong>")
+	text = emSpan.ReplaceAllString(text, "<em>$1</em>")
+
+	for i, code := range codes {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), code, 1)
+	}
+	return text
+}
+
+// safeLink allows web, mail and same-site links only
+func safeLink(href string) bool {
+	lower := strings.ToLower(href)
+	for _, prefix := range []string{"https://", "http://", "mailto:", "/", "#"} {
+		if strings.HasPrefix(lower, prefix) {
+			return !strings.HasPrefix(lower, "//")
+		}
+	}
+	return false
+}
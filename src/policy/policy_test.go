@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/config"
+)
+
+func TestMarkdown(t *testing.T) {
+	got := string(Markdown("# Title\n\nOne **bold** and *em* line\ncontinued `a<b`.\n\n- [site](https://example.com)\n- [bad](javascript:alert)\n\n1. first\n\n---\n\n<p class=\"raw\">kept</p>"))
+	for _, want := range []string{
+		"<h1>Title</h1>",
+		"<p>One <strong>bold</strong> and <em>em</em> line continued <code>a&lt;b</code>.</p>",
+		"<ul>\n<li><a href=\"https://example.com\">site</a></li>\n<li>bad</li>\n</ul>",
+		"<ol>\n<li>first</li>\n</ol>",
+		"<hr>",
+		"<p class=\"raw\">kept</p>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "javascript:") {
+		t.Errorf("unsafe link rendered:\n%s", got)
+	}
+}
+
+func TestMarkdownEscapesText(t *testing.T) {
+	got := string(Markdown("Hi <script>alert(1)</script>"))
+	if strings.Contains(got, "<script>") {
+		t.Errorf("text HTML not escaped: %s", got)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.Branding.Title = "Example Search"
+	vars := Variables(cfg)
+	if vars["instance_name"] != "Example Search" || vars["contact"] != "[the contact form](/server/contact)" {
+		t.Errorf("vars = %v", vars)
+	}
+	if !strings.Contains(vars["engines"], "duckduckgo") || vars["access_log"] != "enabled" || vars["analytics"] != "none" {
+		t.Errorf("vars = %v", vars)
+	}
+
+	cfg.Server.Contact.General.Email = "hello@example.com"
+	got := string(Render("Write to {contact}. {unknown} stays.", Variables(cfg)))
+	if !strings.Contains(got, `<a href="mailto:hello@example.com">hello@example.com</a>`) || !strings.Contains(got, "{unknown}") {
+		t.Errorf("Render = %s", got)
+	}
+}
+
+func TestTemplatesUseKnownVariables(t *testing.T) {
+	vars := Variables(config.DefaultConfig())
+	for name, text := range map[string]string{"privacy": Privacy, "terms": Terms} {
+		for _, m := range placeholder.FindAllStringSubmatch(text, -1) {
+			if _, ok := vars[m[1]]; !ok {
+				t.Errorf("%s uses unknown placeholder {%s}", name, m[1])
+			}
+		}
+	}
+}
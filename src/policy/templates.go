@@ -0,0 +1,68 @@
+package policy
+
+// Privacy is the starter privacy policy "search --init policy" writes to
+// server.pages.privacy.content. It states what this software does; the
+// placeholders keep it in step with the config.
+const Privacy = `{instance_name} is a metasearch engine: it sends your search to other search engines and shows you their combined results. It is built to know as little about you as possible.
+
+## What happens to your searches
+
+- Your query is forwarded to the enabled search engines ({engines}) without your IP address, cookies or browser details. Their privacy policies apply to what they receive.
+- Queries are not stored, logged or used to build a profile.
+- Safe search defaults to {safe_search}.
+
+## Logs
+
+- Access logging is {access_log}. Access logs record only the request method, path, status, size and timing, {access_log_client}.
+- Server logs are kept at level {log_level}.
+- Access logs are rotated {log_rotation}.
+
+## Cookies
+
+Preferences you choose, such as theme, language and safe search, are stored in a cookie in your browser. They are not stored on the server. No tracking or advertising cookies are set.
+
+## Analytics
+
+Third-party analytics: {analytics}.
+
+## Tor
+
+A Tor onion service is {tor}.
+
+## Search alerts
+
+If you subscribe to a search alert, the server stores your email address, the alert's query and settings, and the results found for it, until you delete the alert. The manage link in your alert email lets you download this data or delete it.
+
+## Your rights
+
+Apart from search alerts, no personal data is stored. To have every alert for your email address exported or erased, contact {contact}. Other questions about this policy go there too.
+`
+
+// Terms is the starter terms of service "search --init policy" writes to
+// server.pages.terms.content
+const Terms = `By using {instance_name} ({base_url}) you agree to these terms.
+
+## The service
+
+{instance_name} shows results from other search engines. It does not host, control or endorse the sites in those results, and results may be incomplete, out of date or unavailable.
+
+## Acceptable use
+
+- Do not use the service to break the law or to harm others.
+- Do not send automated queries at a rate that degrades the service for other people, and respect the published rate limits.
+- Do not try to reach parts of the service you were not given access to.
+
+Access may be limited or blocked for anyone who breaks these terms.
+
+## No warranty
+
+The service is provided as is, without warranties of any kind. The operators are not liable for any damages arising from its use.
+
+## Reporting abuse
+
+Report abuse or infringing content to {abuse_contact}.
+
+## Changes
+
+These terms may change. Continuing to use the service after a change means accepting the new terms. Questions go to {contact}.
+`
@@ -0,0 +1,322 @@
+// Package preferences keeps search preferences on the server, so they
+// survive cleared site data and follow a searcher between browsers. There
+// are no passwords: a profile belongs to whoever holds its token. An
+// anonymous profile is created the first time a browser saves its
+// preferences and its token kept in a cookie; an account is a named
+// profile an operator creates and whose token they hand over. Tokens are
+// stored only as SHA-256 hashes.
+package preferences
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for an unknown token or account
+	ErrNotFound = errors.New("preferences profile not found")
+	// ErrInvalidInput is returned for preferences or a name that is rejected
+	ErrInvalidInput = errors.New("invalid preferences")
+)
+
+const (
+	// CookieName is the cookie holding a browser's profile token
+	CookieName = "search_profile"
+	// HeaderName carries a profile token for API clients without cookies
+	HeaderName = "X-Preferences-Token"
+)
+
+const (
+	maxEngines    = 100
+	maxNameLength = 100
+)
+
+var (
+	languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+	enginePattern   = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+)
+
+// Preferences are what a profile stores. An empty field keeps the server
+// default.
+type Preferences struct {
+	// SafeSearch is 0 (off), 1 (moderate) or 2 (strict)
+	SafeSearch *int `json:"safe_search,omitempty"`
+	// Language is the language results are searched in, such as "de"
+	Language string `json:"language,omitempty"`
+	// Engines limits searches to these engines where they serve the
+	// category
+	Engines []string `json:"engines,omitempty"`
+	// ResultsPerPage is between 1 and 100
+	ResultsPerPage int `json:"results_per_page,omitempty"`
+	// Theme is light, dark, auto or contrast
+	Theme string `json:"theme,omitempty"`
+}
+
+// Normalize checks p and returns it in canonical form: lower case, with
+// duplicate engines removed
+func Normalize(p Preferences) (Preferences, error) {
+	if p.SafeSearch != nil && (*p.SafeSearch < 0 || *p.SafeSearch > 2) {
+		return p, fmt.Errorf("%w: safe_search must be 0, 1 or 2", ErrInvalidInput)
+	}
+	p.Language = strings.ToLower(strings.TrimSpace(p.Language))
+	if p.Language != "" && !languagePattern.MatchString(p.Language) {
+		return p, fmt.Errorf("%w: language must be a language code such as \"de\" or \"pt-br\"", ErrInvalidInput)
+	}
+	if p.ResultsPerPage < 0 || p.ResultsPerPage > 100 {
+		return p, fmt.Errorf("%w: results_per_page must be between 1 and 100", ErrInvalidInput)
+	}
+	p.Theme = strings.ToLower(strings.TrimSpace(p.Theme))
+	switch p.Theme {
+	case "", "light", "dark", "auto", "contrast":
+	default:
+		return p, fmt.Errorf("%w: theme must be light, dark, auto or contrast", ErrInvalidInput)
+	}
+
+	if len(p.Engines) > maxEngines {
+		return p, fmt.Errorf("%w: at most %d engines", ErrInvalidInput, maxEngines)
+	}
+	engines := make([]string, 0, len(p.Engines))
+	seen := make(map[string]bool, len(p.Engines))
+	for _, name := range p.Engines {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !enginePattern.MatchString(name) {
+			return p, fmt.Errorf("%w: %q is not an engine name", ErrInvalidInput, name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			engines = append(engines, name)
+		}
+	}
+	p.Engines = nil
+	if len(engines) > 0 {
+		p.Engines = engines
+	}
+	return p, nil
+}
+
+// Profile is a stored set of preferences
+type Profile struct {
+	ID string `json:"id"`
+	// Name is set for accounts and empty for anonymous profiles
+	Name        string      `json:"name,omitempty"`
+	Preferences Preferences `json:"preferences"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// IsAccount reports whether the profile is an account an operator made
+func (p *Profile) IsAccount() bool {
+	return p.Name != ""
+}
+
+// Store keeps profiles in the users database
+type Store struct {
+	db       *sql.DB
+	profiles string
+}
+
+// NewStore creates a store; tablePrefix is the users table prefix
+func NewStore(db *sql.DB, tablePrefix string) *Store {
+	return &Store{db: db, profiles: tablePrefix + "preference_profiles"}
+}
+
+// Create stores an anonymous profile and returns it with its token. The
+// token is shown only here.
+func (s *Store) Create(ctx context.Context, prefs Preferences) (*Profile, string, error) {
+	prefs, err := Normalize(prefs)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.insert(ctx, "", prefs)
+}
+
+// CreateAccount stores an empty, named profile and returns it with its
+// token. The token is shown only here.
+func (s *Store) CreateAccount(ctx context.Context, name string) (*Profile, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return nil, "", fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidInput, maxNameLength)
+	}
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+s.profiles+` WHERE name = ?`, name).Scan(&exists)
+	if err != nil {
+		return nil, "", fmt.Errorf("create account: %w", err)
+	}
+	if exists > 0 {
+		return nil, "", fmt.Errorf("%w: an account named %q exists", ErrInvalidInput, name)
+	}
+	return s.insert(ctx, name, Preferences{})
+}
+
+func (s *Store) insert(ctx context.Context, name string, prefs Preferences) (*Profile, string, error) {
+	if s == nil || s.db == nil {
+		return nil, "", errors.New("preferences storage is unavailable")
+	}
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	var nameValue interface{}
+	if name != "" {
+		nameValue = name
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.profiles+`
+		(id, name, token_hash, preferences, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, nameValue, hashToken(token), string(data), now, now); err != nil {
+		return nil, "", fmt.Errorf("create profile: %w", err)
+	}
+	return &Profile{ID: id, Name: name, Preferences: prefs, CreatedAt: now, UpdatedAt: now}, token, nil
+}
+
+// Get returns the profile for a token
+func (s *Store) Get(ctx context.Context, token string) (*Profile, error) {
+	token = strings.TrimSpace(token)
+	if s == nil || s.db == nil || token == "" {
+		return nil, ErrNotFound
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT id, name, preferences, created_at, updated_at FROM `+s.profiles+`
+		WHERE token_hash = ?`, hashToken(token))
+	return scanProfile(row)
+}
+
+// Update replaces the preferences of the profile for a token
+func (s *Store) Update(ctx context.Context, token string, prefs Preferences) (*Profile, error) {
+	p, err := s.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if prefs, err = Normalize(prefs); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, err
+	}
+	p.Preferences, p.UpdatedAt = prefs, time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, `UPDATE `+s.profiles+` SET preferences = ?, updated_at = ? WHERE id = ?`,
+		string(data), p.UpdatedAt, p.ID); err != nil {
+		return nil, fmt.Errorf("update profile: %w", err)
+	}
+	return p, nil
+}
+
+// Delete removes the profile for a token
+func (s *Store) Delete(ctx context.Context, token string) error {
+	p, err := s.Get(ctx, token)
+	if err != nil {
+		return err
+	}
+	return s.deleteID(ctx, p.ID)
+}
+
+// Accounts lists the accounts by name
+func (s *Store) Accounts(ctx context.Context) ([]Profile, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("preferences storage is unavailable")
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, preferences, created_at, updated_at FROM `+s.profiles+`
+		WHERE name IS NOT NULL ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []Profile{}
+	for rows.Next() {
+		p, err := scanProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *p)
+	}
+	return accounts, rows.Err()
+}
+
+// DeleteAccount removes the account with the given ID
+func (s *Store) DeleteAccount(ctx context.Context, id string) error {
+	if s == nil || s.db == nil {
+		return ErrNotFound
+	}
+	var name sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT name FROM `+s.profiles+` WHERE id = ?`, id).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !name.Valid) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+	return s.deleteID(ctx, id)
+}
+
+func (s *Store) deleteID(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM `+s.profiles+` WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete profile: %w", err)
+	}
+	return nil
+}
+
+// TokenFromRequest returns the profile token a request carries, in the
+// HeaderName header or the CookieName cookie
+func TokenFromRequest(r *http.Request) string {
+	if token := strings.TrimSpace(r.Header.Get(HeaderName)); token != "" {
+		return token
+	}
+	if c, err := r.Cookie(CookieName); err == nil {
+		return strings.TrimSpace(c.Value)
+	}
+	return ""
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProfile(row scanner) (*Profile, error) {
+	var p Profile
+	var name sql.NullString
+	var data string
+	if err := row.Scan(&p.ID, &name, &data, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("load profile: %w", err)
+	}
+	p.Name = name.String
+	if err := json.Unmarshal([]byte(data), &p.Preferences); err != nil {
+		return nil, fmt.Errorf("load profile: %w", err)
+	}
+	return &p, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomToken(bytesLen int) (string, error) {
+	buf := make([]byte, bytesLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,138 @@
+package preferences
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Users(t), "")
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize(Preferences{
+		SafeSearch:     intPtr(2),
+		Language:       " PT-BR ",
+		Engines:        []string{"Google", " bing", "google"},
+		ResultsPerPage: 50,
+		Theme:          "Dark",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Preferences{SafeSearch: intPtr(2), Language: "pt-br", Engines: []string{"google", "bing"}, ResultsPerPage: 50, Theme: "dark"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []Preferences{
+		{SafeSearch: intPtr(3)},
+		{Language: "english"},
+		{Engines: []string{"no such/engine"}},
+		{ResultsPerPage: 101},
+		{Theme: "neon"},
+	} {
+		if _, err := Normalize(bad); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Normalize(%+v) error = %v, want ErrInvalidInput", bad, err)
+		}
+	}
+}
+
+func TestStoreProfile(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	created, token, err := store.Create(ctx, Preferences{SafeSearch: intPtr(0), Language: "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" || created.IsAccount() {
+		t.Fatalf("Create() = %+v, %q", created, token)
+	}
+
+	got, err := store.Get(ctx, token)
+	if err != nil || got.ID != created.ID || got.Preferences.SafeSearch == nil || *got.Preferences.SafeSearch != 0 || got.Preferences.Language != "de" {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	updated, err := store.Update(ctx, token, Preferences{Engines: []string{"duckduckgo"}, Theme: "light"})
+	if err != nil || updated.Preferences.Language != "" || updated.Preferences.Theme != "light" {
+		t.Fatalf("Update() = %+v, %v", updated, err)
+	}
+	if _, err := store.Update(ctx, token, Preferences{Theme: "neon"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Update() with a bad theme: error = %v", err)
+	}
+
+	if err := store.Delete(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete: error = %v", err)
+	}
+	if _, err := store.Get(ctx, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(\"\"): error = %v", err)
+	}
+}
+
+func TestStoreAccounts(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	// Anonymous profiles are not accounts
+	if _, _, err := store.Create(ctx, Preferences{}); err != nil {
+		t.Fatal(err)
+	}
+	account, token, err := store.CreateAccount(ctx, " Kiosk ")
+	if err != nil || account.Name != "Kiosk" || !account.IsAccount() {
+		t.Fatalf("CreateAccount() = %+v, %v", account, err)
+	}
+	if _, _, err := store.CreateAccount(ctx, "Kiosk"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("duplicate account: error = %v", err)
+	}
+	if _, _, err := store.CreateAccount(ctx, " "); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("empty name: error = %v", err)
+	}
+
+	if _, err := store.Update(ctx, token, Preferences{ResultsPerPage: 10}); err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := store.Accounts(ctx)
+	if err != nil || len(accounts) != 1 || accounts[0].Preferences.ResultsPerPage != 10 {
+		t.Fatalf("Accounts() = %+v, %v", accounts, err)
+	}
+
+	if err := store.DeleteAccount(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteAccount(missing): error = %v", err)
+	}
+	if err := store.DeleteAccount(ctx, account.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("token of a deleted account still works: %v", err)
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if TokenFromRequest(r) != "" {
+		t.Error("token from an empty request")
+	}
+	r.Header.Set("Cookie", CookieName+"=from-cookie")
+	if got := TokenFromRequest(r); got != "from-cookie" {
+		t.Errorf("TokenFromRequest() = %q", got)
+	}
+	r.Header.Set(HeaderName, "from-header")
+	if got := TokenFromRequest(r); got != "from-header" {
+		t.Errorf("TokenFromRequest() = %q, the header should win", got)
+	}
+}
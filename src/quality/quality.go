@@ -0,0 +1,392 @@
+// Package quality runs a suite of searches against an instance and checks
+// where expected domains rank, so a change to ranking, engine weights or
+// category engines can be compared before and after it is merged. A suite
+// is the golden ranking: for each query, the domains that must appear in
+// the top results and those that must not.
+package quality
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultTop is how far down a domain may rank when a check sets no top
+	defaultTop = 10
+	// maxTop is the most results the search API returns per page
+	maxTop         = 100
+	defaultTimeout = 30 * time.Second
+)
+
+// Suite is a list of cases, read from YAML:
+//
+//	cases:
+//	  - query: golang generics
+//	    expect:
+//	      - domain: go.dev
+//	        top: 3
+//	      - domain: pinterest.com
+//	        absent: true
+type Suite struct {
+	Cases []Case `yaml:"cases" json:"cases"`
+}
+
+// Case is one search and the domains expected in its results
+type Case struct {
+	// Name labels the case in reports; the query when empty
+	Name     string  `yaml:"name" json:"name,omitempty"`
+	Query    string  `yaml:"query" json:"query"`
+	Category string  `yaml:"category" json:"category,omitempty"`
+	Expect   []Check `yaml:"expect" json:"expect"`
+}
+
+// Label is the case's name, or its query
+func (c Case) Label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Query
+}
+
+// Check is one expectation about a domain. A domain matches its
+// subdomains, so go.dev also matches pkg.go.dev and www.go.dev.
+type Check struct {
+	Domain string `yaml:"domain" json:"domain"`
+	// Top is the lowest rank the domain may have (default 10)
+	Top int `yaml:"top" json:"top"`
+	// Absent inverts the check: the domain must not rank within Top
+	Absent bool `yaml:"absent" json:"absent,omitempty"`
+}
+
+// String describes the check, e.g. "go.dev in top 3"
+func (c Check) String() string {
+	if c.Absent {
+		return fmt.Sprintf("%s not in top %d", c.Domain, c.Top)
+	}
+	return fmt.Sprintf("%s in top %d", c.Domain, c.Top)
+}
+
+// LoadSuite reads and validates a suite file
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := suite.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &suite, nil
+}
+
+// Validate checks every case has a query and every check a domain and a
+// top the search API can return, and fills in defaults: domains are
+// lowercased without "www." and an unset top is 10
+func (s *Suite) Validate() error {
+	if len(s.Cases) == 0 {
+		return errors.New("the suite has no cases")
+	}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		c.Query = strings.TrimSpace(c.Query)
+		if c.Query == "" {
+			return fmt.Errorf("case %d has no query", i+1)
+		}
+		if len(c.Expect) == 0 {
+			return fmt.Errorf("case %q has no expectations", c.Label())
+		}
+		for j := range c.Expect {
+			check := &c.Expect[j]
+			check.Domain = normalizeDomain(check.Domain)
+			if check.Domain == "" {
+				return fmt.Errorf("case %q: expectation %d has no domain", c.Label(), j+1)
+			}
+			if check.Top == 0 {
+				check.Top = defaultTop
+			}
+			if check.Top < 1 || check.Top > maxTop {
+				return fmt.Errorf("case %q: top %d for %s is outside 1-%d", c.Label(), check.Top, check.Domain, maxTop)
+			}
+		}
+	}
+	return nil
+}
+
+// Options configures a run
+type Options struct {
+	// BaseURL is the instance under test, e.g. http://127.0.0.1:64080
+	BaseURL string
+	Suite   *Suite
+	// Timeout bounds each search (default 30s)
+	Timeout time.Duration
+	// Client overrides the HTTP client
+	Client *http.Client
+	// Progress, when set, is called before each search
+	Progress func(done, total int, c Case)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Check
+	// Rank is the domain's best position, from 1; 0 when it is not in the
+	// results that were fetched
+	Rank   int  `json:"rank"`
+	Passed bool `json:"passed"`
+}
+
+// CaseResult is the outcome of one case
+type CaseResult struct {
+	Case    Case     `json:"case"`
+	Results []Result `json:"results,omitempty"`
+	// Domains are the domains of the results in rank order
+	Domains []string `json:"domains,omitempty"`
+	// Error is set when the search failed; no check is run then
+	Error string `json:"error,omitempty"`
+}
+
+// Passed reports whether the search succeeded and every check passed
+func (c CaseResult) Passed() bool {
+	if c.Error != "" {
+		return false
+	}
+	for _, r := range c.Results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Report is the outcome of a run. It is saved as JSON to serve as the
+// baseline of a later run (see Compare).
+type Report struct {
+	BaseURL string       `json:"base_url"`
+	RanAt   time.Time    `json:"ran_at"`
+	Cases   []CaseResult `json:"cases"`
+}
+
+// Counts returns how many checks passed and failed, and how many cases
+// could not be searched
+func (r *Report) Counts() (passed, failed, errored int) {
+	for _, c := range r.Cases {
+		if c.Error != "" {
+			errored++
+			continue
+		}
+		for _, res := range c.Results {
+			if res.Passed {
+				passed++
+			} else {
+				failed++
+			}
+		}
+	}
+	return passed, failed, errored
+}
+
+// Run searches each case in turn, one at a time so engines are not sent
+// a burst, and checks its results
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Suite == nil || len(opts.Suite.Cases) == 0 {
+		return nil, errors.New("the suite has no cases")
+	}
+	searchURL, err := url.Parse(strings.TrimSuffix(opts.BaseURL, "/") + "/api/v1/search")
+	if err != nil || (searchURL.Scheme != "http" && searchURL.Scheme != "https") || searchURL.Host == "" {
+		return nil, fmt.Errorf("invalid instance URL %q", opts.BaseURL)
+	}
+	client := opts.Client
+	if client == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	report := &Report{BaseURL: opts.BaseURL, RanAt: time.Now().UTC()}
+	for i, c := range opts.Suite.Cases {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if opts.Progress != nil {
+			opts.Progress(i, len(opts.Suite.Cases), c)
+		}
+		result := CaseResult{Case: c}
+		domains, err := search(ctx, client, searchURL, c)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Domains = domains
+			result.Results = evaluate(c.Expect, domains)
+		}
+		report.Cases = append(report.Cases, result)
+	}
+	return report, nil
+}
+
+// searchResponse is the part of the search API response a run reads
+type searchResponse struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+	Data    struct {
+		Results []struct {
+			URL    string `json:"url"`
+			Domain string `json:"domain"`
+		} `json:"results"`
+		Degraded bool `json:"degraded"`
+	} `json:"data"`
+}
+
+// search returns the domains of a case's results in rank order, fetching
+// as many results as its deepest check looks at
+func search(ctx context.Context, client *http.Client, searchURL *url.URL, c Case) ([]string, error) {
+	limit := 0
+	for _, check := range c.Expect {
+		limit = max(limit, check.Top)
+	}
+	params := url.Values{"q": {c.Query}, "limit": {strconv.Itoa(limit)}}
+	if c.Category != "" {
+		params.Set("category", c.Category)
+	}
+	u := *searchURL
+	u.RawQuery = params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "search-quality")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body searchResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("HTTP %d: response is not JSON", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || !body.OK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, body.Message)
+	}
+	// Stale results from the cache say nothing about the current ranking
+	if body.Data.Degraded {
+		return nil, errors.New("no engine answered; the instance returned stale results")
+	}
+
+	domains := make([]string, 0, len(body.Data.Results))
+	for _, r := range body.Data.Results {
+		domain := r.Domain
+		if domain == "" {
+			if parsed, err := url.Parse(r.URL); err == nil {
+				domain = parsed.Hostname()
+			}
+		}
+		domains = append(domains, normalizeDomain(domain))
+	}
+	return domains, nil
+}
+
+// evaluate runs checks against the result domains in rank order
+func evaluate(checks []Check, domains []string) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		rank := 0
+		for i, domain := range domains {
+			if matchDomain(domain, check.Domain) {
+				rank = i + 1
+				break
+			}
+		}
+		within := rank > 0 && rank <= check.Top
+		results = append(results, Result{Check: check, Rank: rank, Passed: within != check.Absent})
+	}
+	return results
+}
+
+// normalizeDomain lowercases a domain and drops "www." and a trailing dot
+func normalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// matchDomain reports whether domain is want or one of its subdomains
+func matchDomain(domain, want string) bool {
+	return domain == want || strings.HasSuffix(domain, "."+want)
+}
+
+// Change is a check whose outcome differs from the baseline's
+type Change struct {
+	Case   string `json:"case"`
+	Result Result `json:"result"`
+	// BaselineRank is the domain's rank in the baseline run
+	BaselineRank int `json:"baseline_rank"`
+}
+
+// Compare returns the checks that passed in baseline and fail in current
+// (regressions) and those that failed in baseline and pass now (fixes).
+// Checks are matched by case label and check; new checks and cases that
+// could not be searched in either run are left out.
+func Compare(baseline, current *Report) (regressions, fixes []Change) {
+	type key struct {
+		label string
+		check Check
+	}
+	before := make(map[key]Result)
+	for _, c := range baseline.Cases {
+		for _, r := range c.Results {
+			before[key{c.Case.Label(), r.Check}] = r
+		}
+	}
+	for _, c := range current.Cases {
+		for _, r := range c.Results {
+			old, ok := before[key{c.Case.Label(), r.Check}]
+			if !ok || old.Passed == r.Passed {
+				continue
+			}
+			change := Change{Case: c.Case.Label(), Result: r, BaselineRank: old.Rank}
+			if old.Passed {
+				regressions = append(regressions, change)
+			} else {
+				fixes = append(fixes, change)
+			}
+		}
+	}
+	return regressions, fixes
+}
+
+// LoadReport reads a report saved as JSON
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Save writes the report as JSON, for use as a later run's baseline
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
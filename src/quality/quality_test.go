@@ -0,0 +1,161 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rankingServer answers the search API with results from the given
+// domains, in order, for every query
+func rankingServer(t *testing.T, domains map[string][]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list, ok := domains[r.URL.Query().Get("q")]
+		if r.URL.Path != "/api/v1/search" || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"message":"unknown query"}`))
+			return
+		}
+		results := make([]string, len(list))
+		for i, d := range list {
+			// Every other result leaves domain out, as some engines do
+			if i%2 == 0 {
+				results[i] = fmt.Sprintf(`{"url":"https://%s/page","domain":%q}`, d, d)
+			} else {
+				results[i] = fmt.Sprintf(`{"url":"https://%s/page"}`, d)
+			}
+		}
+		fmt.Fprintf(w, `{"ok":true,"data":{"results":[%s]}}`, strings.Join(results, ","))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLoadSuite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quality.yml")
+	os.WriteFile(path, []byte(`cases:
+  - query: " golang generics "
+    expect:
+      - domain: WWW.Go.Dev
+        top: 3
+      - domain: pinterest.com
+        absent: true
+`), 0644)
+	suite, err := LoadSuite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := suite.Cases[0]
+	if c.Query != "golang generics" || c.Expect[0].Domain != "go.dev" || c.Expect[1].Top != defaultTop {
+		t.Errorf("case not normalized: %+v", c)
+	}
+
+	for name, body := range map[string]string{
+		"no cases":  "cases: []",
+		"no query":  "cases:\n  - expect: [{domain: go.dev}]",
+		"no expect": "cases:\n  - query: go",
+		"no domain": "cases:\n  - query: go\n    expect: [{top: 3}]",
+		"top":       "cases:\n  - query: go\n    expect: [{domain: go.dev, top: 500}]",
+	} {
+		os.WriteFile(path, []byte(body), 0644)
+		if _, err := LoadSuite(path); err == nil {
+			t.Errorf("%s: suite accepted", name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv := rankingServer(t, map[string][]string{
+		"golang": {"www.pinterest.com", "pkg.go.dev", "stackoverflow.com", "go.dev"},
+	})
+	suite := &Suite{Cases: []Case{
+		{Query: "golang", Expect: []Check{
+			{Domain: "go.dev", Top: 2},
+			{Domain: "stackoverflow.com", Top: 2},
+			{Domain: "pinterest.com", Absent: true},
+			{Domain: "reddit.com", Absent: true},
+		}},
+		{Name: "broken", Query: "unknown", Expect: []Check{{Domain: "go.dev"}}},
+	}}
+	if err := suite.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []string
+	report, err := Run(context.Background(), Options{
+		BaseURL: srv.URL,
+		Suite:   suite,
+		Progress: func(done, total int, c Case) {
+			progress = append(progress, fmt.Sprintf("%d/%d %s", done, total, c.Label()))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := report.Cases[0].Results
+	want := []Result{
+		{Check: Check{Domain: "go.dev", Top: 2}, Rank: 2, Passed: true},
+		{Check: Check{Domain: "stackoverflow.com", Top: 2}, Rank: 3, Passed: false},
+		{Check: Check{Domain: "pinterest.com", Top: 10, Absent: true}, Rank: 1, Passed: false},
+		{Check: Check{Domain: "reddit.com", Top: 10, Absent: true}, Rank: 0, Passed: true},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("check %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if broken := report.Cases[1]; broken.Error == "" || broken.Passed() || !strings.Contains(broken.Error, "unknown query") {
+		t.Errorf("failed search: %+v", broken)
+	}
+	if passed, failed, errored := report.Counts(); passed != 2 || failed != 2 || errored != 1 {
+		t.Errorf("Counts() = %d, %d, %d", passed, failed, errored)
+	}
+	if strings.Join(progress, ",") != "0/2 golang,1/2 broken" {
+		t.Errorf("progress = %v", progress)
+	}
+
+	if _, err := Run(context.Background(), Options{BaseURL: "ftp://host", Suite: suite}); err == nil {
+		t.Error("Run accepted a non-HTTP URL")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	suite := &Suite{Cases: []Case{{Query: "golang", Expect: []Check{
+		{Domain: "go.dev", Top: 1},
+		{Domain: "stackoverflow.com", Top: 3},
+	}}}}
+	suite.Validate()
+	run := func(domains ...string) *Report {
+		srv := rankingServer(t, map[string][]string{"golang": domains})
+		report, err := Run(context.Background(), Options{BaseURL: srv.URL, Suite: suite})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	// The baseline survives a save and load
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := run("go.dev", "example.com", "example.org", "stackoverflow.com").Save(path); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := LoadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regressions, fixes := Compare(baseline, run("stackoverflow.com", "go.dev"))
+	if len(regressions) != 1 || regressions[0].Result.Domain != "go.dev" || regressions[0].BaselineRank != 1 || regressions[0].Result.Rank != 2 {
+		t.Errorf("regressions = %+v", regressions)
+	}
+	if len(fixes) != 1 || fixes[0].Result.Domain != "stackoverflow.com" || fixes[0].Case != "golang" {
+		t.Errorf("fixes = %+v", fixes)
+	}
+}
@@ -0,0 +1,397 @@
+// Package release describes built binaries for supply-chain-conscious
+// operators. For each artifact --build writes an SPDX and a CycloneDX SBOM
+// listing the modules linked into it, read from the binary's own build
+// info, and an in-toto SLSA provenance statement naming its digest, the
+// source commit and how it was built.
+//
+// Builds are reproducible: the build time comes from SOURCE_DATE_EPOCH or
+// the commit time, paths are trimmed and VCS info is stamped by the Go
+// toolchain, so rebuilding a commit gives the same bytes and the same SBOMs.
+package release
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attestation file suffixes, appended to the artifact name
+const (
+	SPDXSuffix       = ".spdx.json"
+	CycloneDXSuffix  = ".cdx.json"
+	ProvenanceSuffix = ".intoto.jsonl"
+)
+
+// Repository is the source repository recorded in SBOMs and provenance
+const Repository = "https://github.com/apimgr/search"
+
+// BuilderID identifies the --build command as the provenance builder
+const BuilderID = Repository + "/build@v1"
+
+// BuildType names the provenance build definition
+const BuildType = Repository + "/build-type/go-docker@v1"
+
+// Artifact is a built binary
+type Artifact struct {
+	Name   string
+	Path   string
+	SHA256 string
+	Size   int64
+	Info   *debug.BuildInfo
+}
+
+// Inspect hashes the binary at path and reads the build info the Go
+// toolchain embedded in it
+func Inspect(path string) (*Artifact, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("hash %s: %w", path, err)
+	}
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read build info of %s: %w", path, err)
+	}
+	return &Artifact{
+		Name:   filepath.Base(path),
+		Path:   path,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+		Size:   size,
+		Info:   info,
+	}, nil
+}
+
+// Setting returns a build setting such as vcs.revision or GOOS
+func (a *Artifact) Setting(key string) string {
+	for _, s := range a.Info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// modules returns the linked modules, replacements resolved, by path
+func (a *Artifact) modules() []debug.Module {
+	mods := make([]debug.Module, 0, len(a.Info.Deps))
+	for _, dep := range a.Info.Deps {
+		if dep.Replace != nil {
+			mods = append(mods, *dep.Replace)
+			continue
+		}
+		mods = append(mods, *dep)
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Path < mods[j].Path })
+	return mods
+}
+
+// mainVersion is the version of the main module: the release version
+// when known, else what the toolchain recorded
+func (a *Artifact) mainVersion(version string) string {
+	if version != "" {
+		return version
+	}
+	return a.Info.Main.Version
+}
+
+// purl returns the package URL of a Go module
+func purl(path, version string) string {
+	p := "pkg:golang/" + path
+	if version != "" && version != "(devel)" {
+		p += "@" + version
+	}
+	return p
+}
+
+// SourceDateEpoch returns the time builds of srcDir are stamped with:
+// SOURCE_DATE_EPOCH when set, else the time of the checked out commit, else
+// now (the build is then not reproducible)
+func SourceDateEpoch(srcDir string) (time.Time, bool) {
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC(), true
+		}
+	}
+	out, err := exec.Command("git", "-C", srcDir, "log", "-1", "--format=%ct").Output()
+	if err == nil {
+		if sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC(), true
+		}
+	}
+	return time.Now().UTC(), false
+}
+
+// SPDX returns an SPDX 2.3 JSON SBOM of the artifact. created should be
+// the build's source date, so the SBOM is as reproducible as the binary.
+func SPDX(a *Artifact, version string, created time.Time) ([]byte, error) {
+	type checksum struct {
+		Algorithm     string `json:"algorithm"`
+		ChecksumValue string `json:"checksumValue"`
+	}
+	type externalRef struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	}
+	type pkg struct {
+		SPDXID           string        `json:"SPDXID"`
+		Name             string        `json:"name"`
+		VersionInfo      string        `json:"versionInfo,omitempty"`
+		DownloadLocation string        `json:"downloadLocation"`
+		FilesAnalyzed    bool          `json:"filesAnalyzed"`
+		Checksums        []checksum    `json:"checksums,omitempty"`
+		ExternalRefs     []externalRef `json:"externalRefs,omitempty"`
+	}
+	type relationship struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	}
+
+	mainVersion := a.mainVersion(version)
+	packages := []pkg{{
+		SPDXID:           "SPDXRef-Package-main",
+		Name:             a.Info.Main.Path,
+		VersionInfo:      mainVersion,
+		DownloadLocation: "git+" + Repository,
+		Checksums:        []checksum{{Algorithm: "SHA256", ChecksumValue: a.SHA256}},
+		ExternalRefs: []externalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  purl(a.Info.Main.Path, mainVersion),
+		}},
+	}}
+	relationships := []relationship{{
+		Element: "SPDXRef-DOCUMENT",
+		Type:    "DESCRIBES",
+		Related: "SPDXRef-Package-main",
+	}}
+	for i, mod := range a.modules() {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		packages = append(packages, pkg{
+			SPDXID:           id,
+			Name:             mod.Path,
+			VersionInfo:      mod.Version,
+			DownloadLocation: "https://proxy.golang.org/" + mod.Path,
+			ExternalRefs: []externalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(mod.Path, mod.Version),
+			}},
+		})
+		relationships = append(relationships, relationship{
+			Element: "SPDXRef-Package-main",
+			Type:    "DEPENDS_ON",
+			Related: id,
+		})
+	}
+
+	doc := map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              a.Name,
+		"documentNamespace": Repository + "/spdx/" + a.Name + "-" + a.SHA256,
+		"creationInfo": map[string]any{
+			"created":  created.UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: search --build"},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// CycloneDX returns a CycloneDX 1.5 JSON SBOM of the artifact
+func CycloneDX(a *Artifact, version string, created time.Time) ([]byte, error) {
+	type hash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+	type component struct {
+		Type    string `json:"type"`
+		BOMRef  string `json:"bom-ref"`
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+		PURL    string `json:"purl"`
+		Hashes  []hash `json:"hashes,omitempty"`
+	}
+
+	mainVersion := a.mainVersion(version)
+	mainRef := purl(a.Info.Main.Path, mainVersion)
+	var components []component
+	var dependsOn []string
+	for _, mod := range a.modules() {
+		ref := purl(mod.Path, mod.Version)
+		components = append(components, component{
+			Type:    "library",
+			BOMRef:  ref,
+			Name:    mod.Path,
+			Version: mod.Version,
+			PURL:    ref,
+		})
+		dependsOn = append(dependsOn, ref)
+	}
+
+	doc := map[string]any{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + digestUUID(a.SHA256),
+		"version":      1,
+		"metadata": map[string]any{
+			"timestamp": created.UTC().Format(time.RFC3339),
+			"tools": map[string]any{"components": []map[string]string{
+				{"type": "application", "name": "search --build"},
+			}},
+			"component": component{
+				Type:    "application",
+				BOMRef:  mainRef,
+				Name:    a.Info.Main.Path,
+				Version: mainVersion,
+				PURL:    mainRef,
+				Hashes:  []hash{{Alg: "SHA-256", Content: a.SHA256}},
+			},
+		},
+		"components": components,
+		"dependencies": []map[string]any{
+			{"ref": mainRef, "dependsOn": dependsOn},
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// digestUUID derives the SBOM serial number from the artifact digest, so
+// the same binary always gets the same SBOM
+func digestUUID(sha string) string {
+	b, err := hex.DecodeString(sha)
+	if err != nil || len(b) < 16 {
+		b = make([]byte, 16)
+	}
+	b = b[:16]
+	// Version 8 (custom), RFC 4122 variant
+	b[6] = b[6]&0x0f | 0x80
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// BuildParams describe how an artifact was built, for its provenance
+type BuildParams struct {
+	// Version is the release version stamped into the binary
+	Version string
+	// Commit is the full source commit
+	Commit string
+	// Image is the build container image
+	Image string
+	// SourceDate is the time stamped into the binary
+	SourceDate time.Time
+	// Started and Finished bound the build
+	Started, Finished time.Time
+}
+
+// Provenance returns an in-toto statement carrying a SLSA v1 provenance
+// predicate for the artifact, as one JSON line
+func Provenance(a *Artifact, p BuildParams) ([]byte, error) {
+	commit := p.Commit
+	if commit == "" {
+		commit = a.Setting("vcs.revision")
+	}
+	dependencies := []map[string]any{}
+	if commit != "" {
+		dependencies = append(dependencies, map[string]any{
+			"uri":    "git+" + Repository,
+			"digest": map[string]string{"gitCommit": commit},
+		})
+	}
+
+	statement := map[string]any{
+		"_type": "https://in-toto.io/Statement/v1",
+		"subject": []map[string]any{{
+			"name":   a.Name,
+			"digest": map[string]string{"sha256": a.SHA256},
+		}},
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"predicate": map[string]any{
+			"buildDefinition": map[string]any{
+				"buildType": BuildType,
+				"externalParameters": map[string]any{
+					"version": p.Version,
+					"goos":    a.Setting("GOOS"),
+					"goarch":  a.Setting("GOARCH"),
+				},
+				"internalParameters": map[string]any{
+					"image":           p.Image,
+					"goVersion":       a.Info.GoVersion,
+					"sourceDateEpoch": p.SourceDate.Unix(),
+					"trimpath":        a.Setting("-trimpath") == "true",
+					"vcsModified":     a.Setting("vcs.modified") == "true",
+					"cgoEnabled":      a.Setting("CGO_ENABLED") == "1",
+					"ldflags":         a.Setting("-ldflags"),
+				},
+				"resolvedDependencies": dependencies,
+			},
+			"runDetails": map[string]any{
+				"builder": map[string]any{"id": BuilderID},
+				"metadata": map[string]any{
+					"startedOn":  p.Started.UTC().Format(time.RFC3339),
+					"finishedOn": p.Finished.UTC().Format(time.RFC3339),
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteAttestations writes the SBOMs and provenance of the artifact at
+// path next to it and returns the written paths
+func WriteAttestations(path string, p BuildParams) ([]string, error) {
+	a, err := Inspect(path)
+	if err != nil {
+		return nil, err
+	}
+	spdx, err := SPDX(a, p.Version, p.SourceDate)
+	if err != nil {
+		return nil, fmt.Errorf("encode SPDX SBOM: %w", err)
+	}
+	cdx, err := CycloneDX(a, p.Version, p.SourceDate)
+	if err != nil {
+		return nil, fmt.Errorf("encode CycloneDX SBOM: %w", err)
+	}
+	provenance, err := Provenance(a, p)
+	if err != nil {
+		return nil, fmt.Errorf("encode provenance: %w", err)
+	}
+
+	var written []string
+	for _, file := range []struct {
+		suffix string
+		data   []byte
+	}{{SPDXSuffix, spdx}, {CycloneDXSuffix, cdx}, {ProvenanceSuffix, provenance}} {
+		out := path + file.suffix
+		if err := os.WriteFile(out, file.data, 0644); err != nil {
+			return written, fmt.Errorf("write %s: %w", out, err)
+		}
+		written = append(written, out)
+	}
+	return written, nil
+}
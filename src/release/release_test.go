@@ -0,0 +1,156 @@
+package release
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testArtifact builds an Artifact with fixed build info
+func testArtifact() *Artifact {
+	return &Artifact{
+		Name:   "search-linux-amd64",
+		SHA256: strings.Repeat("ab", 32),
+		Info: &debug.BuildInfo{
+			GoVersion: "go1.23.0",
+			Main:      debug.Module{Path: "github.com/apimgr/search", Version: "(devel)"},
+			Deps: []*debug.Module{
+				{Path: "github.com/go-chi/chi/v5", Version: "v5.1.0"},
+				{Path: "example.com/old", Version: "v1.0.0", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.0.1"}},
+			},
+			Settings: []debug.BuildSetting{
+				{Key: "-trimpath", Value: "true"},
+				{Key: "GOOS", Value: "linux"},
+				{Key: "GOARCH", Value: "amd64"},
+				{Key: "vcs.revision", Value: "0123456789abcdef"},
+			},
+		},
+	}
+}
+
+func TestSPDX(t *testing.T) {
+	a := testArtifact()
+	created := time.Unix(1700000000, 0)
+	first, err := SPDX(a, "1.2.3", created)
+	if err != nil {
+		t.Fatalf("SPDX: %v", err)
+	}
+	second, _ := SPDX(a, "1.2.3", created)
+	if !bytes.Equal(first, second) {
+		t.Error("SPDX output differs between runs of the same artifact")
+	}
+
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			Name        string `json:"name"`
+			VersionInfo string `json:"versionInfo"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(first, &doc); err != nil {
+		t.Fatalf("SPDX is not valid JSON: %v", err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || len(doc.Packages) != 3 {
+		t.Fatalf("SPDX = %s with %d packages, want SPDX-2.3 with 3", doc.SPDXVersion, len(doc.Packages))
+	}
+	if doc.Packages[0].VersionInfo != "1.2.3" || doc.Packages[1].Name != "example.com/fork" {
+		t.Errorf("SPDX packages = %+v; want main at 1.2.3 and the replacement module", doc.Packages)
+	}
+}
+
+func TestCycloneDX(t *testing.T) {
+	data, err := CycloneDX(testArtifact(), "", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("CycloneDX: %v", err)
+	}
+	var doc struct {
+		BOMFormat    string `json:"bomFormat"`
+		SerialNumber string `json:"serialNumber"`
+		Components   []struct {
+			PURL string `json:"purl"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("CycloneDX is not valid JSON: %v", err)
+	}
+	uuid := regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if doc.BOMFormat != "CycloneDX" || !uuid.MatchString(doc.SerialNumber) {
+		t.Errorf("CycloneDX bomFormat %q serialNumber %q", doc.BOMFormat, doc.SerialNumber)
+	}
+	if len(doc.Components) != 2 || doc.Components[1].PURL != "pkg:golang/github.com/go-chi/chi/v5@v5.1.0" {
+		t.Errorf("CycloneDX components = %+v", doc.Components)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	a := testArtifact()
+	data, err := Provenance(a, BuildParams{Version: "1.2.3", SourceDate: time.Unix(1700000000, 0)})
+	if err != nil {
+		t.Fatalf("Provenance: %v", err)
+	}
+	if bytes.Count(data, []byte("\n")) != 1 {
+		t.Error("provenance is not a single JSON line")
+	}
+	var statement struct {
+		Type    string `json:"_type"`
+		Subject []struct {
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+		Predicate struct {
+			BuildDefinition struct {
+				ResolvedDependencies []struct {
+					Digest map[string]string `json:"digest"`
+				} `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(data, &statement); err != nil {
+		t.Fatalf("provenance is not valid JSON: %v", err)
+	}
+	if statement.Type != "https://in-toto.io/Statement/v1" || statement.Subject[0].Digest["sha256"] != a.SHA256 {
+		t.Errorf("provenance subject = %+v", statement.Subject)
+	}
+	deps := statement.Predicate.BuildDefinition.ResolvedDependencies
+	if len(deps) != 1 || deps[0].Digest["gitCommit"] != "0123456789abcdef" {
+		t.Errorf("provenance source = %+v, want the vcs.revision commit", deps)
+	}
+}
+
+func TestWriteAttestations(t *testing.T) {
+	// The test binary carries build info like any Go binary
+	data, err := os.ReadFile(os.Args[0])
+	if err != nil {
+		t.Fatalf("read test binary: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "search-test")
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := WriteAttestations(path, BuildParams{SourceDate: time.Unix(1700000000, 0)})
+	if err != nil {
+		t.Fatalf("WriteAttestations: %v", err)
+	}
+	if len(written) != 3 {
+		t.Fatalf("WriteAttestations wrote %v, want 3 files", written)
+	}
+	for _, suffix := range []string{SPDXSuffix, CycloneDXSuffix, ProvenanceSuffix} {
+		if _, err := os.Stat(path + suffix); err != nil {
+			t.Errorf("%s not written: %v", suffix, err)
+		}
+	}
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	got, ok := SourceDateEpoch(t.TempDir())
+	if !ok || got.Unix() != 1700000000 {
+		t.Errorf("SourceDateEpoch() = %v, %v; want 1700000000 from the environment", got, ok)
+	}
+}
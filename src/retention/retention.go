@@ -0,0 +1,332 @@
+// Package retention applies the server.retention limits: how long, and how
+// much, of each class of stored data is kept. Every run produces a report of
+// what is over a limit; data is only deleted when enforcement is on, so an
+// operator can review a dry run before enabling it.
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// Data classes, as named in server.retention
+const (
+	ClassLogs    = "logs"
+	ClassHistory = "history"
+	ClassMetrics = "metrics"
+	ClassCache   = "cache"
+)
+
+// table is a database table governed by a class, aged by one column
+type table struct {
+	name   string
+	column string
+	// date columns hold a day ("2006-01-02") rather than a timestamp
+	date bool
+}
+
+var classTables = map[string][]table{
+	ClassHistory: {{name: "search_alert_results", column: "first_seen_at"}},
+	ClassMetrics: {
+		{name: "search_stats", column: "date", date: true},
+		{name: "engine_stats", column: "date", date: true},
+		{name: "scheduler_history", column: "started_at"},
+	},
+}
+
+// Engine measures and purges data against the retention rules
+type Engine struct {
+	rules    config.RetentionConfig
+	logDir   string
+	cacheDir string
+	db       *sql.DB
+	// prefix is the server table prefix (srv_ on libsql)
+	prefix string
+	now    func() time.Time
+}
+
+// NewEngine creates an engine for the rules. db may be nil when the server
+// has no database, and tablePrefix is the server table prefix.
+func NewEngine(rules config.RetentionConfig, logDir, cacheDir string, db *sql.DB, tablePrefix string) *Engine {
+	return &Engine{
+		rules:    rules,
+		logDir:   logDir,
+		cacheDir: cacheDir,
+		db:       db,
+		prefix:   tablePrefix,
+		now:      time.Now,
+	}
+}
+
+// Report is the outcome of a retention run
+type Report struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	DryRun      bool          `json:"dry_run"`
+	Classes     []ClassReport `json:"classes"`
+}
+
+// ClassReport is what one class holds and what is over its limits
+type ClassReport struct {
+	Class   string `json:"class"`
+	MaxAge  string `json:"max_age,omitempty"`
+	MaxSize string `json:"max_size,omitempty"`
+	MaxRows int    `json:"max_rows,omitempty"`
+	// Files over a limit, oldest first (file classes)
+	Files []FileItem `json:"files,omitempty"`
+	// Rows over a limit per table (table classes)
+	Tables []TableItem `json:"tables,omitempty"`
+	// PurgeBytes is the size of the files listed
+	PurgeBytes int64 `json:"purge_bytes"`
+	// PurgeRows is the number of rows listed
+	PurgeRows int64 `json:"purge_rows"`
+	// KeptBytes is the size of what remains once the files are removed
+	KeptBytes int64  `json:"kept_bytes,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FileItem is a file over a limit
+type FileItem struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Reason is "age" or "size"
+	Reason string `json:"reason"`
+}
+
+// TableItem is the rows of one table over a limit
+type TableItem struct {
+	Table   string `json:"table"`
+	Total   int64  `json:"total"`
+	ByAge   int64  `json:"by_age"`
+	ByCount int64  `json:"by_count"`
+}
+
+// Plan reports what Enforce would remove, without removing anything
+func (e *Engine) Plan(ctx context.Context) *Report {
+	return e.run(ctx, true)
+}
+
+// Enforce removes everything over the limits and reports what was removed.
+// Callers decide whether enforcement is enabled.
+func (e *Engine) Enforce(ctx context.Context) *Report {
+	return e.run(ctx, false)
+}
+
+func (e *Engine) run(ctx context.Context, dryRun bool) *Report {
+	report := &Report{GeneratedAt: e.now().UTC(), DryRun: dryRun}
+	for _, class := range []struct {
+		name string
+		rule config.RetentionRule
+	}{
+		{ClassLogs, e.rules.Logs},
+		{ClassHistory, e.rules.History},
+		{ClassMetrics, e.rules.Metrics},
+		{ClassCache, e.rules.Cache},
+	} {
+		cr := ClassReport{Class: class.name, MaxAge: class.rule.MaxAge, MaxSize: class.rule.MaxSize, MaxRows: class.rule.MaxRows}
+		var err error
+		switch class.name {
+		case ClassLogs:
+			err = e.files(&cr, class.rule, e.logDir, rotatedLog, dryRun)
+		case ClassCache:
+			err = e.files(&cr, class.rule, e.cacheDir, nil, dryRun)
+		default:
+			err = e.tables(ctx, &cr, class.rule, classTables[class.name], dryRun)
+		}
+		if err != nil {
+			cr.Error = err.Error()
+		}
+		report.Classes = append(report.Classes, cr)
+	}
+	return report
+}
+
+// rotatedLog matches rotated log files such as access.log.20240101; the
+// active *.log files are left for log rotation
+func rotatedLog(name string) bool {
+	idx := strings.Index(name, ".log.")
+	return idx > 0 && idx+len(".log.") < len(name)
+}
+
+// files applies rule to the files under dir that match (all when nil)
+func (e *Engine) files(cr *ClassReport, rule config.RetentionRule, dir string, match func(string) bool, dryRun bool) error {
+	maxAge, err := ParseAge(rule.MaxAge)
+	if err != nil {
+		return err
+	}
+	maxSize, err := ParseSize(rule.MaxSize)
+	if err != nil {
+		return err
+	}
+	if rule.MaxRows > 0 {
+		return fmt.Errorf("max_rows applies to table classes; use max_size for %s", cr.Class)
+	}
+	if dir == "" {
+		return nil
+	}
+
+	var found []FileItem
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || (match != nil && !match(d.Name())) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		found = append(found, FileItem{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ModTime.Before(found[j].ModTime) })
+
+	var total int64
+	for _, f := range found {
+		total += f.Size
+	}
+	cutoff := e.now().Add(-maxAge)
+	for _, f := range found {
+		switch {
+		case maxAge > 0 && f.ModTime.Before(cutoff):
+			f.Reason = "age"
+		case maxSize > 0 && total > maxSize:
+			f.Reason = "size"
+		default:
+			continue
+		}
+		total -= f.Size
+		cr.Files = append(cr.Files, f)
+		cr.PurgeBytes += f.Size
+	}
+	cr.KeptBytes = total
+
+	if dryRun {
+		return nil
+	}
+	for _, f := range cr.Files {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// tables applies rule to each table: rows older than max_age, then the
+// oldest rows beyond max_rows
+func (e *Engine) tables(ctx context.Context, cr *ClassReport, rule config.RetentionRule, tables []table, dryRun bool) error {
+	maxAge, err := ParseAge(rule.MaxAge)
+	if err != nil {
+		return err
+	}
+	if rule.MaxSize != "" {
+		return fmt.Errorf("max_size applies to file classes; use max_rows for %s", cr.Class)
+	}
+	if e.db == nil {
+		return nil
+	}
+
+	for _, t := range tables {
+		name := e.prefix + t.name
+		var item TableItem
+		item.Table = name
+		if err := e.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+name).Scan(&item.Total); err != nil {
+			// A table this build does not create is not an error
+			if strings.Contains(err.Error(), "no such table") {
+				continue
+			}
+			return fmt.Errorf("count %s: %w", name, err)
+		}
+
+		var cutoff any = e.now().UTC().Add(-maxAge)
+		if t.date {
+			cutoff = e.now().UTC().Add(-maxAge).Format("2006-01-02")
+		}
+		if maxAge > 0 {
+			if err := e.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+name+` WHERE `+t.column+` < ?`, cutoff).Scan(&item.ByAge); err != nil {
+				return fmt.Errorf("count %s: %w", name, err)
+			}
+		}
+		if rule.MaxRows > 0 {
+			if excess := item.Total - item.ByAge - int64(rule.MaxRows); excess > 0 {
+				item.ByCount = excess
+			}
+		}
+		if item.ByAge == 0 && item.ByCount == 0 {
+			continue
+		}
+		cr.Tables = append(cr.Tables, item)
+		cr.PurgeRows += item.ByAge + item.ByCount
+		if dryRun {
+			continue
+		}
+
+		if item.ByAge > 0 {
+			if _, err := e.db.ExecContext(ctx, `DELETE FROM `+name+` WHERE `+t.column+` < ?`, cutoff); err != nil {
+				return fmt.Errorf("purge %s: %w", name, err)
+			}
+		}
+		if item.ByCount > 0 {
+			if _, err := e.db.ExecContext(ctx, `DELETE FROM `+name+` WHERE rowid IN (
+				SELECT rowid FROM `+name+` ORDER BY `+t.column+` ASC LIMIT ?
+			)`, item.ByCount); err != nil {
+				return fmt.Errorf("purge %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseAge parses a max_age such as "30d", "12h" or "2w"; empty is no limit
+func ParseAge(s string) (time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	seconds, err := config.ParseDuration(s)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid max_age %q", s)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// ParseSize parses a max_size such as "500MB", "1GB" or "4096"; units are
+// powers of 1024 and empty is no limit
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid max_size %q", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,126 @@
+package retention
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func writeFile(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mod := time.Now().Add(-age)
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func classReport(t *testing.T, report *Report, class string) ClassReport {
+	t.Helper()
+	for _, cr := range report.Classes {
+		if cr.Class == class {
+			return cr
+		}
+	}
+	t.Fatalf("no %s class in report", class)
+	return ClassReport{}
+}
+
+func TestLogsPlanAndEnforce(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "access.log"), 10, 90*24*time.Hour)
+	writeFile(t, filepath.Join(dir, "access.log.20240101"), 10, 60*24*time.Hour)
+	writeFile(t, filepath.Join(dir, "access.log.20240601"), 600, 2*24*time.Hour)
+	writeFile(t, filepath.Join(dir, "server.log.20240602"), 600, 24*time.Hour)
+
+	rules := config.RetentionConfig{Logs: config.RetentionRule{MaxAge: "30d", MaxSize: "1KB"}}
+	engine := NewEngine(rules, dir, "", nil, "")
+
+	logs := classReport(t, engine.Plan(context.Background()), ClassLogs)
+	if logs.Error != "" {
+		t.Fatal(logs.Error)
+	}
+	if len(logs.Files) != 2 || logs.Files[0].Reason != "age" || logs.Files[1].Reason != "size" {
+		t.Fatalf("files = %+v, want the old rotated log by age and the next oldest by size", logs.Files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "access.log.20240101")); err != nil {
+		t.Fatal("Plan() deleted a file")
+	}
+
+	engine.Enforce(context.Background())
+	for name, want := range map[string]bool{
+		"access.log":          true,
+		"access.log.20240101": false,
+		"access.log.20240601": false,
+		"server.log.20240602": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+		}
+	}
+}
+
+func TestTablesPlanAndEnforce(t *testing.T) {
+	db := dbtest.Server(t)
+	now := time.Now().UTC()
+	for _, days := range []int{200, 100, 10, 5, 1} {
+		if _, err := db.Exec(`INSERT INTO search_stats (date, hour) VALUES (?, 0)`, now.AddDate(0, 0, -days).Format("2006-01-02")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := config.RetentionConfig{Metrics: config.RetentionRule{MaxAge: "90d", MaxRows: 2}}
+	engine := NewEngine(rules, "", "", db, "")
+	metrics := classReport(t, engine.Plan(context.Background()), ClassMetrics)
+	if metrics.Error != "" {
+		t.Fatal(metrics.Error)
+	}
+	// The other metrics tables are empty and are skipped
+	if len(metrics.Tables) != 1 || metrics.Tables[0].ByAge != 2 || metrics.Tables[0].ByCount != 1 || metrics.PurgeRows != 3 {
+		t.Fatalf("tables = %+v, want 2 rows by age and 1 by count", metrics.Tables)
+	}
+
+	engine.Enforce(context.Background())
+	var oldest string
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*), MIN(date) FROM search_stats`).Scan(&count, &oldest); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || oldest != now.AddDate(0, 0, -5).Format("2006-01-02") {
+		t.Errorf("kept %d rows from %s, want the 2 newest", count, oldest)
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	rules := config.RetentionConfig{
+		Logs:    config.RetentionRule{MaxAge: "soon"},
+		History: config.RetentionRule{MaxSize: "1GB"},
+		Cache:   config.RetentionRule{MaxRows: 10},
+	}
+	report := NewEngine(rules, t.TempDir(), t.TempDir(), nil, "").Plan(context.Background())
+	for _, class := range []string{ClassLogs, ClassHistory, ClassCache} {
+		if classReport(t, report, class).Error == "" {
+			t.Errorf("%s: expected a rule error", class)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{"": 0, "512": 512, "1KB": 1024, "1.5 mb": 1572864, "2GB": 2 << 30} {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseSize("lots"); err == nil {
+		t.Error("ParseSize(lots) should fail")
+	}
+}
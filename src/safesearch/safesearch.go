@@ -0,0 +1,172 @@
+// Package safesearch holds the three safe search levels and the filter
+// used for engines that have no safe search parameter of their own. Engines
+// that do are sent their native parameter and their results are trusted;
+// the results of the others are checked against a domain blocklist at
+// moderate and, at strict, against a keyword list as well.
+package safesearch
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Levels
+const (
+	Off      = 0
+	Moderate = 1
+	Strict   = 2
+)
+
+// Parse reads a level written as off, moderate or strict, their first
+// letters, or 0, 1 or 2
+func Parse(value string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "0", "off", "o", "none":
+		return Off, true
+	case "1", "moderate", "m":
+		return Moderate, true
+	case "2", "strict", "s":
+		return Strict, true
+	}
+	return 0, false
+}
+
+// Name returns off, moderate or strict; an unknown level is moderate
+func Name(level int) string {
+	switch level {
+	case Off:
+		return "off"
+	case Strict:
+		return "strict"
+	default:
+		return "moderate"
+	}
+}
+
+// Valid reports whether level is Off, Moderate or Strict
+func Valid(level int) bool {
+	return level >= Off && level <= Strict
+}
+
+// FromValues returns the level a request asks for with safesearch= or the
+// older safe_search=, and false when it asks for none or an unknown one
+func FromValues(values url.Values) (int, bool) {
+	for _, name := range []string{"safesearch", "safe_search"} {
+		if value := values.Get(name); value != "" {
+			return Parse(value)
+		}
+	}
+	return 0, false
+}
+
+// defaultDomains are adult sites and top-level domains blocked at moderate
+// and strict when the built-in list is on
+var defaultDomains = []string{
+	"xxx", "porn", "adult", "sex",
+	"pornhub.com", "xvideos.com", "xnxx.com", "xhamster.com", "redtube.com",
+	"youporn.com", "tube8.com", "spankbang.com", "eporner.com", "motherless.com",
+	"brazzers.com", "onlyfans.com", "fansly.com", "chaturbate.com",
+	"stripchat.com", "livejasmin.com", "cam4.com", "bongacams.com",
+	"nhentai.net", "e-hentai.org", "rule34.xxx", "hentaihaven.xxx",
+}
+
+// defaultKeywords are the words and phrases blocked at strict when the
+// built-in list is on
+var defaultKeywords = []string{
+	"porn", "porno", "pornography", "xxx", "nsfw", "hentai", "nude", "nudes",
+	"onlyfans", "camgirl", "camgirls", "milf", "erotic", "erotica",
+	"sex video", "sex tape", "adult video", "hardcore sex",
+}
+
+// Filter decides which results of an engine without native safe search
+// are dropped. It is read-only once built and safe for concurrent use.
+type Filter struct {
+	domains  map[string]bool
+	keywords []string
+}
+
+// NewFilter builds a filter from the operator's domains and keywords, plus
+// the built-in lists when builtIn is set
+func NewFilter(domains, keywords []string, builtIn bool) *Filter {
+	f := &Filter{domains: make(map[string]bool)}
+	if builtIn {
+		domains = append(append([]string(nil), defaultDomains...), domains...)
+		keywords = append(append([]string(nil), defaultKeywords...), keywords...)
+	}
+	for _, d := range domains {
+		if d = normalizeHost(d); d != "" {
+			f.domains[d] = true
+		}
+	}
+	seen := make(map[string]bool)
+	for _, k := range keywords {
+		if k = normalizeText(k); strings.TrimSpace(k) != "" && !seen[k] {
+			seen[k] = true
+			f.keywords = append(f.keywords, k)
+		}
+	}
+	return f
+}
+
+// Blocked reports whether result must be dropped at level: its domain, or
+// a parent of it, is listed from moderate up, and at strict its title,
+// content or URL holds a listed keyword
+func (f *Filter) Blocked(result model.Result, level int) bool {
+	if f == nil || level <= Off {
+		return false
+	}
+	if f.domainBlocked(result.URL) {
+		return true
+	}
+	if level < Strict || len(f.keywords) == 0 {
+		return false
+	}
+	text := normalizeText(result.Title + " " + result.Content + " " + result.URL)
+	for _, k := range f.keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) domainBlocked(rawURL string) bool {
+	if len(f.domains) == 0 {
+		return false
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	host := normalizeHost(u.Hostname())
+	for host != "" {
+		if f.domains[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return false
+}
+
+// normalizeHost lower-cases a domain and drops a leading www. or dot
+func normalizeHost(host string) string {
+	host = strings.Trim(strings.ToLower(strings.TrimSpace(host)), ".")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// normalizeText lower-cases text and reduces it to its words separated by
+// single spaces, with a space at either end, so keywords only match whole
+// words
+func normalizeText(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
@@ -0,0 +1,79 @@
+package safesearch
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in    string
+		level int
+		ok    bool
+	}{
+		{"off", Off, true},
+		{"0", Off, true},
+		{" Moderate ", Moderate, true},
+		{"m", Moderate, true},
+		{"STRICT", Strict, true},
+		{"2", Strict, true},
+		{"3", 0, false},
+		{"high", 0, false},
+	}
+	for _, tt := range tests {
+		if level, ok := Parse(tt.in); level != tt.level || ok != tt.ok {
+			t.Errorf("Parse(%q) = %d, %v, want %d, %v", tt.in, level, ok, tt.level, tt.ok)
+		}
+	}
+	if Name(Off) != "off" || Name(Strict) != "strict" || Name(7) != "moderate" {
+		t.Error("Name() mismatch")
+	}
+}
+
+func TestFromValues(t *testing.T) {
+	values, _ := url.ParseQuery("safesearch=strict&safe_search=0")
+	if level, ok := FromValues(values); !ok || level != Strict {
+		t.Errorf("FromValues() = %d, %v, want safesearch= to win", level, ok)
+	}
+	values, _ = url.ParseQuery("safe_search=0")
+	if level, ok := FromValues(values); !ok || level != Off {
+		t.Errorf("FromValues(safe_search=0) = %d, %v", level, ok)
+	}
+	if _, ok := FromValues(url.Values{}); ok {
+		t.Error("FromValues() of no parameter reported a level")
+	}
+}
+
+func TestFilterBlocked(t *testing.T) {
+	f := NewFilter([]string{"Example-Adult.org"}, []string{"Blocked Phrase"}, true)
+	tests := []struct {
+		name   string
+		result model.Result
+		level  int
+		want   bool
+	}{
+		{"listed domain", model.Result{URL: "https://www.pornhub.com/view"}, Moderate, true},
+		{"subdomain of a listed domain", model.Result{URL: "https://de.xhamster.com/"}, Moderate, true},
+		{"adult top-level domain", model.Result{URL: "https://anything.xxx/"}, Moderate, true},
+		{"operator domain", model.Result{URL: "http://example-adult.org/x"}, Moderate, true},
+		{"off lets everything through", model.Result{URL: "https://pornhub.com/"}, Off, false},
+		{"keyword at moderate", model.Result{URL: "https://example.com/", Title: "Free porn"}, Moderate, false},
+		{"keyword at strict", model.Result{URL: "https://example.com/", Title: "Free porn"}, Strict, true},
+		{"operator phrase", model.Result{URL: "https://example.com/", Content: "a blocked, phrase here"}, Strict, true},
+		{"word inside another", model.Result{URL: "https://example.com/", Title: "Nudest colonies"}, Strict, false},
+		{"unrelated word", model.Result{URL: "https://example.com/", Title: "Essex county"}, Strict, false},
+		{"similar domain", model.Result{URL: "https://notpornhub.com/"}, Strict, false},
+	}
+	for _, tt := range tests {
+		if got := f.Blocked(tt.result, tt.level); got != tt.want {
+			t.Errorf("%s: Blocked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	bare := NewFilter(nil, nil, false)
+	if bare.Blocked(model.Result{URL: "https://pornhub.com/", Title: "porn"}, Strict) {
+		t.Error("filter without the built-in lists blocked a result")
+	}
+}
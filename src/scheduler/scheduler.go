@@ -38,6 +38,24 @@ const (
 	// TaskPublicIPRefresh refreshes the cached server public IP per
 	// AI.md PART 8 step 16 (startup + every 12h, hardcoded — not configurable).
 	TaskPublicIPRefresh TaskID = "public_ip_refresh"
+	// TaskMarketRefresh keeps stock/crypto quotes warm for instant answers
+	// and the ticker widget; only registered when market data is enabled.
+	TaskMarketRefresh TaskID = "market_refresh"
+	// TaskCacheWarm precomputes the operator's popular queries off-peak so
+	// they are served from the result cache.
+	TaskCacheWarm TaskID = "cache_warm"
+	// TaskRetention applies server.retention: it reports what is over a
+	// limit and deletes it only when enforcement is on.
+	TaskRetention TaskID = "retention"
+	// TaskThreatFeedUpdate downloads the malware and phishing feeds used to
+	// screen results; only registered when search.screening is enabled.
+	TaskThreatFeedUpdate TaskID = "threat_feed_update"
+	// TaskKeyRotation rotates the server secrets due under
+	// server.security.keys and retires old JWT signing keys.
+	TaskKeyRotation TaskID = "key_rotation"
+	// TaskBackupVerify checks every stored backup against its checksum
+	// manifest without restoring it.
+	TaskBackupVerify TaskID = "backup_verify"
 )
 
 // TaskStatus represents task execution status
@@ -365,6 +383,20 @@ func (s *Scheduler) RegisterBuiltinTasks(handlers *TaskHandlers) {
 		task.Enabled = false
 	}
 
+	// Backup Verify - Weekly on Sunday at 06:00, skippable
+	if handlers.BackupVerify != nil {
+		s.Register(&Task{
+			ID:          TaskBackupVerify,
+			Name:        "Backup Verification",
+			Description: "Check every stored backup against its checksum manifest without restoring it",
+			Schedule:    "0 6 * * 0",
+			TaskType:    TaskTypeGlobal,
+			Run:         handlers.BackupVerify,
+			Skippable:   true,
+			Enabled:     true,
+		})
+	}
+
 	// Health Check Self - Every 5 minutes, NOT skippable
 	if handlers.HealthcheckSelf != nil {
 		s.Register(&Task{
@@ -451,6 +483,77 @@ func (s *Scheduler) RegisterBuiltinTasks(handlers *TaskHandlers) {
 		})
 	}
 
+	// Market Refresh - Every 5 minutes, skippable
+	if handlers.MarketRefresh != nil {
+		s.Register(&Task{
+			ID:          TaskMarketRefresh,
+			Name:        "Market Data Refresh",
+			Description: "Refresh cached stock and cryptocurrency quotes",
+			Schedule:    "@every 5m",
+			TaskType:    TaskTypeLocal,
+			Run:         handlers.MarketRefresh,
+			Skippable:   true,
+			RunOnStart:  true,
+			Enabled:     true,
+		})
+	}
+
+	// Cache Warm - Daily at 03:30, before the morning peak, skippable
+	if handlers.CacheWarm != nil {
+		s.Register(&Task{
+			ID:          TaskCacheWarm,
+			Name:        "Cache Warm",
+			Description: "Precompute popular queries from search.warm_queries",
+			Schedule:    "30 3 * * *",
+			TaskType:    TaskTypeLocal,
+			Run:         handlers.CacheWarm,
+			Skippable:   true,
+			Enabled:     true,
+		})
+	}
+
+	// Retention - Daily at 04:30, after the nightly backup, skippable
+	if handlers.Retention != nil {
+		s.Register(&Task{
+			ID:          TaskRetention,
+			Name:        "Data Retention",
+			Description: "Report, and when enforced delete, data over the server.retention limits",
+			Schedule:    "30 4 * * *",
+			TaskType:    TaskTypeLocal,
+			Run:         handlers.Retention,
+			Skippable:   true,
+			Enabled:     true,
+		})
+	}
+
+	// Threat Feed Update - Every 6 hours, skippable
+	if handlers.ThreatFeedUpdate != nil {
+		s.Register(&Task{
+			ID:          TaskThreatFeedUpdate,
+			Name:        "Threat Feed Update",
+			Description: "Download the malware and phishing feeds used by search.screening",
+			Schedule:    "@every 6h",
+			TaskType:    TaskTypeLocal,
+			Run:         handlers.ThreatFeedUpdate,
+			Skippable:   true,
+			RunOnStart:  true,
+			Enabled:     true,
+		})
+	}
+
+	// Key Rotation - Daily at 05:00, skippable
+	if handlers.KeyRotation != nil {
+		s.Register(&Task{
+			ID:          TaskKeyRotation,
+			Name:        "Key Rotation",
+			Description: "Rotate the server secrets due under server.security.keys and re-encrypt what they protect",
+			Schedule:    "0 5 * * *",
+			TaskType:    TaskTypeLocal,
+			Run:         handlers.KeyRotation,
+			Skippable:   true,
+			Enabled:     true,
+		})
+	}
 }
 
 // TaskHandlers holds handler functions for built-in tasks
@@ -463,6 +566,8 @@ type TaskHandlers struct {
 	LogRotation     func(ctx context.Context) error
 	BackupDaily     func(ctx context.Context) error
 	BackupHourly    func(ctx context.Context) error
+	// BackupVerify verifies the stored backups
+	BackupVerify    func(ctx context.Context) error
 	HealthcheckSelf func(ctx context.Context) error
 	TorHealth       func(ctx context.Context) error
 	AlertsImmediate func(ctx context.Context) error
@@ -471,6 +576,16 @@ type TaskHandlers struct {
 	// PublicIPRefresh refreshes the cached public IP per AI.md PART 8
 	// step 16. Schedule and cadence are hardcoded (startup + every 12h).
 	PublicIPRefresh func(ctx context.Context) error
+	// MarketRefresh refreshes cached market quotes (nil when disabled)
+	MarketRefresh func(ctx context.Context) error
+	// CacheWarm precomputes the warm queries file
+	CacheWarm func(ctx context.Context) error
+	// Retention applies the server.retention limits
+	Retention func(ctx context.Context) error
+	// ThreatFeedUpdate downloads the screening feeds (nil when disabled)
+	ThreatFeedUpdate func(ctx context.Context) error
+	// KeyRotation rotates the secrets due for rotation
+	KeyRotation func(ctx context.Context) error
 }
 
 // Start starts the scheduler
@@ -758,6 +758,7 @@ func TestRegisterBuiltinTasksAllHandlers(t *testing.T) {
 		LogRotation:     func(ctx context.Context) error { return nil },
 		BackupDaily:     func(ctx context.Context) error { return nil },
 		BackupHourly:    func(ctx context.Context) error { return nil },
+		BackupVerify:    func(ctx context.Context) error { return nil },
 		HealthcheckSelf: func(ctx context.Context) error { return nil },
 		TorHealth:       func(ctx context.Context) error { return nil },
 	}
@@ -765,8 +766,16 @@ func TestRegisterBuiltinTasksAllHandlers(t *testing.T) {
 	s.RegisterBuiltinTasks(handlers)
 
 	tasks := s.GetTasks()
-	if len(tasks) != 10 {
-		t.Errorf("Expected 10 builtin tasks, got %d", len(tasks))
+	if len(tasks) != 11 {
+		t.Errorf("Expected 11 builtin tasks, got %d", len(tasks))
+	}
+
+	verifyTask, err := s.GetTask(TaskBackupVerify)
+	if err != nil {
+		t.Fatalf("BackupVerify task not found: %v", err)
+	}
+	if !verifyTask.Enabled || !verifyTask.Skippable {
+		t.Errorf("BackupVerify Enabled = %v, Skippable = %v, want both true", verifyTask.Enabled, verifyTask.Skippable)
 	}
 
 	// Check BackupHourly is disabled by default
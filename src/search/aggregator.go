@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"math/rand"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -20,10 +22,56 @@ type Aggregator struct {
 	engines        []Engine
 	timeout        time.Duration
 	cache          *ResultCache
+	index          *localIndex
 	cacheEnabled   bool
 	cacheTTL       time.Duration
 	maxConcurrent  int
 	rotationOffset atomic.Uint64
+
+	// Explicit per-category engine lists; see SetCategoryEngines
+	categoryMu      sync.RWMutex
+	categoryEngines map[model.Category][]CategoryEngine
+	// Categories turned off on this instance; see SetDisabledCategories
+	disabledCategories map[model.Category]bool
+
+	// Per-engine request templates; see SetRequestTemplates
+	requestMu        sync.RWMutex
+	requestTemplates map[string]RequestTemplate
+
+	// Response limits; see SetEngineLimits
+	limitsMu      sync.RWMutex
+	defaultLimits EngineLimits
+	engineLimits  map[string]EngineLimits
+
+	// Block page handling; see SetBlockHandler
+	blockHandler atomic.Pointer[BlockHandler]
+	// Results hidden from every search; see SetResultFilter
+	resultFilter atomic.Pointer[ResultFilter]
+	// Warnings attached to results; see SetResultMarker
+	resultMarker atomic.Pointer[ResultMarker]
+	// Adult image check for strict safe search; see SetImageClassifier
+	imageClassifier atomic.Pointer[ImageClassifier]
+	// Safe search for engines without a parameter; see SetSafeSearchFilter
+	safeSearchFilter atomic.Pointer[SafeSearchFilter]
+	uaRotation      atomic.Uint64
+	// Score boost for results local to the searcher, as float64 bits; see
+	// SetGeoBoost
+	geoBoost atomic.Uint64
+	// Daily request budgets; see SetEngineQuotas
+	quota quotaBudget
+	// Drift at which an engine's parser is suspected, as float64 bits; see
+	// SetSchemaDrift
+	driftThreshold atomic.Uint64
+	// Request spacing and caps per upstream host; see SetPoliteness
+	hosts hostGate
+	// User agent pool and how each fares per engine; see SetUserAgents
+	userAgents userAgentPool
+	// Ranking stages run on merged results; see SetRanker
+	ranker atomic.Pointer[Ranker]
+	// Faults injected into engine calls in development; see SetChaos
+	chaos atomic.Pointer[map[string]ChaosFault]
+	// Results gathered for page tokens; see SearchPages
+	pages pageStore
 }
 
 // AggregatorConfig holds aggregator configuration
@@ -64,6 +112,7 @@ func NewAggregator(engines []Engine, config AggregatorConfig) *Aggregator {
 			backend = cache.NewMemoryCache(1000, config.CacheTTL)
 		}
 		a.cache = NewResultCache(backend, config.CacheTTL)
+		a.index = newLocalIndex(maxLocalIndexResults)
 	}
 
 	return a
@@ -80,10 +129,28 @@ func NewAggregatorSimple(engines []Engine, timeout time.Duration) *Aggregator {
 
 // Search performs concurrent searches across all engines
 func (a *Aggregator) Search(ctx context.Context, query *model.Query) (*model.SearchResults, error) {
+	results, err := a.search(ctx, query, true, nil)
+	return a.localizeResults(query, a.classifyImages(ctx, query, a.applyRanking(query, a.screenResults(results)))), err
+}
+
+// Refresh searches the engines without reading the cache and stores the
+// fresh results, so the next Search for query is a cache hit
+func (a *Aggregator) Refresh(ctx context.Context, query *model.Query) (*model.SearchResults, error) {
+	results, err := a.search(ctx, query, false, nil)
+	return a.screenResults(results), err
+}
+
+// search runs a search; useCache false skips the cache lookup only.
+// progress, when not nil, is called as each engine answers.
+func (a *Aggregator) search(ctx context.Context, query *model.Query, useCache bool, progress func(EngineProgress)) (*model.SearchResults, error) {
 	if err := query.ValidateSearchQuery(); err != nil {
 		return nil, err
 	}
 
+	if !a.CategoryEnabled(query.Category) {
+		return nil, model.ErrCategoryDisabled
+	}
+
 	if len(a.engines) == 0 {
 		return nil, model.ErrNoEngines
 	}
@@ -98,8 +165,10 @@ func (a *Aggregator) Search(ctx context.Context, query *model.Query) (*model.Sea
 
 	// Check cache
 	cacheKey := a.generateCacheKey(query)
-	if a.cacheEnabled && a.cache != nil {
-		if cached := a.cache.Get(cacheKey); cached != nil {
+	if useCache && a.cacheEnabled && a.cache != nil {
+		if cached := a.cache.GetFor(cacheKey, query.Category); cached != nil {
+			// The key is of the normalized query; report the one asked
+			cached.Query = query.Text
 			// Update search time to indicate cache hit
 			// Nearly instant
 			cached.SearchTime = 0.001
@@ -122,13 +191,14 @@ func (a *Aggregator) Search(ctx context.Context, query *model.Query) (*model.Sea
 		results []model.Result
 		err     error
 		latency time.Duration
+		timing  model.EngineTiming
 	}
 
 	// Filter engines
 	activeEngines := a.filterEngines(query)
 	if len(activeEngines) == 0 {
-		if stale := a.getStaleFallback(cacheKey); stale != nil {
-			return stale, nil
+		if degraded := a.degradedFallback(query, cacheKey); degraded != nil {
+			return degraded, nil
 		}
 		return nil, model.ErrNoEngines
 	}
@@ -142,13 +212,27 @@ func (a *Aggregator) Search(ctx context.Context, query *model.Query) (*model.Sea
 		go func(eng Engine) {
 			defer wg.Done()
 
+			trace := &engineTrace{}
 			start := time.Now()
-			results, err := eng.Search(searchCtx, query)
+			engineCtx, ua := a.withUserAgent(a.withRequestOverrides(trace.withTrace(searchCtx), eng, query), eng)
+			results, err := a.runEngine(engineCtx, eng, query)
+			a.userAgents.record(eng.Name(), ua, len(results), err)
+			var blocked *BlockedError
+			if errors.As(err, &blocked) && searchCtx.Err() == nil {
+				// Ask once more looking like another browser before
+				// counting the engine as blocked
+				results, err = a.runEngine(a.withAlternateUserAgent(engineCtx), eng, query)
+				if err != nil {
+					a.reportBlocked(eng, blocked)
+				}
+			}
+			finished := time.Now()
 			resultsChan <- engineResult{
 				engine:  eng,
 				results: results,
 				err:     err,
-				latency: time.Since(start),
+				latency: finished.Sub(start),
+				timing:  trace.timing(eng.Name(), startTime, start, finished, len(results), err),
 			}
 		}(engine)
 	}
@@ -166,18 +250,38 @@ func (a *Aggregator) Search(ctx context.Context, query *model.Query) (*model.Sea
 	searchResults.SortedBy = query.SortBy
 
 	usedEngines := make([]string, 0)
+	timings := make([]model.EngineTiming, 0, len(activeEngines))
 	successCount := 0
 	errorCount := 0
 
 	for result := range resultsChan {
+		timings = append(timings, result.timing)
 		if result.err != nil {
 			errorCount++
 			a.recordEngineFailure(result.engine, result.err)
+			if progress != nil {
+				progress(EngineProgress{Engine: result.engine.DisplayName(), Timing: result.timing, Completed: len(timings), Total: len(activeEngines)})
+			}
 			continue
 		}
 
 		successCount++
 		a.recordEngineSuccess(result.engine, result.latency)
+		result.results = a.filterSafeSearch(result.engine, query, result.results)
+		if weight := a.categoryEngineWeight(query.Category, result.engine.Name()); weight != 1 {
+			for i := range result.results {
+				result.results[i].Score *= weight
+			}
+		}
+		if progress != nil {
+			progress(EngineProgress{
+				Engine:    result.engine.DisplayName(),
+				Results:   a.engineResults(ctx, query, result.results),
+				Timing:    result.timing,
+				Completed: len(timings),
+				Total:     len(activeEngines),
+			})
+		}
 		if len(result.results) > 0 {
 			searchResults.AddResults(result.results)
 			// Use the human-readable display name (e.g. "Hacker News" not "hackernews").
@@ -206,14 +310,24 @@ func (a *Aggregator) Search(ctx context.Context, query *model.Query) (*model.Sea
 
 	// Cache results
 	if a.cacheEnabled && a.cache != nil && len(searchResults.Results) > 0 {
-		a.cache.Set(cacheKey, searchResults)
+		a.cache.SetFor(cacheKey, query.Category, searchResults)
+		a.index.add(query.Category, query.SafeSearch, searchResults.Results)
 	}
 
+	// Timings describe this search only, so they are added after caching
+	countContributions(timings, searchResults.Results)
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Engine < timings[j].Engine })
+	searchResults.EngineTimings = timings
+
 	if len(searchResults.Results) == 0 {
 		if successCount == 0 && errorCount > 0 {
-			if stale := a.getStaleFallback(cacheKey); stale != nil {
-				return stale, nil
+			if degraded := a.degradedFallback(query, cacheKey); degraded != nil {
+				return degraded, nil
 			}
+			// Nothing to fall back on; still report the outage rather
+			// than an ordinary empty result
+			searchResults.Degraded = true
+			return searchResults, nil
 		}
 		return searchResults, model.ErrNoResults
 	}
@@ -266,11 +380,21 @@ func (a *Aggregator) applyOperators(query *model.Query, ops *SearchOperators) {
 
 // filterEngines returns engines that should be used for this query
 func (a *Aggregator) filterEngines(query *model.Query) []Engine {
-	eligible := make([]Engine, 0, len(a.engines))
+	// An explicit category list picks and orders the candidates, unless the
+	// query names its own engines
+	candidates := a.engines
+	listed := false
+	if len(query.Engines) == 0 {
+		if list, ok := a.CategoryEngines(query.Category); ok {
+			candidates = a.listedEngines(list)
+			listed = true
+		}
+	}
 
-	for _, engine := range a.engines {
-		// Check category support
-		if !engine.SupportsCategory(query.Category) {
+	eligible := make([]Engine, 0, len(candidates))
+
+	for _, engine := range candidates {
+		if !a.engineEligible(engine, query) {
 			continue
 		}
 
@@ -288,34 +412,72 @@ func (a *Aggregator) filterEngines(query *model.Query) []Engine {
 			}
 		}
 
-		// Check if engine is excluded
-		if len(query.ExcludeEngines) > 0 {
-			excluded := false
-			for _, e := range query.ExcludeEngines {
-				if strings.EqualFold(e, engine.Name()) {
-					excluded = true
-					break
-				}
-			}
-			if excluded {
-				continue
-			}
-		}
-
 		eligible = append(eligible, engine)
 	}
 
+	// Engines that spent their daily quota give way to their fallback
 	if len(query.Engines) > 0 {
-		return a.orderExplicitEngines(query.Engines, eligible)
+		return a.budgetEngines(a.orderExplicitEngines(query.Engines, eligible), query)
+	}
+	eligible = a.budgetEngines(preferredEngines(eligible, query.EnabledEngines), query)
+	if listed {
+		return a.selectListedEngines(eligible)
 	}
 
 	return a.selectEnginesForSearch(eligible)
 }
 
+// preferredEngines keeps the engines the searcher enabled, or all of
+// eligible when none of them can answer, so a preference for web engines
+// does not leave an images search empty
+func preferredEngines(eligible []Engine, enabled []string) []Engine {
+	if len(enabled) == 0 {
+		return eligible
+	}
+	preferred := make([]Engine, 0, len(eligible))
+	for _, engine := range eligible {
+		for _, name := range enabled {
+			if strings.EqualFold(name, engine.Name()) {
+				preferred = append(preferred, engine)
+				break
+			}
+		}
+	}
+	if len(preferred) == 0 {
+		return eligible
+	}
+	return preferred
+}
+
+// engineEligible reports whether engine can answer query: it supports the
+// category and the query's filters, and the query does not exclude it
+func (a *Aggregator) engineEligible(engine Engine, query *model.Query) bool {
+	if !engine.SupportsCategory(query.Category) {
+		return false
+	}
+
+	// Engines that cannot filter images by color or license would mix
+	// unfiltered images in
+	if query.HasImageFilters() && !engine.GetConfig().SupportsImageFilters {
+		return false
+	}
+	if query.HasVideoFilters() && !engine.GetConfig().SupportsVideoFilters {
+		return false
+	}
+
+	for _, e := range query.ExcludeEngines {
+		if strings.EqualFold(e, engine.Name()) {
+			return false
+		}
+	}
+	return true
+}
+
 // RefreshEngineHealth probes engines that are unhealthy, degraded, or not yet checked.
 func (a *Aggregator) RefreshEngineHealth(ctx context.Context) error {
 	for _, engine := range a.engines {
-		if !a.shouldProbeEngine(engine) {
+		// A probe would spend quota the searches need
+		if !a.shouldProbeEngine(engine) || !a.quota.allows(engine.Name(), time.Now()) {
 			continue
 		}
 
@@ -336,7 +498,7 @@ func (a *Aggregator) RefreshEngineHealth(ctx context.Context) error {
 		}
 		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
 		start := time.Now()
-		_, err := engine.Search(probeCtx, probeQuery)
+		_, err := a.runEngine(a.withRequestOverrides(probeCtx, engine, probeQuery), engine, probeQuery)
 		cancel()
 		if err != nil {
 			a.recordEngineFailure(engine, err)
@@ -348,6 +510,43 @@ func (a *Aggregator) RefreshEngineHealth(ctx context.Context) error {
 	return nil
 }
 
+// degradedFallback answers a search no engine could serve: the stale copy of
+// the same search if the cache has one, else matching results from the local
+// index. Results are marked degraded; nil means neither had anything.
+func (a *Aggregator) degradedFallback(query *model.Query, cacheKey string) *model.SearchResults {
+	if stale := a.getStaleFallback(cacheKey); stale != nil {
+		stale.Degraded = true
+		return stale
+	}
+	if a.index == nil {
+		return nil
+	}
+
+	text := query.CleanedText
+	if text == "" {
+		text = query.Text
+	}
+	matches, indexedAt := a.index.search(query.Category, query.SafeSearch, text)
+	matches = a.applyFilters(matches, query)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	results := model.NewSearchResults(query.Text, query.Category)
+	results.Page = query.Page
+	results.PerPage = query.PerPage
+	results.SortedBy = query.SortBy
+	results.AddResults(matches)
+	results.CalculateTotalPages()
+	results.SearchTime = 0.001
+	results.FromCache = true
+	results.Stale = true
+	results.Degraded = true
+	results.CacheAgeSec = int64(time.Since(indexedAt).Seconds())
+	results.CachedAt = &indexedAt
+	return results
+}
+
 func (a *Aggregator) getStaleFallback(cacheKey string) *model.SearchResults {
 	if !a.cacheEnabled || a.cache == nil {
 		return nil
@@ -361,6 +560,8 @@ func (a *Aggregator) getStaleFallback(cacheKey string) *model.SearchResults {
 	stale.FromCache = true
 	stale.Stale = true
 	stale.CacheAgeSec = int64(age.Seconds())
+	cachedAt := time.Now().Add(-age)
+	stale.CachedAt = &cachedAt
 	return stale
 }
 
@@ -395,8 +596,10 @@ func (a *Aggregator) selectEnginesForSearch(engines []Engine) []Engine {
 	if len(selected) > limit {
 		selected = selected[:limit]
 	}
-	if len(selected) < limit && len(recovering) > 0 {
-		selected = append(selected, recovering[:min(limit-len(selected), len(recovering))]...)
+	// Engines with an open circuit are asked only when no other engine is
+	// left, so a dead upstream does not hold up every search
+	if len(selected) == 0 && len(recovering) > 0 {
+		selected = append(selected, recovering[:min(limit, len(recovering))]...)
 	}
 
 	return selected
@@ -442,7 +645,13 @@ func (a *Aggregator) recordEngineSuccess(engine Engine, latency time.Duration) {
 	}
 }
 
+// recordEngineFailure counts a failed engine call against the engine's
+// health. Calls refused here, over the engine's quota or its host's
+// politeness, are not the engine's fault and do not count.
 func (a *Aggregator) recordEngineFailure(engine Engine, err error) {
+	if errors.Is(err, ErrQuotaExhausted) || errors.Is(err, ErrHostBusy) {
+		return
+	}
 	if tracker, ok := engine.(interface{ RecordFailure(error) }); ok {
 		tracker.RecordFailure(err)
 	}
@@ -492,6 +701,8 @@ func (a *Aggregator) healthProbeQuery(engine Engine) string {
 		return "astronomy"
 	case model.CategorySocial:
 		return "opensource"
+	case model.CategoryPackages:
+		return "express"
 	default:
 		return "privacy search"
 	}
@@ -521,6 +732,7 @@ func (a *Aggregator) applyFilters(results []model.Result, query *model.Query) []
 	}
 
 	filtered := make([]model.Result, 0, len(results))
+	now := time.Now()
 
 	for _, r := range results {
 		// Site exclusion filter
@@ -598,27 +810,79 @@ func (a *Aggregator) applyFilters(results []model.Result, query *model.Query) []
 			}
 		}
 
+		// Time range filter, for engines that report dates but cannot
+		// narrow by them
+		if !query.MatchesTimeRange(r.PublishedAt, now) {
+			continue
+		}
+
+		// Video length filter, for engines that report durations but
+		// filter loosely
+		if query.HasVideoFilters() && !query.MatchesVideoLength(r.Duration) {
+			continue
+		}
+
 		filtered = append(filtered, r)
 	}
 
 	return filtered
 }
 
+// LocalIndexSize returns how many results the local index holds, or 0 if
+// caching is disabled
+func (a *Aggregator) LocalIndexSize() int {
+	if a.index == nil {
+		return 0
+	}
+	a.index.mu.Lock()
+	defer a.index.mu.Unlock()
+	return a.index.max
+}
+
+// SetLocalIndexSize changes how many results the local index holds,
+// evicting the oldest; the memory watchdog shrinks it under pressure
+func (a *Aggregator) SetLocalIndexSize(n int) {
+	if a.index != nil && n > 0 {
+		a.index.resize(n)
+	}
+}
+
 // Cache returns the result cache, or nil if caching is disabled.
 // Used by the debug /cache endpoint per AI.md PART 6.
 func (a *Aggregator) Cache() *ResultCache {
 	return a.cache
 }
 
-// generateCacheKey creates a unique cache key for the query
+// generateCacheKey creates a unique cache key for the query. The text is
+// normalized, so searches differing only in case or spacing share results.
 func (a *Aggregator) generateCacheKey(query *model.Query) string {
 	// Include relevant query parameters
-	key := query.Text + "|" +
+	key := strings.Join(strings.Fields(strings.ToLower(query.Text)), " ") + "|" +
 		string(query.Category) + "|" +
 		query.Language + "|" +
 		query.Region + "|" +
 		string(query.SortBy) + "|" +
-		query.TimeRange
+		query.TimeRange + "|" +
+		strconv.Itoa(query.SafeSearch)
+	// Later engine pages are cached apart from the first
+	if query.Page > 1 {
+		key += "|page" + strconv.Itoa(query.Page)
+	}
+	if query.HasImageFilters() {
+		key += "|" + query.ImageColor + "|" + query.ImageLicense
+	}
+	if query.HasVideoFilters() {
+		key += "|" + query.VideoLength + "|" + query.VideoQuality
+	}
+	// Searches limited to preferred engines have their own results
+	if len(query.EnabledEngines) > 0 {
+		enabled := make([]string, len(query.EnabledEngines))
+		for i, name := range query.EnabledEngines {
+			enabled[i] = strings.ToLower(name)
+		}
+		sort.Strings(enabled)
+		key += "|engines:" + strings.Join(enabled, ",")
+	}
 
 	hash := sha256.Sum256([]byte(key))
 	return hex.EncodeToString(hash[:16])
@@ -660,6 +924,11 @@ func deduplicateResults(results []model.Result) []model.Result {
 				existing.Author = result.Author
 			}
 
+			// Keep structured data if we don't have any
+			if existing.Structured == nil && result.Structured != nil {
+				existing.Structured = result.Structured
+			}
+
 			// Keep earlier publish date
 			if !result.PublishedAt.IsZero() {
 				if existing.PublishedAt.IsZero() || result.PublishedAt.Before(existing.PublishedAt) {
@@ -55,7 +55,8 @@ func NewManager() *Manager {
 	return m
 }
 
-// SetCustomBangs sets custom bangs from server configuration
+// SetCustomBangs sets custom bangs from server configuration and the
+// custom bangs stored in the server database
 func (m *Manager) SetCustomBangs(bangs []*Bang) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -259,6 +260,24 @@ func (m *Manager) GetBuiltins() []*Bang {
 	return result
 }
 
+// GetCustom returns the server's custom bangs only
+func (m *Manager) GetCustom() []*Bang {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var result []*Bang
+
+	for _, b := range m.custom {
+		if !seen[b.Shortcut] {
+			seen[b.Shortcut] = true
+			result = append(result, b)
+		}
+	}
+
+	return result
+}
+
 // GetByCategory returns bangs filtered by category
 func (m *Manager) GetByCategory(category string) []*Bang {
 	all := m.GetAll()
@@ -0,0 +1,109 @@
+package bang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ddgOrigin is what the relative URLs of DuckDuckGo's own bangs, such as
+// "/?q={{{s}}}&ia=web", are resolved against
+const ddgOrigin = "https://duckduckgo.com"
+
+// DDGBang is a bang in the format of DuckDuckGo's bang list: t is the
+// trigger, s the name, u the URL with {{{s}}} for the query, d its domain,
+// c and sc the category and subcategory and r how often it was used. The
+// aliases and description fields are this server's own; DuckDuckGo's tools
+// ignore them.
+type DDGBang struct {
+	Trigger     string   `json:"t"`
+	Name        string   `json:"s"`
+	URL         string   `json:"u"`
+	Domain      string   `json:"d,omitempty"`
+	Category    string   `json:"c,omitempty"`
+	Subcategory string   `json:"sc,omitempty"`
+	Rank        int64    `json:"r"`
+	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// ExportDDG returns bangs in DuckDuckGo's format, ranked by their uses
+func ExportDDG(bangs []*Bang, uses map[string]int64) []DDGBang {
+	out := make([]DDGBang, 0, len(bangs))
+	for _, b := range bangs {
+		target := strings.ReplaceAll(b.URL, queryPlaceholder, ddgPlaceholder)
+		if !strings.Contains(target, ddgPlaceholder) {
+			target = strings.Replace(target, "%s", ddgPlaceholder, 1)
+		}
+		entry := DDGBang{
+			Trigger:     b.Shortcut,
+			Name:        b.Name,
+			URL:         target,
+			Category:    b.Category,
+			Rank:        uses[b.Shortcut],
+			Description: b.Description,
+			Aliases:     b.Aliases,
+		}
+		if u, err := url.Parse(b.URL); err == nil {
+			entry.Domain = u.Hostname()
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// importEntry reads a bang written either way: with this server's field
+// names (shortcut, name, url, ...) or DuckDuckGo's (t, s, u, c)
+type importEntry struct {
+	Bang
+	Trigger     string `json:"t"`
+	DDGName     string `json:"s"`
+	DDGURL      string `json:"u"`
+	DDGCategory string `json:"c"`
+}
+
+// ParseImport reads the bangs of an import: a JSON array of bangs in
+// either format, such as DuckDuckGo's bang.js, or an object holding the
+// array under "bangs"
+func ParseImport(data []byte) ([]Bang, error) {
+	data = bytes.TrimSpace(data)
+	var entries []importEntry
+	if len(data) > 0 && data[0] == '{' {
+		var wrapped struct {
+			Bangs []importEntry `json:"bangs"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		entries = wrapped.Bangs
+	} else if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%w: the body must be a JSON array of bangs: %v", ErrInvalidInput, err)
+	}
+	if len(entries) > MaxImport {
+		return nil, fmt.Errorf("%w: at most %d bangs per import", ErrInvalidInput, MaxImport)
+	}
+
+	bangs := make([]Bang, 0, len(entries))
+	for _, e := range entries {
+		b := e.Bang
+		if b.Shortcut == "" {
+			b.Shortcut = e.Trigger
+		}
+		if b.Name == "" {
+			b.Name = e.DDGName
+		}
+		if b.URL == "" {
+			b.URL = e.DDGURL
+			if strings.HasPrefix(b.URL, "/") && !strings.HasPrefix(b.URL, "//") {
+				b.URL = ddgOrigin + b.URL
+			}
+		}
+		if b.Category == "" {
+			b.Category = e.DDGCategory
+		}
+		bangs = append(bangs, b)
+	}
+	return bangs, nil
+}
@@ -0,0 +1,452 @@
+package bang
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrNotFound is returned for an unknown custom bang
+	ErrNotFound = errors.New("bang not found")
+	// ErrInvalidInput is returned for a bang that is rejected
+	ErrInvalidInput = errors.New("invalid bang")
+)
+
+// MaxImport bounds the bangs one import may hold; DuckDuckGo's own list
+// has about 14,000
+const MaxImport = 50000
+
+const (
+	maxShortcutLength    = 50
+	maxNameLength        = 100
+	maxCategoryLength    = 50
+	maxDescriptionLength = 500
+	maxURLLength         = 2048
+	maxAliases           = 20
+	maxImportErrors      = 10
+	defaultUsageSize     = 100
+	maxUsageSize         = 1000
+)
+
+const (
+	queryPlaceholder = "{query}"
+	// ddgPlaceholder is where DuckDuckGo's bang URLs take the query
+	ddgPlaceholder = "{{{s}}}"
+)
+
+// Normalize checks b and returns it in canonical form: shortcut and
+// aliases lower case without a leading "!", the name defaulting to the
+// shortcut and DuckDuckGo's {{{s}}} placeholder written as {query}
+func Normalize(b Bang) (Bang, error) {
+	var err error
+	if b.Shortcut, err = NormalizeShortcut(b.Shortcut); err != nil {
+		return b, err
+	}
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		b.Name = b.Shortcut
+	}
+	if utf8.RuneCountInString(b.Name) > maxNameLength {
+		return b, fmt.Errorf("%w: name must be at most %d characters", ErrInvalidInput, maxNameLength)
+	}
+
+	b.URL = strings.ReplaceAll(strings.TrimSpace(b.URL), ddgPlaceholder, queryPlaceholder)
+	parsed, err := url.Parse(b.URL)
+	if err != nil || len(b.URL) > maxURLLength || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return b, fmt.Errorf("%w: url must be an http or https address", ErrInvalidInput)
+	}
+
+	b.Category = strings.ToLower(strings.TrimSpace(b.Category))
+	if utf8.RuneCountInString(b.Category) > maxCategoryLength {
+		return b, fmt.Errorf("%w: category must be at most %d characters", ErrInvalidInput, maxCategoryLength)
+	}
+	b.Description = strings.TrimSpace(b.Description)
+	if utf8.RuneCountInString(b.Description) > maxDescriptionLength {
+		return b, fmt.Errorf("%w: description must be at most %d characters", ErrInvalidInput, maxDescriptionLength)
+	}
+	b.Icon = strings.TrimSpace(b.Icon)
+	if len(b.Icon) > maxURLLength {
+		return b, fmt.Errorf("%w: icon must be at most %d characters", ErrInvalidInput, maxURLLength)
+	}
+
+	if len(b.Aliases) > maxAliases {
+		return b, fmt.Errorf("%w: at most %d aliases", ErrInvalidInput, maxAliases)
+	}
+	aliases := make([]string, 0, len(b.Aliases))
+	seen := map[string]bool{b.Shortcut: true}
+	for _, alias := range b.Aliases {
+		if alias, err = NormalizeShortcut(alias); err != nil {
+			return b, err
+		}
+		if !seen[alias] {
+			seen[alias] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	b.Aliases = nil
+	if len(aliases) > 0 {
+		b.Aliases = aliases
+	}
+	return b, nil
+}
+
+// NormalizeShortcut lower-cases a shortcut or alias and drops a leading
+// "!". It may not hold spaces or another "!".
+func NormalizeShortcut(shortcut string) (string, error) {
+	shortcut = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(shortcut), "!"))
+	if shortcut == "" || utf8.RuneCountInString(shortcut) > maxShortcutLength {
+		return "", fmt.Errorf("%w: shortcut must be 1 to %d characters", ErrInvalidInput, maxShortcutLength)
+	}
+	for _, r := range shortcut {
+		if r == '!' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return "", fmt.Errorf("%w: %q is not a shortcut; use letters, digits and punctuation other than !", ErrInvalidInput, shortcut)
+		}
+	}
+	return shortcut, nil
+}
+
+// Usage is how often a bang was used. Only the count and the time of the
+// last use are kept, never the query.
+type Usage struct {
+	Shortcut   string    `json:"shortcut"`
+	Uses       int64     `json:"uses"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// ImportResult sums up an import
+type ImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	// Errors explains the first skipped entries
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Store keeps the operator's custom bangs and the use count of every bang
+// in the server database
+type Store struct {
+	db    *sql.DB
+	bangs string
+	usage string
+}
+
+// NewStore creates a store; tablePrefix is the server table prefix
+func NewStore(db *sql.DB, tablePrefix string) *Store {
+	return &Store{db: db, bangs: tablePrefix + "custom_bangs", usage: tablePrefix + "bang_usage"}
+}
+
+// List returns the custom bangs by shortcut
+func (s *Store) List(ctx context.Context) ([]*Bang, error) {
+	if s == nil || s.db == nil {
+		return []*Bang{}, nil
+	}
+	return s.query(ctx, `ORDER BY shortcut`)
+}
+
+// Get returns the custom bang with the given shortcut
+func (s *Store) Get(ctx context.Context, shortcut string) (*Bang, error) {
+	if s == nil || s.db == nil {
+		return nil, ErrNotFound
+	}
+	shortcut, err := NormalizeShortcut(shortcut)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	bangs, err := s.query(ctx, `WHERE shortcut = ?`, shortcut)
+	if err != nil {
+		return nil, err
+	}
+	if len(bangs) == 0 {
+		return nil, ErrNotFound
+	}
+	return bangs[0], nil
+}
+
+// Create stores a new custom bang. Its shortcut and aliases may not be
+// taken by another custom bang.
+func (s *Store) Create(ctx context.Context, b Bang) (*Bang, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("bang storage is unavailable")
+	}
+	b, err := Normalize(b)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conflict(existing, &b, ""); err != nil {
+		return nil, err
+	}
+	if err := s.upsert(ctx, s.db, &b, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, b.Shortcut)
+}
+
+// Update replaces the custom bang with the given shortcut; b may rename it
+func (s *Store) Update(ctx context.Context, shortcut string, b Bang) (*Bang, error) {
+	current, err := s.Get(ctx, shortcut)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(b.Shortcut) == "" {
+		b.Shortcut = current.Shortcut
+	}
+	if b, err = Normalize(b); err != nil {
+		return nil, err
+	}
+	existing, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conflict(existing, &b, current.Shortcut); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update bang: %w", err)
+	}
+	defer tx.Rollback()
+	if b.Shortcut != current.Shortcut {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+s.bangs+` WHERE shortcut = ?`, current.Shortcut); err != nil {
+			return nil, fmt.Errorf("update bang: %w", err)
+		}
+	}
+	if err := s.upsert(ctx, tx, &b, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("update bang: %w", err)
+	}
+	return s.Get(ctx, b.Shortcut)
+}
+
+// Delete removes the custom bang with the given shortcut and returns it.
+// Its usage stays counted.
+func (s *Store) Delete(ctx context.Context, shortcut string) (*Bang, error) {
+	b, err := s.Get(ctx, shortcut)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM `+s.bangs+` WHERE shortcut = ?`, b.Shortcut); err != nil {
+		return nil, fmt.Errorf("delete bang: %w", err)
+	}
+	return b, nil
+}
+
+// Import stores bangs in one transaction, replacing the custom bangs with
+// the same shortcut, or all of them when replace is set. Entries that are
+// invalid, or whose shortcut or aliases another custom bang holds, are
+// skipped and counted.
+func (s *Store) Import(ctx context.Context, bangs []Bang, replace bool) (*ImportResult, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("bang storage is unavailable")
+	}
+	if len(bangs) > MaxImport {
+		return nil, fmt.Errorf("%w: at most %d bangs per import", ErrInvalidInput, MaxImport)
+	}
+	var existing []*Bang
+	if !replace {
+		var err error
+		if existing, err = s.List(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ImportResult{}
+	skip := func(i int, err error) {
+		result.Skipped++
+		if len(result.Errors) < maxImportErrors {
+			result.Errors = append(result.Errors, fmt.Sprintf("entry %d: %s", i+1, strings.TrimPrefix(err.Error(), ErrInvalidInput.Error()+": ")))
+		}
+	}
+	// Later entries replace earlier ones with the same shortcut
+	byShortcut := make(map[string]int, len(existing)+len(bangs))
+	all := make([]*Bang, 0, len(existing)+len(bangs))
+	for _, b := range existing {
+		byShortcut[b.Shortcut] = len(all)
+		all = append(all, b)
+	}
+	var imported []*Bang
+	for i := range bangs {
+		b, err := Normalize(bangs[i])
+		if err != nil {
+			skip(i, err)
+			continue
+		}
+		if err := conflict(all, &b, b.Shortcut); err != nil {
+			skip(i, err)
+			continue
+		}
+		if at, ok := byShortcut[b.Shortcut]; ok {
+			all[at] = &b
+		} else {
+			byShortcut[b.Shortcut] = len(all)
+			all = append(all, &b)
+		}
+		imported = append(imported, &b)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("import bangs: %w", err)
+	}
+	defer tx.Rollback()
+	if replace {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+s.bangs); err != nil {
+			return nil, fmt.Errorf("import bangs: %w", err)
+		}
+	}
+	now := time.Now().UTC()
+	for _, b := range imported {
+		if err := s.upsert(ctx, tx, b, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("import bangs: %w", err)
+	}
+	result.Imported = len(imported)
+	return result, nil
+}
+
+// RecordUse counts one use of the bang with the given shortcut, built-in
+// or custom
+func (s *Store) RecordUse(ctx context.Context, shortcut string, at time.Time) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.usage+` (shortcut, uses, last_used_at) VALUES (?, 1, ?)
+		ON CONFLICT(shortcut) DO UPDATE SET uses = uses + 1, last_used_at = excluded.last_used_at`,
+		shortcut, at.UTC()); err != nil {
+		return fmt.Errorf("record bang use: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the use counts of the most used bangs, most used first
+func (s *Store) Usage(ctx context.Context, limit int) ([]Usage, error) {
+	if s == nil || s.db == nil {
+		return []Usage{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultUsageSize
+	}
+	if limit > maxUsageSize {
+		limit = maxUsageSize
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT shortcut, uses, last_used_at FROM `+s.usage+`
+		ORDER BY uses DESC, last_used_at DESC LIMIT `+strconv.Itoa(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list bang usage: %w", err)
+	}
+	defer rows.Close()
+	usage := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Shortcut, &u.Uses, &u.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("list bang usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// Counts returns the use count of every bang that has been used
+func (s *Store) Counts(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if s == nil || s.db == nil {
+		return counts, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT shortcut, uses FROM `+s.usage)
+	if err != nil {
+		return nil, fmt.Errorf("count bang uses: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var shortcut string
+		var uses int64
+		if err := rows.Scan(&shortcut, &uses); err != nil {
+			return nil, fmt.Errorf("count bang uses: %w", err)
+		}
+		counts[shortcut] = uses
+	}
+	return counts, rows.Err()
+}
+
+// execer is what upsert needs of a database or a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) upsert(ctx context.Context, db execer, b *Bang, now time.Time) error {
+	aliases, err := json.Marshal(b.Aliases)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO `+s.bangs+`
+		(shortcut, name, url, category, description, icon, aliases, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(shortcut) DO UPDATE SET name = excluded.name, url = excluded.url, category = excluded.category,
+		description = excluded.description, icon = excluded.icon, aliases = excluded.aliases, updated_at = excluded.updated_at`,
+		b.Shortcut, b.Name, b.URL, b.Category, b.Description, b.Icon, string(aliases), now, now); err != nil {
+		return fmt.Errorf("save bang: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) query(ctx context.Context, clause string, args ...any) ([]*Bang, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT shortcut, name, url, COALESCE(category, ''), COALESCE(description, ''), icon, aliases FROM `+s.bangs+` `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list bangs: %w", err)
+	}
+	defer rows.Close()
+	bangs := []*Bang{}
+	for rows.Next() {
+		var b Bang
+		var aliases string
+		if err := rows.Scan(&b.Shortcut, &b.Name, &b.URL, &b.Category, &b.Description, &b.Icon, &aliases); err != nil {
+			return nil, fmt.Errorf("list bangs: %w", err)
+		}
+		if err := json.Unmarshal([]byte(aliases), &b.Aliases); err != nil {
+			return nil, fmt.Errorf("list bangs: %w", err)
+		}
+		bangs = append(bangs, &b)
+	}
+	return bangs, rows.Err()
+}
+
+// conflict reports a shortcut or alias of b that a bang in existing,
+// other than the one with shortcut except, already answers to
+func conflict(existing []*Bang, b *Bang, except string) error {
+	taken := make(map[string]string)
+	for _, other := range existing {
+		if other.Shortcut == except {
+			continue
+		}
+		taken[other.Shortcut] = other.Shortcut
+		for _, alias := range other.Aliases {
+			taken[alias] = other.Shortcut
+		}
+	}
+	for _, name := range append([]string{b.Shortcut}, b.Aliases...) {
+		owner, ok := taken[name]
+		switch {
+		case ok && owner == name:
+			return fmt.Errorf("%w: a custom bang !%s exists", ErrInvalidInput, name)
+		case ok:
+			return fmt.Errorf("%w: !%s is an alias of the custom bang !%s", ErrInvalidInput, name, owner)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package bang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Server(t), "")
+}
+
+func TestNormalize(t *testing.T) {
+	b, err := Normalize(Bang{
+		Shortcut: " !Wiki ",
+		URL:      "https://wiki.example/search?q={{{s}}}",
+		Category: " Knowledge ",
+		Aliases:  []string{"W", "wiki", "w"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Bang{Shortcut: "wiki", Name: "wiki", URL: "https://wiki.example/search?q={query}", Category: "knowledge", Aliases: []string{"w"}}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("Normalize = %+v, want %+v", b, want)
+	}
+
+	for _, bad := range []Bang{
+		{Shortcut: "", URL: "https://example.com"},
+		{Shortcut: "two words", URL: "https://example.com"},
+		{Shortcut: "x", URL: "javascript:alert(1)"},
+		{Shortcut: "x", URL: "/relative?q={query}"},
+		{Shortcut: "x", URL: "https://example.com", Aliases: []string{"a!b"}},
+	} {
+		if _, err := Normalize(bad); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Normalize(%+v) error = %v, want ErrInvalidInput", bad, err)
+		}
+	}
+}
+
+func TestStoreCRUD(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	created, err := store.Create(ctx, Bang{Shortcut: "intra", Name: "Intranet", URL: "https://intra.example/?q={query}", Aliases: []string{"in"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Shortcut != "intra" || !reflect.DeepEqual(created.Aliases, []string{"in"}) {
+		t.Fatalf("created = %+v", created)
+	}
+	if _, err := store.Create(ctx, Bang{Shortcut: "intra", URL: "https://other.example/"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("duplicate shortcut error = %v", err)
+	}
+	if _, err := store.Create(ctx, Bang{Shortcut: "other", URL: "https://other.example/", Aliases: []string{"in"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("taken alias error = %v", err)
+	}
+
+	updated, err := store.Update(ctx, "!INTRA", Bang{Shortcut: "wiki", Name: "Wiki", URL: "https://wiki.example/?q={query}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Shortcut != "wiki" || updated.Aliases != nil {
+		t.Errorf("updated = %+v", updated)
+	}
+	if _, err := store.Get(ctx, "intra"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("renamed bang still found under its old shortcut: %v", err)
+	}
+
+	if _, err := store.Delete(ctx, "wiki"); err != nil {
+		t.Fatal(err)
+	}
+	if bangs, err := store.List(ctx); err != nil || len(bangs) != 0 {
+		t.Errorf("List after delete = %v, %v", bangs, err)
+	}
+	if _, err := store.Delete(ctx, "wiki"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete error = %v", err)
+	}
+}
+
+func TestStoreImportExport(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	if _, err := store.Create(ctx, Bang{Shortcut: "old", URL: "https://old.example/?q={query}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// DuckDuckGo's format, with one of its relative URLs and a bad entry
+	bangs, err := ParseImport([]byte(`[
+		{"c":"Tech","d":"pkg.go.dev","r":120,"s":"Go Packages","sc":"Programming","t":"gopkg","u":"https://pkg.go.dev/search?q={{{s}}}"},
+		{"c":"Online Services","d":"duckduckgo.com","r":5,"s":"DuckDuckGo Images","t":"ddgi","u":"/?q={{{s}}}&ia=images"},
+		{"t":"bad entry","u":"https://example.com"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.Import(ctx, bangs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Skipped != 1 || len(result.Errors) != 1 {
+		t.Fatalf("import result = %+v", result)
+	}
+	ddgi, err := store.Get(ctx, "ddgi")
+	if err != nil || ddgi.URL != "https://duckduckgo.com/?q={query}&ia=images" || ddgi.Category != "online services" {
+		t.Fatalf("ddgi = %+v, %v", ddgi, err)
+	}
+	if list, _ := store.List(ctx); len(list) != 3 {
+		t.Errorf("custom bangs after a merge import = %d, want 3", len(list))
+	}
+
+	// This server's own format, wrapped, replacing everything
+	bangs, err = ParseImport([]byte(`{"bangs":[{"shortcut":"mdn","name":"MDN","url":"https://developer.mozilla.org/search?q={query}","aliases":["m"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Import(ctx, bangs, true); err != nil {
+		t.Fatal(err)
+	}
+	list, err := store.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Shortcut != "mdn" {
+		t.Fatalf("custom bangs after a replacing import = %v, %v", list, err)
+	}
+
+	exported := ExportDDG(list, map[string]int64{"mdn": 7})
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw []map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw[0]["t"] != "mdn" || raw[0]["u"] != "https://developer.mozilla.org/search?q={{{s}}}" || raw[0]["d"] != "developer.mozilla.org" || raw[0]["r"] != float64(7) {
+		t.Errorf("exported = %v", raw[0])
+	}
+	again, err := ParseImport(data)
+	if err != nil || len(again) != 1 {
+		t.Fatalf("re-import = %v, %v", again, err)
+	}
+	if b, _ := Normalize(again[0]); !reflect.DeepEqual(b, *list[0]) {
+		t.Errorf("export did not round-trip: %+v, want %+v", b, *list[0])
+	}
+}
+
+func TestStoreUsage(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	for _, shortcut := range []string{"g", "w", "g"} {
+		if err := store.RecordUse(ctx, shortcut, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usage, err := store.Usage(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 || usage[0].Shortcut != "g" || usage[0].Uses != 2 || usage[1].Uses != 1 {
+		t.Errorf("usage = %+v", usage)
+	}
+	counts, err := store.Counts(ctx)
+	if err != nil || counts["g"] != 2 || counts["w"] != 1 {
+		t.Errorf("counts = %v, %v", counts, err)
+	}
+}
@@ -0,0 +1,215 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Kinds of pages engines return instead of results
+const (
+	BlockConsent = "consent"
+	BlockCaptcha = "captcha"
+	BlockDenied  = "denied"
+)
+
+// maxInspectBytes bounds how much of an HTML response is buffered for block
+// detection, matching the engines' own read limit
+const maxInspectBytes = 4 * 1024 * 1024
+
+// BlockedError reports that an engine answered with a consent wall, CAPTCHA
+// or block page instead of results
+type BlockedError struct {
+	Kind   string
+	Status int
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("engine returned a %s page (status %d)", e.Kind, e.Status)
+}
+
+// Unwrap lets callers match model.ErrEngineBlocked
+func (e *BlockedError) Unwrap() error {
+	return model.ErrEngineBlocked
+}
+
+// blockSignature identifies a block page by the URL it redirects to or a
+// marker in its HTML. Markers are lowercase and specific enough not to turn
+// up in ordinary result pages.
+type blockSignature struct {
+	kind   string
+	url    string
+	marker string
+}
+
+// genericBlockSignatures catch CAPTCHA widgets any engine may serve
+var genericBlockSignatures = []blockSignature{
+	{kind: BlockCaptcha, marker: `class="g-recaptcha"`},
+	{kind: BlockCaptcha, marker: `class="h-captcha"`},
+	{kind: BlockCaptcha, marker: "challenges.cloudflare.com"},
+	{kind: BlockCaptcha, marker: `id="challenge-form"`},
+	{kind: BlockCaptcha, marker: `id="captcha-form"`},
+}
+
+// engineBlockSignatures are the consent and block pages of single engines
+var engineBlockSignatures = map[string][]blockSignature{
+	"google": {
+		{kind: BlockConsent, url: "consent.google."},
+		{kind: BlockCaptcha, url: "/sorry/"},
+		{kind: BlockConsent, marker: `action="https://consent.google.`},
+		{kind: BlockCaptcha, marker: "our systems have detected unusual traffic"},
+	},
+	"yahoo": {
+		{kind: BlockConsent, url: "consent.yahoo.com"},
+		{kind: BlockConsent, url: "guce.yahoo.com"},
+	},
+	"duckduckgo": {
+		{kind: BlockCaptcha, marker: "anomaly-modal"},
+	},
+	"yandex": {
+		{kind: BlockCaptcha, url: "/showcaptcha"},
+		{kind: BlockCaptcha, marker: "smartcaptcha"},
+	},
+	"baidu": {
+		{kind: BlockCaptcha, url: "wappass.baidu.com"},
+	},
+	"startpage": {
+		{kind: BlockCaptcha, url: "/sp/captcha"},
+	},
+}
+
+// deniedMarkers flag block pages served with an error status, where looser
+// wording is safe because there are no results to confuse it with
+var deniedMarkers = []string{"captcha", "unusual traffic", "access denied", "are you a robot", "automated queries", "automated requests"}
+
+type engineNameKey struct{}
+
+// withEngineName records which engine a request context belongs to, for
+// the per-engine block signatures
+func withEngineName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, engineNameKey{}, strings.ToLower(name))
+}
+
+// InspectResponse returns a *BlockedError when resp is a consent wall,
+// CAPTCHA or block page. HTML bodies are buffered and put back, so the
+// engine reads the response as usual.
+func InspectResponse(resp *http.Response) error {
+	var engine string
+	if resp.Request != nil {
+		engine, _ = resp.Request.Context().Value(engineNameKey{}).(string)
+	}
+	signatures := engineBlockSignatures[engine]
+
+	// Redirects show up as the final URL, or as Location for engines that
+	// do not follow them
+	locations := make([]string, 0, 2)
+	if resp.Request != nil && resp.Request.URL != nil {
+		locations = append(locations, strings.ToLower(resp.Request.URL.String()))
+	}
+	if loc := resp.Header.Get("Location"); loc != "" {
+		locations = append(locations, strings.ToLower(loc))
+	}
+	for _, sig := range signatures {
+		if sig.url == "" {
+			continue
+		}
+		for _, loc := range locations {
+			if strings.Contains(loc, sig.url) {
+				return &BlockedError{Kind: sig.kind, Status: resp.StatusCode}
+			}
+		}
+	}
+
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxInspectBytes))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		// Leave read errors to the engine
+		return nil
+	}
+
+	lower := strings.ToLower(string(body))
+	for _, set := range [][]blockSignature{signatures, genericBlockSignatures} {
+		for _, sig := range set {
+			if sig.marker != "" && strings.Contains(lower, sig.marker) {
+				return &BlockedError{Kind: sig.kind, Status: resp.StatusCode}
+			}
+		}
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		for _, marker := range deniedMarkers {
+			if strings.Contains(lower, marker) {
+				return &BlockedError{Kind: BlockDenied, Status: resp.StatusCode}
+			}
+		}
+	}
+	return nil
+}
+
+// BlockHandler is told when an engine keeps answering with a block page
+// after the retry with another user agent
+type BlockHandler func(engine string, err *BlockedError)
+
+// SetBlockHandler sets the function told about blocked engines, usually to
+// alert the operator. It is called from search goroutines.
+func (a *Aggregator) SetBlockHandler(handler BlockHandler) {
+	if handler == nil {
+		a.blockHandler.Store(nil)
+		return
+	}
+	a.blockHandler.Store(&handler)
+}
+
+func (a *Aggregator) reportBlocked(engine Engine, err *BlockedError) {
+	if handler := a.blockHandler.Load(); handler != nil {
+		(*handler)(engine.Name(), err)
+	}
+}
+
+// alternateUserAgents are the browsers a blocked engine is retried as. They
+// differ from engine.UserAgent so the retry does not match the same rule.
+var alternateUserAgents = []string{
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+}
+
+// withAlternateUserAgent returns ctx with the next alternate user agent
+// added to its request overrides, other than the one ctx already sends
+func (a *Aggregator) withAlternateUserAgent(ctx context.Context) context.Context {
+	current := ""
+	if o, ok := ctx.Value(requestOverridesKey{}).(*requestOverrides); ok {
+		current = o.headers["User-Agent"]
+	}
+	ua := alternateUserAgents[a.uaRotation.Add(1)%uint64(len(alternateUserAgents))]
+	if ua == current {
+		ua = alternateUserAgents[a.uaRotation.Add(1)%uint64(len(alternateUserAgents))]
+	}
+	return withRequestHeader(ctx, "User-Agent", ua)
+}
+
+// withRequestHeader returns ctx with a header added to its request
+// overrides
+func withRequestHeader(ctx context.Context, name, value string) context.Context {
+	alt := &requestOverrides{headers: map[string]string{}}
+	if o, ok := ctx.Value(requestOverridesKey{}).(*requestOverrides); ok {
+		for name, value := range o.headers {
+			alt.headers[name] = value
+		}
+		alt.cookies = o.cookies
+		alt.params = o.params
+	}
+	alt.headers[name] = value
+	return context.WithValue(ctx, requestOverridesKey{}, alt)
+}
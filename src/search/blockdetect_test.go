@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func inspectTestResponse(t *testing.T, engine, rawURL string, status int, contentType, body string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(withEngineName(context.Background(), engine), "GET", rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+	return resp, InspectResponse(resp)
+}
+
+func TestInspectResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		engine      string
+		url         string
+		status      int
+		contentType string
+		body        string
+		want        string
+	}{
+		{"results", "google", "https://www.google.com/search?q=go", 200, "text/html", "<div>Go is a captcha-free language</div>", ""},
+		{"consent redirect", "google", "https://consent.google.com/ml?continue=x", 200, "text/html", "<form></form>", BlockConsent},
+		{"sorry page", "google", "https://www.google.com/sorry/index?continue=x", 429, "text/html", "", BlockCaptcha},
+		{"unusual traffic", "google", "https://www.google.com/search?q=go", 200, "text/html", "Our systems have detected unusual traffic from your computer", BlockCaptcha},
+		{"yahoo consent", "yahoo", "https://guce.yahoo.com/consent?x=1", 200, "text/html", "", BlockConsent},
+		{"signature of other engine", "bing", "https://guce.yahoo.com/consent", 200, "text/html", "", ""},
+		{"recaptcha widget", "bing", "https://www.bing.com/search?q=go", 200, "text/html; charset=utf-8", `<div class="g-recaptcha" data-sitekey="k"></div>`, BlockCaptcha},
+		{"denied page", "brave", "https://search.brave.com/search?q=go", 403, "text/html", "<h1>Access Denied</h1>", BlockDenied},
+		{"denied wording on 200", "brave", "https://search.brave.com/search?q=go", 200, "text/html", "<h1>Access Denied</h1>", ""},
+		{"json ignored", "reddit", "https://www.reddit.com/search.json", 403, "application/json", `{"message": "captcha"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := inspectTestResponse(t, tt.engine, tt.url, tt.status, tt.contentType, tt.body)
+			var blocked *BlockedError
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("InspectResponse() = %v, want nil", err)
+				}
+			} else if !errors.As(err, &blocked) || blocked.Kind != tt.want {
+				t.Fatalf("InspectResponse() = %v, want %s page", err, tt.want)
+			} else if !errors.Is(err, model.ErrEngineBlocked) {
+				t.Errorf("error does not match model.ErrEngineBlocked")
+			}
+
+			// The engine still reads the whole body
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+// blockingEngine answers with a block page unless the request carries one
+// of the alternate user agents
+type blockingEngine struct {
+	*mockEngine
+	agents []string
+}
+
+func (e *blockingEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://engine.example/search", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "default")
+	ua := ApplyRequestOverrides(req).Header.Get("User-Agent")
+	e.agents = append(e.agents, ua)
+	for _, alt := range alternateUserAgents {
+		if ua == alt && e.searchError == nil {
+			return []model.Result{{URL: "https://engine.example/1", Title: "One"}}, nil
+		}
+	}
+	return nil, &BlockedError{Kind: BlockCaptcha, Status: http.StatusTooManyRequests}
+}
+
+func TestAggregatorRetriesBlockedEngine(t *testing.T) {
+	engine := &blockingEngine{mockEngine: newMockEngine("blocky", model.CategoryGeneral, true)}
+	agg := NewAggregatorSimple([]Engine{engine}, 10*time.Second)
+	var reported []string
+	agg.SetBlockHandler(func(name string, err *BlockedError) {
+		reported = append(reported, name+":"+err.Kind)
+	})
+	query := &model.Query{Text: "go", Category: model.CategoryGeneral}
+
+	results, err := agg.Search(context.Background(), query)
+	if err != nil || len(results.Results) != 1 {
+		t.Fatalf("Search() = %v, %v; want the retry's result", results, err)
+	}
+	if len(engine.agents) != 2 || engine.agents[0] != "default" || engine.agents[1] == "default" {
+		t.Errorf("user agents = %v, want default then an alternate", engine.agents)
+	}
+	if len(reported) != 0 {
+		t.Errorf("reported = %v, want nothing after a successful retry", reported)
+	}
+
+	// Blocked on the retry too: reported, and the engine is degraded
+	engine.agents = nil
+	engine.SetError(errors.New("still blocked"))
+	results, err = agg.Search(context.Background(), query)
+	if err != nil || !results.Degraded {
+		t.Fatalf("Search() = %v, %v; want degraded results", results, err)
+	}
+	if len(reported) != 1 || reported[0] != "blocky:captcha" {
+		t.Errorf("reported = %v, want blocky:captcha", reported)
+	}
+	health := engine.GetHealth()
+	if health.Status != "degraded" || health.Blocked != BlockCaptcha || health.LastBlocked.IsZero() {
+		t.Errorf("health = %+v, want degraded and blocked by a captcha", health)
+	}
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Engine circuit breaker. Every engine keeps its recent calls: the share
+// that failed or were slow, and how long the successful ones took. Enough
+// consecutive failures, or too high a share of bad calls, open the engine's
+// circuit: searches skip it for a cooldown instead of waiting on a dead
+// upstream until the search deadline. Once the cooldown is over the
+// circuit is half open and the next search tries the engine; a good answer
+// closes the circuit, a bad one opens it again for twice as long. The
+// health probe task closes it too when the engine answers a probe.
+
+// Circuit states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// healthWindow is how many recent calls the error rate and latency cover
+const healthWindow = 20
+
+// circuitTrialTimeout is how long a half-open engine's trial search keeps
+// other searches from trying it too
+const circuitTrialTimeout = 30 * time.Second
+
+// CircuitBreaker sets when an engine's circuit opens and for how long
+type CircuitBreaker struct {
+	// Disabled never skips an engine; health is still tracked
+	Disabled bool
+	// FailureThreshold consecutive failures open the circuit
+	FailureThreshold int
+	// ErrorRate is the share of failed or slow calls among the recent
+	// ones that opens the circuit, once there are MinRequests of them
+	ErrorRate   float64
+	MinRequests int
+	// SlowCall is how long a successful call may take before it counts
+	// against the error rate; 0 counts none
+	SlowCall time.Duration
+	// Cooldown is how long the circuit first stays open; each reopening
+	// without a close in between doubles it, up to MaxCooldown
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+}
+
+// DefaultCircuitBreaker is the breaker engines start with
+var DefaultCircuitBreaker = CircuitBreaker{
+	FailureThreshold: 3,
+	ErrorRate:        0.5,
+	MinRequests:      10,
+	SlowCall:         10 * time.Second,
+	Cooldown:         10 * time.Minute,
+	MaxCooldown:      time.Hour,
+}
+
+// engineCall is one call in an engine's health window
+type engineCall struct {
+	latency time.Duration
+	bad     bool
+	ok      bool
+}
+
+// callWindow holds an engine's last healthWindow calls
+type callWindow struct {
+	calls [healthWindow]engineCall
+	next  int
+	count int
+}
+
+func (w *callWindow) add(call engineCall) {
+	w.calls[w.next] = call
+	w.next = (w.next + 1) % healthWindow
+	if w.count < healthWindow {
+		w.count++
+	}
+}
+
+// errorRate returns the share of bad calls in the window
+func (w *callWindow) errorRate() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	bad := 0
+	for _, call := range w.calls[:w.count] {
+		if call.bad {
+			bad++
+		}
+	}
+	return float64(bad) / float64(w.count)
+}
+
+// latency returns the mean and 95th percentile latency of the successful
+// calls in the window
+func (w *callWindow) latency() (avg, p95 time.Duration) {
+	latencies := make([]time.Duration, 0, w.count)
+	var total time.Duration
+	for _, call := range w.calls[:w.count] {
+		if call.ok {
+			latencies = append(latencies, call.latency)
+			total += call.latency
+		}
+	}
+	if len(latencies) == 0 {
+		return 0, 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return total / time.Duration(len(latencies)), latencies[(len(latencies)*95+99)/100-1]
+}
+
+// SetCircuitBreaker sets when the engine's circuit opens
+func (e *BaseEngine) SetCircuitBreaker(breaker CircuitBreaker) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.breaker = breaker
+}
+
+// ResetCircuit closes the engine's circuit and forgets its recent calls,
+// for an engine known to be fixed
+func (e *BaseEngine) ResetCircuit() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.window = callWindow{}
+	e.trips = 0
+	e.trialUntil = time.Time{}
+	e.health.ConsecutiveFailures = 0
+	e.health.CooldownUntil = time.Time{}
+}
+
+// circuitLocked returns the state of the engine's circuit at now
+func (e *BaseEngine) circuitLocked(now time.Time) string {
+	switch {
+	case e.health.CooldownUntil.After(now):
+		return CircuitOpen
+	case e.trips > 0:
+		return CircuitHalfOpen
+	default:
+		return CircuitClosed
+	}
+}
+
+// recordCallLocked adds a call to the window and opens or closes the
+// circuit by its outcome
+func (e *BaseEngine) recordCallLocked(call engineCall, now time.Time) {
+	state := e.circuitLocked(now)
+	e.window.add(call)
+	if e.breaker.Disabled {
+		e.trips = 0
+		e.health.CooldownUntil = time.Time{}
+		return
+	}
+	switch {
+	case state == CircuitOpen:
+		// A probe or a last-resort search; the cooldown stands
+		if call.ok && !call.bad {
+			e.closeCircuitLocked()
+		}
+	case state == CircuitHalfOpen:
+		if call.bad {
+			e.tripLocked(now)
+		} else {
+			e.closeCircuitLocked()
+		}
+	case !call.ok && e.breaker.FailureThreshold > 0 && e.health.ConsecutiveFailures >= e.breaker.FailureThreshold,
+		e.breaker.ErrorRate > 0 && e.window.count >= max(e.breaker.MinRequests, 1) && e.window.errorRate() >= e.breaker.ErrorRate:
+		e.tripLocked(now)
+	}
+}
+
+// tripLocked opens the circuit, for longer each time it reopens
+func (e *BaseEngine) tripLocked(now time.Time) {
+	cooldown := e.breaker.Cooldown
+	for i := 0; i < e.trips && cooldown < e.breaker.MaxCooldown; i++ {
+		cooldown *= 2
+	}
+	if e.breaker.MaxCooldown > 0 && cooldown > e.breaker.MaxCooldown {
+		cooldown = e.breaker.MaxCooldown
+	}
+	e.trips++
+	e.trialUntil = time.Time{}
+	e.health.CircuitOpens++
+	e.health.CooldownUntil = now.Add(cooldown)
+}
+
+func (e *BaseEngine) closeCircuitLocked() {
+	e.trips = 0
+	e.trialUntil = time.Time{}
+	e.window = callWindow{}
+	e.health.CooldownUntil = time.Time{}
+}
+
+// SetCircuitBreaker sets when the engines' circuits open
+func (a *Aggregator) SetCircuitBreaker(breaker CircuitBreaker) {
+	for _, engine := range a.engines {
+		if tracker, ok := engine.(interface{ SetCircuitBreaker(CircuitBreaker) }); ok {
+			tracker.SetCircuitBreaker(breaker)
+		}
+	}
+}
+
+// ResetCircuit closes the circuit of the engine named name. It reports
+// false when there is no such engine.
+func (a *Aggregator) ResetCircuit(name string) bool {
+	for _, engine := range a.engines {
+		if !strings.EqualFold(engine.Name(), name) {
+			continue
+		}
+		if tracker, ok := engine.(interface{ ResetCircuit() }); ok {
+			tracker.ResetCircuit()
+		}
+		return true
+	}
+	return false
+}
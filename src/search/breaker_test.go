@@ -0,0 +1,125 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	engine := newMockEngine("dead", model.CategoryGeneral, true)
+	engine.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 2, Cooldown: time.Minute, MaxCooldown: 3 * time.Minute})
+
+	engine.RecordFailure(model.ErrEngineUnavailable)
+	if h := engine.GetHealth(); h.Circuit != CircuitClosed || !engine.CanSearch(time.Now()) {
+		t.Fatalf("one failure opened the circuit: %+v", h)
+	}
+	engine.RecordFailure(model.ErrEngineUnavailable)
+	h := engine.GetHealth()
+	if h.Circuit != CircuitOpen || h.CircuitOpens != 1 || engine.CanSearch(time.Now()) {
+		t.Fatalf("circuit after two failures = %+v", h)
+	}
+	if d := time.Until(h.CooldownUntil); d <= 0 || d > time.Minute {
+		t.Errorf("cooldown = %s, want a minute", d)
+	}
+
+	// Half open after the cooldown: one search tries the engine
+	later := h.CooldownUntil.Add(time.Second)
+	if !engine.CanSearch(later) || engine.CanSearch(later) {
+		t.Fatal("half-open circuit did not allow exactly one trial")
+	}
+	if !engine.CanSearch(later.Add(circuitTrialTimeout)) {
+		t.Error("a trial that never reported back held the engine forever")
+	}
+
+	// A failed trial reopens the circuit for twice as long, up to the cap
+	expire := func() {
+		engine.mu.Lock()
+		engine.health.CooldownUntil = time.Now().Add(-time.Second)
+		engine.mu.Unlock()
+	}
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+		expire()
+		if h := engine.GetHealth(); h.Circuit != CircuitHalfOpen || h.Status != "degraded" {
+			t.Errorf("after the cooldown = %s %s", h.Circuit, h.Status)
+		}
+		engine.RecordFailure(model.ErrEngineUnavailable)
+		h = engine.GetHealth()
+		if d := time.Until(h.CooldownUntil); h.Circuit != CircuitOpen || d <= want-time.Second || d > want {
+			t.Errorf("reopened cooldown = %s, want %s", d, want)
+		}
+	}
+	expire()
+
+	// A good trial closes it
+	engine.RecordSuccess(100 * time.Millisecond)
+	if h := engine.GetHealth(); h.Circuit != CircuitClosed || h.CircuitOpens != 3 || h.WindowRequests != 0 {
+		t.Errorf("after a good trial = %+v", h)
+	}
+}
+
+func TestCircuitBreakerErrorRate(t *testing.T) {
+	engine := newMockEngine("slow", model.CategoryGeneral, true)
+	engine.SetCircuitBreaker(CircuitBreaker{ErrorRate: 0.5, MinRequests: 4, SlowCall: time.Second, Cooldown: time.Minute})
+
+	engine.RecordSuccess(100 * time.Millisecond)
+	engine.RecordSuccess(300 * time.Millisecond)
+	engine.RecordSuccess(5 * time.Second)
+	h := engine.GetHealth()
+	if h.WindowRequests != 3 || h.ErrorRate != 0.333 || h.AvgResponseTimeMS != 1800 || h.P95ResponseTimeMS != 5000 {
+		t.Errorf("health = %+v", h)
+	}
+	if h.Circuit != CircuitClosed {
+		t.Fatal("opened before min_requests calls")
+	}
+	// Slow calls count against the error rate even though they succeed
+	engine.RecordSuccess(8 * time.Second)
+	if h := engine.GetHealth(); h.Circuit != CircuitOpen || h.ErrorRate != 0.5 {
+		t.Errorf("after half the calls were slow = %+v", h)
+	}
+
+	engine.ResetCircuit()
+	if h := engine.GetHealth(); h.Circuit != CircuitClosed || h.WindowRequests != 0 || !engine.CanSearch(time.Now()) {
+		t.Errorf("after ResetCircuit() = %+v", h)
+	}
+
+	// Disabled: tracked but never skipped
+	engine.SetCircuitBreaker(CircuitBreaker{Disabled: true, FailureThreshold: 1})
+	engine.RecordFailure(model.ErrEngineUnavailable)
+	if h := engine.GetHealth(); h.Circuit != CircuitClosed || h.ErrorRate != 1 || !engine.CanSearch(time.Now()) {
+		t.Errorf("disabled breaker = %+v", h)
+	}
+}
+
+func TestAggregatorSkipsOpenCircuits(t *testing.T) {
+	dead := newMockEngine("dead", model.CategoryGeneral, true)
+	live := newMockEngine("live", model.CategoryGeneral, true)
+	dead.GetConfig().Priority = 100
+	agg := NewAggregator([]Engine{dead, live}, AggregatorConfig{Timeout: 5 * time.Second})
+	agg.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1, Cooldown: time.Minute})
+	dead.RecordFailure(model.ErrEngineUnavailable)
+
+	query := &model.Query{Text: "breaker", Category: model.CategoryGeneral}
+	if selected := agg.filterEngines(query); len(selected) != 1 || selected[0].Name() != "live" {
+		t.Fatalf("selected %d engines with room for both, want only live", len(selected))
+	}
+	agg.SetCategoryEngines(map[model.Category][]CategoryEngine{model.CategoryGeneral: {{Name: "dead", Weight: 1}, {Name: "live", Weight: 1}}})
+	if selected := agg.filterEngines(query); len(selected) != 1 || selected[0].Name() != "live" {
+		t.Errorf("listed engines: selected %d, want only live", len(selected))
+	}
+	agg.SetCategoryEngines(nil)
+
+	// With every circuit open the search still asks someone
+	live.RecordFailure(model.ErrEngineUnavailable)
+	if selected := agg.filterEngines(query); len(selected) != 2 {
+		t.Errorf("selected %d engines with every circuit open, want 2", len(selected))
+	}
+
+	if !agg.ResetCircuit("DEAD") || agg.ResetCircuit("missing") {
+		t.Error("ResetCircuit() found the wrong engines")
+	}
+	if !dead.CanSearch(time.Now()) {
+		t.Error("reset engine still skipped")
+	}
+}
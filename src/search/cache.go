@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,6 +21,27 @@ type ResultCache struct {
 	staleTTL time.Duration
 	hits     atomic.Int64
 	misses   atomic.Int64
+
+	// TTLs per category and hits and misses per category; see Configure
+	mu          sync.RWMutex
+	disabled    bool
+	categoryTTL map[model.Category]time.Duration
+	categories  map[model.Category]*categoryCounts
+}
+
+// categoryCounts is one category's cache hits and misses
+type categoryCounts struct {
+	hits, misses atomic.Int64
+}
+
+// ResultCachePolicy is how long search results are reused
+type ResultCachePolicy struct {
+	// Disabled stops storing and reusing results
+	Disabled bool
+	// TTL for categories without their own; 0 keeps the cache's TTL
+	TTL time.Duration
+	// Categories sets the TTL of the categories listed
+	Categories map[model.Category]time.Duration
 }
 
 type cachedSearchResults struct {
@@ -35,28 +57,102 @@ func NewResultCache(backend cache.Cache, ttl time.Duration) *ResultCache {
 		ttl = 5 * time.Minute
 	}
 	return &ResultCache{
-		backend:  backend,
-		ttl:      ttl,
-		staleTTL: staleCacheTTL(ttl),
+		backend:    backend,
+		ttl:        ttl,
+		staleTTL:   staleCacheTTL(ttl),
+		categories: make(map[model.Category]*categoryCounts),
 	}
 }
 
+// Configure applies policy to the results stored from now on. A nil cache
+// ignores it.
+func (c *ResultCache) Configure(policy ResultCachePolicy) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled = policy.Disabled
+	if policy.TTL > 0 {
+		c.ttl = policy.TTL
+		c.staleTTL = staleCacheTTL(policy.TTL)
+	}
+	c.categoryTTL = make(map[model.Category]time.Duration, len(policy.Categories))
+	for category, ttl := range policy.Categories {
+		if ttl > 0 {
+			c.categoryTTL[category] = ttl
+		}
+	}
+}
+
+// Enabled reports whether results are stored and reused
+func (c *ResultCache) Enabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.backend != nil && !c.disabled
+}
+
+// TTL returns how long the results of a search in category are reused
+func (c *ResultCache) TTL(category model.Category) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if ttl, ok := c.categoryTTL[category]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// counts returns category's hits and misses, creating them
+func (c *ResultCache) counts(category model.Category) *categoryCounts {
+	c.mu.RLock()
+	counts, ok := c.categories[category]
+	c.mu.RUnlock()
+	if ok {
+		return counts
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if counts, ok = c.categories[category]; !ok {
+		counts = &categoryCounts{}
+		c.categories[category] = counts
+	}
+	return counts
+}
+
 // Get retrieves cached results for a key.
 func (c *ResultCache) Get(key string) *model.SearchResults {
+	return c.GetFor(key, "")
+}
+
+// GetFor retrieves the cached results of a search in category, counting
+// the hit or miss for it.
+func (c *ResultCache) GetFor(key string, category model.Category) *model.SearchResults {
+	if !c.Enabled() {
+		return nil
+	}
 	results, _, err := c.get(cacheKey(key))
 	if err != nil {
 		c.misses.Add(1)
+		c.counts(category).misses.Add(1)
 		return nil
 	}
 	c.hits.Add(1)
+	c.counts(category).hits.Add(1)
 	return results
 }
 
 // Set stores results in the cache with the configured TTL.
 func (c *ResultCache) Set(key string, results *model.SearchResults) {
-	if c.backend == nil || results == nil {
+	c.SetFor(key, "", results)
+}
+
+// SetFor stores the results of a search in category for the category's
+// TTL.
+func (c *ResultCache) SetFor(key string, category model.Category, results *model.SearchResults) {
+	if !c.Enabled() || results == nil {
 		return
 	}
+	ttl := c.TTL(category)
 
 	entry := cachedSearchResults{
 		SavedAt: time.Now().UTC(),
@@ -67,8 +163,8 @@ func (c *ResultCache) Set(key string, results *model.SearchResults) {
 		return
 	}
 
-	_ = c.backend.Set(context.Background(), cacheKey(key), data, c.ttl)
-	_ = c.backend.Set(context.Background(), staleCacheKey(key), data, c.staleTTL)
+	_ = c.backend.Set(context.Background(), cacheKey(key), data, ttl)
+	_ = c.backend.Set(context.Background(), staleCacheKey(key), data, staleCacheTTL(ttl))
 }
 
 // Delete removes an item from the cache.
@@ -88,31 +184,74 @@ func (c *ResultCache) Clear() {
 	_ = c.backend.Clear(context.Background(), "search:*")
 	c.hits.Store(0)
 	c.misses.Store(0)
+	c.mu.Lock()
+	c.categories = make(map[model.Category]*categoryCounts)
+	c.mu.Unlock()
 }
 
 // CacheStats holds cache hit/miss statistics.
 type CacheStats struct {
+	Enabled bool `json:"enabled"`
+	// Backend is memory, valkey or redis
+	Backend       string  `json:"backend,omitempty"`
 	Hits          int64   `json:"hits"`
 	Misses        int64   `json:"misses"`
 	HitRate       float64 `json:"hit_rate"`
+	TTLSeconds    float64 `json:"ttl_seconds"`
 	StaleTTLHours float64 `json:"stale_ttl_hours"`
+	// Categories holds the TTL, hits and misses of every category
+	Categories map[model.Category]CategoryCacheStats `json:"categories"`
+}
+
+// CategoryCacheStats is one category's cache statistics
+type CategoryCacheStats struct {
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+}
+
+// hitRate is hits as a share of lookups
+func hitRate(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
 }
 
 // Stats returns cache hit/miss statistics.
 func (c *ResultCache) Stats() CacheStats {
 	hits := c.hits.Load()
 	misses := c.misses.Load()
-	total := hits + misses
-	hitRate := float64(0)
-	if total > 0 {
-		hitRate = float64(hits) / float64(total)
-	}
-	return CacheStats{
+	c.mu.RLock()
+	stats := CacheStats{
+		Enabled:       c.backend != nil && !c.disabled,
 		Hits:          hits,
 		Misses:        misses,
-		HitRate:       hitRate,
+		HitRate:       hitRate(hits, misses),
+		TTLSeconds:    c.ttl.Seconds(),
 		StaleTTLHours: c.staleTTL.Hours(),
+		Categories:    make(map[model.Category]CategoryCacheStats),
+	}
+	counts := make(map[model.Category]*categoryCounts, len(c.categories))
+	for category, n := range c.categories {
+		counts[category] = n
 	}
+	c.mu.RUnlock()
+	if c.backend != nil {
+		if backend, err := c.backend.Stats(context.Background()); err == nil {
+			stats.Backend = backend.Backend
+		}
+	}
+	for _, category := range model.AllCategories() {
+		entry := CategoryCacheStats{TTLSeconds: c.TTL(category).Seconds()}
+		if n, ok := counts[category]; ok {
+			entry.Hits, entry.Misses = n.hits.Load(), n.misses.Load()
+			entry.HitRate = hitRate(entry.Hits, entry.Misses)
+		}
+		stats.Categories[category] = entry
+	}
+	return stats
 }
 
 // cacheKey returns the PART 9-compliant key: search:{hash}
@@ -122,6 +261,9 @@ func cacheKey(hash string) string {
 
 // GetStale retrieves the longer-lived fallback copy of cached results.
 func (c *ResultCache) GetStale(key string) (*model.SearchResults, time.Duration) {
+	if !c.Enabled() {
+		return nil, 0
+	}
 	results, savedAt, err := c.get(staleCacheKey(key))
 	if err != nil {
 		return nil, 0
@@ -284,3 +284,53 @@ func TestCacheKeyFormat(t *testing.T) {
 		t.Errorf("cacheKey = %q, want search:abc123", key)
 	}
 }
+
+func TestResultCacheCategoryTTL(t *testing.T) {
+	rc := newTestCache(time.Minute)
+	rc.Configure(ResultCachePolicy{Categories: map[model.Category]time.Duration{model.CategoryNews: 10 * time.Millisecond}})
+
+	if rc.TTL(model.CategoryNews) != 10*time.Millisecond || rc.TTL(model.CategoryGeneral) != time.Minute {
+		t.Fatalf("TTLs = %v, %v", rc.TTL(model.CategoryNews), rc.TTL(model.CategoryGeneral))
+	}
+
+	rc.SetFor("news", model.CategoryNews, &model.SearchResults{Query: "news"})
+	rc.SetFor("web", model.CategoryGeneral, &model.SearchResults{Query: "web"})
+	time.Sleep(30 * time.Millisecond)
+
+	if rc.GetFor("news", model.CategoryNews) != nil {
+		t.Error("news results outlived their category TTL")
+	}
+	if rc.GetFor("web", model.CategoryGeneral) == nil {
+		t.Error("general results expired with the news TTL")
+	}
+
+	stats := rc.Stats()
+	news, general := stats.Categories[model.CategoryNews], stats.Categories[model.CategoryGeneral]
+	if news.Misses != 1 || news.Hits != 0 || general.Hits != 1 || general.HitRate != 1 {
+		t.Errorf("category stats = %+v, %+v", news, general)
+	}
+	if news.TTLSeconds != 0.01 || stats.Backend != "memory" {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	rc := newTestCache(time.Minute)
+	rc.Set("key1", &model.SearchResults{Query: "test"})
+	rc.Configure(ResultCachePolicy{Disabled: true})
+
+	if rc.Enabled() || rc.Stats().Enabled {
+		t.Error("cache should report disabled")
+	}
+	if rc.Get("key1") != nil {
+		t.Error("disabled cache returned results")
+	}
+	if stale, _ := rc.GetStale("key1"); stale != nil {
+		t.Error("disabled cache returned stale results")
+	}
+
+	rc.Configure(ResultCachePolicy{TTL: 2 * time.Minute})
+	if !rc.Enabled() || rc.TTL(model.CategoryNews) != 2*time.Minute {
+		t.Errorf("re-enabled cache: enabled %v, ttl %v", rc.Enabled(), rc.TTL(model.CategoryNews))
+	}
+}
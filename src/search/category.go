@@ -0,0 +1,111 @@
+package search
+
+import (
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// CategoryEngine is one engine in an explicit category engine list
+type CategoryEngine struct {
+	Name string `json:"engine"`
+	// Weight scales the engine's result scores in the category
+	Weight float64 `json:"weight"`
+}
+
+// SetCategoryEngines replaces the explicit category engine lists. A listed
+// category queries only its engines, in list order rather than by priority,
+// and scales each engine's scores by its weight. Other categories use every
+// engine that supports them.
+func (a *Aggregator) SetCategoryEngines(lists map[model.Category][]CategoryEngine) {
+	copied := make(map[model.Category][]CategoryEngine, len(lists))
+	for category, list := range lists {
+		if len(list) > 0 {
+			copied[category] = append([]CategoryEngine(nil), list...)
+		}
+	}
+	a.categoryMu.Lock()
+	a.categoryEngines = copied
+	a.categoryMu.Unlock()
+}
+
+// CategoryEngines returns the explicit engine list of category, if it has one
+func (a *Aggregator) CategoryEngines(category model.Category) ([]CategoryEngine, bool) {
+	a.categoryMu.RLock()
+	defer a.categoryMu.RUnlock()
+	list, ok := a.categoryEngines[category]
+	return append([]CategoryEngine(nil), list...), ok
+}
+
+// SetDisabledCategories replaces the categories that cannot be searched:
+// a search in one fails with model.ErrCategoryDisabled. The general
+// category is always searchable.
+func (a *Aggregator) SetDisabledCategories(categories []model.Category) {
+	disabled := make(map[model.Category]bool, len(categories))
+	for _, category := range categories {
+		if category != model.CategoryGeneral {
+			disabled[category] = true
+		}
+	}
+	a.categoryMu.Lock()
+	a.disabledCategories = disabled
+	a.categoryMu.Unlock()
+}
+
+// CategoryEnabled reports whether category can be searched
+func (a *Aggregator) CategoryEnabled(category model.Category) bool {
+	a.categoryMu.RLock()
+	defer a.categoryMu.RUnlock()
+	return !a.disabledCategories[category]
+}
+
+// categoryEngineWeight returns the weight of engine in category's list, 1
+// when the category or engine is not listed
+func (a *Aggregator) categoryEngineWeight(category model.Category, engine string) float64 {
+	a.categoryMu.RLock()
+	defer a.categoryMu.RUnlock()
+	for _, e := range a.categoryEngines[category] {
+		if strings.EqualFold(e.Name, engine) {
+			return e.Weight
+		}
+	}
+	return 1
+}
+
+// listedEngines returns the aggregator's engines named in list, in list order
+func (a *Aggregator) listedEngines(list []CategoryEngine) []Engine {
+	engines := make([]Engine, 0, len(list))
+	for _, e := range list {
+		for _, engine := range a.engines {
+			if strings.EqualFold(e.Name, engine.Name()) {
+				engines = append(engines, engine)
+				break
+			}
+		}
+	}
+	return engines
+}
+
+// selectListedEngines keeps list order, moving engines in cooldown behind
+// the ready ones, and applies the concurrency limit
+func (a *Aggregator) selectListedEngines(engines []Engine) []Engine {
+	now := time.Now()
+	selected := make([]Engine, 0, len(engines))
+	var recovering []Engine
+	for _, engine := range engines {
+		if a.canSearch(engine, now) {
+			selected = append(selected, engine)
+			continue
+		}
+		recovering = append(recovering, engine)
+	}
+	if len(selected) == 0 {
+		selected = recovering
+	}
+
+	if a.maxConcurrent > 0 && len(selected) > a.maxConcurrent {
+		selected = selected[:a.maxConcurrent]
+	}
+	return selected
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestAggregatorCategoryEnginesOrder(t *testing.T) {
+	alpha := newMockEngine("alpha", model.CategoryGeneral, true)
+	beta := newMockEngine("beta", model.CategoryGeneral, true)
+	gamma := newMockEngine("gamma", model.CategoryGeneral, true)
+	// Priority would put alpha first; the list overrides it
+	alpha.GetConfig().Priority = 100
+
+	agg := NewAggregator([]Engine{alpha, beta, gamma}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		MaxConcurrent: 3,
+	})
+	agg.SetCategoryEngines(map[model.Category][]CategoryEngine{
+		model.CategoryGeneral: {{Name: "gamma", Weight: 1}, {Name: "Alpha", Weight: 1}, {Name: "missing", Weight: 1}},
+	})
+
+	for range 2 {
+		selected := agg.filterEngines(&model.Query{Text: "order", Category: model.CategoryGeneral})
+		if len(selected) != 2 || selected[0].Name() != "gamma" || selected[1].Name() != "alpha" {
+			t.Fatalf("filterEngines() = %v, want [gamma alpha] without rotation", engineNames(selected))
+		}
+	}
+
+	// Engines named in the query still win over the list
+	selected := agg.filterEngines(&model.Query{Text: "order", Category: model.CategoryGeneral, Engines: []string{"beta"}})
+	if len(selected) != 1 || selected[0].Name() != "beta" {
+		t.Errorf("filterEngines() with engines = %v, want [beta]", engineNames(selected))
+	}
+
+	if _, ok := agg.CategoryEngines(model.CategoryImages); ok {
+		t.Error("images has no list but CategoryEngines reported one")
+	}
+}
+
+func TestAggregatorCategoryEngineWeights(t *testing.T) {
+	light := newMockEngine("light", model.CategoryGeneral, true)
+	heavy := newMockEngine("heavy", model.CategoryGeneral, true)
+	light.SetResults([]model.Result{{URL: "https://light.example", Title: "Light", Score: 100}})
+	heavy.SetResults([]model.Result{{URL: "https://heavy.example", Title: "Heavy", Score: 60}})
+
+	agg := NewAggregatorSimple([]Engine{light, heavy}, 10*time.Second)
+	agg.SetCategoryEngines(map[model.Category][]CategoryEngine{
+		model.CategoryGeneral: {{Name: "light", Weight: 0.5}, {Name: "heavy", Weight: 2}},
+	})
+
+	results, err := agg.Search(context.Background(), &model.Query{Text: "weights", Category: model.CategoryGeneral, PerPage: 10})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results.Results) != 2 || results.Results[0].URL != "https://heavy.example" {
+		t.Fatalf("results = %+v, want the weighted-up engine first", results.Results)
+	}
+	if results.Results[0].Score != 120 || results.Results[1].Score != 50 {
+		t.Errorf("scores = %v, %v, want 120, 50", results.Results[0].Score, results.Results[1].Score)
+	}
+}
+
+func TestAggregatorDisabledCategories(t *testing.T) {
+	files := newMockEngine("files", model.CategoryFiles, true)
+	files.SetResults([]model.Result{{URL: "https://files.example", Title: "File"}})
+	web := newMockEngine("web", model.CategoryGeneral, true)
+	web.SetResults([]model.Result{{URL: "https://web.example", Title: "Web"}})
+
+	agg := NewAggregatorSimple([]Engine{files, web}, 10*time.Second)
+	// General cannot be turned off
+	agg.SetDisabledCategories([]model.Category{model.CategoryFiles, model.CategoryGeneral})
+
+	if _, err := agg.Search(context.Background(), &model.Query{Text: "iso", Category: model.CategoryFiles}); !errors.Is(err, model.ErrCategoryDisabled) {
+		t.Errorf("Search(files) error = %v, want ErrCategoryDisabled", err)
+	}
+	if _, err := agg.Search(context.Background(), &model.Query{Text: "iso", Category: model.CategoryGeneral}); err != nil {
+		t.Errorf("Search(general) error = %v", err)
+	}
+
+	agg.SetDisabledCategories(nil)
+	if !agg.CategoryEnabled(model.CategoryFiles) {
+		t.Error("files is still disabled after SetDisabledCategories(nil)")
+	}
+}
+
+func engineNames(engines []Engine) []string {
+	names := make([]string, len(engines))
+	for i, e := range engines {
+		names[i] = e.Name()
+	}
+	return names
+}
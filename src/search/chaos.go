@@ -0,0 +1,109 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Chaos faults, injected into engine calls in development to check how
+// searches cope with failing upstreams
+const (
+	// ChaosError fails the call
+	ChaosError = "error"
+	// ChaosTimeout holds the call until the search deadline
+	ChaosTimeout = "timeout"
+	// ChaosMalformed cuts the upstream response bodies in half before the
+	// engine parses them
+	ChaosMalformed = "malformed"
+)
+
+// ChaosFault is what is injected into one engine's calls
+type ChaosFault struct {
+	// Latency is added before the call
+	Latency time.Duration
+	// Fault is ChaosError, ChaosTimeout, ChaosMalformed or empty for
+	// latency only
+	Fault string
+	// Rate is the share of calls affected, 0-1; 0 affects every call
+	Rate float64
+}
+
+type chaosMalformedKey struct{}
+
+// SetChaos replaces the faults injected into engine calls, keyed by engine
+// name. nil or empty injects none. Injected failures count against an
+// engine's health like real ones, so they exercise the circuit breaker.
+func (a *Aggregator) SetChaos(faults map[string]ChaosFault) {
+	chaos := make(map[string]ChaosFault, len(faults))
+	for name, fault := range faults {
+		chaos[strings.ToLower(name)] = fault
+	}
+	a.chaos.Store(&chaos)
+}
+
+// Chaos returns the faults injected into engine calls, by engine name
+func (a *Aggregator) Chaos() map[string]ChaosFault {
+	faults := map[string]ChaosFault{}
+	if chaos := a.chaos.Load(); chaos != nil {
+		for name, fault := range *chaos {
+			faults[name] = fault
+		}
+	}
+	return faults
+}
+
+// withChaos runs call with the engine's injected faults, if it has any and
+// this call is among the affected share
+func (a *Aggregator) withChaos(ctx context.Context, engine Engine, call func(ctx context.Context) ([]model.Result, error)) ([]model.Result, error) {
+	chaos := a.chaos.Load()
+	if chaos == nil {
+		return call(ctx)
+	}
+	fault, ok := (*chaos)[strings.ToLower(engine.Name())]
+	if !ok || (fault.Rate > 0 && rand.Float64() >= fault.Rate) {
+		return call(ctx)
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	switch fault.Fault {
+	case ChaosError:
+		return nil, fmt.Errorf("%w: injected by search.chaos", model.ErrEngineUnavailable)
+	case ChaosTimeout:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case ChaosMalformed:
+		return call(context.WithValue(ctx, chaosMalformedKey{}, true))
+	}
+	return call(ctx)
+}
+
+// MalformResponse cuts resp's body in half when its request belongs to an
+// engine call with the malformed chaos fault; otherwise it does nothing
+func MalformResponse(resp *http.Response) {
+	if resp.Request == nil || resp.Request.Context().Value(chaosMalformedKey{}) == nil {
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body[:len(body)/2]), resp.Body}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+}
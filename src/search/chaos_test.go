@@ -0,0 +1,78 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestChaosFaults(t *testing.T) {
+	engine := newMockEngine("flaky", model.CategoryGeneral, true)
+	engine.SetResults([]model.Result{{Title: "ok", URL: "https://example.com/"}})
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{Timeout: 5 * time.Second})
+	query := &model.Query{Text: "chaos", Category: model.CategoryGeneral}
+
+	agg.SetChaos(map[string]ChaosFault{"FLAKY": {Fault: ChaosError}})
+	if _, err := agg.runEngine(context.Background(), engine, query); !errors.Is(err, model.ErrEngineUnavailable) {
+		t.Errorf("error fault = %v", err)
+	}
+	if engine.Calls() != 0 {
+		t.Error("an injected error still called the engine")
+	}
+
+	agg.SetChaos(map[string]ChaosFault{"flaky": {Latency: 30 * time.Millisecond}})
+	start := time.Now()
+	if results, err := agg.runEngine(context.Background(), engine, query); err != nil || len(results) != 1 || time.Since(start) < 30*time.Millisecond {
+		t.Errorf("latency fault = %d results, %v after %s", len(results), err, time.Since(start))
+	}
+
+	agg.SetChaos(map[string]ChaosFault{"flaky": {Fault: ChaosTimeout}})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := agg.runEngine(ctx, engine, query); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout fault = %v", err)
+	}
+
+	// A rate near zero leaves almost every call alone
+	agg.SetChaos(map[string]ChaosFault{"flaky": {Fault: ChaosError, Rate: 0.0001}})
+	if _, err := agg.runEngine(context.Background(), engine, query); err != nil {
+		t.Errorf("rarely affected call = %v", err)
+	}
+
+	agg.SetChaos(nil)
+	if _, err := agg.runEngine(context.Background(), engine, query); err != nil || len(agg.Chaos()) != 0 {
+		t.Errorf("after clearing: %v, %v", err, agg.Chaos())
+	}
+}
+
+func TestMalformResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": [{"title": "one"}]}`))
+	}))
+	defer upstream.Close()
+
+	get := func(ctx context.Context) string {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		MalformResponse(resp)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if body := get(context.Background()); !strings.HasSuffix(body, "}]}") {
+		t.Errorf("untouched body = %q", body)
+	}
+	if body := get(context.WithValue(context.Background(), chaosMalformedKey{}, true)); body != `{"results": [{"` {
+		t.Errorf("malformed body = %q", body)
+	}
+}
@@ -0,0 +1,303 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Schema drift detection. An engine that changes its markup or JSON keeps
+// answering 200 while its parser finds nothing, so health checks never
+// notice. Each successful response is reduced to a structural fingerprint:
+// the key paths of a JSON response, or the tag and class pairs of an HTML
+// page. An engine's first responses teach it which features it always
+// has; later responses missing many of them raise its drift, and past the
+// threshold the engine is flagged as having a parser that is likely broken.
+
+const (
+	// driftLearnResponses is how many responses the baseline is learned
+	// from
+	driftLearnResponses = 20
+	// driftStableShare is how often a feature must appear while learning
+	// to count as part of the engine's structure
+	driftStableShare = 0.9
+	// driftMinStable is the fewest stable features drift is judged on
+	driftMinStable = 3
+	// driftSmoothing weighs each response in the drift average, so a
+	// single odd page does not flag the engine
+	driftSmoothing = 0.2
+	// maxShapeFeatures bounds the fingerprint of one response
+	maxShapeFeatures = 2000
+	// maxShapeDepth bounds how deep JSON is walked
+	maxShapeDepth = 8
+	// maxMissingFeatures is how many missing features are reported
+	maxMissingFeatures = 20
+)
+
+// DefaultDriftThreshold flags an engine once, on average, half of its
+// stable features are missing
+const DefaultDriftThreshold = 0.5
+
+// SchemaDrift is how far an engine's responses stray from the structure
+// it learned
+type SchemaDrift struct {
+	Engine string `json:"engine"`
+	// Learned is the number of responses the baseline was learned from;
+	// drift is judged once it reaches Learning
+	Learned  int `json:"learned"`
+	Learning int `json:"learning"`
+	// Stable is the number of features the learned responses always had
+	Stable int `json:"stable"`
+	// Drift is the smoothed share of stable features missing, 0-1
+	Drift float64 `json:"drift"`
+	// ParserSuspect is set while Drift is at or above the threshold
+	ParserSuspect bool `json:"parser_suspect"`
+	// Missing lists stable features the last response lacked
+	Missing []string `json:"missing,omitempty"`
+}
+
+// schemaBaseline learns an engine's response structure and tracks drift
+// from it. It is guarded by the owning engine's lock.
+type schemaBaseline struct {
+	learned int
+	seen    map[string]int
+	stable  map[string]bool
+	drift   float64
+	suspect bool
+	missing []string
+}
+
+// record adds a response's features. threshold is the drift at which the
+// parser is suspected.
+func (b *schemaBaseline) record(features []string, threshold float64) {
+	if b.stable == nil {
+		if b.seen == nil {
+			b.seen = make(map[string]int)
+		}
+		for _, f := range features {
+			b.seen[f]++
+		}
+		b.learned++
+		if b.learned < driftLearnResponses {
+			return
+		}
+		b.stable = make(map[string]bool)
+		for f, n := range b.seen {
+			if float64(n) >= driftStableShare*float64(b.learned) {
+				b.stable[f] = true
+			}
+		}
+		b.seen = nil
+		return
+	}
+	if len(b.stable) < driftMinStable {
+		return
+	}
+
+	present := make(map[string]bool, len(features))
+	for _, f := range features {
+		present[f] = true
+	}
+	b.missing = b.missing[:0]
+	missing := 0
+	for f := range b.stable {
+		if !present[f] {
+			missing++
+			b.missing = append(b.missing, f)
+		}
+	}
+	sort.Strings(b.missing)
+	if len(b.missing) > maxMissingFeatures {
+		b.missing = b.missing[:maxMissingFeatures]
+	}
+	share := float64(missing) / float64(len(b.stable))
+	b.drift = b.drift*(1-driftSmoothing) + share*driftSmoothing
+	b.suspect = b.drift >= threshold
+}
+
+// status returns the drift report for engine
+func (b *schemaBaseline) status(engine string) SchemaDrift {
+	return SchemaDrift{
+		Engine:        engine,
+		Learned:       b.learned,
+		Learning:      driftLearnResponses,
+		Stable:        len(b.stable),
+		Drift:         math.Round(b.drift*1000) / 1000,
+		ParserSuspect: b.suspect,
+		Missing:       append([]string(nil), b.missing...),
+	}
+}
+
+// RecordResponseShape adds a response's structure to the engine's drift
+// baseline
+func (e *BaseEngine) RecordResponseShape(features []string, threshold float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shape.record(features, threshold)
+}
+
+// SchemaDrift reports how far the engine's responses stray from the
+// structure it learned
+func (e *BaseEngine) SchemaDrift() SchemaDrift {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.shape.status(e.Name())
+}
+
+// ResetSchemaBaseline forgets the learned structure, so it is learned again
+// from the next responses, for example after the parser is fixed
+func (e *BaseEngine) ResetSchemaBaseline() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shape = schemaBaseline{}
+}
+
+// SetSchemaDrift sets the drift at which an engine's parser is suspected,
+// 0-1. 0 turns drift detection off.
+func (a *Aggregator) SetSchemaDrift(threshold float64) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	a.driftThreshold.Store(math.Float64bits(threshold))
+}
+
+// shapeRecorder receives a response's features
+type shapeRecorder func(features []string)
+
+type shapeRecorderKey struct{}
+
+// withShapeRecorder returns ctx recording response structure for engine,
+// unless drift detection is off
+func (a *Aggregator) withShapeRecorder(ctx context.Context, engine Engine) context.Context {
+	threshold := math.Float64frombits(a.driftThreshold.Load())
+	tracker, ok := engine.(interface {
+		RecordResponseShape([]string, float64)
+	})
+	if threshold <= 0 || !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, shapeRecorderKey{}, shapeRecorder(func(features []string) {
+		tracker.RecordResponseShape(features, threshold)
+	}))
+}
+
+// RecordResponseShape fingerprints a successful response for the drift
+// baseline of the engine whose search sent it. The body is buffered and
+// put back, so the engine reads it as usual.
+func RecordResponseShape(resp *http.Response) {
+	if resp.Request == nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return
+	}
+	recorder, ok := resp.Request.Context().Value(shapeRecorderKey{}).(shapeRecorder)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body), resp.Body}
+	if err != nil || len(body) == 0 {
+		return
+	}
+	if features := responseShape(resp.Header.Get("Content-Type"), body); len(features) > 0 {
+		recorder(features)
+	}
+}
+
+// responseShape returns the structural features of a response body: key
+// paths for JSON, tag and class pairs for HTML, element names for XML
+func responseShape(contentType string, body []byte) []string {
+	contentType = strings.ToLower(contentType)
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case strings.Contains(contentType, "json"), len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		var v any
+		if err := json.Unmarshal(trimmed, &v); err != nil {
+			return nil
+		}
+		features := make(map[string]bool)
+		jsonShape(v, "$", 0, features)
+		return sortedFeatures(features)
+	case strings.Contains(contentType, "html"):
+		return markupShape(body, true)
+	case strings.Contains(contentType, "xml"):
+		return markupShape(body, false)
+	}
+	return nil
+}
+
+// jsonShape adds the key paths under v. Array items share a path, and
+// numeric keys, usually IDs, collapse to "#".
+func jsonShape(v any, path string, depth int, features map[string]bool) {
+	if depth > maxShapeDepth || len(features) >= maxShapeFeatures {
+		return
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		for key, child := range t {
+			if key != "" && strings.Trim(key, "0123456789") == "" {
+				key = "#"
+			}
+			p := path + "." + key
+			features[p] = true
+			jsonShape(child, p, depth+1, features)
+		}
+	case []any:
+		// A few items show the item structure
+		for i, child := range t {
+			if i == 3 {
+				break
+			}
+			jsonShape(child, path+"[]", depth+1, features)
+		}
+	}
+}
+
+// markupShape returns the tag.class pairs of an HTML page, or the element
+// names of an XML document
+func markupShape(body []byte, classes bool) []string {
+	features := make(map[string]bool)
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for len(features) < maxShapeFeatures {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		if !classes {
+			features["<"+string(name)+">"] = true
+			continue
+		}
+		for hasAttr {
+			var key, val []byte
+			key, val, hasAttr = z.TagAttr()
+			if string(key) != "class" {
+				continue
+			}
+			for _, class := range strings.Fields(string(val)) {
+				features[string(name)+"."+class] = true
+			}
+		}
+	}
+	return sortedFeatures(features)
+}
+
+func sortedFeatures(features map[string]bool) []string {
+	list := make([]string, 0, len(features))
+	for f := range features {
+		list = append(list, f)
+	}
+	sort.Strings(list)
+	return list
+}
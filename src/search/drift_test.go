@@ -0,0 +1,112 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestResponseShape(t *testing.T) {
+	got := responseShape("application/json", []byte(`{"web":{"results":[{"title":"a","url":"b"},{"title":"c","meta":{"123":1}}]},"query":"x"}`))
+	want := []string{"$.query", "$.web", "$.web.results", "$.web.results[].meta", "$.web.results[].meta.#", "$.web.results[].title", "$.web.results[].url"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON shape = %v, want %v", got, want)
+	}
+
+	got = responseShape("text/html; charset=utf-8", []byte(`<div class="result  web"><a class="title" href="/">x</a><br class="sep"/></div>`))
+	want = []string{"a.title", "br.sep", "div.result", "div.web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HTML shape = %v, want %v", got, want)
+	}
+
+	got = responseShape("application/atom+xml", []byte(`<feed><entry><title>x</title></entry></feed>`))
+	want = []string{"<entry>", "<feed>", "<title>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("XML shape = %v, want %v", got, want)
+	}
+
+	if got := responseShape("text/plain", []byte("hello")); got != nil {
+		t.Errorf("plain text shape = %v", got)
+	}
+}
+
+func TestSchemaDrift(t *testing.T) {
+	eng := newMockEngine("drifty", model.CategoryGeneral, true)
+	usual := []string{"div.result", "a.title", "span.snippet", "div.pager"}
+	for i := 0; i < driftLearnResponses; i++ {
+		features := usual
+		if i%2 == 0 {
+			features = append(features, "div.ad")
+		}
+		eng.RecordResponseShape(features, DefaultDriftThreshold)
+	}
+	if d := eng.SchemaDrift(); d.Learned != driftLearnResponses || d.Stable != len(usual) {
+		t.Fatalf("after learning: %+v", d)
+	}
+
+	// A page without the ads is still the usual page
+	eng.RecordResponseShape(usual, DefaultDriftThreshold)
+	if d := eng.SchemaDrift(); d.Drift != 0 || d.ParserSuspect {
+		t.Errorf("usual page drifted: %+v", d)
+	}
+
+	// One redesigned page is not enough, a run of them is
+	redesign := []string{"div.pager", "div.new-result", "a.new-title"}
+	eng.RecordResponseShape(redesign, DefaultDriftThreshold)
+	if eng.GetHealth().ParserSuspect {
+		t.Error("flagged after one odd page")
+	}
+	for i := 0; i < 10; i++ {
+		eng.RecordResponseShape(redesign, DefaultDriftThreshold)
+	}
+	health := eng.GetHealth()
+	if !health.ParserSuspect || health.SchemaDrift < DefaultDriftThreshold {
+		t.Errorf("health = %+v, want the parser suspected", health)
+	}
+	if d := eng.SchemaDrift(); !reflect.DeepEqual(d.Missing, []string{"a.title", "div.result", "span.snippet"}) {
+		t.Errorf("missing = %v", d.Missing)
+	}
+
+	eng.ResetSchemaBaseline()
+	if d := eng.SchemaDrift(); d.Learned != 0 || d.ParserSuspect || eng.GetHealth().ParserSuspect {
+		t.Errorf("after reset: %+v", d)
+	}
+}
+
+func TestRecordResponseShape(t *testing.T) {
+	eng := newMockEngine("shaped", model.CategoryGeneral, true)
+	agg := NewAggregatorSimple([]Engine{eng}, 0)
+	body := `{"results":[{"title":"x"}]}`
+	respond := func(ctx context.Context, status int) *http.Response {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://engine.example/api", nil)
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+	}
+
+	// Off until a threshold is set
+	RecordResponseShape(respond(agg.withShapeRecorder(context.Background(), eng), http.StatusOK))
+	if d := eng.SchemaDrift(); d.Learned != 0 {
+		t.Errorf("recorded with drift detection off: %+v", d)
+	}
+
+	agg.SetSchemaDrift(DefaultDriftThreshold)
+	ctx := agg.withShapeRecorder(context.Background(), eng)
+	resp := respond(ctx, http.StatusOK)
+	RecordResponseShape(resp)
+	RecordResponseShape(respond(ctx, http.StatusNotFound))
+	if d := eng.SchemaDrift(); d.Learned != 1 {
+		t.Errorf("learned %d responses, want the successful one", d.Learned)
+	}
+	if read, _ := io.ReadAll(resp.Body); string(read) != body {
+		t.Errorf("body after fingerprinting = %q", read)
+	}
+}
@@ -2,6 +2,8 @@ package search
 
 import (
 	"context"
+	"errors"
+	"math"
 	"sync"
 	"time"
 
@@ -32,24 +34,40 @@ type Engine interface {
 	GetConfig() *model.EngineConfig
 }
 
-const (
-	engineFailureThreshold = 3
-	engineCooldownDuration = 10 * time.Minute
-)
-
 // EngineHealth tracks runtime health for an engine.
 type EngineHealth struct {
-	Status              string    `json:"status"`
-	Healthy             bool      `json:"healthy"`
-	LastChecked         time.Time `json:"last_checked,omitempty"`
-	LastSuccess         time.Time `json:"last_success,omitempty"`
-	LastFailure         time.Time `json:"last_failure,omitempty"`
-	LastError           string    `json:"last_error,omitempty"`
-	LastResponseTimeMS  int64     `json:"last_response_time_ms,omitempty"`
-	SuccessCount        int64     `json:"success_count"`
-	FailureCount        int64     `json:"failure_count"`
-	ConsecutiveFailures int       `json:"consecutive_failures"`
-	CooldownUntil       time.Time `json:"cooldown_until,omitempty"`
+	Status              string    `json:"status" xml:"status"`
+	Healthy             bool      `json:"healthy" xml:"healthy"`
+	LastChecked         time.Time `json:"last_checked,omitempty" xml:"last_checked,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty" xml:"last_success,omitempty"`
+	LastFailure         time.Time `json:"last_failure,omitempty" xml:"last_failure,omitempty"`
+	LastError           string    `json:"last_error,omitempty" xml:"last_error,omitempty"`
+	LastResponseTimeMS  int64     `json:"last_response_time_ms,omitempty" xml:"last_response_time_ms,omitempty"`
+	SuccessCount        int64     `json:"success_count" xml:"success_count"`
+	FailureCount        int64     `json:"failure_count" xml:"failure_count"`
+	ConsecutiveFailures int       `json:"consecutive_failures" xml:"consecutive_failures"`
+	CooldownUntil       time.Time `json:"cooldown_until,omitempty" xml:"cooldown_until,omitempty"`
+	// Circuit is closed while searches use the engine, open while they
+	// skip it until CooldownUntil, and half_open once the next search is to
+	// try it again. CircuitOpens counts how often it opened.
+	Circuit      string `json:"circuit" xml:"circuit"`
+	CircuitOpens int64  `json:"circuit_opens" xml:"circuit_opens"`
+	// Over the last 20 calls: how many there were, the share that failed
+	// or were slow, and the mean and 95th percentile time of the
+	// successful ones
+	WindowRequests    int     `json:"window_requests" xml:"window_requests"`
+	ErrorRate         float64 `json:"error_rate" xml:"error_rate"`
+	AvgResponseTimeMS int64   `json:"avg_response_time_ms,omitempty" xml:"avg_response_time_ms,omitempty"`
+	P95ResponseTimeMS int64   `json:"p95_response_time_ms,omitempty" xml:"p95_response_time_ms,omitempty"`
+	// Blocked is the kind of block page the engine last answered with; it
+	// clears on the next success
+	Blocked     string    `json:"blocked,omitempty" xml:"blocked,omitempty"`
+	LastBlocked time.Time `json:"last_blocked,omitempty" xml:"last_blocked,omitempty"`
+	// SchemaDrift is how far recent responses stray from the structure
+	// the engine's first responses had, 0-1; ParserSuspect is set past the
+	// drift threshold, even while requests succeed
+	SchemaDrift   float64 `json:"schema_drift,omitempty" xml:"schema_drift,omitempty"`
+	ParserSuspect bool    `json:"parser_suspect,omitempty" xml:"parser_suspect,omitempty"`
 }
 
 // BaseEngine provides common functionality for engines
@@ -57,6 +75,12 @@ type BaseEngine struct {
 	config *model.EngineConfig
 	mu     sync.RWMutex
 	health EngineHealth
+	shape  schemaBaseline
+	// Circuit breaker settings and state; see breaker.go
+	breaker    CircuitBreaker
+	window     callWindow
+	trips      int
+	trialUntil time.Time
 }
 
 // NewBaseEngine creates a new BaseEngine
@@ -67,6 +91,7 @@ func NewBaseEngine(config *model.EngineConfig) *BaseEngine {
 			Status:  "unknown",
 			Healthy: true,
 		},
+		breaker: DefaultCircuitBreaker,
 	}
 }
 
@@ -108,12 +133,26 @@ func (e *BaseEngine) GetHealth() EngineHealth {
 	return e.healthSnapshotLocked(time.Now())
 }
 
-// CanSearch reports whether the engine should be preferred for live searches.
+// CanSearch reports whether a live search should use the engine: its
+// circuit is closed, or half open with no other search trying it. A yes for
+// a half-open engine makes the caller's search its trial.
 func (e *BaseEngine) CanSearch(now time.Time) bool {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-	return !e.health.CooldownUntil.After(now)
+	if e.breaker.Disabled {
+		return true
+	}
+	switch e.circuitLocked(now) {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if e.trialUntil.After(now) {
+			return false
+		}
+		e.trialUntil = now.Add(circuitTrialTimeout)
+	}
+	return true
 }
 
 // RecordSuccess updates runtime health after a successful request.
@@ -125,12 +164,14 @@ func (e *BaseEngine) RecordSuccess(duration time.Duration) {
 	e.health.LastChecked = now
 	e.health.LastSuccess = now
 	e.health.LastError = ""
+	e.health.Blocked = ""
 	e.health.SuccessCount++
 	e.health.ConsecutiveFailures = 0
-	e.health.CooldownUntil = time.Time{}
 	if duration > 0 {
 		e.health.LastResponseTimeMS = duration.Milliseconds()
 	}
+	slow := e.breaker.SlowCall > 0 && duration >= e.breaker.SlowCall
+	e.recordCallLocked(engineCall{latency: duration, bad: slow, ok: true}, now)
 
 	snapshot := e.healthSnapshotLocked(now)
 	e.health.Status = snapshot.Status
@@ -150,9 +191,12 @@ func (e *BaseEngine) RecordFailure(err error) {
 	if err != nil {
 		e.health.LastError = err.Error()
 	}
-	if e.health.ConsecutiveFailures >= engineFailureThreshold {
-		e.health.CooldownUntil = now.Add(engineCooldownDuration)
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		e.health.Blocked = blocked.Kind
+		e.health.LastBlocked = now
 	}
+	e.recordCallLocked(engineCall{bad: true}, now)
 
 	snapshot := e.healthSnapshotLocked(now)
 	e.health.Status = snapshot.Status
@@ -161,6 +205,17 @@ func (e *BaseEngine) RecordFailure(err error) {
 
 func (e *BaseEngine) healthSnapshotLocked(now time.Time) EngineHealth {
 	snapshot := e.health
+	snapshot.SchemaDrift = math.Round(e.shape.drift*1000) / 1000
+	snapshot.ParserSuspect = e.shape.suspect
+	snapshot.Circuit = e.circuitLocked(now)
+	if e.breaker.Disabled {
+		snapshot.Circuit = CircuitClosed
+	}
+	snapshot.WindowRequests = e.window.count
+	snapshot.ErrorRate = math.Round(e.window.errorRate()*1000) / 1000
+	avg, p95 := e.window.latency()
+	snapshot.AvgResponseTimeMS = avg.Milliseconds()
+	snapshot.P95ResponseTimeMS = p95.Milliseconds()
 
 	switch {
 	case snapshot.CooldownUntil.After(now):
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// archiveOrgPerPage is how many items an Internet Archive search page holds
+const archiveOrgPerPage = 20
+
+// ArchiveOrg searches the public domain and freely licensed items of the
+// Internet Archive. Every item can be downloaded by torrent, which the
+// Archive seeds itself, so results carry the torrent but no peer counts.
+type ArchiveOrg struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewArchiveOrg creates a new Internet Archive engine
+func NewArchiveOrg() *ArchiveOrg {
+	config := model.NewEngineConfig("archiveorg")
+	config.DisplayName = "Internet Archive"
+	config.Priority = 60
+	config.Categories = []string{"files"}
+	config.SupportsTor = true
+
+	return &ArchiveOrg{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs an Internet Archive advanced search
+func (e *ArchiveOrg) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("q", query.Text)
+	for _, field := range []string{"identifier", "title", "description", "item_size", "publicdate", "creator"} {
+		params.Add("fl[]", field)
+	}
+	params.Set("rows", strconv.Itoa(archiveOrgPerPage))
+	params.Set("page", strconv.Itoa(pageNumber(query)))
+	params.Set("output", "json")
+	reqURL := fmt.Sprintf("https://archive.org/advancedsearch.php?%s", params.Encode())
+
+	// title, description and creator are a string or a list of strings
+	var data struct {
+		Response struct {
+			Docs []struct {
+				Identifier  string          `json:"identifier"`
+				Title       json.RawMessage `json:"title"`
+				Description json.RawMessage `json:"description"`
+				Creator     json.RawMessage `json:"creator"`
+				ItemSize    int64           `json:"item_size"`
+				PublicDate  string          `json:"publicdate"`
+			} `json:"docs"`
+		} `json:"response"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("archive.org: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.Response.Docs))
+	for i, doc := range data.Response.Docs {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		title := archiveOrgText(doc.Title)
+		if doc.Identifier == "" || title == "" {
+			continue
+		}
+		id := url.PathEscape(doc.Identifier)
+		info := fileInfo{
+			Name:        title,
+			URL:         "https://archive.org/details/" + id,
+			Description: model.StripHTML(archiveOrgText(doc.Description)),
+			Size:        doc.ItemSize,
+			Torrent:     fmt.Sprintf("https://archive.org/download/%s/%s_archive.torrent", id, id),
+			Uploader:    archiveOrgText(doc.Creator),
+		}
+		if published, err := time.Parse(time.RFC3339, doc.PublicDate); err == nil {
+			info.AddedAt = published.UTC()
+		}
+		results = append(results, fileResult(e.Name(), e.GetPriority(), i, info))
+	}
+
+	return results, nil
+}
+
+// archiveOrgText reads a metadata field that is a string or a list of
+// strings, joining a list with "; "
+func archiveOrgText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.TrimSpace(strings.Join(list, "; "))
+	}
+	return ""
+}
@@ -91,7 +91,7 @@ func (e *ArXiv) Search(ctx context.Context, query *model.Query) ([]model.Result,
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/atom+xml")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
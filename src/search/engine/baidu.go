@@ -92,7 +92,7 @@ func (e *Baidu) Search(ctx context.Context, query *model.Query) ([]model.Result,
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.Header.Set("DNT", "1")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +28,7 @@ func NewBing() *BingEngine {
 	config.Priority = 80
 	config.Categories = []string{"general", "images", "news", "videos", "files", "music"}
 	config.SupportsTor = false
+	config.SupportsSafeSearch = true
 
 	client := &http.Client{
 		Timeout:   time.Duration(config.GetTimeout()) * time.Second,
@@ -60,7 +61,7 @@ func (e *BingEngine) Search(ctx context.Context, query *model.Query) ([]model.Re
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 
 	// Perform request
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -80,10 +81,23 @@ func (e *BingEngine) buildURL(query *model.Query) string {
 	params := url.Values{}
 	params.Set("q", query.Text)
 	params.Set("first", fmt.Sprintf("%d", (query.Page-1)*10+1))
+	params.Set("adlt", bingSafeSearch(query.SafeSearch))
 
 	return fmt.Sprintf("https://www.bing.com/search?%s", params.Encode())
 }
 
+// bingSafeSearch returns Bing's adlt value for a safe search level
+func bingSafeSearch(level int) string {
+	switch level {
+	case 0:
+		return "off"
+	case 2:
+		return "strict"
+	default:
+		return "moderate"
+	}
+}
+
 func (e *BingEngine) parseResults(html string, query *model.Query) ([]model.Result, error) {
 	results := make([]model.Result, 0)
 
@@ -146,12 +160,13 @@ func (e *BingEngine) parseResults(html string, query *model.Query) ([]model.Resu
 		score := float64(1000 - i*10)
 
 		result := model.Result{
-			Title:    title,
-			URL:      resultURL,
-			Content:  content,
-			Engine:   e.Name(),
-			Category: query.Category,
-			Score:    score,
+			Title:      title,
+			URL:        resultURL,
+			Content:    content,
+			Engine:     e.Name(),
+			Category:   query.Category,
+			Score:      score,
+			Structured: search.ExtractStructuredData(resultHTML),
 		}
 
 		results = append(results, result)
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// bingNewsPerPage is how many articles a Bing News feed page holds
+const bingNewsPerPage = 20
+
+// bingNewsIntervals map time ranges to Bing News intervals. Bing has no
+// past-year interval; the aggregator filters those by date.
+var bingNewsIntervals = map[string]string{
+	"day":   "7",
+	"week":  "8",
+	"month": "9",
+}
+
+// BingNews searches news through the RSS feed of Bing News, which names
+// each article's publisher and date
+type BingNews struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewBingNews creates a new Bing News engine
+func NewBingNews() *BingNews {
+	config := model.NewEngineConfig("bingnews")
+	config.DisplayName = "Bing News"
+	config.Priority = 70
+	config.Categories = []string{"news"}
+	config.SupportsTor = false
+
+	return &BingNews{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// bingNewsFeed is the RSS feed of a Bing News search
+type bingNewsFeed struct {
+	Items []struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		PubDate     string `xml:"pubDate"`
+		// News:Source and News:Image
+		Source string `xml:"Source"`
+		Image  string `xml:"Image"`
+	} `xml:"channel>item"`
+}
+
+// Search performs a Bing News search
+func (e *BingNews) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("q", query.Text)
+	params.Set("format", "rss")
+	params.Set("count", strconv.Itoa(bingNewsPerPage))
+	params.Set("first", strconv.Itoa(pageOffset(query, bingNewsPerPage)+1))
+	var filters []string
+	if interval := bingNewsIntervals[query.TimeRange]; interval != "" {
+		filters = append(filters, `interval="`+interval+`"`)
+	}
+	if query.SortBy == model.SortDate {
+		filters = append(filters, `sortbydate="1"`)
+	}
+	if len(filters) > 0 {
+		params.Set("qft", strings.Join(filters, " "))
+	}
+	if query.Language != "" {
+		params.Set("setlang", query.Language)
+	}
+
+	reqURL := fmt.Sprintf("https://www.bing.com/news/search?%s", params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/xml;q=0.9")
+
+	resp, err := doRequest(e.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Bing News returned status %d", resp.StatusCode)
+	}
+
+	var feed bingNewsFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse Bing News feed: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(feed.Items))
+	for i, item := range feed.Items {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		link := bingNewsArticleURL(item.Link)
+		if link == "" || item.Title == "" {
+			continue
+		}
+		result := model.Result{
+			Title:     model.StripHTML(item.Title),
+			URL:       link,
+			Content:   model.StripHTML(item.Description),
+			Thumbnail: item.Image,
+			Author:    strings.TrimSpace(item.Source),
+			Engine:    e.Name(),
+			Category:  model.CategoryNews,
+			Score:     calculateScore(e.GetPriority(), i, 1),
+			Position:  i,
+		}
+		if published, err := time.Parse(time.RFC1123, item.PubDate); err == nil {
+			result.PublishedAt = published.UTC()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// bingNewsArticleURL returns the article a feed link points to. Feed links
+// go through Bing's click counter, which carries the article in its url
+// parameter; linking to the article directly keeps Bing from seeing the
+// click.
+func bingNewsArticleURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	if strings.HasSuffix(u.Hostname(), "bing.com") {
+		target := u.Query().Get("url")
+		if t, err := url.Parse(target); err == nil && (t.Scheme == "http" || t.Scheme == "https") && t.Host != "" {
+			return target
+		}
+		return ""
+	}
+	return u.String()
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,7 @@ func NewBrave() *Brave {
 	config.Priority = 75
 	config.Categories = []string{"general", "images", "news", "files", "music"}
 	config.SupportsTor = true
+	config.SupportsSafeSearch = true
 
 	return &Brave{
 		BaseEngine: search.NewBaseEngine(config),
@@ -44,6 +46,11 @@ func (e *Brave) Search(ctx context.Context, query *model.Query) ([]model.Result,
 	params := url.Values{}
 	params.Set("q", query.Text)
 	params.Set("source", "web")
+	params.Set("safesearch", braveSafeSearch(query.SafeSearch))
+	// Brave pages by page index from 0
+	if page := pageNumber(query); page > 1 {
+		params.Set("offset", strconv.Itoa(page-1))
+	}
 
 	if query.Category == model.CategoryImages {
 		searchURL = "https://search.brave.com/images"
@@ -62,7 +69,7 @@ func (e *Brave) Search(ctx context.Context, query *model.Query) ([]model.Result,
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -148,3 +155,15 @@ func unescapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "&nbsp;", " ")
 	return s
 }
+
+// braveSafeSearch returns Brave's safesearch value for a safe search level
+func braveSafeSearch(level int) string {
+	switch level {
+	case 0:
+		return "off"
+	case 2:
+		return "strict"
+	default:
+		return "moderate"
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// Crates implements crates.io (Rust) registry search
+type Crates struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewCrates creates a new crates.io engine
+func NewCrates() *Crates {
+	config := model.NewEngineConfig("crates")
+	config.DisplayName = "crates.io"
+	config.Priority = 56
+	config.Categories = []string{"packages"}
+	config.SupportsTor = true
+
+	return &Crates{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs a crates.io search
+func (e *Crates) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+
+	params := url.Values{}
+	params.Set("q", query.Text)
+	params.Set("per_page", "10")
+	params.Set("page", strconv.Itoa(page))
+
+	reqURL := fmt.Sprintf("https://crates.io/api/v1/crates?%s", params.Encode())
+
+	var data struct {
+		Crates []struct {
+			Name          string `json:"name"`
+			MaxVersion    string `json:"max_version"`
+			MaxStable     string `json:"max_stable_version"`
+			Description   string `json:"description"`
+			Downloads     int64  `json:"downloads"`
+			Repository    string `json:"repository"`
+			Homepage      string `json:"homepage"`
+			Documentation string `json:"documentation"`
+			UpdatedAt     string `json:"updated_at"`
+		} `json:"crates"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("crates.io: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.Crates))
+	for i, c := range data.Crates {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+
+		version := c.MaxStable
+		if version == "" {
+			version = c.MaxVersion
+		}
+
+		repo := c.Repository
+		if repo == "" {
+			repo = c.Homepage
+		}
+
+		var updated time.Time
+		if c.UpdatedAt != "" {
+			if parsed, err := time.Parse(time.RFC3339, c.UpdatedAt); err == nil {
+				updated = parsed
+			}
+		}
+
+		results = append(results, packageResult(e.Name(), "crates", e.GetPriority(), i, packageInfo{
+			Name:        c.Name,
+			Version:     version,
+			Description: c.Description,
+			Downloads:   c.Downloads,
+			Repository:  repo,
+			URL:         "https://crates.io/crates/" + url.PathEscape(c.Name),
+			UpdatedAt:   updated,
+		}))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,416 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+var (
+	// cveIDPattern matches a CVE identifier anywhere in the query
+	cveIDPattern = regexp.MustCompile(`(?i)\bCVE-(\d{4})-(\d{4,})\b`)
+	// cveKeywordPrefix matches "vulnerability nginx", "cve for openssl", etc.
+	cveKeywordPrefix = regexp.MustCompile(`(?i)^(?:vulnerabilit(?:y|ies)|vulns?|cves?|security advisor(?:y|ies))\s+(?:for\s+|in\s+)?(.+)$`)
+	// cveKeywordSuffix matches "nginx vulnerability", "openssl cves", etc.
+	cveKeywordSuffix = regexp.MustCompile(`(?i)^(.+?)\s+(?:vulnerabilit(?:y|ies)|vulns?|cves?|security advisor(?:y|ies))$`)
+)
+
+// CVE implements a vulnerability search engine backed by the NVD CVE API,
+// with OSV.dev as a fallback for exact CVE IDs. It only answers queries that
+// contain a CVE ID or ask for vulnerabilities in a product; every other query
+// returns no results without any outbound request.
+type CVE struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewCVE creates a new CVE/security advisory engine
+func NewCVE() *CVE {
+	config := model.NewEngineConfig("cve")
+	config.DisplayName = "CVE (NVD/OSV)"
+	config.Priority = 65
+	config.Categories = []string{"general", "it"}
+	config.SupportsTor = true
+
+	return &CVE{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// parseCVEQuery returns the normalized CVE ID or the product keyword for a
+// vulnerability query. Both are empty when the query is not security-related.
+func parseCVEQuery(text string) (cveID, keyword string) {
+	text = strings.TrimSpace(text)
+	if m := cveIDPattern.FindStringSubmatch(text); m != nil {
+		return fmt.Sprintf("CVE-%s-%s", m[1], m[2]), ""
+	}
+	if m := cveKeywordPrefix.FindStringSubmatch(text); m != nil {
+		return "", strings.TrimSpace(m[1])
+	}
+	if m := cveKeywordSuffix.FindStringSubmatch(text); m != nil {
+		return "", strings.TrimSpace(m[1])
+	}
+	return "", ""
+}
+
+// Search looks up CVE IDs or product vulnerabilities
+func (e *CVE) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	cveID, keyword := parseCVEQuery(query.Text)
+
+	switch {
+	case cveID != "":
+		if query.Page > 1 {
+			return []model.Result{}, nil
+		}
+		results, err := e.searchNVD(ctx, url.Values{"cveId": {cveID}})
+		if err == nil && len(results) > 0 {
+			return results, nil
+		}
+		// NVD is heavily rate limited without an API key; OSV mirrors CVE records
+		osvResults, osvErr := e.searchOSV(ctx, cveID)
+		if osvErr == nil {
+			return osvResults, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []model.Result{}, nil
+	case keyword != "":
+		page := query.Page
+		if page < 1 {
+			page = 1
+		}
+		params := url.Values{}
+		params.Set("keywordSearch", keyword)
+		params.Set("resultsPerPage", "20")
+		params.Set("startIndex", strconv.Itoa((page-1)*20))
+		return e.searchNVD(ctx, params)
+	default:
+		return []model.Result{}, nil
+	}
+}
+
+// nvdCVEResponse is the subset of the NVD CVE API 2.0 response we use
+type nvdCVEResponse struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string `json:"id"`
+			Published    string `json:"published"`
+			LastModified string `json:"lastModified"`
+			Descriptions []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"descriptions"`
+			Metrics struct {
+				CVSSMetricV31 []nvdCVSSMetric `json:"cvssMetricV31"`
+				CVSSMetricV30 []nvdCVSSMetric `json:"cvssMetricV30"`
+				CVSSMetricV2  []struct {
+					CVSSData struct {
+						VectorString string  `json:"vectorString"`
+						BaseScore    float64 `json:"baseScore"`
+					} `json:"cvssData"`
+					BaseSeverity string `json:"baseSeverity"`
+				} `json:"cvssMetricV2"`
+			} `json:"metrics"`
+			Configurations []struct {
+				Nodes []struct {
+					CPEMatch []nvdCPEMatch `json:"cpeMatch"`
+				} `json:"nodes"`
+			} `json:"configurations"`
+			References []struct {
+				URL string `json:"url"`
+			} `json:"references"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+type nvdCVSSMetric struct {
+	CVSSData struct {
+		Version      string  `json:"version"`
+		VectorString string  `json:"vectorString"`
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+}
+
+type nvdCPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+// cveRecord is the source-neutral form of a vulnerability before it becomes a result
+type cveRecord struct {
+	ID          string
+	Description string
+	Score       float64
+	Severity    string
+	Vector      string
+	Affected    []string
+	References  []string
+	Published   time.Time
+	URL         string
+	Source      string
+}
+
+func (e *CVE) searchNVD(ctx context.Context, params url.Values) ([]model.Result, error) {
+	reqURL := "https://services.nvd.nist.gov/rest/json/cves/2.0?" + params.Encode()
+
+	var data nvdCVEResponse
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("nvd: %w", err)
+	}
+
+	records := make([]cveRecord, 0, len(data.Vulnerabilities))
+	for _, v := range data.Vulnerabilities {
+		c := v.CVE
+		rec := cveRecord{
+			ID:     c.ID,
+			URL:    "https://nvd.nist.gov/vuln/detail/" + c.ID,
+			Source: "nvd",
+		}
+		for _, d := range c.Descriptions {
+			if d.Lang == "en" {
+				rec.Description = d.Value
+				break
+			}
+		}
+
+		metrics := append(c.Metrics.CVSSMetricV31, c.Metrics.CVSSMetricV30...)
+		if len(metrics) > 0 {
+			rec.Score = metrics[0].CVSSData.BaseScore
+			rec.Severity = metrics[0].CVSSData.BaseSeverity
+			rec.Vector = metrics[0].CVSSData.VectorString
+		} else if len(c.Metrics.CVSSMetricV2) > 0 {
+			rec.Score = c.Metrics.CVSSMetricV2[0].CVSSData.BaseScore
+			rec.Severity = c.Metrics.CVSSMetricV2[0].BaseSeverity
+			rec.Vector = c.Metrics.CVSSMetricV2[0].CVSSData.VectorString
+		}
+
+		seen := make(map[string]bool)
+		for _, cfg := range c.Configurations {
+			for _, node := range cfg.Nodes {
+				for _, m := range node.CPEMatch {
+					if !m.Vulnerable {
+						continue
+					}
+					if affected := formatCPEMatch(m); affected != "" && !seen[affected] {
+						seen[affected] = true
+						rec.Affected = append(rec.Affected, affected)
+					}
+				}
+			}
+		}
+
+		for _, ref := range c.References {
+			rec.References = append(rec.References, ref.URL)
+		}
+		if t, err := time.Parse("2006-01-02T15:04:05.000", c.Published); err == nil {
+			rec.Published = t
+		}
+
+		records = append(records, rec)
+	}
+
+	// NVD returns keyword matches oldest-first; newest advisories are more useful
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Published.After(records[j].Published)
+	})
+
+	return e.toResults(records), nil
+}
+
+// formatCPEMatch turns an NVD CPE match into "vendor product >= a, < b"
+func formatCPEMatch(m nvdCPEMatch) string {
+	// cpe:2.3:part:vendor:product:version:...
+	parts := strings.Split(m.Criteria, ":")
+	if len(parts) < 6 {
+		return ""
+	}
+	name := strings.ReplaceAll(parts[3]+" "+parts[4], "_", " ")
+
+	var bounds []string
+	if m.VersionStartIncluding != "" {
+		bounds = append(bounds, ">= "+m.VersionStartIncluding)
+	}
+	if m.VersionStartExcluding != "" {
+		bounds = append(bounds, "> "+m.VersionStartExcluding)
+	}
+	if m.VersionEndIncluding != "" {
+		bounds = append(bounds, "<= "+m.VersionEndIncluding)
+	}
+	if m.VersionEndExcluding != "" {
+		bounds = append(bounds, "< "+m.VersionEndExcluding)
+	}
+	if len(bounds) > 0 {
+		return name + " " + strings.Join(bounds, ", ")
+	}
+	if v := parts[5]; v != "*" && v != "-" {
+		return name + " " + v
+	}
+	return name
+}
+
+func (e *CVE) searchOSV(ctx context.Context, cveID string) ([]model.Result, error) {
+	reqURL := "https://api.osv.dev/v1/vulns/" + url.PathEscape(cveID)
+
+	var data struct {
+		ID        string `json:"id"`
+		Summary   string `json:"summary"`
+		Details   string `json:"details"`
+		Published string `json:"published"`
+		Severity  []struct {
+			Type  string `json:"type"`
+			Score string `json:"score"`
+		} `json:"severity"`
+		Affected []struct {
+			Package struct {
+				Ecosystem string `json:"ecosystem"`
+				Name      string `json:"name"`
+			} `json:"package"`
+			Ranges []struct {
+				Events []map[string]string `json:"events"`
+			} `json:"ranges"`
+		} `json:"affected"`
+		References []struct {
+			URL string `json:"url"`
+		} `json:"references"`
+	}
+
+	found, err := fetchRegistryJSON(ctx, e.client, reqURL, &data)
+	if err != nil {
+		return nil, fmt.Errorf("osv: %w", err)
+	}
+	if !found || data.ID == "" {
+		return []model.Result{}, nil
+	}
+
+	rec := cveRecord{
+		ID:          data.ID,
+		Description: data.Summary,
+		URL:         "https://osv.dev/vulnerability/" + data.ID,
+		Source:      "osv",
+	}
+	if rec.Description == "" {
+		rec.Description = data.Details
+	}
+	for _, s := range data.Severity {
+		if strings.HasPrefix(s.Type, "CVSS") {
+			rec.Vector = s.Score
+			break
+		}
+	}
+	for _, a := range data.Affected {
+		name := strings.TrimSpace(a.Package.Ecosystem + " " + a.Package.Name)
+		if name == "" {
+			continue
+		}
+		for _, r := range a.Ranges {
+			var bounds []string
+			for _, ev := range r.Events {
+				if v := ev["introduced"]; v != "" && v != "0" {
+					bounds = append(bounds, ">= "+v)
+				}
+				if v := ev["fixed"]; v != "" {
+					bounds = append(bounds, "< "+v)
+				}
+				if v := ev["last_affected"]; v != "" {
+					bounds = append(bounds, "<= "+v)
+				}
+			}
+			if len(bounds) > 0 {
+				rec.Affected = append(rec.Affected, name+" "+strings.Join(bounds, ", "))
+			}
+		}
+		if len(a.Ranges) == 0 {
+			rec.Affected = append(rec.Affected, name)
+		}
+	}
+	for _, ref := range data.References {
+		rec.References = append(rec.References, ref.URL)
+	}
+	if t, err := time.Parse(time.RFC3339, data.Published); err == nil {
+		rec.Published = t
+	}
+
+	return e.toResults([]cveRecord{rec}), nil
+}
+
+// toResults converts vulnerability records into search results. CVSS score,
+// severity, affected versions, and references are exposed through Metadata.
+func (e *CVE) toResults(records []cveRecord) []model.Result {
+	results := make([]model.Result, 0, len(records))
+	for i, rec := range records {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+
+		title := rec.ID
+		if rec.Score > 0 {
+			title = fmt.Sprintf("%s (CVSS %.1f %s)", rec.ID, rec.Score, strings.ToUpper(rec.Severity))
+		}
+
+		content := rec.Description
+		if len(content) > 400 {
+			content = content[:397] + "..."
+		}
+		if len(rec.Affected) > 0 {
+			shown := rec.Affected
+			if len(shown) > 3 {
+				shown = shown[:3]
+			}
+			content = fmt.Sprintf("%s Affected: %s", content, strings.Join(shown, "; "))
+			if len(rec.Affected) > 3 {
+				content += fmt.Sprintf(" (+%d more)", len(rec.Affected)-3)
+			}
+		}
+
+		metadata := map[string]interface{}{
+			"cve_id": rec.ID,
+			"source": rec.Source,
+		}
+		if rec.Score > 0 {
+			metadata["cvss_score"] = rec.Score
+			metadata["severity"] = strings.ToUpper(rec.Severity)
+		}
+		if rec.Vector != "" {
+			metadata["cvss_vector"] = rec.Vector
+		}
+		if len(rec.Affected) > 0 {
+			metadata["affected_versions"] = rec.Affected
+		}
+		if len(rec.References) > 0 {
+			metadata["references"] = rec.References
+		}
+
+		results = append(results, model.Result{
+			Title:       title,
+			URL:         rec.URL,
+			Content:     strings.TrimSpace(content),
+			Engine:      e.Name(),
+			Category:    model.CategoryIT,
+			PublishedAt: rec.Published,
+			// Critical advisories float above low-severity ones from the same engine
+			Score:    calculateScore(e.GetPriority(), i, 1) + rec.Score*10,
+			Position: i,
+			Metadata: metadata,
+		})
+	}
+	return results
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestParseCVEQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantID      string
+		wantKeyword string
+	}{
+		{"CVE-2021-44228", "CVE-2021-44228", ""},
+		{"what is cve-2021-44228", "CVE-2021-44228", ""},
+		{"vulnerability nginx", "", "nginx"},
+		{"vulnerabilities in openssl", "", "openssl"},
+		{"cve for log4j", "", "log4j"},
+		{"apache struts vulnerability", "", "apache struts"},
+		{"openssh vulns", "", "openssh"},
+		{"golang tutorial", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		id, keyword := parseCVEQuery(tt.query)
+		if id != tt.wantID || keyword != tt.wantKeyword {
+			t.Errorf("parseCVEQuery(%q) = (%q, %q), want (%q, %q)", tt.query, id, keyword, tt.wantID, tt.wantKeyword)
+		}
+	}
+}
+
+func TestFormatCPEMatch(t *testing.T) {
+	tests := []struct {
+		match nvdCPEMatch
+		want  string
+	}{
+		{nvdCPEMatch{Criteria: "cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*", VersionStartIncluding: "2.0.1", VersionEndExcluding: "2.15.0"}, "apache log4j >= 2.0.1, < 2.15.0"},
+		{nvdCPEMatch{Criteria: "cpe:2.3:a:f5:nginx:1.20.0:*:*:*:*:*:*:*"}, "f5 nginx 1.20.0"},
+		{nvdCPEMatch{Criteria: "cpe:2.3:o:linux:linux_kernel:*:*:*:*:*:*:*:*", VersionEndIncluding: "5.15"}, "linux linux kernel <= 5.15"},
+		{nvdCPEMatch{Criteria: "invalid"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := formatCPEMatch(tt.match); got != tt.want {
+			t.Errorf("formatCPEMatch(%q) = %q, want %q", tt.match.Criteria, got, tt.want)
+		}
+	}
+}
+
+const testNVDPayload = `{"vulnerabilities":[{"cve":{"id":"CVE-2021-44228",
+	"published":"2021-12-10T10:15:09.143",
+	"descriptions":[{"lang":"en","value":"Apache Log4j2 JNDI features do not protect against attacker controlled LDAP."}],
+	"metrics":{"cvssMetricV31":[{"cvssData":{"version":"3.1","vectorString":"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H","baseScore":10.0,"baseSeverity":"CRITICAL"}}]},
+	"configurations":[{"nodes":[{"cpeMatch":[{"vulnerable":true,"criteria":"cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*","versionStartIncluding":"2.0.1","versionEndExcluding":"2.3.1"}]}]}],
+	"references":[{"url":"https://logging.apache.org/log4j/2.x/security.html"}]}}]}`
+
+func TestCVESearchByID(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		fmt.Fprint(w, testNVDPayload)
+	}))
+	defer server.Close()
+
+	engine := NewCVE()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "cve-2021-44228", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if gotQuery != "cveId=CVE-2021-44228" {
+		t.Errorf("request query = %q, want normalized cveId", gotQuery)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.URL != "https://nvd.nist.gov/vuln/detail/CVE-2021-44228" {
+		t.Errorf("URL = %q", r.URL)
+	}
+	if r.Metadata["cvss_score"] != 10.0 || r.Metadata["severity"] != "CRITICAL" {
+		t.Errorf("Metadata = %v, want CVSS 10.0 CRITICAL", r.Metadata)
+	}
+	affected, _ := r.Metadata["affected_versions"].([]string)
+	if len(affected) != 1 || affected[0] != "apache log4j >= 2.0.1, < 2.3.1" {
+		t.Errorf("affected_versions = %v", r.Metadata["affected_versions"])
+	}
+	if refs, _ := r.Metadata["references"].([]string); len(refs) != 1 {
+		t.Errorf("references = %v", r.Metadata["references"])
+	}
+	if r.PublishedAt.IsZero() {
+		t.Error("PublishedAt should be parsed")
+	}
+}
+
+func TestCVESearchFallsBackToOSV(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/rest/") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"id":"CVE-2021-44228","summary":"Log4Shell","published":"2021-12-10T00:00:00Z",
+			"severity":[{"type":"CVSS_V3","score":"CVSS:3.1/AV:N/AC:L"}],
+			"affected":[{"package":{"ecosystem":"Maven","name":"org.apache.logging.log4j:log4j-core"},
+			"ranges":[{"type":"ECOSYSTEM","events":[{"introduced":"2.0-beta9"},{"fixed":"2.15.0"}]}]}],
+			"references":[{"type":"WEB","url":"https://example.com/advisory"}]}`)
+	}))
+	defer server.Close()
+
+	engine := NewCVE()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "CVE-2021-44228", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	if results[0].Metadata["source"] != "osv" {
+		t.Errorf("source = %v, want osv", results[0].Metadata["source"])
+	}
+	affected, _ := results[0].Metadata["affected_versions"].([]string)
+	if len(affected) != 1 || affected[0] != "Maven org.apache.logging.log4j:log4j-core >= 2.0-beta9, < 2.15.0" {
+		t.Errorf("affected_versions = %v", results[0].Metadata["affected_versions"])
+	}
+}
+
+func TestCVESearchByKeyword(t *testing.T) {
+	var gotKeyword string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKeyword = r.URL.Query().Get("keywordSearch")
+		fmt.Fprint(w, testNVDPayload)
+	}))
+	defer server.Close()
+
+	engine := NewCVE()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "log4j vulnerability", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if gotKeyword != "log4j" {
+		t.Errorf("keywordSearch = %q, want log4j", gotKeyword)
+	}
+	if len(results) != 1 || results[0].Category != model.CategoryIT {
+		t.Errorf("Search() = %v, want one IT result", results)
+	}
+}
+
+func TestCVESkipsUnrelatedQuery(t *testing.T) {
+	engine := NewCVE()
+	engine.client = &http.Client{Transport: redirectToServer("http://127.0.0.1:1")}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "weather tomorrow", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Search() returned %d results for unrelated query, want 0", len(results))
+	}
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// dailymotionFields are the video fields asked of the Dailymotion API
+const dailymotionFields = "id,title,description,url,duration,thumbnail_360_url,created_time,views_total,owner.screenname,owner.url"
+
+// Dailymotion implements Dailymotion video search through its public API,
+// which needs no key
+type Dailymotion struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewDailymotion creates a new Dailymotion engine
+func NewDailymotion() *Dailymotion {
+	config := model.NewEngineConfig("dailymotion")
+	config.DisplayName = "Dailymotion"
+	config.Priority = 50
+	config.Categories = []string{"videos"}
+	config.SupportsTor = true
+	config.SupportsSafeSearch = true
+	config.SupportsVideoFilters = true
+
+	return &Dailymotion{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs a Dailymotion video search
+func (e *Dailymotion) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("search", query.Text)
+	params.Set("fields", dailymotionFields)
+	params.Set("limit", "20")
+	params.Set("page", strconv.Itoa(pageNumber(query)))
+	params.Set("family_filter", strconv.FormatBool(query.SafeSearch > 0))
+	// Lengths are in minutes; the aggregator drops the videos a bound
+	// lets through at its edge
+	switch query.VideoLength {
+	case "short":
+		params.Set("shorter_than", "4")
+	case "medium":
+		params.Set("longer_than", "4")
+		params.Set("shorter_than", "20")
+	case "long":
+		params.Set("longer_than", "20")
+	}
+	if query.VideoQuality == "hd" {
+		params.Set("flags", "hd")
+	}
+
+	reqURL := fmt.Sprintf("https://api.dailymotion.com/videos?%s", params.Encode())
+
+	var data struct {
+		List []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			URL         string `json:"url"`
+			Duration    int    `json:"duration"`
+			Thumbnail   string `json:"thumbnail_360_url"`
+			// Unix seconds
+			CreatedTime int64  `json:"created_time"`
+			Views       int64  `json:"views_total"`
+			Owner       string `json:"owner.screenname"`
+			OwnerURL    string `json:"owner.url"`
+		} `json:"list"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("dailymotion: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.List))
+	for i, video := range data.List {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		if video.URL == "" || video.Title == "" {
+			continue
+		}
+		info := videoInfo{
+			Title:       video.Title,
+			URL:         video.URL,
+			Description: video.Description,
+			Thumbnail:   video.Thumbnail,
+			Duration:    video.Duration,
+			Channel:     video.Owner,
+			ChannelURL:  video.OwnerURL,
+			Views:       video.Views,
+		}
+		if video.CreatedTime > 0 {
+			info.PublishedAt = time.Unix(video.CreatedTime, 0).UTC()
+		}
+		results = append(results, videoResult(e.Name(), e.GetPriority(), i, info))
+	}
+
+	return results, nil
+}
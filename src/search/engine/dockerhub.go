@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// DockerHub implements Docker Hub image repository search
+type DockerHub struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewDockerHub creates a new Docker Hub engine
+func NewDockerHub() *DockerHub {
+	config := model.NewEngineConfig("dockerhub")
+	config.DisplayName = "Docker Hub"
+	config.Priority = 52
+	config.Categories = []string{"packages"}
+	config.SupportsTor = true
+
+	return &DockerHub{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs a Docker Hub repository search
+func (e *DockerHub) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+
+	params := url.Values{}
+	params.Set("query", query.Text)
+	params.Set("page_size", "10")
+	params.Set("page", strconv.Itoa(page))
+
+	reqURL := fmt.Sprintf("https://hub.docker.com/v2/search/repositories/?%s", params.Encode())
+
+	var data struct {
+		Results []struct {
+			RepoName         string `json:"repo_name"`
+			ShortDescription string `json:"short_description"`
+			StarCount        int64  `json:"star_count"`
+			PullCount        int64  `json:"pull_count"`
+			RepoOwner        string `json:"repo_owner"`
+			IsOfficial       bool   `json:"is_official"`
+		} `json:"results"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("docker hub: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.Results))
+	for i, repo := range data.Results {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+
+		// Official images live under the "library" namespace and use /_/ URLs
+		pageURL := "https://hub.docker.com/r/" + repo.RepoName
+		author := repo.RepoOwner
+		if repo.IsOfficial || !strings.Contains(repo.RepoName, "/") {
+			pageURL = "https://hub.docker.com/_/" + strings.TrimPrefix(repo.RepoName, "library/")
+			author = "Docker Official Image"
+		} else if author == "" {
+			author, _, _ = strings.Cut(repo.RepoName, "/")
+		}
+
+		result := packageResult(e.Name(), "docker", e.GetPriority(), i, packageInfo{
+			Name:        repo.RepoName,
+			Description: repo.ShortDescription,
+			Downloads:   repo.PullCount,
+			URL:         pageURL,
+			Author:      author,
+		})
+		if repo.StarCount > 0 {
+			result.Metadata["stars"] = repo.StarCount
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
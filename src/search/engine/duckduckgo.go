@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,6 +29,9 @@ func NewDuckDuckGo() *DuckDuckGo {
 	config.Priority = 100
 	config.Categories = []string{"general", "images", "videos", "news", "files", "music"}
 	config.SupportsTor = true
+	config.SupportsSafeSearch = true
+	config.SupportsImageFilters = true
+	config.SupportsVideoFilters = true
 
 	return &DuckDuckGo{
 		BaseEngine: search.NewBaseEngine(config),
@@ -60,6 +64,13 @@ func (e *DuckDuckGo) searchGeneral(ctx context.Context, query *model.Query) ([]m
 	form.Set("b", "")
 	form.Set("kl", "us-en")
 	form.Set("df", "")
+	// The HTML endpoint shows 10 results first and 15 a page after that,
+	// paged by the offset s of the first result
+	if page := pageNumber(query); page > 1 {
+		offset := 10 + (page-2)*15
+		form.Set("s", strconv.Itoa(offset))
+		form.Set("dc", strconv.Itoa(offset+1))
+	}
 
 	switch query.SafeSearch {
 	case 0:
@@ -90,7 +101,7 @@ func (e *DuckDuckGo) searchGeneral(ctx context.Context, query *model.Query) ([]m
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +124,7 @@ func (e *DuckDuckGo) searchGeneral(ctx context.Context, query *model.Query) ([]m
 	// Treating them as errors lets the circuit-breaker pause DDG and surface
 	// other engines (Brave, Startpage, Mojeek) instead of silently returning 0 results.
 	if isDDGBotChallenge(bodyStr) {
-		return nil, fmt.Errorf("duckduckgo bot challenge detected")
+		return nil, &search.BlockedError{Kind: search.BlockCaptcha, Status: resp.StatusCode}
 	}
 
 	return e.parseWebResults(bodyStr, query)
@@ -224,6 +235,40 @@ func isDDGBotChallenge(body string) bool {
 	return false
 }
 
+// ddgImageColors and ddgImageLicenses map the image filters to the values of
+// DuckDuckGo's f parameter
+var (
+	ddgImageColors = map[string]string{
+		"color":      "color",
+		"monochrome": "Monochrome",
+	}
+	ddgImageLicenses = map[string]string{
+		"public":            "Public",
+		"share":             "Share",
+		"share_commercial":  "ShareCommercially",
+		"modify":            "Modify",
+		"modify_commercial": "ModifyCommercially",
+	}
+)
+
+// ddgImageFilters builds DuckDuckGo's f parameter, the comma-separated
+// size, color, type, layout and license image filters
+func ddgImageFilters(query *model.Query) string {
+	slots := make([]string, 5)
+	if query.ImageColor != "" {
+		c, ok := ddgImageColors[query.ImageColor]
+		if !ok {
+			// Dominant colors are the capitalized name, e.g. "Red"
+			c = strings.ToUpper(query.ImageColor[:1]) + query.ImageColor[1:]
+		}
+		slots[1] = "color:" + c
+	}
+	if l, ok := ddgImageLicenses[query.ImageLicense]; ok {
+		slots[4] = "license:" + l
+	}
+	return strings.Join(slots, ",") + ","
+}
+
 // searchImages performs an image search using DuckDuckGo images
 func (e *DuckDuckGo) searchImages(ctx context.Context, query *model.Query) ([]model.Result, error) {
 	// First, get a VQD token
@@ -239,8 +284,12 @@ func (e *DuckDuckGo) searchImages(ctx context.Context, query *model.Query) ([]mo
 	params.Set("q", query.Text)
 	params.Set("o", "json")
 	params.Set("vqd", vqd)
-	params.Set("f", ",,,,,")
+	params.Set("f", ddgImageFilters(query))
 	params.Set("p", "1")
+	// Images come 100 a page, paged by the offset of the first
+	if page := pageNumber(query); page > 1 {
+		params.Set("s", strconv.Itoa(pageOffset(query, 100)))
+	}
 
 	// Safe search
 	switch query.SafeSearch {
@@ -291,7 +340,7 @@ func (e *DuckDuckGo) searchImages(ctx context.Context, query *model.Query) ([]mo
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Referer", "https://duckduckgo.com/")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -359,7 +408,7 @@ func (e *DuckDuckGo) getVQDToken(ctx context.Context, query string) (string, err
 
 	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return "", err
 	}
@@ -408,6 +457,11 @@ func findSubstring(s, substr string) int {
 
 // searchVideos performs a video search using DuckDuckGo
 func (e *DuckDuckGo) searchVideos(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// This endpoint pages with a cursor from the previous answer
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
 	// First, get a VQD token
 	vqd, err := e.getVQDToken(ctx, query.Text)
 	if err != nil {
@@ -463,7 +517,7 @@ func (e *DuckDuckGo) searchVideos(ctx context.Context, query *model.Query) ([]mo
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Referer", "https://duckduckgo.com/")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -525,6 +579,11 @@ func (e *DuckDuckGo) searchVideos(ctx context.Context, query *model.Query) ([]mo
 
 // searchNews performs a news search using DuckDuckGo
 func (e *DuckDuckGo) searchNews(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// This endpoint pages with a cursor from the previous answer
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
 	// First, get a VQD token
 	vqd, err := e.getVQDToken(ctx, query.Text)
 	if err != nil {
@@ -560,7 +619,7 @@ func (e *DuckDuckGo) searchNews(ctx context.Context, query *model.Query) ([]mode
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Referer", "https://duckduckgo.com/")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
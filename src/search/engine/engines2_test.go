@@ -1261,6 +1261,46 @@ func TestOSMSearchValidJSON(t *testing.T) {
 	}
 }
 
+func TestOSMSearchEndpoint(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"osm_type":"relation","osm_id":62422,"lat":"52.5","lon":"13.4","display_name":"Berlin, Germany","class":"boundary","type":"administrative","boundingbox":["52.3","52.6","13.0","13.7"],"address":{"city":"Berlin","country":"Germany"}}]`)
+	}))
+	defer server.Close()
+
+	engine := NewOpenStreetMap()
+	if engine.Endpoint() != nominatimDefaultURL {
+		t.Errorf("Endpoint() = %q, want the public server", engine.Endpoint())
+	}
+	engine.SetEndpoint(server.URL + "/nominatim/")
+	engine.client = server.Client()
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "Berlin", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if gotPath != "/nominatim/search" {
+		t.Errorf("request path = %q, want /nominatim/search", gotPath)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	place := results[0].Map()
+	if place == nil || place.Latitude != 52.5 || place.Longitude != 13.4 || place.Address != "Berlin, Germany" {
+		t.Fatalf("Map() = %+v", place)
+	}
+	if place.BoundingBox == nil || *place.BoundingBox != (model.BoundingBox{South: 52.3, North: 52.6, West: 13.0, East: 13.7}) {
+		t.Errorf("BoundingBox = %+v", place.BoundingBox)
+	}
+
+	engine.SetEndpoint("")
+	if engine.Endpoint() != nominatimDefaultURL {
+		t.Errorf("SetEndpoint(\"\") should restore the public server, got %q", engine.Endpoint())
+	}
+}
+
 func TestOSMSearchWithLanguage(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lang := r.URL.Query().Get("accept-language")
@@ -1022,9 +1022,9 @@ func TestWikipediaSearchPagination(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Search() error = %v", err)
 	}
-	// page=2 → sroffset=20
-	if !strings.Contains(capturedURL, "sroffset=20") {
-		t.Errorf("expected sroffset=20 in URL, got: %q", capturedURL)
+	// page=2 → gsroffset=10, right after the 10 results of page 1
+	if !strings.Contains(capturedURL, "gsroffset=10&") {
+		t.Errorf("expected gsroffset=10 in URL, got: %q", capturedURL)
 	}
 }
 
@@ -1604,10 +1604,16 @@ func TestYouTubeParseJSONSuccess(t *testing.T) {
 			},
 		},
 		"ownerText": map[string]interface{}{
-			"runs": []map[string]interface{}{{"text": "Rick Astley"}},
+			"runs": []map[string]interface{}{{
+				"text": "Rick Astley",
+				"navigationEndpoint": map[string]interface{}{
+					"browseEndpoint": map[string]interface{}{"canonicalBaseUrl": "/@RickAstleyYT"},
+				},
+			}},
 		},
 		"viewCountText":     map[string]interface{}{"simpleText": "1B views"},
 		"publishedTimeText": map[string]interface{}{"simpleText": "15 years ago"},
+		"lengthText":        map[string]interface{}{"simpleText": "3:33"},
 	}
 
 	ytData := map[string]interface{}{
@@ -1655,6 +1661,16 @@ func TestYouTubeParseJSONSuccess(t *testing.T) {
 	if !strings.Contains(results[0].Content, "Rick Astley") {
 		t.Errorf("content should contain channel name: %q", results[0].Content)
 	}
+	if results[0].Duration != 213 {
+		t.Errorf("duration = %d, want 213", results[0].Duration)
+	}
+	video := results[0].Video()
+	if video == nil || video.Channel != "Rick Astley" || video.ChannelURL != "https://www.youtube.com/@RickAstleyYT" || video.Views != 1000000000 {
+		t.Errorf("Video() = %+v", video)
+	}
+	if age := time.Since(results[0].PublishedAt); age < 14*365*24*time.Hour || age > 16*365*24*time.Hour {
+		t.Errorf("PublishedAt = %v, want about 15 years ago", results[0].PublishedAt)
+	}
 }
 
 // TestYouTubeParseJSONInvalidJSON verifies that malformed JSON returns an error.
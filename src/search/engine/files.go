@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// magnetTrackers are the public trackers added to the magnet links built
+// from an info hash, so a client can find peers without DHT
+var magnetTrackers = []string{
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://open.demonii.com:1337/announce",
+	"udp://tracker.openbittorrent.com:6969/announce",
+	"udp://exodus.desync.com:6969/announce",
+}
+
+// fileInfo is the index-neutral view of a download returned by the files
+// category engines (The Pirate Bay, Nyaa, Internet Archive)
+type fileInfo struct {
+	Name        string
+	URL         string
+	Description string
+	// Size in bytes (0 if unknown)
+	Size     int64
+	Seeders  int
+	Leechers int
+	InfoHash string
+	// Torrent is the URL of the .torrent file, if the index serves one
+	Torrent  string
+	Uploader string
+	AddedAt  time.Time
+}
+
+// fileResult converts fileInfo into a result in the files category. The
+// peer counts and download links go in Metadata, which model.Result.File
+// exposes to the API and the templates.
+func fileResult(engineName string, priority, position int, info fileInfo) model.Result {
+	desc := info.Description
+	if len(desc) > 300 {
+		desc = desc[:297] + "..."
+	}
+
+	metadata := map[string]interface{}{
+		"seeders":  info.Seeders,
+		"leechers": info.Leechers,
+	}
+	if info.InfoHash != "" {
+		metadata["info_hash"] = strings.ToLower(info.InfoHash)
+		metadata["magnet"] = magnetLink(info.InfoHash, info.Name)
+	}
+	if info.Torrent != "" {
+		metadata["torrent"] = info.Torrent
+	}
+
+	return model.Result{
+		Title:       info.Name,
+		URL:         info.URL,
+		Content:     desc,
+		FileSize:    info.Size,
+		Author:      info.Uploader,
+		PublishedAt: info.AddedAt,
+		Popularity:  float64(info.Seeders),
+		Engine:      engineName,
+		Category:    model.CategoryFiles,
+		Score:       calculateScore(priority, position, 1),
+		Position:    position,
+		Metadata:    metadata,
+	}
+}
+
+// magnetLink builds the magnet link of the torrent with infoHash, named
+// name, announced on magnetTrackers
+func magnetLink(infoHash, name string) string {
+	link := "magnet:?xt=urn:btih:" + strings.ToLower(infoHash)
+	if name != "" {
+		link += "&dn=" + url.QueryEscape(name)
+	}
+	for _, tracker := range magnetTrackers {
+		link += "&tr=" + url.QueryEscape(tracker)
+	}
+	return link
+}
+
+// parseFileSize reads a size as indexes print it: "734003200", "1.4 GiB"
+// or "700 MB". It returns 0 when text holds no size.
+func parseFileSize(text string) int64 {
+	text = strings.TrimSpace(text)
+	number, unit := text, ""
+	if i := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	}); i >= 0 {
+		number, unit = text[:i], strings.TrimSpace(text[i:])
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	multipliers := map[string]float64{
+		"": 1, "b": 1, "bytes": 1,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+		"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+	}
+	multiplier, ok := multipliers[strings.ToLower(unit)]
+	if !ok {
+		return 0
+	}
+	return int64(value * multiplier)
+}
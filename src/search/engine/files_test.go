@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestDefaultRegistryFileEngines(t *testing.T) {
+	names := map[string]bool{}
+	for _, e := range DefaultRegistry().GetForCategory(model.CategoryFiles) {
+		names[e.Name()] = true
+	}
+	for _, name := range []string{"archiveorg", "piratebay", "nyaa"} {
+		if !names[name] {
+			t.Errorf("GetForCategory(files) lacks %s", name)
+		}
+	}
+}
+
+func TestParseFileSize(t *testing.T) {
+	tests := map[string]int64{
+		"734003200": 734003200,
+		"1.5 GiB":   1610612736,
+		"700 MB":    700000000,
+		"12 KiB":    12288,
+		"":          0,
+		"big":       0,
+		"3 parsecs": 0,
+	}
+	for text, want := range tests {
+		if got := parseFileSize(text); got != want {
+			t.Errorf("parseFileSize(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestMagnetLink(t *testing.T) {
+	link := magnetLink("ABCDEF0123456789ABCDEF0123456789ABCDEF01", "Ubuntu 24.04 ISO")
+	if !strings.HasPrefix(link, "magnet:?xt=urn:btih:abcdef0123456789abcdef0123456789abcdef01&dn=Ubuntu+24.04+ISO&tr=") {
+		t.Errorf("magnetLink() = %q", link)
+	}
+	if strings.Count(link, "&tr=") != len(magnetTrackers) {
+		t.Errorf("magnetLink() = %q, want %d trackers", link, len(magnetTrackers))
+	}
+}
+
+func TestPirateBaySearch(t *testing.T) {
+	payload := `[{"id":"123","name":"Ubuntu 24.04 Desktop","info_hash":"ABCDEF0123456789ABCDEF0123456789ABCDEF01",
+		"leechers":"7","seeders":"42","size":"6114656256","username":"canonical","added":"1776297600"},
+		{"id":"0","name":"No results returned","info_hash":"0000000000000000000000000000000000000000","seeders":"0"}]`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewPirateBay()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "ubuntu", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !strings.Contains(gotQuery, "q=ubuntu") {
+		t.Errorf("query = %q", gotQuery)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	file := results[0].File()
+	if file == nil || file.Seeders != 42 || file.Leechers != 7 || file.Size != 6114656256 {
+		t.Fatalf("File() = %+v", file)
+	}
+	if !strings.HasPrefix(file.Magnet, "magnet:?xt=urn:btih:abcdef0123456789") || file.InfoHash != "abcdef0123456789abcdef0123456789abcdef01" {
+		t.Errorf("File() = %+v", file)
+	}
+	if results[0].URL != "https://thepiratebay.org/description.php?id=123" || results[0].Category != model.CategoryFiles {
+		t.Errorf("result = %+v", results[0])
+	}
+
+	// The API has one page only
+	if results, err := engine.Search(context.Background(), &model.Query{Text: "ubuntu", Page: 2}); err != nil || len(results) != 0 {
+		t.Errorf("page 2 = %v, %v, want none", results, err)
+	}
+}
+
+func TestNyaaSearch(t *testing.T) {
+	payload := `<?xml version="1.0" encoding="utf-8"?>
+<rss xmlns:atom="http://www.w3.org/2005/Atom" xmlns:nyaa="https://nyaa.si/xmlns/nyaa" version="2.0">
+<channel><title>Nyaa - "frieren" - Torrent File RSS</title>
+<item><title>[Group] Frieren - 01 (1080p).mkv</title>
+<link>https://nyaa.si/download/1900001.torrent</link>
+<guid isPermaLink="true">https://nyaa.si/view/1900001</guid>
+<pubDate>Fri, 16 Oct 2026 12:00:00 -0000</pubDate>
+<nyaa:seeders>120</nyaa:seeders><nyaa:leechers>8</nyaa:leechers>
+<nyaa:infoHash>0123456789abcdef0123456789abcdef01234567</nyaa:infoHash>
+<nyaa:category>Anime - English-translated</nyaa:category><nyaa:size>1.4 GiB</nyaa:size></item>
+</channel></rss>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewNyaa()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "frieren", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	file := r.File()
+	if file == nil || file.Seeders != 120 || file.Leechers != 8 || file.Torrent != "https://nyaa.si/download/1900001.torrent" {
+		t.Fatalf("File() = %+v", file)
+	}
+	if file.Size != 1503238553 || file.Magnet == "" {
+		t.Errorf("File() = %+v", file)
+	}
+	if r.URL != "https://nyaa.si/view/1900001" || !r.PublishedAt.Equal(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestArchiveOrgSearch(t *testing.T) {
+	payload := `{"response":{"numFound":2,"docs":[
+		{"identifier":"night_of_the_living_dead","title":"Night of the Living Dead",
+		 "description":["A 1968 horror film.","Public domain."],"creator":"George A. Romero",
+		 "item_size":1234567890,"publicdate":"2026-01-02T03:04:05Z"},
+		{"identifier":"","title":"No identifier"}]}}`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewArchiveOrg()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "living dead", Page: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !strings.Contains(gotQuery, "page=2") || !strings.Contains(gotQuery, "output=json") {
+		t.Errorf("query = %q", gotQuery)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.URL != "https://archive.org/details/night_of_the_living_dead" || r.Content != "A 1968 horror film.; Public domain." || r.Author != "George A. Romero" {
+		t.Errorf("result = %+v", r)
+	}
+	file := r.File()
+	if file == nil || file.Size != 1234567890 || file.Torrent != "https://archive.org/download/night_of_the_living_dead/night_of_the_living_dead_archive.torrent" || file.Magnet != "" {
+		t.Errorf("File() = %+v", file)
+	}
+}
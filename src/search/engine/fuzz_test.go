@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// Fuzz targets for the engine parsers, one per engine. Each target feeds
+// fuzzed response bodies to the engine's Search through a transport that
+// never reaches the network, so it covers the whole read, decode and parse
+// path. A target fails when the engine panics. `go test` runs the seeds
+// only; `search --test fuzz` runs long fuzz sessions.
+
+// fuzzResponseSeparator splits a fuzzed body into the responses to an
+// engine's successive requests, such as PubMed's search and then fetch.
+// The last response answers any further requests.
+const fuzzResponseSeparator = "\x00\x00"
+
+// fuzzTransport answers requests with the fuzzed responses in turn
+type fuzzTransport struct {
+	contentType string
+	mu          sync.Mutex
+	responses   [][]byte
+}
+
+func newFuzzTransport(contentType string, body []byte) *fuzzTransport {
+	return &fuzzTransport{contentType: contentType, responses: bytes.Split(body, []byte(fuzzResponseSeparator))}
+}
+
+func (t *fuzzTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	body := t.responses[0]
+	if len(t.responses) > 1 {
+		t.responses = t.responses[1:]
+	}
+	t.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{t.contentType}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// fuzzSeed is a response body for one of the engine's categories
+type fuzzSeed struct {
+	category model.Category
+	body     string
+}
+
+// fuzzEngine fuzzes the engine newEngine builds around the given client.
+// The category argument picks one of the engine's categories, so engines
+// with a parser per category have each fuzzed.
+func fuzzEngine(f *testing.F, newEngine func(*http.Client) search.Engine, contentType, queryText string, seeds ...fuzzSeed) {
+	categories := newEngine(nil).GetConfig().Categories
+	index := func(category model.Category) uint8 {
+		for i, c := range categories {
+			if model.Category(c) == category {
+				return uint8(i)
+			}
+		}
+		return 0
+	}
+	for _, body := range []string{"", "{}", "[]", "<html><body></body></html>"} {
+		f.Add(uint8(0), []byte(body))
+	}
+	for _, seed := range seeds {
+		f.Add(index(seed.category), []byte(seed.body))
+	}
+
+	f.Fuzz(func(t *testing.T, category uint8, body []byte) {
+		client := &http.Client{Transport: newFuzzTransport(contentType, body)}
+		query := &model.Query{
+			Text:     queryText,
+			Category: model.Category(categories[int(category)%len(categories)]),
+			Language: "en",
+			Page:     1,
+			PerPage:  10,
+		}
+		// Errors are expected for malformed bodies; panics are not
+		newEngine(client).Search(context.Background(), query)
+	})
+}
+
+// sharedTransportEngine adapts an engine that builds its client on
+// SharedTransport for each search: SharedTransport is pointed at the fuzz
+// transport, and restored when the target ends.
+func sharedTransportEngine(f *testing.F, newEngine func() search.Engine) func(*http.Client) search.Engine {
+	orig := SharedTransport
+	f.Cleanup(func() { SharedTransport = orig })
+	return func(c *http.Client) search.Engine {
+		if c != nil {
+			transport := &http.Transport{}
+			transport.RegisterProtocol("https", c.Transport)
+			transport.RegisterProtocol("http", c.Transport)
+			SharedTransport = transport
+		}
+		return newEngine()
+	}
+}
+
+const (
+	fuzzHTML  = "text/html; charset=utf-8"
+	fuzzJSON  = "application/json"
+	fuzzXML   = "application/xml"
+	fuzzQuery = "golang"
+)
+
+func FuzzDuckDuckGo(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewDuckDuckGo(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<div class="result"><a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2F&amp;rut=x">The <b>Go</b> Programming Language</a><a class="result__snippet" href="x">Go is an open source language.</a></div>`},
+		fuzzSeed{model.CategoryImages, `<script>vqd="4-1234567890"</script>` + fuzzResponseSeparator + `{"results":[{"title":"Gopher","url":"https://go.dev/","image":"https://go.dev/gopher.png","thumbnail":"https://t.example/1.jpg","width":640,"height":480,"source":"Bing"}]}`},
+		fuzzSeed{model.CategoryVideos, `<script>vqd="4-1234567890"</script>` + fuzzResponseSeparator + `{"results":[{"title":"Go talk","content":"https://www.youtube.com/watch?v=abcdefghijk","description":"Talk","duration":"10:01","views":42,"published":"2024-01-02T03:04:05Z","publisher":"YouTube","images":"https://i.example/1.jpg"}]}`},
+		fuzzSeed{model.CategoryNews, `<script>vqd="4-1234567890"</script>` + fuzzResponseSeparator + `{"results":[{"title":"Go 1.23","url":"https://go.dev/blog","excerpt":"Released","source":"Go Blog","image":"","date":1700000000}]}`},
+	)
+}
+
+func FuzzGoogle(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewGoogle(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<div class="g"><a href="/url?q=https://go.dev/&amp;sa=U"><h3 class="r">The Go Programming Language</h3></a><div class="s">Go is an open source language.</div></div>`},
+		fuzzSeed{model.CategoryImages, `<script>AF_initDataCallback({data:[["https://go.dev/images/gopher.png",640,480],["https://t.example/thumb.jpg",120,90]]});</script>`},
+		fuzzSeed{model.CategoryNews, `<a href="https://news.example/go"><div role="heading" aria-level="3">Go 1.23 released</div><span>2 hours ago</span></a>`},
+		fuzzSeed{model.CategoryVideos, `<a href="/url?q=https://www.youtube.com/watch%3Fv%3Dabcdefghijk&amp;sa=U"><h3>Go talk</h3></a>`},
+	)
+}
+
+func FuzzBing(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewBing(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<ol id="b_results"><li class="b_algo" data-id iid=SERP.1><h2 class=""><a href="https://www.bing.com/ck/a?!&amp;&amp;p=x&amp;u=a1aHR0cHM6Ly9nby5kZXYv&amp;ntb=1">The <strong>Go</strong> Programming Language</a></h2><div class="b_caption"><p>Go is an open source language.</p></div></li></ol>`},
+	)
+}
+
+func FuzzWikipedia(f *testing.F) {
+	fuzzEngine(f, sharedTransportEngine(f, func() search.Engine { return NewWikipediaEngine() }), fuzzJSON, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `{"query":{"pages":{"25039021":{"pageid":25039021,"title":"Go (programming language)","extract":"Go is a statically typed language."}}}}`},
+	)
+}
+
+func FuzzQwant(f *testing.F) {
+	fuzzEngine(f, sharedTransportEngine(f, func() search.Engine { return NewQwantEngine() }), fuzzJSON, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `{"status":"success","data":{"result":{"items":[{"title":"Go","url":"https://go.dev/","desc":"Go is an open source language.","source":"go.dev"}]}}}`},
+		fuzzSeed{model.CategoryImages, `{"data":{"result":{"items":[{"title":"Gopher","url":"https://go.dev/","media":"https://go.dev/gopher.png","thumbnail":"https://t.example/1.jpg"}]}}}`},
+	)
+}
+
+func FuzzBrave(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewBrave(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<div class="snippet" data-type="web"><a class="result-header" href="https://go.dev/"><span class="snippet-title">The Go Programming Language</span></a><p class="snippet-description">Go is an open source language.</p></div>`},
+	)
+}
+
+func FuzzYahoo(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewYahoo(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<div class="dd algo algo-sr"><a class="ac-algo" href="https://r.search.yahoo.com/_ylt=x/RV=2/RE=1/RO=10/RU=https%3a%2f%2fgo.dev%2f/RK=2/RS=x-"><h3 class="title">The Go Programming Language</h3></a><p>Go is an open source language.</p></div>`},
+	)
+}
+
+func FuzzGitHub(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewGitHub(); e.client = c; return e }, fuzzJSON, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `{"total_count":1,"items":[{"full_name":"golang/go","html_url":"https://github.com/golang/go","description":"The Go programming language","stargazers_count":120000,"forks_count":17000,"language":"Go","updated_at":"2024-01-02T03:04:05Z"}]}`},
+	)
+}
+
+func FuzzStackOverflow(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewStackOverflow(); e.client = c; return e }, fuzzJSON, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `{"items":[{"question_id":1,"title":"How do I &quot;range&quot; a channel?","link":"https://stackoverflow.com/q/1","body":"<p>Question</p>","tags":["go"],"score":10,"answer_count":2,"is_answered":true,"creation_date":1700000000}]}`},
+	)
+}
+
+func FuzzReddit(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewReddit(); e.client = c; return e }, fuzzJSON, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `{"data":{"children":[{"data":{"title":"Go 1.23","permalink":"/r/golang/comments/x/go_123/","selftext":"","subreddit":"golang","score":100,"num_comments":20,"url":"https://go.dev/blog","created_utc":1700000000.0,"is_self":false}}]}}`},
+	)
+}
+
+func FuzzHackerNews(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewHackerNews(); e.client = c; return e }, fuzzJSON, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `{"hits":[{"title":"Go 1.23","url":"https://go.dev/blog","author":"gopher","points":300,"num_comments":100,"created_at":"2024-01-02T03:04:05Z","objectID":"1","story_text":""}]}`},
+	)
+}
+
+func FuzzStartpage(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewStartpageEngine(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<div class="w-gl__result"><a class="w-gl__result-url result-link" href="https://go.dev/"><h3 class="w-gl__result-title">The Go Programming Language</h3></a><p class="w-gl__description">Go is an open source language.</p></div>`},
+	)
+}
+
+func FuzzYouTube(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewYouTubeEngine(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryVideos, `<script>var ytInitialData = {"contents":{"twoColumnSearchResultsRenderer":{"primaryContents":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"videoRenderer":{"videoId":"abcdefghijk","title":{"runs":[{"text":"Go talk"}]},"ownerText":{"runs":[{"text":"Gopher"}]},"lengthText":{"simpleText":"10:01"},"viewCountText":{"simpleText":"42 views"},"thumbnail":{"thumbnails":[{"url":"https://i.ytimg.com/vi/abcdefghijk/hq.jpg"}]}}}]}}]}}}}};</script>`},
+		fuzzSeed{model.CategoryVideos, `<a href="/watch?v=abcdefghijk">Go talk</a>`},
+	)
+}
+
+func FuzzMojeek(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewMojeek(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<ul class="results-standard"><li class="results-standard"><a class="title" href="https://go.dev/">The Go Programming Language</a><p class="u">go.dev</p><p class="s">Go is an open source language.</p></li></ul>`},
+	)
+}
+
+func FuzzYandex(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewYandex(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<li class="serp-item"><a class="Link organic__title-link" href="https://go.dev/"><h2>The Go Programming Language</h2></a><div class="TextContainer OrganicText"><span class="OrganicTextContentSpan">Go is an open source language.</span></div></li>`},
+		fuzzSeed{model.CategoryGeneral, `<h2><a href="https://go.dev/">Go</a></h2>`},
+	)
+}
+
+func FuzzBaidu(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewBaidu(); e.client = c; return e }, fuzzHTML, fuzzQuery,
+		fuzzSeed{model.CategoryGeneral, `<div class="result c-container" mu="https://go.dev/"><h3 class="c-title t"><a href="http://www.baidu.com/link?url=x">The Go Programming Language</a></h3><div class="c-abstract">Go is an open source language.</div></div>`},
+	)
+}
+
+func FuzzPubMed(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewPubMed(); e.client = c; return e }, fuzzXML, "crispr",
+		fuzzSeed{model.CategoryScience, `<?xml version="1.0"?><eSearchResult><Count>1</Count><RetMax>1</RetMax><RetStart>0</RetStart><IdList><Id>12345</Id></IdList></eSearchResult>` + fuzzResponseSeparator +
+			`<?xml version="1.0"?><PubmedArticleSet><PubmedArticle><MedlineCitation><PMID>12345</PMID><Article><Journal><Title>Nature</Title><JournalIssue><PubDate><Year>2024</Year><Month>Jan</Month><Day>02</Day></PubDate></JournalIssue></Journal><ArticleTitle>CRISPR</ArticleTitle><Abstract><AbstractText Label="BACKGROUND">Text</AbstractText></Abstract><AuthorList><Author><LastName>Doe</LastName><ForeName>Jane</ForeName></Author></AuthorList></Article></MedlineCitation></PubmedArticle></PubmedArticleSet>`},
+	)
+}
+
+func FuzzArXiv(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewArXiv(); e.client = c; return e }, "application/atom+xml", "transformers",
+		fuzzSeed{model.CategoryScience, `<?xml version="1.0" encoding="UTF-8"?><feed xmlns="http://www.w3.org/2005/Atom"><entry><id>http://arxiv.org/abs/2301.00001v1</id><title>A Paper</title><summary>Abstract.</summary><published>2023-01-01T00:00:00Z</published><updated>2023-01-02T00:00:00Z</updated><author><name>Alice</name></author><link rel="alternate" type="text/html" href="https://arxiv.org/abs/2301.00001"/><category term="cs.AI"/></entry></feed>`},
+	)
+}
+
+func FuzzOpenStreetMap(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewOpenStreetMap(); e.client = c; return e }, fuzzJSON, "Berlin",
+		fuzzSeed{model.CategoryMaps, `[{"place_id":1,"osm_type":"relation","osm_id":62422,"lat":"52.5","lon":"13.4","display_name":"Berlin, Germany","class":"boundary","type":"administrative","importance":0.9,"boundingbox":["52.3","52.6","13.0","13.7"],"address":{"city":"Berlin","country":"Germany","country_code":"de"}}]`},
+	)
+}
+
+func FuzzCVE(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewCVE(); e.client = c; return e }, fuzzJSON, "CVE-2024-3094",
+		fuzzSeed{model.CategoryGeneral, `{"vulnerabilities":[{"cve":{"id":"CVE-2024-3094","published":"2024-03-29T17:15:21.150","lastModified":"2024-04-01T00:00:00.000","descriptions":[{"lang":"en","value":"Malicious code in xz."}],"metrics":{"cvssMetricV31":[{"cvssData":{"version":"3.1","vectorString":"CVSS:3.1/AV:N","baseScore":10.0,"baseSeverity":"CRITICAL"}}]},"configurations":[{"nodes":[{"cpeMatch":[{"vulnerable":true,"criteria":"cpe:2.3:a:tukaani:xz:5.6.0:*:*:*:*:*:*:*"}]}]}],"references":[{"url":"https://www.openwall.com/lists/oss-security/2024/03/29/4"}]}}]}`},
+	)
+}
+
+func FuzzNPM(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewNPM(); e.client = c; return e }, fuzzJSON, "left-pad",
+		fuzzSeed{model.CategoryGeneral, `{"objects":[{"package":{"name":"left-pad","version":"1.3.0","description":"String left pad","license":"WTFPL","date":"2018-04-09T00:00:00Z","publisher":{"username":"stevemao"},"links":{"npm":"https://www.npmjs.com/package/left-pad","homepage":"https://github.com/stevemao/left-pad","repository":"https://github.com/stevemao/left-pad"}},"downloads":{"monthly":1000,"weekly":250}}]}`},
+	)
+}
+
+func FuzzPyPI(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewPyPI(); e.client = c; return e }, fuzzJSON, "requests",
+		fuzzSeed{model.CategoryGeneral, `{"info":{"name":"requests","version":"2.31.0","summary":"HTTP for Humans.","license":"Apache 2.0","author":"Kenneth Reitz","home_page":"https://requests.readthedocs.io","package_url":"https://pypi.org/project/requests/","project_urls":{"Source":"https://github.com/psf/requests"},"classifiers":["Programming Language :: Python :: 3"]},"urls":[{"upload_time_iso_8601":"2023-05-22T15:12:44.175073Z"}]}`},
+	)
+}
+
+func FuzzCrates(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewCrates(); e.client = c; return e }, fuzzJSON, "serde",
+		fuzzSeed{model.CategoryGeneral, `{"crates":[{"name":"serde","max_version":"1.0.200","max_stable_version":"1.0.200","description":"A serialization framework","downloads":400000000,"repository":"https://github.com/serde-rs/serde","homepage":"https://serde.rs","documentation":"https://docs.rs/serde","updated_at":"2024-05-01T00:00:00Z"}]}`},
+	)
+}
+
+func FuzzGoModules(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewGoModules(); e.client = c; return e }, fuzzJSON, "github.com/apimgr/search",
+		fuzzSeed{model.CategoryGeneral, `{"Version":"v1.2.3","Time":"2024-01-02T03:04:05Z"}`},
+	)
+}
+
+func FuzzDockerHub(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewDockerHub(); e.client = c; return e }, fuzzJSON, "nginx",
+		fuzzSeed{model.CategoryGeneral, `{"results":[{"repo_name":"nginx","short_description":"Official build of Nginx.","star_count":19000,"pull_count":1000000000,"repo_owner":"","is_official":true}]}`},
+	)
+}
+
+func FuzzWolframAlpha(f *testing.F) {
+	fuzzEngine(f, func(c *http.Client) search.Engine { e := NewWolframAlpha(); e.client = c; return e }, fuzzHTML, "2+2",
+		fuzzSeed{model.CategoryGeneral, `<section><h2 class="pod-title">Result</h2><img alt="4" class="_image" src="x.gif"><div data-stringified="4"></div></section>`},
+		fuzzSeed{model.CategoryGeneral, `<div class="pod">4</div>`},
+	)
+}
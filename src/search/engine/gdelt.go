@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// gdeltTimespans map time ranges to GDELT timespans. GDELT searches the
+// past three months, so a year is its whole index.
+var gdeltTimespans = map[string]string{
+	"day":   "1d",
+	"week":  "1w",
+	"month": "1m",
+}
+
+// GDELT searches news articles through the GDELT Project's DOC API, an
+// open index of news sites worldwide that needs no key. Results link to
+// the article itself.
+type GDELT struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewGDELT creates a new GDELT news engine
+func NewGDELT() *GDELT {
+	config := model.NewEngineConfig("gdelt")
+	config.DisplayName = "GDELT"
+	config.Priority = 50
+	config.Categories = []string{"news"}
+	config.SupportsTor = true
+
+	return &GDELT{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs a GDELT article search
+func (e *GDELT) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// The API returns one list with no paging
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
+	params := url.Values{}
+	params.Set("query", query.Text)
+	params.Set("mode", "artlist")
+	params.Set("format", "json")
+	params.Set("maxrecords", "50")
+	params.Set("sort", "hybridrel")
+	if query.SortBy == model.SortDate {
+		params.Set("sort", "datedesc")
+	}
+	if timespan := gdeltTimespans[query.TimeRange]; timespan != "" {
+		params.Set("timespan", timespan)
+	}
+
+	reqURL := fmt.Sprintf("https://api.gdeltproject.org/api/v2/doc/doc?%s", params.Encode())
+
+	var data struct {
+		Articles []struct {
+			URL         string `json:"url"`
+			Title       string `json:"title"`
+			SeenDate    string `json:"seendate"`
+			SocialImage string `json:"socialimage"`
+			Domain      string `json:"domain"`
+			Language    string `json:"language"`
+		} `json:"articles"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("gdelt: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.Articles))
+	for i, article := range data.Articles {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		if article.URL == "" || article.Title == "" {
+			continue
+		}
+		result := model.Result{
+			Title:     article.Title,
+			URL:       article.URL,
+			Thumbnail: article.SocialImage,
+			Domain:    article.Domain,
+			Engine:    e.Name(),
+			Category:  model.CategoryNews,
+			Score:     calculateScore(e.GetPriority(), i, 1),
+			Position:  i,
+		}
+		// When GDELT first saw the article, close to its publication
+		if seen, err := time.Parse("20060102T150405Z", article.SeenDate); err == nil {
+			result.PublishedAt = seen
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/apimgr/search/src/model"
@@ -45,6 +46,7 @@ func (e *GitHub) Search(ctx context.Context, query *model.Query) ([]model.Result
 	params.Set("sort", "stars")
 	params.Set("order", "desc")
 	params.Set("per_page", "10")
+	params.Set("page", strconv.Itoa(pageNumber(query)))
 
 	reqURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
 
@@ -56,7 +58,7 @@ func (e *GitHub) Search(ctx context.Context, query *model.Query) ([]model.Result
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// GoModules implements Go module lookups through the public module proxy.
+// The proxy has no search endpoint, so only queries that look like a module
+// path (e.g. "github.com/go-chi/chi/v5") produce a result.
+type GoModules struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewGoModules creates a new Go modules engine
+func NewGoModules() *GoModules {
+	config := model.NewEngineConfig("gomodules")
+	config.DisplayName = "Go Modules"
+	config.Priority = 54
+	config.Categories = []string{"packages"}
+	config.SupportsTor = true
+
+	return &GoModules{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// isGoModulePath reports whether text looks like a Go module path:
+// a dotted host followed by at least one path element, no spaces.
+func isGoModulePath(text string) bool {
+	if text == "" || strings.ContainsAny(text, " \t") {
+		return false
+	}
+	host, rest, ok := strings.Cut(text, "/")
+	return ok && rest != "" && strings.Contains(host, ".")
+}
+
+// escapeGoModulePath applies the module proxy case encoding:
+// each uppercase letter becomes "!" followed by its lowercase form.
+func escapeGoModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goModuleRepository derives a browsable repository URL for well-known hosts
+func goModuleRepository(path string) string {
+	parts := strings.Split(path, "/")
+	switch parts[0] {
+	case "github.com", "gitlab.com", "bitbucket.org", "codeberg.org":
+		if len(parts) >= 3 {
+			return "https://" + strings.Join(parts[:3], "/")
+		}
+	case "golang.org":
+		if len(parts) >= 3 && parts[1] == "x" {
+			return "https://go.googlesource.com/" + parts[2]
+		}
+	}
+	return ""
+}
+
+// Search resolves the query as a module path via proxy.golang.org
+func (e *GoModules) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	path := strings.TrimSpace(query.Text)
+	path = strings.TrimPrefix(path, "https://")
+	path = strings.TrimPrefix(path, "http://")
+	path = strings.TrimSuffix(path, "/")
+	if !isGoModulePath(path) || query.Page > 1 {
+		return []model.Result{}, nil
+	}
+
+	reqURL := fmt.Sprintf("https://proxy.golang.org/%s/@latest", escapeGoModulePath(path))
+
+	var data struct {
+		Version string `json:"Version"`
+		Time    string `json:"Time"`
+	}
+
+	found, err := fetchRegistryJSON(ctx, e.client, reqURL, &data)
+	if err != nil {
+		return nil, fmt.Errorf("go modules: %w", err)
+	}
+	if !found || data.Version == "" {
+		return []model.Result{}, nil
+	}
+
+	var updated time.Time
+	if data.Time != "" {
+		if parsed, err := time.Parse(time.RFC3339, data.Time); err == nil {
+			updated = parsed
+		}
+	}
+
+	return []model.Result{
+		packageResult(e.Name(), "go", e.GetPriority(), 0, packageInfo{
+			Name:        path,
+			Version:     data.Version,
+			Description: fmt.Sprintf("go get %s@%s", path, data.Version),
+			Repository:  goModuleRepository(path),
+			URL:         "https://pkg.go.dev/" + path,
+			UpdatedAt:   updated,
+		}),
+	}, nil
+}
@@ -52,6 +52,9 @@ func NewGoogle() *Google {
 	config.Categories = []string{"general", "images", "news", "videos", "files", "music"}
 	// Google blocks Tor exit nodes
 	config.SupportsTor = false
+	config.SupportsSafeSearch = true
+	config.SupportsImageFilters = true
+	config.SupportsVideoFilters = true
 
 	return &Google{
 		BaseEngine: search.NewBaseEngine(config),
@@ -93,7 +96,7 @@ func (e *Google) googleDo(ctx context.Context, rawURL string) (*http.Response, e
 	req.Header.Set("Sec-Fetch-Site", "none")
 	// consent bypass
 	req.Header.Set("Cookie", "SOCS=CAI")
-	return e.client.Do(req)
+	return doRequest(e.client, req)
 }
 
 // googleParams builds the common query parameters for all Google searches.
@@ -150,7 +153,7 @@ func (e *Google) parseWebResults(html string, query *model.Query) []model.Result
 	maxResults := e.GetConfig().GetMaxResults()
 	results := make([]model.Result, 0, min(len(matches), maxResults))
 
-	for _, idx := range matches {
+	for i, idx := range matches {
 		if len(results) >= maxResults {
 			break
 		}
@@ -177,23 +180,56 @@ func (e *Google) parseWebResults(html string, query *model.Query) []model.Result
 		}
 		snippet := extractTextBlock(html[snippetStart:snippetEnd])
 
+		// Rich snippet lines (rating, cooking time, price) sit under the
+		// snippet; stop at the next result so its data is not taken
+		if i+1 < len(matches) && matches[i+1][0] < snippetEnd {
+			snippetEnd = max(snippetStart, matches[i+1][0])
+		}
+
 		results = append(results, model.Result{
-			Title:    title,
-			URL:      realURL,
-			Content:  snippet,
-			Engine:   e.Name(),
-			Category: model.CategoryGeneral,
-			Score:    calculateScore(e.GetPriority(), len(results), 1),
-			Position: len(results),
+			Title:      title,
+			URL:        realURL,
+			Content:    snippet,
+			Engine:     e.Name(),
+			Category:   model.CategoryGeneral,
+			Score:      calculateScore(e.GetPriority(), len(results), 1),
+			Position:   len(results),
+			Structured: search.ExtractStructuredData(html[snippetStart:snippetEnd]),
 		})
 	}
 	return results
 }
 
+// googleImageFilters builds the tbs values for the image color and license
+// filters. Google only tells Creative Commons licenses apart from the rest,
+// so every license filter asks for those.
+func googleImageFilters(query *model.Query) string {
+	var tbs []string
+	switch query.ImageColor {
+	case "":
+	case "color":
+		tbs = append(tbs, "ic:color")
+	case "monochrome":
+		tbs = append(tbs, "ic:gray")
+	default:
+		tbs = append(tbs, "ic:specific", "isc:"+query.ImageColor)
+	}
+	if query.ImageLicense != "" {
+		tbs = append(tbs, "il:cl")
+	}
+	return strings.Join(tbs, ",")
+}
+
 // searchImages performs a Google Images search
 func (e *Google) searchImages(ctx context.Context, query *model.Query) ([]model.Result, error) {
 	params := googleParams(query)
 	params.Set("tbm", "isch")
+	if filters := googleImageFilters(query); filters != "" {
+		if tbs := params.Get("tbs"); tbs != "" {
+			filters = tbs + "," + filters
+		}
+		params.Set("tbs", filters)
+	}
 	reqURL := "https://www.google.com/search?" + params.Encode()
 
 	resp, err := e.googleDo(ctx, reqURL)
@@ -307,10 +343,34 @@ func (e *Google) parseNewsResults(html string, query *model.Query) []model.Resul
 	return results
 }
 
+// googleVideoFilters builds the tbs values for the video length and
+// quality filters
+func googleVideoFilters(query *model.Query) string {
+	var tbs []string
+	switch query.VideoLength {
+	case "short":
+		tbs = append(tbs, "dur:s")
+	case "medium":
+		tbs = append(tbs, "dur:m")
+	case "long":
+		tbs = append(tbs, "dur:l")
+	}
+	if query.VideoQuality == "hd" {
+		tbs = append(tbs, "hq:h")
+	}
+	return strings.Join(tbs, ",")
+}
+
 // searchVideos performs a Google Videos search
 func (e *Google) searchVideos(ctx context.Context, query *model.Query) ([]model.Result, error) {
 	params := googleParams(query)
 	params.Set("tbm", "vid")
+	if filters := googleVideoFilters(query); filters != "" {
+		if tbs := params.Get("tbs"); tbs != "" {
+			filters = tbs + "," + filters
+		}
+		params.Set("tbs", filters)
+	}
 	reqURL := "https://www.google.com/search?" + params.Encode()
 
 	resp, err := e.googleDo(ctx, reqURL)
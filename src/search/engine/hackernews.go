@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/apimgr/search/src/model"
@@ -44,6 +45,8 @@ func (e *HackerNews) Search(ctx context.Context, query *model.Query) ([]model.Re
 	params.Set("query", query.Text)
 	params.Set("tags", "story")
 	params.Set("hitsPerPage", "10")
+	// Algolia numbers pages from 0
+	params.Set("page", strconv.Itoa(pageNumber(query)-1))
 
 	reqURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
 
@@ -55,7 +58,7 @@ func (e *HackerNews) Search(ctx context.Context, query *model.Query) ([]model.Re
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
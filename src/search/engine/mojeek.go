@@ -28,6 +28,7 @@ func NewMojeek() *Mojeek {
 	config.Priority = 65
 	config.Categories = []string{"general", "images", "news", "files", "music"}
 	config.SupportsTor = true
+	config.SupportsSafeSearch = true
 
 	return &Mojeek{
 		BaseEngine: search.NewBaseEngine(config),
@@ -81,7 +82,7 @@ func (e *Mojeek) Search(ctx context.Context, query *model.Query) ([]model.Result
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
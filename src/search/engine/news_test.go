@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestDefaultRegistryNewsEngines(t *testing.T) {
+	names := map[string]bool{}
+	for _, e := range DefaultRegistry().GetForCategory(model.CategoryNews) {
+		names[e.Name()] = true
+	}
+	for _, name := range []string{"bingnews", "gdelt"} {
+		if !names[name] {
+			t.Errorf("GetForCategory(news) lacks %s", name)
+		}
+	}
+}
+
+func TestBingNewsSearch(t *testing.T) {
+	payload := `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0" xmlns:News="https://www.bing.com/news/search?q=election&amp;format=rss">
+<channel><title>election - BingNews</title>
+<item><title>Polls close in &lt;b&gt;election&lt;/b&gt;</title>
+<link>http://www.bing.com/news/apiclick.aspx?ref=FexRss&amp;aid=&amp;tid=1&amp;url=https%3a%2f%2fwww.reuters.com%2fworld%2fpolls-close&amp;c=1</link>
+<description>Voting ended at 8pm.</description>
+<pubDate>Fri, 16 Oct 2026 20:15:00 GMT</pubDate>
+<News:Source>Reuters</News:Source>
+<News:Image>https://www.bing.com/th?id=OVFT.abc</News:Image></item>
+<item><title>No article</title><link>http://www.bing.com/news/apiclick.aspx?ref=FexRss</link></item>
+</channel></rss>`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewBingNews()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{
+		Text: "election", Page: 2, Category: model.CategoryNews, TimeRange: "week", SortBy: model.SortDate,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	for _, param := range []string{"first=21", "format=rss", "interval%3D%228%22", "sortbydate%3D%221%22"} {
+		if !strings.Contains(gotQuery, param) {
+			t.Errorf("query = %q, want %s", gotQuery, param)
+		}
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.URL != "https://www.reuters.com/world/polls-close" || r.Title != "Polls close in election" || r.Author != "Reuters" {
+		t.Errorf("result = %+v", r)
+	}
+	if r.Category != model.CategoryNews || r.Publisher() != "Reuters" || r.Thumbnail != "https://www.bing.com/th?id=OVFT.abc" {
+		t.Errorf("result = %+v", r)
+	}
+	if !r.PublishedAt.Equal(time.Date(2026, 10, 16, 20, 15, 0, 0, time.UTC)) {
+		t.Errorf("PublishedAt = %v", r.PublishedAt)
+	}
+}
+
+func TestBingNewsArticleURL(t *testing.T) {
+	tests := map[string]string{
+		"http://www.bing.com/news/apiclick.aspx?url=https%3a%2f%2fapnews.com%2farticle%2f1": "https://apnews.com/article/1",
+		"http://www.bing.com/news/apiclick.aspx?url=javascript%3aalert(1)":                  "",
+		"https://www.theguardian.com/world/2026/oct/16/story":                               "https://www.theguardian.com/world/2026/oct/16/story",
+		"not a url": "",
+	}
+	for link, want := range tests {
+		if got := bingNewsArticleURL(link); got != want {
+			t.Errorf("bingNewsArticleURL(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestGDELTSearch(t *testing.T) {
+	payload := `{"articles":[{"url":"https://www.lemonde.fr/article","url_mobile":"","title":"Élection présidentielle",
+		"seendate":"20261016T181500Z","socialimage":"https://img.lemde.fr/a.jpg","domain":"lemonde.fr",
+		"language":"French","sourcecountry":"France"},{"url":"","title":"Missing link"}]}`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewGDELT()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{
+		Text: "election", Page: 1, Category: model.CategoryNews, TimeRange: "day", SortBy: model.SortDate,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	for _, param := range []string{"timespan=1d", "sort=datedesc", "mode=artlist"} {
+		if !strings.Contains(gotQuery, param) {
+			t.Errorf("query = %q, want %s", gotQuery, param)
+		}
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.URL != "https://www.lemonde.fr/article" || r.Publisher() != "lemonde.fr" || r.Thumbnail != "https://img.lemde.fr/a.jpg" {
+		t.Errorf("result = %+v", r)
+	}
+	if !r.PublishedAt.Equal(time.Date(2026, 10, 16, 18, 15, 0, 0, time.UTC)) {
+		t.Errorf("PublishedAt = %v", r.PublishedAt)
+	}
+
+	// GDELT has one page only
+	if results, err := engine.Search(context.Background(), &model.Query{Text: "election", Page: 2}); err != nil || len(results) != 0 {
+		t.Errorf("page 2 = %v, %v, want none", results, err)
+	}
+}
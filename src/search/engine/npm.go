@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// NPM implements npm registry search using the public registry search API
+type NPM struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewNPM creates a new npm registry engine
+func NewNPM() *NPM {
+	config := model.NewEngineConfig("npm")
+	config.DisplayName = "npm"
+	config.Priority = 60
+	config.Categories = []string{"packages"}
+	config.SupportsTor = true
+
+	return &NPM{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs an npm registry search
+func (e *NPM) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("text", query.Text)
+	params.Set("size", "10")
+	if query.Page > 1 {
+		params.Set("from", fmt.Sprintf("%d", (query.Page-1)*10))
+	}
+
+	reqURL := fmt.Sprintf("https://registry.npmjs.org/-/v1/search?%s", params.Encode())
+
+	var data struct {
+		Objects []struct {
+			Package struct {
+				Name        string `json:"name"`
+				Version     string `json:"version"`
+				Description string `json:"description"`
+				License     string `json:"license"`
+				Date        string `json:"date"`
+				Publisher   struct {
+					Username string `json:"username"`
+				} `json:"publisher"`
+				Links struct {
+					NPM        string `json:"npm"`
+					Homepage   string `json:"homepage"`
+					Repository string `json:"repository"`
+				} `json:"links"`
+			} `json:"package"`
+			Downloads struct {
+				Monthly int64 `json:"monthly"`
+				Weekly  int64 `json:"weekly"`
+			} `json:"downloads"`
+		} `json:"objects"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("npm: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.Objects))
+	for i, obj := range data.Objects {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+
+		pkg := obj.Package
+		pageURL := pkg.Links.NPM
+		if pageURL == "" {
+			pageURL = "https://www.npmjs.com/package/" + pkg.Name
+		}
+
+		var updated time.Time
+		if pkg.Date != "" {
+			if parsed, err := time.Parse(time.RFC3339, pkg.Date); err == nil {
+				updated = parsed
+			}
+		}
+
+		results = append(results, packageResult(e.Name(), "npm", e.GetPriority(), i, packageInfo{
+			Name:        pkg.Name,
+			Version:     pkg.Version,
+			Description: pkg.Description,
+			License:     pkg.License,
+			Downloads:   obj.Downloads.Monthly,
+			Repository:  pkg.Links.Repository,
+			URL:         pageURL,
+			Author:      pkg.Publisher.Username,
+			UpdatedAt:   updated,
+		}))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// Nyaa searches torrents of anime, manga and East Asian media through the
+// RSS feed of nyaa.si, which reports each torrent's peers and info hash
+type Nyaa struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewNyaa creates a new Nyaa engine
+func NewNyaa() *Nyaa {
+	config := model.NewEngineConfig("nyaa")
+	config.DisplayName = "Nyaa"
+	config.Priority = 40
+	config.Categories = []string{"files"}
+	config.SupportsTor = true
+
+	return &Nyaa{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// nyaaFeed is the RSS feed of a Nyaa search
+type nyaaFeed struct {
+	Items []struct {
+		Title string `xml:"title"`
+		// Link is the .torrent file, GUID the torrent's page
+		Link    string `xml:"link"`
+		GUID    string `xml:"guid"`
+		PubDate string `xml:"pubDate"`
+		// nyaa:seeders, nyaa:leechers, nyaa:infoHash, nyaa:size and
+		// nyaa:category
+		Seeders  string `xml:"seeders"`
+		Leechers string `xml:"leechers"`
+		InfoHash string `xml:"infoHash"`
+		Size     string `xml:"size"`
+		Category string `xml:"category"`
+	} `xml:"channel>item"`
+}
+
+// Search performs a Nyaa torrent search
+func (e *Nyaa) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// The feed holds the newest matches only
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
+	params := url.Values{}
+	params.Set("page", "rss")
+	params.Set("q", query.Text)
+	params.Set("c", "0_0")
+	params.Set("f", "0")
+	reqURL := fmt.Sprintf("https://nyaa.si/?%s", params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/xml;q=0.9")
+
+	resp, err := doRequest(e.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Nyaa returned status %d", resp.StatusCode)
+	}
+
+	var feed nyaaFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse Nyaa feed: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(feed.Items))
+	for i, item := range feed.Items {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		if item.GUID == "" || item.Title == "" {
+			continue
+		}
+		info := fileInfo{
+			Name:        strings.TrimSpace(item.Title),
+			URL:         item.GUID,
+			Description: item.Category,
+			Size:        parseFileSize(item.Size),
+			InfoHash:    strings.TrimSpace(item.InfoHash),
+			Torrent:     item.Link,
+		}
+		info.Seeders, _ = strconv.Atoi(item.Seeders)
+		info.Leechers, _ = strconv.Atoi(item.Leechers)
+		if added, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+			info.AddedAt = added.UTC()
+		}
+		results = append(results, fileResult(e.Name(), e.GetPriority(), i, info))
+	}
+
+	return results, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/apimgr/search/src/model"
@@ -16,11 +17,36 @@ import (
 	"golang.org/x/text/language"
 )
 
+// nominatimDefaultURL is the public OpenStreetMap Nominatim server
+const nominatimDefaultURL = "https://nominatim.openstreetmap.org"
+
 // OpenStreetMap implements OpenStreetMap/Nominatim search engine
 // Uses the Nominatim API for geocoding and location search
 type OpenStreetMap struct {
 	*search.BaseEngine
 	client *http.Client
+	// endpoint is the Nominatim base URL; nil means the public server.
+	// search.maps.nominatim replaces it on reload.
+	endpoint atomic.Pointer[string]
+}
+
+// SetEndpoint points the engine at the Nominatim server at base, such as
+// a self-hosted one; "" restores the public server
+func (e *OpenStreetMap) SetEndpoint(base string) {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	if base == "" {
+		e.endpoint.Store(nil)
+		return
+	}
+	e.endpoint.Store(&base)
+}
+
+// Endpoint returns the Nominatim base URL the engine searches
+func (e *OpenStreetMap) Endpoint() string {
+	if base := e.endpoint.Load(); base != nil {
+		return *base
+	}
+	return nominatimDefaultURL
 }
 
 // NewOpenStreetMap creates a new OpenStreetMap search engine
@@ -73,8 +99,13 @@ type nominatimResult struct {
 
 // Search performs an OpenStreetMap/Nominatim search
 func (e *OpenStreetMap) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// Nominatim has no paging
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
 	// Nominatim search API endpoint
-	baseURL := "https://nominatim.openstreetmap.org/search"
+	baseURL := e.Endpoint() + "/search"
 
 	params := url.Values{}
 	params.Set("q", query.Text)
@@ -102,7 +133,7 @@ func (e *OpenStreetMap) Search(ctx context.Context, query *model.Query) ([]model
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -150,10 +181,13 @@ func (e *OpenStreetMap) parseResults(nominatimResults []nominatimResult, query *
 		metadata["osm_id"] = nr.OSMID
 		metadata["place_type"] = nr.Type
 		metadata["place_class"] = nr.Class
+		if address := e.formatAddress(nr); address != "" {
+			metadata["address"] = address
+		}
 
-		// Add bounding box if available
-		if len(nr.BoundingBox) == 4 {
-			metadata["bbox"] = nr.BoundingBox
+		// Add bounding box if available: south, north, west, east
+		if bbox := parseBoundingBox(nr.BoundingBox); bbox != nil {
+			metadata["bbox"] = bbox
 		}
 
 		results = append(results, model.Result{
@@ -172,6 +206,23 @@ func (e *OpenStreetMap) parseResults(nominatimResults []nominatimResult, query *
 	return results
 }
 
+// parseBoundingBox reads Nominatim's boundingbox, four decimal strings
+// in the order south, north, west, east
+func parseBoundingBox(box []string) []float64 {
+	if len(box) != 4 {
+		return nil
+	}
+	bbox := make([]float64, 4)
+	for i, v := range box {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil
+		}
+		bbox[i] = f
+	}
+	return bbox
+}
+
 // buildTitle creates a title for the location result
 func (e *OpenStreetMap) buildTitle(nr nominatimResult) string {
 	// Get the primary name from the display name (first part)
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// packageInfo is the registry-neutral view of a package returned by the
+// packages category engines (npm, PyPI, crates.io, Go modules, Docker Hub).
+type packageInfo struct {
+	Name        string
+	Version     string
+	Description string
+	License     string
+	// Downloads is the registry's headline download/pull count (0 if unknown)
+	Downloads  int64
+	Repository string
+	URL        string
+	Author     string
+	UpdatedAt  time.Time
+}
+
+// packageResult converts packageInfo into a result in the packages category.
+// Registry fields are exposed through Metadata so the API and templates can
+// render version, license, downloads, and repository consistently.
+func packageResult(engineName, registry string, priority, position int, info packageInfo) model.Result {
+	metadata := map[string]interface{}{
+		"registry": registry,
+	}
+	if info.Version != "" {
+		metadata["version"] = info.Version
+	}
+	if info.License != "" {
+		metadata["license"] = info.License
+	}
+	if info.Downloads > 0 {
+		metadata["downloads"] = info.Downloads
+	}
+	if info.Repository != "" {
+		metadata["repository"] = normalizeRepositoryURL(info.Repository)
+	}
+
+	title := info.Name
+	if info.Version != "" {
+		title = fmt.Sprintf("%s %s", info.Name, info.Version)
+	}
+
+	desc := info.Description
+	if len(desc) > 300 {
+		desc = desc[:297] + "..."
+	}
+
+	return model.Result{
+		Title:       title,
+		URL:         info.URL,
+		Content:     desc,
+		Engine:      engineName,
+		Category:    model.CategoryPackages,
+		Author:      info.Author,
+		PublishedAt: info.UpdatedAt,
+		Popularity:  float64(info.Downloads),
+		Score:       calculateScore(priority, position, 1),
+		Position:    position,
+		Metadata:    metadata,
+	}
+}
+
+// normalizeRepositoryURL converts registry repository references such as
+// "git+https://github.com/a/b.git" or "git://github.com/a/b" into browsable URLs.
+func normalizeRepositoryURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "git+")
+	raw = strings.TrimSuffix(raw, ".git")
+	switch {
+	case strings.HasPrefix(raw, "git://"):
+		raw = "https://" + strings.TrimPrefix(raw, "git://")
+	case strings.HasPrefix(raw, "ssh://git@"):
+		raw = "https://" + strings.TrimPrefix(raw, "ssh://git@")
+	case strings.HasPrefix(raw, "git@"):
+		raw = "https://" + strings.Replace(strings.TrimPrefix(raw, "git@"), ":", "/", 1)
+	case strings.HasPrefix(raw, "github:"):
+		raw = "https://github.com/" + strings.TrimPrefix(raw, "github:")
+	}
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		return ""
+	}
+	return raw
+}
+
+// fetchRegistryJSON performs a GET request against a registry API and decodes
+// the JSON body into out. A 404 is reported as (false, nil) so exact-name
+// lookups can return no results instead of an engine failure.
+func fetchRegistryJSON(ctx context.Context, client *http.Client, reqURL string, out interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("registry API returned status %d", resp.StatusCode)
+	}
+
+	body, err := ReadBody(resp)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
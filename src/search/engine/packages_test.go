@@ -0,0 +1,292 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestPackageEnginesCategory(t *testing.T) {
+	engines := []struct {
+		name   string
+		engine interface {
+			Name() string
+			SupportsCategory(model.Category) bool
+		}
+	}{
+		{"npm", NewNPM()},
+		{"pypi", NewPyPI()},
+		{"crates", NewCrates()},
+		{"gomodules", NewGoModules()},
+		{"dockerhub", NewDockerHub()},
+	}
+
+	for _, tt := range engines {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.engine.Name() != tt.name {
+				t.Errorf("Name() = %q, want %q", tt.engine.Name(), tt.name)
+			}
+			if !tt.engine.SupportsCategory(model.CategoryPackages) {
+				t.Errorf("%s should support CategoryPackages", tt.name)
+			}
+			if tt.engine.SupportsCategory(model.CategoryGeneral) {
+				t.Errorf("%s should not run for CategoryGeneral", tt.name)
+			}
+		})
+	}
+}
+
+func TestDefaultRegistryPackageEngines(t *testing.T) {
+	registry := DefaultRegistry()
+	engines := registry.GetForCategory(model.CategoryPackages)
+	if len(engines) != 5 {
+		t.Errorf("GetForCategory(packages) returned %d engines, want 5", len(engines))
+	}
+}
+
+func TestNormalizeRepositoryURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"git+https://github.com/expressjs/express.git", "https://github.com/expressjs/express"},
+		{"git://github.com/a/b.git", "https://github.com/a/b"},
+		{"git@github.com:a/b.git", "https://github.com/a/b"},
+		{"ssh://git@gitlab.com/a/b", "https://gitlab.com/a/b"},
+		{"github:a/b", "https://github.com/a/b"},
+		{"https://example.com/repo", "https://example.com/repo"},
+		{"not a url", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRepositoryURL(tt.input); got != tt.want {
+			t.Errorf("normalizeRepositoryURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNPMSearch(t *testing.T) {
+	payload := `{"objects":[{"package":{"name":"express","version":"4.19.2",
+		"description":"Fast, unopinionated, minimalist web framework","license":"MIT",
+		"date":"2024-03-25T00:00:00Z","publisher":{"username":"wesleytodd"},
+		"links":{"npm":"https://www.npmjs.com/package/express",
+		"repository":"git+https://github.com/expressjs/express.git"}},
+		"downloads":{"monthly":120000000,"weekly":30000000}}]}`
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewNPM()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "express", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !strings.HasPrefix(gotPath, "/-/v1/search?") {
+		t.Errorf("request path = %q, want registry search API", gotPath)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.Category != model.CategoryPackages {
+		t.Errorf("Category = %q, want packages", r.Category)
+	}
+	if r.Title != "express 4.19.2" {
+		t.Errorf("Title = %q", r.Title)
+	}
+	if r.Metadata["version"] != "4.19.2" || r.Metadata["license"] != "MIT" {
+		t.Errorf("Metadata = %v, want version and license", r.Metadata)
+	}
+	if r.Metadata["downloads"] != int64(120000000) {
+		t.Errorf("downloads = %v, want 120000000", r.Metadata["downloads"])
+	}
+	if r.Metadata["repository"] != "https://github.com/expressjs/express" {
+		t.Errorf("repository = %v", r.Metadata["repository"])
+	}
+}
+
+func TestPyPISearch(t *testing.T) {
+	payload := `{"info":{"name":"Requests","version":"2.32.3","summary":"Python HTTP for Humans.",
+		"license":"Apache-2.0","author":"Kenneth Reitz","package_url":"https://pypi.org/project/requests/",
+		"project_urls":{"Source":"https://github.com/psf/requests"}},
+		"urls":[{"upload_time_iso_8601":"2024-05-29T15:37:47.000000Z"}]}`
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewPyPI()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "Requests", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if gotPath != "/pypi/requests/json" {
+		t.Errorf("request path = %q, want /pypi/requests/json", gotPath)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	if results[0].Metadata["repository"] != "https://github.com/psf/requests" {
+		t.Errorf("repository = %v", results[0].Metadata["repository"])
+	}
+	if results[0].PublishedAt.IsZero() {
+		t.Error("PublishedAt should be parsed from upload time")
+	}
+}
+
+func TestPyPISearchNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	engine := NewPyPI()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "no-such-project", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v, want nil for unknown project", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Search() returned %d results, want 0", len(results))
+	}
+}
+
+func TestPyPILicense(t *testing.T) {
+	if got := pypiLicense("MIT", nil); got != "MIT" {
+		t.Errorf("pypiLicense(MIT) = %q", got)
+	}
+	long := strings.Repeat("Permission is hereby granted ", 10)
+	classifiers := []string{"Programming Language :: Python", "License :: OSI Approved :: BSD License"}
+	if got := pypiLicense(long, classifiers); got != "BSD License" {
+		t.Errorf("pypiLicense(long text) = %q, want classifier fallback", got)
+	}
+}
+
+func TestCratesSearch(t *testing.T) {
+	payload := `{"crates":[{"name":"serde","max_version":"1.0.210","max_stable_version":"1.0.210",
+		"description":"A generic serialization/deserialization framework","downloads":400000000,
+		"repository":"https://github.com/serde-rs/serde","updated_at":"2024-09-06T00:00:00+00:00"}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewCrates()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "serde", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	if results[0].URL != "https://crates.io/crates/serde" {
+		t.Errorf("URL = %q", results[0].URL)
+	}
+	if results[0].Metadata["downloads"] != int64(400000000) {
+		t.Errorf("downloads = %v", results[0].Metadata["downloads"])
+	}
+}
+
+func TestGoModulesSearch(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		fmt.Fprint(w, `{"Version":"v1.0.1","Time":"2024-01-01T00:00:00Z"}`)
+	}))
+	defer server.Close()
+
+	engine := NewGoModules()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "github.com/BurntSushi/toml", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if gotPath != "/github.com/!burnt!sushi/toml/@latest" {
+		t.Errorf("request path = %q, want case-encoded module path", gotPath)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	if results[0].Metadata["repository"] != "https://github.com/BurntSushi/toml" {
+		t.Errorf("repository = %v", results[0].Metadata["repository"])
+	}
+}
+
+func TestGoModulesSkipsNonModuleQuery(t *testing.T) {
+	engine := NewGoModules()
+	engine.client = &http.Client{Transport: redirectToServer("http://127.0.0.1:1")}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "json parser", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Search() returned %d results for non-module query, want 0", len(results))
+	}
+}
+
+func TestDockerHubSearch(t *testing.T) {
+	payload := `{"results":[
+		{"repo_name":"nginx","short_description":"Official build of Nginx.","star_count":20000,"pull_count":1000000000,"is_official":true},
+		{"repo_name":"bitnami/nginx","short_description":"Bitnami nginx","star_count":100,"pull_count":5000,"repo_owner":"","is_official":false}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewDockerHub()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "nginx", Page: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results))
+	}
+	if results[0].URL != "https://hub.docker.com/_/nginx" {
+		t.Errorf("official URL = %q", results[0].URL)
+	}
+	if results[1].URL != "https://hub.docker.com/r/bitnami/nginx" || results[1].Author != "bitnami" {
+		t.Errorf("community result = %q by %q", results[1].URL, results[1].Author)
+	}
+}
+
+func TestPackageEngineServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	engine := NewCrates()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	if _, err := engine.Search(context.Background(), &model.Query{Text: "serde", Page: 1}); err == nil {
+		t.Error("Search() should return an error on 503")
+	}
+}
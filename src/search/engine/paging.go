@@ -0,0 +1,25 @@
+package engine
+
+import "github.com/apimgr/search/src/model"
+
+// Engines page differently upstream: by page number, by result offset, or
+// not at all. These helpers map the aggregator's 1-based engine page onto
+// an engine's own parameters; an engine that cannot page answers a later
+// page with no results, like PyPI does, rather than repeating page one.
+
+// pageNumber is query's engine page, 1 or more
+func pageNumber(query *model.Query) int {
+	return max(query.Page, 1)
+}
+
+// pageOffset is the zero-based index of the first result of query's engine
+// page, for an upstream that returns perPage results a page
+func pageOffset(query *model.Query, perPage int) int {
+	return (pageNumber(query) - 1) * perPage
+}
+
+// firstPageOnly reports whether query asks for a later page of an engine
+// that only has one
+func firstPageOnly(query *model.Query) bool {
+	return pageNumber(query) > 1
+}
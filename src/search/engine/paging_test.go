@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+func TestEnginePaging(t *testing.T) {
+	var mu sync.Mutex
+	var captured url.Values
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		captured = r.Form
+		mu.Unlock()
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+	origTransport := SharedTransport
+	SharedTransport = dialToTLSTransport(srv)
+	defer func() { SharedTransport = origTransport }()
+
+	tests := []struct {
+		engine search.Engine
+		param  string
+		want   string
+	}{
+		{NewGitHub(), "page", "3"},
+		{NewHackerNews(), "page", "2"},
+		{NewStackOverflow(), "page", "3"},
+		{NewBrave(), "offset", "2"},
+		{NewYahoo(), "b", "15"},
+		{NewStartpageEngine(), "page", "3"},
+		{NewQwantEngine(), "offset", "20"},
+		{NewWikipediaEngine(), "gsroffset", "20"},
+		{NewDuckDuckGo(), "s", "25"},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		captured = nil
+		mu.Unlock()
+		tt.engine.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, Page: 3})
+		mu.Lock()
+		got := captured.Get(tt.param)
+		mu.Unlock()
+		if got != tt.want {
+			t.Errorf("%T page 3: %s = %q, want %q", tt.engine, tt.param, got, tt.want)
+		}
+	}
+
+	// Engines without paging answer a later page with nothing, unasked
+	for _, e := range []search.Engine{NewReddit(), NewYouTubeEngine(), NewWolframAlpha(), NewOpenStreetMap()} {
+		mu.Lock()
+		captured = nil
+		mu.Unlock()
+		results, err := e.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, Page: 2})
+		mu.Lock()
+		asked := captured != nil
+		mu.Unlock()
+		if err != nil || len(results) != 0 || asked {
+			t.Errorf("%T page 2 = %d results, %v, asked upstream %v", e, len(results), err, asked)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// peertubePerPage is how many videos a Sepia Search page holds
+const peertubePerPage = 20
+
+// PeerTube searches the videos of the federated PeerTube instances through
+// Sepia Search, the index run by the PeerTube developers. Results link to
+// the video on the instance hosting it.
+type PeerTube struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewPeerTube creates a new PeerTube engine
+func NewPeerTube() *PeerTube {
+	config := model.NewEngineConfig("peertube")
+	config.DisplayName = "PeerTube"
+	config.Priority = 55
+	config.Categories = []string{"videos"}
+	config.SupportsTor = true
+	config.SupportsSafeSearch = true
+
+	return &PeerTube{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs a Sepia Search video search
+func (e *PeerTube) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("search", query.Text)
+	params.Set("start", strconv.Itoa(pageOffset(query, peertubePerPage)))
+	params.Set("count", strconv.Itoa(peertubePerPage))
+	params.Set("sort", "-match")
+	// Videos their uploader marked sensitive are left out unless safe
+	// search is off
+	if query.SafeSearch > 0 {
+		params.Set("nsfw", "false")
+	}
+
+	reqURL := fmt.Sprintf("https://sepiasearch.org/api/v1/search/videos?%s", params.Encode())
+
+	var data struct {
+		Data []struct {
+			Name         string    `json:"name"`
+			Description  string    `json:"description"`
+			Duration     int       `json:"duration"`
+			URL          string    `json:"url"`
+			ThumbnailURL string    `json:"thumbnailUrl"`
+			PublishedAt  time.Time `json:"publishedAt"`
+			Views        int64     `json:"views"`
+			Channel      struct {
+				DisplayName string `json:"displayName"`
+				URL         string `json:"url"`
+			} `json:"channel"`
+		} `json:"data"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("peertube: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data.Data))
+	for i, video := range data.Data {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		if video.URL == "" || video.Name == "" {
+			continue
+		}
+		results = append(results, videoResult(e.Name(), e.GetPriority(), i, videoInfo{
+			Title:       video.Name,
+			URL:         video.URL,
+			Description: video.Description,
+			Thumbnail:   video.ThumbnailURL,
+			Duration:    video.Duration,
+			Channel:     video.Channel.DisplayName,
+			ChannelURL:  video.Channel.URL,
+			Views:       video.Views,
+			PublishedAt: video.PublishedAt,
+		}))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// pirateBayNoResults is the info hash of the placeholder entry the API
+// returns when nothing matches
+const pirateBayNoResults = "0000000000000000000000000000000000000000"
+
+// PirateBay searches torrents through the JSON API behind The Pirate Bay,
+// which reports each torrent's seeders, leechers, size and info hash
+type PirateBay struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewPirateBay creates a new Pirate Bay engine
+func NewPirateBay() *PirateBay {
+	config := model.NewEngineConfig("piratebay")
+	config.DisplayName = "The Pirate Bay"
+	config.Priority = 50
+	config.Categories = []string{"files"}
+	config.SupportsTor = true
+
+	return &PirateBay{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// Search performs a Pirate Bay torrent search
+func (e *PirateBay) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// The API returns one list with no paging
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
+	params := url.Values{}
+	params.Set("q", query.Text)
+	params.Set("cat", "0")
+	reqURL := fmt.Sprintf("https://apibay.org/q.php?%s", params.Encode())
+
+	// Every field is a string
+	var data []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		InfoHash string `json:"info_hash"`
+		Seeders  string `json:"seeders"`
+		Leechers string `json:"leechers"`
+		Size     string `json:"size"`
+		Username string `json:"username"`
+		Added    string `json:"added"`
+	}
+
+	if _, err := fetchRegistryJSON(ctx, e.client, reqURL, &data); err != nil {
+		return nil, fmt.Errorf("piratebay: %w", err)
+	}
+
+	results := make([]model.Result, 0, len(data))
+	for i, torrent := range data {
+		if i >= e.GetConfig().GetMaxResults() {
+			break
+		}
+		if torrent.InfoHash == "" || torrent.InfoHash == pirateBayNoResults || torrent.Name == "" {
+			continue
+		}
+		info := fileInfo{
+			Name:     torrent.Name,
+			URL:      "https://thepiratebay.org/description.php?id=" + url.QueryEscape(torrent.ID),
+			Size:     parseFileSize(torrent.Size),
+			InfoHash: strings.TrimSpace(torrent.InfoHash),
+			Uploader: torrent.Username,
+		}
+		info.Seeders, _ = strconv.Atoi(torrent.Seeders)
+		info.Leechers, _ = strconv.Atoi(torrent.Leechers)
+		if added, err := strconv.ParseInt(torrent.Added, 10, 64); err == nil && added > 0 {
+			info.AddedAt = time.Unix(added, 0).UTC()
+		}
+		results = append(results, fileResult(e.Name(), e.GetPriority(), i, info))
+	}
+
+	return results, nil
+}
@@ -166,7 +166,7 @@ func (e *PubMed) searchIDs(ctx context.Context, query *model.Query) ([]string, e
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/xml")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -213,7 +213,7 @@ func (e *PubMed) fetchArticles(ctx context.Context, ids []string) ([]pubmedArtic
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/xml")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
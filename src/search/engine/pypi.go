@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// pypiNameSeparators matches runs of characters PEP 503 treats as equivalent
+var pypiNameSeparators = regexp.MustCompile(`[-_.\s]+`)
+
+// PyPI implements Python Package Index lookups.
+// PyPI has no JSON search API, so the query is normalized per PEP 503 and
+// resolved as an exact project name through the JSON API.
+type PyPI struct {
+	*search.BaseEngine
+	client *http.Client
+}
+
+// NewPyPI creates a new PyPI engine
+func NewPyPI() *PyPI {
+	config := model.NewEngineConfig("pypi")
+	config.DisplayName = "PyPI"
+	config.Priority = 58
+	config.Categories = []string{"packages"}
+	config.SupportsTor = true
+
+	return &PyPI{
+		BaseEngine: search.NewBaseEngine(config),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetTimeout()) * time.Second,
+			Transport: SharedTransport,
+		},
+	}
+}
+
+// normalizePyPIName normalizes a project name per PEP 503
+func normalizePyPIName(name string) string {
+	return strings.ToLower(pypiNameSeparators.ReplaceAllString(strings.TrimSpace(name), "-"))
+}
+
+// Search looks up the query as a PyPI project name
+func (e *PyPI) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	name := normalizePyPIName(query.Text)
+	if name == "" || query.Page > 1 {
+		return []model.Result{}, nil
+	}
+
+	reqURL := fmt.Sprintf("https://pypi.org/pypi/%s/json", url.PathEscape(name))
+
+	var data struct {
+		Info struct {
+			Name        string            `json:"name"`
+			Version     string            `json:"version"`
+			Summary     string            `json:"summary"`
+			License     string            `json:"license"`
+			Author      string            `json:"author"`
+			HomePage    string            `json:"home_page"`
+			PackageURL  string            `json:"package_url"`
+			ProjectURLs map[string]string `json:"project_urls"`
+			Classifiers []string          `json:"classifiers"`
+		} `json:"info"`
+		URLs []struct {
+			UploadTime string `json:"upload_time_iso_8601"`
+		} `json:"urls"`
+	}
+
+	found, err := fetchRegistryJSON(ctx, e.client, reqURL, &data)
+	if err != nil {
+		return nil, fmt.Errorf("pypi: %w", err)
+	}
+	if !found || data.Info.Name == "" {
+		return []model.Result{}, nil
+	}
+
+	info := data.Info
+	pageURL := info.PackageURL
+	if pageURL == "" {
+		pageURL = fmt.Sprintf("https://pypi.org/project/%s/", url.PathEscape(info.Name))
+	}
+
+	var updated time.Time
+	if len(data.URLs) > 0 {
+		if parsed, err := time.Parse(time.RFC3339, data.URLs[0].UploadTime); err == nil {
+			updated = parsed
+		}
+	}
+
+	return []model.Result{
+		packageResult(e.Name(), "pypi", e.GetPriority(), 0, packageInfo{
+			Name:        info.Name,
+			Version:     info.Version,
+			Description: info.Summary,
+			License:     pypiLicense(info.License, info.Classifiers),
+			Repository:  pypiRepository(info.ProjectURLs, info.HomePage),
+			URL:         pageURL,
+			Author:      info.Author,
+			UpdatedAt:   updated,
+		}),
+	}, nil
+}
+
+// pypiLicense prefers the short license field and falls back to the
+// "License :: OSI Approved :: X" trove classifier when the field holds
+// full license text or is empty.
+func pypiLicense(license string, classifiers []string) string {
+	license = strings.TrimSpace(license)
+	if license != "" && len(license) <= 64 && !strings.Contains(license, "\n") {
+		return license
+	}
+	for _, c := range classifiers {
+		if strings.HasPrefix(c, "License ::") {
+			parts := strings.Split(c, "::")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	return ""
+}
+
+// pypiRepository picks the source repository from project_urls
+func pypiRepository(projectURLs map[string]string, homePage string) string {
+	for _, key := range []string{"Source", "Source Code", "Repository", "Code", "GitHub", "Homepage"} {
+		for k, v := range projectURLs {
+			if strings.EqualFold(k, key) && v != "" {
+				return v
+			}
+		}
+	}
+	return homePage
+}
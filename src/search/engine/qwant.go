@@ -21,6 +21,7 @@ func NewQwantEngine() *QwantEngine {
 	config.DisplayName = "Qwant"
 	config.Categories = []string{"general", "images", "videos", "news", "files", "music"}
 	config.Priority = 75
+	config.SupportsSafeSearch = true
 
 	return &QwantEngine{
 		BaseEngine: search.NewBaseEngine(config),
@@ -44,7 +45,7 @@ type qwantResponse struct {
 }
 
 func (e *QwantEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
-	offset := query.Page * 10
+	offset := pageOffset(query, 10)
 
 	var qwantCategory string
 	switch query.Category {
@@ -58,8 +59,9 @@ func (e *QwantEngine) Search(ctx context.Context, query *model.Query) ([]model.R
 		qwantCategory = "web"
 	}
 
-	searchURL := fmt.Sprintf("https://api.qwant.com/v3/search/%s?q=%s&count=10&offset=%d&locale=en_US",
-		qwantCategory, url.QueryEscape(query.Text), offset)
+	// Qwant takes the level as is: 0 off, 1 moderate, 2 strict
+	searchURL := fmt.Sprintf("https://api.qwant.com/v3/search/%s?q=%s&count=10&offset=%d&locale=en_US&safesearch=%d",
+		qwantCategory, url.QueryEscape(query.Text), offset, query.SafeSearch)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
@@ -69,7 +71,7 @@ func (e *QwantEngine) Search(ctx context.Context, query *model.Query) ([]model.R
 	req.Header.Set("User-Agent", UserAgent)
 
 	client := &http.Client{Timeout: 10 * time.Second, Transport: SharedTransport}
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
 		return nil, err
 	}
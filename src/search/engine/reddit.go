@@ -25,6 +25,7 @@ func NewReddit() *Reddit {
 	config.Priority = 45
 	config.Categories = []string{"general", "social"}
 	config.SupportsTor = true
+	config.SupportsSafeSearch = true
 
 	return &Reddit{
 		BaseEngine: search.NewBaseEngine(config),
@@ -37,6 +38,11 @@ func NewReddit() *Reddit {
 
 // Search performs a Reddit search
 func (e *Reddit) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// Reddit pages with an opaque cursor from the previous answer
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
 	// old.reddit.com JSON API (avoids OAuth requirement on www.reddit.com)
 	searchURL := "https://old.reddit.com/search.json"
 
@@ -45,6 +51,9 @@ func (e *Reddit) Search(ctx context.Context, query *model.Query) ([]model.Result
 	params.Set("sort", "relevance")
 	params.Set("limit", "10")
 	params.Set("type", "link")
+	if query.SafeSearch == 0 {
+		params.Set("include_over_18", "on")
+	}
 
 	reqURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
 
@@ -56,7 +65,7 @@ func (e *Reddit) Search(ctx context.Context, query *model.Query) ([]model.Result
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +88,7 @@ func (e *Reddit) Search(ctx context.Context, query *model.Query) ([]model.Result
 					URL         string  `json:"url"`
 					CreatedUTC  float64 `json:"created_utc"`
 					IsSelf      bool    `json:"is_self"`
+					Over18      bool    `json:"over_18"`
 				} `json:"data"`
 			} `json:"children"`
 		} `json:"data"`
@@ -96,6 +106,10 @@ func (e *Reddit) Search(ctx context.Context, query *model.Query) ([]model.Result
 		}
 
 		item := child.Data
+		// NSFW posts can still come back, from subreddits searched by name
+		if item.Over18 && query.SafeSearch > 0 {
+			continue
+		}
 
 		// Build the full URL using old.reddit.com for consistency
 		postURL := fmt.Sprintf("https://old.reddit.com%s", item.Permalink)
@@ -121,6 +121,10 @@ func DefaultRegistry() *Registry {
 	registry.Register(NewHackerNews())
 	registry.Register(NewStartpageEngine())
 	registry.Register(NewYouTubeEngine())
+	registry.Register(NewPeerTube())
+	registry.Register(NewDailymotion())
+	registry.Register(NewBingNews())
+	registry.Register(NewGDELT())
 	// Additional engines per IDEA.md
 	registry.Register(NewMojeek())
 	registry.Register(NewYandex())
@@ -132,7 +136,22 @@ func DefaultRegistry() *Registry {
 
 	// Specialized engines
 	// WolframAlpha is omitted: JS-rendered page, no open API without key.
+	// Vimeo is omitted for the same reason; Vimeo videos found by the
+	// general engines still play on the watch page.
 	registry.Register(NewOpenStreetMap())
+	registry.Register(NewCVE())
+
+	// Package registry engines
+	registry.Register(NewNPM())
+	registry.Register(NewPyPI())
+	registry.Register(NewCrates())
+	registry.Register(NewGoModules())
+	registry.Register(NewDockerHub())
+
+	// File and torrent index engines
+	registry.Register(NewArchiveOrg())
+	registry.Register(NewPirateBay())
+	registry.Register(NewNyaa())
 
 	return registry
 }
@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// Reverse image search. Yandex and Bing take an image URL as a query
+// parameter, or an upload as a multipart form, and answer with a page
+// listing the sites that show the image.
+
+var (
+	// yandexCbirSitesRe captures the data-state JSON of the "sites with
+	// this image" block
+	yandexCbirSitesRe = regexp.MustCompile(`<div\b[^>]*\bclass="[^"]*CbirSites[^"]*"[^>]*\bdata-state="([^"]+)"`)
+
+	// bingIuscRe captures the metadata JSON of each image tile
+	bingIuscRe = regexp.MustCompile(`<a\b[^>]*\bclass="[^"]*\biusc\b[^"]*"[^>]*\bm="([^"]+)"`)
+)
+
+// ReverseImageSearch finds the sites that show img on Yandex
+func (e *Yandex) ReverseImageSearch(ctx context.Context, img *search.ReverseImage) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("rpt", "imageview")
+	if img.URL != "" {
+		params.Set("url", img.URL)
+	} else {
+		cbir, err := e.uploadImage(ctx, img)
+		if err != nil {
+			return nil, err
+		}
+		params = cbir
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://yandex.com/images/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	body, err := reverseImageDo(e.client, req, "yandex")
+	if err != nil {
+		return nil, err
+	}
+	return e.parseReverseResults(string(body))
+}
+
+// uploadImage sends img to Yandex and returns the query of the page
+// describing it
+func (e *Yandex) uploadImage(ctx context.Context, img *search.ReverseImage) (url.Values, error) {
+	params := url.Values{}
+	params.Set("rpt", "imageview")
+	params.Set("format", "json")
+	params.Set("request", `{"blocks":[{"block":"b-page_type_search-by-image__link"}]}`)
+	req, err := newImageUpload(ctx, "https://yandex.com/images/search?"+params.Encode(), "upfile", img.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := reverseImageDo(e.client, req, "yandex")
+	if err != nil {
+		return nil, err
+	}
+	var upload struct {
+		Blocks []struct {
+			Params struct {
+				URL string `json:"url"`
+			} `json:"params"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(body, &upload); err != nil {
+		return nil, fmt.Errorf("yandex image upload: %w", err)
+	}
+	if len(upload.Blocks) == 0 || upload.Blocks[0].Params.URL == "" {
+		return nil, fmt.Errorf("yandex image upload returned no image id")
+	}
+	return url.ParseQuery(upload.Blocks[0].Params.URL)
+}
+
+// parseReverseResults parses the sites block of a Yandex image page
+func (e *Yandex) parseReverseResults(page string) ([]model.Result, error) {
+	m := yandexCbirSitesRe.FindStringSubmatch(page)
+	if m == nil {
+		return nil, nil
+	}
+	var state struct {
+		Sites []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Description   string `json:"description"`
+			OriginalImage struct {
+				URL    string `json:"url"`
+				Width  int    `json:"width"`
+				Height int    `json:"height"`
+			} `json:"originalImage"`
+			Thumb struct {
+				URL string `json:"url"`
+			} `json:"thumb"`
+		} `json:"sites"`
+	}
+	if err := json.Unmarshal([]byte(html.UnescapeString(m[1])), &state); err != nil {
+		return nil, fmt.Errorf("yandex reverse image results: %w", err)
+	}
+
+	maxResults := e.GetConfig().GetMaxResults()
+	results := make([]model.Result, 0, min(len(state.Sites), maxResults))
+	for _, site := range state.Sites {
+		if len(results) >= maxResults {
+			break
+		}
+		if !strings.HasPrefix(site.URL, "http") {
+			continue
+		}
+		results = append(results, model.Result{
+			Title:       strings.TrimSpace(site.Title),
+			URL:         site.URL,
+			Content:     strings.TrimSpace(site.Description),
+			Thumbnail:   absoluteURL(site.Thumb.URL),
+			ImageWidth:  site.OriginalImage.Width,
+			ImageHeight: site.OriginalImage.Height,
+			Engine:      e.Name(),
+			Category:    model.CategoryImages,
+			Score:       calculateScore(e.GetPriority(), len(results), 1),
+			Position:    len(results),
+		})
+	}
+	return results, nil
+}
+
+// ReverseImageSearch finds pages showing img, or similar images, on Bing
+func (e *BingEngine) ReverseImageSearch(ctx context.Context, img *search.ReverseImage) ([]model.Result, error) {
+	params := url.Values{}
+	params.Set("view", "detailv2")
+	var req *http.Request
+	var err error
+	if img.URL != "" {
+		params.Set("iss", "sbi")
+		params.Set("q", "imgurl:"+img.URL)
+		req, err = http.NewRequestWithContext(ctx, "GET", "https://www.bing.com/images/search?"+params.Encode(), nil)
+	} else {
+		// Bing wants the upload base64 encoded
+		params.Set("iss", "sbiupload")
+		data := []byte(base64.StdEncoding.EncodeToString(img.Data))
+		req, err = newImageUpload(ctx, "https://www.bing.com/images/search?"+params.Encode(), "imageBin", data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	body, err := reverseImageDo(e.client, req, "bing")
+	if err != nil {
+		return nil, err
+	}
+	return e.parseReverseResults(string(body))
+}
+
+// parseReverseResults parses the image tiles of a Bing image page
+func (e *BingEngine) parseReverseResults(page string) ([]model.Result, error) {
+	maxResults := e.GetConfig().GetMaxResults()
+	var results []model.Result
+	for _, m := range bingIuscRe.FindAllStringSubmatch(page, -1) {
+		if len(results) >= maxResults {
+			break
+		}
+		var tile struct {
+			PageURL  string `json:"purl"`
+			ImageURL string `json:"murl"`
+			ThumbURL string `json:"turl"`
+			Title    string `json:"t"`
+			Desc     string `json:"desc"`
+		}
+		if err := json.Unmarshal([]byte(html.UnescapeString(m[1])), &tile); err != nil || !strings.HasPrefix(tile.PageURL, "http") {
+			continue
+		}
+		thumb := tile.ThumbURL
+		if thumb == "" {
+			thumb = tile.ImageURL
+		}
+		results = append(results, model.Result{
+			Title:     strings.TrimSpace(tile.Title),
+			URL:       tile.PageURL,
+			Content:   strings.TrimSpace(tile.Desc),
+			Thumbnail: thumb,
+			Engine:    e.Name(),
+			Category:  model.CategoryImages,
+			Score:     calculateScore(e.GetPriority(), len(results), 1),
+			Position:  len(results),
+		})
+	}
+	return results, nil
+}
+
+// newImageUpload builds a POST of data as the multipart file field
+func newImageUpload(ctx context.Context, uploadURL, field string, data []byte) (*http.Request, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile(field, "image")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req, nil
+}
+
+// reverseImageDo sends req and returns the body of a 200 response
+func reverseImageDo(client *http.Client, req *http.Request, name string) ([]byte, error) {
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s reverse image search returned status %d", name, resp.StatusCode)
+	}
+	return ReadBody(resp)
+}
+
+// absoluteURL adds the scheme to a protocol-relative URL
+func absoluteURL(u string) string {
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
+	return u
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+func TestImageFilterParams(t *testing.T) {
+	tests := []struct {
+		color, license string
+		ddg, google    string
+	}{
+		{"", "", ",,,,,", ""},
+		{"monochrome", "", ",color:Monochrome,,,,", "ic:gray"},
+		{"teal", "modify_commercial", ",color:Teal,,,license:ModifyCommercially,", "ic:specific,isc:teal,il:cl"},
+		{"color", "public", ",color:color,,,license:Public,", "ic:color,il:cl"},
+	}
+	for _, tt := range tests {
+		q := &model.Query{Category: model.CategoryImages, ImageColor: tt.color, ImageLicense: tt.license}
+		if got := ddgImageFilters(q); got != tt.ddg {
+			t.Errorf("ddgImageFilters(%q, %q) = %q, want %q", tt.color, tt.license, got, tt.ddg)
+		}
+		if got := googleImageFilters(q); got != tt.google {
+			t.Errorf("googleImageFilters(%q, %q) = %q, want %q", tt.color, tt.license, got, tt.google)
+		}
+	}
+
+	for _, e := range []search.Engine{NewDuckDuckGo(), NewGoogle()} {
+		if !e.GetConfig().SupportsImageFilters {
+			t.Errorf("%s does not report image filter support", e.Name())
+		}
+	}
+}
+
+func TestVideoFilterParams(t *testing.T) {
+	tests := []struct {
+		length, quality string
+		google, youtube string
+	}{
+		{"", "", "", ""},
+		{"short", "", "dur:s", "EgIYAQ=="},
+		{"medium", "hd", "dur:m,hq:h", "EgQYAyAB"},
+		{"", "hd", "hq:h", "EgIgAQ=="},
+	}
+	for _, tt := range tests {
+		q := &model.Query{Category: model.CategoryVideos, VideoLength: tt.length, VideoQuality: tt.quality}
+		if got := googleVideoFilters(q); got != tt.google {
+			t.Errorf("googleVideoFilters(%q, %q) = %q, want %q", tt.length, tt.quality, got, tt.google)
+		}
+		if got := youtubeFilterParam(q); got != tt.youtube {
+			t.Errorf("youtubeFilterParam(%q, %q) = %q, want %q", tt.length, tt.quality, got, tt.youtube)
+		}
+	}
+
+	for _, e := range []search.Engine{NewDuckDuckGo(), NewGoogle(), NewYouTubeEngine()} {
+		if !e.GetConfig().SupportsVideoFilters {
+			t.Errorf("%s does not report video filter support", e.Name())
+		}
+	}
+}
+
+func TestYandexParseReverseResults(t *testing.T) {
+	page := `<div class="CbirSites CbirSites_infinite" data-state="{&quot;sites&quot;:[` +
+		`{&quot;title&quot;:&quot;Cat on a mat&quot;,&quot;url&quot;:&quot;https://cats.example/mat&quot;,&quot;description&quot;:&quot;A cat.&quot;,` +
+		`&quot;originalImage&quot;:{&quot;url&quot;:&quot;https://cats.example/mat.jpg&quot;,&quot;width&quot;:800,&quot;height&quot;:600},` +
+		`&quot;thumb&quot;:{&quot;url&quot;:&quot;//avatars.mds.yandex.net/i?id=1&quot;}},` +
+		`{&quot;title&quot;:&quot;Internal&quot;,&quot;url&quot;:&quot;/images/search?rpt=imageview&quot;}]}"></div>`
+
+	results, err := NewYandex().parseReverseResults(page)
+	if err != nil {
+		t.Fatalf("parseReverseResults: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	r := results[0]
+	if r.Title != "Cat on a mat" || r.URL != "https://cats.example/mat" || r.Content != "A cat." ||
+		r.Thumbnail != "https://avatars.mds.yandex.net/i?id=1" || r.ImageWidth != 800 || r.Category != model.CategoryImages {
+		t.Errorf("result = %+v", r)
+	}
+
+	if results, err := NewYandex().parseReverseResults(`<html>captcha</html>`); err != nil || len(results) != 0 {
+		t.Errorf("page without sites: %v, %v", results, err)
+	}
+}
+
+func TestBingParseReverseResults(t *testing.T) {
+	page := `<a class="iusc" m="{&quot;purl&quot;:&quot;https://cats.example/mat&quot;,&quot;murl&quot;:&quot;https://cats.example/mat.jpg&quot;,&quot;t&quot;:&quot;Cat on a mat&quot;}" href="#">` +
+		`<a class="iusc" m="not json">` +
+		`<a class="iusc" m="{&quot;purl&quot;:&quot;https://dogs.example/&quot;,&quot;turl&quot;:&quot;https://tse.example/th?id=2&quot;,&quot;t&quot;:&quot;Dog&quot;}">`
+
+	results, err := NewBing().parseReverseResults(page)
+	if err != nil {
+		t.Fatalf("parseReverseResults: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].URL != "https://cats.example/mat" || results[0].Thumbnail != "https://cats.example/mat.jpg" || results[0].Title != "Cat on a mat" {
+		t.Errorf("first result = %+v", results[0])
+	}
+	if results[1].Thumbnail != "https://tse.example/th?id=2" || results[1].Position != 1 {
+		t.Errorf("second result = %+v", results[1])
+	}
+}
+
+func TestReverseImageEngines(t *testing.T) {
+	for _, e := range []search.Engine{NewYandex(), NewBing()} {
+		if _, ok := e.(search.ReverseImageEngine); !ok {
+			t.Errorf("%s cannot search by image", e.Name())
+		}
+	}
+	if u, _ := url.Parse(absoluteURL("//example.com/a.jpg")); u.Scheme != "https" {
+		t.Errorf("absoluteURL kept a protocol-relative URL")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/apimgr/search/src/model"
@@ -46,6 +47,7 @@ func (e *StackOverflow) Search(ctx context.Context, query *model.Query) ([]model
 	params.Set("order", "desc")
 	params.Set("sort", "relevance")
 	params.Set("pagesize", "10")
+	params.Set("page", strconv.Itoa(pageNumber(query)))
 	params.Set("filter", "withbody")
 
 	reqURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
@@ -58,7 +60,7 @@ func (e *StackOverflow) Search(ctx context.Context, query *model.Query) ([]model
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
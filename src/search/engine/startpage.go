@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,7 @@ func NewStartpageEngine() *Startpage {
 	config.Priority = 70
 	config.Categories = []string{"general", "images", "files", "music"}
 	config.SupportsTor = true
+	config.SupportsSafeSearch = true
 
 	return &Startpage{
 		BaseEngine: search.NewBaseEngine(config),
@@ -50,6 +52,15 @@ func (e *Startpage) Search(ctx context.Context, query *model.Query) ([]model.Res
 	params.Set("language", "english")
 	params.Set("t", "default")
 	params.Set("lui", "english")
+	// Startpage's family filter is on or off; moderate keeps it on
+	if query.SafeSearch > 0 {
+		params.Set("qadf", "heavy")
+	} else {
+		params.Set("qadf", "none")
+	}
+	if page := pageNumber(query); page > 1 {
+		params.Set("page", strconv.Itoa(page))
+	}
 
 	reqURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
 
@@ -63,7 +74,7 @@ func (e *Startpage) Search(ctx context.Context, query *model.Query) ([]model.Res
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Set("Referer", "https://www.startpage.com/")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
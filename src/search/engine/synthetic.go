@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// syntheticResults is how many results a synthetic engine returns per search
+const syntheticResults = 10
+
+// SyntheticEngine answers every search with generated results after a
+// simulated response time, without any network access. It stands in for the
+// real engines under load testing (search --test load --engines mock).
+type SyntheticEngine struct {
+	*search.BaseEngine
+	latency time.Duration
+}
+
+// NewSyntheticEngine creates a synthetic engine whose searches take about
+// latency (between half and one and a half times it)
+func NewSyntheticEngine(name string, categories []string, latency time.Duration) *SyntheticEngine {
+	config := model.NewEngineConfig(name)
+	config.DisplayName = "Synthetic " + strings.TrimPrefix(name, "synthetic-")
+	config.Categories = categories
+
+	return &SyntheticEngine{
+		BaseEngine: search.NewBaseEngine(config),
+		latency:    latency,
+	}
+}
+
+// Search waits out the simulated response time and returns results derived
+// from the query. The engines share result URLs, so the aggregator's merging
+// and ranking run as they do for real engines.
+func (e *SyntheticEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	if e.latency > 0 {
+		delay := e.latency/2 + rand.N(e.latency)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	slug := url.PathEscape(strings.ToLower(strings.Join(strings.Fields(query.Text), "-")))
+	offset := 0
+	if query.Page > 1 {
+		offset = (query.Page - 1) * syntheticResults
+	}
+	results := make([]model.Result, 0, syntheticResults)
+	for i := 1; i <= syntheticResults; i++ {
+		n := offset + i
+		result := model.Result{
+			Title:    fmt.Sprintf("%s - result %d", query.Text, n),
+			URL:      fmt.Sprintf("https://results.example/%s/%d", slug, n),
+			Content:  fmt.Sprintf("Synthetic result %d for %q from %s.", n, query.Text, e.DisplayName()),
+			Engine:   e.Name(),
+			Category: query.Category,
+			Domain:   "results.example",
+			Score:    calculateScore(e.GetPriority(), i, 0),
+			Position: i,
+		}
+		if query.Category == model.CategoryImages || query.Category == model.CategoryVideos {
+			result.Thumbnail = fmt.Sprintf("https://results.example/%s/%d.jpg", slug, n)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// SyntheticRegistry returns a registry of synthetic engines covering the
+// main categories, for load testing without reaching real engines
+func SyntheticRegistry(latency time.Duration) *Registry {
+	registry := NewRegistry()
+	registry.Register(NewSyntheticEngine("synthetic-a", []string{"general", "images", "news", "videos"}, latency))
+	registry.Register(NewSyntheticEngine("synthetic-b", []string{"general", "news"}, latency))
+	registry.Register(NewSyntheticEngine("synthetic-c", []string{"general", "images", "videos"}, latency))
+	registry.Register(NewSyntheticEngine("synthetic-d", []string{"general"}, latency))
+	return registry
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestSyntheticEngine(t *testing.T) {
+	e := NewSyntheticEngine("synthetic-x", []string{"general", "images"}, 20*time.Millisecond)
+	query := &model.Query{Text: "Go Programming", Category: model.CategoryImages, Page: 2}
+
+	start := time.Now()
+	results, err := e.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Search() took %v, want at least half the latency", elapsed)
+	}
+	if len(results) != syntheticResults {
+		t.Fatalf("len(results) = %d, want %d", len(results), syntheticResults)
+	}
+	first := results[0]
+	if first.URL != "https://results.example/go-programming/11" || first.Engine != "synthetic-x" || first.Thumbnail == "" {
+		t.Errorf("first result = %+v", first)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow := NewSyntheticEngine("synthetic-y", []string{"general"}, time.Hour)
+	if _, err := slow.Search(ctx, query); err != context.Canceled {
+		t.Errorf("Search() after cancel error = %v, want context.Canceled", err)
+	}
+
+	if got := len(SyntheticRegistry(0).GetForCategory(model.CategoryGeneral)); got != 4 {
+		t.Errorf("synthetic general engines = %d, want 4", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/apimgr/search/src/search"
 )
 
 // SharedTransport is a single http.Transport shared across all engines.
@@ -30,3 +32,40 @@ const maxBodyBytes = 4 * 1024 * 1024
 func ReadBody(resp *http.Response) ([]byte, error) {
 	return io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
 }
+
+// doRequest sends req with the engine's configured request overrides. It
+// fails with a *search.ResponseLimitError when the response is too large or
+// too deeply nested to parse safely, and with a *search.BlockedError when it
+// is a consent wall, CAPTCHA or block page rather than results. Successful
+// responses feed the engine's schema drift baseline. The request waits its
+// turn at the upstream host, and fails with a *search.HostBusyError when
+// the host is busy. During an engine test the exchange is recorded.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	release, err := search.AwaitHost(client, req)
+	if err != nil {
+		return nil, err
+	}
+	// LimitResponse reads the whole body, so the request is done with the
+	// host when doRequest returns
+	defer release()
+	start := time.Now()
+	req = search.ApplyRequestOverrides(req)
+	resp, err := client.Do(req)
+	if err == nil {
+		search.MalformResponse(resp)
+		err = search.LimitResponse(resp)
+	}
+	if err == nil {
+		err = search.InspectResponse(resp)
+	}
+	// An engine test shows blocked and oversized answers too
+	search.RecordExchange(req, resp, err, time.Since(start))
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	search.RecordResponseShape(resp)
+	return resp, nil
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// videoInfo is the host-neutral view of a video returned by the videos
+// category engines (YouTube, PeerTube, Dailymotion)
+type videoInfo struct {
+	Title       string
+	URL         string
+	Description string
+	Thumbnail   string
+	// Duration in seconds (0 if unknown)
+	Duration    int
+	Channel     string
+	ChannelURL  string
+	Views       int64
+	PublishedAt time.Time
+}
+
+// videoResult converts videoInfo into a result in the videos category.
+// The channel, views and publish date go in the result's own fields, which
+// model.Result.Video exposes to the API and the templates.
+func videoResult(engineName string, priority, position int, info videoInfo) model.Result {
+	desc := info.Description
+	if len(desc) > 300 {
+		desc = desc[:297] + "..."
+	}
+
+	result := model.Result{
+		Title:       info.Title,
+		URL:         info.URL,
+		Content:     desc,
+		Thumbnail:   info.Thumbnail,
+		Duration:    info.Duration,
+		Author:      info.Channel,
+		ViewCount:   info.Views,
+		PublishedAt: info.PublishedAt,
+		Engine:      engineName,
+		Category:    model.CategoryVideos,
+		Score:       calculateScore(priority, position, 1),
+	}
+	if info.ChannelURL != "" {
+		result.Metadata = map[string]interface{}{"channel_url": info.ChannelURL}
+	}
+	return result
+}
+
+// parseViewCount reads a view count as video hosts print it: "1,234,567
+// views", "1.2M views" or "987K". It returns 0 when text holds no count.
+func parseViewCount(text string) int64 {
+	text = strings.TrimSpace(text)
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != ',' && r != '.'
+	})
+	number, rest := text, ""
+	if end >= 0 {
+		number, rest = text[:end], text[end:]
+	}
+	number = strings.ReplaceAll(number, ",", "")
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+	switch {
+	case strings.HasPrefix(rest, "K"):
+		value *= 1e3
+	case strings.HasPrefix(rest, "M"):
+		value *= 1e6
+	case strings.HasPrefix(rest, "B"):
+		value *= 1e9
+	}
+	return int64(value)
+}
+
+// relativeAgeUnits are the units of a relative publish date and their
+// approximate lengths
+var relativeAgeUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+// parseRelativeAge reads a publish date given relative to now, such as
+// "3 years ago" or "Streamed 2 weeks ago", as an approximate time. It
+// returns the zero time when text is not one.
+func parseRelativeAge(text string, now time.Time) time.Time {
+	fields := strings.Fields(strings.ToLower(text))
+	for i := 0; i+1 < len(fields); i++ {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n < 0 {
+			continue
+		}
+		for _, unit := range relativeAgeUnits {
+			if strings.HasPrefix(fields[i+1], unit.name) {
+				return now.Add(-time.Duration(n) * unit.size)
+			}
+		}
+	}
+	return time.Time{}
+}
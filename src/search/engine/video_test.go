@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestDefaultRegistryVideoEngines(t *testing.T) {
+	names := map[string]bool{}
+	for _, e := range DefaultRegistry().GetForCategory(model.CategoryVideos) {
+		names[e.Name()] = true
+	}
+	for _, name := range []string{"youtube", "peertube", "dailymotion"} {
+		if !names[name] {
+			t.Errorf("GetForCategory(videos) lacks %s", name)
+		}
+	}
+}
+
+func TestParseViewCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"1,234,567 views", 1234567},
+		{"1.2M views", 1200000},
+		{"987K", 987000},
+		{"1B views", 1000000000},
+		{"No views", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseViewCount(tt.text); got != tt.want {
+			t.Errorf("parseViewCount(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseRelativeAge(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"3 years ago", now.Add(-3 * 365 * 24 * time.Hour)},
+		{"Streamed 2 weeks ago", now.Add(-14 * 24 * time.Hour)},
+		{"1 month ago", now.Add(-30 * 24 * time.Hour)},
+		{"5 hours ago", now.Add(-5 * time.Hour)},
+		{"yesterday", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseRelativeAge(tt.text, now); !got.Equal(tt.want) {
+			t.Errorf("parseRelativeAge(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPeerTubeSearch(t *testing.T) {
+	payload := `{"total":1,"data":[{"name":"What is PeerTube?","description":"An introduction",
+		"duration":166,"url":"https://framatube.org/videos/watch/9c9de5e8-0a1e-484a-b099-e80766180a6d",
+		"thumbnailUrl":"https://framatube.org/static/thumbnails/9c9de5e8.jpg",
+		"publishedAt":"2018-10-01T10:52:46.396Z","views":54321,
+		"channel":{"displayName":"PeerTube","url":"https://framatube.org/video-channels/joinpeertube"}}]}`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewPeerTube()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{Text: "peertube", Page: 2, SafeSearch: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !strings.Contains(gotQuery, "start=20") || !strings.Contains(gotQuery, "nsfw=false") {
+		t.Errorf("query = %q, want the second page without sensitive videos", gotQuery)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.Category != model.CategoryVideos || r.Duration != 166 || r.Author != "PeerTube" || r.ViewCount != 54321 {
+		t.Errorf("result = %+v", r)
+	}
+	if r.PublishedAt.Year() != 2018 {
+		t.Errorf("PublishedAt = %v", r.PublishedAt)
+	}
+	if video := r.Video(); video == nil || video.ChannelURL != "https://framatube.org/video-channels/joinpeertube" {
+		t.Errorf("Video() = %+v", video)
+	}
+}
+
+func TestDailymotionSearch(t *testing.T) {
+	payload := `{"page":1,"limit":20,"has_more":false,"list":[{"id":"x8abc12","title":"Launch",
+		"description":"Rocket launch","url":"https://www.dailymotion.com/video/x8abc12","duration":1500,
+		"thumbnail_360_url":"https://s1.dmcdn.net/v/x8abc12/x360","created_time":1700000000,
+		"views_total":1200,"owner.screenname":"Space News","owner.url":"https://www.dailymotion.com/spacenews"}]}`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		fmt.Fprint(w, payload)
+	}))
+	defer server.Close()
+
+	engine := NewDailymotion()
+	engine.client = &http.Client{Transport: redirectToServer(server.URL)}
+
+	results, err := engine.Search(context.Background(), &model.Query{
+		Text: "launch", Page: 1, Category: model.CategoryVideos, VideoLength: "long", VideoQuality: "hd",
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	for _, param := range []string{"longer_than=20", "flags=hd", "family_filter=false"} {
+		if !strings.Contains(gotQuery, param) {
+			t.Errorf("query = %q, want %s", gotQuery, param)
+		}
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+
+	r := results[0]
+	if r.Duration != 1500 || r.Author != "Space News" || r.ViewCount != 1200 || r.Thumbnail != "https://s1.dmcdn.net/v/x8abc12/x360" {
+		t.Errorf("result = %+v", r)
+	}
+	if !r.PublishedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("PublishedAt = %v", r.PublishedAt)
+	}
+	if r.Metadata["channel_url"] != "https://www.dailymotion.com/spacenews" {
+		t.Errorf("channel_url = %v", r.Metadata["channel_url"])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/apimgr/search/src/model"
@@ -51,7 +52,7 @@ func (e *WikipediaEngine) Search(ctx context.Context, query *model.Query) ([]mod
 	params.Set("generator", "search")
 	params.Set("gsrsearch", query.Text)
 	params.Set("gsrlimit", "10")
-	params.Set("gsroffset", fmt.Sprintf("%d", query.Page*10))
+	params.Set("gsroffset", strconv.Itoa(pageOffset(query, 10)))
 	// prop=extracts gives the article text
 	params.Set("prop", "extracts")
 	// exintro=true limits to the intro section only
@@ -71,7 +72,7 @@ func (e *WikipediaEngine) Search(ctx context.Context, query *model.Query) ([]mod
 	req.Header.Set("User-Agent", UserAgent)
 
 	client := &http.Client{Timeout: 10 * time.Second, Transport: SharedTransport}
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +42,10 @@ func NewWolframAlpha() *WolframAlpha {
 // Search performs a Wolfram Alpha search
 // Returns instant answer results for computational queries
 func (e *WolframAlpha) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// One answer, no further pages
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
 	// Try web scraping approach for Wolfram Alpha results
 	// This provides richer results than the Short Answers API which requires an API key
 	return e.searchWeb(ctx, query)
@@ -66,7 +70,7 @@ func (e *WolframAlpha) searchWeb(ctx context.Context, query *model.Query) ([]mod
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
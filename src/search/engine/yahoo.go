@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,7 @@ func NewYahoo() *Yahoo {
 	config.Priority = 65
 	config.Categories = []string{"general", "images", "news", "files", "music"}
 	config.SupportsTor = false
+	config.SupportsSafeSearch = true
 
 	return &Yahoo{
 		BaseEngine: search.NewBaseEngine(config),
@@ -44,6 +46,19 @@ func (e *Yahoo) Search(ctx context.Context, query *model.Query) ([]model.Result,
 	params := url.Values{}
 	params.Set("p", query.Text)
 	params.Set("ei", "UTF-8")
+	// SafeSearch: p strict, r moderate, i off
+	switch query.SafeSearch {
+	case 0:
+		params.Set("vm", "i")
+	case 2:
+		params.Set("vm", "p")
+	default:
+		params.Set("vm", "r")
+	}
+	// Yahoo pages by the 1-based position of the first result, 7 a page
+	if page := pageNumber(query); page > 1 {
+		params.Set("b", strconv.Itoa(pageOffset(query, 7)+1))
+	}
 
 	if query.Category == model.CategoryImages {
 		searchURL = "https://images.search.yahoo.com/search/images"
@@ -62,7 +77,7 @@ func (e *Yahoo) Search(ctx context.Context, query *model.Query) ([]model.Result,
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +51,7 @@ func NewYandex() *Yandex {
 	config.Categories = []string{"general", "images", "news", "videos", "files", "music"}
 	// Yandex blocks Tor exit nodes
 	config.SupportsTor = false
+	config.SupportsSafeSearch = true
 
 	return &Yandex{
 		BaseEngine: search.NewBaseEngine(config),
@@ -97,7 +98,7 @@ func (e *Yandex) Search(ctx context.Context, query *model.Query) ([]model.Result
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Set("DNT", "1")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
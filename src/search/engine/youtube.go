@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,6 +29,7 @@ func NewYouTubeEngine() *YouTube {
 	config.Priority = 65
 	config.Categories = []string{"videos", "music"}
 	config.SupportsTor = false
+	config.SupportsVideoFilters = true
 
 	return &YouTube{
 		BaseEngine: search.NewBaseEngine(config),
@@ -40,10 +42,18 @@ func NewYouTubeEngine() *YouTube {
 
 // Search performs a YouTube search
 func (e *YouTube) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	// YouTube pages with a continuation token from the previous answer
+	if firstPageOnly(query) {
+		return []model.Result{}, nil
+	}
+
 	searchURL := "https://www.youtube.com/results"
 
 	params := url.Values{}
 	params.Set("search_query", query.Text)
+	if sp := youtubeFilterParam(query); sp != "" {
+		params.Set("sp", sp)
+	}
 
 	reqURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
 
@@ -56,7 +66,7 @@ func (e *YouTube) Search(ctx context.Context, query *model.Query) ([]model.Resul
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +84,29 @@ func (e *YouTube) Search(ctx context.Context, query *model.Query) ([]model.Resul
 	return e.parseResults(string(body), query)
 }
 
+// youtubeFilterParam builds the sp parameter for the video length and
+// quality filters. It is a base64 protobuf message whose field 2 holds the
+// filters: field 3 the duration (1 under 4 minutes, 3 for 4 to 20, 2 over
+// 20) and field 4 set for HD.
+func youtubeFilterParam(query *model.Query) string {
+	var filters []byte
+	switch query.VideoLength {
+	case "short":
+		filters = append(filters, 0x18, 1)
+	case "medium":
+		filters = append(filters, 0x18, 3)
+	case "long":
+		filters = append(filters, 0x18, 2)
+	}
+	if query.VideoQuality == "hd" {
+		filters = append(filters, 0x20, 1)
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(append([]byte{0x12, byte(len(filters))}, filters...))
+}
+
 func (e *YouTube) parseResults(html string, query *model.Query) ([]model.Result, error) {
 	maxResults := e.GetConfig().GetMaxResults()
 
@@ -220,12 +253,13 @@ func (e *YouTube) parseVideoRenderer(video map[string]interface{}, query *model.
 		}
 	}
 
-	// Extract channel name
-	channel := ""
+	// Extract channel name and page
+	channel, channelURL := "", ""
 	if ownerObj, ok := video["ownerText"].(map[string]interface{}); ok {
 		if runs, ok := ownerObj["runs"].([]interface{}); ok && len(runs) > 0 {
 			if run, ok := runs[0].(map[string]interface{}); ok {
 				channel, _ = run["text"].(string)
+				channelURL = youtubeChannelURL(run)
 			}
 		}
 	}
@@ -242,6 +276,14 @@ func (e *YouTube) parseVideoRenderer(video map[string]interface{}, query *model.
 		published, _ = pubObj["simpleText"].(string)
 	}
 
+	// Extract duration, shown as "12:34"
+	duration := 0
+	if lengthObj, ok := video["lengthText"].(map[string]interface{}); ok {
+		if length, ok := lengthObj["simpleText"].(string); ok {
+			duration = parseDuration(length)
+		}
+	}
+
 	// Build result
 	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
 
@@ -264,15 +306,35 @@ func (e *YouTube) parseVideoRenderer(video map[string]interface{}, query *model.
 		}
 	}
 
-	return &model.Result{
+	result := &model.Result{
 		Title:     title,
 		URL:       videoURL,
 		Content:   description,
 		Thumbnail: thumbnail,
-		Engine:    e.Name(),
-		Category:  query.Category,
-		Score:     calculateScore(e.GetPriority(), position, 1),
+		Duration:  duration,
+		Author:    channel,
+		ViewCount: parseViewCount(viewCount),
+		// YouTube only says how long ago, such as "3 years ago"
+		PublishedAt: parseRelativeAge(published, time.Now()),
+		Engine:      e.Name(),
+		Category:    query.Category,
+		Score:       calculateScore(e.GetPriority(), position, 1),
+	}
+	if channelURL != "" {
+		result.Metadata = map[string]interface{}{"channel_url": channelURL}
+	}
+	return result
+}
+
+// youtubeChannelURL returns the channel page a channel name run links to
+func youtubeChannelURL(run map[string]interface{}) string {
+	endpoint, _ := run["navigationEndpoint"].(map[string]interface{})
+	browse, _ := endpoint["browseEndpoint"].(map[string]interface{})
+	path, _ := browse["canonicalBaseUrl"].(string)
+	if !strings.HasPrefix(path, "/") {
+		return ""
 	}
+	return "https://www.youtube.com" + path
 }
 
 func (e *YouTube) parseHTML(html string, query *model.Query, maxResults int) ([]model.Result, error) {
@@ -0,0 +1,220 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// maxExchangeBody is the most of an upstream response body an engine test
+// shows. 256 KB
+const maxExchangeBody = 256 * 1024
+
+// redactedValue replaces a secret in a redacted engine test
+const redactedValue = "[redacted]"
+
+// secretName matches header and query parameter names that carry
+// credentials: API keys, tokens, signatures, sessions and cookies
+var secretName = regexp.MustCompile(`(?i)(^key$|api[-_]?key|appid|app[-_]?id|token|secret|passw|auth|sig(nature)?$|session|cookie)`)
+
+// EngineTest is one query run against a single engine to debug it: every
+// upstream request the engine made with its answer, and what the engine
+// parsed from them
+type EngineTest struct {
+	Engine    string         `json:"engine"`
+	Query     string         `json:"query"`
+	Category  string         `json:"category"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	Redacted  bool           `json:"redacted"`
+	Exchanges []Exchange     `json:"exchanges"`
+	Results   []model.Result `json:"results"`
+	Error     string         `json:"error,omitempty"`
+	// TotalMs is the whole engine call; ParseMs is the part of it not
+	// spent waiting on upstream
+	TotalMs float64 `json:"total_ms"`
+	ParseMs float64 `json:"parse_ms"`
+}
+
+// Exchange is one upstream request an engine made and the answer it got
+type Exchange struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers"`
+	Status          int         `json:"status,omitempty"`
+	FinalURL        string      `json:"final_url,omitempty"`
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	Body            string      `json:"body,omitempty"`
+	BodyBytes       int         `json:"body_bytes"`
+	BodyTruncated   bool        `json:"body_truncated,omitempty"`
+	DurationMs      float64     `json:"duration_ms"`
+	Error           string      `json:"error,omitempty"`
+}
+
+type exchangeLogKey struct{}
+
+// exchangeLog collects the exchanges of an engine test
+type exchangeLog struct {
+	mu        sync.Mutex
+	redact    bool
+	exchanges []Exchange
+}
+
+// RecordExchange records an engine's upstream request and the response or
+// error it got, when the request belongs to an engine test; otherwise it
+// does nothing. A response body is read and put back, so the engine parses
+// it as usual.
+func RecordExchange(req *http.Request, resp *http.Response, err error, took time.Duration) {
+	log, ok := req.Context().Value(exchangeLogKey{}).(*exchangeLog)
+	if !ok {
+		return
+	}
+	ex := Exchange{
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeaders: req.Header.Clone(),
+		DurationMs:     milliseconds(took),
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	if resp != nil {
+		ex.Status = resp.StatusCode
+		ex.ResponseHeaders = resp.Header.Clone()
+		if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != ex.URL {
+			ex.FinalURL = resp.Request.URL.String()
+		}
+		if resp.Body != nil {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, DefaultEngineLimits.MaxResponseBytes))
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{bytes.NewReader(body), resp.Body}
+			ex.BodyBytes = len(body)
+			if len(body) > maxExchangeBody {
+				body = body[:maxExchangeBody]
+				ex.BodyTruncated = true
+			}
+			ex.Body = string(body)
+		}
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.redact {
+		ex.redact()
+	}
+	log.exchanges = append(log.exchanges, ex)
+}
+
+// redact hides credentials in the exchange's URLs and headers
+func (ex *Exchange) redact() {
+	ex.URL = redactURL(ex.URL)
+	ex.FinalURL = redactURL(ex.FinalURL)
+	redactHeaders(ex.RequestHeaders)
+	redactHeaders(ex.ResponseHeaders)
+}
+
+func redactHeaders(h http.Header) {
+	for name, values := range h {
+		if secretName.MatchString(name) {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+	}
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User(redactedValue)
+	}
+	params := u.Query()
+	changed := false
+	for name := range params {
+		if secretName.MatchString(name) {
+			params.Set(name, redactedValue)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = params.Encode()
+	}
+	return u.String()
+}
+
+// TestEngine runs query against the named engine alone and reports what
+// it sent upstream, what came back and what it parsed. The engine is asked
+// as in a search, with its request overrides, user agent, limits and
+// politeness, and the call counts against its quota; but an open circuit
+// does not skip it, and the outcome neither feeds its health nor fills the
+// cache. With redact set, credentials in URLs and headers are hidden.
+func (a *Aggregator) TestEngine(ctx context.Context, name string, query *model.Query, redact bool) (*EngineTest, error) {
+	var engine Engine
+	for _, eng := range a.engines {
+		if strings.EqualFold(eng.Name(), name) {
+			engine = eng
+			break
+		}
+	}
+	if engine == nil {
+		return nil, model.ErrEngineNotFound
+	}
+	if err := query.ValidateSearchQuery(); err != nil {
+		return nil, err
+	}
+	ops := ParseOperators(query.Text)
+	query.ParsedOperators = ops
+	query.CleanedText = ops.CleanedQuery
+	a.applyOperators(query, ops)
+
+	testCtx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	log := &exchangeLog{redact: redact}
+	engineCtx, ua := a.withUserAgent(a.withRequestOverrides(context.WithValue(testCtx, exchangeLogKey{}, log), engine, query), engine)
+
+	start := time.Now()
+	results, err := a.runEngine(engineCtx, engine, query)
+	took := time.Since(start)
+
+	log.mu.Lock()
+	exchanges := append([]Exchange{}, log.exchanges...)
+	log.mu.Unlock()
+	upstream := 0.0
+	for _, ex := range exchanges {
+		upstream += ex.DurationMs
+	}
+	test := &EngineTest{
+		Engine:    engine.Name(),
+		Query:     query.Text,
+		Category:  query.Category.String(),
+		UserAgent: ua,
+		Redacted:  redact,
+		Exchanges: exchanges,
+		Results:   results,
+		TotalMs:   milliseconds(took),
+	}
+	test.ParseMs = max(test.TotalMs-upstream, 0)
+	if test.Results == nil {
+		test.Results = []model.Result{}
+	}
+	if err != nil {
+		test.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			test.Error = "engine did not answer within " + a.timeout.String()
+		}
+	}
+	return test, nil
+}
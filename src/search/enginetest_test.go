@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// upstreamEngine asks a test server the way the real engines do
+type upstreamEngine struct {
+	*BaseEngine
+	url string
+}
+
+func (e *upstreamEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, e.url+"?q="+query.Text+"&api_key=s3cret", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req = ApplyRequestOverrides(req)
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	RecordExchange(req, resp, err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, model.ErrEngineUnavailable
+	}
+	return []model.Result{{Title: string(body), URL: "https://example.com/", Engine: e.Name()}}, nil
+}
+
+func TestEngineTest(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc"})
+		if r.URL.Query().Get("q") == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte("parsed " + r.URL.Query().Get("q")))
+	}))
+	defer upstream.Close()
+
+	engine := &upstreamEngine{BaseEngine: newMockEngine("upstream", model.CategoryGeneral, true).BaseEngine, url: upstream.URL}
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{Timeout: 5 * time.Second})
+	agg.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1, Cooldown: time.Minute})
+	engine.RecordFailure(model.ErrEngineUnavailable)
+
+	test, err := agg.TestEngine(context.Background(), "UPSTREAM", &model.Query{Text: "golang", Category: model.CategoryGeneral}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test.Error != "" || len(test.Results) != 1 || test.Results[0].Title != "parsed golang" {
+		t.Fatalf("an open circuit skipped the test, or it parsed nothing: %+v", test)
+	}
+	if len(test.Exchanges) != 1 {
+		t.Fatalf("recorded %d exchanges, want 1", len(test.Exchanges))
+	}
+	ex := test.Exchanges[0]
+	if ex.Status != http.StatusOK || ex.Body != "parsed golang" || ex.BodyBytes != len(ex.Body) {
+		t.Errorf("exchange = %+v", ex)
+	}
+	if strings.Contains(ex.URL, "s3cret") || ex.RequestHeaders.Get("Authorization") != redactedValue || ex.ResponseHeaders.Get("Set-Cookie") != redactedValue {
+		t.Errorf("redacted exchange leaks credentials: %s %v %v", ex.URL, ex.RequestHeaders, ex.ResponseHeaders)
+	}
+	if h := engine.GetHealth(); h.Circuit != CircuitOpen || h.SuccessCount != 0 {
+		t.Errorf("the test fed the engine's health: %+v", h)
+	}
+
+	test, _ = agg.TestEngine(context.Background(), "upstream", &model.Query{Text: "fail", Category: model.CategoryGeneral}, false)
+	ex = test.Exchanges[0]
+	if test.Error == "" || ex.Status != http.StatusServiceUnavailable || ex.Body != "parsed fail" || !strings.Contains(ex.URL, "s3cret") {
+		t.Errorf("unredacted failing test = %+v", test)
+	}
+
+	if _, err := agg.TestEngine(context.Background(), "missing", &model.Query{Text: "x"}, true); err != model.ErrEngineNotFound {
+		t.Errorf("unknown engine error = %v", err)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://api.example.com/search?q=go&appid=123", "https://api.example.com/search?appid=%5Bredacted%5D&q=go"},
+		{"https://user:pw@example.com/", "https://%5Bredacted%5D@example.com/"},
+		{"https://example.com/?q=token", "https://example.com/?q=token"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := redactURL(tt.in); got != tt.want {
+			t.Errorf("redactURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"math"
+	"strings"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Geo boosting. A search that carries the searcher's country ranks results
+// from that country a little higher: sites on its country-code domain, and
+// results written in a language spoken there. The boost is applied after
+// the result cache, so cached results stay the same for every country.
+
+// Reasons a result was localized
+const (
+	LocalizedDomain   = "domain"
+	LocalizedLanguage = "language"
+)
+
+// genericCCTLDs are country-code domains used worldwide, and .eu, which say
+// nothing about which country a site is from
+var genericCCTLDs = map[string]bool{
+	"ac": true, "ag": true, "ai": true, "am": true, "bz": true, "cc": true,
+	"co": true, "eu": true, "fm": true, "gg": true, "gl": true, "im": true,
+	"io": true, "la": true, "ly": true, "me": true, "nu": true, "sc": true,
+	"sh": true, "so": true, "st": true, "tk": true, "to": true, "tv": true,
+	"vc": true, "ws": true,
+}
+
+// countryLanguages are the languages boosted for a country. English is left
+// out: most results are in English wherever the search comes from, so it
+// would mark nearly every result.
+var countryLanguages = map[string][]string{
+	"AE": {"ar"}, "AR": {"es"}, "AT": {"de"}, "BE": {"nl", "fr", "de"},
+	"BH": {"ar"}, "BO": {"es"}, "BR": {"pt"}, "BY": {"ru"}, "CA": {"fr"},
+	"CH": {"de", "fr", "it"}, "CL": {"es"}, "CN": {"zh"}, "CO": {"es"},
+	"CR": {"es"}, "CU": {"es"}, "DE": {"de"}, "DO": {"es"}, "DZ": {"ar"},
+	"EC": {"es"}, "EG": {"ar"}, "ES": {"es"}, "FR": {"fr"}, "GR": {"el"},
+	"GT": {"es"}, "HK": {"zh"}, "HN": {"es"}, "IL": {"he"}, "IQ": {"ar"},
+	"IR": {"fa"}, "IT": {"it"}, "JO": {"ar"}, "JP": {"ja"}, "KG": {"ru"},
+	"KR": {"ko"}, "KW": {"ar"}, "KZ": {"ru"}, "LB": {"ar"}, "LI": {"de"},
+	"LU": {"fr", "de"}, "LY": {"ar"}, "MA": {"ar", "fr"}, "MC": {"fr"},
+	"MO": {"zh", "pt"}, "MX": {"es"}, "NI": {"es"}, "NL": {"nl"}, "OM": {"ar"},
+	"PA": {"es"}, "PE": {"es"}, "PK": {"ur"}, "PL": {"pl"}, "PT": {"pt"},
+	"PY": {"es"}, "QA": {"ar"}, "RU": {"ru"}, "SA": {"ar"}, "SM": {"it"},
+	"SN": {"fr"}, "SR": {"nl"}, "SV": {"es"}, "SY": {"ar"}, "TH": {"th"},
+	"TN": {"ar", "fr"}, "TW": {"zh"}, "UY": {"es"}, "VA": {"it"}, "VE": {"es"},
+	"YE": {"ar"},
+}
+
+// SetGeoBoost sets how much a localized result's score grows: 0.1 ranks it
+// as if it scored 10% higher. 0 turns geo boosting off.
+func (a *Aggregator) SetGeoBoost(weight float64) {
+	if weight < 0 {
+		weight = 0
+	}
+	a.geoBoost.Store(math.Float64bits(weight))
+}
+
+// localizeResults boosts the results matching query.Country and re-sorts
+// them. The cached copy is left as it was.
+func (a *Aggregator) localizeResults(query *model.Query, results *model.SearchResults) *model.SearchResults {
+	weight := math.Float64frombits(a.geoBoost.Load())
+	if weight <= 0 || query.Country == "" || results == nil || len(results.Results) == 0 ||
+		(query.SortBy != "" && query.SortBy != model.SortRelevance) ||
+		(query.Category != model.CategoryGeneral && query.Category != model.CategoryNews) {
+		return results
+	}
+
+	var boosted []model.Result
+	for i, r := range results.Results {
+		reason := localizedReason(r, query.Country)
+		if reason == "" {
+			continue
+		}
+		if boosted == nil {
+			boosted = append([]model.Result(nil), results.Results...)
+		}
+		boosted[i].Localized = reason
+		boosted[i].Score *= 1 + weight
+	}
+	if boosted == nil {
+		return results
+	}
+	rankResults(boosted)
+	localized := *results
+	localized.Results = boosted
+	return &localized
+}
+
+// localizedReason returns why r is boosted for a searcher in country, or ""
+func localizedReason(r model.Result, country string) string {
+	country = strings.ToUpper(country)
+	if domainCountry(r.ExtractDomain()) == country {
+		return LocalizedDomain
+	}
+	langs := countryLanguages[country]
+	if len(langs) == 0 {
+		return ""
+	}
+	lang := r.Language
+	if lang == "" {
+		lang = detectLanguage(r.Title + " " + r.Content)
+	}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	for _, l := range langs {
+		if strings.EqualFold(l, lang) {
+			return LocalizedLanguage
+		}
+	}
+	return ""
+}
+
+// domainCountry returns the country a country-code domain belongs to, or ""
+// for generic and worldwide-used domains
+func domainCountry(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if i := strings.LastIndexByte(domain, ':'); i > 0 {
+		domain = domain[:i]
+	}
+	i := strings.LastIndexByte(domain, '.')
+	if i < 0 {
+		return ""
+	}
+	tld := domain[i+1:]
+	if len(tld) != 2 || genericCCTLDs[tld] {
+		return ""
+	}
+	if tld == "uk" {
+		return "GB"
+	}
+	return strings.ToUpper(tld)
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"Die Geschichte der Stadt und ihre Bedeutung für die Region ist nicht zu unterschätzen": "de",
+		"La tour Eiffel est le monument le plus visité de Paris et une icône de la France":      "fr",
+		"The history of the city and its importance for the region":                             "en",
+		"東京の天気予報です":                   "ja",
+		"北京天气预报":                      "zh",
+		"Погода в Москве на неделю":   "ru",
+		"آب و هوای تهران در این هفته": "fa",
+		"Wetter":                       "",
+		"Berlin Marathon 2025 results": "",
+	}
+	for text, want := range tests {
+		if got := detectLanguage(text); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestDomainCountry(t *testing.T) {
+	tests := map[string]string{
+		"www.spiegel.de":   "DE",
+		"bbc.co.uk":        "GB",
+		"example.com":      "",
+		"github.io":        "",
+		"europa.eu":        "",
+		"localhost":        "",
+		"lemonde.fr:443":   "FR",
+		"news.example.ca.": "CA",
+	}
+	for domain, want := range tests {
+		if got := domainCountry(domain); got != want {
+			t.Errorf("domainCountry(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestLocalizeResults(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, 10*time.Second)
+	results := model.NewSearchResults("wetter", model.CategoryGeneral)
+	results.Results = []model.Result{
+		{URL: "https://weather.example.com/berlin", Title: "Berlin weather forecast", Content: "The forecast for the week in Berlin", Score: 100},
+		{URL: "https://www.wetter.de/berlin", Title: "Wetter Berlin", Score: 95},
+		{URL: "https://wetter.example.com/", Title: "Das Wetter", Content: "Die Vorhersage für die Stadt und das Umland ist nicht gut", Score: 60},
+	}
+	query := &model.Query{Text: "wetter", Category: model.CategoryGeneral, Country: "DE"}
+
+	// Off until a weight is set
+	if got := agg.localizeResults(query, results); got != results {
+		t.Fatal("boosted with no weight set")
+	}
+
+	agg.SetGeoBoost(0.1)
+	got := agg.localizeResults(query, results)
+	if got.Results[0].URL != "https://www.wetter.de/berlin" || got.Results[0].Localized != LocalizedDomain {
+		t.Errorf("first result = %+v, want the .de site boosted", got.Results[0])
+	}
+	if got.Results[2].Localized != LocalizedLanguage || got.Results[1].Localized != "" {
+		t.Errorf("localized = %q, %q; want the German result marked by language", got.Results[1].Localized, got.Results[2].Localized)
+	}
+	if results.Results[1].Localized != "" || results.Results[1].Score != 95 {
+		t.Error("cached results changed")
+	}
+
+	// Other sorts and categories keep their order
+	for _, q := range []*model.Query{
+		{Country: "DE", Category: model.CategoryGeneral, SortBy: model.SortDate},
+		{Country: "DE", Category: model.CategoryImages},
+		{Category: model.CategoryGeneral},
+	} {
+		if got := agg.localizeResults(q, results); got != results {
+			t.Errorf("query %+v was boosted", q)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// detectLanguage guesses the language of a result's title and snippet, or
+// returns "" when unsure. Non-Latin scripts are told apart by their letters;
+// Latin-script languages by their most common words. A snippet is short, so
+// this only needs to be right about the obvious cases.
+func detectLanguage(text string) string {
+	var latin, han, kana, hangul, cyrillic, greek, hebrew, arabic, thai int
+	var persian, urdu bool
+	for _, r := range text {
+		switch {
+		case r < 0x80:
+			if unicode.IsLetter(r) {
+				latin++
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+			switch r {
+			case 'پ', 'چ', 'ژ', 'گ', 'ی', 'ک':
+				persian = true
+			case 'ٹ', 'ڈ', 'ڑ', 'ں', 'ے', 'ہ':
+				urdu = true
+			}
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+
+	// Japanese mixes kanji with kana; Chinese has no kana
+	scripts := []struct {
+		count int
+		lang  string
+	}{
+		{kana + han, "ja"}, {hangul, "ko"}, {cyrillic, "ru"}, {greek, "el"},
+		{hebrew, "he"}, {arabic, "ar"}, {thai, "th"},
+	}
+	best, lang := latin, ""
+	for _, s := range scripts {
+		if s.count > best {
+			best, lang = s.count, s.lang
+		}
+	}
+	switch {
+	case lang == "ja" && kana == 0:
+		return "zh"
+	case lang == "ar" && urdu:
+		return "ur"
+	case lang == "ar" && persian:
+		return "fa"
+	case lang != "":
+		return lang
+	}
+	return detectLatinLanguage(text)
+}
+
+// stopwords are frequent short words of each Latin-script language that
+// are rare in the others
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "for", "with", "that", "this", "are", "from"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "ein", "eine", "für", "auf", "sich", "auch"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "pour", "dans", "qui", "pas", "sur", "au"},
+	"es": {"el", "los", "las", "y", "del", "es", "una", "por", "para", "con", "que", "como", "más"},
+	"it": {"il", "di", "che", "della", "per", "sono", "gli", "una", "non", "con", "anche", "più", "nel"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "op", "voor", "met", "zijn", "ook", "naar"},
+	"pt": {"o", "os", "da", "do", "das", "dos", "não", "uma", "com", "para", "em", "é", "mais"},
+	"pl": {"i", "w", "się", "na", "nie", "z", "jest", "do", "że", "to", "jak", "dla", "oraz"},
+}
+
+// detectLatinLanguage picks the language whose common words appear most
+// often, needing at least three of them and a clear lead
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	counts := make(map[string]int, len(stopwords))
+	for _, w := range words {
+		for lang, list := range stopwords {
+			for _, s := range list {
+				if w == s {
+					counts[lang]++
+					break
+				}
+			}
+		}
+	}
+	best, second, lang := 0, 0, ""
+	for l, n := range counts {
+		switch {
+		case n > best:
+			best, second, lang = n, best, l
+		case n > second:
+			second = n
+		}
+	}
+	if best < 3 || best < second*2 {
+		return ""
+	}
+	return lang
+}
@@ -0,0 +1,195 @@
+package search
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// maxLocalIndexResults bounds the local index; the oldest results are
+// evicted first
+const maxLocalIndexResults = 10000
+
+// localIndex is a small full-text index over the results of recent
+// successful searches. It lives in memory only and answers searches when
+// every engine has failed and the exact search is not in the cache.
+type localIndex struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List
+	docs  map[string]*list.Element
+	terms map[string]map[string]struct{}
+}
+
+// indexedResult is one result in the local index, keyed by URL. safeSearch
+// is the strictest safe search level it was served under.
+type indexedResult struct {
+	result     model.Result
+	terms      map[string]int
+	indexedAt  time.Time
+	safeSearch int
+}
+
+func newLocalIndex(max int) *localIndex {
+	return &localIndex{
+		max:   max,
+		order: list.New(),
+		docs:  make(map[string]*list.Element),
+		terms: make(map[string]map[string]struct{}),
+	}
+}
+
+// add indexes results from a search in category at safe search level
+// safeSearch. A URL seen again replaces its earlier entry and counts as new
+// for eviction, keeping the stricter of the two levels.
+func (idx *localIndex) add(category model.Category, safeSearch int, results []model.Result) {
+	now := time.Now()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, r := range results {
+		if r.URL == "" {
+			continue
+		}
+		if r.Category == "" {
+			r.Category = category
+		}
+		level := safeSearch
+		if elem, ok := idx.docs[r.URL]; ok {
+			level = max(level, elem.Value.(*indexedResult).safeSearch)
+		}
+		idx.removeLocked(r.URL)
+
+		doc := &indexedResult{result: r, terms: make(map[string]int), indexedAt: now, safeSearch: level}
+		for _, term := range indexTerms(r.Title) {
+			// Title matches outrank content matches
+			doc.terms[term] += 2
+		}
+		for _, term := range indexTerms(r.Content) {
+			doc.terms[term]++
+		}
+		for term := range doc.terms {
+			urls := idx.terms[term]
+			if urls == nil {
+				urls = make(map[string]struct{})
+				idx.terms[term] = urls
+			}
+			urls[r.URL] = struct{}{}
+		}
+		idx.docs[r.URL] = idx.order.PushBack(doc)
+	}
+
+	idx.evictLocked()
+}
+
+// resize changes how many results the index holds, evicting the oldest
+// until it fits
+func (idx *localIndex) resize(max int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.max = max
+	idx.evictLocked()
+}
+
+func (idx *localIndex) evictLocked() {
+	for idx.order.Len() > idx.max {
+		oldest := idx.order.Front().Value.(*indexedResult)
+		idx.removeLocked(oldest.result.URL)
+	}
+}
+
+func (idx *localIndex) removeLocked(url string) {
+	elem, ok := idx.docs[url]
+	if !ok {
+		return
+	}
+	doc := elem.Value.(*indexedResult)
+	for term := range doc.terms {
+		urls := idx.terms[term]
+		delete(urls, url)
+		if len(urls) == 0 {
+			delete(idx.terms, term)
+		}
+	}
+	idx.order.Remove(elem)
+	delete(idx.docs, url)
+}
+
+// search returns the indexed results in category containing every term of
+// text, best matches first, and when the oldest of them was indexed. Only
+// results served at safe search level safeSearch or stricter are returned.
+func (idx *localIndex) search(category model.Category, safeSearch int, text string) ([]model.Result, time.Time) {
+	terms := indexTerms(text)
+	if len(terms) == 0 {
+		return nil, time.Time{}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// Walk the rarest term's postings and check the rest against each doc
+	sort.Slice(terms, func(i, j int) bool { return len(idx.terms[terms[i]]) < len(idx.terms[terms[j]]) })
+	type match struct {
+		doc   *indexedResult
+		score int
+	}
+	var matches []match
+	for url := range idx.terms[terms[0]] {
+		doc := idx.docs[url].Value.(*indexedResult)
+		if doc.result.Category != category || doc.safeSearch < safeSearch {
+			continue
+		}
+		score := 0
+		for _, term := range terms {
+			weight, ok := doc.terms[term]
+			if !ok {
+				score = 0
+				break
+			}
+			score += weight
+		}
+		if score > 0 {
+			matches = append(matches, match{doc, score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].doc.indexedAt.After(matches[j].doc.indexedAt)
+	})
+
+	results := make([]model.Result, 0, len(matches))
+	var oldest time.Time
+	for _, m := range matches {
+		results = append(results, m.doc.result)
+		if oldest.IsZero() || m.doc.indexedAt.Before(oldest) {
+			oldest = m.doc.indexedAt
+		}
+	}
+	return results, oldest
+}
+
+// indexTerms splits text into lowercase words, dropping duplicates and
+// single characters
+func indexTerms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len([]rune(f)) < 2 || seen[f] {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+	}
+	return terms
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestLocalIndexSearch(t *testing.T) {
+	idx := newLocalIndex(10)
+	idx.add(model.CategoryGeneral, 0, []model.Result{
+		{URL: "https://a.example", Title: "Rust book", Content: "Learn the rust language"},
+		{URL: "https://b.example", Title: "Learn Go", Content: "A tour of go"},
+		{URL: "https://c.example", Title: "Go modules", Content: "Learn dependency management"},
+	})
+
+	results, indexedAt := idx.search(model.CategoryGeneral, 0, "Learn, GO!")
+	if len(results) != 2 || indexedAt.IsZero() {
+		t.Fatalf("search() = %d results at %v, want 2 with a time", len(results), indexedAt)
+	}
+	// "Learn Go" matches both terms in the title
+	if results[0].URL != "https://b.example" {
+		t.Errorf("first result = %s, want https://b.example", results[0].URL)
+	}
+	if results[0].Category != model.CategoryGeneral {
+		t.Errorf("Category = %q, want the search category", results[0].Category)
+	}
+
+	if results, _ := idx.search(model.CategoryImages, 0, "learn go"); len(results) != 0 {
+		t.Errorf("search() in images = %d results, want 0", len(results))
+	}
+	if results, _ := idx.search(model.CategoryGeneral, 0, "a"); len(results) != 0 {
+		t.Errorf("search() of a single character = %d results, want 0", len(results))
+	}
+}
+
+func TestLocalIndexEvictsOldest(t *testing.T) {
+	idx := newLocalIndex(2)
+	idx.add(model.CategoryGeneral, 0, []model.Result{{URL: "https://1.example", Title: "first page"}})
+	idx.add(model.CategoryGeneral, 0, []model.Result{{URL: "https://2.example", Title: "second page"}})
+	idx.add(model.CategoryGeneral, 0, []model.Result{{URL: "https://3.example", Title: "third page"}})
+
+	results, _ := idx.search(model.CategoryGeneral, 0, "page")
+	if len(results) != 2 {
+		t.Fatalf("search() = %d results, want 2 after eviction", len(results))
+	}
+	if _, ok := idx.terms["first"]; ok {
+		t.Error("evicted result's terms are still indexed")
+	}
+
+	// Re-adding a URL replaces it rather than duplicating it
+	idx.add(model.CategoryGeneral, 0, []model.Result{{URL: "https://3.example", Title: "third page again"}})
+	if idx.order.Len() != 2 || len(idx.docs) != 2 {
+		t.Errorf("index holds %d/%d entries, want 2", idx.order.Len(), len(idx.docs))
+	}
+}
+
+func TestLocalIndexResize(t *testing.T) {
+	idx := newLocalIndex(3)
+	for _, url := range []string{"https://1.example", "https://2.example", "https://3.example"} {
+		idx.add(model.CategoryGeneral, 0, []model.Result{{URL: url, Title: "some page"}})
+	}
+	idx.resize(1)
+	if _, ok := idx.docs["https://3.example"]; !ok || idx.order.Len() != 1 {
+		t.Errorf("index holds %d entries after resize(1), want only the newest", idx.order.Len())
+	}
+}
+
+func TestLocalIndexSafeSearch(t *testing.T) {
+	idx := newLocalIndex(10)
+	idx.add(model.CategoryGeneral, 0, []model.Result{{URL: "https://off.example", Title: "beach photos"}})
+	idx.add(model.CategoryGeneral, 2, []model.Result{{URL: "https://strict.example", Title: "beach photos"}})
+
+	if results, _ := idx.search(model.CategoryGeneral, 2, "beach"); len(results) != 1 || results[0].URL != "https://strict.example" {
+		t.Errorf("strict search = %+v, want only the result served under strict", results)
+	}
+	if results, _ := idx.search(model.CategoryGeneral, 0, "beach"); len(results) != 2 {
+		t.Errorf("search with safe search off = %d results, want 2", len(results))
+	}
+
+	// Seen again with safe search off, a result keeps its stricter level
+	idx.add(model.CategoryGeneral, 0, []model.Result{{URL: "https://strict.example", Title: "beach photos"}})
+	if results, _ := idx.search(model.CategoryGeneral, 2, "beach"); len(results) != 1 {
+		t.Errorf("strict search after re-adding = %d results, want 1", len(results))
+	}
+}
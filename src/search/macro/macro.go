@@ -0,0 +1,188 @@
+package macro
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Search macros are saved searches with parameters. A macro named issues
+// with the query "site:github.com/{repo}/issues" turns "~issues
+// apimgr/search leak" into "site:github.com/apimgr/search/issues leak":
+// each parameter takes one word, in the order the parameters first appear
+// in the query, and the words left over are searched alongside.
+
+// Prefix starts a macro in a search
+const Prefix = "~"
+
+// MaxUser is how many macros a user may keep in their cookie
+const MaxUser = 20
+
+// MaxQuery is the longest macro query
+const MaxQuery = 200
+
+// CookieName is the cookie holding a user's macros
+const CookieName = "search_macros"
+
+var (
+	namePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	paramPattern = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+)
+
+// Macro is a saved search with parameters
+type Macro struct {
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Description string `json:"description,omitempty"`
+}
+
+// ValidName reports whether name may name a macro: lowercase letters,
+// digits, '_' and '-', up to 32 characters
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Valid checks the macro can be saved
+func (m Macro) Valid() error {
+	switch {
+	case !ValidName(m.Name):
+		return fmt.Errorf("macro name %q must be lowercase letters, digits, '_' or '-'", m.Name)
+	case strings.TrimSpace(m.Query) == "":
+		return fmt.Errorf("macro %s has no query", m.Name)
+	case len(m.Query) > MaxQuery:
+		return fmt.Errorf("macro %s query is longer than %d characters", m.Name, MaxQuery)
+	case strings.HasPrefix(strings.TrimSpace(m.Query), Prefix):
+		return fmt.Errorf("macro %s may not expand to another macro", m.Name)
+	}
+	return nil
+}
+
+// Params returns the macro's parameters in the order they first appear
+func (m Macro) Params() []string {
+	var params []string
+	seen := make(map[string]bool)
+	for _, match := range paramPattern.FindAllStringSubmatch(m.Query, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			params = append(params, match[1])
+		}
+	}
+	return params
+}
+
+// Usage returns how the macro is invoked, as in "~issues {repo}"
+func (m Macro) Usage() string {
+	usage := Prefix + m.Name
+	for _, param := range m.Params() {
+		usage += " {" + param + "}"
+	}
+	return usage
+}
+
+// MissingArgsError is returned for a macro invoked without a word for
+// each parameter
+type MissingArgsError struct {
+	Macro   string
+	Missing []string
+	// Usage is how the macro is invoked, for the error shown to the user
+	Usage string
+}
+
+func (e *MissingArgsError) Error() string {
+	return fmt.Sprintf("%s%s needs %s", Prefix, e.Macro, strings.Join(e.Missing, ", "))
+}
+
+// Expand fills the macro's parameters from args and returns the query to
+// search
+func (m Macro) Expand(args string) (string, error) {
+	words := strings.Fields(args)
+	params := m.Params()
+	if len(words) < len(params) {
+		return "", &MissingArgsError{Macro: m.Name, Missing: params[len(words):], Usage: m.Usage()}
+	}
+	values := make(map[string]string, len(params))
+	for i, param := range params {
+		values[param] = words[i]
+	}
+	query := paramPattern.ReplaceAllStringFunc(m.Query, func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	})
+	if rest := words[len(params):]; len(rest) > 0 {
+		query += " " + strings.Join(rest, " ")
+	}
+	return strings.TrimSpace(query), nil
+}
+
+// Parse splits a search starting with a macro into the macro name and its
+// arguments
+func Parse(query string) (name, args string, ok bool) {
+	query = strings.TrimSpace(query)
+	if !strings.HasPrefix(query, Prefix) {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(query[len(Prefix):], " ")
+	name = strings.ToLower(name)
+	if !ValidName(name) {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// Lookup finds a macro by name in the first set that has it
+func Lookup(name string, sets ...[]Macro) (Macro, bool) {
+	for _, set := range sets {
+		for _, m := range set {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return Macro{}, false
+}
+
+// Expand expands a search that starts with a macro found in sets, which
+// are searched in order. It reports false for a search that names no
+// known macro, which is searched as typed.
+func Expand(query string, sets ...[]Macro) (string, bool, error) {
+	name, args, ok := Parse(query)
+	if !ok {
+		return query, false, nil
+	}
+	m, ok := Lookup(name, sets...)
+	if !ok {
+		return query, false, nil
+	}
+	expanded, err := m.Expand(args)
+	return expanded, true, err
+}
+
+// EncodeCookie encodes a user's macros for their cookie
+func EncodeCookie(macros []Macro) string {
+	data, err := json.Marshal(macros)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCookie decodes a user's macros from their cookie, dropping any
+// that are invalid
+func DecodeCookie(value string) []Macro {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	var decoded []Macro
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+	macros := make([]Macro, 0, min(len(decoded), MaxUser))
+	for _, m := range decoded {
+		if m.Valid() == nil && len(macros) < MaxUser {
+			macros = append(macros, m)
+		}
+	}
+	return macros
+}
@@ -0,0 +1,99 @@
+package macro
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMacroExpand(t *testing.T) {
+	issues := Macro{Name: "issues", Query: "site:github.com/{repo}/issues"}
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"apimgr/search", "site:github.com/apimgr/search/issues"},
+		{"  apimgr/search   memory leak ", "site:github.com/apimgr/search/issues memory leak"},
+	}
+	for _, tt := range tests {
+		if got, err := issues.Expand(tt.args); err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+
+	pr := Macro{Name: "pr", Query: `{owner}/{repo} "pull request" {owner}`}
+	if got := pr.Params(); !reflect.DeepEqual(got, []string{"owner", "repo"}) {
+		t.Errorf("Params() = %v", got)
+	}
+	if got := pr.Usage(); got != "~pr {owner} {repo}" {
+		t.Errorf("Usage() = %q", got)
+	}
+	if got, _ := pr.Expand("golang go"); got != `golang/go "pull request" golang` {
+		t.Errorf("repeated parameter = %q", got)
+	}
+	var missing *MissingArgsError
+	if _, err := pr.Expand("golang"); !errors.As(err, &missing) || !reflect.DeepEqual(missing.Missing, []string{"repo"}) {
+		t.Errorf("Expand() with too few words = %v", err)
+	}
+}
+
+func TestExpandSearch(t *testing.T) {
+	instance := []Macro{{Name: "issues", Query: "site:github.com/{repo}/issues"}, {Name: "docs", Query: "site:pkg.go.dev"}}
+	user := []Macro{{Name: "issues", Query: "site:gitlab.com/{repo}/-/issues"}}
+
+	tests := []struct {
+		query    string
+		want     string
+		expanded bool
+	}{
+		{"~issues apimgr/search", "site:gitlab.com/apimgr/search/-/issues", true},
+		{"~DOCS context", "site:pkg.go.dev context", true},
+		{"~unknown thing", "~unknown thing", false},
+		{"golang ~issues", "golang ~issues", false},
+		{"~", "~", false},
+	}
+	for _, tt := range tests {
+		got, expanded, err := Expand(tt.query, user, instance)
+		if err != nil || got != tt.want || expanded != tt.expanded {
+			t.Errorf("Expand(%q) = %q, %v, %v", tt.query, got, expanded, err)
+		}
+	}
+	if _, expanded, err := Expand("~issues", user, instance); !expanded || err == nil || !strings.Contains(err.Error(), "repo") {
+		t.Errorf("Expand() without its argument = %v, %v", expanded, err)
+	}
+}
+
+func TestMacroValid(t *testing.T) {
+	for _, m := range []Macro{
+		{Name: "Issues", Query: "x"},
+		{Name: "issues", Query: " "},
+		{Name: "loop", Query: "~loop"},
+		{Name: "long", Query: strings.Repeat("x", MaxQuery+1)},
+	} {
+		if m.Valid() == nil {
+			t.Errorf("%+v is valid", m)
+		}
+	}
+	if err := (Macro{Name: "go-docs_2", Query: "site:go.dev {q}"}).Valid(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	macros := []Macro{{Name: "issues", Query: "site:github.com/{repo}/issues", Description: "GitHub issues"}}
+	if got := DecodeCookie(EncodeCookie(macros)); !reflect.DeepEqual(got, macros) {
+		t.Errorf("round trip = %+v", got)
+	}
+	many := make([]Macro, MaxUser+5)
+	for i := range many {
+		many[i] = Macro{Name: "m" + strings.Repeat("x", i), Query: "q"}
+	}
+	many[0].Name = "BAD"
+	if got := DecodeCookie(EncodeCookie(many)); len(got) != MaxUser || got[0].Name != "mx" {
+		t.Errorf("decoded %d macros, first %q", len(got), got[0].Name)
+	}
+	if got := DecodeCookie("not base64!"); got != nil {
+		t.Errorf("garbage cookie = %+v", got)
+	}
+}
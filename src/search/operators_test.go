@@ -1,6 +1,7 @@
 package search
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -905,3 +906,33 @@ func TestParseOperatorsInAnchorTakesFirst(t *testing.T) {
 		t.Errorf("InAnchor = %q, want download", ops.InAnchor)
 	}
 }
+
+// FuzzParseOperators checks the query parser never panics and only ever
+// removes text from the query. Run long sessions with `search --test fuzz`.
+func FuzzParseOperators(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"golang site:go.dev -site:example.com filetype:pdf",
+		`"exact phrase" -exclude intitle:go allintitle:go tutorial site:x.com`,
+		"allinurl:a b OR c AND d",
+		"camera $100..$500 before:2024-01-01 after:2023-01-01 daterange:1-2",
+		"define:word weather:berlin stocks:AAPL map:paris movie:dune source:bbc loc:uk lang:de *",
+		"related:go.dev cache:go.dev info:go.dev inurl:blog intext:x inanchor:y allintext:z allinanchor:w",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		ops := ParseOperators(query)
+		if len(ops.CleanedQuery) > len(query) {
+			t.Errorf("CleanedQuery %q is longer than the query %q", ops.CleanedQuery, query)
+		}
+		if strings.Contains(ops.CleanedQuery, "  ") || strings.TrimSpace(ops.CleanedQuery) != ops.CleanedQuery {
+			t.Errorf("CleanedQuery %q is not whitespace-normalized", ops.CleanedQuery)
+		}
+		ops.HasOperators()
+		ops.ToGoogleQuery()
+		ops.ToDuckDuckGoQuery()
+		ops.ToBingQuery()
+		ops.ToBasicQuery()
+	})
+}
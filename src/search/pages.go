@@ -0,0 +1,192 @@
+package search
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Paginated searches are built up one engine page at a time: page 1 of the
+// results comes from every engine's first page, and a later page asks the
+// engines for their next page only once the results gathered so far run
+// out. New results go after the ones already gathered, without the ones
+// seen before, so paging on never reorders or repeats what was shown.
+
+const (
+	// PageTokenTTL is how long a page token keeps a search's gathered
+	// results
+	PageTokenTTL = 10 * time.Minute
+	// maxEnginePages is the deepest engine page a search asks for
+	maxEnginePages = 5
+	// maxPageSessions bounds the searches kept for page tokens
+	maxPageSessions = 1000
+)
+
+// PageInfo is what a paginated search gathered beyond the page asked for
+type PageInfo struct {
+	// Token asks for further pages of the same results
+	Token string
+	// More is false once the engines have nothing more to give
+	More bool
+}
+
+// pageSession is the results gathered for one search's pages
+type pageSession struct {
+	key        string
+	base       model.SearchResults
+	results    []model.Result
+	seen       map[string]bool
+	enginePage int
+	exhausted  bool
+	expires    time.Time
+}
+
+// pageStore keeps page sessions by token until they expire
+type pageStore struct {
+	mu       sync.Mutex
+	sessions map[string]*pageSession
+}
+
+func (s *pageStore) get(token string) *pageSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := s.sessions[token]
+	if session == nil || time.Now().After(session.expires) {
+		return nil
+	}
+	return session
+}
+
+// put stores session under token, unless a session gathered further is
+// already there, and drops expired sessions and, when full, the one
+// closest to expiring
+func (s *pageStore) put(token string, session *pageSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*pageSession)
+	}
+	if old := s.sessions[token]; old != nil && old.key == session.key && len(old.results) > len(session.results) {
+		old.expires = session.expires
+		return
+	}
+	now := time.Now()
+	var oldest string
+	for t, existing := range s.sessions {
+		if now.After(existing.expires) {
+			delete(s.sessions, t)
+		} else if oldest == "" || existing.expires.Before(s.sessions[oldest].expires) {
+			oldest = t
+		}
+	}
+	if len(s.sessions) >= maxPageSessions && oldest != "" {
+		delete(s.sessions, oldest)
+	}
+	s.sessions[token] = session
+}
+
+// newPageToken returns a random page token
+func newPageToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// SearchPages answers page query.Page of query, query.PerPage results a
+// page, returning every result gathered up to and including that page. A
+// token from an earlier page of the same search continues its results;
+// an unknown, expired or mismatched token starts the search afresh.
+func (a *Aggregator) SearchPages(ctx context.Context, query *model.Query, token string) (*model.SearchResults, PageInfo, error) {
+	page := max(query.Page, 1)
+	perPage := query.PerPage
+	if perPage <= 0 {
+		perPage = 20
+	}
+	key := a.pageKey(query)
+
+	var firstErr error
+	session := a.pages.get(token)
+	if session == nil || session.key != key {
+		token = newPageToken()
+		first := *query
+		first.Page = 1
+		results, err := a.Search(ctx, &first)
+		if results == nil {
+			return nil, PageInfo{}, err
+		}
+		firstErr = err
+		session = &pageSession{key: key, base: *results, seen: make(map[string]bool), enginePage: 1}
+		session.add(results.Results)
+		session.exhausted = len(results.Results) == 0
+	}
+
+	// Gather further engine pages until the page asked for is full
+	for len(session.results) < page*perPage && !session.exhausted && session.enginePage < maxEnginePages {
+		next := *query
+		next.Page = session.enginePage + 1
+		results, _ := a.Search(ctx, &next)
+		if ctx.Err() != nil {
+			// Out of time; the pages gathered so far still answer
+			break
+		}
+		session = session.extend()
+		session.enginePage = next.Page
+		if results == nil || results.Degraded || session.add(results.Results) == 0 {
+			session.exhausted = true
+		}
+	}
+	if session.enginePage >= maxEnginePages {
+		session.exhausted = true
+	}
+	session.expires = time.Now().Add(PageTokenTTL)
+	a.pages.put(token, session)
+
+	out := session.base
+	out.Query = query.Text
+	out.Results = session.results
+	out.TotalResults = len(session.results)
+	out.Page = page
+	out.PerPage = perPage
+	out.CalculateTotalPages()
+	return &out, PageInfo{Token: token, More: !session.exhausted || len(session.results) > page*perPage}, firstErr
+}
+
+// pageKey identifies the search query pages through: the cache key of its
+// first page, so operators and filters count but the page does not
+func (a *Aggregator) pageKey(query *model.Query) string {
+	q := *query
+	q.Page = 1
+	ops := ParseOperators(q.Text)
+	a.applyOperators(&q, ops)
+	return a.generateCacheKey(&q)
+}
+
+// extend returns a copy of the session to gather further results into, so
+// a session shared with another request is never changed under it
+func (s *pageSession) extend() *pageSession {
+	next := *s
+	next.results = append([]model.Result(nil), s.results...)
+	next.seen = make(map[string]bool, len(s.seen))
+	for url := range s.seen {
+		next.seen[url] = true
+	}
+	return &next
+}
+
+// add appends the results not gathered before and returns how many
+func (s *pageSession) add(results []model.Result) int {
+	added := 0
+	for _, r := range results {
+		if s.seen[r.URL] {
+			continue
+		}
+		s.seen[r.URL] = true
+		s.results = append(s.results, r)
+		added++
+	}
+	return added
+}
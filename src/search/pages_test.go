@@ -0,0 +1,107 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// pagedEngine answers 6 results a page for 3 pages; its page 2 repeats
+// one result of page 1, and its answers shift on every call, like an
+// upstream whose ranking moves between requests
+type pagedEngine struct {
+	*BaseEngine
+	calls atomic.Int32
+}
+
+func (e *pagedEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	call := e.calls.Add(1)
+	if query.Page > 3 {
+		return nil, nil
+	}
+	results := make([]model.Result, 0, 6)
+	for i := range 6 {
+		n := (query.Page-1)*6 + i
+		if query.Page == 2 && i == 0 {
+			n = 0
+		}
+		results = append(results, model.Result{
+			Title:  fmt.Sprintf("result %d", n),
+			URL:    fmt.Sprintf("https://example.com/%d", n),
+			Engine: e.Name(),
+			Score:  float64(100 - n + int(call)%3),
+		})
+	}
+	return results, nil
+}
+
+func TestSearchPages(t *testing.T) {
+	engine := &pagedEngine{BaseEngine: newMockEngine("paged", model.CategoryGeneral, true).BaseEngine}
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{Timeout: 5 * time.Second})
+	query := func(page int) *model.Query {
+		return &model.Query{Text: "golang", Category: model.CategoryGeneral, Page: page, PerPage: 4}
+	}
+
+	first, info, err := agg.SearchPages(context.Background(), query(1), "")
+	if err != nil || info.Token == "" || !info.More {
+		t.Fatalf("page 1: %v, %+v", err, info)
+	}
+	if len(first.Results) != 6 || engine.calls.Load() != 1 {
+		t.Fatalf("page 1 gathered %d results in %d calls, want 6 in 1", len(first.Results), engine.calls.Load())
+	}
+	page1 := append([]model.Result(nil), first.GetPage(1)...)
+
+	// Page 2 needs results 5-8: the engine's page 2, less its repeat
+	second, info2, _ := agg.SearchPages(context.Background(), query(2), info.Token)
+	if info2.Token != info.Token || engine.calls.Load() != 2 {
+		t.Fatalf("page 2 token %q after %d calls", info2.Token, engine.calls.Load())
+	}
+	if len(second.Results) != 11 || second.TotalPages != 3 || second.Page != 2 {
+		t.Fatalf("page 2 = %d results, %d pages", len(second.Results), second.TotalPages)
+	}
+	seen := map[string]bool{}
+	for i, r := range second.Results {
+		if seen[r.URL] {
+			t.Errorf("%s repeats", r.URL)
+		}
+		seen[r.URL] = true
+		if i < len(page1) && r.URL != page1[i].URL {
+			t.Errorf("page 1 reordered: position %d is %s, was %s", i, r.URL, page1[i].URL)
+		}
+	}
+
+	// Page 3 again from the token, then the end
+	third, info3, _ := agg.SearchPages(context.Background(), query(3), info.Token)
+	if len(third.GetPage(3)) != 4 || !info3.More {
+		t.Errorf("page 3 = %d results, more %v", len(third.GetPage(3)), info3.More)
+	}
+	last, info5, _ := agg.SearchPages(context.Background(), query(6), info.Token)
+	if len(last.GetPage(6)) != 0 || info5.More || len(last.Results) != 17 {
+		t.Errorf("past the end: %d gathered, more %v", len(last.Results), info5.More)
+	}
+
+	// Another search with this token starts afresh
+	other := query(1)
+	other.Text = "rust"
+	if _, infoOther, _ := agg.SearchPages(context.Background(), other, info.Token); infoOther.Token == info.Token {
+		t.Error("a token was reused for another search")
+	}
+}
+
+func TestPageStoreBounds(t *testing.T) {
+	var store pageStore
+	for i := range maxPageSessions + 10 {
+		store.put(fmt.Sprint(i), &pageSession{key: "k", expires: time.Now().Add(time.Duration(i+1) * time.Second)})
+	}
+	if len(store.sessions) != maxPageSessions || store.get("0") != nil || store.get(fmt.Sprint(maxPageSessions+9)) == nil {
+		t.Errorf("kept %d sessions", len(store.sessions))
+	}
+	store.put("old", &pageSession{key: "k", expires: time.Now().Add(-time.Second)})
+	if store.get("old") != nil {
+		t.Error("an expired session was returned")
+	}
+}
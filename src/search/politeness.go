@@ -0,0 +1,379 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Host politeness. Several engines may scrape the same upstream host (a web
+// engine and its image and news variants, a retry, a health probe), and
+// under heavy traffic they would each hammer it on their own. Every engine
+// request passes one gate per host that spaces requests, honors the host's
+// robots.txt Crawl-delay and caps the requests in flight. A request that
+// would wait longer than the politeness allows is refused rather than
+// queued, so a busy host fails fast instead of holding up searches.
+
+// robotsTTL is how long a host's robots.txt is trusted
+const robotsTTL = 24 * time.Hour
+
+// ErrHostBusy is matched by every *HostBusyError
+var ErrHostBusy = errors.New("upstream host busy")
+
+// HostBusyError reports an engine request refused because its host was
+// asked too recently or has too many requests in flight
+type HostBusyError struct {
+	Host string
+	Wait time.Duration
+}
+
+func (e *HostBusyError) Error() string {
+	return fmt.Sprintf("upstream host %s is busy, next request in %s", e.Host, e.Wait.Round(time.Millisecond))
+}
+
+// Unwrap lets callers match ErrHostBusy
+func (e *HostBusyError) Unwrap() error {
+	return ErrHostBusy
+}
+
+// HostPolicy spaces and caps the requests to one host
+type HostPolicy struct {
+	// MinInterval is the least time between two requests
+	MinInterval time.Duration
+	// MaxConcurrent is the most requests in flight; 0 is unlimited
+	MaxConcurrent int
+}
+
+// Politeness is how engine requests treat upstream hosts
+type Politeness struct {
+	// Default applies to hosts without a policy of their own
+	Default HostPolicy
+	// Hosts holds per-host policies, keyed by host name. Zero fields fall
+	// back to Default.
+	Hosts map[string]HostPolicy
+	// HonorRobots spaces requests by the host's robots.txt Crawl-delay,
+	// when it is longer than MinInterval
+	HonorRobots bool
+	// MaxCrawlDelay bounds the Crawl-delay honored
+	MaxCrawlDelay time.Duration
+	// MaxWait is the longest a request waits for its turn before it is
+	// refused
+	MaxWait time.Duration
+}
+
+// HostStatus is one host's politeness state
+type HostStatus struct {
+	Host string `json:"host"`
+	// IntervalMS is the spacing applied, Crawl-delay included
+	IntervalMS int64 `json:"interval_ms"`
+	// CrawlDelayMS is the host's robots.txt Crawl-delay, before the cap
+	CrawlDelayMS  int64 `json:"crawl_delay_ms,omitempty"`
+	InFlight      int   `json:"in_flight"`
+	MaxConcurrent int   `json:"max_concurrent,omitempty"`
+	Requests      int64 `json:"requests"`
+	Refused       int64 `json:"refused"`
+	// WaitedMS is the total time requests waited for their turn
+	WaitedMS int64 `json:"waited_ms"`
+}
+
+// hostGate applies a Politeness to every engine request
+type hostGate struct {
+	mu     sync.Mutex
+	policy Politeness
+	hosts  map[string]*hostState
+}
+
+// hostState is one host's schedule, guarded by the gate's lock
+type hostState struct {
+	next          time.Time
+	slots         chan struct{}
+	inFlight      int
+	crawlDelay    time.Duration
+	robotsChecked time.Time
+	requests      int64
+	refused       int64
+	waited        time.Duration
+}
+
+// SetPoliteness replaces how engine requests treat upstream hosts. The zero
+// Politeness turns the gate off.
+func (a *Aggregator) SetPoliteness(p Politeness) {
+	hosts := make(map[string]HostPolicy, len(p.Hosts))
+	for host, policy := range p.Hosts {
+		hosts[strings.ToLower(host)] = policy
+	}
+	p.Hosts = hosts
+	a.hosts.mu.Lock()
+	a.hosts.policy = p
+	if a.hosts.hosts == nil {
+		a.hosts.hosts = make(map[string]*hostState)
+	}
+	a.hosts.mu.Unlock()
+}
+
+// HostStatus reports the politeness state of every host engines asked,
+// busiest first
+func (a *Aggregator) HostStatus() []HostStatus {
+	g := &a.hosts
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status := make([]HostStatus, 0, len(g.hosts))
+	for host, st := range g.hosts {
+		policy := g.policyLocked(host)
+		status = append(status, HostStatus{
+			Host:          host,
+			IntervalMS:    g.intervalLocked(policy, st).Milliseconds(),
+			CrawlDelayMS:  st.crawlDelay.Milliseconds(),
+			InFlight:      st.inFlight,
+			MaxConcurrent: policy.MaxConcurrent,
+			Requests:      st.requests,
+			Refused:       st.refused,
+			WaitedMS:      st.waited.Milliseconds(),
+		})
+	}
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].Requests != status[j].Requests {
+			return status[i].Requests > status[j].Requests
+		}
+		return status[i].Host < status[j].Host
+	})
+	return status
+}
+
+// active reports whether the gate does anything
+func (g *hostGate) active() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p := g.policy
+	return p.Default != (HostPolicy{}) || len(p.Hosts) > 0 || p.HonorRobots
+}
+
+// policyLocked returns host's policy
+func (g *hostGate) policyLocked(host string) HostPolicy {
+	policy, ok := g.policy.Hosts[host]
+	if !ok {
+		return g.policy.Default
+	}
+	if policy.MinInterval <= 0 {
+		policy.MinInterval = g.policy.Default.MinInterval
+	}
+	if policy.MaxConcurrent <= 0 {
+		policy.MaxConcurrent = g.policy.Default.MaxConcurrent
+	}
+	return policy
+}
+
+// intervalLocked is the spacing between requests to a host
+func (g *hostGate) intervalLocked(policy HostPolicy, st *hostState) time.Duration {
+	interval := policy.MinInterval
+	if g.policy.HonorRobots {
+		delay := st.crawlDelay
+		if g.policy.MaxCrawlDelay > 0 && delay > g.policy.MaxCrawlDelay {
+			delay = g.policy.MaxCrawlDelay
+		}
+		if delay > interval {
+			interval = delay
+		}
+	}
+	return interval
+}
+
+// acquire waits for req's turn at its host: a free request slot, then the
+// spacing since the host's last request. client fetches the host's
+// robots.txt the first time it is asked. The returned release must be
+// called once the response is read.
+func (g *hostGate) acquire(client *http.Client, req *http.Request) (func(), error) {
+	ctx := req.Context()
+	host := strings.ToLower(req.URL.Hostname())
+	start := time.Now()
+
+	g.mu.Lock()
+	st, ok := g.hosts[host]
+	if !ok {
+		st = &hostState{}
+		g.hosts[host] = st
+	}
+	if g.policy.HonorRobots && start.Sub(st.robotsChecked) > robotsTTL {
+		// Mark it checked first, so one fetch runs at a time; requests go
+		// on at the configured spacing until it is known
+		st.robotsChecked = start
+		go g.fetchRobots(client, req.URL.Scheme, req.URL.Host, host)
+	}
+	policy := g.policyLocked(host)
+	if policy.MaxConcurrent > 0 && cap(st.slots) != policy.MaxConcurrent {
+		// The cap changed; requests in flight give back their old slots
+		st.slots = make(chan struct{}, policy.MaxConcurrent)
+	}
+	slots := st.slots
+	maxWait := g.policy.MaxWait
+	g.mu.Unlock()
+
+	refuse := func(wait time.Duration) error {
+		g.mu.Lock()
+		st.refused++
+		g.mu.Unlock()
+		return &HostBusyError{Host: host, Wait: wait}
+	}
+
+	if policy.MaxConcurrent > 0 {
+		select {
+		case slots <- struct{}{}:
+		default:
+			var timeout <-chan time.Time
+			if maxWait > 0 {
+				timer := time.NewTimer(maxWait)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-timeout:
+				return nil, refuse(maxWait)
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			g.mu.Lock()
+			st.inFlight--
+			g.mu.Unlock()
+			if policy.MaxConcurrent > 0 {
+				<-slots
+			}
+		})
+	}
+
+	now := time.Now()
+	g.mu.Lock()
+	st.inFlight++
+	slot := st.next
+	if slot.Before(now) {
+		slot = now
+	}
+	wait := slot.Sub(now)
+	deadline, hasDeadline := ctx.Deadline()
+	if (maxWait > 0 && now.Add(wait).Sub(start) > maxWait) || (hasDeadline && slot.After(deadline)) {
+		g.mu.Unlock()
+		release()
+		return nil, refuse(wait)
+	}
+	st.next = slot.Add(g.intervalLocked(policy, st))
+	st.requests++
+	st.waited += slot.Sub(start)
+	g.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// fetchRobots reads the Crawl-delay of host's robots.txt
+func (g *hostGate) fetchRobots(client *http.Client, scheme, hostPort, host string) {
+	if scheme == "" {
+		scheme = "https"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+hostPort+"/robots.txt", nil)
+	if err != nil {
+		return
+	}
+	var delay time.Duration
+	if resp, err := client.Do(req); err == nil {
+		if resp.StatusCode == http.StatusOK {
+			delay = robotsCrawlDelay(io.LimitReader(resp.Body, 512*1024))
+		}
+		resp.Body.Close()
+	}
+	g.mu.Lock()
+	if st, ok := g.hosts[host]; ok {
+		st.crawlDelay = delay
+	}
+	g.mu.Unlock()
+}
+
+// robotsCrawlDelay returns the Crawl-delay robots.txt sets for every user
+// agent ("*")
+func robotsCrawlDelay(r io.Reader) time.Duration {
+	var delay time.Duration
+	everyone := false
+	// Consecutive User-agent lines open one group
+	inAgents := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				everyone = false
+			}
+			inAgents = true
+			if value == "*" {
+				everyone = true
+			}
+		case "crawl-delay":
+			inAgents = false
+			if !everyone {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 && !math.IsInf(seconds, 0) {
+				delay = time.Duration(seconds * float64(time.Second))
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return delay
+}
+
+type hostGateKey struct{}
+
+// withHostGate returns ctx passing the engine's requests through the
+// politeness gate, unless it is off
+func (a *Aggregator) withHostGate(ctx context.Context) context.Context {
+	if !a.hosts.active() {
+		return ctx
+	}
+	return context.WithValue(ctx, hostGateKey{}, &a.hosts)
+}
+
+// AwaitHost waits for req's turn at its upstream host, shared by every
+// engine of the search that sends it. It fails with a *HostBusyError when
+// the turn is too far off. release must be called once the response body
+// is read.
+func AwaitHost(client *http.Client, req *http.Request) (release func(), err error) {
+	g, ok := req.Context().Value(hostGateKey{}).(*hostGate)
+	if !ok {
+		return func() {}, nil
+	}
+	return g.acquire(client, req)
+}
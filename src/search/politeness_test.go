@@ -0,0 +1,144 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsCrawlDelay(t *testing.T) {
+	tests := []struct {
+		robots string
+		want   time.Duration
+	}{
+		{"User-agent: *\nCrawl-delay: 2\n", 2 * time.Second},
+		{"User-agent: Googlebot\nCrawl-delay: 1\n\nUser-agent: *\nDisallow: /search\nCrawl-delay: 0.5 # be nice\n", 500 * time.Millisecond},
+		{"User-agent: Bingbot\nUser-agent: *\nCrawl-delay: 3\n", 3 * time.Second},
+		{"User-agent: Bingbot\nCrawl-delay: 10\n", 0},
+		{"User-agent: *\nCrawl-delay: soon\n", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := robotsCrawlDelay(strings.NewReader(tt.robots)); got != tt.want {
+			t.Errorf("robotsCrawlDelay(%q) = %v, want %v", tt.robots, got, tt.want)
+		}
+	}
+}
+
+// awaitHost runs AwaitHost for url through agg's gate
+func awaitHost(t *testing.T, ctx context.Context, agg *Aggregator, url string) (func(), error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(agg.withHostGate(ctx), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return AwaitHost(http.DefaultClient, req)
+}
+
+func TestHostPolitenessSpacing(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, time.Second)
+	agg.SetPoliteness(Politeness{
+		Default: HostPolicy{MinInterval: 40 * time.Millisecond},
+		Hosts:   map[string]HostPolicy{"Slow.Example": {MinInterval: time.Second}},
+		MaxWait: 500 * time.Millisecond,
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := awaitHost(t, context.Background(), agg, "https://fast.example/search")
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("3 requests took %v, want them spaced 40ms apart", elapsed)
+	}
+	// Another host has its own schedule
+	if release, err := awaitHost(t, context.Background(), agg, "https://slow.example/"); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+	// and a turn further off than MaxWait is refused
+	if _, err := awaitHost(t, context.Background(), agg, "https://slow.example/"); !errors.Is(err, ErrHostBusy) {
+		t.Errorf("second request to slow.example: err = %v", err)
+	}
+
+	status := agg.HostStatus()
+	if len(status) != 2 || status[0].Host != "fast.example" || status[0].Requests != 3 || status[1].Refused != 1 || status[1].IntervalMS != 1000 {
+		t.Errorf("HostStatus() = %+v", status)
+	}
+}
+
+func TestHostPolitenessConcurrency(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, time.Second)
+	agg.SetPoliteness(Politeness{
+		Default: HostPolicy{MaxConcurrent: 1},
+		MaxWait: 30 * time.Millisecond,
+	})
+
+	release, err := awaitHost(t, context.Background(), agg, "https://busy.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := awaitHost(t, context.Background(), agg, "https://busy.example/"); !errors.Is(err, ErrHostBusy) {
+		t.Errorf("over the cap: err = %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	// A slot freed within MaxWait is taken
+	second, err := awaitHost(t, context.Background(), agg, "https://busy.example/")
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	second()
+	second()
+	if status := agg.HostStatus(); status[0].InFlight != 0 {
+		t.Errorf("in flight = %d after every release", status[0].InFlight)
+	}
+
+	// Off without a policy
+	agg.SetPoliteness(Politeness{})
+	if ctx := agg.withHostGate(context.Background()); ctx.Value(hostGateKey{}) != nil {
+		t.Error("gate active with the zero Politeness")
+	}
+}
+
+func TestHostPolitenessRobots(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 30\n"))
+		}
+	}))
+	defer upstream.Close()
+
+	agg := NewAggregatorSimple([]Engine{}, time.Second)
+	agg.SetPoliteness(Politeness{HonorRobots: true, MaxCrawlDelay: 3 * time.Second})
+	release, err := awaitHost(t, context.Background(), agg, upstream.URL+"/search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status := agg.HostStatus()
+		if status[0].CrawlDelayMS == 30000 {
+			if status[0].IntervalMS != 3000 {
+				t.Errorf("interval = %dms, want Crawl-delay capped at 3s", status[0].IntervalMS)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("robots.txt never read: %+v", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// previewTimeout bounds the upstream request made for a preview cache miss.
+// A preview that arrives after the user has finished typing is useless.
+const previewTimeout = 1500 * time.Millisecond
+
+// Preview returns up to limit results for a partial query without a full
+// fan-out. A cached results page for the same query is used when present;
+// otherwise, unless cacheOnly is set, the single fastest healthy engine is
+// asked under a short deadline. Preview results are cached under their own
+// key so they never stand in for a full search.
+func (a *Aggregator) Preview(ctx context.Context, query *model.Query, limit int, cacheOnly bool) (*model.SearchResults, error) {
+	if err := query.ValidateSearchQuery(); err != nil {
+		return nil, err
+	}
+
+	ops := ParseOperators(query.Text)
+	query.ParsedOperators = ops
+	query.CleanedText = ops.CleanedQuery
+	a.applyOperators(query, ops)
+
+	fullKey := a.generateCacheKey(query)
+	previewKey := "preview:" + fullKey
+	if a.cacheEnabled && a.cache != nil {
+		for _, key := range []string{fullKey, previewKey} {
+			if cached := a.cache.GetFor(key, query.Category); cached != nil {
+				preview := trimPreview(a.screenResults(cached), limit)
+				preview.FromCache = true
+				return preview, nil
+			}
+		}
+	}
+	if cacheOnly {
+		return nil, model.ErrNoResults
+	}
+
+	eng := a.previewEngine(query)
+	if eng == nil {
+		return nil, model.ErrNoEngines
+	}
+
+	previewCtx, cancel := context.WithTimeout(ctx, previewTimeout)
+	defer cancel()
+
+	start := time.Now()
+	results, err := a.runEngine(a.withRequestOverrides(previewCtx, eng, query), eng, query)
+	if err != nil {
+		a.recordEngineFailure(eng, err)
+		return nil, err
+	}
+	a.recordEngineSuccess(eng, time.Since(start))
+	results = a.filterSafeSearch(eng, query, results)
+
+	searchResults := model.NewSearchResults(query.Text, query.Category)
+	searchResults.AddResults(results)
+	searchResults.Results = a.applyFilters(deduplicateResults(searchResults.Results), query)
+	sortResults(searchResults.Results, query.SortBy)
+	searchResults.TotalResults = len(searchResults.Results)
+	searchResults.Engines = []string{eng.DisplayName()}
+	searchResults.SearchTime = time.Since(start).Seconds()
+	if len(searchResults.Results) == 0 {
+		return nil, model.ErrNoResults
+	}
+
+	if a.cacheEnabled && a.cache != nil {
+		a.cache.SetFor(previewKey, query.Category, searchResults)
+	}
+	return trimPreview(a.screenResults(searchResults), limit), nil
+}
+
+// previewEngine picks the eligible engine with the lowest recent response
+// time. Engines that have not reported a latency yet rank after measured
+// ones; ties go to the higher-priority engine.
+func (a *Aggregator) previewEngine(query *model.Query) Engine {
+	now := time.Now()
+	candidates := make([]Engine, 0, len(a.engines))
+	for _, eng := range a.engines {
+		if eng.SupportsCategory(query.Category) && a.canSearch(eng, now) {
+			candidates = append(candidates, eng)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	latency := func(eng Engine) int64 {
+		if tracker, ok := eng.(interface{ GetHealth() EngineHealth }); ok {
+			if ms := tracker.GetHealth().LastResponseTimeMS; ms > 0 {
+				return ms
+			}
+		}
+		return int64(previewTimeout / time.Millisecond)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		li, lj := latency(candidates[i]), latency(candidates[j])
+		if li != lj {
+			return li < lj
+		}
+		return candidates[i].GetPriority() > candidates[j].GetPriority()
+	})
+	return candidates[0]
+}
+
+// trimPreview copies results keeping only the first limit entries, so the
+// cached page is never modified
+func trimPreview(results *model.SearchResults, limit int) *model.SearchResults {
+	trimmed := *results
+	if limit > 0 && len(trimmed.Results) > limit {
+		trimmed.Results = trimmed.Results[:limit]
+	}
+	return &trimmed
+}
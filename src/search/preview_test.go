@@ -0,0 +1,162 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func previewResults(n int) []model.Result {
+	results := make([]model.Result, n)
+	for i := range results {
+		results[i] = model.Result{
+			URL:   "https://example.com/" + string(rune('a'+i)),
+			Title: "Result " + string(rune('A'+i)),
+		}
+	}
+	return results
+}
+
+func TestAggregatorPreviewUsesFullSearchCache(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults(previewResults(5))
+
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 1,
+	})
+
+	if _, err := agg.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	calls := engine.Calls()
+
+	preview, err := agg.Preview(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}, 3, true)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if !preview.FromCache {
+		t.Error("Preview() should be served from cache")
+	}
+	if len(preview.Results) != 3 {
+		t.Errorf("Preview() returned %d results, want 3", len(preview.Results))
+	}
+	if engine.Calls() != calls {
+		t.Error("Preview() should not query engines on a cache hit")
+	}
+
+	// The cached full page must keep all its results
+	full, err := agg.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(full.Results) != 5 {
+		t.Errorf("cached Search() returned %d results, want 5", len(full.Results))
+	}
+}
+
+func TestAggregatorPreviewCacheOnlyMiss(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults(previewResults(2))
+
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 1,
+	})
+
+	_, err := agg.Preview(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}, 3, true)
+	if !errors.Is(err, model.ErrNoResults) {
+		t.Errorf("Preview() error = %v, want ErrNoResults", err)
+	}
+	if engine.Calls() != 0 {
+		t.Errorf("cache-only Preview() made %d engine calls, want 0", engine.Calls())
+	}
+}
+
+func TestAggregatorPreviewPicksFastestEngine(t *testing.T) {
+	slow := newMockEngine("slow", model.CategoryGeneral, true)
+	fast := newMockEngine("fast", model.CategoryGeneral, true)
+	slow.GetConfig().Priority = 100
+	fast.GetConfig().Priority = 10
+	slow.RecordSuccess(900 * time.Millisecond)
+	fast.RecordSuccess(120 * time.Millisecond)
+	slow.SetResults(previewResults(4))
+	fast.SetResults(previewResults(4))
+
+	agg := NewAggregator([]Engine{slow, fast}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 2,
+	})
+
+	preview, err := agg.Preview(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}, 3, false)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if fast.Calls() != 1 || slow.Calls() != 0 {
+		t.Errorf("engine calls fast=%d slow=%d, want 1 and 0", fast.Calls(), slow.Calls())
+	}
+	if len(preview.Results) != 3 {
+		t.Errorf("Preview() returned %d results, want 3", len(preview.Results))
+	}
+
+	// A repeat preview is answered from the preview cache entry
+	again, err := agg.Preview(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}, 3, false)
+	if err != nil {
+		t.Fatalf("second Preview() error = %v", err)
+	}
+	if !again.FromCache || fast.Calls() != 1 {
+		t.Errorf("second Preview() FromCache=%v calls=%d, want cached with no new call", again.FromCache, fast.Calls())
+	}
+
+	// A full search is not satisfied by the preview entry
+	if _, err := agg.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if fast.Calls()+slow.Calls() < 2 {
+		t.Error("Search() should fan out rather than reuse the preview cache entry")
+	}
+}
+
+func TestAggregatorPreviewSafeSearch(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults([]model.Result{
+		{URL: "https://blocked.example/a", Title: "Blocked"},
+		{URL: "https://example.com/b", Title: "Kept"},
+	})
+
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 1,
+	})
+	agg.SetSafeSearchFilter(func(r model.Result, level int) bool {
+		return r.URL == "https://blocked.example/a"
+	})
+
+	preview, err := agg.Preview(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, SafeSearch: 2}, 5, false)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if len(preview.Results) != 1 || preview.Results[0].URL != "https://example.com/b" {
+		t.Errorf("strict Preview() = %+v, want only the result the filter passed", preview.Results)
+	}
+
+	// The cached preview is the filtered one
+	cached, err := agg.Preview(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, SafeSearch: 2}, 5, true)
+	if err != nil {
+		t.Fatalf("cache-only Preview() error = %v", err)
+	}
+	if len(cached.Results) != 1 {
+		t.Errorf("cached strict Preview() returned %d results, want 1", len(cached.Results))
+	}
+}
@@ -0,0 +1,351 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Engine quota budgeting. Engines that call a paid or keyed API often have
+// a daily request quota. The aggregator counts each engine's requests per
+// day and, once the day's budget is spent, searches the engine's fallback
+// in its place (usually a scraping engine for the same site) or leaves it
+// out until the next day. Part of the budget can be held back for peak
+// hours, so a quiet morning cannot spend what the evening needs. Days and
+// hours are in the server's local time.
+
+// quotaHistoryDays is how many days of usage are kept for charts
+const quotaHistoryDays = 30
+
+// ErrQuotaExhausted is matched by every *QuotaExhaustedError
+var ErrQuotaExhausted = errors.New("engine quota exhausted")
+
+// QuotaExhaustedError reports an engine request refused because the
+// engine's daily budget is spent
+type QuotaExhaustedError struct {
+	Engine string
+}
+
+func (e *QuotaExhaustedError) Error() string {
+	return fmt.Sprintf("engine %s has used its daily quota", e.Engine)
+}
+
+// Unwrap lets callers match ErrQuotaExhausted
+func (e *QuotaExhaustedError) Unwrap() error {
+	return ErrQuotaExhausted
+}
+
+// EngineQuota is an engine's daily request budget
+type EngineQuota struct {
+	// Daily is the number of requests allowed per day
+	Daily int
+	// PeakStart and PeakEnd are the peak hours, [PeakStart, PeakEnd) in
+	// 0-23; equal hours mean no peak. A peak may wrap past midnight.
+	PeakStart int
+	PeakEnd   int
+	// PeakReserve is the number of requests held back for peak hours
+	PeakReserve int
+	// Fallback names the engine searched in place of this one once its
+	// budget is spent; "" leaves the engine out
+	Fallback string
+}
+
+// inPeak reports whether hour falls in the peak hours
+func (q EngineQuota) inPeak(hour int) bool {
+	switch {
+	case q.PeakStart == q.PeakEnd:
+		return false
+	case q.PeakStart < q.PeakEnd:
+		return hour >= q.PeakStart && hour < q.PeakEnd
+	default:
+		return hour >= q.PeakStart || hour < q.PeakEnd
+	}
+}
+
+// reserved returns the requests held back at hour: the peak reserve, from
+// the start of the day until the day's peak begins
+func (q EngineQuota) reserved(hour int) int {
+	if q.PeakStart == q.PeakEnd || q.inPeak(hour) || hour >= q.PeakStart {
+		return 0
+	}
+	return q.PeakReserve
+}
+
+// allows reports whether a request may be made at hour with used requests
+// already made today
+func (q EngineQuota) allows(used, hour int) bool {
+	return used < q.Daily-q.reserved(hour)
+}
+
+// Quota states reported by QuotaStatus
+const (
+	QuotaOK        = "ok"
+	QuotaReserved  = "reserved"
+	QuotaExhausted = "exhausted"
+)
+
+// QuotaUse is the number of requests an engine made in one hour, as stored
+// and restored across restarts
+type QuotaUse struct {
+	Engine   string
+	Day      string
+	Hour     int
+	Requests int
+}
+
+// QuotaRecorder is told about every request counted against a quota,
+// usually to store it
+type QuotaRecorder func(engine string, at time.Time)
+
+// QuotaDay is an engine's requests on one day
+type QuotaDay struct {
+	Day      string `json:"day"`
+	Requests int    `json:"requests"`
+}
+
+// QuotaStatus is an engine's budget and usage
+type QuotaStatus struct {
+	Engine    string `json:"engine"`
+	Daily     int    `json:"daily"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	// Reserved is the part of Remaining held back for peak hours now
+	Reserved  int    `json:"reserved"`
+	PeakHours string `json:"peak_hours,omitempty"`
+	Fallback  string `json:"fallback,omitempty"`
+	// State is QuotaOK, QuotaReserved while only the peak reserve is left
+	// and it is not yet peak time, or QuotaExhausted
+	State string `json:"state"`
+	// Hourly is today's requests per hour
+	Hourly [24]int `json:"hourly"`
+	// History is the requests per day, oldest first, today included
+	History []QuotaDay `json:"history"`
+}
+
+// quotaBudget holds the engines' quotas and their usage per day and hour
+type quotaBudget struct {
+	mu     sync.Mutex
+	quotas map[string]EngineQuota
+	// usage is keyed by engine, then day
+	usage    map[string]map[string]*[24]int
+	recorder QuotaRecorder
+}
+
+func quotaDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// hours returns an engine's usage on day, creating it when create is set.
+// The caller holds b.mu.
+func (b *quotaBudget) hours(engine, day string, create bool) *[24]int {
+	days := b.usage[engine]
+	if days == nil {
+		if !create {
+			return nil
+		}
+		days = make(map[string]*[24]int)
+		if b.usage == nil {
+			b.usage = make(map[string]map[string]*[24]int)
+		}
+		b.usage[engine] = days
+	}
+	h := days[day]
+	if h == nil && create {
+		h = new([24]int)
+		days[day] = h
+		b.prune(days)
+	}
+	return h
+}
+
+// prune drops days beyond the kept history. The caller holds b.mu.
+func (b *quotaBudget) prune(days map[string]*[24]int) {
+	if len(days) <= quotaHistoryDays {
+		return
+	}
+	keys := make([]string, 0, len(days))
+	for day := range days {
+		keys = append(keys, day)
+	}
+	sort.Strings(keys)
+	for _, day := range keys[:len(keys)-quotaHistoryDays] {
+		delete(days, day)
+	}
+}
+
+// usedOn returns an engine's requests on day. The caller holds b.mu.
+func (b *quotaBudget) usedOn(engine, day string) int {
+	h := b.hours(engine, day, false)
+	if h == nil {
+		return 0
+	}
+	total := 0
+	for _, n := range h {
+		total += n
+	}
+	return total
+}
+
+// allows reports whether engine may make a request at now
+func (b *quotaBudget) allows(engine string, now time.Time) bool {
+	engine = strings.ToLower(engine)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.quotas[engine]
+	if !ok {
+		return true
+	}
+	return q.allows(b.usedOn(engine, quotaDay(now)), now.Hour())
+}
+
+// take counts a request by engine at now, or reports false when its budget
+// does not allow one
+func (b *quotaBudget) take(engine string, now time.Time) bool {
+	name := strings.ToLower(engine)
+	b.mu.Lock()
+	q, ok := b.quotas[name]
+	if !ok {
+		b.mu.Unlock()
+		return true
+	}
+	day := quotaDay(now)
+	if !q.allows(b.usedOn(name, day), now.Hour()) {
+		b.mu.Unlock()
+		return false
+	}
+	b.hours(name, day, true)[now.Hour()]++
+	recorder := b.recorder
+	b.mu.Unlock()
+
+	if recorder != nil {
+		recorder(name, now)
+	}
+	return true
+}
+
+// fallback returns the engine to search in place of engine, or ""
+func (b *quotaBudget) fallback(engine string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.quotas[strings.ToLower(engine)].Fallback
+}
+
+// SetEngineQuotas replaces the daily quotas, keyed by engine name. Engines
+// without one are not budgeted. Usage counted so far is kept.
+func (a *Aggregator) SetEngineQuotas(quotas map[string]EngineQuota) {
+	copied := make(map[string]EngineQuota, len(quotas))
+	for name, q := range quotas {
+		if q.Daily <= 0 {
+			continue
+		}
+		if q.PeakReserve < 0 {
+			q.PeakReserve = 0
+		}
+		if q.PeakReserve > q.Daily {
+			q.PeakReserve = q.Daily
+		}
+		q.Fallback = strings.ToLower(q.Fallback)
+		copied[strings.ToLower(name)] = q
+	}
+	a.quota.mu.Lock()
+	a.quota.quotas = copied
+	a.quota.mu.Unlock()
+}
+
+// SetQuotaRecorder sets the function told about every request counted
+// against a quota
+func (a *Aggregator) SetQuotaRecorder(recorder QuotaRecorder) {
+	a.quota.mu.Lock()
+	a.quota.recorder = recorder
+	a.quota.mu.Unlock()
+}
+
+// RestoreQuotaUsage adds stored usage, so a restart does not reset the
+// day's budget
+func (a *Aggregator) RestoreQuotaUsage(uses []QuotaUse) {
+	a.quota.mu.Lock()
+	defer a.quota.mu.Unlock()
+	for _, u := range uses {
+		if u.Hour < 0 || u.Hour > 23 || u.Requests <= 0 {
+			continue
+		}
+		a.quota.hours(strings.ToLower(u.Engine), u.Day, true)[u.Hour] += u.Requests
+	}
+}
+
+// QuotaStatus returns the budget and usage of every engine with a quota,
+// by engine name
+func (a *Aggregator) QuotaStatus(now time.Time) []QuotaStatus {
+	b := &a.quota
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	today := quotaDay(now)
+	statuses := make([]QuotaStatus, 0, len(b.quotas))
+	for name, q := range b.quotas {
+		used := b.usedOn(name, today)
+		st := QuotaStatus{
+			Engine:    name,
+			Daily:     q.Daily,
+			Used:      used,
+			Remaining: max(q.Daily-used, 0),
+			Fallback:  q.Fallback,
+			State:     QuotaOK,
+			History:   make([]QuotaDay, 0, quotaHistoryDays),
+		}
+		if q.PeakStart != q.PeakEnd {
+			st.PeakHours = fmt.Sprintf("%02d:00-%02d:00", q.PeakStart, q.PeakEnd)
+		}
+		st.Reserved = min(q.reserved(now.Hour()), st.Remaining)
+		switch {
+		case st.Remaining == 0:
+			st.State = QuotaExhausted
+		case !q.allows(used, now.Hour()):
+			st.State = QuotaReserved
+		}
+		if h := b.hours(name, today, false); h != nil {
+			st.Hourly = *h
+		}
+		for i := quotaHistoryDays - 1; i >= 0; i-- {
+			day := quotaDay(now.AddDate(0, 0, -i))
+			st.History = append(st.History, QuotaDay{Day: day, Requests: b.usedOn(name, day)})
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Engine < statuses[j].Engine })
+	return statuses
+}
+
+// budgetEngines replaces each engine whose budget is spent with its
+// fallback, or drops it when it has none usable for query
+func (a *Aggregator) budgetEngines(engines []Engine, query *model.Query) []Engine {
+	now := time.Now()
+	budgeted := make([]Engine, 0, len(engines))
+	chosen := make(map[string]bool, len(engines))
+	for _, engine := range engines {
+		chosen[strings.ToLower(engine.Name())] = true
+	}
+	for _, engine := range engines {
+		if a.quota.allows(engine.Name(), now) {
+			budgeted = append(budgeted, engine)
+			continue
+		}
+		name := a.quota.fallback(engine.Name())
+		if name == "" || chosen[name] || !a.quota.allows(name, now) {
+			continue
+		}
+		for _, fb := range a.engines {
+			if strings.ToLower(fb.Name()) == name && fb.IsEnabled() && a.engineEligible(fb, query) {
+				budgeted = append(budgeted, fb)
+				chosen[name] = true
+				break
+			}
+		}
+	}
+	return budgeted
+}
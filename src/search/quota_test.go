@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestEngineQuotaReserve(t *testing.T) {
+	evening := EngineQuota{Daily: 100, PeakStart: 17, PeakEnd: 22, PeakReserve: 30}
+	night := EngineQuota{Daily: 100, PeakStart: 22, PeakEnd: 2, PeakReserve: 30}
+	tests := []struct {
+		quota EngineQuota
+		used  int
+		hour  int
+		want  bool
+	}{
+		{evening, 69, 9, true},
+		{evening, 70, 9, false},
+		{evening, 70, 17, true},
+		{evening, 99, 21, true},
+		{evening, 100, 21, false},
+		{evening, 70, 23, true},
+		{night, 70, 1, true},
+		{night, 70, 12, false},
+		{night, 70, 22, true},
+		{EngineQuota{Daily: 10}, 9, 9, true},
+		{EngineQuota{Daily: 10}, 10, 9, false},
+	}
+	for _, tt := range tests {
+		if got := tt.quota.allows(tt.used, tt.hour); got != tt.want {
+			t.Errorf("%+v allows(%d, %d) = %v, want %v", tt.quota, tt.used, tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestAggregatorEngineQuota(t *testing.T) {
+	api := newMockEngine("api", model.CategoryGeneral, true)
+	api.SetResults([]model.Result{{Title: "From the API", URL: "https://example.com/api"}})
+	scraper := newMockEngine("scraper", model.CategoryGeneral, true)
+	scraper.SetResults([]model.Result{{Title: "Scraped", URL: "https://example.com/scraped"}})
+	agg := NewAggregatorSimple([]Engine{api, scraper}, 5*time.Second)
+	agg.SetEngineQuotas(map[string]EngineQuota{"API": {Daily: 2, Fallback: "scraper"}})
+	var recorded int
+	agg.SetQuotaRecorder(func(engine string, at time.Time) { recorded++ })
+
+	query := &model.Query{Text: "quota", Category: model.CategoryGeneral, Engines: []string{"api"}}
+	for i := 0; i < 3; i++ {
+		if _, err := agg.Search(context.Background(), query); err != nil {
+			t.Fatalf("search %d: %v", i, err)
+		}
+	}
+	if api.Calls() != 2 || scraper.Calls() != 1 {
+		t.Errorf("calls: api %d, scraper %d; want the third search on the fallback", api.Calls(), scraper.Calls())
+	}
+	if recorded != 2 {
+		t.Errorf("recorded %d requests, want 2", recorded)
+	}
+	if _, err := agg.runEngine(context.Background(), api, query); !errors.Is(err, ErrQuotaExhausted) {
+		t.Errorf("runEngine over budget: err = %v", err)
+	}
+
+	status := agg.QuotaStatus(time.Now())
+	if len(status) != 1 || status[0].Engine != "api" || status[0].Used != 2 || status[0].State != QuotaExhausted {
+		t.Fatalf("QuotaStatus() = %+v", status)
+	}
+	if last := status[0].History[len(status[0].History)-1]; last.Requests != 2 || len(status[0].History) != quotaHistoryDays {
+		t.Errorf("history ends with %+v over %d days", last, len(status[0].History))
+	}
+
+	// Without a fallback the engine is left out
+	agg.SetEngineQuotas(map[string]EngineQuota{"api": {Daily: 2}})
+	if engines := agg.filterEngines(&model.Query{Category: model.CategoryGeneral}); len(engines) != 1 || engines[0].Name() != "scraper" {
+		t.Errorf("filterEngines() kept %d engines", len(engines))
+	}
+}
+
+func TestRestoreQuotaUsage(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, time.Second)
+	agg.SetEngineQuotas(map[string]EngineQuota{"api": {Daily: 100}})
+	now := time.Date(2026, 3, 14, 15, 0, 0, 0, time.Local)
+	agg.RestoreQuotaUsage([]QuotaUse{
+		{Engine: "api", Day: "2026-03-14", Hour: 9, Requests: 40},
+		{Engine: "API", Day: "2026-03-14", Hour: 15, Requests: 2},
+		{Engine: "api", Day: "2026-03-13", Hour: 20, Requests: 90},
+		{Engine: "api", Day: "2026-03-14", Hour: 24, Requests: 5},
+	})
+	status := agg.QuotaStatus(now)
+	if len(status) != 1 || status[0].Used != 42 || status[0].Hourly[9] != 40 || status[0].Remaining != 58 {
+		t.Fatalf("QuotaStatus() = %+v", status)
+	}
+	if yesterday := status[0].History[quotaHistoryDays-2]; yesterday.Day != "2026-03-13" || yesterday.Requests != 90 {
+		t.Errorf("yesterday = %+v", yesterday)
+	}
+}
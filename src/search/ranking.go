@@ -0,0 +1,243 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// Ranking pipeline. Engines score their own results and the merge rewards
+// results several engines agree on; a Ranker then adjusts the merged scores
+// with the stages the operator chose, in the order chosen, before results
+// are sorted by relevance. Like the geo boost it runs after the result
+// cache, so a changed pipeline reorders cached results at once.
+
+// Ranking stage names
+const (
+	StageEngineWeight     = "engine_weight"
+	StageDomainAuthority  = "domain_authority"
+	StageFreshness        = "freshness"
+	StageDuplicatePenalty = "duplicate_penalty"
+)
+
+// RankStageNames lists every ranking stage
+var RankStageNames = []string{StageEngineWeight, StageDomainAuthority, StageFreshness, StageDuplicatePenalty}
+
+// RankStage adjusts the scores of merged results. Results are in score
+// order, highest first, as the previous stage left them.
+type RankStage interface {
+	Name() string
+	Apply(results []model.Result, now time.Time)
+}
+
+// Ranker runs ranking stages in order
+type Ranker struct {
+	stages []RankStage
+}
+
+// NewRanker returns a ranker running stages in the order given
+func NewRanker(stages ...RankStage) *Ranker {
+	return &Ranker{stages: stages}
+}
+
+// Stages returns the names of the ranker's stages, in order
+func (r *Ranker) Stages() []string {
+	names := make([]string, 0, len(r.stages))
+	for _, stage := range r.stages {
+		names = append(names, stage.Name())
+	}
+	return names
+}
+
+// Rank returns a copy of results rescored by every stage and sorted by the
+// new scores. results is left as it was.
+func (r *Ranker) Rank(results []model.Result, now time.Time) []model.Result {
+	ranked := append([]model.Result(nil), results...)
+	byScore := func(i, j int) bool { return ranked[i].Score > ranked[j].Score }
+	sort.SliceStable(ranked, byScore)
+	for _, stage := range r.stages {
+		stage.Apply(ranked, now)
+		sort.SliceStable(ranked, byScore)
+	}
+	return ranked
+}
+
+// EngineWeight multiplies a result's score by the weight of the engine
+// that found it, keyed by engine name. Engines not listed keep their
+// scores.
+type EngineWeight map[string]float64
+
+// Name implements RankStage
+func (EngineWeight) Name() string { return StageEngineWeight }
+
+// Apply implements RankStage
+func (w EngineWeight) Apply(results []model.Result, now time.Time) {
+	for i := range results {
+		if weight, ok := w[strings.ToLower(results[i].Engine)]; ok {
+			results[i].Score *= weight
+		}
+	}
+}
+
+// DomainAuthority multiplies a result's score by the weight of its domain,
+// keyed by domain. A listed domain covers its subdomains; the longest
+// match wins.
+type DomainAuthority map[string]float64
+
+// Name implements RankStage
+func (DomainAuthority) Name() string { return StageDomainAuthority }
+
+// Apply implements RankStage
+func (w DomainAuthority) Apply(results []model.Result, now time.Time) {
+	for i := range results {
+		domain := strings.TrimPrefix(strings.ToLower(results[i].ExtractDomain()), "www.")
+		for domain != "" {
+			if weight, ok := w[domain]; ok {
+				results[i].Score *= weight
+				break
+			}
+			dot := strings.IndexByte(domain, '.')
+			if dot < 0 {
+				break
+			}
+			domain = domain[dot+1:]
+		}
+	}
+}
+
+// Freshness raises the score of dated results by Boost for a result
+// published now, decaying by half every HalfLife of age. Undated results
+// keep their scores.
+type Freshness struct {
+	Boost    float64
+	HalfLife time.Duration
+}
+
+// Name implements RankStage
+func (Freshness) Name() string { return StageFreshness }
+
+// Apply implements RankStage
+func (f Freshness) Apply(results []model.Result, now time.Time) {
+	if f.Boost <= 0 || f.HalfLife <= 0 {
+		return
+	}
+	for i := range results {
+		if results[i].PublishedAt.IsZero() {
+			continue
+		}
+		age := now.Sub(results[i].PublishedAt)
+		if age < 0 {
+			age = 0
+		}
+		results[i].Score *= 1 + f.Boost*math.Exp2(-float64(age)/float64(f.HalfLife))
+	}
+}
+
+// DuplicatePenalty lowers further results from a domain already ranked
+// higher: the second scores Factor of its score, the third Factor squared,
+// and so on, so one site cannot fill the page
+type DuplicatePenalty struct {
+	Factor float64
+}
+
+// Name implements RankStage
+func (DuplicatePenalty) Name() string { return StageDuplicatePenalty }
+
+// Apply implements RankStage
+func (d DuplicatePenalty) Apply(results []model.Result, now time.Time) {
+	if d.Factor <= 0 || d.Factor >= 1 {
+		return
+	}
+	seen := make(map[string]int)
+	for i := range results {
+		domain := strings.TrimPrefix(strings.ToLower(results[i].ExtractDomain()), "www.")
+		if domain == "" {
+			continue
+		}
+		if n := seen[domain]; n > 0 {
+			results[i].Score *= math.Pow(d.Factor, float64(n))
+		}
+		seen[domain]++
+	}
+}
+
+// SetRanker sets the ranking pipeline; nil or a ranker without stages
+// leaves results as the engines and the merge scored them. It is called
+// from request goroutines.
+func (a *Aggregator) SetRanker(ranker *Ranker) {
+	if ranker == nil || len(ranker.stages) == 0 {
+		a.ranker.Store(nil)
+		return
+	}
+	a.ranker.Store(ranker)
+}
+
+// Ranker returns the ranking pipeline, or nil when there is none
+func (a *Aggregator) Ranker() *Ranker {
+	return a.ranker.Load()
+}
+
+// rankWith reranks results with ranker when the search is sorted by
+// relevance. The cached copy is left as it was.
+func rankWith(ranker *Ranker, query *model.Query, results *model.SearchResults) *model.SearchResults {
+	if ranker == nil || results == nil || len(results.Results) == 0 ||
+		(query.SortBy != "" && query.SortBy != model.SortRelevance) {
+		return results
+	}
+	ranked := *results
+	ranked.Results = ranker.Rank(results.Results, time.Now())
+	return &ranked
+}
+
+// applyRanking reranks results with the aggregator's ranker
+func (a *Aggregator) applyRanking(query *model.Query, results *model.SearchResults) *model.SearchResults {
+	return rankWith(a.ranker.Load(), query, results)
+}
+
+// RankChange is one result in a ranking preview
+type RankChange struct {
+	URL    string  `json:"url"`
+	Title  string  `json:"title"`
+	Engine string  `json:"engine"`
+	Score  float64 `json:"score"`
+	// Rank is the result's 1-based position under the proposed ranking,
+	// Current its position under the ranking in use
+	Rank    int `json:"rank"`
+	Current int `json:"current"`
+}
+
+// PreviewRanking searches query like Search, sorted by relevance, and
+// returns its results ranked by proposed, each with its position under the
+// current ranking, so a pipeline can be tried before it is put in use. A
+// nil proposed ranks as the engines and the merge scored the results.
+func (a *Aggregator) PreviewRanking(ctx context.Context, query *model.Query, proposed *Ranker) ([]RankChange, error) {
+	query.SortBy = model.SortRelevance
+	results, err := a.search(ctx, query, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	results = a.screenResults(results)
+	current := a.applyRanking(query, results)
+	next := rankWith(proposed, query, results)
+
+	positions := make(map[string]int, len(current.Results))
+	for i, r := range current.Results {
+		positions[r.URL] = i + 1
+	}
+	changes := make([]RankChange, 0, len(next.Results))
+	for i, r := range next.Results {
+		changes = append(changes, RankChange{
+			URL:     r.URL,
+			Title:   r.Title,
+			Engine:  r.Engine,
+			Score:   r.Score,
+			Rank:    i + 1,
+			Current: positions[r.URL],
+		})
+	}
+	return changes, nil
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestRankStages(t *testing.T) {
+	now := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	results := []model.Result{
+		{URL: "https://a.example.com/1", Engine: "google", Score: 100},
+		{URL: "https://a.example.com/2", Engine: "bing", Score: 90, PublishedAt: now.Add(-7 * 24 * time.Hour)},
+		{URL: "https://en.wikipedia.org/wiki/Go", Engine: "bing", Score: 80},
+		{URL: "https://b.example.org/", Engine: "brave", Score: 70, PublishedAt: now},
+	}
+	score := func(ranker *Ranker) map[string]float64 {
+		scores := make(map[string]float64)
+		for _, r := range ranker.Rank(results, now) {
+			scores[r.URL] = r.Score
+		}
+		return scores
+	}
+
+	got := score(NewRanker(EngineWeight{"bing": 2}))
+	if got["https://a.example.com/2"] != 180 || got["https://a.example.com/1"] != 100 {
+		t.Errorf("engine_weight scores = %v", got)
+	}
+	got = score(NewRanker(DomainAuthority{"wikipedia.org": 1.5, "example.com": 0.5}))
+	if got["https://en.wikipedia.org/wiki/Go"] != 120 || got["https://a.example.com/1"] != 50 || got["https://b.example.org/"] != 70 {
+		t.Errorf("domain_authority scores = %v", got)
+	}
+	got = score(NewRanker(Freshness{Boost: 0.5, HalfLife: 7 * 24 * time.Hour}))
+	if got["https://b.example.org/"] != 105 || got["https://a.example.com/2"] != 112.5 || got["https://a.example.com/1"] != 100 {
+		t.Errorf("freshness scores = %v", got)
+	}
+	got = score(NewRanker(DuplicatePenalty{Factor: 0.5}))
+	if got["https://a.example.com/1"] != 100 || got["https://a.example.com/2"] != 45 {
+		t.Errorf("duplicate_penalty scores = %v", got)
+	}
+	if results[1].Score != 90 {
+		t.Error("Rank changed its input")
+	}
+
+	// Order matters: the penalty falls on whichever result from a domain
+	// ranks second when it runs
+	ranked := NewRanker(Freshness{Boost: 0.5, HalfLife: 24 * time.Hour}, EngineWeight{"bing": 1.2}, DuplicatePenalty{Factor: 0.5}).Rank(results, now)
+	if ranked[0].URL != "https://a.example.com/2" || ranked[1].URL != "https://b.example.org/" {
+		t.Errorf("ranked = %+v", ranked)
+	}
+	if names := NewRanker(EngineWeight{}, DuplicatePenalty{}).Stages(); len(names) != 2 || names[1] != StageDuplicatePenalty {
+		t.Errorf("Stages() = %v", names)
+	}
+}
+
+func TestAggregatorRanker(t *testing.T) {
+	engine := newMockEngine("mock", model.CategoryGeneral, true)
+	engine.SetResults([]model.Result{
+		{URL: "https://first.example.com/", Title: "First", Engine: "mock", Score: 100},
+		{URL: "https://second.example.org/", Title: "Second", Engine: "mock", Score: 90},
+	})
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{Timeout: 5 * time.Second, CacheEnabled: true})
+	agg.SetRanker(NewRanker())
+	if agg.Ranker() != nil {
+		t.Error("a ranker without stages was kept")
+	}
+
+	results, err := agg.Search(context.Background(), model.NewQuery("ranking"))
+	if err != nil || results.Results[0].URL != "https://first.example.com/" {
+		t.Fatalf("Search() = %+v, %v", results, err)
+	}
+
+	// The cached results are reranked as soon as the pipeline changes
+	agg.SetRanker(NewRanker(DomainAuthority{"example.org": 2}))
+	results, err = agg.Search(context.Background(), model.NewQuery("ranking"))
+	if err != nil || !results.FromCache || results.Results[0].URL != "https://second.example.org/" || results.Results[0].Score != 180 {
+		t.Fatalf("reranked Search() = %+v, %v", results, err)
+	}
+	query := model.NewQuery("ranking")
+	query.SortBy = model.SortDate
+	if results, _ := agg.Search(context.Background(), query); results.Results[0].Score == 180 {
+		t.Error("date-sorted search was reranked")
+	}
+
+	changes, err := agg.PreviewRanking(context.Background(), model.NewQuery("ranking"), NewRanker(DomainAuthority{"example.com": 3}))
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("PreviewRanking() = %+v, %v", changes, err)
+	}
+	if changes[0].URL != "https://first.example.com/" || changes[0].Rank != 1 || changes[0].Current != 2 || changes[0].Score != 300 {
+		t.Errorf("preview = %+v", changes)
+	}
+	// Once for relevance and once for the date sort; the preview is cached
+	if engine.Calls() != 2 {
+		t.Errorf("engine searched %d times, want 2", engine.Calls())
+	}
+}
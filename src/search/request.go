@@ -0,0 +1,145 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// RequestTemplate customizes an engine's outgoing HTTP requests, for
+// example with the consent cookie an engine requires. Values may contain
+// the placeholders {query}, {page}, {locale} and {safe_search}, resolved
+// for each search.
+type RequestTemplate struct {
+	Headers map[string]string
+	Cookies map[string]string
+	// Params are set on the request URL's query string, replacing the
+	// engine's own value for the same name
+	Params map[string]string
+}
+
+// requestOverrides is a RequestTemplate resolved for one search
+type requestOverrides struct {
+	headers map[string]string
+	cookies map[string]string
+	params  map[string]string
+}
+
+type requestOverridesKey struct{}
+
+// SetRequestTemplates replaces the per-engine request templates, keyed by
+// engine name
+func (a *Aggregator) SetRequestTemplates(templates map[string]RequestTemplate) {
+	copied := make(map[string]RequestTemplate, len(templates))
+	for name, t := range templates {
+		copied[strings.ToLower(name)] = t
+	}
+	a.requestMu.Lock()
+	a.requestTemplates = copied
+	a.requestMu.Unlock()
+}
+
+// withRequestOverrides returns ctx carrying the engine's name and its
+// request template resolved for query, if it has one
+func (a *Aggregator) withRequestOverrides(ctx context.Context, engine Engine, query *model.Query) context.Context {
+	ctx = withEngineName(ctx, engine.Name())
+	a.requestMu.RLock()
+	t, ok := a.requestTemplates[strings.ToLower(engine.Name())]
+	a.requestMu.RUnlock()
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestOverridesKey{}, t.resolve(query))
+}
+
+func (t RequestTemplate) resolve(query *model.Query) *requestOverrides {
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	locale := query.Language
+	if locale == "" {
+		locale = "en"
+	}
+	if query.Region != "" {
+		locale += "-" + strings.ToUpper(query.Region)
+	}
+	vars := strings.NewReplacer(
+		"{query}", query.Text,
+		"{page}", strconv.Itoa(page),
+		"{locale}", locale,
+		"{safe_search}", strconv.Itoa(query.SafeSearch),
+	)
+	expand := func(values map[string]string, headerSafe bool) map[string]string {
+		out := make(map[string]string, len(values))
+		for k, v := range values {
+			v = vars.Replace(v)
+			if headerSafe {
+				// The query must not be able to start a new header line
+				v = strings.Map(func(r rune) rune {
+					if r < 0x20 || r == 0x7f {
+						return ' '
+					}
+					return r
+				}, v)
+			}
+			out[k] = v
+		}
+		return out
+	}
+	return &requestOverrides{
+		headers: expand(t.Headers, true),
+		cookies: expand(t.Cookies, true),
+		params:  expand(t.Params, false),
+	}
+}
+
+// ApplyRequestOverrides applies the configured headers, cookies and URL
+// parameters of the engine whose search built req. Engines call it on every
+// request just before sending it, so configuration wins over their own
+// values. It returns req.
+func ApplyRequestOverrides(req *http.Request) *http.Request {
+	o, ok := req.Context().Value(requestOverridesKey{}).(*requestOverrides)
+	if !ok {
+		return req
+	}
+
+	for name, value := range o.headers {
+		req.Header.Set(name, value)
+	}
+
+	if len(o.cookies) > 0 {
+		existing := req.Cookies()
+		req.Header.Del("Cookie")
+		for _, c := range existing {
+			if _, replaced := o.cookies[c.Name]; !replaced {
+				req.AddCookie(c)
+			}
+		}
+		names := make([]string, 0, len(o.cookies))
+		for name := range o.cookies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			req.AddCookie(&http.Cookie{Name: name, Value: o.cookies[name]})
+		}
+	}
+
+	if len(o.params) > 0 {
+		values, err := url.ParseQuery(req.URL.RawQuery)
+		if err != nil {
+			values = url.Values{}
+		}
+		for name, value := range o.params {
+			values.Set(name, value)
+		}
+		req.URL.RawQuery = values.Encode()
+	}
+	return req
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// requestCapturingEngine builds a request the way real engines do and
+// records it after overrides are applied
+type requestCapturingEngine struct {
+	*mockEngine
+	req *http.Request
+}
+
+func (e *requestCapturingEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://engine.example/search?q=x&hl=en", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Language", "en-US")
+	req.Header.Set("Cookie", "SOCS=CAI; keep=1")
+	e.req = ApplyRequestOverrides(req)
+	return []model.Result{{URL: "https://engine.example/1", Title: "One"}}, nil
+}
+
+func TestApplyRequestOverrides(t *testing.T) {
+	engine := &requestCapturingEngine{mockEngine: newMockEngine("capture", model.CategoryGeneral, true)}
+	agg := NewAggregatorSimple([]Engine{engine}, 10*time.Second)
+	agg.SetRequestTemplates(map[string]RequestTemplate{
+		"Capture": {
+			Headers: map[string]string{"Accept-Language": "{locale}", "X-Query": "{query}"},
+			Cookies: map[string]string{"SOCS": "CAE", "safe": "{safe_search}"},
+			Params:  map[string]string{"hl": "{locale}", "p": "{page}"},
+		},
+	})
+
+	query := &model.Query{Text: "line\r\nX-Injected: 1", Category: model.CategoryGeneral, Language: "de", Region: "at", Page: 2, SafeSearch: 1}
+	if _, err := agg.Search(context.Background(), query); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	req := engine.req
+	if got := req.Header.Get("Accept-Language"); got != "de-AT" {
+		t.Errorf("Accept-Language = %q, want de-AT", got)
+	}
+	if got := req.Header.Get("X-Query"); got != "line  X-Injected: 1" {
+		t.Errorf("X-Query = %q, want control characters replaced", got)
+	}
+	cookies := map[string]string{}
+	for _, c := range req.Cookies() {
+		cookies[c.Name] = c.Value
+	}
+	if len(cookies) != 3 || cookies["SOCS"] != "CAE" || cookies["keep"] != "1" || cookies["safe"] != "1" {
+		t.Errorf("cookies = %v, want SOCS replaced, keep kept and safe added", cookies)
+	}
+	if got := req.URL.Query(); got.Get("hl") != "de-AT" || got.Get("p") != "2" || got.Get("q") != "x" {
+		t.Errorf("query string = %q", req.URL.RawQuery)
+	}
+}
+
+func TestApplyRequestOverridesWithoutTemplate(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://engine.example/search?q=x", nil)
+	req.Header.Set("Cookie", "a=1")
+	if got := ApplyRequestOverrides(req); got != req || req.URL.RawQuery != "q=x" || req.Header.Get("Cookie") != "a=1" {
+		t.Errorf("request changed without a template: %s %v", req.URL, req.Header)
+	}
+}
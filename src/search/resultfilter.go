@@ -0,0 +1,154 @@
+package search
+
+import (
+	"context"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// ResultFilter reports whether a result must not be shown, for example
+// because the operator blocked its domain
+type ResultFilter func(result model.Result) bool
+
+// ResultMarker returns the threat to warn about for a result, such as
+// "malware", or "" for none
+type ResultMarker func(result model.Result) string
+
+// SetResultFilter sets the filter applied to every search's results. It runs
+// on the way out, cached results included, so a new rule applies at once and
+// a removed one brings its results back. It is called from request
+// goroutines.
+func (a *Aggregator) SetResultFilter(filter ResultFilter) {
+	if filter == nil {
+		a.resultFilter.Store(nil)
+		return
+	}
+	a.resultFilter.Store(&filter)
+}
+
+// SetResultMarker sets the marker that fills in Result.Threat. Like the
+// result filter it runs on the way out, so cached results are marked with
+// the current feeds.
+func (a *Aggregator) SetResultMarker(marker ResultMarker) {
+	if marker == nil {
+		a.resultMarker.Store(nil)
+		return
+	}
+	a.resultMarker.Store(&marker)
+}
+
+// screenResults returns results without the entries the result filter
+// rejects and with the rest marked. The cached copy is left as it was.
+func (a *Aggregator) screenResults(results *model.SearchResults) *model.SearchResults {
+	filter := a.resultFilter.Load()
+	marker := a.resultMarker.Load()
+	if (filter == nil && marker == nil) || results == nil {
+		return results
+	}
+	kept := make([]model.Result, 0, len(results.Results))
+	changed := false
+	for _, r := range results.Results {
+		if filter != nil && (*filter)(r) {
+			changed = true
+			continue
+		}
+		if marker != nil {
+			if threat := (*marker)(r); threat != r.Threat {
+				r.Threat = threat
+				changed = true
+			}
+		}
+		kept = append(kept, r)
+	}
+	if !changed {
+		return results
+	}
+	screened := *results
+	screened.Results = kept
+	screened.TotalResults = len(kept)
+	screened.CalculateTotalPages()
+	return &screened
+}
+
+// SafeSearchFilter reports whether a result must not be shown at a safe
+// search level
+type SafeSearchFilter func(result model.Result, level int) bool
+
+// SetSafeSearchFilter sets the filter applied to the results of engines
+// that have no safe search parameter of their own. Unlike the result
+// filter it runs as results arrive, before they are cached: the cache key
+// includes the level.
+func (a *Aggregator) SetSafeSearchFilter(filter SafeSearchFilter) {
+	if filter == nil {
+		a.safeSearchFilter.Store(nil)
+		return
+	}
+	a.safeSearchFilter.Store(&filter)
+}
+
+// filterSafeSearch drops the results of an engine without native safe
+// search that the safe search filter rejects at the query's level
+func (a *Aggregator) filterSafeSearch(engine Engine, query *model.Query, results []model.Result) []model.Result {
+	if engine.GetConfig().SupportsSafeSearch {
+		return results
+	}
+	return a.dropUnsafe(results, query.SafeSearch)
+}
+
+// dropUnsafe drops the results the safe search filter rejects at level
+func (a *Aggregator) dropUnsafe(results []model.Result, level int) []model.Result {
+	filter := a.safeSearchFilter.Load()
+	if filter == nil || level <= 0 {
+		return results
+	}
+	kept := make([]model.Result, 0, len(results))
+	for _, r := range results {
+		if !(*filter)(r, level) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// ImageClassifier reports which image results are adult content, one flag
+// per result
+type ImageClassifier func(ctx context.Context, results []model.Result) []bool
+
+// SetImageClassifier sets the adult-content check run on image searches
+// with strict safe search. Flagged results stay in the list, marked and
+// without a thumbnail, so a wrong verdict can be reported.
+func (a *Aggregator) SetImageClassifier(classifier ImageClassifier) {
+	if classifier == nil {
+		a.imageClassifier.Store(nil)
+		return
+	}
+	a.imageClassifier.Store(&classifier)
+}
+
+// classifyImages marks the adult results of a strict image search. The
+// cached copy is left as it was.
+func (a *Aggregator) classifyImages(ctx context.Context, query *model.Query, results *model.SearchResults) *model.SearchResults {
+	classifier := a.imageClassifier.Load()
+	if classifier == nil || results == nil || len(results.Results) == 0 ||
+		query.Category != model.CategoryImages || query.SafeSearch != 2 {
+		return results
+	}
+	flags := (*classifier)(ctx, results.Results)
+	var marked []model.Result
+	for i, adult := range flags {
+		if !adult || i >= len(results.Results) {
+			continue
+		}
+		if marked == nil {
+			marked = append([]model.Result(nil), results.Results...)
+		}
+		marked[i].ContentFilter = "adult"
+		marked[i].Thumbnail = ""
+	}
+	if marked == nil {
+		return results
+	}
+	classified := *results
+	classified.Results = marked
+	return &classified
+}
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestAggregatorResultFilter(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults(previewResults(4))
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 1,
+	})
+	query := &model.Query{Text: "golang", Category: model.CategoryGeneral}
+	if _, err := agg.Search(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new filter applies to cached results at once
+	agg.SetResultFilter(func(r model.Result) bool { return r.URL == "https://example.com/b" })
+	results, err := agg.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Results) != 3 || results.TotalResults != 3 {
+		t.Fatalf("filtered search = %d results (total %d), want 3", len(results.Results), results.TotalResults)
+	}
+	for _, r := range results.Results {
+		if r.URL == "https://example.com/b" {
+			t.Error("the filtered result is still shown")
+		}
+	}
+	if preview, _ := agg.Preview(context.Background(), query, 10, true); len(preview.Results) != 3 {
+		t.Errorf("filtered preview = %d results, want 3", len(preview.Results))
+	}
+
+	// Removing it brings the result back: the cache kept it
+	agg.SetResultFilter(nil)
+	if results, _ := agg.Search(context.Background(), query); len(results.Results) != 4 {
+		t.Errorf("unfiltered search = %d results, want 4", len(results.Results))
+	}
+}
+
+func TestAggregatorResultMarker(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults(previewResults(2))
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 1,
+	})
+	query := &model.Query{Text: "golang", Category: model.CategoryGeneral}
+	if _, err := agg.Search(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+
+	agg.SetResultMarker(func(r model.Result) string {
+		if r.URL == "https://example.com/a" {
+			return "phishing"
+		}
+		return ""
+	})
+	results, err := agg.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Results) != 2 {
+		t.Fatalf("marked search = %d results, want 2", len(results.Results))
+	}
+	for _, r := range results.Results {
+		if want := map[bool]string{true: "phishing"}[r.URL == "https://example.com/a"]; r.Threat != want {
+			t.Errorf("%s threat = %q, want %q", r.URL, r.Threat, want)
+		}
+	}
+
+	// The cached copy is not marked
+	agg.SetResultMarker(nil)
+	results, _ = agg.Search(context.Background(), query)
+	for _, r := range results.Results {
+		if r.Threat != "" {
+			t.Errorf("%s is still marked %q: the mark was stored in the cache", r.URL, r.Threat)
+		}
+	}
+}
+
+func TestAggregatorImageClassifier(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryImages, true)
+	images := previewResults(2)
+	for i := range images {
+		images[i].Thumbnail = images[i].URL + ".jpg"
+	}
+	engine.SetResults(images)
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 1,
+	})
+	var asked int
+	agg.SetImageClassifier(func(ctx context.Context, results []model.Result) []bool {
+		asked++
+		flags := make([]bool, len(results))
+		for i, r := range results {
+			flags[i] = r.URL == "https://example.com/a"
+		}
+		return flags
+	})
+
+	moderate := &model.Query{Text: "cats", Category: model.CategoryImages, SafeSearch: 1}
+	if _, err := agg.Search(context.Background(), moderate); err != nil {
+		t.Fatal(err)
+	}
+	if asked != 0 {
+		t.Error("the classifier ran without strict safe search")
+	}
+
+	strict := &model.Query{Text: "cats", Category: model.CategoryImages, SafeSearch: 2}
+	results, err := agg.Search(context.Background(), strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asked != 1 || len(results.Results) != 2 {
+		t.Fatalf("asked %d times for %d results", asked, len(results.Results))
+	}
+	for _, r := range results.Results {
+		adult := r.URL == "https://example.com/a"
+		if adult != (r.ContentFilter == "adult") || adult != (r.Thumbnail == "") {
+			t.Errorf("%s: content filter %q, thumbnail %q", r.URL, r.ContentFilter, r.Thumbnail)
+		}
+	}
+
+	// The cached copy keeps its thumbnails
+	agg.SetImageClassifier(nil)
+	results, _ = agg.Search(context.Background(), strict)
+	for _, r := range results.Results {
+		if r.Thumbnail == "" || r.ContentFilter != "" {
+			t.Errorf("%s lost its thumbnail in the cache", r.URL)
+		}
+	}
+}
+
+func TestAggregatorSafeSearchFilter(t *testing.T) {
+	native := newMockEngine("native", model.CategoryGeneral, true)
+	native.GetConfig().SupportsSafeSearch = true
+	native.SetResults([]model.Result{{URL: "https://native.example/adult", Title: "Native"}})
+	plain := newMockEngine("plain", model.CategoryGeneral, true)
+	plain.SetResults([]model.Result{
+		{URL: "https://plain.example/adult", Title: "Plain adult"},
+		{URL: "https://plain.example/ok", Title: "Plain"},
+	})
+	agg := NewAggregator([]Engine{native, plain}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 2,
+	})
+	var levels []int
+	agg.SetSafeSearchFilter(func(r model.Result, level int) bool {
+		levels = append(levels, level)
+		return strings.HasSuffix(r.URL, "/adult")
+	})
+
+	urls := func(results *model.SearchResults) map[string]bool {
+		got := make(map[string]bool)
+		for _, r := range results.Results {
+			got[r.URL] = true
+		}
+		return got
+	}
+
+	results, err := agg.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, SafeSearch: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := urls(results)
+	if len(got) != 2 || !got["https://native.example/adult"] || !got["https://plain.example/ok"] {
+		t.Errorf("strict search = %v, want the native engine's result and the plain one that passed", got)
+	}
+	if len(levels) != 2 || levels[0] != 2 {
+		t.Errorf("filter called with levels %v, want the plain engine's two results at 2", levels)
+	}
+
+	// Off is cached apart from strict and not filtered
+	results, err = agg.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, SafeSearch: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := urls(results); len(got) != 3 {
+		t.Errorf("safe search off = %v, want all three results", got)
+	}
+}
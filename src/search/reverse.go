@@ -0,0 +1,208 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// ErrNoReverseImage is returned for a reverse image search with neither an
+// image URL nor image data
+var ErrNoReverseImage = errors.New("reverse image search needs an image URL or upload")
+
+// Errors for an image that cannot be searched by
+var (
+	ErrReverseImageURL      = errors.New("image URL must be an absolute http or https URL")
+	ErrReverseImageTooLarge = errors.New("uploaded image is too large")
+	ErrReverseImageType     = errors.New("uploaded file is not an image")
+)
+
+// ReverseImage is the image a reverse image search looks for: a public URL,
+// or the bytes of an uploaded image
+type ReverseImage struct {
+	URL         string
+	Data        []byte
+	ContentType string
+}
+
+// ReadReverseImage reads the image of a search by image request: the url
+// parameter, or an upload in the multipart field "image" of at most
+// maxBytes. The URL is passed on to the engines, never fetched here.
+func ReadReverseImage(r *http.Request, maxBytes int64) (*ReverseImage, error) {
+	if r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// Room for the other form fields and the multipart framing
+		r.Body = http.MaxBytesReader(nil, r.Body, maxBytes+64*1024)
+		file, _, err := r.FormFile("image")
+		if err == nil {
+			defer file.Close()
+			data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+			if err != nil {
+				return nil, err
+			}
+			if int64(len(data)) > maxBytes {
+				return nil, ErrReverseImageTooLarge
+			}
+			if len(data) > 0 {
+				contentType := http.DetectContentType(data)
+				if !strings.HasPrefix(contentType, "image/") {
+					return nil, ErrReverseImageType
+				}
+				return &ReverseImage{Data: data, ContentType: contentType}, nil
+			}
+		} else if errors.As(err, new(*http.MaxBytesError)) {
+			return nil, ErrReverseImageTooLarge
+		}
+	}
+
+	raw := strings.TrimSpace(r.FormValue("url"))
+	if raw == "" {
+		return nil, ErrNoReverseImage
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrReverseImageURL
+	}
+	return &ReverseImage{URL: u.String()}, nil
+}
+
+// ReverseImageEngine is an engine that can find pages showing an image
+type ReverseImageEngine interface {
+	Engine
+
+	// ReverseImageSearch returns pages and images similar to img
+	ReverseImageSearch(ctx context.Context, img *ReverseImage) ([]model.Result, error)
+}
+
+// ReverseImageEngines returns the names of the enabled engines that can
+// search by image
+func (a *Aggregator) ReverseImageEngines() []string {
+	var names []string
+	for _, engine := range a.engines {
+		if _, ok := engine.(ReverseImageEngine); ok && engine.IsEnabled() {
+			names = append(names, engine.Name())
+		}
+	}
+	return names
+}
+
+// ReverseImageSearch forwards img to the reverse image engines named in
+// engines, or to all of them when engines is empty, and merges their
+// results. No engine is told the safe search level, so results the safe
+// search filter rejects at safeSearch are dropped. Results are not cached:
+// an uploaded image has no stable key.
+func (a *Aggregator) ReverseImageSearch(ctx context.Context, img *ReverseImage, engines []string, safeSearch int) (*model.SearchResults, error) {
+	if img == nil || (img.URL == "" && len(img.Data) == 0) {
+		return nil, ErrNoReverseImage
+	}
+
+	now := time.Now()
+	var active []ReverseImageEngine
+	for _, engine := range a.engines {
+		reverse, ok := engine.(ReverseImageEngine)
+		if !ok || !engine.IsEnabled() || !a.canSearch(engine, now) {
+			continue
+		}
+		if len(engines) > 0 && !containsFold(engines, engine.Name()) {
+			continue
+		}
+		active = append(active, reverse)
+	}
+	if len(active) == 0 {
+		return nil, model.ErrNoEngines
+	}
+
+	startTime := time.Now()
+	searchCtx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	type engineResult struct {
+		engine  ReverseImageEngine
+		results []model.Result
+		err     error
+		latency time.Duration
+		timing  model.EngineTiming
+	}
+	resultsChan := make(chan engineResult, len(active))
+	var wg sync.WaitGroup
+	for _, engine := range active {
+		wg.Add(1)
+		go func(eng ReverseImageEngine) {
+			defer wg.Done()
+			trace := &engineTrace{}
+			start := time.Now()
+			results, err := a.runSandboxed(trace.withTrace(searchCtx), eng, func(ctx context.Context) ([]model.Result, error) {
+				return eng.ReverseImageSearch(ctx, img)
+			})
+			finished := time.Now()
+			resultsChan <- engineResult{
+				engine:  eng,
+				results: results,
+				err:     err,
+				latency: finished.Sub(start),
+				timing:  trace.timing(eng.Name(), startTime, start, finished, len(results), err),
+			}
+		}(engine)
+	}
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	searchResults := model.NewSearchResults(img.URL, model.CategoryImages)
+	searchResults.Page = 1
+	timings := make([]model.EngineTiming, 0, len(active))
+	usedEngines := make([]string, 0)
+	successCount := 0
+	for result := range resultsChan {
+		timings = append(timings, result.timing)
+		if result.err != nil {
+			a.recordEngineFailure(result.engine, result.err)
+			continue
+		}
+		successCount++
+		a.recordEngineSuccess(result.engine, result.latency)
+		result.results = a.dropUnsafe(result.results, safeSearch)
+		if len(result.results) > 0 {
+			searchResults.AddResults(result.results)
+			usedEngines = append(usedEngines, result.engine.DisplayName())
+		}
+	}
+
+	searchResults.Engines = usedEngines
+	searchResults.Results = deduplicateResults(searchResults.Results)
+	sortResults(searchResults.Results, model.SortRelevance)
+	searchResults.TotalResults = len(searchResults.Results)
+	searchResults.PerPage = max(searchResults.TotalResults, 1)
+	searchResults.CalculateTotalPages()
+	searchResults.SearchTime = time.Since(startTime).Seconds()
+
+	countContributions(timings, searchResults.Results)
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Engine < timings[j].Engine })
+	searchResults.EngineTimings = timings
+
+	if len(searchResults.Results) == 0 {
+		if successCount == 0 {
+			searchResults.Degraded = true
+			return a.screenResults(searchResults), nil
+		}
+		return searchResults, model.ErrNoResults
+	}
+	return a.screenResults(searchResults), nil
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+type mockReverseEngine struct {
+	*mockEngine
+	got *ReverseImage
+}
+
+func (m *mockReverseEngine) ReverseImageSearch(ctx context.Context, img *ReverseImage) ([]model.Result, error) {
+	m.got = img
+	return m.searchResults, m.searchError
+}
+
+func newMockReverseEngine(name string, results ...model.Result) *mockReverseEngine {
+	e := &mockReverseEngine{mockEngine: newMockEngine(name, model.CategoryImages, true)}
+	e.SetResults(results)
+	return e
+}
+
+func TestReverseImageSearch(t *testing.T) {
+	yandex := newMockReverseEngine("yandex",
+		model.Result{Title: "Cat", URL: "https://a.example/cat", Engine: "yandex", Score: 2},
+		model.Result{Title: "Cat again", URL: "https://b.example/cat", Engine: "yandex", Score: 1})
+	bing := newMockReverseEngine("bing", model.Result{Title: "Cat", URL: "https://a.example/cat", Engine: "bing", Score: 1})
+	broken := newMockReverseEngine("broken")
+	broken.SetError(errors.New("upstream down"))
+	plain := newMockEngine("plain", model.CategoryImages, true)
+	agg := NewAggregatorSimple([]Engine{yandex, bing, broken, plain}, 5*time.Second)
+
+	if names := agg.ReverseImageEngines(); len(names) != 3 {
+		t.Errorf("ReverseImageEngines() = %v", names)
+	}
+
+	img := &ReverseImage{URL: "https://example.com/cat.jpg"}
+	results, err := agg.ReverseImageSearch(context.Background(), img, nil, 0)
+	if err != nil {
+		t.Fatalf("ReverseImageSearch: %v", err)
+	}
+	if len(results.Results) != 2 || results.Results[0].URL != "https://a.example/cat" || results.Category != model.CategoryImages {
+		t.Errorf("results = %+v", results.Results)
+	}
+	if len(results.Engines) != 2 || len(results.EngineTimings) != 3 || results.TotalPages != 1 {
+		t.Errorf("engines = %v, timings = %d, pages = %d", results.Engines, len(results.EngineTimings), results.TotalPages)
+	}
+	if plain.Calls() != 0 || yandex.got != img {
+		t.Error("image not sent to the reverse engines only")
+	}
+
+	bing.got = nil
+	if _, err := agg.ReverseImageSearch(context.Background(), img, []string{"Yandex"}, 0); err != nil || bing.got != nil {
+		t.Errorf("engine list not honored: err %v, bing called %v", err, bing.got != nil)
+	}
+	if _, err := agg.ReverseImageSearch(context.Background(), img, []string{"google"}, 0); !errors.Is(err, model.ErrNoEngines) {
+		t.Errorf("unknown engine: err = %v", err)
+	}
+	if _, err := agg.ReverseImageSearch(context.Background(), &ReverseImage{}, nil, 0); !errors.Is(err, ErrNoReverseImage) {
+		t.Errorf("empty image: err = %v", err)
+	}
+
+	degraded, err := agg.ReverseImageSearch(context.Background(), img, []string{"broken"}, 0)
+	if err != nil || !degraded.Degraded {
+		t.Errorf("all engines failing: degraded = %v, err = %v", degraded.Degraded, err)
+	}
+
+	// No engine is told the level, so the safe search filter applies
+	agg.SetSafeSearchFilter(func(r model.Result, level int) bool { return r.URL == "https://b.example/cat" })
+	strict, err := agg.ReverseImageSearch(context.Background(), img, []string{"yandex"}, 2)
+	if err != nil || len(strict.Results) != 1 || strict.Results[0].URL != "https://a.example/cat" {
+		t.Errorf("strict search = %+v, %v, want the blocked result dropped", strict, err)
+	}
+}
+
+func TestImageFiltersSelectEngines(t *testing.T) {
+	filtering := newMockEngine("filtering", model.CategoryImages, true)
+	filtering.GetConfig().SupportsImageFilters = true
+	plain := newMockEngine("plain", model.CategoryImages, true)
+	agg := NewAggregatorSimple([]Engine{filtering, plain}, 5*time.Second)
+
+	query := &model.Query{Text: "sunset", Category: model.CategoryImages}
+	if got := agg.filterEngines(query); len(got) != 2 {
+		t.Errorf("unfiltered: filterEngines() = %v", engineNames(got))
+	}
+	unfilteredKey := agg.generateCacheKey(query)
+
+	query.ImageColor = "orange"
+	if got := agg.filterEngines(query); len(got) != 1 || got[0].Name() != "filtering" {
+		t.Errorf("color filter: filterEngines() = %v, want [filtering]", engineNames(got))
+	}
+	if agg.generateCacheKey(query) == unfilteredKey {
+		t.Error("color filter shares the unfiltered cache key")
+	}
+}
+
+func TestReadReverseImage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	upload := func(data []byte) *http.Request {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("image", "cat.png")
+		part.Write(data)
+		w.Close()
+		r := httptest.NewRequest(http.MethodPost, "/search/image", &body)
+		r.Header.Set("Content-Type", w.FormDataContentType())
+		return r
+	}
+
+	img, err := ReadReverseImage(httptest.NewRequest(http.MethodGet, "/search/image?url=https://example.com/cat.jpg", nil), 1024)
+	if err != nil || img.URL != "https://example.com/cat.jpg" {
+		t.Errorf("url: img = %+v, err = %v", img, err)
+	}
+	img, err = ReadReverseImage(upload(png), 1024)
+	if err != nil || img.ContentType != "image/png" || len(img.Data) != len(png) {
+		t.Errorf("upload: img = %+v, err = %v", img, err)
+	}
+
+	for name, tc := range map[string]struct {
+		r    *http.Request
+		want error
+	}{
+		"no image":   {httptest.NewRequest(http.MethodGet, "/search/image", nil), ErrNoReverseImage},
+		"file URL":   {httptest.NewRequest(http.MethodGet, "/search/image?url=file:///etc/passwd", nil), ErrReverseImageURL},
+		"not image":  {upload([]byte("<html>hello</html>")), ErrReverseImageType},
+		"too large":  {upload(append(png, make([]byte, 2048)...)), ErrReverseImageTooLarge},
+		"empty file": {upload(nil), ErrNoReverseImage},
+	} {
+		if _, err := ReadReverseImage(tc.r, 1024); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+	"golang.org/x/net/html"
+)
+
+// EngineLimits bounds what one engine response may cost to parse
+type EngineLimits struct {
+	// MaxResponseBytes is the largest response body an engine may read
+	MaxResponseBytes int64
+	// MaxHTMLDepth is the deepest element nesting accepted in an HTML
+	// response
+	MaxHTMLDepth int
+}
+
+// DefaultEngineLimits apply to engines without limits of their own
+var DefaultEngineLimits = EngineLimits{
+	// 4 MB, the engines' own read limit
+	MaxResponseBytes: 4 * 1024 * 1024,
+	MaxHTMLDepth:     256,
+}
+
+// ErrResponseLimit is matched by every *ResponseLimitError
+var ErrResponseLimit = errors.New("engine response exceeds a limit")
+
+// ResponseLimitError reports an engine response refused before parsing
+type ResponseLimitError struct {
+	// Limit is "size" or "html_depth"
+	Limit string
+	Max   int64
+}
+
+func (e *ResponseLimitError) Error() string {
+	if e.Limit == "size" {
+		return fmt.Sprintf("engine response is larger than %d bytes", e.Max)
+	}
+	return fmt.Sprintf("engine response nests HTML deeper than %d elements", e.Max)
+}
+
+// Unwrap lets callers match ErrResponseLimit
+func (e *ResponseLimitError) Unwrap() error {
+	return ErrResponseLimit
+}
+
+// EnginePanicError reports an engine that panicked, usually while parsing
+// a response it did not expect
+type EnginePanicError struct {
+	Engine string
+	Value  any
+}
+
+func (e *EnginePanicError) Error() string {
+	return fmt.Sprintf("engine %s panicked: %v", e.Engine, e.Value)
+}
+
+type engineLimitsKey struct{}
+
+// SetEngineLimits replaces the response limits: defaults for every engine,
+// and perEngine, keyed by engine name, for engines that need others. Zero
+// fields fall back to the defaults, and zero defaults to
+// DefaultEngineLimits.
+func (a *Aggregator) SetEngineLimits(defaults EngineLimits, perEngine map[string]EngineLimits) {
+	defaults = defaults.orDefaults(DefaultEngineLimits)
+	limits := make(map[string]EngineLimits, len(perEngine))
+	for name, l := range perEngine {
+		limits[strings.ToLower(name)] = l.orDefaults(defaults)
+	}
+	a.limitsMu.Lock()
+	a.defaultLimits = defaults
+	a.engineLimits = limits
+	a.limitsMu.Unlock()
+}
+
+func (l EngineLimits) orDefaults(defaults EngineLimits) EngineLimits {
+	if l.MaxResponseBytes <= 0 {
+		l.MaxResponseBytes = defaults.MaxResponseBytes
+	}
+	if l.MaxHTMLDepth <= 0 {
+		l.MaxHTMLDepth = defaults.MaxHTMLDepth
+	}
+	return l
+}
+
+// withEngineLimits returns ctx carrying the engine's response limits
+func (a *Aggregator) withEngineLimits(ctx context.Context, engine Engine) context.Context {
+	a.limitsMu.RLock()
+	limits, ok := a.engineLimits[strings.ToLower(engine.Name())]
+	if !ok {
+		limits = a.defaultLimits
+	}
+	a.limitsMu.RUnlock()
+	return context.WithValue(ctx, engineLimitsKey{}, limits.orDefaults(DefaultEngineLimits))
+}
+
+// runEngine runs one engine search in its own goroutine. A panic becomes an
+// *EnginePanicError, and an engine still busy when ctx ends is abandoned,
+// so one malformed response can neither crash the server nor hold up the
+// search. Go cannot stop the abandoned goroutine; it exits when the engine
+// returns.
+func (a *Aggregator) runEngine(ctx context.Context, engine Engine, query *model.Query) ([]model.Result, error) {
+	return a.runSandboxed(ctx, engine, func(ctx context.Context) ([]model.Result, error) {
+		return engine.Search(ctx, query)
+	})
+}
+
+// runSandboxed runs an engine call with the engine's limits, recovering a
+// panic and abandoning a call that outlives ctx. The call counts against
+// the engine's daily quota and is refused once it is spent.
+func (a *Aggregator) runSandboxed(ctx context.Context, engine Engine, call func(ctx context.Context) ([]model.Result, error)) ([]model.Result, error) {
+	if !a.quota.take(engine.Name(), time.Now()) {
+		return nil, &QuotaExhaustedError{Engine: engine.Name()}
+	}
+	type outcome struct {
+		results []model.Result
+		err     error
+	}
+	done := make(chan outcome, 1)
+	engineCtx := a.withHostGate(a.withShapeRecorder(a.withEngineLimits(ctx, engine), engine))
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("engine panicked", "engine", engine.Name(), "panic", p, "stack", string(debug.Stack()))
+				done <- outcome{err: &EnginePanicError{Engine: engine.Name(), Value: p}}
+			}
+		}()
+		results, err := a.withChaos(engineCtx, engine, call)
+		done <- outcome{results: results, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.results, o.err
+	case <-ctx.Done():
+		select {
+		case o := <-done:
+			return o.results, o.err
+		default:
+		}
+		slog.Warn("engine ignored the search deadline and was abandoned", "engine", engine.Name())
+		return nil, ctx.Err()
+	}
+}
+
+// LimitResponse enforces the limits of the engine whose search sent resp's
+// request: a body over the size limit, or HTML nested deeper than the depth
+// limit, fails with a *ResponseLimitError before the engine parses it. The
+// body is buffered and put back, so the engine reads it as usual.
+func LimitResponse(resp *http.Response) error {
+	limits := DefaultEngineLimits
+	if resp.Request != nil {
+		if l, ok := resp.Request.Context().Value(engineLimitsKey{}).(EngineLimits); ok {
+			limits = l
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limits.MaxResponseBytes+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body), resp.Body}
+	if err != nil {
+		// Leave read errors to the engine
+		return nil
+	}
+	if int64(len(body)) > limits.MaxResponseBytes {
+		return &ResponseLimitError{Limit: "size", Max: limits.MaxResponseBytes}
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") && htmlDepthExceeds(body, limits.MaxHTMLDepth) {
+		return &ResponseLimitError{Limit: "html_depth", Max: int64(limits.MaxHTMLDepth)}
+	}
+	return nil
+}
+
+// htmlVoidElements never have content, so they never nest
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlSiblingElements are closed by the next element of the same name when
+// their end tag is left out
+var htmlSiblingElements = map[string]bool{
+	"p": true, "li": true, "dt": true, "dd": true, "option": true, "tr": true, "td": true, "th": true,
+}
+
+// htmlDepthExceeds reports whether body opens more than maxDepth elements
+// without closing them. It is a tokenizer pass, not a full parse: other
+// implicitly closed elements count until their parent closes, so the check
+// errs towards a deeper count, and maxDepth is set well above real pages.
+func htmlDepthExceeds(body []byte, maxDepth int) bool {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	var open []string
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if htmlVoidElements[string(name)] {
+				continue
+			}
+			if htmlSiblingElements[string(name)] && len(open) > 0 && open[len(open)-1] == string(name) {
+				open = open[:len(open)-1]
+			}
+			open = append(open, string(name))
+			if len(open) > maxDepth {
+				return true
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == string(name) {
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+}
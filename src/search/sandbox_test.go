@@ -0,0 +1,130 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// sandboxEngine runs fn in place of a real search
+type sandboxEngine struct {
+	*mockEngine
+	fn func(ctx context.Context) ([]model.Result, error)
+}
+
+func (e *sandboxEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	return e.fn(ctx)
+}
+
+func TestAggregatorIsolatesEngines(t *testing.T) {
+	good := newMockEngine("good", model.CategoryGeneral, true)
+	good.SetResults([]model.Result{{Title: "Go", URL: "https://go.dev/", Engine: "good"}})
+	panicking := &sandboxEngine{
+		mockEngine: newMockEngine("panicking", model.CategoryGeneral, true),
+		fn: func(context.Context) ([]model.Result, error) {
+			var results []model.Result
+			return results[:1], nil
+		},
+	}
+	release := make(chan struct{})
+	defer close(release)
+	stalled := &sandboxEngine{
+		mockEngine: newMockEngine("stalled", model.CategoryGeneral, true),
+		fn: func(context.Context) ([]model.Result, error) {
+			// Ignores cancellation, like a parser stuck on a huge response
+			<-release
+			return nil, nil
+		},
+	}
+
+	agg := NewAggregator([]Engine{good, panicking, stalled}, AggregatorConfig{Timeout: 200 * time.Millisecond})
+	query := model.NewQuery("golang")
+	query.Category = model.CategoryGeneral
+
+	start := time.Now()
+	results, err := agg.Search(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Search() took %v, want it to stop waiting at the timeout", elapsed)
+	}
+	if len(results.Results) != 1 || results.Results[0].URL != "https://go.dev/" {
+		t.Errorf("results = %+v, want only the good engine's result", results.Results)
+	}
+
+	_, err = agg.runEngine(context.Background(), panicking, query)
+	var panicked *EnginePanicError
+	if !errors.As(err, &panicked) || panicked.Engine != "panicking" {
+		t.Errorf("runEngine() error = %v, want an *EnginePanicError", err)
+	}
+}
+
+func limitTestResponse(t *testing.T, limits EngineLimits, contentType, body string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.WithValue(context.Background(), engineLimitsKey{}, limits), "GET", "https://engine.example/search", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+	return resp, LimitResponse(resp)
+}
+
+func TestLimitResponse(t *testing.T) {
+	limits := EngineLimits{MaxResponseBytes: 64, MaxHTMLDepth: 4}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"small html", "text/html", "<html><body><ul><li>a<li>b<li>c</ul><p>x<p>y<br></body></html>", ""},
+		{"too large", "application/json", `{"results": "` + strings.Repeat("x", 64) + `"}`, "size"},
+		{"too deep", "text/html; charset=utf-8", strings.Repeat("<div>", 5), "html_depth"},
+		{"deep json ignored", "application/json", strings.Repeat("<div>", 5), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := limitTestResponse(t, limits, tt.contentType, tt.body)
+			var limited *ResponseLimitError
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("LimitResponse() = %v, want nil", err)
+				}
+				// The engine still reads the whole body
+				if body, _ := io.ReadAll(resp.Body); string(body) != tt.body {
+					t.Errorf("body after LimitResponse() = %q", body)
+				}
+			} else if !errors.As(err, &limited) || limited.Limit != tt.want || !errors.Is(err, ErrResponseLimit) {
+				t.Fatalf("LimitResponse() = %v, want the %s limit", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetEngineLimits(t *testing.T) {
+	agg := NewAggregatorSimple(nil, time.Second)
+	agg.SetEngineLimits(EngineLimits{MaxHTMLDepth: 100}, map[string]EngineLimits{"Google": {MaxResponseBytes: 1024}})
+
+	limitsOf := func(name string) EngineLimits {
+		ctx := agg.withEngineLimits(context.Background(), newMockEngine(name, model.CategoryGeneral, true))
+		return ctx.Value(engineLimitsKey{}).(EngineLimits)
+	}
+	if got := limitsOf("google"); got.MaxResponseBytes != 1024 || got.MaxHTMLDepth != 100 {
+		t.Errorf("google limits = %+v, want its own size and the default depth", got)
+	}
+	if got := limitsOf("bing"); got.MaxResponseBytes != DefaultEngineLimits.MaxResponseBytes || got.MaxHTMLDepth != 100 {
+		t.Errorf("bing limits = %+v, want the defaults", got)
+	}
+}
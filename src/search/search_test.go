@@ -361,6 +361,36 @@ func TestAggregatorFilterEnginesExclusion(t *testing.T) {
 	}
 }
 
+func TestAggregatorFilterEnginesPreferred(t *testing.T) {
+	engine1 := newMockEngine("engine1", model.CategoryGeneral, true)
+	engine2 := newMockEngine("engine2", model.CategoryGeneral, true)
+	images := newMockEngine("images", model.CategoryImages, true)
+
+	agg := NewAggregatorSimple([]Engine{engine1, engine2, images}, 10*time.Second)
+
+	query := &model.Query{Text: "test", Category: model.CategoryGeneral, EnabledEngines: []string{"Engine2", "images"}}
+	engines := agg.filterEngines(query)
+	if len(engines) != 1 || engines[0].Name() != "engine2" {
+		t.Errorf("filterEngines() = %v, want only engine2", engineNames(engines))
+	}
+
+	// None of the preferred engines serve the category, so every engine
+	// that does is used
+	query = &model.Query{Text: "test", Category: model.CategoryGeneral, EnabledEngines: []string{"images"}}
+	if engines := agg.filterEngines(query); len(engines) != 2 {
+		t.Errorf("filterEngines() = %v, want engine1 and engine2", engineNames(engines))
+	}
+
+	unlimited := agg.generateCacheKey(&model.Query{Text: "test", Category: model.CategoryGeneral})
+	if agg.generateCacheKey(query) == unlimited {
+		t.Error("cache key ignores the preferred engines")
+	}
+	reordered := &model.Query{Text: "test", Category: model.CategoryGeneral, EnabledEngines: []string{"engine2", "Images"}}
+	if agg.generateCacheKey(reordered) != agg.generateCacheKey(&model.Query{Text: "test", Category: model.CategoryGeneral, EnabledEngines: []string{"images", "engine2"}}) {
+		t.Error("cache key depends on the order of the preferred engines")
+	}
+}
+
 func TestAggregatorApplyFilters(t *testing.T) {
 	agg := NewAggregatorSimple([]Engine{}, 10*time.Second)
 
@@ -425,6 +455,60 @@ func TestAggregatorApplyFiltersDateRange(t *testing.T) {
 	}
 }
 
+func TestAggregatorApplyFiltersVideoLength(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, 10*time.Second)
+
+	results := []model.Result{
+		{URL: "https://example.com/1", Title: "Clip", Duration: 95},
+		{URL: "https://example.com/2", Title: "Lecture", Duration: 5400},
+		{URL: "https://example.com/3", Title: "Unknown length"},
+	}
+
+	query := &model.Query{
+		Text:        "test",
+		Category:    model.CategoryVideos,
+		VideoLength: "long",
+	}
+
+	filtered := agg.applyFilters(results, query)
+
+	// Should keep the long and unknown-length videos
+	if len(filtered) != 2 || filtered[0].Title != "Lecture" {
+		t.Errorf("applyFilters() = %v, want Lecture and Unknown length", filtered)
+	}
+
+	plain := newMockEngine("plain", model.CategoryVideos, true)
+	filtering := newMockEngine("filtering", model.CategoryVideos, true)
+	filtering.GetConfig().SupportsVideoFilters = true
+	agg = NewAggregatorSimple([]Engine{filtering, plain}, 10*time.Second)
+	if got := agg.filterEngines(query); len(got) != 1 || got[0].Name() != "filtering" {
+		t.Errorf("filterEngines() = %v, want [filtering]", engineNames(got))
+	}
+}
+
+func TestAggregatorApplyFiltersTimeRange(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, 10*time.Second)
+
+	results := []model.Result{
+		{URL: "https://example.com/1", Title: "Today", PublishedAt: time.Now().Add(-2 * time.Hour)},
+		{URL: "https://example.com/2", Title: "Last month", PublishedAt: time.Now().AddDate(0, -1, -1)},
+		{URL: "https://example.com/3", Title: "Undated"},
+	}
+
+	query := &model.Query{
+		Text:      "test",
+		Category:  model.CategoryNews,
+		TimeRange: "week",
+	}
+
+	filtered := agg.applyFilters(results, query)
+
+	// Should keep the recent and undated articles
+	if len(filtered) != 2 || filtered[0].Title != "Today" || filtered[1].Title != "Undated" {
+		t.Errorf("applyFilters() = %v, want Today and Undated", filtered)
+	}
+}
+
 // Tests for deduplication and sorting
 
 func TestDeduplicateResults(t *testing.T) {
@@ -1033,11 +1117,14 @@ func TestAggregatorSearchEngineError(t *testing.T) {
 	agg := NewAggregatorSimple([]Engine{engine}, 10*time.Second)
 
 	query := &model.Query{Text: "test", Category: model.CategoryGeneral}
-	_, err := agg.Search(context.Background(), query)
+	results, err := agg.Search(context.Background(), query)
 
-	// When engine returns error, results are empty, so we get ErrNoResults
-	if err != model.ErrNoResults {
-		t.Errorf("Search() error = %v, want ErrNoResults", err)
+	// When every engine fails the search degrades instead of failing
+	if err != nil {
+		t.Fatalf("Search() error = %v, want nil", err)
+	}
+	if !results.Degraded || len(results.Results) != 0 {
+		t.Errorf("Search() degraded=%v results=%d, want degraded and empty", results.Degraded, len(results.Results))
 	}
 }
 
@@ -1274,6 +1361,42 @@ func TestAggregatorSearchFallsBackToStaleCache(t *testing.T) {
 	}
 }
 
+func TestAggregatorSearchDegradedFromLocalIndex(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults([]model.Result{
+		{URL: "https://go.dev/doc", Title: "Go documentation", Content: "Tutorials and references"},
+		{URL: "https://go.dev/blog", Title: "The Go Blog", Content: "News from the Go team"},
+	})
+
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		MaxConcurrent: 1,
+	})
+
+	if _, err := agg.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral, PerPage: 10}); err != nil {
+		t.Fatalf("initial Search() error = %v", err)
+	}
+
+	engine.SetError(model.ErrEngineUnavailable)
+	results, err := agg.Search(context.Background(), &model.Query{Text: "go documentation", Category: model.CategoryGeneral, PerPage: 10})
+	if err != nil {
+		t.Fatalf("degraded Search() error = %v", err)
+	}
+	if !results.Degraded || !results.Stale || results.CachedAt == nil {
+		t.Fatalf("degraded=%v stale=%v cached_at=%v, want labeled degraded results", results.Degraded, results.Stale, results.CachedAt)
+	}
+	if len(results.Results) != 1 || results.Results[0].URL != "https://go.dev/doc" {
+		t.Errorf("degraded results = %+v, want the indexed documentation page", results.Results)
+	}
+
+	// Other categories are not answered from general results
+	results, err = agg.Search(context.Background(), &model.Query{Text: "go documentation", Category: model.CategoryNews, PerPage: 10})
+	if err != model.ErrNoEngines {
+		t.Errorf("news Search() = %+v, %v, want ErrNoEngines", results, err)
+	}
+}
+
 func TestAggregatorFilterEnginesRotatesHealthySubset(t *testing.T) {
 	e1 := newMockEngine("alpha", model.CategoryGeneral, true)
 	e2 := newMockEngine("beta", model.CategoryGeneral, true)
@@ -1552,3 +1675,64 @@ func TestSortResultsSingle(t *testing.T) {
 		t.Error("Single result should remain unchanged")
 	}
 }
+
+func TestAggregatorSearchEngineTimings(t *testing.T) {
+	good := newMockEngine("good", model.CategoryGeneral, true)
+	good.SetResults([]model.Result{
+		{URL: "https://example.com/1", Title: "Result 1", Engine: "good"},
+		{URL: "https://example.com/1", Title: "Duplicate", Engine: "good"},
+	})
+	bad := newMockEngine("bad", model.CategoryGeneral, true)
+	bad.SetError(context.DeadlineExceeded)
+
+	agg := NewAggregatorSimple([]Engine{good, bad}, 10*time.Second)
+	results, err := agg.Search(context.Background(), &model.Query{Text: "timings", Category: model.CategoryGeneral})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(results.EngineTimings) != 2 {
+		t.Fatalf("EngineTimings = %+v, want one per engine", results.EngineTimings)
+	}
+	// Sorted by engine name
+	badTiming, goodTiming := results.EngineTimings[0], results.EngineTimings[1]
+	if goodTiming.Engine != "good" || goodTiming.Results != 2 || goodTiming.Contributed != 1 || goodTiming.Error != "" {
+		t.Errorf("good timing = %+v, want 2 results contributing 1", goodTiming)
+	}
+	if badTiming.Engine != "bad" || !badTiming.TimedOut || badTiming.Error == "" {
+		t.Errorf("bad timing = %+v, want a timeout", badTiming)
+	}
+	if goodTiming.TotalMs < 0 || goodTiming.RespondedMs < goodTiming.RequestedMs {
+		t.Errorf("good timing = %+v, want ordered non-negative times", goodTiming)
+	}
+}
+
+func TestAggregatorRefreshBypassesCache(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults([]model.Result{{URL: "https://example.com/1", Title: "Result 1"}})
+
+	agg := NewAggregator([]Engine{engine}, AggregatorConfig{
+		Timeout:       10 * time.Second,
+		CacheEnabled:  true,
+		CacheTTL:      time.Hour,
+		MaxConcurrent: 1,
+	})
+
+	if _, err := agg.Refresh(context.Background(), model.NewQuery("warm")); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := agg.Refresh(context.Background(), model.NewQuery("warm")); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if engine.Calls() != 2 {
+		t.Errorf("engine calls after two refreshes = %d, want 2", engine.Calls())
+	}
+
+	results, err := agg.Search(context.Background(), model.NewQuery("warm"))
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !results.FromCache || engine.Calls() != 2 {
+		t.Errorf("Search() after Refresh: from_cache=%v calls=%d, want a cache hit", results.FromCache, engine.Calls())
+	}
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// StreamEvent is one step of a search run by SearchStream: an engine's
+// answer, or the final results once every engine has answered
+type StreamEvent struct {
+	// Engine is set for each engine as it answers
+	Engine *EngineProgress
+	// Results and Err are set on the last event, as Search returns them
+	Results *model.SearchResults
+	Err     error
+}
+
+// Done reports whether the event carries the final results
+func (e StreamEvent) Done() bool {
+	return e.Engine == nil
+}
+
+// EngineProgress is one engine's answer to a streamed search
+type EngineProgress struct {
+	// Engine is the engine's display name
+	Engine string
+	// Results are the engine's own results, filtered, screened and ranked
+	// as the final results will be, but not yet merged with the others
+	Results []model.Result
+	Timing  model.EngineTiming
+	// Completed engines out of Total have answered, this one included
+	Completed int
+	Total     int
+}
+
+// SearchStream runs a search like Search, sending an event as each engine
+// answers and then one with the final results, after which the channel is
+// closed. A cached search sends only the final event. Cancelling ctx ends
+// the search and the stream.
+func (a *Aggregator) SearchStream(ctx context.Context, query *model.Query) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+	send := func(event StreamEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(events)
+		results, err := a.search(ctx, query, true, func(p EngineProgress) {
+			send(StreamEvent{Engine: &p})
+		})
+		send(StreamEvent{
+			Results: a.localizeResults(query, a.classifyImages(ctx, query, a.applyRanking(query, a.screenResults(results)))),
+			Err:     err,
+		})
+	}()
+	return events
+}
+
+// engineResults prepares one engine's results for a progress event the way
+// search and Search prepare the merged results
+func (a *Aggregator) engineResults(ctx context.Context, query *model.Query, results []model.Result) []model.Result {
+	if len(results) == 0 {
+		return nil
+	}
+	partial := &model.SearchResults{Results: a.applyFilters(append([]model.Result(nil), results...), query)}
+	sortResults(partial.Results, query.SortBy)
+	return a.localizeResults(query, a.classifyImages(ctx, query, a.applyRanking(query, a.screenResults(partial)))).Results
+}
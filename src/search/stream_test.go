@@ -0,0 +1,76 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestSearchStream(t *testing.T) {
+	good := newMockEngine("good", model.CategoryGeneral, true)
+	good.SetResults([]model.Result{
+		{URL: "https://example.com/1", Title: "Result 1"},
+		{URL: "https://example.com/2", Title: "Result 2"},
+	})
+	bad := newMockEngine("bad", model.CategoryGeneral, true)
+	bad.SetError(errors.New("upstream down"))
+	agg := NewAggregator([]Engine{good, bad}, AggregatorConfig{Timeout: 10 * time.Second, CacheEnabled: true})
+
+	stream := func() []StreamEvent {
+		var events []StreamEvent
+		for event := range agg.SearchStream(context.Background(), &model.Query{Text: "stream", Category: model.CategoryGeneral}) {
+			events = append(events, event)
+		}
+		return events
+	}
+
+	events := stream()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want one per engine and the final one", len(events))
+	}
+	answered := map[string]int{}
+	for i, event := range events[:2] {
+		if event.Done() || event.Engine.Completed != i+1 || event.Engine.Total != 2 {
+			t.Errorf("event %d = %+v", i, event.Engine)
+			continue
+		}
+		answered[event.Engine.Engine] = len(event.Engine.Results)
+	}
+	if answered["good Engine"] != 2 || answered["bad Engine"] != 0 {
+		t.Errorf("engine results = %v", answered)
+	}
+	final := events[2]
+	if !final.Done() || final.Err != nil || len(final.Results.Results) != 2 {
+		t.Fatalf("final event = %+v", final)
+	}
+
+	// A cached search has nothing to report but its results
+	if events := stream(); len(events) != 1 || !events[0].Done() || !events[0].Results.FromCache {
+		t.Errorf("cached stream = %+v", events)
+	}
+}
+
+func TestSearchStreamCancelled(t *testing.T) {
+	engine := newMockEngine("test", model.CategoryGeneral, true)
+	engine.SetResults([]model.Result{{URL: "https://example.com/1", Title: "Result 1"}})
+	agg := NewAggregatorSimple([]Engine{engine}, 10*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := agg.SearchStream(ctx, &model.Query{Text: "stream", Category: model.CategoryGeneral})
+	cancel()
+	// Nobody reads after cancelling; the stream still ends
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("stream not closed after cancel")
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package search
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// ldJSONPattern matches JSON-LD script blocks
+var ldJSONPattern = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+// isoDurationPattern matches ISO 8601 durations as schema.org uses them,
+// e.g. PT1H30M or P0DT45M
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:\d+(?:\.\d+)?S)?)?$`)
+
+// Rich snippet text as engines print it, e.g. "Rating: 4.8 · 1,234 reviews
+// · 1 hr 15 min" or "Rating: 4.5/5 · $29.99 · In stock"
+var (
+	snippetRatingPattern   = regexp.MustCompile(`(?i)\brating:?\s*(\d(?:[.,]\d+)?)(?:\s*/\s*(\d+))?(?:\s*[·(-]\s*([\d.,]+)\s*(?:reviews?|votes?|ratings?))?`)
+	snippetDurationPattern = regexp.MustCompile(`(?i)·\s*(?:(\d+)\s*(?:hr|hrs|h)\b)?\s*(?:(\d+)\s*(?:min|mins|m)\b)?`)
+	snippetPricePattern    = regexp.MustCompile(`(?:^|\s|·)([$€£¥])\s?(\d+(?:[.,]\d{2})?)\b`)
+	snippetStockPattern    = regexp.MustCompile(`(?i)\b(in stock|out of stock)\b`)
+	snippetTagPattern      = regexp.MustCompile(`<[^>]*>`)
+	firstNumberPattern     = regexp.MustCompile(`\d+`)
+)
+
+// snippetLineLength is how far past the rating the rest of a rich snippet
+// line is looked for
+const snippetLineLength = 60
+
+// maxStructuredItems is how many ingredients or steps of a result are
+// kept; the counts cover the rest
+const maxStructuredItems = 12
+
+// maxStepLength is the longest step text kept, in runes
+const maxStepLength = 100
+
+// snippetCurrencies maps the currency symbols rich snippets print to ISO
+// 4217 codes
+var snippetCurrencies = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY"}
+
+// ExtractStructuredData reads the schema.org data of a result from an HTML
+// fragment: a JSON-LD block, microdata, or the rich snippet text an
+// engine printed for the result, in that order. It returns nil when the
+// fragment has nothing a snippet can show.
+func ExtractStructuredData(fragment string) *model.StructuredData {
+	if d := structuredFromJSONLD(fragment); d.Valid() {
+		return d
+	}
+	if strings.Contains(fragment, "itemscope") {
+		if d := structuredFromMicrodata(fragment); d.Valid() {
+			return d
+		}
+	}
+	text := html.UnescapeString(snippetTagPattern.ReplaceAllString(fragment, " "))
+	if d := structuredFromSnippet(text); d.Valid() {
+		return d
+	}
+	return nil
+}
+
+// structuredFromJSONLD reads the first usable node of the fragment's
+// JSON-LD blocks
+func structuredFromJSONLD(fragment string) *model.StructuredData {
+	for _, m := range ldJSONPattern.FindAllStringSubmatch(fragment, -1) {
+		var doc any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(m[1])), &doc); err != nil {
+			continue
+		}
+		if d := structuredFromNode(doc); d.Valid() {
+			return d
+		}
+	}
+	return nil
+}
+
+// structuredFromNode searches a JSON-LD value, including arrays and
+// @graph lists, for a node with data a snippet can show
+func structuredFromNode(node any) *model.StructuredData {
+	switch n := node.(type) {
+	case []any:
+		for _, item := range n {
+			if d := structuredFromNode(item); d.Valid() {
+				return d
+			}
+		}
+	case map[string]any:
+		if graph, ok := n["@graph"]; ok {
+			if d := structuredFromNode(graph); d.Valid() {
+				return d
+			}
+		}
+		return structuredFromItem(n)
+	}
+	return nil
+}
+
+// structuredFromItem reads one schema.org item, given as JSON-LD or as
+// microdata converted to the same shape
+func structuredFromItem(item map[string]any) *model.StructuredData {
+	d := &model.StructuredData{Rating: ldRating(item["aggregateRating"])}
+	for _, t := range ldStrings(item["@type"]) {
+		t = strings.ToLower(t[strings.LastIndexAny(t, "/#")+1:])
+		switch {
+		case t == "recipe":
+			d.Type = model.StructuredRecipe
+			d.Recipe = &model.RecipeInfo{
+				TotalTime: ldMinutes(item["totalTime"]),
+				Calories:  ldInt(ldField(item["nutrition"], "calories")),
+				Yield:     ldString(item["recipeYield"]),
+			}
+			if d.Recipe.TotalTime == 0 {
+				d.Recipe.TotalTime = ldMinutes(item["prepTime"]) + ldMinutes(item["cookTime"])
+			}
+			ingredients := ldStrings(item["recipeIngredient"])
+			if len(ingredients) == 0 {
+				ingredients = ldStrings(item["ingredients"])
+			}
+			d.Recipe.IngredientCount = len(ingredients)
+			d.Recipe.Ingredients = ingredients[:min(len(ingredients), maxStructuredItems)]
+		case t == "howto":
+			steps := ldSteps(item["step"])
+			d.Type = model.StructuredHowTo
+			d.HowTo = &model.HowToInfo{
+				TotalTime: ldMinutes(item["totalTime"]),
+				Steps:     steps[:min(len(steps), maxStructuredItems)],
+				StepCount: len(steps),
+			}
+		case t == "product":
+			d.Type = model.StructuredProduct
+			d.Product = ldOffer(item["offers"])
+		case strings.HasSuffix(t, "event"):
+			d.Type = model.StructuredEvent
+			d.Event = &model.EventInfo{
+				StartDate: ldTime(item["startDate"]),
+				Location:  ldString(item["location"]),
+			}
+		default:
+			continue
+		}
+		return d
+	}
+	if d.Rating != nil {
+		d.Type = model.StructuredRating
+		return d
+	}
+	return nil
+}
+
+// ldSteps returns the names of a HowTo's steps, or their text when they
+// have no name. Sections (HowToSection) are flattened into their steps.
+func ldSteps(v any) []string {
+	var steps []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch s := v.(type) {
+		case []any:
+			for _, item := range s {
+				walk(item)
+			}
+		case string:
+			if s = strings.TrimSpace(s); s != "" {
+				steps = append(steps, shortenStep(s))
+			}
+		case map[string]any:
+			if list, ok := s["itemListElement"]; ok {
+				walk(list)
+				return
+			}
+			name := ldString(s["name"])
+			if name == "" {
+				name = ldString(s["text"])
+			}
+			if name != "" {
+				steps = append(steps, shortenStep(name))
+			}
+		}
+	}
+	walk(v)
+	return steps
+}
+
+// shortenStep cuts a step's text to maxStepLength runes at a word boundary
+func shortenStep(step string) string {
+	step = strings.Join(strings.Fields(step), " ")
+	runes := []rune(step)
+	if len(runes) <= maxStepLength {
+		return step
+	}
+	cut := string(runes[:maxStepLength])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, ",.;:") + "…"
+}
+
+// ldRating reads an AggregateRating, or nil when it has no usable value
+func ldRating(v any) *model.Rating {
+	r, ok := ldFirst(v).(map[string]any)
+	if !ok {
+		return nil
+	}
+	value, err := strconv.ParseFloat(strings.Replace(ldString(r["ratingValue"]), ",", ".", 1), 64)
+	if err != nil {
+		return nil
+	}
+	best, err := strconv.ParseFloat(ldString(r["bestRating"]), 64)
+	if err != nil || best <= 0 {
+		best = 5
+	}
+	if value <= 0 || value > best {
+		return nil
+	}
+	count := ldInt(r["ratingCount"])
+	if count == 0 {
+		count = ldInt(r["reviewCount"])
+	}
+	return &model.Rating{Value: value, Best: best, Count: int64(count)}
+}
+
+// ldOffer reads the first offer of a product; an AggregateOffer gives its
+// lowest price
+func ldOffer(v any) *model.ProductInfo {
+	offer, ok := ldFirst(v).(map[string]any)
+	if !ok {
+		return nil
+	}
+	price := ldString(offer["price"])
+	if price == "" {
+		price = ldString(offer["lowPrice"])
+	}
+	p := &model.ProductInfo{Price: price, Currency: strings.ToUpper(ldString(offer["priceCurrency"]))}
+	switch availability := strings.ToLower(ldString(offer["availability"])); {
+	case strings.HasSuffix(availability, "outofstock"), strings.HasSuffix(availability, "soldout"):
+		p.Availability = "out_of_stock"
+	case strings.HasSuffix(availability, "instock"), strings.HasSuffix(availability, "limitedavailability"):
+		p.Availability = "in_stock"
+	}
+	return p
+}
+
+// ldFirst returns the first element of a list, or the value itself
+func ldFirst(v any) any {
+	if list, ok := v.([]any); ok {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[0]
+	}
+	return v
+}
+
+// ldField returns a property of an object value
+func ldField(v any, name string) any {
+	if obj, ok := ldFirst(v).(map[string]any); ok {
+		return obj[name]
+	}
+	return nil
+}
+
+// ldString returns a value as text: strings as they are, numbers
+// formatted, and objects by their name or @value
+func ldString(v any) string {
+	switch s := ldFirst(v).(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case map[string]any:
+		if name := ldString(s["name"]); name != "" {
+			return name
+		}
+		return ldString(s["@value"])
+	}
+	return ""
+}
+
+// ldStrings returns a value that may be a string or a list of strings
+func ldStrings(v any) []string {
+	if list, ok := v.([]any); ok {
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s := ldString(item); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	if s := ldString(v); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// ldInt returns the first whole number in a value, so "250 calories" and
+// "1,234" read as numbers
+func ldInt(v any) int {
+	s := strings.NewReplacer(",", "", " ", "").Replace(ldString(v))
+	n, _ := strconv.Atoi(firstNumberPattern.FindString(s))
+	return n
+}
+
+// ldMinutes reads an ISO 8601 duration in minutes
+func ldMinutes(v any) int {
+	m := isoDurationPattern.FindStringSubmatch(strings.ToUpper(ldString(v)))
+	if m == nil {
+		return 0
+	}
+	days, _ := strconv.Atoi(m[1])
+	hours, _ := strconv.Atoi(m[2])
+	minutes, _ := strconv.Atoi(m[3])
+	return days*24*60 + hours*60 + minutes
+}
+
+// ldTime reads a schema.org date or date-time
+func ldTime(v any) time.Time {
+	s := ldString(v)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// structuredFromMicrodata reads the first top-level microdata item with
+// data a snippet can show
+func structuredFromMicrodata(fragment string) *model.StructuredData {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return nil
+	}
+	var found *model.StructuredData
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if found != nil {
+			return
+		}
+		if n.Type == html.ElementNode && hasAttr(n, "itemscope") && !hasAttr(n, "itemprop") {
+			if d := structuredFromItem(microdataItem(n)); d.Valid() {
+				found = d
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return found
+}
+
+// microdataItem converts an itemscope element to the shape of a JSON-LD
+// node: @type from itemtype, one key per itemprop, nested items as objects
+func microdataItem(n *html.Node) map[string]any {
+	item := map[string]any{}
+	if types := strings.Fields(attr(n, "itemtype")); len(types) > 0 {
+		list := make([]any, len(types))
+		for i, t := range types {
+			list[i] = t
+		}
+		item["@type"] = list
+	}
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			scoped := hasAttr(c, "itemscope")
+			if props := strings.Fields(attr(c, "itemprop")); len(props) > 0 {
+				var value any
+				if scoped {
+					value = microdataItem(c)
+				} else {
+					value = microdataValue(c)
+				}
+				// Repeated properties, such as recipeIngredient, become lists
+				for _, p := range props {
+					switch prev := item[p].(type) {
+					case nil:
+						item[p] = value
+					case []any:
+						item[p] = append(prev, value)
+					default:
+						item[p] = []any{prev, value}
+					}
+				}
+			}
+			if !scoped {
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+	return item
+}
+
+// microdataValue is an itemprop's value: the content, datetime, href or
+// src attribute when present, else the element's text
+func microdataValue(n *html.Node) string {
+	for _, name := range []string{"content", "datetime", "href", "src"} {
+		if v, ok := attrOK(n, name); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	var sb strings.Builder
+	var text func(n *html.Node)
+	text = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			text(c)
+		}
+	}
+	text(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func attr(n *html.Node, name string) string {
+	v, _ := attrOK(n, name)
+	return v
+}
+
+func attrOK(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasAttr(n *html.Node, name string) bool {
+	_, ok := attrOK(n, name)
+	return ok
+}
+
+// structuredFromSnippet reads a rating, with the cooking time, price or
+// stock that follow it, from an engine's rich snippet text
+func structuredFromSnippet(text string) *model.StructuredData {
+	// Engines mark numbers left-to-right
+	text = strings.ReplaceAll(text, "\u200e", "")
+	loc := snippetRatingPattern.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil
+	}
+	m := func(i int) string {
+		if loc[2*i] < 0 {
+			return ""
+		}
+		return text[loc[2*i]:loc[2*i+1]]
+	}
+	value, _ := strconv.ParseFloat(strings.Replace(m(1), ",", ".", 1), 64)
+	best, _ := strconv.ParseFloat(m(2), 64)
+	if best <= 0 {
+		best = 5
+	}
+	if value <= 0 || value > best {
+		return nil
+	}
+	d := &model.StructuredData{
+		Type:   model.StructuredRating,
+		Rating: &model.Rating{Value: value, Best: best, Count: int64(ldInt(m(3)))},
+	}
+
+	// The rich snippet line is short; past it is the description
+	rest := text[loc[1]:]
+	if len(rest) > snippetLineLength {
+		rest = rest[:snippetLineLength]
+	}
+	price := snippetPricePattern.FindStringSubmatch(rest)
+	stock := snippetStockPattern.FindStringSubmatch(rest)
+	switch {
+	case price != nil || stock != nil:
+		d.Type = model.StructuredProduct
+		d.Product = &model.ProductInfo{}
+		if price != nil {
+			d.Product.Currency = snippetCurrencies[price[1]]
+			d.Product.Price = price[2]
+		}
+		if stock != nil {
+			d.Product.Availability = strings.ReplaceAll(strings.ToLower(stock[1]), " ", "_")
+		}
+	default:
+		for _, dm := range snippetDurationPattern.FindAllStringSubmatch(rest, -1) {
+			hours, _ := strconv.Atoi(dm[1])
+			minutes, _ := strconv.Atoi(dm[2])
+			if total := hours*60 + minutes; total > 0 {
+				d.Type = model.StructuredRecipe
+				d.Recipe = &model.RecipeInfo{TotalTime: total}
+				break
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+func TestExtractStructuredDataJSONLD(t *testing.T) {
+	recipe := ExtractStructuredData(`<script type="application/ld+json">{
+		"@context": "https://schema.org",
+		"@graph": [
+			{"@type": "WebPage", "name": "Pancakes"},
+			{"@type": "Recipe", "name": "Pancakes", "prepTime": "PT10M", "cookTime": "PT1H5M",
+			 "recipeYield": ["4 servings"], "nutrition": {"calories": "250 calories"},
+			 "recipeIngredient": ["2 eggs", "200 g flour", "300 ml milk", "1 tbsp sugar", "Butter"],
+			 "aggregateRating": {"ratingValue": "4.7", "ratingCount": "1,234"}}
+		]
+	}</script>`)
+	if recipe == nil || recipe.Type != model.StructuredRecipe {
+		t.Fatalf("recipe = %+v", recipe)
+	}
+	if r := recipe.Recipe; r.TotalTime != 75 || r.Calories != 250 || r.Yield != "4 servings" || r.IngredientCount != 5 {
+		t.Errorf("recipe info = %+v", r)
+	}
+	if r := recipe.Recipe; len(r.IngredientPreview()) != 4 || r.IngredientPreview()[1] != "200 g flour" || r.MoreIngredients() != 1 {
+		t.Errorf("ingredient preview = %v, %d more", r.IngredientPreview(), r.MoreIngredients())
+	}
+	if r := recipe.Rating; r.Value != 4.7 || r.Best != 5 || r.Count != 1234 || r.String() != "4.7/5" || r.Stars() != "★★★★★" {
+		t.Errorf("rating = %+v", r)
+	}
+
+	product := ExtractStructuredData(`<script type=application/ld+json>[{"@type": ["Product"],
+		"offers": {"@type": "Offer", "price": 29.99, "priceCurrency": "usd", "availability": "https://schema.org/OutOfStock"}}]</script>`)
+	if product == nil || product.Type != model.StructuredProduct || *product.Product != (model.ProductInfo{Price: "29.99", Currency: "USD", Availability: "out_of_stock"}) {
+		t.Errorf("product = %+v", product)
+	}
+
+	howto := ExtractStructuredData(`<script type="application/ld+json">{"@type": "HowTo", "totalTime": "PT30M",
+		"step": [
+			{"@type": "HowToSection", "name": "Prepare", "itemListElement": [
+				{"@type": "HowToStep", "text": "Turn off the water supply under the sink and open the tap to drain the remaining water from the pipes completely."},
+				{"@type": "HowToStep", "name": "Remove the handle"}
+			]},
+			"Replace the cartridge"
+		]}</script>`)
+	if howto == nil || howto.Type != model.StructuredHowTo || !howto.Card() || howto.HowTo.TotalTime != 30 || howto.HowTo.StepCount != 3 {
+		t.Fatalf("howto = %+v", howto)
+	}
+	if steps := howto.HowTo.Steps; steps[0] != "Turn off the water supply under the sink and open the tap to drain the remaining water from the…" || steps[2] != "Replace the cartridge" {
+		t.Errorf("howto steps = %q", steps)
+	}
+
+	event := ExtractStructuredData(`<script type="application/ld+json">{"@type": "MusicEvent",
+		"startDate": "2026-11-20T19:30", "location": {"@type": "Place", "name": "Town Hall"}}</script>`)
+	if event == nil || event.Type != model.StructuredEvent || event.Event.Location != "Town Hall" ||
+		!event.Event.StartDate.Equal(time.Date(2026, 11, 20, 19, 30, 0, 0, time.UTC)) {
+		t.Errorf("event = %+v", event)
+	}
+
+	for name, fragment := range map[string]string{
+		"invalid JSON":     `<script type="application/ld+json">{"@type": "Recipe",</script>`,
+		"no fields":        `<script type="application/ld+json">{"@type": "Recipe", "name": "Pancakes"}</script>`,
+		"rating off scale": `<script type="application/ld+json">{"@type": "Book", "aggregateRating": {"ratingValue": 9, "bestRating": 5}}</script>`,
+		"plain snippet":    `<span>Go is an open source programming language.</span>`,
+	} {
+		if d := ExtractStructuredData(fragment); d != nil {
+			t.Errorf("%s: got %+v", name, d)
+		}
+	}
+}
+
+func TestExtractStructuredDataMicrodata(t *testing.T) {
+	d := ExtractStructuredData(`<div itemscope itemtype="https://schema.org/Product">
+		<span itemprop="name">Kettle</span>
+		<div itemprop="aggregateRating" itemscope itemtype="https://schema.org/AggregateRating">
+			<span itemprop="ratingValue">8</span>/<span itemprop="bestRating">10</span>
+			(<span itemprop="reviewCount">56</span> reviews)
+		</div>
+		<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+			<meta itemprop="priceCurrency" content="EUR"><span itemprop="price" content="39.00">€39</span>
+			<link itemprop="availability" href="https://schema.org/InStock">
+		</div>
+	</div>`)
+	if d == nil || d.Type != model.StructuredProduct {
+		t.Fatalf("microdata = %+v", d)
+	}
+	if *d.Rating != (model.Rating{Value: 8, Best: 10, Count: 56}) || d.Rating.Stars() != "★★★★☆" {
+		t.Errorf("rating = %+v", d.Rating)
+	}
+	if *d.Product != (model.ProductInfo{Price: "39.00", Currency: "EUR", Availability: "in_stock"}) {
+		t.Errorf("product = %+v", d.Product)
+	}
+
+	recipe := ExtractStructuredData(`<div itemscope itemtype="https://schema.org/Recipe">
+		<span itemprop="recipeIngredient">2 eggs</span>
+		<span itemprop="recipeIngredient">200 g flour</span>
+		<meta itemprop="totalTime" content="PT20M">
+	</div>`)
+	if recipe == nil || recipe.Recipe.TotalTime != 20 || len(recipe.Recipe.Ingredients) != 2 || recipe.Recipe.Ingredients[1] != "200 g flour" {
+		t.Errorf("microdata recipe = %+v", recipe)
+	}
+}
+
+func TestExtractStructuredDataSnippet(t *testing.T) {
+	recipe := ExtractStructuredData(`<div>Rating: 4.8 · ‎1,234 reviews · 1 hr 15 min</div><div>Fluffy pancakes in no time.</div>`)
+	if recipe == nil || recipe.Type != model.StructuredRecipe || recipe.Recipe.TotalTime != 75 ||
+		*recipe.Rating != (model.Rating{Value: 4.8, Best: 5, Count: 1234}) {
+		t.Errorf("recipe = %+v", recipe)
+	}
+
+	product := ExtractStructuredData(`<span>Rating: 4.5/5 · $29.99 · In stock</span>`)
+	if product == nil || product.Type != model.StructuredProduct ||
+		*product.Product != (model.ProductInfo{Price: "29.99", Currency: "USD", Availability: "in_stock"}) {
+		t.Errorf("product = %+v", product)
+	}
+
+	rating := ExtractStructuredData(`Rating: 3.9 · 87 votes`)
+	if rating == nil || rating.Type != model.StructuredRating || rating.Rating.Count != 87 {
+		t.Errorf("rating = %+v", rating)
+	}
+}
@@ -0,0 +1,252 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/version"
+)
+
+const (
+	// SuggestionBudget is how long a suggestion request waits for
+	// providers; slower ones are left out of that response
+	SuggestionBudget = 150 * time.Millisecond
+	// suggestionProviderTimeout bounds a provider request. It outlives the
+	// budget so a late answer still reaches the history.
+	suggestionProviderTimeout = 2 * time.Second
+	maxSuggestions            = 10
+	maxSuggestionBodyBytes    = 64 * 1024
+)
+
+// SuggestionProvider returns autocomplete suggestions for a partial query
+type SuggestionProvider interface {
+	Name() string
+	Suggest(ctx context.Context, query string) ([]string, error)
+}
+
+// WeightedSuggestionProvider is a provider and its say in the merged order
+type WeightedSuggestionProvider struct {
+	Provider SuggestionProvider
+	Weight   float64
+}
+
+// Suggester merges the suggestions of several providers and the local
+// history into one deduplicated list
+type Suggester struct {
+	providers     []WeightedSuggestionProvider
+	history       *SuggestionHistory
+	historyWeight float64
+	budget        time.Duration
+}
+
+// NewSuggester creates a suggester over providers. history may be nil to
+// leave the local history out.
+func NewSuggester(providers []WeightedSuggestionProvider, history *SuggestionHistory, historyWeight float64) *Suggester {
+	return &Suggester{
+		providers:     providers,
+		history:       history,
+		historyWeight: historyWeight,
+		budget:        SuggestionBudget,
+	}
+}
+
+// Suggest returns up to ten suggestions for the typed text. Providers that have not
+// answered within the budget, or that fail, are silently left out; the
+// result is never nil.
+func (s *Suggester) Suggest(ctx context.Context, typed string) []string {
+	query := strings.TrimSpace(typed)
+	if query == "" {
+		return []string{}
+	}
+
+	type providerAnswer struct {
+		weight      float64
+		suggestions []string
+	}
+	answers := make(chan providerAnswer, len(s.providers))
+	for _, p := range s.providers {
+		go func(p WeightedSuggestionProvider) {
+			// Not cancelled with the request, so the history still learns
+			// from providers that answer after the budget
+			pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), suggestionProviderTimeout)
+			defer cancel()
+			suggestions, err := p.Provider.Suggest(pctx, query)
+			if err != nil {
+				suggestions = nil
+			}
+			if s.history != nil {
+				s.history.Add(suggestions)
+			}
+			answers <- providerAnswer{p.Weight, suggestions}
+		}(p)
+	}
+
+	m := newSuggestionMerge()
+	budget := time.NewTimer(s.budget)
+	defer budget.Stop()
+collect:
+	for received := 0; received < len(s.providers); received++ {
+		select {
+		case a := <-answers:
+			m.add(a.suggestions, a.weight)
+		case <-budget.C:
+			break collect
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	if s.history != nil {
+		// The untrimmed text, so "go " completes whole words only
+		m.add(s.history.Complete(typed, maxSuggestions), s.historyWeight)
+	}
+	return m.top(maxSuggestions)
+}
+
+// suggestionMerge scores suggestions across lists: each list adds its
+// weight divided by the suggestion's rank, so suggestions near the top of
+// several lists win
+type suggestionMerge struct {
+	scores  map[string]float64
+	display map[string]string
+	best    map[string]float64
+}
+
+func newSuggestionMerge() *suggestionMerge {
+	return &suggestionMerge{
+		scores:  make(map[string]float64),
+		display: make(map[string]string),
+		best:    make(map[string]float64),
+	}
+}
+
+func (m *suggestionMerge) add(suggestions []string, weight float64) {
+	seen := make(map[string]bool, len(suggestions))
+	rank := 0
+	for _, s := range suggestions {
+		s = strings.Join(strings.Fields(s), " ")
+		key := strings.ToLower(s)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		rank++
+		score := weight / float64(rank)
+		m.scores[key] += score
+		// Show the spelling of the strongest single vote
+		if score > m.best[key] {
+			m.best[key] = score
+			m.display[key] = s
+		}
+	}
+}
+
+func (m *suggestionMerge) top(limit int) []string {
+	keys := make([]string, 0, len(m.scores))
+	for key := range m.scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m.scores[keys[i]] != m.scores[keys[j]] {
+			return m.scores[keys[i]] > m.scores[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	suggestions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		suggestions = append(suggestions, m.display[key])
+	}
+	return suggestions
+}
+
+// suggestionEndpoints are the providers NewSuggestionProvider knows. All
+// answer in the OpenSearch suggestions format, [query, [suggestions...]].
+var suggestionEndpoints = map[string]string{
+	"duckduckgo": "https://duckduckgo.com/ac/?type=list&q=",
+	"google":     "https://suggestqueries.google.com/complete/search?client=firefox&q=",
+	"brave":      "https://search.brave.com/api/suggest?q=",
+	"wikipedia":  "https://en.wikipedia.org/w/api.php?action=opensearch&format=json&limit=10&search=",
+}
+
+// SuggestionProviderNames lists the providers NewSuggestionProvider knows
+func SuggestionProviderNames() []string {
+	names := make([]string, 0, len(suggestionEndpoints))
+	for name := range suggestionEndpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openSearchSuggestionProvider asks an OpenSearch suggestions endpoint
+type openSearchSuggestionProvider struct {
+	name     string
+	endpoint string
+	client   *http.Client
+}
+
+// NewSuggestionProvider returns the named built-in provider
+func NewSuggestionProvider(name string) (SuggestionProvider, bool) {
+	endpoint, ok := suggestionEndpoints[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return &openSearchSuggestionProvider{
+		name:     strings.ToLower(name),
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: suggestionProviderTimeout},
+	}, true
+}
+
+func (p *openSearchSuggestionProvider) Name() string {
+	return p.name
+}
+
+func (p *openSearchSuggestionProvider) Suggest(ctx context.Context, query string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpoint+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.BrowserUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s suggestions returned status %d", p.name, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSuggestionBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseOpenSearchSuggestions(body)
+}
+
+// parseOpenSearchSuggestions reads [query, [suggestions...], ...]
+func parseOpenSearchSuggestions(body []byte) ([]string, error) {
+	var result []json.RawMessage
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if len(result) < 2 {
+		return nil, fmt.Errorf("suggestions response has no suggestion list")
+	}
+	var suggestions []string
+	if err := json.Unmarshal(result[1], &suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
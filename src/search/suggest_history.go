@@ -0,0 +1,173 @@
+package search
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// maxSuggestionHistory bounds the suggestion history; the least recently
+// seen phrases are evicted first
+const maxSuggestionHistory = 5000
+
+// SuggestionHistory is a prefix trie of the suggestions providers have
+// returned. It only ever holds provider output, never what users typed, and
+// lives in memory only.
+type SuggestionHistory struct {
+	mu    sync.Mutex
+	max   int
+	root  *trieNode
+	order *list.List
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// entry is set when a phrase ends at this node
+	entry *list.Element
+}
+
+// historyPhrase is one phrase in the history
+type historyPhrase struct {
+	key      string
+	phrase   string
+	seen     int
+	lastSeen time.Time
+}
+
+// NewSuggestionHistory creates an empty suggestion history
+func NewSuggestionHistory() *SuggestionHistory {
+	return newSuggestionHistory(maxSuggestionHistory)
+}
+
+func newSuggestionHistory(max int) *SuggestionHistory {
+	return &SuggestionHistory{
+		max:   max,
+		root:  &trieNode{},
+		order: list.New(),
+	}
+}
+
+// Add records phrases returned by a provider
+func (h *SuggestionHistory) Add(phrases []string) {
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, phrase := range phrases {
+		phrase = strings.Join(strings.Fields(phrase), " ")
+		key := strings.ToLower(phrase)
+		if key == "" {
+			continue
+		}
+		node := h.root
+		for _, r := range key {
+			child := node.children[r]
+			if child == nil {
+				if node.children == nil {
+					node.children = make(map[rune]*trieNode)
+				}
+				child = &trieNode{}
+				node.children[r] = child
+			}
+			node = child
+		}
+		if node.entry != nil {
+			p := node.entry.Value.(*historyPhrase)
+			p.seen++
+			p.lastSeen = now
+			h.order.MoveToBack(node.entry)
+			continue
+		}
+		node.entry = h.order.PushBack(&historyPhrase{key: key, phrase: phrase, seen: 1, lastSeen: now})
+	}
+
+	for h.order.Len() > h.max {
+		h.removeLocked(h.order.Front().Value.(*historyPhrase).key)
+	}
+}
+
+// removeLocked deletes key and prunes the branches it leaves empty
+func (h *SuggestionHistory) removeLocked(key string) {
+	runes := []rune(key)
+	path := make([]*trieNode, 0, len(runes)+1)
+	node := h.root
+	path = append(path, node)
+	for _, r := range runes {
+		node = node.children[r]
+		if node == nil {
+			return
+		}
+		path = append(path, node)
+	}
+	if node.entry == nil {
+		return
+	}
+	h.order.Remove(node.entry)
+	node.entry = nil
+
+	for i := len(runes) - 1; i >= 0; i-- {
+		child := path[i+1]
+		if child.entry != nil || len(child.children) > 0 {
+			break
+		}
+		delete(path[i].children, runes[i])
+	}
+}
+
+// Complete returns up to limit phrases starting with prefix, the most often
+// seen first
+func (h *SuggestionHistory) Complete(prefix string, limit int) []string {
+	typed := prefix
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+	if prefix == "" {
+		return nil
+	}
+	// A trailing space means the last word is complete
+	if strings.TrimRightFunc(typed, unicode.IsSpace) != typed {
+		prefix += " "
+	}
+
+	h.mu.Lock()
+	node := h.root
+	for _, r := range prefix {
+		node = node.children[r]
+		if node == nil {
+			h.mu.Unlock()
+			return nil
+		}
+	}
+	var phrases []historyPhrase
+	stack := []*trieNode{node}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n.entry != nil {
+			phrases = append(phrases, *n.entry.Value.(*historyPhrase))
+		}
+		for _, child := range n.children {
+			stack = append(stack, child)
+		}
+	}
+	h.mu.Unlock()
+
+	sort.Slice(phrases, func(i, j int) bool {
+		if phrases[i].seen != phrases[j].seen {
+			return phrases[i].seen > phrases[j].seen
+		}
+		if !phrases[i].lastSeen.Equal(phrases[j].lastSeen) {
+			return phrases[i].lastSeen.After(phrases[j].lastSeen)
+		}
+		return phrases[i].key < phrases[j].key
+	})
+	if len(phrases) > limit {
+		phrases = phrases[:limit]
+	}
+	completions := make([]string, 0, len(phrases))
+	for _, p := range phrases {
+		completions = append(completions, p.phrase)
+	}
+	return completions
+}
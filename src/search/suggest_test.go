@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeSuggestionProvider struct {
+	name        string
+	delay       time.Duration
+	suggestions []string
+	err         error
+}
+
+func (p *fakeSuggestionProvider) Name() string { return p.name }
+
+func (p *fakeSuggestionProvider) Suggest(ctx context.Context, query string) ([]string, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return p.suggestions, p.err
+}
+
+func TestSuggesterMergesProviders(t *testing.T) {
+	s := NewSuggester([]WeightedSuggestionProvider{
+		{Provider: &fakeSuggestionProvider{name: "a", suggestions: []string{"golang", "go  tutorial", "gopher"}}, Weight: 1},
+		{Provider: &fakeSuggestionProvider{name: "b", suggestions: []string{"Go Tutorial", "golang"}}, Weight: 2},
+		{Provider: &fakeSuggestionProvider{name: "broken", err: errors.New("down")}, Weight: 5},
+	}, nil, 0)
+
+	got := s.Suggest(context.Background(), "go")
+	// go tutorial: 1/2 + 2/1, golang: 1/1 + 2/2, gopher: 1/3
+	want := []string{"Go Tutorial", "golang", "gopher"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest() = %q, want %q", got, want)
+	}
+}
+
+func TestSuggesterBudget(t *testing.T) {
+	history := NewSuggestionHistory()
+	s := NewSuggester([]WeightedSuggestionProvider{
+		{Provider: &fakeSuggestionProvider{name: "fast", suggestions: []string{"go fast"}}, Weight: 1},
+		{Provider: &fakeSuggestionProvider{name: "slow", delay: SuggestionBudget + 200*time.Millisecond, suggestions: []string{"go slow"}}, Weight: 1},
+	}, history, 0.5)
+
+	start := time.Now()
+	got := s.Suggest(context.Background(), "go")
+	if elapsed := time.Since(start); elapsed > SuggestionBudget+100*time.Millisecond {
+		t.Errorf("Suggest() took %v, want about %v", elapsed, SuggestionBudget)
+	}
+	if !reflect.DeepEqual(got, []string{"go fast"}) {
+		t.Errorf("Suggest() = %q, want only the fast provider", got)
+	}
+
+	// The late answer still reaches the history
+	deadline := time.Now().Add(2 * time.Second)
+	for len(history.Complete("go s", 10)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := history.Complete("go s", 10); !reflect.DeepEqual(got, []string{"go slow"}) {
+		t.Errorf("history.Complete() = %q, want the slow provider's suggestion", got)
+	}
+}
+
+func TestSuggesterEmptyQuery(t *testing.T) {
+	s := NewSuggester(nil, NewSuggestionHistory(), 1)
+	if got := s.Suggest(context.Background(), "  "); got == nil || len(got) != 0 {
+		t.Errorf("Suggest() = %#v, want an empty list", got)
+	}
+	if got := s.Suggest(context.Background(), "nothing"); got == nil || len(got) != 0 {
+		t.Errorf("Suggest() = %#v, want an empty list", got)
+	}
+}
+
+func TestSuggestionHistory(t *testing.T) {
+	h := newSuggestionHistory(3)
+	h.Add([]string{"go modules", "Go  Generics", "gopher"})
+	h.Add([]string{"go modules"})
+
+	if got, want := h.Complete("GO ", 10), []string{"go modules", "Go Generics"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Complete() = %q, want %q", got, want)
+	}
+
+	// Over capacity the least recently seen phrase goes, with its branch
+	h.Add([]string{"rust"})
+	if got := h.Complete("go g", 10); len(got) != 0 {
+		t.Errorf("Complete() = %q, want the evicted phrase gone", got)
+	}
+	if _, ok := h.root.children['g'].children['o'].children[' '].children['g']; ok {
+		t.Error("evicted phrase left an empty branch")
+	}
+	if got := h.Complete("r", 10); !reflect.DeepEqual(got, []string{"rust"}) {
+		t.Errorf("Complete() = %q, want rust", got)
+	}
+}
+
+func TestParseOpenSearchSuggestions(t *testing.T) {
+	got, err := parseOpenSearchSuggestions([]byte(`["go",["golang","go tutorial"],["desc"],["url"]]`))
+	if err != nil || !reflect.DeepEqual(got, []string{"golang", "go tutorial"}) {
+		t.Errorf("parseOpenSearchSuggestions() = %q, %v", got, err)
+	}
+	for _, body := range []string{`{}`, `["go"]`, `["go", "golang"]`} {
+		if _, err := parseOpenSearchSuggestions([]byte(body)); err == nil {
+			t.Errorf("parseOpenSearchSuggestions(%s) succeeded, want an error", body)
+		}
+	}
+	if _, ok := NewSuggestionProvider("nope"); ok {
+		t.Error("NewSuggestionProvider accepted an unknown name")
+	}
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// engineTrace records when an engine's HTTP requests go out and answers come
+// back. Engines build requests from the search context, so a client trace on
+// that context sees every request without engine changes.
+type engineTrace struct {
+	mu        sync.Mutex
+	requested time.Time
+	responded time.Time
+}
+
+// withTrace returns ctx carrying a client trace that fills t
+func (t *engineTrace) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			if t.requested.IsZero() {
+				t.requested = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.responded = time.Now()
+			t.mu.Unlock()
+		},
+	})
+}
+
+// timing builds the engine's timing entry. searchStart is when the search
+// began; started and finished bound the engine's Search call.
+func (t *engineTrace) timing(name string, searchStart, started, finished time.Time, results int, err error) model.EngineTiming {
+	t.mu.Lock()
+	requested, responded := t.requested, t.responded
+	t.mu.Unlock()
+
+	// Engines that never reached the network report their own bounds
+	if requested.IsZero() {
+		requested = started
+	}
+	if responded.IsZero() || responded.After(finished) {
+		responded = finished
+	}
+
+	timing := model.EngineTiming{
+		Engine:      name,
+		RequestedMs: milliseconds(requested.Sub(searchStart)),
+		RespondedMs: milliseconds(responded.Sub(searchStart)),
+		ParseMs:     milliseconds(finished.Sub(responded)),
+		TotalMs:     milliseconds(finished.Sub(started)),
+		Results:     results,
+	}
+	if err != nil {
+		timing.Error = err.Error()
+		timing.TimedOut = errors.Is(err, context.DeadlineExceeded)
+	}
+	return timing
+}
+
+// countContributions sets Contributed from the engines of the final results
+func countContributions(timings []model.EngineTiming, results []model.Result) {
+	counts := make(map[string]int, len(timings))
+	for _, r := range results {
+		counts[r.Engine]++
+	}
+	for i := range timings {
+		timings[i].Contributed = counts[timings[i].Engine]
+	}
+}
+
+// milliseconds rounds d to microsecond precision in milliseconds, matching
+// the API's other timings
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,283 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// User agent strategy. Engines tell scrapers apart by their User-Agent, and
+// the one that parses well today may be served a stripped page tomorrow.
+// The aggregator keeps a pool of browser user agents and picks one per
+// engine request by the engine's policy, counting for every engine and user
+// agent how often the response parsed into results. Switches of the user
+// agent an engine settled on are logged.
+
+// User agent policies
+const (
+	// UserAgentFixed sends the engine's built-in user agent
+	UserAgentFixed = "fixed"
+	// UserAgentRotate takes the pool's user agents in turn
+	UserAgentRotate = "rotate"
+	// UserAgentBest sends the user agent that parses best, trying the others
+	// on every uaExploreEvery-th request (A/B testing them)
+	UserAgentBest = "best"
+	// UserAgentPinned is reported for engines pinned to one user agent
+	UserAgentPinned = "pinned"
+)
+
+const (
+	// uaExploreEvery is how often the best policy tries another user agent
+	uaExploreEvery = 10
+	// maxUserAgentSwitches is how many switches are kept per engine
+	maxUserAgentSwitches = 50
+)
+
+// EngineUserAgent is one engine's user agent policy
+type EngineUserAgent struct {
+	// Policy is UserAgentFixed, UserAgentRotate or UserAgentBest; empty
+	// uses the strategy's
+	Policy string
+	// Pin sends this user agent whatever the policy
+	Pin string
+}
+
+// UserAgentStrategy is how engine requests pick their user agent
+type UserAgentStrategy struct {
+	// Pool holds the user agents rotated and tested
+	Pool []string
+	// Policy applies to engines without their own; empty is UserAgentFixed
+	Policy string
+	// Engines holds per-engine policies, keyed by engine name
+	Engines map[string]EngineUserAgent
+}
+
+// UserAgentStats is how one user agent fared with one engine. An empty
+// UserAgent is the engine's built-in one.
+type UserAgentStats struct {
+	UserAgent string `json:"user_agent"`
+	Requests  int64  `json:"requests"`
+	// Parsed counts responses that parsed into results
+	Parsed    int64     `json:"parsed"`
+	Blocked   int64     `json:"blocked"`
+	Failed    int64     `json:"failed"`
+	ParseRate float64   `json:"parse_rate"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// UserAgentSwitch records an engine moving to another user agent
+type UserAgentSwitch struct {
+	At     time.Time `json:"at"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason"`
+}
+
+// UserAgentStatus is one engine's user agent policy and results
+type UserAgentStatus struct {
+	Engine string `json:"engine"`
+	Policy string `json:"policy"`
+	// Current is the user agent last picked by the fixed, best or pinned
+	// policy
+	Current  string            `json:"current"`
+	Stats    []UserAgentStats  `json:"stats"`
+	Switches []UserAgentSwitch `json:"switches"`
+}
+
+// userAgentPool picks user agents and tracks how they fare
+type userAgentPool struct {
+	mu       sync.Mutex
+	strategy UserAgentStrategy
+	engines  map[string]*engineUserAgents
+}
+
+// engineUserAgents is one engine's user agent state, guarded by the pool's
+// lock
+type engineUserAgents struct {
+	requests int
+	current  string
+	stats    map[string]*UserAgentStats
+	switches []UserAgentSwitch
+}
+
+// SetUserAgents replaces the user agent strategy. Collected results are
+// kept.
+func (a *Aggregator) SetUserAgents(s UserAgentStrategy) {
+	engines := make(map[string]EngineUserAgent, len(s.Engines))
+	for name, e := range s.Engines {
+		engines[strings.ToLower(name)] = e
+	}
+	s.Engines = engines
+	s.Pool = append([]string(nil), s.Pool...)
+	a.userAgents.mu.Lock()
+	a.userAgents.strategy = s
+	a.userAgents.mu.Unlock()
+}
+
+// UserAgentStatus reports every engine's user agent policy, how each user
+// agent fared with it and when it switched, for the engines named
+func (a *Aggregator) UserAgentStatus(engines []string) []UserAgentStatus {
+	p := &a.userAgents
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make([]UserAgentStatus, 0, len(engines))
+	for _, name := range engines {
+		key := strings.ToLower(name)
+		st := UserAgentStatus{Engine: name, Policy: p.policyLocked(key), Stats: []UserAgentStats{}, Switches: []UserAgentSwitch{}}
+		if e, ok := p.engines[key]; ok {
+			st.Current = e.current
+			for _, s := range e.stats {
+				st.Stats = append(st.Stats, *s)
+			}
+			st.Switches = append(st.Switches, e.switches...)
+		}
+		sort.Slice(st.Stats, func(i, j int) bool {
+			if st.Stats[i].ParseRate != st.Stats[j].ParseRate {
+				return st.Stats[i].ParseRate > st.Stats[j].ParseRate
+			}
+			return st.Stats[i].UserAgent < st.Stats[j].UserAgent
+		})
+		status = append(status, st)
+	}
+	return status
+}
+
+// policyLocked returns engine's policy, UserAgentPinned for a pin
+func (p *userAgentPool) policyLocked(engine string) string {
+	e := p.strategy.Engines[engine]
+	if e.Pin != "" {
+		return UserAgentPinned
+	}
+	policy := e.Policy
+	if policy == "" {
+		policy = p.strategy.Policy
+	}
+	if policy == "" || len(p.strategy.Pool) == 0 {
+		return UserAgentFixed
+	}
+	return policy
+}
+
+// stateLocked returns engine's state, creating it
+func (p *userAgentPool) stateLocked(engine string) *engineUserAgents {
+	if p.engines == nil {
+		p.engines = make(map[string]*engineUserAgents)
+	}
+	e, ok := p.engines[engine]
+	if !ok {
+		e = &engineUserAgents{stats: make(map[string]*UserAgentStats)}
+		p.engines[engine] = e
+	}
+	return e
+}
+
+// pick returns the user agent for engine's next request; "" keeps the
+// engine's built-in one
+func (p *userAgentPool) pick(engine string) string {
+	key := strings.ToLower(engine)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.stateLocked(key)
+	e.requests++
+	pool := p.strategy.Pool
+	policy := p.policyLocked(key)
+	switch policy {
+	case UserAgentPinned:
+		ua := p.strategy.Engines[key].Pin
+		e.settle(engine, ua, policy)
+		return ua
+	case UserAgentRotate:
+		return pool[e.requests%len(pool)]
+	case UserAgentBest:
+		if e.requests%uaExploreEvery == 0 {
+			// Try the pool in turn, so every user agent keeps being
+			// measured
+			return pool[(e.requests/uaExploreEvery)%len(pool)]
+		}
+		best, bestScore := pool[0], -1.0
+		for _, ua := range pool {
+			// Untried user agents start at an even chance
+			var parsed, requests float64
+			if s, ok := e.stats[ua]; ok {
+				parsed, requests = float64(s.Parsed), float64(s.Requests)
+			}
+			if score := (parsed + 1) / (requests + 2); score > bestScore {
+				best, bestScore = ua, score
+			}
+		}
+		e.settle(engine, best, policy)
+		return best
+	}
+	e.settle(engine, "", policy)
+	return ""
+}
+
+// settle records ua as the one engine's policy settled on, logging a switch
+func (e *engineUserAgents) settle(engine, ua, reason string) {
+	if ua == e.current {
+		return
+	}
+	slog.Info("engine user agent switched", "engine", engine, "from", e.current, "to", ua, "reason", reason)
+	e.switches = append(e.switches, UserAgentSwitch{At: time.Now(), From: e.current, To: ua, Reason: reason})
+	if len(e.switches) > maxUserAgentSwitches {
+		e.switches = e.switches[len(e.switches)-maxUserAgentSwitches:]
+	}
+	e.current = ua
+}
+
+// record counts how a request sent as ua fared. Calls refused before
+// reaching the engine and canceled searches say nothing about ua.
+func (p *userAgentPool) record(engine, ua string, results int, err error) {
+	if errors.Is(err, ErrQuotaExhausted) || errors.Is(err, ErrHostBusy) || errors.Is(err, context.Canceled) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.stateLocked(strings.ToLower(engine))
+	s, ok := e.stats[ua]
+	if !ok {
+		s = &UserAgentStats{UserAgent: ua}
+		e.stats[ua] = s
+	}
+	s.Requests++
+	s.LastUsed = time.Now()
+	switch {
+	case errors.Is(err, model.ErrEngineBlocked):
+		s.Blocked++
+	case err != nil:
+		s.Failed++
+	case results > 0:
+		s.Parsed++
+	}
+	s.ParseRate = float64(s.Parsed) / float64(s.Requests)
+}
+
+// withUserAgent returns ctx sending the user agent engine's policy picks,
+// and that user agent. A User-Agent set by the engine's request template
+// wins, and "" is returned for it as for the built-in one.
+func (a *Aggregator) withUserAgent(ctx context.Context, engine Engine) (context.Context, string) {
+	if o, ok := ctx.Value(requestOverridesKey{}).(*requestOverrides); ok {
+		for name := range o.headers {
+			if strings.EqualFold(name, "User-Agent") {
+				return ctx, ""
+			}
+		}
+	}
+	ua := a.userAgents.pick(engine.Name())
+	if ua == "" {
+		return ctx, ""
+	}
+	return withRequestHeader(ctx, "User-Agent", ua), ua
+}
+
+// AlternateUserAgents returns the browser user agents blocked engines are
+// retried as, a starting pool for the user agent strategy
+func AlternateUserAgents() []string {
+	return append([]string(nil), alternateUserAgents...)
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// pickyEngine parses results only for requests sent as one user agent
+type pickyEngine struct {
+	*mockEngine
+	parses string
+	agents []string
+}
+
+func (e *pickyEngine) Search(ctx context.Context, query *model.Query) ([]model.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://engine.example/search", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "built-in")
+	ua := ApplyRequestOverrides(req).Header.Get("User-Agent")
+	e.agents = append(e.agents, ua)
+	if ua != e.parses {
+		// A stripped page: HTTP 200 with nothing to parse
+		return nil, nil
+	}
+	return []model.Result{{URL: "https://engine.example/1", Title: "One"}}, nil
+}
+
+func TestUserAgentPolicies(t *testing.T) {
+	agg := NewAggregatorSimple([]Engine{}, time.Second)
+	pool := []string{"ua-a", "ua-b", "ua-c"}
+	agg.SetUserAgents(UserAgentStrategy{
+		Pool: pool,
+		Engines: map[string]EngineUserAgent{
+			"Rotating": {Policy: UserAgentRotate},
+			"pinned":   {Policy: UserAgentRotate, Pin: "ua-pinned"},
+		},
+	})
+
+	if ua := agg.userAgents.pick("plain"); ua != "" {
+		t.Errorf("fixed policy picked %q, want the built-in user agent", ua)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < len(pool); i++ {
+		seen[agg.userAgents.pick("rotating")] = true
+	}
+	if len(seen) != len(pool) {
+		t.Errorf("rotation used %v, want the whole pool", seen)
+	}
+	if ua := agg.userAgents.pick("pinned"); ua != "ua-pinned" {
+		t.Errorf("pinned engine picked %q", ua)
+	}
+
+	// A template's User-Agent wins over the pool
+	agg.SetRequestTemplates(map[string]RequestTemplate{"rotating": {Headers: map[string]string{"user-agent": "mine"}}})
+	engine := newMockEngine("rotating", model.CategoryGeneral, true)
+	if _, ua := agg.withUserAgent(agg.withRequestOverrides(context.Background(), engine, &model.Query{Text: "q"}), engine); ua != "" {
+		t.Errorf("template user agent replaced by %q", ua)
+	}
+
+	status := agg.UserAgentStatus([]string{"pinned", "plain"})
+	if status[0].Policy != UserAgentPinned || status[0].Current != "ua-pinned" || len(status[0].Switches) != 1 {
+		t.Errorf("pinned status = %+v", status[0])
+	}
+	if status[1].Policy != UserAgentFixed || status[1].Current != "" || len(status[1].Switches) != 0 {
+		t.Errorf("fixed status = %+v", status[1])
+	}
+}
+
+func TestUserAgentBestPolicy(t *testing.T) {
+	engine := &pickyEngine{mockEngine: newMockEngine("picky", model.CategoryGeneral, true), parses: "ua-c"}
+	agg := NewAggregatorSimple([]Engine{engine}, time.Second)
+	agg.SetUserAgents(UserAgentStrategy{Pool: []string{"ua-a", "ua-b", "ua-c"}, Policy: UserAgentBest})
+
+	query := &model.Query{Text: "go", Category: model.CategoryGeneral}
+	for i := 0; i < 40; i++ {
+		agg.Search(context.Background(), query)
+	}
+
+	status := agg.UserAgentStatus([]string{"picky"})[0]
+	if status.Current != "ua-c" {
+		t.Fatalf("settled on %q, want the user agent that parses; agents sent: %v", status.Current, engine.agents)
+	}
+	if best := status.Stats[0]; best.UserAgent != "ua-c" || best.ParseRate != 1 || best.Parsed != best.Requests {
+		t.Errorf("best stats = %+v", best)
+	}
+	var explored int
+	for _, ua := range engine.agents[len(engine.agents)-20:] {
+		if ua != "ua-c" {
+			explored++
+		}
+	}
+	if explored == 0 || explored > 20/uaExploreEvery {
+		t.Errorf("tried other user agents %d times in the last 20 requests", explored)
+	}
+	if last := status.Switches[len(status.Switches)-1]; last.To != "ua-c" || last.Reason != UserAgentBest {
+		t.Errorf("last switch = %+v", last)
+	}
+}
@@ -0,0 +1,172 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Operator JWTs are short-lived HS256 tokens exchanged for the server token
+// or a named operator token. They carry everything needed to authorize a
+// request, so any instance holding the signing keys can check them without
+// the server database.
+
+var (
+	// ErrOperatorJWTInvalid is returned for a malformed token, an unknown
+	// key or a bad signature
+	ErrOperatorJWTInvalid = errors.New("invalid operator JWT")
+	// ErrOperatorJWTExpired is returned for a token past its expiry
+	ErrOperatorJWTExpired = errors.New("operator JWT has expired")
+)
+
+// OperatorJWTSubject is the subject of tokens exchanged for the server
+// token; those of named tokens have the named token's prefix
+const OperatorJWTSubject = "operator"
+
+// JWTKey is an HMAC key that signs or verifies operator JWTs, named by its
+// ID in the token's kid header
+type JWTKey struct {
+	ID     string
+	Secret []byte
+}
+
+// OperatorClaims are the claims of an operator JWT
+type OperatorClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+	// The named token's ID, name, space-separated scopes and requests per
+	// minute at issue; empty for the server token
+	TokenID   int64  `json:"tid,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	RateLimit int    `json:"rate_limit,omitempty"`
+}
+
+// Operator reports whether the token was exchanged for the server token
+func (c *OperatorClaims) Operator() bool {
+	return c.Subject == OperatorJWTSubject && c.TokenID == 0
+}
+
+// Token returns the named operator token the claims stand for, with the
+// scopes and rate limit it had at issue
+func (c *OperatorClaims) Token() *OperatorToken {
+	return &OperatorToken{
+		ID:        c.TokenID,
+		Name:      c.Name,
+		Prefix:    c.Subject,
+		Scopes:    strings.Fields(c.Scope),
+		ExpiresAt: time.Unix(c.ExpiresAt, 0).UTC(),
+		RateLimit: c.RateLimit,
+	}
+}
+
+// OperatorJWTClaims returns the claims of a token valid for ttl from now,
+// for the server token when token is nil and otherwise for token
+func OperatorJWTClaims(token *OperatorToken, ttl time.Duration, now time.Time) OperatorClaims {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	claims := OperatorClaims{
+		Subject:   OperatorJWTSubject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		ID:        hex.EncodeToString(id),
+	}
+	if token != nil {
+		claims.Subject = token.Prefix
+		claims.TokenID = token.ID
+		claims.Name = token.Name
+		claims.Scope = strings.Join(token.Scopes, " ")
+		claims.RateLimit = token.LimitAt(now)
+		// A named token's JWT does not outlive the token
+		if !token.ExpiresAt.IsZero() && token.ExpiresAt.Unix() < claims.ExpiresAt {
+			claims.ExpiresAt = token.ExpiresAt.Unix()
+		}
+	}
+	return claims
+}
+
+// jwtHeader is the JOSE header of an operator JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// SignOperatorJWT returns claims as a JWT signed with key
+func SignOperatorJWT(claims OperatorClaims, key JWTKey) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(jwtSignature(key.Secret, signed)), nil
+}
+
+// VerifyOperatorJWT checks raw's signature against the key its kid names
+// among keys and returns its claims, unless it has expired at now
+func VerifyOperatorJWT(raw string, keys []JWTKey, now time.Time) (*OperatorClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrOperatorJWTInvalid
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrOperatorJWTInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrOperatorJWTInvalid
+	}
+	valid := false
+	for _, key := range keys {
+		if key.ID == header.Kid {
+			valid = hmac.Equal(signature, jwtSignature(key.Secret, parts[0]+"."+parts[1]))
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrOperatorJWTInvalid
+	}
+	var claims OperatorClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil || claims.Subject == "" {
+		return nil, ErrOperatorJWTInvalid
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrOperatorJWTExpired
+	}
+	return &claims, nil
+}
+
+// LooksLikeJWT reports whether a bearer token has the shape of a JWT, to
+// tell it from the opaque operator tokens
+func LooksLikeJWT(raw string) bool {
+	return strings.HasPrefix(raw, "eyJ") && strings.Count(raw, ".") == 2
+}
+
+// jwtSignature is the HMAC-SHA256 of signed with secret
+func jwtSignature(secret []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+// decodeJWTPart decodes a base64url JSON part of a JWT into v
+func decodeJWTPart(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
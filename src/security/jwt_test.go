@@ -0,0 +1,63 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOperatorJWTRoundTrip(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	old := JWTKey{ID: "old", Secret: []byte(strings.Repeat("o", 32))}
+	current := JWTKey{ID: "new", Secret: []byte(strings.Repeat("n", 32))}
+
+	token := &OperatorToken{ID: 7, Name: "ci", Prefix: "adm_abcd", Scopes: []string{ScopeRead}, RateLimit: 50}
+	claims := OperatorJWTClaims(token, 15*time.Minute, now)
+	raw, err := SignOperatorJWT(claims, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !LooksLikeJWT(raw) || LooksLikeJWT("adm_abcd") {
+		t.Fatalf("LooksLikeJWT(%q) = false", raw)
+	}
+
+	// Tokens signed with a key still listed verify after rotation
+	got, err := VerifyOperatorJWT(raw, []JWTKey{current, old}, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Operator() {
+		t.Error("named token JWT reported as the server token")
+	}
+	named := got.Token()
+	if named.ID != 7 || named.Name != "ci" || !named.HasScope(ScopeRead) || named.LimitAt(now) != 50 {
+		t.Errorf("Token() = %+v", named)
+	}
+
+	if _, err := VerifyOperatorJWT(raw, []JWTKey{current}, now); !errors.Is(err, ErrOperatorJWTInvalid) {
+		t.Errorf("removed key: err = %v", err)
+	}
+	if _, err := VerifyOperatorJWT(raw, []JWTKey{old}, now.Add(15*time.Minute)); !errors.Is(err, ErrOperatorJWTExpired) {
+		t.Errorf("expired: err = %v", err)
+	}
+	// Server token claims under the named token's signature
+	signature := raw[strings.LastIndex(raw, ".")+1:]
+	forged, _ := SignOperatorJWT(OperatorJWTClaims(nil, time.Hour, now), JWTKey{ID: "old", Secret: []byte("guess")})
+	forged = forged[:strings.LastIndex(forged, ".")+1] + signature
+	if _, err := VerifyOperatorJWT(forged, []JWTKey{old}, now); !errors.Is(err, ErrOperatorJWTInvalid) {
+		t.Errorf("forged claims: err = %v", err)
+	}
+}
+
+func TestOperatorJWTClaims(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	if c := OperatorJWTClaims(nil, time.Hour, now); !c.Operator() || c.ExpiresAt != now.Add(time.Hour).Unix() || c.ID == "" {
+		t.Errorf("server token claims = %+v", c)
+	}
+	// A named token's JWT ends with the token
+	token := &OperatorToken{ID: 1, Prefix: "adm_x", ExpiresAt: now.Add(time.Minute)}
+	if c := OperatorJWTClaims(token, time.Hour, now); c.Operator() || c.ExpiresAt != now.Add(time.Minute).Unix() {
+		t.Errorf("named token claims = %+v", c)
+	}
+}
@@ -0,0 +1,240 @@
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apimgr/search/src/database"
+)
+
+// The server secrets the key manager versions and rotates
+const (
+	// KeySecretKey is server.secret_key: search alert tokens and webhook
+	// secrets, DNS-01 credentials, and the derived JWT key
+	KeySecretKey = "secret_key"
+	// KeyEncryptionKey is server.security.encryption_key: security report
+	// bodies stored without PGP
+	KeyEncryptionKey = "encryption_key"
+	// KeyInstallationSecret is server.security.installation_secret: the
+	// security_id and the lock on the PGP private keys
+	KeyInstallationSecret = "installation_secret"
+	// KeyJWT is the first of server.jwt.keys, which signs operator JWTs
+	KeyJWT = "jwt"
+)
+
+// ManagedKeys lists the managed secrets in the order they are reported
+var ManagedKeys = []string{KeySecretKey, KeyEncryptionKey, KeyInstallationSecret, KeyJWT}
+
+// ErrUnknownKey is returned for a name not in ManagedKeys
+var ErrUnknownKey = errors.New("unknown key; keys are secret_key, encryption_key, installation_secret and jwt")
+
+// KeyVersion is one version of a managed secret. Only its fingerprint is
+// stored; the secret itself stays in server.yml.
+type KeyVersion struct {
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	Fingerprint string `json:"fingerprint"`
+	// Why the version began: "initial", "rotated", "scheduled" or
+	// "changed" when server.yml was edited by hand
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecretFingerprint identifies a secret without revealing it
+func SecretFingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("key-fingerprint:" + secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CurrentKeyVersion returns the latest recorded version of the secret
+// name, or sql.ErrNoRows before the first
+func CurrentKeyVersion(ctx context.Context, db *database.DB, name string) (KeyVersion, error) {
+	versions, err := keyVersions(ctx, db, name, 1)
+	if err != nil {
+		return KeyVersion{}, err
+	}
+	if len(versions) == 0 {
+		return KeyVersion{}, sql.ErrNoRows
+	}
+	return versions[0], nil
+}
+
+// KeyHistory returns the recorded versions of the secret name, newest first
+func KeyHistory(ctx context.Context, db *database.DB, name string) ([]KeyVersion, error) {
+	return keyVersions(ctx, db, name, 0)
+}
+
+func keyVersions(ctx context.Context, db *database.DB, name string, limit int) ([]KeyVersion, error) {
+	query := fmt.Sprintf(`SELECT name, version, fingerprint, reason, created_at FROM %s WHERE name = ? ORDER BY version DESC`,
+		database.ServerTableName(db, "server_key_versions"))
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := db.Query(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("list key versions: %w", err)
+	}
+	defer rows.Close()
+	versions := []KeyVersion{}
+	for rows.Next() {
+		var v KeyVersion
+		var created string
+		if err := rows.Scan(&v.Name, &v.Version, &v.Fingerprint, &v.Reason, &created); err != nil {
+			return nil, fmt.Errorf("read key version: %w", err)
+		}
+		v.CreatedAt = parseDBTime(created)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// ObserveKey records secret as a new version of name unless it is the
+// current one, and returns the current version. The first version is
+// recorded as "initial" and one nobody rotated as "changed".
+func ObserveKey(ctx context.Context, db *database.DB, name, secret string, now time.Time) (KeyVersion, error) {
+	current, err := CurrentKeyVersion(ctx, db, name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return RecordKeyVersion(ctx, db, name, secret, "initial", now)
+	case err != nil:
+		return KeyVersion{}, err
+	case current.Fingerprint != SecretFingerprint(secret):
+		return RecordKeyVersion(ctx, db, name, secret, "changed", now)
+	}
+	return current, nil
+}
+
+// RecordKeyVersion records secret as the next version of name
+func RecordKeyVersion(ctx context.Context, db *database.DB, name, secret, reason string, now time.Time) (KeyVersion, error) {
+	version := KeyVersion{Name: name, Version: 1, Fingerprint: SecretFingerprint(secret), Reason: reason, CreatedAt: now.UTC().Truncate(time.Second)}
+	if current, err := CurrentKeyVersion(ctx, db, name); err == nil {
+		version.Version = current.Version + 1
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return KeyVersion{}, err
+	}
+	if _, err := db.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (name, version, fingerprint, reason, created_at) VALUES (?, ?, ?, ?, ?)`,
+		database.ServerTableName(db, "server_key_versions")),
+		version.Name, version.Version, version.Fingerprint, version.Reason, version.CreatedAt.Format(dbTimeLayout)); err != nil {
+		return KeyVersion{}, fmt.Errorf("record key version: %w", err)
+	}
+	return version, nil
+}
+
+// CountAESGCMReports returns how many security report bodies are encrypted
+// with the encryption key rather than to the PGP key
+func CountAESGCMReports(ctx context.Context, db *database.DB) (int, error) {
+	var n int
+	err := db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE encryption_method = ?`,
+		database.ServerTableName(db, "security_reports")), EncryptionMethodAESGCM).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count security reports: %w", err)
+	}
+	return n, nil
+}
+
+// ReencryptReports re-encrypts the security report bodies encrypted with
+// oldKey under newKey, all or none, returning how many there were
+func ReencryptReports(ctx context.Context, db *database.DB, oldKey, newKey string) (int, error) {
+	table := database.ServerTableName(db, "security_reports")
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT tracking_id, encrypted_body FROM %s WHERE encryption_method = ?`, table), EncryptionMethodAESGCM)
+	if err != nil {
+		return 0, fmt.Errorf("list security reports: %w", err)
+	}
+	bodies := map[string][]byte{}
+	for rows.Next() {
+		var id string
+		var body []byte
+		if err := rows.Scan(&id, &body); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("read security report: %w", err)
+		}
+		bodies[id] = body
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for id, body := range bodies {
+		plaintext, err := DecryptAESGCM(oldKey, string(body))
+		if err != nil {
+			return 0, fmt.Errorf("decrypt security report %s: %w", id, err)
+		}
+		encrypted, err := EncryptAESGCM(newKey, plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET encrypted_body = ? WHERE tracking_id = ?`, table), []byte(encrypted), id); err != nil {
+			return 0, fmt.Errorf("update security report %s: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(bodies), nil
+}
+
+// pgpPrivateKeyFiles are the locked private keys: the current one and the
+// one kept through a rotation's grace period
+var pgpPrivateKeyFiles = []string{PGPEncryptedPrivateKeyFilename, "pgp.priv.old.asc.enc"}
+
+// CountPGPPrivateKeys returns how many locked PGP private keys are stored
+func CountPGPPrivateKeys(configDir string) int {
+	n := 0
+	for _, name := range pgpPrivateKeyFiles {
+		if _, err := os.Stat(filepath.Join(configDir, PGPDirName, name)); err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// RelockPrivateKeys re-locks the stored PGP private keys, locked with
+// oldSecret, with newSecret, returning how many there were. Each is
+// unlocked before any is rewritten.
+func RelockPrivateKeys(configDir, oldSecret, newSecret string) (int, error) {
+	relocked := map[string]string{}
+	for _, name := range pgpPrivateKeyFiles {
+		path := filepath.Join(configDir, PGPDirName, name)
+		armored, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		key, err := UnlockPrivateKey(string(armored), oldSecret)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		kp, err := keypairFromKey(key, newSecret)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		relocked[path] = kp.EncryptedPrivateKey
+	}
+	for path, armored := range relocked {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(armored), 0o600); err != nil {
+			return 0, err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return 0, err
+		}
+	}
+	return len(relocked), nil
+}
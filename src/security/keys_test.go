@@ -0,0 +1,116 @@
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyVersions(t *testing.T) {
+	db := newTokenTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	v, err := ObserveKey(ctx, db, KeySecretKey, "first", now)
+	if err != nil || v.Version != 1 || v.Reason != "initial" || v.Fingerprint != SecretFingerprint("first") {
+		t.Fatalf("first ObserveKey() = %+v, %v", v, err)
+	}
+	if v, err = ObserveKey(ctx, db, KeySecretKey, "first", now.Add(time.Hour)); err != nil || v.Version != 1 || !v.CreatedAt.Equal(now) {
+		t.Errorf("unchanged ObserveKey() = %+v, %v", v, err)
+	}
+	if v, err = RecordKeyVersion(ctx, db, KeySecretKey, "second", "rotated", now.Add(time.Hour)); err != nil || v.Version != 2 {
+		t.Errorf("RecordKeyVersion() = %+v, %v", v, err)
+	}
+	if v, err = ObserveKey(ctx, db, KeySecretKey, "edited", now.Add(2*time.Hour)); err != nil || v.Version != 3 || v.Reason != "changed" {
+		t.Errorf("edited ObserveKey() = %+v, %v", v, err)
+	}
+
+	history, err := KeyHistory(ctx, db, KeySecretKey)
+	if err != nil || len(history) != 3 || history[0].Version != 3 || history[2].Reason != "initial" {
+		t.Errorf("KeyHistory() = %+v, %v", history, err)
+	}
+	if strings.Contains(history[0].Fingerprint, "edited") || len(history[0].Fingerprint) != 16 {
+		t.Errorf("fingerprint = %q", history[0].Fingerprint)
+	}
+	if history, _ := KeyHistory(ctx, db, KeyJWT); len(history) != 0 {
+		t.Errorf("other key history = %+v", history)
+	}
+}
+
+func TestReencryptReports(t *testing.T) {
+	db := newTokenTestDB(t)
+	ctx := context.Background()
+	oldKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("n", 32)))
+
+	body, err := EncryptAESGCM(oldKey, []byte("the report"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InsertReport(ctx, db, Report{TrackingID: "sec_1", EncryptedBody: []byte(body), EncryptionMethod: EncryptionMethodAESGCM}); err != nil {
+		t.Fatal(err)
+	}
+	if err := InsertReport(ctx, db, Report{TrackingID: "sec_2", EncryptedBody: []byte("-----BEGIN PGP MESSAGE-----"), EncryptionMethod: "pgp"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := CountAESGCMReports(ctx, db); err != nil || n != 1 {
+		t.Fatalf("CountAESGCMReports() = %d, %v", n, err)
+	}
+
+	// The wrong old key changes nothing
+	if _, err := ReencryptReports(ctx, db, newKey, oldKey); err == nil {
+		t.Fatal("ReencryptReports() with the wrong key succeeded")
+	}
+	if n, err := ReencryptReports(ctx, db, oldKey, newKey); err != nil || n != 1 {
+		t.Fatalf("ReencryptReports() = %d, %v", n, err)
+	}
+	var stored []byte
+	if err := db.QueryRow(ctx, `SELECT encrypted_body FROM security_reports WHERE tracking_id = 'sec_1'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := DecryptAESGCM(newKey, string(stored)); err != nil || string(plaintext) != "the report" {
+		t.Errorf("decrypt with the new key = %q, %v", plaintext, err)
+	}
+}
+
+func TestRelockPrivateKeys(t *testing.T) {
+	dir := t.TempDir()
+	if n := CountPGPPrivateKeys(dir); n != 0 {
+		t.Errorf("CountPGPPrivateKeys() on an empty dir = %d", n)
+	}
+	if n, err := RelockPrivateKeys(dir, "a", "b"); err != nil || n != 0 {
+		t.Errorf("RelockPrivateKeys() on an empty dir = %d, %v", n, err)
+	}
+
+	kp := generateTestKeypair(t)
+	if err := os.MkdirAll(filepath.Join(dir, PGPDirName), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, PGPDirName, PGPEncryptedPrivateKeyFilename)
+	if err := os.WriteFile(path, []byte(kp.EncryptedPrivateKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if n := CountPGPPrivateKeys(dir); n != 1 {
+		t.Errorf("CountPGPPrivateKeys() = %d", n)
+	}
+	if _, err := RelockPrivateKeys(dir, "wrong", "new-installation-secret"); err == nil {
+		t.Fatal("RelockPrivateKeys() with the wrong secret succeeded")
+	}
+	if n, err := RelockPrivateKeys(dir, "test-installation-secret", "new-installation-secret"); err != nil || n != 1 {
+		t.Fatalf("RelockPrivateKeys() = %d, %v", n, err)
+	}
+	armored, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnlockPrivateKey(string(armored), "new-installation-secret"); err != nil {
+		t.Errorf("unlock with the new secret: %v", err)
+	}
+	if _, err := UnlockPrivateKey(string(armored), "test-installation-secret"); err == nil {
+		t.Error("old secret still unlocks the key")
+	}
+}
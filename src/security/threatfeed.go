@@ -0,0 +1,417 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Threat feed formats
+const (
+	// One URL per line (URLhaus text exports, OpenPhish)
+	ThreatFormatURLs = "urls"
+	// One domain per line, or hosts-file lines ("0.0.0.0 evil.example")
+	ThreatFormatDomains = "domains"
+	// Hex SHA-256 of the canonical URL, one per line
+	ThreatFormatSHA256 = "sha256"
+)
+
+// Screening sensitivities, from fewest to most matches
+const (
+	// Only URLs that appear in a feed
+	ThreatSensitivityLow = "low"
+	// Also any host under a domain listed by a domain feed
+	ThreatSensitivityMedium = "medium"
+	// Also any host that appears in a listed URL. Shared hosts such as
+	// code or file hosting sites will be flagged as a whole.
+	ThreatSensitivityHigh = "high"
+)
+
+// maxThreatFeedSize caps a single feed download
+const maxThreatFeedSize = 64 << 20
+
+// ThreatFeed defines a malware or phishing list download source.
+type ThreatFeed struct {
+	Name     string
+	URL      string
+	Format   string // "urls", "domains", "sha256"
+	Category string // "malware", "phishing"
+	Enabled  bool
+}
+
+// ThreatMatch describes why a URL was flagged.
+type ThreatMatch struct {
+	Feed     string `json:"feed"`
+	Category string `json:"category"`
+	// "url", "domain" or "host"
+	Scope string `json:"scope"`
+}
+
+// DefaultThreatFeeds returns the default malware and phishing feeds.
+func DefaultThreatFeeds() []ThreatFeed {
+	return []ThreatFeed{
+		{
+			Name:     "urlhaus",
+			URL:      "https://urlhaus.abuse.ch/downloads/text_online/",
+			Format:   ThreatFormatURLs,
+			Category: "malware",
+			Enabled:  true,
+		},
+		{
+			Name:     "openphish",
+			URL:      "https://openphish.com/feed.txt",
+			Format:   ThreatFormatURLs,
+			Category: "phishing",
+			Enabled:  true,
+		},
+	}
+}
+
+// ThreatFeedManager downloads malware and phishing feeds and checks URLs
+// against them. Listed URLs are kept as SHA-256 hashes of their canonical
+// form; every check is local, so result URLs never leave the server.
+type ThreatFeedManager struct {
+	mu        sync.RWMutex
+	urls      map[[sha256.Size]byte]int
+	domains   map[string]int
+	hosts     map[string]int
+	updatedAt time.Time
+	dataDir   string
+	feeds     []ThreatFeed
+	client    *http.Client
+}
+
+// NewThreatFeedManager creates a new threat feed manager.
+func NewThreatFeedManager(dataDir string, feeds []ThreatFeed) *ThreatFeedManager {
+	if feeds == nil {
+		feeds = DefaultThreatFeeds()
+	}
+	// Feed names become file names
+	valid := make([]ThreatFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		if !validThreatFeedName(feed.Name) {
+			slog.Warn("threat feed skipped: invalid name", "feed", feed.Name)
+			continue
+		}
+		valid = append(valid, feed)
+	}
+	return &ThreatFeedManager{
+		urls:    make(map[[sha256.Size]byte]int),
+		domains: make(map[string]int),
+		hosts:   make(map[string]int),
+		dataDir: dataDir,
+		feeds:   valid,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Check reports whether a URL is listed by any feed at the given
+// sensitivity. An unknown sensitivity is treated as medium.
+func (m *ThreatFeedManager) Check(rawURL, sensitivity string) (ThreatMatch, bool) {
+	canonical, host := CanonicalThreatURL(rawURL)
+	if canonical == "" {
+		return ThreatMatch{}, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if i, ok := m.urls[sha256.Sum256([]byte(canonical))]; ok {
+		return m.match(i, "url"), true
+	}
+	if sensitivity == ThreatSensitivityLow {
+		return ThreatMatch{}, false
+	}
+
+	// Walk the host and its parent domains
+	for h := host; strings.Contains(h, "."); h = h[strings.Index(h, ".")+1:] {
+		if i, ok := m.domains[h]; ok {
+			return m.match(i, "domain"), true
+		}
+	}
+
+	if sensitivity == ThreatSensitivityHigh {
+		if i, ok := m.hosts[host]; ok {
+			return m.match(i, "host"), true
+		}
+	}
+	return ThreatMatch{}, false
+}
+
+// match builds a ThreatMatch for the feed at index i.
+func (m *ThreatFeedManager) match(i int, scope string) ThreatMatch {
+	return ThreatMatch{Feed: m.feeds[i].Name, Category: m.feeds[i].Category, Scope: scope}
+}
+
+// Update downloads all enabled feeds and reloads them. A feed that fails
+// to download keeps its previous copy on disk.
+func (m *ThreatFeedManager) Update(ctx context.Context) error {
+	slog.Info("starting threat feed update")
+
+	feedDir := m.feedDir()
+	if err := os.MkdirAll(feedDir, 0700); err != nil {
+		return fmt.Errorf("failed to create threat feed directory: %w", err)
+	}
+
+	var updateErrors []string
+	enabled := 0
+	for _, feed := range m.feeds {
+		if !feed.Enabled {
+			continue
+		}
+		enabled++
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := m.download(ctx, feed, feedDir); err != nil {
+			slog.Warn("threat feed failed", "feed", feed.Name, "error", err)
+			updateErrors = append(updateErrors, fmt.Sprintf("%s: %v", feed.Name, err))
+		}
+	}
+
+	if err := m.LoadFromDisk(); err != nil {
+		return err
+	}
+
+	urls, domains, hosts := m.Count()
+	slog.Info("threat feed update complete",
+		"urls", urls,
+		"domains", domains,
+		"hosts", hosts,
+		"errors", len(updateErrors))
+
+	if enabled > 0 && len(updateErrors) == enabled {
+		return fmt.Errorf("all threat feeds failed: %s", strings.Join(updateErrors, "; "))
+	}
+	return nil
+}
+
+// download saves a feed to dir, replacing the previous copy only once the
+// whole body has arrived.
+func (m *ThreatFeedManager) download(ctx context.Context, feed ThreatFeed, dir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp(dir, feed.Name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, maxThreatFeedSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+	if n > maxThreatFeedSize {
+		return fmt.Errorf("feed larger than %d bytes", maxThreatFeedSize)
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, feed.Name+".txt"))
+}
+
+// LoadFromDisk loads the enabled feeds from previously downloaded files.
+func (m *ThreatFeedManager) LoadFromDisk() error {
+	urls := make(map[[sha256.Size]byte]int)
+	domains := make(map[string]int)
+	hosts := make(map[string]int)
+	var newest time.Time
+
+	for i, feed := range m.feeds {
+		if !feed.Enabled {
+			continue
+		}
+
+		path := filepath.Join(m.feedDir(), feed.Name+".txt")
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open threat feed %s: %w", feed.Name, err)
+		}
+
+		err = parseThreatFeed(file, feed.Format, i, urls, domains, hosts)
+		if info, statErr := file.Stat(); statErr == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse threat feed %s: %w", feed.Name, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.urls = urls
+	m.domains = domains
+	m.hosts = hosts
+	m.updatedAt = newest
+	m.mu.Unlock()
+
+	return nil
+}
+
+// parseThreatFeed adds the entries of one feed to the lookup sets. The
+// first feed to list an entry keeps it.
+func parseThreatFeed(r io.Reader, format string, feed int, urls map[[sha256.Size]byte]int, domains, hosts map[string]int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip comments and empty lines
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		switch format {
+		case ThreatFormatURLs:
+			canonical, host := CanonicalThreatURL(line)
+			if canonical == "" {
+				continue
+			}
+			sum := sha256.Sum256([]byte(canonical))
+			if _, ok := urls[sum]; !ok {
+				urls[sum] = feed
+			}
+			if _, ok := hosts[host]; !ok {
+				hosts[host] = feed
+			}
+		case ThreatFormatDomains:
+			// Accept hosts-file lines such as "0.0.0.0 evil.example"
+			fields := strings.Fields(line)
+			domain := fields[0]
+			if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+				domain = fields[1]
+			}
+			domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+			if !strings.Contains(domain, ".") || net.ParseIP(domain) != nil {
+				continue
+			}
+			if _, ok := domains[domain]; !ok {
+				domains[domain] = feed
+			}
+		case ThreatFormatSHA256:
+			var sum [sha256.Size]byte
+			if b, err := hex.DecodeString(strings.Fields(line)[0]); err == nil && len(b) == sha256.Size {
+				copy(sum[:], b)
+				if _, ok := urls[sum]; !ok {
+					urls[sum] = feed
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// CanonicalThreatURL returns the form of a URL that feed entries are hashed
+// in, and its host: lowercase scheme and host, no default port, no
+// fragment and "/" for an empty path. Anything that is not an http(s) URL
+// returns "".
+func CanonicalThreatURL(rawURL string) (string, string) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", ""
+	}
+
+	hostport := host
+	if strings.Contains(host, ":") {
+		hostport = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		hostport += ":" + port
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := scheme + "://" + hostport + path
+	if u.RawQuery != "" {
+		canonical += "?" + u.RawQuery
+	}
+	return canonical, host
+}
+
+// ThreatURLHash returns the hex SHA-256 of a URL's canonical form, as used
+// by sha256 feeds.
+func ThreatURLHash(rawURL string) string {
+	canonical, _ := CanonicalThreatURL(rawURL)
+	if canonical == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:])
+}
+
+// Count returns the number of listed URLs, domains and URL hosts.
+func (m *ThreatFeedManager) Count() (urls, domains, hosts int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.urls), len(m.domains), len(m.hosts)
+}
+
+// UpdatedAt returns when the newest loaded feed was downloaded.
+func (m *ThreatFeedManager) UpdatedAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.updatedAt
+}
+
+// validThreatFeedName reports whether a feed name is safe as a file name
+func validThreatFeedName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// feedDir returns where downloaded feeds are kept.
+func (m *ThreatFeedManager) feedDir() string {
+	return filepath.Join(m.dataDir, "security", "threatfeeds")
+}
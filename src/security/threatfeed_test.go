@@ -0,0 +1,144 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newThreatFeedServer serves one feed body per path.
+func newThreatFeedServer(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestThreatFeedManager_Check(t *testing.T) {
+	srv := newThreatFeedServer(t, map[string]string{
+		"/urls":    "# URLhaus\nHTTP://Files.Share.example:80/payload.exe#x\nnot a url\n",
+		"/domains": "0.0.0.0 phish.example\nlogin-bank.example.\n127.0.0.1\n",
+		"/hashes":  ThreatURLHash("https://hashed.example/a?b=1") + "\nzz\n",
+	})
+	m := NewThreatFeedManager(t.TempDir(), []ThreatFeed{
+		{Name: "urls", URL: srv.URL + "/urls", Format: ThreatFormatURLs, Category: "malware", Enabled: true},
+		{Name: "domains", URL: srv.URL + "/domains", Format: ThreatFormatDomains, Category: "phishing", Enabled: true},
+		{Name: "hashes", URL: srv.URL + "/hashes", Format: ThreatFormatSHA256, Category: "malware", Enabled: true},
+	})
+	if err := m.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if urls, domains, hosts := m.Count(); urls != 2 || domains != 2 || hosts != 1 {
+		t.Errorf("Count() = %d, %d, %d; want 2, 2, 1", urls, domains, hosts)
+	}
+
+	tests := []struct {
+		url         string
+		sensitivity string
+		want        string
+	}{
+		{"http://files.share.example/payload.exe", ThreatSensitivityLow, "url"},
+		{"https://hashed.example/a?b=1", ThreatSensitivityLow, "url"},
+		{"https://www.phish.example/", ThreatSensitivityLow, ""},
+		{"https://www.phish.example/", ThreatSensitivityMedium, "domain"},
+		{"https://login-bank.example/", "", "domain"},
+		{"http://files.share.example/readme", ThreatSensitivityMedium, ""},
+		{"http://files.share.example/readme", ThreatSensitivityHigh, "host"},
+		{"https://share.example/", ThreatSensitivityHigh, ""},
+		{"javascript:alert(1)", ThreatSensitivityHigh, ""},
+	}
+	for _, tt := range tests {
+		match, ok := m.Check(tt.url, tt.sensitivity)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Check(%q, %q) = %+v, want no match", tt.url, tt.sensitivity, match)
+			}
+			continue
+		}
+		if !ok || match.Scope != tt.want {
+			t.Errorf("Check(%q, %q) = %+v, %v; want scope %s", tt.url, tt.sensitivity, match, ok, tt.want)
+		}
+	}
+
+	if match, _ := m.Check("https://phish.example/", ThreatSensitivityMedium); match.Feed != "domains" || match.Category != "phishing" {
+		t.Errorf("match = %+v, want the domains feed", match)
+	}
+}
+
+// TestThreatFeedManager_Update_KeepsPreviousCopy verifies a failed download
+// leaves the last good copy of a feed in use.
+func TestThreatFeedManager_Update_KeepsPreviousCopy(t *testing.T) {
+	dataDir := t.TempDir()
+	srv := newThreatFeedServer(t, map[string]string{"/ok": "evil.example\n"})
+	feeds := []ThreatFeed{
+		{Name: "kept", URL: srv.URL + "/gone", Format: ThreatFormatDomains, Category: "malware", Enabled: true},
+		{Name: "fresh", URL: srv.URL + "/ok", Format: ThreatFormatDomains, Category: "malware", Enabled: true},
+	}
+
+	dir := filepath.Join(dataDir, "security", "threatfeeds")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kept.txt"), []byte("old.example\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewThreatFeedManager(dataDir, feeds)
+	if err := m.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v, want nil when one feed succeeds", err)
+	}
+	for _, u := range []string{"https://old.example/", "https://evil.example/"} {
+		if _, ok := m.Check(u, ThreatSensitivityMedium); !ok {
+			t.Errorf("Check(%q) = no match after a partial update", u)
+		}
+	}
+
+	feeds[1].URL = srv.URL + "/gone"
+	if err := NewThreatFeedManager(dataDir, feeds).Update(context.Background()); err == nil {
+		t.Error("Update() error = nil, want an error when every feed fails")
+	}
+
+	// A fresh manager sees the saved feeds
+	reloaded := NewThreatFeedManager(dataDir, feeds)
+	if err := reloaded.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if _, domains, _ := reloaded.Count(); domains != 2 || reloaded.UpdatedAt().IsZero() {
+		t.Errorf("LoadFromDisk() loaded %d domains, updated %v", domains, reloaded.UpdatedAt())
+	}
+}
+
+func TestNewThreatFeedManager_SkipsUnsafeNames(t *testing.T) {
+	m := NewThreatFeedManager(t.TempDir(), []ThreatFeed{
+		{Name: "../escape", URL: "http://127.0.0.1/", Format: ThreatFormatURLs, Enabled: true},
+		{Name: "ok_feed-1", URL: "http://127.0.0.1/", Format: ThreatFormatURLs, Enabled: true},
+	})
+	if len(m.feeds) != 1 || m.feeds[0].Name != "ok_feed-1" {
+		t.Errorf("feeds = %+v, want only ok_feed-1", m.feeds)
+	}
+}
+
+func TestCanonicalThreatURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"HTTPS://Example.COM":             "https://example.com/",
+		"https://example.com:443/a?q=1#f": "https://example.com/a?q=1",
+		"http://example.com:8080/a":       "http://example.com:8080/a",
+		"http://[2001:db8::1]:80/x":       "http://[2001:db8::1]/x",
+		"ftp://example.com/file":          "",
+		"https://":                        "",
+	} {
+		if got, _ := CanonicalThreatURL(in); got != want {
+			t.Errorf("CanonicalThreatURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
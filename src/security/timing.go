@@ -0,0 +1,159 @@
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuthFailureFloor is the least time a failed credential check takes to
+// answer. A request without credentials, with an unknown token or with a
+// token that only fails a database lookup all answer after the same delay,
+// so the time to a rejection tells nothing about why it was rejected.
+var AuthFailureFloor = 50 * time.Millisecond
+
+// TokenEqual reports whether presented is the expected secret. Both are
+// hashed with SHA-256 first, so the comparison takes the same time whatever
+// their lengths and wherever they differ. An empty expected secret matches
+// nothing.
+func TokenEqual(presented, expected string) bool {
+	if expected == "" {
+		return false
+	}
+	presentedSum := sha256.Sum256([]byte(presented))
+	expectedSum := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(presentedSum[:], expectedSum[:]) == 1
+}
+
+// HashEqual reports whether two digests, such as stored token hashes, are
+// equal, in time that does not depend on where they differ
+func HashEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// AuthTimer times a credential check so a failure answers no sooner than
+// AuthFailureFloor after the check started
+type AuthTimer struct {
+	start time.Time
+}
+
+// StartAuth starts timing a credential check
+func StartAuth() AuthTimer {
+	return AuthTimer{start: time.Now()}
+}
+
+// Fail waits out the rest of AuthFailureFloor, or until ctx is done, before
+// a failure is answered
+func (t AuthTimer) Fail(ctx context.Context) {
+	wait := AuthFailureFloor - time.Since(t.start)
+	if wait <= 0 {
+		return
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// SelfTestCheck is the outcome of one check of SelfTest
+type SelfTestCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// timingTolerance is how much slower than the fastest case another case
+// of a constant-time check may measure before the self-test fails it
+const timingTolerance = 1.5
+
+// SelfTest checks at runtime that secrets are compared in constant time
+// and that failed credential checks answer in uniform time. The timing
+// checks compare the fastest of several rounds of each case, which noise
+// only slows down, so a busy machine does not fail them.
+func SelfTest() []SelfTestCheck {
+	secret := "adm_" + strings.Repeat("k", 40)
+	almost := secret[:len(secret)-1] + "x"
+	checks := []SelfTestCheck{
+		selfTestResult("Token comparison", TokenEqual(secret, secret) &&
+			!TokenEqual(almost, secret) && !TokenEqual(secret[:8], secret) && !TokenEqual("", "") &&
+			HashEqual(secret, secret) && !HashEqual(almost, secret),
+			"equal, unequal, truncated and empty secrets"),
+	}
+
+	first, short := "x"+secret[1:], secret[:8]
+	checks = append(checks, TimingCheck("Token comparison timing", map[string]func(){
+		"first byte differs": func() { TokenEqual(first, secret) },
+		"last byte differs":  func() { TokenEqual(almost, secret) },
+		"shorter":            func() { TokenEqual(short, secret) },
+		"equal":              func() { TokenEqual(secret, secret) },
+	}))
+	hash := HashOperatorToken(secret)
+	hashFirst, hashLast := "0"+hash[1:], hash[:len(hash)-1]+"0"
+	checks = append(checks, TimingCheck("Hash comparison timing", map[string]func(){
+		"first byte differs": func() { HashEqual(hashFirst, hash) },
+		"last byte differs":  func() { HashEqual(hashLast, hash) },
+		"equal":              func() { HashEqual(hash, hash) },
+	}))
+
+	// A failure answered at once and one that first spent time on a
+	// lookup must both answer after the floor, and close to each other
+	fast := timeAuthFailure(0)
+	slow := timeAuthFailure(AuthFailureFloor / 2)
+	spread := fast - slow
+	if spread < 0 {
+		spread = -spread
+	}
+	checks = append(checks, selfTestResult("Uniform failure timing",
+		fast >= AuthFailureFloor && slow >= AuthFailureFloor && spread < AuthFailureFloor/5,
+		fmt.Sprintf("immediate failure %s, failure after a lookup %s, floor %s", fast.Round(time.Millisecond), slow.Round(time.Millisecond), AuthFailureFloor)))
+	return checks
+}
+
+func selfTestResult(name string, ok bool, detail string) SelfTestCheck {
+	return SelfTestCheck{Name: name, OK: ok, Detail: detail}
+}
+
+// TimingCheck checks that every case of a comparison, such as a wrong
+// secret differing in its first or its last byte, takes about as long as
+// the fastest
+func TimingCheck(name string, cases map[string]func()) SelfTestCheck {
+	const rounds, calls = 15, 2000
+	fastest := make(map[string]time.Duration, len(cases))
+	for range rounds {
+		for label, fn := range cases {
+			start := time.Now()
+			for range calls {
+				fn()
+			}
+			if took := time.Since(start); fastest[label] == 0 || took < fastest[label] {
+				fastest[label] = took
+			}
+		}
+	}
+	lo, hi := time.Duration(0), time.Duration(0)
+	var slowest string
+	for label, took := range fastest {
+		if lo == 0 || took < lo {
+			lo = took
+		}
+		if took > hi {
+			hi, slowest = took, label
+		}
+	}
+	ratio := float64(hi) / float64(max(lo, 1))
+	return selfTestResult(name, ratio < timingTolerance,
+		fmt.Sprintf("slowest case (%s) takes %.2fx the fastest", slowest, ratio))
+}
+
+// timeAuthFailure times a failure answered after spending work on the check
+func timeAuthFailure(work time.Duration) time.Duration {
+	timer := StartAuth()
+	time.Sleep(work)
+	timer.Fail(context.Background())
+	return time.Since(timer.start)
+}
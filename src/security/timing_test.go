@@ -0,0 +1,60 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenEqual(t *testing.T) {
+	tests := []struct {
+		presented, expected string
+		want                bool
+	}{
+		{"s3cret", "s3cret", true},
+		{"s3creT", "s3cret", false},
+		{"s3cret-and-more", "s3cret", false},
+		{"", "s3cret", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := TokenEqual(tt.presented, tt.expected); got != tt.want {
+			t.Errorf("TokenEqual(%q, %q) = %v", tt.presented, tt.expected, got)
+		}
+	}
+	if !HashEqual("abc", "abc") || HashEqual("abc", "abd") || HashEqual("abc", "ab") {
+		t.Error("HashEqual compares wrongly")
+	}
+}
+
+func TestAuthTimerFail(t *testing.T) {
+	floor := AuthFailureFloor
+	AuthFailureFloor = 30 * time.Millisecond
+	defer func() { AuthFailureFloor = floor }()
+
+	if took := timeAuthFailure(0); took < AuthFailureFloor {
+		t.Errorf("an immediate failure answered after %s, before the floor", took)
+	}
+	if took := timeAuthFailure(2 * AuthFailureFloor); took > 3*AuthFailureFloor {
+		t.Errorf("a failure past the floor waited again: %s", took)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	StartAuth().Fail(ctx)
+	if time.Since(start) >= AuthFailureFloor {
+		t.Error("a canceled request still waited out the floor")
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	for _, check := range SelfTest() {
+		if check.Name == "Token comparison" && !check.OK {
+			t.Errorf("%s: %s", check.Name, check.Detail)
+		}
+		if check.Detail == "" {
+			t.Errorf("%s has no detail", check.Name)
+		}
+	}
+}
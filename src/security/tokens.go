@@ -0,0 +1,390 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/database"
+)
+
+// OperatorTokenPrefix marks named operator tokens, so they are told apart
+// from the server.token at a glance and in secret scanners.
+const OperatorTokenPrefix = "adm_"
+
+// Operator token scopes. The server.token holds every scope; a named token
+// holds only the scopes it was issued with, and write scopes do not imply
+// read.
+const (
+	// ScopeRead allows the read-only operator endpoints: status, config,
+	// engine lists, Tor services and clients, audits and previews
+	ScopeRead = "read"
+	// ScopeConfigWrite allows changing server settings, such as Tor client
+	// authorization and feature flags
+	ScopeConfigWrite = "config:write"
+	// ScopeEnginesWrite allows changing per-category engine lists
+	ScopeEnginesWrite = "engines:write"
+	// ScopeBackups allows listing and creating backups
+	ScopeBackups = "backups"
+)
+
+// OperatorTokenScopes lists every scope a named token can hold
+var OperatorTokenScopes = []string{ScopeRead, ScopeConfigWrite, ScopeEnginesWrite, ScopeBackups}
+
+// maxOperatorTokenName bounds a token's name
+const maxOperatorTokenName = 64
+
+// dbTimeLayout is how token times are stored, matching CURRENT_TIMESTAMP
+const dbTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	// ErrOperatorTokenName is returned for an empty or overlong token name
+	ErrOperatorTokenName = errors.New("token name must be 1-64 characters")
+	// ErrOperatorTokenScope is returned for a token without scopes or with
+	// an unknown one
+	ErrOperatorTokenScope = errors.New("token scopes must be one or more of read, config:write, engines:write, backups")
+	// ErrOperatorTokenExpiry is returned for an expiry in the past
+	ErrOperatorTokenExpiry = errors.New("token expiry must be in the future")
+	// ErrOperatorTokenNotFound is returned when no live token matches
+	ErrOperatorTokenNotFound = errors.New("token not found")
+	// ErrOperatorTokenRateLimit is returned for a negative rate limit
+	ErrOperatorTokenRateLimit = errors.New("token rate limit must be 0 (unlimited) or more requests per minute")
+	// ErrOperatorTokenBoost is returned for a boost without extra requests
+	// or whose end is not in the future
+	ErrOperatorTokenBoost = errors.New("token boost must add requests per minute until a time in the future")
+)
+
+// DefaultOperatorTokenRateLimit is the requests per minute a named token
+// is allowed unless given its own limit
+const DefaultOperatorTokenRateLimit = 100
+
+// OperatorToken is a named operator token. Only the SHA-256 hash of the
+// token is stored; the token itself is shown once, when it is created.
+type OperatorToken struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// Zero for a token that never expires
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// Zero until the token is first used
+	LastUsed  time.Time `json:"last_used,omitzero"`
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+	// Requests per minute; 0 for unlimited
+	RateLimit int `json:"rate_limit"`
+	// Boost adds requests per minute until BoostUntil
+	Boost      int       `json:"boost,omitempty"`
+	BoostUntil time.Time `json:"boost_until,omitzero"`
+	// A frozen token is refused without being revoked, until FrozenUntil,
+	// or until it is unfrozen when FrozenUntil is zero
+	Frozen      bool      `json:"frozen,omitempty"`
+	FrozenUntil time.Time `json:"frozen_until,omitzero"`
+}
+
+// HasScope reports whether the token holds scope
+func (t *OperatorToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token has expired at now
+func (t *OperatorToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// FrozenAt reports whether the token is frozen at now
+func (t *OperatorToken) FrozenAt(now time.Time) bool {
+	return t.Frozen && (t.FrozenUntil.IsZero() || now.Before(t.FrozenUntil))
+}
+
+// LimitAt returns the token's requests per minute at now, its boost
+// included while it lasts; 0 means unlimited
+func (t *OperatorToken) LimitAt(now time.Time) int {
+	if t.RateLimit > 0 && t.Boost > 0 && now.Before(t.BoostUntil) {
+		return t.RateLimit + t.Boost
+	}
+	return t.RateLimit
+}
+
+// NormalizeOperatorTokenScopes lowercases, deduplicates and orders scopes,
+// rejecting unknown ones
+func NormalizeOperatorTokenScopes(scopes []string) ([]string, error) {
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(OperatorTokenScopes, scope) {
+			return nil, ErrOperatorTokenScope
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrOperatorTokenScope
+	}
+	slices.SortFunc(normalized, func(a, b string) int {
+		return slices.Index(OperatorTokenScopes, a) - slices.Index(OperatorTokenScopes, b)
+	})
+	return normalized, nil
+}
+
+// CreateOperatorToken issues a named token with scopes, expiring at
+// expiresAt (zero for never). It returns the raw token, which is not stored.
+func CreateOperatorToken(ctx context.Context, db *database.DB, name string, scopes []string, expiresAt, now time.Time) (string, *OperatorToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxOperatorTokenName {
+		return "", nil, ErrOperatorTokenName
+	}
+	scopes, err := NormalizeOperatorTokenScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return "", nil, ErrOperatorTokenExpiry
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("generate operator token: %w", err)
+	}
+	raw := OperatorTokenPrefix + hex.EncodeToString(buf)
+	token := &OperatorToken{
+		Name:      name,
+		Prefix:    raw[:len(OperatorTokenPrefix)+8],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+		CreatedAt: now.UTC().Truncate(time.Second),
+		RateLimit: DefaultOperatorTokenRateLimit,
+	}
+
+	var expires any
+	if !token.ExpiresAt.IsZero() {
+		expires = token.ExpiresAt.Format(dbTimeLayout)
+	}
+	table := database.ServerTableName(db, "api_tokens")
+	result, err := db.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (name, token_hash, token_prefix, permissions, rate_limit, active, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)`, table),
+		token.Name, HashOperatorToken(raw), token.Prefix, strings.Join(scopes, ","),
+		token.RateLimit, expires, token.CreatedAt.Format(dbTimeLayout))
+	if err != nil {
+		return "", nil, fmt.Errorf("insert operator token: %w", err)
+	}
+	if token.ID, err = result.LastInsertId(); err != nil {
+		return "", nil, fmt.Errorf("insert operator token: %w", err)
+	}
+	return raw, token, nil
+}
+
+// HashOperatorToken hashes a raw token for comparison against token_hash
+func HashOperatorToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// selectOperatorTokens selects the tokens, as t, with their boost and
+// freeze, for scanOperatorToken
+func selectOperatorTokens(db *database.DB) string {
+	return fmt.Sprintf(`SELECT t.id, t.name, t.token_prefix, t.permissions, t.active, t.expires_at, t.last_used, t.created_at,
+		t.rate_limit, l.boost, l.boost_until, l.frozen, l.frozen_until
+		FROM %s t LEFT JOIN %s l ON l.token_id = t.id`,
+		database.ServerTableName(db, "api_tokens"), database.ServerTableName(db, "api_token_limits"))
+}
+
+// scanOperatorToken reads a row selected with selectOperatorTokens
+func scanOperatorToken(row interface{ Scan(...any) error }) (*OperatorToken, error) {
+	var token OperatorToken
+	var scopes, expiresAt, lastUsed, createdAt, boostUntil, frozenUntil sql.NullString
+	var rateLimit, boost, frozen sql.NullInt64
+	var active int
+	if err := row.Scan(&token.ID, &token.Name, &token.Prefix, &scopes, &active, &expiresAt, &lastUsed, &createdAt,
+		&rateLimit, &boost, &boostUntil, &frozen, &frozenUntil); err != nil {
+		return nil, err
+	}
+	if scopes.String != "" {
+		token.Scopes = strings.Split(scopes.String, ",")
+	}
+	token.Revoked = active == 0
+	token.ExpiresAt = parseDBTime(expiresAt.String)
+	token.LastUsed = parseDBTime(lastUsed.String)
+	token.CreatedAt = parseDBTime(createdAt.String)
+	token.RateLimit = int(rateLimit.Int64)
+	if !rateLimit.Valid {
+		token.RateLimit = DefaultOperatorTokenRateLimit
+	}
+	token.Boost = int(boost.Int64)
+	token.BoostUntil = parseDBTime(boostUntil.String)
+	token.Frozen = frozen.Int64 != 0
+	token.FrozenUntil = parseDBTime(frozenUntil.String)
+	return &token, nil
+}
+
+// parseDBTime reads a stored time; SQLite drivers return either layout
+func parseDBTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC()
+	}
+	if parsed, err := time.Parse(dbTimeLayout, value); err == nil {
+		return parsed
+	}
+	return time.Time{}
+}
+
+// ListOperatorTokens returns every named token, revoked and expired ones
+// included, newest first
+func ListOperatorTokens(ctx context.Context, db *database.DB) ([]*OperatorToken, error) {
+	rows, err := db.Query(ctx, selectOperatorTokens(db)+` WHERE t.token_prefix LIKE ? ORDER BY t.id DESC`,
+		OperatorTokenPrefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("list operator tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*OperatorToken{}
+	for rows.Next() {
+		token, err := scanOperatorToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list operator tokens: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeOperatorToken revokes the token with id. The row is kept, so the
+// token still shows in the list with its last use.
+func RevokeOperatorToken(ctx context.Context, db *database.DB, id int64) error {
+	table := database.ServerTableName(db, "api_tokens")
+	result, err := db.Exec(ctx, fmt.Sprintf(`UPDATE %s SET active = 0 WHERE id = ? AND active = 1 AND token_prefix LIKE ?`, table),
+		id, OperatorTokenPrefix+"%")
+	if err != nil {
+		return fmt.Errorf("revoke operator token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrOperatorTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateOperatorToken returns the live token matching raw and
+// records its use. Unknown, revoked and expired tokens return
+// ErrOperatorTokenNotFound.
+func AuthenticateOperatorToken(ctx context.Context, db *database.DB, raw string, now time.Time) (*OperatorToken, error) {
+	if !strings.HasPrefix(raw, OperatorTokenPrefix) {
+		return nil, ErrOperatorTokenNotFound
+	}
+	table := database.ServerTableName(db, "api_tokens")
+	token, err := scanOperatorToken(db.QueryRow(ctx, selectOperatorTokens(db)+` WHERE t.token_hash = ?`,
+		HashOperatorToken(raw)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOperatorTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up operator token: %w", err)
+	}
+	if token.Revoked || token.Expired(now) {
+		return nil, ErrOperatorTokenNotFound
+	}
+
+	token.LastUsed = now.UTC().Truncate(time.Second)
+	if _, err := db.Exec(ctx, fmt.Sprintf(`UPDATE %s SET last_used = ? WHERE id = ?`, table),
+		token.LastUsed.Format(dbTimeLayout), token.ID); err != nil {
+		return nil, fmt.Errorf("record operator token use: %w", err)
+	}
+	return token, nil
+}
+
+// GetOperatorToken returns the named token with id, revoked or not
+func GetOperatorToken(ctx context.Context, db *database.DB, id int64) (*OperatorToken, error) {
+	token, err := scanOperatorToken(db.QueryRow(ctx, selectOperatorTokens(db)+` WHERE t.id = ? AND t.token_prefix LIKE ?`,
+		id, OperatorTokenPrefix+"%"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOperatorTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up operator token: %w", err)
+	}
+	return token, nil
+}
+
+// SetOperatorTokenRateLimit sets the requests per minute the live token
+// with id is allowed; 0 means unlimited
+func SetOperatorTokenRateLimit(ctx context.Context, db *database.DB, id int64, limit int) error {
+	if limit < 0 {
+		return ErrOperatorTokenRateLimit
+	}
+	table := database.ServerTableName(db, "api_tokens")
+	result, err := db.Exec(ctx, fmt.Sprintf(`UPDATE %s SET rate_limit = ? WHERE id = ? AND active = 1 AND token_prefix LIKE ?`, table),
+		limit, id, OperatorTokenPrefix+"%")
+	if err != nil {
+		return fmt.Errorf("set operator token rate limit: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrOperatorTokenNotFound
+	}
+	return nil
+}
+
+// BoostOperatorToken adds extra requests per minute to the live token with
+// id until until, replacing any earlier boost
+func BoostOperatorToken(ctx context.Context, db *database.DB, id int64, extra int, until, now time.Time) error {
+	if extra <= 0 || !until.After(now) {
+		return ErrOperatorTokenBoost
+	}
+	return setOperatorTokenLimits(ctx, db, id, "boost = excluded.boost, boost_until = excluded.boost_until",
+		extra, until.UTC().Format(dbTimeLayout), 0, nil)
+}
+
+// UnboostOperatorToken ends the live token's boost
+func UnboostOperatorToken(ctx context.Context, db *database.DB, id int64) error {
+	return setOperatorTokenLimits(ctx, db, id, "boost = 0, boost_until = NULL", 0, nil, 0, nil)
+}
+
+// FreezeOperatorToken refuses the live token with id until until, or until
+// it is unfrozen when until is zero, without revoking it
+func FreezeOperatorToken(ctx context.Context, db *database.DB, id int64, until, now time.Time) error {
+	var frozenUntil any
+	if !until.IsZero() {
+		if !until.After(now) {
+			return ErrOperatorTokenExpiry
+		}
+		frozenUntil = until.UTC().Format(dbTimeLayout)
+	}
+	return setOperatorTokenLimits(ctx, db, id, "frozen = 1, frozen_until = excluded.frozen_until",
+		0, nil, 1, frozenUntil)
+}
+
+// UnfreezeOperatorToken accepts the live token with id again
+func UnfreezeOperatorToken(ctx context.Context, db *database.DB, id int64) error {
+	return setOperatorTokenLimits(ctx, db, id, "frozen = 0, frozen_until = NULL", 0, nil, 0, nil)
+}
+
+// setOperatorTokenLimits inserts the live token's boost and freeze row, or
+// applies update to the existing one
+func setOperatorTokenLimits(ctx context.Context, db *database.DB, id int64, update string, boost int, boostUntil any, frozen int, frozenUntil any) error {
+	token, err := GetOperatorToken(ctx, db, id)
+	if err != nil {
+		return err
+	}
+	if token.Revoked {
+		return ErrOperatorTokenNotFound
+	}
+	table := database.ServerTableName(db, "api_token_limits")
+	if _, err := db.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (token_id, boost, boost_until, frozen, frozen_until) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(token_id) DO UPDATE SET %s`, table, update),
+		id, boost, boostUntil, frozen, frozenUntil); err != nil {
+		return fmt.Errorf("update operator token limits: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/database"
+)
+
+func newTokenTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	dm, err := database.NewDatabaseManager(&database.Config{
+		Driver:   "sqlite",
+		DataDir:  t.TempDir(),
+		MaxOpen:  5,
+		MaxIdle:  2,
+		Lifetime: 60,
+	})
+	if err != nil {
+		t.Fatalf("NewDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	if err := database.InitSchema(context.Background(), dm); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+	return dm.ServerDB()
+}
+
+func TestNormalizeOperatorTokenScopes(t *testing.T) {
+	got, err := NormalizeOperatorTokenScopes([]string{"backups", " READ ", "read"})
+	if err != nil || strings.Join(got, ",") != "read,backups" {
+		t.Errorf("NormalizeOperatorTokenScopes() = %v, %v; want [read backups]", got, err)
+	}
+	for _, scopes := range [][]string{nil, {"admin"}} {
+		if _, err := NormalizeOperatorTokenScopes(scopes); !errors.Is(err, ErrOperatorTokenScope) {
+			t.Errorf("NormalizeOperatorTokenScopes(%v) error = %v, want ErrOperatorTokenScope", scopes, err)
+		}
+	}
+}
+
+func TestOperatorTokenLifecycle(t *testing.T) {
+	ctx := context.Background()
+	db := newTokenTestDB(t)
+	now := time.Now()
+
+	raw, token, err := CreateOperatorToken(ctx, db, "ci", []string{ScopeEnginesWrite}, now.Add(time.Hour), now)
+	if err != nil {
+		t.Fatalf("CreateOperatorToken: %v", err)
+	}
+	if !strings.HasPrefix(raw, OperatorTokenPrefix) || !strings.HasPrefix(raw, token.Prefix) {
+		t.Errorf("token %q does not start with %q", raw, token.Prefix)
+	}
+
+	got, err := AuthenticateOperatorToken(ctx, db, raw, now)
+	if err != nil || got.Name != "ci" || !got.HasScope(ScopeEnginesWrite) || got.HasScope(ScopeRead) {
+		t.Fatalf("AuthenticateOperatorToken() = %+v, %v", got, err)
+	}
+	if _, err := AuthenticateOperatorToken(ctx, db, raw, now.Add(2*time.Hour)); !errors.Is(err, ErrOperatorTokenNotFound) {
+		t.Errorf("expired token error = %v, want ErrOperatorTokenNotFound", err)
+	}
+	if _, err := AuthenticateOperatorToken(ctx, db, OperatorTokenPrefix+"unknown", now); !errors.Is(err, ErrOperatorTokenNotFound) {
+		t.Errorf("unknown token error = %v, want ErrOperatorTokenNotFound", err)
+	}
+
+	tokens, err := ListOperatorTokens(ctx, db)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsed.IsZero() {
+		t.Fatalf("ListOperatorTokens() = %+v, %v; want one used token", tokens, err)
+	}
+
+	if err := RevokeOperatorToken(ctx, db, token.ID); err != nil {
+		t.Fatalf("RevokeOperatorToken: %v", err)
+	}
+	if _, err := AuthenticateOperatorToken(ctx, db, raw, now); !errors.Is(err, ErrOperatorTokenNotFound) {
+		t.Errorf("revoked token error = %v, want ErrOperatorTokenNotFound", err)
+	}
+	if err := RevokeOperatorToken(ctx, db, token.ID); !errors.Is(err, ErrOperatorTokenNotFound) {
+		t.Errorf("second revoke error = %v, want ErrOperatorTokenNotFound", err)
+	}
+}
+
+func TestCreateOperatorTokenValidation(t *testing.T) {
+	ctx := context.Background()
+	db := newTokenTestDB(t)
+	now := time.Now()
+
+	if _, _, err := CreateOperatorToken(ctx, db, " ", []string{ScopeRead}, time.Time{}, now); !errors.Is(err, ErrOperatorTokenName) {
+		t.Errorf("empty name error = %v, want ErrOperatorTokenName", err)
+	}
+	if _, _, err := CreateOperatorToken(ctx, db, "old", []string{ScopeRead}, now.Add(-time.Minute), now); !errors.Is(err, ErrOperatorTokenExpiry) {
+		t.Errorf("past expiry error = %v, want ErrOperatorTokenExpiry", err)
+	}
+}
+
+func TestOperatorTokenLimits(t *testing.T) {
+	ctx := context.Background()
+	db := newTokenTestDB(t)
+	now := time.Now()
+
+	raw, token, err := CreateOperatorToken(ctx, db, "ci", []string{ScopeRead}, time.Time{}, now)
+	if err != nil {
+		t.Fatalf("CreateOperatorToken: %v", err)
+	}
+	if token.RateLimit != DefaultOperatorTokenRateLimit {
+		t.Errorf("rate limit = %d, want the default", token.RateLimit)
+	}
+	if err := SetOperatorTokenRateLimit(ctx, db, token.ID, 10); err != nil {
+		t.Fatalf("SetOperatorTokenRateLimit: %v", err)
+	}
+	if err := SetOperatorTokenRateLimit(ctx, db, token.ID, -1); !errors.Is(err, ErrOperatorTokenRateLimit) {
+		t.Errorf("negative rate limit error = %v", err)
+	}
+	if err := BoostOperatorToken(ctx, db, token.ID, 5, now.Add(-time.Minute), now); !errors.Is(err, ErrOperatorTokenBoost) {
+		t.Errorf("past boost error = %v", err)
+	}
+	if err := BoostOperatorToken(ctx, db, token.ID, 5, now.Add(time.Hour), now); err != nil {
+		t.Fatalf("BoostOperatorToken: %v", err)
+	}
+	if err := FreezeOperatorToken(ctx, db, token.ID, time.Time{}, now); err != nil {
+		t.Fatalf("FreezeOperatorToken: %v", err)
+	}
+
+	// A frozen token still authenticates; the server refuses it
+	got, err := AuthenticateOperatorToken(ctx, db, raw, now)
+	if err != nil {
+		t.Fatalf("AuthenticateOperatorToken: %v", err)
+	}
+	if got.LimitAt(now) != 15 || got.LimitAt(now.Add(2*time.Hour)) != 10 || !got.FrozenAt(now.Add(24*time.Hour)) {
+		t.Errorf("token = %+v", got)
+	}
+
+	if err := UnfreezeOperatorToken(ctx, db, token.ID); err != nil {
+		t.Fatalf("UnfreezeOperatorToken: %v", err)
+	}
+	if err := UnboostOperatorToken(ctx, db, token.ID); err != nil {
+		t.Fatalf("UnboostOperatorToken: %v", err)
+	}
+	got, err = GetOperatorToken(ctx, db, token.ID)
+	if err != nil || got.FrozenAt(now) || got.LimitAt(now) != 10 {
+		t.Errorf("GetOperatorToken() = %+v, %v", got, err)
+	}
+
+	if err := RevokeOperatorToken(ctx, db, token.ID); err != nil {
+		t.Fatalf("RevokeOperatorToken: %v", err)
+	}
+	if err := FreezeOperatorToken(ctx, db, token.ID, time.Time{}, now); !errors.Is(err, ErrOperatorTokenNotFound) {
+		t.Errorf("freeze revoked token error = %v, want ErrOperatorTokenNotFound", err)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/a11y"
+	"github.com/apimgr/search/src/model"
+)
+
+// accessibilityPages are the public pages rendered by the accessibility
+// self-check. The last path does not exist, so the 404 page is checked too.
+var accessibilityPages = []string{
+	"/",
+	"/preferences",
+	"/server/about",
+	"/server/privacy",
+	"/server/help",
+	"/server/terms",
+	"/server/docs",
+	"/server/contact",
+	"/server/security",
+	"/alerts/new",
+	"/server/a11y-check-not-found",
+}
+
+// handleAccessibilityAudit renders the public templates and reports basic
+// accessibility lint (landmarks, alt text, labels, contrast), gated by
+// operator token. Per IDEA.md there is no admin UI; this is the self-check.
+func (s *Server) handleAccessibilityAudit(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.accessibilityReport(r),
+	})
+}
+
+// accessibilityReport lints each page in accessibilityPages, a sample search
+// results page per layout, and the theme palettes in common.css
+func (s *Server) accessibilityReport(r *http.Request) *a11y.Report {
+	report := a11y.NewReport()
+
+	for _, path := range accessibilityPages {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, path, nil)
+		if err != nil {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+		req.Host = r.Host
+		req.RemoteAddr = r.RemoteAddr
+		req.Header.Set("Accept", "text/html")
+
+		rec := newPageRecorder()
+		s.router.ServeHTTP(rec, req)
+		s.lintRecorded(report, path, rec)
+	}
+
+	// Search pages need results; render them from sample data rather than
+	// querying engines
+	for _, category := range []model.Category{model.CategoryGeneral, model.CategoryImages} {
+		path := "/search?q=accessibility&category=" + string(category)
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, path, nil)
+		if err != nil {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+		rec := newPageRecorder()
+		rec.header.Set("Content-Type", "text/html; charset=utf-8")
+		data := s.buildSearchPageData(rec, req, "accessibility", sampleSearchResults(category), string(category), nil)
+		if err := s.renderer.Render(rec, "search", data); err != nil {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+		s.lintRecorded(report, path, rec)
+	}
+
+	if css, err := fs.ReadFile(EmbeddedFS, "static/css/common.css"); err == nil {
+		report.Add(a11y.CheckPalettes("static/css/common.css", a11y.ParsePalettes(string(css)), ThemeContrast)...)
+	}
+	return report
+}
+
+func (s *Server) lintRecorded(report *a11y.Report, path string, rec *pageRecorder) {
+	if rec.status >= http.StatusInternalServerError || !strings.HasPrefix(rec.header.Get("Content-Type"), "text/html") {
+		report.Skipped = append(report.Skipped, path)
+		return
+	}
+	issues, err := a11y.LintPage(path, &rec.body)
+	if err != nil {
+		report.Skipped = append(report.Skipped, path)
+		return
+	}
+	report.Pages = append(report.Pages, path)
+	report.Add(issues...)
+}
+
+// sampleSearchResults builds a results page that exercises the result
+// templates, including thumbnails for media categories
+func sampleSearchResults(category model.Category) *model.SearchResults {
+	results := model.NewSearchResults("accessibility", category)
+	for _, sample := range []struct{ title, url string }{
+		{"Web Content Accessibility Guidelines", "https://www.w3.org/TR/WCAG22/"},
+		{"Accessibility", "https://developer.mozilla.org/en-US/docs/Web/Accessibility"},
+	} {
+		results.AddResult(model.Result{
+			Title:     sample.title,
+			URL:       sample.url,
+			Content:   "Sample result rendered by the accessibility self-check.",
+			Engine:    "sample",
+			Category:  category,
+			Thumbnail: "/static/img/favicon.svg",
+		})
+	}
+	results.TotalResults = len(results.Results)
+	results.Engines = []string{"sample"}
+	return results
+}
+
+// pageRecorder captures a rendered page in memory for linting
+type pageRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newPageRecorder() *pageRecorder {
+	return &pageRecorder{header: make(http.Header)}
+}
+
+func (p *pageRecorder) Header() http.Header { return p.header }
+
+func (p *pageRecorder) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+func (p *pageRecorder) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	// Sniff like net/http does for handlers that never set a type
+	if p.header.Get("Content-Type") == "" {
+		p.header.Set("Content-Type", http.DetectContentType(b))
+	}
+	return p.body.Write(b)
+}
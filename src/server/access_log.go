@@ -0,0 +1,56 @@
+package server
+
+import (
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/logging"
+)
+
+// configureAccessLog applies server.logs.access to the access logger
+func configureAccessLog(access *logging.AccessLogger, cfg config.AccessLogConfig) {
+	access.SetEnabled(!cfg.Disabled)
+	access.SetPrivacy(cfg.Privacy)
+	if cfg.Format == "custom" {
+		access.SetCustomFormat(cfg.Custom)
+	} else {
+		access.SetFormat(cfg.Format)
+	}
+}
+
+// rotationDue reports whether a log of size bytes is due to rotate under
+// rot at now. It is asked once a day, at midnight: daily rotates every
+// time, weekly on Mondays and monthly on the 1st.
+func rotationDue(rot config.LogRotation, now time.Time, size int64) bool {
+	if size == 0 {
+		return false
+	}
+	switch {
+	case rot.Size > 0 && size >= rot.Size:
+		return true
+	case rot.Period == "daily":
+		return true
+	case rot.Period == "weekly":
+		return now.Weekday() == time.Monday
+	case rot.Period == "monthly":
+		return now.Day() == 1
+	}
+	return false
+}
+
+// rotateAccessLog rotates the access log when server.logs.access.rotate
+// says it is due and reports whether it did
+func (s *Server) rotateAccessLog(now time.Time) (bool, error) {
+	if s.logManager == nil {
+		return false, nil
+	}
+	rot, err := config.ParseLogRotation(s.config.Server.Logs.Access.Rotate)
+	if err != nil {
+		return false, err
+	}
+	access := s.logManager.Access()
+	if !rotationDue(rot, now, access.Size()) {
+		return false, nil
+	}
+	return true, access.Rotate()
+}
@@ -37,6 +37,8 @@ type AlertCreatedPageData struct {
 	Alert     *alert.Alert
 	ManageURL string
 	RSSURL    string
+	// Shown once, so webhook receivers can verify deliveries
+	WebhookSecret string
 }
 
 type AlertManagePageData struct {
@@ -148,10 +150,11 @@ func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
 	baseData := s.newPageData(w, r, "", "alerts-created")
 	baseData.Title = s.getI18nManager().T(baseData.Lang, "alerts.created_title")
 	data := &AlertCreatedPageData{
-		PageData:  *baseData,
-		Alert:     created.Alert,
-		ManageURL: s.getBaseURL(r) + "/alerts/manage/" + created.ManageToken,
-		RSSURL:    s.getBaseURL(r) + "/alerts/" + created.RSSToken + ".rss",
+		PageData:      *baseData,
+		Alert:         created.Alert,
+		ManageURL:     s.getBaseURL(r) + "/alerts/manage/" + created.ManageToken,
+		RSSURL:        s.getBaseURL(r) + "/alerts/" + created.RSSToken + ".rss",
+		WebhookSecret: created.WebhookSecret,
 	}
 	if err := s.renderer.Render(w, "alerts-created", data); err != nil {
 		slog.Error("template render error", "err", err)
@@ -5,28 +5,38 @@
 //
 //  1. Operator/server token — configured via server.token in server.yml
 //     (auto-generated on first run). Sent as: Authorization: Bearer <token>
-//     Compared via SHA-256 + subtle.ConstantTimeCompare.
+//     Compared in constant time by security.TokenEqual.
 //
-//  2. Per-resource owner tokens — stored as SHA-256 in the api_tokens table.
-//     (Handled at the resource layer, not here.)
+//  2. Named operator tokens — "adm_" tokens stored as SHA-256 in the
+//     api_tokens table, each with scopes and an optional expiry. They are
+//     accepted by endpoints wrapped in RequireScope that name one of their
+//     scopes, up to their rate limit of requests per minute unless frozen;
+//     only the server.token itself can manage them.
 //
-// All API mutations that require operator privilege must call ValidateOperatorToken.
+// With server.jwt.enabled, either can be exchanged for a short-lived JWT
+// that stands in for it, checked by signature alone (see operator_jwt.go).
+//
+// All API mutations that require operator privilege must go through
+// RequireOperator or RequireScope. A rejected credential answers after
+// security.AuthFailureFloor, however far its check got.
 package server
 
 import (
-	"crypto/sha256"
-	"crypto/subtle"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/apimgr/search/src/common/httputil"
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/logging"
+	"github.com/apimgr/search/src/security"
 )
 
 // ValidateOperatorToken returns true when the request carries a valid operator
-// token in the Authorization header. Comparison is constant-time over the
-// SHA-256 digests so that timing leaks do not reveal token bytes.
+// token in the Authorization header. The comparison is constant-time, so
+// its timing does not reveal token bytes.
 //
 // Returns false (without error) if:
 //   - server.token is empty in the configuration (no operator configured)
@@ -46,9 +56,7 @@ func ValidateOperatorToken(r *http.Request, cfg *config.Config) bool {
 		return false
 	}
 
-	expectedSum := sha256.Sum256([]byte(expected))
-	presentedSum := sha256.Sum256([]byte(presented))
-	return subtle.ConstantTimeCompare(expectedSum[:], presentedSum[:]) == 1
+	return security.TokenEqual(presented, expected)
 }
 
 // extractBearerToken pulls the token value from an Authorization header of the
@@ -70,22 +78,57 @@ func extractBearerToken(r *http.Request) (string, bool) {
 }
 
 // RequireOperator wraps an http.HandlerFunc and rejects requests that do not
-// present a valid operator token. Per AI.md PART 10: logs auth attempts to audit log.
+// present the operator token itself; named operator tokens are not enough.
+// Per AI.md PART 10: logs auth attempts to audit log.
 func (s *Server) RequireOperator(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireOperatorScope("", next)
+}
+
+// RequireScope wraps an http.HandlerFunc and rejects requests that present
+// neither the operator token nor a live named operator token holding scope.
+func (s *Server) RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return s.requireOperatorScope(scope, next)
+}
+
+func (s *Server) requireOperatorScope(scope string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		timer := security.StartAuth()
 		clientIP := getClientIPSimple(r)
-		if !ValidateOperatorToken(r, s.config) {
+		actor := logging.AuditActor{Type: "operator", IP: clientIP, UserAgent: r.UserAgent()}
+		status := 0
+		var token *security.OperatorToken
+		operator := ValidateOperatorToken(r, s.config)
+		if !operator {
+			if claims := s.operatorJWT(r); claims == nil {
+				token = s.namedOperatorToken(r)
+			} else if claims.Operator() {
+				operator = true
+				actor.ID = claims.ID
+			} else {
+				token = claims.Token()
+			}
+		}
+		if !operator {
+			switch {
+			case token == nil:
+				actor.Type = "anonymous"
+				status = http.StatusUnauthorized
+			case scope == "" || !token.HasScope(scope):
+				actor = logging.AuditActor{Type: "operator_token", ID: token.Prefix, Username: token.Name, IP: clientIP, UserAgent: r.UserAgent()}
+				status = http.StatusForbidden
+			default:
+				actor = logging.AuditActor{Type: "operator_token", ID: token.Prefix, Username: token.Name, IP: clientIP, UserAgent: r.UserAgent()}
+			}
+		}
+
+		if status != 0 {
 			// Log failed auth attempt to audit log
 			if s.logManager != nil && s.logManager.Audit() != nil {
 				s.logManager.Audit().Log(logging.AuditEntry{
 					Event:    logging.AuditActionInvalidToken,
 					Category: logging.AuditCategorySecurity,
 					Severity: logging.AuditSeverityWarning,
-					Actor: logging.AuditActor{
-						Type:      "anonymous",
-						IP:        clientIP,
-						UserAgent: r.UserAgent(),
-					},
+					Actor:    actor,
 					Target: &logging.AuditTarget{
 						Type: "endpoint",
 						ID:   r.URL.Path,
@@ -93,24 +136,30 @@ func (s *Server) RequireOperator(next http.HandlerFunc) http.HandlerFunc {
 					Result: "failure",
 					Details: map[string]any{
 						"method": r.Method,
+						"scope":  scope,
 					},
 				})
 			}
+			timer.Fail(r.Context())
+			if status == http.StatusForbidden {
+				localizedHTTPError(w, r, http.StatusForbidden, "errors.forbidden")
+				return
+			}
 			w.Header().Set("WWW-Authenticate", `Bearer realm="operator"`)
 			localizedHTTPError(w, r, http.StatusUnauthorized, "errors.unauthorized")
 			return
 		}
+		// Named tokens are held to their rate limit and freeze
+		if token != nil && !s.allowOperatorToken(w, r, token) {
+			return
+		}
 		// Log successful auth to audit log
 		if s.logManager != nil && s.logManager.Audit() != nil {
 			s.logManager.Audit().Log(logging.AuditEntry{
 				Event:    logging.AuditActionConfigChange,
 				Category: logging.AuditCategorySecurity,
 				Severity: logging.AuditSeverityInfo,
-				Actor: logging.AuditActor{
-					Type:      "operator",
-					IP:        clientIP,
-					UserAgent: r.UserAgent(),
-				},
+				Actor:    actor,
 				Target: &logging.AuditTarget{
 					Type: "endpoint",
 					ID:   r.URL.Path,
@@ -125,6 +174,26 @@ func (s *Server) RequireOperator(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// namedOperatorToken returns the live named operator token the request
+// presents, recording its use, or nil
+func (s *Server) namedOperatorToken(r *http.Request) *security.OperatorToken {
+	presented, ok := extractBearerToken(r)
+	if !ok || !strings.HasPrefix(presented, security.OperatorTokenPrefix) {
+		return nil
+	}
+	if s.dbManager == nil || s.dbManager.ServerDB() == nil {
+		return nil
+	}
+	token, err := security.AuthenticateOperatorToken(r.Context(), s.dbManager.ServerDB(), presented, time.Now())
+	if err != nil {
+		if !errors.Is(err, security.ErrOperatorTokenNotFound) {
+			slog.Error("operator token lookup failed", "err", err)
+		}
+		return nil
+	}
+	return token
+}
+
 // getClientIPSimple extracts the client IP address from a request.
 // Delegates to httputil.GetClientIP which applies the trusted-proxy gate per AI.md PART 12.
 // Priority (from trusted proxy only): CF-Connecting-IP → True-Client-IP → X-Real-IP → X-Forwarded-For → X-Client-IP → RemoteAddr
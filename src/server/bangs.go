@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/search/bang"
+)
+
+// maxBangImportBytes caps an import body; DuckDuckGo's whole bang list is
+// a little over 3 MiB
+const maxBangImportBytes = 16 << 20
+
+// loadCustomBangs hands the bang manager the custom bangs of server.yml
+// and those stored in the server DB. A stored bang wins over a server.yml
+// one with the same shortcut.
+func (s *Server) loadCustomBangs(ctx context.Context) error {
+	custom := s.config.Search.Bangs.Custom
+	bangs := make([]*bang.Bang, 0, len(custom))
+	for _, bc := range custom {
+		bangs = append(bangs, &bang.Bang{
+			Shortcut:    bc.Shortcut,
+			Name:        bc.Name,
+			URL:         bc.URL,
+			Category:    bc.Category,
+			Description: bc.Description,
+			Aliases:     bc.Aliases,
+		})
+	}
+	stored, err := s.bangs.List(ctx)
+	bangs = append(bangs, stored...)
+	s.bangManager.SetCustomBangs(bangs)
+	return err
+}
+
+// reloadCustomBangs is loadCustomBangs after a change made through the API
+func (s *Server) reloadCustomBangs(ctx context.Context) {
+	if err := s.loadCustomBangs(ctx); err != nil {
+		slog.Warn("custom bangs not reloaded", "err", err)
+	}
+}
+
+// recordBangUse counts a bang's use in the background, like the engine
+// quota; the query itself is never stored
+func (s *Server) recordBangUse(shortcut string) {
+	if s.bangs == nil {
+		return
+	}
+	at := time.Now().UTC()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.bangs.RecordUse(ctx, shortcut, at); err != nil {
+			slog.Warn("bang usage not saved", "bang", shortcut, "err", err)
+		}
+	}()
+}
+
+// handleCustomBangs lists the custom bangs stored in the server DB. Those
+// of server.yml are not listed; they are edited in the file.
+// Per IDEA.md there is no admin UI; this API is the bang editor.
+func (s *Server) handleCustomBangs(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	bangs, err := s.bangs.List(r.Context())
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": bangs})
+}
+
+// handleCustomBangCreate stores a custom bang. The body is a bang:
+// {"shortcut": "...", "name": "...", "url": "...{query}...", "category":
+// "...", "description": "...", "icon": "...", "aliases": [...]}.
+func (s *Server) handleCustomBangCreate(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	var req bang.Bang
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	b, err := s.bangs.Create(r.Context(), req)
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	s.reloadCustomBangs(r.Context())
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogBangChanged("operator", getClientIPSimple(r), true, b.Shortcut)
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{"ok": true, "data": b})
+}
+
+// handleCustomBangUpdate replaces a custom bang; a new shortcut renames it
+func (s *Server) handleCustomBangUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	var req bang.Bang
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	b, err := s.bangs.Update(r.Context(), chi.URLParam(r, "shortcut"), req)
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	s.reloadCustomBangs(r.Context())
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogBangChanged("operator", getClientIPSimple(r), true, b.Shortcut)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": b})
+}
+
+// handleCustomBangDelete removes a custom bang; a built-in bang it
+// overrode answers again at once. The bang moves to the trash, from which
+// it can be restored.
+func (s *Server) handleCustomBangDelete(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	b, err := s.bangs.Delete(r.Context(), chi.URLParam(r, "shortcut"))
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	s.moveToTrash(r.Context(), trashKindBang, b.Shortcut, b)
+	s.reloadCustomBangs(r.Context())
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogBangChanged("operator", getClientIPSimple(r), false, b.Shortcut)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": b})
+}
+
+// handleBangTrash lists deleted custom bangs that can still be restored
+func (s *Server) handleBangTrash(w http.ResponseWriter, r *http.Request) {
+	s.respondTrash(w, r, trashKindBang)
+}
+
+// handleBangRestore stores the most recently deleted bang with a shortcut
+// again, unless another stored bang has taken the shortcut since
+func (s *Server) handleBangRestore(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	shortcut, _ := bang.NormalizeShortcut(chi.URLParam(r, "shortcut"))
+	item := s.trashItem(w, r, trashKindBang, shortcut)
+	if item == nil {
+		return
+	}
+	var deleted bang.Bang
+	if err := item.Decode(&deleted); err != nil {
+		respondBangError(w, err)
+		return
+	}
+	if _, err := s.bangs.Get(r.Context(), deleted.Shortcut); err == nil {
+		respondError(w, http.StatusConflict, "A stored bang has this shortcut")
+		return
+	}
+	b, err := s.bangs.Create(r.Context(), deleted)
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	s.restoredFromTrash(r.Context(), item)
+	s.reloadCustomBangs(r.Context())
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogBangChanged("operator", getClientIPSimple(r), true, b.Shortcut)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": b})
+}
+
+// handleBangsExport downloads the custom bangs as a JSON array in
+// DuckDuckGo's bang format, each ranked by its use count
+func (s *Server) handleBangsExport(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	bangs, err := s.bangs.List(r.Context())
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	uses, err := s.bangs.Counts(r.Context())
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="bangs.json"`)
+	respondJSON(w, http.StatusOK, bang.ExportDDG(bangs, uses))
+}
+
+// handleBangsImport stores the bangs of a JSON array in DuckDuckGo's
+// format or this server's, merging them into the custom bangs or, with
+// ?replace=true, replacing all of them; the bangs a replacing import
+// removes go to the trash
+func (s *Server) handleBangsImport(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBangImportBytes))
+	if err != nil {
+		respondError(w, http.StatusRequestEntityTooLarge, "Import is too large")
+		return
+	}
+	bangs, err := bang.ParseImport(data)
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	replace, _ := strconv.ParseBool(r.URL.Query().Get("replace"))
+	var before []*bang.Bang
+	if replace {
+		if before, err = s.bangs.List(r.Context()); err != nil {
+			respondBangError(w, err)
+			return
+		}
+	}
+	result, err := s.bangs.Import(r.Context(), bangs, replace)
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	// A replacing import deletes the bangs it does not bring back; they go
+	// to the trash like any other deleted bang
+	if len(before) > 0 {
+		kept, err := s.bangs.List(r.Context())
+		if err == nil {
+			stored := make(map[string]bool, len(kept))
+			for _, b := range kept {
+				stored[b.Shortcut] = true
+			}
+			for _, b := range before {
+				if !stored[b.Shortcut] {
+					s.moveToTrash(r.Context(), trashKindBang, b.Shortcut, b)
+				}
+			}
+		}
+	}
+	s.reloadCustomBangs(r.Context())
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogBangsImported("operator", getClientIPSimple(r), result.Imported, result.Skipped, replace)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": result})
+}
+
+// handleBangUsage lists the most used bangs, built-in or custom, with
+// their use counts and when each was last used
+func (s *Server) handleBangUsage(w http.ResponseWriter, r *http.Request) {
+	if s.bangs == nil {
+		respondError(w, http.StatusServiceUnavailable, "Bang storage is unavailable")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	usage, err := s.bangs.Usage(r.Context(), limit)
+	if err != nil {
+		respondBangError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": usage})
+}
+
+func respondBangError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, bang.ErrNotFound):
+		respondError(w, http.StatusNotFound, "Bang not found")
+	case errors.Is(err, bang.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		slog.Error("bang request failed", "err", err)
+		respondError(w, http.StatusInternalServerError, "Bang request failed")
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database/dbtest"
+	"github.com/apimgr/search/src/search/bang"
+	"github.com/apimgr/search/src/trash"
+)
+
+func TestCustomBangHandlers(t *testing.T) {
+	db := dbtest.Server(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Search.Bangs.Custom = []config.BangConfig{{Shortcut: "yml", Name: "From server.yml", URL: "https://yml.example/?q={query}"}}
+	s := &Server{config: cfg, bangManager: bang.NewManager(), bangs: bang.NewStore(db, ""), trash: trash.NewStore(db, "")}
+	if err := s.loadCustomBangs(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/bangs", s.handleCustomBangCreate)
+	r.Get("/bangs/export", s.handleBangsExport)
+	r.Post("/bangs/import", s.handleBangsImport)
+	r.Put("/bangs/{shortcut}", s.handleCustomBangUpdate)
+	r.Delete("/bangs/{shortcut}", s.handleCustomBangDelete)
+	r.Get("/bangs/trash", s.handleBangTrash)
+	r.Post("/bangs/trash/{shortcut}/restore", s.handleBangRestore)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(http.MethodPost, "/bangs", `{"shortcut":"intra","url":"https://intra.example/?q={query}"}`); w.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/bangs", `{"shortcut":"bad","url":"ftp://intra.example/"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid bang = %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/bangs/import", `[{"t":"gopkg","s":"Go Packages","u":"https://pkg.go.dev/search?q={{{s}}}","c":"Tech"}]`); w.Code != http.StatusOK {
+		t.Fatalf("import = %d %s", w.Code, w.Body)
+	}
+
+	// Stored bangs answer at once, next to those of server.yml
+	for query, want := range map[string]string{
+		"!intra wiki":   "https://intra.example/?q=wiki",
+		"!gopkg chi":    "https://pkg.go.dev/search?q=chi",
+		"!yml settings": "https://yml.example/?q=settings",
+	} {
+		if res := s.bangManager.Parse(query); res == nil || res.TargetURL != want {
+			t.Errorf("Parse(%q) = %+v, want %s", query, res, want)
+		}
+	}
+
+	w := do(http.MethodGet, "/bangs/export", "")
+	var exported []bang.DDGBang
+	if err := json.NewDecoder(w.Body).Decode(&exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 2 || exported[0].Trigger != "gopkg" || exported[0].URL != "https://pkg.go.dev/search?q={{{s}}}" {
+		t.Errorf("export = %+v", exported)
+	}
+
+	if w := do(http.MethodPut, "/bangs/intra", `{"shortcut":"in","url":"https://intra.example/search?q={query}"}`); w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	if s.bangManager.Parse("!intra x") != nil || s.bangManager.Parse("!in x") == nil {
+		t.Error("renamed bang not reloaded")
+	}
+	if w := do(http.MethodDelete, "/bangs/in", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete = %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodDelete, "/bangs/in", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second delete = %d", w.Code)
+	}
+	if s.bangManager.Parse("!in x") != nil {
+		t.Error("deleted bang still answers")
+	}
+
+	// A deleted bang waits in the trash and can be restored once
+	if w := do(http.MethodGet, "/bangs/trash", ""); !strings.Contains(w.Body.String(), `"key":"in"`) {
+		t.Errorf("trash = %s", w.Body)
+	}
+	if w := do(http.MethodPost, "/bangs/trash/!IN/restore", ""); w.Code != http.StatusOK {
+		t.Fatalf("restore = %d %s", w.Code, w.Body)
+	}
+	if res := s.bangManager.Parse("!in x"); res == nil || res.TargetURL != "https://intra.example/search?q=x" {
+		t.Errorf("restored bang = %+v", res)
+	}
+	if w := do(http.MethodPost, "/bangs/trash/in/restore", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second restore = %d", w.Code)
+	}
+
+	// So do the bangs a replacing import removes
+	if w := do(http.MethodPost, "/bangs/import?replace=true", `[{"t":"mdn","u":"https://developer.mozilla.org/search?q={{{s}}}"}]`); w.Code != http.StatusOK {
+		t.Fatalf("replacing import = %d %s", w.Code, w.Body)
+	}
+	items, err := s.trash.List(t.Context(), trashKindBang)
+	if err != nil || len(items) != 2 {
+		t.Errorf("trash after a replacing import = %+v, %v; want in and gopkg", items, err)
+	}
+	if w := do(http.MethodPost, "/bangs", `{"shortcut":"gopkg","url":"https://other.example/?q={query}"}`); w.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/bangs/trash/gopkg/restore", ""); w.Code != http.StatusConflict {
+		t.Errorf("restore over a stored bang = %d, want 409", w.Code)
+	}
+}
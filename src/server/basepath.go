@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// servePrefixed is the middleware behind server.base_path. A request under
+// the base path is routed with the prefix removed, the base path itself
+// redirects to its home page and anything else is not found. Redirects
+// and cookies the handlers set with root paths get the prefix on the way
+// out, so handlers keep working with paths from the instance root.
+func (s *Server) servePrefixed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := s.config.Server.BasePath
+		if base == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.URL.Path == base {
+			target := base + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		rest, ok := strings.CutPrefix(r.URL.Path, base+"/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/" + rest
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = "/" + strings.TrimPrefix(r.URL.RawPath, base+"/")
+		}
+		next.ServeHTTP(&prefixedResponseWriter{ResponseWriter: w, base: base}, r2)
+	})
+}
+
+// prefixedResponseWriter puts the base path in front of the root paths of
+// the Location header and of cookie paths when the header is written
+type prefixedResponseWriter struct {
+	http.ResponseWriter
+	base        string
+	wroteHeader bool
+}
+
+func (w *prefixedResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.Header()
+		if location := header.Get("Location"); isRootPath(location) {
+			header.Set("Location", w.base+location)
+		}
+		if cookies := header.Values("Set-Cookie"); len(cookies) > 0 {
+			header.Del("Set-Cookie")
+			for _, line := range cookies {
+				header.Add("Set-Cookie", prefixCookiePath(line, w.base))
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *prefixedResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *prefixedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isRootPath reports whether target is a path from the site root, not a
+// URL with a scheme or a scheme-relative one
+func isRootPath(target string) bool {
+	return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") && !strings.HasPrefix(target, "/\\")
+}
+
+// prefixCookiePath returns a Set-Cookie line with its path under base. A
+// cookie without a path gets base, since the browser would otherwise scope
+// it to the directory of the request.
+func prefixCookiePath(line, base string) string {
+	cookie, err := http.ParseSetCookie(line)
+	if err != nil {
+		return line
+	}
+	switch {
+	case cookie.Path == "" || cookie.Path == "/":
+		cookie.Path = base
+	case isRootPath(cookie.Path):
+		cookie.Path = base + cookie.Path
+	}
+	if value := cookie.String(); value != "" {
+		return value
+	}
+	return line
+}
+
+// rootLinkAttrs are the attributes whose root paths prefixRootLinks changes
+var rootLinkAttrs = []string{`href="`, `src="`, `action="`}
+
+// prefixRootLinks puts base in front of the root paths of the links in
+// answer HTML, which the answer packages write from the instance root
+func prefixRootLinks(content, base string) string {
+	if base == "" {
+		return content
+	}
+	for _, attr := range rootLinkAttrs {
+		var b strings.Builder
+		rest := content
+		for {
+			i := strings.Index(rest, attr)
+			if i < 0 {
+				break
+			}
+			i += len(attr)
+			b.WriteString(rest[:i])
+			rest = rest[i:]
+			if isRootPath(rest) {
+				b.WriteString(base)
+			}
+		}
+		b.WriteString(rest)
+		content = b.String()
+	}
+	return content
+}
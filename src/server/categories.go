@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// serverCategoryView is one category as the operator API shows it
+type serverCategoryView struct {
+	Category string `json:"category"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// disabledCategories converts search.disabled_categories for the
+// aggregator, skipping names that are not categories
+func disabledCategories(names []string) []model.Category {
+	categories := make([]model.Category, 0, len(names))
+	for _, name := range names {
+		if category := model.Category(strings.ToLower(strings.TrimSpace(name))); category.IsValid() {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// serverCategoryViews lists every category and whether it is searchable
+func (s *Server) serverCategoryViews() []serverCategoryView {
+	views := make([]serverCategoryView, 0, len(model.AllCategories()))
+	for _, category := range model.AllCategories() {
+		views = append(views, serverCategoryView{
+			Category: string(category),
+			Enabled:  s.config.Search.CategoryEnabled(string(category)),
+		})
+	}
+	return views
+}
+
+// handleServerCategories lists the categories and which are turned off,
+// gated by operator token. Per IDEA.md there is no admin UI; this API
+// turns categories on and off.
+func (s *Server) handleServerCategories(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.serverCategoryViews(),
+	})
+}
+
+// handleServerCategorySet turns a category on or off. The body is
+// {"enabled": false}. The general category cannot be turned off.
+func (s *Server) handleServerCategorySet(w http.ResponseWriter, r *http.Request) {
+	category := model.Category(strings.ToLower(strings.TrimSpace(chi.URLParam(r, "category"))))
+	if !category.IsValid() {
+		respondError(w, http.StatusNotFound, "Unknown category")
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil || req.Enabled == nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with an enabled flag")
+		return
+	}
+	if category == model.CategoryGeneral && !*req.Enabled {
+		respondError(w, http.StatusBadRequest, "The general category cannot be disabled")
+		return
+	}
+
+	disabled := make([]string, 0, len(s.config.Search.DisabledCategories)+1)
+	for _, name := range s.config.Search.DisabledCategories {
+		if !strings.EqualFold(strings.TrimSpace(name), string(category)) {
+			disabled = append(disabled, name)
+		}
+	}
+	if !*req.Enabled {
+		disabled = append(disabled, string(category))
+	}
+
+	previous := s.config.Search.DisabledCategories
+	s.config.Search.DisabledCategories = disabled
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("search.disabled_categories", disabled); err != nil {
+			s.config.Search.DisabledCategories = previous
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	s.aggregator.SetDisabledCategories(disabledCategories(disabled))
+	// Pages rendered with the old category tabs are stale
+	if s.renderCache != nil {
+		s.renderCache.clear()
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.serverCategoryViews(),
+	})
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+	"github.com/go-chi/chi/v5"
+)
+
+// categoryEngineView is one category's engines as the operator API shows
+// them. Explicit is false while the category uses every engine that
+// supports it.
+type categoryEngineView struct {
+	Category string               `json:"category"`
+	Explicit bool                 `json:"explicit"`
+	Engines  []categoryEngineInfo `json:"engines"`
+}
+
+type categoryEngineInfo struct {
+	Engine      string  `json:"engine"`
+	DisplayName string  `json:"display_name"`
+	Weight      float64 `json:"weight"`
+}
+
+// resolveCategoryEngines checks a configured list against the enabled
+// engines. Every engine must exist and support the category, so a list can
+// never leave its category without engines.
+func resolveCategoryEngines(category string, list []config.CategoryEngineConfig, engines []search.Engine) (model.Category, []search.CategoryEngine, error) {
+	cat := model.Category(strings.ToLower(strings.TrimSpace(category)))
+	if !cat.IsValid() {
+		return "", nil, fmt.Errorf("unknown category '%s'", category)
+	}
+	normalized, err := config.NormalizeCategoryEngines(list)
+	if err != nil {
+		return "", nil, err
+	}
+
+	byName := make(map[string]search.Engine, len(engines))
+	for _, e := range engines {
+		byName[strings.ToLower(e.Name())] = e
+	}
+	resolved := make([]search.CategoryEngine, 0, len(normalized))
+	for _, entry := range normalized {
+		engine, ok := byName[entry.Engine]
+		if !ok {
+			return "", nil, fmt.Errorf("engine '%s' is not enabled", entry.Engine)
+		}
+		if !engine.SupportsCategory(cat) {
+			return "", nil, fmt.Errorf("engine '%s' does not support %s", entry.Engine, cat)
+		}
+		resolved = append(resolved, search.CategoryEngine{Name: engine.Name(), Weight: entry.Weight})
+	}
+	return cat, resolved, nil
+}
+
+// categoryEngineLists resolves search.category_engines. An invalid list is
+// logged and skipped, leaving its category on every supporting engine.
+func categoryEngineLists(lists map[string][]config.CategoryEngineConfig, engines []search.Engine) map[model.Category][]search.CategoryEngine {
+	resolved := make(map[model.Category][]search.CategoryEngine, len(lists))
+	for category, list := range lists {
+		cat, entries, err := resolveCategoryEngines(category, list, engines)
+		if err != nil {
+			slog.Warn("Ignoring category engine list", "category", category, "err", err)
+			continue
+		}
+		resolved[cat] = entries
+	}
+	return resolved
+}
+
+// categoryEngineView describes category's engines: its list, or the
+// supporting engines by priority when it has none
+func (s *Server) categoryEngineView(category model.Category) categoryEngineView {
+	engines := s.registry.GetEnabled()
+	view := categoryEngineView{Category: string(category), Engines: []categoryEngineInfo{}}
+
+	if list, ok := s.aggregator.CategoryEngines(category); ok {
+		view.Explicit = true
+		for _, entry := range list {
+			for _, e := range engines {
+				if e.Name() == entry.Name {
+					view.Engines = append(view.Engines, categoryEngineInfo{Engine: e.Name(), DisplayName: e.DisplayName(), Weight: entry.Weight})
+					break
+				}
+			}
+		}
+		return view
+	}
+
+	supporting := make([]search.Engine, 0, len(engines))
+	for _, e := range engines {
+		if e.SupportsCategory(category) {
+			supporting = append(supporting, e)
+		}
+	}
+	sort.Slice(supporting, func(i, j int) bool {
+		if supporting[i].GetPriority() != supporting[j].GetPriority() {
+			return supporting[i].GetPriority() > supporting[j].GetPriority()
+		}
+		return supporting[i].Name() < supporting[j].Name()
+	})
+	for _, e := range supporting {
+		view.Engines = append(view.Engines, categoryEngineInfo{Engine: e.Name(), DisplayName: e.DisplayName(), Weight: 1})
+	}
+	return view
+}
+
+// saveCategoryEngines stores lists as search.category_engines, applies them
+// to the aggregator and persists server.yml
+func (s *Server) saveCategoryEngines(lists map[string][]config.CategoryEngineConfig) error {
+	previous := s.config.Search.CategoryEngines
+	s.config.Search.CategoryEngines = lists
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("search.category_engines", lists); err != nil {
+			s.config.Search.CategoryEngines = previous
+			return err
+		}
+	}
+	s.aggregator.SetCategoryEngines(categoryEngineLists(lists, s.registry.GetEnabled()))
+	return nil
+}
+
+// handleCategoryEngines lists every category's engines, gated by operator
+// token. Per IDEA.md there is no admin UI; this API orders and weights the
+// engines of each category.
+func (s *Server) handleCategoryEngines(w http.ResponseWriter, r *http.Request) {
+	views := make([]categoryEngineView, 0, len(model.AllCategories()))
+	for _, category := range model.AllCategories() {
+		views = append(views, s.categoryEngineView(category))
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": views,
+	})
+}
+
+// handleCategoryEnginesSet replaces a category's engine list. The body is
+// {"engines": [{"engine": "...", "weight": 1.0}, ...]} in query order.
+func (s *Server) handleCategoryEnginesSet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Engines []config.CategoryEngineConfig `json:"engines"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with an engines list")
+		return
+	}
+	category, _, err := resolveCategoryEngines(chi.URLParam(r, "category"), req.Engines, s.registry.GetEnabled())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	normalized, _ := config.NormalizeCategoryEngines(req.Engines)
+
+	lists := make(map[string][]config.CategoryEngineConfig, len(s.config.Search.CategoryEngines)+1)
+	for name, list := range s.config.Search.CategoryEngines {
+		lists[name] = list
+	}
+	lists[string(category)] = normalized
+	if err := s.saveCategoryEngines(lists); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.categoryEngineView(category),
+	})
+}
+
+// handleCategoryEnginesReset removes a category's list, returning it to
+// every engine that supports it
+func (s *Server) handleCategoryEnginesReset(w http.ResponseWriter, r *http.Request) {
+	category := model.Category(strings.ToLower(chi.URLParam(r, "category")))
+	if _, ok := s.config.Search.CategoryEngines[string(category)]; !ok {
+		respondError(w, http.StatusNotFound, "Category has no engine list")
+		return
+	}
+
+	lists := make(map[string][]config.CategoryEngineConfig, len(s.config.Search.CategoryEngines))
+	for name, list := range s.config.Search.CategoryEngines {
+		if name != string(category) {
+			lists[name] = list
+		}
+	}
+	if err := s.saveCategoryEngines(lists); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.categoryEngineView(category),
+	})
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/apimgr/search/src/api"
+	"github.com/apimgr/search/src/config"
+)
+
+// Cache-Control of responses a shared cache must not keep: everything the
+// cache policy does not list, and anything that sets a cookie
+const cdnPrivatePolicy = "private, no-cache"
+
+// Cache-Control of live responses listed only so that no broader rule
+// covers them
+const cdnNoStorePolicy = "no-store"
+
+// Default seconds shared caches keep static assets (server.cdn.static_max_age)
+const defaultStaticMaxAge = 86400
+
+// cdnRule is one entry of /.well-known/cache-policy: the responses under
+// Path are the same for every visitor, so a shared cache may keep them
+// under a key of the listed parts, ignoring cookies, unless CacheControl
+// says no-store. The first rule matching a path applies.
+type cdnRule struct {
+	// Exact path, or a prefix when it ends in "*"
+	Path         string   `json:"path"`
+	CacheControl string   `json:"cache_control"`
+	Key          []string `json:"key"`
+}
+
+// cdnPolicy is the machine-readable cache policy served at
+// /.well-known/cache-policy, from which Varnish or Cloudflare rules can be
+// generated
+type cdnPolicy struct {
+	Version int `json:"version"`
+	// How query strings are put in canonical order before keying
+	Query cdnQueryPolicy `json:"query"`
+	// Requests carrying any of these headers bypass the cache
+	BypassHeaders []string `json:"bypass_request_headers"`
+	// Responses setting a cookie are never shared
+	BypassSetCookie bool      `json:"bypass_set_cookie"`
+	Rules           []cdnRule `json:"rules"`
+	// Everything the rules do not match
+	Default cdnDefault `json:"default"`
+}
+
+type cdnQueryPolicy struct {
+	Sort           string `json:"sort"`
+	DropEmpty      bool   `json:"drop_empty"`
+	KeepEncoding   bool   `json:"keep_encoding"`
+	RedirectStatus int    `json:"redirect_status"`
+}
+
+type cdnDefault struct {
+	Cacheable    bool   `json:"cacheable"`
+	CacheControl string `json:"cache_control"`
+}
+
+// cdnRules lists the responses a shared cache may keep, with the policy
+// each is sent with, in the order they are matched
+func cdnRules(cfg *config.Config) []cdnRule {
+	staticMaxAge := cfg.Server.CDN.StaticMaxAge
+	if staticMaxAge <= 0 {
+		staticMaxAge = defaultStaticMaxAge
+	}
+	apiCache := cfg.Server.APICache
+	key := []string{"host", "path", "query"}
+	rules := []cdnRule{
+		{Path: "/static/*", CacheControl: fmt.Sprintf("public, max-age=%d", staticMaxAge)},
+		{Path: "/locales/*", CacheControl: "public, max-age=300"},
+		{Path: "/robots.txt", CacheControl: "public, max-age=86400"},
+		{Path: "/sitemap.xml", CacheControl: "public, max-age=86400"},
+		{Path: "/.well-known/security.txt", CacheControl: "public, max-age=86400"},
+		{Path: "/.well-known/cache-policy", CacheControl: "public, max-age=300"},
+		{Path: "/api/autodiscover", CacheControl: apiCache.Policy(config.APICacheInfo)},
+		{Path: api.APIPrefix + "/info", CacheControl: apiCache.Policy(config.APICacheInfo)},
+		{Path: api.APIPrefix + "/engines", CacheControl: apiCache.Policy(config.APICacheEngines)},
+		// Live circuit and latency state, ahead of the engines/* rule
+		{Path: api.APIPrefix + "/engines/health", CacheControl: cdnNoStorePolicy},
+		{Path: api.APIPrefix + "/engines/*", CacheControl: apiCache.Policy(config.APICacheEngines)},
+		{Path: api.APIPrefix + "/categories", CacheControl: apiCache.Policy(config.APICacheCategories)},
+		{Path: api.APIPrefix + "/bangs", CacheControl: apiCache.Policy(config.APICacheBangs)},
+		{Path: api.APIPrefix + "/widgets", CacheControl: apiCache.Policy(config.APICacheWidgets)},
+		{Path: api.APIPrefix + "/widgets/*", CacheControl: apiCache.Policy(config.APICacheWidgets)},
+	}
+	for i := range rules {
+		rules[i].Key = key
+	}
+	return rules
+}
+
+// matchCDNRule returns the rule covering path, or nil
+func matchCDNRule(rules []cdnRule, path string) *cdnRule {
+	for i, rule := range rules {
+		if prefix, ok := strings.CutSuffix(rule.Path, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return &rules[i]
+			}
+		} else if path == rule.Path {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// canonicalQuery returns a raw query with its parameters sorted by name.
+// Parameters with the same name keep their order, encodings are left as
+// they are and empty parameters, both empty segments and "name=" pairs,
+// are dropped, so a cache that sorts the same way computes the same key.
+// A bare "name" is a flag and is kept.
+func canonicalQuery(raw string) string {
+	params := strings.Split(raw, "&")
+	kept := params[:0]
+	for _, p := range params {
+		if p != "" && !strings.HasSuffix(p, "=") {
+			kept = append(kept, p)
+		}
+	}
+	name := func(p string) string {
+		n, _, _ := strings.Cut(p, "=")
+		return n
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return name(kept[i]) < name(kept[j]) })
+	return strings.Join(kept, "&")
+}
+
+// cdnCache is the middleware behind server.cdn. Requests for responses the
+// cache policy lists are redirected to their canonical query order, and
+// every response without its own Cache-Control gets the policy's: public
+// for listed responses, private for the rest and for anything that sets a
+// cookie or answers an Authorization header.
+func (s *Server) cdnCache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.Server.CDN.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule := matchCDNRule(cdnRules(s.config), r.URL.Path)
+		if rule != nil && (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.URL.RawQuery != "" {
+			if canonical := canonicalQuery(r.URL.RawQuery); canonical != r.URL.RawQuery {
+				target := r.URL.Path
+				if canonical != "" {
+					target += "?" + canonical
+				}
+				w.Header().Set("Cache-Control", rule.CacheControl)
+				http.Redirect(w, r, target, http.StatusMovedPermanently)
+				return
+			}
+		}
+
+		policy := cdnPrivatePolicy
+		if rule != nil && r.Header.Get("Authorization") == "" {
+			policy = rule.CacheControl
+		}
+		next.ServeHTTP(&cdnResponseWriter{ResponseWriter: w, policy: policy}, r)
+	})
+}
+
+// cdnResponseWriter adds the cache policy's Cache-Control to a response
+// that has none when its header is written
+type cdnResponseWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (w *cdnResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.Header()
+		switch {
+		case len(header.Values("Set-Cookie")) > 0:
+			// Never share a response that sets a cookie
+			header.Set("Cache-Control", cdnPrivatePolicy)
+		case header.Get("Cache-Control") != "":
+		case code >= 200 && code < 300 || code == http.StatusNotModified:
+			header.Set("Cache-Control", w.policy)
+		default:
+			header.Set("Cache-Control", cdnPrivatePolicy)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cdnResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *cdnResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// handleCachePolicy serves /.well-known/cache-policy: the cache keys and
+// policies of server.cdn, for generating CDN or Varnish configuration.
+// It is not found while server.cdn is off.
+func (s *Server) handleCachePolicy(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Server.CDN.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	respondJSON(w, http.StatusOK, cdnPolicy{
+		Version: 1,
+		Query: cdnQueryPolicy{
+			Sort:           "name",
+			DropEmpty:      true,
+			KeepEncoding:   true,
+			RedirectStatus: http.StatusMovedPermanently,
+		},
+		BypassHeaders:   []string{"Authorization"},
+		BypassSetCookie: true,
+		Rules:           cdnRules(s.config),
+		Default:         cdnDefault{Cacheable: false, CacheControl: cdnPrivatePolicy},
+	})
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+)
+
+// chaosFaults converts search.chaos into the faults the aggregator injects.
+// Outside development mode it is nil, so a chaos entry left in a
+// production server.yml does nothing.
+func chaosFaults(cfg *config.Config) map[string]search.ChaosFault {
+	if !cfg.IsDevelopment() {
+		return nil
+	}
+	faults := make(map[string]search.ChaosFault, len(cfg.Search.Chaos.Engines))
+	for name, e := range cfg.Search.Chaos.Engines {
+		latency, _ := time.ParseDuration(e.Latency)
+		faults[name] = search.ChaosFault{Latency: latency, Fault: e.Fault, Rate: e.Rate}
+	}
+	return faults
+}
+
+// chaosEntry is one engine's chaos settings as the API reports them
+type chaosEntry struct {
+	Engine string `json:"engine"`
+	config.ChaosEngineConfig
+}
+
+// chaosEntries lists search.chaos.engines by engine name
+func chaosEntries(engines map[string]config.ChaosEngineConfig) []chaosEntry {
+	entries := make([]chaosEntry, 0, len(engines))
+	for name, e := range engines {
+		entries = append(entries, chaosEntry{Engine: name, ChaosEngineConfig: e})
+	}
+	slices.SortFunc(entries, func(a, b chaosEntry) int { return strings.Compare(a.Engine, b.Engine) })
+	return entries
+}
+
+// chaosAvailable answers 404 outside development mode and reports
+// whether the request may go on
+func (s *Server) chaosAvailable(w http.ResponseWriter) bool {
+	if !s.config.IsDevelopment() {
+		respondError(w, http.StatusNotFound, "Chaos testing is only available in development mode")
+		return false
+	}
+	return true
+}
+
+// handleChaos lists the latency and faults injected into engine calls
+func (s *Server) handleChaos(w http.ResponseWriter, r *http.Request) {
+	if !s.chaosAvailable(w) {
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": chaosEntries(s.config.Search.Chaos.Engines),
+	})
+}
+
+// handleChaosSet injects latency or a fault into an engine's calls. The
+// body is {"latency": "2s", "fault": "timeout", "rate": 0.5}.
+func (s *Server) handleChaosSet(w http.ResponseWriter, r *http.Request) {
+	if !s.chaosAvailable(w) {
+		return
+	}
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	if !slices.Contains(s.enabledEngineNames(), name) {
+		respondError(w, http.StatusNotFound, "Unknown engine")
+		return
+	}
+	var req config.ChaosEngineConfig
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8*1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a latency or fault")
+		return
+	}
+	req.Latency = strings.TrimSpace(req.Latency)
+	req.Fault = strings.ToLower(strings.TrimSpace(req.Fault))
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid chaos settings: "+err.Error())
+		return
+	}
+
+	engines := make(map[string]config.ChaosEngineConfig, len(s.config.Search.Chaos.Engines)+1)
+	for engine, e := range s.config.Search.Chaos.Engines {
+		engines[engine] = e
+	}
+	engines[name] = req
+	if err := s.saveChaos(engines); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": chaosEntry{Engine: name, ChaosEngineConfig: req},
+	})
+}
+
+// handleChaosReset stops injecting latency and faults into an engine's calls
+func (s *Server) handleChaosReset(w http.ResponseWriter, r *http.Request) {
+	if !s.chaosAvailable(w) {
+		return
+	}
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	if _, ok := s.config.Search.Chaos.Engines[name]; !ok {
+		respondError(w, http.StatusNotFound, "Engine has no chaos settings")
+		return
+	}
+	engines := make(map[string]config.ChaosEngineConfig, len(s.config.Search.Chaos.Engines))
+	for engine, e := range s.config.Search.Chaos.Engines {
+		if engine != name {
+			engines[engine] = e
+		}
+	}
+	if err := s.saveChaos(engines); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": chaosEntries(engines),
+	})
+}
+
+// saveChaos writes the chaos settings to server.yml and applies them
+func (s *Server) saveChaos(engines map[string]config.ChaosEngineConfig) error {
+	previous := s.config.Search.Chaos.Engines
+	s.config.Search.Chaos.Engines = engines
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("search.chaos.engines", engines); err != nil {
+			s.config.Search.Chaos.Engines = previous
+			return err
+		}
+	}
+	s.aggregator.SetChaos(chaosFaults(s.config))
+	return nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+)
+
+// circuitBreaker converts search.circuit_breaker into the engines' breaker
+// settings. Durations were checked when the config loaded.
+func circuitBreaker(cb config.CircuitBreakerConfig) search.CircuitBreaker {
+	duration := func(s string) time.Duration {
+		d, _ := time.ParseDuration(s)
+		return d
+	}
+	return search.CircuitBreaker{
+		Disabled:         cb.Disabled,
+		FailureThreshold: cb.FailureThreshold,
+		ErrorRate:        cb.ErrorRate,
+		MinRequests:      cb.MinRequests,
+		SlowCall:         duration(cb.SlowCall),
+		Cooldown:         duration(cb.Cooldown),
+		MaxCooldown:      duration(cb.MaxCooldown),
+	}
+}
+
+// handleEngineCircuitReset closes an engine's circuit, once the operator
+// knows the upstream is back, instead of waiting out the cooldown
+func (s *Server) handleEngineCircuitReset(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	if !s.aggregator.ResetCircuit(name) {
+		respondError(w, http.StatusNotFound, "Unknown engine")
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogConfigChange("operator", getClientIPSimple(r), "engines."+name+".circuit", "closed")
+	}
+	if s.registry != nil {
+		if eng, err := s.registry.Get(name); err == nil {
+			if tracker, ok := eng.(interface{ GetHealth() search.EngineHealth }); ok {
+				respondJSON(w, http.StatusOK, map[string]any{
+					"ok":   true,
+					"data": tracker.GetHealth(),
+				})
+				return
+			}
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -1,11 +1,41 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/a11y"
+	"github.com/apimgr/search/src/api"
+	"github.com/apimgr/search/src/cache"
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database"
+	"github.com/apimgr/search/src/feature"
+	"github.com/apimgr/search/src/imageclass"
+	"github.com/apimgr/search/src/logging"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/search/bang"
+	"github.com/apimgr/search/src/search/engine"
+	"github.com/apimgr/search/src/search/macro"
+	"github.com/apimgr/search/src/security"
+	"github.com/apimgr/search/src/ssl"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // coverage2_test.go targets functions that were at 0 % or low coverage after
@@ -345,6 +375,144 @@ func TestHandleServerConfig(t *testing.T) {
 	}
 }
 
+// TestHandleAccessibilityAudit confirms the self-check renders the public
+// pages and the sample search pages, and checks the theme palettes.
+func TestHandleAccessibilityAudit(t *testing.T) {
+	s := newTestServer(t)
+	if s.router == nil {
+		s.setupRoutes()
+	}
+	req := httptest.NewRequest(http.MethodGet, "/server/a11y", nil)
+	rec := httptest.NewRecorder()
+
+	s.handleAccessibilityAudit(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleAccessibilityAudit status = %d, want 200", rec.Code)
+	}
+	var resp struct {
+		OK   bool        `json:"ok"`
+		Data a11y.Report `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("handleAccessibilityAudit: invalid JSON: %v", err)
+	}
+	if !resp.OK {
+		t.Error("handleAccessibilityAudit: ok = false")
+	}
+	for _, page := range []string{"/", "/preferences", "/search?q=accessibility&category=images"} {
+		found := false
+		for _, p := range resp.Data.Pages {
+			found = found || p == page
+		}
+		if !found {
+			t.Errorf("handleAccessibilityAudit: page %q not linted; pages = %v skipped = %v", page, resp.Data.Pages, resp.Data.Skipped)
+		}
+	}
+	for _, issue := range resp.Data.Issues {
+		if strings.Contains(issue.Message, "theme contrast:") {
+			t.Errorf("high-contrast theme fails its own check: %s", issue.Message)
+		}
+	}
+}
+
+// ---------- pwa.go ----------
+
+// TestHandleManifest confirms the manifest is valid JSON built from config.
+func TestHandleManifest(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/manifest.webmanifest", nil)
+	rec := httptest.NewRecorder()
+
+	s.handleManifest(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/manifest+json" {
+		t.Errorf("handleManifest Content-Type = %q, want application/manifest+json", ct)
+	}
+	var manifest WebAppManifest
+	if err := json.Unmarshal(rec.Body.Bytes(), &manifest); err != nil {
+		t.Fatalf("handleManifest: invalid JSON: %v", err)
+	}
+	if manifest.Name == "" || manifest.StartURL != "/" || manifest.Display != "standalone" || len(manifest.Icons) == 0 {
+		t.Errorf("handleManifest: incomplete manifest %+v", manifest)
+	}
+	if manifest.ShareTarget == nil || manifest.ShareTarget.Action != "/share" || manifest.ShareTarget.Params.Text != "text" {
+		t.Errorf("handleManifest: share_target = %+v, want GET /share", manifest.ShareTarget)
+	}
+}
+
+// TestHandleShare confirms shared text redirects to a pre-filled home page,
+// preferring selected text over the title and URL.
+func TestHandleShare(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{"text", "/share?title=Page&text=%20selected%0Atext%20&url=https://example.com", "/?q=selected+text"},
+		{"title", "/share?title=Page+title&url=https://example.com", "/?q=Page+title"},
+		{"url", "/share?url=https://example.com/a", "/?q=https%3A%2F%2Fexample.com%2Fa"},
+		{"empty", "/share", "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleShare(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusSeeOther {
+				t.Fatalf("status = %d, want 303", rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := strings.Repeat("é", maxSharedQueryRunes+10)
+	if got := sharedQuery("", long, ""); len([]rune(got)) != maxSharedQueryRunes {
+		t.Errorf("sharedQuery kept %d runes, want %d", len([]rune(got)), maxSharedQueryRunes)
+	}
+}
+
+// TestHandleServiceWorker confirms sw.js is served with a root scope and the
+// build version substituted into its cache name.
+func TestHandleServiceWorker(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/sw.js", nil)
+	rec := httptest.NewRecorder()
+
+	s.handleServiceWorker(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleServiceWorker status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Service-Worker-Allowed"); got != "/" {
+		t.Errorf("Service-Worker-Allowed = %q, want /", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", got)
+	}
+	if body := rec.Body.String(); strings.Contains(body, serviceWorkerVersionToken) {
+		t.Error("handleServiceWorker: version token not replaced")
+	}
+}
+
+// TestHandleOffline confirms the offline fallback page renders.
+func TestHandleOffline(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/offline", nil)
+	rec := httptest.NewRecorder()
+
+	s.handleOffline(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleOffline status = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `id="offline-recent-list"`) {
+		t.Error("handleOffline: recent searches list missing")
+	}
+}
+
 // ---------- response.go – mapHTTPStatusToCode ----------
 
 // TestMapHTTPStatusToCode verifies every explicit switch case and the default.
@@ -477,3 +645,2329 @@ func TestValidateNotPrivateProxy_Extra(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleQRCode confirms the web address QR code renders as PNG and SVG,
+// and that the onion QR code is absent while Tor is not running.
+func TestHandleQRCode(t *testing.T) {
+	s := newTestServer(t)
+	if s.router == nil {
+		s.setupRoutes()
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/server/qr/web", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("/server/qr/web = %d %q, want 200 image/png", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/server/qr/web?format=svg", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "<svg ") {
+		t.Errorf("/server/qr/web?format=svg = %d, want an svg image", rec.Code)
+	}
+
+	for _, target := range []string{"onion", "other"} {
+		rec = httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/server/qr/"+target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("/server/qr/%s status = %d, want 404", target, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/server/qr/web", nil)
+	req.Host = "abcdefghijklmnop.onion"
+	if got := s.qrAddress(req, qrTargetWeb); got != "" {
+		t.Errorf("qrAddress(web) over Tor without base_url = %q, want empty", got)
+	}
+}
+
+// TestOnionRedirect covers the opt-in redirect rules and confirms the
+// middleware passes requests through untouched while Tor is not running.
+func TestOnionRedirect(t *testing.T) {
+	page := func(path string, optIn bool) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		if optIn {
+			req.AddCookie(&http.Cookie{Name: onionRedirectCookie, Value: "1"})
+		}
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want bool
+	}{
+		{"opted in page load", page("/search?q=tor", true), true},
+		{"not opted in", page("/search?q=tor", false), false},
+		{"preferences page", page("/preferences", true), false},
+		{"api request", page("/api/v1/search", true), false},
+		{"static asset", page("/static/css/public.css", true), false},
+	}
+	post := page("/search", true)
+	post.Method = http.MethodPost
+	tests = append(tests, struct {
+		name string
+		req  *http.Request
+		want bool
+	}{"post", post, false})
+	for _, tt := range tests {
+		if got := wantsOnionRedirect(tt.req); got != tt.want {
+			t.Errorf("%s: wantsOnionRedirect = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	onionReq := page("/", true)
+	onionReq.Host = "abcdefghijklmnop.onion:8080"
+	if !isOnionRequest(onionReq) {
+		t.Error("isOnionRequest(.onion host) = false, want true")
+	}
+	if isOnionRequest(page("/", true)) {
+		t.Error("isOnionRequest(clearnet host) = true, want false")
+	}
+
+	s := newTestServer(t)
+	rec := httptest.NewRecorder()
+	s.onionLocation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(rec, page("/", true))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Onion-Location") != "" {
+		t.Errorf("without Tor: status %d, Onion-Location %q; want pass-through", rec.Code, rec.Header().Get("Onion-Location"))
+	}
+}
+
+// TestTorClientRoutes confirms the client authorization API requires the
+// operator token and rejects bad enrollment requests before touching keys.
+func TestTorClientRoutes(t *testing.T) {
+	s := newTestServer(t)
+	if s.router == nil {
+		s.setupRoutes()
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/tor/clients", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/server/tor/clients without token = %d, want 401", rec.Code)
+	}
+
+	for body, want := range map[string]int{
+		`not json`:          http.StatusBadRequest,
+		`{"name":"../etc"}`: http.StatusBadRequest,
+		`{"name":""}`:       http.StatusBadRequest,
+	} {
+		rec = httptest.NewRecorder()
+		s.handleTorClientAdd(rec, httptest.NewRequest(http.MethodPost, "/api/v1/server/tor/clients", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("POST %s = %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/server/tor/clients/missing", nil)
+	s.handleTorClientRevoke(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE of an unknown client = %d, want 404", rec.Code)
+	}
+}
+
+// TestOnionServesScope confirms an API-scoped onion serves only the API and
+// health checks, while other onions serve everything.
+func TestOnionServesScope(t *testing.T) {
+	tests := []struct {
+		scope, path string
+		want        bool
+	}{
+		{config.TorScopeAll, "/search", true},
+		{config.TorScopeAPI, "/api/v1/search", true},
+		{config.TorScopeAPI, "/openapi.json", true},
+		{config.TorScopeAPI, "/server/healthz", true},
+		{config.TorScopeAPI, "/search", false},
+		{config.TorScopeAPI, "/", false},
+		{config.TorScopeAPI, "/preferences", false},
+	}
+	for _, tt := range tests {
+		if got := scopeServes(tt.scope, tt.path); got != tt.want {
+			t.Errorf("scopeServes(%q, %q) = %v, want %v", tt.scope, tt.path, got, tt.want)
+		}
+	}
+
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/search", nil)
+	req.Host = "unknown.onion"
+	if !s.onionServes(req) {
+		t.Error("onionServes(unknown onion) = false, want true")
+	}
+}
+
+// TestParseWarmQueries covers the warm queries file format: comments,
+// category prefixes, operators that look like prefixes, and the cap.
+func TestParseWarmQueries(t *testing.T) {
+	input := `# popular searches
+weather
+
+images: cute  cats
+site:example.com privacy
+news:election results
+weather
+general: weather
+videos:
+last one
+`
+	got, err := parseWarmQueries(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatalf("parseWarmQueries() error = %v", err)
+	}
+	want := []warmQuery{
+		{model.CategoryGeneral, "weather"},
+		{model.CategoryImages, "cute cats"},
+		{model.CategoryGeneral, "site:example.com privacy"},
+		{model.CategoryNews, "election results"},
+		{model.CategoryGeneral, "last one"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseWarmQueries() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("query %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got, _ := parseWarmQueries(strings.NewReader(input), 2); len(got) != 2 {
+		t.Errorf("parseWarmQueries(max 2) returned %d queries", len(got))
+	}
+}
+
+// ---------- category_engines.go ----------
+
+type categoryTestEngine struct {
+	*search.BaseEngine
+}
+
+func (e *categoryTestEngine) Search(context.Context, *model.Query) ([]model.Result, error) {
+	return nil, nil
+}
+
+func newCategoryTestEngine(name string, categories ...string) search.Engine {
+	return &categoryTestEngine{search.NewBaseEngine(&model.EngineConfig{Name: name, DisplayName: name, Enabled: true, Categories: categories})}
+}
+
+// TestResolveCategoryEngines confirms lists are normalized and rejected when
+// they would leave a category without usable engines.
+func TestResolveCategoryEngines(t *testing.T) {
+	engines := []search.Engine{
+		newCategoryTestEngine("web", "general"),
+		newCategoryTestEngine("pics", "general", "images"),
+	}
+
+	cat, list, err := resolveCategoryEngines("Images", []config.CategoryEngineConfig{{Engine: "PICS", Weight: 2}}, engines)
+	if err != nil || cat != model.CategoryImages || len(list) != 1 || list[0] != (search.CategoryEngine{Name: "pics", Weight: 2}) {
+		t.Errorf("resolveCategoryEngines() = %q, %+v, %v", cat, list, err)
+	}
+
+	for name, tt := range map[string]struct {
+		category string
+		list     []config.CategoryEngineConfig
+	}{
+		"empty list":       {"general", nil},
+		"unknown category": {"recipes", []config.CategoryEngineConfig{{Engine: "web"}}},
+		"unknown engine":   {"general", []config.CategoryEngineConfig{{Engine: "missing"}}},
+		"unsupported":      {"images", []config.CategoryEngineConfig{{Engine: "web"}}},
+		"duplicate":        {"general", []config.CategoryEngineConfig{{Engine: "web"}, {Engine: "Web"}}},
+		"weight too high":  {"general", []config.CategoryEngineConfig{{Engine: "web", Weight: 50}}},
+	} {
+		if _, _, err := resolveCategoryEngines(tt.category, tt.list, engines); err == nil {
+			t.Errorf("%s: resolveCategoryEngines() accepted the list", name)
+		}
+	}
+
+	// Invalid lists are skipped at load, leaving the category implicit
+	lists := categoryEngineLists(map[string][]config.CategoryEngineConfig{
+		"general": {{Engine: "web"}},
+		"images":  {},
+	}, engines)
+	if len(lists) != 1 || len(lists[model.CategoryGeneral]) != 1 {
+		t.Errorf("categoryEngineLists() = %+v, want only general", lists)
+	}
+}
+
+// TestCategoryEngineRoutes covers the operator API's validation paths.
+func TestCategoryEngineRoutes(t *testing.T) {
+	s := newTestServer(t)
+	if s.router == nil {
+		s.setupRoutes()
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/categories", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/server/engines/categories without token = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleCategoryEngines(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/categories", nil))
+	var resp struct {
+		Data []categoryEngineView `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Data) != len(model.AllCategories()) {
+		t.Fatalf("GET categories = %d %s, want one entry per category", rec.Code, rec.Body.String())
+	}
+
+	r := chi.NewRouter()
+	r.Put("/{category}", s.handleCategoryEnginesSet)
+	r.Delete("/{category}", s.handleCategoryEnginesReset)
+	for body, want := range map[string]int{
+		`not json`:                        http.StatusBadRequest,
+		`{"engines":[]}`:                  http.StatusBadRequest,
+		`{"engines":[{"engine":"nope"}]}`: http.StatusBadRequest,
+	} {
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/general", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("PUT %s = %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/images", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE of a category without a list = %d, want 404", rec.Code)
+	}
+}
+
+// ---------- engine_alerts.go ----------
+
+func TestEngineAlertLogAllow(t *testing.T) {
+	var log engineAlertLog
+	now := time.Now()
+	if !log.allow("google", now) {
+		t.Fatal("first alert about google was throttled")
+	}
+	if log.allow("google", now.Add(engineAlertInterval/2)) {
+		t.Error("second alert within the interval was allowed")
+	}
+	if !log.allow("bing", now) {
+		t.Error("alert about another engine was throttled")
+	}
+	if !log.allow("google", now.Add(engineAlertInterval)) {
+		t.Error("alert after the interval was throttled")
+	}
+}
+
+// ---------- view_as.go ----------
+
+func TestViewAsSession(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.handleViewAsCreate(rec, httptest.NewRequest(http.MethodPost, "/api/v1/server/view-as", strings.NewReader(`{"prefs":"t=l;c=images","language":"de"}`)))
+	var created struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated || !strings.HasPrefix(created.Data.URL, "/view-as/") {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body.String())
+	}
+
+	r := chi.NewRouter()
+	r.Get("/view-as/end", s.handleViewAsEnd)
+	r.Get("/view-as/{token}", s.handleViewAsStart)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, created.Data.URL, nil))
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusSeeOther || len(cookies) != 1 || cookies[0].Name != viewAsCookie || !cookies[0].HttpOnly {
+		t.Fatalf("start = %d, cookies %v", rec.Code, cookies)
+	}
+
+	// The session's settings win over the browser's own cookies
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	req.AddCookie(&http.Cookie{Name: "theme", Value: ThemeContrast})
+	req.AddCookie(&http.Cookie{Name: "cookieConsent", Value: "yes"})
+	data := s.newPageData(httptest.NewRecorder(), req, "Home", "index")
+	if !data.ViewAs || data.Lang != "de" || data.ThemeMode != ThemeLight || data.Category != "images" || data.HasConsentCookie {
+		t.Errorf("page data = lang %s theme %s category %s consent %v, want the previewed user's", data.Lang, data.ThemeMode, data.Category, data.HasConsentCookie)
+	}
+
+	rec = httptest.NewRecorder()
+	endReq := httptest.NewRequest(http.MethodGet, "/view-as/end", nil)
+	endReq.AddCookie(cookies[0])
+	r.ServeHTTP(rec, endReq)
+	if s.viewAsSession(req) != nil {
+		t.Error("session still active after ending it")
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/view-as/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("start with an unknown token = %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.handleViewAsCreate(rec, httptest.NewRequest(http.MethodPost, "/api/v1/server/view-as", strings.NewReader(`{"language":"xx"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("create with an unsupported language = %d, want 400", rec.Code)
+	}
+}
+
+func TestViewAsStoreLimit(t *testing.T) {
+	var store viewAsStore
+	now := time.Now()
+	first, _ := store.create("", "", now)
+	for i := 1; i < maxViewAsSessions; i++ {
+		store.create("", "", now.Add(time.Duration(i)*time.Second))
+	}
+	store.create("", "", now.Add(maxViewAsSessions*time.Second))
+	if len(store.sessions) != maxViewAsSessions || store.get(first.token, now) != nil {
+		t.Errorf("sessions = %d, want %d with the oldest dropped", len(store.sessions), maxViewAsSessions)
+	}
+	if store.get(first.token, now.Add(viewAsTTL)) != nil {
+		t.Error("expired session still returned")
+	}
+}
+
+// ---------- operator_tokens.go ----------
+
+func TestRequireScope(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.Token = "secret"
+	s := &Server{config: cfg}
+	handler := s.RequireScope(security.ScopeRead, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for token, want := range map[string]int{
+		"":                   http.StatusUnauthorized,
+		"secret":             http.StatusOK,
+		"adm_unknown":        http.StatusUnauthorized,
+		"not-an-admin-token": http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/server/status", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != want {
+			t.Errorf("token %q: status = %d, want %d", token, rec.Code, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/server/tokens/self", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	s.handleOperatorTokenSelf(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"operator":true`) {
+		t.Errorf("self with the server token = %d %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	s.handleOperatorTokens(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/tokens", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("list without a database = %d, want 503", rec.Code)
+	}
+}
+
+// ---------- operator_jwt.go ----------
+
+func TestOperatorJWT(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Update(func(sc *config.ServerConfig) {
+		sc.Token = "tok_operator"
+		sc.JWT.Enabled = true
+	})
+	s := &Server{config: cfg}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	call := func(h http.HandlerFunc, bearer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/server/tokens/jwt", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	rec := call(s.handleOperatorJWTIssue, "tok_operator", `{"ttl": "1m"}`)
+	var resp struct {
+		Data struct {
+			Token     string `json:"token"`
+			ExpiresIn int    `json:"expires_in"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("issue: %d %s", rec.Code, rec.Body)
+	}
+	if resp.Data.ExpiresIn != 60 {
+		t.Errorf("expires_in = %d, want the shorter ttl asked for", resp.Data.ExpiresIn)
+	}
+	jwt := resp.Data.Token
+	if rec := call(s.RequireOperator(ok), jwt, ""); rec.Code != http.StatusOK {
+		t.Errorf("server token JWT on an operator route = %d", rec.Code)
+	}
+	if rec := call(s.handleOperatorJWTIssue, jwt, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("JWT exchanged for a JWT = %d, want 401", rec.Code)
+	}
+
+	// A named token's JWT holds the token's scopes, without the database
+	named := security.OperatorJWTClaims(&security.OperatorToken{ID: 3, Prefix: "adm_x", Scopes: []string{security.ScopeRead}, RateLimit: 10}, time.Minute, time.Now())
+	namedJWT, err := security.SignOperatorJWT(named, s.jwtKeys()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := call(s.RequireScope(security.ScopeRead, ok), namedJWT, ""); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") == "" {
+		t.Errorf("named JWT with its scope = %d, limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if rec := call(s.RequireScope(security.ScopeConfigWrite, ok), namedJWT, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("named JWT without the scope = %d, want 403", rec.Code)
+	}
+
+	// A TTL that never went through validation is not issued as zero
+	cfg.Update(func(sc *config.ServerConfig) { sc.JWT.TTL = "soon" })
+	if rec := call(s.handleOperatorJWTIssue, "tok_operator", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("issue with a bad ttl = %d, want 500", rec.Code)
+	}
+
+	cfg.Update(func(sc *config.ServerConfig) { sc.JWT.Enabled = false })
+	if rec := call(s.RequireOperator(ok), jwt, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("JWT with the mode off = %d, want 401", rec.Code)
+	}
+	if rec := call(s.handleOperatorJWTIssue, "tok_operator", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("issue with the mode off = %d, want 404", rec.Code)
+	}
+}
+
+// ---------- token_limits.go ----------
+
+func TestAllowOperatorToken(t *testing.T) {
+	s := &Server{config: config.DefaultConfig()}
+	now := time.Now()
+	token := &security.OperatorToken{ID: 1, RateLimit: 2}
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/server/status", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		if s.allowOperatorToken(rec, req, token) {
+			rec.WriteHeader(http.StatusOK)
+		}
+		return rec
+	}
+
+	if rec := request("192.0.2.1"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("first request = %d, remaining %q", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	request("192.0.2.2")
+	rec := request("192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over the limit = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// A boost raises the limit while it lasts
+	token.Boost, token.BoostUntil = 1, now.Add(time.Hour)
+	if rec := request("192.0.2.1"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "3" {
+		t.Errorf("boosted = %d, limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	token.Frozen = true
+	if rec := request("192.0.2.1"); rec.Code != http.StatusForbidden {
+		t.Errorf("frozen = %d, want 403", rec.Code)
+	}
+
+	report := s.tokenUsage.report(token, time.Now())
+	if report.Used != 3 || report.Total != 3 || report.Remaining != 0 || len(report.Refusals) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Refusals[0].Status != http.StatusForbidden || report.Refusals[1].Status != http.StatusTooManyRequests {
+		t.Errorf("refusals = %+v, want the newest first", report.Refusals)
+	}
+	if len(report.TopIPs) != 2 || report.TopIPs[0] != (tokenIP{IP: "192.0.2.1", Requests: 2}) {
+		t.Errorf("top IPs = %+v", report.TopIPs)
+	}
+}
+
+// ---------- features.go ----------
+
+func TestFeatureHandlers(t *testing.T) {
+	cfg := config.DefaultConfig()
+	s := &Server{config: cfg, features: feature.NewSet(map[string]config.FeatureConfig{
+		"summarization": {Enabled: false},
+	}, nil)}
+	r := chi.NewRouter()
+	r.Put("/api/v1/server/features/{name}", s.handleFeatureSet)
+	r.Delete("/api/v1/server/features/{name}", s.handleFeatureReset)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/server/features/summarization", strings.NewReader(`{"enabled":true,"percent":50}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// The browser gets a bucket cookie and keeps it across requests
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	first := s.featureEnabled(rec, req, "summarization")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != featureBucketCookie {
+		t.Fatalf("cookies = %v, want a %s cookie", cookies, featureBucketCookie)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	for i := 0; i < 5; i++ {
+		if s.featureEnabled(nil, req, "summarization") != first {
+			t.Fatal("featureEnabled() changed for the same browser")
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/server/features/unknown", strings.NewReader(`{"enabled":true}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("set unknown flag = %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/server/features/summarization", nil))
+	if rec.Code != http.StatusOK || s.featureEnabled(nil, req, "summarization") {
+		t.Errorf("reset = %d, flag still on: %v", rec.Code, s.featureEnabled(nil, req, "summarization"))
+	}
+}
+
+// ---------- metrics.go (engine health) ----------
+
+// TestSetEngineHealth checks engine health totals feed the request and error
+// counters by their growth, which the exported alert rules rate over.
+func TestSetEngineHealth(t *testing.T) {
+	m := &Metrics{
+		engineUp:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_engine_up"}, []string{"engine"}),
+		engineRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "t_engine_requests"}, []string{"engine"}),
+		engineErrors:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "t_engine_errors"}, []string{"engine"}),
+		certExpiry:     prometheus.NewGauge(prometheus.GaugeOpts{Name: "t_cert_expiry"}),
+	}
+
+	m.SetEngineHealth("ddg", true, 5, 1)
+	m.SetEngineHealth("ddg", false, 7, 4)
+	if got := testutil.ToFloat64(m.engineRequests.WithLabelValues("ddg")); got != 11 {
+		t.Errorf("engine requests = %v, want 11", got)
+	}
+	if got := testutil.ToFloat64(m.engineErrors.WithLabelValues("ddg")); got != 4 {
+		t.Errorf("engine errors = %v, want 4", got)
+	}
+	if got := testutil.ToFloat64(m.engineUp.WithLabelValues("ddg")); got != 0 {
+		t.Errorf("engine up = %v, want 0", got)
+	}
+
+	expiry := time.Unix(1900000000, 0)
+	m.SetCertificateExpiry(expiry)
+	if got := testutil.ToFloat64(m.certExpiry); got != 1900000000 {
+		t.Errorf("cert expiry = %v, want %d", got, expiry.Unix())
+	}
+	m.SetCertificateExpiry(time.Time{})
+	if got := testutil.ToFloat64(m.certExpiry); got != 0 {
+		t.Errorf("cert expiry without TLS = %v, want 0", got)
+	}
+}
+
+func TestHandlePresetApply(t *testing.T) {
+	s := &Server{config: config.DefaultConfig()}
+	apply := func(name, query string) map[string]any {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("name", name)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/server/presets/"+name+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handlePresetApply(rec, req)
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusOK {
+			return map[string]any{"status": rec.Code}
+		}
+		return resp.Data
+	}
+
+	if got := apply("nope", ""); got["status"] != http.StatusNotFound {
+		t.Errorf("unknown preset = %v, want 404", got)
+	}
+	if got := apply("family-safe", "?dry_run=true"); got["applied"] != false || got["restart_required"] != true || s.config.Search.SafeSearchLocked {
+		t.Errorf("dry run = %v, locked %v", got, s.config.Search.SafeSearchLocked)
+	}
+	if got := apply("family-safe", ""); got["applied"] != true || !s.config.Search.SafeSearchLocked || s.config.Search.SafeSearch != 2 {
+		t.Errorf("apply = %v, safe search %d locked %v", got, s.config.Search.SafeSearch, s.config.Search.SafeSearchLocked)
+	}
+	if got := apply("family-safe", ""); got["applied"] != false {
+		t.Errorf("second apply = %v, want nothing to apply", got)
+	}
+}
+
+// ---------- reports.go ----------
+
+func TestReportChallenge(t *testing.T) {
+	s := &Server{config: config.DefaultConfig(), startTime: time.Now()}
+	ch := s.newReportChallenge()
+	answer := strconv.Itoa(ch.A + ch.B)
+
+	if !s.verifyReportChallenge(ch.ID, " "+answer+" ") {
+		t.Error("the right answer was rejected")
+	}
+	if s.verifyReportChallenge(ch.ID, strconv.Itoa(ch.A+ch.B+1)) {
+		t.Error("a wrong answer was accepted")
+	}
+
+	expired := "1." + s.reportChallengeMAC(ch.A+ch.B, "1")
+	if s.verifyReportChallenge(expired, answer) {
+		t.Error("an expired challenge was accepted")
+	}
+	other := &Server{config: s.config, startTime: s.startTime.Add(time.Second)}
+	if other.verifyReportChallenge(ch.ID, answer) {
+		t.Error("a challenge from another process was accepted")
+	}
+}
+
+// ---------- screening.go ----------
+
+func TestScreeningFilterAndMarker(t *testing.T) {
+	dataDir := t.TempDir()
+	feedDir := dataDir + "/security/threatfeeds"
+	if err := os.MkdirAll(feedDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(feedDir+"/urlhaus.txt", []byte("http://files.example/x.exe\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	feeds := security.NewThreatFeedManager(dataDir, nil)
+	if err := feeds.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Search.Screening.Enabled = true
+	s := &Server{config: cfg, threatFeeds: feeds}
+	listed := model.Result{URL: "http://FILES.example:80/x.exe"}
+	clean := model.Result{URL: "http://files.example/readme"}
+
+	if got := s.markResult(listed); got != "malware" || s.hideResult(listed) {
+		t.Errorf("warn: mark = %q, hidden = %v; want a malware mark, shown", got, s.hideResult(listed))
+	}
+	if s.markResult(clean) != "" || s.hideResult(clean) {
+		t.Error("warn: an unlisted result was flagged")
+	}
+
+	cfg.Search.Screening.Action = "hide"
+	if s.markResult(listed) != "" || !s.hideResult(listed) {
+		t.Error("hide: the listed result must be dropped, not marked")
+	}
+
+	cfg.Search.Screening.Enabled = false
+	if s.hideResult(listed) {
+		t.Error("a listed result was hidden with screening off")
+	}
+
+	if got := resultHref("https://a.example/?q=1", "phishing"); got != "/warning?url=https%3A%2F%2Fa.example%2F%3Fq%3D1" {
+		t.Errorf("resultHref(flagged) = %q", got)
+	}
+	if got := resultHref("https://a.example/", ""); got != "https://a.example/" {
+		t.Errorf("resultHref(clean) = %q", got)
+	}
+}
+
+// ---------- imageclass.go ----------
+
+func TestFlagAdultImages(t *testing.T) {
+	cfg := config.DefaultConfig()
+	s := &Server{config: cfg, imageClassifier: imageclass.New(imageclass.Options{Endpoint: "http://127.0.0.1:1/classify"})}
+	results := []model.Result{{URL: "ftp://images.example/a.jpg"}, {URL: "data:image/png;base64,AA=="}}
+
+	if flags := s.flagAdultImages(context.Background(), results); flags != nil {
+		t.Errorf("flags = %v with the classifier disabled, want nil", flags)
+	}
+
+	// Images that cannot be fetched are never flagged
+	cfg.Search.ImageClassifier.Enabled = true
+	flags := s.flagAdultImages(context.Background(), results)
+	if len(flags) != 2 || flags[0] || flags[1] {
+		t.Errorf("flags = %v, want two unflagged results", flags)
+	}
+
+	s.imageClassifier = nil
+	if flags := s.flagAdultImages(context.Background(), results); flags != nil {
+		t.Errorf("flags = %v without a classifier, want nil", flags)
+	}
+}
+
+func TestMemoryOptions(t *testing.T) {
+	opts := memoryOptions(config.MemoryConfig{Limit: "512MB", SoftPercent: 60, HardPercent: 80, Interval: "30s"})
+	if opts.Limit != 512<<20 || opts.SoftPercent != 60 || opts.HardPercent != 80 || opts.Interval != 30*time.Second {
+		t.Errorf("memoryOptions() = %+v", opts)
+	}
+
+	// auto and invalid values leave detection and defaults to the watchdog
+	for _, mc := range []config.MemoryConfig{{Limit: "auto"}, {Limit: "lots", Interval: "often"}} {
+		if opts := memoryOptions(mc); opts.Limit != 0 || opts.Interval != 0 {
+			t.Errorf("memoryOptions(%+v) = %+v, want limit and interval unset", mc, opts)
+		}
+	}
+}
+
+func TestHandleMemoryDisabled(t *testing.T) {
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	s.handleMemory(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/memory", nil))
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Data["enabled"] != false {
+		t.Errorf("handleMemory() without a watchdog = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// ---------- render_cache.go ----------
+
+// newRenderCacheServer returns a server that renders search pages for the
+// synthetic engines and caches them
+func newRenderCacheServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Search.Bangs.Enabled = false
+	cfg.Server.Web.Announcements.Enabled = false
+	i18nMgr, err := i18n.DefaultManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := engine.SyntheticRegistry(0)
+	return &Server{
+		config:      cfg,
+		i18nManager: i18nMgr,
+		renderer:    NewTemplateRenderer(cfg, i18nMgr),
+		aggregator: search.NewAggregator(registry.GetEnabled(), search.AggregatorConfig{
+			Timeout: 5 * time.Second,
+		}),
+		renderCache: newRenderCache(cfg.Search.RenderCache),
+	}
+}
+
+func TestSearchRenderCache(t *testing.T) {
+	s := newRenderCacheServer(t)
+	search := func(modify func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/search?q=golang+generics", nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+		if modify != nil {
+			modify(req)
+		}
+		rec := httptest.NewRecorder()
+		s.handleSearch(rec, req)
+		return rec
+	}
+
+	first := search(nil)
+	if first.Code != http.StatusOK || first.Header().Get("X-Render-Cache") != "MISS" {
+		t.Fatalf("first search: %d, X-Render-Cache %q", first.Code, first.Header().Get("X-Render-Cache"))
+	}
+	if !strings.Contains(first.Body.String(), `class="results-list"`) {
+		t.Fatalf("first search did not render the results template:\n%s", first.Body)
+	}
+	second := search(nil)
+	if second.Header().Get("X-Render-Cache") != "HIT" || second.Body.String() != first.Body.String() {
+		t.Fatalf("repeat search: X-Render-Cache %q, same page %v", second.Header().Get("X-Render-Cache"), second.Body.String() == first.Body.String())
+	}
+
+	// A different theme renders a different page
+	themed := search(func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "theme", Value: ThemeLight}) })
+	if themed.Header().Get("X-Render-Cache") != "MISS" {
+		t.Errorf("light theme: X-Render-Cache %q, want a page of its own", themed.Header().Get("X-Render-Cache"))
+	}
+
+	// Authenticated requests never use the cache
+	authed := search(func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") })
+	if authed.Code != http.StatusOK || authed.Header().Get("X-Render-Cache") != "" {
+		t.Errorf("authenticated search: %d, X-Render-Cache %q", authed.Code, authed.Header().Get("X-Render-Cache"))
+	}
+
+	// A reload empties the cache; disabling it bypasses it
+	s.renderCache.configure(config.RenderCacheConfig{Disabled: true})
+	if got := search(nil).Header().Get("X-Render-Cache"); got != "" {
+		t.Errorf("disabled cache: X-Render-Cache %q", got)
+	}
+	s.renderCache.configure(config.RenderCacheConfig{})
+	if got := search(nil).Header().Get("X-Render-Cache"); got != "MISS" {
+		t.Errorf("after a reload: X-Render-Cache %q, want MISS", got)
+	}
+}
+
+func TestRenderCacheTTL(t *testing.T) {
+	c := newRenderCache(config.RenderCacheConfig{TTL: "not-a-duration"})
+	if got := time.Duration(c.ttl.Load()); got != defaultRenderCacheTTL {
+		t.Errorf("invalid ttl: %s, want the default %s", got, defaultRenderCacheTTL)
+	}
+	c.configure(config.RenderCacheConfig{TTL: "1ms"})
+	c.put("page:a", []byte("<html>"))
+	time.Sleep(5 * time.Millisecond)
+	if page := c.get("page:a"); page != nil {
+		t.Errorf("page outlived its TTL: %q", page)
+	}
+}
+
+// ---------- cdn.go ----------
+
+func TestCanonicalQuery(t *testing.T) {
+	for raw, want := range map[string]string{
+		"category=general":            "category=general",
+		"q=a&category=b":              "category=b&q=a",
+		"b=2&a=1&b=1":                 "a=1&b=2&b=1",
+		"search=g%20o&&category=&x=1": "search=g%20o&x=1",
+		"z&y=1":                       "y=1&z",
+		"category=&lang=":             "",
+	} {
+		if got := canonicalQuery(raw); got != want {
+			t.Errorf("canonicalQuery(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestCDNCacheMiddleware(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.CDN.Enabled = true
+	s := &Server{config: cfg}
+	handler := s.cdnCache(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cookie") != "" {
+			http.SetCookie(w, &http.Cookie{Name: "lang", Value: "de"})
+		}
+		w.Write([]byte("ok"))
+	}))
+	serve := func(target string, modify func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if modify != nil {
+			modify(req)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("/static/css/common.css", nil); rec.Header().Get("Cache-Control") != "public, max-age=86400" {
+		t.Errorf("static asset: Cache-Control %q", rec.Header().Get("Cache-Control"))
+	}
+	if rec := serve("/search?q=test", nil); rec.Header().Get("Cache-Control") != cdnPrivatePolicy {
+		t.Errorf("search page: Cache-Control %q, want private", rec.Header().Get("Cache-Control"))
+	}
+	if rec := serve("/static/app.js?cookie=1", nil); rec.Header().Get("Cache-Control") != cdnPrivatePolicy {
+		t.Errorf("response setting a cookie: Cache-Control %q, want private", rec.Header().Get("Cache-Control"))
+	}
+	authed := serve(api.APIPrefix+"/categories", func(r *http.Request) { r.Header.Set("Authorization", "Bearer x") })
+	if authed.Header().Get("Cache-Control") != cdnPrivatePolicy {
+		t.Errorf("authorized request: Cache-Control %q, want private", authed.Header().Get("Cache-Control"))
+	}
+
+	rec := serve(api.APIPrefix+"/bangs?search=g&category=general", nil)
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != api.APIPrefix+"/bangs?category=general&search=g" {
+		t.Errorf("unsorted query: %d to %q, want a redirect to the sorted one", rec.Code, rec.Header().Get("Location"))
+	}
+	rec = serve(api.APIPrefix+"/bangs?search=g&category=", nil)
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != api.APIPrefix+"/bangs?search=g" {
+		t.Errorf("empty parameter: %d to %q, want a redirect without it", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := serve(api.APIPrefix+"/engines/health", nil); rec.Header().Get("Cache-Control") != cdnNoStorePolicy {
+		t.Errorf("engine health: Cache-Control %q, want no-store", rec.Header().Get("Cache-Control"))
+	}
+	if rec := serve(api.APIPrefix+"/engines/google", nil); rec.Header().Get("Cache-Control") == cdnNoStorePolicy {
+		t.Error("engine details: no-store, want the engines policy")
+	}
+	if rec := serve("/search?q=a&category=b", nil); rec.Code != http.StatusOK {
+		t.Errorf("uncached page with an unsorted query: %d, want no redirect", rec.Code)
+	}
+
+	cfg.Server.CDN.Enabled = false
+	if rec := serve("/static/css/common.css", nil); rec.Header().Get("Cache-Control") != "" {
+		t.Errorf("cdn mode off: Cache-Control %q", rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestHandleCachePolicy(t *testing.T) {
+	cfg := config.DefaultConfig()
+	s := &Server{config: cfg}
+	rec := httptest.NewRecorder()
+	s.handleCachePolicy(rec, httptest.NewRequest(http.MethodGet, "/.well-known/cache-policy", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("cdn mode off: %d, want 404", rec.Code)
+	}
+
+	cfg.Server.CDN.Enabled = true
+	cfg.Server.APICache.Engines = "public, max-age=5"
+	rec = httptest.NewRecorder()
+	s.handleCachePolicy(rec, httptest.NewRequest(http.MethodGet, "/.well-known/cache-policy", nil))
+	var policy cdnPolicy
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		t.Fatalf("policy is not JSON: %v", err)
+	}
+	engines := matchCDNRule(policy.Rules, api.APIPrefix+"/engines/google")
+	if engines == nil || engines.CacheControl != "public, max-age=5" || len(engines.Key) == 0 {
+		t.Errorf("engines rule = %+v", engines)
+	}
+	if matchCDNRule(policy.Rules, "/search") != nil || policy.Default.Cacheable {
+		t.Error("search pages must not be publicly cacheable")
+	}
+}
+
+// ---------- template/page/search.tmpl (rich snippets) ----------
+
+func TestSearchTemplateRichSnippets(t *testing.T) {
+	s := newRenderCacheServer(t)
+	results := model.NewSearchResults("pancakes", model.CategoryGeneral)
+	results.AddResult(model.Result{
+		Title:  "Fluffy Pancakes",
+		URL:    "https://recipes.example/pancakes",
+		Engine: "google",
+		Structured: &model.StructuredData{
+			Type:   model.StructuredRecipe,
+			Rating: &model.Rating{Value: 4.7, Best: 5, Count: 1234},
+			Recipe: &model.RecipeInfo{
+				TotalTime:       75,
+				Calories:        250,
+				Ingredients:     []string{"2 eggs", "200 g flour", "300 ml milk", "1 tbsp sugar", "Butter"},
+				IngredientCount: 7,
+			},
+		},
+	})
+	results.AddResult(model.Result{
+		Title:  "Fix a Dripping Tap",
+		URL:    "https://diy.example/tap",
+		Engine: "google",
+		Structured: &model.StructuredData{
+			Type:  model.StructuredHowTo,
+			HowTo: &model.HowToInfo{Steps: []string{"Turn off the water", "Remove the handle"}, StepCount: 2},
+		},
+	})
+	results.AddResult(model.Result{
+		Title:  "Cast Iron Pan",
+		URL:    "https://shop.example/pan",
+		Engine: "bing",
+		Structured: &model.StructuredData{
+			Type:    model.StructuredProduct,
+			Product: &model.ProductInfo{Price: "29.99", Currency: "USD", Availability: "out_of_stock"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=pancakes", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "pancakes", results, string(model.CategoryGeneral), nil)
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"★★★★★", "4.7/5 (1.2K reviews)", "75 min", "250 kcal", "29.99 USD", "Out of stock",
+		`class="rich-card rich-card-recipe"`, "2 eggs, 200 g flour, 300 ml milk, 1 tbsp sugar", "3 more",
+		`class="rich-card rich-card-howto"`, "2 steps", "<li>Remove the handle</li>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("rich snippets: %q missing", want)
+		}
+	}
+	if strings.Count(body, `class="result-rich"`) != 3 || strings.Count(body, `class="rich-card `) != 2 {
+		t.Errorf("rich snippets: want one block per result with structured data, cards for the recipe and how-to")
+	}
+}
+
+// ---------- reverse_image.go ----------
+
+func TestSearchTemplateImageTools(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.config.Search.ReverseImage.Enabled = true
+	results := model.NewSearchResults("sunset", model.CategoryImages)
+	results.AddResult(model.Result{Title: "Sunset", URL: "https://img.example/sunset.jpg", Thumbnail: "https://img.example/t.jpg", Engine: "google"})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=sunset&category=images&image_color=red&image_license=bogus", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "sunset", results, string(model.CategoryImages), nil)
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`data-image-color="red"`, `<option value="red" selected>Red</option>`, `<option value="share">Free to share and use</option>`,
+		`action="/search/image"`, `href="/search/image?url=https%3A%2F%2Fimg.example%2Fsunset.jpg"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("image tools: %q missing", want)
+		}
+	}
+	if strings.Contains(body, "data-image-license") {
+		t.Error("image tools: unknown license kept")
+	}
+}
+
+func TestHandleImageSearch(t *testing.T) {
+	s := newRenderCacheServer(t)
+	rec := httptest.NewRecorder()
+	s.handleImageSearch(rec, httptest.NewRequest(http.MethodGet, "/search/image?url=https://img.example/cat.jpg", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("disabled: status %d, want 404", rec.Code)
+	}
+
+	s.config.Search.ReverseImage.Enabled = true
+	rec = httptest.NewRecorder()
+	s.handleImageSearch(rec, httptest.NewRequest(http.MethodGet, "/search/image?url=javascript:alert(1)", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad url: status %d, want 400", rec.Code)
+	}
+
+	// No engine in the synthetic registry searches by image
+	rec = httptest.NewRecorder()
+	s.handleImageSearch(rec, httptest.NewRequest(http.MethodGet, "/search/image?url=https://img.example/cat.jpg", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `class="reverse-image" open`) ||
+		!strings.Contains(body, `value="https://img.example/cat.jpg"`) || strings.Contains(body, `id="scroll-trigger"`) {
+		t.Errorf("results page: status %d", rec.Code)
+	}
+}
+
+// ---------- player.go ----------
+
+func TestSearchTemplateVideoTools(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.config.Search.VideoPlayer = config.VideoPlayerConfig{Enabled: true, Frontend: "nocookie"}
+	results := model.NewSearchResults("lecture", model.CategoryVideos)
+	results.AddResult(model.Result{Title: "Lecture", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Engine: "youtube", Duration: 3600,
+		Author: "Open Courses", Metadata: map[string]interface{}{"channel_url": "https://www.youtube.com/@opencourses"},
+		PublishedAt: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)})
+	results.AddResult(model.Result{Title: "Elsewhere", URL: "https://www.twitch.tv/videos/123456789", Engine: "duckduckgo", Author: "Streamer"})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=lecture&category=videos&video_length=long&video_quality=hd", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "lecture", results, string(model.CategoryVideos), nil)
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`data-video-length="long"`, `<option value="long" selected>Over 20 minutes</option>`, `value="hd" checked`,
+		`href="/watch?title=Lecture&amp;url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3DdQw4w9WgXcQ"`,
+		`<a href="https://www.youtube.com/@opencourses" target="_blank" rel="noopener noreferrer">Open Courses</a>`,
+		`<span class="video-author">Streamer</span>`, `class="video-date"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("video tools: %q missing", want)
+		}
+	}
+	if n := strings.Count(body, `class="video-watch"`); n != 1 {
+		t.Errorf("video tools: %d watch links, want 1 for the playable video", n)
+	}
+}
+
+func TestSearchTemplateNewsTools(t *testing.T) {
+	s := newRenderCacheServer(t)
+	results := model.NewSearchResults("election", model.CategoryNews)
+	results.AddResult(model.Result{Title: "Polls close", URL: "https://www.reuters.com/world/polls-close", Engine: "bingnews",
+		Category: model.CategoryNews, Author: "Reuters", PublishedAt: time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)})
+	results.AddResult(model.Result{Title: "Turnout", URL: "https://apnews.com/article/turnout", Engine: "gdelt", Category: model.CategoryNews})
+	results.AddResult(model.Result{Title: "Results", URL: "https://www.reuters.com/world/results", Engine: "gdelt", Category: model.CategoryNews, Author: "Reuters"})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=election&category=news&time_range=week&sort=date&group=publisher", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "election", results, string(model.CategoryNews), nil)
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`data-time-range="week"`, `<option value="week" selected>Past Week</option>`, `<option value="date" selected>Newest first</option>`,
+		`value="publisher" checked`, `<h2 class="news-group-publisher">Reuters</h2>`, `<h2 class="news-group-publisher">apnews.com</h2>`,
+		`class="news-date"`, `data-sort="date"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("news tools: %q missing", want)
+		}
+	}
+	if n := strings.Count(body, `class="news-group"`); n != 2 {
+		t.Errorf("news tools: %d publisher groups, want 2", n)
+	}
+	if strings.Contains(body, `id="scroll-trigger"`) {
+		t.Error("news tools: grouped results scroll in ungrouped pages")
+	}
+}
+
+func TestHandleWatch(t *testing.T) {
+	s := newRenderCacheServer(t)
+	rec := httptest.NewRecorder()
+	s.handleWatch(rec, httptest.NewRequest(http.MethodGet, "/watch?url=https://youtu.be/dQw4w9WgXcQ", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("disabled: status %d, want 404", rec.Code)
+	}
+
+	s.config.Search.VideoPlayer = config.VideoPlayerConfig{Enabled: true, Frontend: "invidious", Instance: "https://yewtu.be"}
+	rec = httptest.NewRecorder()
+	s.handleWatch(rec, httptest.NewRequest(http.MethodGet, "/watch?url=javascript:alert(1)", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad url: status %d, want 400", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Content-Security-Policy", "default-src 'self'; frame-src 'self'; media-src 'self' blob:")
+	s.handleWatch(rec, httptest.NewRequest(http.MethodGet, "/watch?url=https://youtu.be/dQw4w9WgXcQ&title=Song", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `<iframe src="https://yewtu.be/embed/dQw4w9WgXcQ"`) ||
+		!strings.Contains(body, `referrerpolicy="no-referrer"`) || !strings.Contains(body, "<h1>Song</h1>") {
+		t.Errorf("player page: status %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != "default-src 'self'; frame-src 'self' https://yewtu.be; media-src 'self' blob:" {
+		t.Errorf("player page CSP = %q", got)
+	}
+	if rec.Header().Get("Referrer-Policy") != "no-referrer" {
+		t.Error("player page sends a referrer")
+	}
+
+	// Not playable here: the page links to the original, never redirects
+	rec = httptest.NewRecorder()
+	s.handleWatch(rec, httptest.NewRequest(http.MethodGet, "/watch?url=https://www.twitch.tv/videos/123456789", nil))
+	body = rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Contains(body, "<iframe") || !strings.Contains(body, `href="https://www.twitch.tv/videos/123456789"`) {
+		t.Errorf("unplayable page: status %d", rec.Code)
+	}
+}
+
+func TestAllowCSPSource(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Security-Policy-Report-Only", "default-src 'self'")
+	allowCSPSource(h, "media-src", "https://media.example")
+	if got := h.Get("Content-Security-Policy-Report-Only"); got != "default-src 'self'; media-src 'self' https://media.example" {
+		t.Errorf("report-only policy = %q", got)
+	}
+	if h.Get("Content-Security-Policy") != "" {
+		t.Error("enforced policy added")
+	}
+}
+
+// ---------- geoboost.go ----------
+
+func TestSearchTemplateLocalizedBadge(t *testing.T) {
+	s := newRenderCacheServer(t)
+	results := model.NewSearchResults("wetter", model.CategoryGeneral)
+	results.AddResult(model.Result{Title: "Wetter Berlin", URL: "https://www.wetter.de/berlin", Engine: "duckduckgo", Localized: "domain"})
+	results.AddResult(model.Result{Title: "Weather", URL: "https://weather.example.com/", Engine: "duckduckgo"})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=wetter", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "wetter", results, string(model.CategoryGeneral), nil)
+	data.GeoCountry = "Germany"
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`data-geo-country="Germany"`,
+		`title="Ranked higher: the site&#39;s domain is from Germany">Localized`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("localized badge: %q missing", want)
+		}
+	}
+	if n := strings.Count(body, `class="result-localized"`); n != 1 {
+		t.Errorf("localized badge: %d badges, want 1", n)
+	}
+}
+
+func TestSearchCountryDisabled(t *testing.T) {
+	s := newRenderCacheServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/search?q=wetter", nil)
+	req.RemoteAddr = "81.169.145.1:1234"
+	if got := s.searchCountry(req); got != "" {
+		t.Errorf("searchCountry() with geo boost off = %q", got)
+	}
+	s.config.Search.GeoBoost.Enabled = true
+	if got := s.searchCountry(req); got != "" {
+		t.Errorf("searchCountry() without GeoIP = %q", got)
+	}
+}
+
+// ---------- engine_quota.go ----------
+
+func TestEngineQuotas(t *testing.T) {
+	quotas := engineQuotas(map[string]config.EngineConfig{
+		"synthetic-a": {Quota: config.EngineQuotaConfig{Daily: 200, PeakHours: "18-23", PeakReserve: 25, Fallback: "synthetic-b"}},
+		"synthetic-b": {Quota: config.EngineQuotaConfig{Daily: 0}},
+	})
+	want := search.EngineQuota{Daily: 200, PeakStart: 18, PeakEnd: 23, PeakReserve: 50, Fallback: "synthetic-b"}
+	if len(quotas) != 1 || quotas["synthetic-a"] != want {
+		t.Errorf("engineQuotas() = %+v", quotas)
+	}
+}
+
+func TestHandleEngineQuotas(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.aggregator.SetEngineQuotas(map[string]search.EngineQuota{"synthetic-a": {Daily: 1}})
+	if _, err := s.aggregator.Search(context.Background(), &model.Query{Text: "golang", Category: model.CategoryGeneral}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleEngineQuotas(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/quotas", nil))
+	var resp struct {
+		Data []search.QuotaStatus `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Used != 1 || resp.Data[0].State != search.QuotaExhausted {
+		t.Errorf("quotas = %+v", resp.Data)
+	}
+
+	m := &Metrics{
+		quotaUsed:      prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_quota_used"}, []string{"engine"}),
+		quotaRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_quota_remaining"}, []string{"engine"}),
+		certExpiry:     prometheus.NewGauge(prometheus.GaugeOpts{Name: "t_cert_expiry"}),
+	}
+	s.collectServerMetrics(m)
+	if got := testutil.ToFloat64(m.quotaRemaining.WithLabelValues("synthetic-a")); got != 0 {
+		t.Errorf("quota remaining metric = %v, want 0", got)
+	}
+}
+
+// ---------- schema_drift.go ----------
+
+func TestHandleSchemaDrift(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.registry = engine.SyntheticRegistry(0)
+	eng, _ := s.registry.Get("synthetic-c")
+	shaped := eng.(interface {
+		RecordResponseShape([]string, float64)
+	})
+	for i := 0; i < 20; i++ {
+		shaped.RecordResponseShape([]string{"div.result", "a.title", "p.snippet"}, search.DefaultDriftThreshold)
+	}
+	for i := 0; i < 5; i++ {
+		shaped.RecordResponseShape([]string{"div.card"}, search.DefaultDriftThreshold)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleSchemaDrift(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/drift", nil))
+	var resp struct {
+		Data []search.SchemaDrift `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 4 || resp.Data[0].Engine != "synthetic-c" || !resp.Data[0].ParserSuspect {
+		t.Fatalf("drift = %+v, want synthetic-c flagged first", resp.Data)
+	}
+
+	reset := func(name string) int {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("engine", name)
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/server/engines/drift/"+name, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleSchemaDriftReset(rec, req)
+		return rec.Code
+	}
+	if code := reset("Synthetic-C"); code != http.StatusOK {
+		t.Errorf("reset status = %d", code)
+	}
+	if d := eng.(interface{ SchemaDrift() search.SchemaDrift }).SchemaDrift(); d.Learned != 0 || d.ParserSuspect {
+		t.Errorf("after reset: %+v", d)
+	}
+	if code := reset("nope"); code != http.StatusNotFound {
+		t.Errorf("unknown engine status = %d", code)
+	}
+
+	m := &Metrics{
+		schemaDrift:   prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_schema_drift"}, []string{"engine"}),
+		parserSuspect: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_parser_suspect"}, []string{"engine"}),
+	}
+	m.SetEngineDrift("synthetic-c", 0.6, true)
+	m.SetEngineDrift("synthetic-c", 0.4, false)
+	if got := testutil.ToFloat64(m.parserSuspect.WithLabelValues("synthetic-c")); got != 0 {
+		t.Errorf("parser suspect metric = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(m.schemaDrift.WithLabelValues("synthetic-c")); got != 0.4 {
+		t.Errorf("schema drift metric = %v", got)
+	}
+}
+
+// ---------- politeness.go ----------
+
+func TestHostPoliteness(t *testing.T) {
+	p := config.DefaultConfig().Search.Politeness
+	p.Hosts = map[string]config.HostPolitenessConfig{"www.google.com": {MinInterval: "1s"}}
+	got := hostPoliteness(p)
+	want := search.Politeness{
+		Default:       search.HostPolicy{MinInterval: 250 * time.Millisecond, MaxConcurrent: 4},
+		Hosts:         map[string]search.HostPolicy{"www.google.com": {MinInterval: time.Second}},
+		HonorRobots:   true,
+		MaxCrawlDelay: 5 * time.Second,
+		MaxWait:       2 * time.Second,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hostPoliteness() = %+v, want %+v", got, want)
+	}
+	p.Disabled = true
+	if got := hostPoliteness(p); got.HonorRobots || got.Default != (search.HostPolicy{}) || len(got.Hosts) != 0 {
+		t.Errorf("disabled politeness = %+v", got)
+	}
+}
+
+func TestHandleEngineHosts(t *testing.T) {
+	s := newRenderCacheServer(t)
+	rec := httptest.NewRecorder()
+	s.handleEngineHosts(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/hosts", nil))
+	var resp struct {
+		OK   bool                `json:"ok"`
+		Data []search.HostStatus `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.OK || resp.Data == nil {
+		t.Errorf("response = %s, want an empty list", rec.Body.String())
+	}
+}
+
+// ---------- result_cache.go ----------
+
+func TestResultCachePolicy(t *testing.T) {
+	got := resultCachePolicy(config.DefaultConfig().Search.ResultCache)
+	want := search.ResultCachePolicy{TTL: 5 * time.Minute, Categories: map[model.Category]time.Duration{model.CategoryNews: time.Minute}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resultCachePolicy() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandleResultCache(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.cache = search.NewResultCache(cache.NewMemoryCache(100, time.Minute), time.Minute)
+	s.cache.SetFor("k", model.CategoryNews, &model.SearchResults{Query: "q"})
+	s.cache.GetFor("k", model.CategoryNews)
+	s.renderCache.put("page", []byte("<html>"))
+
+	rec := httptest.NewRecorder()
+	s.handleResultCache(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/cache", nil))
+	var resp struct {
+		OK   bool              `json:"ok"`
+		Data search.CacheStats `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.OK || resp.Data.Categories[model.CategoryNews].Hits != 1 {
+		t.Fatalf("stats = %+v", resp.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleResultCacheFlush(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/server/cache", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("flush: %d", rec.Code)
+	}
+	if s.cache.Get("k") != nil || s.renderCache.get("page") != nil {
+		t.Error("flush left entries behind")
+	}
+	if stats := s.cache.Stats(); stats.Categories[model.CategoryNews].Hits != 0 {
+		t.Errorf("flush kept the hit counts: %+v", stats.Categories[model.CategoryNews])
+	}
+}
+
+// ---------- user_agents.go ----------
+
+func TestHandleUserAgents(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.registry = engine.SyntheticRegistry(0)
+	s.config.Search.UserAgents = config.UserAgentsConfig{Policy: "fixed"}
+
+	call := func(handler http.HandlerFunc, method, name, body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("engine", name)
+		req := httptest.NewRequest(method, "/api/v1/server/engines/user-agents/"+name, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := call(s.handleUserAgentSet, http.MethodPut, "synthetic-a", `{"pin": "Bot/1.0"}`); rec.Code != http.StatusOK {
+		t.Fatalf("pin: %d %s", rec.Code, rec.Body.String())
+	}
+	if pin := s.config.Search.UserAgents.Engines["synthetic-a"].Pin; pin != "Bot/1.0" {
+		t.Errorf("saved pin = %q", pin)
+	}
+	for _, bad := range []struct{ name, body string }{
+		{"synthetic-a", `{"policy": "random"}`},
+		{"synthetic-a", `{"pin": "Bot\r\nX-Injected: 1"}`},
+		{"synthetic-a", `{}`},
+		{"nope", `{"policy": "best"}`},
+	} {
+		if rec := call(s.handleUserAgentSet, http.MethodPut, bad.name, bad.body); rec.Code == http.StatusOK {
+			t.Errorf("PUT %s %s accepted", bad.name, bad.body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleUserAgents(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/user-agents", nil))
+	var resp struct {
+		Data struct {
+			Pool    []string                 `json:"pool"`
+			Engines []search.UserAgentStatus `json:"engines"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Pool) == 0 || resp.Data.Pool[0] != engine.UserAgent {
+		t.Errorf("pool = %v, want the built-in pool", resp.Data.Pool)
+	}
+	if len(resp.Data.Engines) != 4 || resp.Data.Engines[0].Engine != "synthetic-a" || resp.Data.Engines[0].Policy != search.UserAgentPinned {
+		t.Errorf("engines = %+v", resp.Data.Engines)
+	}
+
+	if rec := call(s.handleUserAgentReset, http.MethodDelete, "synthetic-a", ""); rec.Code != http.StatusOK {
+		t.Errorf("reset: %d", rec.Code)
+	}
+	if _, ok := s.config.Search.UserAgents.Engines["synthetic-a"]; ok {
+		t.Error("pin kept after reset")
+	}
+	if rec := call(s.handleUserAgentReset, http.MethodDelete, "synthetic-a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second reset: %d", rec.Code)
+	}
+}
+
+// ---------- docs.go ----------
+
+func TestHandleDocs(t *testing.T) {
+	s := newRenderCacheServer(t)
+	get := func(handler http.HandlerFunc, target, page string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rctx := chi.NewRouteContext()
+		if page != "" {
+			rctx.URLParams.Add("page", page)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := get(s.handleDocs, "/server/docs/configuration", "configuration")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("configuration page: %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`<h3 id="politeness"`, `href="/server/docs/config-reference"`, `aria-current="page"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("configuration page lacks %q", want)
+		}
+	}
+
+	rec = get(s.handleDocs, "/server/docs?q=crawl+delay", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/server/docs/configuration#politeness"`) {
+		t.Errorf("search: %d, no hit for the politeness section", rec.Code)
+	}
+
+	if rec := get(s.handleDocs, "/server/docs/nope", "nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown page: %d", rec.Code)
+	}
+
+	rec = get(handleDocsRedirect, "/docs/api?q=x", "api")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/server/docs/api?q=x" {
+		t.Errorf("redirect: %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+// ---------- keys.go ----------
+
+func TestKeyRotation(t *testing.T) {
+	dm, err := database.NewDatabaseManager(&database.Config{Driver: "sqlite", DataDir: t.TempDir(), MaxOpen: 5, MaxIdle: 2, Lifetime: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	ctx := context.Background()
+	if err := database.InitSchema(ctx, dm); err != nil {
+		t.Fatal(err)
+	}
+	oldReportKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("r", 32)))
+	creds, err := ssl.EncryptCredentials(map[string]string{"api_token": "cf"}, "old-secret-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Update(func(sc *config.ServerConfig) {
+		sc.SecretKey = "old-secret-key"
+		sc.Security.EncryptionKey = oldReportKey
+		sc.SSL.DNS01.CredentialsEncrypted = creds
+		sc.JWT.Enabled = true
+		sc.JWT.TTL = "15m"
+		sc.JWT.Keys = []config.JWTKeyConfig{{ID: "k1", Secret: strings.Repeat("j", 32)}}
+		sc.Security.Keys.RotateAfter = "30d"
+	})
+	body, _ := security.EncryptAESGCM(oldReportKey, []byte("report"))
+	if err := security.InsertReport(ctx, dm.ServerDB(), security.Report{TrackingID: "sec_1", EncryptedBody: []byte(body), EncryptionMethod: security.EncryptionMethodAESGCM}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: cfg, dbManager: dm}
+	rotate := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/server/keys/"+name+"/rotate", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("name", name)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleKeyRotate(rec, req)
+		return rec
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleKeys(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/keys", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "old-secret-key") {
+		t.Fatalf("keys: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			Keys []keyStatus `json:"keys"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Data.Keys) != 4 {
+		t.Fatalf("keys: %s", rec.Body)
+	}
+	if k := resp.Data.Keys[0]; k.Name != security.KeySecretKey || k.Version != 1 || !k.Scheduled || k.NextRotation.IsZero() || len(k.Protects) != 1 || k.Protects[0].Items != 1 {
+		t.Errorf("secret_key status = %+v", k)
+	}
+	if k := resp.Data.Keys[1]; k.Name != security.KeyEncryptionKey || len(k.Protects) != 1 || k.Protects[0].Items != 1 {
+		t.Errorf("encryption_key status = %+v", k)
+	}
+
+	if rec := rotate("session"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown key: %d", rec.Code)
+	}
+	if rec := rotate(security.KeyEncryptionKey); rec.Code != http.StatusOK {
+		t.Fatalf("rotate encryption_key: %d %s", rec.Code, rec.Body)
+	}
+	var stored []byte
+	if err := dm.ServerDB().QueryRow(ctx, `SELECT encrypted_body FROM security_reports`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := security.DecryptAESGCM(cfg.Get().Security.EncryptionKey, string(stored)); err != nil || string(plaintext) != "report" {
+		t.Errorf("report under the new key = %q, %v", plaintext, err)
+	}
+
+	if rec := rotate(security.KeySecretKey); rec.Code != http.StatusOK {
+		t.Fatalf("rotate secret_key: %d %s", rec.Code, rec.Body)
+	}
+	sc := cfg.Get()
+	if got, err := ssl.DecryptCredentials(sc.SSL.DNS01.CredentialsEncrypted, sc.SecretKey); err != nil || got["api_token"] != "cf" || sc.SecretKey == "old-secret-key" {
+		t.Errorf("dns01 credentials under the new key = %v, %v", got, err)
+	}
+	if v, _ := security.CurrentKeyVersion(ctx, dm.ServerDB(), security.KeySecretKey); v.Version != 2 || v.Reason != "rotated" {
+		t.Errorf("secret_key version = %+v", v)
+	}
+
+	// A failed re-encryption leaves the old secret in place
+	cfg.Update(func(sc *config.ServerConfig) { sc.SSL.DNS01.CredentialsEncrypted = "garbage" })
+	before := cfg.Get().SecretKey
+	if rec := rotate(security.KeySecretKey); rec.Code != http.StatusInternalServerError || cfg.Get().SecretKey != before {
+		t.Errorf("failed rotation: %d, secret changed = %v", rec.Code, cfg.Get().SecretKey != before)
+	}
+
+	// The scheduled task rotates the JWT key once due and later retires
+	// the old one
+	now := time.Now()
+	if err := s.rotateDueKeys(ctx, now.Add(31*24*time.Hour)); err == nil {
+		t.Error("rotateDueKeys() hid the secret_key failure")
+	}
+	if keys := cfg.Get().JWT.Keys; len(keys) != 2 || keys[1].ID != "k1" {
+		t.Fatalf("jwt keys after scheduled rotation = %+v", keys)
+	}
+	if err := s.retireJWTKeys(ctx, now.Add(31*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if keys := cfg.Get().JWT.Keys; len(keys) != 2 {
+		t.Errorf("jwt keys retired before the ttl: %+v", keys)
+	}
+	if err := s.retireJWTKeys(ctx, now.Add(31*24*time.Hour+16*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if keys := cfg.Get().JWT.Keys; len(keys) != 1 || keys[0].ID == "k1" {
+		t.Errorf("jwt keys after the ttl = %+v", keys)
+	}
+}
+
+// ---------- ranking.go ----------
+
+func TestRankingPipeline(t *testing.T) {
+	if stages := rankingPipeline(config.DefaultConfig().Search.Ranking.Stages).Stages(); len(stages) != 0 {
+		t.Errorf("default pipeline runs %v", stages)
+	}
+	got := rankingPipeline([]config.RankingStageConfig{
+		{Name: "duplicate_penalty", Enabled: true, Factor: 0.8},
+		{Name: "engine_weight"},
+		{Name: "freshness", Enabled: true, Boost: 0.5, HalfLife: "7d"},
+	}).Stages()
+	if !reflect.DeepEqual(got, []string{"duplicate_penalty", "freshness"}) {
+		t.Errorf("pipeline = %v", got)
+	}
+}
+
+func TestHandleRanking(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.renderCache.put("page", []byte("<html>"))
+
+	for body, want := range map[string]int{
+		`not json`: http.StatusBadRequest,
+		`{"stages":[{"name":"pagerank","enabled":true}]}`:                             http.StatusBadRequest,
+		`{"stages":[{"name":"freshness"},{"name":"freshness"}]}`:                      http.StatusBadRequest,
+		`{"stages":[{"name":"domain_authority","enabled":true,"weights":{"x":100}}]}`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		s.handleRankingSet(rec, httptest.NewRequest(http.MethodPut, "/api/v1/server/ranking", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("PUT %s = %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"stages":[{"name":"Freshness","enabled":true},{"name":"engine_weight","enabled":false}]}`
+	s.handleRankingSet(rec, httptest.NewRequest(http.MethodPut, "/api/v1/server/ranking", strings.NewReader(body)))
+	var resp struct {
+		Data rankingView `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d %s", rec.Code, rec.Body.String())
+	}
+	if !reflect.DeepEqual(resp.Data.Active, []string{"freshness"}) || len(resp.Data.Stages) != 2 || resp.Data.Stages[0].HalfLife != "7d" {
+		t.Errorf("ranking = %+v", resp.Data)
+	}
+	if s.config.Search.Ranking.Stages[0].Name != "freshness" || s.aggregator.Ranker() == nil {
+		t.Error("pipeline not applied")
+	}
+	if s.renderCache.get("page") != nil {
+		t.Error("rendered pages kept their old order")
+	}
+
+	for body, want := range map[string]int{
+		`{}`: http.StatusBadRequest,
+		`{"query":"golang","category":"recipes"}`:    http.StatusBadRequest,
+		`{"query":"golang","stages":[{"name":"x"}]}`: http.StatusBadRequest,
+		`{"query":"golang","stages":[]}`:             http.StatusOK,
+		`{"query":"golang","category":"general"}`:    http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		s.handleRankingPreview(rec, httptest.NewRequest(http.MethodPost, "/api/v1/server/ranking/preview", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("preview %s = %d, want %d: %s", body, rec.Code, want, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	body = `{"query":"golang","stages":[{"name":"duplicate_penalty","enabled":true,"factor":0.5}]}`
+	s.handleRankingPreview(rec, httptest.NewRequest(http.MethodPost, "/api/v1/server/ranking/preview", strings.NewReader(body)))
+	var preview struct {
+		Data struct {
+			Stages  []string            `json:"stages"`
+			Results []search.RankChange `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(preview.Data.Stages, []string{"duplicate_penalty"}) || len(preview.Data.Results) == 0 {
+		t.Fatalf("preview = %+v", preview.Data)
+	}
+	for i, r := range preview.Data.Results {
+		if r.Rank != i+1 || r.Current == 0 {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+}
+
+// ---------- circuit_breaker.go ----------
+
+func TestCircuitBreakerConfig(t *testing.T) {
+	if got := circuitBreaker(config.DefaultConfig().Search.CircuitBreaker); got != search.DefaultCircuitBreaker {
+		t.Errorf("default breaker = %+v, want %+v", got, search.DefaultCircuitBreaker)
+	}
+	cb := config.DefaultConfig().Search.CircuitBreaker
+	cb.Disabled = true
+	cb.SlowCall = "0"
+	if got := circuitBreaker(cb); !got.Disabled || got.SlowCall != 0 {
+		t.Errorf("breaker = %+v", got)
+	}
+}
+
+func TestHandleEngineCircuitReset(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.registry = engine.SyntheticRegistry(0)
+	s.aggregator = search.NewAggregator(s.registry.GetEnabled(), search.AggregatorConfig{Timeout: 5 * time.Second})
+	s.aggregator.SetCircuitBreaker(search.CircuitBreaker{FailureThreshold: 1, Cooldown: time.Minute})
+	eng, _ := s.registry.Get("synthetic-a")
+	tracker := eng.(interface {
+		RecordFailure(error)
+		GetHealth() search.EngineHealth
+	})
+	tracker.RecordFailure(model.ErrEngineUnavailable)
+	if h := tracker.GetHealth(); h.Circuit != search.CircuitOpen {
+		t.Fatalf("circuit = %s, want open", h.Circuit)
+	}
+
+	reset := func(name string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("engine", name)
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/server/engines/circuits/"+name, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleEngineCircuitReset(rec, req)
+		return rec
+	}
+	rec := reset("Synthetic-A")
+	var resp struct {
+		Data search.EngineHealth `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("reset = %d %s", rec.Code, rec.Body.String())
+	}
+	if resp.Data.Circuit != search.CircuitClosed || tracker.GetHealth().Circuit != search.CircuitClosed {
+		t.Errorf("after reset: %+v", resp.Data)
+	}
+	if rec := reset("nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown engine status = %d", rec.Code)
+	}
+
+	m := &Metrics{
+		circuitOpen: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_circuit_open"}, []string{"engine"}),
+		engineP95:   prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "t_engine_p95"}, []string{"engine"}),
+	}
+	m.SetEngineCircuit("synthetic-a", true, 1500*time.Millisecond)
+	if got := testutil.ToFloat64(m.circuitOpen.WithLabelValues("synthetic-a")); got != 1 {
+		t.Errorf("circuit open metric = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.engineP95.WithLabelValues("synthetic-a")); got != 1.5 {
+		t.Errorf("p95 metric = %v, want 1.5", got)
+	}
+}
+
+// ---------- macros.go ----------
+
+func TestSearchMacroExpansion(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.config.Search.Macros = []config.MacroConfig{{Name: "issues", Query: "site:github.com/{repo}/issues"}}
+
+	rec := httptest.NewRecorder()
+	s.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/search?q=~issues+apimgr/search+leak&category=it&page=3", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want a redirect", rec.Code)
+	}
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	if q := loc.Query(); loc.Path != "/search" || q.Get("q") != "site:github.com/apimgr/search/issues leak" || q.Get("category") != "it" || q.Has("page") {
+		t.Errorf("Location = %s", loc)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/search?q=~issues", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "~issues {repo}") {
+		t.Errorf("missing argument status = %d", rec.Code)
+	}
+}
+
+func TestHandleMacroPreferencesSave(t *testing.T) {
+	s := newRenderCacheServer(t)
+	post := func(form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/preferences/macros", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		s.handleMacroPreferencesSave(rec, req)
+		return rec
+	}
+	saved := func(rec *httptest.ResponseRecorder) *http.Cookie {
+		t.Helper()
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("status = %d", rec.Code)
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == macro.CookieName {
+				return c
+			}
+		}
+		t.Fatal("no macros cookie set")
+		return nil
+	}
+
+	cookie := saved(post(url.Values{"action": {"add"}, "name": {"~Issues"}, "query": {"site:github.com/{repo}/issues"}}, nil))
+	got := macro.DecodeCookie(cookie.Value)
+	if len(got) != 1 || got[0].Name != "issues" {
+		t.Fatalf("saved macros = %+v", got)
+	}
+	if rec := post(url.Values{"action": {"add"}, "name": {"loop"}, "query": {"~issues x"}}, cookie); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid macro status = %d", rec.Code)
+	}
+	if c := saved(post(url.Values{"action": {"delete"}, "name": {"issues"}}, cookie)); c.MaxAge >= 0 {
+		t.Errorf("deleting the last macro left the cookie: %+v", c)
+	}
+
+	full := make([]macro.Macro, macro.MaxUser)
+	for i := range full {
+		full[i] = macro.Macro{Name: fmt.Sprintf("m%d", i), Query: "q"}
+	}
+	cookie = &http.Cookie{Name: macro.CookieName, Value: macro.EncodeCookie(full)}
+	if rec := post(url.Values{"name": {"one-more"}, "query": {"q"}}, cookie); rec.Code != http.StatusBadRequest {
+		t.Errorf("adding past the limit status = %d", rec.Code)
+	}
+	if c := saved(post(url.Values{"name": {"m0"}, "query": {"replaced"}}, cookie)); macro.DecodeCookie(c.Value)[0].Query != "replaced" {
+		t.Error("re-adding a macro did not replace it")
+	}
+
+	// The preferences page lists both sets
+	s.bangManager = bang.NewManager()
+	s.config.Search.Macros = []config.MacroConfig{{Name: "issues", Query: "site:github.com/{repo}/issues"}}
+	req := httptest.NewRequest(http.MethodGet, "/preferences", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	s.handlePreferences(rec, req)
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "~issues {repo}") || !strings.Contains(body, `value="m19"`) {
+		t.Errorf("preferences page status = %d, missing macros", rec.Code)
+	}
+}
+
+// ---------- engine_tester.go ----------
+
+func TestHandleEngineTest(t *testing.T) {
+	s := newRenderCacheServer(t)
+	run := func(name, body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("engine", name)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/server/engines/"+name+"/test", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleEngineTest(rec, req)
+		return rec
+	}
+
+	rec := run("Synthetic-A", `{"query": "golang", "redact": false}`)
+	var resp struct {
+		Data search.EngineTest `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("test = %d %s", rec.Code, rec.Body.String())
+	}
+	if resp.Data.Engine != "synthetic-a" || len(resp.Data.Results) == 0 || resp.Data.Redacted || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("test = %+v", resp.Data)
+	}
+	// Synthetic engines never reach the network
+	if resp.Data.Exchanges == nil || len(resp.Data.Exchanges) != 0 {
+		t.Errorf("exchanges = %+v", resp.Data.Exchanges)
+	}
+
+	for body, want := range map[string]int{
+		`{"query": ""}`:                         http.StatusBadRequest,
+		`not json`:                              http.StatusBadRequest,
+		`{"query": "x", "category": "nothing"}`: http.StatusBadRequest,
+	} {
+		if rec := run("synthetic-a", body); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+	if rec := run("nope", `{"query": "x"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown engine status = %d", rec.Code)
+	}
+}
+
+// ---------- opensearch.go ----------
+
+func TestOpenSearchDescriptionURLs(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.BaseURL = "https://search.example.com"
+	cfg.Search.OpenSearch.Image = "/static/img/favicon.svg"
+	s := &Server{config: cfg}
+
+	rec := httptest.NewRecorder()
+	s.handleOpenSearch(rec, httptest.NewRequest(http.MethodGet, "/opensearch.xml", nil))
+	var osd OpenSearchDescription
+	if err := xml.Unmarshal(rec.Body.Bytes(), &osd); err != nil {
+		t.Fatal(err)
+	}
+	templates := map[string]string{}
+	for _, u := range osd.URLs {
+		templates[u.Type] = u.Template
+	}
+	if templates["application/x-suggestions+json"] != "https://search.example.com/api/v1/autocomplete?q={searchTerms}&format=opensearch" {
+		t.Errorf("suggestions template = %q", templates["application/x-suggestions+json"])
+	}
+	if templates["application/opensearchdescription+xml"] != "https://search.example.com/opensearch.xml" {
+		t.Errorf("self template = %q", templates["application/opensearchdescription+xml"])
+	}
+	if osd.Image == nil || osd.Image.Type != "image/svg+xml" || osd.Image.URL != "https://search.example.com/static/img/favicon.svg" {
+		t.Errorf("image = %+v", osd.Image)
+	}
+}
+
+// ---------- chaos.go ----------
+
+func TestHandleChaos(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.registry = engine.SyntheticRegistry(0)
+	call := func(handler http.HandlerFunc, method, name, body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("engine", name)
+		req := httptest.NewRequest(method, "/api/v1/server/engines/chaos/"+name, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	s.config.Server.Mode = "production"
+	if rec := call(s.handleChaosSet, http.MethodPut, "synthetic-a", `{"fault": "error"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("production PUT status = %d", rec.Code)
+	}
+
+	s.config.Server.Mode = "development"
+	if rec := call(s.handleChaosSet, http.MethodPut, "Synthetic-A", `{"latency": "10ms", "fault": "Error", "rate": 0.5}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", rec.Code, rec.Body.String())
+	}
+	if fault := s.aggregator.Chaos()["synthetic-a"]; fault.Fault != search.ChaosError || fault.Latency != 10*time.Millisecond || fault.Rate != 0.5 {
+		t.Errorf("applied fault = %+v", fault)
+	}
+	for _, bad := range []struct{ name, body string }{
+		{"synthetic-a", `{"fault": "explode"}`},
+		{"synthetic-a", `{"latency": "soon"}`},
+		{"synthetic-a", `{"fault": "error", "rate": 2}`},
+		{"synthetic-a", `{}`},
+		{"nope", `{"fault": "error"}`},
+	} {
+		if rec := call(s.handleChaosSet, http.MethodPut, bad.name, bad.body); rec.Code == http.StatusOK {
+			t.Errorf("PUT %s %s accepted", bad.name, bad.body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleChaos(rec, httptest.NewRequest(http.MethodGet, "/api/v1/server/engines/chaos", nil))
+	if !strings.Contains(rec.Body.String(), `"engine":"synthetic-a"`) {
+		t.Errorf("GET = %s", rec.Body.String())
+	}
+
+	if rec := call(s.handleChaosReset, http.MethodDelete, "synthetic-a", ""); rec.Code != http.StatusOK || len(s.aggregator.Chaos()) != 0 {
+		t.Errorf("DELETE: %d, still injecting %v", rec.Code, s.aggregator.Chaos())
+	}
+	if rec := call(s.handleChaosReset, http.MethodDelete, "synthetic-a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d", rec.Code)
+	}
+
+	// A chaos entry left in a production server.yml injects nothing
+	s.config.Search.Chaos.Engines = map[string]config.ChaosEngineConfig{"synthetic-a": {Fault: "timeout"}}
+	s.config.Server.Mode = "production"
+	if faults := chaosFaults(s.config); faults != nil {
+		t.Errorf("production faults = %v", faults)
+	}
+}
+
+// ---------- access_log.go ----------
+
+func TestRotateAccessLog(t *testing.T) {
+	s := newRenderCacheServer(t)
+	dir := t.TempDir()
+	s.logManager = logging.NewManager(dir)
+	defer s.logManager.Close()
+	s.config.Server.Logs.Access.Rotate = "weekly,1KB"
+	configureAccessLog(s.logManager.Access(), s.config.Server.Logs.Access)
+
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	if rotated, err := s.rotateAccessLog(monday); rotated || err != nil {
+		t.Errorf("an empty log rotated: %v, %v", rotated, err)
+	}
+	s.logManager.Access().Log(logging.AccessEntry{IP: "-", Method: "GET", Path: "/", Status: 200})
+	if rotated, _ := s.rotateAccessLog(monday.AddDate(0, 0, 1)); rotated {
+		t.Error("a small log rotated on a Tuesday")
+	}
+	if rotated, err := s.rotateAccessLog(monday); !rotated || err != nil {
+		t.Errorf("weekly rotation on a Monday: %v, %v", rotated, err)
+	}
+	for range 20 {
+		s.logManager.Access().Log(logging.AccessEntry{IP: "-", Method: "GET", Path: "/" + strings.Repeat("x", 100), Status: 200})
+	}
+	if rotated, _ := s.rotateAccessLog(monday.AddDate(0, 0, 2)); !rotated {
+		t.Error("a log over its size limit did not rotate")
+	}
+
+	tests := []struct {
+		rot  config.LogRotation
+		now  time.Time
+		want bool
+	}{
+		{config.LogRotation{Period: "daily"}, monday.AddDate(0, 0, 3), true},
+		{config.LogRotation{Period: "monthly"}, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), true},
+		{config.LogRotation{Period: "monthly"}, monday, false},
+		{config.LogRotation{}, monday, false},
+	}
+	for _, tt := range tests {
+		if got := rotationDue(tt.rot, tt.now, 10); got != tt.want {
+			t.Errorf("rotationDue(%+v, %s) = %v", tt.rot, tt.now.Format("Mon 2 Jan"), got)
+		}
+	}
+
+	// A disabled access log writes nothing
+	s.config.Server.Logs.Access.Disabled = true
+	configureAccessLog(s.logManager.Access(), s.config.Server.Logs.Access)
+	before := s.logManager.Access().Size()
+	s.logManager.Access().Log(logging.AccessEntry{IP: "-", Method: "GET", Path: "/", Status: 200})
+	if s.logManager.Access().Size() != before {
+		t.Error("a disabled access log grew")
+	}
+}
+
+func TestSearchTemplateFiles(t *testing.T) {
+	s := newRenderCacheServer(t)
+	results := model.NewSearchResults("ubuntu", model.CategoryFiles)
+	results.AddResult(model.Result{Title: "Ubuntu 24.04 Desktop", URL: "https://thepiratebay.org/description.php?id=123", Engine: "piratebay",
+		Category: model.CategoryFiles, FileSize: 6114656256,
+		Metadata: map[string]interface{}{"seeders": 42, "leechers": 7, "magnet": "magnet:?xt=urn:btih:abc&dn=Ubuntu"}})
+	results.AddResult(model.Result{Title: "Ubuntu archive", URL: "https://archive.org/details/ubuntu", Engine: "archiveorg",
+		Category: model.CategoryFiles, Metadata: map[string]interface{}{"torrent": "https://archive.org/download/ubuntu/ubuntu_archive.torrent"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=ubuntu&category=files", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "ubuntu", results, string(model.CategoryFiles), nil)
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<span class="file-size">5.7 GiB</span>`, `<span class="file-seeders">42 seeders</span>`, `<span class="file-leechers">7 leechers</span>`,
+		`href="magnet:?xt=urn:btih:abc&amp;dn=Ubuntu"`, `href="https://archive.org/download/ubuntu/ubuntu_archive.torrent"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("files layout: %q missing", want)
+		}
+	}
+	// The archive item has no peer counts
+	if n := strings.Count(body, `class="file-seeders"`); n != 1 {
+		t.Errorf("files layout: %d seeder counts, want 1", n)
+	}
+}
+
+// ---------- maps.go ----------
+
+func TestSearchTemplateMaps(t *testing.T) {
+	s := newRenderCacheServer(t)
+	results := model.NewSearchResults("berlin", model.CategoryMaps)
+	results.AddResult(model.Result{Title: "Berlin", URL: "https://www.openstreetmap.org/relation/62422", Engine: "openstreetmap",
+		Category: model.CategoryMaps, Metadata: map[string]interface{}{"latitude": 52.5173885, "longitude": 13.3951309,
+			"place_type": "city", "address": "Berlin, Germany", "bbox": []float64{52.33, 52.67, 13.08, 13.76}}})
+	results.AddResult(model.Result{Title: "Somewhere", URL: "https://example.com/", Engine: "openstreetmap", Category: model.CategoryMaps, Content: "No coordinates"})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=berlin&category=maps", nil)
+	rec := httptest.NewRecorder()
+	data := s.buildSearchPageData(rec, req, "berlin", results, string(model.CategoryMaps), nil)
+	if err := s.renderer.Render(rec, "search", data); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`id="results-map"`, `data-tiles="https://tile.openstreetmap.org/{z}/{x}/{y}.png"`,
+		`src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js" integrity="sha256-`,
+		`data-lat="52.5173885" data-lon="13.3951309" data-bbox="52.33,52.67,13.08,13.76"`,
+		`<span class="map-place-type">City</span>`, `<span class="map-coordinates">52.51739, 13.39513</span>`,
+		`Berlin, Germany`, `No coordinates`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("maps layout: %q missing", want)
+		}
+	}
+	// The place without coordinates cannot be shown on the map
+	if n := strings.Count(body, `data-map-focus`); n != 1 {
+		t.Errorf("maps layout: %d map buttons, want 1", n)
+	}
+}
+
+func TestAllowMapSources(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:")
+	allowMapSources(h, config.MapsConfig{Tiles: "https://{s}.tiles.example/{z}/{x}/{y}.png", Leaflet: "https://static.example/leaflet"})
+	want := "default-src 'self'; img-src 'self' data: https://static.example https://*.tiles.example; script-src 'self' https://static.example; style-src 'self' https://static.example"
+	if got := h.Get("Content-Security-Policy"); got != want {
+		t.Errorf("policy = %q, want %q", got, want)
+	}
+
+	if leafletIntegrity("https://static.example/leaflet", "leaflet.js") != "" {
+		t.Error("self-hosted Leaflet pinned")
+	}
+	if leafletIntegrity(config.DefaultLeafletURL, "leaflet.js") == "" {
+		t.Error("default Leaflet not pinned")
+	}
+}
+
+func TestServerCategories(t *testing.T) {
+	s := newRenderCacheServer(t)
+	set := func(category, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/server/categories/"+category, strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("category", category)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleServerCategorySet(rec, req)
+		return rec
+	}
+
+	if rec := set("general", `{"enabled":false}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("disable general: status %d, want 400", rec.Code)
+	}
+	if rec := set("warez", `{"enabled":false}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown category: status %d, want 404", rec.Code)
+	}
+	if rec := set("files", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("no enabled flag: status %d, want 400", rec.Code)
+	}
+	if rec := set("files", `{"enabled":false}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"category":"files","enabled":false`) {
+		t.Fatalf("disable files: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if len(s.config.Search.DisabledCategories) != 1 || s.aggregator.CategoryEnabled(model.CategoryFiles) {
+		t.Errorf("disabled categories = %v", s.config.Search.DisabledCategories)
+	}
+
+	// The tab is gone and a search in the category is not found
+	rec := httptest.NewRecorder()
+	s.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/search?q=ubuntu&category=files", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("search files: status %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/search?q=ubuntu", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "category=files") {
+		t.Errorf("search general: status %d, files tab shown %v", rec.Code, strings.Contains(rec.Body.String(), "category=files"))
+	}
+
+	if rec := set("files", `{"enabled":true}`); rec.Code != http.StatusOK || len(s.config.Search.DisabledCategories) != 0 {
+		t.Errorf("enable files: status %d, disabled %v", rec.Code, s.config.Search.DisabledCategories)
+	}
+}
+
+func TestServePrefixed(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.BasePath = "/search"
+	s := &Server{config: cfg}
+	var routed string
+	handler := s.servePrefixed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routed = r.URL.Path
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "alerts", Value: "1", Path: "/alerts"})
+		http.Redirect(w, r, "/preferences?saved=1", http.StatusSeeOther)
+	}))
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := serve("/search/search?q=go")
+	if routed != "/search" {
+		t.Errorf("routed path = %q, want /search", routed)
+	}
+	if got := rec.Header().Get("Location"); got != "/search/preferences?saved=1" {
+		t.Errorf("Location = %q", got)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 2 || cookies[0].Path != "/search" || cookies[1].Path != "/search/alerts" {
+		t.Errorf("cookies = %v", cookies)
+	}
+
+	if rec := serve("/search?q=go"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/search/?q=go" {
+		t.Errorf("bare base path = %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := serve("/preferences"); rec.Code != http.StatusNotFound {
+		t.Errorf("path outside the base path = %d, want 404", rec.Code)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.AllowedHosts = []string{"example.com", "www.example.com"}
+	cfg.Server.CanonicalHost = config.CanonicalHostConfig{Host: "example.com", HTTPS: true}
+	s := &Server{config: cfg}
+	handler := s.checkHost(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, target, host string, secure bool, remote ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Host = host
+		if secure {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if len(remote) > 0 {
+			req.RemoteAddr = remote[0]
+		}
+		if len(remote) > 1 {
+			req.Header.Set("X-Forwarded-For", remote[1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		name, method, target, host string
+		secure                     bool
+		code                       int
+		location                   string
+		remote                     []string
+	}{
+		{"canonical", http.MethodGet, "/search?q=go", "example.com", true, http.StatusOK, "", nil},
+		{"www to apex", http.MethodGet, "/search?q=go", "www.example.com", true, http.StatusMovedPermanently, "https://example.com/search?q=go", nil},
+		{"http to https", http.MethodGet, "/", "example.com:80", false, http.StatusMovedPermanently, "https://example.com/", nil},
+		{"post keeps its method", http.MethodPost, "/api/v1/search", "www.example.com", true, http.StatusPermanentRedirect, "https://example.com/api/v1/search", nil},
+		{"unexpected host", http.MethodGet, "/", "evil.example", true, http.StatusMisdirectedRequest, "", nil},
+		{"health check by address", http.MethodGet, "/server/healthz", "10.1.2.3:8080", false, http.StatusOK, "", nil},
+		{"api health check by address", http.MethodGet, "/api/v1/healthz", "10.1.2.3:8080", false, http.StatusOK, "", nil},
+		{"path ending like a health check", http.MethodGet, "/search/healthz", "10.1.2.3:8080", false, http.StatusMisdirectedRequest, "", nil},
+		{"root health alias turned off", http.MethodGet, "/healthz", "10.1.2.3:8080", false, http.StatusMisdirectedRequest, "", nil},
+		{"loopback from this machine", http.MethodGet, "/", "127.0.0.1:8080", false, http.StatusOK, "", []string{"127.0.0.1:51000"}},
+		{"localhost from this machine", http.MethodGet, "/", "localhost:8080", false, http.StatusOK, "", []string{"[::1]:51000"}},
+		{"loopback host from elsewhere", http.MethodGet, "/", "127.0.0.1:8080", false, http.StatusMisdirectedRequest, "", nil},
+		{"localhost through a local proxy", http.MethodGet, "/", "localhost", false, http.StatusMisdirectedRequest, "", []string{"127.0.0.1:51000", "203.0.113.9"}},
+		{"onion this instance does not publish", http.MethodGet, "/", "abcdefghijklmnop.onion", false, http.StatusMisdirectedRequest, "", nil},
+		{"i2p host", http.MethodGet, "/", "abcdefghijklmnop.b32.i2p", false, http.StatusMisdirectedRequest, "", nil},
+		{"acme challenge over http", http.MethodGet, "/.well-known/acme-challenge/token", "example.com", false, http.StatusOK, "", nil},
+	} {
+		rec := serve(tc.method, tc.target, tc.host, tc.secure, tc.remote...)
+		if rec.Code != tc.code || rec.Header().Get("Location") != tc.location {
+			t.Errorf("%s: %d %q, want %d %q", tc.name, rec.Code, rec.Header().Get("Location"), tc.code, tc.location)
+		}
+	}
+
+	// Health checks are matched under the base path
+	cfg.Server.BasePath = "/search"
+	if rec := serve(http.MethodGet, "/search/server/healthz", "10.1.2.3", false); rec.Code != http.StatusOK {
+		t.Errorf("health check under the base path: %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/server/healthz", "10.1.2.3", false); rec.Code != http.StatusMisdirectedRequest {
+		t.Errorf("health check outside the base path: %d", rec.Code)
+	}
+	cfg.Server.BasePath = ""
+
+	cfg.Server.UnexpectedHost = config.UnexpectedHostRedirect
+	if rec := serve(http.MethodGet, "/about", "evil.example", false); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/about" {
+		t.Errorf("redirected unexpected host: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestPrefixRootLinks(t *testing.T) {
+	in := `<a href="/direct/dict/go">go</a> <a href="//cdn.example.com/x">x</a> <img src="https://example.com/i.png">`
+	want := `<a href="/search/direct/dict/go">go</a> <a href="//cdn.example.com/x">x</a> <img src="https://example.com/i.png">`
+	if got := prefixRootLinks(in, "/search"); got != want {
+		t.Errorf("prefixRootLinks = %s", got)
+	}
+	if got := prefixRootLinks(in, ""); got != in {
+		t.Errorf("prefixRootLinks without a base path = %s", got)
+	}
+}
+
+func TestSearchPageBasePath(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.config.Server.BasePath = "/search"
+	req := httptest.NewRequest(http.MethodGet, "/search?q=golang", nil)
+	rec := httptest.NewRecorder()
+	s.handleSearch(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `data-base-path="/search"`) {
+		t.Fatalf("search page: %d, no data-base-path", rec.Code)
+	}
+	for _, link := range []string{`href="/search/static/`, `src="/search/static/js/app.js`, `action="/search/search"`} {
+		if !strings.Contains(body, link) {
+			t.Errorf("search page has no %s", link)
+		}
+	}
+	for _, link := range []string{`href="/static/`, `src="/static/`, `action="/search"`} {
+		if strings.Contains(body, link) {
+			t.Errorf("search page still links %s", link)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -2998,6 +2999,52 @@ func TestCSRFProtect_GetGeneratesToken(t *testing.T) {
 	}
 }
 
+// TestCSRFProtect_CookieAttributes applies server.security.cookies.csrf.
+func TestCSRFProtect_CookieAttributes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.Security.CSRF.Enabled = true
+	cfg.Server.Security.CSRF.CookieName = "csrf_token"
+	cfg.Server.Security.Cookies.CSRF = config.CookieConfig{SameSite: "none", Domain: "example.com", Path: "/search", Secure: true}
+	csrf := NewCSRFMiddleware(cfg)
+
+	handler := csrf.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v, want the CSRF cookie", cookies)
+	}
+	c := cookies[0]
+	if c.SameSite != http.SameSiteNoneMode || !c.Secure || c.Domain != "example.com" || c.Path != "/search" || !c.HttpOnly {
+		t.Errorf("CSRF cookie = %+v", c)
+	}
+}
+
+// TestCSRFProtect_CookieSecureOverTLS marks the CSRF cookie Secure on a
+// request that arrived over TLS, as the session cookies are, even with
+// server.ssl off
+func TestCSRFProtect_CookieSecureOverTLS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.Security.CSRF.Enabled = true
+	cfg.Server.Security.CSRF.CookieName = "csrf_token"
+	cfg.Server.SSL.Enabled = false
+	handler := NewCSRFMiddleware(cfg).Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, secure := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if secure {
+			req.TLS = &tls.ConnectionState{}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Secure != secure {
+			t.Errorf("over TLS %v: cookies = %+v, want Secure %v", secure, cookies, secure)
+		}
+	}
+}
+
 // TestCSRFProtect_PostInvalidToken returns 403 when token does not match cookie.
 func TestCSRFProtect_PostInvalidToken(t *testing.T) {
 	cfg := config.DefaultConfig()
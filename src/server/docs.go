@@ -0,0 +1,62 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/docs"
+)
+
+// maxDocsHits bounds the sections a documentation search lists
+const maxDocsHits = 50
+
+// DocsPageData is a page of the offline documentation, or a search of it
+type DocsPageData struct {
+	PageData
+	Pages   []docs.Page
+	Current docs.Page
+	// DocsQuery is the documentation search; Hits are its results
+	DocsQuery string
+	Hits      []docs.Hit
+}
+
+// handleDocs serves the documentation embedded in the binary, so it is
+// there without internet access. ?q= searches it.
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "page")
+	if slug == "" {
+		slug = "index"
+	}
+	current, ok := docs.Get(slug)
+	if !ok {
+		s.handleNotFound(w, r)
+		return
+	}
+	data := &DocsPageData{Pages: docs.Pages(), Current: current}
+	baseData := s.newPageData(w, r, "", "docs")
+	baseData.Title = current.Title + " - " + s.getI18nManager().T(baseData.Lang, "docs.page_title")
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		data.DocsQuery = q
+		data.Hits = docs.Search(q, maxDocsHits)
+		baseData.Title = s.getI18nManager().T(baseData.Lang, "docs.page_title")
+	}
+	data.PageData = *baseData
+	if err := s.renderer.Render(w, "docs", data); err != nil {
+		slog.Error("template render error", "err", err)
+	}
+}
+
+// handleDocsRedirect sends the short /docs URLs to /server/docs
+func handleDocsRedirect(w http.ResponseWriter, r *http.Request) {
+	target := docs.Path
+	if page := chi.URLParam(r, "page"); page != "" {
+		target = docs.URL(page, "")
+	}
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
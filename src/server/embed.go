@@ -14,8 +14,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/apimgr/search/src/common/httputil"
 	"github.com/apimgr/search/src/common/i18n"
 	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/instant"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/policy"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -81,7 +85,24 @@ func (tr *TemplateRenderer) newFuncMap(i18nFuncs template.FuncMap) template.Func
 			}
 			return "ltr"
 		},
+		// basePath is server.base_path, put in front of every link from
+		// the instance root
+		"basePath": func() string {
+			if tr.config == nil {
+				return ""
+			}
+			return tr.config.Server.BasePath
+		},
+		// rootLinks puts server.base_path in front of the root links of
+		// answer HTML
+		"rootLinks": func(content string) string {
+			if tr.config == nil {
+				return content
+			}
+			return prefixRootLinks(content, tr.config.Server.BasePath)
+		},
 		"safe":      func(s string) template.HTML { return template.HTML(s) },
+		"policy":    func(cfg *config.Config, md string) template.HTML { return policy.Render(md, policy.Variables(cfg)) },
 		"safeHTML":  func(s string) template.HTML { return template.HTML(s) },
 		"safeURL":   func(s string) template.URL { return template.URL(s) },
 		"safeCSS":   func(s string) template.CSS { return template.CSS(s) },
@@ -140,6 +161,13 @@ func (tr *TemplateRenderer) newFuncMap(i18nFuncs template.FuncMap) template.Func
 		"urlquery": func(s string) string {
 			return url.QueryEscape(s)
 		},
+		// resultHref is where a result links to: the result itself, or the
+		// warning page when a threat feed lists it
+		"resultHref": resultHref,
+		// watchHref is the watch page for a playable video result, or ""
+		"watchHref": func(rawURL, title, threat string) string {
+			return watchHref(videoPlayer(tr.config), rawURL, title, threat)
+		},
 		// humanDuration formats a float64 seconds value as a human-readable duration.
 		// Shows milliseconds for sub-second values, seconds for longer durations.
 		"humanDuration": func(secs float64) string {
@@ -151,8 +179,22 @@ func (tr *TemplateRenderer) newFuncMap(i18nFuncs template.FuncMap) template.Func
 			}
 			return fmt.Sprintf("%.1fs", secs)
 		},
+		// categoryEnabled reports whether the category tab is shown:
+		// search.disabled_categories does not list it
+		"categoryEnabled": func(category string) bool {
+			return tr.config == nil || tr.config.Search.CategoryEnabled(category)
+		},
 		"formatVideoDuration": formatVideoDuration,
 		"formatViewCount":     formatViewCount,
+		"formatFileSize":      formatFileSize,
+		// mapsConfig is search.maps; a zero value yields the defaults
+		"mapsConfig": func() config.MapsConfig {
+			if tr.config == nil {
+				return config.MapsConfig{}
+			}
+			return tr.config.Search.Maps
+		},
+		"leafletIntegrity": leafletIntegrity,
 		// Use a numeric date format so search results do not hardcode English month names.
 		"formatSearchDate": formatSearchDate,
 		// inSlice reports whether item is in the string slice.
@@ -434,6 +476,17 @@ type PageData struct {
 	Extra              map[string]interface{}
 	ServerURL          string
 	PrefsQuery         string
+	// WebAddress is the clear web URL offered as a QR code; empty when it is
+	// unknown (reached over Tor without server.base_url)
+	WebAddress string
+	// ViewAs is set in an operator's "view as user" preview; ViewAsPrefs
+	// seeds the page scripts with the previewed user's preferences
+	ViewAs        bool
+	ViewAsPrefs   string
+	ViewAsExpires string
+	// PrivacySignals are the browser's Do Not Track and Global Privacy
+	// Control opt-outs, which turn off location-based ranking
+	PrivacySignals httputil.PrivacySignals
 }
 
 // ErrorPageData extends PageData with error-specific fields.
@@ -457,9 +510,44 @@ type SearchPageData struct {
 	Engines       []string
 	PerPage       int
 	SafeSearch    int
+	TimeRange     string
+	// ShareLinks shows the share button for /s/<token> permalinks
+	ShareLinks    bool
+	// ReportLinks shows a report link on each result
+	ReportLinks   bool
 	Pagination    *Pagination
 	Error         string
-	InstantAnswer interface{}
+	// Degraded is set when no engine answered; CachedAt is when the
+	// fallback results were fetched, empty if there are none
+	Degraded bool
+	CachedAt string
+	// Instant answers in ladder order; CollapsedAnswers render folded
+	InstantAnswers   []*instant.Answer
+	CollapsedAnswers []*instant.Answer
+	// Image filters: the chosen color and license, and the choices
+	ImageColor    string
+	ImageLicense  string
+	ImageColors   []string
+	ImageLicenses []string
+	// ReverseImage shows search by image; ReverseResults marks a page of
+	// its results, for the image at ReverseImageURL or an upload
+	ReverseImage    bool
+	ReverseResults  bool
+	ReverseImageURL string
+	// Video filters: the chosen length and quality, and the lengths
+	VideoLength  string
+	VideoQuality string
+	VideoLengths []string
+	// News filters: the time ranges, the chosen sort ("date" for newest
+	// first), and the page's results grouped by publisher when
+	// GroupPublisher is chosen
+	TimeRanges     []string
+	Sort           string
+	GroupPublisher bool
+	NewsGroups     []model.PublisherGroup
+	// GeoCountry names the country results were boosted toward, for the
+	// localized badge
+	GeoCountry string
 }
 
 // HealthPageData extends PageData with health-specific fields
@@ -661,6 +749,15 @@ func NewPageData(cfg *config.Config, title, page string) *PageData {
 	return pd
 }
 
+// resultHref returns a result's link target. Flagged results go through
+// /warning so the user sees why before opening them.
+func resultHref(rawURL, threat string) string {
+	if threat == "" {
+		return rawURL
+	}
+	return "/warning?url=" + url.QueryEscape(rawURL)
+}
+
 // formatVideoDuration formats seconds to MM:SS or H:MM:SS format for video durations
 func formatVideoDuration(seconds int) string {
 	if seconds <= 0 {
@@ -707,6 +804,26 @@ func formatViewCount(count int64) string {
 	return fmt.Sprintf("%d", count)
 }
 
+// formatFileSize formats a size in bytes with binary units: 700 MiB, 1.4 GiB
+func formatFileSize(size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, unit := float64(size)/1024, 0
+	for value >= 1024 && unit < 4 {
+		value /= 1024
+		unit++
+	}
+	s := fmt.Sprintf("%.1f", value)
+	if value >= 10 {
+		s = fmt.Sprintf("%.0f", value)
+	}
+	return strings.TrimSuffix(s, ".0") + " " + []string{"KiB", "MiB", "GiB", "TiB", "PiB"}[unit]
+}
+
 func formatSearchDate(t time.Time) string {
 	if t.IsZero() {
 		return ""
@@ -722,6 +839,7 @@ var knownWidgetTypes = map[string]bool{
 	"currency": true, "timezone": true, "translate": true, "wikipedia": true,
 	"tracking": true, "nutrition": true, "qrcode": true, "timer": true,
 	"lorem": true, "dictionary": true, "ipaddress": true, "colorpicker": true,
+	"flight": true, "transit": true, "ticker": true,
 }
 
 // widgetCookieName is the HTTP cookie that stores the user's enabled widget list.
@@ -757,6 +875,9 @@ func widgetI18nKey(widgetType string) string {
 		"nutrition":   "widgets_ui.widget_name_nutrition",
 		"qrcode":      "widgets_ui.widget_name_qrcode",
 		"lorem":       "widgets_ui.widget_name_lorem",
+		"flight":      "widgets_ui.widget_name_flight",
+		"transit":     "widgets_ui.widget_name_transit",
+		"ticker":      "widgets_ui.widget_name_ticker",
 	}
 	if key, ok := keys[widgetType]; ok {
 		return key
@@ -791,6 +912,9 @@ func widgetIconForType(widgetType string) string {
 		"lorem":       "📄",
 		"dictionary":  "📖",
 		"ipaddress":   "🌐",
+		"flight":      "✈️",
+		"transit":     "🚌",
+		"ticker":      "💹",
 	}
 	if icon, ok := icons[widgetType]; ok {
 		return icon
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/search"
+)
+
+// engineAlertInterval is the shortest time between two alerts about the
+// same blocked engine
+const engineAlertInterval = time.Hour
+
+// engineAlertLog remembers when each engine was last alerted about, so a
+// blocked engine sends one alert an hour rather than one per search
+type engineAlertLog struct {
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// allow reports whether an alert about engine may be sent now, and if so
+// records it
+func (l *engineAlertLog) allow(engine string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last, ok := l.sent[engine]; ok && now.Sub(last) < engineAlertInterval {
+		return false
+	}
+	if l.sent == nil {
+		l.sent = make(map[string]time.Time)
+	}
+	l.sent[engine] = now
+	return true
+}
+
+// handleEngineBlocked is the aggregator's block handler. It logs every
+// block and emails the operator, at most hourly per engine.
+func (s *Server) handleEngineBlocked(engine string, err *search.BlockedError) {
+	slog.Warn("search engine returned a block page",
+		"engine", engine,
+		"kind", err.Kind,
+		"status", err.Status)
+
+	if !s.engineAlerts.allow(engine, time.Now()) {
+		return
+	}
+	if s.mailer == nil || !s.mailer.IsEnabled() {
+		return
+	}
+	body := fmt.Sprintf("%s\n\n%s: %s\n%s: %s\n%s: %d\n\n%s\n\n---\n%s\n",
+		i18n.TDefault("email_notifications.engine_blocked_title"),
+		i18n.TDefault("email_notifications.engine_label"),
+		engine,
+		i18n.TDefault("email_notifications.block_kind_label"),
+		err.Kind,
+		i18n.TDefault("email_notifications.status_label"),
+		err.Status,
+		i18n.TDefault("email_notifications.engine_blocked_notice"),
+		i18n.TDefault("email_notifications.automated_notice"),
+	)
+	if sendErr := s.mailer.SendAlert(i18n.TDefault("email_notifications.engine_blocked_subject"), body); sendErr != nil {
+		slog.Error("failed to send engine blocked notification email", "engine", engine, "err", sendErr)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/search"
+)
+
+// quotaHistory is how far back stored quota usage is loaded; older rows are
+// deleted
+const quotaHistory = 30 * 24 * time.Hour
+
+// loadQuotaUsage restores the stored engine quota usage into the aggregator
+// and deletes usage too old to chart
+func (s *Server) loadQuotaUsage(ctx context.Context) error {
+	since := time.Now().Add(-quotaHistory).Format("2006-01-02")
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE day < ?`, s.quotaTable), since); err != nil {
+		return fmt.Errorf("prune engine quota usage: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT engine, day, hour, requests FROM %s`, s.quotaTable))
+	if err != nil {
+		return fmt.Errorf("load engine quota usage: %w", err)
+	}
+	defer rows.Close()
+
+	var uses []search.QuotaUse
+	for rows.Next() {
+		var u search.QuotaUse
+		if err := rows.Scan(&u.Engine, &u.Day, &u.Hour, &u.Requests); err != nil {
+			return fmt.Errorf("load engine quota usage: %w", err)
+		}
+		uses = append(uses, u)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load engine quota usage: %w", err)
+	}
+	s.aggregator.RestoreQuotaUsage(uses)
+	return nil
+}
+
+// recordQuotaUse is the aggregator's quota recorder. It stores the request
+// in the background so a slow database never holds up a search.
+func (s *Server) recordQuotaUse(engine string, at time.Time) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(
+			`INSERT INTO %s (engine, day, hour, requests) VALUES (?, ?, ?, 1)
+			ON CONFLICT(engine, day, hour) DO UPDATE SET requests = requests + 1`, s.quotaTable),
+			engine, at.Format("2006-01-02"), at.Hour()); err != nil {
+			slog.Warn("engine quota usage not saved", "engine", engine, "err", err)
+		}
+	}()
+}
+
+// handleEngineQuotas reports each budgeted engine's daily quota, what is
+// left of it, and its requests per hour today and per day for the last 30
+// days, ready to chart
+func (s *Server) handleEngineQuotas(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.aggregator.QuotaStatus(time.Now()),
+	})
+}
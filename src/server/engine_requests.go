@@ -0,0 +1,61 @@
+package server
+
+import (
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+)
+
+// engineRequestTemplates converts the request settings of the engines map
+// into the aggregator's request templates
+func engineRequestTemplates(engines map[string]config.EngineConfig) map[string]search.RequestTemplate {
+	templates := make(map[string]search.RequestTemplate)
+	for name, engine := range engines {
+		if engine.Request.IsZero() {
+			continue
+		}
+		templates[name] = search.RequestTemplate{
+			Headers: engine.Request.Headers,
+			Cookies: engine.Request.Cookies,
+			Params:  engine.Request.Params,
+		}
+	}
+	return templates
+}
+
+// engineLimits converts search.engine_limits and the limits of the engines
+// map into the aggregator's response limits
+func engineLimits(cfg *config.Config) (search.EngineLimits, map[string]search.EngineLimits) {
+	convert := func(l config.EngineLimitsConfig) search.EngineLimits {
+		return search.EngineLimits{
+			MaxResponseBytes: int64(l.MaxResponseKB) * 1024,
+			MaxHTMLDepth:     l.MaxHTMLDepth,
+		}
+	}
+	perEngine := make(map[string]search.EngineLimits)
+	for name, engine := range cfg.Engines {
+		if engine.Limits == (config.EngineLimitsConfig{}) {
+			continue
+		}
+		perEngine[name] = convert(engine.Limits)
+	}
+	return convert(cfg.Search.EngineLimits), perEngine
+}
+
+// engineQuotas converts the quotas of the engines map into the aggregator's
+// daily budgets
+func engineQuotas(engines map[string]config.EngineConfig) map[string]search.EngineQuota {
+	quotas := make(map[string]search.EngineQuota)
+	for name, engine := range engines {
+		q := engine.Quota
+		if q.Daily <= 0 {
+			continue
+		}
+		quota := search.EngineQuota{Daily: q.Daily, Fallback: q.Fallback}
+		if start, end, ok := q.PeakRange(); ok {
+			quota.PeakStart, quota.PeakEnd = start, end
+			quota.PeakReserve = q.Daily * q.PeakReserve / 100
+		}
+		quotas[name] = quota
+	}
+	return quotas
+}
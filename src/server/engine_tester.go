@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// handleEngineTest runs one query against a single engine and answers with
+// the raw upstream exchanges, the parsed results and the timings, so an
+// operator can see why an engine returns nothing without shell access.
+// Credentials in URLs and headers are redacted unless the body sets
+// "redact": false.
+func (s *Server) handleEngineTest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query    string `json:"query"`
+		Category string `json:"category"`
+		Page     int    `json:"page"`
+		Redact   *bool  `json:"redact"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a query")
+		return
+	}
+	query := model.NewQuery(req.Query)
+	if query.Text == "" {
+		respondError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	if req.Category != "" {
+		query.Category = model.Category(strings.ToLower(strings.TrimSpace(req.Category)))
+		if !query.Category.IsValid() {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("unknown category '%s'", req.Category))
+			return
+		}
+	}
+	if req.Page > 1 {
+		query.Page = req.Page
+	}
+	redact := req.Redact == nil || *req.Redact
+
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	test, err := s.aggregator.TestEngine(r.Context(), name, query, redact)
+	if errors.Is(err, model.ErrEngineNotFound) {
+		respondError(w, http.StatusNotFound, "Unknown engine")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !redact && s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogConfigChange("operator", getClientIPSimple(r), "engines."+name+".test", "unredacted")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": test,
+	})
+}
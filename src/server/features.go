@@ -0,0 +1,145 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/feature"
+)
+
+// featureBucketCookie holds a browser's rollout bucket, 0-99. It is random,
+// never stored server-side, and only set once a flag is rolled out to part
+// of the browsers.
+const featureBucketCookie = "feature_bucket"
+
+// featureBucketTTL is how long a browser keeps its bucket
+const featureBucketTTL = 365 * 24 * time.Hour
+
+// featureEnabled reports whether the feature flag name is on for this
+// browser. Gate new features with it:
+//
+//	if s.featureEnabled(w, r, "summarization") { ... }
+func (s *Server) featureEnabled(w http.ResponseWriter, r *http.Request, name string) bool {
+	if s.features == nil {
+		return false
+	}
+	flag, ok := s.features.Get(name)
+	if !ok || !flag.Enabled {
+		return false
+	}
+	if flag.Percent >= 100 {
+		return true
+	}
+	return s.features.Enabled(name, s.featureBucket(w, r))
+}
+
+// featureBucket returns the browser's rollout bucket, assigning one when it
+// has none. w may be nil to read without assigning.
+func (s *Server) featureBucket(w http.ResponseWriter, r *http.Request) int {
+	if c, err := r.Cookie(featureBucketCookie); err == nil {
+		if bucket, err := strconv.Atoi(c.Value); err == nil && bucket >= 0 && bucket < 100 {
+			return bucket
+		}
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(100))
+	if err != nil {
+		return 0
+	}
+	bucket := int(n.Int64())
+	if w != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     featureBucketCookie,
+			Value:    strconv.Itoa(bucket),
+			Path:     "/",
+			Expires:  time.Now().Add(featureBucketTTL),
+			HttpOnly: true,
+			Secure:   s.config.Server.RequestTLS(r),
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return bucket
+}
+
+// handleFeatures lists the feature flags declared in server.yml with their
+// effective state. Per IDEA.md there is no admin UI; this API flips flags
+// without a redeploy.
+func (s *Server) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	flags := []feature.Flag{}
+	if s.features != nil {
+		flags = s.features.List()
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": flags,
+	})
+}
+
+// handleFeatureSet overrides a flag. The body is {"enabled": true,
+// "percent": 10}; percent defaults to 100.
+func (s *Server) handleFeatureSet(w http.ResponseWriter, r *http.Request) {
+	if s.features == nil {
+		respondError(w, http.StatusServiceUnavailable, "Feature flags are not available")
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+		Percent *int  `json:"percent"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&req); err != nil || req.Enabled == nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with enabled and an optional percent")
+		return
+	}
+	percent := 100
+	if req.Percent != nil {
+		percent = *req.Percent
+	}
+
+	name := chi.URLParam(r, "name")
+	err := s.features.Override(r.Context(), name, *req.Enabled, percent)
+	switch {
+	case errors.Is(err, feature.ErrUnknownFlag):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, feature.ErrPercent):
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	flag, _ := s.features.Get(name)
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": flag,
+	})
+}
+
+// handleFeatureReset drops a flag's override, returning it to server.yml
+func (s *Server) handleFeatureReset(w http.ResponseWriter, r *http.Request) {
+	if s.features == nil {
+		respondError(w, http.StatusServiceUnavailable, "Feature flags are not available")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if _, ok := s.features.Get(name); !ok {
+		respondError(w, http.StatusNotFound, feature.ErrUnknownFlag.Error())
+		return
+	}
+	if err := s.features.Reset(r.Context(), name); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	flag, _ := s.features.Get(name)
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": flag,
+	})
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/apimgr/search/src/common/httputil"
+)
+
+// searchCountry returns the GeoIP country results are boosted toward for
+// this request, or "" when geo boosting is off, the user turned it off or
+// their browser sends Do Not Track or Global Privacy Control, or the
+// address has no known country
+func (s *Server) searchCountry(r *http.Request) string {
+	if !s.config.Search.GeoBoost.Enabled || s.geoipLookup == nil || !s.geoipLookup.IsLoaded() {
+		return ""
+	}
+	if httputil.RequestPrivacySignals(r).OptOut() {
+		return ""
+	}
+	if !parseSearchPreferences(s.requestPrefs(r)).Localize {
+		return ""
+	}
+	if result := s.geoipLookup.Lookup(getClientIPSimple(r)); result.Found {
+		return result.CountryCode
+	}
+	return ""
+}
+
+// searchCountryName is the name of searchCountry, shown with localized results
+func (s *Server) searchCountryName(r *http.Request) string {
+	code := s.searchCountry(r)
+	if code == "" {
+		return ""
+	}
+	if country := s.geoipLookup.GetCountry(code); country != nil {
+		return country.Name
+	}
+	return code
+}
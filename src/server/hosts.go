@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/api"
+	"github.com/apimgr/search/src/common/httputil"
+	"github.com/apimgr/search/src/config"
+)
+
+// checkHost is the middleware behind server.allowed_hosts and
+// server.canonical_host. A request for a host the instance does not serve
+// gets 421 Misdirected Request, or a redirect to the canonical host, so a
+// forged Host header never reaches the links and redirects built from it.
+// Allowed hosts other than the canonical one, and plain HTTP when https is
+// set, are redirected permanently, so the site is indexed under one URL.
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := &s.config.Server
+		if len(cfg.AllowedHosts) == 0 && cfg.CanonicalHost.Host == "" && !cfg.CanonicalHost.HTTPS {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rawHost := httputil.GetHostFromRequest(r)
+		host := config.NormalizeHost(rawHost)
+		if s.hostCheckExempt(r, host) || s.isHealthCheckPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		proto := httputil.GetProtoFromRequest(r)
+		wantProto := proto
+		if cfg.CanonicalHost.HTTPS && !strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+			wantProto = "https"
+		}
+
+		if !cfg.HostAllowed(host) {
+			target := ""
+			if strings.EqualFold(strings.TrimSpace(cfg.UnexpectedHost), config.UnexpectedHostRedirect) {
+				target = cfg.HostRedirectTarget()
+			}
+			if target == "" {
+				http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+				return
+			}
+			redirectToHost(w, r, wantProto, target)
+			return
+		}
+
+		wantHost := host
+		if canonical := config.NormalizeHost(cfg.CanonicalHost.Host); canonical != "" {
+			wantHost = canonical
+		}
+		if wantHost == host && wantProto == proto {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Only a request that keeps its scheme keeps its port; a move to
+		// https or another host goes to that scheme's default port
+		if _, port, err := net.SplitHostPort(rawHost); err == nil && wantProto == proto {
+			wantHost = net.JoinHostPort(wantHost, port)
+		}
+		redirectToHost(w, r, wantProto, wantHost)
+	})
+}
+
+// redirectToHost sends the request to the same path and query on another
+// scheme and host: 301 for GET and HEAD, and 308 for other methods so a
+// form or API call is sent again with its body
+func redirectToHost(w http.ResponseWriter, r *http.Request, proto, host string) {
+	if strings.Contains(host, ":") && !strings.Contains(host, "]") && net.ParseIP(host) != nil {
+		host = "[" + host + "]"
+	}
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, proto+"://"+host+r.URL.RequestURI(), code)
+}
+
+// hostCheckExempt reports whether a request for host, normalized, is
+// served whatever the host settings: one to a hidden service this instance
+// publishes, which has no other host or https to go to, and one made on
+// this machine to localhost or a loopback address, for local health checks
+// and the CLI. A Host header naming any other onion, or a loopback host on
+// a request from elsewhere, gets no exemption.
+func (s *Server) hostCheckExempt(r *http.Request, host string) bool {
+	if strings.HasSuffix(host, ".onion") {
+		if s.torService == nil {
+			return false
+		}
+		for _, svc := range s.torService.HiddenServices() {
+			if config.NormalizeHost(svc.OnionAddress) == host {
+				return true
+			}
+		}
+		return false
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return false
+	}
+	// The connection and the client a trusted proxy forwards for must both
+	// be local, so a proxy on this machine does not exempt its clients
+	return isLoopbackAddr(r.RemoteAddr) && isLoopbackAddr(httputil.GetClientIP(r))
+}
+
+// isLoopbackAddr reports whether addr, an IP with or without a port, is a
+// loopback address
+func isLoopbackAddr(addr string) bool {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		addr = h
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isHealthCheckPath reports whether path, under the base path, is one of
+// the health checks, which probes reach by address rather than by host
+// name
+func (s *Server) isHealthCheckPath(path string) bool {
+	path, ok := strings.CutPrefix(path, s.config.Server.BasePath)
+	if !ok {
+		return false
+	}
+	switch path {
+	case "/server/healthz", "/server/healthz.txt", "/readyz", "/livez",
+		api.APIPrefix + "/server/healthz", api.APIPrefix + "/server/healthz.txt",
+		api.APIPrefix + "/healthz", api.APIPrefix + "/healthz.txt", "/api/healthz":
+		return true
+	case "/healthz", "/healthz.txt":
+		return s.config.Server.Healthz.Root.Enabled
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+
+	"github.com/apimgr/search/src/model"
+)
+
+// flagAdultImages is the aggregator's image classifier. Images the operator
+// allowed after a misclassified report are never sent to the classifier.
+func (s *Server) flagAdultImages(ctx context.Context, results []model.Result) []bool {
+	if !s.config.Search.ImageClassifier.Enabled || s.imageClassifier == nil {
+		return nil
+	}
+	var check []model.Result
+	var index []int
+	for i, r := range results {
+		if s.moderation.ImageAllowed(r.URL) {
+			continue
+		}
+		check = append(check, r)
+		index = append(index, i)
+	}
+	flags := make([]bool, len(results))
+	for i, adult := range s.imageClassifier.Flag(ctx, check) {
+		flags[index[i]] = adult
+	}
+	return flags
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/security"
+	"github.com/apimgr/search/src/ssl"
+)
+
+// keyStore is data encrypted with one of the server secrets
+type keyStore struct {
+	Name string `json:"name"`
+	// Items is how many stored values the secret protects
+	Items int `json:"items"`
+	count func(ctx context.Context) (int, error)
+	// reencrypt decrypts every item with the old secret and encrypts it
+	// with the new one, all or none
+	reencrypt func(ctx context.Context, oldSecret, newSecret string) (int, error)
+}
+
+// managedKey is a server secret the key manager versions and rotates
+type managedKey struct {
+	name     string
+	get      func(sc *config.ServerConfig) string
+	set      func(sc *config.ServerConfig, secret string)
+	restore  func(sc *config.ServerConfig, previous config.ServerConfig)
+	generate func() string
+	stores   []keyStore
+	note     string
+}
+
+// keyStatus is a managed secret's version, age, schedule and what it
+// protects, for GET /api/v1/server/keys
+type keyStatus struct {
+	Name         string                `json:"name"`
+	Version      int                   `json:"version"`
+	Fingerprint  string                `json:"fingerprint"`
+	CreatedAt    time.Time             `json:"created_at,omitzero"`
+	AgeDays      int                   `json:"age_days"`
+	Scheduled    bool                  `json:"scheduled"`
+	NextRotation time.Time             `json:"next_rotation,omitzero"`
+	Protects     []keyStore            `json:"protects"`
+	Note         string                `json:"note,omitempty"`
+	History      []security.KeyVersion `json:"history"`
+}
+
+// randomSecret returns n random bytes encoded by encode
+func randomSecret(n int, encode func([]byte) string) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed generating a secret: " + err.Error())
+	}
+	return encode(b)
+}
+
+// managedKeys describes every server secret and the data it protects
+func (s *Server) managedKeys() []managedKey {
+	var alerts []keyStore
+	if s.alertManager != nil {
+		alerts = append(alerts, keyStore{Name: "search_alerts", count: s.alertManager.CountEncrypted, reencrypt: s.alertManager.Reencrypt})
+	}
+	var reports []keyStore
+	if s.dbManager != nil && s.dbManager.ServerDB() != nil {
+		db := s.dbManager.ServerDB()
+		reports = append(reports, keyStore{
+			Name:  "security_reports",
+			count: func(ctx context.Context) (int, error) { return security.CountAESGCMReports(ctx, db) },
+			reencrypt: func(ctx context.Context, oldSecret, newSecret string) (int, error) {
+				return security.ReencryptReports(ctx, db, oldSecret, newSecret)
+			},
+		})
+	}
+	return []managedKey{
+		{
+			name:     security.KeySecretKey,
+			get:      func(sc *config.ServerConfig) string { return sc.SecretKey },
+			set:      func(sc *config.ServerConfig, secret string) { sc.SecretKey = secret },
+			restore:  func(sc *config.ServerConfig, previous config.ServerConfig) { sc.SecretKey = previous.SecretKey },
+			generate: func() string { return randomSecret(32, hex.EncodeToString) },
+			stores:   append(alerts, s.dns01KeyStore()),
+			note:     "Rotating also ends operator JWTs signed with the key derived from it when server.jwt.keys is empty",
+		},
+		{
+			name: security.KeyEncryptionKey,
+			get:  func(sc *config.ServerConfig) string { return sc.Security.EncryptionKey },
+			set:  func(sc *config.ServerConfig, secret string) { sc.Security.EncryptionKey = secret },
+			restore: func(sc *config.ServerConfig, previous config.ServerConfig) {
+				sc.Security.EncryptionKey = previous.Security.EncryptionKey
+			},
+			generate: func() string { return randomSecret(32, base64.StdEncoding.EncodeToString) },
+			stores:   reports,
+		},
+		{
+			name: security.KeyInstallationSecret,
+			get:  func(sc *config.ServerConfig) string { return sc.Security.InstallationSecret },
+			set:  func(sc *config.ServerConfig, secret string) { sc.Security.InstallationSecret = secret },
+			restore: func(sc *config.ServerConfig, previous config.ServerConfig) {
+				sc.Security.InstallationSecret = previous.Security.InstallationSecret
+			},
+			generate: func() string { return randomSecret(32, base64.StdEncoding.EncodeToString) },
+			stores: []keyStore{{
+				Name: "pgp_private_keys",
+				count: func(ctx context.Context) (int, error) {
+					return security.CountPGPPrivateKeys(config.GetConfigDir()), nil
+				},
+				reencrypt: func(ctx context.Context, oldSecret, newSecret string) (int, error) {
+					return security.RelockPrivateKeys(config.GetConfigDir(), oldSecret, newSecret)
+				},
+			}},
+			note: "Rotating changes the security_id, so researchers must reload security.txt",
+		},
+		{
+			name: security.KeyJWT,
+			get: func(sc *config.ServerConfig) string {
+				if len(sc.JWT.Keys) == 0 {
+					return ""
+				}
+				return sc.JWT.Keys[0].Secret
+			},
+			// The new key signs from now on; the old ones verify the JWTs
+			// they signed until those expire
+			set: func(sc *config.ServerConfig, secret string) {
+				id := time.Now().UTC().Format("20060102-150405")
+				sc.JWT.Keys = append([]config.JWTKeyConfig{{ID: id, Secret: secret}}, sc.JWT.Keys...)
+			},
+			restore:  func(sc *config.ServerConfig, previous config.ServerConfig) { sc.JWT.Keys = previous.JWT.Keys },
+			generate: func() string { return randomSecret(32, hex.EncodeToString) },
+			note:     "Earlier keys keep verifying until server.jwt.ttl after a rotation; without server.jwt.keys the key is derived from secret_key",
+		},
+	}
+}
+
+// dns01KeyStore is the DNS-01 provider credentials in server.yml
+func (s *Server) dns01KeyStore() keyStore {
+	return keyStore{
+		Name: "dns01_credentials",
+		count: func(ctx context.Context) (int, error) {
+			if s.config.Get().SSL.DNS01.CredentialsEncrypted == "" {
+				return 0, nil
+			}
+			return 1, nil
+		},
+		reencrypt: func(ctx context.Context, oldSecret, newSecret string) (int, error) {
+			n := 0
+			var err error
+			s.config.Update(func(sc *config.ServerConfig) {
+				if sc.SSL.DNS01.CredentialsEncrypted == "" {
+					return
+				}
+				var creds map[string]string
+				if creds, err = ssl.DecryptCredentials(sc.SSL.DNS01.CredentialsEncrypted, oldSecret); err != nil {
+					return
+				}
+				var encrypted string
+				if encrypted, err = ssl.EncryptCredentials(creds, newSecret); err != nil {
+					return
+				}
+				sc.SSL.DNS01.CredentialsEncrypted = encrypted
+				n = 1
+			})
+			return n, err
+		},
+	}
+}
+
+// lookupKey returns the managed secret called name
+func (s *Server) lookupKey(name string) (managedKey, bool) {
+	for _, key := range s.managedKeys() {
+		if key.name == name {
+			return key, true
+		}
+	}
+	return managedKey{}, false
+}
+
+// observeKey records the secret's current value as a new version when it
+// is not the one last recorded, and returns the current version. A secret
+// derived from another, such as the default JWT key, has no version.
+func (s *Server) observeKey(ctx context.Context, key managedKey, now time.Time) (security.KeyVersion, error) {
+	cfg := s.config.Get()
+	secret := key.get(&cfg)
+	if secret == "" {
+		return security.KeyVersion{Name: key.name}, nil
+	}
+	return security.ObserveKey(ctx, s.dbManager.ServerDB(), key.name, secret, now)
+}
+
+// keyStatuses reports every managed secret
+func (s *Server) keyStatuses(ctx context.Context, now time.Time) ([]keyStatus, error) {
+	cfg := s.config.Get()
+	rotateAfter, _ := config.ParseDuration(cfg.Security.Keys.RotateAfter)
+	var statuses []keyStatus
+	for _, key := range s.managedKeys() {
+		version, err := s.observeKey(ctx, key, now)
+		if err != nil {
+			return nil, err
+		}
+		status := keyStatus{
+			Name:        key.name,
+			Version:     version.Version,
+			Fingerprint: version.Fingerprint,
+			CreatedAt:   version.CreatedAt,
+			Scheduled:   slices.Contains(cfg.Security.Keys.Scheduled, key.name),
+			Protects:    []keyStore{},
+			Note:        key.note,
+		}
+		if !version.CreatedAt.IsZero() {
+			status.AgeDays = int(now.Sub(version.CreatedAt).Hours() / 24)
+			if status.Scheduled && rotateAfter > 0 {
+				status.NextRotation = version.CreatedAt.Add(time.Duration(rotateAfter) * time.Second)
+			}
+		}
+		for _, store := range key.stores {
+			if store.Items, err = store.count(ctx); err != nil {
+				return nil, fmt.Errorf("count %s: %w", store.Name, err)
+			}
+			status.Protects = append(status.Protects, store)
+		}
+		if status.History, err = security.KeyHistory(ctx, s.dbManager.ServerDB(), key.name); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// rotateKey replaces the secret called name with a new one: everything it
+// protects is re-encrypted, then server.yml is saved with the new secret.
+// If any step fails the earlier ones are undone and the old secret stays.
+func (s *Server) rotateKey(ctx context.Context, name, reason string, now time.Time) (security.KeyVersion, error) {
+	key, ok := s.lookupKey(name)
+	if !ok {
+		return security.KeyVersion{}, security.ErrUnknownKey
+	}
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
+	previous := s.config.Get()
+	if _, err := s.observeKey(ctx, key, now); err != nil {
+		return security.KeyVersion{}, err
+	}
+	oldSecret, newSecret := key.get(&previous), key.generate()
+	var done []keyStore
+	undo := func() {
+		for _, store := range slices.Backward(done) {
+			if _, err := store.reencrypt(ctx, newSecret, oldSecret); err != nil {
+				slog.Error("key rotation: restoring data encrypted with the old key failed", "key", name, "store", store.Name, "err", err)
+			}
+		}
+	}
+	for _, store := range key.stores {
+		if _, err := store.reencrypt(ctx, oldSecret, newSecret); err != nil {
+			undo()
+			return security.KeyVersion{}, fmt.Errorf("re-encrypt %s: %w", store.Name, err)
+		}
+		done = append(done, store)
+	}
+	s.config.Update(func(sc *config.ServerConfig) { key.set(sc, newSecret) })
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("server.security.keys", name); err != nil {
+			s.config.Update(func(sc *config.ServerConfig) { key.restore(sc, previous) })
+			undo()
+			return security.KeyVersion{}, fmt.Errorf("save server.yml: %w", err)
+		}
+	}
+	version, err := security.RecordKeyVersion(ctx, s.dbManager.ServerDB(), name, newSecret, reason, now)
+	if err != nil {
+		// The rotation stands; the version is recorded as changed when next
+		// observed
+		slog.Warn("key rotation: recording the new version failed", "key", name, "err", err)
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogConfigChange("operator", "", "server.security.keys."+name, fmt.Sprintf("rotated to version %d (%s)", version.Version, reason))
+	}
+	return version, nil
+}
+
+// rotateDueKeys rotates the scheduled secrets older than
+// server.security.keys.rotate_after, then retires the JWT keys no JWT can
+// still need
+func (s *Server) rotateDueKeys(ctx context.Context, now time.Time) error {
+	if s.dbManager == nil || s.dbManager.ServerDB() == nil {
+		return nil
+	}
+	cfg := s.config.Get()
+	rotateAfter, _ := config.ParseDuration(cfg.Security.Keys.RotateAfter)
+	var errs []error
+	for _, name := range cfg.Security.Keys.Scheduled {
+		key, ok := s.lookupKey(name)
+		// The JWT key is only rotated while JWTs are in use
+		if !ok || rotateAfter <= 0 || (name == security.KeyJWT && !cfg.JWT.Enabled) {
+			continue
+		}
+		version, err := s.observeKey(ctx, key, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !version.CreatedAt.IsZero() && now.Sub(version.CreatedAt) < time.Duration(rotateAfter)*time.Second {
+			continue
+		}
+		if _, err := s.rotateKey(ctx, name, "scheduled", now); err != nil {
+			errs = append(errs, fmt.Errorf("rotate %s: %w", name, err))
+		}
+	}
+	if err := s.retireJWTKeys(ctx, now); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// retireJWTKeys drops the JWT keys after the first once the first has
+// signed for server.jwt.ttl, when every JWT the others signed has expired
+func (s *Server) retireJWTKeys(ctx context.Context, now time.Time) error {
+	cfg := s.config.Get()
+	if len(cfg.JWT.Keys) < 2 {
+		return nil
+	}
+	current, err := security.CurrentKeyVersion(ctx, s.dbManager.ServerDB(), security.KeyJWT)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ttl, _ := time.ParseDuration(cfg.JWT.TTL)
+	if current.Fingerprint != security.SecretFingerprint(cfg.JWT.Keys[0].Secret) || now.Before(current.CreatedAt.Add(ttl)) {
+		return nil
+	}
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	s.config.Update(func(sc *config.ServerConfig) { sc.JWT.Keys = sc.JWT.Keys[:1] })
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("server.jwt.keys", nil); err != nil {
+			s.config.Update(func(sc *config.ServerConfig) { sc.JWT.Keys = cfg.JWT.Keys })
+			return fmt.Errorf("save server.yml: %w", err)
+		}
+	}
+	return nil
+}
+
+// handleKeys reports the version, age, rotation schedule and protected
+// data of every server secret; never the secrets
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	statuses, err := s.keyStatuses(r.Context(), time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"rotate_after": s.config.Get().Security.Keys.RotateAfter,
+			"keys":         statuses,
+		},
+	})
+}
+
+// handleKeyRotate rotates one server secret now
+func (s *Server) handleKeyRotate(w http.ResponseWriter, r *http.Request) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	version, err := s.rotateKey(r.Context(), chi.URLParam(r, "name"), "rotated", time.Now())
+	switch {
+	case errors.Is(err, security.ErrUnknownKey):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": version})
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search/macro"
+)
+
+// instanceMacros returns the macros set in server.yml. They are read per
+// request so a config reload applies without a restart.
+func instanceMacros(cfg []config.MacroConfig) []macro.Macro {
+	macros := make([]macro.Macro, 0, len(cfg))
+	for _, mc := range cfg {
+		macros = append(macros, macro.Macro{Name: mc.Name, Query: mc.Query, Description: mc.Description})
+	}
+	return macros
+}
+
+// userMacros returns the macros saved in the user's cookie
+func userMacros(r *http.Request) []macro.Macro {
+	c, err := r.Cookie(macro.CookieName)
+	if err != nil {
+		return nil
+	}
+	return macro.DecodeCookie(c.Value)
+}
+
+// expandMacro expands a search starting with ~name. A user's own macro
+// wins over an instance macro of the same name. It reports true once it
+// has answered the request: a redirect to the expanded search, so the
+// address bar shows what was searched, or an error for missing words.
+func (s *Server) expandMacro(w http.ResponseWriter, r *http.Request, queryStr string) bool {
+	expanded, ok, err := macro.Expand(queryStr, userMacros(r), instanceMacros(s.config.Search.Macros))
+	if !ok {
+		return false
+	}
+	var missing *macro.MissingArgsError
+	if errors.As(err, &missing) {
+		s.handleError(w, r, http.StatusBadRequest, i18n.RequestString(r, "search.error_title"), i18n.RequestString(r, "search.macro_missing_args", missing.Usage))
+		return true
+	}
+	params := r.URL.Query()
+	params.Set("q", expanded)
+	params.Del("page")
+	http.Redirect(w, r, r.URL.Path+"?"+params.Encode(), http.StatusFound)
+	return true
+}
+
+// handleMacroPreferencesSave handles the add and delete forms of the
+// search macros section of the preferences page. Macros are kept in a
+// cookie, like the widget selection, so they work without JavaScript and
+// without an account.
+func (s *Server) handleMacroPreferencesSave(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	macros := userMacros(r)
+	name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(r.FormValue("name")), macro.Prefix))
+	switch r.FormValue("action") {
+	case "delete":
+		macros = slices.DeleteFunc(macros, func(m macro.Macro) bool { return m.Name == name })
+	default:
+		m := macro.Macro{
+			Name:        name,
+			Query:       strings.TrimSpace(r.FormValue("query")),
+			Description: strings.TrimSpace(r.FormValue("description")),
+		}
+		if m.Valid() != nil {
+			s.handleError(w, r, http.StatusBadRequest, i18n.RequestString(r, "preferences.macros_heading"), i18n.RequestString(r, "preferences.macro_invalid"))
+			return
+		}
+		if i := slices.IndexFunc(macros, func(old macro.Macro) bool { return old.Name == m.Name }); i >= 0 {
+			macros[i] = m
+		} else if len(macros) >= macro.MaxUser {
+			s.handleError(w, r, http.StatusBadRequest, i18n.RequestString(r, "preferences.macros_heading"), i18n.RequestString(r, "preferences.macros_full", macro.MaxUser))
+			return
+		} else {
+			macros = append(macros, m)
+		}
+	}
+
+	cookie := &http.Cookie{
+		Name:     macro.CookieName,
+		Value:    macro.EncodeCookie(macros),
+		Path:     "/",
+		MaxAge:   widgetCookieMaxAge,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if len(macros) == 0 {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+
+	http.Redirect(w, r, "/preferences#search-macros", http.StatusSeeOther)
+}
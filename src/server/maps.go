@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search/engine"
+)
+
+// leafletSRI pins the files of the default Leaflet copy, so the CDN
+// cannot serve anything else; a self-hosted copy is trusted as it is
+var leafletSRI = map[string]string{
+	"leaflet.js":  "sha256-20nQCchB9co0qIjJZRGuk2/Z9VM+kNiyxNV1lvTlZBo=",
+	"leaflet.css": "sha256-p4NxAoJBhIIN+hmNHrzRCf9tD/miZyoHS5obTRR9BMY=",
+}
+
+// leafletIntegrity returns the integrity attribute value of file in the
+// Leaflet copy at base, or "" when base is not the pinned default
+func leafletIntegrity(base, file string) string {
+	if base != config.DefaultLeafletURL {
+		return ""
+	}
+	return leafletSRI[file]
+}
+
+// setNominatimEndpoint points the maps engine at search.maps.nominatim
+func setNominatimEndpoint(registry *engine.Registry, maps config.MapsConfig) {
+	e, err := registry.Get("openstreetmap")
+	if err != nil {
+		return
+	}
+	if osm, ok := e.(*engine.OpenStreetMap); ok {
+		osm.SetEndpoint(maps.NominatimURL())
+	}
+}
+
+// allowMapSources lets the maps results page load Leaflet and the map
+// tiles from the servers search.maps names
+func allowMapSources(h http.Header, maps config.MapsConfig) {
+	if origin := urlOrigin(maps.LeafletURL()); origin != "" {
+		allowCSPSource(h, "script-src", origin)
+		allowCSPSource(h, "style-src", origin)
+		// Leaflet's stylesheet loads its marker and control images
+		allowCSPSource(h, "img-src", origin)
+	}
+	// {s} subdomains (a.tile..., b.tile...) are allowed by wildcard
+	tiles := strings.ReplaceAll(maps.TileURL(), "{s}.", "*.")
+	if origin := urlOrigin(tiles); origin != "" {
+		allowCSPSource(h, "img-src", origin)
+	}
+}
+
+// urlOrigin returns the scheme and host of raw, or "" when it has none
+func urlOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/cache"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/memwatch"
+	"github.com/apimgr/search/src/retention"
+)
+
+// memoryOptions converts server.memory to watchdog options; invalid values
+// fall back to the defaults with a warning
+func memoryOptions(mc config.MemoryConfig) memwatch.Options {
+	opts := memwatch.Options{SoftPercent: mc.SoftPercent, HardPercent: mc.HardPercent}
+	if limit := strings.TrimSpace(mc.Limit); limit != "" && !strings.EqualFold(limit, "auto") {
+		if n, err := retention.ParseSize(limit); err != nil || n <= 0 {
+			slog.Warn("server.memory.limit is not a size, detecting the limit instead", "limit", mc.Limit)
+		} else {
+			opts.Limit = uint64(n)
+		}
+	}
+	if mc.Interval != "" {
+		if d, err := time.ParseDuration(mc.Interval); err != nil || d <= 0 {
+			slog.Warn("server.memory.interval is not a duration, using the default", "interval", mc.Interval)
+		} else {
+			opts.Interval = d
+		}
+	}
+	return opts
+}
+
+// startMemoryWatchdog puts the in-memory caches under a memory watchdog
+// and runs it until Shutdown. results is the search result cache when it
+// is held in memory rather than in Redis or Valkey.
+func (s *Server) startMemoryWatchdog(results *cache.MemoryCache) {
+	watchdog := memwatch.New(memoryOptions(s.config.Server.Memory))
+	if results != nil {
+		watchdog.Register("results", results.MaxSize(), results.SetMaxSize)
+	}
+	if n := s.aggregator.LocalIndexSize(); n > 0 {
+		watchdog.Register("local_index", n, s.aggregator.SetLocalIndexSize)
+	}
+	if s.renderCache != nil {
+		watchdog.Register("rendered_pages", s.renderCache.pages.MaxSize(), s.renderCache.pages.SetMaxSize)
+	}
+	if s.imageClassifier != nil {
+		watchdog.Register("image_verdicts", s.imageClassifier.CacheSize(), s.imageClassifier.SetCacheSize)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.memWatch, s.stopMemWatch = watchdog, cancel
+	go watchdog.Run(ctx)
+	s.config.OnReload(func(c *config.Config) {
+		watchdog.SetOptions(memoryOptions(c.Server.Memory))
+	})
+}
+
+// handleMemory returns the memory watchdog's latest reading: pressure
+// level, memory limit and usage, GOGC and each cache's current capacity
+func (s *Server) handleMemory(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{"enabled": s.memWatch != nil}
+	if s.memWatch != nil {
+		data["status"] = s.memWatch.Status()
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": data,
+	})
+}
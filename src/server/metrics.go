@@ -2,13 +2,12 @@ package server
 
 import (
 	"bufio"
-	"crypto/sha256"
-	"crypto/subtle"
 	"net/http"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,6 +16,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/memwatch"
+	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/security"
 )
 
 // Metrics collects server metrics using Prometheus client library
@@ -65,6 +67,29 @@ type Metrics struct {
 	searchDuration *prometheus.HistogramVec
 	engineRequests *prometheus.CounterVec
 	engineErrors   *prometheus.CounterVec
+	engineUp       *prometheus.GaugeVec
+	quotaUsed      *prometheus.GaugeVec
+	quotaRemaining *prometheus.GaugeVec
+	schemaDrift    *prometheus.GaugeVec
+	parserSuspect  *prometheus.GaugeVec
+	circuitOpen    *prometheus.GaugeVec
+	engineP95      *prometheus.GaugeVec
+
+	// TLS metrics
+	certExpiry prometheus.Gauge
+
+	// Memory watchdog metrics
+	memLimit      prometheus.Gauge
+	memRSS        prometheus.Gauge
+	memPressure   prometheus.Gauge
+	gcPercent     prometheus.Gauge
+	cacheCapacity *prometheus.GaugeVec
+
+	// collectors refresh gauges from server state on each collection;
+	// engineSeen holds the health counts already added to the engine counters
+	collectorsMu sync.Mutex
+	collectors   []func(*Metrics)
+	engineSeen   map[string][2]int64
 
 	// System metrics
 	uptimeSeconds   prometheus.Gauge
@@ -281,6 +306,96 @@ func NewMetrics(cfg *config.Config) *Metrics {
 			},
 			[]string{"engine"},
 		),
+		engineUp: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_up",
+				Help: "Whether a search engine is accepting searches (0 while cooling down after failures)",
+			},
+			[]string{"engine"},
+		),
+		quotaUsed: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_quota_used",
+				Help: "Requests an engine with a daily quota has made today",
+			},
+			[]string{"engine"},
+		),
+		quotaRemaining: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_quota_remaining",
+				Help: "Requests left in an engine's daily quota, peak reserve included",
+			},
+			[]string{"engine"},
+		),
+		schemaDrift: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_schema_drift",
+				Help: "Share of an engine's usual response structure missing from recent responses (0-1)",
+			},
+			[]string{"engine"},
+		),
+		parserSuspect: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_parser_suspect",
+				Help: "Whether an engine's responses drifted far enough that its parser is likely broken",
+			},
+			[]string{"engine"},
+		),
+		circuitOpen: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_circuit_open",
+				Help: "Whether an engine's circuit breaker is open, so searches skip it",
+			},
+			[]string{"engine"},
+		),
+		engineP95: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_engine_latency_p95_seconds",
+				Help: "95th percentile time of an engine's last 20 successful calls",
+			},
+			[]string{"engine"},
+		),
+
+		// TLS metrics
+		certExpiry: promauto.With(reg).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "search_ssl_certificate_expiry_timestamp_seconds",
+				Help: "Unix time the served TLS certificate expires (0 when TLS is off)",
+			},
+		),
+
+		// Memory watchdog metrics
+		memLimit: promauto.With(reg).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "search_memory_limit_bytes",
+				Help: "Memory limit the watchdog keeps the process under (0 when unknown)",
+			},
+		),
+		memRSS: promauto.With(reg).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "search_memory_rss_bytes",
+				Help: "Resident memory of the process",
+			},
+		),
+		memPressure: promauto.With(reg).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "search_memory_pressure_level",
+				Help: "Memory pressure: 0 normal, 1 elevated, 2 critical",
+			},
+		),
+		gcPercent: promauto.With(reg).NewGauge(
+			prometheus.GaugeOpts{
+				Name: "search_gc_percent",
+				Help: "GOGC in force (-1 when the garbage collector is off)",
+			},
+		),
+		cacheCapacity: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "search_cache_capacity",
+				Help: "Current capacity of an in-memory cache, in entries",
+			},
+			[]string{"cache"},
+		),
 
 		// System metrics
 		uptimeSeconds: promauto.With(reg).NewGauge(
@@ -382,6 +497,21 @@ func (m *Metrics) collectSystemMetrics() {
 			m.diskUsedPercent.Set(float64(diskUsed) / float64(diskTotal) * 100)
 		}
 	}
+
+	m.collectorsMu.Lock()
+	collectors := append([]func(*Metrics){}, m.collectors...)
+	m.collectorsMu.Unlock()
+	for _, collect := range collectors {
+		collect(m)
+	}
+}
+
+// AddCollector registers fn to refresh metrics from server state every
+// collection interval
+func (m *Metrics) AddCollector(fn func(*Metrics)) {
+	m.collectorsMu.Lock()
+	m.collectors = append(m.collectors, fn)
+	m.collectorsMu.Unlock()
 }
 
 // RecordRequest records an HTTP request
@@ -413,6 +543,81 @@ func (m *Metrics) RecordEngineError(engine string) {
 	m.engineErrors.WithLabelValues(engine).Inc()
 }
 
+// SetEngineHealth records an engine's health. successes and failures are
+// the engine's running totals; the growth since the last call is added to
+// the engine request and error counters.
+func (m *Metrics) SetEngineHealth(engine string, up bool, successes, failures int64) {
+	if up {
+		m.engineUp.WithLabelValues(engine).Set(1)
+	} else {
+		m.engineUp.WithLabelValues(engine).Set(0)
+	}
+
+	m.collectorsMu.Lock()
+	if m.engineSeen == nil {
+		m.engineSeen = make(map[string][2]int64)
+	}
+	seen := m.engineSeen[engine]
+	m.engineSeen[engine] = [2]int64{successes, failures}
+	m.collectorsMu.Unlock()
+
+	if d := (successes + failures) - (seen[0] + seen[1]); d > 0 {
+		m.engineRequests.WithLabelValues(engine).Add(float64(d))
+	}
+	if d := failures - seen[1]; d > 0 {
+		m.engineErrors.WithLabelValues(engine).Add(float64(d))
+	}
+}
+
+// SetEngineDrift records how far an engine's responses drifted from their
+// learned structure
+func (m *Metrics) SetEngineDrift(engine string, drift float64, suspect bool) {
+	m.schemaDrift.WithLabelValues(engine).Set(drift)
+	if suspect {
+		m.parserSuspect.WithLabelValues(engine).Set(1)
+	} else {
+		m.parserSuspect.WithLabelValues(engine).Set(0)
+	}
+}
+
+// SetEngineCircuit records an engine's circuit breaker state and recent
+// latency
+func (m *Metrics) SetEngineCircuit(engine string, open bool, p95 time.Duration) {
+	if open {
+		m.circuitOpen.WithLabelValues(engine).Set(1)
+	} else {
+		m.circuitOpen.WithLabelValues(engine).Set(0)
+	}
+	m.engineP95.WithLabelValues(engine).Set(p95.Seconds())
+}
+
+// SetEngineQuota records an engine's quota usage today
+func (m *Metrics) SetEngineQuota(engine string, used, remaining int) {
+	m.quotaUsed.WithLabelValues(engine).Set(float64(used))
+	m.quotaRemaining.WithLabelValues(engine).Set(float64(remaining))
+}
+
+// SetCertificateExpiry records when the served TLS certificate expires; a
+// zero time means no certificate
+func (m *Metrics) SetCertificateExpiry(notAfter time.Time) {
+	if notAfter.IsZero() {
+		m.certExpiry.Set(0)
+		return
+	}
+	m.certExpiry.Set(float64(notAfter.Unix()))
+}
+
+// SetMemoryStatus records the memory watchdog's latest reading
+func (m *Metrics) SetMemoryStatus(status memwatch.Status) {
+	m.memLimit.Set(float64(status.LimitBytes))
+	m.memRSS.Set(float64(status.RSSBytes))
+	m.memPressure.Set(float64(status.Level))
+	m.gcPercent.Set(float64(status.GCPercent))
+	for _, c := range status.Caches {
+		m.cacheCapacity.WithLabelValues(c.Name).Set(float64(c.Size))
+	}
+}
+
 // RecordDBQuery records a database query
 func (m *Metrics) RecordDBQuery(operation, table string, duration time.Duration) {
 	m.dbQueriesTotal.WithLabelValues(operation, table).Inc()
@@ -487,20 +692,22 @@ func (m *Metrics) AuthenticatedHandler() http.HandlerFunc {
 		// Check for Bearer token if configured
 		token := m.config.Server.Metrics.Token
 		if token != "" {
+			timer := security.StartAuth()
 			auth := r.Header.Get("Authorization")
 			if auth == "" {
+				timer.Fail(r.Context())
 				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
 				localizedHTTPError(w, r, http.StatusUnauthorized, "errors.unauthorized")
 				return
 			}
 			if len(auth) < 7 || auth[:7] != "Bearer " {
+				timer.Fail(r.Context())
 				localizedHTTPError(w, r, http.StatusUnauthorized, "errors.unauthorized")
 				return
 			}
-			// Compare over fixed-length SHA-256 digests so timing does not leak the token length.
-			presentedSum := sha256.Sum256([]byte(auth[7:]))
-			expectedSum := sha256.Sum256([]byte(token))
-			if subtle.ConstantTimeCompare(presentedSum[:], expectedSum[:]) != 1 {
+			// Constant-time, so timing leaks neither the token nor its length
+			if !security.TokenEqual(auth[7:], token) {
+				timer.Fail(r.Context())
 				localizedHTTPError(w, r, http.StatusUnauthorized, "errors.invalid_token")
 				return
 			}
@@ -555,6 +762,11 @@ func (w *metricsResponseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // normalizePath normalizes URL paths to reduce cardinality
 func normalizePath(path string) string {
 	// Normalize common patterns to reduce cardinality
@@ -662,3 +874,37 @@ func getDiskUsage() (used, total uint64) {
 
 	return getDiskUsageUnix()
 }
+
+// collectServerMetrics refreshes the engine health, certificate and memory
+// metrics the exported alert rules watch (see --observability export)
+func (s *Server) collectServerMetrics(m *Metrics) {
+	if s.registry != nil {
+		for _, eng := range s.registry.GetEnabled() {
+			tracker, ok := eng.(interface{ GetHealth() search.EngineHealth })
+			if !ok {
+				continue
+			}
+			health := tracker.GetHealth()
+			m.SetEngineHealth(eng.Name(), health.Healthy, health.SuccessCount, health.FailureCount)
+			m.SetEngineDrift(eng.Name(), health.SchemaDrift, health.ParserSuspect)
+			m.SetEngineCircuit(eng.Name(), health.Circuit == search.CircuitOpen, time.Duration(health.P95ResponseTimeMS)*time.Millisecond)
+		}
+	}
+	if s.aggregator != nil {
+		for _, quota := range s.aggregator.QuotaStatus(time.Now()) {
+			m.SetEngineQuota(quota.Engine, quota.Used, quota.Remaining)
+		}
+	}
+
+	var notAfter time.Time
+	if s.tlsManager != nil {
+		if info, err := s.tlsManager.GetCertInfo(); err == nil {
+			notAfter = info.NotAfter
+		}
+	}
+	m.SetCertificateExpiry(notAfter)
+
+	if s.memWatch != nil {
+		m.SetMemoryStatus(s.memWatch.Status())
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/apimgr/search/src/api"
+	"github.com/apimgr/search/src/common/httputil"
 	"github.com/apimgr/search/src/common/i18n"
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/geoip"
@@ -155,7 +156,7 @@ func (m *Middleware) SecurityHeaders(next http.Handler) http.Handler {
 			if host == "" {
 				host = m.config.Server.BaseURL
 			}
-			reportsURL := fmt.Sprintf("%s://%s/api/v1/server/reports/default", scheme, host)
+			reportsURL := fmt.Sprintf("%s://%s%s/api/v1/server/reports/default", scheme, host, m.config.Server.BasePath)
 			w.Header().Set("Reporting-Endpoints", fmt.Sprintf(`default="%s"`, reportsURL))
 			w.Header().Set("Report-To", fmt.Sprintf(
 				`{"group":"default","max_age":%d,"endpoints":[{"url":"%s"}]}`,
@@ -572,15 +573,15 @@ func (c *CSRFMiddleware) Protect(next http.Handler) http.Handler {
 			c.tokens.Store(token, time.Now())
 
 			// Set cookie — SameSite=Strict per AI.md PART 11 (blocks cross-site attachment,
-			// neutralizing CSRF before the double-submit check even runs).
-			http.SetCookie(w, &http.Cookie{
+			// neutralizing CSRF before the double-submit check even runs) unless
+			// server.security.cookies.csrf says otherwise for an embedded deployment.
+			cookie := &http.Cookie{
 				Name:     csrf.CookieName,
 				Value:    token,
-				Path:     "/",
 				HttpOnly: true,
-				Secure:   c.config.Server.SSL.Enabled,
-				SameSite: http.SameSiteStrictMode,
-			})
+			}
+			c.config.Server.Security.Cookies.CSRF.Apply(cookie, http.SameSiteStrictMode, c.config.Server.RequestTLS(r))
+			http.SetCookie(w, cookie)
 
 			next.ServeHTTP(w, r)
 			return
@@ -641,9 +642,16 @@ func (m *Middleware) Logger(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
-		// Log to access log (Apache/Nginx combined format)
+		// Log to access log; the client address is resolved only for the
+		// anonymized privacy preset, which records its network, and not
+		// for a request that opted out of tracking
 		if m.logManager != nil {
-			m.logManager.Access().LogRequest(r, wrapped.statusCode, int64(wrapped.bytesWritten), duration)
+			access := m.logManager.Access()
+			clientIP := ""
+			if access.Privacy() == logging.PrivacyAnonymized && !httputil.RequestPrivacySignals(r).OptOut() {
+				clientIP = getClientIP(r, m.config.Server.TrustedProxies.Additional)
+			}
+			access.LogRequestFrom(r, clientIP, "", wrapped.statusCode, int64(wrapped.bytesWritten), duration)
 		}
 
 		// Debug-mode request log — never log client IP (privacy is the product)
@@ -678,6 +686,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Recovery middleware recovers from panics
 // Per AI.md PART 9: All panics must be safely recovered and logged with context
 func (m *Middleware) Recovery(next http.Handler) http.Handler {
@@ -36,6 +36,7 @@ func (s *Server) renderJSONSearchResults(w http.ResponseWriter, results *model.S
 func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *SearchPageData) {
 	lang := data.Lang
 	im := s.getI18nManager()
+	base := html.EscapeString(s.config.Server.BasePath)
 
 	title := im.T(lang, "search.results_title")
 	if data.Query != "" {
@@ -56,10 +57,10 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 
 	// Header with search form
 	b.WriteString("<header>\n")
-	b.WriteString(`<nav><a href="/">` + html.EscapeString(s.config.Server.Title) + `</a></nav>` + "\n")
+	b.WriteString(`<nav><a href="` + base + `/">` + html.EscapeString(s.config.Server.Title) + `</a></nav>` + "\n")
 
 	// Search form — GET /search, all controls via standard HTML
-	b.WriteString(`<form method="GET" action="/search" role="search">` + "\n")
+	b.WriteString(`<form method="GET" action="` + base + `/search" role="search">` + "\n")
 	b.WriteString(`<label for="q">` + html.EscapeString(im.T(lang, "search.placeholder")) + `</label>` + "\n")
 	b.WriteString(`<input id="q" type="search" name="q" value="` + html.EscapeString(data.Query) + `" required autofocus>` + "\n")
 	b.WriteString(`<input type="hidden" name="category" value="` + html.EscapeString(data.Category) + `">` + "\n")
@@ -81,10 +82,11 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 		{"science", im.T(lang, "search.categories.science")},
 		{"it", im.T(lang, "search.categories.it")},
 		{"social", im.T(lang, "search.categories.social")},
+		{"packages", im.T(lang, "search.categories.packages")},
 	}
 	b.WriteString(`<nav aria-label="` + html.EscapeString(im.T(lang, "search.categories_label")) + `">` + "\n<ul>\n")
 	for _, cat := range categories {
-		href := "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + cat.key
+		href := base + "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + cat.key
 		active := ""
 		if cat.key == data.Category {
 			active = ` aria-current="page"`
@@ -96,6 +98,14 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 	// Results section
 	results, _ := data.Results.([]model.Result)
 
+	if data.Degraded {
+		degradedMsg := im.T(lang, "search.degraded_none")
+		if len(results) > 0 {
+			degradedMsg = im.T(lang, "search.degraded_cached", data.CachedAt)
+		}
+		b.WriteString(`<p class="degraded" role="status">` + html.EscapeString(degradedMsg) + `</p>` + "\n")
+	}
+
 	if data.TotalResults > 0 {
 		resultCountMsg := im.T(lang, "search.result_count_other", data.TotalResults)
 		b.WriteString(`<p class="result-count">` + html.EscapeString(resultCountMsg) + `</p>` + "\n")
@@ -124,11 +134,11 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 	if data.Pagination != nil && data.Pagination.TotalPages > 1 {
 		b.WriteString(`<nav aria-label="` + html.EscapeString(im.T(lang, "search.pagination_label")) + `">` + "\n<ul>\n")
 		if data.Pagination.HasPrev {
-			href := "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + html.EscapeString(data.Category) + "&amp;page=" + itoa(data.Pagination.PrevPage)
+			href := base + "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + html.EscapeString(data.Category) + "&amp;page=" + itoa(data.Pagination.PrevPage)
 			b.WriteString(`<li><a href="` + href + `" rel="prev">` + html.EscapeString(im.T(lang, "search.prev_page")) + `</a></li>` + "\n")
 		}
 		for _, p := range data.Pagination.Pages {
-			href := "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + html.EscapeString(data.Category) + "&amp;page=" + itoa(p)
+			href := base + "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + html.EscapeString(data.Category) + "&amp;page=" + itoa(p)
 			current := ""
 			if p == data.Pagination.CurrentPage {
 				current = ` aria-current="page"`
@@ -136,7 +146,7 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 			b.WriteString(`<li><a href="` + href + `"` + current + `>` + itoa(p) + `</a></li>` + "\n")
 		}
 		if data.Pagination.HasNext {
-			href := "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + html.EscapeString(data.Category) + "&amp;page=" + itoa(data.Pagination.NextPage)
+			href := base + "/search?q=" + htmlQueryEscape(data.Query) + "&amp;category=" + html.EscapeString(data.Category) + "&amp;page=" + itoa(data.Pagination.NextPage)
 			b.WriteString(`<li><a href="` + href + `" rel="next">` + html.EscapeString(im.T(lang, "search.next_page")) + `</a></li>` + "\n")
 		}
 		b.WriteString("</ul>\n</nav>\n")
@@ -146,7 +156,7 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 
 	// Minimal footer
 	b.WriteString("<footer>\n")
-	b.WriteString(`<p><a href="/">` + html.EscapeString(s.config.Server.Title) + `</a> &mdash; <a href="/privacy">` + html.EscapeString(im.T(lang, "footer.privacy_policy")) + `</a></p>` + "\n")
+	b.WriteString(`<p><a href="` + base + `/">` + html.EscapeString(s.config.Server.Title) + `</a> &mdash; <a href="` + base + `/privacy">` + html.EscapeString(im.T(lang, "footer.privacy_policy")) + `</a></p>` + "\n")
 	b.WriteString("</footer>\n</body>\n</html>\n")
 
 	if _, err := fmt.Fprint(w, b.String()); err != nil {
@@ -159,6 +169,7 @@ func (s *Server) renderNoJSSearch(w http.ResponseWriter, r *http.Request, data *
 func (s *Server) renderNoJSHome(w http.ResponseWriter, r *http.Request, data *PageData) {
 	lang := data.Lang
 	im := s.getI18nManager()
+	base := html.EscapeString(s.config.Server.BasePath)
 	title := html.EscapeString(s.config.Server.Title)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -180,18 +191,18 @@ func (s *Server) renderNoJSHome(w http.ResponseWriter, r *http.Request, data *Pa
 	}
 
 	// Search form
-	b.WriteString(`<form method="GET" action="/search" role="search">` + "\n")
+	b.WriteString(`<form method="GET" action="` + base + `/search" role="search">` + "\n")
 	b.WriteString(`<label for="q">` + html.EscapeString(im.T(lang, "search.placeholder")) + `</label>` + "\n")
-	b.WriteString(`<input id="q" type="search" name="q" required autofocus>` + "\n")
+	b.WriteString(`<input id="q" type="search" name="q" value="` + html.EscapeString(data.Query) + `" required autofocus>` + "\n")
 	b.WriteString(`<button type="submit">` + html.EscapeString(im.T(lang, "search.button")) + `</button>` + "\n")
 	b.WriteString("</form>\n")
 	b.WriteString("</main>\n")
 
 	b.WriteString("<footer>\n")
 	b.WriteString(`<ul>` + "\n")
-	b.WriteString(`<li><a href="/about">` + html.EscapeString(im.T(lang, "nav.about")) + `</a></li>` + "\n")
-	b.WriteString(`<li><a href="/privacy">` + html.EscapeString(im.T(lang, "footer.privacy_policy")) + `</a></li>` + "\n")
-	b.WriteString(`<li><a href="/preferences">` + html.EscapeString(im.T(lang, "nav.preferences")) + `</a></li>` + "\n")
+	b.WriteString(`<li><a href="` + base + `/about">` + html.EscapeString(im.T(lang, "nav.about")) + `</a></li>` + "\n")
+	b.WriteString(`<li><a href="` + base + `/privacy">` + html.EscapeString(im.T(lang, "footer.privacy_policy")) + `</a></li>` + "\n")
+	b.WriteString(`<li><a href="` + base + `/preferences">` + html.EscapeString(im.T(lang, "nav.preferences")) + `</a></li>` + "\n")
 	b.WriteString(`</ul>` + "\n")
 	b.WriteString("</footer>\n</body>\n</html>\n")
 
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/search/src/common/httputil"
+	"github.com/apimgr/search/src/config"
+)
+
+// onionRedirectCookie is set from /preferences by users who want this
+// browser sent straight to the onion address (Tor Browser users)
+const onionRedirectCookie = "onion_redirect"
+
+// onionLocation advertises the onion address on clearnet responses with the
+// Onion-Location header, which Tor Browser offers to follow. Users who opted
+// in on /preferences are redirected there instead. Requests to an additional
+// hidden service are limited to the routes its scope serves.
+func (s *Server) onionLocation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isOnionRequest(r) && !s.onionServes(r) {
+			s.handleNotFound(w, r)
+			return
+		}
+		onion := s.onionURL(r)
+		if onion == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if wantsOnionRedirect(r) {
+			http.Redirect(w, r, onion, http.StatusFound)
+			return
+		}
+		w.Header().Set("Onion-Location", onion)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// onionURL returns the onion counterpart of a clearnet request, or "" when
+// the hidden service is not running or the request arrived over it
+func (s *Server) onionURL(r *http.Request) string {
+	onion := s.TorAddress()
+	if onion == "" || isOnionRequest(r) {
+		return ""
+	}
+	if port := s.config.Server.Tor.VirtualPort; port != 0 && port != 80 {
+		onion = net.JoinHostPort(onion, strconv.Itoa(port))
+	}
+	return "http://" + onion + r.URL.RequestURI()
+}
+
+// onionServes reports whether the onion a request was made to serves its
+// path
+func (s *Server) onionServes(r *http.Request) bool {
+	if s.torService == nil {
+		return true
+	}
+	host := httputil.GetHostFromRequest(r)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return scopeServes(s.torService.ServiceScope(host), r.URL.Path)
+}
+
+// scopeServes reports whether a hidden service scope serves path. API-scoped
+// onions serve only the API, OpenAPI docs and health checks.
+func scopeServes(scope, path string) bool {
+	if scope != config.TorScopeAPI {
+		return true
+	}
+	switch {
+	case strings.HasPrefix(path, "/api/"), strings.HasPrefix(path, "/openapi"):
+		return true
+	case path == "/server/healthz", path == "/server/healthz.txt", path == "/readyz", path == "/livez":
+		return true
+	}
+	return false
+}
+
+// isOnionRequest reports whether the request was made to a .onion host
+func isOnionRequest(r *http.Request) bool {
+	host := httputil.GetHostFromRequest(r)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.HasSuffix(strings.ToLower(host), ".onion")
+}
+
+// wantsOnionRedirect reports whether a page load should be redirected to the
+// onion address. The preferences page is never redirected, so a browser that
+// cannot reach the onion can always turn the option off again.
+func wantsOnionRedirect(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if c, err := r.Cookie(onionRedirectCookie); err != nil || c.Value != "1" {
+		return false
+	}
+	switch path := r.URL.Path; {
+	case path == "/preferences", path == "/server/preferences":
+		return false
+	case strings.HasPrefix(path, "/api/"), strings.HasPrefix(path, "/static/"):
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
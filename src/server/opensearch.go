@@ -4,9 +4,11 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 
@@ -99,9 +101,15 @@ func (s *Server) handleOpenSearch(w http.ResponseWriter, r *http.Request) {
 			},
 			{
 				Type:     "application/x-suggestions+json",
-				Template: baseURL + api.APIPrefix + "/autocomplete?q={searchTerms}",
+				Template: baseURL + api.APIPrefix + "/autocomplete?q={searchTerms}&format=opensearch",
 				Rel:      "suggestions",
 			},
+			{
+				// Lets browsers refresh the description from where they found it
+				Type:     "application/opensearchdescription+xml",
+				Template: baseURL + "/opensearch.xml",
+				Rel:      "self",
+			},
 		},
 	}
 
@@ -111,10 +119,14 @@ func (s *Server) handleOpenSearch(w http.ResponseWriter, r *http.Request) {
 		if !strings.HasPrefix(imageURL, "http") {
 			imageURL = baseURL + imageURL
 		}
+		imageType := mime.TypeByExtension(path.Ext(strings.SplitN(imageURL, "?", 2)[0]))
+		if imageType == "" {
+			imageType = "image/png"
+		}
 		osd.Image = &OpenSearchImage{
 			Width:  64,
 			Height: 64,
-			Type:   "image/png",
+			Type:   imageType,
 			URL:    imageURL,
 		}
 	}
@@ -244,6 +256,8 @@ func (s *Server) handlePreferences(w http.ResponseWriter, r *http.Request) {
 		"bangs":      s.bangManager.GetAll(),
 		"categories": s.bangManager.GetCategories(),
 		"builtins":   s.bangManager.GetBuiltins(),
+		"macros":     instanceMacros(s.config.Search.Macros),
+		"userMacros": userMacros(r),
 	}
 
 	if err := s.renderer.Render(w, "preferences", data); err != nil {
@@ -301,16 +315,13 @@ func (s *Server) handleWidgetPreferencesSave(w http.ResponseWriter, r *http.Requ
 	http.Redirect(w, r, "/preferences", http.StatusSeeOther)
 }
 
-// getBaseURL returns the base URL for the server.
+// getBaseURL returns the base URL for the server, server.base_path
+// included.
 // Honors reverse-proxy headers only from trusted proxies (per AI.md PART 12).
 func (s *Server) getBaseURL(r *http.Request) string {
-	// Use configured base URL if available
-	if s.config.Server.BaseURL != "" {
-		return strings.TrimRight(s.config.Server.BaseURL, "/")
-	}
 	scheme := httputil.GetProtoFromRequest(r)
 	host := httputil.GetHostFromRequest(r)
-	return fmt.Sprintf("%s://%s", scheme, host)
+	return s.config.Server.PublicURL(fmt.Sprintf("%s://%s", scheme, host))
 }
 
 // validateNotPrivateProxy rejects hostnames that resolve to private, loopback,
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/security"
+)
+
+// jwtKeys returns the keys that sign and verify operator JWTs, the signing
+// key first: server.jwt.keys, or one derived from server.secret_key
+func (s *Server) jwtKeys() []security.JWTKey {
+	cfg := s.config.Get()
+	if len(cfg.JWT.Keys) == 0 {
+		mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+		mac.Write([]byte("operator-jwt"))
+		return []security.JWTKey{{ID: "secret_key", Secret: mac.Sum(nil)}}
+	}
+	keys := make([]security.JWTKey, len(cfg.JWT.Keys))
+	for i, k := range cfg.JWT.Keys {
+		keys[i] = security.JWTKey{ID: k.ID, Secret: []byte(k.Secret)}
+	}
+	return keys
+}
+
+// operatorJWT returns the claims of the valid operator JWT the request
+// presents, or nil. Nothing is looked up: the signature is the proof.
+func (s *Server) operatorJWT(r *http.Request) *security.OperatorClaims {
+	if s.config == nil || !s.config.Get().JWT.Enabled {
+		return nil
+	}
+	presented, ok := extractBearerToken(r)
+	if !ok || !security.LooksLikeJWT(presented) {
+		return nil
+	}
+	claims, err := security.VerifyOperatorJWT(presented, s.jwtKeys(), time.Now())
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
+// handleOperatorJWTIssue exchanges the server token or a named operator
+// token for a JWT valid for server.jwt.ttl. The optional body
+// {"ttl": "5m"} asks for a shorter lifetime. A JWT cannot be exchanged for
+// another, so access ends when the credential behind it is revoked and its
+// last JWT expires.
+func (s *Server) handleOperatorJWTIssue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	cfg := s.config.Get().JWT
+	if !cfg.Enabled {
+		respondError(w, http.StatusNotFound, "JWT mode is not enabled (server.jwt.enabled)")
+		return
+	}
+	timer := security.StartAuth()
+	var token *security.OperatorToken
+	if !ValidateOperatorToken(r, s.config) {
+		if token = s.namedOperatorToken(r); token == nil {
+			timer.Fail(r.Context())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="operator"`)
+			localizedHTTPError(w, r, http.StatusUnauthorized, "errors.unauthorized")
+			return
+		}
+		if !s.allowOperatorToken(w, r, token) {
+			return
+		}
+	}
+
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil || ttl <= 0 {
+		respondError(w, http.StatusInternalServerError, "server.jwt.ttl is not a valid duration")
+		return
+	}
+	var req struct {
+		TTL string `json:"ttl"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON")
+		return
+	}
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			respondError(w, http.StatusBadRequest, "ttl must be a positive duration such as 5m")
+			return
+		}
+		ttl = min(ttl, d)
+	}
+
+	now := time.Now()
+	claims := security.OperatorJWTClaims(token, ttl, now)
+	keys := s.jwtKeys()
+	signed, err := security.SignOperatorJWT(claims, keys[0])
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogTokenCreate(claims.Subject, getClientIPSimple(r), "jwt:"+claims.ID)
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"token":      signed,
+			"token_type": "Bearer",
+			"expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+			"expires_in": claims.ExpiresAt - claims.IssuedAt,
+		},
+	})
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/backup"
+	"github.com/apimgr/search/src/security"
+)
+
+// serverDBOrUnavailable reports whether the server database is available,
+// writing a 503 when it is not
+func (s *Server) serverDBOrUnavailable(w http.ResponseWriter) bool {
+	if s.dbManager == nil || s.dbManager.ServerDB() == nil {
+		respondError(w, http.StatusServiceUnavailable, "Server database is not available")
+		return false
+	}
+	return true
+}
+
+// handleOperatorTokens lists the named operator tokens, revoked and expired
+// ones included, with their scopes and last use
+func (s *Server) handleOperatorTokens(w http.ResponseWriter, r *http.Request) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	tokens, err := security.ListOperatorTokens(r.Context(), s.dbManager.ServerDB())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	now := time.Now()
+	list := make([]map[string]any, 0, len(tokens))
+	for _, t := range tokens {
+		list = append(list, map[string]any{
+			"token":   t,
+			"expired": t.Expired(now),
+		})
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"tokens": list,
+			"scopes": security.OperatorTokenScopes,
+		},
+	})
+}
+
+// handleOperatorTokenSelf describes the token the request presents: the
+// server.token holds every scope, a named token its own name, scopes and
+// expiry. A JWT is described as the token it was exchanged for, with its
+// own expiry.
+func (s *Server) handleOperatorTokenSelf(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	timer := security.StartAuth()
+	if claims := s.operatorJWT(r); claims != nil {
+		data := map[string]any{
+			"operator":       claims.Operator(),
+			"jwt_expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+		}
+		if claims.Operator() {
+			data["scopes"] = security.OperatorTokenScopes
+		} else {
+			data["token"] = claims.Token()
+		}
+		respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": data})
+		return
+	}
+	if ValidateOperatorToken(r, s.config) {
+		respondJSON(w, http.StatusOK, map[string]any{
+			"ok": true,
+			"data": map[string]any{
+				"operator": true,
+				"scopes":   security.OperatorTokenScopes,
+			},
+		})
+		return
+	}
+	token := s.namedOperatorToken(r)
+	if token == nil {
+		timer.Fail(r.Context())
+		w.Header().Set("WWW-Authenticate", `Bearer realm="operator"`)
+		localizedHTTPError(w, r, http.StatusUnauthorized, "errors.unauthorized")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"operator": false,
+			"token":    token,
+		},
+	})
+}
+
+// handleOperatorTokenCreate issues a named operator token. The body is
+// {"name": "...", "scopes": [...], "expires_at": "RFC 3339", "rate_limit": n};
+// expires_at may be left out for a token that never expires, and
+// rate_limit for the default requests per minute. The token is returned
+// once and cannot be read again.
+func (s *Server) handleOperatorTokenCreate(w http.ResponseWriter, r *http.Request) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	var req struct {
+		Name      string    `json:"name"`
+		Scopes    []string  `json:"scopes"`
+		ExpiresAt time.Time `json:"expires_at"`
+		RateLimit *int      `json:"rate_limit"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a name and scopes")
+		return
+	}
+	if req.RateLimit != nil && *req.RateLimit < 0 {
+		respondError(w, http.StatusBadRequest, security.ErrOperatorTokenRateLimit.Error())
+		return
+	}
+
+	raw, token, err := security.CreateOperatorToken(r.Context(), s.dbManager.ServerDB(), req.Name, req.Scopes, req.ExpiresAt, time.Now())
+	switch {
+	case errors.Is(err, security.ErrOperatorTokenName),
+		errors.Is(err, security.ErrOperatorTokenScope),
+		errors.Is(err, security.ErrOperatorTokenExpiry):
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if req.RateLimit != nil {
+		if err := security.SetOperatorTokenRateLimit(r.Context(), s.dbManager.ServerDB(), token.ID, *req.RateLimit); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		token.RateLimit = *req.RateLimit
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogTokenCreate("operator", getClientIPSimple(r), token.Name)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"token":  raw,
+			"detail": token,
+		},
+	})
+}
+
+// handleOperatorTokenRevoke revokes a named operator token by id
+func (s *Server) handleOperatorTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusNotFound, security.ErrOperatorTokenNotFound.Error())
+		return
+	}
+	err = security.RevokeOperatorToken(r.Context(), s.dbManager.ServerDB(), id)
+	switch {
+	case errors.Is(err, security.ErrOperatorTokenNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogTokenRevoke("operator", getClientIPSimple(r), strconv.FormatInt(id, 10))
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleBackups lists the backup archives in the backup directory
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := backup.NewManager().List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	list := make([]map[string]any, 0, len(backups))
+	for _, b := range backups {
+		list = append(list, map[string]any{
+			"filename":   b.Filename,
+			"size":       b.Size,
+			"created_at": b.CreatedAt.UTC(),
+		})
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": map[string]any{"backups": list},
+	})
+}
+
+// handleBackupCreate runs a backup now, with the same encryption,
+// verification and retention as the scheduled backups
+func (s *Server) handleBackupCreate(w http.ResponseWriter, r *http.Request) {
+	if err := s.performScheduledBackup(r.Context(), "manual"); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	backups, err := backup.NewManager().List()
+	if err != nil || len(backups) == 0 {
+		respondJSON(w, http.StatusCreated, map[string]any{"ok": true})
+		return
+	}
+	latest := backups[0]
+	for _, b := range backups[1:] {
+		if b.CreatedAt.After(latest.CreatedAt) {
+			latest = b
+		}
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"filename":   filepath.Base(latest.Filename),
+			"size":       latest.Size,
+			"created_at": latest.CreatedAt.UTC(),
+		},
+	})
+}
@@ -33,6 +33,8 @@ func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
 
 	data := s.newPageData(w, r, "", "home")
 	data.CSRFToken = s.getCSRFToken(r)
+	// ?q= pre-fills the search box without searching (see handleShare)
+	data.Query = sanitizeInput(strings.TrimSpace(r.URL.Query().Get("q")))
 
 	// Text browsers receive a JavaScript-free home page (just a search form).
 	// Per AI.md PART 14: text browsers are INTERACTIVE, no JS.
@@ -407,6 +409,7 @@ func (s *Server) handleHelp(w http.ResponseWriter, r *http.Request) {
 	data.Title = s.getI18nManager().T(data.Lang, "help.page_title")
 	data.CSRFToken = s.getCSRFToken(r)
 	data.ServerURL = s.getBaseURL(r)
+	data.WebAddress = s.qrAddress(r, qrTargetWeb)
 
 	if err := s.renderer.Render(w, "help", data); err != nil {
 		s.handleInternalError(w, r, "template render", err)
@@ -691,6 +694,7 @@ func (s *Server) respondHealthHTML(w http.ResponseWriter, r *http.Request, healt
 	// Use newPageData for TorAddress support per AI.md PART 32
 	baseData := s.newPageData(w, r, "", "healthz")
 	baseData.Title = s.getI18nManager().T(baseData.Lang, "health.page_title")
+	baseData.WebAddress = s.qrAddress(r, qrTargetWeb)
 
 	data := &HealthPageData{
 		PageData: *baseData,
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/permalink"
+	"github.com/go-chi/chi/v5"
+)
+
+// handlePermalinkCreate stores the posted search and redirects to its
+// /s/<token> link. The search arrives in the form body, so it never shows
+// up in a URL.
+func (s *Server) handlePermalinkCreate(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Search.Permalinks.Enabled || s.permalinks == nil {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.handleError(w, r, http.StatusBadRequest, i18n.RequestString(r, "search.error_title"), i18n.RequestString(r, "search.empty_query"))
+		return
+	}
+	perPage, _ := strconv.Atoi(strings.TrimSpace(r.FormValue("per_page")))
+	link, err := s.permalinks.Create(r.Context(), permalink.State{
+		Query:      sanitizeInput(r.FormValue("q")),
+		Category:   sanitizeInput(r.FormValue("category")),
+		SafeSearch: r.FormValue("safe_search"),
+		TimeRange:  r.FormValue("time_range"),
+		PerPage:    perPage,
+	}, permalink.ParseTTL(s.config.Search.Permalinks.TTL))
+	if errors.Is(err, permalink.ErrInvalidState) {
+		s.handleError(w, r, http.StatusBadRequest, i18n.RequestString(r, "search.error_title"), i18n.RequestString(r, "search.empty_query"))
+		return
+	}
+	if err != nil {
+		slog.Error("permalink create failed", "err", err)
+		localizedHTTPError(w, r, http.StatusInternalServerError, "errors.server_error")
+		return
+	}
+	http.Redirect(w, r, "/s/"+link.Token, http.StatusSeeOther)
+}
+
+// handlePermalink runs the search behind a /s/<token> link. Only the
+// token is in the URL, so access logs and referrers never carry the query;
+// ?page= may be added to page through the results.
+func (s *Server) handlePermalink(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Search.Permalinks.Enabled || s.permalinks == nil {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+	state, _, err := s.permalinks.Get(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if !errors.Is(err, permalink.ErrNotFound) {
+			slog.Error("permalink load failed", "err", err)
+		}
+		s.handleError(w, r, http.StatusNotFound, i18n.RequestString(r, "search.permalink_expired_title"), i18n.RequestString(r, "search.permalink_expired"))
+		return
+	}
+
+	params := url.Values{}
+	params.Set("q", state.Query)
+	if state.Category != "" {
+		params.Set("category", state.Category)
+	}
+	if state.SafeSearch != "" {
+		params.Set("safe_search", state.SafeSearch)
+	}
+	if state.TimeRange != "" {
+		params.Set("time_range", state.TimeRange)
+	}
+	if state.PerPage > 0 {
+		params.Set("per_page", strconv.Itoa(state.PerPage))
+	}
+	if page := r.URL.Query().Get("page"); page != "" {
+		params.Set("page", page)
+	}
+	if prefs := r.URL.Query().Get("prefs"); prefs != "" {
+		params.Set("prefs", prefs)
+	}
+
+	// The results page must not hand the link on to result sites either
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	search := r.Clone(r.Context())
+	search.URL.RawQuery = params.Encode()
+	s.handleSearch(w, search)
+}
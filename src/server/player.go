@@ -0,0 +1,107 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/player"
+)
+
+// WatchPageData is the page playing a video result
+type WatchPageData struct {
+	PageData
+	URL        string
+	VideoTitle string
+	// Embed is nil when the video cannot be played here
+	Embed *player.Embed
+}
+
+// maxWatchTitle bounds the title shown above the player
+const maxWatchTitle = 200
+
+// videoPlayer returns the configured player, or nil when the watch page is
+// off
+func videoPlayer(cfg *config.Config) *player.Player {
+	if cfg == nil || !cfg.Search.VideoPlayer.Enabled {
+		return nil
+	}
+	return player.New(cfg.Search.VideoPlayer.Frontend, cfg.Search.VideoPlayer.Instance)
+}
+
+// watchHref returns the watch page for a video result, or "" when it cannot
+// be played here. Flagged results keep their warning page.
+func watchHref(p *player.Player, rawURL, title, threat string) string {
+	if threat != "" {
+		return ""
+	}
+	return p.WatchHref(rawURL, title)
+}
+
+// handleWatch plays a video in an embedded player. Nothing from the video
+// host loads until the visitor opens this page, and the frame gets no
+// referrer.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	p := videoPlayer(s.config)
+	if p == nil {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+	target := strings.TrimSpace(r.URL.Query().Get("url"))
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		localizedHTTPError(w, r, http.StatusBadRequest, "errors.bad_request")
+		return
+	}
+
+	title := strings.TrimSpace(r.URL.Query().Get("title"))
+	if runes := []rune(title); len(runes) > maxWatchTitle {
+		title = string(runes[:maxWatchTitle]) + "…"
+	}
+	data := &WatchPageData{URL: target, VideoTitle: title}
+	if embed, ok := p.Embed(target); ok {
+		data.Embed = embed
+		directive := "frame-src"
+		if embed.Video {
+			directive = "media-src"
+		}
+		allowCSPSource(w.Header(), directive, embed.Origin)
+	}
+
+	baseData := s.newPageData(w, r, "", "watch")
+	baseData.Title = s.getI18nManager().T(baseData.Lang, "watch.page_title")
+	if title != "" {
+		baseData.Title = title
+	}
+	data.PageData = *baseData
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	if err := s.renderer.Render(w, "watch", data); err != nil {
+		slog.Error("template render error", "err", err)
+	}
+}
+
+// allowCSPSource adds source to one directive of the response's content
+// security policy, enforced or report-only, adding the directive when the
+// policy lacks it
+func allowCSPSource(h http.Header, directive, source string) {
+	for _, name := range []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+		policy := h.Get(name)
+		if policy == "" {
+			continue
+		}
+		parts := strings.Split(policy, ";")
+		found := false
+		for i, part := range parts {
+			fields := strings.Fields(part)
+			if len(fields) > 0 && strings.EqualFold(fields[0], directive) {
+				parts[i] = strings.TrimRight(part, " ") + " " + source
+				found = true
+			}
+		}
+		if !found {
+			parts = append(parts, " "+directive+" 'self' "+source)
+		}
+		h.Set(name, strings.Join(parts, ";"))
+	}
+}
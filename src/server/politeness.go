@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+)
+
+// hostPoliteness converts search.politeness into the aggregator's per-host
+// request spacing and caps. Durations were checked when the config loaded.
+func hostPoliteness(p config.PolitenessConfig) search.Politeness {
+	if p.Disabled {
+		return search.Politeness{}
+	}
+	duration := func(s string) time.Duration {
+		d, _ := time.ParseDuration(s)
+		return d
+	}
+	hosts := make(map[string]search.HostPolicy, len(p.Hosts))
+	for host, h := range p.Hosts {
+		hosts[host] = search.HostPolicy{MinInterval: duration(h.MinInterval), MaxConcurrent: h.MaxConcurrent}
+	}
+	return search.Politeness{
+		Default:       search.HostPolicy{MinInterval: duration(p.MinInterval), MaxConcurrent: p.MaxConcurrent},
+		Hosts:         hosts,
+		HonorRobots:   !p.IgnoreRobots,
+		MaxCrawlDelay: duration(p.MaxCrawlDelay),
+		MaxWait:       duration(p.MaxWait),
+	}
+}
+
+// handleEngineHosts reports the spacing, Crawl-delay and load of every
+// upstream host the engines asked, busiest first
+func (s *Server) handleEngineHosts(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.aggregator.HostStatus(),
+	})
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// presetView is a configuration preset with the settings it would change
+type presetView struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Changes     []config.SettingChange `json:"changes"`
+}
+
+// handlePresets lists the configuration presets, each with a preview of
+// what applying it would change in the running config
+func (s *Server) handlePresets(w http.ResponseWriter, r *http.Request) {
+	views := make([]presetView, 0, len(config.Presets()))
+	for _, preset := range config.Presets() {
+		changes, err := preset.Preview(s.config)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		views = append(views, presetView{Name: preset.Name, Description: preset.Description, Changes: changes})
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": views,
+	})
+}
+
+// handlePresetApply applies a preset and persists server.yml. With
+// ?dry_run=true it only returns the changes. Engine changes take effect
+// after a restart, since the engine registry is built at startup.
+func (s *Server) handlePresetApply(w http.ResponseWriter, r *http.Request) {
+	preset, err := config.LookupPreset(chi.URLParam(r, "name"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	changes, err := preset.Preview(s.config)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	restart := false
+	for _, change := range changes {
+		if strings.HasPrefix(change.Key, "engines.") {
+			restart = true
+		}
+	}
+	view := map[string]any{
+		"preset":           preset.Name,
+		"changes":          changes,
+		"applied":          false,
+		"restart_required": restart,
+	}
+	if r.URL.Query().Get("dry_run") == "true" || len(changes) == 0 {
+		respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": view})
+		return
+	}
+
+	preset.Apply(s.config)
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("preset", preset.Name); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogConfigChange("operator", getClientIPSimple(r), "preset", fmt.Sprintf("applied %s (%d settings)", preset.Name, len(changes)))
+	}
+	view["applied"] = true
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": view})
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WebAppManifest is the Web App Manifest that makes the instance installable
+type WebAppManifest struct {
+	Name            string                `json:"name"`
+	ShortName       string                `json:"short_name"`
+	Description     string                `json:"description,omitempty"`
+	StartURL        string                `json:"start_url"`
+	Scope           string                `json:"scope"`
+	Display         string                `json:"display"`
+	BackgroundColor string                `json:"background_color"`
+	ThemeColor      string                `json:"theme_color"`
+	Lang            string                `json:"lang,omitempty"`
+	Dir             string                `json:"dir,omitempty"`
+	Categories      []string              `json:"categories,omitempty"`
+	Icons           []WebAppManifestIcon  `json:"icons"`
+	Shortcuts       []WebAppManifestEntry `json:"shortcuts,omitempty"`
+	ShareTarget     *WebAppShareTarget    `json:"share_target,omitempty"`
+}
+
+// WebAppManifestIcon is an icon entry in the manifest
+type WebAppManifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// WebAppManifestEntry is a launcher shortcut in the manifest
+type WebAppManifestEntry struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// WebAppShareTarget registers the installed app as a target of the OS share
+// sheet (Android intents, Web Share Target API)
+type WebAppShareTarget struct {
+	Action string                  `json:"action"`
+	Method string                  `json:"method"`
+	Params WebAppShareTargetParams `json:"params"`
+}
+
+// WebAppShareTargetParams names the query parameters shared data arrives in
+type WebAppShareTargetParams struct {
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// maxSharedQueryRunes caps a shared selection; apps can share whole articles
+const maxSharedQueryRunes = 512
+
+// serviceWorkerVersionToken is replaced with the build version when sw.js is
+// served, so upgrading the server invalidates the browser's offline caches
+const serviceWorkerVersionToken = "__CACHE_VERSION__"
+
+// handleManifest serves the web app manifest built from the instance branding
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	i18nManager := s.getI18nManager()
+	lang := i18nManager.ResolveLanguage(w, r)
+
+	name := s.config.Server.Title
+	if s.config.Server.Branding.Title != "" {
+		name = s.config.Server.Branding.Title
+	}
+	description := s.config.Server.Description
+	if s.config.Server.Branding.Description != "" {
+		description = s.config.Server.Branding.Description
+	}
+	themeColor := "#bd93f9"
+	if s.config.Server.Branding.PrimaryColor != "" {
+		themeColor = s.config.Server.Branding.PrimaryColor
+	}
+
+	path := s.config.Server.URLPath
+	manifest := WebAppManifest{
+		Name:            name,
+		ShortName:       name,
+		Description:     description,
+		StartURL:        path("/"),
+		Scope:           path("/"),
+		Display:         "standalone",
+		BackgroundColor: "#282a36",
+		ThemeColor:      themeColor,
+		Lang:            lang,
+		Categories:      []string{"utilities", "productivity"},
+		Icons: []WebAppManifestIcon{
+			{Src: path("/static/img/icon-192.svg"), Sizes: "192x192", Type: "image/svg+xml", Purpose: "any maskable"},
+			{Src: path("/static/img/icon-512.svg"), Sizes: "512x512", Type: "image/svg+xml", Purpose: "any maskable"},
+			{Src: path("/static/img/favicon.svg"), Sizes: "any", Type: "image/svg+xml", Purpose: "any"},
+		},
+		Shortcuts: []WebAppManifestEntry{
+			{Name: i18nManager.T(lang, "common.search"), URL: path("/")},
+			{Name: i18nManager.T(lang, "nav.settings"), URL: path("/preferences")},
+		},
+		ShareTarget: &WebAppShareTarget{
+			Action: path("/share"),
+			Method: http.MethodGet,
+			Params: WebAppShareTargetParams{Title: "title", Text: "text", URL: "url"},
+		},
+	}
+	if i18nManager.IsRTL(lang) {
+		manifest.Dir = "rtl"
+	}
+
+	body, err := jsonMarshal(manifest)
+	if err != nil {
+		s.handleInternalError(w, r, "manifest", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(body)
+}
+
+// handleServiceWorker serves the service worker from the site root so its
+// scope covers every page. Browsers check it for updates on navigation, so
+// it is never cached by HTTP.
+func (s *Server) handleServiceWorker(w http.ResponseWriter, r *http.Request) {
+	script, err := fs.ReadFile(EmbeddedFS, "static/sw.js")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	script = bytes.ReplaceAll(script, []byte(serviceWorkerVersionToken), []byte(strings.ReplaceAll(getVersion(), "'", "")))
+
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Service-Worker-Allowed", "/")
+	w.Write(script)
+}
+
+// handleOffline renders the page the service worker shows when a page is
+// requested offline and no cached copy exists
+func (s *Server) handleOffline(w http.ResponseWriter, r *http.Request) {
+	data := s.newPageData(w, r, "", "offline")
+	data.Title = s.getI18nManager().T(data.Lang, "offline.title")
+
+	if err := s.renderer.Render(w, "offline", data); err != nil {
+		s.handleInternalError(w, r, "template render", err)
+	}
+}
+
+// handleShare receives data from the OS share sheet and opens the home page
+// with the search box pre-filled, so the user can edit the selection before
+// searching
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := sharedQuery(params.Get("title"), params.Get("text"), params.Get("url"))
+	if query == "" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/?q="+url.QueryEscape(query), http.StatusSeeOther)
+}
+
+// sharedQuery picks the search text from shared data. Selected text wins;
+// apps that share a page usually send only a title and URL, and the title
+// makes the better query.
+func sharedQuery(title, text, link string) string {
+	query := ""
+	for _, candidate := range []string{text, title, link} {
+		if candidate = strings.Join(strings.Fields(sanitizeInput(candidate)), " "); candidate != "" {
+			query = candidate
+			break
+		}
+	}
+	if runes := []rune(query); len(runes) > maxSharedQueryRunes {
+		query = strings.TrimSpace(string(runes[:maxSharedQueryRunes]))
+	}
+	return query
+}
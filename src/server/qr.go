@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/common/qr"
+)
+
+// QR code targets served by handleQRCode
+const (
+	qrTargetWeb   = "web"
+	qrTargetOnion = "onion"
+)
+
+// handleQRCode renders a QR code for the clear web or onion address, so
+// mobile users can scan their way from one to the other. PNG by default;
+// ?format=svg returns a scalable image for pages.
+func (s *Server) handleQRCode(w http.ResponseWriter, r *http.Request) {
+	address := s.qrAddress(r, chi.URLParam(r, "target"))
+	if address == "" {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if r.URL.Query().Get("format") == "svg" {
+		svg, err := qr.SVG(address)
+		if err != nil {
+			s.handleInternalError(w, r, "qr code", err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte(svg))
+		return
+	}
+
+	png, err := qr.PNG(address, qr.DefaultSize)
+	if err != nil {
+		s.handleInternalError(w, r, "qr code", err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+// qrAddress returns the URL a QR target encodes, or "" when it is unknown:
+// the onion address before Tor has published it, or the clear web address
+// when the instance is only reached over Tor and server.base_url is unset
+func (s *Server) qrAddress(r *http.Request, target string) string {
+	switch target {
+	case qrTargetOnion:
+		if onion := s.TorAddress(); onion != "" {
+			return "http://" + onion
+		}
+	case qrTargetWeb:
+		if s.config.Server.BaseURL != "" {
+			return s.config.Server.PublicURL("")
+		}
+		if !isOnionRequest(r) {
+			return s.getBaseURL(r)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// rankingPipeline converts search.ranking into a ranker of its enabled
+// stages, in order. Settings were checked when the config loaded.
+func rankingPipeline(stages []config.RankingStageConfig) *search.Ranker {
+	pipeline := make([]search.RankStage, 0, len(stages))
+	for _, stage := range stages {
+		if !stage.Enabled {
+			continue
+		}
+		switch stage.Name {
+		case search.StageEngineWeight:
+			pipeline = append(pipeline, search.EngineWeight(stage.Weights))
+		case search.StageDomainAuthority:
+			pipeline = append(pipeline, search.DomainAuthority(stage.Weights))
+		case search.StageFreshness:
+			secs, _ := config.ParseDuration(stage.HalfLife)
+			pipeline = append(pipeline, search.Freshness{Boost: stage.Boost, HalfLife: time.Duration(secs) * time.Second})
+		case search.StageDuplicatePenalty:
+			pipeline = append(pipeline, search.DuplicatePenalty{Factor: stage.Factor})
+		}
+	}
+	return search.NewRanker(pipeline...)
+}
+
+// rankingView is the ranking pipeline as the operator API shows it: every
+// stage in order, and the enabled ones now running
+type rankingView struct {
+	Stages []config.RankingStageConfig `json:"stages"`
+	Active []string                    `json:"active"`
+}
+
+func (s *Server) rankingView() rankingView {
+	view := rankingView{Stages: s.config.Search.Ranking.Stages, Active: []string{}}
+	if ranker := s.aggregator.Ranker(); ranker != nil {
+		view.Active = ranker.Stages()
+	}
+	return view
+}
+
+// handleRanking shows the ranking pipeline. Per IDEA.md there is no admin
+// UI; this API orders, enables and previews the ranking stages.
+func (s *Server) handleRanking(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.rankingView(),
+	})
+}
+
+// handleRankingSet replaces the ranking pipeline. The body is
+// {"stages": [{"name": "...", "enabled": true, ...}, ...]} in the order
+// the stages run. Cached rendered pages are dropped so they show the new
+// order; cached results are reranked as they are served.
+func (s *Server) handleRankingSet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stages []config.RankingStageConfig `json:"stages"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a stages list")
+		return
+	}
+	stages, err := config.NormalizeRankingStages(req.Stages)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	previous := s.config.Search.Ranking.Stages
+	s.config.Search.Ranking.Stages = stages
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("search.ranking.stages", stages); err != nil {
+			s.config.Search.Ranking.Stages = previous
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	ranker := rankingPipeline(stages)
+	s.aggregator.SetRanker(ranker)
+	if s.renderCache != nil {
+		s.renderCache.pages.Clear(context.Background(), "*")
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		active := strings.Join(ranker.Stages(), ", ")
+		if active == "" {
+			active = "none"
+		}
+		s.logManager.Audit().LogConfigChange("operator", getClientIPSimple(r), "search.ranking", "stages: "+active)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.rankingView(),
+	})
+}
+
+// handleRankingPreview ranks a sample query with a proposed pipeline
+// without putting it in use. The body is {"query": "...", "category":
+// "general", "stages": [...]}; without stages the saved pipeline is
+// previewed. Each result carries its rank under the proposed pipeline and
+// under the one in use.
+func (s *Server) handleRankingPreview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query    string                      `json:"query"`
+		Category string                      `json:"category"`
+		Stages   []config.RankingStageConfig `json:"stages"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a query")
+		return
+	}
+	query := model.NewQuery(req.Query)
+	if query.Text == "" {
+		respondError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	if req.Category != "" {
+		query.Category = model.Category(strings.ToLower(strings.TrimSpace(req.Category)))
+		if !query.Category.IsValid() {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("unknown category '%s'", req.Category))
+			return
+		}
+	}
+	stages := s.config.Search.Ranking.Stages
+	if req.Stages != nil {
+		var err error
+		if stages, err = config.NormalizeRankingStages(req.Stages); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	proposed := rankingPipeline(stages)
+	changes, err := s.aggregator.PreviewRanking(r.Context(), query, proposed)
+	if err != nil && !errors.Is(err, model.ErrNoResults) {
+		respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []search.RankChange{}
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"query":   query.Text,
+			"stages":  proposed.Stages(),
+			"results": changes,
+		},
+	})
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/apimgr/search/src/cache"
+	"github.com/apimgr/search/src/common/httputil"
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/preferences"
+)
+
+// Defaults for search.render_cache
+const (
+	defaultRenderCacheTTL     = 30 * time.Second
+	defaultRenderCacheEntries = 200
+)
+
+// renderCache holds rendered search result pages for anonymous browsers.
+// A page is keyed by everything that shapes its HTML (see searchPageKey),
+// so two visitors share an entry only when they would be sent the same
+// bytes.
+type renderCache struct {
+	pages   *cache.MemoryCache
+	enabled atomic.Bool
+	ttl     atomic.Int64
+}
+
+// newRenderCache returns a render cache sized and timed by rc
+func newRenderCache(rc config.RenderCacheConfig) *renderCache {
+	entries := rc.MaxEntries
+	if entries <= 0 {
+		entries = defaultRenderCacheEntries
+	}
+	c := &renderCache{pages: cache.NewMemoryCache(entries, defaultRenderCacheTTL)}
+	c.configure(rc)
+	return c
+}
+
+// configure applies search.render_cache and drops every cached page, since
+// a reload may change what the pages show. The number of entries is fixed
+// at startup.
+func (c *renderCache) configure(rc config.RenderCacheConfig) {
+	ttl := defaultRenderCacheTTL
+	if rc.TTL != "" {
+		if d, err := time.ParseDuration(rc.TTL); err != nil || d <= 0 {
+			slog.Warn("search.render_cache.ttl is not a duration, using the default", "ttl", rc.TTL)
+		} else {
+			ttl = d
+		}
+	}
+	c.ttl.Store(int64(ttl))
+	c.enabled.Store(!rc.Disabled)
+	c.clear()
+}
+
+// clear drops every cached page
+func (c *renderCache) clear() {
+	c.pages.Clear(context.Background(), "*")
+}
+
+// get returns the page cached under key, or nil
+func (c *renderCache) get(key string) []byte {
+	page, err := c.pages.Get(context.Background(), key)
+	if err != nil {
+		return nil
+	}
+	return page
+}
+
+// put caches page under key for the configured TTL
+func (c *renderCache) put(key string, page []byte) {
+	c.pages.Set(context.Background(), key, page, time.Duration(c.ttl.Load()))
+}
+
+// searchPageKey returns the render cache key for a search page, or "" when
+// the request must not be answered from the cache: the cache is off, the
+// client is not a regular browser, the request is authenticated, part of
+// a view-as session or carries stored preferences, or templates reload on
+// every request (development).
+func (s *Server) searchPageKey(r *http.Request, query, category string, page, perPage int) string {
+	if s.renderCache == nil || !s.renderCache.enabled.Load() {
+		return ""
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return ""
+	}
+	if s.renderer == nil || s.renderer.devMode {
+		return ""
+	}
+	if httputil.IsOurCliClient(r) || httputil.IsTextBrowser(r) || httputil.IsHttpTool(r) {
+		return ""
+	}
+	// Anyone presenting credentials is treated as logged in
+	if r.Header.Get("Authorization") != "" || s.viewAsSession(r) != nil {
+		return ""
+	}
+	if preferences.TokenFromRequest(r) != "" {
+		return ""
+	}
+
+	params := r.URL.Query()
+	parts := []string{
+		query,
+		category,
+		strconv.Itoa(page),
+		strconv.Itoa(perPage),
+		params.Get("safe_search"),
+		params.Get("time_range"),
+		params.Get("sort"),
+		params.Get("group"),
+		params.Get("image_color"),
+		params.Get("image_license"),
+		params.Get("video_length"),
+		params.Get("video_quality"),
+		s.searchCountry(r),
+		s.requestPrefs(r),
+		s.getI18nManager().ResolveLanguage(nil, r),
+		GetTheme(r),
+		cookieValue(r, "cookieConsent"),
+		cookieValue(r, "dismissed_announcements"),
+	}
+	if s.config.Server.Web.Announcements.Enabled {
+		for _, a := range s.config.Server.Web.Announcements.ActiveAnnouncements() {
+			parts = append(parts, a.ID)
+		}
+	}
+	if s.torService != nil {
+		parts = append(parts, strconv.FormatBool(s.torService.IsRunning()), s.torService.GetOnionAddress())
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return "page:" + hex.EncodeToString(sum[:])
+}
+
+// serveCachedSearchPage writes the page cached under key and reports
+// whether there was one. The language cookie a ?lang= request sets is
+// still written.
+func (s *Server) serveCachedSearchPage(w http.ResponseWriter, r *http.Request, key string) bool {
+	page := s.renderCache.get(key)
+	if page == nil {
+		return false
+	}
+	s.getI18nManager().ResolveLanguage(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+	w.Header().Set("X-Render-Cache", "HIT")
+	w.Write(page)
+	return true
+}
+
+// renderCachedSearchResults renders a search page into a buffer, caches it
+// under key and writes it. Only complete, ordinary result pages are kept:
+// pages with instant answers (which may depend on the visitor) and
+// degraded pages served from stale results are not.
+func (s *Server) renderCachedSearchResults(w http.ResponseWriter, r *http.Request, key, query string, results *model.SearchResults, category string) {
+	data := s.buildSearchPageData(w, r, query, results, category, nil)
+	var buf bytes.Buffer
+	if err := s.renderer.Render(&buf, "search", data); err != nil {
+		s.renderSearchResultsInline(w, r, query, results, category)
+		return
+	}
+	page := buf.Bytes()
+	if !results.Degraded {
+		s.renderCache.put(key, page)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+	w.Header().Set("X-Render-Cache", "MISS")
+	w.Write(page)
+}
+
+// cookieValue returns the value of the named cookie, or ""
+func cookieValue(r *http.Request, name string) string {
+	if c, err := r.Cookie(name); err == nil {
+		return c.Value
+	}
+	return ""
+}
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/moderation"
+)
+
+// reportCaptchaTTL is how long a report form's arithmetic question can be
+// answered
+const reportCaptchaTTL = 30 * time.Minute
+
+// ReportPageData is the report-a-result form
+type ReportPageData struct {
+	PageData
+	URL         string
+	ResultTitle string
+	Reason      string
+	Comment     string
+	Reasons     []string
+	Captcha     bool
+	CaptchaA    int
+	CaptchaB    int
+	CaptchaID   string
+	Error       string
+	Sent        bool
+}
+
+// reportChallenge is the arithmetic question asked before a report is
+// accepted when search.reports.captcha is on
+type reportChallenge struct {
+	A  int    `json:"a"`
+	B  int    `json:"b"`
+	ID string `json:"captcha_id"`
+}
+
+// reportsEnabled reports whether the report form takes reports
+func (s *Server) reportsEnabled() bool {
+	return s.config.Search.Reports.Enabled && s.moderation != nil
+}
+
+// handleReportForm shows the form for reporting one result. JSON clients
+// get the reasons and, when required, a CAPTCHA challenge to answer.
+func (s *Server) handleReportForm(w http.ResponseWriter, r *http.Request) {
+	if !s.reportsEnabled() {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		data := map[string]any{"reasons": moderation.Reasons}
+		if s.config.Search.Reports.Captcha {
+			data["captcha"] = s.newReportChallenge()
+		}
+		respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": data})
+		return
+	}
+	s.renderReportForm(w, r, http.StatusOK, &ReportPageData{
+		URL:         strings.TrimSpace(r.URL.Query().Get("url")),
+		ResultTitle: strings.TrimSpace(r.URL.Query().Get("title")),
+		Reason:      r.URL.Query().Get("reason"),
+	})
+}
+
+// handleReportSubmit files a report from the form. It is rate limited per
+// client IP and, when search.reports.captcha is on, needs the answer to
+// the form's question.
+func (s *Server) handleReportSubmit(w http.ResponseWriter, r *http.Request) {
+	if !s.reportsEnabled() {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+	wantsJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err := r.ParseForm(); err != nil {
+		s.reportFailed(w, r, wantsJSON, http.StatusBadRequest, "report.invalid", nil)
+		return
+	}
+	data := &ReportPageData{
+		URL:         strings.TrimSpace(r.FormValue("url")),
+		ResultTitle: strings.TrimSpace(r.FormValue("title")),
+		Reason:      r.FormValue("reason"),
+		Comment:     strings.TrimSpace(r.FormValue("comment")),
+	}
+
+	if s.reportLimiter != nil && !s.reportLimiter.Allow(getClientIPSimple(r)) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.reportLimiter.RemainingTime(getClientIPSimple(r)).Seconds())+1))
+		s.reportFailed(w, r, wantsJSON, http.StatusTooManyRequests, "report.rate_limited", data)
+		return
+	}
+	if s.config.Search.Reports.Captcha && !s.verifyReportChallenge(r.FormValue("captcha_id"), r.FormValue("captcha")) {
+		s.reportFailed(w, r, wantsJSON, http.StatusBadRequest, "report.captcha_failed", data)
+		return
+	}
+
+	report, err := s.moderation.Submit(r.Context(), data.URL, data.Reason, data.Comment)
+	switch {
+	case errors.Is(err, moderation.ErrInvalidInput):
+		s.reportFailed(w, r, wantsJSON, http.StatusBadRequest, "report.invalid", data)
+		return
+	case errors.Is(err, moderation.ErrQueueFull):
+		s.reportFailed(w, r, wantsJSON, http.StatusServiceUnavailable, "report.rate_limited", data)
+		return
+	case err != nil:
+		slog.Error("result report failed", "err", err)
+		s.reportFailed(w, r, wantsJSON, http.StatusInternalServerError, "errors.server_error", data)
+		return
+	}
+
+	if wantsJSON {
+		// The queue itself is for the operator; the reporter only learns it
+		// was received
+		respondJSON(w, http.StatusAccepted, map[string]any{
+			"ok":   true,
+			"data": map[string]any{"received": true, "reason": report.Reason},
+		})
+		return
+	}
+	s.renderReportForm(w, r, http.StatusOK, &ReportPageData{URL: report.URL, Sent: true})
+}
+
+// reportFailed answers a rejected report: JSON with a fresh challenge when
+// one is needed, otherwise the form again with the error and what was typed
+func (s *Server) reportFailed(w http.ResponseWriter, r *http.Request, wantsJSON bool, status int, messageKey string, data *ReportPageData) {
+	message := i18n.RequestString(r, messageKey)
+	if wantsJSON {
+		details := map[string]any{}
+		if s.config.Search.Reports.Captcha {
+			details["captcha"] = s.newReportChallenge()
+		}
+		body := map[string]any{"ok": false, "error": mapHTTPStatusToCode(status), "message": message, "details": details}
+		respondJSON(w, status, body)
+		return
+	}
+	if data == nil {
+		data = &ReportPageData{}
+	}
+	data.Error = message
+	s.renderReportForm(w, r, status, data)
+}
+
+func (s *Server) renderReportForm(w http.ResponseWriter, r *http.Request, status int, data *ReportPageData) {
+	baseData := s.newPageData(w, r, "", "report")
+	baseData.Title = s.getI18nManager().T(baseData.Lang, "report.page_title")
+	baseData.CSRFToken = s.getCSRFToken(r)
+	data.PageData = *baseData
+	data.Reasons = moderation.Reasons
+	// Images hidden by the content filter link here to report a mistake
+	if data.Reason == moderation.ReasonMisclassified {
+		data.Reasons = []string{moderation.ReasonMisclassified}
+	}
+	if !data.Sent && s.config.Search.Reports.Captcha {
+		challenge := s.newReportChallenge()
+		data.Captcha = true
+		data.CaptchaA, data.CaptchaB, data.CaptchaID = challenge.A, challenge.B, challenge.ID
+	}
+	// Keep the reported URL out of the Referer of any link on the page
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := s.renderer.Render(w, "report", data); err != nil {
+		slog.Error("template render error", "err", err)
+	}
+}
+
+// newReportChallenge makes an arithmetic question. Its ID carries an expiry
+// and an HMAC over the answer and expiry, never the answer itself.
+func (s *Server) newReportChallenge() reportChallenge {
+	a, _ := rand.Int(rand.Reader, big.NewInt(10))
+	b, _ := rand.Int(rand.Reader, big.NewInt(10))
+	challenge := reportChallenge{A: int(a.Int64()) + 1, B: int(b.Int64()) + 1}
+	expires := strconv.FormatInt(time.Now().Add(reportCaptchaTTL).Unix(), 10)
+	challenge.ID = expires + "." + s.reportChallengeMAC(challenge.A+challenge.B, expires)
+	return challenge
+}
+
+// verifyReportChallenge checks an answer against an unexpired challenge ID
+func (s *Server) verifyReportChallenge(id, answer string) bool {
+	expires, mac, ok := strings.Cut(id, ".")
+	if !ok {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(s.reportChallengeMAC(n, expires)))
+}
+
+func (s *Server) reportChallengeMAC(answer int, expires string) string {
+	// Keyed like signCaptcha: stable per process, not guessable
+	mac := hmac.New(sha256.New, []byte("report:"+s.startTime.Format(time.RFC3339Nano)))
+	mac.Write([]byte(strconv.Itoa(answer) + "|" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// handleReports lists reported results for review, most reported first.
+// Per IDEA.md there is no admin UI; this API is the moderation queue.
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	if s.moderation == nil {
+		respondError(w, http.StatusServiceUnavailable, "Report storage is unavailable")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	reports, err := s.moderation.List(r.Context(), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": reports})
+}
+
+// handleReportResolve closes a report. The body is {"action": "dismiss" |
+// "block_domain" | "block_url" | "allow_image", "note": "..."}; the block
+// actions add a result rule and close every open report it covers, and
+// allow_image exempts a wrongly hidden image from the content filter.
+func (s *Server) handleReportResolve(w http.ResponseWriter, r *http.Request) {
+	if s.moderation == nil {
+		respondError(w, http.StatusServiceUnavailable, "Report storage is unavailable")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Report not found")
+		return
+	}
+	var req struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	report, rule, err := s.moderation.Resolve(r.Context(), id, req.Action, req.Note)
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogReportResolved("operator", getClientIPSimple(r), report.ID, req.Action)
+		if rule != nil {
+			s.logManager.Audit().LogResultRuleChanged("operator", getClientIPSimple(r), true, rule.Kind, rule.Pattern)
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": map[string]any{"report": report, "rule": rule},
+	})
+}
+
+// handleResultRules lists the result blocklist
+func (s *Server) handleResultRules(w http.ResponseWriter, r *http.Request) {
+	if s.moderation == nil {
+		respondError(w, http.StatusServiceUnavailable, "Report storage is unavailable")
+		return
+	}
+	rules, err := s.moderation.Rules(r.Context())
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": rules})
+}
+
+// handleResultRuleAdd blocks a domain or URL, or exempts an image from the
+// content filter, without a report. The body is {"kind": "domain" | "url" |
+// "image_allow", "pattern": "...", "reason": "..."}.
+func (s *Server) handleResultRuleAdd(w http.ResponseWriter, r *http.Request) {
+	if s.moderation == nil {
+		respondError(w, http.StatusServiceUnavailable, "Report storage is unavailable")
+		return
+	}
+	var req struct {
+		Kind    string `json:"kind"`
+		Pattern string `json:"pattern"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	rule, err := s.moderation.AddRule(r.Context(), req.Kind, req.Pattern, req.Reason, "operator")
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogResultRuleChanged("operator", getClientIPSimple(r), true, rule.Kind, rule.Pattern)
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{"ok": true, "data": rule})
+}
+
+// handleResultRuleRemove deletes a rule; its results show again at once.
+// The rule moves to the trash, from which it can be restored.
+func (s *Server) handleResultRuleRemove(w http.ResponseWriter, r *http.Request) {
+	if s.moderation == nil {
+		respondError(w, http.StatusServiceUnavailable, "Report storage is unavailable")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Rule not found")
+		return
+	}
+	rule, err := s.moderation.RemoveRule(r.Context(), id)
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	s.moveToTrash(r.Context(), trashKindResultRule, strconv.FormatInt(rule.ID, 10), rule)
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogResultRuleChanged("operator", getClientIPSimple(r), false, rule.Kind, rule.Pattern)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": rule})
+}
+
+// handleResultRuleTrash lists removed rules that can still be restored
+func (s *Server) handleResultRuleTrash(w http.ResponseWriter, r *http.Request) {
+	s.respondTrash(w, r, trashKindResultRule)
+}
+
+// handleResultRuleRestore adds a removed rule back from the trash. It gets
+// a new id; restoring a rule that was added again returns that one.
+func (s *Server) handleResultRuleRestore(w http.ResponseWriter, r *http.Request) {
+	if s.moderation == nil {
+		respondError(w, http.StatusServiceUnavailable, "Report storage is unavailable")
+		return
+	}
+	item := s.trashItem(w, r, trashKindResultRule, chi.URLParam(r, "id"))
+	if item == nil {
+		return
+	}
+	var removed moderation.Rule
+	if err := item.Decode(&removed); err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	rule, err := s.moderation.AddRule(r.Context(), removed.Kind, removed.Pattern, removed.Reason, removed.Source)
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	s.restoredFromTrash(r.Context(), item)
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogResultRuleChanged("operator", getClientIPSimple(r), true, rule.Kind, rule.Pattern)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": rule})
+}
+
+func respondModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, moderation.ErrNotFound):
+		respondError(w, http.StatusNotFound, "Report or rule not found")
+	case errors.Is(err, moderation.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		slog.Error("moderation request failed", "err", err)
+		respondError(w, http.StatusInternalServerError, "Moderation request failed")
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// resultCachePolicy converts search.result_cache into the result cache's
+// TTLs. Durations were checked when the config loaded.
+func resultCachePolicy(rc config.ResultCacheConfig) search.ResultCachePolicy {
+	ttl, _ := time.ParseDuration(rc.TTL)
+	categories := make(map[model.Category]time.Duration, len(rc.Categories))
+	for category, s := range rc.Categories {
+		if d, err := time.ParseDuration(s); err == nil {
+			categories[model.Category(category)] = d
+		}
+	}
+	return search.ResultCachePolicy{Disabled: rc.Disabled, TTL: ttl, Categories: categories}
+}
+
+// handleResultCache reports the result cache's backend, TTLs and hit rates,
+// overall and per category
+func (s *Server) handleResultCache(w http.ResponseWriter, r *http.Request) {
+	if s.cache == nil {
+		respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": search.CacheStats{}})
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": s.cache.Stats()})
+}
+
+// handleResultCacheFlush drops every cached search result and rendered
+// result page, and resets the hit counters
+func (s *Server) handleResultCacheFlush(w http.ResponseWriter, r *http.Request) {
+	if s.cache != nil {
+		s.cache.Clear()
+	}
+	if s.renderCache != nil {
+		s.renderCache.pages.Clear(context.Background(), "*")
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogConfigChange("operator", getClientIPSimple(r), "search.result_cache", "flushed")
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
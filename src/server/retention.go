@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database"
+	"github.com/apimgr/search/src/retention"
+)
+
+// retentionEngine builds an engine for the current server.retention rules,
+// so a config reload applies at the next run
+func (s *Server) retentionEngine() *retention.Engine {
+	var db *database.DB
+	if s.dbManager != nil {
+		db = s.dbManager.ServerDB()
+	}
+	if db == nil || db.SQL() == nil {
+		return retention.NewEngine(s.config.Server.Retention, config.GetLogDir(), config.GetCacheDir(), nil, "")
+	}
+	return retention.NewEngine(s.config.Server.Retention, config.GetLogDir(), config.GetCacheDir(), db.SQL(), database.ServerTableName(db, ""))
+}
+
+// runRetention is the retention task. Until server.retention.enforce is
+// set it only logs what it would delete. The trash and the revoked Tor
+// clients are purged either way: their retention is a fixed promise, not a
+// server.retention limit.
+func (s *Server) runRetention(ctx context.Context) error {
+	var failed []string
+	if purged, err := s.trash.Purge(ctx); err != nil {
+		failed = append(failed, "trash: "+err.Error())
+	} else if purged > 0 {
+		slog.Info("retention", "class", "trash", "rows", purged)
+	}
+	if s.torService != nil {
+		if err := s.torService.PurgeRevokedClientAuth(); err != nil {
+			failed = append(failed, "tor client trash: "+err.Error())
+		}
+	}
+
+	engine := s.retentionEngine()
+	var report *retention.Report
+	if s.config.Server.Retention.Enforce {
+		report = engine.Enforce(ctx)
+	} else {
+		report = engine.Plan(ctx)
+	}
+
+	for _, class := range report.Classes {
+		if class.Error != "" {
+			failed = append(failed, class.Class+": "+class.Error)
+			continue
+		}
+		if len(class.Files) == 0 && len(class.Tables) == 0 {
+			continue
+		}
+		slog.Info("retention",
+			"class", class.Class,
+			"dry_run", report.DryRun,
+			"files", len(class.Files),
+			"bytes", class.PurgeBytes,
+			"rows", class.PurgeRows)
+		if !report.DryRun && s.logManager != nil && s.logManager.Audit() != nil {
+			s.logManager.Audit().LogRetentionPurged(class.Class, len(class.Files), class.PurgeBytes, class.PurgeRows)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("retention failed for %v", failed)
+	}
+	return nil
+}
+
+// handleRetention returns the dry-run retention report: what the retention
+// task would delete under the current rules. Nothing is deleted.
+func (s *Server) handleRetention(w http.ResponseWriter, r *http.Request) {
+	report := s.retentionEngine().Plan(r.Context())
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"enforce": s.config.Server.Retention.Enforce,
+			"report":  report,
+		},
+	})
+}
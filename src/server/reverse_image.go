@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/apimgr/search/src/common/i18n"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/search"
+)
+
+// handleImageSearch handles /search/image, search by image: the url
+// parameter, or an image uploaded in the form field "image", is forwarded
+// to the reverse image engines and their results shown as an images page
+func (s *Server) handleImageSearch(w http.ResponseWriter, r *http.Request) {
+	cfg := s.config.Search.ReverseImage
+	if !cfg.Enabled {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+
+	img, err := search.ReadReverseImage(r, cfg.MaxUploadBytes())
+	if err != nil {
+		status, message := http.StatusBadRequest, "search.reverse_image_invalid"
+		if errors.Is(err, search.ErrReverseImageTooLarge) {
+			status, message = http.StatusRequestEntityTooLarge, "search.reverse_image_too_large"
+		}
+		s.handleError(w, r, status, i18n.RequestString(r, "search.error_title"), i18n.RequestString(r, message))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	results, err := s.aggregator.ReverseImageSearch(ctx, img, cfg.Engines, s.safeSearchLevel(r, s.searchPrefs(r)))
+	if errors.Is(err, model.ErrNoEngines) {
+		results, err = model.NewSearchResults(img.URL, model.CategoryImages), nil
+		results.Degraded = true
+	}
+	if err != nil && !errors.Is(err, model.ErrNoResults) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		s.renderSearchError(w, r, "", err)
+		return
+	}
+
+	data := s.buildSearchPageData(w, r, "", results, string(model.CategoryImages), nil)
+	data.ReverseResults = true
+	data.ReverseImageURL = img.URL
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.renderer.Render(w, "search", data); err != nil {
+		s.renderSearchResultsInline(w, r, img.URL, results, string(model.CategoryImages))
+	}
+}
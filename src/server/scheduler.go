@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/apimgr/search/src/alert"
@@ -54,7 +55,7 @@ func (s *Server) initScheduler(db *sql.DB) {
 
 // createTaskHandlers creates handler functions for all built-in tasks
 func (s *Server) createTaskHandlers() *scheduler.TaskHandlers {
-	return &scheduler.TaskHandlers{
+	handlers := &scheduler.TaskHandlers{
 		// SSL Renewal - check and renew certs 7 days before expiry
 		SSLRenewal: func(ctx context.Context) error {
 			slog.Info("SSL certificate renewal check complete")
@@ -101,13 +102,22 @@ func (s *Server) createTaskHandlers() *scheduler.TaskHandlers {
 
 		// Token Cleanup - remove expired tokens
 		TokenCleanup: func(ctx context.Context) error {
-			slog.Info("token cleanup complete")
+			purged, err := s.permalinks.PurgeExpired(ctx)
+			if err != nil {
+				return err
+			}
+			slog.Info("token cleanup complete", "permalinks_purged", purged)
 			return nil
 		},
 
-		// Log Rotation - rotate and compress old logs
+		// Log Rotation - rotate the access log per server.logs.access.rotate;
+		// rotated files are pruned by the retention task
 		LogRotation: func(ctx context.Context) error {
-			slog.Info("log rotation complete")
+			rotated, err := s.rotateAccessLog(time.Now())
+			if err != nil {
+				return err
+			}
+			slog.Info("log rotation complete", "access_log_rotated", rotated)
 			return nil
 		},
 
@@ -123,6 +133,11 @@ func (s *Server) createTaskHandlers() *scheduler.TaskHandlers {
 			return s.performScheduledBackup(ctx, "hourly")
 		},
 
+		// Backup Verify - check stored backups against their manifests
+		BackupVerify: func(ctx context.Context) error {
+			return s.performScheduledVerification(ctx)
+		},
+
 		// Healthcheck Self - verify own health
 		HealthcheckSelf: func(ctx context.Context) error {
 			if s.aggregator != nil {
@@ -173,7 +188,38 @@ func (s *Server) createTaskHandlers() *scheduler.TaskHandlers {
 		PublicIPRefresh: func(ctx context.Context) error {
 			return s.refreshPublicIP(ctx)
 		},
+
+		// Cache Warm - precompute search.warm_queries off-peak
+		CacheWarm: func(ctx context.Context) error {
+			return s.warmCache(ctx)
+		},
+
+		// Retention - purge data over server.retention limits when enforced
+		Retention: func(ctx context.Context) error {
+			return s.runRetention(ctx)
+		},
+
+		// Key Rotation - rotate the secrets due under server.security.keys
+		KeyRotation: func(ctx context.Context) error {
+			return s.rotateDueKeys(ctx, time.Now())
+		},
+	}
+
+	// Market Refresh - only scheduled when market data is enabled
+	if s.marketService != nil {
+		handlers.MarketRefresh = func(ctx context.Context) error {
+			return s.marketService.Refresh(ctx)
+		}
+	}
+
+	// Threat Feed Update - only scheduled when result screening is enabled
+	if s.threatFeeds != nil {
+		handlers.ThreatFeedUpdate = func(ctx context.Context) error {
+			return s.threatFeeds.Update(ctx)
+		}
 	}
+
+	return handlers
 }
 
 // applyTaskConfig applies user configuration to skippable tasks
@@ -187,6 +233,9 @@ func (s *Server) applyTaskConfig(sched *scheduler.Scheduler) {
 	if tasks.BackupHourly.Enabled {
 		sched.Enable(scheduler.TaskBackupHourly)
 	}
+	if !tasks.BackupVerify.Enabled {
+		sched.Disable(scheduler.TaskBackupVerify)
+	}
 	if !tasks.GeoIPUpdate.Enabled {
 		sched.Disable(scheduler.TaskGeoIPUpdate)
 	}
@@ -196,6 +245,18 @@ func (s *Server) applyTaskConfig(sched *scheduler.Scheduler) {
 	if !tasks.CVEUpdate.Enabled {
 		sched.Disable(scheduler.TaskCVEUpdate)
 	}
+	if !tasks.CacheWarm.Enabled {
+		sched.Disable(scheduler.TaskCacheWarm)
+	}
+	if !tasks.Retention.Enabled {
+		sched.Disable(scheduler.TaskRetention)
+	}
+	if !tasks.ThreatFeedUpdate.Enabled {
+		sched.Disable(scheduler.TaskThreatFeedUpdate)
+	}
+	if !tasks.KeyRotation.Enabled {
+		sched.Disable(scheduler.TaskKeyRotation)
+	}
 }
 
 // GetSchedulerTasks returns all scheduler tasks for API/UI
@@ -285,14 +346,20 @@ func (s *Server) performScheduledBackup(ctx context.Context, backupType string)
 	// Per AI.md PART 22: Check compliance mode
 	// If compliance enabled and no password, skip backup with warning
 	complianceEnabled := s.config.Server.Compliance.Enabled
-	encryptionEnabled := s.config.Server.Backup.Encryption.Enabled
+	encryptionEnabled := s.config.Server.Backup.Encryption.IsEnabled()
 
 	// Get backup password from environment variable (NEVER stored in config)
 	// Per AI.md PART 22/24: Password is NEVER stored - derived on-demand
-	backupPassword := os.Getenv("BACKUP_PASSWORD")
+	// A configured public key needs no secret on the server at all
+	keys, err := backup.KeysFromConfig(s.config.Server.Backup.Encryption, os.Getenv("BACKUP_PASSWORD"))
+	if err != nil {
+		slog.Error("backup encryption keys unusable, backup skipped", "err", err)
+		s.logAuditEvent("backup.skipped", fmt.Sprintf("Backup encryption keys unusable: %v", err))
+		return fmt.Errorf("backup encryption: %w", err)
+	}
 
 	if complianceEnabled {
-		if backupPassword == "" {
+		if !keys.CanEncrypt() {
 			// Per AI.md PART 22: Scheduled backups skip with audit log warning
 			slog.Warn("compliance mode enabled but BACKUP_PASSWORD not set, backup skipped")
 			s.logAuditEvent("backup.skipped", "Compliance mode requires backup encryption but password not set")
@@ -302,9 +369,10 @@ func (s *Server) performScheduledBackup(ctx context.Context, backupType string)
 		encryptionEnabled = true
 	}
 
-	// Set password if encryption is enabled
-	if encryptionEnabled && backupPassword != "" {
-		mgr.SetPassword(backupPassword)
+	// Set keys if encryption is enabled
+	encrypt := encryptionEnabled && keys.CanEncrypt()
+	if encrypt {
+		mgr.SetKeys(keys)
 	}
 
 	// Get retention settings from config
@@ -317,11 +385,10 @@ func (s *Server) performScheduledBackup(ctx context.Context, backupType string)
 
 	var backupPath string
 	var verifyResult *backup.VerificationResult
-	var err error
 
 	// Create backup with verification
 	// Per AI.md PART 22: Only delete old backups if new backup passes ALL verification checks
-	if encryptionEnabled && backupPassword != "" {
+	if encrypt {
 		// Create encrypted backup with verification
 		backupPath, verifyResult, err = mgr.CreateEncryptedAndVerify("")
 	} else {
@@ -372,6 +439,41 @@ func (s *Server) performScheduledBackup(ctx context.Context, backupType string)
 	return nil
 }
 
+// performScheduledVerification checks every stored backup against its
+// checksum manifest without restoring it. Encrypted backups are decrypted
+// in memory with the configured keys; those encrypted to a public key
+// only have their header checked.
+func (s *Server) performScheduledVerification(ctx context.Context) error {
+	mgr := backup.NewManager()
+	keys, err := backup.KeysFromConfig(s.config.Server.Backup.Encryption, os.Getenv("BACKUP_PASSWORD"))
+	if err != nil {
+		slog.Warn("backup encryption keys unusable, encrypted backups checked by header only", "err", err)
+	} else {
+		mgr.SetKeys(keys)
+	}
+
+	verified, err := mgr.VerifyAll()
+	if err != nil {
+		return fmt.Errorf("backup verification: %w", err)
+	}
+
+	var failed []string
+	for _, v := range verified {
+		if v.Result.AllPassed {
+			continue
+		}
+		failed = append(failed, v.Filename)
+		slog.Error("backup failed verification", "file", v.Filename, "errors", v.Result.Errors)
+		s.logAuditEvent("backup.verification_failed", fmt.Sprintf("%s failed verification: %v", v.Filename, v.Result.Errors))
+	}
+
+	slog.Info("backup verification complete", "backups", len(verified), "failed", len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d backups failed verification: %s", len(failed), len(verified), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // logAuditEvent logs an audit event (simplified version for scheduler)
 // Per AI.md PART 22: Audit logging for backup events
 func (s *Server) logAuditEvent(event, details string) {
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/search"
+)
+
+// driftTracker is an engine that learns its response structure
+type driftTracker interface {
+	SchemaDrift() search.SchemaDrift
+	ResetSchemaBaseline()
+}
+
+// handleSchemaDrift reports each enabled engine's response schema drift,
+// engines whose parser is likely broken first
+func (s *Server) handleSchemaDrift(w http.ResponseWriter, r *http.Request) {
+	drift := []search.SchemaDrift{}
+	if s.registry != nil {
+		for _, eng := range s.registry.GetEnabled() {
+			if tracker, ok := eng.(driftTracker); ok {
+				drift = append(drift, tracker.SchemaDrift())
+			}
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].ParserSuspect != drift[j].ParserSuspect {
+			return drift[i].ParserSuspect
+		}
+		if drift[i].Drift != drift[j].Drift {
+			return drift[i].Drift > drift[j].Drift
+		}
+		return drift[i].Engine < drift[j].Engine
+	})
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": drift,
+	})
+}
+
+// handleSchemaDriftReset forgets an engine's learned response structure,
+// once its parser is fixed or the new structure is known to be fine
+func (s *Server) handleSchemaDriftReset(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	if s.registry != nil {
+		for _, eng := range s.registry.GetEnabled() {
+			tracker, ok := eng.(driftTracker)
+			if !ok || strings.ToLower(eng.Name()) != name {
+				continue
+			}
+			tracker.ResetSchemaBaseline()
+			respondJSON(w, http.StatusOK, map[string]any{
+				"ok":   true,
+				"data": tracker.SchemaDrift(),
+			})
+			return
+		}
+	}
+	respondError(w, http.StatusNotFound, "Unknown engine")
+}
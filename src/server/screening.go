@@ -0,0 +1,120 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/safesearch"
+	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/security"
+)
+
+// WarningPageData is the page shown before opening a flagged result
+type WarningPageData struct {
+	PageData
+	URL      string
+	Category string
+	Feed     string
+	// Listed is false when no feed lists the URL any more
+	Listed bool
+}
+
+// screeningEnabled reports whether results are checked against the threat
+// feeds
+func (s *Server) screeningEnabled() bool {
+	return s.config.Search.Screening.Enabled && s.threatFeeds != nil
+}
+
+// checkThreat looks a URL up in the local feeds at the configured
+// sensitivity
+func (s *Server) checkThreat(rawURL string) (security.ThreatMatch, bool) {
+	if !s.screeningEnabled() {
+		return security.ThreatMatch{}, false
+	}
+	return s.threatFeeds.Check(rawURL, s.config.Search.Screening.Sensitivity)
+}
+
+// hideResult is the aggregator's result filter: results the operator
+// blocked, and flagged results when search.screening.action is hide
+func (s *Server) hideResult(result model.Result) bool {
+	if s.moderation != nil && s.moderation.Blocked(result.URL) {
+		return true
+	}
+	if s.config.Search.Screening.Action != "hide" {
+		return false
+	}
+	_, listed := s.checkThreat(result.URL)
+	return listed
+}
+
+// markResult is the aggregator's result marker: the threat category of a
+// flagged result when search.screening.action is warn
+func (s *Server) markResult(result model.Result) string {
+	if s.config.Search.Screening.Action == "hide" {
+		return ""
+	}
+	match, listed := s.checkThreat(result.URL)
+	if !listed {
+		return ""
+	}
+	return match.Category
+}
+
+// safeSearchFilter builds the aggregator's safe search filter from
+// search.safe_search_filter
+func safeSearchFilter(cfg config.SafeSearchFilterConfig) search.SafeSearchFilter {
+	return safesearch.NewFilter(cfg.Domains, cfg.Keywords, cfg.BuiltIn).Blocked
+}
+
+// threatFeedsFromConfig converts search.screening.feeds; nil selects the
+// default feeds
+func threatFeedsFromConfig(feeds []config.ThreatFeedConfig) []security.ThreatFeed {
+	if len(feeds) == 0 {
+		return nil
+	}
+	out := make([]security.ThreatFeed, 0, len(feeds))
+	for _, f := range feeds {
+		out = append(out, security.ThreatFeed{
+			Name:     f.Name,
+			URL:      f.URL,
+			Format:   f.Format,
+			Category: f.Category,
+			Enabled:  f.Enabled,
+		})
+	}
+	return out
+}
+
+// handleThreatWarning is where flagged results link to. It checks the URL
+// again, says which feed lists it and leaves opening it to the user: the
+// page never redirects.
+func (s *Server) handleThreatWarning(w http.ResponseWriter, r *http.Request) {
+	if !s.screeningEnabled() {
+		localizedHTTPError(w, r, http.StatusNotFound, "errors.not_found")
+		return
+	}
+	target := strings.TrimSpace(r.URL.Query().Get("url"))
+	if canonical, _ := security.CanonicalThreatURL(target); canonical == "" {
+		localizedHTTPError(w, r, http.StatusBadRequest, "errors.bad_request")
+		return
+	}
+
+	data := &WarningPageData{URL: target}
+	if match, listed := s.checkThreat(target); listed {
+		data.Listed = true
+		data.Category = match.Category
+		data.Feed = match.Feed
+	}
+
+	baseData := s.newPageData(w, r, "", "warning")
+	baseData.Title = s.getI18nManager().T(baseData.Lang, "screening.page_title")
+	data.PageData = *baseData
+	// The flagged site must not learn it was reached through this page
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	if err := s.renderer.Render(w, "warning", data); err != nil {
+		slog.Error("template render error", "err", err)
+	}
+}
@@ -3,10 +3,13 @@ package server
 import (
 	"encoding/base64"
 	"encoding/json"
+	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/preferences"
+	"github.com/apimgr/search/src/safesearch"
 )
 
 type searchPreferences struct {
@@ -17,6 +20,74 @@ type searchPreferences struct {
 	NewTab            bool
 	InfiniteScroll    bool
 	KeyboardShortcuts bool
+	// Localize lets search.geo_boost rank local results higher
+	Localize bool
+	// Language and Engines come only from a stored profile
+	Language string
+	Engines  []string
+	// safeSearchSet is false while SafeSearch is the built-in default,
+	// which search.safe_search replaces
+	safeSearchSet bool
+}
+
+// searchPrefs returns the preferences a page applies: those of
+// requestPrefs, or when there are none the stored profile the request's
+// token names
+func (s *Server) searchPrefs(r *http.Request) searchPreferences {
+	raw := s.requestPrefs(r)
+	prefs := parseSearchPreferences(raw)
+	if raw != "" {
+		return prefs
+	}
+	profile := s.storedProfile(r)
+	if profile == nil {
+		return prefs
+	}
+	stored := profile.Preferences
+	if stored.SafeSearch != nil {
+		prefs.SafeSearch = normalizeSafeSearch(*stored.SafeSearch)
+		prefs.safeSearchSet = true
+	}
+	if stored.ResultsPerPage > 0 {
+		prefs.ResultsPerPage = normalizeResultsPerPage(stored.ResultsPerPage)
+	}
+	if stored.Theme != "" {
+		prefs.Theme = normalizeThemePreference(stored.Theme)
+	}
+	prefs.Language = stored.Language
+	prefs.Engines = stored.Engines
+	return prefs
+}
+
+// safeSearchLevel returns the safe search level of a search: the one
+// &safesearch= or &safe_search= asks for, else the visitor's preference,
+// else search.safe_search, and always search.safe_search while it is locked
+func (s *Server) safeSearchLevel(r *http.Request, prefs searchPreferences) int {
+	level, ok := safesearch.FromValues(r.URL.Query())
+	if !ok {
+		level = s.config.Search.SafeSearch
+		if prefs.safeSearchSet {
+			level = prefs.SafeSearch
+		}
+	}
+	return s.config.Search.ResolveSafeSearch(level)
+}
+
+// storedProfile returns the stored preferences profile the request's
+// cookie or header names, or nil
+func (s *Server) storedProfile(r *http.Request) *preferences.Profile {
+	if s.preferences == nil || !s.config.Search.Preferences.Enabled {
+		return nil
+	}
+	token := preferences.TokenFromRequest(r)
+	if token == "" {
+		return nil
+	}
+	profile, err := s.preferences.Get(r.Context(), token)
+	if err != nil {
+		return nil
+	}
+	return profile
 }
 
 func parseSearchPreferences(raw string) searchPreferences {
@@ -28,6 +99,7 @@ func parseSearchPreferences(raw string) searchPreferences {
 		NewTab:            false,
 		InfiniteScroll:    false,
 		KeyboardShortcuts: true,
+		Localize:          true,
 	}
 
 	raw = strings.TrimSpace(raw)
@@ -50,6 +122,7 @@ func parseSearchPreferences(raw string) searchPreferences {
 			}
 			if safeSearch, ok := payload["safe_search"].(float64); ok {
 				prefs.SafeSearch = normalizeSafeSearch(int(safeSearch))
+				prefs.safeSearchSet = true
 			}
 			if resultsPerPage, ok := payload["results_per_page"].(float64); ok {
 				prefs.ResultsPerPage = normalizeResultsPerPage(int(resultsPerPage))
@@ -63,6 +136,9 @@ func parseSearchPreferences(raw string) searchPreferences {
 			if keyboardShortcuts, ok := payload["keyboard_shortcuts"].(bool); ok {
 				prefs.KeyboardShortcuts = keyboardShortcuts
 			}
+			if localize, ok := payload["localize"].(bool); ok {
+				prefs.Localize = localize
+			}
 			return prefs
 		}
 	}
@@ -88,6 +164,7 @@ func parseSearchPreferences(raw string) searchPreferences {
 			prefs.DefaultCategory = model.ParseCategory(value)
 		case "s":
 			prefs.SafeSearch = normalizeSafeSearchAlias(value)
+			prefs.safeSearchSet = true
 		case "r":
 			if parsed, err := strconv.Atoi(value); err == nil {
 				prefs.ResultsPerPage = normalizeResultsPerPage(parsed)
@@ -98,6 +175,8 @@ func parseSearchPreferences(raw string) searchPreferences {
 			prefs.InfiniteScroll = strings.EqualFold(value, "i")
 		case "k":
 			prefs.KeyboardShortcuts = value != "0"
+		case "g":
+			prefs.Localize = value != "0"
 		}
 	}
 
@@ -112,27 +191,23 @@ func normalizeThemePreference(value string) string {
 		return ThemeLight
 	case "a", "auto", "system":
 		return ThemeAuto
+	case "h", "contrast", "high-contrast":
+		return ThemeContrast
 	default:
 		return ""
 	}
 }
 
 func normalizeSafeSearchAlias(value string) int {
-	switch strings.ToLower(strings.TrimSpace(value)) {
-	case "o", "off", "0":
-		return 0
-	case "m", "moderate", "1":
-		return 1
-	case "s", "strict", "2":
-		return 2
-	default:
-		return 1
+	if level, ok := safesearch.Parse(value); ok {
+		return level
 	}
+	return safesearch.Moderate
 }
 
 func normalizeSafeSearch(value int) int {
-	if value < 0 || value > 2 {
-		return 1
+	if !safesearch.Valid(value) {
+		return safesearch.Moderate
 	}
 	return value
 }
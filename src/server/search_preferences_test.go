@@ -1,14 +1,19 @@
 package server
 
 import (
+	"context"
 	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/apimgr/search/src/database/dbtest"
 	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/preferences"
 )
 
 func TestParseSearchPreferencesCompactString(t *testing.T) {
-	prefs := parseSearchPreferences("t=l;c=web;s=s;r=50;n=1;p=i;k=0")
+	prefs := parseSearchPreferences("t=l;c=web;s=s;r=50;n=1;p=i;k=0;g=0")
 
 	if prefs.Theme != ThemeLight {
 		t.Fatalf("Theme = %q, want %q", prefs.Theme, ThemeLight)
@@ -31,6 +36,9 @@ func TestParseSearchPreferencesCompactString(t *testing.T) {
 	if prefs.KeyboardShortcuts {
 		t.Fatal("KeyboardShortcuts = true, want false")
 	}
+	if prefs.Localize {
+		t.Fatal("Localize = true, want false")
+	}
 }
 
 func TestParseSearchPreferencesBase64JSON(t *testing.T) {
@@ -86,3 +94,70 @@ func TestParseSearchPreferencesInvalidValuesUseDefaults(t *testing.T) {
 		t.Fatal("KeyboardShortcuts = false, want true")
 	}
 }
+
+func TestSearchPrefsStoredProfile(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.preferences = preferences.NewStore(dbtest.Users(t), "")
+	safeSearch := 2
+	_, token, err := s.preferences.Create(context.Background(), preferences.Preferences{
+		SafeSearch:     &safeSearch,
+		Language:       "de",
+		Engines:        []string{"google"},
+		ResultsPerPage: 50,
+		Theme:          "contrast",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=test", nil)
+	req.AddCookie(&http.Cookie{Name: preferences.CookieName, Value: token})
+	prefs := s.searchPrefs(req)
+	if prefs.SafeSearch != 2 || prefs.ResultsPerPage != 50 || prefs.Theme != ThemeContrast || prefs.Language != "de" || len(prefs.Engines) != 1 {
+		t.Errorf("searchPrefs() = %+v, want the stored profile", prefs)
+	}
+	if s.searchPageKey(req, "test", "general", 1, 50) != "" {
+		t.Error("a page with stored preferences is answered from the render cache")
+	}
+
+	// A prefs string wins over the stored profile
+	req = httptest.NewRequest(http.MethodGet, "/search?q=test&prefs=r%3D10", nil)
+	req.AddCookie(&http.Cookie{Name: preferences.CookieName, Value: token})
+	if prefs := s.searchPrefs(req); prefs.ResultsPerPage != 10 || prefs.Language != "" {
+		t.Errorf("searchPrefs() with prefs = %+v", prefs)
+	}
+
+	s.config.Search.Preferences.Enabled = false
+	req = httptest.NewRequest(http.MethodGet, "/search?q=test", nil)
+	req.AddCookie(&http.Cookie{Name: preferences.CookieName, Value: token})
+	if prefs := s.searchPrefs(req); prefs.SafeSearch != 1 || prefs.Language != "" {
+		t.Errorf("searchPrefs() while disabled = %+v", prefs)
+	}
+}
+
+func TestSafeSearchLevel(t *testing.T) {
+	s := newRenderCacheServer(t)
+	s.config.Search.SafeSearch = 0
+
+	level := func(target, prefs string) int {
+		return s.safeSearchLevel(httptest.NewRequest(http.MethodGet, target, nil), parseSearchPreferences(prefs))
+	}
+	if got := level("/search?q=test", ""); got != 0 {
+		t.Errorf("without a preference = %d, want search.safe_search 0", got)
+	}
+	if got := level("/search?q=test", "s=s"); got != 2 {
+		t.Errorf("with the strict preference = %d, want 2", got)
+	}
+	if got := level("/search?q=test&safesearch=moderate", "s=s"); got != 1 {
+		t.Errorf("safesearch=moderate = %d, want 1", got)
+	}
+	if got := level("/search?q=test&safe_search=0", "s=s"); got != 0 {
+		t.Errorf("safe_search=0 = %d, want 0", got)
+	}
+
+	s.config.Search.SafeSearch = 2
+	s.config.Search.SafeSearchLocked = true
+	if got := level("/search?q=test&safesearch=off", ""); got != 2 {
+		t.Errorf("locked level = %d, want 2", got)
+	}
+}
@@ -135,9 +135,13 @@ func (s *Server) handleSecurityReportStatus(w http.ResponseWriter, r *http.Reque
 	// same-origin resource load from this page never forwards the token.
 	w.Header().Set("Referrer-Policy", "no-referrer")
 
+	// An unknown report, an expired token and a wrong token answer alike
+	// and after the same delay, so none can be told from the others
+	timer := security.StartAuth()
 	status, err := security.LookupReportStatus(r.Context(), s.dbManager.ServerDB(), trackingID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			timer.Fail(r.Context())
 			s.handleNotFound(w, r)
 			return
 		}
@@ -145,12 +149,8 @@ func (s *Server) handleSecurityReportStatus(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if status.TokenExpired(time.Now()) {
-		s.handleNotFound(w, r)
-		return
-	}
-
-	if security.HashReportToken(token) != status.ReportTokenHash {
+	if !security.HashEqual(security.HashReportToken(token), status.ReportTokenHash) || status.TokenExpired(time.Now()) {
+		timer.Fail(r.Context())
 		s.handleNotFound(w, r)
 		return
 	}
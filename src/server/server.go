@@ -14,22 +14,31 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/apimgr/search/src/alert"
 	"github.com/apimgr/search/src/api"
 	"github.com/apimgr/search/src/cache"
+	"github.com/apimgr/search/src/collection"
 	"github.com/apimgr/search/src/common/httputil"
 	"github.com/apimgr/search/src/common/i18n"
 	"github.com/apimgr/search/src/config"
 	"github.com/apimgr/search/src/database"
 	"github.com/apimgr/search/src/direct"
 	"github.com/apimgr/search/src/email"
+	"github.com/apimgr/search/src/feature"
 	"github.com/apimgr/search/src/geoip"
 	graphqlpkg "github.com/apimgr/search/src/graphql"
+	"github.com/apimgr/search/src/imageclass"
 	"github.com/apimgr/search/src/instant"
 	"github.com/apimgr/search/src/logging"
+	"github.com/apimgr/search/src/market"
+	"github.com/apimgr/search/src/memwatch"
 	"github.com/apimgr/search/src/model"
+	"github.com/apimgr/search/src/moderation"
+	"github.com/apimgr/search/src/permalink"
+	"github.com/apimgr/search/src/preferences"
 	"github.com/apimgr/search/src/scheduler"
 	"github.com/apimgr/search/src/search"
 	"github.com/apimgr/search/src/search/bang"
@@ -37,6 +46,7 @@ import (
 	"github.com/apimgr/search/src/security"
 	"github.com/apimgr/search/src/service"
 	"github.com/apimgr/search/src/ssl"
+	"github.com/apimgr/search/src/trash"
 	"github.com/apimgr/search/src/widget"
 	"github.com/go-chi/chi/v5"
 )
@@ -70,10 +80,45 @@ type Server struct {
 	metrics          *Metrics
 	dbManager        *database.DatabaseManager
 	alertManager     *alert.Manager
+	permalinks       *permalink.Store
+	// Search preferences stored server-side, by profile token
+	preferences      *preferences.Store
+	// Reported results and the result blocklist
+	moderation       *moderation.Store
+	// Custom bangs and bang usage in the server DB
+	bangs            *bang.Store
+	// Deleted result rules and custom bangs, restorable for trash.Retention
+	trash            *trash.Store
+	// Per-IP limit on result reports; nil when unlimited
+	reportLimiter    *EndpointRateLimiter
+	// Malware and phishing feeds; nil unless search.screening.enabled
+	threatFeeds      *security.ThreatFeedManager
+	// Adult image check for strict safe search; nil unless enabled
+	imageClassifier  *imageclass.Classifier
 	blocklistManager *security.BlocklistManager
 	cveManager       *security.CVEManager
+	// Stock/crypto quotes; nil unless search.market.enabled
+	marketService *market.Service
+	// Throttles operator alerts about blocked engines
+	engineAlerts engineAlertLog
+	// Table keeping engine quota usage across restarts; "" without a
+	// server database
+	quotaTable string
+	// Live "view as user" preview sessions
+	viewAs viewAsStore
+	// Named operator token requests against their rate limits
+	tokenUsage tokenUsageTracker
+	// Dark-launch flags from server.features plus operator overrides
+	features *feature.Set
 	// Per AI.md PART 5: config sync persists settings back to server.yml
 	configSync *config.ConfigSync
+	// Rendered result pages for anonymous browsers
+	renderCache *renderCache
+	// Held while a server secret is rotated
+	keysMu sync.Mutex
+	// Memory watchdog; nil when server.memory.disabled
+	memWatch     *memwatch.Watchdog
+	stopMemWatch context.CancelFunc
 
 	// Internationalization per AI.md PART 32
 	i18nManager *i18n.Manager
@@ -86,6 +131,13 @@ type Server struct {
 
 // NewServer creates a new server instance
 func NewServer(cfg *config.Config) *Server {
+	return NewServerWithRegistry(cfg, engine.DefaultRegistry())
+}
+
+// NewServerWithRegistry creates a server that searches the engines in
+// registry instead of the default engines, such as the synthetic engines
+// of search --test load
+func NewServerWithRegistry(cfg *config.Config, registry *engine.Registry) *Server {
 	// Create logging manager
 	logDir := config.GetLogDir()
 	logMgr := logging.NewManager(logDir)
@@ -99,15 +151,11 @@ func NewServer(cfg *config.Config) *Server {
 		logMgr.Server().SetLevel(logging.LevelError)
 	}
 
-	// Configure access log format
-	if cfg.Server.Logs.Access.Format == "json" {
-		logMgr.Access().SetFormat("json")
-	} else if cfg.Server.Logs.Access.Format == "common" {
-		logMgr.Access().SetFormat("common")
-	}
-
-	// Create engine registry with default engines
-	registry := engine.DefaultRegistry()
+	// Configure the access log format, privacy preset and on/off switch
+	configureAccessLog(logMgr.Access(), cfg.Server.Logs.Access)
+	cfg.OnReload(func(c *config.Config) {
+		configureAccessLog(logMgr.Access(), c.Server.Logs.Access)
+	})
 
 	// Get all enabled engines (already filtered by IsEnabled())
 	enabledEngines := registry.GetEnabled()
@@ -152,6 +200,13 @@ func NewServer(cfg *config.Config) *Server {
 		}
 	}
 
+	// Held in memory, the result cache is resized by the memory watchdog
+	var memoryCache *cache.MemoryCache
+	if cacheBackend == nil {
+		memoryCache = cache.NewMemoryCache(1000, 5*time.Minute)
+		cacheBackend = memoryCache
+	}
+
 	// Create aggregator with 30 second timeout and caching
 	aggregator := search.NewAggregator(enabledEngines, search.AggregatorConfig{
 		Timeout:       time.Duration(cfg.Search.Timeout) * time.Second,
@@ -160,6 +215,38 @@ func NewServer(cfg *config.Config) *Server {
 		MaxConcurrent: cfg.Search.MaxConcurrent,
 		Cache:         cacheBackend,
 	})
+	// Explicit per-category engine lists, per-engine request settings and
+	// response limits, re-applied when server.yml changes
+	aggregator.SetCategoryEngines(categoryEngineLists(cfg.Search.CategoryEngines, enabledEngines))
+	aggregator.SetDisabledCategories(disabledCategories(cfg.Search.DisabledCategories))
+	setNominatimEndpoint(registry, cfg.Search.Maps)
+	aggregator.SetRequestTemplates(engineRequestTemplates(cfg.Engines))
+	aggregator.SetEngineLimits(engineLimits(cfg))
+	aggregator.SetEngineQuotas(engineQuotas(cfg.Engines))
+	aggregator.SetSchemaDrift(cfg.Search.SchemaDrift.EffectiveThreshold())
+	aggregator.SetGeoBoost(cfg.Search.GeoBoost.BoostWeight())
+	aggregator.SetPoliteness(hostPoliteness(cfg.Search.Politeness))
+	aggregator.SetUserAgents(userAgentStrategy(cfg.Search.UserAgents))
+	aggregator.Cache().Configure(resultCachePolicy(cfg.Search.ResultCache))
+	aggregator.SetRanker(rankingPipeline(cfg.Search.Ranking.Stages))
+	aggregator.SetCircuitBreaker(circuitBreaker(cfg.Search.CircuitBreaker))
+	aggregator.SetChaos(chaosFaults(cfg))
+	cfg.OnReload(func(c *config.Config) {
+		aggregator.SetCategoryEngines(categoryEngineLists(c.Search.CategoryEngines, enabledEngines))
+		aggregator.SetDisabledCategories(disabledCategories(c.Search.DisabledCategories))
+		setNominatimEndpoint(registry, c.Search.Maps)
+		aggregator.SetRequestTemplates(engineRequestTemplates(c.Engines))
+		aggregator.SetEngineLimits(engineLimits(c))
+		aggregator.SetEngineQuotas(engineQuotas(c.Engines))
+		aggregator.SetSchemaDrift(c.Search.SchemaDrift.EffectiveThreshold())
+		aggregator.SetGeoBoost(c.Search.GeoBoost.BoostWeight())
+		aggregator.SetPoliteness(hostPoliteness(c.Search.Politeness))
+		aggregator.SetUserAgents(userAgentStrategy(c.Search.UserAgents))
+		aggregator.Cache().Configure(resultCachePolicy(c.Search.ResultCache))
+		aggregator.SetRanker(rankingPipeline(c.Search.Ranking.Stages))
+		aggregator.SetCircuitBreaker(circuitBreaker(c.Search.CircuitBreaker))
+		aggregator.SetChaos(chaosFaults(c))
+	})
 
 	// Create middleware with logging
 	mw := NewMiddleware(cfg, logMgr)
@@ -184,6 +271,12 @@ func NewServer(cfg *config.Config) *Server {
 
 	// Create API handler
 	apiHandler := api.NewHandler(cfg, registry, aggregator)
+	// Autocomplete providers, re-applied when server.yml changes
+	suggestionHistory := search.NewSuggestionHistory()
+	apiHandler.SetSuggester(suggesterFromConfig(cfg.Search.Suggestions, suggestionHistory))
+	cfg.OnReload(func(c *config.Config) {
+		apiHandler.SetSuggester(suggesterFromConfig(c.Search.Suggestions, suggestionHistory))
+	})
 
 	// Create Tor service - auto-enabled if tor binary found per AI.md PART 32
 	// "Auto-enabled if tor binary is installed - no enable flag needed"
@@ -195,22 +288,6 @@ func NewServer(cfg *config.Config) *Server {
 	// Create bang manager
 	bangMgr := bang.NewManager()
 
-	// Set custom bangs from config
-	if len(cfg.Search.Bangs.Custom) > 0 {
-		customBangs := make([]*bang.Bang, 0, len(cfg.Search.Bangs.Custom))
-		for _, bc := range cfg.Search.Bangs.Custom {
-			customBangs = append(customBangs, &bang.Bang{
-				Shortcut:    bc.Shortcut,
-				Name:        bc.Name,
-				URL:         bc.URL,
-				Category:    bc.Category,
-				Description: bc.Description,
-				Aliases:     bc.Aliases,
-			})
-		}
-		bangMgr.SetCustomBangs(customBangs)
-	}
-
 	// Create TLS manager if SSL is enabled
 	var tlsMgr *ssl.Manager
 	if cfg.Server.SSL.Enabled {
@@ -268,6 +345,12 @@ func NewServer(cfg *config.Config) *Server {
 	if cfg.Search.Widgets.RSS.Enabled {
 		widgetMgr.RegisterFetcher(widget.NewRSSFetcher(&cfg.Search.Widgets.RSS))
 	}
+	if cfg.Search.Widgets.Flight.Enabled {
+		widgetMgr.RegisterFetcher(widget.NewFlightFetcher(&cfg.Search.Widgets.Flight))
+	}
+	if cfg.Search.Widgets.Transit.Enabled {
+		widgetMgr.RegisterFetcher(widget.NewTransitFetcher(&cfg.Search.Widgets.Transit))
+	}
 
 	// Register additional widget fetchers (use free APIs, no API keys needed)
 	// These widgets are always available when widgets are enabled
@@ -283,6 +366,21 @@ func NewServer(cfg *config.Config) *Server {
 	// Create instant answer manager
 	instantMgr := instant.NewManager()
 
+	// Market data is opt-in: it powers the price instant answer and ticker widget
+	var marketSvc *market.Service
+	if cfg.Search.Market.Enabled {
+		marketSvc = market.NewService(&cfg.Search.Market)
+		instantMgr.Register(instant.NewMarketHandler(marketSvc))
+		if cfg.Search.Widgets.Enabled {
+			widgetMgr.RegisterFetcher(widget.NewTickerFetcher(marketSvc))
+		}
+	}
+
+	// Apply the operator's answer ladder once every handler is registered
+	if unknown := instantMgr.Configure(&cfg.Search.InstantAnswers); len(unknown) > 0 {
+		slog.Warn("Unknown instant answer handlers in config", "names", unknown)
+	}
+
 	// Create direct answer manager (full-page results per IDEA.md)
 	directMgr := direct.NewManager()
 
@@ -379,6 +477,28 @@ func NewServer(cfg *config.Config) *Server {
 		alertMgr = alert.NewManager(dbMgr.ServerDB().SQL(), cfg, aggregator, mailer)
 	}
 
+	var permalinkStore *permalink.Store
+	var collectionStore *collection.Store
+	var moderationStore *moderation.Store
+	var bangStore *bang.Store
+	var trashStore *trash.Store
+	if dbMgr != nil && dbMgr.ServerDB() != nil && dbMgr.ServerDB().SQL() != nil {
+		prefix := database.ServerTableName(dbMgr.ServerDB(), "")
+		permalinkStore = permalink.NewStore(dbMgr.ServerDB().SQL(), prefix)
+		collectionStore = collection.NewStore(dbMgr.ServerDB().SQL(), prefix)
+		moderationStore = moderation.NewStore(dbMgr.ServerDB().SQL(), prefix)
+		if err := moderationStore.Load(context.Background()); err != nil {
+			slog.Warn("result blocklist not loaded", "err", err)
+		}
+		bangStore = bang.NewStore(dbMgr.ServerDB().SQL(), prefix)
+		trashStore = trash.NewStore(dbMgr.ServerDB().SQL(), prefix)
+	}
+
+	var preferenceStore *preferences.Store
+	if dbMgr != nil && dbMgr.UsersDB() != nil && dbMgr.UsersDB().SQL() != nil {
+		preferenceStore = preferences.NewStore(dbMgr.UsersDB().SQL(), database.UsersTableName(dbMgr.UsersDB(), ""))
+	}
+
 	// Create blocklist manager per AI.md PART 18
 	blocklistMgr := security.NewBlocklistManager(config.GetDataDir(), nil)
 	// Load any previously downloaded blocklists
@@ -386,6 +506,25 @@ func NewServer(cfg *config.Config) *Server {
 		slog.Warn("blocklist load from disk failed", "err", err)
 	}
 
+	// Malware and phishing feeds for result screening
+	var threatFeeds *security.ThreatFeedManager
+	if cfg.Search.Screening.Enabled {
+		threatFeeds = security.NewThreatFeedManager(config.GetDataDir(), threatFeedsFromConfig(cfg.Search.Screening.Feeds))
+		if err := threatFeeds.LoadFromDisk(); err != nil {
+			slog.Warn("threat feed load from disk failed", "err", err)
+		}
+	}
+
+	var imageClassifier *imageclass.Classifier
+	if ic := cfg.Search.ImageClassifier; ic.Enabled {
+		imageClassifier = imageclass.New(imageclass.Options{
+			Endpoint:  ic.Endpoint,
+			Threshold: ic.Threshold,
+			Timeout:   time.Duration(ic.Timeout) * time.Second,
+			CacheSize: ic.CacheSize,
+		})
+	}
+
 	// Create CVE manager per AI.md PART 18
 	cveMgr := security.NewCVEManager(config.GetDataDir(), nil)
 	// Load any previously downloaded CVE data
@@ -427,8 +566,16 @@ func NewServer(cfg *config.Config) *Server {
 		metrics:          metrics,
 		dbManager:        dbMgr,
 		alertManager:     alertMgr,
+		permalinks:       permalinkStore,
+		preferences:      preferenceStore,
+		moderation:       moderationStore,
+		bangs:            bangStore,
+		trash:            trashStore,
+		threatFeeds:      threatFeeds,
+		imageClassifier:  imageClassifier,
 		blocklistManager: blocklistMgr,
 		cveManager:       cveMgr,
+		marketService:    marketSvc,
 		i18nManager:      i18nMgr,
 		// Debug accessors per AI.md PART 6
 		cache: resultCache,
@@ -444,6 +591,74 @@ func NewServer(cfg *config.Config) *Server {
 		s.configSync = config.NewConfigSync(dbMgr.ServerDB().SQL(), cfg, configPath)
 	}
 
+	// Feature flags: server.yml declares them, the server DB keeps overrides
+	var featureDB *database.DB
+	if dbMgr != nil {
+		featureDB = dbMgr.ServerDB()
+	}
+	s.features = feature.NewSet(cfg.Server.Features, featureDB)
+	if err := s.features.Load(context.Background()); err != nil {
+		slog.Warn("Feature flag overrides not loaded", "err", err)
+	}
+	cfg.OnReload(func(c *config.Config) {
+		s.features.SetDefaults(c.Server.Features)
+	})
+
+	// Engine health and certificate expiry for the exported alert rules
+	metrics.AddCollector(s.collectServerMetrics)
+
+	// Rendered result pages, emptied whenever server.yml changes
+	s.renderCache = newRenderCache(cfg.Search.RenderCache)
+	cfg.OnReload(func(c *config.Config) {
+		s.renderCache.configure(c.Search.RenderCache)
+	})
+
+	// Shrink the caches and tighten GOGC as memory runs short
+	if !cfg.Server.Memory.Disabled {
+		s.startMemoryWatchdog(memoryCache)
+	}
+
+	// Alert the operator when an engine keeps answering with block pages
+	aggregator.SetBlockHandler(s.handleEngineBlocked)
+
+	// Engine quota usage survives restarts in the server DB
+	if serverDB != nil {
+		s.quotaTable = database.ServerTableName(dbMgr.ServerDB(), "engine_quota_usage")
+		if err := s.loadQuotaUsage(context.Background()); err != nil {
+			slog.Warn("engine quota usage not loaded", "err", err)
+		}
+		aggregator.SetQuotaRecorder(s.recordQuotaUse)
+	}
+
+	// Custom bangs from server.yml and the server DB
+	if err := s.loadCustomBangs(context.Background()); err != nil {
+		slog.Warn("stored custom bangs not loaded", "err", err)
+	}
+	cfg.OnReload(func(c *config.Config) {
+		s.reloadCustomBangs(context.Background())
+	})
+
+	// Hide results the operator blocked from every search, and hide or
+	// mark those the threat feeds list
+	if moderationStore != nil || threatFeeds != nil {
+		aggregator.SetResultFilter(s.hideResult)
+	}
+	if threatFeeds != nil {
+		aggregator.SetResultMarker(s.markResult)
+	}
+	if imageClassifier != nil {
+		aggregator.SetImageClassifier(s.flagAdultImages)
+	}
+	// Results of engines without a safe search parameter go through the
+	// blocklist instead
+	aggregator.SetSafeSearchFilter(safeSearchFilter(cfg.Search.SafeSearchFilter))
+	cfg.OnReload(func(c *config.Config) {
+		aggregator.SetSafeSearchFilter(safeSearchFilter(c.Search.SafeSearchFilter))
+	})
+	if limit := cfg.Search.Reports.RateLimitPerHour; limit > 0 {
+		s.reportLimiter = NewEndpointRateLimiter(limit, time.Hour)
+	}
+
 	// Set widget manager on API handler
 	s.apiHandler.SetWidgetManager(widgetMgr)
 
@@ -457,6 +672,10 @@ func NewServer(cfg *config.Config) *Server {
 	relatedSearches := search.NewRelatedSearches()
 	s.apiHandler.SetRelatedSearches(relatedSearches)
 	s.apiHandler.SetAlertManager(alertMgr)
+	s.apiHandler.SetPermalinkStore(permalinkStore)
+	s.apiHandler.SetCollectionStore(collectionStore)
+	s.apiHandler.SetPreferenceStore(preferenceStore)
+	s.apiHandler.SetBangManager(bangMgr)
 	s.apiHandler.SetGeoIPLookup(s.geoipLookup)
 
 	// Initialize scheduler - ALWAYS RUNNING per AI.md PART 19
@@ -495,6 +714,10 @@ func (s *Server) StartHTTPServer(readyCh chan<- struct{}) error {
 	}
 	if s.torService != nil && s.torService.IsRunning() {
 		slog.Info("Tor hidden service active", "onion_address", s.torService.GetOnionAddress())
+		// Continue vanity jobs interrupted by the restart
+		if err := s.torService.ResumeVanity(); err != nil {
+			slog.Warn("Tor vanity generation not resumed", "err", err)
+		}
 	}
 
 	// Scheduler is already started by initScheduler() per AI.md PART 19
@@ -680,6 +903,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.torService.StopTorService()
 	}
 
+	// Stop memory watchdog
+	if s.stopMemWatch != nil {
+		s.stopMemWatch()
+	}
+
 	// Stop HTTP->HTTPS redirect server if running
 	if s.redirectServer != nil {
 		s.redirectServer.Shutdown(ctx)
@@ -728,18 +956,38 @@ func (s *Server) UpdateConfig(cfg *config.Config) {
 func (s *Server) newPageData(w http.ResponseWriter, r *http.Request, title, page string) *PageData {
 	data := NewPageData(s.config, title, page)
 	i18nManager := s.getI18nManager()
-	data.Lang = i18nManager.ResolveLanguage(w, r)
+	// A view-as session shows the previewed user's settings and ignores the
+	// browser's own cookies
+	viewAs := s.viewAsSession(r)
+	switch {
+	case viewAs == nil:
+		data.Lang = i18nManager.ResolveLanguage(w, r)
+	case r.URL.Query().Get("lang") != "":
+		// No cookie is written for a language picked during the preview
+		data.Lang = i18nManager.ResolveLanguage(nil, r)
+	default:
+		data.Lang = i18nManager.ResolveSupportedLanguage(viewAs.language)
+	}
 	if i18nManager.IsRTL(data.Lang) {
 		data.Dir = "rtl"
 	} else {
 		data.Dir = "ltr"
 	}
 	data.AvailableLanguages = i18nManager.SupportedLanguages()
-	prefsQuery := strings.TrimSpace(r.URL.Query().Get("prefs"))
-	prefs := parseSearchPreferences(prefsQuery)
+	prefsQuery := s.requestPrefs(r)
+	prefs := s.searchPrefs(r)
 	// Per AI.md PART 16: Theme read from cookie; resolve "auto" to "dark" server-side
 	// JS overrides with system preference on page load when mode is "auto"
 	themeMode := GetTheme(r)
+	if viewAs != nil {
+		themeMode = DefaultTheme
+		if theme := r.URL.Query().Get("theme"); theme == ThemeLight || theme == ThemeDark || theme == ThemeAuto || theme == ThemeContrast {
+			themeMode = theme
+		}
+		data.ViewAs = true
+		data.ViewAsPrefs = viewAs.prefs
+		data.ViewAsExpires = viewAs.expiresAt.UTC().Format("15:04 UTC")
+	}
 	if prefs.Theme != "" {
 		themeMode = prefs.Theme
 	}
@@ -751,6 +999,7 @@ func (s *Server) newPageData(w http.ResponseWriter, r *http.Request, title, page
 		data.Theme = themeMode
 	}
 	data.PrefsQuery = prefsQuery
+	data.PrivacySignals = httputil.RequestPrivacySignals(r)
 	if prefs.DefaultCategory != "" {
 		data.Category = prefs.DefaultCategory.String()
 	}
@@ -769,11 +1018,11 @@ func (s *Server) newPageData(w http.ResponseWriter, r *http.Request, title, page
 		}
 	}
 	// Set HasConsentCookie: skip banner when a valid cookieConsent cookie exists
-	if c, err := r.Cookie("cookieConsent"); err == nil && c.Value != "" {
+	if c, err := r.Cookie("cookieConsent"); err == nil && c.Value != "" && viewAs == nil {
 		data.HasConsentCookie = true
 	}
 	// Filter out already-dismissed announcements from the dismissed_announcements cookie
-	if dc, err := r.Cookie("dismissed_announcements"); err == nil && dc.Value != "" {
+	if dc, err := r.Cookie("dismissed_announcements"); err == nil && dc.Value != "" && viewAs == nil {
 		dismissed := strings.Split(dc.Value, ",")
 		dmSet := make(map[string]bool, len(dismissed))
 		for _, id := range dismissed {
@@ -852,9 +1101,16 @@ func (s *Server) setupRoutes() http.Handler {
 
 	// Search
 	r.HandleFunc("/search", s.handleSearch)
+	r.HandleFunc("/search/image", s.handleImageSearch)
+	r.Post("/s", s.handlePermalinkCreate)
+	r.Get("/s/{token}", s.handlePermalink)
 	r.HandleFunc("/alerts/new", s.handleAlertNew)
 	r.HandleFunc("/alerts", s.handleAlerts)
 	r.HandleFunc("/alerts/*", s.handleAlertAction)
+	r.Get("/report", s.handleReportForm)
+	r.Post("/report", s.handleReportSubmit)
+	r.Get("/warning", s.handleThreatWarning)
+	r.Get("/watch", s.handleWatch)
 
 	// Direct answers (full-page results for type:term queries per IDEA.md)
 	r.HandleFunc("/direct/*", s.handleDirect)
@@ -863,8 +1119,115 @@ func (s *Server) setupRoutes() http.Handler {
 	r.HandleFunc("/autocomplete", s.handleAutocomplete)
 
 	// Operator-gated server management endpoints per API.md PART 13/14
-	r.Get("/server/status", s.RequireOperator(s.handleServerStatus))
-	r.Get("/server/config", s.RequireOperator(s.handleServerConfig))
+	r.Get("/server/status", s.RequireScope(security.ScopeRead, s.handleServerStatus))
+	r.Get("/server/config", s.RequireScope(security.ScopeRead, s.handleServerConfig))
+	// Accessibility self-check over the rendered templates
+	r.Get("/server/a11y", s.RequireScope(security.ScopeRead, s.handleAccessibilityAudit))
+	r.Get(api.APIPrefix+"/server/a11y", s.RequireScope(security.ScopeRead, s.handleAccessibilityAudit))
+	// Published onions and hidden service client authorization keys
+	r.Get(api.APIPrefix+"/server/tor/services", s.RequireScope(security.ScopeRead, s.handleTorServices))
+	r.Get(api.APIPrefix+"/server/tor/clients", s.RequireScope(security.ScopeRead, s.handleTorClients))
+	r.Post(api.APIPrefix+"/server/tor/clients", s.RequireScope(security.ScopeConfigWrite, s.handleTorClientAdd))
+	r.Delete(api.APIPrefix+"/server/tor/clients/{name}", s.RequireScope(security.ScopeConfigWrite, s.handleTorClientRevoke))
+	r.Get(api.APIPrefix+"/server/tor/clients/trash", s.RequireScope(security.ScopeRead, s.handleTorClientTrash))
+	r.Post(api.APIPrefix+"/server/tor/clients/trash/{name}/restore", s.RequireScope(security.ScopeConfigWrite, s.handleTorClientRestore))
+	// Per-category engine lists: order and weights
+	r.Get(api.APIPrefix+"/server/engines/categories", s.RequireScope(security.ScopeRead, s.handleCategoryEngines))
+	r.Put(api.APIPrefix+"/server/engines/categories/{category}", s.RequireScope(security.ScopeEnginesWrite, s.handleCategoryEnginesSet))
+	r.Delete(api.APIPrefix+"/server/engines/categories/{category}", s.RequireScope(security.ScopeEnginesWrite, s.handleCategoryEnginesReset))
+	// Categories the instance offers; one can be turned off, for example
+	// files where torrent indexes are not allowed
+	r.Get(api.APIPrefix+"/server/categories", s.RequireScope(security.ScopeRead, s.handleServerCategories))
+	r.Put(api.APIPrefix+"/server/categories/{category}", s.RequireScope(security.ScopeConfigWrite, s.handleServerCategorySet))
+	// Daily engine quotas: budget, usage today by hour and the last 30 days
+	r.Get(api.APIPrefix+"/server/engines/quotas", s.RequireScope(security.ScopeRead, s.handleEngineQuotas))
+	// Response schema drift: engines whose parser is likely broken
+	r.Get(api.APIPrefix+"/server/engines/drift", s.RequireScope(security.ScopeRead, s.handleSchemaDrift))
+	r.Delete(api.APIPrefix+"/server/engines/drift/{engine}", s.RequireScope(security.ScopeEnginesWrite, s.handleSchemaDriftReset))
+	r.Get(api.APIPrefix+"/server/engines/hosts", s.RequireScope(security.ScopeRead, s.handleEngineHosts))
+	// Close an engine's circuit breaker before its cooldown ends
+	r.Delete(api.APIPrefix+"/server/engines/circuits/{engine}", s.RequireScope(security.ScopeEnginesWrite, s.handleEngineCircuitReset))
+	// Run one query against one engine and show the raw upstream exchange
+	r.Post(api.APIPrefix+"/server/engines/{engine}/test", s.RequireScope(security.ScopeEnginesWrite, s.handleEngineTest))
+	// Latency and faults injected into engine calls; development mode only
+	r.Get(api.APIPrefix+"/server/engines/chaos", s.RequireScope(security.ScopeRead, s.handleChaos))
+	r.Put(api.APIPrefix+"/server/engines/chaos/{engine}", s.RequireScope(security.ScopeEnginesWrite, s.handleChaosSet))
+	r.Delete(api.APIPrefix+"/server/engines/chaos/{engine}", s.RequireScope(security.ScopeEnginesWrite, s.handleChaosReset))
+	// Result cache hit rates per category, and flushing it
+	r.Get(api.APIPrefix+"/server/cache", s.RequireScope(security.ScopeRead, s.handleResultCache))
+	r.Delete(api.APIPrefix+"/server/cache", s.RequireScope(security.ScopeConfigWrite, s.handleResultCacheFlush))
+	// Ranking pipeline: stage order and settings, and previews of a change
+	r.Get(api.APIPrefix+"/server/ranking", s.RequireScope(security.ScopeRead, s.handleRanking))
+	r.Put(api.APIPrefix+"/server/ranking", s.RequireScope(security.ScopeConfigWrite, s.handleRankingSet))
+	r.Post(api.APIPrefix+"/server/ranking/preview", s.RequireScope(security.ScopeRead, s.handleRankingPreview))
+	r.Get(api.APIPrefix+"/server/engines/user-agents", s.RequireScope(security.ScopeRead, s.handleUserAgents))
+	r.Put(api.APIPrefix+"/server/engines/user-agents/{engine}", s.RequireScope(security.ScopeEnginesWrite, s.handleUserAgentSet))
+	r.Delete(api.APIPrefix+"/server/engines/user-agents/{engine}", s.RequireScope(security.ScopeEnginesWrite, s.handleUserAgentReset))
+	// Server secrets: versions and rotation, never the secrets themselves
+	r.Get(api.APIPrefix+"/server/keys", s.RequireOperator(s.handleKeys))
+	r.Post(api.APIPrefix+"/server/keys/{name}/rotate", s.RequireOperator(s.handleKeyRotate))
+	// Named operator tokens: only the server.token manages them
+	r.Get(api.APIPrefix+"/server/tokens", s.RequireOperator(s.handleOperatorTokens))
+	r.Post(api.APIPrefix+"/server/tokens", s.RequireOperator(s.handleOperatorTokenCreate))
+	r.Get(api.APIPrefix+"/server/tokens/self", s.handleOperatorTokenSelf)
+	// Short-lived JWTs standing in for either kind of token, checked
+	// without the database
+	r.Post(api.APIPrefix+"/server/tokens/jwt", s.handleOperatorJWTIssue)
+	r.Delete(api.APIPrefix+"/server/tokens/{id}", s.RequireOperator(s.handleOperatorTokenRevoke))
+	// A token's usage against its rate limit; boosts and freezes without
+	// revoking
+	r.Get(api.APIPrefix+"/server/tokens/{id}", s.RequireOperator(s.handleOperatorToken))
+	r.Put(api.APIPrefix+"/server/tokens/{id}/rate-limit", s.RequireOperator(s.handleOperatorTokenLimit))
+	r.Put(api.APIPrefix+"/server/tokens/{id}/boost", s.RequireOperator(s.handleOperatorTokenBoost))
+	r.Delete(api.APIPrefix+"/server/tokens/{id}/boost", s.RequireOperator(s.handleOperatorTokenUnboost))
+	r.Put(api.APIPrefix+"/server/tokens/{id}/freeze", s.RequireOperator(s.handleOperatorTokenFreeze))
+	r.Delete(api.APIPrefix+"/server/tokens/{id}/freeze", s.RequireOperator(s.handleOperatorTokenUnfreeze))
+	// Dark-launch feature flags
+	r.Get(api.APIPrefix+"/server/features", s.RequireScope(security.ScopeRead, s.handleFeatures))
+	r.Put(api.APIPrefix+"/server/features/{name}", s.RequireScope(security.ScopeConfigWrite, s.handleFeatureSet))
+	r.Delete(api.APIPrefix+"/server/features/{name}", s.RequireScope(security.ScopeConfigWrite, s.handleFeatureReset))
+	// Configuration presets, previewed before they are applied
+	r.Get(api.APIPrefix+"/server/presets", s.RequireScope(security.ScopeRead, s.handlePresets))
+	r.Post(api.APIPrefix+"/server/presets/{name}", s.RequireScope(security.ScopeConfigWrite, s.handlePresetApply))
+
+	// Data-subject requests for alert subscribers; the full operator token
+	// only, since the export holds personal data
+	r.Post(api.APIPrefix+"/server/subjects/export", s.RequireOperator(s.handleSubjectExport))
+	r.Post(api.APIPrefix+"/server/subjects/erase", s.RequireOperator(s.handleSubjectErase))
+
+	// Result moderation: the queue of reported results and the result
+	// blocklist their resolutions feed
+	r.Get(api.APIPrefix+"/server/reports", s.RequireScope(security.ScopeRead, s.handleReports))
+	r.Post(api.APIPrefix+"/server/reports/{id}/resolve", s.RequireScope(security.ScopeConfigWrite, s.handleReportResolve))
+	r.Get(api.APIPrefix+"/server/result-rules", s.RequireScope(security.ScopeRead, s.handleResultRules))
+	r.Post(api.APIPrefix+"/server/result-rules", s.RequireScope(security.ScopeConfigWrite, s.handleResultRuleAdd))
+	r.Delete(api.APIPrefix+"/server/result-rules/{id}", s.RequireScope(security.ScopeConfigWrite, s.handleResultRuleRemove))
+	r.Get(api.APIPrefix+"/server/result-rules/trash", s.RequireScope(security.ScopeRead, s.handleResultRuleTrash))
+	r.Post(api.APIPrefix+"/server/result-rules/trash/{id}/restore", s.RequireScope(security.ScopeConfigWrite, s.handleResultRuleRestore))
+
+	// Custom bangs: the editor, DuckDuckGo-compatible import and export,
+	// the use counts of every bang, and the trash of deleted ones
+	r.Get(api.APIPrefix+"/server/bangs", s.RequireScope(security.ScopeRead, s.handleCustomBangs))
+	r.Post(api.APIPrefix+"/server/bangs", s.RequireScope(security.ScopeConfigWrite, s.handleCustomBangCreate))
+	r.Get(api.APIPrefix+"/server/bangs/export", s.RequireScope(security.ScopeRead, s.handleBangsExport))
+	r.Post(api.APIPrefix+"/server/bangs/import", s.RequireScope(security.ScopeConfigWrite, s.handleBangsImport))
+	r.Get(api.APIPrefix+"/server/bangs/usage", s.RequireScope(security.ScopeRead, s.handleBangUsage))
+	r.Get(api.APIPrefix+"/server/bangs/trash", s.RequireScope(security.ScopeRead, s.handleBangTrash))
+	r.Post(api.APIPrefix+"/server/bangs/trash/{shortcut}/restore", s.RequireScope(security.ScopeConfigWrite, s.handleBangRestore))
+	r.Put(api.APIPrefix+"/server/bangs/{shortcut}", s.RequireScope(security.ScopeConfigWrite, s.handleCustomBangUpdate))
+	r.Delete(api.APIPrefix+"/server/bangs/{shortcut}", s.RequireScope(security.ScopeConfigWrite, s.handleCustomBangDelete))
+
+	// Retention dry run: what the retention task would delete
+	r.Get(api.APIPrefix+"/server/retention", s.RequireScope(security.ScopeRead, s.handleRetention))
+	// Memory watchdog: pressure level, limits and cache sizes
+	r.Get(api.APIPrefix+"/server/memory", s.RequireScope(security.ScopeRead, s.handleMemory))
+	// Backups on demand
+	r.Get(api.APIPrefix+"/server/backups", s.RequireScope(security.ScopeBackups, s.handleBackups))
+	r.Post(api.APIPrefix+"/server/backups", s.RequireScope(security.ScopeBackups, s.handleBackupCreate))
+	// "View as user" previews of the web UI
+	r.Post(api.APIPrefix+"/server/view-as", s.RequireScope(security.ScopeRead, s.handleViewAsCreate))
+	r.Get("/view-as/end", s.handleViewAsEnd)
+	r.Get("/view-as/{token}", s.handleViewAsStart)
 
 	// Standard server pages (per AI.md spec)
 	// /server → /server/about redirect per AI.md line 17696
@@ -879,6 +1242,20 @@ func (s *Server) setupRoutes() http.Handler {
 	r.HandleFunc("/server/contact", s.handleContact)
 	r.HandleFunc("/server/help", s.handleHelp)
 	r.HandleFunc("/server/terms", s.handleTerms)
+	// Short policy URLs, as linked from other sites and app stores
+	r.Get("/privacy", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/server/privacy", http.StatusMovedPermanently)
+	})
+	r.Get("/terms", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/server/terms", http.StatusMovedPermanently)
+	})
+	// Offline documentation, embedded in the binary
+	r.Get("/server/docs", s.handleDocs)
+	r.Get("/server/docs/{page}", s.handleDocs)
+	r.Get("/docs", handleDocsRedirect)
+	r.Get("/docs/{page}", handleDocsRedirect)
+	// QR codes for the clear web and onion addresses (web|onion)
+	r.Get("/server/qr/{target}", s.handleQRCode)
 
 	// Coordinated-disclosure security pages per AI.md PART 11 "Public Pages"
 	r.HandleFunc("/server/security", s.handleSecurityOverview)
@@ -901,12 +1278,21 @@ func (s *Server) setupRoutes() http.Handler {
 	// Well-known URIs per RFC 8615
 	// Password change redirect per AI.md PART 11
 	r.HandleFunc("/.well-known/change-password", s.handleWellKnownChangePassword)
+	// Cache keys and policies for a CDN in front of the server (server.cdn)
+	r.HandleFunc("/.well-known/cache-policy", s.handleCachePolicy)
 
 	// Catch-all for unknown /.well-known/* paths — returns 404 per AI.md PART 11
 	// Must be registered after specific handlers
 	r.HandleFunc("/.well-known/*", s.handleWellKnownCatchAll)
 	r.HandleFunc("/.well-known/", s.handleWellKnownCatchAll)
 
+	// PWA: manifest, root-scoped service worker, offline fallback page, and
+	// the share target declared in the manifest
+	r.HandleFunc("/manifest.webmanifest", s.handleManifest)
+	r.HandleFunc("/sw.js", s.handleServiceWorker)
+	r.HandleFunc("/offline", s.handleOffline)
+	r.Get("/share", s.handleShare)
+
 	// OpenSearch
 	if s.config.Search.OpenSearch.Enabled {
 		r.HandleFunc("/opensearch.xml", s.handleOpenSearch)
@@ -923,6 +1309,7 @@ func (s *Server) setupRoutes() http.Handler {
 	r.HandleFunc("/preferences", s.handlePreferences)
 	r.HandleFunc("/server/preferences", s.handlePreferences)
 	r.Post("/preferences/widgets", s.handleWidgetPreferencesSave)
+	r.Post("/preferences/macros", s.handleMacroPreferencesSave)
 
 	// Cookie consent and CCPA per AI.md PART 16/PART 12
 	// POST /consent: sets cookieConsent JSON cookie (accept/decline/save), redirects back
@@ -971,14 +1358,21 @@ func (s *Server) setupRoutes() http.Handler {
 	// Chain() iterates from last to first, so index 0 = outermost = first to execute.
 	// Middleware order per AI.md PART 5 (NON-NEGOTIABLE):
 	// Recovery → URLNormalize(1) → RequestID(2) → PathSecurity(3) →
-	// SecurityHeaders(4) → SecGPC → CORS → Allowlist(5) → Blocklist(6) →
+	// SecurityHeaders(4) → SecGPC → CORS → OnionLocation → Allowlist(5) → Blocklist(6) →
 	// RateLimit(7) → GeoIP(8) → Logging(10). Auth(9) is per-route.
 	handler := Chain(
 		r,
 		// outermost: catches all panics
 		s.middleware.Recovery,
+		// 0. answer only allowed hosts; send others and plain HTTP to the
+		// canonical URL (server.allowed_hosts, server.canonical_host)
+		s.checkHost,
+		// 0b. serve under server.base_path: routes see paths from the root
+		s.servePrefixed,
 		// 1. normalize URLs (trailing slash, etc.)
 		URLNormalizeMiddleware,
+		// 1b. canonical query order and Cache-Control for a CDN (server.cdn)
+		s.cdnCache,
 		// 2. attach request ID (before logging)
 		s.middleware.RequestID,
 		// 3. validate paths, block traversal
@@ -989,6 +1383,9 @@ func (s *Server) setupRoutes() http.Handler {
 		s.middleware.SecGPC,
 		// 4c. CORS (near security headers; handles preflight)
 		s.middleware.CORS,
+		// 4d. Onion-Location header; opted-in Tor Browser users go to the
+		// onion; API-scoped onions serve only the API
+		s.onionLocation,
 		// 5. set allowlisted flag (bypasses 6/7/8, not auth)
 		s.middleware.Allowlist,
 		// 6. IP/domain blocklist check
@@ -1031,6 +1428,7 @@ func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
 		{"/server/privacy", "0.3", "monthly"},
 		{"/server/help", "0.5", "monthly"},
 		{"/server/terms", "0.3", "monthly"},
+		{"/server/docs", "0.4", "monthly"},
 		{"/openapi", "0.4", "weekly"},
 		{"/server/docs/graphql", "0.4", "weekly"},
 		{"/server/healthz", "0.2", "always"},
@@ -1056,7 +1454,7 @@ func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
 
 // handleSearch handles search requests
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-	prefs := parseSearchPreferences(r.URL.Query().Get("prefs"))
+	prefs := s.searchPrefs(r)
 
 	// Sanitize and validate input
 	queryStr := sanitizeInput(strings.TrimSpace(r.URL.Query().Get("q")))
@@ -1073,7 +1471,7 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	if perPage == 0 {
 		perPage, _ = strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("limit")))
 	}
-	safeSearch, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("safe_search")))
+	timeRange := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("time_range")))
 
 	// Default to general if no category specified
 	if category == "" {
@@ -1088,18 +1486,36 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	if perPage < 1 || perPage > 100 {
 		perPage = 20
 	}
-	if strings.TrimSpace(r.URL.Query().Get("safe_search")) == "" {
-		safeSearch = prefs.SafeSearch
+	safeSearch := s.safeSearchLevel(r, prefs)
+	switch timeRange {
+	case "day", "week", "month", "year":
+	default:
+		timeRange = "any"
 	}
 
 	if queryStr == "" {
 		s.handleError(w, r, http.StatusBadRequest, i18n.RequestString(r, "search.error_title"), i18n.RequestString(r, "search.empty_query"))
 		return
 	}
+	// A default category the operator turned off falls back to general
+	if !s.config.Search.CategoryEnabled(category) {
+		if categoryParam == "" {
+			category = "general"
+		} else {
+			s.handleError(w, r, http.StatusNotFound, i18n.RequestString(r, "search.error_title"), i18n.RequestString(r, "search.category_disabled"))
+			return
+		}
+	}
+
+	// Expand search macros (~name words), then search the expansion
+	if s.expandMacro(w, r, queryStr) {
+		return
+	}
 
 	// Check for bang commands
 	if s.config.Search.Bangs.Enabled {
 		if bangResult := s.bangManager.Parse(queryStr); bangResult != nil {
+			s.recordBangUse(bangResult.Bang.Shortcut)
 			// Handle bang search
 			if s.config.Search.Bangs.ProxyRequests {
 				// Proxy mode: redirect to our bang proxy handler
@@ -1123,6 +1539,18 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// The maps page loads Leaflet and the map tiles from search.maps
+	if category == "maps" {
+		allowMapSources(w.Header(), s.config.Search.Maps)
+	}
+
+	// An anonymous browser repeating a recent search gets the page rendered
+	// for it then, without searching or executing templates again
+	pageKey := s.searchPageKey(r, queryStr, category, page, perPage)
+	if pageKey != "" && s.serveCachedSearchPage(w, r, pageKey) {
+		return
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
@@ -1140,12 +1568,9 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	ctx = instant.WithLang(ctx, s.getI18nManager().DetectLanguage(r))
 
 	// Check for instant answers first (only for general category)
-	var instantAnswer *instant.Answer
+	var instantAnswers []*instant.Answer
 	if category == "general" && s.instantManager != nil {
-		answer, err := s.instantManager.Process(ctx, queryStr)
-		if err == nil && answer != nil {
-			instantAnswer = answer
-		}
+		instantAnswers = s.instantManager.ProcessAll(ctx, queryStr)
 	}
 
 	// Perform search
@@ -1154,8 +1579,24 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	query.Page = page
 	query.PerPage = perPage
 	query.SafeSearch = safeSearch
+	query.TimeRange = timeRange
+	query.SortBy = model.ParseSortOrder(r.URL.Query().Get("sort"))
+	if prefs.Language != "" {
+		query.Language = prefs.Language
+	}
+	query.EnabledEngines = prefs.Engines
+	if query.Category == model.CategoryImages {
+		query.ImageColor = model.ParseImageColor(r.URL.Query().Get("image_color"))
+		query.ImageLicense = model.ParseImageLicense(r.URL.Query().Get("image_license"))
+	}
+	if query.Category == model.CategoryVideos {
+		query.VideoLength = model.ParseVideoLength(r.URL.Query().Get("video_length"))
+		query.VideoQuality = model.ParseVideoQuality(r.URL.Query().Get("video_quality"))
+	}
+	query.Country = s.searchCountry(r)
 
-	results, err := s.aggregator.Search(ctx, query)
+	// Pages are gathered across engine pages, so page 2 continues page 1
+	results, _, err := s.aggregator.SearchPages(ctx, query, r.URL.Query().Get("page_token"))
 
 	if err != nil && !errors.Is(err, model.ErrNoResults) {
 		// For HTTP tools, render the error as plain text
@@ -1184,7 +1625,7 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	// 2. Text browsers (lynx, w3m, links, elinks) — INTERACTIVE, NO JavaScript
 	//    Serve completely different HTML: server-rendered, forms via GET, nav via <a href>
 	if httputil.IsTextBrowser(r) {
-		data := s.buildSearchPageData(w, r, queryStr, results, category, instantAnswer)
+		data := s.buildSearchPageData(w, r, queryStr, results, category, instantAnswers)
 		s.renderNoJSSearch(w, r, data)
 		return
 	}
@@ -1192,14 +1633,18 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	// 3. HTTP tools (curl, wget, httpie) — NON-INTERACTIVE, just dump output
 	//    Render full HTML first, then convert to terminal-friendly plain text
 	if httputil.IsHttpTool(r) {
-		data := s.buildSearchPageData(w, r, queryStr, results, category, instantAnswer)
+		data := s.buildSearchPageData(w, r, queryStr, results, category, instantAnswers)
 		s.renderHTMLToText(w, "search", data)
 		return
 	}
 
 	// 4. Regular browsers — full HTML with JavaScript
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	s.renderSearchResultsWithInstant(w, r, queryStr, results, category, instantAnswer)
+	if pageKey != "" && err == nil && len(instantAnswers) == 0 {
+		s.renderCachedSearchResults(w, r, pageKey, queryStr, results, category)
+		return
+	}
+	s.renderSearchResultsWithInstant(w, r, queryStr, results, category, instantAnswers)
 }
 
 // handleDirect handles direct answer requests
@@ -1286,9 +1731,9 @@ type DirectAnswerPageData struct {
 
 // renderDirectAnswerFallback renders a direct answer without templates
 func (s *Server) renderDirectAnswerFallback(w http.ResponseWriter, r *http.Request, answer *direct.Answer) {
-	baseURL := s.config.Server.BaseURL
+	baseURL := s.config.Server.PublicURL("")
 	if baseURL == "" {
-		baseURL = ""
+		baseURL = s.config.Server.BasePath
 	}
 	appName := s.config.Server.Branding.Title
 	if appName == "" {
@@ -1364,7 +1809,7 @@ footer a{color:var(--accent);text-decoration:none}
 	}
 	fmt.Fprint(w, `">`)
 	// Content is already HTML formatted by handlers
-	fmt.Fprint(w, answer.Content)
+	fmt.Fprint(w, prefixRootLinks(answer.Content, s.config.Server.BasePath))
 	fmt.Fprint(w, `</div>`)
 	if answer.Source != "" || answer.SourceURL != "" {
 		fmt.Fprint(w, `<div class="source">`)
@@ -1427,8 +1872,8 @@ func (s *Server) renderSearchError(w http.ResponseWriter, r *http.Request, query
 
 // buildSearchPageData constructs a SearchPageData struct without writing any response.
 // Used by renderSearchResultsWithInstant, renderNoJSSearch, and renderHTMLToText.
-func (s *Server) buildSearchPageData(w http.ResponseWriter, r *http.Request, query string, results *model.SearchResults, category string, instantAnswer *instant.Answer) *SearchPageData {
-	safeSearch, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("safe_search")))
+func (s *Server) buildSearchPageData(w http.ResponseWriter, r *http.Request, query string, results *model.SearchResults, category string, instantAnswers []*instant.Answer) *SearchPageData {
+	safeSearch := s.safeSearchLevel(r, s.searchPrefs(r))
 
 	baseData := s.newPageData(w, r, query, "search")
 	baseData.Description = fmt.Sprintf("Search results for: %s", query)
@@ -1445,7 +1890,42 @@ func (s *Server) buildSearchPageData(w http.ResponseWriter, r *http.Request, que
 		Engines:       results.Engines,
 		PerPage:       results.PerPage,
 		SafeSearch:    safeSearch,
-		InstantAnswer: instantAnswer,
+		TimeRange:     strings.TrimSpace(r.URL.Query().Get("time_range")),
+		ShareLinks:    s.config.Search.Permalinks.Enabled && s.permalinks != nil,
+		ReportLinks:   s.reportsEnabled(),
+		Degraded:      results.Degraded,
+		ImageColor:    model.ParseImageColor(r.URL.Query().Get("image_color")),
+		ImageLicense:  model.ParseImageLicense(r.URL.Query().Get("image_license")),
+		ImageColors:   model.ImageColors,
+		ImageLicenses: model.ImageLicenses,
+		ReverseImage:  s.config.Search.ReverseImage.Enabled,
+		VideoLength:   model.ParseVideoLength(r.URL.Query().Get("video_length")),
+		VideoQuality:  model.ParseVideoQuality(r.URL.Query().Get("video_quality")),
+		VideoLengths:  model.VideoLengths,
+		TimeRanges:    model.TimeRanges,
+		GeoCountry:    s.searchCountryName(r),
+	}
+	if category == string(model.CategoryNews) {
+		data.TimeRange = model.ParseTimeRange(r.URL.Query().Get("time_range"))
+		if sortBy := model.ParseSortOrder(r.URL.Query().Get("sort")); sortBy != model.SortRelevance {
+			data.Sort = string(sortBy)
+		}
+		if r.URL.Query().Get("group") == "publisher" {
+			data.GroupPublisher = true
+			data.NewsGroups = model.GroupByPublisher(results.GetPage(results.Page))
+		}
+	}
+	if results.CachedAt != nil {
+		data.CachedAt = results.CachedAt.UTC().Format("2006-01-02 15:04 UTC")
+	}
+
+	// Answers past the operator's limit render collapsed below the rest
+	data.InstantAnswers = instantAnswers
+	if s.instantManager != nil {
+		if n := s.instantManager.MaxAnswers(); len(instantAnswers) > n {
+			data.InstantAnswers = instantAnswers[:n]
+			data.CollapsedAnswers = instantAnswers[n:]
+		}
 	}
 
 	pageLinks := make([]int, 0, results.TotalPages)
@@ -1467,8 +1947,8 @@ func (s *Server) buildSearchPageData(w http.ResponseWriter, r *http.Request, que
 }
 
 // renderSearchResultsWithInstant renders search results with optional instant answer
-func (s *Server) renderSearchResultsWithInstant(w http.ResponseWriter, r *http.Request, query string, results *model.SearchResults, category string, instantAnswer *instant.Answer) {
-	data := s.buildSearchPageData(w, r, query, results, category, instantAnswer)
+func (s *Server) renderSearchResultsWithInstant(w http.ResponseWriter, r *http.Request, query string, results *model.SearchResults, category string, instantAnswers []*instant.Answer) {
+	data := s.buildSearchPageData(w, r, query, results, category, instantAnswers)
 
 	if err := s.renderer.Render(w, "search", data); err != nil {
 		// Fallback to inline rendering
@@ -1485,9 +1965,9 @@ func (s *Server) renderSearchResultsInline(w http.ResponseWriter, r *http.Reques
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>%s - %s</title>
-    <link rel="stylesheet" href="/static/css/common.css">
-    <link rel="stylesheet" href="/static/css/components.css">
-    <link rel="stylesheet" href="/static/css/public.css">
+    <link rel="stylesheet" href="%s/static/css/common.css">
+    <link rel="stylesheet" href="%s/static/css/components.css">
+    <link rel="stylesheet" href="%s/static/css/public.css">
         <h1>Results for: %s</h1>
         <p>%d results (%.3fs)</p>`,
 		html.EscapeString(baseData.Lang),
@@ -1495,6 +1975,9 @@ func (s *Server) renderSearchResultsInline(w http.ResponseWriter, r *http.Reques
 		html.EscapeString(baseData.Theme),
 		html.EscapeString(query),
 		html.EscapeString(s.config.Server.Title),
+		html.EscapeString(s.config.Server.BasePath),
+		html.EscapeString(s.config.Server.BasePath),
+		html.EscapeString(s.config.Server.BasePath),
 		html.EscapeString(query),
 		results.TotalResults,
 		results.SearchTime,
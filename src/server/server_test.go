@@ -1234,6 +1234,7 @@ func TestGetThemeClass(t *testing.T) {
 		{"dark", "theme-dark"},
 		{"light", "theme-light"},
 		{"auto", "theme-auto"},
+		{"contrast", "theme-contrast"},
 		{"invalid", "theme-dark"},
 		{"", "theme-dark"},
 	}
@@ -1256,6 +1257,7 @@ func TestIsValidTheme(t *testing.T) {
 		{"dark", true},
 		{"light", true},
 		{"auto", true},
+		{"contrast", true},
 		{"invalid", false},
 		{"", false},
 		{"Dark", false},
@@ -9,9 +9,24 @@
     // THEME MANAGEMENT
     // ========================================================================
     const SEARCH_PREFERENCES_KEY = 'search_preferences';
-    const THEMES = ['dark', 'light', 'auto'];
+    const THEMES = ['dark', 'light', 'auto', 'contrast'];
     let clientTranslations = null;
 
+    // server.base_path, such as "/search" when the instance is served under
+    // a path: every URL from the instance root is put under it
+    const BASE_PATH = document.documentElement.getAttribute('data-base-path') || '';
+    const COOKIE_PATH = BASE_PATH || '/';
+
+    function withBase(path) {
+        return BASE_PATH + path;
+    }
+
+    // An operator's "view as user" preview runs on in-memory storage and
+    // never writes cookies, so the operator's own settings stay untouched
+    if (document.documentElement.hasAttribute('data-view-as')) {
+        installViewAsSandbox(document.documentElement.getAttribute('data-view-as'));
+    }
+
     function normalizeThemePreference(theme) {
         if (theme === 'system') return 'auto';
         return THEMES.includes(theme) ? theme : 'auto';
@@ -35,7 +50,7 @@
     }
 
     function loadClientTranslations() {
-        return fetch('/locales/' + encodeURIComponent(getClientLanguage()) + '.json', {
+        return fetch(withBase('/locales/') + encodeURIComponent(getClientLanguage()) + '.json', {
             headers: {
                 'Accept': 'application/json'
             }
@@ -62,15 +77,15 @@
 
     function writeConsentCookie(consent) {
         document.cookie = 'cookieConsent=' + encodeURIComponent(JSON.stringify(consent)) +
-            '; path=/; max-age=31536000; SameSite=Lax';
+            '; path=' + COOKIE_PATH + '; max-age=31536000; SameSite=Lax';
     }
 
     function clearCookie(name) {
-        document.cookie = name + '=; path=/; max-age=0; SameSite=Lax';
+        document.cookie = name + '=; path=' + COOKIE_PATH + '; max-age=0; SameSite=Lax';
     }
 
     function setCookie(name, value, maxAgeSeconds) {
-        document.cookie = name + '=' + encodeURIComponent(value) + '; path=/; max-age=' + maxAgeSeconds + '; SameSite=Lax';
+        document.cookie = name + '=' + encodeURIComponent(value) + '; path=' + COOKIE_PATH + '; max-age=' + maxAgeSeconds + '; SameSite=Lax';
     }
 
     // Remove the legacy cookie_consent cookie and ensure the theme cookie is set.
@@ -99,7 +114,9 @@
             results_per_page: prefs.results_per_page ? String(prefs.results_per_page) : '20',
             new_tab: !!prefs.new_tab,
             infinite_scroll: !!prefs.infinite_scroll,
-            keyboard_shortcuts: prefs.keyboard_shortcuts !== false
+            keyboard_shortcuts: prefs.keyboard_shortcuts !== false,
+            search_preview: !!prefs.search_preview,
+            localize: prefs.localize !== false
         };
     }
 
@@ -142,7 +159,7 @@
 
             switch (key) {
                 case 't':
-                    prefs.theme = normalizeThemePreference(value === 'd' ? 'dark' : value === 'l' ? 'light' : value === 'a' ? 'auto' : value);
+                    prefs.theme = normalizeThemePreference(value === 'd' ? 'dark' : value === 'l' ? 'light' : value === 'a' ? 'auto' : value === 'h' ? 'contrast' : value);
                     break;
                 case 'c':
                     prefs.default_category = value === 'web' ? 'general' : value;
@@ -162,15 +179,49 @@
                 case 'k':
                     prefs.keyboard_shortcuts = value !== '0';
                     break;
+                case 'v':
+                    prefs.search_preview = value === '1';
+                    break;
+                case 'g':
+                    prefs.localize = value !== '0';
+                    break;
             }
         });
 
         return normalizeSearchPreferences(prefs);
     }
 
+    function installViewAsSandbox(prefsRaw) {
+        var items = {};
+        var memory = {
+            getItem: function(key) {
+                return Object.prototype.hasOwnProperty.call(items, key) ? items[key] : null;
+            },
+            setItem: function(key, value) { items[key] = String(value); },
+            removeItem: function(key) { delete items[key]; },
+            clear: function() { items = {}; },
+            key: function(index) { return Object.keys(items)[index] || null; },
+            get length() { return Object.keys(items).length; }
+        };
+        if (prefsRaw) {
+            memory.setItem(SEARCH_PREFERENCES_KEY, JSON.stringify(parsePreferenceString(prefsRaw)));
+        }
+        try {
+            Object.defineProperty(window, 'localStorage', { value: memory, configurable: true });
+            // The previewed user has none of this browser's cookies
+            Object.defineProperty(document, 'cookie', {
+                get: function() { return ''; },
+                set: function() {},
+                configurable: true
+            });
+        } catch (e) {
+            // Read-only in this browser; the server still ignores its cookies
+        }
+    }
+
     function encodePreferenceString(prefs) {
         prefs = normalizeSearchPreferences(prefs);
-        var themeMap = { dark: 'd', light: 'l', auto: 'a' };
+        var themeMap = { dark: 'd', light: 'l', auto: 'a', contrast: 'h' };
         var safeSearchMap = { '0': 'o', '1': 'm', '2': 's' };
         var category = prefs.default_category === 'general' ? 'web' : prefs.default_category;
         return [
@@ -180,7 +231,9 @@
             'r=' + prefs.results_per_page,
             'n=' + (prefs.new_tab ? '1' : '0'),
             'p=' + (prefs.infinite_scroll ? 'i' : 'p'),
-            'k=' + (prefs.keyboard_shortcuts ? '1' : '0')
+            'k=' + (prefs.keyboard_shortcuts ? '1' : '0'),
+            'v=' + (prefs.search_preview ? '1' : '0'),
+            'g=' + (prefs.localize ? '1' : '0')
         ].join(';');
     }
 
@@ -208,7 +261,9 @@
             results_per_page: urlPrefs.results_per_page || stored.results_per_page,
             new_tab: urlPrefs.new_tab,
             infinite_scroll: urlPrefs.infinite_scroll,
-            keyboard_shortcuts: urlPrefs.keyboard_shortcuts
+            keyboard_shortcuts: urlPrefs.keyboard_shortcuts,
+            search_preview: urlPrefs.search_preview,
+            localize: urlPrefs.localize
         });
         localStorage.setItem(SEARCH_PREFERENCES_KEY, JSON.stringify(merged));
         return merged;
@@ -230,7 +285,7 @@
         var prefs = getActiveSearchPreferences();
         var urlPrefs = getURLPreferenceString();
 
-        document.querySelectorAll('form[action="/search"]').forEach(function(form) {
+        document.querySelectorAll('form[action="' + withBase('/search') + '"]').forEach(function(form) {
             upsertHiddenField(form, 'safe_search', prefs.safe_search);
             upsertHiddenField(form, 'per_page', prefs.results_per_page);
             if (urlPrefs) {
@@ -250,9 +305,10 @@
         return 'auto';
     }
 
-    // Resolve "auto" to actual dark/light based on system preference
+    // Resolve "auto" to actual dark/light/contrast based on system preference
     function resolveTheme(theme) {
         if (theme === 'auto') {
+            if (window.matchMedia('(prefers-contrast: more)').matches) return 'contrast';
             return window.matchMedia('(prefers-color-scheme: light)').matches ? 'light' : 'dark';
         }
         return theme;
@@ -267,7 +323,7 @@
 
     function setTheme(theme) {
         applyTheme(theme);
-        document.cookie = 'theme=' + encodeURIComponent(theme) + '; path=/; max-age=31536000; SameSite=Lax';
+        document.cookie = 'theme=' + encodeURIComponent(theme) + '; path=' + COOKIE_PATH + '; max-age=31536000; SameSite=Lax';
     }
 
     // No-op: icon display is now fully CSS-driven via [data-theme-mode] attribute
@@ -287,11 +343,19 @@
             applyTheme('auto');
         }
     }
-    if (systemThemeQuery.addEventListener) {
-        systemThemeQuery.addEventListener('change', onSystemThemeChange);
-    } else if (systemThemeQuery.addListener) {
-        // Safari < 14 fallback
-        systemThemeQuery.addListener(onSystemThemeChange);
+    [systemThemeQuery, window.matchMedia('(prefers-contrast: more)')].forEach(function(query) {
+        if (query.addEventListener) {
+            query.addEventListener('change', onSystemThemeChange);
+        } else if (query.addListener) {
+            // Safari < 14 fallback
+            query.addListener(onSystemThemeChange);
+        }
+    });
+
+    // Honor prefers-reduced-motion: no smooth scrolling or animated previews
+    var reducedMotionQuery = window.matchMedia('(prefers-reduced-motion: reduce)');
+    function prefersReducedMotion() {
+        return reducedMotionQuery.matches;
     }
 
     // ========================================================================
@@ -487,7 +551,7 @@
 
     function applyConsent(consent) {
         if (consent.preferences) {
-            document.cookie = 'preferencesEnabled=true; path=/; max-age=31536000';
+            document.cookie = 'preferencesEnabled=true; path=' + COOKIE_PATH + '; max-age=31536000';
         }
         if (consent.analytics) {
             loadTracking();
@@ -507,7 +571,7 @@
                 applyCCPAOptOut();
             }
         }
-        document.querySelectorAll('form[action="/consent/ccpa"]').forEach(function(form) {
+        document.querySelectorAll('form[action="' + withBase('/consent/ccpa') + '"]').forEach(function(form) {
             form.addEventListener('submit', function(event) {
                 if (form.elements.optout && form.elements.optout.value === 'true') {
                     event.preventDefault();
@@ -530,7 +594,7 @@
     }
 
     function applyCCPAOptOut() {
-        document.cookie = 'ccpa_opt_out=true; path=/; max-age=31536000';
+        document.cookie = 'ccpa_opt_out=true; path=' + COOKIE_PATH + '; max-age=31536000';
     }
 
     // ========================================================================
@@ -669,6 +733,13 @@
         var suggestions = [];
         var dropdown = null;
         var abortController = null;
+        // Search-as-you-type previews: opt-in per user, and only when the
+        // operator has enabled the preview endpoint
+        var previewOn = document.body.hasAttribute('data-search-preview') && getActiveSearchPreferences().search_preview;
+        var previewTimer = null;
+        var previewController = null;
+        var suggestionItems = [];
+        var previewItems = [];
 
         // Create dropdown element
         function createDropdown() {
@@ -705,7 +776,12 @@
             }
 
             dropdown.innerHTML = items.map(function(item, index) {
-                if (item.type === 'bang') {
+                if (item.type === 'result') {
+                    return '<div class="autocomplete-item autocomplete-result" data-index="' + index + '" role="option" aria-selected="false">' +
+                        '<span class="autocomplete-result-title">' + escapeHtml(item.title) + '</span>' +
+                        '<span class="autocomplete-result-domain">' + escapeHtml(item.domain) + '</span>' +
+                    '</div>';
+                } else if (item.type === 'bang') {
                     return '<div class="autocomplete-item autocomplete-bang" data-index="' + index + '" role="option" aria-selected="false">' +
                         '<span class="autocomplete-bang-shortcut">!' + escapeHtml(item.shortcut) + '</span>' +
                         '<span class="autocomplete-bang-name">' + escapeHtml(item.name) + '</span>' +
@@ -768,6 +844,11 @@
             if (index < 0 || index >= suggestions.length) return;
 
             var item = suggestions[index];
+            if (item.type === 'result') {
+                hideDropdown();
+                window.location.href = item.url;
+                return;
+            }
             if (item.type === 'bang') {
                 // For bangs, keep the bang syntax and add a space
                 input.value = '!' + item.shortcut + ' ';
@@ -812,7 +893,7 @@
             abortController = new AbortController();
 
             // Fetch from autocomplete API - per AI.md PART 14: use origin prefix
-            fetch(window.location.origin + '/autocomplete?q=' + encodeURIComponent(query), {
+            fetch(window.location.origin + withBase('/autocomplete?q=') + encodeURIComponent(query), {
                 signal: abortController.signal
             })
             .then(function(response) {
@@ -832,7 +913,8 @@
                         return { type: 'suggestion', text: text };
                     });
                 }
-                showDropdown(results);
+                suggestionItems = results;
+                showDropdown(suggestionItems.concat(previewItems));
             })
             .catch(function(err) {
                 if (err.name !== 'AbortError') {
@@ -841,6 +923,41 @@
             });
         }
 
+        // Fetch the top few results for a partial query and list them
+        // below the suggestions
+        function fetchPreview(query) {
+            if (previewController) {
+                previewController.abort();
+            }
+            previewController = new AbortController();
+
+            var params = 'q=' + encodeURIComponent(query);
+            var page = document.querySelector('.search-results-page');
+            if (page && page.dataset.category) {
+                params += '&category=' + encodeURIComponent(page.dataset.category);
+            }
+            params += '&safe_search=' + encodeURIComponent(getActiveSearchPreferences().safe_search);
+
+            fetch(window.location.origin + withBase('/api/v1/search/preview?') + params, {
+                signal: previewController.signal
+            })
+            .then(function(response) {
+                if (!response.ok) throw new Error('Network error');
+                return response.json();
+            })
+            .then(function(data) {
+                if (input.value.trim() !== query) return;
+                var results = (data && data.ok && data.data && data.data.results) || [];
+                previewItems = results.map(function(r) {
+                    // Results a threat feed lists open the warning page instead
+                    var url = r.threat ? withBase('/warning?url=') + encodeURIComponent(r.url) : r.url;
+                    return { type: 'result', title: r.title, url: url, domain: r.domain || r.url };
+                });
+                showDropdown(suggestionItems.concat(previewItems));
+            })
+            .catch(function() {});
+        }
+
         // Filter bangs based on query
         function filterBangs(query) {
             if (!query) {
@@ -873,6 +990,17 @@
             debounceTimer = setTimeout(function() {
                 fetchSuggestions(query);
             }, 300);
+
+            if (previewOn) {
+                // Previews may reach an upstream engine, so wait for a longer pause
+                clearTimeout(previewTimer);
+                previewItems = [];
+                if (query.length >= 3 && query.charAt(0) !== '!') {
+                    previewTimer = setTimeout(function() {
+                        fetchPreview(query);
+                    }, 600);
+                }
+            }
         });
 
         // Keyboard navigation
@@ -1114,7 +1242,7 @@
         currentResultIndex = index;
         if (index >= 0 && index < results.length) {
             results[index].classList.add('keyboard-selected');
-            results[index].scrollIntoView({ behavior: 'smooth', block: 'center' });
+            results[index].scrollIntoView({ behavior: prefersReducedMotion() ? 'auto' : 'smooth', block: 'center' });
             // Announce for screen readers
             var title = results[index].querySelector('h3, .result-title, a')?.textContent || (t('accessibility.result_fallback', 'Result') + ' ' + (index + 1));
             announce(t('accessibility.selected_prefix', 'Selected') + ': ' + title);
@@ -1248,6 +1376,77 @@
         });
     }
 
+    // ========================================================================
+    // RESULTS MAP - maps category places on a Leaflet map
+    // ========================================================================
+    function initResultsMap() {
+        var container = document.getElementById('results-map');
+        if (!container) return;
+
+        // Leaflet is loaded with defer; wait for it when app.js runs first
+        if (typeof window.L === 'undefined') {
+            var script = document.querySelector('script[data-leaflet]');
+            if (script && !container.dataset.waiting) {
+                container.dataset.waiting = '1';
+                script.addEventListener('load', initResultsMap);
+                script.addEventListener('error', function() {
+                    container.classList.add('hidden');
+                });
+            }
+            return;
+        }
+
+        var places = [];
+        document.querySelectorAll('.map-result[data-lat][data-lon]').forEach(function(item) {
+            var lat = parseFloat(item.dataset.lat);
+            var lon = parseFloat(item.dataset.lon);
+            if (!isNaN(lat) && !isNaN(lon)) {
+                places.push({ item: item, lat: lat, lon: lon });
+            }
+        });
+        if (places.length === 0) {
+            container.classList.add('hidden');
+            return;
+        }
+        container.classList.remove('hidden');
+
+        var map = window.L.map(container, { scrollWheelZoom: false });
+        window.L.tileLayer(container.dataset.tiles, {
+            maxZoom: 19,
+            attribution: escapeHtml(container.dataset.attribution || '')
+        }).addTo(map);
+
+        var bounds = window.L.latLngBounds([]);
+        places.forEach(function(place) {
+            var link = place.item.querySelector('.result-title a');
+            var marker = window.L.marker([place.lat, place.lon]).addTo(map);
+            if (link) {
+                marker.bindPopup(escapeHtml(link.textContent.trim()));
+            }
+            bounds.extend([place.lat, place.lon]);
+
+            // Show on map: fit the place's bounding box, or zoom to its point
+            var button = place.item.querySelector('[data-map-focus]');
+            if (button) {
+                button.addEventListener('click', function() {
+                    var box = (place.item.dataset.bbox || '').split(',').map(parseFloat);
+                    if (box.length === 4 && box.every(function(v) { return !isNaN(v); })) {
+                        map.fitBounds([[box[0], box[2]], [box[1], box[3]]]);
+                    } else {
+                        map.setView([place.lat, place.lon], 16);
+                    }
+                    marker.openPopup();
+                    container.scrollIntoView({ behavior: 'smooth', block: 'nearest' });
+                });
+            }
+        });
+        if (places.length === 1) {
+            map.setView([places[0].lat, places[0].lon], 13);
+        } else {
+            map.fitBounds(bounds, { padding: [24, 24] });
+        }
+    }
+
     // ========================================================================
     // INFINITE SCROLL
     // ========================================================================
@@ -1273,6 +1472,15 @@
         var perPage = parseInt(container.dataset.perPage) || 20;
         var safeSearch = container.dataset.safeSearch || '1';
         var prefsParam = container.dataset.prefs || '';
+        var reportLinks = container.dataset.reportLinks === '1';
+        var imageColor = container.dataset.imageColor || '';
+        var imageLicense = container.dataset.imageLicense || '';
+        var reverseImage = container.dataset.reverseImage === '1';
+        var videoLength = container.dataset.videoLength || '';
+        var videoQuality = container.dataset.videoQuality || '';
+        var timeRange = container.dataset.timeRange || '';
+        var sortBy = container.dataset.sort || '';
+        var geoCountry = container.dataset.geoCountry || '';
         var isLoading = false;
         var hasMore = true;
 
@@ -1297,6 +1505,20 @@
             return count.toString();
         }
 
+        // Format a size in bytes with binary units (e.g., 700 MiB, 1.4 GiB)
+        function formatFileSize(size) {
+            if (!size || size <= 0) return '';
+            if (size < 1024) return size + ' B';
+            var units = ['KiB', 'MiB', 'GiB', 'TiB', 'PiB'];
+            var value = size / 1024;
+            var unit = 0;
+            while (value >= 1024 && unit < units.length - 1) {
+                value /= 1024;
+                unit++;
+            }
+            return (value >= 10 ? value.toFixed(0) : value.toFixed(1).replace(/\.0$/, '')) + ' ' + units[unit];
+        }
+
         // Escape HTML for safe insertion
         function escapeHtmlLocal(text) {
             if (!text) return '';
@@ -1305,21 +1527,36 @@
             return div.innerHTML;
         }
 
+        // Where a result links to: flagged results go through the warning page
+        function resultHref(result) {
+            return result.threat ? withBase('/warning?url=') + encodeURIComponent(result.url) : escapeHtmlLocal(result.url);
+        }
+
         // Create result card HTML based on category
         function createResultCard(result) {
             var firstLetter = result.title ? result.title.charAt(0).toUpperCase() : '?';
+            var threatBadge = result.threat ? ' <span class="result-threat">' + escapeHtmlLocal(t('screening.badge', 'Unsafe')) + '</span>' : '';
 
             if (category === 'images') {
+                // Hidden by the adult-content classifier: no image, no link
+                if (result.content_filter) {
+                    return '<div class="image-result image-filtered">' +
+                        '<div class="image-thumb-wrap"><span>' + escapeHtmlLocal(t('search.image_filtered', 'Hidden by the content filter')) + '</span></div>' +
+                        '<div class="image-result-info">' +
+                        (reportLinks ? '<a class="result-report" href="' + BASE_PATH + '/report?url=' + encodeURIComponent(result.url) + '&reason=misclassified" rel="nofollow">' + escapeHtmlLocal(t('search.image_filtered_report', 'Not adult content? Report it')) + '</a>' : '') +
+                        '</div></div>';
+                }
                 return '<div class="image-result" data-full-url="' + escapeHtmlLocal(result.url) + '">' +
-                    '<a href="' + escapeHtmlLocal(result.url) + '" target="_blank" rel="noopener noreferrer">' +
+                    '<a href="' + resultHref(result) + '" target="_blank" rel="noopener noreferrer">' +
                     (result.thumbnail
                         ? '<img src="' + escapeHtmlLocal(result.thumbnail) + '" alt="' + escapeHtmlLocal(result.title) + '" loading="lazy">'
                         : '<div class="image-placeholder"><span>\uD83D\uDDBC\uFE0F</span></div>'
                     ) +
                     '</a>' +
                     '<div class="image-result-info">' +
-                    '<a href="' + escapeHtmlLocal(result.url) + '" class="image-title" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a>' +
+                    '<a href="' + resultHref(result) + '" class="image-title" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a>' +
                     '<div class="image-source">' + escapeHtmlLocal(result.engine) + '</div>' +
+                    (reverseImage ? '<a class="image-reverse" href="' + BASE_PATH + '/search/image?url=' + encodeURIComponent(result.url) + '" rel="nofollow">' + escapeHtmlLocal(t('search.reverse_image_similar', 'Similar images')) + '</a>' : '') +
                     '</div></div>';
             }
 
@@ -1327,7 +1564,7 @@
                 var durationStr = result.duration ? formatDuration(result.duration) : '';
                 var viewCountStr = result.view_count ? formatViewCount(result.view_count) + ' views' : '';
                 return '<div class="video-result">' +
-                    '<a href="' + escapeHtmlLocal(result.url) + '" class="video-thumbnail-link" target="_blank" rel="noopener noreferrer">' +
+                    '<a href="' + resultHref(result) + '" class="video-thumbnail-link" target="_blank" rel="noopener noreferrer">' +
                     '<div class="video-thumbnail-container">' +
                     (result.thumbnail
                         ? '<img src="' + escapeHtmlLocal(result.thumbnail) + '" alt="' + escapeHtmlLocal(result.title) + '" loading="lazy" class="video-thumbnail">'
@@ -1337,32 +1574,81 @@
                     (durationStr ? '<span class="video-duration">' + escapeHtmlLocal(durationStr) + '</span>' : '') +
                     '</div></a>' +
                     '<div class="video-info">' +
-                    '<h3 class="video-title"><a href="' + escapeHtmlLocal(result.url) + '" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a></h3>' +
+                    '<h3 class="video-title"><a href="' + resultHref(result) + '" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a>' + threatBadge + '</h3>' +
                     '<div class="video-meta">' +
                     '<span class="video-engine">' + escapeHtmlLocal(result.engine) + '</span>' +
                     (viewCountStr ? '<span class="video-views">' + escapeHtmlLocal(viewCountStr) + '</span>' : '') +
                     (result.author ? '<span class="video-author">' + escapeHtmlLocal(result.author) + '</span>' : '') +
                     '</div>' +
                     (result.description ? '<p class="video-description">' + escapeHtmlLocal(result.description) + '</p>' : '') +
+                    (result.watch ? '<a class="video-watch" href="' + escapeHtmlLocal(result.watch) + '" rel="nofollow">' + escapeHtmlLocal(t('search.video_watch', 'Play here')) + '</a>' : '') +
                     '</div></div>';
             }
 
+            if (category === 'news') {
+                return '<article class="result-item news-result">' +
+                    (result.thumbnail
+                        ? '<a href="' + resultHref(result) + '" class="news-thumbnail-link" target="_blank" rel="noopener noreferrer"><img src="' + escapeHtmlLocal(result.thumbnail) + '" alt="" loading="lazy" class="news-thumbnail"></a>'
+                        : ''
+                    ) +
+                    '<div class="result-body">' +
+                    '<h3 class="result-title"><a href="' + resultHref(result) + '" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a>' + threatBadge + '</h3>' +
+                    '<div class="result-meta news-meta">' +
+                    (result.publisher ? '<span class="news-publisher">' + escapeHtmlLocal(result.publisher) + '</span>' : '') +
+                    (result.date ? '<span class="news-date">' + escapeHtmlLocal(result.date) + '</span>' : '') +
+                    '<span class="result-engine">' + escapeHtmlLocal(result.engine) + '</span>' +
+                    '</div>' +
+                    (result.description ? '<p class="result-description">' + escapeHtmlLocal(result.description) + '</p>' : '') +
+                    '</div></article>';
+            }
+
+            if (category === 'files') {
+                var file = result.file || {};
+                var magnet = file.magnet && file.magnet.indexOf('magnet:?') === 0 ? file.magnet : '';
+                var hasPeers = file.seeders || file.leechers || magnet;
+                return '<article class="result-item file-result">' +
+                    '<div class="result-body">' +
+                    '<h3 class="result-title"><a href="' + resultHref(result) + '" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a>' + threatBadge + '</h3>' +
+                    '<div class="result-meta file-meta">' +
+                    (file.size ? '<span class="file-size">' + escapeHtmlLocal(formatFileSize(file.size)) + '</span>' : '') +
+                    (hasPeers ? '<span class="file-seeders">' + escapeHtmlLocal(t('search.file_seeders', '%d seeders').replace('%d', file.seeders || 0)) + '</span>' +
+                        '<span class="file-leechers">' + escapeHtmlLocal(t('search.file_leechers', '%d leechers').replace('%d', file.leechers || 0)) + '</span>' : '') +
+                    (result.date ? '<span class="file-date">' + escapeHtmlLocal(result.date.slice(0, 10)) + '</span>' : '') +
+                    '<span class="result-engine">' + escapeHtmlLocal(result.engine) + '</span>' +
+                    '</div>' +
+                    (result.description ? '<p class="result-description">' + escapeHtmlLocal(result.description) + '</p>' : '') +
+                    (!result.threat && (magnet || file.torrent) ? '<div class="file-links">' +
+                        (magnet ? '<a class="file-magnet" href="' + escapeHtmlLocal(magnet) + '" rel="nofollow noopener">\uD83E\uDDF2 ' + escapeHtmlLocal(t('search.file_magnet', 'Magnet link')) + '</a>' : '') +
+                        (file.torrent ? '<a class="file-torrent" href="' + escapeHtmlLocal(file.torrent) + '" rel="nofollow noopener noreferrer">' + escapeHtmlLocal(t('search.file_torrent', 'Torrent file')) + '</a>' : '') +
+                        '</div>' : '') +
+                    '</div></article>';
+            }
+
             // Standard results
             return '<article class="result-item">' +
                 '<div class="result-favicon">' +
-                '<img src="/api/v1/favicon?url=' + encodeURIComponent(result.url) + '" alt="" loading="lazy" data-favicon-fallback>' +
+                '<img src="' + BASE_PATH + '/api/v1/favicon?url=' + encodeURIComponent(result.url) + '" alt="" loading="lazy" data-favicon-fallback>' +
                 '<span class="favicon-placeholder hidden">' + escapeHtmlLocal(firstLetter) + '</span>' +
                 '</div>' +
                 '<div class="result-body">' +
-                '<h3 class="result-title"><a href="' + escapeHtmlLocal(result.url) + '" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a></h3>' +
+                '<h3 class="result-title"><a href="' + resultHref(result) + '" target="_blank" rel="noopener noreferrer">' + escapeHtmlLocal(result.title) + '</a>' + threatBadge + '</h3>' +
                 '<div class="result-url"><span class="result-url-text">' + escapeHtmlLocal(result.url) + '</span></div>' +
                 '<p class="result-description">' + escapeHtmlLocal(result.description || '') + '</p>' +
                 '<div class="result-meta">' +
                 '<span class="result-engine">' + escapeHtmlLocal(result.engine) + '</span>' +
+                (result.localized ? localizedBadge(result.localized) : '') +
                 (result.date ? '<span class="result-date">' + escapeHtmlLocal(result.date) + '</span>' : '') +
+                (reportLinks ? '<a class="result-report" href="' + BASE_PATH + '/report?url=' + encodeURIComponent(result.url) + '&title=' + encodeURIComponent(result.title || '') + '" rel="nofollow">' + escapeHtmlLocal(t('report.link', 'Report')) + '</a>' : '') +
                 '</div></div></article>';
         }
 
+        // localizedBadge explains why geo boosting ranked a result higher
+        function localizedBadge(reason) {
+            var why = escapeHtmlLocal(t('search.localized_' + reason, '').replace('%s', geoCountry));
+            return '<span class="result-localized" title="' + why + '">' + escapeHtmlLocal(t('search.localized', 'Localized')) +
+                '<span class="sr-only">: ' + why + '</span></span>';
+        }
+
         function loadMoreResults() {
             if (isLoading || !hasMore) return;
 
@@ -1370,7 +1656,7 @@
             if (loadingIndicator) loadingIndicator.classList.remove('hidden');
 
             var nextPage = currentPage + 1;
-            var apiURL = '/api/v1/search?q=' + encodeURIComponent(query) +
+            var apiURL = withBase('/api/v1/search?q=') + encodeURIComponent(query) +
                 '&category=' + encodeURIComponent(category) +
                 '&page=' + nextPage +
                 '&limit=' + perPage +
@@ -1378,6 +1664,27 @@
             if (prefsParam) {
                 apiURL += '&prefs=' + encodeURIComponent(prefsParam);
             }
+            if (imageColor) {
+                apiURL += '&image_color=' + encodeURIComponent(imageColor);
+            }
+            if (imageLicense) {
+                apiURL += '&image_license=' + encodeURIComponent(imageLicense);
+            }
+            if (videoLength) {
+                apiURL += '&video_length=' + encodeURIComponent(videoLength);
+            }
+            if (videoQuality) {
+                apiURL += '&video_quality=' + encodeURIComponent(videoQuality);
+            }
+            if (timeRange) {
+                apiURL += '&time_range=' + encodeURIComponent(timeRange);
+            }
+            if (sortBy) {
+                apiURL += '&sort=' + encodeURIComponent(sortBy);
+            }
+            if (!getActiveSearchPreferences().localize) {
+                apiURL += '&localize=0';
+            }
             fetch(apiURL)
                 .then(function(response) { return response.json(); })
                 .then(function(data) {
@@ -1711,14 +2018,79 @@
     // ========================================================================
     // SERVICE WORKER
     // ========================================================================
+    // Cache names shared with sw.js
+    var OFFLINE_SETTINGS_CACHE = 'search-settings';
+    var OFFLINE_SEARCHES_FLAG = '/__offline-searches';
+    var OFFLINE_DATA_CACHES = ['search-recent', 'search-widgets'];
+
     function initServiceWorker() {
         if ('serviceWorker' in navigator) {
             window.addEventListener('load', function() {
-                navigator.serviceWorker.register('/sw.js').catch(function() {
+                navigator.serviceWorker.register(withBase('/sw.js'), { scope: withBase('/') }).catch(function() {
                     // Service worker registration failed
                 });
             });
         }
+        initOfflineData();
+    }
+
+    // Offline data lives only in this browser's Cache Storage. The service
+    // worker cannot read localStorage, so the opt-in flag is a cache entry.
+    function initOfflineData() {
+        if (!('caches' in window)) return;
+
+        var toggle = document.getElementById('offline-searches');
+        if (toggle) {
+            caches.open(OFFLINE_SETTINGS_CACHE).then(function(cache) {
+                return cache.match(OFFLINE_SEARCHES_FLAG);
+            }).then(function(flag) {
+                toggle.checked = Boolean(flag);
+            });
+            toggle.addEventListener('change', function() {
+                caches.open(OFFLINE_SETTINGS_CACHE).then(function(cache) {
+                    if (toggle.checked) {
+                        return cache.put(OFFLINE_SEARCHES_FLAG, new Response('1'));
+                    }
+                    // Turning the option off also forgets saved searches
+                    return cache.delete(OFFLINE_SEARCHES_FLAG).then(function() {
+                        return caches.delete('search-recent');
+                    });
+                });
+            });
+        }
+
+        var clearButton = document.getElementById('clear-offline-data');
+        if (clearButton) {
+            clearButton.addEventListener('click', function() {
+                Promise.all(OFFLINE_DATA_CACHES.map(function(name) {
+                    return caches.delete(name);
+                })).then(function() {
+                    showToast(t('preferences.offline_data_cleared', 'Offline data cleared'), 'success');
+                });
+            });
+        }
+
+        // Offline page: list results pages saved on this device
+        var recent = document.getElementById('offline-recent');
+        var recentList = document.getElementById('offline-recent-list');
+        if (recent && recentList) {
+            caches.open('search-recent').then(function(cache) {
+                return cache.keys();
+            }).then(function(requests) {
+                requests.reverse().forEach(function(request) {
+                    var url = new URL(request.url);
+                    var query = url.searchParams.get('q');
+                    if (!query) return;
+                    var item = document.createElement('li');
+                    var link = document.createElement('a');
+                    link.href = url.pathname + url.search;
+                    link.textContent = query;
+                    item.appendChild(link);
+                    recentList.appendChild(item);
+                });
+                recent.hidden = recentList.children.length === 0;
+            });
+        }
     }
 
     // ========================================================================
@@ -1773,6 +2145,30 @@
                 return;
             }
 
+            // Regenerate link in password/passphrase answers: swap in a fresh
+            // answer from the instant API; the href reloads the page without JS
+            if (target.closest('.instant-regenerate')) {
+                const link = target.closest('.instant-regenerate');
+                const box = link.closest('.instant-answer-content');
+                if (box && link.dataset.regenerate) {
+                    e.preventDefault();
+                    fetch(window.location.origin + withBase('/api/v1/instant?q=') + encodeURIComponent(link.dataset.regenerate))
+                        .then(function(response) {
+                            if (!response.ok) throw new Error('HTTP ' + response.status);
+                            return response.json();
+                        })
+                        .then(function(data) {
+                            if (data.ok && data.data && data.data.found) {
+                                box.innerHTML = data.data.content.replace(/(href|src|action)="\/(?![\/\\])/g, '$1="' + BASE_PATH + '/');
+                            }
+                        })
+                        .catch(function() {
+                            window.location.href = link.href;
+                        });
+                }
+                return;
+            }
+
             // Copy button with data-copy attribute - per AI.md PART 16
             if (target.matches('.copy-btn') || target.closest('.copy-btn')) {
                 const btn = target.closest('.copy-btn') || target;
@@ -2060,6 +2456,8 @@
                 var newTabCheckbox = document.getElementById('new-tab');
                 var infiniteScrollCheckbox = document.getElementById('infinite-scroll');
                 var keyboardShortcutsCheckbox = document.getElementById('keyboard-shortcuts');
+                var searchPreviewCheckbox = document.getElementById('search-preview');
+                var localizeCheckbox = document.getElementById('localize');
 
                 // Theme is stored in the 'theme' cookie (not localStorage)
                 if (themeSelect) themeSelect.value = getPreferredTheme();
@@ -2069,6 +2467,8 @@
                 if (prefs.new_tab && newTabCheckbox) newTabCheckbox.checked = prefs.new_tab;
                 if (infiniteScrollCheckbox) infiniteScrollCheckbox.checked = !!prefs.infinite_scroll;
                 if (keyboardShortcutsCheckbox) keyboardShortcutsCheckbox.checked = prefs.keyboard_shortcuts !== false;
+                if (searchPreviewCheckbox) searchPreviewCheckbox.checked = !!prefs.search_preview;
+                if (localizeCheckbox) localizeCheckbox.checked = prefs.localize !== false;
             } catch (e) {
                 console.error('Failed to load preferences:', e);
             }
@@ -2083,6 +2483,8 @@
             var newTabCheckbox = document.getElementById('new-tab');
             var infiniteScrollCheckbox = document.getElementById('infinite-scroll');
             var keyboardShortcutsCheckbox = document.getElementById('keyboard-shortcuts');
+            var searchPreviewCheckbox = document.getElementById('search-preview');
+            var localizeCheckbox = document.getElementById('localize');
 
             var prefs = {
                 theme: themeSelect ? normalizeThemePreference(themeSelect.value) : 'auto',
@@ -2091,11 +2493,13 @@
                 results_per_page: resultsPerPageSelect ? resultsPerPageSelect.value : '20',
                 new_tab: newTabCheckbox ? newTabCheckbox.checked : false,
                 infinite_scroll: infiniteScrollCheckbox ? infiniteScrollCheckbox.checked : false,
-                keyboard_shortcuts: keyboardShortcutsCheckbox ? keyboardShortcutsCheckbox.checked : true
+                keyboard_shortcuts: keyboardShortcutsCheckbox ? keyboardShortcutsCheckbox.checked : true,
+                search_preview: searchPreviewCheckbox ? searchPreviewCheckbox.checked : false,
+                localize: localizeCheckbox ? localizeCheckbox.checked : true
             };
 
             localStorage.setItem(PREFS_KEY, JSON.stringify(prefs));
-            document.cookie = 'theme=' + encodeURIComponent(prefs.theme) + '; path=/; max-age=31536000; SameSite=Lax';
+            document.cookie = 'theme=' + encodeURIComponent(prefs.theme) + '; path=' + COOKIE_PATH + '; max-age=31536000; SameSite=Lax';
 
             applyTheme(prefs.theme);
 
@@ -2187,14 +2591,14 @@
         function updatePreferenceSharing() {
             var prefs = normalizeSearchPreferences(JSON.parse(localStorage.getItem(PREFS_KEY) || '{}'));
             var prefString = encodePreferenceString(prefs);
-            var prefURL = window.location.origin + '/?prefs=' + encodeURIComponent(prefString);
+            var prefURL = window.location.origin + withBase('/?prefs=') + encodeURIComponent(prefString);
             var prefStringInput = document.getElementById('preference-string');
             var prefURLInput = document.getElementById('preference-url');
             var qrLink = document.getElementById('qr-pref-link');
 
             if (prefStringInput) prefStringInput.value = prefString;
             if (prefURLInput) prefURLInput.value = prefURL;
-            if (qrLink) qrLink.href = '/direct/qr/' + encodeURIComponent(prefURL);
+            if (qrLink) qrLink.href = withBase('/direct/qr/') + encodeURIComponent(prefURL);
         }
 
         // Event handlers via delegation
@@ -2227,7 +2631,7 @@
             // Copy OpenSearch URL
             if (e.target.id === 'copy-opensearch') {
                 var customName = document.getElementById('custom-engine-name');
-                var url = window.location.origin + '/opensearch.xml';
+                var url = window.location.origin + withBase('/opensearch.xml');
                 if (customName && customName.value) {
                     url += '?name=' + encodeURIComponent(customName.value);
                 }
@@ -2241,6 +2645,21 @@
                 return;
             }
 
+            // Register via the Web Search Provider API (shown only where supported)
+            if (e.target.id === 'add-search-provider') {
+                var providerName = document.getElementById('custom-engine-name');
+                var providerUrl = window.location.origin + withBase('/opensearch.xml');
+                if (providerName && providerName.value) {
+                    providerUrl += '?name=' + encodeURIComponent(providerName.value);
+                }
+                try {
+                    window.external.AddSearchProvider(providerUrl);
+                } catch (err) {
+                    showPrefStatus(t('preferences.opensearch_copy_manual', 'URL updated above - copy manually'));
+                }
+                return;
+            }
+
             // Export preferences (widgets are server-side cookie — not included in export)
             if (e.target.id === 'export-prefs') {
                 var data = {
@@ -2372,13 +2791,41 @@
             });
         }
 
+        // Onion redirect is a cookie the server reads, so it applies at once.
+        // It is meaningless on the onion itself, where the option is hidden.
+        var onionRedirect = document.getElementById('onion-redirect');
+        if (onionRedirect) {
+            if (/\.onion$/i.test(window.location.hostname)) {
+                var onionGroup = document.getElementById('onion-redirect-group');
+                var onionNote = document.getElementById('onion-redirect-note');
+                if (onionGroup) onionGroup.hidden = true;
+                if (onionNote) onionNote.hidden = true;
+            }
+            onionRedirect.checked = /(?:^|;\s*)onion_redirect=1/.test(document.cookie);
+            onionRedirect.addEventListener('change', function() {
+                if (onionRedirect.checked) {
+                    setCookie('onion_redirect', '1', 31536000);
+                } else {
+                    clearCookie('onion_redirect');
+                }
+                showPrefStatus(t('preferences.saved', 'Preferences saved'));
+            });
+        }
+
+        // Offer one-click registration where the browser still implements
+        // window.external.AddSearchProvider
+        var addProviderBtn = document.getElementById('add-search-provider');
+        if (addProviderBtn && window.external && typeof window.external.AddSearchProvider === 'function') {
+            addProviderBtn.hidden = false;
+        }
+
         // OpenSearch URL update on custom name change
         var customEngineInput = document.getElementById('custom-engine-name');
         if (customEngineInput) {
             customEngineInput.addEventListener('input', function() {
                 var osUrl = document.getElementById('opensearch-url');
                 if (!osUrl) return;
-                var url = window.location.origin + '/opensearch.xml';
+                var url = window.location.origin + withBase('/opensearch.xml');
                 if (this.value) {
                     url += '?name=' + encodeURIComponent(this.value);
                 }
@@ -2548,7 +2995,7 @@
                     ids.push(announcementId);
                 }
                 document.cookie = 'dismissed_announcements=' + encodeURIComponent(ids.join(',')) +
-                    '; path=/; max-age=31536000; SameSite=Lax';
+                    '; path=' + COOKIE_PATH + '; max-age=31536000; SameSite=Lax';
                 banner.remove();
             });
         });
@@ -2657,6 +3104,7 @@
         initKeyboardShortcuts();
         initLazyLoading();
         initImageViewer();
+        initResultsMap();
         initInfiniteScroll();
         initFormProtection();
         initServiceWorker();
@@ -2924,6 +3372,30 @@
             icon: 'palette',
             category: 'tool',
             render: renderColorPickerWidget
+        },
+        flight: {
+            type: 'flight',
+            name: 'Flight Status',
+            icon: 'plane',
+            category: 'data',
+            refreshInterval: 60000,
+            render: renderFlightWidget
+        },
+        transit: {
+            type: 'transit',
+            name: 'Transit Departures',
+            icon: 'bus',
+            category: 'data',
+            refreshInterval: 30000,
+            render: renderTransitWidget
+        },
+        ticker: {
+            type: 'ticker',
+            name: 'Market Ticker',
+            icon: 'chart-area',
+            category: 'data',
+            refreshInterval: 300000,
+            render: renderTickerWidget
         }
     };
 
@@ -2950,7 +3422,7 @@
         widgets.forEach(function(wt) {
             params.append('widget', wt);
         });
-        fetch('/preferences/widgets', {
+        fetch(withBase('/preferences/widgets'), {
             method: 'POST',
             headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
             body: params.toString(),
@@ -2977,7 +3449,7 @@
     async function fetchWidgetData(widgetType, params) {
         params = params || {};
         const queryString = new URLSearchParams(params).toString();
-        const url = '/api/v1/widgets/' + widgetType + (queryString ? '?' + queryString : '');
+        const url = withBase('/api/v1/widgets/') + widgetType + (queryString ? '?' + queryString : '');
 
         try {
             const response = await fetch(url);
@@ -3242,6 +3714,149 @@
         container.innerHTML = html;
     }
 
+    function renderFlightWidget(container, data, settings) {
+        var flightEmpty = escapeHtml(t('widgets_ui.flight_empty', 'Enter a flight number in widget settings'));
+        var configureLabel = escapeHtml(t('widgets_ui.configure', 'Configure'));
+        if (!settings.flight || !data || data.error) {
+            container.innerHTML =
+                '<div class="widget-placeholder">' +
+                    '<div class="widget-placeholder-text">' + (data && data.error ? escapeHtml(data.error) : flightEmpty) + '</div>' +
+                    '<button class="widget-settings-btn" data-widget-settings="flight">' + configureLabel + '</button>' +
+                '</div>';
+            return;
+        }
+
+        var statusLabels = {
+            airborne: t('widgets_ui.flight_status_airborne', 'In the air'),
+            on_ground: t('widgets_ui.flight_status_on_ground', 'On the ground'),
+            not_seen: t('widgets_ui.flight_status_not_seen', 'Not currently tracked')
+        };
+        var status = statusLabels[data.status] || data.status;
+
+        var html = '<div class="flight-widget">' +
+            '<div class="flight-header">' +
+                '<span class="flight-number">' + escapeHtml(data.flight || data.callsign) + '</span>' +
+                (data.airline ? '<span class="flight-airline">' + escapeHtml(data.airline) + '</span>' : '') +
+            '</div>' +
+            '<div class="flight-status flight-status-' + escapeHtml(data.status) + '">' + escapeHtml(status) + '</div>';
+
+        if (data.departure && data.arrival) {
+            html += '<div class="flight-route">' +
+                '<span title="' + escapeHtml(data.departure.airport) + '">' + escapeHtml(data.departure.iata || data.departure.airport) + '</span>' +
+                ' &#8594; ' +
+                '<span title="' + escapeHtml(data.arrival.airport) + '">' + escapeHtml(data.arrival.iata || data.arrival.airport) + '</span>' +
+            '</div>';
+            if (data.departure.delay_minutes) {
+                html += '<div class="flight-delay">' + escapeHtml(t('widgets_ui.flight_delay', 'Delayed %s min').replace('%s', data.departure.delay_minutes)) + '</div>';
+            }
+        }
+
+        if (data.position && !data.position.on_ground) {
+            html += '<div class="flight-position">' +
+                '<span>' + data.position.altitude_ft.toLocaleString() + ' ft</span>' +
+                '<span>' + Math.round(data.position.ground_speed_kt) + ' kt</span>' +
+            '</div>';
+        }
+
+        if (data.track_url) {
+            html += '<a href="' + escapeHtml(data.track_url) + '" class="flight-track" target="_blank" rel="noopener">' + escapeHtml(t('widgets_ui.flight_track', 'Live map')) + '</a>';
+        }
+
+        html += '</div>';
+        container.innerHTML = html;
+    }
+
+    function renderTransitWidget(container, data, settings) {
+        var configureLabel = escapeHtml(t('widgets_ui.configure', 'Configure'));
+        if (!data || data.error) {
+            container.innerHTML =
+                '<div class="widget-placeholder">' +
+                    '<div class="widget-placeholder-text">' + escapeHtml(data && data.error ? data.error : t('widgets_ui.transit_unavailable', 'Transit departures are not available')) + '</div>' +
+                '</div>';
+            return;
+        }
+
+        // No stop selected yet: offer the operator-configured stops
+        if (data.available) {
+            var html = '<div class="transit-widget">' +
+                '<div class="transit-choose">' + escapeHtml(t('widgets_ui.transit_choose_stop', 'Choose a stop')) + '</div>';
+            data.available.forEach(function(feed) {
+                html += '<div class="transit-feed-name">' + escapeHtml(feed.name || feed.id) + '</div>';
+                (feed.stops || []).forEach(function(stop) {
+                    html += '<button class="transit-stop-btn" data-transit-feed="' + escapeHtml(feed.id) + '" data-transit-stop="' + escapeHtml(stop.id) + '">' + escapeHtml(stop.name || stop.id) + '</button>';
+                });
+            });
+            html += '<button class="widget-settings-btn" data-widget-settings="transit">' + configureLabel + '</button></div>';
+            container.innerHTML = html;
+            return;
+        }
+
+        var html = '<div class="transit-widget">' +
+            '<div class="transit-stop-name">' + escapeHtml(data.stop_name || data.stop) + '</div>';
+        if (!data.departures || data.departures.length === 0) {
+            html += '<div class="transit-empty">' + escapeHtml(t('widgets_ui.transit_empty', 'No upcoming departures')) + '</div>';
+        } else {
+            var nowLabel = t('widgets_ui.transit_now', 'Now');
+            var minLabel = t('widgets_ui.transit_minutes', '%s min');
+            html += '<ul class="transit-departures">';
+            data.departures.forEach(function(dep) {
+                var when = dep.minutes_away <= 0 ? nowLabel : minLabel.replace('%s', dep.minutes_away);
+                var delayClass = dep.delay_seconds >= 60 ? ' late' : (dep.delay_seconds <= -60 ? ' early' : '');
+                html += '<li class="transit-departure">' +
+                    '<span class="transit-route">' + escapeHtml(dep.route) + '</span>' +
+                    '<span class="transit-time' + delayClass + '" title="' + escapeHtml(new Date(dep.time).toLocaleTimeString()) + '">' + escapeHtml(when) + '</span>' +
+                '</li>';
+            });
+            html += '</ul>';
+        }
+        html += '</div>';
+        container.innerHTML = html;
+    }
+
+    // sparklineSvg draws a price series as an inline SVG polyline
+    function sparklineSvg(points) {
+        if (!points || points.length < 2) return '';
+        var lo = Math.min.apply(null, points);
+        var hi = Math.max.apply(null, points);
+        var span = (hi - lo) || 1;
+        var coords = points.map(function(p, i) {
+            var x = (i / (points.length - 1) * 100).toFixed(1);
+            var y = (20 - (p - lo) / span * 20).toFixed(1);
+            return x + ',' + y;
+        }).join(' ');
+        var direction = points[points.length - 1] < points[0] ? 'negative' : 'positive';
+        return '<svg class="ticker-sparkline ' + direction + '" viewBox="0 0 100 20" preserveAspectRatio="none" aria-hidden="true">' +
+            '<polyline fill="none" stroke="currentColor" stroke-width="1.5" points="' + coords + '"/></svg>';
+    }
+
+    function renderTickerWidget(container, data, settings) {
+        var tickerEmpty = escapeHtml(t('widgets_ui.ticker_empty', 'Market data is not available'));
+        var configureLabel = escapeHtml(t('widgets_ui.configure', 'Configure'));
+        if (!data || data.error || !data.quotes || data.quotes.length === 0) {
+            container.innerHTML =
+                '<div class="widget-placeholder">' +
+                    '<div class="widget-placeholder-text">' + tickerEmpty + '</div>' +
+                    '<button class="widget-settings-btn" data-widget-settings="ticker">' + configureLabel + '</button>' +
+                '</div>';
+            return;
+        }
+
+        var html = '<div class="ticker-widget">';
+        data.quotes.forEach(function(quote) {
+            var changeClass = quote.change >= 0 ? 'positive' : 'negative';
+            var changeSign = quote.change >= 0 ? '+' : '';
+            var digits = Math.abs(quote.price) < 1 ? 4 : 2;
+            html += '<a class="ticker-item" href="' + BASE_PATH + '/search?q=' + encodeURIComponent(quote.symbol + (quote.kind === 'crypto' ? ' price' : ' stock')) + '" title="' + escapeHtml(quote.name) + '">' +
+                '<span class="ticker-symbol">' + escapeHtml(quote.symbol) + '</span>' +
+                sparklineSvg(quote.sparkline) +
+                '<span class="ticker-price">' + quote.price.toLocaleString(undefined, {minimumFractionDigits: digits, maximumFractionDigits: digits}) + ' ' + escapeHtml(quote.currency) + '</span>' +
+                '<span class="ticker-change ' + changeClass + '">' + changeSign + quote.change_percent.toFixed(2) + '%</span>' +
+            '</a>';
+        });
+        html += '</div>';
+        container.innerHTML = html;
+    }
+
     function renderCryptoWidget(container, data) {
         var cryptoLoading = escapeHtml(t('widgets_ui.crypto_loading', 'Loading crypto prices...'));
         if (!data || !data.coins || data.coins.length === 0) {
@@ -3353,20 +3968,45 @@
             '</div>';
     }
 
+    // resolveTimeZone maps a city name ("Tokyo", "new york") or IANA name
+    // ("Asia/Tokyo") to an IANA time zone supported by this browser.
+    function resolveTimeZone(name) {
+        var wanted = name.trim().replace(/\s+/g, '_').toLowerCase();
+        if (!wanted) return '';
+        var zones = typeof Intl.supportedValuesOf === 'function' ? Intl.supportedValuesOf('timeZone') : [];
+        for (var i = 0; i < zones.length; i++) {
+            if (zones[i].toLowerCase() === wanted) return zones[i];
+        }
+        for (var j = 0; j < zones.length; j++) {
+            if (zones[j].split('/').pop().toLowerCase() === wanted) return zones[j];
+        }
+        // Older browsers without supportedValuesOf: accept anything Intl can format
+        try {
+            return new Intl.DateTimeFormat('en-US', { timeZone: name.trim() }).resolvedOptions().timeZone;
+        } catch (e) {
+            return '';
+        }
+    }
+
     function renderTimezoneWidget(container, data, settings) {
         var timezones = settings.timezones || ['America/New_York', 'Europe/London', 'Asia/Tokyo'];
+        var format = getWidgetSettings('clock').format || '24h';
 
         function update() {
             var html = '<div class="timezone-widget">';
             timezones.forEach(function(tz) {
                 var now = new Date();
-                var timeStr = now.toLocaleTimeString('en-US', { timeZone: tz, hour: '2-digit', minute: '2-digit', hour12: true });
-                var cityName = tz.split('/')[1].replace(/_/g, ' ');
+                var timeStr = now.toLocaleTimeString(undefined, { timeZone: tz, hour: '2-digit', minute: '2-digit', hour12: format === '12h' });
+                var dayStr = now.toLocaleDateString(undefined, { timeZone: tz, weekday: 'short' });
+                var cityName = tz.split('/').pop().replace(/_/g, ' ');
                 html += '<div class="timezone-item">' +
-                    '<span class="timezone-city">' + cityName + '</span>' +
-                    '<span class="timezone-time">' + timeStr + '</span>' +
+                    '<span class="timezone-city">' + escapeHtml(cityName) + '</span>' +
+                    '<span class="timezone-time">' + escapeHtml(timeStr) + ' <small>' + escapeHtml(dayStr) + '</small></span>' +
                 '</div>';
             });
+            (settings.unresolved || []).forEach(function(name) {
+                html += '<p class="setting-help">' + escapeHtml(t('widgets_ui.settings_timezone_unknown', 'Unknown city or time zone: %s').replace('%s', name)) + '</p>';
+            });
             html += '</div>';
             container.innerHTML = html;
         }
@@ -3730,6 +4370,16 @@
                     return;
                 }
 
+                // Transit stop chooser
+                if (target.matches('[data-transit-stop]')) {
+                    var transitSettings = getWidgetSettings('transit');
+                    transitSettings.feed = target.dataset.transitFeed;
+                    transitSettings.stop = target.dataset.transitStop;
+                    saveWidgetSettings('transit', transitSettings);
+                    self.initWidget('transit');
+                    return;
+                }
+
                 // Widget menu button
                 if (target.matches('.widget-menu')) {
                     var widgetType = target.closest('[data-widget]').dataset.widget;
@@ -4014,6 +4664,12 @@
                     return t('widgets_ui.widget_name_crypto', WIDGETS[widgetType]?.name || widgetType);
                 case 'rss':
                     return t('widgets_ui.widget_name_rss', WIDGETS[widgetType]?.name || widgetType);
+                case 'flight':
+                    return t('widgets_ui.widget_name_flight', WIDGETS[widgetType]?.name || widgetType);
+                case 'transit':
+                    return t('widgets_ui.widget_name_transit', WIDGETS[widgetType]?.name || widgetType);
+                case 'ticker':
+                    return t('widgets_ui.widget_name_ticker', WIDGETS[widgetType]?.name || widgetType);
                 default:
                     return WIDGETS[widgetType]?.name || widgetType;
             }
@@ -4107,6 +4763,14 @@
                             '<option value="12h"' + (settings.format === '12h' ? ' selected' : '') + '>' + format12h + '</option>' +
                         '</select></label>';
                     break;
+                case 'timezone':
+                    var tzLabel = escapeHtml(t('widgets_ui.settings_timezone_cities_label', 'Cities or time zones (comma-separated)'));
+                    var tzPlaceholder = escapeHtml(t('widgets_ui.settings_timezone_cities_placeholder', 'e.g., Tokyo, London, America/New_York'));
+                    var tzCities = settings.cities || (settings.timezones || ['America/New_York', 'Europe/London', 'Asia/Tokyo']).map(function(tz) {
+                        return tz.split('/').pop().replace(/_/g, ' ');
+                    });
+                    content = '<label>' + tzLabel + ':<input type="text" id="setting-timezones" value="' + escapeHtml(tzCities.join(', ')) + '" placeholder="' + tzPlaceholder + '"></label>';
+                    break;
                 case 'stocks':
                     var stocksLabel = escapeHtml(t('widgets_ui.settings_stocks_symbols_label', 'Stock Symbols (comma-separated)'));
                     var stocksPlaceholder = escapeHtml(t('widgets_ui.settings_stocks_symbols_placeholder', 'e.g., AAPL, GOOGL, MSFT'));
@@ -4117,6 +4781,28 @@
                     var cryptoPlaceholder = escapeHtml(t('widgets_ui.settings_crypto_coins_placeholder', 'e.g., bitcoin, ethereum'));
                     content = '<label>' + cryptoLabel + ':<input type="text" id="setting-coins" value="' + escapeHtml((settings.coins || ['bitcoin', 'ethereum']).join(', ')) + '" placeholder="' + cryptoPlaceholder + '"></label>';
                     break;
+                case 'ticker':
+                    var tickerLabel = escapeHtml(t('widgets_ui.settings_ticker_symbols_label', 'Symbols (comma-separated)'));
+                    var tickerPlaceholder = escapeHtml(t('widgets_ui.settings_ticker_symbols_placeholder', 'e.g., AAPL, MSFT, BTC, ETH'));
+                    var tickerHint = escapeHtml(t('widgets_ui.settings_ticker_hint', 'Leave empty to use the symbols configured on this server'));
+                    content =
+                        '<label>' + tickerLabel + ':<input type="text" id="setting-ticker-symbols" value="' + escapeHtml(settings.symbols || '') + '" placeholder="' + tickerPlaceholder + '"></label>' +
+                        '<p class="setting-help">' + tickerHint + '</p>';
+                    break;
+                case 'flight':
+                    var flightLabel = escapeHtml(t('widgets_ui.settings_flight_number_label', 'Flight number or callsign'));
+                    var flightPlaceholder = escapeHtml(t('widgets_ui.settings_flight_number_placeholder', 'e.g., UA123 or BAW117'));
+                    content = '<label>' + flightLabel + ':<input type="text" id="setting-flight" value="' + escapeHtml(settings.flight || '') + '" placeholder="' + flightPlaceholder + '"></label>';
+                    break;
+                case 'transit':
+                    var transitFeedLabel = escapeHtml(t('widgets_ui.settings_transit_feed_label', 'Feed ID'));
+                    var transitStopLabel = escapeHtml(t('widgets_ui.settings_transit_stop_label', 'Stop ID'));
+                    var transitHint = escapeHtml(t('widgets_ui.settings_transit_hint', 'Leave the stop empty to pick from the stops configured on this server'));
+                    content =
+                        '<label>' + transitFeedLabel + ':<input type="text" id="setting-transit-feed" value="' + escapeHtml(settings.feed || '') + '"></label>' +
+                        '<label>' + transitStopLabel + ':<input type="text" id="setting-transit-stop" value="' + escapeHtml(settings.stop || '') + '"></label>' +
+                        '<p class="setting-help">' + transitHint + '</p>';
+                    break;
                 case 'rss':
                     var rssLabel = escapeHtml(t('widgets_ui.settings_rss_feeds_label', 'RSS Feed URLs (one per line)'));
                     var rssPlaceholder = escapeHtml(t('widgets_ui.settings_rss_feeds_placeholder', 'https://example.com/feed.xml'));
@@ -4243,6 +4929,20 @@
                 case 'clock':
                     settings.format = document.getElementById('setting-format')?.value || '24h';
                     break;
+                case 'timezone':
+                    var citiesStr = document.getElementById('setting-timezones')?.value || '';
+                    settings.cities = citiesStr.split(',').map(function(s) { return s.trim(); }).filter(function(s) { return s; });
+                    settings.timezones = [];
+                    settings.unresolved = [];
+                    settings.cities.forEach(function(city) {
+                        var tz = resolveTimeZone(city);
+                        if (tz) {
+                            settings.timezones.push(tz);
+                        } else {
+                            settings.unresolved.push(city);
+                        }
+                    });
+                    break;
                 case 'stocks':
                     var symbolsStr = document.getElementById('setting-symbols')?.value || '';
                     settings.symbols = symbolsStr.split(',').map(function(s) { return s.trim().toUpperCase(); }).filter(function(s) { return s; });
@@ -4251,6 +4951,18 @@
                     var coinsStr = document.getElementById('setting-coins')?.value || '';
                     settings.coins = coinsStr.split(',').map(function(s) { return s.trim().toLowerCase(); }).filter(function(s) { return s; });
                     break;
+                case 'ticker':
+                    var tickerStr = document.getElementById('setting-ticker-symbols')?.value || '';
+                    // Stored as a string: settings are sent to the API as query params
+                    settings.symbols = tickerStr.split(',').map(function(s) { return s.trim().toUpperCase(); }).filter(function(s) { return s; }).join(',');
+                    break;
+                case 'flight':
+                    settings.flight = (document.getElementById('setting-flight')?.value || '').trim().toUpperCase();
+                    break;
+                case 'transit':
+                    settings.feed = (document.getElementById('setting-transit-feed')?.value || '').trim();
+                    settings.stop = (document.getElementById('setting-transit-stop')?.value || '').trim();
+                    break;
                 case 'rss':
                     var feedsStr = document.getElementById('setting-feeds')?.value || '';
                     settings.feeds = feedsStr.split('\n').map(function(s) { return s.trim(); }).filter(function(s) { return s; });
@@ -4668,6 +5380,7 @@
     var activePreview = null;
     var previewContainer = null;
     var isTouchDevice = 'ontouchstart' in window;
+    var reducedMotionQuery = window.matchMedia('(prefers-reduced-motion: reduce)');
 
     // Initialize video preview functionality
     function initVideoPreview() {
@@ -4720,6 +5433,9 @@
     }
 
     function showVideoPreview(videoResult) {
+        // Autoplaying previews are motion the user asked not to see
+        if (reducedMotionQuery.matches) return;
+
         var videoId = videoResult.dataset.videoId;
         var previewUrl = videoResult.dataset.previewUrl;
         var videoSource = videoResult.dataset.videoSource || 'youtube';
@@ -5161,7 +5877,7 @@
         if (!query) return;
 
         var region = document.getElementById('adv-region')?.value;
-        var searchUrl = '/search?q=' + encodeURIComponent(query);
+        var searchUrl = withBase('/search?q=') + encodeURIComponent(query);
         if (region) {
             searchUrl += '&region=' + encodeURIComponent(region);
         }
@@ -5238,7 +5954,7 @@
 
     function init() {
         // Only on search results page
-        if (!window.location.pathname.includes('/search')) return;
+        if (!window.location.pathname.includes(withBase('/search'))) return;
 
         var urlParams = new URLSearchParams(window.location.search);
         currentQuery = urlParams.get('q');
@@ -5273,7 +5989,7 @@
 
     function fetchRelatedSearches(query) {
         // Try API first - per AI.md PART 14: use origin prefix
-        fetch(window.location.origin + '/api/v1/search/related?q=' + encodeURIComponent(query) + '&limit=8')
+        fetch(window.location.origin + withBase('/api/v1/search/related?q=') + encodeURIComponent(query) + '&limit=8')
             .then(function(response) {
                 if (!response.ok) throw new Error('API not available');
                 return response.json();
@@ -5347,7 +6063,7 @@
             '<div class="related-list">';
 
         suggestions.forEach(function(suggestion) {
-            var searchUrl = '/search?q=' + encodeURIComponent(suggestion);
+            var searchUrl = withBase('/search?q=') + encodeURIComponent(suggestion);
             html += '<a href="' + searchUrl + '" class="related-item">' +
                 '<svg class="related-icon" viewBox="0 0 24 24" width="16" height="16" fill="none" stroke="currentColor" stroke-width="2">' +
                     '<circle cx="11" cy="11" r="8"/><path d="M21 21l-4.35-4.35"/>' +
@@ -5393,7 +6109,7 @@
                 '</summary>' +
                 '<div class="paa-answer">' +
                     (q.answer ? '<p>' + escapeHtml(q.answer) + '</p>' : '<p class="paa-loading">' + escapeHtml(t('common.loading', 'Loading...')) + '</p>') +
-                    '<a href="/search?q=' + encodeURIComponent(q.question) + '" class="paa-link">' + escapeHtml(t('search.search_for_this', 'Search for this')) + '</a>' +
+                    '<a href="' + BASE_PATH + '/search?q=' + encodeURIComponent(q.question) + '" class="paa-link">' + escapeHtml(t('search.search_for_this', 'Search for this')) + '</a>' +
                 '</div>' +
             '</details>';
         });
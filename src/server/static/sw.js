@@ -1,34 +1,52 @@
 // Service Worker for Search PWA
 // Per AI.md PART 15-16: PWA Support
+//
+// Served from /sw.js (not /static/) so its scope covers the whole site. The
+// server substitutes the build version into VERSION, so every upgrade
+// installs a fresh app shell. Paths below are from the instance root and
+// go under BASE, the server.base_path the worker was registered from.
 
-const CACHE_NAME = 'search-v1';
-const STATIC_ASSETS = [
+const VERSION = '__CACHE_VERSION__';
+const BASE = new URL('./', self.location).pathname.replace(/\/$/, '');
+const SHELL_CACHE = 'search-shell-' + VERSION;
+// Homepage widget data, so widgets still render offline
+const WIDGET_CACHE = 'search-widgets';
+// Recent results pages; only written when the user opts in on /preferences
+const RECENT_CACHE = 'search-recent';
+// Holds the opt-in flag, which app.js writes (workers cannot read localStorage)
+const SETTINGS_CACHE = 'search-settings';
+const OFFLINE_SEARCHES_FLAG = '/__offline-searches';
+const MAX_RECENT_SEARCHES = 10;
+
+const SHELL_ASSETS = [
   '/',
+  '/offline',
   '/static/css/common.css',
   '/static/css/components.css',
   '/static/css/public.css',
-  '/static/js/main.js',
+  '/static/js/app.js',
   '/static/img/favicon.svg',
-  '/static/manifest.json'
-];
+  '/static/img/icon-192.svg',
+  '/static/img/icon-512.svg',
+  '/manifest.webmanifest'
+].map((path) => BASE + path);
 
-// Install event - cache static assets
+// Install event - cache the app shell
 self.addEventListener('install', (event) => {
   event.waitUntil(
-    caches.open(CACHE_NAME).then((cache) => {
-      return cache.addAll(STATIC_ASSETS);
-    })
+    caches.open(SHELL_CACHE).then((cache) => cache.addAll(SHELL_ASSETS))
   );
   self.skipWaiting();
 });
 
-// Activate event - clean up old caches
+// Activate event - drop app shells from previous versions
 self.addEventListener('activate', (event) => {
   event.waitUntil(
     caches.keys().then((cacheNames) => {
       return Promise.all(
         cacheNames
-          .filter((name) => name !== CACHE_NAME)
+          .filter((name) => name.startsWith('search-shell-') || name === 'search-v1')
+          .filter((name) => name !== SHELL_CACHE)
           .map((name) => caches.delete(name))
       );
     })
@@ -36,42 +54,107 @@ self.addEventListener('activate', (event) => {
   self.clients.claim();
 });
 
-// Fetch event - network first, fallback to cache
 self.addEventListener('fetch', (event) => {
-  // Skip non-GET requests
-  if (event.request.method !== 'GET') {
+  const request = event.request;
+  // Skip non-GET and cross-origin requests
+  if (request.method !== 'GET') {
+    return;
+  }
+  const url = new URL(request.url);
+  if (url.origin !== self.location.origin || !url.pathname.startsWith(BASE + '/')) {
     return;
   }
+  const path = url.pathname.slice(BASE.length);
 
-  // Skip API requests - always go to network
-  if (event.request.url.includes('/api/')) {
+  if (request.mode === 'navigate') {
+    event.respondWith(handleNavigation(request, path));
     return;
   }
 
-  event.respondWith(
-    fetch(event.request)
-      .then((response) => {
-        // Clone response for caching
-        if (response.status === 200) {
-          const responseClone = response.clone();
-          caches.open(CACHE_NAME).then((cache) => {
-            cache.put(event.request, responseClone);
-          });
-        }
-        return response;
-      })
-      .catch(() => {
-        // Fallback to cache if network fails
-        return caches.match(event.request).then((cachedResponse) => {
-          if (cachedResponse) {
-            return cachedResponse;
-          }
-          // Return offline page for navigation requests
-          if (event.request.mode === 'navigate') {
-            return caches.match('/');
-          }
-          return new Response('Offline', { status: 503 });
-        });
-      })
-  );
+  if (path.startsWith('/api/v1/widgets')) {
+    event.respondWith(networkFirst(request, WIDGET_CACHE));
+    return;
+  }
+
+  // Everything else under /api/ always goes to the network
+  if (path.startsWith('/api/')) {
+    return;
+  }
+
+  if (path.startsWith('/static/') || path.startsWith('/locales/')) {
+    event.respondWith(staleWhileRevalidate(request, SHELL_CACHE));
+  }
 });
+
+// Pages: network first; offline, fall back to a cached copy, then /offline
+async function handleNavigation(request, path) {
+  try {
+    const response = await fetch(request);
+    if (response.ok) {
+      if (path === '/') {
+        const shell = await caches.open(SHELL_CACHE);
+        await shell.put(BASE + '/', response.clone());
+      } else if (path === '/search' && await offlineSearchesEnabled()) {
+        await rememberSearch(request, response.clone());
+      }
+    }
+    return response;
+  } catch (err) {
+    const cached = await caches.match(request, { ignoreVary: true });
+    if (cached) {
+      return cached;
+    }
+    const offline = await caches.match(BASE + '/offline');
+    return offline || new Response('Offline', { status: 503, headers: { 'Content-Type': 'text/plain' } });
+  }
+}
+
+async function networkFirst(request, cacheName) {
+  const cache = await caches.open(cacheName);
+  try {
+    const response = await fetch(request);
+    if (response.ok) {
+      await cache.put(request, response.clone());
+    }
+    return response;
+  } catch (err) {
+    const cached = await cache.match(request);
+    return cached || new Response('{"ok":false,"error":"offline"}', {
+      status: 503,
+      headers: { 'Content-Type': 'application/json' }
+    });
+  }
+}
+
+async function staleWhileRevalidate(request, cacheName) {
+  const cache = await caches.open(cacheName);
+  const cached = await cache.match(request);
+  const refresh = fetch(request).then((response) => {
+    if (response.ok) {
+      cache.put(request, response.clone());
+    }
+    return response;
+  });
+  if (cached) {
+    refresh.catch(() => {});
+    return cached;
+  }
+  return refresh;
+}
+
+async function offlineSearchesEnabled() {
+  const settings = await caches.open(SETTINGS_CACHE);
+  return Boolean(await settings.match(OFFLINE_SEARCHES_FLAG));
+}
+
+// Keep the newest MAX_RECENT_SEARCHES results pages; re-visiting a search
+// moves it to the end
+async function rememberSearch(request, response) {
+  const cache = await caches.open(RECENT_CACHE);
+  await cache.delete(request, { ignoreVary: true });
+  await cache.put(request, response);
+  const keys = await cache.keys();
+  for (let i = 0; i < keys.length - MAX_RECENT_SEARCHES; i++) {
+    await cache.delete(keys[i]);
+  }
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/apimgr/search/src/alert"
+)
+
+// subjectRequest names the data subject of an export or erasure request.
+// The email travels in the body so it stays out of access logs.
+type subjectRequest struct {
+	Email string `json:"email"`
+}
+
+// decodeSubjectRequest reads the subject of a data-subject request,
+// responding with an error and returning "" when there is none
+func (s *Server) decodeSubjectRequest(w http.ResponseWriter, r *http.Request) string {
+	if s.alertManager == nil {
+		respondError(w, http.StatusServiceUnavailable, "Alert storage is unavailable")
+		return ""
+	}
+	var req subjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return ""
+	}
+	if req.Email == "" {
+		respondError(w, http.StatusBadRequest, "email is required")
+		return ""
+	}
+	return req.Email
+}
+
+// handleSubjectExport returns every record stored about the subject. Alert
+// subscriptions are the only per-person data this server keeps.
+func (s *Server) handleSubjectExport(w http.ResponseWriter, r *http.Request) {
+	email := s.decodeSubjectRequest(w, r)
+	if email == "" {
+		return
+	}
+	export, err := s.alertManager.ExportSubject(r.Context(), email)
+	if err != nil {
+		respondSubjectError(w, err)
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogSubjectExported("operator", getClientIPSimple(r), alert.SubjectHash(email), len(export.Alerts))
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": export})
+}
+
+// handleSubjectErase irreversibly deletes every record stored about the
+// subject. The audit record keeps a hash of the email, not the email.
+func (s *Server) handleSubjectErase(w http.ResponseWriter, r *http.Request) {
+	email := s.decodeSubjectRequest(w, r)
+	if email == "" {
+		return
+	}
+	erased, err := s.alertManager.EraseSubject(r.Context(), email)
+	if err != nil {
+		respondSubjectError(w, err)
+		return
+	}
+	subjectHash := alert.SubjectHash(email)
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogSubjectErased("operator", getClientIPSimple(r), subjectHash, erased)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"subject_hash": subjectHash,
+			"alerts":       erased,
+		},
+	})
+}
+
+func respondSubjectError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alert.ErrInvalidInput) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondError(w, http.StatusInternalServerError, err.Error())
+}
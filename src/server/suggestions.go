@@ -0,0 +1,30 @@
+package server
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+)
+
+// suggesterFromConfig builds the autocomplete suggester for
+// search.suggestions. Unknown providers are logged and skipped. history is
+// shared across reloads so it survives config changes; it is left out when
+// the history is disabled.
+func suggesterFromConfig(sc config.SuggestionsConfig, history *search.SuggestionHistory) *search.Suggester {
+	providers := make([]search.WeightedSuggestionProvider, 0, len(sc.Providers))
+	for _, p := range sc.Providers {
+		provider, ok := search.NewSuggestionProvider(p.Name)
+		if !ok {
+			slog.Warn("Ignoring unknown suggestion provider", "provider", p.Name,
+				"known", strings.Join(search.SuggestionProviderNames(), ", "))
+			continue
+		}
+		providers = append(providers, search.WeightedSuggestionProvider{Provider: provider, Weight: p.Weight})
+	}
+	if !sc.History {
+		history = nil
+	}
+	return search.NewSuggester(providers, history, sc.HistoryWeight)
+}
@@ -10,6 +10,8 @@ const (
 	ThemeDark  = "dark"
 	ThemeLight = "light"
 	ThemeAuto  = "auto"
+	// ThemeContrast is the high-contrast theme; it meets WCAG AAA contrast
+	ThemeContrast = "contrast"
 )
 
 // DefaultTheme is the default theme when no preference is set
@@ -28,7 +30,7 @@ func GetTheme(r *http.Request) string {
 	// Check for theme cookie
 	if cookie, err := r.Cookie("theme"); err == nil {
 		switch cookie.Value {
-		case ThemeLight, ThemeDark, ThemeAuto:
+		case ThemeLight, ThemeDark, ThemeAuto, ThemeContrast:
 			return cookie.Value
 		}
 	}
@@ -36,7 +38,7 @@ func GetTheme(r *http.Request) string {
 	// Check for theme query parameter (for theme switching)
 	if theme := r.URL.Query().Get("theme"); theme != "" {
 		switch theme {
-		case ThemeLight, ThemeDark, ThemeAuto:
+		case ThemeLight, ThemeDark, ThemeAuto, ThemeContrast:
 			return theme
 		}
 	}
@@ -50,7 +52,7 @@ func GetTheme(r *http.Request) string {
 func SetTheme(w http.ResponseWriter, theme string) {
 	// Validate theme value
 	switch theme {
-	case ThemeLight, ThemeDark, ThemeAuto:
+	case ThemeLight, ThemeDark, ThemeAuto, ThemeContrast:
 		// Valid theme
 	default:
 		theme = DefaultTheme
@@ -81,6 +83,8 @@ func GetThemeClass(theme string) string {
 		return "theme-dark"
 	case ThemeAuto:
 		return "theme-auto"
+	case ThemeContrast:
+		return "theme-contrast"
 	default:
 		return "theme-dark"
 	}
@@ -89,21 +93,22 @@ func GetThemeClass(theme string) string {
 // IsValidTheme checks if a theme string is valid
 func IsValidTheme(theme string) bool {
 	switch theme {
-	case ThemeLight, ThemeDark, ThemeAuto:
+	case ThemeLight, ThemeDark, ThemeAuto, ThemeContrast:
 		return true
 	}
 	return false
 }
 
 // ThemeInfo holds theme metadata for template rendering.
-// Current is the user preference (light, dark, auto).
-// ClassName is the CSS class applied to <html> (theme-light, theme-dark, theme-auto).
+// Current is the user preference (light, dark, auto, contrast).
+// ClassName is the CSS class applied to <html> (theme-light, theme-dark, theme-auto, theme-contrast).
 type ThemeInfo struct {
-	Current   string
-	ClassName string
-	IsDark    bool
-	IsLight   bool
-	IsAuto    bool
+	Current    string
+	ClassName  string
+	IsDark     bool
+	IsLight    bool
+	IsAuto     bool
+	IsContrast bool
 }
 
 // GetThemeInfo returns complete theme information for template rendering
@@ -111,9 +116,10 @@ func GetThemeInfo(r *http.Request) ThemeInfo {
 	theme := GetTheme(r)
 
 	info := ThemeInfo{
-		Current:   theme,
-		ClassName: GetThemeClass(theme),
-		IsAuto:    theme == ThemeAuto,
+		Current:    theme,
+		ClassName:  GetThemeClass(theme),
+		IsAuto:     theme == ThemeAuto,
+		IsContrast: theme == ThemeContrast,
 	}
 
 	// Determine effective theme for auto mode
@@ -123,7 +129,8 @@ func GetThemeInfo(r *http.Request) ThemeInfo {
 		info.IsDark = true
 		info.IsLight = false
 	} else {
-		info.IsDark = theme == ThemeDark
+		// The high-contrast theme is light text on black
+		info.IsDark = theme == ThemeDark || theme == ThemeContrast
 		info.IsLight = theme == ThemeLight
 	}
 
@@ -0,0 +1,358 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/security"
+)
+
+const (
+	// maxTokenRefusals bounds the recent refusals kept per token
+	maxTokenRefusals = 50
+	// maxTokenIPs bounds the client addresses counted per token; later
+	// ones are not counted
+	maxTokenIPs = 256
+	// topTokenIPs is how many client addresses a token's detail lists
+	topTokenIPs = 10
+)
+
+// tokenUsageTracker counts the requests of named operator tokens in memory,
+// per minute against each token's rate limit, with the requests refused
+// and the client addresses the token is used from. The zero value is ready
+// to use; counts start over when the server restarts.
+type tokenUsageTracker struct {
+	mu     sync.Mutex
+	tokens map[int64]*tokenUsage
+}
+
+// tokenUsage is one token's requests
+type tokenUsage struct {
+	// minutes holds the requests of the last hour, by minute
+	minutes  [60]tokenMinute
+	total    int
+	refusals []tokenRefusal
+	ips      map[string]int
+}
+
+// tokenMinute is the requests allowed in the minute starting at start
+type tokenMinute struct {
+	start time.Time
+	count int
+}
+
+// tokenRefusal is a request refused for the token's rate limit or freeze
+type tokenRefusal struct {
+	At     time.Time `json:"at"`
+	Status int       `json:"status"`
+	IP     string    `json:"ip"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+}
+
+// tokenIP is a client address and its requests with the token
+type tokenIP struct {
+	IP       string `json:"ip"`
+	Requests int    `json:"requests"`
+}
+
+// usage returns the token's usage, creating it; mu must be held
+func (t *tokenUsageTracker) usage(id int64) *tokenUsage {
+	if t.tokens == nil {
+		t.tokens = make(map[int64]*tokenUsage)
+	}
+	u, ok := t.tokens[id]
+	if !ok {
+		u = &tokenUsage{ips: make(map[string]int)}
+		t.tokens[id] = u
+	}
+	return u
+}
+
+// minute returns the bucket counting the requests of now's minute
+func (u *tokenUsage) minute(now time.Time) *tokenMinute {
+	start := now.Truncate(time.Minute)
+	m := &u.minutes[start.Minute()]
+	if !m.start.Equal(start) {
+		*m = tokenMinute{start: start}
+	}
+	return m
+}
+
+// lastHour sums the requests of the 60 minutes up to now
+func (u *tokenUsage) lastHour(now time.Time) int {
+	since := now.Truncate(time.Minute).Add(-59 * time.Minute)
+	n := 0
+	for _, m := range u.minutes {
+		if !m.start.Before(since) && !m.start.After(now) {
+			n += m.count
+		}
+	}
+	return n
+}
+
+// refuse records a refused request
+func (u *tokenUsage) refuse(r *http.Request, ip string, status int, now time.Time) {
+	u.refusals = append(u.refusals, tokenRefusal{At: now.UTC(), Status: status, IP: ip, Method: r.Method, Path: r.URL.Path})
+	if len(u.refusals) > maxTokenRefusals {
+		u.refusals = u.refusals[len(u.refusals)-maxTokenRefusals:]
+	}
+}
+
+// allow counts a request with token from ip against its limit at now,
+// returning the limit (0 for none), the requests left this minute and when
+// the minute ends. A frozen or exhausted token's request is recorded as
+// refused, with the status it is refused with.
+func (t *tokenUsageTracker) allow(token *security.OperatorToken, r *http.Request, ip string, now time.Time) (limit, remaining int, reset time.Time, status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u := t.usage(token.ID)
+	m := u.minute(now)
+	limit = token.LimitAt(now)
+	reset = m.start.Add(time.Minute)
+	switch {
+	case token.FrozenAt(now):
+		status = http.StatusForbidden
+	case limit > 0 && m.count >= limit:
+		status = http.StatusTooManyRequests
+	}
+	if status != 0 {
+		u.refuse(r, ip, status, now)
+		return limit, 0, reset, status
+	}
+	m.count++
+	u.total++
+	if _, ok := u.ips[ip]; ok || len(u.ips) < maxTokenIPs {
+		u.ips[ip]++
+	}
+	if limit > 0 {
+		remaining = limit - m.count
+	}
+	return limit, remaining, reset, 0
+}
+
+// tokenUsageReport is a token's usage for its detail
+type tokenUsageReport struct {
+	// Limit is the requests allowed per minute now, boost included; 0 for
+	// unlimited
+	Limit int `json:"limit"`
+	// Used and Remaining are this minute's requests and those left
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	LastHour  int       `json:"last_hour"`
+	// Total counts the requests since the server started
+	Total int `json:"total"`
+	// Refusals are the latest requests refused, the newest first
+	Refusals []tokenRefusal `json:"refusals"`
+	TopIPs   []tokenIP      `json:"top_ips"`
+}
+
+// report returns token's usage at now
+func (t *tokenUsageTracker) report(token *security.OperatorToken, now time.Time) tokenUsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u := t.usage(token.ID)
+	m := u.minute(now)
+	report := tokenUsageReport{
+		Limit:    token.LimitAt(now),
+		Used:     m.count,
+		Reset:    m.start.Add(time.Minute).UTC(),
+		LastHour: u.lastHour(now),
+		Total:    u.total,
+		Refusals: make([]tokenRefusal, 0, len(u.refusals)),
+		TopIPs:   make([]tokenIP, 0, len(u.ips)),
+	}
+	if report.Limit > 0 {
+		report.Remaining = max(report.Limit-m.count, 0)
+	}
+	for i := len(u.refusals) - 1; i >= 0; i-- {
+		report.Refusals = append(report.Refusals, u.refusals[i])
+	}
+	for ip, n := range u.ips {
+		report.TopIPs = append(report.TopIPs, tokenIP{IP: ip, Requests: n})
+	}
+	sort.Slice(report.TopIPs, func(i, j int) bool {
+		if report.TopIPs[i].Requests != report.TopIPs[j].Requests {
+			return report.TopIPs[i].Requests > report.TopIPs[j].Requests
+		}
+		return report.TopIPs[i].IP < report.TopIPs[j].IP
+	})
+	if len(report.TopIPs) > topTokenIPs {
+		report.TopIPs = report.TopIPs[:topTokenIPs]
+	}
+	return report
+}
+
+// allowOperatorToken applies a named token's rate limit and freeze to a
+// request, setting the X-RateLimit headers. It writes the refusal and
+// returns false when the request may not go on.
+func (s *Server) allowOperatorToken(w http.ResponseWriter, r *http.Request, token *security.OperatorToken) bool {
+	limit, remaining, reset, status := s.tokenUsage.allow(token, r, getClientIPSimple(r), time.Now())
+	if limit > 0 {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}
+	switch status {
+	case http.StatusForbidden:
+		localizedHTTPError(w, r, http.StatusForbidden, "errors.forbidden")
+		return false
+	case http.StatusTooManyRequests:
+		if s.logManager != nil && s.logManager.Security() != nil {
+			s.logManager.Security().LogRateLimited("-", r.URL.Path)
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(max(int(time.Until(reset).Seconds()+0.5), 1)))
+		localizedHTTPError(w, r, http.StatusTooManyRequests, "errors.rate_limit")
+		return false
+	}
+	return true
+}
+
+// operatorTokenID reads the {id} of a token route, writing a 404 when it
+// is not a number
+func operatorTokenID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusNotFound, security.ErrOperatorTokenNotFound.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+// handleOperatorToken describes a named operator token with its usage:
+// requests this minute against its rate limit, the last hour's requests,
+// recent refusals and the client addresses using it most
+func (s *Server) handleOperatorToken(w http.ResponseWriter, r *http.Request) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	id, ok := operatorTokenID(w, r)
+	if !ok {
+		return
+	}
+	token, err := security.GetOperatorToken(r.Context(), s.dbManager.ServerDB(), id)
+	switch {
+	case errors.Is(err, security.ErrOperatorTokenNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	now := time.Now()
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"token":   token,
+			"expired": token.Expired(now),
+			"frozen":  token.FrozenAt(now),
+			"usage":   s.tokenUsage.report(token, now),
+		},
+	})
+}
+
+// handleOperatorTokenLimit changes a token's requests per minute. The body
+// is {"rate_limit": n}; 0 means unlimited.
+func (s *Server) handleOperatorTokenLimit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RateLimit *int `json:"rate_limit"`
+	}
+	s.updateOperatorToken(w, r, &req, func(id int64) error {
+		if req.RateLimit == nil {
+			return security.ErrOperatorTokenRateLimit
+		}
+		return security.SetOperatorTokenRateLimit(r.Context(), s.dbManager.ServerDB(), id, *req.RateLimit)
+	})
+}
+
+// handleOperatorTokenBoost raises a token's rate limit for a while. The
+// body is {"requests": n, "until": "RFC 3339"}: n more requests per minute
+// until then.
+func (s *Server) handleOperatorTokenBoost(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Requests int       `json:"requests"`
+		Until    time.Time `json:"until"`
+	}
+	s.updateOperatorToken(w, r, &req, func(id int64) error {
+		return security.BoostOperatorToken(r.Context(), s.dbManager.ServerDB(), id, req.Requests, req.Until, time.Now())
+	})
+}
+
+// handleOperatorTokenUnboost ends a token's boost
+func (s *Server) handleOperatorTokenUnboost(w http.ResponseWriter, r *http.Request) {
+	s.updateOperatorToken(w, r, nil, func(id int64) error {
+		return security.UnboostOperatorToken(r.Context(), s.dbManager.ServerDB(), id)
+	})
+}
+
+// handleOperatorTokenFreeze refuses a token's requests without revoking
+// it. The body may give {"until": "RFC 3339"}; without it the token stays
+// frozen until it is unfrozen.
+func (s *Server) handleOperatorTokenFreeze(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Until time.Time `json:"until"`
+	}
+	s.updateOperatorToken(w, r, &req, func(id int64) error {
+		return security.FreezeOperatorToken(r.Context(), s.dbManager.ServerDB(), id, req.Until, time.Now())
+	})
+}
+
+// handleOperatorTokenUnfreeze accepts a frozen token again
+func (s *Server) handleOperatorTokenUnfreeze(w http.ResponseWriter, r *http.Request) {
+	s.updateOperatorToken(w, r, nil, func(id int64) error {
+		return security.UnfreezeOperatorToken(r.Context(), s.dbManager.ServerDB(), id)
+	})
+}
+
+// updateOperatorToken decodes the JSON body into req, when given, applies
+// update to the token of the route and responds with the token as it now
+// is
+func (s *Server) updateOperatorToken(w http.ResponseWriter, r *http.Request, req any, update func(id int64) error) {
+	if !s.serverDBOrUnavailable(w) {
+		return
+	}
+	id, ok := operatorTokenID(w, r)
+	if !ok {
+		return
+	}
+	if req != nil {
+		// An empty body leaves req as it is
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+			respondError(w, http.StatusBadRequest, "Request body must be JSON")
+			return
+		}
+	}
+	err := update(id)
+	switch {
+	case errors.Is(err, security.ErrOperatorTokenNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, security.ErrOperatorTokenRateLimit),
+		errors.Is(err, security.ErrOperatorTokenBoost),
+		errors.Is(err, security.ErrOperatorTokenExpiry):
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	token, err := security.GetOperatorToken(r.Context(), s.dbManager.ServerDB(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if s.logManager != nil && s.logManager.Audit() != nil {
+		s.logManager.Audit().LogConfigChange("operator", getClientIPSimple(r), "api_tokens."+strconv.FormatInt(id, 10), r.Method+" "+r.URL.Path)
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true, "data": token})
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/apimgr/search/src/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleTorServices lists the published onions: the main one and any
+// additional services from tor.services, gated by operator token
+func (s *Server) handleTorServices(w http.ResponseWriter, r *http.Request) {
+	if s.torService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Tor is not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.torService.HiddenServices(),
+	})
+}
+
+// handleTorClients lists the clients enrolled for hidden service client
+// authorization, gated by operator token. Per IDEA.md there is no admin UI;
+// this API is the key management surface.
+func (s *Server) handleTorClients(w http.ResponseWriter, r *http.Request) {
+	if s.torService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Tor is not available")
+		return
+	}
+	clients, err := s.torService.ListClientAuth()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			// The hidden service only admits enrolled clients while any exist
+			"restricted": len(clients) > 0,
+			"clients":    clients,
+		},
+	})
+}
+
+// handleTorClientAdd enrolls a client and returns its private key. The key
+// is not stored, so the response is the only chance to copy it.
+func (s *Server) handleTorClientAdd(w http.ResponseWriter, r *http.Request) {
+	if s.torService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Tor is not available")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a name")
+		return
+	}
+
+	creds, err := s.torService.AddClientAuth(req.Name)
+	switch {
+	case errors.Is(err, service.ErrTorClientName):
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, service.ErrTorClientExists):
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"ok":   true,
+		"data": creds,
+	})
+}
+
+// handleTorClientRevoke removes a client's key; the hidden service is
+// re-published without it
+func (s *Server) handleTorClientRevoke(w http.ResponseWriter, r *http.Request) {
+	if s.torService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Tor is not available")
+		return
+	}
+	err := s.torService.RevokeClientAuth(chi.URLParam(r, "name"))
+	switch {
+	case errors.Is(err, service.ErrTorClientNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleTorClientTrash lists revoked clients that can still be restored
+func (s *Server) handleTorClientTrash(w http.ResponseWriter, r *http.Request) {
+	if s.torService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Tor is not available")
+		return
+	}
+	revoked, err := s.torService.ListRevokedClientAuth()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"retention_days": int(service.TorClientTrashRetention.Hours() / 24),
+			"clients":        revoked,
+		},
+	})
+}
+
+// handleTorClientRestore re-enrolls a revoked client from the trash
+func (s *Server) handleTorClientRestore(w http.ResponseWriter, r *http.Request) {
+	if s.torService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Tor is not available")
+		return
+	}
+	client, err := s.torService.RestoreClientAuth(chi.URLParam(r, "name"))
+	switch {
+	case errors.Is(err, service.ErrTorClientNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrTorClientExists):
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": client,
+	})
+}
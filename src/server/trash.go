@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/apimgr/search/src/trash"
+)
+
+// Kinds of the resources kept in the trash
+const (
+	trashKindResultRule = "result_rule"
+	trashKindBang       = "bang"
+)
+
+// moveToTrash keeps a deleted resource for trash.Retention. The deletion
+// itself has happened, so a failure here is only logged.
+func (s *Server) moveToTrash(ctx context.Context, kind, key string, v any) {
+	if err := s.trash.Put(ctx, kind, key, v); err != nil {
+		slog.Warn("deleted resource not kept in the trash", "kind", kind, "key", key, "err", err)
+	}
+}
+
+// respondTrash lists the trashed resources of kind that can be restored,
+// newest first, with the retention
+func (s *Server) respondTrash(w http.ResponseWriter, r *http.Request, kind string) {
+	if s.trash == nil {
+		respondError(w, http.StatusServiceUnavailable, "Trash is unavailable")
+		return
+	}
+	items, err := s.trash.List(r.Context(), kind)
+	if err != nil {
+		slog.Error("trash not listed", "kind", kind, "err", err)
+		respondError(w, http.StatusInternalServerError, "Trash request failed")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"retention_days": int(trash.Retention.Hours() / 24),
+			"items":          items,
+		},
+	})
+}
+
+// trashItem returns the most recently trashed resource of kind with key,
+// answering 404 or 503 itself when there is none
+func (s *Server) trashItem(w http.ResponseWriter, r *http.Request, kind, key string) *trash.Item {
+	if s.trash == nil {
+		respondError(w, http.StatusServiceUnavailable, "Trash is unavailable")
+		return nil
+	}
+	item, err := s.trash.Get(r.Context(), kind, key)
+	switch {
+	case errors.Is(err, trash.ErrNotFound):
+		respondError(w, http.StatusNotFound, "Not found in the trash")
+		return nil
+	case err != nil:
+		slog.Error("trash not read", "kind", kind, "err", err)
+		respondError(w, http.StatusInternalServerError, "Trash request failed")
+		return nil
+	}
+	return item
+}
+
+// restoredFromTrash removes a restored item from the trash; the resource
+// is back, so a failure here is only logged
+func (s *Server) restoredFromTrash(ctx context.Context, item *trash.Item) {
+	if err := s.trash.Remove(ctx, item.ID); err != nil {
+		slog.Warn("restored resource left in the trash", "kind", item.Kind, "key", item.Key, "err", err)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/database/dbtest"
+	"github.com/apimgr/search/src/moderation"
+	"github.com/apimgr/search/src/trash"
+)
+
+func TestResultRuleTrash(t *testing.T) {
+	db := dbtest.Server(t)
+	s := &Server{config: config.DefaultConfig(), moderation: moderation.NewStore(db, ""), trash: trash.NewStore(db, "")}
+	rule, err := s.moderation.AddRule(t.Context(), moderation.RuleDomain, "spam.example", "spam", "operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Delete("/rules/{id}", s.handleResultRuleRemove)
+	r.Get("/rules/trash", s.handleResultRuleTrash)
+	r.Post("/rules/trash/{id}/restore", s.handleResultRuleRestore)
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	id := strconv.FormatInt(rule.ID, 10)
+	if w := do(http.MethodDelete, "/rules/"+id); w.Code != http.StatusOK {
+		t.Fatalf("remove = %d %s", w.Code, w.Body)
+	}
+	if s.moderation.Blocked("https://spam.example/") {
+		t.Fatal("removed rule still blocks")
+	}
+
+	w := do(http.MethodGet, "/rules/trash")
+	var listed struct {
+		Data struct {
+			RetentionDays int          `json:"retention_days"`
+			Items         []trash.Item `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if listed.Data.RetentionDays != 7 || len(listed.Data.Items) != 1 || listed.Data.Items[0].Key != id {
+		t.Fatalf("trash = %+v", listed.Data)
+	}
+
+	if w := do(http.MethodPost, "/rules/trash/"+id+"/restore"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"spam.example"`) {
+		t.Fatalf("restore = %d %s", w.Code, w.Body)
+	}
+	if !s.moderation.Blocked("https://spam.example/") {
+		t.Error("restored rule does not block")
+	}
+	if w := do(http.MethodPost, "/rules/trash/"+id+"/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("second restore = %d, want 404", w.Code)
+	}
+}
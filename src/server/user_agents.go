@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/search"
+	"github.com/apimgr/search/src/search/engine"
+)
+
+// maxUserAgentLength bounds a pinned user agent
+const maxUserAgentLength = 512
+
+// userAgentStrategy converts search.user_agents into the aggregator's user
+// agent strategy. An empty pool is the engines' built-in user agent and
+// the browsers blocked engines are retried as.
+func userAgentStrategy(ua config.UserAgentsConfig) search.UserAgentStrategy {
+	pool := ua.Pool
+	if len(pool) == 0 {
+		pool = append([]string{engine.UserAgent}, search.AlternateUserAgents()...)
+	}
+	engines := make(map[string]search.EngineUserAgent, len(ua.Engines))
+	for name, e := range ua.Engines {
+		engines[name] = search.EngineUserAgent{Policy: e.Policy, Pin: e.Pin}
+	}
+	return search.UserAgentStrategy{Pool: pool, Policy: ua.Policy, Engines: engines}
+}
+
+// enabledEngineNames returns the names of the enabled engines
+func (s *Server) enabledEngineNames() []string {
+	var names []string
+	if s.registry != nil {
+		for _, eng := range s.registry.GetEnabled() {
+			names = append(names, eng.Name())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// handleUserAgents reports the user agent pool and, for every enabled
+// engine, its policy, how each user agent fared and when it switched
+func (s *Server) handleUserAgents(w http.ResponseWriter, r *http.Request) {
+	strategy := userAgentStrategy(s.config.Search.UserAgents)
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"policy":  s.config.Search.UserAgents.Policy,
+			"pool":    strategy.Pool,
+			"engines": s.aggregator.UserAgentStatus(s.enabledEngineNames()),
+		},
+	})
+}
+
+// handleUserAgentSet sets an engine's user agent policy or pins it to one
+// user agent. The body is {"policy": "best"} or {"pin": "Mozilla/5.0 ..."}.
+func (s *Server) handleUserAgentSet(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	if !slices.Contains(s.enabledEngineNames(), name) {
+		respondError(w, http.StatusNotFound, "Unknown engine")
+		return
+	}
+	var req config.EngineUserAgentConfig
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8*1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON with a policy or pin")
+		return
+	}
+	req.Policy = strings.ToLower(strings.TrimSpace(req.Policy))
+	req.Pin = strings.TrimSpace(req.Pin)
+	switch {
+	case req.Policy == "" && req.Pin == "":
+		respondError(w, http.StatusBadRequest, "Set a policy or pin")
+		return
+	case req.Policy != "" && !slices.Contains(config.UserAgentPolicies, req.Policy):
+		respondError(w, http.StatusBadRequest, "Policy must be one of "+strings.Join(config.UserAgentPolicies, ", "))
+		return
+	case len(req.Pin) > maxUserAgentLength || strings.ContainsFunc(req.Pin, func(r rune) bool { return r < 0x20 || r == 0x7f }):
+		respondError(w, http.StatusBadRequest, "Pin must be a single-line user agent")
+		return
+	}
+
+	engines := make(map[string]config.EngineUserAgentConfig, len(s.config.Search.UserAgents.Engines)+1)
+	for engine, e := range s.config.Search.UserAgents.Engines {
+		engines[engine] = e
+	}
+	engines[name] = req
+	if err := s.saveUserAgents(engines); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.aggregator.UserAgentStatus([]string{name})[0],
+	})
+}
+
+// handleUserAgentReset returns an engine to search.user_agents.policy
+func (s *Server) handleUserAgentReset(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(chi.URLParam(r, "engine"))
+	if _, ok := s.config.Search.UserAgents.Engines[name]; !ok {
+		respondError(w, http.StatusNotFound, "Engine has no user agent policy")
+		return
+	}
+	engines := make(map[string]config.EngineUserAgentConfig, len(s.config.Search.UserAgents.Engines))
+	for engine, e := range s.config.Search.UserAgents.Engines {
+		if engine != name {
+			engines[engine] = e
+		}
+	}
+	if err := s.saveUserAgents(engines); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"ok":   true,
+		"data": s.aggregator.UserAgentStatus([]string{name})[0],
+	})
+}
+
+// saveUserAgents writes the per-engine user agent policies to server.yml
+// and applies them
+func (s *Server) saveUserAgents(engines map[string]config.EngineUserAgentConfig) error {
+	previous := s.config.Search.UserAgents.Engines
+	s.config.Search.UserAgents.Engines = engines
+	if s.configSync != nil {
+		if err := s.configSync.SaveSetting("search.user_agents.engines", engines); err != nil {
+			s.config.Search.UserAgents.Engines = previous
+			return err
+		}
+	}
+	s.aggregator.SetUserAgents(userAgentStrategy(s.config.Search.UserAgents))
+	return nil
+}
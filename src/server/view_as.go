@@ -0,0 +1,204 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// viewAsCookie carries the token of the browser's view-as session
+	viewAsCookie = "view_as"
+	// viewAsTTL is how long a view-as session lasts
+	viewAsTTL = 30 * time.Minute
+	// maxViewAsSessions bounds the sessions kept at once; the one closest
+	// to expiry is dropped first
+	maxViewAsSessions = 50
+)
+
+// viewAsSession previews the web UI the way one user sees it: the
+// preferences they shared, their language, and no cookies or stored
+// settings of the operator's browser
+type viewAsSession struct {
+	token string
+	// Preferences string as shared from the preferences page; empty for an
+	// anonymous visitor
+	prefs     string
+	language  string
+	expiresAt time.Time
+}
+
+// viewAsStore holds the live view-as sessions in memory
+type viewAsStore struct {
+	mu       sync.Mutex
+	sessions map[string]*viewAsSession
+}
+
+func (v *viewAsStore) create(prefs, language string, now time.Time) (*viewAsSession, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	session := &viewAsSession{
+		token:     hex.EncodeToString(b),
+		prefs:     prefs,
+		language:  language,
+		expiresAt: now.Add(viewAsTTL),
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.sessions == nil {
+		v.sessions = make(map[string]*viewAsSession)
+	}
+	for token, s := range v.sessions {
+		if !s.expiresAt.After(now) {
+			delete(v.sessions, token)
+		}
+	}
+	for len(v.sessions) >= maxViewAsSessions {
+		var oldest *viewAsSession
+		for _, s := range v.sessions {
+			if oldest == nil || s.expiresAt.Before(oldest.expiresAt) {
+				oldest = s
+			}
+		}
+		delete(v.sessions, oldest.token)
+	}
+	v.sessions[session.token] = session
+	return session, nil
+}
+
+func (v *viewAsStore) get(token string, now time.Time) *viewAsSession {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	session, ok := v.sessions[token]
+	if !ok || !session.expiresAt.After(now) {
+		return nil
+	}
+	return session
+}
+
+func (v *viewAsStore) end(token string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.sessions, token)
+}
+
+// viewAsSession returns the request's view-as session, if it is in one
+func (s *Server) viewAsSession(r *http.Request) *viewAsSession {
+	c, err := r.Cookie(viewAsCookie)
+	if err != nil || c.Value == "" {
+		return nil
+	}
+	return s.viewAs.get(c.Value, time.Now())
+}
+
+// requestPrefs returns the preferences string a page applies: the prefs
+// query parameter, or in a view-as session the previewed user's
+// preferences when the page was opened without one
+func (s *Server) requestPrefs(r *http.Request) string {
+	prefs := strings.TrimSpace(r.URL.Query().Get("prefs"))
+	if prefs == "" {
+		if session := s.viewAsSession(r); session != nil {
+			prefs = session.prefs
+		}
+	}
+	return prefs
+}
+
+// handleViewAsCreate starts a view-as session, gated by operator token.
+// The body is {"prefs": "...", "language": "..."}; prefs is the string a
+// user shares from the preferences page, and both may be empty to view the
+// site as an anonymous visitor. Opening the returned URL in a browser puts
+// that browser in the session until it ends or expires.
+func (s *Server) handleViewAsCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prefs    string `json:"prefs"`
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8*1024)).Decode(&req); err != nil && err != io.EOF {
+		respondError(w, http.StatusBadRequest, "Request body must be JSON")
+		return
+	}
+	req.Prefs = strings.TrimSpace(req.Prefs)
+	if len(req.Prefs) > 2048 {
+		respondError(w, http.StatusBadRequest, "Preferences string is too long")
+		return
+	}
+	req.Language = strings.TrimSpace(req.Language)
+	if req.Language != "" {
+		if !s.getI18nManager().IsSupported(req.Language) {
+			respondError(w, http.StatusBadRequest, "Unsupported language")
+			return
+		}
+		req.Language = s.getI18nManager().ResolveSupportedLanguage(req.Language)
+	}
+
+	session, err := s.viewAs.create(req.Prefs, req.Language, time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	prefs := parseSearchPreferences(session.prefs)
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"url":        "/view-as/" + session.token,
+			"expires_at": session.expiresAt.UTC(),
+			"preferences": map[string]any{
+				"theme":            prefs.Theme,
+				"default_category": prefs.DefaultCategory,
+				"safe_search":      prefs.SafeSearch,
+				"results_per_page": prefs.ResultsPerPage,
+				"language":         session.language,
+			},
+		},
+	})
+}
+
+// handleViewAsStart enters the browser into the view-as session named in
+// the URL and opens the home page
+func (s *Server) handleViewAsStart(w http.ResponseWriter, r *http.Request) {
+	session := s.viewAs.get(chi.URLParam(r, "token"), time.Now())
+	if session == nil {
+		s.handleNotFound(w, r)
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     viewAsCookie,
+		Value:    session.token,
+		Expires:  session.expiresAt,
+		HttpOnly: true,
+	}
+	s.config.Server.Security.Cookies.Session.Apply(cookie, http.SameSiteStrictMode, s.config.Server.RequestTLS(r))
+	http.SetCookie(w, cookie)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleViewAsEnd leaves the view-as session, returning the browser to its
+// own settings
+func (s *Server) handleViewAsEnd(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(viewAsCookie); err == nil {
+		s.viewAs.end(c.Value)
+	}
+	cookie := &http.Cookie{
+		Name:     viewAsCookie,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+	}
+	s.config.Server.Security.Cookies.Session.Apply(cookie, http.SameSiteStrictMode, s.config.Server.RequestTLS(r))
+	http.SetCookie(w, cookie)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
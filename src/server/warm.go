@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/model"
+)
+
+// defaultWarmDelay spaces warm queries when search.warm_queries.delay is unset
+const defaultWarmDelay = 2 * time.Second
+
+// warmQuery is one line of the warm queries file
+type warmQuery struct {
+	Category model.Category
+	Text     string
+}
+
+// warmCache precomputes the queries in the warm queries file, one at a time,
+// so popular searches are answered from the result cache. A missing file
+// means the operator has not listed any queries.
+func (s *Server) warmCache(ctx context.Context) error {
+	if s.aggregator == nil {
+		return nil
+	}
+	cfg := s.config.Search.WarmQueries
+	if cfg.File == "" {
+		return nil
+	}
+	path := cfg.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.GetConfigDir(), path)
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	queries, err := parseWarmQueries(f, cfg.MaxQueries)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	delay := defaultWarmDelay
+	if d, err := time.ParseDuration(cfg.Delay); err == nil && d >= 0 {
+		delay = d
+	}
+
+	warmed, failed := 0, 0
+	for i, q := range queries {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		query := model.NewQuery(q.Text)
+		query.Category = q.Category
+		if _, err := s.aggregator.Refresh(ctx, query); err != nil && !errors.Is(err, model.ErrNoResults) {
+			failed++
+			slog.Debug("cache warm query failed", "query", q.Text, "category", q.Category, "err", err)
+			continue
+		}
+		warmed++
+	}
+	slog.Info("cache warm complete", "queries", len(queries), "warmed", warmed, "failed", failed)
+	return nil
+}
+
+// parseWarmQueries reads one query per line. "category: query" targets a
+// category when the prefix names one, so operators such as site: stay part
+// of the query. Blank lines, # comments and duplicates are skipped; at most
+// max queries are returned (0 means no limit).
+func parseWarmQueries(r io.Reader, max int) ([]warmQuery, error) {
+	var queries []warmQuery
+	seen := make(map[warmQuery]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		q := warmQuery{Category: model.CategoryGeneral, Text: line}
+		if prefix, rest, ok := strings.Cut(line, ":"); ok {
+			if category := model.Category(strings.ToLower(strings.TrimSpace(prefix))); category.IsValid() {
+				q = warmQuery{Category: category, Text: strings.TrimSpace(rest)}
+			}
+		}
+		q.Text = strings.Join(strings.Fields(q.Text), " ")
+		if q.Text == "" || seen[q] {
+			continue
+		}
+		seen[q] = true
+		queries = append(queries, q)
+		if max > 0 && len(queries) >= max {
+			break
+		}
+	}
+	return queries, scanner.Err()
+}
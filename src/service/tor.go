@@ -1,10 +1,9 @@
+//go:build !notor
+
 package service
 
 import (
 	"context"
-	"crypto/ed25519"
-	"crypto/rand"
-	"encoding/base32"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -19,7 +18,6 @@ import (
 	"github.com/apimgr/search/src/config"
 	"github.com/cretz/bine/control"
 	"github.com/cretz/bine/tor"
-	"golang.org/x/crypto/sha3"
 )
 
 // findTorBinary finds the Tor binary using config, PATH, or common locations
@@ -91,6 +89,8 @@ type TorService struct {
 	cancel    context.CancelFunc
 	dataDir   string
 	configDir string
+	// services are the additional hidden services that are published
+	services []HiddenService
 }
 
 // NewTorService creates a new Tor service manager
@@ -283,6 +283,11 @@ func (t *TorService) StartTorService() error {
 
 	torConfig := &t.config.Server.Tor
 
+	if torConfig.Disabled {
+		slog.Info("Tor disabled in server.yml, hidden service not started")
+		return nil
+	}
+
 	// Per AI.md PART 32: Find Tor binary first - auto-enable if found
 	torBinary := findTorBinary(torConfig.Binary)
 	if torBinary == "" {
@@ -395,31 +400,15 @@ func (t *TorService) StartTorService() error {
 
 	// Create hidden service via ADD_ONION control command
 	// Per AI.md PART 32: This forwards .onion:{virtual_port} → 127.0.0.1:serverPort
-	serverPort := t.config.Server.Port
-	virtualPort := torConfig.VirtualPort
-	if virtualPort == 0 {
-		virtualPort = 80
-	}
-
-	// Create public hidden service - no client authorization required
-	// Per AI.md PART 32: Service is accessible to anyone with the .onion address
-	// Not setting Flags or ClientAuthV3 = public service (no auth/login)
-	addOnionReq := &control.AddOnionRequest{
-		Ports: []*control.KeyVal{
-			control.NewKeyVal(fmt.Sprintf("%d", virtualPort), fmt.Sprintf("127.0.0.1:%d", serverPort)),
-		},
-	}
-
-	if existingKey != nil {
-		// Use existing key for persistent .onion address
-		addOnionReq.Key = existingKey
-	} else {
+	// The service is public unless client keys are enrolled (tor_clients.go)
+	var onionKey control.Key = existingKey
+	if existingKey == nil {
 		// Generate new ED25519-V3 key (v3 onion address)
-		addOnionReq.Key = control.GenKey(control.KeyAlgoED25519V3)
+		onionKey = control.GenKey(control.KeyAlgoED25519V3)
 	}
 
 	// Call ADD_ONION via control connection
-	resp, err := torInstance.Control.AddOnion(addOnionReq)
+	resp, err := t.addOnionLocked(torInstance.Control, onionKey)
 	if err != nil {
 		torInstance.Close()
 		return fmt.Errorf("failed to create hidden service: %w", err)
@@ -443,6 +432,9 @@ func (t *TorService) StartTorService() error {
 	t.onionAddr = resp.ServiceID + ".onion"
 	t.running = true
 
+	// Additional hidden services from tor.services (tor_services.go)
+	t.publishServicesLocked(torInstance.Control)
+
 	// Initialize outbound dialer if enabled
 	if torConfig.UseNetwork || torConfig.AllowUserPreference {
 		dialer, err := torInstance.Dialer(t.ctx, nil)
@@ -483,6 +475,7 @@ func (t *TorService) StopTorService() error {
 	t.running = false
 	t.onionAddr = ""
 	t.serviceID = ""
+	t.services = nil
 	slog.Info("Tor service stopped")
 	return nil
 }
@@ -558,21 +551,8 @@ func (t *TorService) RegenerateAddress() (string, error) {
 	keyPath := filepath.Join(t.dataDir, "site", "hs_ed25519_secret_key")
 	os.Remove(keyPath)
 
-	// Create new hidden service with new keys
-	serverPort := t.config.Server.Port
-	virtualPort := t.config.Server.Tor.VirtualPort
-	if virtualPort == 0 {
-		virtualPort = 80
-	}
-
-	addOnionReq := &control.AddOnionRequest{
-		Key: control.GenKey(control.KeyAlgoED25519V3),
-		Ports: []*control.KeyVal{
-			control.NewKeyVal(fmt.Sprintf("%d", virtualPort), fmt.Sprintf("127.0.0.1:%d", serverPort)),
-		},
-	}
-
-	resp, err := t.tor.Control.AddOnion(addOnionReq)
+	// Create new hidden service with new keys; enrolled clients keep access
+	resp, err := t.addOnionLocked(t.tor.Control, control.GenKey(control.KeyAlgoED25519V3))
 	if err != nil {
 		return "", fmt.Errorf("failed to create new hidden service: %w", err)
 	}
@@ -637,6 +617,9 @@ func (t *TorService) GetTorStatus() map[string]interface{} {
 		"running":       t.running,
 		"onion_address": t.onionAddr,
 	}
+	if len(t.services) > 0 {
+		status["services"] = t.services
+	}
 
 	if t.running && t.tor != nil {
 		// Get Tor version
@@ -669,180 +652,6 @@ func CheckTorConnection(socksAddr string) bool {
 	return true
 }
 
-// VanityProgress represents the progress of vanity address generation
-// Per AI.md PART 31: Vanity address generation (built-in, max 6 chars)
-type VanityProgress struct {
-	Prefix    string
-	Attempts  int64
-	StartTime time.Time
-	Running   bool
-	Found     bool
-	Address   string
-	// ED25519 private key for the found address — used by ApplyVanityAddress
-	PrivateKey []byte
-	Error      string
-}
-
-// vanityGenerator holds vanity generation state
-type vanityGenerator struct {
-	progress *VanityProgress
-	cancel   context.CancelFunc
-	mu       sync.RWMutex
-}
-
-var vanityGen = &vanityGenerator{
-	progress: &VanityProgress{},
-}
-
-// GetVanityProgress returns the current vanity generation progress
-func (t *TorService) GetVanityProgress() *VanityProgress {
-	vanityGen.mu.RLock()
-	defer vanityGen.mu.RUnlock()
-
-	pk := make([]byte, len(vanityGen.progress.PrivateKey))
-	copy(pk, vanityGen.progress.PrivateKey)
-	return &VanityProgress{
-		Prefix:     vanityGen.progress.Prefix,
-		Attempts:   vanityGen.progress.Attempts,
-		StartTime:  vanityGen.progress.StartTime,
-		Running:    vanityGen.progress.Running,
-		Found:      vanityGen.progress.Found,
-		Address:    vanityGen.progress.Address,
-		PrivateKey: pk,
-		Error:      vanityGen.progress.Error,
-	}
-}
-
-// GenerateVanity starts background vanity address generation
-// Per AI.md PART 32: Built-in generation supports max 6 character prefixes
-func (t *TorService) GenerateVanity(prefix string) error {
-	// Validate prefix length
-	if len(prefix) > 6 {
-		return fmt.Errorf("prefix too long: max 6 characters for built-in generation")
-	}
-
-	// Check for valid characters (base32 lowercase only)
-	validChars := "abcdefghijklmnopqrstuvwxyz234567"
-	for _, c := range prefix {
-		if !strings.ContainsRune(validChars, c) {
-			return fmt.Errorf("invalid character '%c' in prefix: must be lowercase a-z or 2-7", c)
-		}
-	}
-
-	// Cancel any existing generation
-	t.CancelVanity()
-
-	// Start new generation
-	ctx, cancel := context.WithCancel(context.Background())
-	vanityGen.mu.Lock()
-	vanityGen.cancel = cancel
-	vanityGen.progress = &VanityProgress{
-		Prefix:    prefix,
-		Attempts:  0,
-		StartTime: time.Now(),
-		Running:   true,
-	}
-	vanityGen.mu.Unlock()
-
-	go t.runVanityGeneration(ctx, prefix)
-
-	return nil
-}
-
-// runVanityGeneration runs the vanity generation in the background
-func (t *TorService) runVanityGeneration(ctx context.Context, prefix string) {
-	defer func() {
-		vanityGen.mu.Lock()
-		vanityGen.progress.Running = false
-		vanityGen.mu.Unlock()
-	}()
-
-	prefix = strings.ToLower(prefix)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			vanityGen.mu.Lock()
-			vanityGen.progress.Attempts++
-			vanityGen.mu.Unlock()
-
-			address, privKey, err := generateOnionV3Address()
-			if err != nil {
-				vanityGen.mu.Lock()
-				vanityGen.progress.Error = err.Error()
-				vanityGen.mu.Unlock()
-				continue
-			}
-
-			if strings.HasPrefix(address, prefix) {
-				vanityGen.mu.Lock()
-				vanityGen.progress.Found = true
-				vanityGen.progress.Address = address
-				vanityGen.progress.PrivateKey = privKey
-				vanityGen.mu.Unlock()
-				slog.Info("Tor vanity address found", "address", address+".onion", "attempts", vanityGen.progress.Attempts)
-				return
-			}
-		}
-	}
-}
-
-// generateOnionV3Address generates a fresh ED25519 key pair and derives its v3 onion address.
-// Returns (56-char base32 address without .onion, ed25519 private key, error).
-// Algorithm: address = base32(pubkey[32] || checksum[2] || version[1]) where
-// checksum = SHA3-256(".onion checksum" || pubkey || 0x03)[:2]
-func generateOnionV3Address() (string, []byte, error) {
-	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
-	if err != nil {
-		return "", nil, err
-	}
-
-	// Compute 2-byte checksum per Tor spec for v3 onion addresses
-	version := byte(0x03)
-	h := sha3.New256()
-	h.Write([]byte(".onion checksum"))
-	h.Write(pubKey)
-	h.Write([]byte{version})
-	checksum := h.Sum(nil)
-
-	// Build 35-byte payload: pubkey[32] + checksum[2] + version[1]
-	payload := make([]byte, 35)
-	copy(payload[0:32], pubKey)
-	copy(payload[32:34], checksum[:2])
-	payload[34] = version
-
-	// Base32-encode without padding → 56-char lowercase string
-	address := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(payload))
-
-	return address, []byte(privKey), nil
-}
-
-// CancelVanity cancels any running vanity generation
-func (t *TorService) CancelVanity() {
-	vanityGen.mu.Lock()
-	defer vanityGen.mu.Unlock()
-
-	if vanityGen.cancel != nil {
-		vanityGen.cancel()
-		vanityGen.cancel = nil
-	}
-	vanityGen.progress.Running = false
-}
-
-// ApplyVanityAddress applies the generated vanity address by importing its private key
-func (t *TorService) ApplyVanityAddress() (string, error) {
-	progress := t.GetVanityProgress()
-	if !progress.Found {
-		return "", fmt.Errorf("no vanity address has been generated")
-	}
-	if len(progress.PrivateKey) == 0 {
-		return "", fmt.Errorf("no private key available for vanity address")
-	}
-	return t.ImportKeys(progress.PrivateKey)
-}
-
 // ExportKeys exports the current Tor hidden service keys
 // Per AI.md PART 32: Key import/export for external vanity addresses
 func (t *TorService) ExportKeys() ([]byte, error) {
@@ -0,0 +1,399 @@
+//go:build !notor
+
+package service
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cretz/bine/control"
+)
+
+// Client authorization restricts the hidden service to enrolled Tor clients.
+// It follows Tor's own rule for authorized_clients: the service is restricted
+// while at least one client key is enrolled, and public when none are. Only
+// public keys are kept; the private key is handed out once at enrollment.
+
+// torClientAuthDir holds one <name>.auth file per client under the hidden
+// service directory, in the format tor reads from authorized_clients
+const torClientAuthDir = "authorized_clients"
+
+// torClientAuthExt is the extension of client public key files
+const torClientAuthExt = ".auth"
+
+// torClientTrashDir holds revoked client keys as <name>.<unix revoke
+// time>.auth, next to torClientAuthDir, until TorClientTrashRetention has
+// passed. The private key is never stored, so a key revoked by mistake
+// could otherwise only be replaced by enrolling the client again. See
+// TorClientTrashRetention for why this is not the shared trash.
+const torClientTrashDir = "authorized_clients_trash"
+
+// torClientNamePattern limits client names to safe file names
+var torClientNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// torKeyEncoding is the unpadded base32 tor uses for x25519 keys
+var torKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (t *TorService) clientAuthDir() string {
+	return filepath.Join(t.dataDir, "site", torClientAuthDir)
+}
+
+func (t *TorService) clientTrashDir() string {
+	return filepath.Join(t.dataDir, "site", torClientTrashDir)
+}
+
+// ListClientAuth returns the enrolled clients sorted by name
+func (t *TorService) ListClientAuth() ([]TorClient, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.listClientAuthLocked()
+}
+
+func (t *TorService) listClientAuthLocked() ([]TorClient, error) {
+	entries, err := os.ReadDir(t.clientAuthDir())
+	if os.IsNotExist(err) {
+		return []TorClient{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read authorized clients: %w", err)
+	}
+
+	clients := []TorClient{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), torClientAuthExt)
+		if !ok || entry.IsDir() || !torClientNamePattern.MatchString(name) {
+			continue
+		}
+		path := filepath.Join(t.clientAuthDir(), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read authorized client %s: %w", name, err)
+		}
+		pub, ok := parseClientAuthLine(string(data))
+		if !ok {
+			slog.Warn("Tor ignoring malformed authorized client", "client", name)
+			continue
+		}
+		client := TorClient{Name: name, PublicKey: pub}
+		if info, err := entry.Info(); err == nil {
+			client.AddedAt = info.ModTime().UTC()
+		}
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+	return clients, nil
+}
+
+// AddClientAuth enrolls a client under name and returns its credentials. A
+// running hidden service is re-published with the new key list, keeping its
+// address.
+func (t *TorService) AddClientAuth(name string) (*TorClientCredentials, error) {
+	if !torClientNamePattern.MatchString(name) {
+		return nil, ErrTorClientName
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := filepath.Join(t.clientAuthDir(), name+torClientAuthExt)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTorClientExists, name)
+	}
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate client key: %w", err)
+	}
+	creds := &TorClientCredentials{
+		TorClient: TorClient{
+			Name:      name,
+			PublicKey: torKeyEncoding.EncodeToString(key.PublicKey().Bytes()),
+			AddedAt:   time.Now().UTC(),
+		},
+		PrivateKey: torKeyEncoding.EncodeToString(key.Bytes()),
+	}
+
+	if err := os.MkdirAll(t.clientAuthDir(), 0700); err != nil {
+		return nil, fmt.Errorf("create authorized clients dir: %w", err)
+	}
+	line := "descriptor:x25519:" + creds.PublicKey + "\n"
+	if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+		return nil, fmt.Errorf("save client key: %w", err)
+	}
+
+	if err := t.republishLocked(); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	if t.serviceID != "" {
+		creds.AuthFile = t.serviceID + ":descriptor:x25519:" + creds.PrivateKey
+	}
+	slog.Info("Tor client authorized", "client", name)
+	return creds, nil
+}
+
+// RevokeClientAuth removes a client's key. A running hidden service is
+// re-published without it, so the client loses access at once. Revoking
+// the last client makes the service public again. The key moves to the
+// trash, from which RestoreClientAuth can bring it back until
+// TorClientTrashRetention has passed.
+func (t *TorService) RevokeClientAuth(name string) error {
+	if !torClientNamePattern.MatchString(name) {
+		return ErrTorClientNotFound
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := filepath.Join(t.clientAuthDir(), name+torClientAuthExt)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrTorClientNotFound, name)
+	}
+	if err := os.MkdirAll(t.clientTrashDir(), 0700); err != nil {
+		return fmt.Errorf("create client trash dir: %w", err)
+	}
+	trashed := filepath.Join(t.clientTrashDir(), fmt.Sprintf("%s.%d%s", name, time.Now().Unix(), torClientAuthExt))
+	if err := os.Rename(path, trashed); err != nil {
+		return fmt.Errorf("remove client key: %w", err)
+	}
+
+	if err := t.republishLocked(); err != nil {
+		// Put the key back so the file list matches the published service
+		os.Rename(trashed, path)
+		return err
+	}
+	slog.Info("Tor client revoked", "client", name)
+	return nil
+}
+
+// ListRevokedClientAuth returns the revoked clients that can still be
+// restored, newest first, deleting those past TorClientTrashRetention
+func (t *TorService) ListRevokedClientAuth() ([]RevokedTorClient, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.listRevokedClientAuthLocked(time.Now())
+}
+
+// PurgeRevokedClientAuth deletes the revoked clients past
+// TorClientTrashRetention; the retention task calls it
+func (t *TorService) PurgeRevokedClientAuth() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.listRevokedClientAuthLocked(time.Now())
+	return err
+}
+
+func (t *TorService) listRevokedClientAuthLocked(now time.Time) ([]RevokedTorClient, error) {
+	entries, err := os.ReadDir(t.clientTrashDir())
+	if os.IsNotExist(err) {
+		return []RevokedTorClient{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read revoked clients: %w", err)
+	}
+
+	revoked := []RevokedTorClient{}
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), torClientAuthExt)
+		name, stamp, found := strings.Cut(base, ".")
+		unix, err := strconv.ParseInt(stamp, 10, 64)
+		if !ok || !found || err != nil || entry.IsDir() || !torClientNamePattern.MatchString(name) {
+			continue
+		}
+		path := filepath.Join(t.clientTrashDir(), entry.Name())
+		client := RevokedTorClient{
+			TorClient: TorClient{Name: name},
+			RevokedAt: time.Unix(unix, 0).UTC(),
+		}
+		client.PurgeAt = client.RevokedAt.Add(TorClientTrashRetention)
+		if !now.Before(client.PurgeAt) {
+			if err := os.Remove(path); err != nil {
+				slog.Warn("Tor revoked client not purged", "client", name, "err", err)
+			}
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read revoked client %s: %w", name, err)
+		}
+		pub, ok := parseClientAuthLine(string(data))
+		if !ok {
+			continue
+		}
+		client.PublicKey = pub
+		if info, err := entry.Info(); err == nil {
+			client.AddedAt = info.ModTime().UTC()
+		}
+		revoked = append(revoked, client)
+	}
+	sort.Slice(revoked, func(i, j int) bool { return revoked[i].RevokedAt.After(revoked[j].RevokedAt) })
+	return revoked, nil
+}
+
+// RestoreClientAuth re-enrolls the most recently revoked key of name, so
+// the client regains access with the private key it already holds
+func (t *TorService) RestoreClientAuth(name string) (*TorClient, error) {
+	if !torClientNamePattern.MatchString(name) {
+		return nil, ErrTorClientNotFound
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := filepath.Join(t.clientAuthDir(), name+torClientAuthExt)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTorClientExists, name)
+	}
+	revoked, err := t.listRevokedClientAuthLocked(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range revoked {
+		if client.Name != name {
+			continue
+		}
+		trashed := filepath.Join(t.clientTrashDir(), fmt.Sprintf("%s.%d%s", name, client.RevokedAt.Unix(), torClientAuthExt))
+		if err := os.MkdirAll(t.clientAuthDir(), 0700); err != nil {
+			return nil, fmt.Errorf("create authorized clients dir: %w", err)
+		}
+		if err := os.Rename(trashed, path); err != nil {
+			return nil, fmt.Errorf("restore client key: %w", err)
+		}
+		if err := t.republishLocked(); err != nil {
+			os.Rename(path, trashed)
+			return nil, err
+		}
+		slog.Info("Tor client restored", "client", name)
+		return &client.TorClient, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTorClientNotFound, name)
+}
+
+// clientAuthKeysLocked returns the enrolled public keys for ADD_ONION
+func (t *TorService) clientAuthKeysLocked() ([]string, error) {
+	clients, err := t.listClientAuthLocked()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(clients))
+	for _, c := range clients {
+		keys = append(keys, c.PublicKey)
+	}
+	return keys, nil
+}
+
+// republishLocked replaces the running hidden service with one carrying the
+// current client list. The stored key keeps the address unchanged. It is a
+// no-op while Tor is not running; the list is applied at the next start.
+func (t *TorService) republishLocked() error {
+	if !t.running || t.tor == nil {
+		return nil
+	}
+	keyData, err := os.ReadFile(filepath.Join(t.dataDir, "site", "hs_ed25519_secret_key"))
+	if err != nil {
+		return fmt.Errorf("read hidden service key: %w", err)
+	}
+	key, err := control.ED25519KeyFromBlob(strings.TrimSpace(string(keyData)))
+	if err != nil {
+		return fmt.Errorf("parse hidden service key: %w", err)
+	}
+
+	if t.serviceID != "" {
+		if err := t.tor.Control.DelOnion(t.serviceID); err != nil {
+			slog.Warn("Tor failed to remove hidden service", "err", err)
+		}
+	}
+	resp, err := t.addOnionLocked(t.tor.Control, key)
+	if err != nil {
+		return fmt.Errorf("re-publish hidden service: %w", err)
+	}
+	t.serviceID = resp.ServiceID
+	t.onionAddr = resp.ServiceID + ".onion"
+	return nil
+}
+
+// addOnionLocked publishes the hidden service with key, restricted to the
+// enrolled clients if there are any
+func (t *TorService) addOnionLocked(conn *control.Conn, key control.Key) (*control.AddOnionResponse, error) {
+	virtualPort := t.config.Server.Tor.VirtualPort
+	if virtualPort == 0 {
+		virtualPort = 80
+	}
+	req := &control.AddOnionRequest{
+		Key: key,
+		Ports: []*control.KeyVal{
+			control.NewKeyVal(fmt.Sprintf("%d", virtualPort), fmt.Sprintf("127.0.0.1:%d", t.config.Server.Port)),
+		},
+	}
+
+	clientKeys, err := t.clientAuthKeysLocked()
+	if err != nil {
+		return nil, err
+	}
+	if len(clientKeys) == 0 {
+		return conn.AddOnion(req)
+	}
+	return addOnionV3Auth(conn, req, clientKeys)
+}
+
+// addOnionV3Auth sends ADD_ONION with v3 client authorization, which the
+// control library does not support (its ClientAuths are the v2 form)
+func addOnionV3Auth(conn *control.Conn, req *control.AddOnionRequest, clientKeys []string) (*control.AddOnionResponse, error) {
+	resp, err := conn.SendRequest("%s", addOnionV3AuthCommand(req, clientKeys))
+	if err != nil {
+		return nil, err
+	}
+	ret := &control.AddOnionResponse{RawResponse: resp}
+	for _, data := range resp.Data {
+		key, val, _ := strings.Cut(data, "=")
+		switch key {
+		case "ServiceID":
+			ret.ServiceID = val
+		case "PrivateKey":
+			if ret.Key, err = control.KeyFromString(val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ret, nil
+}
+
+func addOnionV3AuthCommand(req *control.AddOnionRequest, clientKeys []string) string {
+	var b strings.Builder
+	b.WriteString("ADD_ONION " + string(req.Key.Type()) + ":" + req.Key.Blob())
+	b.WriteString(" Flags=" + strings.Join(append(append([]string{}, req.Flags...), "V3Auth"), ","))
+	for _, port := range req.Ports {
+		b.WriteString(" Port=" + port.Key)
+		if port.Val != "" {
+			b.WriteString("," + port.Val)
+		}
+	}
+	for _, k := range clientKeys {
+		b.WriteString(" ClientAuthV3=" + k)
+	}
+	return b.String()
+}
+
+// parseClientAuthLine returns the public key of a "descriptor:x25519:<key>"
+// line, the format of tor's authorized_clients files
+func parseClientAuthLine(line string) (string, bool) {
+	pub, ok := strings.CutPrefix(strings.TrimSpace(line), "descriptor:x25519:")
+	if !ok {
+		return "", false
+	}
+	raw, err := torKeyEncoding.DecodeString(pub)
+	if err != nil || len(raw) != 32 {
+		return "", false
+	}
+	return pub, true
+}
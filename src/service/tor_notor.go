@@ -0,0 +1,93 @@
+//go:build notor
+
+package service
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// errTorNotCompiled is returned by every Tor operation in a notor build
+var errTorNotCompiled = errors.New("tor support is not compiled into this binary")
+
+// TorService is empty in a notor build. NewTorService returns nil, so
+// callers take the same path as when no tor binary is installed.
+type TorService struct{}
+
+// NewTorService returns nil: this binary was built without Tor support
+func NewTorService(cfg *config.Config) *TorService {
+	slog.Info("Tor support is not compiled in (built with -tags notor)")
+	return nil
+}
+
+// StartTorService is not supported in a notor build.
+func (t *TorService) StartTorService() error {
+	return errTorNotCompiled
+}
+
+// StopTorService does nothing in a notor build.
+func (t *TorService) StopTorService() error {
+	return nil
+}
+
+// RestartTorService is not supported in a notor build.
+func (t *TorService) RestartTorService() error {
+	return errTorNotCompiled
+}
+
+// IsRunning is always false in a notor build.
+func (t *TorService) IsRunning() bool {
+	return false
+}
+
+// GetOnionAddress is always empty in a notor build.
+func (t *TorService) GetOnionAddress() string {
+	return ""
+}
+
+// HiddenServices is always empty in a notor build.
+func (t *TorService) HiddenServices() []HiddenService {
+	return nil
+}
+
+// ServiceScope is config.TorScopeAll in a notor build.
+func (t *TorService) ServiceScope(host string) string {
+	return config.TorScopeAll
+}
+
+// ResumeVanity does nothing in a notor build.
+func (t *TorService) ResumeVanity() error {
+	return nil
+}
+
+// ListClientAuth is not supported in a notor build.
+func (t *TorService) ListClientAuth() ([]TorClient, error) {
+	return nil, errTorNotCompiled
+}
+
+// AddClientAuth is not supported in a notor build.
+func (t *TorService) AddClientAuth(name string) (*TorClientCredentials, error) {
+	return nil, errTorNotCompiled
+}
+
+// RevokeClientAuth is not supported in a notor build.
+func (t *TorService) RevokeClientAuth(name string) error {
+	return errTorNotCompiled
+}
+
+// ListRevokedClientAuth is not supported in a notor build.
+func (t *TorService) ListRevokedClientAuth() ([]RevokedTorClient, error) {
+	return nil, errTorNotCompiled
+}
+
+// PurgeRevokedClientAuth has nothing to purge in a notor build.
+func (t *TorService) PurgeRevokedClientAuth() error {
+	return nil
+}
+
+// RestoreClientAuth is not supported in a notor build.
+func (t *TorService) RestoreClientAuth(name string) (*TorClient, error) {
+	return nil, errTorNotCompiled
+}
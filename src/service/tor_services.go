@@ -0,0 +1,119 @@
+//go:build !notor
+
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/cretz/bine/control"
+)
+
+// torServicesDir holds the keys of additional hidden services, one
+// directory per service name, next to the main service's site/ directory
+const torServicesDir = "services"
+
+// mainHiddenService names the main onion in HiddenServices
+const mainHiddenService = "site"
+
+// HiddenServices returns the main onion followed by the additional services
+// that are published. Empty while Tor is not running.
+func (t *TorService) HiddenServices() []HiddenService {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return []HiddenService{}
+	}
+	virtualPort := t.config.Server.Tor.VirtualPort
+	if virtualPort == 0 {
+		virtualPort = 80
+	}
+	services := []HiddenService{{
+		Name:         mainHiddenService,
+		OnionAddress: t.onionAddr,
+		VirtualPort:  virtualPort,
+		Scope:        config.TorScopeAll,
+	}}
+	return append(services, t.services...)
+}
+
+// ServiceScope returns the scope of the onion a request was made to. Hosts
+// that are not an additional service, including the main onion, serve
+// every route.
+func (t *TorService) ServiceScope(host string) string {
+	host = strings.ToLower(host)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, svc := range t.services {
+		if svc.OnionAddress == host {
+			return svc.Scope
+		}
+	}
+	return config.TorScopeAll
+}
+
+// publishServicesLocked publishes the enabled additional hidden services.
+// A service that fails is logged and skipped; the main onion stays up.
+func (t *TorService) publishServicesLocked(conn *control.Conn) {
+	t.services = nil
+	for _, cfg := range t.config.Server.Tor.Services {
+		if !cfg.Enabled {
+			continue
+		}
+		svc, err := t.publishService(conn, cfg)
+		if err != nil {
+			slog.Warn("Tor additional hidden service failed", "service", cfg.Name, "err", err)
+			continue
+		}
+		t.services = append(t.services, *svc)
+		slog.Info("Tor additional hidden service active", "service", svc.Name, "onion_address", svc.OnionAddress, "scope", svc.Scope)
+	}
+}
+
+// publishService publishes one additional service with its stored key,
+// generating and saving a key on first start
+func (t *TorService) publishService(conn *control.Conn, cfg config.TorServiceConfig) (*HiddenService, error) {
+	keyPath := filepath.Join(t.dataDir, torServicesDir, cfg.Name, "hs_ed25519_secret_key")
+
+	var key control.Key = control.GenKey(control.KeyAlgoED25519V3)
+	existing := false
+	if keyData, err := os.ReadFile(keyPath); err == nil && len(keyData) > 0 {
+		k, err := control.ED25519KeyFromBlob(strings.TrimSpace(string(keyData)))
+		if err != nil {
+			return nil, fmt.Errorf("parse key: %w", err)
+		}
+		key = k
+		existing = true
+	}
+
+	resp, err := conn.AddOnion(&control.AddOnionRequest{
+		Key: key,
+		Ports: []*control.KeyVal{
+			control.NewKeyVal(fmt.Sprintf("%d", cfg.VirtualPort), fmt.Sprintf("127.0.0.1:%d", t.config.Server.Port)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !existing && resp.Key != nil {
+		if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+			return nil, fmt.Errorf("create key dir: %w", err)
+		}
+		if err := os.WriteFile(keyPath, []byte(resp.Key.Blob()), 0600); err != nil {
+			slog.Warn("Tor failed to save hidden service key", "service", cfg.Name, "err", err)
+		}
+	}
+
+	return &HiddenService{
+		Name:         cfg.Name,
+		OnionAddress: resp.ServiceID + ".onion",
+		VirtualPort:  cfg.VirtualPort,
+		Scope:        cfg.Scope,
+	}, nil
+}
@@ -1,16 +1,21 @@
+//go:build !notor
+
 package service
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/apimgr/search/src/config"
+	"github.com/cretz/bine/control"
 )
 
 func TestNewTorService(t *testing.T) {
@@ -1390,18 +1395,24 @@ func TestRunVanityGenerationFindsMatch(t *testing.T) {
 	defer cancel()
 
 	// Initialise global state exactly as GenerateVanity does, but synchronously.
+	attempts := new(atomic.Int64)
 	vanityGen.mu.Lock()
+	vanityGen.job++
+	job := vanityGen.job
+	vanityGen.attempts = attempts
+	vanityGen.runStart = time.Now()
 	vanityGen.progress = &VanityProgress{
 		Prefix:    "a",
 		Attempts:  0,
 		StartTime: time.Now(),
 		Running:   true,
+		Workers:   1,
 	}
 	vanityGen.cancel = cancel
 	vanityGen.mu.Unlock()
 
 	// Call the worker directly in the test goroutine; it blocks until found or ctx done.
-	ts.runVanityGeneration(ctx, "a")
+	ts.runVanityGeneration(ctx, job, "a", 1, attempts)
 
 	progress := ts.GetVanityProgress()
 	if !progress.Found {
@@ -1505,3 +1516,257 @@ func TestEnsureTorDirsFailure(t *testing.T) {
 		t.Error("ensureTorDirs() should fail when dataDir is occupied by a regular file")
 	}
 }
+
+// resetVanity clears the package-level vanity generator before and after a
+// test
+func resetVanity(t *testing.T) {
+	reset := func() {
+		vanityGen.mu.Lock()
+		vanityGen.stopLocked()
+		vanityGen.progress = &VanityProgress{}
+		vanityGen.statePath = ""
+		vanityGen.mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestVanityWorkersLimit(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ts := NewTorService(cfg)
+
+	if got := ts.vanityWorkers(); got != runtime.NumCPU() {
+		t.Errorf("vanityWorkers() = %d, want %d (all cores)", got, runtime.NumCPU())
+	}
+	cfg.Server.Tor.VanityWorkers = 1
+	if got := ts.vanityWorkers(); got != 1 {
+		t.Errorf("vanityWorkers() with limit 1 = %d, want 1", got)
+	}
+	cfg.Server.Tor.VanityWorkers = runtime.NumCPU() + 8
+	if got := ts.vanityWorkers(); got != runtime.NumCPU() {
+		t.Errorf("vanityWorkers() above core count = %d, want %d", got, runtime.NumCPU())
+	}
+}
+
+// TestVanityQueuePersists runs two queued single-character jobs and checks
+// both results survive a reload from the state file.
+func TestVanityQueuePersists(t *testing.T) {
+	resetVanity(t)
+	ts := NewTorService(config.DefaultConfig())
+	ts.dataDir = t.TempDir()
+
+	if err := ts.QueueVanity("a"); err != nil {
+		t.Fatalf("QueueVanity(a) error = %v", err)
+	}
+	if err := ts.QueueVanity("b"); err != nil {
+		t.Fatalf("QueueVanity(b) error = %v", err)
+	}
+	if err := ts.QueueVanity("ABC"); err == nil {
+		t.Error("QueueVanity(ABC) should fail for invalid characters")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	var progress *VanityProgress
+	for {
+		progress = ts.GetVanityProgress()
+		if len(progress.Results) == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(progress.Results) != 2 {
+		t.Fatalf("Results = %d after 10s, want 2", len(progress.Results))
+	}
+	for i, prefix := range []string{"a", "b"} {
+		if r := progress.Results[i]; r.Prefix != prefix || !strings.HasPrefix(r.Address, prefix) || r.Attempts == 0 {
+			t.Errorf("Results[%d] = %+v, want a match for %q", i, r, prefix)
+		}
+	}
+
+	vanityGen.mu.Lock()
+	vanityGen.progress = &VanityProgress{}
+	vanityGen.mu.Unlock()
+	if err := ts.ResumeVanity(); err != nil {
+		t.Fatalf("ResumeVanity() error = %v", err)
+	}
+	if got := ts.GetVanityProgress(); len(got.Results) != 2 || got.Running {
+		t.Errorf("after resume: Results = %d, Running = %v; want 2 results, idle", len(got.Results), got.Running)
+	}
+}
+
+// TestVanityResumeUnfinishedJob checks an interrupted job continues from its
+// saved attempts and reports a rate and ETA.
+func TestVanityResumeUnfinishedJob(t *testing.T) {
+	resetVanity(t)
+	cfg := config.DefaultConfig()
+	cfg.Server.Tor.VanityWorkers = 1
+	ts := NewTorService(cfg)
+	ts.dataDir = t.TempDir()
+
+	state := `{"prefix":"234567","attempts":1000,"elapsed":10000000000,"queue":["abc"]}`
+	if err := os.WriteFile(filepath.Join(ts.dataDir, vanityStateFile), []byte(state), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.ResumeVanity(); err != nil {
+		t.Fatalf("ResumeVanity() error = %v", err)
+	}
+
+	progress := ts.GetVanityProgress()
+	if !progress.Running || progress.Prefix != "234567" || progress.Workers != 1 {
+		t.Fatalf("resumed progress = %+v, want job 234567 running on 1 worker", progress)
+	}
+	if progress.Attempts < 1000 || progress.Elapsed < 10*time.Second {
+		t.Errorf("Attempts = %d, Elapsed = %v; want at least the saved 1000 and 10s", progress.Attempts, progress.Elapsed)
+	}
+	if progress.Rate <= 0 || progress.ETA <= 0 {
+		t.Errorf("Rate = %v, ETA = %v; want both positive", progress.Rate, progress.ETA)
+	}
+	if len(progress.Queue) != 1 || progress.Queue[0] != "abc" {
+		t.Errorf("Queue = %v, want [abc]", progress.Queue)
+	}
+
+	ts.CancelVanity()
+	data, err := os.ReadFile(filepath.Join(ts.dataDir, vanityStateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "234567") || strings.Contains(string(data), "abc") {
+		t.Errorf("state after cancel = %s, want no job or queue", data)
+	}
+}
+
+func TestClientAuthEnrollAndRevoke(t *testing.T) {
+	ts := NewTorService(config.DefaultConfig())
+	ts.dataDir = t.TempDir()
+
+	if clients, err := ts.ListClientAuth(); err != nil || len(clients) != 0 {
+		t.Fatalf("ListClientAuth() on empty dir = %v, %v; want none", clients, err)
+	}
+
+	creds, err := ts.AddClientAuth("laptop")
+	if err != nil {
+		t.Fatalf("AddClientAuth() error = %v", err)
+	}
+	if len(creds.PublicKey) != 52 || len(creds.PrivateKey) != 52 {
+		t.Errorf("keys = %q / %q, want 52-char base32", creds.PublicKey, creds.PrivateKey)
+	}
+	if creds.AuthFile != "" {
+		t.Errorf("AuthFile = %q while Tor is stopped, want empty", creds.AuthFile)
+	}
+	data, err := os.ReadFile(filepath.Join(ts.dataDir, "site", "authorized_clients", "laptop.auth"))
+	if err != nil {
+		t.Fatalf("client key file not written: %v", err)
+	}
+	if got, want := strings.TrimSpace(string(data)), "descriptor:x25519:"+creds.PublicKey; got != want {
+		t.Errorf("client key file = %q, want %q", got, want)
+	}
+	if strings.Contains(string(data), creds.PrivateKey) {
+		t.Error("client key file contains the private key")
+	}
+
+	if _, err := ts.AddClientAuth("laptop"); err == nil {
+		t.Error("AddClientAuth() with a duplicate name succeeded")
+	}
+	for _, name := range []string{"", "../escape", "has space", strings.Repeat("a", 33)} {
+		if _, err := ts.AddClientAuth(name); err == nil {
+			t.Errorf("AddClientAuth(%q) succeeded, want error", name)
+		}
+	}
+
+	clients, err := ts.ListClientAuth()
+	if err != nil || len(clients) != 1 || clients[0].Name != "laptop" || clients[0].PublicKey != creds.PublicKey {
+		t.Fatalf("ListClientAuth() = %+v, %v; want the laptop key", clients, err)
+	}
+
+	if err := ts.RevokeClientAuth("laptop"); err != nil {
+		t.Fatalf("RevokeClientAuth() error = %v", err)
+	}
+	if err := ts.RevokeClientAuth("laptop"); err == nil {
+		t.Error("RevokeClientAuth() of a removed client succeeded")
+	}
+	if clients, _ := ts.ListClientAuth(); len(clients) != 0 {
+		t.Errorf("ListClientAuth() after revoke = %+v, want none", clients)
+	}
+}
+
+func TestClientAuthTrashRestore(t *testing.T) {
+	ts := NewTorService(config.DefaultConfig())
+	ts.dataDir = t.TempDir()
+
+	creds, err := ts.AddClientAuth("phone")
+	if err != nil {
+		t.Fatalf("AddClientAuth() error = %v", err)
+	}
+	if err := ts.RevokeClientAuth("phone"); err != nil {
+		t.Fatalf("RevokeClientAuth() error = %v", err)
+	}
+	revoked, err := ts.ListRevokedClientAuth()
+	if err != nil || len(revoked) != 1 || revoked[0].Name != "phone" || revoked[0].PublicKey != creds.PublicKey {
+		t.Fatalf("ListRevokedClientAuth() = %+v, %v; want the phone key", revoked, err)
+	}
+	if got := revoked[0].PurgeAt.Sub(revoked[0].RevokedAt); got != TorClientTrashRetention {
+		t.Errorf("purge after %v, want %v", got, TorClientTrashRetention)
+	}
+
+	restored, err := ts.RestoreClientAuth("phone")
+	if err != nil || restored.PublicKey != creds.PublicKey {
+		t.Fatalf("RestoreClientAuth() = %+v, %v", restored, err)
+	}
+	if clients, _ := ts.ListClientAuth(); len(clients) != 1 {
+		t.Errorf("ListClientAuth() after restore = %+v, want the phone key", clients)
+	}
+	if _, err := ts.RestoreClientAuth("phone"); !errors.Is(err, ErrTorClientExists) {
+		t.Errorf("RestoreClientAuth() of an enrolled client error = %v, want ErrTorClientExists", err)
+	}
+
+	// Keys past the retention window are purged, not restored
+	if err := ts.RevokeClientAuth("phone"); err != nil {
+		t.Fatalf("RevokeClientAuth() error = %v", err)
+	}
+	revoked, _ = ts.listRevokedClientAuthLocked(time.Now().Add(TorClientTrashRetention + time.Minute))
+	if len(revoked) != 0 {
+		t.Errorf("expired trash = %+v, want purged", revoked)
+	}
+	if _, err := ts.RestoreClientAuth("phone"); !errors.Is(err, ErrTorClientNotFound) {
+		t.Errorf("RestoreClientAuth() of a purged client error = %v, want ErrTorClientNotFound", err)
+	}
+}
+
+func TestAddOnionV3AuthCommand(t *testing.T) {
+	req := &control.AddOnionRequest{
+		Key:   control.GenKey(control.KeyAlgoED25519V3),
+		Ports: []*control.KeyVal{control.NewKeyVal("80", "127.0.0.1:8080")},
+	}
+	cmd := addOnionV3AuthCommand(req, []string{"AAAA", "BBBB"})
+	for _, want := range []string{"ADD_ONION NEW:ED25519-V3 ", "Flags=V3Auth", " Port=80,127.0.0.1:8080", " ClientAuthV3=AAAA", " ClientAuthV3=BBBB"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command %q missing %q", cmd, want)
+		}
+	}
+
+	if _, ok := parseClientAuthLine("descriptor:x25519:short"); ok {
+		t.Error("parseClientAuthLine accepted a short key")
+	}
+}
+
+func TestHiddenServicesAndScope(t *testing.T) {
+	ts := NewTorService(config.DefaultConfig())
+	if got := ts.HiddenServices(); len(got) != 0 {
+		t.Errorf("HiddenServices() while stopped = %+v, want none", got)
+	}
+
+	ts.running = true
+	ts.onionAddr = "mainmainmain.onion"
+	ts.services = []HiddenService{{Name: "api", OnionAddress: "apiapiapi.onion", VirtualPort: 80, Scope: config.TorScopeAPI}}
+
+	got := ts.HiddenServices()
+	if len(got) != 2 || got[0].Name != mainHiddenService || got[0].OnionAddress != "mainmainmain.onion" || got[1].Name != "api" {
+		t.Errorf("HiddenServices() = %+v, want main then api", got)
+	}
+	if scope := ts.ServiceScope("APIAPIAPI.onion"); scope != config.TorScopeAPI {
+		t.Errorf("ServiceScope(api onion) = %q, want %q", scope, config.TorScopeAPI)
+	}
+	if scope := ts.ServiceScope("mainmainmain.onion"); scope != config.TorScopeAll {
+		t.Errorf("ServiceScope(main onion) = %q, want %q", scope, config.TorScopeAll)
+	}
+}
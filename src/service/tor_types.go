@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/apimgr/search/src/trash"
+)
+
+// Declarations shared by the Tor service and the notor stub, so callers
+// build the same way whether or not Tor support is compiled in
+
+// TorClientTrashRetention is how long a revoked client can be restored:
+// the retention of the shared trash. Revoked keys are the one exception to
+// that trash: they stay files in the hidden service directory, next to the
+// enrolled ones tor reads, because the Tor service has no database handle,
+// starts before the server database is open, and its keys are backed up
+// and moved with that directory rather than with the database.
+const TorClientTrashRetention = trash.Retention
+
+// Client authorization errors, for callers that map them to API statuses
+var (
+	ErrTorClientName     = errors.New("client name must be 1-32 letters, digits, '-' or '_'")
+	ErrTorClientExists   = errors.New("client already exists")
+	ErrTorClientNotFound = errors.New("client not found")
+)
+
+// TorClient is an enrolled client authorization key
+type TorClient struct {
+	Name      string    `json:"name"`
+	PublicKey string    `json:"public_key"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// RevokedTorClient is a revoked client key that can still be restored
+type RevokedTorClient struct {
+	TorClient
+	RevokedAt time.Time `json:"revoked_at"`
+	// PurgeAt is when the key is deleted for good
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TorClientCredentials is returned once, when a client is enrolled. The
+// private key is not stored on the server.
+type TorClientCredentials struct {
+	TorClient
+	PrivateKey string `json:"private_key"`
+	// AuthFile is the line Tor Browser or a tor client stores in its
+	// ClientOnionAuthDir as <name>.auth_private. Empty while the onion
+	// address is unknown; the private key alone can then be pasted into Tor
+	// Browser's prompt.
+	AuthFile string `json:"auth_file,omitempty"`
+}
+
+// HiddenService is a published onion
+type HiddenService struct {
+	Name         string `json:"name"`
+	OnionAddress string `json:"onion_address"`
+	VirtualPort  int    `json:"virtual_port"`
+	// Scope is config.TorScopeAll or config.TorScopeAPI
+	Scope string `json:"scope"`
+}
@@ -0,0 +1,454 @@
+//go:build !notor
+
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// vanityStateFile holds vanity jobs in the Tor data directory, so progress
+// survives restarts
+const vanityStateFile = "vanity.json"
+
+// vanityCheckpointInterval is how often a running job's progress is saved
+const vanityCheckpointInterval = 30 * time.Second
+
+// vanityAlphabet is the base32 alphabet of onion addresses
+const vanityAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// VanityProgress represents the progress of vanity address generation
+// Per AI.md PART 31: Vanity address generation (built-in, max 6 chars)
+type VanityProgress struct {
+	Prefix string
+	// Attempts counts keys tried for Prefix, including runs before a restart
+	Attempts  int64
+	StartTime time.Time
+	Running   bool
+	Found     bool
+	Address   string
+	// ED25519 private key for the found address — used by ApplyVanityAddress
+	PrivateKey []byte
+	Error      string
+	// Workers is the number of goroutines searching: one per CPU core, capped
+	// by tor.vanity_workers
+	Workers int
+	// Elapsed is the time spent searching for Prefix, across restarts
+	Elapsed time.Duration
+	// Rate is attempts per second over Elapsed
+	Rate float64
+	// ETA is the expected time to a match at Rate. Attempts are independent,
+	// so it does not shrink as attempts accumulate.
+	ETA time.Duration
+	// Queue holds prefixes waiting for the current job to finish
+	Queue []string
+	// Results holds the addresses found by finished jobs, oldest first
+	Results []VanityResult
+}
+
+// VanityResult is an address found by a finished vanity job
+type VanityResult struct {
+	Prefix     string        `json:"prefix"`
+	Address    string        `json:"address"`
+	PrivateKey []byte        `json:"private_key"`
+	Attempts   int64         `json:"attempts"`
+	Elapsed    time.Duration `json:"elapsed"`
+	FoundAt    time.Time     `json:"found_at"`
+}
+
+// vanityState is the persisted form of the generator
+type vanityState struct {
+	// Prefix is the unfinished job, resumed from Attempts and Elapsed
+	Prefix   string         `json:"prefix,omitempty"`
+	Attempts int64          `json:"attempts,omitempty"`
+	Elapsed  time.Duration  `json:"elapsed,omitempty"`
+	Queue    []string       `json:"queue,omitempty"`
+	Results  []VanityResult `json:"results,omitempty"`
+}
+
+// vanityGenerator holds vanity generation state
+type vanityGenerator struct {
+	progress *VanityProgress
+	cancel   context.CancelFunc
+	// job identifies the current job, so goroutines of a cancelled job
+	// cannot update its successor
+	job int64
+	// attempts counts the current run; progress.Attempts holds earlier runs
+	attempts *atomic.Int64
+	// runStart is when the current run began; progress.Elapsed holds earlier runs
+	runStart time.Time
+	// statePath is where jobs are saved; empty until a job is started
+	statePath string
+	mu        sync.RWMutex
+}
+
+var vanityGen = &vanityGenerator{
+	progress: &VanityProgress{},
+	attempts: new(atomic.Int64),
+}
+
+// GetVanityProgress returns the current vanity generation progress
+func (t *TorService) GetVanityProgress() *VanityProgress {
+	vanityGen.mu.RLock()
+	defer vanityGen.mu.RUnlock()
+
+	progress := *vanityGen.progress
+	progress.PrivateKey = append(make([]byte, 0, len(progress.PrivateKey)), progress.PrivateKey...)
+	progress.Queue = append([]string(nil), progress.Queue...)
+	progress.Results = append([]VanityResult(nil), progress.Results...)
+	progress.Attempts, progress.Elapsed = vanityGen.liveLocked()
+
+	if secs := progress.Elapsed.Seconds(); secs > 0 {
+		progress.Rate = float64(progress.Attempts) / secs
+	}
+	if progress.Running && progress.Rate > 0 {
+		// 32^len(prefix) attempts are expected per match
+		eta := math.Pow(float64(len(vanityAlphabet)), float64(len(progress.Prefix))) / progress.Rate
+		if eta < float64(math.MaxInt64)/float64(time.Second) {
+			progress.ETA = time.Duration(eta * float64(time.Second))
+		}
+	}
+	return &progress
+}
+
+// GenerateVanity starts background vanity address generation, replacing the
+// current job. Queued prefixes still run after it.
+// Per AI.md PART 32: Built-in generation supports max 6 character prefixes
+func (t *TorService) GenerateVanity(prefix string) error {
+	if err := validateVanityPrefix(prefix); err != nil {
+		return err
+	}
+
+	vanityGen.mu.Lock()
+	defer vanityGen.mu.Unlock()
+	vanityGen.stopLocked()
+	t.startVanityLocked(prefix, 0, 0)
+	vanityGen.saveLocked()
+	return nil
+}
+
+// QueueVanity adds a prefix to the job queue. It starts at once when no job
+// is running.
+func (t *TorService) QueueVanity(prefix string) error {
+	if err := validateVanityPrefix(prefix); err != nil {
+		return err
+	}
+
+	vanityGen.mu.Lock()
+	defer vanityGen.mu.Unlock()
+	if vanityGen.progress.Running {
+		vanityGen.progress.Queue = append(vanityGen.progress.Queue, prefix)
+	} else {
+		t.startVanityLocked(prefix, 0, 0)
+	}
+	vanityGen.saveLocked()
+	return nil
+}
+
+// ResumeVanity restores jobs saved before a restart: found addresses, the
+// queue, and the unfinished job, which continues from its saved attempt
+// count and elapsed time
+func (t *TorService) ResumeVanity() error {
+	path := filepath.Join(t.dataDir, vanityStateFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read vanity progress: %w", err)
+	}
+	var state vanityState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid vanity progress file: %w", err)
+	}
+
+	vanityGen.mu.Lock()
+	defer vanityGen.mu.Unlock()
+	if vanityGen.progress.Running {
+		return nil
+	}
+
+	var queue []string
+	for _, prefix := range state.Queue {
+		if validateVanityPrefix(prefix) == nil {
+			queue = append(queue, prefix)
+		}
+	}
+	vanityGen.statePath = path
+	vanityGen.progress = &VanityProgress{Queue: queue, Results: state.Results}
+
+	prefix, attempts, elapsed := state.Prefix, state.Attempts, state.Elapsed
+	if prefix == "" && len(queue) > 0 {
+		prefix, attempts, elapsed = queue[0], 0, 0
+		vanityGen.progress.Queue = queue[1:]
+	}
+	if prefix == "" || validateVanityPrefix(prefix) != nil {
+		return nil
+	}
+	t.startVanityLocked(prefix, attempts, elapsed)
+	slog.Info("Tor vanity generation resumed", "prefix", prefix, "attempts", attempts, "queued", len(vanityGen.progress.Queue))
+	return nil
+}
+
+// CancelVanity cancels any running vanity generation and clears the queue
+func (t *TorService) CancelVanity() {
+	vanityGen.mu.Lock()
+	defer vanityGen.mu.Unlock()
+
+	vanityGen.stopLocked()
+	vanityGen.progress.Queue = nil
+	vanityGen.saveLocked()
+}
+
+// ApplyVanityAddress applies the generated vanity address by importing its
+// private key: the current job's match, else the latest found by the queue
+func (t *TorService) ApplyVanityAddress() (string, error) {
+	progress := t.GetVanityProgress()
+	privateKey := progress.PrivateKey
+	if !progress.Found {
+		if len(progress.Results) == 0 {
+			return "", fmt.Errorf("no vanity address has been generated")
+		}
+		privateKey = progress.Results[len(progress.Results)-1].PrivateKey
+	}
+	if len(privateKey) == 0 {
+		return "", fmt.Errorf("no private key available for vanity address")
+	}
+	return t.ImportKeys(privateKey)
+}
+
+// vanityWorkers returns the goroutine count for vanity generation: every CPU
+// core, capped by tor.vanity_workers when set
+func (t *TorService) vanityWorkers() int {
+	workers := runtime.NumCPU()
+	if limit := t.config.Server.Tor.VanityWorkers; limit > 0 && limit < workers {
+		workers = limit
+	}
+	return workers
+}
+
+// startVanityLocked starts a job for prefix, continuing from attempts and
+// elapsed when resuming. Queue and results carry over. vanityGen.mu must be
+// held.
+func (t *TorService) startVanityLocked(prefix string, attempts int64, elapsed time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	workers := t.vanityWorkers()
+
+	vanityGen.statePath = filepath.Join(t.dataDir, vanityStateFile)
+	vanityGen.job++
+	vanityGen.cancel = cancel
+	vanityGen.attempts = new(atomic.Int64)
+	vanityGen.runStart = time.Now()
+	vanityGen.progress = &VanityProgress{
+		Prefix:    prefix,
+		Attempts:  attempts,
+		Elapsed:   elapsed,
+		StartTime: vanityGen.runStart,
+		Running:   true,
+		Workers:   workers,
+		Queue:     vanityGen.progress.Queue,
+		Results:   vanityGen.progress.Results,
+	}
+
+	go t.runVanityGeneration(ctx, vanityGen.job, prefix, workers, vanityGen.attempts)
+}
+
+// vanityMatch is a key whose address has the wanted prefix
+type vanityMatch struct {
+	address    string
+	privateKey []byte
+}
+
+// runVanityGeneration runs a job's workers in the background, saving progress
+// periodically until a worker finds a match or the job is cancelled
+func (t *TorService) runVanityGeneration(ctx context.Context, job int64, prefix string, workers int, attempts *atomic.Int64) {
+	search, stop := context.WithCancel(ctx)
+	defer stop()
+
+	prefix = strings.ToLower(prefix)
+	matches := make(chan vanityMatch, 1)
+	for i := 0; i < workers; i++ {
+		go vanityWorker(search, job, prefix, attempts, matches)
+	}
+
+	checkpoint := time.NewTicker(vanityCheckpointInterval)
+	defer checkpoint.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-checkpoint.C:
+			vanityGen.mu.Lock()
+			if vanityGen.job == job {
+				vanityGen.saveLocked()
+			}
+			vanityGen.mu.Unlock()
+		case match := <-matches:
+			stop()
+			t.finishVanity(job, match)
+			return
+		}
+	}
+}
+
+// vanityWorker generates keys until one matches prefix or ctx is done
+func vanityWorker(ctx context.Context, job int64, prefix string, attempts *atomic.Int64, matches chan<- vanityMatch) {
+	for ctx.Err() == nil {
+		address, privKey, err := generateOnionV3Address()
+		attempts.Add(1)
+		if err != nil {
+			vanityGen.mu.Lock()
+			if vanityGen.job == job {
+				vanityGen.progress.Error = err.Error()
+			}
+			vanityGen.mu.Unlock()
+			continue
+		}
+
+		if strings.HasPrefix(address, prefix) {
+			select {
+			case matches <- vanityMatch{address: address, privateKey: privKey}:
+			default:
+			}
+			return
+		}
+	}
+}
+
+// finishVanity records a match and starts the next queued prefix
+func (t *TorService) finishVanity(job int64, match vanityMatch) {
+	vanityGen.mu.Lock()
+	defer vanityGen.mu.Unlock()
+	if vanityGen.job != job {
+		return
+	}
+
+	vanityGen.stopLocked()
+	progress := vanityGen.progress
+	progress.Found = true
+	progress.Address = match.address
+	progress.PrivateKey = match.privateKey
+	progress.Results = append(progress.Results, VanityResult{
+		Prefix:     progress.Prefix,
+		Address:    match.address,
+		PrivateKey: match.privateKey,
+		Attempts:   progress.Attempts,
+		Elapsed:    progress.Elapsed,
+		FoundAt:    time.Now(),
+	})
+	slog.Info("Tor vanity address found", "address", match.address+".onion", "attempts", progress.Attempts, "elapsed", progress.Elapsed.Round(time.Second))
+
+	if len(progress.Queue) > 0 {
+		next := progress.Queue[0]
+		progress.Queue = progress.Queue[1:]
+		t.startVanityLocked(next, 0, 0)
+	}
+	vanityGen.saveLocked()
+}
+
+// liveLocked returns the job's attempts and elapsed time including the
+// current run
+func (g *vanityGenerator) liveLocked() (int64, time.Duration) {
+	if !g.progress.Running {
+		return g.progress.Attempts, g.progress.Elapsed
+	}
+	return g.progress.Attempts + g.attempts.Load(), g.progress.Elapsed + time.Since(g.runStart)
+}
+
+// stopLocked cancels the current job and folds its run into progress
+func (g *vanityGenerator) stopLocked() {
+	if g.cancel != nil {
+		g.cancel()
+		g.cancel = nil
+	}
+	if g.progress.Running {
+		g.progress.Attempts, g.progress.Elapsed = g.liveLocked()
+		g.progress.Running = false
+	}
+}
+
+// saveLocked writes the jobs to the state file. Nothing is written until Tor
+// has created its data directory.
+func (g *vanityGenerator) saveLocked() {
+	if g.statePath == "" {
+		return
+	}
+	if _, err := os.Stat(filepath.Dir(g.statePath)); err != nil {
+		return
+	}
+
+	state := vanityState{Queue: g.progress.Queue, Results: g.progress.Results}
+	if g.progress.Running {
+		state.Prefix = g.progress.Prefix
+		state.Attempts, state.Elapsed = g.liveLocked()
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(g.statePath, data, 0600); err != nil {
+		slog.Warn("Tor failed to save vanity progress", "err", err)
+	}
+}
+
+// validateVanityPrefix checks a prefix can be generated by the built-in
+// generator
+func validateVanityPrefix(prefix string) error {
+	// Validate prefix length
+	if len(prefix) > 6 {
+		return fmt.Errorf("prefix too long: max 6 characters for built-in generation")
+	}
+
+	// Check for valid characters (base32 lowercase only)
+	for _, c := range prefix {
+		if !strings.ContainsRune(vanityAlphabet, c) {
+			return fmt.Errorf("invalid character '%c' in prefix: must be lowercase a-z or 2-7", c)
+		}
+	}
+	return nil
+}
+
+// generateOnionV3Address generates a fresh ED25519 key pair and derives its v3 onion address.
+// Returns (56-char base32 address without .onion, ed25519 private key, error).
+// Algorithm: address = base32(pubkey[32] || checksum[2] || version[1]) where
+// checksum = SHA3-256(".onion checksum" || pubkey || 0x03)[:2]
+func generateOnionV3Address() (string, []byte, error) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Compute 2-byte checksum per Tor spec for v3 onion addresses
+	version := byte(0x03)
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubKey)
+	h.Write([]byte{version})
+	checksum := h.Sum(nil)
+
+	// Build 35-byte payload: pubkey[32] + checksum[2] + version[1]
+	payload := make([]byte, 35)
+	copy(payload[0:32], pubKey)
+	copy(payload[32:34], checksum[:2])
+	payload[34] = version
+
+	// Base32-encode without padding → 56-char lowercase string
+	address := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(payload))
+
+	return address, []byte(privKey), nil
+}
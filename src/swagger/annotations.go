@@ -365,6 +365,189 @@ func generatePaths() map[string]PathItem {
 		},
 	}
 
+	paths[api.APIPrefix+"/alerts/{token}/export"] = PathItem{
+		Get: &Operation{
+			Summary:     "Export alert data",
+			Description: "Return the alert subscription and every result stored for it",
+			Tags:        []string{"Alerts"},
+			Parameters: []Parameter{
+				{Name: "token", In: "path", Description: "Alert manage token", Required: true, Schema: &Schema{Type: "string"}},
+			},
+			Responses: map[string]Response{
+				"200": {Description: "Alert data export"},
+				"404": {Description: "Alert not found"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/permalinks"] = PathItem{
+		Post: &Operation{
+			Summary:     "Create search permalink",
+			Description: "Store a search server-side and return an expiring /s/{token} link that carries no query",
+			Tags:        []string{"Search"},
+			RequestBody: &RequestBody{
+				Description: "Search to share",
+				Required:    true,
+				Content: map[string]MediaType{
+					"application/json": {
+						Schema: &Schema{
+							Type: "object",
+							Properties: map[string]Schema{
+								"query":       {Type: "string"},
+								"category":    {Type: "string"},
+								"safe_search": {Type: "string"},
+								"time_range":  {Type: "string"},
+								"per_page":    {Type: "integer"},
+							},
+						},
+					},
+				},
+			},
+			Responses: map[string]Response{
+				"201": {Description: "Permalink created"},
+				"400": {Description: "Invalid search"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/permalinks/{token}"] = PathItem{
+		Get: &Operation{
+			Summary:     "Get search permalink",
+			Description: "Return the search behind a permalink and when it expires",
+			Tags:        []string{"Search"},
+			Parameters: []Parameter{
+				{Name: "token", In: "path", Description: "Permalink token", Required: true, Schema: &Schema{Type: "string"}},
+			},
+			Responses: map[string]Response{
+				"200": {Description: "Permalink search"},
+				"404": {Description: "Permalink not found or expired"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/collections"] = PathItem{
+		Post: &Operation{
+			Summary:     "Create collection",
+			Description: "Create a named list of saved results; the response holds its manage token, shown once",
+			Tags:        []string{"Collections"},
+			RequestBody: &RequestBody{
+				Description: "Collection details",
+				Required:    true,
+				Content: map[string]MediaType{
+					"application/json": {
+						Schema: &Schema{
+							Type: "object",
+							Properties: map[string]Schema{
+								"name":        {Type: "string"},
+								"description": {Type: "string"},
+							},
+						},
+					},
+				},
+			},
+			Responses: map[string]Response{
+				"201": {Description: "Collection created"},
+				"400": {Description: "Invalid collection"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/collections/{token}"] = PathItem{
+		Get: &Operation{
+			Summary:     "Get collection",
+			Description: "Return a collection and its items in order",
+			Tags:        []string{"Collections"},
+			Parameters:  []Parameter{{Name: "token", In: "path", Description: "Collection manage token", Required: true, Schema: &Schema{Type: "string"}}},
+			Responses: map[string]Response{
+				"200": {Description: "Collection"},
+				"404": {Description: "Collection not found"},
+			},
+		},
+		Patch: &Operation{
+			Summary:     "Update collection",
+			Description: "Rename a collection or change its description",
+			Tags:        []string{"Collections"},
+			Parameters:  []Parameter{{Name: "token", In: "path", Description: "Collection manage token", Required: true, Schema: &Schema{Type: "string"}}},
+			Responses: map[string]Response{
+				"200": {Description: "Collection updated"},
+				"404": {Description: "Collection not found"},
+			},
+		},
+		Delete: &Operation{
+			Summary:     "Delete collection",
+			Description: "Delete a collection and its items",
+			Tags:        []string{"Collections"},
+			Parameters:  []Parameter{{Name: "token", In: "path", Description: "Collection manage token", Required: true, Schema: &Schema{Type: "string"}}},
+			Responses: map[string]Response{
+				"200": {Description: "Collection deleted"},
+				"404": {Description: "Collection not found"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/collections/{token}/items"] = PathItem{
+		Post: &Operation{
+			Summary:     "Add collection item",
+			Description: "Append a saved result with an optional note",
+			Tags:        []string{"Collections"},
+			Parameters:  []Parameter{{Name: "token", In: "path", Description: "Collection manage token", Required: true, Schema: &Schema{Type: "string"}}},
+			RequestBody: &RequestBody{
+				Description: "Saved result",
+				Required:    true,
+				Content: map[string]MediaType{
+					"application/json": {
+						Schema: &Schema{
+							Type: "object",
+							Properties: map[string]Schema{
+								"url":     {Type: "string"},
+								"title":   {Type: "string"},
+								"content": {Type: "string"},
+								"engine":  {Type: "string"},
+								"note":    {Type: "string"},
+							},
+						},
+					},
+				},
+			},
+			Responses: map[string]Response{
+				"201": {Description: "Item added"},
+				"400": {Description: "Invalid item or collection full"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/collections/{token}/export"] = PathItem{
+		Get: &Operation{
+			Summary:     "Export collection",
+			Description: "Download a collection as JSON, Markdown or CSV",
+			Tags:        []string{"Collections"},
+			Parameters: []Parameter{
+				{Name: "token", In: "path", Description: "Collection manage token", Required: true, Schema: &Schema{Type: "string"}},
+				{Name: "format", In: "query", Description: "json (default), markdown or csv", Schema: &Schema{Type: "string"}},
+			},
+			Responses: map[string]Response{
+				"200": {Description: "Collection export"},
+				"404": {Description: "Collection not found"},
+			},
+		},
+	}
+
+	paths[api.APIPrefix+"/collections/shared/{token}"] = PathItem{
+		Get: &Operation{
+			Summary:     "Get shared collection",
+			Description: "Read-only view of a shared collection, as JSON, Markdown or CSV",
+			Tags:        []string{"Collections"},
+			Parameters: []Parameter{
+				{Name: "token", In: "path", Description: "Collection share token", Required: true, Schema: &Schema{Type: "string"}},
+				{Name: "format", In: "query", Description: "json (default), markdown or csv", Schema: &Schema{Type: "string"}},
+			},
+			Responses: map[string]Response{
+				"200": {Description: "Shared collection"},
+				"404": {Description: "Not shared or not found"},
+			},
+		},
+	}
+
 	return paths
 }
 
@@ -0,0 +1,156 @@
+// Package trash keeps resources the operator deletes, such as result
+// blocklist rules and custom bangs, for Retention so one deleted by
+// mistake can be restored. Each caller stores a resource as JSON under its
+// own kind and key and decodes it again to restore it; expired items are
+// deleted by the retention task and whenever the trash is read. Revoked Tor
+// client keys are the exception and stay files; see the service package.
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Retention is how long a deleted resource can be restored
+const Retention = 7 * 24 * time.Hour
+
+// ErrNotFound is returned for a resource that is not in the trash, or no
+// longer is
+var ErrNotFound = errors.New("not found in the trash")
+
+// Item is a deleted resource. Data is the resource as it was stored.
+type Item struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	Key       string          `json:"key"`
+	Data      json.RawMessage `json:"data"`
+	DeletedAt time.Time       `json:"deleted_at"`
+	// PurgeAt is when the item is deleted for good
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// Decode unmarshals the deleted resource into v
+func (i *Item) Decode(v any) error {
+	return json.Unmarshal(i.Data, v)
+}
+
+// Store keeps the trash in the server database
+type Store struct {
+	db    *sql.DB
+	table string
+}
+
+// NewStore creates a store; tablePrefix is the server table prefix
+func NewStore(db *sql.DB, tablePrefix string) *Store {
+	return &Store{db: db, table: tablePrefix + "trash"}
+}
+
+// Put moves a deleted resource of kind, known by key, into the trash
+func (s *Store) Put(ctx context.Context, kind, key string, v any) error {
+	if s == nil || s.db == nil {
+		return errors.New("trash is unavailable")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s %s: %w", kind, key, err)
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO `+s.table+` (kind, item_key, data, deleted_at) VALUES (?, ?, ?, ?)`,
+		kind, key, string(data), time.Now().UTC()); err != nil {
+		return fmt.Errorf("trash %s %s: %w", kind, key, err)
+	}
+	return nil
+}
+
+// List returns the items of kind that can still be restored, newest
+// first, after deleting those past Retention
+func (s *Store) List(ctx context.Context, kind string) ([]Item, error) {
+	if s == nil || s.db == nil {
+		return []Item{}, nil
+	}
+	if _, err := s.purge(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, kind, item_key, data, deleted_at FROM `+s.table+`
+		WHERE kind = ? ORDER BY deleted_at DESC, id DESC`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("list trash: %w", err)
+	}
+	defer rows.Close()
+	items := []Item{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// Get returns the most recently deleted item of kind with key. It stays in
+// the trash until Remove, so a restore that fails loses nothing.
+func (s *Store) Get(ctx context.Context, kind, key string) (*Item, error) {
+	if s == nil || s.db == nil {
+		return nil, ErrNotFound
+	}
+	if _, err := s.purge(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	item, err := scanItem(s.db.QueryRowContext(ctx, `SELECT id, kind, item_key, data, deleted_at FROM `+s.table+`
+		WHERE kind = ? AND item_key = ? ORDER BY deleted_at DESC, id DESC LIMIT 1`, kind, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotFound, kind, key)
+	}
+	return item, err
+}
+
+// Remove deletes an item from the trash, once it has been restored
+func (s *Store) Remove(ctx context.Context, id int64) error {
+	if s == nil || s.db == nil {
+		return ErrNotFound
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("remove from trash: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes the items past Retention and returns how many it deleted
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, nil
+	}
+	return s.purge(ctx, time.Now())
+}
+
+// purge deletes the items deleted Retention or longer before now
+func (s *Store) purge(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE deleted_at <= ?`, now.Add(-Retention).UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanItem(row scanner) (*Item, error) {
+	var item Item
+	var data string
+	if err := row.Scan(&item.ID, &item.Kind, &item.Key, &data, &item.DeletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("read trash: %w", err)
+	}
+	item.Data = json.RawMessage(data)
+	item.DeletedAt = item.DeletedAt.UTC()
+	item.PurgeAt = item.DeletedAt.Add(Retention)
+	return &item, nil
+}
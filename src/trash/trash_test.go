@@ -0,0 +1,106 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apimgr/search/src/database/dbtest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Server(t), "")
+}
+
+type rule struct {
+	Pattern string `json:"pattern"`
+}
+
+func TestStore(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	for _, pattern := range []string{"old.example", "new.example"} {
+		if err := store.Put(ctx, "rule", "1", rule{pattern}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(ctx, "bang", "g", rule{"bang"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := store.List(ctx, "rule")
+	if err != nil || len(items) != 2 {
+		t.Fatalf("List = %+v, %v", items, err)
+	}
+	if items[0].PurgeAt.Sub(items[0].DeletedAt) != Retention {
+		t.Errorf("purge at %v, deleted at %v", items[0].PurgeAt, items[0].DeletedAt)
+	}
+
+	// The newest deletion of a key is the one restored
+	item, err := store.Get(ctx, "rule", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got rule
+	if err := item.Decode(&got); err != nil || got.Pattern != "new.example" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+	if err := store.Remove(ctx, item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := store.List(ctx, "rule"); len(items) != 1 {
+		t.Errorf("items after Remove = %d, want 1", len(items))
+	}
+	if _, err := store.Get(ctx, "bang", "w"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a key never deleted: err = %v", err)
+	}
+
+	// Past the retention an item is purged
+	if _, err := store.db.Exec(`UPDATE trash SET deleted_at = ? WHERE kind = 'bang'`, time.Now().Add(-Retention-time.Minute).UTC()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "bang", "g"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired item: err = %v", err)
+	}
+	var count int
+	store.db.QueryRow(`SELECT COUNT(*) FROM trash`).Scan(&count)
+	if count != 1 {
+		t.Errorf("%d items left, want the expired one deleted", count)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"old", "new"} {
+		if err := store.Put(ctx, "rule", key, rule{key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.db.Exec(`UPDATE trash SET deleted_at = ? WHERE item_key = 'old'`, time.Now().Add(-Retention-time.Minute).UTC()); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Purge(ctx); err != nil || n != 1 {
+		t.Fatalf("Purge = %d, %v, want 1", n, err)
+	}
+	if n, err := store.Purge(ctx); err != nil || n != 0 {
+		t.Errorf("second Purge = %d, %v, want 0", n, err)
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	if items, err := store.List(context.Background(), "rule"); err != nil || len(items) != 0 {
+		t.Errorf("List = %v, %v", items, err)
+	}
+	if _, err := store.Get(context.Background(), "rule", "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get err = %v", err)
+	}
+	if n, err := store.Purge(context.Background()); err != nil || n != 0 {
+		t.Errorf("Purge = %d, %v", n, err)
+	}
+}
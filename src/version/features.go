@@ -0,0 +1,44 @@
+package version
+
+import "strings"
+
+// Feature is an optional subsystem that a build tag leaves out of the
+// binary, for embedded and container builds that do not need it
+type Feature struct {
+	Name string `json:"name"`
+	// Tag is the build tag that leaves the feature out
+	Tag      string `json:"tag"`
+	Compiled bool   `json:"compiled"`
+}
+
+// Features returns the optional features and whether each is compiled in
+func Features() []Feature {
+	return []Feature{
+		{Name: "tor", Tag: "notor", Compiled: torCompiled},
+	}
+}
+
+// HasFeature reports whether the named optional feature is compiled in
+func HasFeature(name string) bool {
+	for _, f := range Features() {
+		if f.Name == name {
+			return f.Compiled
+		}
+	}
+	return false
+}
+
+// FeatureString lists the optional features for --version, each prefixed
+// "+" when compiled in and "-" when left out (e.g. "+tor")
+func FeatureString() string {
+	features := Features()
+	parts := make([]string, len(features))
+	for i, f := range features {
+		if f.Compiled {
+			parts[i] = "+" + f.Name
+		} else {
+			parts[i] = "-" + f.Name
+		}
+	}
+	return strings.Join(parts, " ")
+}
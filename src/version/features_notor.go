@@ -0,0 +1,6 @@
+//go:build notor
+
+package version
+
+// torCompiled is false in binaries built with -tags notor
+const torCompiled = false
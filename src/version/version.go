@@ -30,6 +30,7 @@ const (
 	LabelGo        = "Go:"
 	LabelOSArch    = "OS/Arch:"
 	LabelCompiler  = "Compiler:"
+	LabelFeatures  = "Features:"
 )
 
 // Build-time variables - set via ldflags
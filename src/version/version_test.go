@@ -147,3 +147,20 @@ func TestIsDev(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureString(t *testing.T) {
+	got := FeatureString()
+	want := "+tor"
+	if !torCompiled {
+		want = "-tor"
+	}
+	if got != want {
+		t.Errorf("FeatureString() = %q, want %q", got, want)
+	}
+	if HasFeature("tor") != torCompiled {
+		t.Errorf("HasFeature(tor) = %v, want %v", HasFeature("tor"), torCompiled)
+	}
+	if HasFeature("unknown") {
+		t.Error("HasFeature should be false for unknown features")
+	}
+}
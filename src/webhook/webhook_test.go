@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1760650000, 0)
+	body := []byte(`{"alert":{"id":"a1"}}`)
+	h := http.Header{}
+	if err := Sign(h, "secret", body, now); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	var guard ReplayGuard
+	if err := Verify(h, "secret", body, now.Add(time.Minute), 0, &guard); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := Verify(h, "secret", body, now.Add(time.Minute), 0, &guard); !errors.Is(err, ErrReplayed) {
+		t.Errorf("replayed Verify() error = %v, want ErrReplayed", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"wrong secret", "other", body, now, ErrBadSignature},
+		{"tampered body", "secret", []byte(`{}`), now, ErrBadSignature},
+		{"too old", "secret", body, now.Add(DefaultTolerance + time.Second), ErrExpired},
+		{"from the future", "secret", body, now.Add(-DefaultTolerance - time.Second), ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(h, tt.secret, tt.body, tt.now, 0, nil); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := Verify(http.Header{}, "secret", body, now, 0, nil); !errors.Is(err, ErrMissingHeaders) {
+		t.Errorf("Verify() without headers error = %v, want ErrMissingHeaders", err)
+	}
+}
+
+func TestReplayGuardForgetsExpiredNonces(t *testing.T) {
+	var g ReplayGuard
+	now := time.Now()
+	if !g.Use("n", now.Add(time.Minute), now) {
+		t.Fatal("first Use() = false")
+	}
+	if !g.Use("n", now.Add(3*time.Minute), now.Add(2*time.Minute)) {
+		t.Error("Use() after expiry = false, want the nonce forgotten")
+	}
+	if len(g.seen) != 1 {
+		t.Errorf("guard holds %d nonces, want 1", len(g.seen))
+	}
+}
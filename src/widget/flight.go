@@ -0,0 +1,362 @@
+package widget
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/version"
+)
+
+// FlightFetcher fetches live flight status from a readsb-compatible ADS-B
+// API (adsb.lol, airplanes.live, or a local feeder), optionally enriched
+// with schedule and delay data from AviationStack when a key is configured.
+type FlightFetcher struct {
+	client           *http.Client
+	config           *config.FlightWidgetConfig
+	aviationStackURL string
+}
+
+// FlightData represents flight widget data
+type FlightData struct {
+	Query    string          `json:"query"`
+	Callsign string          `json:"callsign"`
+	Flight   string          `json:"flight,omitempty"`
+	Airline  string          `json:"airline,omitempty"`
+	Status   string          `json:"status"`
+	Position *FlightPosition `json:"position,omitempty"`
+	// Schedule fields are only present when AviationStack is configured
+	Departure *FlightEndpoint `json:"departure,omitempty"`
+	Arrival   *FlightEndpoint `json:"arrival,omitempty"`
+	TrackURL  string          `json:"track_url,omitempty"`
+	Sources   []string        `json:"sources"`
+}
+
+// FlightPosition is the latest ADS-B position report for an aircraft
+type FlightPosition struct {
+	Hex           string  `json:"hex"`
+	Registration  string  `json:"registration,omitempty"`
+	AircraftType  string  `json:"aircraft_type,omitempty"`
+	Latitude      float64 `json:"lat"`
+	Longitude     float64 `json:"lon"`
+	AltitudeFt    int     `json:"altitude_ft"`
+	OnGround      bool    `json:"on_ground"`
+	GroundSpeedKt float64 `json:"ground_speed_kt"`
+	Track         float64 `json:"track"`
+	Squawk        string  `json:"squawk,omitempty"`
+	SeenSeconds   float64 `json:"seen_seconds"`
+}
+
+// FlightEndpoint is the departure or arrival side of a scheduled flight
+type FlightEndpoint struct {
+	Airport      string `json:"airport"`
+	IATA         string `json:"iata,omitempty"`
+	Terminal     string `json:"terminal,omitempty"`
+	Gate         string `json:"gate,omitempty"`
+	Scheduled    string `json:"scheduled,omitempty"`
+	Estimated    string `json:"estimated,omitempty"`
+	Actual       string `json:"actual,omitempty"`
+	DelayMinutes int    `json:"delay_minutes,omitempty"`
+}
+
+// Flight status values reported by the ADS-B source
+const (
+	FlightStatusAirborne = "airborne"
+	FlightStatusOnGround = "on_ground"
+	FlightStatusNotFound = "not_seen"
+)
+
+// airlineIATAToICAO maps common airline IATA codes to the ICAO prefixes
+// used in ADS-B callsigns ("UA123" is broadcast as "UAL123")
+var airlineIATAToICAO = map[string]string{
+	"AA": "AAL", "AC": "ACA", "AF": "AFR", "AI": "AIC", "AM": "AMX",
+	"AS": "ASA", "AV": "AVA", "AY": "FIN", "AZ": "ITY", "B6": "JBU",
+	"BA": "BAW", "CA": "CCA", "CX": "CPA", "CZ": "CSN", "DL": "DAL",
+	"EI": "EIN", "EK": "UAE", "ET": "ETH", "EY": "ETD", "F9": "FFT",
+	"FR": "RYR", "G4": "AAY", "HA": "HAL", "IB": "IBE", "JL": "JAL",
+	"KE": "KAL", "KL": "KLM", "LA": "LAN", "LH": "DLH", "LO": "LOT",
+	"LX": "SWR", "MU": "CES", "NH": "ANA", "NK": "NKS", "NZ": "ANZ",
+	"OS": "AUA", "OZ": "AAR", "QF": "QFA", "QR": "QTR", "SK": "SAS",
+	"SN": "BEL", "SQ": "SIA", "SY": "SCX", "TK": "THY", "TP": "TAP",
+	"U2": "EZY", "UA": "UAL", "VS": "VIR", "VY": "VLG", "W6": "WZZ",
+	"WN": "SWA", "WS": "WJA",
+}
+
+var (
+	iataFlightPattern = regexp.MustCompile(`^([A-Z0-9]{2})(\d{1,4}[A-Z]?)$`)
+	icaoFlightPattern = regexp.MustCompile(`^[A-Z]{3}\d{1,4}[A-Z]?$`)
+	callsignPattern   = regexp.MustCompile(`^[A-Z0-9]{2,8}$`)
+)
+
+// NewFlightFetcher creates a new flight status fetcher
+func NewFlightFetcher(cfg *config.FlightWidgetConfig) *FlightFetcher {
+	return &FlightFetcher{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		config:           cfg,
+		aviationStackURL: "https://api.aviationstack.com/v1/flights",
+	}
+}
+
+// WidgetType returns the widget type
+func (f *FlightFetcher) WidgetType() WidgetType {
+	return WidgetFlight
+}
+
+// CacheDuration returns how long to cache the data.
+// ADS-B positions move quickly, so keep this short.
+func (f *FlightFetcher) CacheDuration() time.Duration {
+	return time.Minute
+}
+
+// normalizeFlightNumber converts user input ("ua 123", "UAL123") into the
+// ADS-B callsign and, when known, the IATA flight number
+func normalizeFlightNumber(input string) (callsign, iata string, ok bool) {
+	s := strings.ToUpper(strings.Join(strings.Fields(input), ""))
+	if icaoFlightPattern.MatchString(s) {
+		return s, "", true
+	}
+	if m := iataFlightPattern.FindStringSubmatch(s); m != nil {
+		if icao, known := airlineIATAToICAO[m[1]]; known {
+			return icao + m[2], s, true
+		}
+	}
+	// Unknown airline or general aviation; try the raw callsign
+	if callsignPattern.MatchString(s) {
+		return s, "", true
+	}
+	return "", "", false
+}
+
+// Fetch looks up a flight by number or callsign (param "flight")
+func (f *FlightFetcher) Fetch(ctx context.Context, params map[string]string) (*WidgetData, error) {
+	callsign, iata, ok := normalizeFlightNumber(params["flight"])
+	if !ok {
+		return &WidgetData{
+			Type:      WidgetFlight,
+			Error:     "flight number required (e.g. UA123 or UAL123)",
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	data := &FlightData{
+		Query:    params["flight"],
+		Callsign: callsign,
+		Flight:   iata,
+		Status:   FlightStatusNotFound,
+		Sources:  []string{},
+	}
+
+	position, err := f.fetchADSB(ctx, callsign)
+	if err != nil && f.config.AviationStackKey == "" {
+		return &WidgetData{
+			Type:      WidgetFlight,
+			Error:     err.Error(),
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+	if position != nil {
+		data.Position = position
+		data.Status = FlightStatusAirborne
+		if position.OnGround {
+			data.Status = FlightStatusOnGround
+		}
+		data.TrackURL = "https://globe.adsb.lol/?icao=" + url.QueryEscape(position.Hex)
+		data.Sources = append(data.Sources, "adsb")
+	}
+
+	// Schedule data is best-effort; a failure still leaves the live position
+	if f.config.AviationStackKey != "" {
+		if err := f.fetchSchedule(ctx, callsign, iata, data); err == nil {
+			data.Sources = append(data.Sources, "aviationstack")
+		}
+	}
+
+	return &WidgetData{
+		Type:      WidgetFlight,
+		Data:      data,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// adsbResponse is the readsb v2 API response shape
+type adsbResponse struct {
+	Aircraft []struct {
+		Hex          string          `json:"hex"`
+		Flight       string          `json:"flight"`
+		Registration string          `json:"r"`
+		Type         string          `json:"t"`
+		AltBaro      json.RawMessage `json:"alt_baro"`
+		GroundSpeed  float64         `json:"gs"`
+		Track        float64         `json:"track"`
+		Squawk       string          `json:"squawk"`
+		Lat          float64         `json:"lat"`
+		Lon          float64         `json:"lon"`
+		Seen         float64         `json:"seen"`
+	} `json:"ac"`
+}
+
+func (f *FlightFetcher) fetchADSB(ctx context.Context, callsign string) (*FlightPosition, error) {
+	base := strings.TrimSuffix(f.config.ADSBURL, "/")
+	if base == "" {
+		base = "https://api.adsb.lol"
+	}
+	apiURL := base + "/v2/callsign/" + url.PathEscape(callsign)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.BrowserUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ADS-B API returned status %d", resp.StatusCode)
+	}
+
+	var adsb adsbResponse
+	if err := json.NewDecoder(resp.Body).Decode(&adsb); err != nil {
+		return nil, err
+	}
+	if len(adsb.Aircraft) == 0 {
+		return nil, nil
+	}
+
+	// Several receivers may report the same callsign; prefer the freshest
+	ac := adsb.Aircraft[0]
+	for _, candidate := range adsb.Aircraft[1:] {
+		if candidate.Seen < ac.Seen {
+			ac = candidate
+		}
+	}
+
+	pos := &FlightPosition{
+		Hex:           ac.Hex,
+		Registration:  ac.Registration,
+		AircraftType:  ac.Type,
+		Latitude:      ac.Lat,
+		Longitude:     ac.Lon,
+		GroundSpeedKt: ac.GroundSpeed,
+		Track:         ac.Track,
+		Squawk:        ac.Squawk,
+		SeenSeconds:   ac.Seen,
+	}
+	// alt_baro is either a number of feet or the string "ground"
+	var alt float64
+	if err := json.Unmarshal(ac.AltBaro, &alt); err == nil {
+		pos.AltitudeFt = int(alt)
+	} else {
+		pos.OnGround = true
+	}
+	return pos, nil
+}
+
+// aviationStackResponse is the subset of the AviationStack flights response we use
+type aviationStackResponse struct {
+	Data []struct {
+		FlightStatus string                `json:"flight_status"`
+		Departure    aviationStackEndpoint `json:"departure"`
+		Arrival      aviationStackEndpoint `json:"arrival"`
+		Airline      struct {
+			Name string `json:"name"`
+		} `json:"airline"`
+		Flight struct {
+			IATA string `json:"iata"`
+		} `json:"flight"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type aviationStackEndpoint struct {
+	Airport   string `json:"airport"`
+	IATA      string `json:"iata"`
+	Terminal  string `json:"terminal"`
+	Gate      string `json:"gate"`
+	Scheduled string `json:"scheduled"`
+	Estimated string `json:"estimated"`
+	Actual    string `json:"actual"`
+	Delay     *int   `json:"delay"`
+}
+
+func (e aviationStackEndpoint) toEndpoint() *FlightEndpoint {
+	ep := &FlightEndpoint{
+		Airport:   e.Airport,
+		IATA:      e.IATA,
+		Terminal:  e.Terminal,
+		Gate:      e.Gate,
+		Scheduled: e.Scheduled,
+		Estimated: e.Estimated,
+		Actual:    e.Actual,
+	}
+	if e.Delay != nil {
+		ep.DelayMinutes = *e.Delay
+	}
+	return ep
+}
+
+func (f *FlightFetcher) fetchSchedule(ctx context.Context, callsign, iata string, data *FlightData) error {
+	params := url.Values{}
+	params.Set("access_key", f.config.AviationStackKey)
+	if iata != "" {
+		params.Set("flight_iata", iata)
+	} else {
+		params.Set("flight_icao", callsign)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", f.aviationStackURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", version.BrowserUserAgent)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("AviationStack returned status %d", resp.StatusCode)
+	}
+
+	var as aviationStackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&as); err != nil {
+		return err
+	}
+	if as.Error != nil {
+		return fmt.Errorf("AviationStack: %s", as.Error.Message)
+	}
+	if len(as.Data) == 0 {
+		return fmt.Errorf("AviationStack: flight not found")
+	}
+
+	// Results are ordered by date; the first entry is today's leg
+	flight := as.Data[0]
+	data.Airline = flight.Airline.Name
+	if data.Flight == "" {
+		data.Flight = flight.Flight.IATA
+	}
+	data.Departure = flight.Departure.toEndpoint()
+	data.Arrival = flight.Arrival.toEndpoint()
+	// Prefer the schedule status (scheduled, landed, cancelled, ...) when
+	// the aircraft is not currently visible to ADS-B receivers
+	if data.Position == nil && flight.FlightStatus != "" {
+		data.Status = flight.FlightStatus
+	}
+	return nil
+}
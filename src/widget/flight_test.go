@@ -0,0 +1,118 @@
+package widget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apimgr/search/src/config"
+)
+
+func TestNormalizeFlightNumber(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantCallsign string
+		wantIATA     string
+		wantOK       bool
+	}{
+		{"iata known airline", "UA123", "UAL123", "UA123", true},
+		{"iata lowercase with space", "ua 123", "UAL123", "UA123", true},
+		{"iata digit airline code", "U2 8001", "EZY8001", "U28001", true},
+		{"icao callsign", "BAW117", "BAW117", "", true},
+		{"unknown airline kept as callsign", "ZZ123", "ZZ123", "", true},
+		{"registration", "N123AB", "N123AB", "", true},
+		{"empty", "", "", "", false},
+		{"punctuation", "UA-123!", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callsign, iata, ok := normalizeFlightNumber(tt.input)
+			if callsign != tt.wantCallsign || iata != tt.wantIATA || ok != tt.wantOK {
+				t.Errorf("normalizeFlightNumber(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.input, callsign, iata, ok, tt.wantCallsign, tt.wantIATA, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFlightFetcherFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/callsign/UAL123":
+			w.Write([]byte(`{"ac":[
+				{"hex":"a1b2c3","flight":"UAL123  ","r":"N12345","t":"B738","alt_baro":35000,"gs":450.5,"track":270,"lat":40.1,"lon":-100.2,"seen":12},
+				{"hex":"a1b2c3","flight":"UAL123  ","alt_baro":35025,"gs":451,"lat":40.1,"lon":-100.3,"seen":0.5}
+			]}`))
+		case r.URL.Path == "/v2/callsign/DAL5":
+			w.Write([]byte(`{"ac":[{"hex":"abcdef","alt_baro":"ground","gs":3,"lat":33.6,"lon":-84.4}]}`))
+		case r.URL.Path == "/v2/callsign/AAL1":
+			w.Write([]byte(`{"ac":[]}`))
+		case strings.HasPrefix(r.URL.Path, "/flights"):
+			if r.URL.Query().Get("access_key") != "secret" || r.URL.Query().Get("flight_iata") != "AA1" {
+				w.Write([]byte(`{"error":{"message":"bad request"}}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"flight_status":"scheduled","airline":{"name":"American Airlines"},
+				"flight":{"iata":"AA1"},
+				"departure":{"airport":"John F Kennedy International","iata":"JFK","gate":"8","scheduled":"2026-01-01T08:00:00+00:00","delay":15},
+				"arrival":{"airport":"Los Angeles International","iata":"LAX"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("airborne picks freshest report", func(t *testing.T) {
+		f := NewFlightFetcher(&config.FlightWidgetConfig{Enabled: true, ADSBURL: server.URL})
+		wd, err := f.Fetch(context.Background(), map[string]string{"flight": "ua123"})
+		if err != nil || wd.Error != "" {
+			t.Fatalf("Fetch() err = %v, widget error = %q", err, wd.Error)
+		}
+		data := wd.Data.(*FlightData)
+		if data.Status != FlightStatusAirborne {
+			t.Errorf("Status = %q, want %q", data.Status, FlightStatusAirborne)
+		}
+		if data.Position == nil || data.Position.AltitudeFt != 35025 {
+			t.Errorf("Position = %+v, want freshest report at 35025 ft", data.Position)
+		}
+		if data.Flight != "UA123" || data.Callsign != "UAL123" {
+			t.Errorf("Flight/Callsign = %q/%q", data.Flight, data.Callsign)
+		}
+	})
+
+	t.Run("on ground", func(t *testing.T) {
+		f := NewFlightFetcher(&config.FlightWidgetConfig{Enabled: true, ADSBURL: server.URL})
+		wd, _ := f.Fetch(context.Background(), map[string]string{"flight": "DAL5"})
+		data := wd.Data.(*FlightData)
+		if data.Status != FlightStatusOnGround || !data.Position.OnGround {
+			t.Errorf("Status = %q, OnGround = %v, want on ground", data.Status, data.Position.OnGround)
+		}
+	})
+
+	t.Run("schedule fills in when not seen", func(t *testing.T) {
+		f := NewFlightFetcher(&config.FlightWidgetConfig{Enabled: true, ADSBURL: server.URL, AviationStackKey: "secret"})
+		f.aviationStackURL = server.URL + "/flights"
+		wd, _ := f.Fetch(context.Background(), map[string]string{"flight": "AA1"})
+		data := wd.Data.(*FlightData)
+		if data.Status != "scheduled" {
+			t.Errorf("Status = %q, want scheduled", data.Status)
+		}
+		if data.Airline != "American Airlines" || data.Departure == nil || data.Departure.DelayMinutes != 15 {
+			t.Errorf("schedule not applied: airline=%q departure=%+v", data.Airline, data.Departure)
+		}
+		if len(data.Sources) != 1 || data.Sources[0] != "aviationstack" {
+			t.Errorf("Sources = %v, want [aviationstack]", data.Sources)
+		}
+	})
+
+	t.Run("invalid flight number", func(t *testing.T) {
+		f := NewFlightFetcher(&config.FlightWidgetConfig{Enabled: true, ADSBURL: server.URL})
+		wd, err := f.Fetch(context.Background(), map[string]string{"flight": ""})
+		if err != nil || wd.Error == "" {
+			t.Errorf("Fetch() with empty flight: err = %v, widget error = %q", err, wd.Error)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package widget
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apimgr/search/src/market"
+)
+
+// TickerFetcher serves stock and crypto quotes for the homepage ticker from
+// the shared market data service, which the scheduler keeps warm
+type TickerFetcher struct {
+	service *market.Service
+}
+
+// TickerData represents ticker widget data
+type TickerData struct {
+	Quotes []*market.Quote `json:"quotes"`
+}
+
+// maxTickerSymbols bounds how many symbols one widget request may ask for
+const maxTickerSymbols = 12
+
+// NewTickerFetcher creates a new ticker fetcher
+func NewTickerFetcher(service *market.Service) *TickerFetcher {
+	return &TickerFetcher{service: service}
+}
+
+// WidgetType returns the widget type
+func (f *TickerFetcher) WidgetType() WidgetType {
+	return WidgetTicker
+}
+
+// CacheDuration returns how long to cache the data.
+// The market service has its own cache, so this only absorbs bursts.
+func (f *TickerFetcher) CacheDuration() time.Duration {
+	return time.Minute
+}
+
+// Fetch returns quotes for params "symbols" (comma-separated), or the
+// operator-configured ticker when none are given
+func (f *TickerFetcher) Fetch(ctx context.Context, params map[string]string) (*WidgetData, error) {
+	var symbols []string
+	for _, s := range strings.Split(params["symbols"], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			symbols = append(symbols, s)
+		}
+	}
+	if len(symbols) == 0 {
+		symbols = f.service.Ticker()
+	}
+	if len(symbols) > maxTickerSymbols {
+		symbols = symbols[:maxTickerSymbols]
+	}
+
+	quotes := f.service.Quotes(ctx, symbols)
+	if len(quotes) == 0 {
+		return &WidgetData{
+			Type:      WidgetTicker,
+			Error:     "no market data available",
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	return &WidgetData{
+		Type:      WidgetTicker,
+		Data:      &TickerData{Quotes: quotes},
+		UpdatedAt: time.Now(),
+	}, nil
+}
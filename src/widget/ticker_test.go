@@ -0,0 +1,48 @@
+package widget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/market"
+)
+
+func TestTickerFetcherFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v8/finance/chart/MSFT" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"chart":{"result":[{"meta":{"symbol":"MSFT","currency":"USD","regularMarketPrice":400,"chartPreviousClose":390}}]}}`))
+	}))
+	defer server.Close()
+
+	svc := market.NewService(&config.MarketConfig{
+		StocksURL:    server.URL,
+		CoinGeckoURL: server.URL,
+		Ticker:       []string{"MSFT", "NOPE"},
+	})
+	f := NewTickerFetcher(svc)
+
+	if f.WidgetType() != WidgetTicker {
+		t.Errorf("WidgetType() = %q, want %q", f.WidgetType(), WidgetTicker)
+	}
+
+	// Defaults to the configured ticker, skipping symbols without data
+	wd, err := f.Fetch(context.Background(), map[string]string{})
+	if err != nil || wd.Error != "" {
+		t.Fatalf("Fetch() err = %v, widget error = %q", err, wd.Error)
+	}
+	quotes := wd.Data.(*TickerData).Quotes
+	if len(quotes) != 1 || quotes[0].Symbol != "MSFT" {
+		t.Errorf("Quotes = %+v, want MSFT only", quotes)
+	}
+
+	wd, _ = f.Fetch(context.Background(), map[string]string{"symbols": "NOPE"})
+	if wd.Error == "" {
+		t.Error("expected error when no symbols resolve")
+	}
+}
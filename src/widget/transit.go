@@ -0,0 +1,456 @@
+package widget
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/apimgr/search/src/config"
+	"github.com/apimgr/search/src/version"
+)
+
+// TransitFetcher fetches upcoming departures from operator-configured
+// GTFS-Realtime trip update feeds
+type TransitFetcher struct {
+	client *http.Client
+	config *config.TransitWidgetConfig
+
+	// Decoded feeds are shared by every stop on the same feed, so they are
+	// cached here rather than per widget request
+	mu    sync.Mutex
+	feeds map[string]*transitFeedCache
+}
+
+type transitFeedCache struct {
+	feed      *gtfsFeed
+	fetchedAt time.Time
+}
+
+// transitFeedTTL bounds how often a single GTFS-RT feed is downloaded.
+// Agencies typically publish every 15-30 seconds.
+const transitFeedTTL = 30 * time.Second
+
+// maxTransitFeedSize caps the protobuf download; large agencies publish a few MB
+const maxTransitFeedSize = 32 << 20
+
+// TransitData represents transit widget data
+type TransitData struct {
+	Feed       string             `json:"feed,omitempty"`
+	Stop       string             `json:"stop,omitempty"`
+	StopName   string             `json:"stop_name,omitempty"`
+	Departures []TransitDeparture `json:"departures,omitempty"`
+	// Available lists configured feeds and stops when no stop is selected
+	Available []TransitFeedInfo `json:"available,omitempty"`
+}
+
+// TransitDeparture is a single upcoming departure at a stop
+type TransitDeparture struct {
+	RouteID      string    `json:"route_id"`
+	Route        string    `json:"route"`
+	TripID       string    `json:"trip_id"`
+	Vehicle      string    `json:"vehicle,omitempty"`
+	Time         time.Time `json:"time"`
+	MinutesAway  int       `json:"minutes_away"`
+	DelaySeconds int32     `json:"delay_seconds"`
+}
+
+// TransitFeedInfo describes a configured feed for the widget settings UI
+type TransitFeedInfo struct {
+	ID    string                     `json:"id"`
+	Name  string                     `json:"name"`
+	Stops []config.TransitStopConfig `json:"stops"`
+}
+
+// NewTransitFetcher creates a new transit departures fetcher
+func NewTransitFetcher(cfg *config.TransitWidgetConfig) *TransitFetcher {
+	return &TransitFetcher{
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		config: cfg,
+		feeds:  make(map[string]*transitFeedCache),
+	}
+}
+
+// WidgetType returns the widget type
+func (f *TransitFetcher) WidgetType() WidgetType {
+	return WidgetTransit
+}
+
+// CacheDuration returns how long to cache the data
+func (f *TransitFetcher) CacheDuration() time.Duration {
+	return transitFeedTTL
+}
+
+// Fetch returns departures for params "feed" and "stop". Without a stop it
+// returns the configured feeds and stops so the user can pick one.
+func (f *TransitFetcher) Fetch(ctx context.Context, params map[string]string) (*WidgetData, error) {
+	if len(f.config.Feeds) == 0 {
+		return &WidgetData{
+			Type:      WidgetTransit,
+			Error:     "no transit feeds configured",
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	stopID := params["stop"]
+	if stopID == "" {
+		available := make([]TransitFeedInfo, 0, len(f.config.Feeds))
+		for _, feed := range f.config.Feeds {
+			available = append(available, TransitFeedInfo{ID: feed.ID, Name: feed.Name, Stops: feed.Stops})
+		}
+		return &WidgetData{
+			Type:      WidgetTransit,
+			Data:      &TransitData{Available: available},
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	feedCfg := f.findFeed(params["feed"])
+	if feedCfg == nil {
+		return &WidgetData{
+			Type:      WidgetTransit,
+			Error:     fmt.Sprintf("unknown transit feed %q", params["feed"]),
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	feed, err := f.loadFeed(ctx, feedCfg)
+	if err != nil {
+		return &WidgetData{
+			Type:      WidgetTransit,
+			Error:     err.Error(),
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	data := &TransitData{
+		Feed:       feedCfg.ID,
+		Stop:       stopID,
+		StopName:   stopID,
+		Departures: f.departures(feed, feedCfg, stopID, time.Now()),
+	}
+	for _, stop := range feedCfg.Stops {
+		if stop.ID == stopID && stop.Name != "" {
+			data.StopName = stop.Name
+			break
+		}
+	}
+
+	return &WidgetData{
+		Type:      WidgetTransit,
+		Data:      data,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// findFeed returns the feed with the given ID, or the only feed when id is empty
+func (f *TransitFetcher) findFeed(id string) *config.TransitFeedConfig {
+	if id == "" && len(f.config.Feeds) == 1 {
+		return &f.config.Feeds[0]
+	}
+	for i := range f.config.Feeds {
+		if f.config.Feeds[i].ID == id {
+			return &f.config.Feeds[i]
+		}
+	}
+	return nil
+}
+
+func (f *TransitFetcher) loadFeed(ctx context.Context, feedCfg *config.TransitFeedConfig) (*gtfsFeed, error) {
+	f.mu.Lock()
+	cached := f.feeds[feedCfg.ID]
+	f.mu.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < transitFeedTTL {
+		return cached.feed, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", feedCfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.BrowserUserAgent)
+	req.Header.Set("Accept", "application/x-protobuf")
+	for k, v := range feedCfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transit feed returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTransitFeedSize))
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := decodeGTFSRealtime(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GTFS-Realtime feed: %w", err)
+	}
+
+	f.mu.Lock()
+	f.feeds[feedCfg.ID] = &transitFeedCache{feed: feed, fetchedAt: time.Now()}
+	f.mu.Unlock()
+
+	return feed, nil
+}
+
+// departures returns the upcoming departures at stopID, soonest first
+func (f *TransitFetcher) departures(feed *gtfsFeed, feedCfg *config.TransitFeedConfig, stopID string, now time.Time) []TransitDeparture {
+	limit := f.config.MaxDepartures
+	if limit <= 0 {
+		limit = 8
+	}
+
+	var out []TransitDeparture
+	for _, tu := range feed.TripUpdates {
+		if tu.Canceled {
+			continue
+		}
+		for _, st := range tu.StopTimes {
+			if st.StopID != stopID || st.Skipped {
+				continue
+			}
+			event := st.Departure
+			if event.Time == 0 {
+				event = st.Arrival
+			}
+			// Delay-only updates need the static schedule, which we don't load
+			if event.Time == 0 {
+				continue
+			}
+			at := time.Unix(event.Time, 0)
+			// Keep departures that left under a minute ago; the feed may lag
+			if at.Before(now.Add(-time.Minute)) {
+				continue
+			}
+
+			route := tu.RouteID
+			if name, ok := feedCfg.Routes[tu.RouteID]; ok && name != "" {
+				route = name
+			}
+			out = append(out, TransitDeparture{
+				RouteID:      tu.RouteID,
+				Route:        route,
+				TripID:       tu.TripID,
+				Vehicle:      tu.VehicleLabel,
+				Time:         at,
+				MinutesAway:  int(at.Sub(now).Minutes()),
+				DelaySeconds: event.Delay,
+			})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// gtfsFeed is the subset of a GTFS-Realtime FeedMessage used for departures
+type gtfsFeed struct {
+	Timestamp   uint64
+	TripUpdates []gtfsTripUpdate
+}
+
+type gtfsTripUpdate struct {
+	TripID       string
+	RouteID      string
+	Canceled     bool
+	VehicleLabel string
+	StopTimes    []gtfsStopTime
+}
+
+type gtfsStopTime struct {
+	StopID    string
+	Skipped   bool
+	Arrival   gtfsStopTimeEvent
+	Departure gtfsStopTimeEvent
+}
+
+type gtfsStopTimeEvent struct {
+	Delay int32
+	Time  int64
+}
+
+// GTFS-Realtime field numbers (gtfs-realtime.proto)
+const (
+	gtfsFeedHeader          = 1
+	gtfsFeedEntity          = 2
+	gtfsHeaderTimestamp     = 3
+	gtfsEntityTripUpdate    = 3
+	gtfsTripUpdateTrip      = 1
+	gtfsTripUpdateStopTime  = 2
+	gtfsTripUpdateVehicle   = 3
+	gtfsTripID              = 1
+	gtfsTripScheduleRel     = 4
+	gtfsTripRouteID         = 5
+	gtfsVehicleID           = 1
+	gtfsVehicleLabel        = 2
+	gtfsStopTimeArrival     = 2
+	gtfsStopTimeDeparture   = 3
+	gtfsStopTimeStopID      = 4
+	gtfsStopTimeScheduleRel = 5
+	gtfsEventDelay          = 1
+	gtfsEventTime           = 2
+
+	gtfsTripCanceled    = 3
+	gtfsStopTimeSkipped = 1
+)
+
+// decodeGTFSRealtime decodes the trip updates of a GTFS-Realtime FeedMessage.
+// Only the handful of fields needed for departure boards are read; everything
+// else (alerts, vehicle positions, extensions) is skipped.
+func decodeGTFSRealtime(b []byte) (*gtfsFeed, error) {
+	feed := &gtfsFeed{}
+	err := walkProto(b, func(num protowire.Number, v []byte, n uint64) error {
+		switch num {
+		case gtfsFeedHeader:
+			return walkProto(v, func(num protowire.Number, _ []byte, n uint64) error {
+				if num == gtfsHeaderTimestamp {
+					feed.Timestamp = n
+				}
+				return nil
+			})
+		case gtfsFeedEntity:
+			return walkProto(v, func(num protowire.Number, v []byte, _ uint64) error {
+				if num != gtfsEntityTripUpdate {
+					return nil
+				}
+				tu, err := decodeTripUpdate(v)
+				if err != nil {
+					return err
+				}
+				feed.TripUpdates = append(feed.TripUpdates, tu)
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+func decodeTripUpdate(b []byte) (gtfsTripUpdate, error) {
+	var tu gtfsTripUpdate
+	err := walkProto(b, func(num protowire.Number, v []byte, _ uint64) error {
+		switch num {
+		case gtfsTripUpdateTrip:
+			return walkProto(v, func(num protowire.Number, v []byte, n uint64) error {
+				switch num {
+				case gtfsTripID:
+					tu.TripID = string(v)
+				case gtfsTripRouteID:
+					tu.RouteID = string(v)
+				case gtfsTripScheduleRel:
+					tu.Canceled = n == gtfsTripCanceled
+				}
+				return nil
+			})
+		case gtfsTripUpdateVehicle:
+			return walkProto(v, func(num protowire.Number, v []byte, _ uint64) error {
+				// Prefer the rider-facing label; fall back to the vehicle ID
+				if num == gtfsVehicleLabel || (num == gtfsVehicleID && tu.VehicleLabel == "") {
+					tu.VehicleLabel = string(v)
+				}
+				return nil
+			})
+		case gtfsTripUpdateStopTime:
+			st, err := decodeStopTimeUpdate(v)
+			if err != nil {
+				return err
+			}
+			tu.StopTimes = append(tu.StopTimes, st)
+		}
+		return nil
+	})
+	return tu, err
+}
+
+func decodeStopTimeUpdate(b []byte) (gtfsStopTime, error) {
+	var st gtfsStopTime
+	err := walkProto(b, func(num protowire.Number, v []byte, n uint64) error {
+		switch num {
+		case gtfsStopTimeStopID:
+			st.StopID = string(v)
+		case gtfsStopTimeScheduleRel:
+			st.Skipped = n == gtfsStopTimeSkipped
+		case gtfsStopTimeArrival:
+			return decodeStopTimeEvent(v, &st.Arrival)
+		case gtfsStopTimeDeparture:
+			return decodeStopTimeEvent(v, &st.Departure)
+		}
+		return nil
+	})
+	return st, err
+}
+
+func decodeStopTimeEvent(b []byte, ev *gtfsStopTimeEvent) error {
+	return walkProto(b, func(num protowire.Number, _ []byte, n uint64) error {
+		switch num {
+		case gtfsEventDelay:
+			// int32 fields are plain (not zigzag) varints
+			ev.Delay = int32(n)
+		case gtfsEventTime:
+			ev.Time = int64(n)
+		}
+		return nil
+	})
+}
+
+// walkProto calls fn for each field of a protobuf message. Length-delimited
+// fields pass their bytes in v; varint fields pass their value in n. Other
+// wire types are skipped.
+func walkProto(b []byte, fn func(num protowire.Number, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return protowire.ParseError(l)
+		}
+		b = b[l:]
+
+		switch typ {
+		case protowire.VarintType:
+			n, l := protowire.ConsumeVarint(b)
+			if l < 0 {
+				return protowire.ParseError(l)
+			}
+			if err := fn(num, nil, n); err != nil {
+				return err
+			}
+			b = b[l:]
+		case protowire.BytesType:
+			v, l := protowire.ConsumeBytes(b)
+			if l < 0 {
+				return protowire.ParseError(l)
+			}
+			if err := fn(num, v, 0); err != nil {
+				return err
+			}
+			b = b[l:]
+		default:
+			l := protowire.ConsumeFieldValue(num, typ, b)
+			if l < 0 {
+				return protowire.ParseError(l)
+			}
+			b = b[l:]
+		}
+	}
+	return nil
+}
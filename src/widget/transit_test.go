@@ -0,0 +1,198 @@
+package widget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/apimgr/search/src/config"
+)
+
+// gtfsTestStopTime builds a StopTimeUpdate with a departure event
+func gtfsTestStopTime(stopID string, at int64, delay int32, skipped bool) []byte {
+	var ev []byte
+	ev = protowire.AppendTag(ev, gtfsEventDelay, protowire.VarintType)
+	ev = protowire.AppendVarint(ev, uint64(int64(delay)))
+	ev = protowire.AppendTag(ev, gtfsEventTime, protowire.VarintType)
+	ev = protowire.AppendVarint(ev, uint64(at))
+
+	var st []byte
+	st = protowire.AppendTag(st, gtfsStopTimeStopID, protowire.BytesType)
+	st = protowire.AppendString(st, stopID)
+	st = protowire.AppendTag(st, gtfsStopTimeDeparture, protowire.BytesType)
+	st = protowire.AppendBytes(st, ev)
+	if skipped {
+		st = protowire.AppendTag(st, gtfsStopTimeScheduleRel, protowire.VarintType)
+		st = protowire.AppendVarint(st, gtfsStopTimeSkipped)
+	}
+	return st
+}
+
+// gtfsTestEntity builds a FeedEntity wrapping a TripUpdate
+func gtfsTestEntity(tripID, routeID, vehicle string, canceled bool, stopTimes ...[]byte) []byte {
+	var trip []byte
+	trip = protowire.AppendTag(trip, gtfsTripID, protowire.BytesType)
+	trip = protowire.AppendString(trip, tripID)
+	trip = protowire.AppendTag(trip, gtfsTripRouteID, protowire.BytesType)
+	trip = protowire.AppendString(trip, routeID)
+	if canceled {
+		trip = protowire.AppendTag(trip, gtfsTripScheduleRel, protowire.VarintType)
+		trip = protowire.AppendVarint(trip, gtfsTripCanceled)
+	}
+
+	var tu []byte
+	tu = protowire.AppendTag(tu, gtfsTripUpdateTrip, protowire.BytesType)
+	tu = protowire.AppendBytes(tu, trip)
+	for _, st := range stopTimes {
+		tu = protowire.AppendTag(tu, gtfsTripUpdateStopTime, protowire.BytesType)
+		tu = protowire.AppendBytes(tu, st)
+	}
+	if vehicle != "" {
+		var v []byte
+		v = protowire.AppendTag(v, gtfsVehicleLabel, protowire.BytesType)
+		v = protowire.AppendString(v, vehicle)
+		tu = protowire.AppendTag(tu, gtfsTripUpdateVehicle, protowire.BytesType)
+		tu = protowire.AppendBytes(tu, v)
+	}
+
+	var e []byte
+	e = protowire.AppendTag(e, 1, protowire.BytesType)
+	e = protowire.AppendString(e, tripID)
+	e = protowire.AppendTag(e, gtfsEntityTripUpdate, protowire.BytesType)
+	e = protowire.AppendBytes(e, tu)
+	return e
+}
+
+func gtfsTestFeed(now time.Time) []byte {
+	var header []byte
+	header = protowire.AppendTag(header, 1, protowire.BytesType)
+	header = protowire.AppendString(header, "2.0")
+	header = protowire.AppendTag(header, gtfsHeaderTimestamp, protowire.VarintType)
+	header = protowire.AppendVarint(header, uint64(now.Unix()))
+
+	var feed []byte
+	feed = protowire.AppendTag(feed, gtfsFeedHeader, protowire.BytesType)
+	feed = protowire.AppendBytes(feed, header)
+	entities := [][]byte{
+		gtfsTestEntity("t-late", "10", "Bus 4021", false, gtfsTestStopTime("S1", now.Add(12*time.Minute).Unix(), 120, false)),
+		gtfsTestEntity("t-soon", "20", "", false,
+			gtfsTestStopTime("S0", now.Add(-2*time.Minute).Unix(), 0, false),
+			gtfsTestStopTime("S1", now.Add(3*time.Minute).Unix(), -30, false)),
+		gtfsTestEntity("t-gone", "10", "", false, gtfsTestStopTime("S1", now.Add(-10*time.Minute).Unix(), 0, false)),
+		gtfsTestEntity("t-cancel", "10", "", true, gtfsTestStopTime("S1", now.Add(5*time.Minute).Unix(), 0, false)),
+		gtfsTestEntity("t-skip", "10", "", false, gtfsTestStopTime("S1", now.Add(6*time.Minute).Unix(), 0, true)),
+		gtfsTestEntity("t-other", "10", "", false, gtfsTestStopTime("S2", now.Add(4*time.Minute).Unix(), 0, false)),
+	}
+	for _, e := range entities {
+		feed = protowire.AppendTag(feed, gtfsFeedEntity, protowire.BytesType)
+		feed = protowire.AppendBytes(feed, e)
+	}
+	return feed
+}
+
+func TestDecodeGTFSRealtime(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	feed, err := decodeGTFSRealtime(gtfsTestFeed(now))
+	if err != nil {
+		t.Fatalf("decodeGTFSRealtime() error = %v", err)
+	}
+	if feed.Timestamp != uint64(now.Unix()) {
+		t.Errorf("Timestamp = %d, want %d", feed.Timestamp, now.Unix())
+	}
+	if len(feed.TripUpdates) != 6 {
+		t.Fatalf("TripUpdates = %d, want 6", len(feed.TripUpdates))
+	}
+	soon := feed.TripUpdates[1]
+	if soon.TripID != "t-soon" || soon.RouteID != "20" || len(soon.StopTimes) != 2 {
+		t.Errorf("trip update = %+v", soon)
+	}
+	if soon.StopTimes[1].Departure.Delay != -30 {
+		t.Errorf("negative delay decoded as %d, want -30", soon.StopTimes[1].Departure.Delay)
+	}
+	if !feed.TripUpdates[3].Canceled || !feed.TripUpdates[4].StopTimes[0].Skipped {
+		t.Error("canceled trip or skipped stop not decoded")
+	}
+
+	if _, err := decodeGTFSRealtime([]byte{0x12, 0xff}); err == nil {
+		t.Error("decodeGTFSRealtime() of truncated input should fail")
+	}
+}
+
+func TestTransitFetcherFetch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Api-Key") != "k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(gtfsTestFeed(time.Now()))
+	}))
+	defer server.Close()
+
+	cfg := &config.TransitWidgetConfig{
+		Enabled:       true,
+		MaxDepartures: 5,
+		Feeds: []config.TransitFeedConfig{{
+			ID:      "metro",
+			Name:    "Metro",
+			URL:     server.URL,
+			Headers: map[string]string{"X-Api-Key": "k"},
+			Stops:   []config.TransitStopConfig{{ID: "S1", Name: "Main St"}},
+			Routes:  map[string]string{"20": "Route 20 Express"},
+		}},
+	}
+	f := NewTransitFetcher(cfg)
+
+	t.Run("lists feeds without a stop", func(t *testing.T) {
+		wd, err := f.Fetch(context.Background(), map[string]string{})
+		if err != nil || wd.Error != "" {
+			t.Fatalf("Fetch() err = %v, widget error = %q", err, wd.Error)
+		}
+		data := wd.Data.(*TransitData)
+		if len(data.Available) != 1 || data.Available[0].Stops[0].Name != "Main St" {
+			t.Errorf("Available = %+v", data.Available)
+		}
+	})
+
+	t.Run("departures at stop", func(t *testing.T) {
+		wd, err := f.Fetch(context.Background(), map[string]string{"stop": "S1"})
+		if err != nil || wd.Error != "" {
+			t.Fatalf("Fetch() err = %v, widget error = %q", err, wd.Error)
+		}
+		data := wd.Data.(*TransitData)
+		if data.Feed != "metro" || data.StopName != "Main St" {
+			t.Errorf("Feed/StopName = %q/%q", data.Feed, data.StopName)
+		}
+		if len(data.Departures) != 2 {
+			t.Fatalf("Departures = %+v, want 2", data.Departures)
+		}
+		first, second := data.Departures[0], data.Departures[1]
+		if first.TripID != "t-soon" || first.Route != "Route 20 Express" || first.DelaySeconds != -30 {
+			t.Errorf("first departure = %+v", first)
+		}
+		if second.TripID != "t-late" || second.Route != "10" || second.Vehicle != "Bus 4021" {
+			t.Errorf("second departure = %+v", second)
+		}
+	})
+
+	t.Run("feed is cached between stops", func(t *testing.T) {
+		before := requests
+		f.Fetch(context.Background(), map[string]string{"feed": "metro", "stop": "S2"})
+		if requests != before {
+			t.Errorf("feed downloaded again within TTL (%d requests)", requests-before)
+		}
+	})
+
+	t.Run("unknown feed", func(t *testing.T) {
+		wd, _ := f.Fetch(context.Background(), map[string]string{"feed": "nope", "stop": "S1"})
+		if wd.Error == "" {
+			t.Error("expected error for unknown feed")
+		}
+	})
+}
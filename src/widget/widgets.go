@@ -37,6 +37,9 @@ const (
 	WidgetDictionary  WidgetType = "dictionary"
 	WidgetIPAddress   WidgetType = "ipaddress"
 	WidgetColorPicker WidgetType = "colorpicker"
+	WidgetFlight      WidgetType = "flight"
+	WidgetTransit     WidgetType = "transit"
+	WidgetTicker      WidgetType = "ticker"
 )
 
 // WidgetCategory represents the category of a widget
@@ -133,6 +136,9 @@ func (m *Manager) registerWidgets() {
 		{Type: WidgetDictionary, Name: "Dictionary", Description: "Word definitions", Icon: "spell-check", Category: CategoryData, Order: 22},
 		{Type: WidgetIPAddress, Name: "IP Address", Description: "Your IP address info", Icon: "network-wired", Category: CategoryTool, Order: 23},
 		{Type: WidgetColorPicker, Name: "Color Picker", Description: "Color selection tool", Icon: "palette", Category: CategoryTool, Order: 24},
+		{Type: WidgetFlight, Name: "Flight Status", Description: "Live flight status and position", Icon: "plane", Category: CategoryData, Order: 25},
+		{Type: WidgetTransit, Name: "Transit Departures", Description: "Upcoming departures at a stop", Icon: "bus", Category: CategoryData, Order: 26},
+		{Type: WidgetTicker, Name: "Market Ticker", Description: "Stock and crypto prices", Icon: "chart-area", Category: CategoryData, Order: 27},
 	}
 
 	for _, w := range widgets {